@@ -0,0 +1,34 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/geoip"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+// ClientIPFromConfig converts cfg, typically cfg.Server.ClientIP, to the
+// settings of middleware.ClientIP, opening the GeoIP database when one is
+// configured. It returns nil when cfg sets nothing, so the result can be
+// assigned to Profile.ClientIP directly.
+func ClientIPFromConfig(cfg config.ClientIPConfig) (*middleware.ClientIPConfig, error) {
+	if len(cfg.TrustedProxies) == 0 && len(cfg.Headers) == 0 && cfg.GeoIPDB == "" {
+		return nil, nil
+	}
+
+	proxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	clientIP := &middleware.ClientIPConfig{TrustedProxies: proxies, Headers: cfg.Headers}
+
+	if cfg.GeoIPDB != "" {
+		db, err := geoip.Open(cfg.GeoIPDB)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load geoip database: %w", err)
+		}
+		clientIP.Locator = db
+	}
+	return clientIP, nil
+}
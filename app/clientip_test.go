@@ -0,0 +1,53 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestClientIPFromConfig(t *testing.T) {
+	if got, err := ClientIPFromConfig(config.ClientIPConfig{}); got != nil || err != nil {
+		t.Errorf("ClientIPFromConfig() without settings = %+v, %v, want nil", got, err)
+	}
+
+	got, err := ClientIPFromConfig(config.ClientIPConfig{
+		TrustedProxies: []string{"10.0.0.0/8", "192.0.2.10"},
+		Headers:        []string{"X-Real-IP"},
+	})
+	if err != nil || got == nil || len(got.TrustedProxies) != 2 || got.Headers[0] != "X-Real-IP" || got.Locator != nil {
+		t.Errorf("ClientIPFromConfig() = %+v, %v", got, err)
+	}
+
+	if _, err := ClientIPFromConfig(config.ClientIPConfig{TrustedProxies: []string{"nope"}}); err == nil {
+		t.Error("ClientIPFromConfig(invalid proxy) succeeded")
+	}
+	if _, err := ClientIPFromConfig(config.ClientIPConfig{GeoIPDB: filepath.Join(t.TempDir(), "missing.mmdb")}); err == nil {
+		t.Error("ClientIPFromConfig(missing database) succeeded")
+	}
+}
+
+func TestProfileClientIP(t *testing.T) {
+	p := ProfileInternal()
+	p.ClientIP, _ = ClientIPFromConfig(config.ClientIPConfig{TrustedProxies: []string{"10.0.0.0/8"}})
+
+	var got string
+	r := chi.NewRouter()
+	r.Use(p.Middlewares()...)
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		got = middleware.GetClientInfo(r.Context()).IP.String()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.1.2.3:1234"
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 192.168.1.20")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if got != "192.168.1.20" {
+		t.Errorf("client IP = %q, want 192.168.1.20", got)
+	}
+}
@@ -0,0 +1,245 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDependencies is returned by the first start function of Setup when the
+// components' dependencies cannot be satisfied.
+var ErrDependencies = errors.New("invalid component dependencies")
+
+// Component declares a component for Setup with a name, the names of the
+// components it depends on and how long it may take to start. Setup starts
+// dependencies first and stops them last. Components passed unwrapped are
+// named after their type, e.g. "postgres.userStore", and depend on nothing.
+type Component struct {
+	Name      string
+	Value     any
+	DependsOn []string
+	// StartTimeout bounds Start; zero waits as long as the context passed to
+	// app.Start allows.
+	StartTimeout time.Duration
+}
+
+// component is a component of Setup with its declaration resolved.
+type component struct {
+	Component
+	deps []int
+}
+
+// declare resolves the declarations of comps, wrapped in Component or not.
+func declare(comps []any) []component {
+	declared := make([]component, len(comps))
+	for i, c := range comps {
+		switch v := c.(type) {
+		case Component:
+			declared[i].Component = v
+		case *Component:
+			declared[i].Component = *v
+		default:
+			declared[i].Component = Component{Value: c}
+		}
+		if declared[i].Name == "" {
+			declared[i].Name = componentName(declared[i].Value)
+		}
+	}
+	return declared
+}
+
+// order sorts comps so every component follows its dependencies, keeping
+// the order they were passed in otherwise.
+func order(comps []component) ([]component, error) {
+	index := make(map[string]int, len(comps))
+	ambiguous := make(map[string]bool)
+	for i, c := range comps {
+		if _, ok := index[c.Name]; ok {
+			ambiguous[c.Name] = true
+		}
+		index[c.Name] = i
+	}
+
+	for i := range comps {
+		for _, dep := range comps[i].DependsOn {
+			j, ok := index[dep]
+			switch {
+			case !ok:
+				return nil, fmt.Errorf("%w: %s depends on unknown component %s", ErrDependencies, comps[i].Name, dep)
+			case ambiguous[dep]:
+				return nil, fmt.Errorf("%w: %s depends on %s, which names several components", ErrDependencies, comps[i].Name, dep)
+			case j == i:
+				return nil, fmt.Errorf("%w: %s depends on itself", ErrDependencies, comps[i].Name)
+			}
+			comps[i].deps = append(comps[i].deps, j)
+		}
+	}
+
+	sorted := make([]component, 0, len(comps))
+	placed := make([]bool, len(comps))
+	pos := make([]int, len(comps))
+	for len(sorted) < len(comps) {
+		next := -1
+		for i, c := range comps {
+			if !placed[i] && allPlaced(c.deps, placed) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("%w: dependency cycle among %s", ErrDependencies, strings.Join(unplaced(comps, placed), ", "))
+		}
+		placed[next] = true
+		pos[next] = len(sorted)
+		sorted = append(sorted, comps[next])
+	}
+
+	for i := range sorted {
+		deps := make([]int, len(sorted[i].deps))
+		for k, d := range sorted[i].deps {
+			deps[k] = pos[d]
+		}
+		sorted[i].deps = deps
+	}
+	return sorted, nil
+}
+
+func allPlaced(deps []int, placed []bool) bool {
+	for _, d := range deps {
+		if !placed[d] {
+			return false
+		}
+	}
+	return true
+}
+
+func unplaced(comps []component, placed []bool) []string {
+	var names []string
+	for i, c := range comps {
+		if !placed[i] {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// startState records whether a component of Setup started, so that its
+// dependents, when started concurrently, wait for it.
+type startState struct {
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+func newStartState() *startState {
+	return &startState{done: make(chan struct{})}
+}
+
+func (s *startState) finish(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
+
+func (s *startState) started() bool {
+	select {
+	case <-s.done:
+		return s.err == nil
+	default:
+		return false
+	}
+}
+
+// plan holds the ordered components of Setup and the start state of those
+// that are Startable.
+type plan struct {
+	comps  []component
+	states []*startState
+}
+
+func newPlan(comps []component) *plan {
+	p := &plan{comps: comps, states: make([]*startState, len(comps))}
+	for i, c := range comps {
+		if _, ok := c.Value.(Startable); ok {
+			p.states[i] = newStartState()
+		}
+	}
+	return p
+}
+
+// awaitDeps waits until the dependencies of component i, and of those that
+// have nothing to start, have started.
+func (p *plan) awaitDeps(ctx context.Context, i int) error {
+	for _, d := range p.comps[i].deps {
+		if s := p.states[d]; s != nil {
+			select {
+			case <-s.done:
+				if s.err != nil {
+					return fmt.Errorf("dependency %s did not start", p.comps[d].Name)
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err := p.awaitDeps(ctx, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startFunc returns the start function of component i. It waits for the
+// component's dependencies, names the component in its errors and gives up
+// after its start timeout.
+func (p *plan) startFunc(i int, start func(context.Context) error) func(context.Context) error {
+	c := p.comps[i]
+	return func(ctx context.Context) error {
+		err := p.awaitDeps(ctx, i)
+		if err == nil {
+			err = startWithin(ctx, c.StartTimeout, start)
+		}
+		if err != nil {
+			err = fmt.Errorf("cannot start %s: %w", c.Name, err)
+		}
+		p.states[i].finish(err)
+		return err
+	}
+}
+
+// stopFunc returns the stop function of component i, which does nothing
+// when the component has something to start and has not started.
+func (p *plan) stopFunc(i int, stop func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if s := p.states[i]; s != nil && !s.started() {
+			return nil
+		}
+		return stop(ctx)
+	}
+}
+
+// startWithin runs start, giving up after timeout when positive. A start
+// that ignores its context is left running in the background.
+func startWithin(ctx context.Context, timeout time.Duration, start func(context.Context) error) error {
+	if timeout <= 0 {
+		return start(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- start(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("did not start within %s: %w", timeout, ctx.Err())
+		}
+		return ctx.Err()
+	}
+}
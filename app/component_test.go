@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/go-chi/chi/v5"
+)
+
+type orderedStartable struct {
+	name  string
+	order *[]string
+	err   error
+	block bool
+}
+
+func (o *orderedStartable) Start(ctx context.Context) error {
+	if o.block {
+		select {}
+	}
+	if o.err != nil {
+		return o.err
+	}
+	*o.order = append(*o.order, o.name)
+	return nil
+}
+
+func TestSetupOrdersByDependencies(t *testing.T) {
+	var started []string
+	db := &orderedStartable{name: "db", order: &started}
+	cache := &orderedStartable{name: "cache", order: &started}
+	api := &orderedStartable{name: "api", order: &started}
+	worker := &orderedStartable{name: "worker", order: &started}
+
+	starts, _, _ := Setup(context.Background(), chi.NewRouter(),
+		Component{Name: "api", Value: api, DependsOn: []string{"db", "cache"}},
+		worker,
+		Component{Name: "cache", Value: cache, DependsOn: []string{"db"}},
+		Component{Name: "db", Value: db},
+	)
+	if err := Start(context.Background(), log.NewNoopLogger(), starts, nil, nil, chi.NewRouter()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	if got, want := strings.Join(started, ","), "worker,db,cache,api"; got != want {
+		t.Errorf("start order = %s, want %s", got, want)
+	}
+}
+
+func TestSetupInvalidDependencies(t *testing.T) {
+	var started []string
+	a := &orderedStartable{name: "a", order: &started}
+	b := &orderedStartable{name: "b", order: &started}
+
+	tests := []struct {
+		name  string
+		comps []any
+		want  string
+	}{
+		{"unknown", []any{Component{Name: "a", Value: a, DependsOn: []string{"db"}}}, "a depends on unknown component db"},
+		{"self", []any{Component{Name: "a", Value: a, DependsOn: []string{"a"}}}, "a depends on itself"},
+		{"cycle", []any{
+			Component{Name: "a", Value: a, DependsOn: []string{"b"}},
+			Component{Name: "b", Value: b, DependsOn: []string{"a"}},
+		}, "dependency cycle among a, b"},
+		{"ambiguous", []any{
+			&orderedStartable{}, &orderedStartable{},
+			Component{Name: "a", Value: a, DependsOn: []string{"app.orderedStartable"}},
+		}, "names several components"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			starts, stops, registrars := Setup(context.Background(), chi.NewRouter(), tt.comps...)
+			if len(starts) != 1 || len(stops) != 0 || len(registrars) != 0 {
+				t.Fatalf("Setup() = %d starts, %d stops, %d registrars, want a single failing start", len(starts), len(stops), len(registrars))
+			}
+			err := starts[0](context.Background())
+			if !errors.Is(err, ErrDependencies) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("start error = %v, want ErrDependencies with %q", err, tt.want)
+			}
+		})
+	}
+	if len(started) != 0 {
+		t.Errorf("started %v, want nothing", started)
+	}
+}
+
+func TestSetupStartErrors(t *testing.T) {
+	var started []string
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name string
+		comp any
+		want string
+	}{
+		{"named", Component{Name: "db", Value: &orderedStartable{order: &started, err: errBoom}}, "cannot start db: boom"},
+		{"unnamed", &orderedStartable{order: &started, err: errBoom}, "cannot start app.orderedStartable: boom"},
+		{"timeout", Component{Name: "broker", Value: &orderedStartable{block: true}, StartTimeout: 10 * time.Millisecond}, "cannot start broker: did not start within 10ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			starts, _, _ := Setup(context.Background(), chi.NewRouter(), tt.comp)
+			err := starts[0](context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("start error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetupNamesHealthChecksAfterComponent(t *testing.T) {
+	health := NewHealthRegistry()
+
+	Setup(context.Background(), chi.NewRouter(), health, Component{Name: "users", Value: &fakePinger{}})
+
+	if checks := health.Checks(); len(checks) != 1 || checks[0].Name != "users" {
+		t.Errorf("checks = %+v, want one named users", checks)
+	}
+}
+
+// rendezvous starts only once its peer is starting too, so it can start
+// only concurrently with it.
+type rendezvous struct {
+	self, peer chan struct{}
+	stopped    atomic.Bool
+}
+
+func (r *rendezvous) Start(ctx context.Context) error {
+	close(r.self)
+	select {
+	case <-r.peer:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *rendezvous) Stop(ctx context.Context) error {
+	r.stopped.Store(true)
+	return nil
+}
+
+func TestStartConcurrently(t *testing.T) {
+	a2b, b2a := make(chan struct{}), make(chan struct{})
+	a := &rendezvous{self: a2b, peer: b2a}
+	b := &rendezvous{self: b2a, peer: a2b}
+	var started []string
+	api := &orderedStartable{name: "api", order: &started}
+
+	starts, stops, _ := Setup(context.Background(), chi.NewRouter(),
+		Component{Name: "api", Value: api, DependsOn: []string{"a", "b"}},
+		Component{Name: "a", Value: a},
+		Component{Name: "b", Value: b},
+	)
+	err := Start(context.Background(), log.NewNoopLogger(), starts, stops, nil, chi.NewRouter(),
+		WithConcurrentStart(), WithStartTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if len(started) != 1 {
+		t.Errorf("api started %d times, want once after a and b", len(started))
+	}
+}
+
+func TestStartConcurrentlyFailure(t *testing.T) {
+	var started []string
+	db := &fakeComponent{fakeStartable: fakeStartable{err: errors.New("db down")}}
+	cache := &fakeComponent{fakeStartable: fakeStartable{err: errors.New("cache down")}}
+	broker := &fakeComponent{}
+	api := &orderedStartable{name: "api", order: &started}
+
+	starts, stops, _ := Setup(context.Background(), chi.NewRouter(),
+		Component{Name: "db", Value: db},
+		Component{Name: "cache", Value: cache},
+		Component{Name: "broker", Value: broker},
+		Component{Name: "api", Value: api, DependsOn: []string{"db"}},
+	)
+	err := Start(context.Background(), log.NewNoopLogger(), starts, stops, nil, chi.NewRouter(), WithConcurrentStart())
+
+	for _, want := range []string{"cannot start db: db down", "cannot start cache: cache down", "cannot start api: dependency db did not start"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Start() error = %v, want it to contain %q", err, want)
+		}
+	}
+	if len(started) != 0 {
+		t.Error("expected api not to start without db")
+	}
+	if !broker.stopped.Load() {
+		t.Error("expected broker to be stopped (rollback)")
+	}
+	if db.stopped.Load() || cache.stopped.Load() {
+		t.Error("expected components that did not start not to be stopped")
+	}
+}
+
+func TestStartWithStartTimeout(t *testing.T) {
+	starts := []func(context.Context) error{func(context.Context) error { select {} }}
+
+	err := Start(context.Background(), log.NewNoopLogger(), starts, nil, nil, chi.NewRouter(), WithStartTimeout(10*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Start() error = %v, want deadline exceeded", err)
+	}
+}
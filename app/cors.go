@@ -0,0 +1,37 @@
+package app
+
+import (
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// CORSFromConfig converts cfg to the policy applied by middleware.CORS. It
+// returns nil when cfg allows no origins, so the result can be assigned to
+// Profile.CORS directly.
+func CORSFromConfig(cfg config.CORSConfig) *middleware.CORSConfig {
+	if len(cfg.AllowedOrigins) == 0 {
+		return nil
+	}
+	return &middleware.CORSConfig{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           int(cfg.MaxAge.Seconds()),
+	}
+}
+
+// WithCORS answers preflight requests and sets CORS headers for the origins
+// in cfg, typically cfg.Server.CORS. It does nothing when no origins are
+// configured. It installs a middleware, so it must be applied before any
+// route is registered; with WithProfile, set Profile.CORS instead.
+func WithCORS(cfg config.CORSConfig) RouterOption {
+	return func(r chi.Router) error {
+		if policy := CORSFromConfig(cfg); policy != nil {
+			r.Use(middleware.CORS(*policy))
+		}
+		return nil
+	}
+}
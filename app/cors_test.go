@@ -0,0 +1,109 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestCORSFromConfig(t *testing.T) {
+	if got := CORSFromConfig(config.CORSConfig{}); got != nil {
+		t.Errorf("CORSFromConfig() without origins = %+v, want nil", got)
+	}
+
+	got := CORSFromConfig(config.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedHeaders:   []string{"Authorization"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	if got == nil || got.MaxAge != 600 || !got.AllowCredentials || got.AllowedHeaders[0] != "Authorization" {
+		t.Errorf("CORSFromConfig() = %+v", got)
+	}
+}
+
+func TestWithCORS(t *testing.T) {
+	cfg := config.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}
+
+	r := chi.NewRouter()
+	if err := ApplyRouterOptions(r, WithCORS(cfg)); err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		wantStatus  int
+		wantOrigin  string
+		wantMethods string
+		wantMaxAge  string
+	}{
+		{
+			name:        "preflight",
+			method:      http.MethodOptions,
+			origin:      "https://app.example.com",
+			wantStatus:  http.StatusNoContent,
+			wantOrigin:  "https://app.example.com",
+			wantMethods: "GET, POST",
+			wantMaxAge:  "3600",
+		},
+		{name: "simple request", method: http.MethodGet, origin: "https://app.example.com", wantStatus: http.StatusOK, wantOrigin: "https://app.example.com"},
+		{name: "unknown origin", method: http.MethodGet, origin: "https://evil.example.com", wantStatus: http.StatusOK},
+		{name: "same origin", method: http.MethodGet, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/users", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			h := rec.Header()
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := h.Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("Allow-Methods = %q, want %q", got, tt.wantMethods)
+			}
+			if got := h.Get("Access-Control-Max-Age"); got != tt.wantMaxAge {
+				t.Errorf("Max-Age = %q, want %q", got, tt.wantMaxAge)
+			}
+			if tt.wantOrigin != "" && h.Get("Access-Control-Allow-Credentials") != "true" {
+				t.Error("expected Allow-Credentials")
+			}
+		})
+	}
+}
+
+func TestWithCORSDisabled(t *testing.T) {
+	r := chi.NewRouter()
+	if err := ApplyRouterOptions(r, WithCORS(config.CORSConfig{})); err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+	if n := len(r.Middlewares()); n != 0 {
+		t.Errorf("WithCORS() without origins installed %d middlewares, want 0", n)
+	}
+}
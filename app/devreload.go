@@ -0,0 +1,138 @@
+package app
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// devReloadInterval is how often WithDevReload looks for changed files at
+// most, however many requests arrive.
+var devReloadInterval = 500 * time.Millisecond
+
+// Reloader reloads what it read from files, such as templates.
+// *web.TemplateManager implements it.
+type Reloader interface {
+	Reload() error
+}
+
+// ReloaderFunc adapts a function to Reloader.
+type ReloaderFunc func() error
+
+func (f ReloaderFunc) Reload() error {
+	return f()
+}
+
+// DevFS returns dir on disk when dev is set and embedded otherwise, so that in
+// development templates and static files are read from the working tree
+// instead of the copy compiled in. dir is the directory the embed patterns
+// are relative to, usually the package directory: "." for //go:embed assets.
+func DevFS(embedded fs.FS, dir string, dev bool) fs.FS {
+	if !dev {
+		return embedded
+	}
+	return os.DirFS(dir)
+}
+
+// WithDevReload reloads the reloaders before serving a request whenever a
+// file under dir changed, so edited templates render without restarting the
+// process. Changes are looked for at most every half second by comparing
+// file sizes and modification times. While a reload fails, for instance on a
+// template syntax error, requests are answered 500 with the error.
+// It is meant for development, typically enabled with cfg.AQM.DevMode
+// together with DevFS, and installs a middleware, so it must be applied
+// before any route is registered.
+func WithDevReload(dir string, reloaders ...Reloader) RouterOption {
+	return func(r chi.Router) error {
+		if dir == "" {
+			return fmt.Errorf("dev reload directory cannot be empty")
+		}
+		if len(reloaders) == 0 {
+			return fmt.Errorf("dev reload needs at least one reloader")
+		}
+		stamp, err := fingerprint(dir)
+		if err != nil {
+			return fmt.Errorf("cannot watch %s: %w", dir, err)
+		}
+
+		d := &devReloader{dir: dir, reloaders: reloaders, stamp: stamp, checked: time.Now()}
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if err := d.check(); err != nil {
+					http.Error(w, "Dev reload failed: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+				next.ServeHTTP(w, req)
+			})
+		})
+		return nil
+	}
+}
+
+// devReloader tracks the files under dir for WithDevReload.
+type devReloader struct {
+	dir       string
+	reloaders []Reloader
+
+	mu      sync.Mutex
+	checked time.Time
+	stamp   uint64
+	err     error
+}
+
+// check reloads when the files changed since the last reload that
+// succeeded, and returns the error of the last reload until one succeeds.
+func (d *devReloader) check() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if time.Since(d.checked) < devReloadInterval {
+		return d.err
+	}
+	d.checked = time.Now()
+
+	stamp, err := fingerprint(d.dir)
+	if err != nil {
+		return err
+	}
+	if stamp == d.stamp && d.err == nil {
+		return nil
+	}
+
+	d.err = nil
+	for _, rl := range d.reloaders {
+		if err := rl.Reload(); err != nil {
+			d.err = err
+			return err
+		}
+	}
+	d.stamp = stamp
+	return nil
+}
+
+// fingerprint hashes the path, size and modification time of the files
+// under dir.
+func fingerprint(dir string) (uint64, error) {
+	h := fnv.New64a()
+	err := fs.WalkDir(os.DirFS(dir), ".", func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() {
+			return nil
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return h.Sum64(), err
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestWithDevReload(t *testing.T) {
+	defer func(d time.Duration) { devReloadInterval = d }(devReloadInterval)
+	devReloadInterval = 0
+
+	dir := t.TempDir()
+	page := filepath.Join(dir, "page.html")
+	if err := os.WriteFile(page, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reloads := 0
+	var reloadErr error
+	r := chi.NewRouter()
+	if err := WithDevReload(dir, ReloaderFunc(func() error {
+		reloads++
+		return reloadErr
+	}))(r); err != nil {
+		t.Fatalf("WithDevReload() error = %v", err)
+	}
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	get := func() int {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code
+	}
+
+	if code := get(); code != http.StatusOK || reloads != 0 {
+		t.Fatalf("unchanged files: status %d, %d reloads, want 200 and none", code, reloads)
+	}
+
+	os.WriteFile(page, []byte("v2 {{"), 0o644)
+	reloadErr = errors.New("template: page.html: unexpected EOF")
+	if code := get(); code != http.StatusInternalServerError || reloads != 1 {
+		t.Fatalf("failing reload: status %d, %d reloads, want 500 after one", code, reloads)
+	}
+
+	os.WriteFile(page, []byte("v3"), 0o644)
+	reloadErr = nil
+	if code := get(); code != http.StatusOK || reloads != 2 {
+		t.Fatalf("fixed file: status %d, %d reloads, want 200 after two", code, reloads)
+	}
+	if code := get(); code != http.StatusOK || reloads != 2 {
+		t.Errorf("after reload: status %d, %d reloads, want no more", code, reloads)
+	}
+}
+
+func TestWithDevReloadInvalid(t *testing.T) {
+	reloader := ReloaderFunc(func() error { return nil })
+	tests := []struct {
+		name      string
+		dir       string
+		reloaders []Reloader
+		want      string
+	}{
+		{"no dir", "", []Reloader{reloader}, "cannot be empty"},
+		{"no reloaders", t.TempDir(), nil, "at least one reloader"},
+		{"missing dir", filepath.Join(t.TempDir(), "missing"), []Reloader{reloader}, "cannot watch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WithDevReload(tt.dir, tt.reloaders...)(chi.NewRouter())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("WithDevReload() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDevFS(t *testing.T) {
+	embedded := fstest.MapFS{"assets/page.html": {Data: []byte("embedded")}}
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "assets"), 0o755)
+	os.WriteFile(filepath.Join(dir, "assets", "page.html"), []byte("disk"), 0o644)
+
+	for dev, want := range map[bool]string{false: "embedded", true: "disk"} {
+		got, err := fs.ReadFile(DevFS(embedded, dir, dev), "assets/page.html")
+		if err != nil || string(got) != want {
+			t.Errorf("DevFS(dev=%v) reads %q, %v, want %q", dev, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RouteGroup declares routes that share a middleware stack, such as the
+// public API, internal endpoints and admin area of one service. Its
+// middlewares run after the router's global ones and only for its routes.
+type RouteGroup struct {
+	// Name identifies the group in errors, e.g. "public" or "admin".
+	Name string
+	// Prefix mounts the group below a path such as "/admin"; empty registers
+	// its routes at the router's root.
+	Prefix string
+	// Middlewares are applied in order, e.g. authentication, rate and body
+	// limits. See Profile.Restrictions.
+	Middlewares []func(http.Handler) http.Handler
+	// Routes registers the group's routes on a router carrying its middlewares.
+	Routes func(chi.Router)
+}
+
+// Group returns a RouteGroup applying the profile's restrictions followed by
+// mws, named after the profile.
+func (p Profile) Group(prefix string, routes func(chi.Router), mws ...func(http.Handler) http.Handler) RouteGroup {
+	return RouteGroup{
+		Name:        p.Name,
+		Prefix:      prefix,
+		Middlewares: append(p.Restrictions(), mws...),
+		Routes:      routes,
+	}
+}
+
+// WithRouteGroups registers each group with its own middleware stack. Names
+// and prefixes must be unique, and every group needs Routes. Apply it after
+// the options installing global middlewares.
+func WithRouteGroups(groups ...RouteGroup) RouterOption {
+	return func(r chi.Router) error {
+		names := make(map[string]bool, len(groups))
+		prefixes := make(map[string]bool, len(groups))
+		for _, g := range groups {
+			if g.Name == "" {
+				return fmt.Errorf("route group requires a name")
+			}
+			if g.Routes == nil {
+				return fmt.Errorf("route group %s has no routes", g.Name)
+			}
+			if names[g.Name] {
+				return fmt.Errorf("duplicate route group %s", g.Name)
+			}
+			names[g.Name] = true
+
+			prefix := strings.TrimSuffix(g.Prefix, "/")
+			if prefix != "" && !strings.HasPrefix(prefix, "/") {
+				return fmt.Errorf("route group %s: prefix must start with /, got %q", g.Name, g.Prefix)
+			}
+			if prefix != "" && prefixes[prefix] {
+				return fmt.Errorf("route group %s: prefix %s is already used", g.Name, prefix)
+			}
+			prefixes[prefix] = true
+		}
+
+		for _, g := range groups {
+			register := func(gr chi.Router) {
+				gr.Use(g.Middlewares...)
+				g.Routes(gr)
+			}
+			if prefix := strings.TrimSuffix(g.Prefix, "/"); prefix != "" {
+				r.Route(prefix, register)
+			} else {
+				r.Group(register)
+			}
+		}
+		return nil
+	}
+}
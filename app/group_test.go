@@ -0,0 +1,101 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestWithRouteGroups(t *testing.T) {
+	tag := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Group", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	requireKey := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Admin-Key") != "secret" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	r := chi.NewRouter()
+	err := ApplyRouterOptions(r, WithPing(), WithRouteGroups(
+		RouteGroup{Name: "public", Middlewares: []func(http.Handler) http.Handler{tag("public")}, Routes: func(r chi.Router) {
+			r.Get("/orders", ok)
+		}},
+		RouteGroup{Name: "admin", Prefix: "/admin", Middlewares: []func(http.Handler) http.Handler{tag("admin"), requireKey}, Routes: func(r chi.Router) {
+			r.Get("/users", ok)
+		}},
+		ProfileInternal().Group("/internal", func(r chi.Router) {
+			r.Get("/sync", ok)
+		}, tag("internal")),
+	))
+	if err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		remoteAddr string
+		header     map[string]string
+		want       int
+		group      string
+	}{
+		{"public", "/orders", "203.0.113.5:1234", nil, http.StatusOK, "public"},
+		{"admin without key", "/admin/users", "203.0.113.5:1234", nil, http.StatusUnauthorized, "admin"},
+		{"admin with key", "/admin/users", "203.0.113.5:1234", map[string]string{"X-Admin-Key": "secret"}, http.StatusOK, "admin"},
+		{"internal from private network", "/internal/sync", "10.0.0.1:1234", nil, http.StatusOK, "internal"},
+		{"internal from public network", "/internal/sync", "203.0.113.5:1234", nil, http.StatusForbidden, ""},
+		{"outside groups", "/ping", "203.0.113.5:1234", nil, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.want)
+			}
+			if got := rec.Header().Values("X-Group"); len(got) > 1 || (len(got) == 1) != (tt.group != "") || (tt.group != "" && got[0] != tt.group) {
+				t.Errorf("groups applied = %v, want %q", got, tt.group)
+			}
+		})
+	}
+}
+
+func TestWithRouteGroupsInvalid(t *testing.T) {
+	routes := func(chi.Router) {}
+	tests := []struct {
+		name   string
+		groups []RouteGroup
+	}{
+		{"no name", []RouteGroup{{Routes: routes}}},
+		{"no routes", []RouteGroup{{Name: "public"}}},
+		{"duplicate name", []RouteGroup{{Name: "public", Routes: routes}, {Name: "public", Prefix: "/v2", Routes: routes}}},
+		{"duplicate prefix", []RouteGroup{{Name: "a", Prefix: "/admin", Routes: routes}, {Name: "b", Prefix: "/admin/", Routes: routes}}},
+		{"relative prefix", []RouteGroup{{Name: "admin", Prefix: "admin", Routes: routes}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ApplyRouterOptions(chi.NewRouter(), WithRouteGroups(tt.groups...)); err == nil {
+				t.Error("WithRouteGroups() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthChecker reports whether a dependency is usable; *database.Database implements it.
+type HealthChecker interface {
+	CheckHealth(context.Context) error
+}
+
+// HealthCheckFunc adapts a function to HealthChecker.
+type HealthCheckFunc func(context.Context) error
+
+// CheckHealth calls f(ctx).
+func (f HealthCheckFunc) CheckHealth(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Pinger represents a component whose backing storage can be pinged.
+// Every store interface (auth.UserStore, audit.Store, quota.Store, ...) includes it.
+type Pinger interface {
+	Ping(context.Context) error
+}
+
+// HealthCheck is a named dependency check reported by GET /health.
+type HealthCheck struct {
+	Name    string
+	Checker HealthChecker
+}
+
+// HealthCollector collects health checks. *HealthRegistry implements it.
+type HealthCollector interface {
+	Add(name string, c HealthChecker) error
+}
+
+// HealthReporter represents a component exposing several named readiness
+// checks, e.g. a database reporting both connectivity and migrations.
+// Setup adds them under their own names instead of the component's type name.
+// Only standard types are used so packages can implement it without importing app.
+type HealthReporter interface {
+	HealthChecks() map[string]func(context.Context) error
+}
+
+// Drainer represents a component that stops reporting ready when shutdown
+// begins. *HealthRegistry implements it.
+type Drainer interface {
+	Drain()
+}
+
+// HealthRegistry is a concurrency-safe set of health checks served by
+// WithHealthRegistry and WithProbes. When passed to Setup it is filled with
+// every HealthReporter, HealthChecker and Pinger among the other components.
+type HealthRegistry struct {
+	mu       sync.RWMutex
+	checks   []HealthCheck
+	draining atomic.Bool
+}
+
+// NewHealthRegistry creates an empty registry.
+func NewHealthRegistry() *HealthRegistry {
+	return &HealthRegistry{}
+}
+
+// Add registers c under name. Names must be unique.
+func (h *HealthRegistry) Add(name string, c HealthChecker) error {
+	if name == "" || c == nil {
+		return fmt.Errorf("health check requires a name and a checker")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, existing := range h.checks {
+		if existing.Name == name {
+			return fmt.Errorf("health check %s already registered", name)
+		}
+	}
+	h.checks = append(h.checks, HealthCheck{Name: name, Checker: c})
+	return nil
+}
+
+// Checks returns the registered checks in registration order.
+func (h *HealthRegistry) Checks() []HealthCheck {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	checks := make([]HealthCheck, len(h.checks))
+	copy(checks, h.checks)
+	return checks
+}
+
+// Drain marks the service as shutting down; from then on readiness fails
+// regardless of the checks so load balancers stop routing new requests.
+func (h *HealthRegistry) Drain() {
+	h.draining.Store(true)
+}
+
+// Draining reports whether Drain has been called.
+func (h *HealthRegistry) Draining() bool {
+	return h.draining.Load()
+}
+
+// healthCheckTimeout bounds each check run by GET /health.
+const healthCheckTimeout = 2 * time.Second
+
+// runHealthChecks runs checks concurrently and returns "ok" or the error
+// message for each, keyed by check name, and whether all of them passed.
+func runHealthChecks(ctx context.Context, checks []HealthCheck) (map[string]string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]string, len(checks))
+	healthy := true
+
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c HealthCheck) {
+			defer wg.Done()
+			err := c.Checker.CheckHealth(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				results[c.Name] = err.Error()
+				healthy = false
+				return
+			}
+			results[c.Name] = "ok"
+		}(c)
+	}
+
+	wg.Wait()
+	return results, healthy
+}
+
+// healthChecksFor returns the health checks of a component, if it has any.
+// HealthReporter takes precedence over HealthChecker, which takes precedence over Pinger.
+// A HealthChecker or Pinger is checked under name.
+func healthChecksFor(c any, name string) []HealthCheck {
+	switch v := c.(type) {
+	case HealthReporter:
+		reported := v.HealthChecks()
+		checks := make([]HealthCheck, 0, len(reported))
+		for name, fn := range reported {
+			checks = append(checks, HealthCheck{Name: name, Checker: HealthCheckFunc(fn)})
+		}
+		sort.Slice(checks, func(i, j int) bool { return checks[i].Name < checks[j].Name })
+		return checks
+	case HealthChecker:
+		return []HealthCheck{{Name: name, Checker: v}}
+	case Pinger:
+		return []HealthCheck{{Name: name, Checker: HealthCheckFunc(v.Ping)}}
+	}
+	return nil
+}
+
+// componentName names a component after its type, e.g. "postgres.userStore".
+func componentName(c any) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", c), "*")
+}
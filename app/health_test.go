@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthRegistryAdd(t *testing.T) {
+	reg := NewHealthRegistry()
+	ok := HealthCheckFunc(func(ctx context.Context) error { return nil })
+
+	if err := reg.Add("db", ok); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := reg.Add("db", ok); err == nil {
+		t.Error("Add() should fail for a duplicate name")
+	}
+	if err := reg.Add("", ok); err == nil {
+		t.Error("Add() should fail without a name")
+	}
+	if err := reg.Add("cache", nil); err == nil {
+		t.Error("Add() should fail without a checker")
+	}
+
+	if checks := reg.Checks(); len(checks) != 1 || checks[0].Name != "db" {
+		t.Errorf("Checks() = %v, want only db", checks)
+	}
+}
+
+func TestRunHealthChecks(t *testing.T) {
+	checks := []HealthCheck{
+		{Name: "db", Checker: HealthCheckFunc(func(ctx context.Context) error { return nil })},
+		{Name: "broker", Checker: HealthCheckFunc(func(ctx context.Context) error { return errors.New("not connected") })},
+	}
+
+	results, healthy := runHealthChecks(context.Background(), checks)
+	if healthy {
+		t.Error("expected unhealthy when a check fails")
+	}
+	if results["db"] != "ok" || results["broker"] != "not connected" {
+		t.Errorf("results = %v", results)
+	}
+}
+
+func TestRunHealthChecksTimeout(t *testing.T) {
+	slow := HealthCheckFunc(func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Minute):
+			return nil
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, healthy := runHealthChecks(ctx, []HealthCheck{{Name: "slow", Checker: slow}}); healthy {
+		t.Error("expected a check exceeding the deadline to fail")
+	}
+}
+
+type fakeReporter struct{}
+
+func (fakeReporter) HealthChecks() map[string]func(context.Context) error {
+	return map[string]func(context.Context) error{
+		"migrations": func(ctx context.Context) error { return nil },
+		"database":   func(ctx context.Context) error { return nil },
+	}
+}
+
+// CheckHealth is ignored in favour of the reported checks.
+func (fakeReporter) CheckHealth(ctx context.Context) error { return nil }
+
+func TestHealthChecksFor(t *testing.T) {
+	checks := healthChecksFor(fakeReporter{}, "app.fakeReporter")
+	if len(checks) != 2 || checks[0].Name != "database" || checks[1].Name != "migrations" {
+		t.Errorf("reporter checks = %v, want database and migrations in order", checks)
+	}
+
+	if checks := healthChecksFor(&fakePinger{}, "app.fakePinger"); len(checks) != 1 || checks[0].Name != "app.fakePinger" {
+		t.Errorf("pinger checks = %v, want app.fakePinger", checks)
+	}
+
+	if checks := healthChecksFor(&fakeStartable{}, "app.fakeStartable"); len(checks) != 0 {
+		t.Errorf("expected no checks for a plain component, got %v", checks)
+	}
+}
+
+func TestHealthRegistryDrain(t *testing.T) {
+	reg := NewHealthRegistry()
+	if reg.Draining() {
+		t.Error("new registry should not be draining")
+	}
+	reg.Drain()
+	if !reg.Draining() {
+		t.Error("registry should be draining after Drain")
+	}
+}
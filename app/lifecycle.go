@@ -2,11 +2,16 @@ package app
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/aquamarinepk/aqm/errreport"
 	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/pubsub"
 )
 
 // Startable represents a component that can be started.
@@ -27,9 +32,34 @@ type RouteRegistrar interface {
 	RegisterRoutes(chi.Router)
 }
 
+// SubscriptionRouter collects event handlers. *pubsub.Consumer implements it.
+type SubscriptionRouter interface {
+	Handle(topic string, handler pubsub.Handler, opts ...pubsub.HandleOption)
+}
+
+// SubscriptionRegistrar represents a component that handles events.
+// Components implementing this interface will have RegisterSubscriptions called
+// during setup with the first SubscriptionRouter among the components.
+type SubscriptionRegistrar interface {
+	RegisterSubscriptions(SubscriptionRouter)
+}
+
 // Setup discovers component capabilities and builds startup/shutdown pipelines.
 // It inspects each component for RouteRegistrar, Startable, and Stoppable interfaces,
 // collecting start/stop functions and route registrars in order.
+// SubscriptionRegistrars are registered immediately with the first SubscriptionRouter
+// component, so their handlers are in place when the router is started.
+// Likewise the checks of every HealthReporter, HealthChecker or Pinger component
+// (stores, databases, brokers) are added to the first HealthCollector component,
+// named after the component unless reported by name; repeated names are
+// numbered ("postgres.userStore-2").
+//
+// Components may be wrapped in Component to declare what they depend on and
+// bound how long they take to start. Components come after their dependencies
+// in every result, so they start after and stop before them; otherwise the
+// order they were passed in is kept. Start errors name the component. When
+// the dependencies cannot be satisfied, the only start function returned
+// fails with ErrDependencies.
 //
 // Returns slices of start functions, stop functions, and route registrars to be executed by Start.
 func Setup(ctx context.Context, r chi.Router, comps ...any) (
@@ -37,36 +67,125 @@ func Setup(ctx context.Context, r chi.Router, comps ...any) (
 	stops []func(context.Context) error,
 	registrars []RouteRegistrar,
 ) {
+	declared, err := order(declare(comps))
+	if err != nil {
+		return []func(context.Context) error{func(context.Context) error { return err }}, nil, nil
+	}
+	p := newPlan(declared)
+	comps = make([]any, len(declared))
+	for i, d := range declared {
+		comps[i] = d.Value
+	}
+
+	var subs SubscriptionRouter
 	for _, c := range comps {
+		if sr, ok := c.(SubscriptionRouter); ok {
+			subs = sr
+			break
+		}
+	}
+
+	var health HealthCollector
+	for _, c := range comps {
+		if hc, ok := c.(HealthCollector); ok {
+			health = hc
+			break
+		}
+	}
+
+	seen := make(map[string]int)
+	for i, c := range comps {
+		if health != nil && c != any(health) {
+			for _, hc := range healthChecksFor(c, declared[i].Name) {
+				name := hc.Name
+				if seen[name]++; seen[name] > 1 {
+					name = fmt.Sprintf("%s-%d", name, seen[name])
+				}
+				health.Add(name, hc.Checker)
+			}
+		}
+		if sr, ok := c.(SubscriptionRegistrar); ok && subs != nil {
+			sr.RegisterSubscriptions(subs)
+		}
 		if rr, ok := c.(RouteRegistrar); ok {
 			registrars = append(registrars, rr)
 		}
 		if s, ok := c.(Startable); ok {
-			starts = append(starts, s.Start)
+			starts = append(starts, p.startFunc(i, s.Start))
 		}
 		if st, ok := c.(Stoppable); ok {
-			stops = append(stops, st.Stop)
+			stops = append(stops, p.stopFunc(i, st.Stop))
 		}
 	}
 	return
 }
 
+// StartOption configures Start.
+type StartOption func(*startConfig)
+
+type startConfig struct {
+	concurrent bool
+	timeout    time.Duration
+}
+
+// WithConcurrentStart makes Start run all start functions at once instead of
+// one after the other, cutting cold-start time for services with many
+// dependencies. Start functions from Setup wait for the components they
+// depend on, so only independent components start concurrently. On failure
+// every stop function is called in reverse order; those from Setup skip
+// components that did not start.
+func WithConcurrentStart() StartOption {
+	return func(c *startConfig) {
+		c.concurrent = true
+	}
+}
+
+// WithStartTimeout bounds every start function by d, on top of the start
+// timeouts declared with Component.
+func WithStartTimeout(d time.Duration) StartOption {
+	return func(c *startConfig) {
+		c.timeout = d
+	}
+}
+
 // Start executes startup functions in order with automatic rollback on failure.
 // If any start function fails, already-started components are stopped in reverse order.
+// Failures are reported to errreport.Default.
 // After all components start successfully, routes are registered.
 //
 // This ensures transactional-like behavior: either all components start successfully
-// or none remain running.
-func Start(ctx context.Context, logger log.Logger, starts []func(context.Context) error, stops []func(context.Context) error, registrars []RouteRegistrar, router chi.Router) error {
-	for i, start := range starts {
-		if err := start(ctx); err != nil {
-			logger.Errorf("error starting component #%d: %v", i, err)
-			for j := i - 1; j >= 0; j-- {
-				if rErr := stops[j](context.Background()); rErr != nil {
-					logger.Errorf("error stopping component #%d during rollback: %v", j, rErr)
+// or none remain running. With WithConcurrentStart the errors of every
+// component that failed are joined.
+func Start(ctx context.Context, logger log.Logger, starts []func(context.Context) error, stops []func(context.Context) error, registrars []RouteRegistrar, router chi.Router, opts ...StartOption) error {
+	var cfg startConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.timeout > 0 {
+		bounded := make([]func(context.Context) error, len(starts))
+		for i, start := range starts {
+			bounded[i] = func(ctx context.Context) error { return startWithin(ctx, cfg.timeout, start) }
+		}
+		starts = bounded
+	}
+
+	if cfg.concurrent {
+		if err := startConcurrently(ctx, logger, starts, stops); err != nil {
+			return err
+		}
+	} else {
+		for i, start := range starts {
+			if err := start(ctx); err != nil {
+				logger.Errorf("error starting component #%d: %v", i, err)
+				errreport.Capture(ctx, fmt.Errorf("cannot start component #%d: %w", i, err))
+				for j := i - 1; j >= 0; j-- {
+					if rErr := stops[j](context.Background()); rErr != nil {
+						logger.Errorf("error stopping component #%d during rollback: %v", j, rErr)
+						errreport.Capture(ctx, fmt.Errorf("cannot stop component #%d during rollback: %w", j, rErr))
+					}
 				}
+				return err
 			}
-			return err
 		}
 	}
 
@@ -77,6 +196,41 @@ func Start(ctx context.Context, logger log.Logger, starts []func(context.Context
 	return nil
 }
 
+// startConcurrently runs every start function at once and, if any fails,
+// calls every stop function in reverse order.
+func startConcurrently(ctx context.Context, logger log.Logger, starts []func(context.Context) error, stops []func(context.Context) error) error {
+	errs := make([]error, len(starts))
+	var wg sync.WaitGroup
+	for i, start := range starts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = start(ctx)
+		}()
+	}
+	wg.Wait()
+
+	failed := false
+	for i, err := range errs {
+		if err != nil {
+			failed = true
+			logger.Errorf("error starting component #%d: %v", i, err)
+			errreport.Capture(ctx, fmt.Errorf("cannot start component #%d: %w", i, err))
+		}
+	}
+	if !failed {
+		return nil
+	}
+
+	for j := len(stops) - 1; j >= 0; j-- {
+		if rErr := stops[j](context.Background()); rErr != nil {
+			logger.Errorf("error stopping component #%d during rollback: %v", j, rErr)
+			errreport.Capture(ctx, fmt.Errorf("cannot stop component #%d during rollback: %w", j, rErr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Serve starts the HTTP server and blocks until it's shut down.
 func Serve(router chi.Router, port string) error {
 	srv := &http.Server{
@@ -91,15 +245,48 @@ func Serve(router chi.Router, port string) error {
 	return nil
 }
 
+// ShutdownOption configures Shutdown.
+type ShutdownOption func(*shutdownConfig)
+
+type shutdownConfig struct {
+	drainers   []Drainer
+	drainDelay time.Duration
+}
+
+// WithDrain makes Shutdown call d.Drain and wait delay before closing the
+// server, so readiness probes fail and load balancers stop sending traffic
+// while in-flight requests are still being served.
+func WithDrain(d Drainer, delay time.Duration) ShutdownOption {
+	return func(c *shutdownConfig) {
+		c.drainers = append(c.drainers, d)
+		if delay > c.drainDelay {
+			c.drainDelay = delay
+		}
+	}
+}
+
 // Shutdown performs graceful shutdown of the HTTP server and all components.
-// It first attempts graceful server shutdown with a 5-second timeout,
+// With WithDrain it first flips readiness to failing and waits for the drain
+// delay. It then attempts graceful server shutdown with a 5-second timeout,
 // then stops all components in reverse order (LIFO).
 //
 // This ensures proper cleanup cascade: server stops accepting requests,
 // then components clean up in reverse dependency order.
-func Shutdown(srv *http.Server, logger log.Logger, stops []func(context.Context) error) {
+func Shutdown(srv *http.Server, logger log.Logger, stops []func(context.Context) error, opts ...ShutdownOption) {
 	logger.Info("Shutting down gracefully, press Ctrl+C again to force")
 
+	var cfg shutdownConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	for _, d := range cfg.drainers {
+		d.Drain()
+	}
+	if len(cfg.drainers) > 0 && cfg.drainDelay > 0 {
+		logger.Infof("Draining for %s", cfg.drainDelay)
+		time.Sleep(cfg.drainDelay)
+	}
+
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -10,7 +10,9 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/aquamarinepk/aqm/errreport"
 	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/pubsub"
 )
 
 type fakeRouteRegistrar struct {
@@ -88,6 +90,104 @@ func TestSetupWithRouteRegistrar(t *testing.T) {
 	}
 }
 
+type fakeSubscriptionRegistrar struct {
+	topics []string
+}
+
+func (f *fakeSubscriptionRegistrar) RegisterSubscriptions(r SubscriptionRouter) {
+	for _, topic := range f.topics {
+		r.Handle(topic, func(ctx context.Context, env pubsub.Envelope) error { return nil })
+	}
+}
+
+func TestSetupWithSubscriptionRegistrar(t *testing.T) {
+	r := chi.NewRouter()
+	consumer := pubsub.NewConsumer(pubsub.NewMemoryBroker(), nil, log.NewNoopLogger())
+	orders := &fakeSubscriptionRegistrar{topics: []string{"orders.created"}}
+	users := &fakeSubscriptionRegistrar{topics: []string{"users.created", "users.deleted"}}
+
+	starts, stops, _ := Setup(context.Background(), r, orders, consumer, users)
+
+	topics := consumer.Topics()
+	want := []string{"orders.created", "users.created", "users.deleted"}
+	if len(topics) != len(want) {
+		t.Fatalf("expected topics %v, got %v", want, topics)
+	}
+	for i := range want {
+		if topics[i] != want[i] {
+			t.Errorf("topic %d: expected %s, got %s", i, want[i], topics[i])
+		}
+	}
+	if len(starts) != 1 || len(stops) != 1 {
+		t.Errorf("expected consumer start and stop, got %d starts and %d stops", len(starts), len(stops))
+	}
+}
+
+func TestSetupWithSubscriptionRegistrarWithoutRouter(t *testing.T) {
+	comp := &fakeSubscriptionRegistrar{topics: []string{"orders.created"}}
+
+	starts, stops, registrars := Setup(context.Background(), chi.NewRouter(), comp)
+
+	if len(starts) != 0 || len(stops) != 0 || len(registrars) != 0 {
+		t.Error("expected registrar without router to be ignored")
+	}
+}
+
+type fakePinger struct {
+	err error
+}
+
+func (f *fakePinger) Ping(ctx context.Context) error {
+	return f.err
+}
+
+func TestSetupRegistersHealthChecks(t *testing.T) {
+	health := NewHealthRegistry()
+	checker := HealthCheckFunc(func(ctx context.Context) error { return nil })
+	users, roles := &fakePinger{}, &fakePinger{err: errors.New("down")}
+
+	Setup(context.Background(), chi.NewRouter(), users, health, checker, roles, &fakeStartable{})
+
+	checks := health.Checks()
+	want := []string{"app.fakePinger", "app.HealthCheckFunc", "app.fakePinger-2"}
+	if len(checks) != len(want) {
+		t.Fatalf("expected checks %v, got %d checks", want, len(checks))
+	}
+	for i := range want {
+		if checks[i].Name != want[i] {
+			t.Errorf("check %d: expected %s, got %s", i, want[i], checks[i].Name)
+		}
+	}
+	if err := checks[2].Checker.CheckHealth(context.Background()); err == nil {
+		t.Error("expected pinger error to be reported by its check")
+	}
+}
+
+func TestSetupWithHealthReporter(t *testing.T) {
+	health := NewHealthRegistry()
+
+	Setup(context.Background(), chi.NewRouter(), health, fakeReporter{}, &fakePinger{})
+
+	checks := health.Checks()
+	want := []string{"database", "migrations", "app.fakePinger"}
+	if len(checks) != len(want) {
+		t.Fatalf("expected checks %v, got %d checks", want, len(checks))
+	}
+	for i := range want {
+		if checks[i].Name != want[i] {
+			t.Errorf("check %d: expected %s, got %s", i, want[i], checks[i].Name)
+		}
+	}
+}
+
+func TestSetupWithHealthCheckerWithoutCollector(t *testing.T) {
+	starts, stops, registrars := Setup(context.Background(), chi.NewRouter(), &fakePinger{})
+
+	if len(starts) != 0 || len(stops) != 0 || len(registrars) != 0 {
+		t.Error("expected pinger without collector to be ignored")
+	}
+}
+
 func TestSetupWithStartable(t *testing.T) {
 	r := chi.NewRouter()
 	comp := &fakeStartable{}
@@ -290,6 +390,27 @@ func TestStartWithRollbackFailure(t *testing.T) {
 	}
 }
 
+func TestStartReportsFailures(t *testing.T) {
+	var reported []error
+	errreport.SetDefault(errreport.ReporterFunc(func(_ context.Context, err error) {
+		reported = append(reported, err)
+	}))
+	defer errreport.SetDefault(nil)
+
+	testErr := errors.New("start error")
+	stopErr := errors.New("stop error")
+	comp1 := &fakeComponent{fakeStoppable: fakeStoppable{err: stopErr}}
+	comp2 := &fakeComponent{fakeStartable: fakeStartable{err: testErr}}
+
+	starts := []func(context.Context) error{comp1.Start, comp2.Start}
+	stops := []func(context.Context) error{comp1.Stop, comp2.Stop}
+	Start(context.Background(), log.NewNoopLogger(), starts, stops, nil, chi.NewRouter())
+
+	if len(reported) != 2 || !errors.Is(reported[0], testErr) || !errors.Is(reported[1], stopErr) {
+		t.Errorf("reported = %v, want the start and rollback errors", reported)
+	}
+}
+
 func TestShutdown(t *testing.T) {
 	comp1 := &fakeComponent{}
 	comp2 := &fakeComponent{}
@@ -383,3 +504,33 @@ func TestShutdownWithStopError(t *testing.T) {
 		t.Error("expected comp2 to be stopped despite comp1 error")
 	}
 }
+
+type fakeDrainer struct {
+	drainedAt time.Time
+}
+
+func (f *fakeDrainer) Drain() {
+	f.drainedAt = time.Now()
+}
+
+func TestShutdownWithDrain(t *testing.T) {
+	comp := &fakeComponent{}
+	drainer := &fakeDrainer{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	start := time.Now()
+	Shutdown(srv.Config, log.NewNoopLogger(), []func(context.Context) error{comp.Stop}, WithDrain(drainer, 50*time.Millisecond))
+
+	if drainer.drainedAt.IsZero() {
+		t.Fatal("expected drainer to be drained")
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected shutdown to wait for the drain delay, took %v", elapsed)
+	}
+	if !comp.stopped.Load() {
+		t.Error("expected component to be stopped after draining")
+	}
+}
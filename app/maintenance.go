@@ -0,0 +1,140 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// DefaultMaintenanceRetryAfter is the Retry-After hint of WithMaintenanceMode
+// when none is given.
+const DefaultMaintenanceRetryAfter = 5 * time.Minute
+
+// maintenanceExempt are the routes of the other router options that keep
+// answering in maintenance mode, so probes and operators still reach them.
+var maintenanceExempt = []string{"/ping", "/health", "/healthz", "/readyz", "/metrics", "/debug", "/maintenance"}
+
+// MaintenanceFlag reports whether maintenance mode is on. *MaintenanceSwitch
+// implements it; wrap a feature flag client with MaintenanceFlagFunc.
+type MaintenanceFlag interface {
+	Enabled() bool
+}
+
+// MaintenanceFlagFunc adapts a function to MaintenanceFlag.
+type MaintenanceFlagFunc func() bool
+
+func (f MaintenanceFlagFunc) Enabled() bool {
+	return f()
+}
+
+// MaintenanceSwitch is a MaintenanceFlag toggled at runtime, typically
+// created from cfg.Server.Maintenance.Enabled and exposed with
+// WithMaintenanceToggle.
+type MaintenanceSwitch struct {
+	on atomic.Bool
+}
+
+// NewMaintenanceSwitch creates a switch that starts on or off.
+func NewMaintenanceSwitch(on bool) *MaintenanceSwitch {
+	s := &MaintenanceSwitch{}
+	s.on.Store(on)
+	return s
+}
+
+// Enabled reports whether the switch is on.
+func (s *MaintenanceSwitch) Enabled() bool {
+	return s.on.Load()
+}
+
+// Set turns the switch on or off.
+func (s *MaintenanceSwitch) Set(on bool) {
+	s.on.Store(on)
+}
+
+// WithMaintenanceMode answers 503 with a Retry-After hint of retryAfter
+// (DefaultMaintenanceRetryAfter when zero) while flag is enabled, except for
+// ping, health, probe, metrics, debug and maintenance routes and the paths
+// under allow, such as "/admin". The flag is read on every request.
+// It installs a middleware, so it must be applied before any route is registered.
+func WithMaintenanceMode(flag MaintenanceFlag, retryAfter time.Duration, allow ...string) RouterOption {
+	return func(r chi.Router) error {
+		if flag == nil {
+			return fmt.Errorf("maintenance flag cannot be nil")
+		}
+		if retryAfter <= 0 {
+			retryAfter = DefaultMaintenanceRetryAfter
+		}
+
+		exempt := append(append([]string{}, maintenanceExempt...), allow...)
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if flag.Enabled() && !underAny(req.URL.Path, exempt) {
+					middleware.WriteRetryAfter(w, http.StatusServiceUnavailable, "MAINTENANCE", "Service is under maintenance", retryAfter)
+					return
+				}
+				next.ServeHTTP(w, req)
+			})
+		})
+		return nil
+	}
+}
+
+// MaintenanceStatus is the body of the maintenance toggle endpoint.
+type MaintenanceStatus struct {
+	Enabled bool `json:"enabled"`
+}
+
+// WithMaintenanceToggle enables GET /maintenance, reporting whether sw is on,
+// and PUT /maintenance, switching it with a MaintenanceStatus body.
+// Mount it on internal routers only.
+func WithMaintenanceToggle(sw *MaintenanceSwitch) RouterOption {
+	return func(r chi.Router) error {
+		if sw == nil {
+			return fmt.Errorf("maintenance switch cannot be nil")
+		}
+		r.Get("/maintenance", handleMaintenanceStatus(sw))
+		r.Put("/maintenance", handleMaintenanceToggle(sw))
+		return nil
+	}
+}
+
+func handleMaintenanceStatus(sw *MaintenanceSwitch) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeMaintenanceStatus(w, sw)
+	}
+}
+
+func handleMaintenanceToggle(sw *MaintenanceSwitch) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var status MaintenanceStatus
+		if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+			http.Error(w, "Invalid maintenance status", http.StatusBadRequest)
+			return
+		}
+		sw.Set(status.Enabled)
+		writeMaintenanceStatus(w, sw)
+	}
+}
+
+func writeMaintenanceStatus(w http.ResponseWriter, sw *MaintenanceSwitch) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(MaintenanceStatus{Enabled: sw.Enabled()})
+}
+
+// underAny reports whether path is one of prefixes or below one of them.
+func underAny(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		p = strings.TrimSuffix(p, "/")
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
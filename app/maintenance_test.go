@@ -0,0 +1,118 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestWithMaintenanceMode(t *testing.T) {
+	sw := NewMaintenanceSwitch(true)
+	r := chi.NewRouter()
+	if err := ApplyRouterOptions(r, WithMaintenanceMode(sw, 90*time.Second, "/admin"), WithPing(), WithMaintenanceToggle(sw)); err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r.Get("/orders", ok)
+	r.Get("/admin/users", ok)
+	r.Get("/administrators", ok)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/orders", http.StatusServiceUnavailable},
+		{"/administrators", http.StatusServiceUnavailable},
+		{"/admin/users", http.StatusOK},
+		{"/ping", http.StatusOK},
+		{"/maintenance", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.want {
+				t.Fatalf("GET %s = %d, want %d", tt.path, rec.Code, tt.want)
+			}
+			if tt.want == http.StatusServiceUnavailable {
+				if got := rec.Header().Get("Retry-After"); got != "90" {
+					t.Errorf("Retry-After = %q, want 90", got)
+				}
+				if !strings.Contains(rec.Body.String(), `"MAINTENANCE"`) {
+					t.Errorf("body = %s, want code MAINTENANCE", rec.Body.String())
+				}
+			}
+		})
+	}
+
+	sw.Set(false)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /orders after switching off = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestWithMaintenanceModeFlagFunc(t *testing.T) {
+	on := false
+	r := chi.NewRouter()
+	if err := ApplyRouterOptions(r, WithMaintenanceMode(MaintenanceFlagFunc(func() bool { return on }), 0)); err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+	r.Get("/orders", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	on = true
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("GET /orders = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "300" {
+		t.Errorf("Retry-After = %q, want the default 300", got)
+	}
+}
+
+func TestWithMaintenanceModeNilFlag(t *testing.T) {
+	if err := ApplyRouterOptions(chi.NewRouter(), WithMaintenanceMode(nil, 0)); err == nil {
+		t.Error("WithMaintenanceMode(nil) error = nil, want error")
+	}
+}
+
+func TestWithMaintenanceToggle(t *testing.T) {
+	sw := NewMaintenanceSwitch(false)
+	r := chi.NewRouter()
+	if err := ApplyRouterOptions(r, WithMaintenanceToggle(sw)); err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		want   int
+		on     bool
+	}{
+		{"status", http.MethodGet, "", http.StatusOK, false},
+		{"enable", http.MethodPut, `{"enabled":true}`, http.StatusOK, true},
+		{"invalid body", http.MethodPut, `{`, http.StatusBadRequest, true},
+		{"disable", http.MethodPut, `{"enabled":false}`, http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tt.method, "/maintenance", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.want {
+				t.Errorf("%s /maintenance = %d, want %d", tt.method, rec.Code, tt.want)
+			}
+			if sw.Enabled() != tt.on {
+				t.Errorf("Enabled() = %v, want %v", sw.Enabled(), tt.on)
+			}
+		})
+	}
+}
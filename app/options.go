@@ -7,8 +7,10 @@ import (
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/aquamarinepk/aqm/errreport"
 	"github.com/aquamarinepk/aqm/log"
 	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/telemetry"
 )
 
 // RouterOption configures optional features for the main router.
@@ -35,6 +37,29 @@ func WithDebugRoutes() RouterOption {
 	}
 }
 
+// ConfigDumper exposes the effective configuration; *config.Config implements it.
+type ConfigDumper interface {
+	Dump(redact bool) map[string]any
+}
+
+// configSourcer is optionally implemented by a ConfigDumper to report where each key came from.
+type configSourcer interface {
+	Sources() map[string]string
+}
+
+// WithDebugConfig enables GET /debug/config endpoint that serves the redacted
+// effective configuration and, when available, the source of each key.
+// Mount it on internal routers only.
+func WithDebugConfig(cfg ConfigDumper) RouterOption {
+	return func(r chi.Router) error {
+		if cfg == nil {
+			return fmt.Errorf("config dumper cannot be nil")
+		}
+		r.Get("/debug/config", handleDebugConfig(cfg))
+		return nil
+	}
+}
+
 // WithPing enables GET /ping health check endpoint.
 func WithPing() RouterOption {
 	return func(r chi.Router) error {
@@ -44,14 +69,80 @@ func WithPing() RouterOption {
 }
 
 // WithHealthChecks enables GET /health endpoint with service information.
-func WithHealthChecks(name, version string) RouterOption {
+// Each check is run on every request; if any fails the endpoint responds
+// 503 with status "degraded" and the failing check's error.
+func WithHealthChecks(name, version string, checks ...HealthCheck) RouterOption {
+	return func(r chi.Router) error {
+		reg := NewHealthRegistry()
+		for _, c := range checks {
+			if err := reg.Add(c.Name, c.Checker); err != nil {
+				return err
+			}
+		}
+		r.Get("/health", handleHealthCheck(name, version, reg))
+		return nil
+	}
+}
+
+// WithHealthRegistry is like WithHealthChecks but reports the checks of reg,
+// including those added after the router is built. Pass reg to Setup as well
+// to have stores and other Pinger components checked automatically.
+func WithHealthRegistry(name, version string, reg *HealthRegistry) RouterOption {
 	return func(r chi.Router) error {
-		r.Get("/health", handleHealthCheck(name, version))
+		if reg == nil {
+			return fmt.Errorf("health registry cannot be nil")
+		}
+		r.Get("/health", handleHealthCheck(name, version, reg))
+		return nil
+	}
+}
+
+// WithProbes enables GET /healthz (liveness) and GET /readyz (readiness).
+// Liveness only reports that the process is serving requests. Readiness runs
+// the checks of reg and responds 503 if any fails or once reg is draining.
+func WithProbes(reg *HealthRegistry) RouterOption {
+	return func(r chi.Router) error {
+		if reg == nil {
+			return fmt.Errorf("health registry cannot be nil")
+		}
+		r.Get("/healthz", handlePing)
+		r.Get("/readyz", handleReadiness(reg))
+		return nil
+	}
+}
+
+// WithMetrics enables GET /metrics and instruments every route with request
+// count, latency, and in-flight metrics using telemetry.DefaultPrometheus.
+// It installs a middleware, so it must be applied before any route is registered.
+func WithMetrics() RouterOption {
+	return WithMetricsFrom(telemetry.DefaultPrometheus())
+}
+
+// WithMetricsFrom is like WithMetrics but uses the given Prometheus instance.
+func WithMetricsFrom(p *telemetry.Prometheus) RouterOption {
+	return func(r chi.Router) error {
+		if p == nil {
+			return fmt.Errorf("prometheus metrics cannot be nil")
+		}
+		r.Use(p.Middleware())
+		r.Method(http.MethodGet, "/metrics", p.Handler())
 		return nil
 	}
 }
 
-// WithDefaultMiddlewares applies the default middleware stack (RequestID, RealIP, Logger, Recoverer).
+// WithErrorReporting records each request for the errors reported while
+// serving it and reports panics to reporter, the errreport default when nil.
+// Apply it after WithDefaultMiddlewares or WithProfile so their recoverer
+// still answers the panic, and before any route is registered.
+func WithErrorReporting(reporter errreport.Reporter) RouterOption {
+	return func(r chi.Router) error {
+		r.Use(errreport.Middleware(reporter))
+		return nil
+	}
+}
+
+// WithDefaultMiddlewares applies the default middleware stack (RequestID, ClientIP, Logger, Recoverer).
+// Forwarding headers are ignored; behind a proxy use WithClientIPMiddlewares.
 func WithDefaultMiddlewares() RouterOption {
 	return func(r chi.Router) error {
 		r.Use(middleware.DefaultStack()...)
@@ -59,6 +150,16 @@ func WithDefaultMiddlewares() RouterOption {
 	}
 }
 
+// WithClientIPMiddlewares is like WithDefaultMiddlewares but takes the client
+// address from the forwarding headers of the trusted proxies of cfg, see
+// ClientIPFromConfig.
+func WithClientIPMiddlewares(cfg middleware.ClientIPConfig) RouterOption {
+	return func(r chi.Router) error {
+		r.Use(middleware.ClientIPStack(cfg)...)
+		return nil
+	}
+}
+
 // WithDefaultInternalMiddlewares applies the default middleware stack plus InternalOnly restriction.
 func WithDefaultInternalMiddlewares() RouterOption {
 	return func(r chi.Router) error {
@@ -83,20 +184,53 @@ func handlePing(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"status":"ok"}`))
 }
 
-func handleHealthCheck(name, version string) http.HandlerFunc {
+func handleHealthCheck(name, version string, reg *HealthRegistry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		health := map[string]string{
+		health := map[string]any{
 			"status":  "ok",
 			"service": name,
 			"version": version,
 		}
 
+		status := http.StatusOK
+		if checks := reg.Checks(); len(checks) > 0 {
+			results, healthy := runHealthChecks(r.Context(), checks)
+			if !healthy {
+				health["status"] = "degraded"
+				status = http.StatusServiceUnavailable
+			}
+			health["checks"] = results
+		}
+
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
+		w.WriteHeader(status)
 		json.NewEncoder(w).Encode(health)
 	}
 }
 
+func handleReadiness(reg *HealthRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ready := map[string]any{"status": "ok"}
+		status := http.StatusOK
+
+		if reg.Draining() {
+			ready["status"] = "draining"
+			status = http.StatusServiceUnavailable
+		} else if checks := reg.Checks(); len(checks) > 0 {
+			results, healthy := runHealthChecks(r.Context(), checks)
+			if !healthy {
+				ready["status"] = "unavailable"
+				status = http.StatusServiceUnavailable
+			}
+			ready["checks"] = results
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ready)
+	}
+}
+
 func handleDebugRoutes(w http.ResponseWriter, r *http.Request) {
 	router := chi.RouteContext(r.Context()).Routes
 
@@ -117,3 +251,18 @@ func handleDebugRoutes(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Registered Routes:\n\n"))
 	w.Write([]byte(strings.Join(routes, "\n")))
 }
+
+func handleDebugConfig(cfg ConfigDumper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := map[string]any{
+			"config": cfg.Dump(true),
+		}
+		if s, ok := cfg.(configSourcer); ok {
+			resp["sources"] = s.Sources()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -1,13 +1,19 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"strings"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/aquamarinepk/aqm/errreport"
 	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/telemetry"
 )
 
 func TestWithPing(t *testing.T) {
@@ -62,6 +68,127 @@ func TestWithHealthChecks(t *testing.T) {
 	}
 }
 
+func TestWithHealthChecksDependencies(t *testing.T) {
+	ok := HealthCheckFunc(func(ctx context.Context) error { return nil })
+	down := HealthCheckFunc(func(ctx context.Context) error { return errors.New("connection refused") })
+
+	tests := []struct {
+		name       string
+		checks     []HealthCheck
+		wantStatus int
+		wantBody   []string
+	}{
+		{
+			name:       "all healthy",
+			checks:     []HealthCheck{{Name: "db", Checker: ok}},
+			wantStatus: http.StatusOK,
+			wantBody:   []string{`"status":"ok"`, `"checks":{"db":"ok"}`},
+		},
+		{
+			name:       "one failing",
+			checks:     []HealthCheck{{Name: "db", Checker: down}, {Name: "cache", Checker: ok}},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   []string{`"status":"degraded"`, `"db":"connection refused"`, `"cache":"ok"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := chi.NewRouter()
+			if err := ApplyRouterOptions(r, WithHealthChecks("test-service", "1.0.0", tt.checks...)); err != nil {
+				t.Fatalf("ApplyRouterOptions() error = %v", err)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(rec.Body.String(), want) {
+					t.Errorf("body = %s, want to contain %s", rec.Body.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestWithHealthRegistry(t *testing.T) {
+	reg := NewHealthRegistry()
+	r := chi.NewRouter()
+	if err := ApplyRouterOptions(r, WithHealthRegistry("test-service", "1.0.0", reg)); err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+
+	reg.Add("db", HealthCheckFunc(func(ctx context.Context) error { return errors.New("down") }))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d for check added after the router was built", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	if err := ApplyRouterOptions(chi.NewRouter(), WithHealthRegistry("test", "1.0.0", nil)); err == nil {
+		t.Error("WithHealthRegistry() should fail for a nil registry")
+	}
+}
+
+func TestWithProbes(t *testing.T) {
+	reg := NewHealthRegistry()
+	var dbErr error
+	reg.Add("db", HealthCheckFunc(func(ctx context.Context) error { return dbErr }))
+
+	r := chi.NewRouter()
+	if err := ApplyRouterOptions(r, WithProbes(reg)); err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+
+	probe := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := probe("/readyz"); rec.Code != http.StatusOK {
+		t.Errorf("ready status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	dbErr = errors.New("connection refused")
+	rec := probe("/readyz")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("failing check status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if !strings.Contains(rec.Body.String(), `"db":"connection refused"`) {
+		t.Errorf("body = %s, want failing check", rec.Body.String())
+	}
+	if rec := probe("/healthz"); rec.Code != http.StatusOK {
+		t.Errorf("liveness should not depend on checks, status = %d", rec.Code)
+	}
+
+	dbErr = nil
+	reg.Drain()
+	rec = probe("/readyz")
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"status":"draining"`) {
+		t.Errorf("draining readiness = %d %s, want 503 draining", rec.Code, rec.Body.String())
+	}
+	if rec := probe("/healthz"); rec.Code != http.StatusOK {
+		t.Errorf("liveness while draining status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if err := ApplyRouterOptions(chi.NewRouter(), WithProbes(nil)); err == nil {
+		t.Error("WithProbes() should fail for a nil registry")
+	}
+}
+
+func TestWithHealthChecksInvalid(t *testing.T) {
+	r := chi.NewRouter()
+	if err := ApplyRouterOptions(r, WithHealthChecks("test", "1.0.0", HealthCheck{Name: "db"})); err == nil {
+		t.Error("WithHealthChecks() should fail for a check without a checker")
+	}
+}
+
 func TestWithDefaultMiddlewares(t *testing.T) {
 	r := chi.NewRouter()
 
@@ -83,6 +210,43 @@ func TestWithDefaultMiddlewares(t *testing.T) {
 	}
 }
 
+func TestWithClientIPMiddlewares(t *testing.T) {
+	r := chi.NewRouter()
+	cfg := middleware.ClientIPConfig{TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}
+
+	if err := ApplyRouterOptions(r, WithClientIPMiddlewares(cfg)); err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+
+	r.Get("/test", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		want       string
+	}{
+		{"trusted proxy", "10.1.2.3:1234", "198.51.100.1"},
+		{"untrusted peer", "203.0.113.5:1234", "203.0.113.5:1234"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", "198.51.100.1")
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestWithDefaultInternalMiddlewares(t *testing.T) {
 	r := chi.NewRouter()
 
@@ -153,6 +317,90 @@ func TestWithDebugRoutes(t *testing.T) {
 	}
 }
 
+type fakeDumper struct {
+	redacted bool
+}
+
+func (f *fakeDumper) Dump(redact bool) map[string]any {
+	f.redacted = redact
+	return map[string]any{"server.port": ":8080", "database.password": "[REDACTED]"}
+}
+
+func (f *fakeDumper) Sources() map[string]string {
+	return map[string]string{"server.port": "file"}
+}
+
+func TestWithDebugConfig(t *testing.T) {
+	r := chi.NewRouter()
+	dumper := &fakeDumper{}
+
+	if err := ApplyRouterOptions(r, WithDebugConfig(dumper)); err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/config", nil)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("handleDebugConfig() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if !dumper.redacted {
+		t.Error("handleDebugConfig() should request a redacted dump")
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{`"server.port":":8080"`, `"database.password":"[REDACTED]"`, `"sources":{"server.port":"file"}`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("handleDebugConfig() body = %q, want to contain %q", body, want)
+		}
+	}
+}
+
+func TestWithDebugConfigNil(t *testing.T) {
+	r := chi.NewRouter()
+
+	if err := ApplyRouterOptions(r, WithDebugConfig(nil)); err == nil {
+		t.Error("WithDebugConfig(nil) should return error")
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	r := chi.NewRouter()
+
+	if err := ApplyRouterOptions(r, WithMetricsFrom(telemetry.NewPrometheus())); err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+	r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	want := `http_requests_total{method="GET",route="/items/{id}",status="200"} 1`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("body should contain %q", want)
+	}
+}
+
+func TestWithMetricsNil(t *testing.T) {
+	r := chi.NewRouter()
+
+	if err := ApplyRouterOptions(r, WithMetricsFrom(nil)); err == nil {
+		t.Error("WithMetricsFrom(nil) should return error")
+	}
+}
+
 func TestApplyRouterOptions(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -271,3 +519,28 @@ func TestNewRouter(t *testing.T) {
 		t.Error("NewRouter debug routes should list /ping endpoint")
 	}
 }
+
+func TestWithErrorReporting(t *testing.T) {
+	var reported []error
+	reporter := errreport.ReporterFunc(func(_ context.Context, err error) {
+		reported = append(reported, err)
+	})
+
+	r := chi.NewRouter()
+	if err := ApplyRouterOptions(r, WithDefaultMiddlewares(), WithErrorReporting(reporter)); err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("kaboom")
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if len(reported) != 1 {
+		t.Errorf("reported = %v, want the panic", reported)
+	}
+}
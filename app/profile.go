@@ -0,0 +1,140 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// Profile bundles transport settings for a class of service: server timeouts,
+// security headers, rate limits, body limits, and CORS posture.
+// Use ProfileInternal or ProfilePublic as a starting point and adjust fields as needed.
+type Profile struct {
+	Name string
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// MaxBodyBytes limits request bodies; 0 disables the limit.
+	MaxBodyBytes int64
+
+	// RateLimit is the number of requests allowed per client IP in RateWindow; 0 disables limiting.
+	RateLimit  int
+	RateWindow time.Duration
+
+	SecurityHeaders map[string]string
+
+	// CORS is the cross-origin policy; nil rejects all cross-origin browser requests.
+	CORS *middleware.CORSConfig
+
+	// ClientIP, when set, configures the trusted proxies whose forwarding
+	// headers give the client address, and the GeoIP database to locate it
+	// with. Without it forwarding headers are ignored.
+	ClientIP *middleware.ClientIPConfig
+
+	// InternalOnly restricts access to private networks.
+	InternalOnly bool
+}
+
+// ProfileInternal returns the profile for service-to-service endpoints reachable
+// only from private networks: generous timeouts and body limits, no rate limit, no CORS.
+func ProfileInternal() Profile {
+	return Profile{
+		Name:              "internal",
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		MaxBodyBytes:      10 << 20,
+		SecurityHeaders: map[string]string{
+			"X-Content-Type-Options": "nosniff",
+		},
+		InternalOnly: true,
+	}
+}
+
+// ProfilePublic returns the profile for internet-facing endpoints such as authn:
+// tight timeouts, 1 MiB bodies, 100 requests per minute per IP, strict security
+// headers, and no cross-origin access unless CORS is set explicitly.
+func ProfilePublic() Profile {
+	return Profile{
+		Name:              "public",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		MaxBodyBytes:      1 << 20,
+		RateLimit:         100,
+		RateWindow:        time.Minute,
+		SecurityHeaders:   middleware.StrictSecurityHeaders(),
+	}
+}
+
+// Middlewares returns the default stack followed by the profile's restrictions.
+// With ClientIP set the stack is middleware.ClientIPStack instead.
+func (p Profile) Middlewares() []func(http.Handler) http.Handler {
+	stack := middleware.DefaultStack()
+	if p.ClientIP != nil {
+		stack = middleware.ClientIPStack(*p.ClientIP)
+	}
+	return append(stack, p.Restrictions()...)
+}
+
+// Restrictions returns the profile's restrictions without the default stack,
+// for a RouteGroup of a router that already has it. ClientIP is not applied.
+func (p Profile) Restrictions() []func(http.Handler) http.Handler {
+	var stack []func(http.Handler) http.Handler
+	if p.InternalOnly {
+		stack = append(stack, middleware.InternalOnly())
+	}
+	if len(p.SecurityHeaders) > 0 {
+		stack = append(stack, middleware.SecurityHeaders(p.SecurityHeaders))
+	}
+	if p.CORS != nil {
+		stack = append(stack, middleware.CORS(*p.CORS))
+	}
+	if p.RateLimit > 0 && p.RateWindow > 0 {
+		stack = append(stack, middleware.RateLimit(p.RateLimit, p.RateWindow))
+	}
+	if p.MaxBodyBytes > 0 {
+		stack = append(stack, middleware.MaxBodySize(p.MaxBodyBytes))
+	}
+
+	return stack
+}
+
+// Server returns an http.Server for addr with the profile's timeouts.
+func (p Profile) Server(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: p.ReadHeaderTimeout,
+		ReadTimeout:       p.ReadTimeout,
+		WriteTimeout:      p.WriteTimeout,
+		IdleTimeout:       p.IdleTimeout,
+	}
+}
+
+// WithProfile applies the profile's middleware stack to the router.
+// Use it in place of WithDefaultMiddlewares or WithDefaultInternalMiddlewares.
+func WithProfile(p Profile) RouterOption {
+	return func(r chi.Router) error {
+		r.Use(p.Middlewares()...)
+		return nil
+	}
+}
+
+// ServeProfile starts the HTTP server with the profile's timeouts and blocks until it's shut down.
+func ServeProfile(router chi.Router, port string, p Profile) error {
+	srv := p.Server(port, router)
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestProfileMiddlewares(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		want    int
+	}{
+		{"internal", ProfileInternal(), 7},
+		{"public", ProfilePublic(), 7},
+		{"public with cors", func() Profile {
+			p := ProfilePublic()
+			p.CORS = &middleware.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}
+			return p
+		}(), 8},
+		{"empty", Profile{}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(tt.profile.Middlewares()); got != tt.want {
+				t.Errorf("Middlewares() returned %d middlewares, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileServer(t *testing.T) {
+	p := ProfilePublic()
+	srv := p.Server(":8080", http.NewServeMux())
+
+	if srv.Addr != ":8080" {
+		t.Errorf("Addr = %q, want %q", srv.Addr, ":8080")
+	}
+	if srv.ReadHeaderTimeout != 5*time.Second || srv.WriteTimeout != 15*time.Second {
+		t.Errorf("timeouts = %v/%v, want profile timeouts", srv.ReadHeaderTimeout, srv.WriteTimeout)
+	}
+}
+
+func TestWithProfilePublic(t *testing.T) {
+	p := ProfilePublic()
+	p.RateLimit = 1
+
+	r := chi.NewRouter()
+	if err := ApplyRouterOptions(r, WithProfile(p)); err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+	r.Post("/signin", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/signin", strings.NewReader("{}"))
+	req.RemoteAddr = "8.8.8.8:1234"
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec.Header().Get("X-Frame-Options") != "DENY" {
+		t.Error("public profile should set strict security headers")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/signin", strings.NewReader("{}"))
+	req.RemoteAddr = "8.8.8.8:1234"
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("second request status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestWithProfileInternal(t *testing.T) {
+	r := chi.NewRouter()
+	if err := ApplyRouterOptions(r, WithProfile(ProfileInternal())); err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+	r.Get("/test", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		wantStatus int
+	}{
+		{"private", "10.0.0.1:1234", http.StatusOK},
+		{"public", "8.8.8.8:1234", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
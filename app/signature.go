@@ -0,0 +1,24 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+// RequestSignatureFromConfig builds a middleware.RequireSignature guard
+// accepting the keys of cfg, typically cfg.Auth.RequestSigning, for
+// handler.WithInternal or route groups of internal endpoints. It returns nil
+// when cfg has no keys, leaving those endpoints unguarded.
+func RequestSignatureFromConfig(cfg config.RequestSigningConfig) func(http.Handler) http.Handler {
+	if len(cfg.Keys) == 0 {
+		return nil
+	}
+
+	keys := make(middleware.StaticSigningKeys, len(cfg.Keys))
+	for id, secret := range cfg.Keys {
+		keys[id] = []byte(secret)
+	}
+	return middleware.RequireSignature(middleware.RequestSignatureConfig{Keys: keys, Tolerance: cfg.Tolerance})
+}
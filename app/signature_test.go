@@ -0,0 +1,35 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+func TestRequestSignatureFromConfig(t *testing.T) {
+	if guard := RequestSignatureFromConfig(config.RequestSigningConfig{}); guard != nil {
+		t.Error("RequestSignatureFromConfig() without keys returned a guard")
+	}
+
+	secret := "0123456789abcdef0123456789abcdef"
+	guard := RequestSignatureFromConfig(config.RequestSigningConfig{Keys: map[string]string{"authz": secret}, Tolerance: time.Minute})
+	h := guard(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/bootstrap", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned request = %d, want 401", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/bootstrap", nil)
+	crypto.SignRequest(req, "authz", []byte(secret), time.Now())
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("signed request = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/spiffe"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// NewServer returns an http.Server for cfg.Port with the configured timeouts
+// and request body limit.
+func NewServer(cfg config.ServerConfig, handler http.Handler) *http.Server {
+	if cfg.MaxBodyBytes > 0 {
+		handler = middleware.MaxBodySize(cfg.MaxBodyBytes.Int64())(handler)
+	}
+	return &http.Server{
+		Addr:              cfg.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+// ServeTLS starts the server described by cfg and blocks until it's shut down.
+// With tls.enabled it serves HTTPS (HTTP/2 is negotiated automatically) using
+// tls.certfile/tls.keyfile or, when tls.autocert.domains is set, certificates
+// from Let's Encrypt. In autocert mode a second listener on
+// tls.autocert.httpaddr answers ACME challenges and redirects to HTTPS;
+// leave it empty to rely on TLS-ALPN challenges only.
+// With tls.spiffe.enabled it serves the SVID of the SPIFFE Workload API at
+// tls.spiffe.socket instead, which it waits for and keeps rotated.
+// With tls.clientcafile, or the SVID trust bundle with tls.spiffe, client
+// certificates are verified, and required with tls.clientauth "required";
+// map them to callers with middleware.ClientCert.
+// Without tls.enabled it serves plain HTTP with the configured timeouts.
+func ServeTLS(router chi.Router, cfg config.ServerConfig) error {
+	ln, err := net.Listen("tcp", cfg.Port)
+	if err != nil {
+		return fmt.Errorf("cannot listen on %s: %w", cfg.Port, err)
+	}
+	return serveTLS(ln, router, cfg)
+}
+
+func serveTLS(ln net.Listener, handler http.Handler, cfg config.ServerConfig) error {
+	srv := NewServer(cfg, handler)
+
+	var err error
+	switch {
+	case !cfg.TLS.Enabled:
+		err = srv.Serve(ln)
+
+	case cfg.TLS.UsesSPIFFE():
+		source := spiffe.NewSource(spiffe.SourceConfig{Addr: cfg.TLS.SPIFFE.Socket})
+		if err := source.Start(context.Background()); err != nil {
+			ln.Close()
+			return fmt.Errorf("cannot fetch svid: %w", err)
+		}
+		defer source.Stop(context.Background())
+
+		srv.TLSConfig = source.ServerTLSConfig(clientAuth(cfg.TLS))
+		err = srv.ServeTLS(ln, "", "")
+
+	case cfg.TLS.UsesAutocert():
+		m := autocertManager(cfg.TLS.Autocert)
+		srv.TLSConfig = m.TLSConfig()
+		srv.TLSConfig.MinVersion = tls.VersionTLS12
+		if err := clientCerts(srv.TLSConfig, cfg.TLS); err != nil {
+			ln.Close()
+			return err
+		}
+
+		if addr := cfg.TLS.Autocert.HTTPAddr; addr != "" {
+			challengeLn, lerr := net.Listen("tcp", addr)
+			if lerr != nil {
+				ln.Close()
+				return fmt.Errorf("cannot listen for acme challenges on %s: %w", addr, lerr)
+			}
+			challenge := &http.Server{
+				Handler:           m.HTTPHandler(nil),
+				ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			}
+			go challenge.Serve(challengeLn)
+			defer challenge.Close()
+		}
+
+		err = srv.ServeTLS(ln, "", "")
+
+	default:
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		if err := clientCerts(srv.TLSConfig, cfg.TLS); err != nil {
+			ln.Close()
+			return err
+		}
+		err = srv.ServeTLS(ln, cfg.TLS.CertFile, cfg.TLS.KeyFile)
+	}
+
+	if err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// clientCerts makes tc verify client certificates against the trust bundle of
+// cfg, when set.
+func clientCerts(tc *tls.Config, cfg config.TLSConfig) error {
+	if cfg.ClientCAFile == "" {
+		return nil
+	}
+
+	bundle, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return fmt.Errorf("cannot read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return fmt.Errorf("no certificates in client CA bundle %s", cfg.ClientCAFile)
+	}
+
+	tc.ClientCAs = pool
+	tc.ClientAuth = clientAuth(cfg)
+	return nil
+}
+
+// clientAuth is how client certificates are verified under cfg.
+func clientAuth(cfg config.TLSConfig) tls.ClientAuthType {
+	if cfg.ClientAuth == config.ClientAuthRequired {
+		return tls.RequireAndVerifyClientCert
+	}
+	return tls.VerifyClientCertIfGiven
+}
+
+func autocertManager(cfg config.AutocertConfig) *autocert.Manager {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.Domains...),
+		Email:      cfg.Email,
+	}
+	if cfg.CacheDir != "" {
+		m.Cache = autocert.DirCache(cfg.CacheDir)
+	}
+	return m
+}
@@ -0,0 +1,305 @@
+package app
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+)
+
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("cannot create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("cannot marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("cannot write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("cannot write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+// startServeTLS runs serveTLS on a random local port until the test ends.
+func startServeTLS(t *testing.T, cfg config.ServerConfig) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- serveTLS(ln, handler, cfg) }()
+	t.Cleanup(func() {
+		ln.Close()
+		<-done
+	})
+
+	return ln.Addr().String()
+}
+
+func TestServeTLSWithCertFiles(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+	addr := startServeTLS(t, config.ServerConfig{
+		TLS: config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile},
+	})
+
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: true,
+		},
+	}
+
+	resp, err := client.Get("https://" + addr + "/")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if resp.ProtoMajor != 2 {
+		t.Errorf("proto = %s, want HTTP/2", resp.Proto)
+	}
+	if resp.TLS == nil || resp.TLS.Version < tls.VersionTLS12 {
+		t.Error("connection should use TLS 1.2 or newer")
+	}
+}
+
+func TestServeTLSDisabled(t *testing.T) {
+	addr := startServeTLS(t, config.ServerConfig{})
+
+	resp, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestServeTLSMissingCertFile(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+
+	cfg := config.ServerConfig{
+		TLS: config.TLSConfig{Enabled: true, CertFile: "missing.pem", KeyFile: "missing.pem"},
+	}
+	if err := serveTLS(ln, http.NotFoundHandler(), cfg); err == nil {
+		t.Error("serveTLS() should fail when certificate files are missing")
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	cfg := config.ServerConfig{
+		Port:              ":8443",
+		ReadHeaderTimeout: time.Second,
+		ReadTimeout:       2 * time.Second,
+		WriteTimeout:      3 * time.Second,
+		IdleTimeout:       4 * time.Second,
+	}
+
+	srv := NewServer(cfg, http.NotFoundHandler())
+
+	if srv.Addr != ":8443" {
+		t.Errorf("Addr = %q, want %q", srv.Addr, ":8443")
+	}
+	if srv.ReadHeaderTimeout != time.Second || srv.ReadTimeout != 2*time.Second ||
+		srv.WriteTimeout != 3*time.Second || srv.IdleTimeout != 4*time.Second {
+		t.Errorf("timeouts not applied: %+v", srv)
+	}
+}
+
+func TestNewServerMaxBodyBytes(t *testing.T) {
+	srv := NewServer(config.ServerConfig{MaxBodyBytes: 4}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too long")))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestAutocertManager(t *testing.T) {
+	m := autocertManager(config.AutocertConfig{
+		Domains:  []string{"example.com"},
+		CacheDir: t.TempDir(),
+	})
+
+	ctx := context.Background()
+	if err := m.HostPolicy(ctx, "example.com"); err != nil {
+		t.Errorf("HostPolicy(example.com) error = %v", err)
+	}
+	if err := m.HostPolicy(ctx, "evil.example.org"); err == nil {
+		t.Error("HostPolicy should reject hosts outside the whitelist")
+	}
+	if m.Cache == nil {
+		t.Error("Cache should be set when CacheDir is configured")
+	}
+}
+
+// writeClientCA writes a CA bundle to a file and returns it with a client
+// certificate it issued.
+func writeClientCA(t *testing.T) (caFile string, client tls.Certificate) {
+	t.Helper()
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "internal CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("cannot create CA: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	clientKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	clientTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "worker"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"worker.internal"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTmpl, caCert, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("cannot create client certificate: %v", err)
+	}
+
+	caFile = filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0600); err != nil {
+		t.Fatalf("cannot write CA: %v", err)
+	}
+	return caFile, tls.Certificate{Certificate: [][]byte{clientDER}, PrivateKey: clientKey}
+}
+
+func TestServeTLSWithClientCerts(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+	caFile, clientCert := writeClientCA(t)
+	addr := startServeTLS(t, config.ServerConfig{
+		TLS: config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ClientAuth: config.ClientAuthRequired},
+	})
+
+	get := func(certs ...tls.Certificate) error {
+		client := &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: certs},
+			},
+		}
+		resp, err := client.Get("https://" + addr + "/")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	if err := get(clientCert); err != nil {
+		t.Errorf("GET with client certificate error = %v", err)
+	}
+	if err := get(); err == nil {
+		t.Error("GET without client certificate succeeded, want it refused")
+	}
+}
+
+func TestServeTLSInvalidClientCA(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(bundle, []byte("not a certificate"), 0600)
+
+	cfg := config.ServerConfig{
+		TLS: config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, ClientCAFile: bundle},
+	}
+	if err := serveTLS(ln, http.NotFoundHandler(), cfg); err == nil {
+		t.Error("serveTLS() should fail when the client CA bundle has no certificates")
+	}
+}
+
+func TestServeTLSSPIFFEWithoutWorkloadAPI(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+
+	cfg := config.ServerConfig{
+		TLS: config.TLSConfig{Enabled: true, SPIFFE: config.SPIFFEConfig{Enabled: true, Socket: "/no/scheme.sock"}},
+	}
+	if err := serveTLS(ln, http.NotFoundHandler(), cfg); err == nil {
+		t.Error("serveTLS() should fail without a usable workload api address")
+	}
+}
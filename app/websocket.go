@@ -0,0 +1,23 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/ws"
+	"github.com/go-chi/chi/v5"
+)
+
+// WithWebSockets serves hub at GET path, e.g. "/ws". Routes are matched
+// after the router's middlewares, so authentication placed before it
+// identifies the user each connection belongs to. Pass hub to Setup as well
+// so its connections are closed on shutdown.
+func WithWebSockets(path string, hub *ws.Hub) RouterOption {
+	return func(r chi.Router) error {
+		if hub == nil {
+			return fmt.Errorf("websocket hub cannot be nil")
+		}
+		r.Method(http.MethodGet, path, hub)
+		return nil
+	}
+}
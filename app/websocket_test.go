@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/ws"
+	"github.com/coder/websocket"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestWithWebSockets(t *testing.T) {
+	hub := ws.NewHub(log.NewNoopLogger())
+	r := chi.NewRouter()
+	if err := ApplyRouterOptions(r, WithWebSockets("/ws", hub)); err != nil {
+		t.Fatalf("ApplyRouterOptions() error = %v", err)
+	}
+
+	_, stops, _ := Setup(context.Background(), r, hub)
+	if len(stops) != 1 {
+		t.Fatalf("Setup() collected %d stop functions, want the hub's", len(stops))
+	}
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.CloseNow()
+
+	// The client must be reading to answer the close handshake.
+	errc := make(chan error, 1)
+	go func() {
+		_, _, err := c.Read(ctx)
+		errc <- err
+	}()
+
+	if err := stops[0](ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if err := <-errc; websocket.CloseStatus(err) != websocket.StatusGoingAway {
+		t.Errorf("Read() error = %v, want close with going away", err)
+	}
+}
+
+func TestWithWebSocketsNilHub(t *testing.T) {
+	if err := ApplyRouterOptions(chi.NewRouter(), WithWebSockets("/ws", nil)); err == nil {
+		t.Error("WithWebSockets(nil) error = nil, want error")
+	}
+}
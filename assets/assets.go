@@ -0,0 +1,105 @@
+// Package assets stores uploaded files such as user avatars.
+//
+// A Storage keeps objects by key; LocalStorage writes them under a
+// directory and S3Storage to an S3 or S3-compatible bucket. An Uploader
+// reads a file from an HTTP request, enforcing a size limit and a set of
+// allowed content types.
+package assets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+)
+
+// Storage backends accepted in config.AssetsConfig.Storage.
+const (
+	StorageNone  = "none"
+	StorageLocal = "local"
+	StorageS3    = "s3"
+)
+
+var (
+	ErrNotFound           = errors.New("asset not found")
+	ErrInvalidKey         = errors.New("invalid asset key")
+	ErrUnknownStorage     = errors.New("unknown asset storage")
+	ErrTooLarge           = errors.New("asset is too large")
+	ErrUnsupportedType    = errors.New("unsupported asset content type")
+	ErrMissingFile        = errors.New("request has no file")
+	ErrSizeMismatch       = errors.New("asset size does not match")
+	ErrStorageUnavailable = errors.New("asset storage unavailable")
+)
+
+// Object describes a stored asset. ETag changes whenever the content does.
+type Object struct {
+	Key         string
+	ContentType string
+	Size        int64
+	ETag        string
+	ModTime     time.Time
+}
+
+// Storage keeps assets by key. Keys are slash-separated relative paths;
+// see ValidateKey.
+type Storage interface {
+	// Put stores size bytes read from body under key, replacing any
+	// existing asset. It fails with ErrSizeMismatch when body holds a
+	// different number of bytes.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) (*Object, error)
+	// Get opens the asset stored under key. The caller closes the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	// Delete removes the asset stored under key. Deleting a missing asset
+	// is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// FromConfig builds the storage selected by cfg.Storage. It returns a nil
+// Storage for StorageNone, meaning uploads are disabled.
+func FromConfig(cfg config.AssetsConfig) (Storage, error) {
+	switch cfg.Storage {
+	case StorageNone, "":
+		return nil, nil
+	case StorageLocal:
+		storage, err := NewLocalStorage(cfg.Local.Path)
+		if err != nil {
+			return nil, err
+		}
+		return storage, nil
+	case StorageS3:
+		storage, err := NewS3Storage(cfg.S3)
+		if err != nil {
+			return nil, err
+		}
+		return storage, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStorage, cfg.Storage)
+	}
+}
+
+// ValidateKey checks that key is a relative, slash-separated path of
+// segments made of letters, digits, '-', '_' and '.', none of them starting
+// with a dot. This keeps keys safe as file paths and URL paths alike.
+func ValidateKey(key string) error {
+	if key == "" || len(key) > 512 {
+		return fmt.Errorf("%w: key must be 1 to 512 characters", ErrInvalidKey)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment[0] == '.' {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+		for _, r := range segment {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			case r == '-' || r == '_' || r == '.':
+			default:
+				return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package assets_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/assets"
+	"github.com/aquamarinepk/aqm/config"
+)
+
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{key: "avatars/7d0d8b8e-6a43-4a53-9d0a-3a8e4d5b2c10"},
+		{key: "docs/2026/report_v2.PDF"},
+		{key: "", wantErr: true},
+		{key: "/absolute", wantErr: true},
+		{key: "trailing/", wantErr: true},
+		{key: "a//b", wantErr: true},
+		{key: "../escape", wantErr: true},
+		{key: "a/../b", wantErr: true},
+		{key: ".meta/x", wantErr: true},
+		{key: "with space", wantErr: true},
+		{key: `back\slash`, wantErr: true},
+		{key: "query?x=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			err := assets.ValidateKey(tt.key)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("ValidateKey(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, assets.ErrInvalidKey) {
+				t.Errorf("ValidateKey(%q) error = %v, want ErrInvalidKey", tt.key, err)
+			}
+		})
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.AssetsConfig
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "none", cfg: config.AssetsConfig{Storage: assets.StorageNone}, wantNil: true},
+		{name: "local", cfg: config.AssetsConfig{Storage: assets.StorageLocal, Local: config.LocalConfig{Path: t.TempDir()}}},
+		{name: "s3", cfg: config.AssetsConfig{Storage: assets.StorageS3, S3: config.S3Config{Bucket: "uploads", Region: "eu-west-1"}}},
+		{name: "s3 without bucket", cfg: config.AssetsConfig{Storage: assets.StorageS3, S3: config.S3Config{Region: "eu-west-1"}}, wantNil: true, wantErr: true},
+		{name: "unknown", cfg: config.AssetsConfig{Storage: "gcs"}, wantNil: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, err := assets.FromConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (storage == nil) != tt.wantNil {
+				t.Errorf("FromConfig() storage = %v, wantNil %v", storage, tt.wantNil)
+			}
+		})
+	}
+
+	if _, err := assets.FromConfig(config.AssetsConfig{Storage: "gcs"}); !errors.Is(err, assets.ErrUnknownStorage) {
+		t.Errorf("FromConfig(gcs) error = %v, want ErrUnknownStorage", err)
+	}
+}
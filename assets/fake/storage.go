@@ -0,0 +1,110 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/assets"
+)
+
+// Storage keeps assets in memory.
+type Storage struct {
+	mu      sync.RWMutex
+	objects map[string]storedObject
+	err     error
+}
+
+type storedObject struct {
+	obj  assets.Object
+	data []byte
+}
+
+func NewStorage() *Storage {
+	return &Storage{objects: make(map[string]storedObject)}
+}
+
+func (s *Storage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) (*assets.Object, error) {
+	if err := assets.ValidateKey(key); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(body, size+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != size {
+		return nil, fmt.Errorf("%w: got %d bytes, want %d", assets.ErrSizeMismatch, len(data), size)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+	sum := sha256.Sum256(data)
+	obj := assets.Object{
+		Key:         key,
+		ContentType: contentType,
+		Size:        size,
+		ETag:        hex.EncodeToString(sum[:]),
+		ModTime:     time.Now(),
+	}
+	s.objects[key] = storedObject{obj: obj, data: data}
+	return &obj, nil
+}
+
+func (s *Storage) Get(ctx context.Context, key string) (io.ReadCloser, *assets.Object, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.err != nil {
+		return nil, nil, s.err
+	}
+	stored, ok := s.objects[key]
+	if !ok {
+		return nil, nil, assets.ErrNotFound
+	}
+	obj := stored.obj
+	return io.NopCloser(bytes.NewReader(stored.data)), &obj, nil
+}
+
+func (s *Storage) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	delete(s.objects, key)
+	return nil
+}
+
+// Keys returns the stored keys in order.
+func (s *Storage) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FailWith makes later calls return err; nil restores normal behavior.
+func (s *Storage) FailWith(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+}
+
+var _ assets.Storage = (*Storage)(nil)
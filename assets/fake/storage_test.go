@@ -0,0 +1,55 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/assets"
+)
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+	s := NewStorage()
+
+	if _, err := s.Put(ctx, "a/b.txt", strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, err := s.Put(ctx, "a/c.txt", strings.NewReader("hello"), 4, "text/plain"); !errors.Is(err, assets.ErrSizeMismatch) {
+		t.Errorf("Put() short size error = %v, want ErrSizeMismatch", err)
+	}
+	if _, err := s.Put(ctx, "../x", strings.NewReader("x"), 1, ""); !errors.Is(err, assets.ErrInvalidKey) {
+		t.Errorf("Put() bad key error = %v, want ErrInvalidKey", err)
+	}
+
+	body, obj, err := s.Get(ctx, "a/b.txt")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	if string(data) != "hello" || obj.ContentType != "text/plain" || obj.ETag == "" {
+		t.Errorf("Get() = %q %+v", data, obj)
+	}
+	if keys := s.Keys(); len(keys) != 1 || keys[0] != "a/b.txt" {
+		t.Errorf("Keys() = %v", keys)
+	}
+
+	boom := errors.New("boom")
+	s.FailWith(boom)
+	if _, _, err := s.Get(ctx, "a/b.txt"); !errors.Is(err, boom) {
+		t.Errorf("Get() error = %v, want the injected error", err)
+	}
+	s.FailWith(nil)
+
+	if err := s.Delete(ctx, "a/b.txt"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := s.Delete(ctx, "a/b.txt"); err != nil {
+		t.Errorf("Delete() twice error = %v", err)
+	}
+	if _, _, err := s.Get(ctx, "a/b.txt"); !errors.Is(err, assets.ErrNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,90 @@
+package assets
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aquamarinepk/aqm/httpx"
+)
+
+// Error codes reported by HTTPError.
+const (
+	CodeAssetNotFound = "ASSET_NOT_FOUND"
+	CodeFileTooLarge  = "FILE_TOO_LARGE"
+	CodeMissingFile   = "MISSING_FILE"
+)
+
+// HTTPError maps an error from a Storage or an Uploader to an API error.
+func HTTPError(err error) *httpx.Error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return httpx.NotFound(CodeAssetNotFound, "Asset not found").Wrap(err)
+	case errors.Is(err, ErrTooLarge):
+		return httpx.NewError(http.StatusRequestEntityTooLarge, CodeFileTooLarge, err.Error()).Wrap(err)
+	case errors.Is(err, ErrUnsupportedType):
+		return httpx.NewError(http.StatusUnsupportedMediaType, httpx.CodeUnsupportedMediaType, err.Error()).Wrap(err)
+	case errors.Is(err, ErrMissingFile):
+		return httpx.BadRequest(CodeMissingFile, "Request has no file").Wrap(err)
+	case errors.Is(err, ErrInvalidKey):
+		return httpx.BadRequest(httpx.CodeInvalidRequest, err.Error()).Wrap(err)
+	case errors.Is(err, ErrStorageUnavailable):
+		return httpx.NewError(http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "Asset storage is unavailable").Wrap(err)
+	default:
+		return httpx.Internal("Asset storage failed").Wrap(err)
+	}
+}
+
+// Serve writes the asset stored under key to w. It sets ETag and answers
+// 304 Not Modified when If-None-Match matches it. Assets are served with
+// nosniff and a sandboxing Content-Security-Policy so an uploaded document
+// cannot run scripts in the service's origin. The error from Storage.Get is
+// returned without writing a response, so callers choose how to render it;
+// HTTPError maps it.
+func Serve(w http.ResponseWriter, r *http.Request, storage Storage, key string) error {
+	body, obj, err := storage.Get(r.Context(), key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	h := w.Header()
+	if obj.ETag != "" {
+		etag := `"` + obj.ETag + `"`
+		h.Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+	if obj.ContentType != "" {
+		h.Set("Content-Type", obj.ContentType)
+	}
+	if obj.Size >= 0 {
+		h.Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	}
+	if !obj.ModTime.IsZero() {
+		h.Set("Last-Modified", obj.ModTime.UTC().Format(http.TimeFormat))
+	}
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Content-Security-Policy", "sandbox")
+	h.Set("Cache-Control", "no-cache")
+
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		io.Copy(w, body)
+	}
+	return nil
+}
+
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
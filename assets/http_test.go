@@ -0,0 +1,85 @@
+package assets_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/assets"
+	"github.com/aquamarinepk/aqm/assets/fake"
+)
+
+func TestServe(t *testing.T) {
+	storage := fake.NewStorage()
+	obj, err := storage.Put(context.Background(), "docs/a.png", bytes.NewReader(pngData), int64(len(pngData)), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := `"` + obj.ETag + `"`
+
+	w := httptest.NewRecorder()
+	if err := assets.Serve(w, httptest.NewRequest(http.MethodGet, "/", nil), storage, "docs/a.png"); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), pngData) {
+		t.Fatalf("Serve() = %d with %d bytes", w.Code, w.Body.Len())
+	}
+	for header, want := range map[string]string{
+		"Content-Type":            "image/png",
+		"ETag":                    etag,
+		"X-Content-Type-Options":  "nosniff",
+		"Content-Security-Policy": "sandbox",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", `"stale", `+etag)
+	w = httptest.NewRecorder()
+	assets.Serve(w, r, storage, "docs/a.png")
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("Serve() with If-None-Match = %d, want 304 without a body", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	assets.Serve(w, httptest.NewRequest(http.MethodHead, "/", nil), storage, "docs/a.png")
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("Serve() HEAD = %d with %d bytes, want 200 without a body", w.Code, w.Body.Len())
+	}
+
+	w = httptest.NewRecorder()
+	if err := assets.Serve(w, httptest.NewRequest(http.MethodGet, "/", nil), storage, "docs/missing.png"); !errors.Is(err, assets.ErrNotFound) {
+		t.Errorf("Serve() missing error = %v, want ErrNotFound", err)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Serve() wrote %q for a missing asset", w.Body.String())
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+	}{
+		{err: assets.ErrNotFound, wantStatus: http.StatusNotFound},
+		{err: assets.ErrTooLarge, wantStatus: http.StatusRequestEntityTooLarge},
+		{err: assets.ErrUnsupportedType, wantStatus: http.StatusUnsupportedMediaType},
+		{err: assets.ErrMissingFile, wantStatus: http.StatusBadRequest},
+		{err: assets.ErrInvalidKey, wantStatus: http.StatusBadRequest},
+		{err: assets.ErrStorageUnavailable, wantStatus: http.StatusServiceUnavailable},
+		{err: errors.New("disk on fire"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := assets.HTTPError(tt.err); got.Status != tt.wantStatus {
+				t.Errorf("HTTPError(%v).Status = %d, want %d", tt.err, got.Status, tt.wantStatus)
+			}
+		})
+	}
+}
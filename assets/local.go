@@ -0,0 +1,174 @@
+package assets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// metaDir holds the content type of each local asset. ValidateKey rejects
+// segments starting with a dot, so it never collides with a key.
+const metaDir = ".meta"
+
+// LocalStorage keeps assets as files under a root directory. Writes go to a
+// temporary file that is renamed into place, so readers never see a partial
+// asset. ETags are the SHA-256 of the content.
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage creates the root directory if needed.
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if root == "" {
+		return nil, errors.New("local asset storage needs a path")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("create asset directory: %w", err)
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) (*Object, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	name := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(body, size+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n != size {
+		return nil, fmt.Errorf("%w: got %d bytes, want %d", ErrSizeMismatch, n, size)
+	}
+
+	if err := s.writeContentType(key, contentType); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{
+		Key:         key,
+		ContentType: contentType,
+		Size:        n,
+		ETag:        hex.EncodeToString(hash.Sum(nil)),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := f.Stat()
+	if err == nil && info.IsDir() {
+		err = ErrNotFound
+	}
+	var etag string
+	if err == nil {
+		etag, err = fileHash(f)
+	}
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return f, &Object{
+		Key:         key,
+		ContentType: s.readContentType(key),
+		Size:        info.Size(),
+		ETag:        etag,
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(s.metaPath(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+func (s *LocalStorage) metaPath(key string) string {
+	return filepath.Join(s.root, metaDir, filepath.FromSlash(key))
+}
+
+func (s *LocalStorage) writeContentType(key, contentType string) error {
+	name := s.metaPath(key)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, []byte(contentType), 0o644)
+}
+
+// readContentType returns the content type given to Put, falling back to
+// the type registered for the key's extension.
+func (s *LocalStorage) readContentType(key string) string {
+	if b, err := os.ReadFile(s.metaPath(key)); err == nil && len(b) > 0 {
+		return strings.TrimSpace(string(b))
+	}
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// fileHash hashes f and rewinds it.
+func fileHash(f *os.File) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+var _ Storage = (*LocalStorage)(nil)
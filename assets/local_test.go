@@ -0,0 +1,99 @@
+package assets_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/assets"
+)
+
+func TestLocalStorage(t *testing.T) {
+	root := t.TempDir()
+	storage, err := assets.NewLocalStorage(filepath.Join(root, "uploads"))
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	ctx := context.Background()
+
+	obj, err := storage.Put(ctx, "avatars/ann", strings.NewReader("first"), 5, "image/png")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if obj.Size != 5 || obj.ContentType != "image/png" || obj.ETag == "" {
+		t.Errorf("Put() = %+v", obj)
+	}
+
+	replaced, err := storage.Put(ctx, "avatars/ann", strings.NewReader("second"), 6, "image/gif")
+	if err != nil {
+		t.Fatalf("Put() replace error = %v", err)
+	}
+	if replaced.ETag == obj.ETag {
+		t.Error("ETag did not change with the content")
+	}
+
+	body, got, err := storage.Get(ctx, "avatars/ann")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "second" || got.ContentType != "image/gif" || got.Size != 6 || got.ETag != replaced.ETag {
+		t.Errorf("Get() = %q %+v", data, got)
+	}
+
+	if err := storage.Delete(ctx, "avatars/ann"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, _, err := storage.Get(ctx, "avatars/ann"); !errors.Is(err, assets.ErrNotFound) {
+		t.Errorf("Get() after delete error = %v, want ErrNotFound", err)
+	}
+	if err := storage.Delete(ctx, "avatars/ann"); err != nil {
+		t.Errorf("Delete() of a missing asset error = %v", err)
+	}
+}
+
+func TestLocalStorageRejects(t *testing.T) {
+	storage, _ := assets.NewLocalStorage(t.TempDir())
+	ctx := context.Background()
+
+	if _, err := storage.Put(ctx, "a.txt", strings.NewReader("short"), 10, "text/plain"); !errors.Is(err, assets.ErrSizeMismatch) {
+		t.Errorf("Put() short body error = %v, want ErrSizeMismatch", err)
+	}
+	if _, err := storage.Put(ctx, "a.txt", strings.NewReader("too long"), 3, "text/plain"); !errors.Is(err, assets.ErrSizeMismatch) {
+		t.Errorf("Put() long body error = %v, want ErrSizeMismatch", err)
+	}
+	if _, _, err := storage.Get(ctx, "a.txt"); !errors.Is(err, assets.ErrNotFound) {
+		t.Errorf("failed Put left an asset behind: %v", err)
+	}
+	if _, err := storage.Put(ctx, "../escape", strings.NewReader("x"), 1, "text/plain"); !errors.Is(err, assets.ErrInvalidKey) {
+		t.Errorf("Put() escaping key error = %v, want ErrInvalidKey", err)
+	}
+	if _, err := storage.Put(ctx, "dir/file", strings.NewReader("x"), 1, ""); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, _, err := storage.Get(ctx, "dir"); !errors.Is(err, assets.ErrNotFound) {
+		t.Errorf("Get() of a directory error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalStorageContentTypeFallback(t *testing.T) {
+	root := t.TempDir()
+	storage, _ := assets.NewLocalStorage(root)
+	if err := os.WriteFile(filepath.Join(root, "logo.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	body, obj, err := storage.Get(context.Background(), "logo.png")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	body.Close()
+	if obj.ContentType != "image/png" {
+		t.Errorf("ContentType = %q, want image/png from the extension", obj.ContentType)
+	}
+}
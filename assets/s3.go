@@ -0,0 +1,208 @@
+package assets
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/internal/sigv4"
+)
+
+// S3Storage keeps assets in an S3 bucket, or in any service speaking the S3
+// API, signing requests with AWS Signature Version 4. Credentials missing
+// from the config are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN; without any, requests are sent unsigned.
+type S3Storage struct {
+	cfg          config.S3Config
+	base         *url.URL
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	client       *http.Client
+	now          func() time.Time
+}
+
+func NewS3Storage(cfg config.S3Config) (*S3Storage, error) {
+	if cfg.Bucket == "" || cfg.Region == "" {
+		return nil, errors.New("s3 asset storage needs a bucket and a region")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", endpoint)
+	}
+	if cfg.PathStyle {
+		base.Path = strings.TrimSuffix(base.Path, "/") + "/" + cfg.Bucket
+	} else {
+		base.Host = cfg.Bucket + "." + base.Host
+	}
+
+	s := &S3Storage{
+		cfg:          cfg,
+		base:         base,
+		accessKey:    cfg.AccessKeyID,
+		secretKey:    cfg.SecretAccessKey,
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{Timeout: 30 * time.Second},
+		now:          time.Now,
+	}
+	if s.accessKey == "" && s.secretKey == "" {
+		s.accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		s.secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	} else {
+		s.sessionToken = ""
+	}
+	return s, nil
+}
+
+// WithHTTPClient replaces the HTTP client used to reach the bucket.
+func (s *S3Storage) WithHTTPClient(client *http.Client) *S3Storage {
+	s.client = client
+	return s
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) (*Object, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	counted := &countingReader{r: body}
+	req, err := s.newRequest(ctx, http.MethodPut, key, counted)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(req, sigv4.UnsignedPayload)
+	if err != nil {
+		if counted.n != size {
+			return nil, fmt.Errorf("%w: got %d bytes, want %d", ErrSizeMismatch, counted.n, size)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return &Object{
+		Key:         key,
+		ContentType: contentType,
+		Size:        size,
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+		ModTime:     s.now().UTC(),
+	}, nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, nil, err
+	}
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.do(req, sigv4.EmptyPayloadHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	obj := &Object{
+		Key:         key,
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		obj.ModTime = modTime
+	}
+	return resp.Body, obj, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, sigv4.EmptyPayloadHash)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Storage) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	u := *s.base
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.cfg.Prefix + key
+	u.RawPath = ""
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// do signs and sends req. Responses other than 2xx are closed and turned
+// into errors: ErrNotFound for 404 and ErrStorageUnavailable for 5xx.
+func (s *S3Storage) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	if s.accessKey != "" {
+		if s.sessionToken != "" {
+			req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+		}
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+		creds := sigv4.Credentials{AccessKeyID: s.accessKey, SecretAccessKey: s.secretKey}
+		sigv4.Sign(req, creds, s.cfg.Region, "s3", payloadHash, s.now())
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&s3Err)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode >= 500:
+		return nil, fmt.Errorf("%w: s3 %s %s: %d %s", ErrStorageUnavailable, req.Method, req.URL.Path, resp.StatusCode, s3Err.Code)
+	default:
+		return nil, fmt.Errorf("s3 %s %s: %d %s %s", req.Method, req.URL.Path, resp.StatusCode, s3Err.Code, s3Err.Message)
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+var _ Storage = (*S3Storage)(nil)
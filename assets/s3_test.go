@@ -0,0 +1,156 @@
+package assets
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/config"
+)
+
+// fakeS3 serves objects from memory and records the paths it was asked for.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	paths   []string
+	auth    []string
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paths = append(f.paths, r.Method+" "+r.URL.Path)
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = fakeObject{data: data, contentType: r.Header.Get("Content-Type")}
+		w.Header().Set("ETag", `"etag-put"`)
+	case http.MethodGet:
+		obj, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("ETag", `"stored"`)
+		w.Header().Set("Last-Modified", "Wed, 01 Apr 2026 10:00:00 GMT")
+		w.Write(obj.data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	fake := &fakeS3{objects: map[string]fakeObject{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	storage, err := NewS3Storage(config.S3Config{
+		Bucket: "uploads", Region: "eu-west-1", Endpoint: server.URL, PathStyle: true,
+		AccessKeyID: "AKID", SecretAccessKey: "secret", Prefix: "app/",
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage() error = %v", err)
+	}
+	ctx := context.Background()
+
+	obj, err := storage.Put(ctx, "avatars/ann", strings.NewReader("image"), 5, "image/png")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if obj.ETag != "etag-put" {
+		t.Errorf("Put() ETag = %q, want the unquoted server ETag", obj.ETag)
+	}
+
+	body, got, err := storage.Get(ctx, "avatars/ann")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "image" || got.ContentType != "image/png" || got.Size != 5 || got.ETag != "stored" || got.ModTime.IsZero() {
+		t.Errorf("Get() = %q %+v", data, got)
+	}
+
+	if err := storage.Delete(ctx, "avatars/ann"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, _, err := storage.Get(ctx, "avatars/ann"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after delete error = %v, want ErrNotFound", err)
+	}
+
+	if fake.paths[0] != "PUT /uploads/app/avatars/ann" {
+		t.Errorf("first request = %q, want a path-style key with the prefix", fake.paths[0])
+	}
+	for _, auth := range fake.auth {
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+			t.Errorf("Authorization = %q", auth)
+		}
+	}
+}
+
+func TestS3StorageErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, `<Error><Code>SlowDown</Code></Error>`)
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+	}))
+	defer server.Close()
+
+	storage, _ := NewS3Storage(config.S3Config{Bucket: "uploads", Region: "eu-west-1", Endpoint: server.URL, PathStyle: true})
+	ctx := context.Background()
+
+	if _, _, err := storage.Get(ctx, "a"); !errors.Is(err, ErrStorageUnavailable) {
+		t.Errorf("Get() error = %v, want ErrStorageUnavailable", err)
+	}
+	_, err := storage.Put(ctx, "a", strings.NewReader("x"), 1, "text/plain")
+	if err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Put() error = %v, want the S3 error code", err)
+	}
+	if _, err := storage.Put(ctx, "a", strings.NewReader("x"), 4, "text/plain"); !errors.Is(err, ErrSizeMismatch) {
+		t.Errorf("Put() short body error = %v, want ErrSizeMismatch", err)
+	}
+}
+
+func TestNewS3StorageAddressing(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.S3Config
+		want string
+	}{
+		{name: "aws virtual host", cfg: config.S3Config{Bucket: "b", Region: "eu-west-1"}, want: "https://b.s3.eu-west-1.amazonaws.com/k"},
+		{name: "aws path style", cfg: config.S3Config{Bucket: "b", Region: "eu-west-1", PathStyle: true}, want: "https://s3.eu-west-1.amazonaws.com/b/k"},
+		{name: "custom endpoint", cfg: config.S3Config{Bucket: "b", Region: "us-east-1", Endpoint: "http://minio:9000", PathStyle: true}, want: "http://minio:9000/b/k"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, err := NewS3Storage(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewS3Storage() error = %v", err)
+			}
+			req, _ := storage.newRequest(context.Background(), http.MethodGet, "k", nil)
+			if got := req.URL.String(); got != tt.want {
+				t.Errorf("URL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
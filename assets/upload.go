@@ -0,0 +1,182 @@
+package assets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// DefaultMaxUploadSize is the size limit of an Uploader without WithMaxSize.
+const DefaultMaxUploadSize = 5 << 20
+
+// ImageTypes are the content types browsers display as images and that
+// http.DetectContentType recognizes, for use with WithContentTypes.
+var ImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// Uploader reads files from upload requests. The body is either
+// multipart/form-data, holding the file in the configured form field, or the
+// raw file itself. The content type is sniffed from the data rather than
+// trusted from the client, falling back to the declared type only when
+// sniffing finds nothing more specific than application/octet-stream.
+type Uploader struct {
+	maxSize int64
+	types   []string
+	field   string
+}
+
+// UploadOption configures an Uploader.
+type UploadOption func(*Uploader)
+
+// WithMaxSize limits uploads to n bytes.
+func WithMaxSize(n int64) UploadOption {
+	return func(u *Uploader) {
+		if n > 0 {
+			u.maxSize = n
+		}
+	}
+}
+
+// WithContentTypes accepts only files of the given media types. Without it
+// any type is accepted.
+func WithContentTypes(types ...string) UploadOption {
+	return func(u *Uploader) {
+		u.types = types
+	}
+}
+
+// WithFormField names the multipart form field holding the file; it is
+// "file" by default.
+func WithFormField(name string) UploadOption {
+	return func(u *Uploader) {
+		if name != "" {
+			u.field = name
+		}
+	}
+}
+
+func NewUploader(opts ...UploadOption) *Uploader {
+	u := &Uploader{maxSize: DefaultMaxUploadSize, field: "file"}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
+}
+
+// MaxSize returns the upload size limit in bytes.
+func (u *Uploader) MaxSize() int64 {
+	return u.maxSize
+}
+
+// File is an upload read into memory.
+type File struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Size returns the length of the file in bytes.
+func (f *File) Size() int64 {
+	return int64(len(f.Data))
+}
+
+// Reader returns a reader over the file's content, for Storage.Put.
+func (f *File) Reader() io.Reader {
+	return bytes.NewReader(f.Data)
+}
+
+// Receive reads the uploaded file from r. It fails with ErrMissingFile for
+// an empty upload, ErrTooLarge over the size limit and ErrUnsupportedType
+// for a content type the Uploader does not accept.
+func (u *Uploader) Receive(r *http.Request) (*File, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, ErrMissingFile
+	}
+
+	var file *File
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		file, err = u.receivePart(r)
+	} else {
+		file = &File{ContentType: mediaType}
+		file.Data, err = u.read(r.Body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Data) == 0 {
+		return nil, ErrMissingFile
+	}
+
+	file.ContentType = detectContentType(file.Data, file.ContentType)
+	if len(u.types) > 0 && !slices.Contains(u.types, file.ContentType) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, file.ContentType)
+	}
+	return file, nil
+}
+
+func (u *Uploader) receivePart(r *http.Request) (*File, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, ErrMissingFile
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, ErrMissingFile
+		}
+		if err != nil {
+			return nil, tooLargeOr(err)
+		}
+		if part.FormName() != u.field {
+			part.Close()
+			continue
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		file := &File{Filename: part.FileName(), ContentType: mediaType}
+		file.Data, err = u.read(part)
+		part.Close()
+		return file, err
+	}
+}
+
+// read reads body up to the size limit.
+func (u *Uploader) read(body io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(body, u.maxSize+1))
+	if err != nil {
+		return nil, tooLargeOr(err)
+	}
+	if int64(len(data)) > u.maxSize {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrTooLarge, u.maxSize)
+	}
+	return data, nil
+}
+
+// tooLargeOr reports an http.MaxBytesReader limit set by the caller as
+// ErrTooLarge.
+func tooLargeOr(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return fmt.Errorf("%w: limit is %d bytes", ErrTooLarge, maxErr.Limit)
+	}
+	return err
+}
+
+// detectContentType sniffs data, keeping the declared type when sniffing
+// only finds generic binary or plain text data.
+func detectContentType(data []byte, declared string) string {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if sniffed == "application/octet-stream" && declared != "" {
+		return declared
+	}
+	if sniffed == "text/plain" && strings.HasPrefix(declared, "text/") {
+		return declared
+	}
+	return sniffed
+}
@@ -0,0 +1,112 @@
+package assets_test
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/assets"
+)
+
+// pngData starts with the PNG signature, which is enough for sniffing.
+var pngData = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 32)...)
+
+func multipartRequest(t *testing.T, field, filename string, data []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("note", "ignored")
+	part, err := mw.CreateFormFile(field, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPut, "/", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestUploaderReceive(t *testing.T) {
+	uploader := assets.NewUploader(assets.WithMaxSize(64), assets.WithContentTypes(assets.ImageTypes...))
+
+	raw := httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(pngData))
+	raw.Header.Set("Content-Type", "image/jpeg")
+	file, err := uploader.Receive(raw)
+	if err != nil {
+		t.Fatalf("Receive() raw error = %v", err)
+	}
+	if file.ContentType != "image/png" || file.Size() != int64(len(pngData)) {
+		t.Errorf("Receive() raw = %s %d, want the sniffed image/png", file.ContentType, file.Size())
+	}
+
+	file, err = uploader.Receive(multipartRequest(t, "file", "me.png", pngData))
+	if err != nil {
+		t.Fatalf("Receive() multipart error = %v", err)
+	}
+	if file.Filename != "me.png" || file.ContentType != "image/png" {
+		t.Errorf("Receive() multipart = %+v", file)
+	}
+}
+
+func TestUploaderReceiveErrors(t *testing.T) {
+	uploader := assets.NewUploader(assets.WithMaxSize(64), assets.WithContentTypes(assets.ImageTypes...))
+
+	tests := []struct {
+		name    string
+		req     *http.Request
+		wantErr error
+	}{
+		{name: "empty body", req: httptest.NewRequest(http.MethodPut, "/", nil), wantErr: assets.ErrMissingFile},
+		{name: "too large", req: httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(append(pngData, make([]byte, 64)...))), wantErr: assets.ErrTooLarge},
+		{name: "html posing as png", req: func() *http.Request {
+			r := httptest.NewRequest(http.MethodPut, "/", strings.NewReader("<html><script>alert(1)</script></html>"))
+			r.Header.Set("Content-Type", "image/png")
+			return r
+		}(), wantErr: assets.ErrUnsupportedType},
+		{name: "missing form field", req: multipartRequest(t, "avatar", "me.png", pngData), wantErr: assets.ErrMissingFile},
+		{name: "empty form file", req: multipartRequest(t, "file", "me.png", nil), wantErr: assets.ErrMissingFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := uploader.Receive(tt.req); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Receive() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUploaderDeclaredTypeFallback(t *testing.T) {
+	uploader := assets.NewUploader(assets.WithFormField("doc"))
+
+	r := multipartRequest(t, "doc", "notes.csv", []byte("a,b\n1,2\n"))
+	file, err := uploader.Receive(r)
+	if err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if file.ContentType != "text/plain" {
+		t.Errorf("ContentType = %q, want text/plain sniffed over the generic declared type", file.ContentType)
+	}
+
+	raw := httptest.NewRequest(http.MethodPut, "/", strings.NewReader("a,b\n1,2\n"))
+	raw.Header.Set("Content-Type", "text/csv")
+	if file, _ := uploader.Receive(raw); file.ContentType != "text/csv" {
+		t.Errorf("ContentType = %q, want the declared text/csv", file.ContentType)
+	}
+}
+
+func TestUploaderMaxBytesReader(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(pngData))
+	r.Body = http.MaxBytesReader(w, r.Body, 8)
+
+	if _, err := assets.NewUploader().Receive(r); !errors.Is(err, assets.ErrTooLarge) {
+		t.Errorf("Receive() error = %v, want ErrTooLarge", err)
+	}
+}
@@ -0,0 +1,108 @@
+// Package audit stores a trail of security-relevant operations and lets
+// operators query and prune it. Handlers emit events (see the AuditRecorder
+// option in auth/handler); a Store persists them, and export.Stream streams
+// them to external systems such as a SIEM.
+package audit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidFilter = errors.New("invalid audit filter")
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Default and maximum page sizes for List.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// Event is a single audit record.
+type Event struct {
+	ID       uuid.UUID         `json:"id"`
+	At       time.Time         `json:"at"`
+	Actor    string            `json:"actor"`
+	Action   string            `json:"action"`
+	Resource string            `json:"resource"`
+	Outcome  string            `json:"outcome"`
+	Error    string            `json:"error,omitempty"`
+	RemoteIP string            `json:"remote_ip,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// NewEvent creates an event with a fresh ID, stamped with the current time.
+func NewEvent(actor, action, resource string) *Event {
+	return &Event{
+		ID:       uuid.New(),
+		At:       time.Now().UTC(),
+		Actor:    actor,
+		Action:   action,
+		Resource: resource,
+		Outcome:  OutcomeSuccess,
+	}
+}
+
+// Filter selects events in List. Zero fields match everything.
+// Since is inclusive and Until exclusive. Results are ordered newest first.
+type Filter struct {
+	Actor    string
+	Action   string
+	Resource string
+	Outcome  string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+	Offset   int
+}
+
+// Normalize applies the default limit and validates the filter.
+func (f Filter) Normalize() (Filter, error) {
+	if f.Limit == 0 {
+		f.Limit = DefaultLimit
+	}
+	if f.Limit < 0 || f.Limit > MaxLimit || f.Offset < 0 {
+		return f, ErrInvalidFilter
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && !f.Since.Before(f.Until) {
+		return f, ErrInvalidFilter
+	}
+	return f, nil
+}
+
+// Matches reports whether e satisfies the filter, ignoring Limit and Offset.
+func (f Filter) Matches(e *Event) bool {
+	switch {
+	case f.Actor != "" && e.Actor != f.Actor:
+		return false
+	case f.Action != "" && e.Action != f.Action:
+		return false
+	case f.Resource != "" && e.Resource != f.Resource:
+		return false
+	case f.Outcome != "" && e.Outcome != f.Outcome:
+		return false
+	case !f.Since.IsZero() && e.At.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.At.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+type Store interface {
+	Append(ctx context.Context, event *Event) error
+	List(ctx context.Context, filter Filter) ([]*Event, error)
+	// Prune deletes events recorded before the given time and returns how many were removed.
+	Prune(ctx context.Context, before time.Time) (int64, error)
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewEvent(t *testing.T) {
+	e := NewEvent("alice", "role.created", "admins")
+
+	if e.ID.String() == "00000000-0000-0000-0000-000000000000" {
+		t.Error("NewEvent() should assign an ID")
+	}
+	if e.At.IsZero() || e.At.Location() != time.UTC {
+		t.Errorf("NewEvent() At = %v, want current UTC time", e.At)
+	}
+	if e.Outcome != OutcomeSuccess {
+		t.Errorf("NewEvent() Outcome = %q, want %q", e.Outcome, OutcomeSuccess)
+	}
+}
+
+func TestFilterNormalize(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		filter    Filter
+		wantLimit int
+		wantErr   bool
+	}{
+		{"default limit", Filter{}, DefaultLimit, false},
+		{"explicit limit", Filter{Limit: 10}, 10, false},
+		{"max limit", Filter{Limit: MaxLimit}, MaxLimit, false},
+		{"limit too large", Filter{Limit: MaxLimit + 1}, 0, true},
+		{"negative limit", Filter{Limit: -1}, 0, true},
+		{"negative offset", Filter{Offset: -1}, 0, true},
+		{"valid range", Filter{Since: now.Add(-time.Hour), Until: now}, DefaultLimit, false},
+		{"inverted range", Filter{Since: now, Until: now.Add(-time.Hour)}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.filter.Normalize()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidFilter) {
+					t.Errorf("Normalize() error = %v, want ErrInvalidFilter", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if got.Limit != tt.wantLimit {
+				t.Errorf("Normalize() Limit = %d, want %d", got.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	e := &Event{Actor: "alice", Action: "role.created", Resource: "admins", Outcome: OutcomeSuccess, At: at}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty", Filter{}, true},
+		{"actor", Filter{Actor: "alice"}, true},
+		{"other actor", Filter{Actor: "bob"}, false},
+		{"action", Filter{Action: "role.created"}, true},
+		{"other action", Filter{Action: "role.deleted"}, false},
+		{"resource", Filter{Resource: "admins"}, true},
+		{"other resource", Filter{Resource: "users"}, false},
+		{"outcome", Filter{Outcome: OutcomeFailure}, false},
+		{"since inclusive", Filter{Since: at}, true},
+		{"since after", Filter{Since: at.Add(time.Second)}, false},
+		{"until exclusive", Filter{Until: at}, false},
+		{"until after", Filter{Until: at.Add(time.Second)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(e); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+// Package export streams audit events to external systems such as a SIEM.
+// A Stream wraps an audit.Store: every event appended to the store is also
+// queued for each exporter and delivered in batches in the background.
+// Delivery is at-least-once: a batch is retried until its exporter accepts
+// it, so consumers should deduplicate by event ID.
+package export
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aquamarinepk/aqm/audit"
+	"github.com/aquamarinepk/aqm/log"
+)
+
+// Exporter delivers a batch of audit events, oldest first. An error makes
+// the stream retry the whole batch.
+type Exporter interface {
+	Export(ctx context.Context, events []*audit.Event) error
+}
+
+// ExporterFunc adapts a function to Exporter.
+type ExporterFunc func(ctx context.Context, events []*audit.Event) error
+
+func (f ExporterFunc) Export(ctx context.Context, events []*audit.Event) error {
+	return f(ctx, events)
+}
+
+// Option configures a Stream.
+type Option func(*Stream)
+
+// WithExporter adds an exporter, named in logs. Each exporter has its own
+// queue, so one that is down does not hold back the others.
+func WithExporter(name string, e Exporter) Option {
+	return func(s *Stream) {
+		s.workers = append(s.workers, &worker{name: name, exporter: e})
+	}
+}
+
+// WithBufferSize sets how many events wait for each exporter. Default 1000.
+func WithBufferSize(n int) Option {
+	return func(s *Stream) {
+		s.bufferSize = n
+	}
+}
+
+// WithBatch sets the largest batch and how long events wait for one to
+// fill. Defaults 100 and 1s.
+func WithBatch(size int, interval time.Duration) Option {
+	return func(s *Stream) {
+		s.batchSize = size
+		s.flushInterval = interval
+	}
+}
+
+// WithBackoff sets the delay before retrying a failed batch, doubled after
+// every failure up to max. Defaults 1s and 1m.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(s *Stream) {
+		s.initialBackoff = initial
+		s.maxBackoff = max
+	}
+}
+
+// WithBlockTimeout sets how long Append waits for room when an exporter's
+// buffer is full before dropping the event for that exporter. Default 1s.
+func WithBlockTimeout(d time.Duration) Option {
+	return func(s *Stream) {
+		s.blockTimeout = d
+	}
+}
+
+// Stream is an audit.Store that streams the events appended to it. Start
+// begins delivering; Stop delivers what is still queued.
+type Stream struct {
+	audit.Store
+	log     log.Logger
+	workers []*worker
+
+	bufferSize     int
+	batchSize      int
+	flushInterval  time.Duration
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	blockTimeout   time.Duration
+
+	mu      sync.RWMutex
+	started bool
+	stopped bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+type worker struct {
+	name     string
+	exporter Exporter
+	queue    chan *audit.Event
+	done     chan struct{}
+	dropped  atomic.Int64
+}
+
+// NewStream creates a stream appending events to store and exporting them
+// with the exporters of WithExporter.
+func NewStream(store audit.Store, logger log.Logger, opts ...Option) *Stream {
+	if logger == nil {
+		logger = log.NewNoopLogger()
+	}
+	s := &Stream{
+		Store:          store,
+		log:            logger,
+		bufferSize:     1000,
+		batchSize:      100,
+		flushInterval:  time.Second,
+		initialBackoff: time.Second,
+		maxBackoff:     time.Minute,
+		blockTimeout:   time.Second,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.bufferSize < 1 {
+		s.bufferSize = 1
+	}
+	if s.batchSize < 1 {
+		s.batchSize = 1
+	}
+	if s.flushInterval <= 0 {
+		s.flushInterval = time.Second
+	}
+	if s.initialBackoff <= 0 {
+		s.initialBackoff = time.Second
+	}
+	for _, w := range s.workers {
+		w.queue = make(chan *audit.Event, s.bufferSize)
+		w.done = make(chan struct{})
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+// Append appends event to the store and queues it for every exporter. When
+// an exporter's buffer is full it waits up to the block timeout, or until
+// ctx is done, and then drops the event for that exporter; it stays in the
+// store. It returns only the store's error.
+func (s *Stream) Append(ctx context.Context, event *audit.Event) error {
+	if err := s.Store.Append(ctx, event); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, w := range s.workers {
+		if s.stopped {
+			w.dropped.Add(1)
+			continue
+		}
+		select {
+		case w.queue <- event:
+			continue
+		default:
+		}
+
+		timer := time.NewTimer(s.blockTimeout)
+		select {
+		case w.queue <- event:
+		case <-timer.C:
+			s.drop(w, event)
+		case <-ctx.Done():
+			s.drop(w, event)
+		}
+		timer.Stop()
+	}
+	return nil
+}
+
+func (s *Stream) drop(w *worker, event *audit.Event) {
+	w.dropped.Add(1)
+	s.log.Error("Audit export buffer is full, dropping event", "exporter", w.name, "event", event.ID)
+}
+
+// Dropped returns how many events were not queued for the exporter named
+// name because its buffer was full or the stream had stopped.
+func (s *Stream) Dropped(name string) int64 {
+	for _, w := range s.workers {
+		if w.name == name {
+			return w.dropped.Load()
+		}
+	}
+	return 0
+}
+
+// Start starts delivering queued events.
+func (s *Stream) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || s.stopped {
+		return nil
+	}
+	s.started = true
+	for _, w := range s.workers {
+		go s.run(w)
+	}
+	return nil
+}
+
+// Stop stops queueing events and waits until those queued are delivered,
+// then closes the exporters that implement io.Closer. If ctx expires first,
+// pending retries are abandoned and ctx.Err is returned.
+func (s *Stream) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopped = true
+	started := s.started
+	for _, w := range s.workers {
+		close(w.queue)
+	}
+	s.mu.Unlock()
+
+	if !started {
+		for _, w := range s.workers {
+			go s.run(w)
+		}
+	}
+
+	var err error
+	for _, w := range s.workers {
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+			s.cancel()
+			<-w.done
+			err = ctx.Err()
+		}
+	}
+	s.cancel()
+
+	for _, w := range s.workers {
+		if c, ok := w.exporter.(io.Closer); ok {
+			if cerr := c.Close(); cerr != nil {
+				err = errors.Join(err, cerr)
+			}
+		}
+	}
+	return err
+}
+
+// run batches the events of w's queue and delivers them until the queue is
+// closed and drained.
+func (s *Stream) run(w *worker) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*audit.Event, 0, s.batchSize)
+	for {
+		select {
+		case e, ok := <-w.queue:
+			if !ok {
+				s.deliver(w, batch)
+				return
+			}
+			batch = append(batch, e)
+			if len(batch) < s.batchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		if len(batch) > 0 {
+			s.deliver(w, batch)
+			batch = make([]*audit.Event, 0, s.batchSize)
+		}
+	}
+}
+
+// deliver exports batch, retrying with backoff until it succeeds or the
+// stream is cancelled.
+func (s *Stream) deliver(w *worker, batch []*audit.Event) {
+	if len(batch) == 0 {
+		return
+	}
+	backoff := s.initialBackoff
+	for {
+		err := w.exporter.Export(s.ctx, batch)
+		if err == nil {
+			return
+		}
+		if s.ctx.Err() != nil {
+			s.log.Error("Abandoning audit export", "exporter", w.name, "events", len(batch), "error", err)
+			return
+		}
+		s.log.Error("Cannot export audit events, retrying", "exporter", w.name, "events", len(batch), "retry_in", backoff, "error", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-s.ctx.Done():
+			timer.Stop()
+			s.log.Error("Abandoning audit export", "exporter", w.name, "events", len(batch))
+			return
+		}
+		if backoff *= 2; s.maxBackoff > 0 && backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
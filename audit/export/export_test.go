@@ -0,0 +1,120 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/audit"
+	"github.com/aquamarinepk/aqm/audit/fake"
+	"github.com/aquamarinepk/aqm/log"
+)
+
+// collector records the batches it accepts, failing the first failures calls.
+type collector struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	batches  [][]*audit.Event
+}
+
+func (c *collector) Export(ctx context.Context, events []*audit.Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if c.calls <= c.failures {
+		return errors.New("siem down")
+	}
+	c.batches = append(c.batches, append([]*audit.Event(nil), events...))
+	return nil
+}
+
+func (c *collector) actions() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var actions []string
+	for _, b := range c.batches {
+		for _, e := range b {
+			actions = append(actions, e.Action)
+		}
+	}
+	return actions
+}
+
+func TestStreamExportsInBatches(t *testing.T) {
+	store := fake.NewStore()
+	col := &collector{}
+	s := NewStream(store, log.NewNoopLogger(), WithExporter("siem", col), WithBatch(2, time.Hour))
+	s.Start(context.Background())
+
+	for _, action := range []string{"login", "logout", "grant"} {
+		if err := s.Append(context.Background(), audit.NewEvent("user-1", action, "user-1")); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	if got := col.actions(); len(got) != 3 || got[0] != "login" || got[2] != "grant" {
+		t.Errorf("exported %v, want login, logout, grant", got)
+	}
+	if len(col.batches) != 2 || len(col.batches[0]) != 2 {
+		t.Errorf("batches = %d, want a full batch of 2 and the rest on stop", len(col.batches))
+	}
+	if stored, _ := store.List(context.Background(), audit.Filter{}); len(stored) != 3 {
+		t.Errorf("stored %d events, want 3", len(stored))
+	}
+}
+
+func TestStreamRetriesUntilAccepted(t *testing.T) {
+	col := &collector{failures: 2}
+	s := NewStream(fake.NewStore(), log.NewNoopLogger(),
+		WithExporter("siem", col), WithBatch(1, time.Hour), WithBackoff(time.Millisecond, time.Millisecond))
+	s.Start(context.Background())
+
+	s.Append(context.Background(), audit.NewEvent("user-1", "login", "user-1"))
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	if col.calls != 3 || len(col.actions()) != 1 {
+		t.Errorf("calls = %d, exported %v, want the event accepted on the third call", col.calls, col.actions())
+	}
+}
+
+func TestStreamBackpressure(t *testing.T) {
+	col := &collector{}
+	s := NewStream(fake.NewStore(), log.NewNoopLogger(),
+		WithExporter("siem", col), WithBufferSize(1), WithBlockTimeout(time.Millisecond))
+
+	// Not started, so the buffer fills up.
+	s.Append(context.Background(), audit.NewEvent("user-1", "login", "user-1"))
+	s.Append(context.Background(), audit.NewEvent("user-1", "logout", "user-1"))
+
+	if got := s.Dropped("siem"); got != 1 {
+		t.Errorf("Dropped() = %d, want 1", got)
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if got := col.actions(); len(got) != 1 || got[0] != "login" {
+		t.Errorf("exported %v, want the queued login only", got)
+	}
+}
+
+func TestStreamStopTimeout(t *testing.T) {
+	col := &collector{failures: 1 << 30}
+	s := NewStream(fake.NewStore(), log.NewNoopLogger(),
+		WithExporter("siem", col), WithBatch(1, time.Hour), WithBackoff(time.Millisecond, time.Millisecond))
+	s.Start(context.Background())
+	s.Append(context.Background(), audit.NewEvent("user-1", "login", "user-1"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop() error = %v, want deadline exceeded", err)
+	}
+}
@@ -0,0 +1,54 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/aquamarinepk/aqm/audit"
+)
+
+// FileExporter appends events to a file as JSON lines, for log shippers such
+// as Filebeat or the Splunk forwarder to pick up.
+type FileExporter struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileExporter opens path for appending, creating it if needed.
+func NewFileExporter(path string) (*FileExporter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open audit export file: %w", err)
+	}
+	return &FileExporter{f: f}, nil
+}
+
+// Export writes events, one JSON object per line, and syncs the file so an
+// accepted batch survives a crash.
+func (e *FileExporter) Export(ctx context.Context, events []*audit.Event) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	w := bufio.NewWriter(e.f)
+	enc := json.NewEncoder(w)
+	for _, event := range events {
+		if err := enc.Encode(event); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return e.f.Sync()
+}
+
+// Close closes the file.
+func (e *FileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.f.Close()
+}
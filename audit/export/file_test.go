@@ -0,0 +1,47 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/audit"
+)
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	e, err := NewFileExporter(path)
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+
+	events := []*audit.Event{audit.NewEvent("user-1", "login", "user-1"), audit.NewEvent("user-1", "logout", "user-1")}
+	if err := e.Export(context.Background(), events[:1]); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if err := e.Export(context.Background(), events[1:]); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("file has %d lines, want 2", len(lines))
+	}
+	var got audit.Event
+	if err := json.Unmarshal([]byte(lines[1]), &got); err != nil || got.ID != events[1].ID || got.Action != "logout" {
+		t.Errorf("second line = %s, %v", lines[1], err)
+	}
+}
+
+func TestNewFileExporterInvalidPath(t *testing.T) {
+	if _, err := NewFileExporter(filepath.Join(t.TempDir(), "missing", "audit.log")); err == nil {
+		t.Error("NewFileExporter() error = nil, want error")
+	}
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aquamarinepk/aqm/audit"
+)
+
+// Encoder encodes a batch of events as the body of a bulk request and
+// returns its content type.
+type Encoder func(events []*audit.Event) ([]byte, string, error)
+
+// NDJSON encodes events as JSON lines.
+func NDJSON(events []*audit.Event) ([]byte, string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return nil, "", err
+		}
+	}
+	return buf.Bytes(), "application/x-ndjson", nil
+}
+
+// ElasticBulk encodes events for the Elasticsearch _bulk API, indexed into
+// index with the event ID as document ID so that retries do not duplicate
+// documents.
+func ElasticBulk(index string) Encoder {
+	return func(events []*audit.Event) ([]byte, string, error) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, e := range events {
+			action := map[string]any{"create": map[string]string{"_index": index, "_id": e.ID.String()}}
+			if err := enc.Encode(action); err != nil {
+				return nil, "", err
+			}
+			if err := enc.Encode(e); err != nil {
+				return nil, "", err
+			}
+		}
+		return buf.Bytes(), "application/x-ndjson", nil
+	}
+}
+
+// SplunkHEC encodes events for the Splunk HTTP Event Collector, with source
+// and sourcetype "aqm:audit" and the event time.
+func SplunkHEC(source string) Encoder {
+	return func(events []*audit.Event) ([]byte, string, error) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, e := range events {
+			hec := map[string]any{
+				"time":       float64(e.At.UnixMilli()) / 1000,
+				"source":     source,
+				"sourcetype": "aqm:audit",
+				"event":      e,
+			}
+			if err := enc.Encode(hec); err != nil {
+				return nil, "", err
+			}
+		}
+		return buf.Bytes(), "application/json", nil
+	}
+}
+
+// HTTPConfig configures an HTTPExporter.
+type HTTPConfig struct {
+	// URL receives the bulk requests, e.g. https://es:9200/_bulk or
+	// https://splunk:8088/services/collector.
+	URL string
+	// Header is sent with every request, e.g. Authorization.
+	Header http.Header
+	// Encoder defaults to NDJSON.
+	Encoder Encoder
+	// Client defaults to a client with a 10 second timeout.
+	Client *http.Client
+}
+
+// HTTPExporter posts batches of events to a bulk endpoint. Any response
+// other than 2xx fails the batch, which the stream retries.
+type HTTPExporter struct {
+	cfg HTTPConfig
+}
+
+// NewHTTPExporter creates an exporter for cfg.
+func NewHTTPExporter(cfg HTTPConfig) (*HTTPExporter, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("audit export url is required")
+	}
+	if cfg.Encoder == nil {
+		cfg.Encoder = NDJSON
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &HTTPExporter{cfg: cfg}, nil
+}
+
+// Export posts events in one request.
+func (e *HTTPExporter) Export(ctx context.Context, events []*audit.Event) error {
+	body, contentType, err := e.cfg.Encoder(events)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range e.cfg.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := e.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("audit export endpoint responded %d", resp.StatusCode)
+	}
+	return nil
+}
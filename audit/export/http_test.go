@@ -0,0 +1,73 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/audit"
+)
+
+func TestHTTPExporter(t *testing.T) {
+	var body, contentType, auth string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, contentType, auth = string(data), r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	e, err := NewHTTPExporter(HTTPConfig{URL: srv.URL, Header: http.Header{"Authorization": {"Splunk token"}}, Encoder: SplunkHEC("authn")})
+	if err != nil {
+		t.Fatalf("NewHTTPExporter() error = %v", err)
+	}
+
+	event := audit.NewEvent("user-1", "login", "user-1")
+	if err := e.Export(context.Background(), []*audit.Event{event}); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if auth != "Splunk token" || contentType != "application/json" {
+		t.Errorf("authorization = %q, content type = %q", auth, contentType)
+	}
+	var hec struct {
+		Source string      `json:"source"`
+		Event  audit.Event `json:"event"`
+	}
+	if err := json.Unmarshal([]byte(body), &hec); err != nil || hec.Source != "authn" || hec.Event.ID != event.ID {
+		t.Errorf("body = %s, %v", body, err)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := e.Export(context.Background(), []*audit.Event{event}); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Export() error = %v, want 503", err)
+	}
+}
+
+func TestElasticBulk(t *testing.T) {
+	events := []*audit.Event{audit.NewEvent("user-1", "login", "user-1"), audit.NewEvent("user-2", "login", "user-2")}
+
+	body, contentType, err := ElasticBulk("audit")(events)
+	if err != nil {
+		t.Fatalf("ElasticBulk() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines) != 4 || contentType != "application/x-ndjson" {
+		t.Fatalf("body has %d lines with %s, want 4 ndjson lines", len(lines), contentType)
+	}
+	var action map[string]map[string]string
+	json.Unmarshal([]byte(lines[2]), &action)
+	if action["create"]["_index"] != "audit" || action["create"]["_id"] != events[1].ID.String() {
+		t.Errorf("action line = %s", lines[2])
+	}
+}
+
+func TestNewHTTPExporterRequiresURL(t *testing.T) {
+	if _, err := NewHTTPExporter(HTTPConfig{}); err == nil {
+		t.Error("NewHTTPExporter() error = nil, want error")
+	}
+}
@@ -0,0 +1,35 @@
+package export
+
+import (
+	"context"
+
+	"github.com/aquamarinepk/aqm/audit"
+	"github.com/aquamarinepk/aqm/pubsub"
+)
+
+// PublisherExporter publishes every event to a topic, such as a NATS subject
+// with the pubsub/nats broker. Envelopes carry the event ID as their ID and
+// the action in their "action" metadata.
+type PublisherExporter struct {
+	pub   pubsub.Publisher
+	topic string
+}
+
+// NewPublisherExporter creates an exporter publishing to topic with pub.
+func NewPublisherExporter(pub pubsub.Publisher, topic string) *PublisherExporter {
+	return &PublisherExporter{pub: pub, topic: topic}
+}
+
+// Export publishes events in order, stopping at the first failure.
+func (e *PublisherExporter) Export(ctx context.Context, events []*audit.Event) error {
+	for _, event := range events {
+		env := pubsub.NewEnvelope(e.topic, event)
+		env.ID = event.ID.String()
+		env.Timestamp = event.At
+		env.Metadata["action"] = event.Action
+		if err := e.pub.Publish(ctx, e.topic, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
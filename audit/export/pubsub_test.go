@@ -0,0 +1,28 @@
+package export
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/audit"
+	"github.com/aquamarinepk/aqm/pubsub"
+)
+
+func TestPublisherExporter(t *testing.T) {
+	broker := pubsub.NewNoopBroker()
+	e := NewPublisherExporter(broker, "audit.events")
+
+	event := audit.NewEvent("user-1", "login", "user-1")
+	if err := e.Export(context.Background(), []*audit.Event{event}); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	published := broker.Published()
+	if len(published) != 1 {
+		t.Fatalf("published %d envelopes, want 1", len(published))
+	}
+	env := published[0]
+	if env.Topic != "audit.events" || env.ID != event.ID.String() || env.Metadata["action"] != "login" || env.Payload != event {
+		t.Errorf("envelope = %+v", env)
+	}
+}
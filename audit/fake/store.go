@@ -0,0 +1,80 @@
+package fake
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/audit"
+)
+
+type Store struct {
+	mu     sync.RWMutex
+	events []*audit.Event
+}
+
+func NewStore() *Store {
+	return &Store{}
+}
+
+func (s *Store) Append(ctx context.Context, event *audit.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *Store) List(ctx context.Context, filter audit.Filter) ([]*audit.Event, error) {
+	filter, err := filter.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []*audit.Event
+	for _, e := range s.events {
+		if filter.Matches(e) {
+			matched = append(matched, e)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].At.After(matched[j].At)
+	})
+
+	if filter.Offset >= len(matched) {
+		return []*audit.Event{}, nil
+	}
+	matched = matched[filter.Offset:]
+	if len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, nil
+}
+
+func (s *Store) Prune(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.events[:0]
+	for _, e := range s.events {
+		if !e.At.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+
+	removed := int64(len(s.events) - len(kept))
+	for i := len(kept); i < len(s.events); i++ {
+		s.events[i] = nil
+	}
+	s.events = kept
+	return removed, nil
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,93 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/audit"
+)
+
+func seedEvents(t *testing.T, s *Store, base time.Time) {
+	t.Helper()
+
+	for i, e := range []struct{ actor, action, resource string }{
+		{"alice", "role.created", "admins"},
+		{"bob", "grant.assigned", "carol"},
+		{"alice", "role.deleted", "admins"},
+		{"carol", "auth.signin", "carol"},
+	} {
+		event := audit.NewEvent(e.actor, e.action, e.resource)
+		event.At = base.Add(time.Duration(i) * time.Hour)
+		if err := s.Append(context.Background(), event); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	s := NewStore()
+	seedEvents(t, s, base)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		filter  audit.Filter
+		actions []string
+	}{
+		{"all newest first", audit.Filter{}, []string{"auth.signin", "role.deleted", "grant.assigned", "role.created"}},
+		{"by actor", audit.Filter{Actor: "alice"}, []string{"role.deleted", "role.created"}},
+		{"by resource", audit.Filter{Resource: "carol"}, []string{"auth.signin", "grant.assigned"}},
+		{"by range", audit.Filter{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)}, []string{"role.deleted", "grant.assigned"}},
+		{"paged", audit.Filter{Limit: 2, Offset: 1}, []string{"role.deleted", "grant.assigned"}},
+		{"offset past end", audit.Filter{Offset: 10}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := s.List(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(events) != len(tt.actions) {
+				t.Fatalf("List() returned %d events, want %d", len(events), len(tt.actions))
+			}
+			for i, e := range events {
+				if e.Action != tt.actions[i] {
+					t.Errorf("events[%d].Action = %q, want %q", i, e.Action, tt.actions[i])
+				}
+			}
+		})
+	}
+
+	if _, err := s.List(ctx, audit.Filter{Limit: -1}); err == nil {
+		t.Error("List() should reject an invalid filter")
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	s := NewStore()
+	seedEvents(t, s, base)
+	ctx := context.Background()
+
+	deleted, err := s.Prune(ctx, base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Prune() = %d, want 2", deleted)
+	}
+
+	events, _ := s.List(ctx, audit.Filter{})
+	if len(events) != 2 {
+		t.Errorf("List() after Prune returned %d events, want 2", len(events))
+	}
+}
+
+func TestStorePing(t *testing.T) {
+	if err := NewStore().Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+// Package handler exposes the audit trail over HTTP for operators.
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aquamarinepk/aqm/audit"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// Permissions required by the audit routes.
+const (
+	PermissionRead  = "audit:read"
+	PermissionPrune = "audit:delete"
+)
+
+// ActionPruned is recorded after every successful prune.
+const ActionPruned = "audit.pruned"
+
+type Handler struct {
+	store   audit.Store
+	checker middleware.RoleChecker
+	now     func() time.Time
+}
+
+// NewHandler creates the audit admin handler. Callers must hold
+// PermissionRead to list events and PermissionPrune to delete them; the
+// caller is identified by the user set by the Session or Bearer middleware.
+func NewHandler(store audit.Store, checker middleware.RoleChecker) *Handler {
+	return &Handler{
+		store:   store,
+		checker: checker,
+		now:     time.Now,
+	}
+}
+
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.With(middleware.RequirePermission(h.checker, PermissionRead)).Get("/audit/events", h.handleListEvents)
+	r.With(middleware.RequirePermission(h.checker, PermissionPrune)).Delete("/audit/events", h.handlePruneEvents)
+}
+
+type ListEventsResponse struct {
+	Events []*audit.Event `json:"events"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+type PruneEventsResponse struct {
+	Deleted int64     `json:"deleted"`
+	Before  time.Time `json:"before"`
+}
+
+type ErrorResponse = httpx.ErrorResponse
+
+// handleListEvents serves GET /audit/events. Supported query parameters:
+// actor, action, resource, outcome, since and until (RFC 3339), limit, offset.
+func (h *Handler) handleListEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_FILTER", err.Error()))
+		return
+	}
+
+	filter, err = filter.Normalize()
+	if err != nil {
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_FILTER", "Invalid limit, offset or time range"))
+		return
+	}
+
+	events, err := h.store.List(r.Context(), filter)
+	if err != nil {
+		httpx.WriteError(w, r, httpx.Internal("Cannot list audit events"))
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, ListEventsResponse{Events: events, Limit: filter.Limit, Offset: filter.Offset})
+}
+
+// handlePruneEvents serves DELETE /audit/events?before=... where before is an
+// RFC 3339 timestamp or a retention duration such as 2160h.
+func (h *Handler) handlePruneEvents(w http.ResponseWriter, r *http.Request) {
+	before, err := parseBefore(r.URL.Query().Get("before"), h.now())
+	if err != nil {
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_REQUEST", err.Error()))
+		return
+	}
+
+	deleted, err := h.store.Prune(r.Context(), before)
+	if err != nil {
+		httpx.WriteError(w, r, httpx.Internal("Cannot prune audit events"))
+		return
+	}
+
+	event := audit.NewEvent(middleware.GetUserID(r.Context()), ActionPruned, "audit_events")
+	event.At = h.now().UTC()
+	event.Metadata = map[string]string{
+		"before":  before.Format(time.RFC3339),
+		"deleted": strconv.FormatInt(deleted, 10),
+	}
+	h.store.Append(r.Context(), event)
+
+	httpx.WriteJSON(w, http.StatusOK, PruneEventsResponse{Deleted: deleted, Before: before})
+}
+
+func parseFilter(r *http.Request) (audit.Filter, error) {
+	q := r.URL.Query()
+	filter := audit.Filter{
+		Actor:    q.Get("actor"),
+		Action:   q.Get("action"),
+		Resource: q.Get("resource"),
+		Outcome:  q.Get("outcome"),
+	}
+
+	var err error
+	if filter.Since, err = parseTime(q.Get("since")); err != nil {
+		return filter, errors.New("since must be an RFC 3339 timestamp")
+	}
+	if filter.Until, err = parseTime(q.Get("until")); err != nil {
+		return filter, errors.New("until must be an RFC 3339 timestamp")
+	}
+	if filter.Limit, err = parseInt(q.Get("limit")); err != nil {
+		return filter, errors.New("limit must be an integer")
+	}
+	if filter.Offset, err = parseInt(q.Get("offset")); err != nil {
+		return filter, errors.New("offset must be an integer")
+	}
+	return filter, nil
+}
+
+func parseBefore(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("before is required")
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(-d).UTC(), nil
+	}
+	return time.Time{}, errors.New("before must be an RFC 3339 timestamp or a positive duration")
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func parseInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
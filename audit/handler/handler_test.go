@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/audit"
+	"github.com/aquamarinepk/aqm/audit/fake"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeChecker struct {
+	allowed map[string]bool
+}
+
+func (c fakeChecker) HasRole(ctx context.Context, userID, roleName string) (bool, error) {
+	return false, nil
+}
+
+func (c fakeChecker) CheckPermission(ctx context.Context, userID, permission string) (bool, error) {
+	return c.allowed[permission], nil
+}
+
+func (c fakeChecker) CheckAnyPermission(ctx context.Context, userID string, permissions []string) (bool, error) {
+	return false, nil
+}
+
+func (c fakeChecker) CheckAllPermissions(ctx context.Context, userID string, permissions []string) (bool, error) {
+	return false, nil
+}
+
+var testNow = time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+
+func setupHandler(t *testing.T, allowed ...string) (http.Handler, *fake.Store) {
+	t.Helper()
+
+	store := fake.NewStore()
+	for i, e := range []struct{ actor, action, resource string }{
+		{"alice", "role.created", "admins"},
+		{"bob", "grant.assigned", "carol"},
+		{"alice", "role.deleted", "admins"},
+	} {
+		event := audit.NewEvent(e.actor, e.action, e.resource)
+		event.At = testNow.Add(-time.Duration(3-i) * 24 * time.Hour)
+		store.Append(context.Background(), event)
+	}
+
+	checker := fakeChecker{allowed: map[string]bool{}}
+	for _, p := range allowed {
+		checker.allowed[p] = true
+	}
+
+	h := NewHandler(store, checker)
+	h.now = func() time.Time { return testNow }
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	return r, store
+}
+
+func serve(h http.Handler, method, target, userID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if userID != "" {
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestListEvents(t *testing.T) {
+	h, _ := setupHandler(t, PermissionRead)
+
+	tests := []struct {
+		name    string
+		query   string
+		actions []string
+	}{
+		{"all", "", []string{"role.deleted", "grant.assigned", "role.created"}},
+		{"by actor", "?actor=alice", []string{"role.deleted", "role.created"}},
+		{"by action", "?action=grant.assigned", []string{"grant.assigned"}},
+		{"by resource", "?resource=admins&limit=1", []string{"role.deleted"}},
+		{"by range", "?since=2025-06-07T12:00:00Z&until=2025-06-09T00:00:00Z", []string{"grant.assigned"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(h, http.MethodGet, "/audit/events"+tt.query, "admin")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+
+			var resp ListEventsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("cannot decode response: %v", err)
+			}
+			if len(resp.Events) != len(tt.actions) {
+				t.Fatalf("got %d events, want %d", len(resp.Events), len(tt.actions))
+			}
+			for i, e := range resp.Events {
+				if e.Action != tt.actions[i] {
+					t.Errorf("events[%d].Action = %q, want %q", i, e.Action, tt.actions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestListEventsInvalidFilter(t *testing.T) {
+	h, _ := setupHandler(t, PermissionRead)
+
+	for _, query := range []string{"?since=yesterday", "?limit=lots", "?limit=100000", "?offset=-1",
+		"?since=2025-06-09T00:00:00Z&until=2025-06-08T00:00:00Z"} {
+		t.Run(query, func(t *testing.T) {
+			rec := serve(h, http.MethodGet, "/audit/events"+query, "admin")
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestPruneEvents(t *testing.T) {
+	tests := []struct {
+		name        string
+		before      string
+		wantDeleted int64
+	}{
+		{"timestamp", "2025-06-08T00:00:00Z", 1},
+		{"retention duration", "24h", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, store := setupHandler(t, PermissionPrune)
+
+			rec := serve(h, http.MethodDelete, "/audit/events?before="+tt.before, "admin")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+
+			var resp PruneEventsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("cannot decode response: %v", err)
+			}
+			if resp.Deleted != tt.wantDeleted {
+				t.Errorf("deleted = %d, want %d", resp.Deleted, tt.wantDeleted)
+			}
+
+			events, _ := store.List(context.Background(), audit.Filter{Action: ActionPruned})
+			if len(events) != 1 || events[0].Actor != "admin" {
+				t.Errorf("prune should be audited, got %+v", events)
+			}
+		})
+	}
+}
+
+func TestPruneEventsInvalidBefore(t *testing.T) {
+	h, _ := setupHandler(t, PermissionPrune)
+
+	for _, query := range []string{"", "?before=soon", "?before=-24h"} {
+		t.Run(query, func(t *testing.T) {
+			rec := serve(h, http.MethodDelete, "/audit/events"+query, "admin")
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		userID  string
+		method  string
+		target  string
+		want    int
+	}{
+		{"list unauthenticated", []string{PermissionRead}, "", http.MethodGet, "/audit/events", http.StatusUnauthorized},
+		{"list without permission", nil, "admin", http.MethodGet, "/audit/events", http.StatusForbidden},
+		{"prune with read only", []string{PermissionRead}, "admin", http.MethodDelete, "/audit/events?before=24h", http.StatusForbidden},
+		{"list with prune only", []string{PermissionPrune}, "admin", http.MethodGet, "/audit/events", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := setupHandler(t, tt.allowed...)
+			if rec := serve(h, tt.method, tt.target, tt.userID); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id UUID PRIMARY KEY,
+    at TIMESTAMPTZ NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,
+    resource TEXT NOT NULL DEFAULT '',
+    outcome TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    remote_ip TEXT NOT NULL DEFAULT '',
+    metadata JSONB NOT NULL DEFAULT '{}'::jsonb
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_at ON audit_events(at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor, at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events(action, at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_resource ON audit_events(resource, at DESC);
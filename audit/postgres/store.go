@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/audit"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) audit.Store {
+	return &store{db: db}
+}
+
+func (s *store) Append(ctx context.Context, event *audit.Event) error {
+	metadata := event.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metaJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_events (
+			id, at, actor, action, resource, outcome, error, remote_ip, metadata
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		)
+	`
+	_, err = s.db.ExecContext(ctx, query,
+		event.ID, event.At, event.Actor, event.Action, event.Resource,
+		event.Outcome, event.Error, event.RemoteIP, metaJSON,
+	)
+	return err
+}
+
+func (s *store) List(ctx context.Context, filter audit.Filter) ([]*audit.Event, error) {
+	filter, err := filter.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.Actor != "" {
+		add("actor = $%d", filter.Actor)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.Resource != "" {
+		add("resource = $%d", filter.Resource)
+	}
+	if filter.Outcome != "" {
+		add("outcome = $%d", filter.Outcome)
+	}
+	if !filter.Since.IsZero() {
+		add("at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("at < $%d", filter.Until)
+	}
+
+	query := `
+		SELECT id, at, actor, action, resource, outcome, error, remote_ip, metadata
+		FROM audit_events
+	`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY at DESC, id LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*audit.Event{}
+	for rows.Next() {
+		event := &audit.Event{}
+		var metaJSON []byte
+		if err := rows.Scan(
+			&event.ID, &event.At, &event.Actor, &event.Action, &event.Resource,
+			&event.Outcome, &event.Error, &event.RemoteIP, &metaJSON,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(metaJSON, &event.Metadata); err != nil {
+			return nil, err
+		}
+		if len(event.Metadata) == 0 {
+			event.Metadata = nil
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (s *store) Prune(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM audit_events WHERE at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/audit"
+	"github.com/aquamarinepk/aqm/testhelper"
+)
+
+func TestMain(m *testing.M) {
+	testhelper.Main(m)
+}
+
+func setupTestDB(t *testing.T) (*sql.DB, func()) {
+	t.Helper()
+
+	db, _, cleanup := testhelper.SetupTestDB(t)
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_events (
+			id UUID PRIMARY KEY,
+			at TIMESTAMPTZ NOT NULL,
+			actor TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL,
+			resource TEXT NOT NULL DEFAULT '',
+			outcome TEXT NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			remote_ip TEXT NOT NULL DEFAULT '',
+			metadata JSONB NOT NULL DEFAULT '{}'::jsonb
+		)
+	`)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to create tables: %v", err)
+	}
+
+	return db, cleanup
+}
+
+func TestStoreAppendAndList(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := NewStore(db)
+	ctx := context.Background()
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, e := range []struct{ actor, action, resource string }{
+		{"alice", "role.created", "admins"},
+		{"bob", "grant.assigned", "carol"},
+		{"alice", "role.deleted", "admins"},
+	} {
+		event := audit.NewEvent(e.actor, e.action, e.resource)
+		event.At = base.Add(time.Duration(i) * time.Hour)
+		if i == 1 {
+			event.Outcome = audit.OutcomeFailure
+			event.Error = "role not found"
+			event.Metadata = map[string]string{"role": "admins"}
+		}
+		if err := s.Append(ctx, event); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	events, err := s.List(ctx, audit.Filter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(events) != 3 || events[0].Action != "role.deleted" {
+		t.Fatalf("List() should return all events newest first, got %d", len(events))
+	}
+
+	events, err = s.List(ctx, audit.Filter{Actor: "alice", Action: "role.created"})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Resource != "admins" {
+		t.Errorf("List(actor, action) = %v", events)
+	}
+
+	events, err = s.List(ctx, audit.Filter{Outcome: audit.OutcomeFailure})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Error != "role not found" || events[0].Metadata["role"] != "admins" {
+		t.Errorf("List(outcome) = %+v", events)
+	}
+
+	events, err = s.List(ctx, audit.Filter{Since: base.Add(time.Hour), Limit: 1})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Action != "role.deleted" {
+		t.Errorf("List(since, limit) = %+v", events)
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := NewStore(db)
+	ctx := context.Background()
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		event := audit.NewEvent("alice", "auth.signin", "alice")
+		event.At = base.Add(time.Duration(i) * time.Hour)
+		if err := s.Append(ctx, event); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	deleted, err := s.Prune(ctx, base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Prune() = %d, want 2", deleted)
+	}
+}
+
+func TestStorePing(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	if err := store.Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+
+	db.Close()
+	if err := store.Ping(context.Background()); err == nil {
+		t.Error("Ping() should fail once the database is closed")
+	}
+}
@@ -0,0 +1,47 @@
+package auth
+
+import "strings"
+
+// PermissionManageGrants prefixes the permissions that let administrators
+// assign and revoke roles: grants:manage:<role> covers the role named
+// <role>. The last segment may end in '*' to cover every role whose name
+// starts with what precedes it, so roles of a tenant or group sharing a
+// prefix, such as acme-editor and acme-viewer, are covered by
+// grants:manage:acme-*. grants:manage:* covers every role.
+const PermissionManageGrants = "grants:manage"
+
+// ManageGrantsPermission returns the permission needed to assign and
+// revoke the role named name.
+func ManageGrantsPermission(name string) string {
+	return PermissionManageGrants + ":" + name
+}
+
+// ManagesRole reports whether permissions allow assigning and revoking the
+// role named name (see PermissionManageGrants).
+func ManagesRole(permissions []string, name string) bool {
+	if HasPermission(permissions, ManageGrantsPermission(name)) {
+		return true
+	}
+	prefix := PermissionManageGrants + ":"
+	for _, p := range permissions {
+		pattern, ok := strings.CutPrefix(p, prefix)
+		if !ok {
+			continue
+		}
+		if head, ok := strings.CutSuffix(pattern, "*"); ok && head != "" && strings.HasPrefix(name, head) {
+			return true
+		}
+	}
+	return false
+}
+
+// ManagesAnyRole reports whether permissions allow managing the grants of
+// at least one role.
+func ManagesAnyRole(permissions []string) bool {
+	for _, p := range permissions {
+		if strings.HasPrefix(p, PermissionManageGrants+":") || Permission(p).Matches(Permission(ManageGrantsPermission("*"))) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package auth
+
+import "testing"
+
+func TestManagesRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions []string
+		role        string
+		want        bool
+	}{
+		{"superadmin", []string{"*"}, "editor", true},
+		{"every role", []string{"grants:manage:*"}, "editor", true},
+		{"every grant permission", []string{"grants:*:*"}, "editor", true},
+		{"named role", []string{"grants:manage:editor"}, "editor", true},
+		{"other role", []string{"grants:manage:editor"}, "admin", false},
+		{"group prefix", []string{"grants:manage:acme-*"}, "acme-viewer", true},
+		{"other group", []string{"grants:manage:acme-*"}, "globex-viewer", false},
+		{"prefix is not a substring match", []string{"grants:manage:acme-*"}, "acme", false},
+		{"unrelated permissions", []string{"roles:read", "grants:read"}, "editor", false},
+		{"none", nil, "editor", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ManagesRole(tt.permissions, tt.role); got != tt.want {
+				t.Errorf("ManagesRole(%v, %q) = %v, want %v", tt.permissions, tt.role, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagesAnyRole(t *testing.T) {
+	for _, perms := range [][]string{{"*"}, {"grants:*:*"}, {"grants:manage:*"}, {"grants:manage:acme-*"}, {"grants:manage:editor"}} {
+		if !ManagesAnyRole(perms) {
+			t.Errorf("ManagesAnyRole(%v) = false", perms)
+		}
+	}
+	for _, perms := range [][]string{nil, {"grants:read"}, {"grants:manage"}, {"roles:*"}} {
+		if ManagesAnyRole(perms) {
+			t.Errorf("ManagesAnyRole(%v) = true", perms)
+		}
+	}
+}
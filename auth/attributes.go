@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"strconv"
+)
+
+// Limits on the custom attributes stored with a user.
+const (
+	MaxAttributes        = 64
+	MaxAttributeKeyLen   = 64
+	MaxAttributesEncoded = 16 << 10
+)
+
+// Attributes holds custom, product-specific user data such as an avatar URL,
+// locale or timezone. Values are anything JSON can represent; after a round
+// trip through a store numbers come back as float64, so read them with the
+// typed accessors rather than type assertions.
+type Attributes map[string]any
+
+// Get returns the value stored under key.
+func (a Attributes) Get(key string) (any, bool) {
+	v, ok := a[key]
+	return v, ok
+}
+
+// String returns the value under key if it is a string.
+func (a Attributes) String(key string) (string, bool) {
+	s, ok := a[key].(string)
+	return s, ok
+}
+
+// Bool returns the value under key if it is a boolean.
+func (a Attributes) Bool(key string) (bool, bool) {
+	b, ok := a[key].(bool)
+	return b, ok
+}
+
+// Int returns the value under key if it is an integral number.
+func (a Attributes) Int(key string) (int64, bool) {
+	switch v := a[key].(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v), true
+		}
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// Float returns the value under key if it is a number.
+func (a Attributes) Float(key string) (float64, bool) {
+	switch v := a[key].(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// Text returns the value under key as text: strings as they are and other
+// values in their JSON form. It is how attribute search compares values,
+// matching the postgres ->> operator. Null and missing values have no text.
+func (a Attributes) Text(key string) (string, bool) {
+	v, ok := a[key]
+	if !ok || v == nil {
+		return "", false
+	}
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// Set stores value under key. Setting a value on a nil Attributes panics, so
+// use User.SetAttribute on users.
+func (a Attributes) Set(key string, value any) {
+	a[key] = value
+}
+
+// Delete removes key.
+func (a Attributes) Delete(key string) {
+	delete(a, key)
+}
+
+// Clone returns a deep copy of a, so nested objects and arrays are not
+// shared. A nil Attributes clones to nil.
+func (a Attributes) Clone() Attributes {
+	if a == nil {
+		return nil
+	}
+	out := make(Attributes, len(a))
+	for k, v := range a {
+		out[k] = cloneAttributeValue(v)
+	}
+	return out
+}
+
+func cloneAttributeValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		return map[string]any(Attributes(v).Clone())
+	case Attributes:
+		return v.Clone()
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = cloneAttributeValue(item)
+		}
+		return out
+	}
+	return v
+}
+
+// Merge applies patch to a following JSON Merge Patch (RFC 7386): null
+// removes a key, objects merge recursively into objects and any other value
+// replaces the stored one.
+func (a Attributes) Merge(patch map[string]any) {
+	for k, v := range patch {
+		if v == nil {
+			delete(a, k)
+			continue
+		}
+		if obj, ok := v.(map[string]any); ok {
+			target, _ := a[k].(map[string]any)
+			if target == nil {
+				target = map[string]any{}
+			} else {
+				target = maps.Clone(target)
+			}
+			Attributes(target).Merge(obj)
+			a[k] = target
+			continue
+		}
+		a[k] = v
+	}
+}
+
+// ValidateAttributeKey checks that key is 1 to MaxAttributeKeyLen characters
+// of lowercase letters, digits, '_' and '-', starting with a letter. Keys end
+// up in query paths, so they are kept plain.
+func ValidateAttributeKey(key string) error {
+	if key == "" || len(key) > MaxAttributeKeyLen {
+		return fmt.Errorf("%w: key must be 1 to %d characters", ErrInvalidAttribute, MaxAttributeKeyLen)
+	}
+	for i, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '_' || r == '-'):
+		default:
+			return fmt.Errorf("%w: key %q must be lowercase letters, digits, '_' or '-' and start with a letter", ErrInvalidAttribute, key)
+		}
+	}
+	return nil
+}
+
+// ValidateAttributes checks every key, the number of attributes and their
+// encoded size, and that every value can be stored as JSON.
+func ValidateAttributes(a Attributes) error {
+	if len(a) > MaxAttributes {
+		return fmt.Errorf("%w: at most %d attributes are allowed", ErrInvalidAttribute, MaxAttributes)
+	}
+	for key := range a {
+		if err := ValidateAttributeKey(key); err != nil {
+			return err
+		}
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAttribute, err)
+	}
+	if len(b) > MaxAttributesEncoded {
+		return fmt.Errorf("%w: attributes must encode to at most %d bytes", ErrInvalidAttribute, MaxAttributesEncoded)
+	}
+	return nil
+}
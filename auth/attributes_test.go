@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAttributesAccessors(t *testing.T) {
+	var attrs Attributes
+	if err := json.Unmarshal([]byte(`{"locale":"en-GB","beta":true,"seats":5,"ratio":0.5,"prefs":{"theme":"dark"},"none":null}`), &attrs); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if s, ok := attrs.String("locale"); !ok || s != "en-GB" {
+		t.Errorf("String(locale) = %q, %v", s, ok)
+	}
+	if _, ok := attrs.String("seats"); ok {
+		t.Error("String(seats) ok for a number")
+	}
+	if b, ok := attrs.Bool("beta"); !ok || !b {
+		t.Errorf("Bool(beta) = %v, %v", b, ok)
+	}
+	if n, ok := attrs.Int("seats"); !ok || n != 5 {
+		t.Errorf("Int(seats) = %d, %v", n, ok)
+	}
+	if _, ok := attrs.Int("ratio"); ok {
+		t.Error("Int(ratio) ok for a fraction")
+	}
+	if f, ok := attrs.Float("ratio"); !ok || f != 0.5 {
+		t.Errorf("Float(ratio) = %v, %v", f, ok)
+	}
+	if _, ok := attrs.Get("missing"); ok {
+		t.Error("Get(missing) ok")
+	}
+
+	texts := map[string]string{"locale": "en-GB", "beta": "true", "seats": "5", "ratio": "0.5", "prefs": `{"theme":"dark"}`}
+	for key, want := range texts {
+		if got, ok := attrs.Text(key); !ok || got != want {
+			t.Errorf("Text(%s) = %q, %v, want %q", key, got, ok, want)
+		}
+	}
+	if _, ok := attrs.Text("none"); ok {
+		t.Error("Text(none) ok for null")
+	}
+}
+
+func TestAttributesCloneAndMerge(t *testing.T) {
+	attrs := Attributes{"locale": "en-GB", "prefs": map[string]any{"theme": "dark", "size": "m"}, "tags": []any{"a"}}
+	clone := attrs.Clone()
+
+	clone.Merge(map[string]any{
+		"locale":   nil,
+		"timezone": "UTC",
+		"prefs":    map[string]any{"size": nil, "compact": true},
+	})
+
+	want := Attributes{"timezone": "UTC", "prefs": map[string]any{"theme": "dark", "compact": true}, "tags": []any{"a"}}
+	if !reflect.DeepEqual(clone, want) {
+		t.Errorf("Merge() = %v, want %v", clone, want)
+	}
+	if prefs := attrs["prefs"].(map[string]any); len(prefs) != 2 || prefs["size"] != "m" {
+		t.Errorf("original changed: %v", attrs)
+	}
+	if Attributes(nil).Clone() != nil {
+		t.Error("Clone(nil) != nil")
+	}
+}
+
+func TestValidateAttributes(t *testing.T) {
+	many := Attributes{}
+	for i := 0; i <= MaxAttributes; i++ {
+		many[fmt.Sprintf("key%d", i)] = i
+	}
+
+	tests := []struct {
+		name    string
+		attrs   Attributes
+		wantErr bool
+	}{
+		{name: "nil", attrs: nil},
+		{name: "valid", attrs: Attributes{"avatar_url": "https://example.com/a.png", "time-zone": "UTC", "v2": 1}},
+		{name: "uppercase key", attrs: Attributes{"Locale": "en"}, wantErr: true},
+		{name: "leading digit", attrs: Attributes{"2fa": true}, wantErr: true},
+		{name: "dotted key", attrs: Attributes{"a.b": 1}, wantErr: true},
+		{name: "empty key", attrs: Attributes{"": 1}, wantErr: true},
+		{name: "long key", attrs: Attributes{strings.Repeat("a", MaxAttributeKeyLen+1): 1}, wantErr: true},
+		{name: "too many", attrs: many, wantErr: true},
+		{name: "too large", attrs: Attributes{"bio": strings.Repeat("x", MaxAttributesEncoded)}, wantErr: true},
+		{name: "not json", attrs: Attributes{"fn": func() {}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAttributes(tt.attrs)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("ValidateAttributes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidAttribute) {
+				t.Errorf("ValidateAttributes() error = %v, want ErrInvalidAttribute", err)
+			}
+		})
+	}
+}
+
+func TestUserSetAttribute(t *testing.T) {
+	user := NewUser()
+	if err := user.SetAttribute("locale", "en-GB"); err != nil {
+		t.Fatalf("SetAttribute() error = %v", err)
+	}
+	if s, _ := user.Attributes.String("locale"); s != "en-GB" {
+		t.Errorf("locale = %q", s)
+	}
+	if err := user.SetAttribute("Bad", 1); !errors.Is(err, ErrInvalidAttribute) {
+		t.Errorf("SetAttribute(Bad) error = %v, want ErrInvalidAttribute", err)
+	}
+	user.DeleteAttribute("locale")
+	if len(user.Attributes) != 0 {
+		t.Errorf("Attributes = %v after delete", user.Attributes)
+	}
+}
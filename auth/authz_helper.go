@@ -3,33 +3,75 @@ package auth
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aquamarinepk/aqm/httpclient"
 	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/telemetry"
+)
+
+// Cache metric names reported through telemetry.Metrics.
+const (
+	MetricAuthzCacheHit   = "authz_cache_hit"
+	MetricAuthzCacheMiss  = "authz_cache_miss"
+	MetricAuthzCacheStale = "authz_cache_stale"
 )
 
 type AuthzHelper struct {
-	client   *httpclient.Client
-	cache    *permissionCache
-	cacheTTL time.Duration
-	log      log.Logger
+	client      *httpclient.Client
+	cache       *permissionCache
+	cacheTTL    time.Duration
+	cacheJitter float64
+	metrics     telemetry.Metrics
+	log         log.Logger
+}
+
+// AuthzHelperOption configures optional AuthzHelper behavior.
+type AuthzHelperOption func(*AuthzHelper)
+
+// WithCacheMetrics reports cache hits, misses, and stale reads as counters.
+func WithCacheMetrics(metrics telemetry.Metrics) AuthzHelperOption {
+	return func(h *AuthzHelper) {
+		if metrics != nil {
+			h.metrics = metrics
+		}
+	}
 }
 
-func NewAuthzHelper(authzURL string, cacheTTL time.Duration, log log.Logger) *AuthzHelper {
-	return &AuthzHelper{
+// WithCacheJitter randomizes each cache entry TTL by up to ±fraction of cacheTTL,
+// so entries written together don't expire together and stampede the authz service.
+// Fraction is clamped to [0, 1].
+func WithCacheJitter(fraction float64) AuthzHelperOption {
+	return func(h *AuthzHelper) {
+		h.cacheJitter = min(max(fraction, 0), 1)
+	}
+}
+
+func NewAuthzHelper(authzURL string, cacheTTL time.Duration, log log.Logger, opts ...AuthzHelperOption) *AuthzHelper {
+	h := &AuthzHelper{
 		client:   httpclient.New(authzURL, log),
 		cache:    newPermissionCache(),
 		cacheTTL: cacheTTL,
+		metrics:  telemetry.NoopMetrics{},
 		log:      log,
 	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
 }
 
 func (h *AuthzHelper) CheckPermission(ctx context.Context, userID, permission, resource string) (bool, error) {
 	cacheKey := fmt.Sprintf("%s:%s:%s", userID, permission, resource)
 
-	if cached, ok := h.cache.get(cacheKey); ok {
+	cached, state := h.cache.lookup(cacheKey)
+	h.metrics.Counter(ctx, state.metric(), 1, map[string]string{"permission": permission})
+	if state == cacheHit {
 		return cached, nil
 	}
 
@@ -49,13 +91,56 @@ func (h *AuthzHelper) CheckPermission(ctx context.Context, userID, permission, r
 		return false, fmt.Errorf("parse authz response: %w", err)
 	}
 
-	h.cache.set(cacheKey, result.Allowed, h.cacheTTL)
+	h.cache.set(cacheKey, result.Allowed, jitteredTTL(h.cacheTTL, h.cacheJitter))
 	return result.Allowed, nil
 }
 
+// CacheStats is a snapshot of the permission cache counters.
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Stale  uint64 `json:"stale"`
+}
+
+// CacheStats returns the permission cache counters accumulated since creation.
+func (h *AuthzHelper) CacheStats() CacheStats {
+	return h.cache.stats()
+}
+
+// jitteredTTL spreads ttl uniformly over [ttl-ttl*fraction, ttl+ttl*fraction].
+func jitteredTTL(ttl time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || ttl <= 0 {
+		return ttl
+	}
+	spread := float64(ttl) * fraction
+	return ttl + time.Duration((rand.Float64()*2-1)*spread)
+}
+
+type cacheState int
+
+const (
+	cacheMiss cacheState = iota
+	cacheHit
+	cacheStale
+)
+
+func (s cacheState) metric() string {
+	switch s {
+	case cacheHit:
+		return MetricAuthzCacheHit
+	case cacheStale:
+		return MetricAuthzCacheStale
+	default:
+		return MetricAuthzCacheMiss
+	}
+}
+
 type permissionCache struct {
-	mu    sync.RWMutex
-	items map[string]*cacheItem
+	mu     sync.RWMutex
+	items  map[string]*cacheItem
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	stale  atomic.Uint64
 }
 
 type cacheItem struct {
@@ -70,15 +155,36 @@ func newPermissionCache() *permissionCache {
 }
 
 func (c *permissionCache) get(key string) (bool, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	allowed, state := c.lookup(key)
+	return allowed, state == cacheHit
+}
 
+// lookup returns the cached decision and whether it was a hit, a miss,
+// or an expired (stale) entry. Each call updates the cache counters.
+func (c *permissionCache) lookup(key string) (bool, cacheState) {
+	c.mu.RLock()
 	item, ok := c.items[key]
-	if !ok || time.Now().After(item.expiresAt) {
-		return false, false
+	c.mu.RUnlock()
+
+	switch {
+	case !ok:
+		c.misses.Add(1)
+		return false, cacheMiss
+	case time.Now().After(item.expiresAt):
+		c.stale.Add(1)
+		return false, cacheStale
+	default:
+		c.hits.Add(1)
+		return item.allowed, cacheHit
 	}
+}
 
-	return item.allowed, true
+func (c *permissionCache) stats() CacheStats {
+	return CacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Stale:  c.stale.Load(),
+	}
 }
 
 func (c *permissionCache) set(key string, allowed bool, ttl time.Duration) {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -153,3 +154,99 @@ func TestPermissionCacheConcurrency(t *testing.T) {
 		<-done
 	}
 }
+
+type countingMetrics struct {
+	mu       sync.Mutex
+	counters map[string]float64
+}
+
+func (m *countingMetrics) Counter(_ context.Context, name string, value float64, _ map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counters == nil {
+		m.counters = make(map[string]float64)
+	}
+	m.counters[name] += value
+}
+
+func (m *countingMetrics) ObserveHTTPRequest(string, string, int, time.Duration) {}
+
+func TestCheckPermissionCacheMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"allowed": true}`))
+	}))
+	defer server.Close()
+
+	metrics := &countingMetrics{}
+	helper := NewAuthzHelper(server.URL, time.Minute, log.NewNoopLogger(), WithCacheMetrics(metrics))
+	ctx := context.Background()
+
+	// miss, then hit
+	for i := 0; i < 2; i++ {
+		if _, err := helper.CheckPermission(ctx, "user-1", "read", "docs"); err != nil {
+			t.Fatalf("CheckPermission() error = %v", err)
+		}
+	}
+
+	// stale: expire the entry and check again
+	helper.cache.set("user-1:read:docs", true, -time.Second)
+	if _, err := helper.CheckPermission(ctx, "user-1", "read", "docs"); err != nil {
+		t.Fatalf("CheckPermission() error = %v", err)
+	}
+
+	want := CacheStats{Hits: 1, Misses: 1, Stale: 1}
+	if got := helper.CacheStats(); got != want {
+		t.Errorf("CacheStats() = %+v, want %+v", got, want)
+	}
+
+	for _, name := range []string{MetricAuthzCacheHit, MetricAuthzCacheMiss, MetricAuthzCacheStale} {
+		if metrics.counters[name] != 1 {
+			t.Errorf("counter %s = %v, want 1", name, metrics.counters[name])
+		}
+	}
+}
+
+func TestJitteredTTL(t *testing.T) {
+	ttl := 10 * time.Minute
+
+	tests := []struct {
+		name     string
+		fraction float64
+		wantMin  time.Duration
+		wantMax  time.Duration
+	}{
+		{"no jitter", 0, ttl, ttl},
+		{"ten percent", 0.1, 9 * time.Minute, 11 * time.Minute},
+		{"full", 1, 0, 2 * ttl},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				got := jitteredTTL(ttl, tt.fraction)
+				if got < tt.wantMin || got > tt.wantMax {
+					t.Fatalf("jitteredTTL() = %v, want within [%v, %v]", got, tt.wantMin, tt.wantMax)
+				}
+			}
+		})
+	}
+}
+
+func TestWithCacheJitterClamps(t *testing.T) {
+	tests := []struct {
+		fraction float64
+		want     float64
+	}{
+		{-0.5, 0},
+		{0.25, 0.25},
+		{3, 1},
+	}
+
+	for _, tt := range tests {
+		h := NewAuthzHelper("http://localhost", time.Minute, log.NewNoopLogger(), WithCacheJitter(tt.fraction))
+		if h.cacheJitter != tt.want {
+			t.Errorf("WithCacheJitter(%v) = %v, want %v", tt.fraction, h.cacheJitter, tt.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Package cache provides caching decorators for auth stores.
+package cache
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// Defaults used by NewGrantStore.
+const (
+	DefaultTTL  = 30 * time.Second
+	DefaultSize = 10000
+)
+
+// GrantStore is an auth.GrantStore that caches the reads of another store
+// in an in-memory LRU with a TTL. Create and Delete go to the wrapped store
+// and invalidate the cached entries of the affected user and role.
+//
+// Changes made behind its back, such as edits to a role's permissions or
+// grants written by another process, are visible once the entries expire;
+// call Invalidate or Purge to see them sooner. Errors are never cached.
+type GrantStore struct {
+	store  auth.GrantStore
+	cache  *lru
+	ttl    time.Duration
+	size   int
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	stale  atomic.Uint64
+}
+
+// Option configures a GrantStore.
+type Option func(*GrantStore)
+
+// WithTTL sets how long reads are cached. Non-positive values are ignored.
+func WithTTL(ttl time.Duration) Option {
+	return func(s *GrantStore) {
+		if ttl > 0 {
+			s.ttl = ttl
+		}
+	}
+}
+
+// WithSize sets the maximum number of cached reads. Non-positive values are
+// ignored.
+func WithSize(size int) Option {
+	return func(s *GrantStore) {
+		if size > 0 {
+			s.size = size
+		}
+	}
+}
+
+// NewGrantStore wraps store with a read cache.
+func NewGrantStore(store auth.GrantStore, opts ...Option) *GrantStore {
+	s := &GrantStore{
+		store: store,
+		ttl:   DefaultTTL,
+		size:  DefaultSize,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.cache = newLRU(s.size, s.ttl)
+	return s
+}
+
+func userKey(username string) string {
+	return "user\x00" + username + "\x00"
+}
+
+func roleKey(roleID auth.RoleID) string {
+	return "role\x00" + roleID.String() + "\x00"
+}
+
+func (s *GrantStore) Create(ctx context.Context, grant *auth.Grant) error {
+	err := s.store.Create(ctx, grant)
+	s.invalidate(grant.Username, grant.RoleID)
+	return err
+}
+
+func (s *GrantStore) Delete(ctx context.Context, username string, roleID auth.RoleID) error {
+	err := s.store.Delete(ctx, username, roleID)
+	s.invalidate(username, roleID)
+	return err
+}
+
+func (s *GrantStore) GetUserGrants(ctx context.Context, username string) ([]*auth.Grant, error) {
+	return cached(s, userKey(username)+"grants", func() ([]*auth.Grant, error) {
+		return s.store.GetUserGrants(ctx, username)
+	})
+}
+
+func (s *GrantStore) GetRoleGrants(ctx context.Context, roleID auth.RoleID) ([]*auth.Grant, error) {
+	return cached(s, roleKey(roleID)+"grants", func() ([]*auth.Grant, error) {
+		return s.store.GetRoleGrants(ctx, roleID)
+	})
+}
+
+func (s *GrantStore) GetUserRoles(ctx context.Context, username string) ([]*auth.Role, error) {
+	return cached(s, userKey(username)+"roles", func() ([]*auth.Role, error) {
+		return s.store.GetUserRoles(ctx, username)
+	})
+}
+
+func (s *GrantStore) HasRole(ctx context.Context, username string, roleName string) (bool, error) {
+	key := userKey(username) + "has\x00" + roleName
+	if v, ok := s.lookup(key); ok {
+		return v.(bool), nil
+	}
+	has, err := s.store.HasRole(ctx, username, roleName)
+	if err != nil {
+		return false, err
+	}
+	s.cache.set(key, has)
+	return has, nil
+}
+
+// Ping checks the wrapped store.
+func (s *GrantStore) Ping(ctx context.Context) error {
+	return s.store.Ping(ctx)
+}
+
+// Invalidate drops every cached read for username.
+func (s *GrantStore) Invalidate(username string) {
+	s.cache.deletePrefix(userKey(username))
+}
+
+// Purge drops every cached read, for example after a role's permissions
+// change.
+func (s *GrantStore) Purge() {
+	s.cache.purge()
+}
+
+// Stats returns the cache counters accumulated since creation. Stale counts
+// reads that found an expired entry.
+func (s *GrantStore) Stats() auth.CacheStats {
+	return auth.CacheStats{
+		Hits:   s.hits.Load(),
+		Misses: s.misses.Load(),
+		Stale:  s.stale.Load(),
+	}
+}
+
+// Len returns the number of cached reads.
+func (s *GrantStore) Len() int {
+	return s.cache.len()
+}
+
+func (s *GrantStore) invalidate(username string, roleID auth.RoleID) {
+	s.cache.deletePrefix(userKey(username))
+	s.cache.deletePrefix(roleKey(roleID))
+}
+
+func (s *GrantStore) lookup(key string) (any, bool) {
+	v, found, expired := s.cache.get(key)
+	switch {
+	case found:
+		s.hits.Add(1)
+	case expired:
+		s.stale.Add(1)
+	default:
+		s.misses.Add(1)
+	}
+	return v, found
+}
+
+// cached returns a copy of the slice cached under key, loading and caching
+// it on a miss. Copies keep callers from reordering or appending to the
+// cached slice; the elements themselves are shared.
+func cached[T any](s *GrantStore, key string, load func() ([]T, error)) ([]T, error) {
+	if v, ok := s.lookup(key); ok {
+		return slices.Clone(v.([]T)), nil
+	}
+	items, err := load()
+	if err != nil {
+		return nil, err
+	}
+	s.cache.set(key, items)
+	return slices.Clone(items), nil
+}
+
+var _ auth.GrantStore = (*GrantStore)(nil)
@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+// countingStore counts the reads that reach the wrapped store.
+type countingStore struct {
+	auth.GrantStore
+	reads atomic.Int64
+	err   error
+}
+
+func (s *countingStore) GetUserGrants(ctx context.Context, username string) ([]*auth.Grant, error) {
+	s.reads.Add(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.GrantStore.GetUserGrants(ctx, username)
+}
+
+func (s *countingStore) GetRoleGrants(ctx context.Context, roleID auth.RoleID) ([]*auth.Grant, error) {
+	s.reads.Add(1)
+	return s.GrantStore.GetRoleGrants(ctx, roleID)
+}
+
+func (s *countingStore) GetUserRoles(ctx context.Context, username string) ([]*auth.Role, error) {
+	s.reads.Add(1)
+	return s.GrantStore.GetUserRoles(ctx, username)
+}
+
+func (s *countingStore) HasRole(ctx context.Context, username string, roleName string) (bool, error) {
+	s.reads.Add(1)
+	return s.GrantStore.HasRole(ctx, username, roleName)
+}
+
+func setup(t *testing.T, opts ...Option) (*GrantStore, *countingStore, *auth.Role) {
+	t.Helper()
+	roleStore := fake.NewRoleStore()
+	role := auth.NewRole()
+	role.Name = "editor"
+	if err := roleStore.Create(context.Background(), role); err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+	inner := &countingStore{GrantStore: fake.NewGrantStore(roleStore)}
+	return NewGrantStore(inner, opts...), inner, role
+}
+
+func TestNewGrantStoreDefaults(t *testing.T) {
+	s := NewGrantStore(fake.NewGrantStore(fake.NewRoleStore()), WithTTL(-1), WithSize(0))
+	if s.ttl != DefaultTTL || s.size != DefaultSize {
+		t.Errorf("ttl, size = %v, %v, want %v, %v", s.ttl, s.size, DefaultTTL, DefaultSize)
+	}
+}
+
+func TestGrantStoreCachesReads(t *testing.T) {
+	ctx := context.Background()
+	s, inner, role := setup(t)
+	s.Create(ctx, auth.NewGrant("alice", role.ID, "admin"))
+
+	for range 3 {
+		if has, err := s.HasRole(ctx, "alice", "editor"); err != nil || !has {
+			t.Fatalf("HasRole() = %v, %v, want true, nil", has, err)
+		}
+		if roles, err := s.GetUserRoles(ctx, "alice"); err != nil || len(roles) != 1 {
+			t.Fatalf("GetUserRoles() = %v, %v, want 1 role", roles, err)
+		}
+		if grants, err := s.GetUserGrants(ctx, "alice"); err != nil || len(grants) != 1 {
+			t.Fatalf("GetUserGrants() = %v, %v, want 1 grant", grants, err)
+		}
+		if grants, err := s.GetRoleGrants(ctx, role.ID); err != nil || len(grants) != 1 {
+			t.Fatalf("GetRoleGrants() = %v, %v, want 1 grant", grants, err)
+		}
+	}
+
+	if got := inner.reads.Load(); got != 4 {
+		t.Errorf("wrapped store reads = %d, want 4", got)
+	}
+	if stats := s.Stats(); stats.Hits != 8 || stats.Misses != 4 {
+		t.Errorf("Stats() = %+v, want 8 hits and 4 misses", stats)
+	}
+}
+
+func TestGrantStoreInvalidatesOnWrite(t *testing.T) {
+	ctx := context.Background()
+	s, _, role := setup(t)
+
+	if has, _ := s.HasRole(ctx, "alice", "editor"); has {
+		t.Fatal("HasRole() before grant = true, want false")
+	}
+	s.GetRoleGrants(ctx, role.ID)
+	s.GetUserRoles(ctx, "bob")
+
+	if err := s.Create(ctx, auth.NewGrant("alice", role.ID, "admin")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if has, _ := s.HasRole(ctx, "alice", "editor"); !has {
+		t.Error("HasRole() after Create = false, want true")
+	}
+	if grants, _ := s.GetRoleGrants(ctx, role.ID); len(grants) != 1 {
+		t.Errorf("GetRoleGrants() after Create = %d grants, want 1", len(grants))
+	}
+
+	if err := s.Delete(ctx, "alice", role.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if has, _ := s.HasRole(ctx, "alice", "editor"); has {
+		t.Error("HasRole() after Delete = true, want false")
+	}
+	if grants, _ := s.GetRoleGrants(ctx, role.ID); len(grants) != 0 {
+		t.Errorf("GetRoleGrants() after Delete = %d grants, want 0", len(grants))
+	}
+
+	// bob's entry is untouched by alice's grants.
+	before := s.Stats().Hits
+	s.GetUserRoles(ctx, "bob")
+	if s.Stats().Hits != before+1 {
+		t.Error("GetUserRoles(bob) was invalidated by a write for alice")
+	}
+}
+
+func TestGrantStoreExpires(t *testing.T) {
+	ctx := context.Background()
+	s, inner, _ := setup(t, WithTTL(time.Minute))
+	now := time.Now()
+	s.cache.now = func() time.Time { return now }
+
+	s.GetUserGrants(ctx, "alice")
+	now = now.Add(2 * time.Minute)
+	s.GetUserGrants(ctx, "alice")
+
+	if got := inner.reads.Load(); got != 2 {
+		t.Errorf("wrapped store reads = %d, want 2", got)
+	}
+	if stats := s.Stats(); stats.Stale != 1 {
+		t.Errorf("Stats().Stale = %d, want 1", stats.Stale)
+	}
+}
+
+func TestGrantStoreDoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	s, inner, _ := setup(t)
+	inner.err = errors.New("db down")
+
+	for range 2 {
+		if _, err := s.GetUserGrants(ctx, "alice"); err == nil {
+			t.Fatal("GetUserGrants() error = nil, want error")
+		}
+	}
+	if got := inner.reads.Load(); got != 2 {
+		t.Errorf("wrapped store reads = %d, want 2", got)
+	}
+	if s.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", s.Len())
+	}
+}
+
+func TestGrantStoreReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	s, _, role := setup(t)
+	s.Create(ctx, auth.NewGrant("alice", role.ID, "admin"))
+
+	grants, _ := s.GetUserGrants(ctx, "alice")
+	grants[0] = nil
+	grants, _ = s.GetUserGrants(ctx, "alice")
+	if grants[0] == nil {
+		t.Error("mutating a returned slice changed the cached entry")
+	}
+}
+
+func TestGrantStoreInvalidateAndPurge(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := setup(t)
+	s.GetUserGrants(ctx, "alice")
+	s.GetUserRoles(ctx, "alice")
+	s.GetUserGrants(ctx, "bob")
+
+	s.Invalidate("alice")
+	if s.Len() != 1 {
+		t.Errorf("Len() after Invalidate = %d, want 1", s.Len())
+	}
+	s.Purge()
+	if s.Len() != 0 {
+		t.Errorf("Len() after Purge = %d, want 0", s.Len())
+	}
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRU(2, time.Minute)
+	c.set("a", 1)
+	c.set("b", 2)
+	c.get("a")
+	c.set("c", 3)
+
+	if _, found, _ := c.get("b"); found {
+		t.Error("b should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, found, _ := c.get(key); !found {
+			t.Errorf("%s should still be cached", key)
+		}
+	}
+	if c.len() != 2 {
+		t.Errorf("len() = %d, want 2", c.len())
+	}
+}
+
+func TestLRUSetRefreshesEntry(t *testing.T) {
+	c := newLRU(2, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.set("a", 1)
+	now = now.Add(50 * time.Second)
+	c.set("a", 2)
+	now = now.Add(50 * time.Second)
+
+	v, found, _ := c.get("a")
+	if !found || v != 2 {
+		t.Errorf("get(a) = %v, %v, want 2, true", v, found)
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	c := newLRU(2, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.set("a", 1)
+	now = now.Add(time.Minute + time.Second)
+
+	if _, found, expired := c.get("a"); found || !expired {
+		t.Errorf("get(a) found, expired = %v, %v, want false, true", found, expired)
+	}
+	if _, _, expired := c.get("a"); expired {
+		t.Error("expired entry should be removed after the first read")
+	}
+}
+
+func TestLRUDeletePrefix(t *testing.T) {
+	c := newLRU(10, time.Minute)
+	c.set("user\x00alice\x00grants", 1)
+	c.set("user\x00alice\x00roles", 2)
+	c.set("user\x00alicia\x00grants", 3)
+
+	c.deletePrefix("user\x00alice\x00")
+	if c.len() != 1 {
+		t.Errorf("len() = %d, want 1", c.len())
+	}
+	if _, found, _ := c.get("user\x00alicia\x00grants"); !found {
+		t.Error("deletePrefix removed an entry for a different user")
+	}
+}
@@ -0,0 +1,280 @@
+package casbin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// DefaultActor is recorded as creator of roles and grants added through
+// the adapter.
+const DefaultActor = "casbin"
+
+// ErrUnsupportedPolicy is returned for rules the role and grant tables
+// cannot hold.
+var ErrUnsupportedPolicy = errors.New("unsupported casbin policy")
+
+// Adapter stores Casbin policies in the existing role and grant tables:
+// a p rule (role, permission) is a permission on the role and a g rule
+// (username, role) is a grant. It implements persist.Adapter and
+// persist.ContextAdapter, so an enforcer loads its policy from the stores
+// and, with auto-save, writes policy changes back to them.
+type Adapter struct {
+	roles  auth.RoleStore
+	grants auth.GrantStore
+	actor  string
+}
+
+func NewAdapter(roles auth.RoleStore, grants auth.GrantStore) *Adapter {
+	return &Adapter{roles: roles, grants: grants, actor: DefaultActor}
+}
+
+// WithActor sets the name recorded as creator of roles and grants.
+func (a *Adapter) WithActor(actor string) *Adapter {
+	a.actor = actor
+	return a
+}
+
+// PolicyLines returns the stored policies as Casbin policy lines, such as
+// "p, admin, users:*" and "g, ann, admin".
+func (a *Adapter) PolicyLines(ctx context.Context) ([]string, error) {
+	rules, err := a.rules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, len(rules))
+	for i, rule := range rules {
+		lines[i] = strings.Join(rule, ", ")
+	}
+	return lines, nil
+}
+
+// rules returns the stored p rules followed by the g rules, each led by its
+// policy type. Inactive roles are left out so they grant nothing, as with
+// GrantEngine.
+func (a *Adapter) rules(ctx context.Context) ([][]string, error) {
+	roles, err := a.roles.ListByStatus(ctx, auth.RoleStatusActive)
+	if err != nil {
+		return nil, fmt.Errorf("list roles: %w", err)
+	}
+	slices.SortFunc(roles, func(x, y *auth.Role) int { return strings.Compare(x.Name, y.Name) })
+
+	var rules [][]string
+	for _, role := range roles {
+		for _, p := range role.GrantedPermissions() {
+			rules = append(rules, []string{"p", role.Name, p})
+		}
+	}
+	for _, role := range roles {
+		grants, err := a.grants.GetRoleGrants(ctx, role.ID)
+		if err != nil {
+			return nil, fmt.Errorf("list grants for role %s: %w", role.Name, err)
+		}
+		for _, g := range grants {
+			rules = append(rules, []string{"g", g.Username, role.Name})
+		}
+	}
+	return rules, nil
+}
+
+// LoadPolicyCtx loads the stored policies into m.
+func (a *Adapter) LoadPolicyCtx(ctx context.Context, m model.Model) error {
+	rules, err := a.rules(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if err := persist.LoadPolicyArray(rule, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SavePolicyCtx makes the stores hold the policies of m: missing rules are
+// added and stored rules that m lacks are removed. Roles are never deleted,
+// only left without permissions.
+func (a *Adapter) SavePolicyCtx(ctx context.Context, m model.Model) error {
+	var want [][]string
+	for _, ptype := range []string{"p", "g"} {
+		policies, err := m.GetPolicy(ptype, ptype)
+		if err != nil {
+			return err
+		}
+		for _, rule := range policies {
+			want = append(want, append([]string{ptype}, rule...))
+		}
+	}
+
+	have, err := a.rules(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rule := range have {
+		if !slices.ContainsFunc(want, func(w []string) bool { return slices.Equal(w, rule) }) {
+			if err := a.RemovePolicyCtx(ctx, rule[0], rule[0], rule[1:]); err != nil {
+				return err
+			}
+		}
+	}
+	for _, rule := range want {
+		if err := a.AddPolicyCtx(ctx, rule[0], rule[0], rule[1:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddPolicyCtx persists a p or g rule. A p rule for a role that does not
+// exist creates it; a g rule requires the role to exist. Adding a rule that
+// is already stored is a no-op.
+func (a *Adapter) AddPolicyCtx(ctx context.Context, sec, ptype string, rule []string) error {
+	if len(rule) != 2 {
+		return fmt.Errorf("%w: %s rule needs 2 fields, got %d", ErrUnsupportedPolicy, ptype, len(rule))
+	}
+
+	switch ptype {
+	case "p":
+		return a.addPermission(ctx, rule[0], rule[1])
+	case "g":
+		role, err := a.roles.GetByName(ctx, auth.NormalizeRoleName(rule[1]))
+		if err != nil {
+			return err
+		}
+		err = a.grants.Create(ctx, auth.NewGrant(rule[0], role.ID, a.actor))
+		if errors.Is(err, auth.ErrGrantAlreadyExists) {
+			return nil
+		}
+		return err
+	default:
+		return fmt.Errorf("%w: policy type %q", ErrUnsupportedPolicy, ptype)
+	}
+}
+
+// RemovePolicyCtx deletes a p or g rule. Removing a permission keeps the
+// role, even when it has no permissions left.
+func (a *Adapter) RemovePolicyCtx(ctx context.Context, sec, ptype string, rule []string) error {
+	if len(rule) != 2 {
+		return fmt.Errorf("%w: %s rule needs 2 fields, got %d", ErrUnsupportedPolicy, ptype, len(rule))
+	}
+
+	switch ptype {
+	case "p":
+		role, err := a.roles.GetByName(ctx, auth.NormalizeRoleName(rule[0]))
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(role.Permissions, rule[1]) {
+			return nil
+		}
+		role.RemovePermission(rule[1])
+		role.UpdatedBy = a.actor
+		role.BeforeUpdate()
+		return a.roles.Update(ctx, role)
+	case "g":
+		role, err := a.roles.GetByName(ctx, auth.NormalizeRoleName(rule[1]))
+		if err != nil {
+			return err
+		}
+		return a.grants.Delete(ctx, rule[0], role.ID)
+	default:
+		return fmt.Errorf("%w: policy type %q", ErrUnsupportedPolicy, ptype)
+	}
+}
+
+// RemoveFilteredPolicyCtx deletes the stored rules of ptype whose fields,
+// from fieldIndex on, equal fieldValues. An empty value matches any field.
+func (a *Adapter) RemoveFilteredPolicyCtx(ctx context.Context, sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	if ptype != "p" && ptype != "g" {
+		return fmt.Errorf("%w: policy type %q", ErrUnsupportedPolicy, ptype)
+	}
+
+	rules, err := a.rules(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule[0] == ptype && matchesFilter(rule[1:], fieldIndex, fieldValues) {
+			if err := a.RemovePolicyCtx(ctx, sec, ptype, rule[1:]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func matchesFilter(rule []string, fieldIndex int, fieldValues []string) bool {
+	if fieldIndex < 0 || fieldIndex+len(fieldValues) > len(rule) {
+		return false
+	}
+	for i, v := range fieldValues {
+		if v != "" && rule[fieldIndex+i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadPolicy is LoadPolicyCtx with a background context.
+func (a *Adapter) LoadPolicy(m model.Model) error {
+	return a.LoadPolicyCtx(context.Background(), m)
+}
+
+// SavePolicy is SavePolicyCtx with a background context.
+func (a *Adapter) SavePolicy(m model.Model) error {
+	return a.SavePolicyCtx(context.Background(), m)
+}
+
+// AddPolicy is AddPolicyCtx with a background context.
+func (a *Adapter) AddPolicy(sec, ptype string, rule []string) error {
+	return a.AddPolicyCtx(context.Background(), sec, ptype, rule)
+}
+
+// RemovePolicy is RemovePolicyCtx with a background context.
+func (a *Adapter) RemovePolicy(sec, ptype string, rule []string) error {
+	return a.RemovePolicyCtx(context.Background(), sec, ptype, rule)
+}
+
+// RemoveFilteredPolicy is RemoveFilteredPolicyCtx with a background
+// context.
+func (a *Adapter) RemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return a.RemoveFilteredPolicyCtx(context.Background(), sec, ptype, fieldIndex, fieldValues...)
+}
+
+func (a *Adapter) addPermission(ctx context.Context, roleName, permission string) error {
+	role, err := a.roles.GetByName(ctx, auth.NormalizeRoleName(roleName))
+	if errors.Is(err, auth.ErrRoleNotFound) {
+		role = auth.NewRole()
+		role.Name = roleName
+		role.Permissions = []string{permission}
+		role.CreatedBy = a.actor
+		role.UpdatedBy = a.actor
+		role.BeforeCreate()
+		if err := role.Validate(); err != nil {
+			return err
+		}
+		return a.roles.Create(ctx, role)
+	}
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(role.Permissions, permission) {
+		return nil
+	}
+	role.Permissions = append(role.Permissions, permission)
+	role.UpdatedBy = a.actor
+	role.BeforeUpdate()
+	return a.roles.Update(ctx, role)
+}
+
+var (
+	_ persist.Adapter        = (*Adapter)(nil)
+	_ persist.ContextAdapter = (*Adapter)(nil)
+)
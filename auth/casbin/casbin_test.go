@@ -0,0 +1,241 @@
+package casbin
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/casbin/casbin/v2"
+)
+
+func newTestAdapter() (*Adapter, *fake.RoleStore, *fake.GrantStore) {
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+	return NewAdapter(roles, grants), roles, grants
+}
+
+func TestAdapter(t *testing.T) {
+	ctx := context.Background()
+	a, roles, grants := newTestAdapter()
+
+	for _, rule := range [][]string{
+		{"p", "editor", "posts:*"},
+		{"p", "editor", "comments:read"},
+		{"p", "editor", "comments:read"},
+		{"p", "viewer", "posts:read"},
+		{"g", "ann", "editor"},
+		{"g", "ann", "editor"},
+		{"g", "bob", "viewer"},
+	} {
+		if err := a.AddPolicyCtx(ctx, rule[0], rule[0], rule[1:]); err != nil {
+			t.Fatalf("AddPolicyCtx(%v) error = %v", rule, err)
+		}
+	}
+
+	editor, err := roles.GetByName(ctx, "editor")
+	if err != nil {
+		t.Fatalf("GetByName() error = %v", err)
+	}
+	if editor.CreatedBy != DefaultActor {
+		t.Errorf("created_by = %q, want %q", editor.CreatedBy, DefaultActor)
+	}
+
+	lines, err := a.PolicyLines(ctx)
+	if err != nil {
+		t.Fatalf("PolicyLines() error = %v", err)
+	}
+	want := []string{
+		"p, editor, posts:*",
+		"p, editor, comments:read",
+		"p, viewer, posts:read",
+		"g, ann, editor",
+		"g, bob, viewer",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("PolicyLines() = %v, want %v", lines, want)
+	}
+
+	if err := a.RemovePolicyCtx(ctx, "p", "p", []string{"editor", "comments:read"}); err != nil {
+		t.Fatalf("RemovePolicyCtx(p) error = %v", err)
+	}
+	if err := a.RemovePolicy("g", "g", []string{"bob", "viewer"}); err != nil {
+		t.Fatalf("RemovePolicy(g) error = %v", err)
+	}
+	if ok, _ := grants.HasRole(ctx, "bob", "viewer"); ok {
+		t.Error("expected bob's viewer grant to be removed")
+	}
+	editor, _ = roles.GetByName(ctx, "editor")
+	if !reflect.DeepEqual(editor.Permissions, []string{"posts:*"}) {
+		t.Errorf("editor permissions = %v, want [posts:*]", editor.Permissions)
+	}
+}
+
+func TestAdapterErrors(t *testing.T) {
+	a, _, _ := newTestAdapter()
+
+	tests := []struct {
+		name  string
+		ptype string
+		rule  []string
+		want  error
+	}{
+		{name: "unknown type", ptype: "g2", rule: []string{"ann", "editor"}, want: ErrUnsupportedPolicy},
+		{name: "wrong arity", ptype: "p", rule: []string{"editor", "posts:read", "doc-1"}, want: ErrUnsupportedPolicy},
+		{name: "grant of missing role", ptype: "g", rule: []string{"ann", "missing"}, want: auth.ErrRoleNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := a.AddPolicy(tt.ptype, tt.ptype, tt.rule); !errors.Is(err, tt.want) {
+				t.Errorf("AddPolicy() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine(t *testing.T) {
+	ctx := context.Background()
+	a, roles, _ := newTestAdapter()
+
+	a.AddPolicy("p", "p", []string{"editor", "posts:*"})
+	a.AddPolicy("p", "p", []string{"retired", "billing:read"})
+	a.AddPolicy("g", "g", []string{"ann", "editor"})
+	a.AddPolicy("g", "g", []string{"ann", "retired"})
+
+	retired, _ := roles.GetByName(ctx, "retired")
+	retired.Status = auth.RoleStatusInactive
+	roles.Update(ctx, retired)
+
+	e, err := NewEnforcer(a)
+	if err != nil {
+		t.Fatalf("NewEnforcer() error = %v", err)
+	}
+	engine := NewEngine(e)
+
+	tests := []struct {
+		name       string
+		subject    string
+		permission string
+		want       bool
+	}{
+		{name: "wildcard", subject: "ann", permission: "posts:write", want: true},
+		{name: "inactive role", subject: "ann", permission: "billing:read", want: false},
+		{name: "no grants", subject: "bob", permission: "posts:read", want: false},
+		{name: "empty subject", subject: "", permission: "posts:read", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := engine.Authorize(ctx, tt.subject, tt.permission, "")
+			if err != nil {
+				t.Fatalf("Authorize() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Authorize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnforcerWithModelFile(t *testing.T) {
+	ctx := context.Background()
+	a, roles, grants := newTestAdapter()
+	a.AddPolicy("p", "p", []string{"viewer", "posts:read"})
+	a.AddPolicy("g", "g", []string{"bob", "viewer"})
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer() error = %v", err)
+	}
+	e.AddFunction("permMatch", MatchPermission)
+
+	if ok, err := e.Enforce("bob", "posts:read", ""); err != nil || !ok {
+		t.Errorf("Enforce(bob, posts:read) = %v, %v, want true", ok, err)
+	}
+
+	if _, err := e.AddPolicy("editor", "posts:*"); err != nil {
+		t.Fatalf("AddPolicy() error = %v", err)
+	}
+	if _, err := e.AddGroupingPolicy("ann", "editor"); err != nil {
+		t.Fatalf("AddGroupingPolicy() error = %v", err)
+	}
+	if ok, _ := grants.HasRole(ctx, "ann", "editor"); !ok {
+		t.Error("AddGroupingPolicy() was not saved as a grant")
+	}
+	if ok, err := e.Enforce("ann", "posts:write", ""); err != nil || !ok {
+		t.Errorf("Enforce(ann, posts:write) = %v, %v, want true", ok, err)
+	}
+
+	if _, err := e.DeleteUser("bob"); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if ok, _ := grants.HasRole(ctx, "bob", "viewer"); ok {
+		t.Error("DeleteUser() did not remove bob's grant")
+	}
+
+	if _, err := e.RemovePolicy("editor", "posts:*"); err != nil {
+		t.Fatalf("RemovePolicy() error = %v", err)
+	}
+	editor, _ := roles.GetByName(ctx, "editor")
+	if len(editor.Permissions) != 0 {
+		t.Errorf("editor permissions = %v, want none", editor.Permissions)
+	}
+
+	reloaded, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer() reload error = %v", err)
+	}
+	reloaded.AddFunction("permMatch", MatchPermission)
+	if ok, _ := reloaded.Enforce("ann", "posts:write", ""); ok {
+		t.Error("reloaded enforcer still allows a removed policy")
+	}
+	if ok, _ := reloaded.Enforce("bob", "posts:read", ""); ok {
+		t.Error("reloaded enforcer still allows a deleted user")
+	}
+}
+
+func TestAdapterSavePolicy(t *testing.T) {
+	ctx := context.Background()
+	a, _, grants := newTestAdapter()
+	a.AddPolicy("p", "p", []string{"viewer", "posts:read"})
+	a.AddPolicy("p", "p", []string{"editor", "posts:*"})
+	a.AddPolicy("g", "g", []string{"bob", "viewer"})
+
+	e, err := NewEnforcer(a)
+	if err != nil {
+		t.Fatalf("NewEnforcer() error = %v", err)
+	}
+	e.EnableAutoSave(false)
+	e.RemoveGroupingPolicy("bob", "viewer")
+	e.AddGroupingPolicy("ann", "editor")
+	e.RemovePolicy("viewer", "posts:read")
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("SavePolicy() error = %v", err)
+	}
+
+	lines, err := a.PolicyLines(ctx)
+	if err != nil {
+		t.Fatalf("PolicyLines() error = %v", err)
+	}
+	if want := []string{"p, editor, posts:*", "g, ann, editor"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("PolicyLines() after SavePolicy = %v, want %v", lines, want)
+	}
+	if ok, _ := grants.HasRole(ctx, "bob", "viewer"); ok {
+		t.Error("SavePolicy() kept a grant the model no longer has")
+	}
+}
+
+func TestMatchPermission(t *testing.T) {
+	if _, err := MatchPermission("posts:read"); err == nil {
+		t.Error("expected error for wrong argument count")
+	}
+	if _, err := MatchPermission("posts:read", 1); err == nil {
+		t.Error("expected error for non-string argument")
+	}
+	if got, _ := MatchPermission("posts:read", "posts:*"); got != true {
+		t.Errorf("MatchPermission() = %v, want true", got)
+	}
+}
@@ -0,0 +1,93 @@
+// Package casbin lets a Casbin enforcer make aqm authorization decisions
+// while roles and grants stay in the auth stores.
+//
+// Adapter is a Casbin persist.Adapter over the role and grant stores, and
+// NewEnforcer builds an enforcer on Model with it:
+//
+//	e, _ := aqmcasbin.NewEnforcer(aqmcasbin.NewAdapter(roles, grants))
+//	checker := middleware.NewAuthzChecker(grants).WithEngine(aqmcasbin.NewEngine(e))
+//
+// Policies added or removed through e are saved to the stores. Call
+// e.LoadPolicy to pick up changes made through the auth API.
+package casbin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+// Model is a Casbin RBAC model matching aqm semantics: p rules give a role a
+// permission, g rules grant a role to a username, and permissions match
+// with the same wildcards as auth.HasPermission.
+const Model = `[request_definition]
+r = sub, perm, res
+
+[policy_definition]
+p = sub, perm
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && permMatch(r.perm, p.perm)
+`
+
+// NewEnforcer returns an enforcer on Model, with MatchPermission registered
+// as permMatch, whose policy is loaded from and saved to adapter.
+func NewEnforcer(adapter *Adapter) (*casbin.Enforcer, error) {
+	m, err := model.NewModelFromString(Model)
+	if err != nil {
+		return nil, fmt.Errorf("parse casbin model: %w", err)
+	}
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		return nil, fmt.Errorf("create casbin enforcer: %w", err)
+	}
+	e.AddFunction("permMatch", MatchPermission)
+	return e, nil
+}
+
+// Enforcer is the part of *casbin.Enforcer the engine uses.
+type Enforcer interface {
+	Enforce(rvals ...any) (bool, error)
+}
+
+// Engine is an auth.AuthorizationEngine backed by a Casbin enforcer.
+type Engine struct {
+	enforcer Enforcer
+}
+
+func NewEngine(enforcer Enforcer) *Engine {
+	return &Engine{enforcer: enforcer}
+}
+
+// Authorize enforces the request (subject, permission, resource).
+func (e *Engine) Authorize(ctx context.Context, subject, permission, resource string) (bool, error) {
+	if subject == "" {
+		return false, nil
+	}
+	return e.enforcer.Enforce(subject, permission, resource)
+}
+
+// MatchPermission is the permMatch function used by Model. Register it with
+// enforcer.AddFunction("permMatch", MatchPermission).
+func MatchPermission(args ...any) (any, error) {
+	if len(args) != 2 {
+		return false, fmt.Errorf("permMatch: expected 2 arguments, got %d", len(args))
+	}
+	requested, ok1 := args[0].(string)
+	granted, ok2 := args[1].(string)
+	if !ok1 || !ok2 {
+		return false, fmt.Errorf("permMatch: arguments must be strings")
+	}
+	return auth.HasPermission([]string{granted}, requested), nil
+}
+
+var _ auth.AuthorizationEngine = (*Engine)(nil)
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Limits on policy names and versions.
+const (
+	MaxPolicyNameLen    = 64
+	MaxPolicyVersionLen = 64
+)
+
+// Consent records that a user accepted a version of a policy, such as the
+// terms of service or the privacy policy. Accepting a new version adds a
+// consent; earlier ones are kept as history.
+type Consent struct {
+	ID         ConsentID `json:"id" db:"id" bson:"_id"`
+	UserID     UserID    `json:"user_id" db:"user_id" bson:"user_id"`
+	Policy     string    `json:"policy" db:"policy" bson:"policy"`
+	Version    string    `json:"version" db:"version" bson:"version"`
+	AcceptedAt time.Time `json:"accepted_at" db:"accepted_at" bson:"accepted_at"`
+	RemoteIP   string    `json:"remote_ip,omitempty" db:"remote_ip" bson:"remote_ip,omitempty"`
+}
+
+// NewConsent creates userID's acceptance of version of policy.
+func NewConsent(userID UserID, policy, version string) *Consent {
+	return &Consent{
+		UserID:  userID,
+		Policy:  NormalizePolicyName(policy),
+		Version: strings.TrimSpace(version),
+	}
+}
+
+func (c *Consent) EnsureID() {
+	if c.ID.IsZero() {
+		c.ID = NewConsentID()
+	}
+}
+
+func (c *Consent) BeforeCreate() {
+	c.EnsureID()
+	if c.AcceptedAt.IsZero() {
+		c.AcceptedAt = time.Now()
+	}
+}
+
+func (c *Consent) Validate() error {
+	if err := ValidatePolicyName(c.Policy); err != nil {
+		return err
+	}
+	return ValidatePolicyVersion(c.Version)
+}
+
+// Policies maps the name of each policy users must accept to its current
+// version. Publishing a new version means changing it here: users who only
+// accepted earlier versions must accept again.
+type Policies map[string]string
+
+// Current returns the current version of policy.
+func (p Policies) Current(policy string) (string, bool) {
+	version, ok := p[NormalizePolicyName(policy)]
+	return version, ok
+}
+
+// Names returns the policy names, sorted.
+func (p Policies) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks every policy name and version.
+func (p Policies) Validate() error {
+	for name, version := range p {
+		if err := ValidatePolicyName(name); err != nil {
+			return err
+		}
+		if err := ValidatePolicyVersion(version); err != nil {
+			return fmt.Errorf("policy %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ValidatePolicyName checks that name is 1 to MaxPolicyNameLen lowercase
+// letters, digits, '-' and '_'.
+func ValidatePolicyName(name string) error {
+	if name == "" || len(name) > MaxPolicyNameLen {
+		return fmt.Errorf("%w: policy must be 1 to %d characters", ErrInvalidConsent, MaxPolicyNameLen)
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return fmt.Errorf("%w: policy may only contain lowercase letters, digits, '-' and '_'", ErrInvalidConsent)
+		}
+	}
+	return nil
+}
+
+// ValidatePolicyVersion checks that version is 1 to MaxPolicyVersionLen
+// printable characters without spaces, such as "2024-06-01" or "v3".
+func ValidatePolicyVersion(version string) error {
+	if version == "" || len(version) > MaxPolicyVersionLen {
+		return fmt.Errorf("%w: version must be 1 to %d characters", ErrInvalidConsent, MaxPolicyVersionLen)
+	}
+	for _, r := range version {
+		if !unicode.IsPrint(r) || unicode.IsSpace(r) {
+			return fmt.Errorf("%w: version must be printable and contain no spaces", ErrInvalidConsent)
+		}
+	}
+	return nil
+}
+
+// NormalizePolicyName trims and lowercases name.
+func NormalizePolicyName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
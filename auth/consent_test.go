@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConsentValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		consent *Consent
+		wantErr bool
+	}{
+		{name: "valid", consent: NewConsent(NewUserID(), " Terms ", " 2024-06-01 ")},
+		{name: "empty policy", consent: NewConsent(NewUserID(), "", "v1"), wantErr: true},
+		{name: "policy with spaces", consent: NewConsent(NewUserID(), "terms of service", "v1"), wantErr: true},
+		{name: "long policy", consent: NewConsent(NewUserID(), strings.Repeat("p", MaxPolicyNameLen+1), "v1"), wantErr: true},
+		{name: "empty version", consent: NewConsent(NewUserID(), "terms", " "), wantErr: true},
+		{name: "version with spaces", consent: NewConsent(NewUserID(), "terms", "v 1"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.consent.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidConsent) {
+				t.Errorf("Validate() error = %v, want ErrInvalidConsent", err)
+			}
+		})
+	}
+}
+
+func TestConsentBeforeCreate(t *testing.T) {
+	consent := NewConsent(NewUserID(), "Terms", "v1")
+	consent.BeforeCreate()
+
+	if consent.ID.IsZero() || consent.AcceptedAt.IsZero() {
+		t.Errorf("BeforeCreate() = %+v, want ID and AcceptedAt set", consent)
+	}
+	if consent.Policy != "terms" {
+		t.Errorf("Policy = %q, want it normalized", consent.Policy)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	policies := Policies{"terms": "v2", "privacy": "2024-01"}
+
+	if v, ok := policies.Current(" Terms "); !ok || v != "v2" {
+		t.Errorf("Current(terms) = %q, %v", v, ok)
+	}
+	if _, ok := policies.Current("cookies"); ok {
+		t.Error("Current(cookies) found an unknown policy")
+	}
+	if names := policies.Names(); len(names) != 2 || names[0] != "privacy" {
+		t.Errorf("Names() = %v", names)
+	}
+	if err := policies.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := (Policies{"Terms": "v1"}).Validate(); !errors.Is(err, ErrInvalidConsent) {
+		t.Errorf("Validate(uppercase name) error = %v", err)
+	}
+	if err := (Policies{"terms": ""}).Validate(); !errors.Is(err, ErrInvalidConsent) {
+		t.Errorf("Validate(empty version) error = %v", err)
+	}
+}
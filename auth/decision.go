@@ -0,0 +1,39 @@
+package auth
+
+// Decision effects.
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Outcomes of evaluating a single grant in a Decision trace.
+const (
+	StepMatched      = "matched"
+	StepNoMatch      = "no_match"
+	StepRoleInactive = "role_inactive"
+	StepRoleMissing  = "role_missing"
+)
+
+// Decision is the result of a policy check together with the trace that
+// explains it. The user is identified by username, the natural key of
+// grants.
+type Decision struct {
+	UserID     string         `json:"user_id"`
+	Permission string         `json:"permission"`
+	Resource   string         `json:"resource,omitempty"`
+	Allowed    bool           `json:"allowed"`
+	Effect     string         `json:"effect"`
+	Reason     string         `json:"reason"`
+	Trace      []DecisionStep `json:"trace"`
+}
+
+// DecisionStep records how one of the user's grants was evaluated.
+// MatchedBy is the role permission that satisfied the request, if any.
+type DecisionStep struct {
+	GrantID    GrantID    `json:"grant_id"`
+	RoleID     RoleID     `json:"role_id"`
+	Role       string     `json:"role,omitempty"`
+	RoleStatus RoleStatus `json:"role_status,omitempty"`
+	Outcome    string     `json:"outcome"`
+	MatchedBy  string     `json:"matched_by,omitempty"`
+}
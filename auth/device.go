@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+// Limits on the client-supplied device fields.
+const (
+	MaxDeviceFingerprintLen = 256
+	MaxDeviceNameLen        = 100
+	maxDeviceUserAgentLen   = 512
+)
+
+// Device is a browser or app a user signs in from, recognized by the
+// fingerprint its client reports. The user can mark a device as trusted
+// ("remember this device"): it then holds a long-lived trust token, only
+// the hash of which is stored, and sign-ins presenting it until
+// TrustedUntil can skip second-factor checks.
+type Device struct {
+	ID          DeviceID `json:"id" db:"id" bson:"_id"`
+	UserID      UserID   `json:"user_id" db:"user_id" bson:"user_id"`
+	Fingerprint string   `json:"fingerprint" db:"fingerprint" bson:"fingerprint"`
+	Name        string   `json:"name" db:"name" bson:"name"`
+	UserAgent   string   `json:"user_agent,omitempty" db:"user_agent" bson:"user_agent"`
+
+	LastSeenIP      string    `json:"last_seen_ip,omitempty" db:"last_seen_ip" bson:"last_seen_ip"`
+	LastSeenCountry string    `json:"last_seen_country,omitempty" db:"last_seen_country" bson:"last_seen_country,omitempty"`
+	LastSeenCity    string    `json:"last_seen_city,omitempty" db:"last_seen_city" bson:"last_seen_city,omitempty"`
+	LastSeenAt      time.Time `json:"last_seen_at" db:"last_seen_at" bson:"last_seen_at"`
+
+	TrustTokenHash []byte     `json:"-" db:"trust_token_hash" bson:"trust_token_hash,omitempty"`
+	TrustedUntil   *time.Time `json:"trusted_until,omitempty" db:"trusted_until" bson:"trusted_until,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at" bson:"created_at"`
+}
+
+// NewDevice creates an untrusted device of userID.
+func NewDevice(userID UserID, fingerprint, name string) *Device {
+	return &Device{
+		UserID:      userID,
+		Fingerprint: strings.TrimSpace(fingerprint),
+		Name:        NormalizeDeviceName(name),
+	}
+}
+
+func (d *Device) EnsureID() {
+	if d.ID.IsZero() {
+		d.ID = NewDeviceID()
+	}
+}
+
+func (d *Device) BeforeCreate() {
+	d.EnsureID()
+	now := time.Now()
+	d.CreatedAt = now
+	if d.LastSeenAt.IsZero() {
+		d.LastSeenAt = now
+	}
+}
+
+// Validate checks the client-supplied fields.
+func (d *Device) Validate() error {
+	if err := ValidateDeviceFingerprint(d.Fingerprint); err != nil {
+		return err
+	}
+	if utf8.RuneCountInString(d.Name) > MaxDeviceNameLen {
+		return fmt.Errorf("%w: name must be at most %d characters", ErrInvalidDevice, MaxDeviceNameLen)
+	}
+	return nil
+}
+
+// Touch records that the device was seen at at, from ip, with userAgent.
+func (d *Device) Touch(ip, userAgent string, at time.Time) {
+	d.LastSeenIP = ip
+	if userAgent != "" {
+		d.UserAgent = truncate(userAgent, maxDeviceUserAgentLen)
+	}
+	d.LastSeenAt = at
+}
+
+// Trust generates a new trust token, valid until now plus ttl, replacing
+// any previous one, and returns it. As with service account secrets only
+// its hash is kept.
+func (d *Device) Trust(ttl time.Duration, now time.Time) (string, error) {
+	secret, err := crypto.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	until := now.Add(ttl)
+	d.TrustTokenHash = hashSecret(secret)
+	d.TrustedUntil = &until
+	return secret, nil
+}
+
+// Untrust drops the device's trust token.
+func (d *Device) Untrust() {
+	d.TrustTokenHash = nil
+	d.TrustedUntil = nil
+}
+
+// Trusted reports whether the device holds a trust token valid at now.
+func (d *Device) Trusted(now time.Time) bool {
+	return len(d.TrustTokenHash) > 0 && d.TrustedUntil != nil && now.Before(*d.TrustedUntil)
+}
+
+// VerifyTrustToken reports whether secret is the device's trust token and
+// is still valid at now.
+func (d *Device) VerifyTrustToken(secret string, now time.Time) bool {
+	return d.Trusted(now) && subtle.ConstantTimeCompare(hashSecret(secret), d.TrustTokenHash) == 1
+}
+
+// ValidateDeviceFingerprint checks that fingerprint is 1 to
+// MaxDeviceFingerprintLen printable characters.
+func ValidateDeviceFingerprint(fingerprint string) error {
+	if fingerprint == "" || len(fingerprint) > MaxDeviceFingerprintLen {
+		return fmt.Errorf("%w: fingerprint must be 1 to %d characters", ErrInvalidDevice, MaxDeviceFingerprintLen)
+	}
+	for _, r := range fingerprint {
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("%w: fingerprint must be printable", ErrInvalidDevice)
+		}
+	}
+	return nil
+}
+
+// NormalizeDeviceName trims name and collapses inner whitespace.
+func NormalizeDeviceName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
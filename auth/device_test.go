@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeviceTrust(t *testing.T) {
+	now := time.Now()
+	device := NewDevice(NewUserID(), "fp-1", "  Ann's   laptop ")
+	device.BeforeCreate()
+
+	if device.Name != "Ann's laptop" {
+		t.Errorf("Name = %q, want it normalized", device.Name)
+	}
+	if device.Trusted(now) {
+		t.Fatal("new device is trusted")
+	}
+
+	token, err := device.Trust(time.Hour, now)
+	if err != nil {
+		t.Fatalf("Trust() error = %v", err)
+	}
+	if !device.Trusted(now) || !device.VerifyTrustToken(token, now) {
+		t.Error("trusted device does not verify its token")
+	}
+	if device.VerifyTrustToken(token+"x", now) {
+		t.Error("VerifyTrustToken() accepted a wrong token")
+	}
+	if device.VerifyTrustToken(token, now.Add(2*time.Hour)) {
+		t.Error("VerifyTrustToken() accepted an expired token")
+	}
+
+	rotated, _ := device.Trust(time.Hour, now)
+	if device.VerifyTrustToken(token, now) || !device.VerifyTrustToken(rotated, now) {
+		t.Error("Trust() did not replace the previous token")
+	}
+
+	device.Untrust()
+	if device.Trusted(now) || device.VerifyTrustToken(rotated, now) {
+		t.Error("Untrust() kept the device trusted")
+	}
+}
+
+func TestDeviceValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		device  *Device
+		wantErr bool
+	}{
+		{name: "valid", device: NewDevice(NewUserID(), "fp-1", "Phone")},
+		{name: "empty fingerprint", device: NewDevice(NewUserID(), " ", "Phone"), wantErr: true},
+		{name: "long fingerprint", device: NewDevice(NewUserID(), strings.Repeat("f", MaxDeviceFingerprintLen+1), ""), wantErr: true},
+		{name: "control characters", device: NewDevice(NewUserID(), "fp\x00", ""), wantErr: true},
+		{name: "long name", device: NewDevice(NewUserID(), "fp-1", strings.Repeat("n", MaxDeviceNameLen+1)), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.device.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidDevice) {
+				t.Errorf("Validate() error = %v, want ErrInvalidDevice", err)
+			}
+		})
+	}
+}
+
+func TestDeviceTouch(t *testing.T) {
+	device := NewDevice(NewUserID(), "fp-1", "")
+	at := time.Now()
+	device.Touch("198.51.100.7", strings.Repeat("é", 400), at)
+
+	if device.LastSeenIP != "198.51.100.7" || !device.LastSeenAt.Equal(at) {
+		t.Errorf("Touch() = %s %v", device.LastSeenIP, device.LastSeenAt)
+	}
+	if len(device.UserAgent) > maxDeviceUserAgentLen || !strings.HasPrefix(device.UserAgent, "é") || strings.ContainsRune(device.UserAgent, '�') {
+		t.Errorf("UserAgent was not truncated on a rune boundary: %d bytes", len(device.UserAgent))
+	}
+
+	device.Touch("198.51.100.8", "", at)
+	if device.UserAgent == "" {
+		t.Error("Touch() without a user agent cleared the previous one")
+	}
+}
@@ -0,0 +1,19 @@
+package auth
+
+// EffectivePermission is a permission a user holds, with every way they
+// come to hold it.
+type EffectivePermission struct {
+	Permission string             `json:"permission"`
+	Sources    []PermissionSource `json:"sources"`
+}
+
+// PermissionSource is one way a user holds a permission: through the role
+// of a grant, directly or from one of its permission sets. Wildcard is the
+// permission the role grants when the permission was expanded from it.
+type PermissionSource struct {
+	GrantID       GrantID `json:"grant_id"`
+	RoleID        RoleID  `json:"role_id"`
+	Role          string  `json:"role"`
+	PermissionSet string  `json:"permission_set,omitempty"`
+	Wildcard      string  `json:"wildcard,omitempty"`
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MaxEmailDomainPatterns bounds each list of EmailDomains.
+const MaxEmailDomainPatterns = 100
+
+// EmailDomains restricts which email addresses may create accounts. Each
+// pattern is a domain, matching only itself, or "*." and a domain,
+// matching its subdomains but not the domain itself. Deny wins over Allow;
+// with Allow empty every domain not denied is allowed.
+type EmailDomains struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// IsZero reports whether d restricts nothing.
+func (d EmailDomains) IsZero() bool {
+	return len(d.Allow) == 0 && len(d.Deny) == 0
+}
+
+// Allows reports whether email may create an account.
+func (d EmailDomains) Allows(email string) bool {
+	if d.IsZero() {
+		return true
+	}
+	_, domain, ok := strings.Cut(NormalizeEmail(email), "@")
+	if !ok || domain == "" {
+		return false
+	}
+	if matchesAnyEmailDomain(d.Deny, domain) {
+		return false
+	}
+	return len(d.Allow) == 0 || matchesAnyEmailDomain(d.Allow, domain)
+}
+
+// Check returns ErrEmailDomainNotAllowed when email may not create an
+// account.
+func (d EmailDomains) Check(email string) error {
+	if !d.Allows(email) {
+		return ErrEmailDomainNotAllowed
+	}
+	return nil
+}
+
+// Normalize lowercases and sorts both lists, dropping blanks and
+// duplicates.
+func (d *EmailDomains) Normalize() {
+	d.Allow = normalizeEmailDomains(d.Allow)
+	d.Deny = normalizeEmailDomains(d.Deny)
+}
+
+// Validate checks every pattern of both lists.
+func (d EmailDomains) Validate() error {
+	if err := validateEmailDomains("allow", d.Allow); err != nil {
+		return err
+	}
+	return validateEmailDomains("deny", d.Deny)
+}
+
+func validateEmailDomains(list string, patterns []string) error {
+	if len(patterns) > MaxEmailDomainPatterns {
+		return fmt.Errorf("at most %d %s email domains", MaxEmailDomainPatterns, list)
+	}
+	for _, pattern := range patterns {
+		if err := ValidateEmailDomain(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MatchEmailDomain reports whether domain matches pattern, as described
+// on EmailDomains. Both must be normalized.
+func MatchEmailDomain(pattern, domain string) bool {
+	if parent, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(domain, "."+parent)
+	}
+	return domain == pattern
+}
+
+func matchesAnyEmailDomain(patterns []string, domain string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		return MatchEmailDomain(pattern, domain)
+	})
+}
+
+func normalizeEmailDomains(patterns []string) []string {
+	normalized := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern = NormalizeEmailDomain(pattern); pattern != "" {
+			normalized = append(normalized, pattern)
+		}
+	}
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+	if len(normalized) == 0 {
+		return nil
+	}
+	return normalized
+}
+
+// NormalizeEmailDomain trims and lowercases a domain pattern, dropping a
+// leading "@".
+func NormalizeEmailDomain(pattern string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(pattern)), "@")
+}
+
+// ValidateEmailDomain checks that pattern, as normalized by
+// NormalizeEmailDomain, is a dotted domain name, optionally preceded by
+// "*." to match its subdomains.
+func ValidateEmailDomain(pattern string) error {
+	domain := strings.TrimPrefix(pattern, "*.")
+	if strings.Contains(domain, "*") || ValidateEmail("user@"+domain) != nil {
+		return fmt.Errorf("invalid email domain %q", pattern)
+	}
+	return nil
+}
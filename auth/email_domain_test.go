@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMatchEmailDomain(t *testing.T) {
+	tests := []struct {
+		pattern string
+		domain  string
+		want    bool
+	}{
+		{"example.com", "example.com", true},
+		{"example.com", "sub.example.com", false},
+		{"*.example.com", "sub.example.com", true},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "badexample.com", false},
+		{"example.com", "example.com.evil.io", false},
+	}
+	for _, tt := range tests {
+		if got := MatchEmailDomain(tt.pattern, tt.domain); got != tt.want {
+			t.Errorf("MatchEmailDomain(%q, %q) = %v, want %v", tt.pattern, tt.domain, got, tt.want)
+		}
+	}
+}
+
+func TestEmailDomainsAllows(t *testing.T) {
+	domains := EmailDomains{
+		Allow: []string{"example.com", "*.example.com"},
+		Deny:  []string{"contractors.example.com"},
+	}
+	for email, want := range map[string]bool{
+		"ann@example.com":             true,
+		"Ann@EXAMPLE.com":             true,
+		"bob@eu.example.com":          true,
+		"eve@contractors.example.com": false,
+		"eve@evil.com":                false,
+		"not-an-email":                false,
+	} {
+		if got := domains.Allows(email); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", email, got, want)
+		}
+	}
+
+	denyOnly := EmailDomains{Deny: []string{"*.mailinator.com", "mailinator.com"}}
+	if !denyOnly.Allows("ann@example.com") || denyOnly.Allows("eve@mailinator.com") || denyOnly.Allows("eve@x.mailinator.com") {
+		t.Error("deny-only lists do not allow every other domain")
+	}
+	if !(EmailDomains{}).Allows("anyone@anywhere.io") {
+		t.Error("empty lists rejected an email")
+	}
+	if err := domains.Check("eve@evil.com"); !errors.Is(err, ErrEmailDomainNotAllowed) {
+		t.Errorf("Check() error = %v, want ErrEmailDomainNotAllowed", err)
+	}
+}
+
+func TestEmailDomainsNormalize(t *testing.T) {
+	domains := EmailDomains{Allow: []string{" @Example.com", "*.Example.com", "example.com", ""}, Deny: []string{" "}}
+	domains.Normalize()
+	if want := []string{"*.example.com", "example.com"}; !slices.Equal(domains.Allow, want) {
+		t.Errorf("Normalize() allow = %v, want %v", domains.Allow, want)
+	}
+	if domains.Deny != nil {
+		t.Errorf("Normalize() deny = %v, want nil", domains.Deny)
+	}
+}
+
+func TestEmailDomainsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		domains EmailDomains
+		wantErr bool
+	}{
+		{"empty", EmailDomains{}, false},
+		{"valid", EmailDomains{Allow: []string{"example.com", "*.example.com"}, Deny: []string{"evil.com"}}, false},
+		{"inner wildcard", EmailDomains{Allow: []string{"a.*.com"}}, true},
+		{"bare wildcard", EmailDomains{Deny: []string{"*"}}, true},
+		{"not a domain", EmailDomains{Deny: []string{"not a domain"}}, true},
+		{"too many", EmailDomains{Allow: make([]string, MaxEmailDomainPatterns+1)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.domains.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package auth
+
+import "context"
+
+// AuthorizationEngine decides whether subject, a username, may use
+// permission, optionally on a resource. GrantEngine, the default, evaluates
+// roles and grants; other engines such as Casbin or OPA can replace it
+// wherever one is accepted.
+type AuthorizationEngine interface {
+	Authorize(ctx context.Context, subject, permission, resource string) (bool, error)
+}
+
+// BatchAuthorizer is implemented by engines that can decide several
+// permissions for subject at once, returning one decision per permission.
+// Callers checking a list of permissions use it when the engine has it.
+type BatchAuthorizer interface {
+	AuthorizeAll(ctx context.Context, subject string, permissions []string, resource string) ([]bool, error)
+}
+
+// GrantEngine allows a permission when one of the subject's active roles
+// includes it. Resources are ignored: role permissions are global.
+type GrantEngine struct {
+	grants GrantStore
+}
+
+func NewGrantEngine(grants GrantStore) *GrantEngine {
+	return &GrantEngine{grants: grants}
+}
+
+func (e *GrantEngine) Authorize(ctx context.Context, subject, permission, resource string) (bool, error) {
+	allowed, err := e.AuthorizeAll(ctx, subject, []string{permission}, resource)
+	if err != nil {
+		return false, err
+	}
+	return allowed[0], nil
+}
+
+// AuthorizeAll decides every permission from a single read of the
+// subject's roles.
+func (e *GrantEngine) AuthorizeAll(ctx context.Context, subject string, permissions []string, resource string) ([]bool, error) {
+	allowed := make([]bool, len(permissions))
+	if subject == "" {
+		return allowed, nil
+	}
+
+	roles, err := e.grants.GetUserRoles(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, permission := range permissions {
+		for _, role := range roles {
+			if role.Status == RoleStatusActive && role.HasPermission(permission) {
+				allowed[i] = true
+				break
+			}
+		}
+	}
+	return allowed, nil
+}
+
+var (
+	_ AuthorizationEngine = (*GrantEngine)(nil)
+	_ BatchAuthorizer     = (*GrantEngine)(nil)
+)
@@ -0,0 +1,85 @@
+package auth_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestGrantEngine(t *testing.T) {
+	ctx := context.Background()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+
+	for _, r := range []struct {
+		name   string
+		perms  []string
+		status auth.RoleStatus
+	}{
+		{"editor", []string{"posts:*"}, auth.RoleStatusActive},
+		{"retired", []string{"billing:read"}, auth.RoleStatusInactive},
+	} {
+		role := auth.NewRole()
+		role.Name, role.Permissions, role.Status = r.name, r.perms, r.status
+		role.BeforeCreate()
+		roles.Create(ctx, role)
+		grants.Create(ctx, auth.NewGrant("ann", role.ID, "system"))
+	}
+
+	engine := auth.NewGrantEngine(grants)
+
+	tests := []struct {
+		name       string
+		subject    string
+		permission string
+		want       bool
+	}{
+		{name: "granted by wildcard", subject: "ann", permission: "posts:write", want: true},
+		{name: "inactive role", subject: "ann", permission: "billing:read", want: false},
+		{name: "not granted", subject: "ann", permission: "users:read", want: false},
+		{name: "unknown subject", subject: "bob", permission: "posts:write", want: false},
+		{name: "empty subject", subject: "", permission: "posts:write", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := engine.Authorize(ctx, tt.subject, tt.permission, "post-1")
+			if err != nil {
+				t.Fatalf("Authorize() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Authorize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrantEngineAuthorizeAll(t *testing.T) {
+	ctx := context.Background()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+
+	role := auth.NewRole()
+	role.Name, role.Permissions, role.Status = "editor", []string{"posts:*"}, auth.RoleStatusActive
+	role.BeforeCreate()
+	roles.Create(ctx, role)
+	grants.Create(ctx, auth.NewGrant("ann", role.ID, "system"))
+
+	engine := auth.NewGrantEngine(grants)
+
+	got, err := engine.AuthorizeAll(ctx, "ann", []string{"posts:write", "users:read", "posts:read"}, "")
+	if err != nil {
+		t.Fatalf("AuthorizeAll() error = %v", err)
+	}
+	if want := []bool{true, false, true}; !slices.Equal(got, want) {
+		t.Errorf("AuthorizeAll() = %v, want %v", got, want)
+	}
+
+	got, err = engine.AuthorizeAll(ctx, "", []string{"posts:write"}, "")
+	if err != nil || !slices.Equal(got, []bool{false}) {
+		t.Errorf("AuthorizeAll() with empty subject = %v, %v, want [false]", got, err)
+	}
+}
@@ -23,4 +23,51 @@ var (
 	ErrPasswordHashFailed        = errors.New("password hash failed")
 	ErrTokenGenerationFailed     = errors.New("token generation failed")
 	ErrTokenVerificationFailed   = errors.New("token verification failed")
+	ErrTooManyAttempts           = errors.New("too many attempts")
+	ErrAccountLocked             = errors.New("account is temporarily locked")
+	ErrServiceUnavailable        = errors.New("service temporarily unavailable")
+	ErrIdentityNotFound          = errors.New("identity not found")
+	ErrIdentityAlreadyExists     = errors.New("identity already exists")
+	ErrInvalidIdentity           = errors.New("invalid identity")
+	ErrUnverifiedEmail           = errors.New("email not verified by identity provider")
+	ErrInvalidUserQuery          = errors.New("invalid user query")
+	ErrInvalidPermission         = errors.New("invalid permission")
+	ErrUnknownPermission         = errors.New("unknown permission")
+	ErrVersionConflict           = errors.New("version conflict")
+	ErrSessionNotFound           = errors.New("session not found")
+	ErrImpersonationNotAllowed   = errors.New("impersonation not allowed")
+	ErrServiceAccountNotFound    = errors.New("service account not found")
+	ErrServiceAccountExists      = errors.New("service account already exists")
+	ErrInvalidServiceAccountName = errors.New("invalid service account name")
+	ErrInvalidAttribute          = errors.New("invalid attribute")
+	ErrInvalidMagicLink          = errors.New("invalid or expired magic link")
+	ErrDeviceNotFound            = errors.New("device not found")
+	ErrDeviceAlreadyExists       = errors.New("device already exists")
+	ErrInvalidDevice             = errors.New("invalid device")
+	ErrUntrustedDevice           = errors.New("device is not trusted")
+	ErrConsentNotFound           = errors.New("consent not found")
+	ErrConsentAlreadyExists      = errors.New("consent already exists")
+	ErrInvalidConsent            = errors.New("invalid consent")
+	ErrUnknownPolicy             = errors.New("unknown policy")
+	ErrAlreadyBootstrapped       = errors.New("system already bootstrapped")
+	ErrInvalidBootstrapToken     = errors.New("invalid bootstrap token")
+	ErrPermissionSetNotFound     = errors.New("permission set not found")
+	ErrPermissionSetExists       = errors.New("permission set already exists")
+	ErrInvalidPermissionSetName  = errors.New("invalid permission set name")
+	ErrPermissionSetInUse        = errors.New("permission set is referenced by roles")
+	ErrGrantRequestNotFound      = errors.New("grant request not found")
+	ErrGrantRequestExists        = errors.New("grant request already pending")
+	ErrGrantRequestDecided       = errors.New("grant request already decided")
+	ErrInvalidGrantRequest       = errors.New("invalid grant request")
+	ErrSelfApproval              = errors.New("requests cannot be decided by their requester")
+	ErrRoleOutOfScope            = errors.New("role is outside the administrator's scope")
+	ErrEmailInUse                = errors.New("email already in use")
+	ErrUsernameReserved          = errors.New("username was released recently")
+	ErrInvalidEmailChange        = errors.New("invalid or expired email change")
+	ErrIncorrectPassword         = errors.New("current password is incorrect")
+	ErrSettingsNotFound          = errors.New("settings not found")
+	ErrInvalidSettings           = errors.New("invalid settings")
+	ErrEmailDomainNotAllowed     = errors.New("email domain is not allowed")
+	ErrUserStatusConflict        = errors.New("user status does not allow this change")
+	ErrInvalidScope              = errors.New("invalid token scope")
 )
@@ -31,6 +31,15 @@ func TestErrors(t *testing.T) {
 		{"password hash failed", ErrPasswordHashFailed, "password hash failed"},
 		{"token generation failed", ErrTokenGenerationFailed, "token generation failed"},
 		{"token verification failed", ErrTokenVerificationFailed, "token verification failed"},
+		{"too many attempts", ErrTooManyAttempts, "too many attempts"},
+		{"account locked", ErrAccountLocked, "account is temporarily locked"},
+		{"service unavailable", ErrServiceUnavailable, "service temporarily unavailable"},
+		{"version conflict", ErrVersionConflict, "version conflict"},
+		{"session not found", ErrSessionNotFound, "session not found"},
+		{"impersonation not allowed", ErrImpersonationNotAllowed, "impersonation not allowed"},
+		{"service account not found", ErrServiceAccountNotFound, "service account not found"},
+		{"service account exists", ErrServiceAccountExists, "service account already exists"},
+		{"invalid service account name", ErrInvalidServiceAccountName, "invalid service account name"},
 	}
 
 	for _, tt := range tests {
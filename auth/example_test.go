@@ -9,7 +9,6 @@ import (
 	"github.com/aquamarinepk/aqm/auth/seed"
 	"github.com/aquamarinepk/aqm/auth/service"
 	"github.com/aquamarinepk/aqm/log"
-	"github.com/google/uuid"
 )
 
 // Example of manual wiring with fake implementations
@@ -114,7 +113,7 @@ func Example_roleBasedAccessControl() {
 
 	// Create roles with different permissions
 	adminRole := &auth.Role{
-		ID:          uuid.New(),
+		ID:          auth.NewRoleID(),
 		Name:        "admin",
 		Permissions: []string{"users:read", "users:write", "users:delete"},
 		Status:      auth.RoleStatusActive,
@@ -122,7 +121,7 @@ func Example_roleBasedAccessControl() {
 	_ = roleStore.Create(ctx, adminRole)
 
 	editorRole := &auth.Role{
-		ID:          uuid.New(),
+		ID:          auth.NewRoleID(),
 		Name:        "editor",
 		Permissions: []string{"users:read", "users:write"},
 		Status:      auth.RoleStatusActive,
@@ -201,14 +200,14 @@ func Example_userStatusManagement() {
 
 	// Create users with different statuses
 	activeUser := &auth.User{
-		ID:       uuid.New(),
+		ID:       auth.NewUserID(),
 		Username: "active_user",
 		Status:   auth.UserStatusActive,
 	}
 	_ = userStore.Create(ctx, activeUser)
 
 	suspendedUser := &auth.User{
-		ID:       uuid.New(),
+		ID:       auth.NewUserID(),
 		Username: "suspended_user",
 		Status:   auth.UserStatusSuspended,
 	}
@@ -249,7 +248,7 @@ func Example_authenticationFlow() {
 
 	// 2. Create a role
 	role := &auth.Role{
-		ID:          uuid.New(),
+		ID:          auth.NewRoleID(),
 		Name:        "member",
 		Description: "Member role",
 		Permissions: []string{"posts:read"},
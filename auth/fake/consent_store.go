@@ -0,0 +1,84 @@
+package fake
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+type ConsentStore struct {
+	mu       sync.RWMutex
+	consents map[auth.ConsentID]*auth.Consent
+}
+
+func NewConsentStore() *ConsentStore {
+	return &ConsentStore{
+		consents: make(map[auth.ConsentID]*auth.Consent),
+	}
+}
+
+func (s *ConsentStore) Create(ctx context.Context, consent *auth.Consent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.consents[consent.ID]; exists {
+		return auth.ErrConsentAlreadyExists
+	}
+	for _, existing := range s.consents {
+		if existing.UserID == consent.UserID && existing.Policy == consent.Policy && existing.Version == consent.Version {
+			return auth.ErrConsentAlreadyExists
+		}
+	}
+
+	s.consents[consent.ID] = consent
+	return nil
+}
+
+func (s *ConsentStore) Get(ctx context.Context, userID auth.UserID, policy, version string) (*auth.Consent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, consent := range s.consents {
+		if consent.UserID == userID && consent.Policy == policy && consent.Version == version {
+			return consent, nil
+		}
+	}
+	return nil, auth.ErrConsentNotFound
+}
+
+func (s *ConsentStore) ListByUser(ctx context.Context, userID auth.UserID) ([]*auth.Consent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	consents := make([]*auth.Consent, 0)
+	for _, consent := range s.consents {
+		if consent.UserID == userID {
+			consents = append(consents, consent)
+		}
+	}
+	sort.Slice(consents, func(i, j int) bool {
+		return consents[i].AcceptedAt.After(consents[j].AcceptedAt)
+	})
+	return consents, nil
+}
+
+func (s *ConsentStore) ListAccepted(ctx context.Context, policy, version string) ([]auth.UserID, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]auth.UserID, 0)
+	for _, consent := range s.consents {
+		if consent.Policy == policy && consent.Version == version {
+			users = append(users, consent.UserID)
+		}
+	}
+	return users, nil
+}
+
+func (s *ConsentStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+var _ auth.ConsentStore = (*ConsentStore)(nil)
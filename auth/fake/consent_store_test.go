@@ -0,0 +1,59 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func newTestConsent(userID auth.UserID, version string, at time.Time) *auth.Consent {
+	consent := auth.NewConsent(userID, "terms", version)
+	consent.AcceptedAt = at
+	consent.BeforeCreate()
+	return consent
+}
+
+func TestConsentStore(t *testing.T) {
+	store := NewConsentStore()
+	ctx := context.Background()
+	ann, bob := auth.NewUserID(), auth.NewUserID()
+	now := time.Now()
+
+	for _, consent := range []*auth.Consent{
+		newTestConsent(ann, "v1", now.Add(-time.Hour)),
+		newTestConsent(ann, "v2", now),
+		newTestConsent(bob, "v1", now),
+	} {
+		if err := store.Create(ctx, consent); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if err := store.Create(ctx, newTestConsent(ann, "v2", now)); err != auth.ErrConsentAlreadyExists {
+		t.Errorf("Create() duplicate error = %v, want ErrConsentAlreadyExists", err)
+	}
+
+	if got, err := store.Get(ctx, ann, "terms", "v2"); err != nil || got.UserID != ann {
+		t.Errorf("Get() = %+v, %v", got, err)
+	}
+	if _, err := store.Get(ctx, bob, "terms", "v2"); err != auth.ErrConsentNotFound {
+		t.Errorf("Get() missing error = %v, want ErrConsentNotFound", err)
+	}
+
+	consents, _ := store.ListByUser(ctx, ann)
+	if len(consents) != 2 || consents[0].Version != "v2" {
+		t.Errorf("ListByUser() = %+v, want v2 first", consents)
+	}
+
+	accepted, _ := store.ListAccepted(ctx, "terms", "v1")
+	if len(accepted) != 2 {
+		t.Errorf("ListAccepted(v1) = %v, want 2 users", accepted)
+	}
+	if accepted, _ := store.ListAccepted(ctx, "privacy", "v1"); len(accepted) != 0 {
+		t.Errorf("ListAccepted(privacy) = %v, want none", accepted)
+	}
+	if err := store.Ping(ctx); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
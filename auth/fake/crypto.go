@@ -5,22 +5,40 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
+	"strings"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/crypto"
 )
 
 type CryptoService struct {
 	encKey []byte
 	sigKey []byte
+	params crypto.PasswordParams
+	scheme auth.KeyScheme
 }
 
 func NewCryptoService() *CryptoService {
 	return &CryptoService{
 		encKey: []byte("12345678901234567890123456789012"),
 		sigKey: []byte("12345678901234567890123456789012"),
+		params: crypto.DefaultPasswordParams(),
 	}
 }
 
+// WithPasswordParams sets the parameters returned by PasswordParams.
+func (c *CryptoService) WithPasswordParams(params crypto.PasswordParams) *CryptoService {
+	c.params = params
+	return c
+}
+
+// WithKeyScheme sets the scheme returned by KeyScheme.
+func (c *CryptoService) WithKeyScheme(scheme auth.KeyScheme) *CryptoService {
+	c.scheme = scheme
+	return c
+}
+
 func (c *CryptoService) EncryptionKey() []byte {
 	return c.encKey
 }
@@ -43,16 +61,44 @@ func (c *CryptoService) ComputePINLookupHash(pin string) []byte {
 	return []byte(hash)
 }
 
+func (c *CryptoService) PasswordParams() crypto.PasswordParams {
+	return c.params
+}
+
+func (c *CryptoService) KeyScheme() auth.KeyScheme {
+	return c.scheme
+}
+
 type TokenGenerator struct{}
 
 func NewTokenGenerator() *TokenGenerator {
 	return &TokenGenerator{}
 }
 
-func (t *TokenGenerator) GenerateToken(userID uuid.UUID) (string, error) {
+func (t *TokenGenerator) GenerateToken(userID auth.UserID) (string, error) {
 	return fmt.Sprintf("token-%s", userID.String()), nil
 }
 
+func (t *TokenGenerator) GenerateTokenWithTTL(userID auth.UserID, ttl time.Duration) (string, error) {
+	return fmt.Sprintf("token-%s-ttl-%d", userID.String(), int(ttl.Seconds())), nil
+}
+
+func (t *TokenGenerator) GenerateServiceToken(subject string, ttl time.Duration) (string, error) {
+	return fmt.Sprintf("token-%s", subject), nil
+}
+
+func (t *TokenGenerator) GenerateImpersonationToken(userID auth.UserID, actor string, ttl time.Duration) (string, error) {
+	return fmt.Sprintf("token-%s-as-%s", actor, userID.String()), nil
+}
+
+func (t *TokenGenerator) GenerateMFAPendingToken(userID auth.UserID, ttl time.Duration) (string, error) {
+	return fmt.Sprintf("token-%s-mfa-pending", userID.String()), nil
+}
+
+func (t *TokenGenerator) GenerateScopedToken(parent crypto.TokenClaims, scope []string, expiresAt time.Time) (string, error) {
+	return fmt.Sprintf("token-%s-scope-%s", parent.Subject, strings.Join(scope, ",")), nil
+}
+
 type PasswordGenerator struct{}
 
 func NewPasswordGenerator() *PasswordGenerator {
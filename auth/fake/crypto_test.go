@@ -3,7 +3,7 @@ package fake
 import (
 	"testing"
 
-	"github.com/google/uuid"
+	"github.com/aquamarinepk/aqm/auth"
 )
 
 func TestNewCryptoService(t *testing.T) {
@@ -123,15 +123,15 @@ func TestNewTokenGenerator(t *testing.T) {
 func TestTokenGenerator_GenerateToken(t *testing.T) {
 	tests := []struct {
 		name   string
-		userID uuid.UUID
+		userID auth.UserID
 	}{
 		{
 			name:   "generate token for user",
-			userID: uuid.New(),
+			userID: auth.NewUserID(),
 		},
 		{
 			name:   "generate token for another user",
-			userID: uuid.New(),
+			userID: auth.NewUserID(),
 		},
 	}
 
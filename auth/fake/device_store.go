@@ -0,0 +1,116 @@
+package fake
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+type DeviceStore struct {
+	mu      sync.RWMutex
+	devices map[auth.DeviceID]*auth.Device
+}
+
+func NewDeviceStore() *DeviceStore {
+	return &DeviceStore{
+		devices: make(map[auth.DeviceID]*auth.Device),
+	}
+}
+
+func (s *DeviceStore) Create(ctx context.Context, device *auth.Device) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.devices[device.ID]; exists {
+		return auth.ErrDeviceAlreadyExists
+	}
+	for _, existing := range s.devices {
+		if existing.UserID == device.UserID && existing.Fingerprint == device.Fingerprint {
+			return auth.ErrDeviceAlreadyExists
+		}
+	}
+
+	s.devices[device.ID] = device
+	return nil
+}
+
+func (s *DeviceStore) Get(ctx context.Context, id auth.DeviceID) (*auth.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	device, exists := s.devices[id]
+	if !exists {
+		return nil, auth.ErrDeviceNotFound
+	}
+	return device, nil
+}
+
+func (s *DeviceStore) GetByFingerprint(ctx context.Context, userID auth.UserID, fingerprint string) (*auth.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, device := range s.devices {
+		if device.UserID == userID && device.Fingerprint == fingerprint {
+			return device, nil
+		}
+	}
+	return nil, auth.ErrDeviceNotFound
+}
+
+func (s *DeviceStore) Update(ctx context.Context, device *auth.Device) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.devices[device.ID]; !exists {
+		return auth.ErrDeviceNotFound
+	}
+	s.devices[device.ID] = device
+	return nil
+}
+
+func (s *DeviceStore) Delete(ctx context.Context, id auth.DeviceID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.devices[id]; !exists {
+		return auth.ErrDeviceNotFound
+	}
+	delete(s.devices, id)
+	return nil
+}
+
+func (s *DeviceStore) ListByUser(ctx context.Context, userID auth.UserID) ([]*auth.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	devices := make([]*auth.Device, 0)
+	for _, device := range s.devices {
+		if device.UserID == userID {
+			devices = append(devices, device)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].LastSeenAt.After(devices[j].LastSeenAt)
+	})
+	return devices, nil
+}
+
+func (s *DeviceStore) DeleteByUser(ctx context.Context, userID auth.UserID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, device := range s.devices {
+		if device.UserID == userID {
+			delete(s.devices, id)
+		}
+	}
+	return nil
+}
+
+func (s *DeviceStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+var _ auth.DeviceStore = (*DeviceStore)(nil)
@@ -0,0 +1,76 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func newTestDevice(userID auth.UserID, fingerprint string, seen time.Time) *auth.Device {
+	device := auth.NewDevice(userID, fingerprint, "Laptop")
+	device.LastSeenAt = seen
+	device.BeforeCreate()
+	return device
+}
+
+func TestDeviceStore(t *testing.T) {
+	store := NewDeviceStore()
+	ctx := context.Background()
+	ann, bob := auth.NewUserID(), auth.NewUserID()
+	now := time.Now()
+
+	older := newTestDevice(ann, "fp-1", now.Add(-time.Hour))
+	newer := newTestDevice(ann, "fp-2", now)
+	shared := newTestDevice(bob, "fp-1", now)
+	for _, device := range []*auth.Device{older, newer, shared} {
+		if err := store.Create(ctx, device); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if err := store.Create(ctx, newTestDevice(ann, "fp-1", now)); err != auth.ErrDeviceAlreadyExists {
+		t.Errorf("Create() duplicate fingerprint error = %v, want ErrDeviceAlreadyExists", err)
+	}
+
+	got, err := store.GetByFingerprint(ctx, bob, "fp-1")
+	if err != nil || got.ID != shared.ID {
+		t.Errorf("GetByFingerprint() = %v, %v, want bob's device", got, err)
+	}
+	if _, err := store.GetByFingerprint(ctx, bob, "fp-2"); err != auth.ErrDeviceNotFound {
+		t.Errorf("GetByFingerprint() error = %v, want ErrDeviceNotFound", err)
+	}
+
+	devices, _ := store.ListByUser(ctx, ann)
+	if len(devices) != 2 || devices[0].ID != newer.ID {
+		t.Fatalf("ListByUser() = %v, want ann's devices newest first", devices)
+	}
+
+	older.Name = "Old laptop"
+	if err := store.Update(ctx, older); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := store.Update(ctx, newTestDevice(ann, "fp-9", now)); err != auth.ErrDeviceNotFound {
+		t.Errorf("Update() missing error = %v, want ErrDeviceNotFound", err)
+	}
+
+	if err := store.Delete(ctx, older.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, older.ID); err != auth.ErrDeviceNotFound {
+		t.Errorf("Get() after Delete error = %v, want ErrDeviceNotFound", err)
+	}
+	if err := store.Delete(ctx, older.ID); err != auth.ErrDeviceNotFound {
+		t.Errorf("Delete() twice error = %v, want ErrDeviceNotFound", err)
+	}
+
+	if err := store.DeleteByUser(ctx, ann); err != nil {
+		t.Fatalf("DeleteByUser() error = %v", err)
+	}
+	if devices, _ := store.ListByUser(ctx, ann); len(devices) != 0 {
+		t.Errorf("ListByUser() after DeleteByUser = %v", devices)
+	}
+	if _, err := store.Get(ctx, shared.ID); err != nil {
+		t.Errorf("DeleteByUser() removed another user's device: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package fake
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// GrantRequestStore keeps copies of the requests, so a request decided by
+// a caller is only changed in the store by Update.
+type GrantRequestStore struct {
+	mu       sync.RWMutex
+	requests map[auth.GrantRequestID]auth.GrantRequest
+}
+
+func NewGrantRequestStore() *GrantRequestStore {
+	return &GrantRequestStore{
+		requests: make(map[auth.GrantRequestID]auth.GrantRequest),
+	}
+}
+
+func (s *GrantRequestStore) Create(ctx context.Context, req *auth.GrantRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.requests[req.ID]; exists {
+		return auth.ErrGrantRequestExists
+	}
+
+	s.requests[req.ID] = *req
+	return nil
+}
+
+func (s *GrantRequestStore) Get(ctx context.Context, id auth.GrantRequestID) (*auth.GrantRequest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	req, exists := s.requests[id]
+	if !exists {
+		return nil, auth.ErrGrantRequestNotFound
+	}
+	return &req, nil
+}
+
+// Update stores req if the stored request is still pending; otherwise it
+// returns auth.ErrGrantRequestDecided.
+func (s *GrantRequestStore) Update(ctx context.Context, req *auth.GrantRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.requests[req.ID]
+	if !exists {
+		return auth.ErrGrantRequestNotFound
+	}
+	if !current.IsPending() {
+		return auth.ErrGrantRequestDecided
+	}
+
+	s.requests[req.ID] = *req
+	return nil
+}
+
+func (s *GrantRequestStore) List(ctx context.Context, status auth.GrantRequestStatus) ([]*auth.GrantRequest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	requests := make([]*auth.GrantRequest, 0, len(s.requests))
+	for _, req := range s.requests {
+		if status == "" || req.Status == status {
+			requests = append(requests, &req)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].RequestedAt.Before(requests[j].RequestedAt)
+	})
+	return requests, nil
+}
+
+func (s *GrantRequestStore) ListByUser(ctx context.Context, username string) ([]*auth.GrantRequest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	requests := make([]*auth.GrantRequest, 0)
+	for _, req := range s.requests {
+		if req.Username == username {
+			requests = append(requests, &req)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].RequestedAt.After(requests[j].RequestedAt)
+	})
+	return requests, nil
+}
+
+func (s *GrantRequestStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+var _ auth.GrantRequestStore = (*GrantRequestStore)(nil)
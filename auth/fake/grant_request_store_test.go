@@ -0,0 +1,61 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func TestGrantRequestStore(t *testing.T) {
+	store := NewGrantRequestStore()
+	ctx := context.Background()
+
+	first := auth.NewGrantRequest("ann", auth.NewRoleID(), "", "ann")
+	second := auth.NewGrantRequest("bob", auth.NewRoleID(), "", "bob")
+	second.RequestedAt = first.RequestedAt.Add(time.Second)
+	for _, req := range []*auth.GrantRequest{second, first} {
+		if err := store.Create(ctx, req); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	req, err := store.Get(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if err := req.Decide(auth.GrantRequestApproved, "carol", "", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := store.Get(ctx, first.ID); !stored.IsPending() {
+		t.Errorf("stored request changed before Update()")
+	}
+	if err := store.Update(ctx, req); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := store.Update(ctx, req); err != auth.ErrGrantRequestDecided {
+		t.Errorf("Update() decided error = %v, want ErrGrantRequestDecided", err)
+	}
+
+	all, _ := store.List(ctx, "")
+	if len(all) != 2 || all[0].ID != first.ID {
+		t.Errorf("List() = %+v, want oldest first", all)
+	}
+	pending, _ := store.List(ctx, auth.GrantRequestPending)
+	if len(pending) != 1 || pending[0].ID != second.ID {
+		t.Errorf("List(pending) = %+v", pending)
+	}
+
+	mine, _ := store.ListByUser(ctx, "ann")
+	if len(mine) != 1 || mine[0].ID != first.ID {
+		t.Errorf("ListByUser(ann) = %+v", mine)
+	}
+	if none, err := store.ListByUser(ctx, "zoe"); err != nil || len(none) != 0 {
+		t.Errorf("ListByUser(zoe) = %+v, %v", none, err)
+	}
+
+	if _, err := store.Get(ctx, auth.NewGrantRequestID()); err != auth.ErrGrantRequestNotFound {
+		t.Errorf("Get() missing error = %v, want ErrGrantRequestNotFound", err)
+	}
+}
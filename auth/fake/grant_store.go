@@ -5,12 +5,11 @@ import (
 	"sync"
 
 	"github.com/aquamarinepk/aqm/auth"
-	"github.com/google/uuid"
 )
 
 type grantKey struct {
 	Username string
-	RoleID   uuid.UUID
+	RoleID   auth.RoleID
 }
 
 type GrantStore struct {
@@ -39,7 +38,7 @@ func (s *GrantStore) Create(ctx context.Context, grant *auth.Grant) error {
 	return nil
 }
 
-func (s *GrantStore) Delete(ctx context.Context, username string, roleID uuid.UUID) error {
+func (s *GrantStore) Delete(ctx context.Context, username string, roleID auth.RoleID) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -66,7 +65,7 @@ func (s *GrantStore) GetUserGrants(ctx context.Context, username string) ([]*aut
 	return grants, nil
 }
 
-func (s *GrantStore) GetRoleGrants(ctx context.Context, roleID uuid.UUID) ([]*auth.Grant, error) {
+func (s *GrantStore) GetRoleGrants(ctx context.Context, roleID auth.RoleID) ([]*auth.Grant, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -116,3 +115,7 @@ func (s *GrantStore) HasRole(ctx context.Context, username string, roleName stri
 
 	return false, nil
 }
+
+func (s *GrantStore) Ping(ctx context.Context) error {
+	return nil
+}
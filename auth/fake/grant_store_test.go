@@ -5,7 +5,6 @@ import (
 	"testing"
 
 	"github.com/aquamarinepk/aqm/auth"
-	"github.com/google/uuid"
 )
 
 func TestNewGrantStore(t *testing.T) {
@@ -37,7 +36,7 @@ func TestGrantStore_Create(t *testing.T) {
 			},
 			grant: &auth.Grant{
 				Username: "testuser",
-				RoleID:   uuid.New(),
+				RoleID:   auth.NewRoleID(),
 			},
 			wantErr: false,
 		},
@@ -46,7 +45,7 @@ func TestGrantStore_Create(t *testing.T) {
 			setup: func(s *GrantStore) *auth.Grant {
 				grant := &auth.Grant{
 					Username: "testuser",
-					RoleID:   uuid.New(),
+					RoleID:   auth.NewRoleID(),
 				}
 				_ = s.Create(context.Background(), grant)
 				return grant
@@ -81,14 +80,14 @@ func TestGrantStore_Create(t *testing.T) {
 func TestGrantStore_Delete(t *testing.T) {
 	tests := []struct {
 		name    string
-		setup   func(*GrantStore) (string, uuid.UUID)
+		setup   func(*GrantStore) (string, auth.RoleID)
 		wantErr bool
 	}{
 		{
 			name: "delete existing grant",
-			setup: func(s *GrantStore) (string, uuid.UUID) {
+			setup: func(s *GrantStore) (string, auth.RoleID) {
 				username := "testuser"
-				roleID := uuid.New()
+				roleID := auth.NewRoleID()
 				grant := &auth.Grant{
 					Username: username,
 					RoleID:   roleID,
@@ -100,8 +99,8 @@ func TestGrantStore_Delete(t *testing.T) {
 		},
 		{
 			name: "delete nonexistent grant",
-			setup: func(s *GrantStore) (string, uuid.UUID) {
-				return "nonexistent", uuid.New()
+			setup: func(s *GrantStore) (string, auth.RoleID) {
+				return "nonexistent", auth.NewRoleID()
 			},
 			wantErr: true,
 		},
@@ -126,9 +125,9 @@ func TestGrantStore_GetUserGrants(t *testing.T) {
 	store := NewGrantStore(roleStore)
 
 	username := "testuser"
-	grant1 := &auth.Grant{Username: username, RoleID: uuid.New()}
-	grant2 := &auth.Grant{Username: username, RoleID: uuid.New()}
-	otherGrant := &auth.Grant{Username: "otheruser", RoleID: uuid.New()}
+	grant1 := &auth.Grant{Username: username, RoleID: auth.NewRoleID()}
+	grant2 := &auth.Grant{Username: username, RoleID: auth.NewRoleID()}
+	otherGrant := &auth.Grant{Username: "otheruser", RoleID: auth.NewRoleID()}
 
 	_ = store.Create(context.Background(), grant1)
 	_ = store.Create(context.Background(), grant2)
@@ -147,10 +146,10 @@ func TestGrantStore_GetRoleGrants(t *testing.T) {
 	roleStore := NewRoleStore()
 	store := NewGrantStore(roleStore)
 
-	roleID := uuid.New()
+	roleID := auth.NewRoleID()
 	grant1 := &auth.Grant{Username: "user1", RoleID: roleID}
 	grant2 := &auth.Grant{Username: "user2", RoleID: roleID}
-	otherGrant := &auth.Grant{Username: "user3", RoleID: uuid.New()}
+	otherGrant := &auth.Grant{Username: "user3", RoleID: auth.NewRoleID()}
 
 	_ = store.Create(context.Background(), grant1)
 	_ = store.Create(context.Background(), grant2)
@@ -169,8 +168,8 @@ func TestGrantStore_GetUserRoles(t *testing.T) {
 	roleStore := NewRoleStore()
 	store := NewGrantStore(roleStore)
 
-	role1 := &auth.Role{ID: uuid.New(), Name: "admin"}
-	role2 := &auth.Role{ID: uuid.New(), Name: "editor"}
+	role1 := &auth.Role{ID: auth.NewRoleID(), Name: "admin"}
+	role2 := &auth.Role{ID: auth.NewRoleID(), Name: "editor"}
 	_ = roleStore.Create(context.Background(), role1)
 	_ = roleStore.Create(context.Background(), role2)
 
@@ -193,12 +192,12 @@ func TestGrantStore_GetUserRoles_SkipsInvalidRoles(t *testing.T) {
 	roleStore := NewRoleStore()
 	store := NewGrantStore(roleStore)
 
-	role1 := &auth.Role{ID: uuid.New(), Name: "admin"}
+	role1 := &auth.Role{ID: auth.NewRoleID(), Name: "admin"}
 	_ = roleStore.Create(context.Background(), role1)
 
 	username := "testuser"
 	grant1 := &auth.Grant{Username: username, RoleID: role1.ID}
-	grant2 := &auth.Grant{Username: username, RoleID: uuid.New()}
+	grant2 := &auth.Grant{Username: username, RoleID: auth.NewRoleID()}
 	_ = store.Create(context.Background(), grant1)
 	_ = store.Create(context.Background(), grant2)
 
@@ -215,7 +214,7 @@ func TestGrantStore_HasRole(t *testing.T) {
 	roleStore := NewRoleStore()
 	store := NewGrantStore(roleStore)
 
-	role := &auth.Role{ID: uuid.New(), Name: "admin"}
+	role := &auth.Role{ID: auth.NewRoleID(), Name: "admin"}
 	_ = roleStore.Create(context.Background(), role)
 
 	username := "testuser"
@@ -266,7 +265,7 @@ func TestGrantStore_HasRole_SkipsInvalidRoles(t *testing.T) {
 	store := NewGrantStore(roleStore)
 
 	username := "testuser"
-	grant := &auth.Grant{Username: username, RoleID: uuid.New()}
+	grant := &auth.Grant{Username: username, RoleID: auth.NewRoleID()}
 	_ = store.Create(context.Background(), grant)
 
 	hasRole, err := store.HasRole(context.Background(), username, "admin")
@@ -0,0 +1,87 @@
+package fake
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/google/uuid"
+)
+
+type identityKey struct {
+	Provider string
+	Subject  string
+}
+
+type IdentityStore struct {
+	mu         sync.RWMutex
+	identities map[uuid.UUID]*auth.Identity
+	byKey      map[identityKey]*auth.Identity
+}
+
+func NewIdentityStore() *IdentityStore {
+	return &IdentityStore{
+		identities: make(map[uuid.UUID]*auth.Identity),
+		byKey:      make(map[identityKey]*auth.Identity),
+	}
+}
+
+func (s *IdentityStore) Create(ctx context.Context, identity *auth.Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := identityKey{Provider: identity.Provider, Subject: identity.Subject}
+	if _, exists := s.byKey[key]; exists {
+		return auth.ErrIdentityAlreadyExists
+	}
+
+	s.identities[identity.ID] = identity
+	s.byKey[key] = identity
+	return nil
+}
+
+func (s *IdentityStore) GetByProviderSubject(ctx context.Context, provider, subject string) (*auth.Identity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	identity, exists := s.byKey[identityKey{Provider: provider, Subject: subject}]
+	if !exists {
+		return nil, auth.ErrIdentityNotFound
+	}
+	return identity, nil
+}
+
+func (s *IdentityStore) ListByUser(ctx context.Context, userID auth.UserID) ([]*auth.Identity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var identities []*auth.Identity
+	for _, identity := range s.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		return identities[i].CreatedAt.Before(identities[j].CreatedAt)
+	})
+	return identities, nil
+}
+
+func (s *IdentityStore) Delete(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, exists := s.identities[id]
+	if !exists {
+		return auth.ErrIdentityNotFound
+	}
+
+	delete(s.identities, id)
+	delete(s.byKey, identityKey{Provider: identity.Provider, Subject: identity.Subject})
+	return nil
+}
+
+func (s *IdentityStore) Ping(ctx context.Context) error {
+	return nil
+}
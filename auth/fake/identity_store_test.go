@@ -0,0 +1,59 @@
+package fake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func TestIdentityStore(t *testing.T) {
+	store := NewIdentityStore()
+	ctx := context.Background()
+	userID := auth.NewUserID()
+
+	google := auth.NewIdentity(userID, "google", "g-1")
+	github := auth.NewIdentity(userID, "github", "42")
+	for _, identity := range []*auth.Identity{google, github} {
+		if err := store.Create(ctx, identity); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	if err := store.Create(ctx, auth.NewIdentity(auth.NewUserID(), "google", "g-1")); err != auth.ErrIdentityAlreadyExists {
+		t.Errorf("Create() duplicate error = %v, want ErrIdentityAlreadyExists", err)
+	}
+
+	got, err := store.GetByProviderSubject(ctx, "google", "g-1")
+	if err != nil {
+		t.Fatalf("GetByProviderSubject() error = %v", err)
+	}
+	if got.ID != google.ID {
+		t.Errorf("GetByProviderSubject() ID = %v, want %v", got.ID, google.ID)
+	}
+	if _, err := store.GetByProviderSubject(ctx, "github", "g-1"); err != auth.ErrIdentityNotFound {
+		t.Errorf("GetByProviderSubject() error = %v, want ErrIdentityNotFound", err)
+	}
+
+	list, err := store.ListByUser(ctx, userID)
+	if err != nil {
+		t.Fatalf("ListByUser() error = %v", err)
+	}
+	if len(list) != 2 {
+		t.Errorf("ListByUser() len = %d, want 2", len(list))
+	}
+
+	if err := store.Delete(ctx, google.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := store.Delete(ctx, google.ID); err != auth.ErrIdentityNotFound {
+		t.Errorf("Delete() again error = %v, want ErrIdentityNotFound", err)
+	}
+	if _, err := store.GetByProviderSubject(ctx, "google", "g-1"); err != auth.ErrIdentityNotFound {
+		t.Errorf("GetByProviderSubject() after delete error = %v, want ErrIdentityNotFound", err)
+	}
+
+	if err := store.Ping(ctx); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package fake
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type NonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	now    func() time.Time
+}
+
+func NewNonceStore() *NonceStore {
+	return &NonceStore{
+		nonces: make(map[string]time.Time),
+		now:    time.Now,
+	}
+}
+
+func (s *NonceStore) Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for n, exp := range s.nonces {
+		if !now.Before(exp) {
+			delete(s.nonces, n)
+		}
+	}
+
+	if _, used := s.nonces[nonce]; used {
+		return false, nil
+	}
+	s.nonces[nonce] = expiresAt
+	return true, nil
+}
@@ -0,0 +1,29 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNonceStoreUse(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := NewNonceStore()
+	s.now = func() time.Time { return now }
+
+	if ok, err := s.Use(ctx, "n1", now.Add(time.Minute)); err != nil || !ok {
+		t.Fatalf("first Use() = %v, %v, want true", ok, err)
+	}
+	if ok, _ := s.Use(ctx, "n1", now.Add(time.Minute)); ok {
+		t.Error("second Use() = true, want false")
+	}
+	if ok, _ := s.Use(ctx, "n2", now.Add(time.Minute)); !ok {
+		t.Error("Use() of another nonce = false, want true")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if ok, _ := s.Use(ctx, "n1", now.Add(time.Minute)); !ok {
+		t.Error("Use() after expiry = false, want true")
+	}
+}
@@ -0,0 +1,93 @@
+package fake
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+type PermissionSetStore struct {
+	mu   sync.RWMutex
+	sets map[string]*auth.PermissionSet
+}
+
+func NewPermissionSetStore() *PermissionSetStore {
+	return &PermissionSetStore{
+		sets: make(map[string]*auth.PermissionSet),
+	}
+}
+
+func (s *PermissionSetStore) Create(ctx context.Context, set *auth.PermissionSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.sets[set.Name]; exists {
+		return auth.ErrPermissionSetExists
+	}
+
+	s.sets[set.Name] = set
+	return nil
+}
+
+func (s *PermissionSetStore) Get(ctx context.Context, name string) (*auth.PermissionSet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set, exists := s.sets[name]
+	if !exists {
+		return nil, auth.ErrPermissionSetNotFound
+	}
+	return set, nil
+}
+
+// Update stores set if its Version matches the stored one and increments
+// it; otherwise it returns auth.ErrVersionConflict.
+func (s *PermissionSetStore) Update(ctx context.Context, set *auth.PermissionSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.sets[set.Name]
+	if !exists {
+		return auth.ErrPermissionSetNotFound
+	}
+	if current.Version != set.Version {
+		return auth.ErrVersionConflict
+	}
+
+	set.Version++
+	s.sets[set.Name] = set
+	return nil
+}
+
+func (s *PermissionSetStore) Delete(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.sets[name]; !exists {
+		return auth.ErrPermissionSetNotFound
+	}
+	delete(s.sets, name)
+	return nil
+}
+
+func (s *PermissionSetStore) List(ctx context.Context) ([]*auth.PermissionSet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sets := make([]*auth.PermissionSet, 0, len(s.sets))
+	for _, set := range s.sets {
+		sets = append(sets, set)
+	}
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i].Name < sets[j].Name
+	})
+	return sets, nil
+}
+
+func (s *PermissionSetStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+var _ auth.PermissionSetStore = (*PermissionSetStore)(nil)
@@ -0,0 +1,52 @@
+package fake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func TestPermissionSetStore(t *testing.T) {
+	store := NewPermissionSetStore()
+	ctx := context.Background()
+
+	for _, name := range []string{"reviewer", "content-editor"} {
+		set := auth.NewPermissionSet(name, "", []string{"content:read"})
+		set.BeforeCreate()
+		if err := store.Create(ctx, set); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if err := store.Create(ctx, auth.NewPermissionSet("reviewer", "", nil)); err != auth.ErrPermissionSetExists {
+		t.Errorf("Create() duplicate error = %v, want ErrPermissionSetExists", err)
+	}
+
+	set, err := store.Get(ctx, "content-editor")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	stale := *set
+	set.Permissions = append(set.Permissions, "content:write")
+	if err := store.Update(ctx, set); err != nil || set.Version != 2 {
+		t.Fatalf("Update() = %v, version %d", err, set.Version)
+	}
+	if err := store.Update(ctx, &stale); err != auth.ErrVersionConflict {
+		t.Errorf("Update() stale error = %v, want ErrVersionConflict", err)
+	}
+
+	sets, _ := store.List(ctx)
+	if len(sets) != 2 || sets[0].Name != "content-editor" {
+		t.Errorf("List() = %+v, want content-editor first", sets)
+	}
+
+	if err := store.Delete(ctx, "reviewer"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, "reviewer"); err != auth.ErrPermissionSetNotFound {
+		t.Errorf("Get() deleted error = %v, want ErrPermissionSetNotFound", err)
+	}
+	if err := store.Delete(ctx, "reviewer"); err != auth.ErrPermissionSetNotFound {
+		t.Errorf("Delete() missing error = %v, want ErrPermissionSetNotFound", err)
+	}
+}
@@ -0,0 +1,62 @@
+package fake
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+type RevocationStore struct {
+	mu          sync.RWMutex
+	revocations []auth.Revocation
+	now         func() time.Time
+}
+
+func NewRevocationStore() *RevocationStore {
+	return &RevocationStore{now: time.Now}
+}
+
+// Create stores a copy of revocation, dropping the expired ones first.
+func (s *RevocationStore) Create(ctx context.Context, revocation *auth.Revocation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteExpired(s.now())
+	s.revocations = append(s.revocations, *revocation)
+	return nil
+}
+
+func (s *RevocationStore) IsRevoked(ctx context.Context, userID, sessionID string, issuedAt time.Time) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	for _, r := range s.revocations {
+		if !r.Expired(now) && r.Covers(userID, sessionID, issuedAt) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *RevocationStore) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteExpired(now), nil
+}
+
+func (s *RevocationStore) deleteExpired(now time.Time) int {
+	before := len(s.revocations)
+	s.revocations = slices.DeleteFunc(s.revocations, func(r auth.Revocation) bool { return r.Expired(now) })
+	return before - len(s.revocations)
+}
+
+func (s *RevocationStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+var _ auth.RevocationStore = (*RevocationStore)(nil)
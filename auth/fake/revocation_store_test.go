@@ -0,0 +1,48 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func TestRevocationStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := NewRevocationStore()
+	s.now = func() time.Time { return now }
+
+	s.Create(ctx, &auth.Revocation{UserID: "ann", SessionID: "s-1", IssuedBefore: now, ExpiresAt: now.Add(time.Hour)})
+	s.Create(ctx, &auth.Revocation{UserID: "bob", IssuedBefore: now, ExpiresAt: now.Add(2 * time.Hour)})
+
+	tests := []struct {
+		name      string
+		userID    string
+		sessionID string
+		issuedAt  time.Time
+		want      bool
+	}{
+		{"revoked session", "ann", "s-1", now.Add(-time.Minute), true},
+		{"other session", "ann", "s-2", now.Add(-time.Minute), false},
+		{"user token issued before", "bob", "s-3", now.Add(-time.Minute), true},
+		{"user token issued after", "bob", "s-4", now.Add(time.Minute), false},
+	}
+	for _, tt := range tests {
+		if got, err := s.IsRevoked(ctx, tt.userID, tt.sessionID, tt.issuedAt); err != nil || got != tt.want {
+			t.Errorf("%s: IsRevoked() = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+
+	now = now.Add(90 * time.Minute)
+	if revoked, _ := s.IsRevoked(ctx, "ann", "s-1", time.Time{}); revoked {
+		t.Error("IsRevoked() after expiry = true, want false")
+	}
+	if deleted, _ := s.DeleteExpired(ctx, now); deleted != 1 {
+		t.Errorf("DeleteExpired() = %d, want 1", deleted)
+	}
+	if revoked, _ := s.IsRevoked(ctx, "bob", "s-3", time.Time{}); !revoked {
+		t.Error("DeleteExpired() removed an unexpired revocation")
+	}
+}
@@ -5,18 +5,17 @@ import (
 	"sync"
 
 	"github.com/aquamarinepk/aqm/auth"
-	"github.com/google/uuid"
 )
 
 type RoleStore struct {
 	mu            sync.RWMutex
-	roles         map[uuid.UUID]*auth.Role
+	roles         map[auth.RoleID]*auth.Role
 	rolesByName   map[string]*auth.Role
 }
 
 func NewRoleStore() *RoleStore {
 	return &RoleStore{
-		roles:       make(map[uuid.UUID]*auth.Role),
+		roles:       make(map[auth.RoleID]*auth.Role),
 		rolesByName: make(map[string]*auth.Role),
 	}
 }
@@ -39,7 +38,7 @@ func (s *RoleStore) Create(ctx context.Context, role *auth.Role) error {
 	return nil
 }
 
-func (s *RoleStore) Get(ctx context.Context, id uuid.UUID) (*auth.Role, error) {
+func (s *RoleStore) Get(ctx context.Context, id auth.RoleID) (*auth.Role, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -63,21 +62,28 @@ func (s *RoleStore) GetByName(ctx context.Context, name string) (*auth.Role, err
 	return role, nil
 }
 
+// Update stores role if its Version matches the stored one and increments
+// it; otherwise it returns auth.ErrVersionConflict.
 func (s *RoleStore) Update(ctx context.Context, role *auth.Role) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.roles[role.ID]; !exists {
+	current, exists := s.roles[role.ID]
+	if !exists {
 		return auth.ErrRoleNotFound
 	}
+	if current.Version != role.Version {
+		return auth.ErrVersionConflict
+	}
 
+	role.Version++
 	s.roles[role.ID] = role
 	s.rolesByName[role.Name] = role
 
 	return nil
 }
 
-func (s *RoleStore) Delete(ctx context.Context, id uuid.UUID) error {
+func (s *RoleStore) Delete(ctx context.Context, id auth.RoleID) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -87,6 +93,7 @@ func (s *RoleStore) Delete(ctx context.Context, id uuid.UUID) error {
 	}
 
 	role.Status = "deleted"
+	role.Version++
 	return nil
 }
 
@@ -115,3 +122,7 @@ func (s *RoleStore) ListByStatus(ctx context.Context, status auth.RoleStatus) ([
 
 	return roles, nil
 }
+
+func (s *RoleStore) Ping(ctx context.Context) error {
+	return nil
+}
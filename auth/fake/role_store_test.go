@@ -2,10 +2,10 @@ package fake
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/aquamarinepk/aqm/auth"
-	"github.com/google/uuid"
 )
 
 func TestNewRoleStore(t *testing.T) {
@@ -35,7 +35,7 @@ func TestRoleStore_Create(t *testing.T) {
 				return nil
 			},
 			role: &auth.Role{
-				ID:   uuid.New(),
+				ID:   auth.NewRoleID(),
 				Name: "admin",
 			},
 			wantErr: false,
@@ -44,7 +44,7 @@ func TestRoleStore_Create(t *testing.T) {
 			name: "duplicate role ID",
 			setup: func(s *RoleStore) *auth.Role {
 				existing := &auth.Role{
-					ID:   uuid.New(),
+					ID:   auth.NewRoleID(),
 					Name: "existing",
 				}
 				_ = s.Create(context.Background(), existing)
@@ -58,14 +58,14 @@ func TestRoleStore_Create(t *testing.T) {
 			name: "duplicate role name",
 			setup: func(s *RoleStore) *auth.Role {
 				existing := &auth.Role{
-					ID:   uuid.New(),
+					ID:   auth.NewRoleID(),
 					Name: "duplicate",
 				}
 				_ = s.Create(context.Background(), existing)
 				return nil
 			},
 			role: &auth.Role{
-				ID:   uuid.New(),
+				ID:   auth.NewRoleID(),
 				Name: "duplicate",
 			},
 			wantErr: true,
@@ -99,14 +99,14 @@ func TestRoleStore_Create(t *testing.T) {
 func TestRoleStore_Get(t *testing.T) {
 	store := NewRoleStore()
 	role := &auth.Role{
-		ID:   uuid.New(),
+		ID:   auth.NewRoleID(),
 		Name: "admin",
 	}
 	_ = store.Create(context.Background(), role)
 
 	tests := []struct {
 		name    string
-		id      uuid.UUID
+		id      auth.RoleID
 		wantErr bool
 	}{
 		{
@@ -116,7 +116,7 @@ func TestRoleStore_Get(t *testing.T) {
 		},
 		{
 			name:    "nonexistent role",
-			id:      uuid.New(),
+			id:      auth.NewRoleID(),
 			wantErr: true,
 		},
 	}
@@ -138,7 +138,7 @@ func TestRoleStore_Get(t *testing.T) {
 func TestRoleStore_GetByName(t *testing.T) {
 	store := NewRoleStore()
 	role := &auth.Role{
-		ID:   uuid.New(),
+		ID:   auth.NewRoleID(),
 		Name: "admin",
 	}
 	_ = store.Create(context.Background(), role)
@@ -185,7 +185,7 @@ func TestRoleStore_Update(t *testing.T) {
 			name: "update existing role",
 			setup: func(s *RoleStore) *auth.Role {
 				role := &auth.Role{
-					ID:   uuid.New(),
+					ID:   auth.NewRoleID(),
 					Name: "admin",
 				}
 				_ = s.Create(context.Background(), role)
@@ -200,7 +200,7 @@ func TestRoleStore_Update(t *testing.T) {
 			name: "update nonexistent role",
 			setup: func(s *RoleStore) *auth.Role {
 				return &auth.Role{
-					ID:   uuid.New(),
+					ID:   auth.NewRoleID(),
 					Name: "nonexistent",
 				}
 			},
@@ -223,17 +223,39 @@ func TestRoleStore_Update(t *testing.T) {
 	}
 }
 
+func TestRoleStore_UpdateVersion(t *testing.T) {
+	store := NewRoleStore()
+	ctx := context.Background()
+
+	r := &auth.Role{ID: auth.NewRoleID(), Name: "admin", Version: 1}
+	if err := store.Create(ctx, r); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	stale := *r
+
+	if err := store.Update(ctx, r); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if r.Version != 2 {
+		t.Errorf("Version = %d, want 2", r.Version)
+	}
+
+	if err := store.Update(ctx, &stale); !errors.Is(err, auth.ErrVersionConflict) {
+		t.Errorf("Update() with stale version error = %v, want %v", err, auth.ErrVersionConflict)
+	}
+}
+
 func TestRoleStore_Delete(t *testing.T) {
 	tests := []struct {
 		name    string
-		setup   func(*RoleStore) uuid.UUID
+		setup   func(*RoleStore) auth.RoleID
 		wantErr bool
 	}{
 		{
 			name: "delete existing role",
-			setup: func(s *RoleStore) uuid.UUID {
+			setup: func(s *RoleStore) auth.RoleID {
 				role := &auth.Role{
-					ID:     uuid.New(),
+					ID:     auth.NewRoleID(),
 					Name:   "admin",
 					Status: auth.RoleStatusActive,
 				}
@@ -244,8 +266,8 @@ func TestRoleStore_Delete(t *testing.T) {
 		},
 		{
 			name: "delete nonexistent role",
-			setup: func(s *RoleStore) uuid.UUID {
-				return uuid.New()
+			setup: func(s *RoleStore) auth.RoleID {
+				return auth.NewRoleID()
 			},
 			wantErr: true,
 		},
@@ -275,8 +297,8 @@ func TestRoleStore_Delete(t *testing.T) {
 func TestRoleStore_List(t *testing.T) {
 	store := NewRoleStore()
 
-	role1 := &auth.Role{ID: uuid.New(), Name: "admin"}
-	role2 := &auth.Role{ID: uuid.New(), Name: "editor"}
+	role1 := &auth.Role{ID: auth.NewRoleID(), Name: "admin"}
+	role2 := &auth.Role{ID: auth.NewRoleID(), Name: "editor"}
 	_ = store.Create(context.Background(), role1)
 	_ = store.Create(context.Background(), role2)
 
@@ -292,8 +314,8 @@ func TestRoleStore_List(t *testing.T) {
 func TestRoleStore_ListByStatus(t *testing.T) {
 	store := NewRoleStore()
 
-	activeRole := &auth.Role{ID: uuid.New(), Name: "active", Status: auth.RoleStatusActive}
-	inactiveRole := &auth.Role{ID: uuid.New(), Name: "inactive", Status: auth.RoleStatusInactive}
+	activeRole := &auth.Role{ID: auth.NewRoleID(), Name: "active", Status: auth.RoleStatusActive}
+	inactiveRole := &auth.Role{ID: auth.NewRoleID(), Name: "inactive", Status: auth.RoleStatusInactive}
 	_ = store.Create(context.Background(), activeRole)
 	_ = store.Create(context.Background(), inactiveRole)
 
@@ -0,0 +1,116 @@
+package fake
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/google/uuid"
+)
+
+type ServiceAccountStore struct {
+	mu         sync.RWMutex
+	accounts   map[uuid.UUID]*auth.ServiceAccount
+	byClientID map[string]*auth.ServiceAccount
+}
+
+func NewServiceAccountStore() *ServiceAccountStore {
+	return &ServiceAccountStore{
+		accounts:   make(map[uuid.UUID]*auth.ServiceAccount),
+		byClientID: make(map[string]*auth.ServiceAccount),
+	}
+}
+
+func (s *ServiceAccountStore) Create(ctx context.Context, account *auth.ServiceAccount) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.accounts[account.ID]; exists {
+		return auth.ErrServiceAccountExists
+	}
+	if _, exists := s.byClientID[account.ClientID]; exists {
+		return auth.ErrServiceAccountExists
+	}
+	for _, existing := range s.accounts {
+		if existing.Name == account.Name {
+			return auth.ErrServiceAccountExists
+		}
+	}
+
+	s.accounts[account.ID] = account
+	s.byClientID[account.ClientID] = account
+	return nil
+}
+
+func (s *ServiceAccountStore) Get(ctx context.Context, id uuid.UUID) (*auth.ServiceAccount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	account, exists := s.accounts[id]
+	if !exists {
+		return nil, auth.ErrServiceAccountNotFound
+	}
+	return account, nil
+}
+
+func (s *ServiceAccountStore) GetByClientID(ctx context.Context, clientID string) (*auth.ServiceAccount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	account, exists := s.byClientID[clientID]
+	if !exists {
+		return nil, auth.ErrServiceAccountNotFound
+	}
+	return account, nil
+}
+
+func (s *ServiceAccountStore) Update(ctx context.Context, account *auth.ServiceAccount) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.accounts[account.ID]
+	if !exists {
+		return auth.ErrServiceAccountNotFound
+	}
+
+	delete(s.byClientID, current.ClientID)
+	s.accounts[account.ID] = account
+	s.byClientID[account.ClientID] = account
+	return nil
+}
+
+func (s *ServiceAccountStore) Delete(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, exists := s.accounts[id]
+	if !exists {
+		return auth.ErrServiceAccountNotFound
+	}
+
+	delete(s.accounts, id)
+	delete(s.byClientID, account.ClientID)
+	return nil
+}
+
+// List returns every service account ordered by name.
+func (s *ServiceAccountStore) List(ctx context.Context) ([]*auth.ServiceAccount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	accounts := make([]*auth.ServiceAccount, 0, len(s.accounts))
+	for _, account := range s.accounts {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Name < accounts[j].Name
+	})
+	return accounts, nil
+}
+
+func (s *ServiceAccountStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+var _ auth.ServiceAccountStore = (*ServiceAccountStore)(nil)
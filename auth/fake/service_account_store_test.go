@@ -0,0 +1,64 @@
+package fake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/google/uuid"
+)
+
+func newTestServiceAccount(name string) *auth.ServiceAccount {
+	account := auth.NewServiceAccount()
+	account.Name = name
+	account.BeforeCreate()
+	return account
+}
+
+func TestServiceAccountStore(t *testing.T) {
+	store := NewServiceAccountStore()
+	ctx := context.Background()
+
+	worker := newTestServiceAccount("worker")
+	billing := newTestServiceAccount("billing")
+	for _, account := range []*auth.ServiceAccount{worker, billing} {
+		if err := store.Create(ctx, account); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	if err := store.Create(ctx, newTestServiceAccount("worker")); err != auth.ErrServiceAccountExists {
+		t.Errorf("Create() duplicate name error = %v, want ErrServiceAccountExists", err)
+	}
+
+	got, err := store.GetByClientID(ctx, worker.ClientID)
+	if err != nil || got.ID != worker.ID {
+		t.Fatalf("GetByClientID() = %v, %v, want worker", got, err)
+	}
+	if _, err := store.Get(ctx, uuid.New()); err != auth.ErrServiceAccountNotFound {
+		t.Errorf("Get() error = %v, want ErrServiceAccountNotFound", err)
+	}
+
+	list, _ := store.List(ctx)
+	if len(list) != 2 || list[0].Name != "billing" || list[1].Name != "worker" {
+		t.Errorf("List() = %v, want billing then worker", list)
+	}
+
+	worker.Status = auth.ServiceAccountStatusDisabled
+	if err := store.Update(ctx, worker); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got, _ := store.Get(ctx, worker.ID); got.Status != auth.ServiceAccountStatusDisabled {
+		t.Errorf("Update() status = %v, want disabled", got.Status)
+	}
+
+	if err := store.Delete(ctx, worker.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.GetByClientID(ctx, worker.ClientID); err != auth.ErrServiceAccountNotFound {
+		t.Errorf("GetByClientID() after Delete() error = %v, want ErrServiceAccountNotFound", err)
+	}
+	if err := store.Delete(ctx, worker.ID); err != auth.ErrServiceAccountNotFound {
+		t.Errorf("Delete() twice error = %v, want ErrServiceAccountNotFound", err)
+	}
+}
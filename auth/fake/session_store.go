@@ -0,0 +1,87 @@
+package fake
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+type SessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*auth.Session
+	now      func() time.Time
+}
+
+func NewSessionStore() *SessionStore {
+	return &SessionStore{
+		sessions: make(map[string]*auth.Session),
+		now:      time.Now,
+	}
+}
+
+func (s *SessionStore) Create(ctx context.Context, session *auth.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *SessionStore) Get(ctx context.Context, id string) (*auth.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.sessions[id]
+	if !exists || session.Expired(s.now()) {
+		return nil, auth.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+func (s *SessionStore) ListByUser(ctx context.Context, userID string) ([]*auth.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	sessions := make([]*auth.Session, 0)
+	for _, session := range s.sessions {
+		if session.UserID == userID && !session.Expired(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+func (s *SessionStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[id]
+	if !exists || session.Expired(s.now()) {
+		return auth.ErrSessionNotFound
+	}
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *SessionStore) DeleteByUser(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, session := range s.sessions {
+		if session.UserID == userID {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (s *SessionStore) Ping(ctx context.Context) error {
+	return nil
+}
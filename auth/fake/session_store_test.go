@@ -0,0 +1,73 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func TestSessionStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewSessionStore()
+
+	first := auth.NewSession("alice", time.Hour)
+	second := auth.NewSession("alice", time.Hour)
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+	other := auth.NewSession("bob", time.Hour)
+	for _, s := range []*auth.Session{second, first, other} {
+		if err := store.Create(ctx, s); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	got, err := store.Get(ctx, first.ID)
+	if err != nil || got.UserID != "alice" {
+		t.Fatalf("Get() = %v, %v, want alice's session", got, err)
+	}
+
+	sessions, _ := store.ListByUser(ctx, "alice")
+	if len(sessions) != 2 || sessions[0].ID != first.ID {
+		t.Errorf("ListByUser() = %v, want first then second", sessions)
+	}
+
+	if err := store.Delete(ctx, first.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, first.ID); !errors.Is(err, auth.ErrSessionNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrSessionNotFound", err)
+	}
+	if err := store.Delete(ctx, first.ID); !errors.Is(err, auth.ErrSessionNotFound) {
+		t.Errorf("Delete() twice error = %v, want ErrSessionNotFound", err)
+	}
+
+	if err := store.DeleteByUser(ctx, "alice"); err != nil {
+		t.Fatalf("DeleteByUser() error = %v", err)
+	}
+	if sessions, _ := store.ListByUser(ctx, "alice"); len(sessions) != 0 {
+		t.Errorf("ListByUser() after DeleteByUser = %d sessions, want 0", len(sessions))
+	}
+	if _, err := store.Get(ctx, other.ID); err != nil {
+		t.Errorf("DeleteByUser() removed another user's session: %v", err)
+	}
+}
+
+func TestSessionStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewSessionStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	session := auth.NewSession("alice", time.Minute)
+	store.Create(ctx, session)
+	now = session.ExpiresAt
+
+	if _, err := store.Get(ctx, session.ID); !errors.Is(err, auth.ErrSessionNotFound) {
+		t.Errorf("Get() expired session error = %v, want ErrSessionNotFound", err)
+	}
+	if sessions, _ := store.ListByUser(ctx, "alice"); len(sessions) != 0 {
+		t.Errorf("ListByUser() returned %d expired sessions", len(sessions))
+	}
+}
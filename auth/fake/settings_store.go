@@ -0,0 +1,73 @@
+package fake
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+type SettingsStore struct {
+	mu       sync.RWMutex
+	settings *auth.Settings
+}
+
+func NewSettingsStore() *SettingsStore {
+	return &SettingsStore{}
+}
+
+func (s *SettingsStore) Get(ctx context.Context) (*auth.Settings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.settings == nil {
+		return nil, auth.ErrSettingsNotFound
+	}
+	return copySettings(s.settings), nil
+}
+
+// Save stores a copy of settings if its Version matches the stored one,
+// zero before the first save, and increments it; otherwise it returns
+// auth.ErrVersionConflict.
+func (s *SettingsStore) Save(ctx context.Context, settings *auth.Settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var current int64
+	if s.settings != nil {
+		current = s.settings.Version
+	}
+	if settings.Version != current {
+		return auth.ErrVersionConflict
+	}
+
+	settings.Version++
+	s.settings = copySettings(settings)
+	return nil
+}
+
+func (s *SettingsStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func copySettings(settings *auth.Settings) *auth.Settings {
+	c := *settings
+	if settings.MFARequired != nil {
+		required := *settings.MFARequired
+		c.MFARequired = &required
+	}
+	if settings.PasswordPolicy != nil {
+		policy := *settings.PasswordPolicy
+		c.PasswordPolicy = &policy
+	}
+	if settings.EmailDomains != nil {
+		c.EmailDomains = &auth.EmailDomains{
+			Allow: slices.Clone(settings.EmailDomains.Allow),
+			Deny:  slices.Clone(settings.EmailDomains.Deny),
+		}
+	}
+	return &c
+}
+
+var _ auth.SettingsStore = (*SettingsStore)(nil)
@@ -0,0 +1,38 @@
+package fake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func TestSettingsStore(t *testing.T) {
+	store := NewSettingsStore()
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx); err != auth.ErrSettingsNotFound {
+		t.Fatalf("Get() before Save error = %v, want ErrSettingsNotFound", err)
+	}
+
+	settings := &auth.Settings{SessionTTLSeconds: 3600, EmailDomains: &auth.EmailDomains{Allow: []string{"example.com"}}}
+	if err := store.Save(ctx, settings); err != nil || settings.Version != 1 {
+		t.Fatalf("Save() = %v, version %d", err, settings.Version)
+	}
+	settings.EmailDomains.Allow[0] = "changed.com"
+
+	got, err := store.Get(ctx)
+	if err != nil || got.SessionTTLSeconds != 3600 || got.EmailDomains.Allow[0] != "example.com" {
+		t.Fatalf("Get() = %+v, %v", got, err)
+	}
+
+	stale := &auth.Settings{}
+	if err := store.Save(ctx, stale); err != auth.ErrVersionConflict {
+		t.Errorf("Save() with stale version error = %v, want ErrVersionConflict", err)
+	}
+
+	got.SessionTTLSeconds = 60
+	if err := store.Save(ctx, got); err != nil || got.Version != 2 {
+		t.Errorf("Save() update = %v, version %d", err, got.Version)
+	}
+}
@@ -0,0 +1,50 @@
+package fake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// Transactor runs units of work directly. The fake stores have no
+// transactions, so failed work is not rolled back; Calls and Failures let
+// tests check that work went through it.
+type Transactor struct {
+	mu       sync.Mutex
+	calls    int
+	failures int
+}
+
+func NewTransactor() *Transactor {
+	return &Transactor{}
+}
+
+func (t *Transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := fn(ctx)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls++
+	if err != nil {
+		t.failures++
+	}
+	return err
+}
+
+// Calls returns how many units of work ran.
+func (t *Transactor) Calls() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.calls
+}
+
+// Failures returns how many units of work returned an error and would have
+// been rolled back.
+func (t *Transactor) Failures() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.failures
+}
+
+var _ auth.Transactor = (*Transactor)(nil)
@@ -0,0 +1,29 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTransactor(t *testing.T) {
+	ctx := context.Background()
+	tx := NewTransactor()
+
+	ran := false
+	if err := tx.WithinTx(ctx, func(ctx context.Context) error {
+		ran = true
+		return nil
+	}); err != nil || !ran {
+		t.Fatalf("WithinTx() = %v, ran %v; want fn run without error", err, ran)
+	}
+
+	boom := errors.New("boom")
+	if err := tx.WithinTx(ctx, func(ctx context.Context) error { return boom }); !errors.Is(err, boom) {
+		t.Errorf("WithinTx() error = %v, want %v", err, boom)
+	}
+
+	if tx.Calls() != 2 || tx.Failures() != 1 {
+		t.Errorf("Calls() = %d, Failures() = %d; want 2 and 1", tx.Calls(), tx.Failures())
+	}
+}
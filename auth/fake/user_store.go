@@ -2,23 +2,24 @@ package fake
 
 import (
 	"context"
+	"sort"
 	"sync"
 
 	"github.com/aquamarinepk/aqm/auth"
-	"github.com/google/uuid"
 )
 
 type UserStore struct {
 	mu                sync.RWMutex
-	users             map[uuid.UUID]*auth.User
+	users             map[auth.UserID]*auth.User
 	usersByUsername   map[string]*auth.User
 	usersByEmailLookup map[string]*auth.User
 	usersByPINLookup  map[string]*auth.User
+	grants            *GrantStore
 }
 
 func NewUserStore() *UserStore {
 	return &UserStore{
-		users:              make(map[uuid.UUID]*auth.User),
+		users:              make(map[auth.UserID]*auth.User),
 		usersByUsername:    make(map[string]*auth.User),
 		usersByEmailLookup: make(map[string]*auth.User),
 		usersByPINLookup:   make(map[string]*auth.User),
@@ -49,7 +50,7 @@ func (s *UserStore) Create(ctx context.Context, user *auth.User) error {
 	return nil
 }
 
-func (s *UserStore) Get(ctx context.Context, id uuid.UUID) (*auth.User, error) {
+func (s *UserStore) Get(ctx context.Context, id auth.UserID) (*auth.User, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -97,14 +98,28 @@ func (s *UserStore) GetByPINLookup(ctx context.Context, lookup []byte) (*auth.Us
 	return user, nil
 }
 
+// Update stores user if its Version matches the stored one and increments
+// it; otherwise it returns auth.ErrVersionConflict.
 func (s *UserStore) Update(ctx context.Context, user *auth.User) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.users[user.ID]; !exists {
+	current, exists := s.users[user.ID]
+	if !exists {
 		return auth.ErrUserNotFound
 	}
+	if current.Version != user.Version {
+		return auth.ErrVersionConflict
+	}
+	if other, taken := s.usersByUsername[user.Username]; taken && other.ID != user.ID {
+		return auth.ErrUsernameExists
+	}
+	if other, taken := s.usersByEmailLookup[string(user.EmailLookup)]; taken && other.ID != user.ID {
+		return auth.ErrUserAlreadyExists
+	}
 
+	user.Version++
+	s.unindex(user.ID)
 	s.users[user.ID] = user
 	s.usersByUsername[user.Username] = user
 	if len(user.EmailLookup) > 0 {
@@ -117,7 +132,19 @@ func (s *UserStore) Update(ctx context.Context, user *auth.User) error {
 	return nil
 }
 
-func (s *UserStore) Delete(ctx context.Context, id uuid.UUID) error {
+// unindex drops the username, email and PIN entries of the user with id, so
+// values the user no longer has stop finding them.
+func (s *UserStore) unindex(id auth.UserID) {
+	for _, index := range []map[string]*auth.User{s.usersByUsername, s.usersByEmailLookup, s.usersByPINLookup} {
+		for key, u := range index {
+			if u.ID == id {
+				delete(index, key)
+			}
+		}
+	}
+}
+
+func (s *UserStore) Delete(ctx context.Context, id auth.UserID) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -127,6 +154,7 @@ func (s *UserStore) Delete(ctx context.Context, id uuid.UUID) error {
 	}
 
 	user.Status = "deleted"
+	user.Version++
 	return nil
 }
 
@@ -155,3 +183,60 @@ func (s *UserStore) ListByStatus(ctx context.Context, status auth.UserStatus) ([
 
 	return users, nil
 }
+
+// WithGrants makes Search resolve role filters against grants. Without it a
+// query with a role matches no users.
+func (s *UserStore) WithGrants(grants *GrantStore) *UserStore {
+	s.grants = grants
+	return s
+}
+
+func (s *UserStore) Search(ctx context.Context, q auth.UserQuery) (*auth.UserPage, error) {
+	q, err := q.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	matches := make([]*auth.User, 0)
+	for _, user := range s.users {
+		if q.Matches(user) {
+			matches = append(matches, user)
+		}
+	}
+	s.mu.RUnlock()
+
+	if q.Role != "" {
+		filtered := matches[:0]
+		if s.grants != nil {
+			for _, user := range matches {
+				ok, err := s.grants.HasRole(ctx, user.Username, q.Role)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					filtered = append(filtered, user)
+				}
+			}
+		}
+		matches = filtered
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.After(matches[j].CreatedAt)
+		}
+		return matches[i].ID.String() < matches[j].ID.String()
+	})
+
+	page := &auth.UserPage{Users: []*auth.User{}, Total: len(matches), Limit: q.Limit, Offset: q.Offset}
+	if q.Offset < len(matches) {
+		end := min(q.Offset+q.Limit, len(matches))
+		page.Users = matches[q.Offset:end]
+	}
+	return page, nil
+}
+
+func (s *UserStore) Ping(ctx context.Context) error {
+	return nil
+}
@@ -2,10 +2,12 @@ package fake
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/aquamarinepk/aqm/auth"
-	"github.com/google/uuid"
 )
 
 func TestNewUserStore(t *testing.T) {
@@ -41,7 +43,7 @@ func TestUserStore_Create(t *testing.T) {
 				return nil
 			},
 			user: &auth.User{
-				ID:          uuid.New(),
+				ID:          auth.NewUserID(),
 				Username:    "testuser",
 				EmailLookup: []byte("email-lookup"),
 				PINLookup:   []byte("pin-lookup"),
@@ -52,7 +54,7 @@ func TestUserStore_Create(t *testing.T) {
 			name: "duplicate user ID",
 			setup: func(s *UserStore) *auth.User {
 				existing := &auth.User{
-					ID:       uuid.New(),
+					ID:       auth.NewUserID(),
 					Username: "existing",
 				}
 				_ = s.Create(context.Background(), existing)
@@ -66,14 +68,14 @@ func TestUserStore_Create(t *testing.T) {
 			name: "duplicate username",
 			setup: func(s *UserStore) *auth.User {
 				existing := &auth.User{
-					ID:       uuid.New(),
+					ID:       auth.NewUserID(),
 					Username: "duplicate",
 				}
 				_ = s.Create(context.Background(), existing)
 				return nil
 			},
 			user: &auth.User{
-				ID:       uuid.New(),
+				ID:       auth.NewUserID(),
 				Username: "duplicate",
 			},
 			wantErr: true,
@@ -85,7 +87,7 @@ func TestUserStore_Create(t *testing.T) {
 				return nil
 			},
 			user: &auth.User{
-				ID:          uuid.New(),
+				ID:          auth.NewUserID(),
 				Username:    "nomail",
 				EmailLookup: []byte{},
 			},
@@ -97,7 +99,7 @@ func TestUserStore_Create(t *testing.T) {
 				return nil
 			},
 			user: &auth.User{
-				ID:        uuid.New(),
+				ID:        auth.NewUserID(),
 				Username:  "nopin",
 				PINLookup: []byte{},
 			},
@@ -131,14 +133,14 @@ func TestUserStore_Create(t *testing.T) {
 func TestUserStore_Get(t *testing.T) {
 	store := NewUserStore()
 	user := &auth.User{
-		ID:       uuid.New(),
+		ID:       auth.NewUserID(),
 		Username: "testuser",
 	}
 	_ = store.Create(context.Background(), user)
 
 	tests := []struct {
 		name    string
-		id      uuid.UUID
+		id      auth.UserID
 		wantErr bool
 	}{
 		{
@@ -148,7 +150,7 @@ func TestUserStore_Get(t *testing.T) {
 		},
 		{
 			name:    "nonexistent user",
-			id:      uuid.New(),
+			id:      auth.NewUserID(),
 			wantErr: true,
 		},
 	}
@@ -170,7 +172,7 @@ func TestUserStore_Get(t *testing.T) {
 func TestUserStore_GetByEmailLookup(t *testing.T) {
 	store := NewUserStore()
 	user := &auth.User{
-		ID:          uuid.New(),
+		ID:          auth.NewUserID(),
 		Username:    "testuser",
 		EmailLookup: []byte("email-lookup"),
 	}
@@ -210,7 +212,7 @@ func TestUserStore_GetByEmailLookup(t *testing.T) {
 func TestUserStore_GetByUsername(t *testing.T) {
 	store := NewUserStore()
 	user := &auth.User{
-		ID:       uuid.New(),
+		ID:       auth.NewUserID(),
 		Username: "testuser",
 	}
 	_ = store.Create(context.Background(), user)
@@ -249,7 +251,7 @@ func TestUserStore_GetByUsername(t *testing.T) {
 func TestUserStore_GetByPINLookup(t *testing.T) {
 	store := NewUserStore()
 	user := &auth.User{
-		ID:        uuid.New(),
+		ID:        auth.NewUserID(),
 		Username:  "testuser",
 		PINLookup: []byte("pin-lookup"),
 	}
@@ -297,7 +299,7 @@ func TestUserStore_Update(t *testing.T) {
 			name: "update existing user",
 			setup: func(s *UserStore) *auth.User {
 				user := &auth.User{
-					ID:       uuid.New(),
+					ID:       auth.NewUserID(),
 					Username: "testuser",
 				}
 				_ = s.Create(context.Background(), user)
@@ -312,7 +314,7 @@ func TestUserStore_Update(t *testing.T) {
 			name: "update user with new email lookup",
 			setup: func(s *UserStore) *auth.User {
 				user := &auth.User{
-					ID:       uuid.New(),
+					ID:       auth.NewUserID(),
 					Username: "testuser",
 				}
 				_ = s.Create(context.Background(), user)
@@ -327,7 +329,7 @@ func TestUserStore_Update(t *testing.T) {
 			name: "update user with new PIN lookup",
 			setup: func(s *UserStore) *auth.User {
 				user := &auth.User{
-					ID:       uuid.New(),
+					ID:       auth.NewUserID(),
 					Username: "testuser",
 				}
 				_ = s.Create(context.Background(), user)
@@ -342,7 +344,7 @@ func TestUserStore_Update(t *testing.T) {
 			name: "update nonexistent user",
 			setup: func(s *UserStore) *auth.User {
 				return &auth.User{
-					ID:       uuid.New(),
+					ID:       auth.NewUserID(),
 					Username: "nonexistent",
 				}
 			},
@@ -365,17 +367,75 @@ func TestUserStore_Update(t *testing.T) {
 	}
 }
 
+func TestUserStore_UpdateVersion(t *testing.T) {
+	store := NewUserStore()
+	ctx := context.Background()
+
+	u := &auth.User{ID: auth.NewUserID(), Username: "testuser", Version: 1}
+	if err := store.Create(ctx, u); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	stale := *u
+
+	if err := store.Update(ctx, u); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if u.Version != 2 {
+		t.Errorf("Version = %d, want 2", u.Version)
+	}
+
+	if err := store.Update(ctx, &stale); !errors.Is(err, auth.ErrVersionConflict) {
+		t.Errorf("Update() with stale version error = %v, want %v", err, auth.ErrVersionConflict)
+	}
+}
+
+func TestUserStore_UpdateIndexes(t *testing.T) {
+	store := NewUserStore()
+	ctx := context.Background()
+
+	ann := &auth.User{ID: auth.NewUserID(), Username: "ann", EmailLookup: []byte("ann-lookup")}
+	bob := &auth.User{ID: auth.NewUserID(), Username: "bob", EmailLookup: []byte("bob-lookup")}
+	_ = store.Create(ctx, ann)
+	_ = store.Create(ctx, bob)
+
+	changed := *ann
+	changed.Username, changed.EmailLookup = "annie", []byte("annie-lookup")
+	if err := store.Update(ctx, &changed); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := store.GetByUsername(ctx, "ann"); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("GetByUsername(old) error = %v, want ErrUserNotFound", err)
+	}
+	if _, err := store.GetByEmailLookup(ctx, []byte("ann-lookup")); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("GetByEmailLookup(old) error = %v, want ErrUserNotFound", err)
+	}
+	if u, err := store.GetByEmailLookup(ctx, []byte("annie-lookup")); err != nil || u.ID != ann.ID {
+		t.Errorf("GetByEmailLookup(new) = %v, %v", u, err)
+	}
+
+	taken := changed
+	taken.Username = "bob"
+	if err := store.Update(ctx, &taken); !errors.Is(err, auth.ErrUsernameExists) {
+		t.Errorf("Update() taken username error = %v, want ErrUsernameExists", err)
+	}
+	taken = changed
+	taken.EmailLookup = []byte("bob-lookup")
+	if err := store.Update(ctx, &taken); !errors.Is(err, auth.ErrUserAlreadyExists) {
+		t.Errorf("Update() taken email error = %v, want ErrUserAlreadyExists", err)
+	}
+}
+
 func TestUserStore_Delete(t *testing.T) {
 	tests := []struct {
 		name    string
-		setup   func(*UserStore) uuid.UUID
+		setup   func(*UserStore) auth.UserID
 		wantErr bool
 	}{
 		{
 			name: "delete existing user",
-			setup: func(s *UserStore) uuid.UUID {
+			setup: func(s *UserStore) auth.UserID {
 				user := &auth.User{
-					ID:       uuid.New(),
+					ID:       auth.NewUserID(),
 					Username: "testuser",
 					Status:   auth.UserStatusActive,
 				}
@@ -386,8 +446,8 @@ func TestUserStore_Delete(t *testing.T) {
 		},
 		{
 			name: "delete nonexistent user",
-			setup: func(s *UserStore) uuid.UUID {
-				return uuid.New()
+			setup: func(s *UserStore) auth.UserID {
+				return auth.NewUserID()
 			},
 			wantErr: true,
 		},
@@ -417,8 +477,8 @@ func TestUserStore_Delete(t *testing.T) {
 func TestUserStore_List(t *testing.T) {
 	store := NewUserStore()
 
-	user1 := &auth.User{ID: uuid.New(), Username: "user1"}
-	user2 := &auth.User{ID: uuid.New(), Username: "user2"}
+	user1 := &auth.User{ID: auth.NewUserID(), Username: "user1"}
+	user2 := &auth.User{ID: auth.NewUserID(), Username: "user2"}
 	_ = store.Create(context.Background(), user1)
 	_ = store.Create(context.Background(), user2)
 
@@ -434,8 +494,8 @@ func TestUserStore_List(t *testing.T) {
 func TestUserStore_ListByStatus(t *testing.T) {
 	store := NewUserStore()
 
-	activeUser := &auth.User{ID: uuid.New(), Username: "active", Status: auth.UserStatusActive}
-	suspendedUser := &auth.User{ID: uuid.New(), Username: "suspended", Status: auth.UserStatusSuspended}
+	activeUser := &auth.User{ID: auth.NewUserID(), Username: "active", Status: auth.UserStatusActive}
+	suspendedUser := &auth.User{ID: auth.NewUserID(), Username: "suspended", Status: auth.UserStatusSuspended}
 	_ = store.Create(context.Background(), activeUser)
 	_ = store.Create(context.Background(), suspendedUser)
 
@@ -473,3 +533,74 @@ func TestUserStore_ListByStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestUserStore_Ping(t *testing.T) {
+	if err := NewUserStore().Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
+
+func TestUserStore_Search(t *testing.T) {
+	ctx := context.Background()
+	roles := NewRoleStore()
+	grants := NewGrantStore(roles)
+	store := NewUserStore().WithGrants(grants)
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	users := []*auth.User{
+		{ID: auth.NewUserID(), Username: "ann", Name: "Ann Smith", Status: auth.UserStatusActive, CreatedAt: base},
+		{ID: auth.NewUserID(), Username: "anna", Name: "Anna Jones", Status: auth.UserStatusSuspended, CreatedAt: base.Add(time.Hour)},
+		{ID: auth.NewUserID(), Username: "bob", Name: "Bob Smith", Status: auth.UserStatusActive, CreatedAt: base.Add(2 * time.Hour)},
+	}
+	for _, u := range users {
+		_ = store.Create(ctx, u)
+	}
+
+	admin := &auth.Role{ID: auth.NewRoleID(), Name: "admin"}
+	_ = roles.Create(ctx, admin)
+	_ = grants.Create(ctx, auth.NewGrant("bob", admin.ID, "test"))
+
+	tests := []struct {
+		name      string
+		query     auth.UserQuery
+		wantNames []string
+		wantTotal int
+	}{
+		{name: "all newest first", query: auth.UserQuery{}, wantNames: []string{"bob", "anna", "ann"}, wantTotal: 3},
+		{name: "username prefix", query: auth.UserQuery{UsernamePrefix: "ann"}, wantNames: []string{"anna", "ann"}, wantTotal: 2},
+		{name: "name substring", query: auth.UserQuery{Name: "smith"}, wantNames: []string{"bob", "ann"}, wantTotal: 2},
+		{name: "status", query: auth.UserQuery{Status: auth.UserStatusSuspended}, wantNames: []string{"anna"}, wantTotal: 1},
+		{name: "role", query: auth.UserQuery{Role: "admin"}, wantNames: []string{"bob"}, wantTotal: 1},
+		{name: "created range", query: auth.UserQuery{CreatedAfter: base.Add(time.Hour), CreatedBefore: base.Add(2 * time.Hour)}, wantNames: []string{"anna"}, wantTotal: 1},
+		{name: "page", query: auth.UserQuery{Limit: 1, Offset: 1}, wantNames: []string{"anna"}, wantTotal: 3},
+		{name: "offset past end", query: auth.UserQuery{Offset: 10}, wantNames: []string{}, wantTotal: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := store.Search(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if page.Total != tt.wantTotal {
+				t.Errorf("Search() total = %d, want %d", page.Total, tt.wantTotal)
+			}
+			got := make([]string, 0, len(page.Users))
+			for _, u := range page.Users {
+				got = append(got, u.Username)
+			}
+			if !reflect.DeepEqual(got, tt.wantNames) {
+				t.Errorf("Search() users = %v, want %v", got, tt.wantNames)
+			}
+		})
+	}
+
+	if _, err := store.Search(ctx, auth.UserQuery{Limit: -1}); !errors.Is(err, auth.ErrInvalidUserQuery) {
+		t.Errorf("Search() with invalid query error = %v, want ErrInvalidUserQuery", err)
+	}
+
+	page, err := NewUserStore().Search(ctx, auth.UserQuery{Role: "admin"})
+	if err != nil || page.Total != 0 {
+		t.Errorf("Search() by role without grants = %+v, %v; want no users", page, err)
+	}
+}
@@ -0,0 +1,60 @@
+package fake
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+type UsernameHistoryStore struct {
+	mu      sync.RWMutex
+	changes []auth.UsernameChange
+}
+
+func NewUsernameHistoryStore() *UsernameHistoryStore {
+	return &UsernameHistoryStore{}
+}
+
+func (s *UsernameHistoryStore) Record(ctx context.Context, change *auth.UsernameChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.changes = append(s.changes, *change)
+	return nil
+}
+
+func (s *UsernameHistoryStore) ReleasedSince(ctx context.Context, username string, since time.Time) ([]*auth.UsernameChange, error) {
+	return s.list(func(c auth.UsernameChange) bool {
+		return c.OldUsername == username && !c.ChangedAt.Before(since)
+	}), nil
+}
+
+func (s *UsernameHistoryStore) ListByUser(ctx context.Context, userID auth.UserID) ([]*auth.UsernameChange, error) {
+	return s.list(func(c auth.UsernameChange) bool {
+		return c.UserID == userID
+	}), nil
+}
+
+func (s *UsernameHistoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// list returns copies of the changes matching keep, newest first.
+func (s *UsernameHistoryStore) list(keep func(auth.UsernameChange) bool) []*auth.UsernameChange {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	changes := make([]*auth.UsernameChange, 0)
+	for _, c := range s.changes {
+		if keep(c) {
+			changes = append(changes, &c)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].ChangedAt.After(changes[j].ChangedAt)
+	})
+	return changes
+}
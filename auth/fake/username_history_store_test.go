@@ -0,0 +1,50 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func TestUsernameHistoryStore(t *testing.T) {
+	store := NewUsernameHistoryStore()
+	ctx := context.Background()
+	now := time.Now()
+	ann, bob := auth.NewUserID(), auth.NewUserID()
+
+	changes := []*auth.UsernameChange{
+		{UserID: ann, OldUsername: "ann", NewUsername: "annie", ChangedBy: "ann", ChangedAt: now.Add(-48 * time.Hour)},
+		{UserID: ann, OldUsername: "annie", NewUsername: "ann2", ChangedBy: "ann", ChangedAt: now.Add(-time.Hour)},
+		{UserID: bob, OldUsername: "ann", NewUsername: "bob", ChangedBy: "admin", ChangedAt: now},
+	}
+	for _, c := range changes {
+		if err := store.Record(ctx, c); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	released, err := store.ReleasedSince(ctx, "ann", now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("ReleasedSince() error = %v", err)
+	}
+	if len(released) != 1 || released[0].UserID != bob {
+		t.Errorf("ReleasedSince() = %+v, want bob's change", released)
+	}
+	if released, _ := store.ReleasedSince(ctx, "ann", now.Add(-72*time.Hour)); len(released) != 2 || released[0].UserID != bob {
+		t.Errorf("ReleasedSince() older = %+v, want both, newest first", released)
+	}
+
+	history, err := store.ListByUser(ctx, ann)
+	if err != nil {
+		t.Fatalf("ListByUser() error = %v", err)
+	}
+	if len(history) != 2 || history[0].NewUsername != "ann2" || history[1].NewUsername != "annie" {
+		t.Errorf("ListByUser() = %+v", history)
+	}
+	history[0].NewUsername = "changed"
+	if again, _ := store.ListByUser(ctx, ann); again[0].NewUsername != "ann2" {
+		t.Error("ListByUser() returned stored changes instead of copies")
+	}
+}
@@ -2,8 +2,6 @@ package auth
 
 import (
 	"time"
-
-	"github.com/google/uuid"
 )
 
 // Grant represents a role assignment to a user.
@@ -11,16 +9,16 @@ import (
 // with AuthN. Each service maintains its own internal identifiers.
 // The username serves as the natural key for cross-service correlation.
 type Grant struct {
-	ID         uuid.UUID `json:"id" db:"id" bson:"_id"`
+	ID         GrantID   `json:"id" db:"id" bson:"_id"`
 	Username   string    `json:"username" db:"username" bson:"username"`
-	RoleID     uuid.UUID `json:"role_id" db:"role_id" bson:"role_id"`
+	RoleID     RoleID    `json:"role_id" db:"role_id" bson:"role_id"`
 	AssignedAt time.Time `json:"assigned_at" db:"assigned_at" bson:"assigned_at"`
 	AssignedBy string    `json:"assigned_by" db:"assigned_by" bson:"assigned_by"`
 }
 
-func NewGrant(username string, roleID uuid.UUID, assignedBy string) *Grant {
+func NewGrant(username string, roleID RoleID, assignedBy string) *Grant {
 	return &Grant{
-		ID:         uuid.New(),
+		ID:         NewGrantID(),
 		Username:   username,
 		RoleID:     roleID,
 		AssignedAt: time.Now(),
@@ -32,7 +30,7 @@ func (g *Grant) Validate() error {
 	if g.Username == "" {
 		return ErrUserNotFound
 	}
-	if g.RoleID == uuid.Nil {
+	if g.RoleID.IsZero() {
 		return ErrRoleNotFound
 	}
 	if g.AssignedBy == "" {
@@ -40,3 +38,37 @@ func (g *Grant) Validate() error {
 	}
 	return nil
 }
+
+// GrantPair identifies a (user, role) assignment independent of when or by
+// whom it was made.
+type GrantPair struct {
+	Username string `json:"username"`
+	RoleID   RoleID `json:"role_id"`
+}
+
+// GrantPlan lists the assignments and revocations that bring the grants of
+// a set of roles in line with a desired state.
+type GrantPlan struct {
+	Assign []GrantPair `json:"assign"`
+	Revoke []GrantPair `json:"revoke"`
+}
+
+// HasChanges reports whether the plan assigns or revokes anything.
+func (p *GrantPlan) HasChanges() bool {
+	return len(p.Assign) > 0 || len(p.Revoke) > 0
+}
+
+// GrantFailure records a change from a GrantPlan that could not be applied.
+type GrantFailure struct {
+	GrantPair
+	Op    string `json:"op"`
+	Error string `json:"error"`
+}
+
+// GrantBatchResult reports the outcome of one batch of a GrantPlan.
+type GrantBatchResult struct {
+	Batch    int            `json:"batch"`
+	Assigned []GrantPair    `json:"assigned"`
+	Revoked  []GrantPair    `json:"revoked"`
+	Failed   []GrantFailure `json:"failed"`
+}
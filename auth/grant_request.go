@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PermissionApproveGrants is the permission approvers hold by default to
+// decide grant requests (see handler.WithGrantApproval).
+const PermissionApproveGrants = "grants:approve"
+
+// MaxGrantRequestNoteLen bounds the reason of a request and the note of a
+// decision.
+const MaxGrantRequestNoteLen = 500
+
+type GrantRequestStatus string
+
+const (
+	GrantRequestPending  GrantRequestStatus = "pending"
+	GrantRequestApproved GrantRequestStatus = "approved"
+	GrantRequestRejected GrantRequestStatus = "rejected"
+)
+
+// ParseGrantRequestStatus parses s as a GrantRequestStatus. The empty string
+// parses as the empty status, which matches requests in any status.
+func ParseGrantRequestStatus(s string) (GrantRequestStatus, error) {
+	switch status := GrantRequestStatus(strings.ToLower(strings.TrimSpace(s))); status {
+	case "", GrantRequestPending, GrantRequestApproved, GrantRequestRejected:
+		return status, nil
+	default:
+		return "", fmt.Errorf("%w: unknown status %q", ErrInvalidGrantRequest, s)
+	}
+}
+
+// GrantRequest asks for RoleID to be assigned to Username. It stays pending
+// until an approver decides it; only approval creates the grant, whose ID is
+// then recorded in GrantID.
+type GrantRequest struct {
+	ID          GrantRequestID     `json:"id" db:"id" bson:"_id"`
+	Username    string             `json:"username" db:"username" bson:"username"`
+	RoleID      RoleID             `json:"role_id" db:"role_id" bson:"role_id"`
+	Reason      string             `json:"reason,omitempty" db:"reason" bson:"reason,omitempty"`
+	Status      GrantRequestStatus `json:"status" db:"status" bson:"status"`
+	RequestedBy string             `json:"requested_by" db:"requested_by" bson:"requested_by"`
+	RequestedAt time.Time          `json:"requested_at" db:"requested_at" bson:"requested_at"`
+	DecidedBy   string             `json:"decided_by,omitempty" db:"decided_by" bson:"decided_by,omitempty"`
+	DecidedAt   *time.Time         `json:"decided_at,omitempty" db:"decided_at" bson:"decided_at,omitempty"`
+	Note        string             `json:"note,omitempty" db:"note" bson:"note,omitempty"`
+	GrantID     *GrantID           `json:"grant_id,omitempty" db:"grant_id" bson:"grant_id,omitempty"`
+}
+
+// NewGrantRequest creates a pending request, made by requestedBy, for
+// roleID to be assigned to username.
+func NewGrantRequest(username string, roleID RoleID, reason, requestedBy string) *GrantRequest {
+	return &GrantRequest{
+		ID:          NewGrantRequestID(),
+		Username:    strings.TrimSpace(username),
+		RoleID:      roleID,
+		Reason:      strings.TrimSpace(reason),
+		Status:      GrantRequestPending,
+		RequestedBy: requestedBy,
+		RequestedAt: time.Now(),
+	}
+}
+
+func (r *GrantRequest) Validate() error {
+	if r.Username == "" {
+		return fmt.Errorf("%w: username is required", ErrInvalidGrantRequest)
+	}
+	if r.RoleID.IsZero() {
+		return fmt.Errorf("%w: role_id is required", ErrInvalidGrantRequest)
+	}
+	if r.RequestedBy == "" {
+		return fmt.Errorf("%w: requester is required", ErrInvalidGrantRequest)
+	}
+	if len(r.Reason) > MaxGrantRequestNoteLen || len(r.Note) > MaxGrantRequestNoteLen {
+		return fmt.Errorf("%w: reason and note must be at most %d characters", ErrInvalidGrantRequest, MaxGrantRequestNoteLen)
+	}
+	return nil
+}
+
+// IsPending reports whether the request still awaits a decision.
+func (r *GrantRequest) IsPending() bool {
+	return r.Status == GrantRequestPending
+}
+
+// Decide records that decidedBy moved the pending request to status at at.
+// It fails with ErrGrantRequestDecided when the request was already decided
+// and with ErrSelfApproval when decidedBy made the request.
+func (r *GrantRequest) Decide(status GrantRequestStatus, decidedBy, note string, at time.Time) error {
+	if !r.IsPending() {
+		return ErrGrantRequestDecided
+	}
+	if status != GrantRequestApproved && status != GrantRequestRejected {
+		return fmt.Errorf("%w: cannot decide a request as %q", ErrInvalidGrantRequest, status)
+	}
+	if decidedBy == "" {
+		return ErrPermissionDenied
+	}
+	if decidedBy == r.RequestedBy {
+		return ErrSelfApproval
+	}
+
+	r.Status = status
+	r.DecidedBy = decidedBy
+	r.DecidedAt = &at
+	r.Note = strings.TrimSpace(note)
+	return r.Validate()
+}
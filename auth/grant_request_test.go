@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGrantRequestValidate(t *testing.T) {
+	roleID := NewRoleID()
+	tests := []struct {
+		name    string
+		req     *GrantRequest
+		wantErr bool
+	}{
+		{name: "valid", req: NewGrantRequest(" ann ", roleID, "on-call rotation", "ann")},
+		{name: "no username", req: NewGrantRequest("", roleID, "", "ann"), wantErr: true},
+		{name: "no role", req: NewGrantRequest("ann", RoleID{}, "", "ann"), wantErr: true},
+		{name: "no requester", req: NewGrantRequest("ann", roleID, "", ""), wantErr: true},
+		{name: "long reason", req: NewGrantRequest("ann", roleID, strings.Repeat("r", MaxGrantRequestNoteLen+1), "ann"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidGrantRequest) {
+				t.Errorf("Validate() error = %v, want ErrInvalidGrantRequest", err)
+			}
+		})
+	}
+}
+
+func TestGrantRequestDecide(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	req := NewGrantRequest("ann", NewRoleID(), "", "ann")
+	if !req.IsPending() {
+		t.Fatalf("new request status = %q, want pending", req.Status)
+	}
+	if err := req.Decide(GrantRequestApproved, "ann", "", at); !errors.Is(err, ErrSelfApproval) {
+		t.Errorf("Decide() by requester error = %v, want ErrSelfApproval", err)
+	}
+	if err := req.Decide(GrantRequestPending, "bob", "", at); !errors.Is(err, ErrInvalidGrantRequest) {
+		t.Errorf("Decide(pending) error = %v, want ErrInvalidGrantRequest", err)
+	}
+	if err := req.Decide(GrantRequestApproved, "bob", " ok ", at); err != nil {
+		t.Fatalf("Decide() error = %v", err)
+	}
+	if req.Status != GrantRequestApproved || req.DecidedBy != "bob" || !req.DecidedAt.Equal(at) || req.Note != "ok" {
+		t.Errorf("decided request = %+v", req)
+	}
+	if err := req.Decide(GrantRequestRejected, "carol", "", at); !errors.Is(err, ErrGrantRequestDecided) {
+		t.Errorf("Decide() twice error = %v, want ErrGrantRequestDecided", err)
+	}
+}
+
+func TestParseGrantRequestStatus(t *testing.T) {
+	if status, err := ParseGrantRequestStatus(" Pending "); err != nil || status != GrantRequestPending {
+		t.Errorf("ParseGrantRequestStatus(pending) = %q, %v", status, err)
+	}
+	if status, err := ParseGrantRequestStatus(""); err != nil || status != "" {
+		t.Errorf("ParseGrantRequestStatus(\"\") = %q, %v", status, err)
+	}
+	if _, err := ParseGrantRequestStatus("expired"); !errors.Is(err, ErrInvalidGrantRequest) {
+		t.Errorf("ParseGrantRequestStatus(expired) error = %v, want ErrInvalidGrantRequest", err)
+	}
+}
@@ -1,14 +1,10 @@
 package auth
 
-import (
-	"testing"
-
-	"github.com/google/uuid"
-)
+import "testing"
 
 func TestNewGrant(t *testing.T) {
 	username := "testuser"
-	roleID := uuid.New()
+	roleID := NewRoleID()
 	assignedBy := "admin"
 
 	grant := NewGrant(username, roleID, assignedBy)
@@ -16,7 +12,7 @@ func TestNewGrant(t *testing.T) {
 	if grant == nil {
 		t.Fatal("NewGrant() returned nil")
 	}
-	if grant.ID == uuid.Nil {
+	if grant.ID.IsZero() {
 		t.Error("NewGrant() did not generate ID")
 	}
 	if grant.Username != username {
@@ -34,7 +30,7 @@ func TestNewGrant(t *testing.T) {
 }
 
 func TestGrantValidate(t *testing.T) {
-	roleID := uuid.New()
+	roleID := NewRoleID()
 
 	tests := []struct {
 		name    string
@@ -53,7 +49,7 @@ func TestGrantValidate(t *testing.T) {
 		},
 		{
 			"missing role ID",
-			&Grant{Username: "testuser", RoleID: uuid.Nil, AssignedBy: "admin"},
+			&Grant{Username: "testuser", RoleID: RoleID{}, AssignedBy: "admin"},
 			true,
 		},
 		{
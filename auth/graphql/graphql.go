@@ -0,0 +1,171 @@
+// Package graphql serves the auth domain — users, roles, grants and
+// permission checks — through a single read-only GraphQL endpoint, as an
+// alternative to the REST endpoints for admin UIs. Changes still go through
+// the REST API so they are validated, audited and hooked as usual.
+//
+// Nested fields are resolved through per-request loaders, so listing users
+// with their roles reads each user's grants and each role once.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/go-chi/chi/v5"
+	gql "github.com/graph-gophers/graphql-go"
+)
+
+// DefaultMaxDepth bounds how deeply queries may nest fields unless
+// WithMaxDepth says otherwise.
+const DefaultMaxDepth = 8
+
+// maxRequestSize bounds the body of a query request.
+const maxRequestSize = 1 << 20
+
+// Schema is the GraphQL schema served by Handler.
+const Schema = `
+schema {
+	query: Query
+}
+
+scalar Time
+
+type Query {
+	user(id: ID, username: String): User
+	users(username: String, name: String, status: String, role: String, createdAfter: Time, createdBefore: Time, limit: Int, offset: Int): UserPage!
+	role(id: ID, name: String): Role
+	roles(status: String): [Role!]!
+	checkPermission(username: String!, permission: String!, resource: String): Boolean!
+	hasRole(username: String!, role: String!): Boolean!
+}
+
+type User {
+	id: ID!
+	username: String!
+	name: String!
+	status: String!
+	createdAt: Time!
+	createdBy: String!
+	updatedAt: Time!
+	updatedBy: String!
+	grants: [Grant!]!
+	roles: [Role!]!
+	permissions: [String!]!
+}
+
+type UserPage {
+	users: [User!]!
+	total: Int!
+	limit: Int!
+	offset: Int!
+}
+
+type Role {
+	id: ID!
+	name: String!
+	description: String!
+	status: String!
+	permissions: [String!]!
+	createdAt: Time!
+	updatedAt: Time!
+	grants: [Grant!]!
+}
+
+type Grant {
+	id: ID!
+	username: String!
+	assignedBy: String!
+	assignedAt: Time!
+	role: Role
+	user: User
+}
+`
+
+// Handler serves GraphQL queries against the auth stores.
+type Handler struct {
+	users    auth.UserStore
+	roles    auth.RoleStore
+	grants   auth.GrantStore
+	engine   auth.AuthorizationEngine
+	maxDepth int
+	schema   *gql.Schema
+}
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithEngine answers checkPermission with engine instead of evaluating
+// grants directly.
+func WithEngine(engine auth.AuthorizationEngine) Option {
+	return func(h *Handler) {
+		h.engine = engine
+	}
+}
+
+// WithMaxDepth sets how deeply queries may nest fields. Non-positive values
+// are ignored.
+func WithMaxDepth(depth int) Option {
+	return func(h *Handler) {
+		if depth > 0 {
+			h.maxDepth = depth
+		}
+	}
+}
+
+// New creates a handler serving Schema from the given stores.
+func New(users auth.UserStore, roles auth.RoleStore, grants auth.GrantStore, opts ...Option) *Handler {
+	h := &Handler{
+		users:    users,
+		roles:    roles,
+		grants:   grants,
+		engine:   auth.NewGrantEngine(grants),
+		maxDepth: DefaultMaxDepth,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.schema = gql.MustParseSchema(Schema, &query{h: h}, gql.MaxDepth(h.maxDepth))
+	return h
+}
+
+// RegisterRoutes serves the endpoint at POST /graphql.
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Post("/graphql", h.ServeHTTP)
+}
+
+// Request is the JSON body of a GraphQL request.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// ServeHTTP executes the query in the request body. Query errors are
+// reported in the response's errors list with status 200, as GraphQL
+// clients expect; only unreadable requests fail with 400.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil || req.Query == "" {
+		httpx.WriteJSON(w, http.StatusBadRequest, map[string]any{
+			"errors": []map[string]string{{"message": "request body must be JSON with a query"}},
+		})
+		return
+	}
+
+	ctx := withLoaders(r.Context(), h.newLoaders())
+	resp := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
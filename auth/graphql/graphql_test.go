@@ -0,0 +1,303 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/go-chi/chi/v5"
+)
+
+// countingStores wraps the fake stores to count the calls behind nested
+// fields.
+type countingStores struct {
+	*fake.UserStore
+	roles  *countingRoleStore
+	grants *countingGrantStore
+}
+
+type countingRoleStore struct {
+	*fake.RoleStore
+	gets, lists atomic.Int32
+}
+
+func (s *countingRoleStore) Get(ctx context.Context, id auth.RoleID) (*auth.Role, error) {
+	s.gets.Add(1)
+	return s.RoleStore.Get(ctx, id)
+}
+
+func (s *countingRoleStore) List(ctx context.Context) ([]*auth.Role, error) {
+	s.lists.Add(1)
+	return s.RoleStore.List(ctx)
+}
+
+type countingGrantStore struct {
+	*fake.GrantStore
+	userGrants atomic.Int32
+}
+
+func (s *countingGrantStore) GetUserGrants(ctx context.Context, username string) ([]*auth.Grant, error) {
+	s.userGrants.Add(1)
+	return s.GrantStore.GetUserGrants(ctx, username)
+}
+
+func newTestStores(t *testing.T) *countingStores {
+	t.Helper()
+	ctx := t.Context()
+
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+	users := fake.NewUserStore().WithGrants(grants)
+
+	addRole := func(name string, status auth.RoleStatus, permissions ...string) *auth.Role {
+		r := auth.NewRole()
+		r.Name, r.Status, r.Permissions = name, status, permissions
+		r.BeforeCreate()
+		if err := roles.Create(ctx, r); err != nil {
+			t.Fatalf("Create(%s) error = %v", name, err)
+		}
+		return r
+	}
+	editor := addRole("editor", auth.RoleStatusActive, "posts:write", "posts:read")
+	viewer := addRole("viewer", auth.RoleStatusActive, "posts:read")
+	legacy := addRole("legacy", auth.RoleStatusInactive, "legacy:admin")
+
+	for _, name := range []string{"ann", "bob", "cid"} {
+		u := auth.NewUser()
+		u.Username, u.Name, u.Status = name, strings.ToUpper(name), auth.UserStatusActive
+		u.BeforeCreate()
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("Create(%s) error = %v", name, err)
+		}
+	}
+
+	for _, g := range []struct {
+		username string
+		role     *auth.Role
+	}{
+		{"ann", editor}, {"ann", viewer}, {"ann", legacy}, {"bob", viewer}, {"sa:worker", viewer},
+	} {
+		if err := grants.Create(ctx, auth.NewGrant(g.username, g.role.ID, "admin")); err != nil {
+			t.Fatalf("grant %s error = %v", g.username, err)
+		}
+	}
+
+	return &countingStores{
+		UserStore: users,
+		roles:     &countingRoleStore{RoleStore: roles},
+		grants:    &countingGrantStore{GrantStore: grants},
+	}
+}
+
+type response struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func execute(t *testing.T, h *Handler, query string, variables map[string]any) (int, response) {
+	t.Helper()
+
+	body, _ := json.Marshal(Request{Query: query, Variables: variables})
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+
+	var resp response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v\n%s", err, w.Body.String())
+	}
+	return w.Code, resp
+}
+
+func field[T any](t *testing.T, resp response, name string) T {
+	t.Helper()
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors = %+v", resp.Errors)
+	}
+	var v T
+	if err := json.Unmarshal(resp.Data[name], &v); err != nil {
+		t.Fatalf("decode %s: %v", name, err)
+	}
+	return v
+}
+
+func TestUsersBatchesNestedFields(t *testing.T) {
+	s := newTestStores(t)
+	h := New(s.UserStore, s.roles, s.grants)
+
+	_, resp := execute(t, h, `{
+		users(limit: 10) {
+			total
+			users { username roles { name } permissions }
+		}
+	}`, nil)
+
+	type user struct {
+		Username    string
+		Roles       []struct{ Name string }
+		Permissions []string
+	}
+	page := field[struct {
+		Total int
+		Users []user
+	}](t, resp, "users")
+
+	if page.Total != 3 || len(page.Users) != 3 {
+		t.Fatalf("page = %+v, want 3 users", page)
+	}
+	byName := map[string]user{}
+	for _, u := range page.Users {
+		byName[u.Username] = u
+	}
+	if got := byName["ann"]; len(got.Roles) != 3 || !reflect.DeepEqual(got.Permissions, []string{"posts:read", "posts:write"}) {
+		t.Errorf("ann = %+v, want 3 roles and the active roles' permissions", got)
+	}
+	if got := byName["cid"]; len(got.Roles) != 0 || len(got.Permissions) != 0 {
+		t.Errorf("cid = %+v, want no roles", got)
+	}
+
+	if n := s.grants.userGrants.Load(); n != 3 {
+		t.Errorf("GetUserGrants called %d times, want once per user", n)
+	}
+	if gets, lists := s.roles.gets.Load(), s.roles.lists.Load(); gets+lists > 2 {
+		t.Errorf("roles read %d times by id and %d by list, want them batched", gets, lists)
+	}
+}
+
+func TestRoleGrants(t *testing.T) {
+	s := newTestStores(t)
+	h := New(s.UserStore, s.roles, s.grants)
+
+	_, resp := execute(t, h, `query($name: String) {
+		role(name: $name) {
+			name
+			grants { username role { name } user { name } }
+		}
+	}`, map[string]any{"name": "viewer"})
+
+	role := field[struct {
+		Name   string
+		Grants []struct {
+			Username string
+			Role     struct{ Name string }
+			User     *struct{ Name string }
+		}
+	}](t, resp, "role")
+
+	if role.Name != "viewer" || len(role.Grants) != 3 {
+		t.Fatalf("role = %+v, want viewer with 3 grants", role)
+	}
+	for _, g := range role.Grants {
+		if g.Role.Name != "viewer" {
+			t.Errorf("grant %s role = %q, want viewer", g.Username, g.Role.Name)
+		}
+		if (g.User == nil) != (g.Username == "sa:worker") {
+			t.Errorf("grant %s user = %+v, want null only for the service account", g.Username, g.User)
+		}
+	}
+}
+
+func TestLookups(t *testing.T) {
+	s := newTestStores(t)
+	h := New(s.UserStore, s.roles, s.grants)
+	ann, _ := s.GetByUsername(t.Context(), "ann")
+
+	tests := []struct {
+		name  string
+		query string
+		field string
+		want  string
+	}{
+		{"user by id", `{ user(id: "` + ann.ID.String() + `") { username } }`, "user", `{"username":"ann"}`},
+		{"user by username", `{ user(username: "Bob") { name } }`, "user", `{"name":"BOB"}`},
+		{"unknown user", `{ user(username: "zed") { name } }`, "user", `null`},
+		{"malformed id", `{ user(id: "nope") { name } }`, "user", `null`},
+		{"roles by status", `{ roles(status: "inactive") { name } }`, "roles", `[{"name":"legacy"}]`},
+		{"unknown role", `{ role(name: "missing") { name } }`, "role", `null`},
+		{"granted permission", `{ checkPermission(username: "ann", permission: "posts:write") }`, "checkPermission", `true`},
+		{"inactive role permission", `{ checkPermission(username: "ann", permission: "legacy:admin") }`, "checkPermission", `false`},
+		{"has role", `{ hasRole(username: "bob", role: "viewer") }`, "hasRole", `true`},
+		{"lacks role", `{ hasRole(username: "bob", role: "editor") }`, "hasRole", `false`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := execute(t, h, tt.query, nil)
+			if code != http.StatusOK {
+				t.Fatalf("status = %d", code)
+			}
+			if got := string(field[json.RawMessage](t, resp, tt.field)); got != tt.want {
+				t.Errorf("%s = %s, want %s", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+type denyAll struct{}
+
+func (denyAll) Authorize(ctx context.Context, username, permission, resource string) (bool, error) {
+	return false, nil
+}
+
+func TestWithEngine(t *testing.T) {
+	s := newTestStores(t)
+	h := New(s.UserStore, s.roles, s.grants, WithEngine(denyAll{}))
+
+	_, resp := execute(t, h, `{ checkPermission(username: "ann", permission: "posts:write") }`, nil)
+	if got := field[bool](t, resp, "checkPermission"); got {
+		t.Error("checkPermission = true, want the engine's answer")
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	s := newTestStores(t)
+
+	tests := []struct {
+		name    string
+		h       *Handler
+		query   string
+		wantErr string
+	}{
+		{"missing argument", New(s.UserStore, s.roles, s.grants), `{ user { name } }`, "id or username is required"},
+		{"invalid query", New(s.UserStore, s.roles, s.grants), `{ users(limit: 1000) { total } }`, "invalid user query"},
+		{"unknown field", New(s.UserStore, s.roles, s.grants), `{ users { password } }`, "password"},
+		{"too deep", New(s.UserStore, s.roles, s.grants, WithMaxDepth(3)), `{ users { users { grants { role { name } } } } }`, "depth"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := execute(t, tt.h, tt.query, nil)
+			if code != http.StatusOK {
+				t.Errorf("status = %d, want 200", code)
+			}
+			if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tt.wantErr) {
+				t.Errorf("errors = %+v, want %q", resp.Errors, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBadRequest(t *testing.T) {
+	s := newTestStores(t)
+	r := chi.NewRouter()
+	New(s.UserStore, s.roles, s.grants).RegisterRoutes(r)
+
+	for _, body := range []string{"not json", `{"query": ""}`} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"errors"`) {
+			t.Errorf("POST %q = %d %s, want 400 with errors", body, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+)
+
+// loader batches and caches lookups by key for the duration of a request.
+// Keys primed by a list resolver are fetched together with the first key
+// that is loaded, so resolving a field on every element of a list costs one
+// batch instead of one store call per element. Keys missing from a batch
+// result resolve to the zero value.
+type loader[K comparable, V any] struct {
+	batch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	entries map[K]*entry[V]
+	queued  []K
+}
+
+type entry[V any] struct {
+	done     chan struct{}
+	inFlight bool
+	value    V
+	err      error
+}
+
+func newLoader[K comparable, V any](batch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{batch: batch, entries: make(map[K]*entry[V])}
+}
+
+// prime queues keys to be fetched with the next batch.
+func (l *loader[K, V]) prime(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queue(keys)
+}
+
+func (l *loader[K, V]) queue(keys []K) {
+	for _, k := range keys {
+		if _, ok := l.entries[k]; !ok {
+			l.entries[k] = &entry[V]{done: make(chan struct{})}
+			l.queued = append(l.queued, k)
+		}
+	}
+}
+
+// set caches a value already at hand, such as an element of a list. Keys
+// already fetched or being fetched keep their value.
+func (l *loader[K, V]) set(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		e = &entry[V]{done: make(chan struct{})}
+		l.entries[key] = e
+	} else if e.inFlight || isDone(e.done) {
+		return
+	}
+	e.value = value
+	close(e.done)
+}
+
+func (l *loader[K, V]) load(ctx context.Context, key K) (V, error) {
+	values, err := l.loadAll(ctx, []K{key})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return values[0], nil
+}
+
+// loadAll returns the values of keys, fetching every queued key that is not
+// cached in one batch.
+func (l *loader[K, V]) loadAll(ctx context.Context, keys []K) ([]V, error) {
+	l.mu.Lock()
+	l.queue(keys)
+	var fetch []K
+	var fetching []*entry[V]
+	for _, k := range l.queued {
+		if e := l.entries[k]; !e.inFlight && !isDone(e.done) {
+			e.inFlight = true
+			fetch = append(fetch, k)
+			fetching = append(fetching, e)
+		}
+	}
+	l.queued = nil
+	wanted := make([]*entry[V], len(keys))
+	for i, k := range keys {
+		wanted[i] = l.entries[k]
+	}
+	l.mu.Unlock()
+
+	if len(fetch) > 0 {
+		found, err := l.batch(ctx, fetch)
+		l.mu.Lock()
+		for i, k := range fetch {
+			fetching[i].value, fetching[i].err = found[k], err
+			fetching[i].inFlight = false
+			close(fetching[i].done)
+		}
+		l.mu.Unlock()
+	}
+
+	values := make([]V, len(keys))
+	for i, e := range wanted {
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if e.err != nil {
+			return nil, e.err
+		}
+		values[i] = e.value
+	}
+	return values, nil
+}
+
+func isDone(done chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,137 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+)
+
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (b *batchRecorder) batch(ctx context.Context, keys []string) (map[string]int, error) {
+	b.mu.Lock()
+	b.batches = append(b.batches, slices.Clone(keys))
+	b.mu.Unlock()
+
+	found := make(map[string]int, len(keys))
+	for _, k := range keys {
+		if k != "missing" {
+			found[k] = len(k)
+		}
+	}
+	return found, nil
+}
+
+func TestLoaderBatchesPrimedKeys(t *testing.T) {
+	rec := &batchRecorder{}
+	l := newLoader(rec.batch)
+	l.prime("a", "bb", "ccc")
+
+	got, err := l.load(t.Context(), "bb")
+	if err != nil || got != 2 {
+		t.Fatalf("load(bb) = %v, %v, want 2", got, err)
+	}
+	values, err := l.loadAll(t.Context(), []string{"a", "ccc", "missing"})
+	if err != nil {
+		t.Fatalf("loadAll() error = %v", err)
+	}
+	if want := []int{1, 3, 0}; !reflect.DeepEqual(values, want) {
+		t.Errorf("loadAll() = %v, want %v", values, want)
+	}
+	if want := [][]string{{"a", "bb", "ccc"}, {"missing"}}; !reflect.DeepEqual(rec.batches, want) {
+		t.Errorf("batches = %v, want %v", rec.batches, want)
+	}
+}
+
+func TestLoaderSet(t *testing.T) {
+	rec := &batchRecorder{}
+	l := newLoader(rec.batch)
+	l.set("a", 10)
+	l.prime("a")
+
+	if got, _ := l.load(t.Context(), "a"); got != 10 {
+		t.Errorf("load(a) = %v, want the value set", got)
+	}
+	l.set("a", 20)
+	if got, _ := l.load(t.Context(), "a"); got != 10 {
+		t.Errorf("load(a) after second set = %v, want the first value", got)
+	}
+	if len(rec.batches) != 0 {
+		t.Errorf("batches = %v, want none", rec.batches)
+	}
+}
+
+func TestLoaderError(t *testing.T) {
+	errStore := errors.New("store down")
+	l := newLoader(func(ctx context.Context, keys []string) (map[string]int, error) {
+		return nil, errStore
+	})
+	l.prime("a", "b")
+
+	if _, err := l.load(t.Context(), "a"); !errors.Is(err, errStore) {
+		t.Errorf("load(a) error = %v, want %v", err, errStore)
+	}
+	if _, err := l.load(t.Context(), "b"); !errors.Is(err, errStore) {
+		t.Errorf("load(b) error = %v, want the batch error", err)
+	}
+}
+
+func TestLoaderConcurrentLoads(t *testing.T) {
+	release := make(chan struct{})
+	var calls int
+	var mu sync.Mutex
+	l := newLoader(func(ctx context.Context, keys []string) (map[string]int, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		<-release
+		return map[string]int{"a": 1, "b": 2}, nil
+	})
+	l.prime("a", "b")
+
+	var wg sync.WaitGroup
+	results := make([]int, 8)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := []string{"a", "b"}[i%2]
+			results[i], _ = l.load(context.Background(), key)
+		}()
+	}
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("batch called %d times, want 1", calls)
+	}
+	for i, got := range results {
+		if want := i%2 + 1; got != want {
+			t.Errorf("results[%d] = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestLoaderContextCanceled(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	l := newLoader(func(ctx context.Context, keys []string) (map[string]int, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+	go l.load(context.Background(), "a")
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.load(ctx, "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("load() error = %v, want context.Canceled", err)
+	}
+}
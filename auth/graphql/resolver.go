@@ -0,0 +1,380 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	gql "github.com/graph-gophers/graphql-go"
+)
+
+// loaders holds the per-request loaders behind nested fields.
+type loaders struct {
+	users      *loader[string, *auth.User]
+	userGrants *loader[string, []*auth.Grant]
+	roles      *loader[auth.RoleID, *auth.Role]
+	roleGrants *loader[auth.RoleID, []*auth.Grant]
+}
+
+func (h *Handler) newLoaders() *loaders {
+	return &loaders{
+		users: newLoader(func(ctx context.Context, usernames []string) (map[string]*auth.User, error) {
+			found := make(map[string]*auth.User, len(usernames))
+			for _, username := range usernames {
+				u, err := h.users.GetByUsername(ctx, username)
+				if errors.Is(err, auth.ErrUserNotFound) {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				found[username] = u
+			}
+			return found, nil
+		}),
+		userGrants: newLoader(func(ctx context.Context, usernames []string) (map[string][]*auth.Grant, error) {
+			found := make(map[string][]*auth.Grant, len(usernames))
+			for _, username := range usernames {
+				grants, err := h.grants.GetUserGrants(ctx, username)
+				if err != nil {
+					return nil, err
+				}
+				found[username] = grants
+			}
+			return found, nil
+		}),
+		roles: newLoader(h.loadRoles),
+		roleGrants: newLoader(func(ctx context.Context, ids []auth.RoleID) (map[auth.RoleID][]*auth.Grant, error) {
+			found := make(map[auth.RoleID][]*auth.Grant, len(ids))
+			for _, id := range ids {
+				grants, err := h.grants.GetRoleGrants(ctx, id)
+				if err != nil {
+					return nil, err
+				}
+				found[id] = grants
+			}
+			return found, nil
+		}),
+	}
+}
+
+// loadRoles reads a single role directly and several with one List.
+func (h *Handler) loadRoles(ctx context.Context, ids []auth.RoleID) (map[auth.RoleID]*auth.Role, error) {
+	found := make(map[auth.RoleID]*auth.Role, len(ids))
+	if len(ids) == 1 {
+		role, err := h.roles.Get(ctx, ids[0])
+		if errors.Is(err, auth.ErrRoleNotFound) {
+			return found, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		found[role.ID] = role
+		return found, nil
+	}
+
+	roles, err := h.roles.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if slices.Contains(ids, role.ID) {
+			found[role.ID] = role
+		}
+	}
+	return found, nil
+}
+
+type query struct {
+	h *Handler
+}
+
+func (q *query) User(ctx context.Context, args struct {
+	ID       *gql.ID
+	Username *string
+}) (*userResolver, error) {
+	l := loadersFrom(ctx)
+	switch {
+	case args.ID != nil:
+		id, err := auth.ParseUserID(string(*args.ID))
+		if err != nil {
+			return nil, nil
+		}
+		u, err := q.h.users.Get(ctx, id)
+		if errors.Is(err, auth.ErrUserNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		l.users.set(u.Username, u)
+		return &userResolver{u: u}, nil
+	case args.Username != nil:
+		u, err := l.users.load(ctx, auth.NormalizeUsername(*args.Username))
+		if err != nil || u == nil {
+			return nil, err
+		}
+		return &userResolver{u: u}, nil
+	default:
+		return nil, errors.New("id or username is required")
+	}
+}
+
+func (q *query) Users(ctx context.Context, args struct {
+	Username      *string
+	Name          *string
+	Status        *string
+	Role          *string
+	CreatedAfter  *gql.Time
+	CreatedBefore *gql.Time
+	Limit         *int32
+	Offset        *int32
+}) (*userPageResolver, error) {
+	uq := auth.UserQuery{
+		UsernamePrefix: deref(args.Username),
+		Name:           deref(args.Name),
+		Status:         auth.UserStatus(deref(args.Status)),
+		Role:           deref(args.Role),
+	}
+	if args.CreatedAfter != nil {
+		uq.CreatedAfter = args.CreatedAfter.Time
+	}
+	if args.CreatedBefore != nil {
+		uq.CreatedBefore = args.CreatedBefore.Time
+	}
+	if args.Limit != nil {
+		uq.Limit = int(*args.Limit)
+	}
+	if args.Offset != nil {
+		uq.Offset = int(*args.Offset)
+	}
+
+	page, err := service.SearchUsers(ctx, q.h.users, uq)
+	if err != nil {
+		return nil, err
+	}
+
+	l := loadersFrom(ctx)
+	users := make([]*userResolver, len(page.Users))
+	usernames := make([]string, len(page.Users))
+	for i, u := range page.Users {
+		l.users.set(u.Username, u)
+		users[i] = &userResolver{u: u}
+		usernames[i] = u.Username
+	}
+	l.userGrants.prime(usernames...)
+
+	return &userPageResolver{page: page, users: users}, nil
+}
+
+func (q *query) Role(ctx context.Context, args struct {
+	ID   *gql.ID
+	Name *string
+}) (*roleResolver, error) {
+	l := loadersFrom(ctx)
+	switch {
+	case args.ID != nil:
+		id, err := auth.ParseRoleID(string(*args.ID))
+		if err != nil {
+			return nil, nil
+		}
+		role, err := l.roles.load(ctx, id)
+		if err != nil || role == nil {
+			return nil, err
+		}
+		return &roleResolver{r: role}, nil
+	case args.Name != nil:
+		role, err := service.GetRoleByName(ctx, q.h.roles, *args.Name)
+		if errors.Is(err, auth.ErrRoleNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		l.roles.set(role.ID, role)
+		return &roleResolver{r: role}, nil
+	default:
+		return nil, errors.New("id or name is required")
+	}
+}
+
+func (q *query) Roles(ctx context.Context, args struct{ Status *string }) ([]*roleResolver, error) {
+	var roles []*auth.Role
+	var err error
+	if args.Status != nil {
+		roles, err = service.ListRolesByStatus(ctx, q.h.roles, auth.RoleStatus(*args.Status))
+	} else {
+		roles, err = service.ListRoles(ctx, q.h.roles)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	l := loadersFrom(ctx)
+	resolvers := make([]*roleResolver, len(roles))
+	ids := make([]auth.RoleID, len(roles))
+	for i, role := range roles {
+		l.roles.set(role.ID, role)
+		resolvers[i] = &roleResolver{r: role}
+		ids[i] = role.ID
+	}
+	l.roleGrants.prime(ids...)
+	return resolvers, nil
+}
+
+func (q *query) CheckPermission(ctx context.Context, args struct {
+	Username   string
+	Permission string
+	Resource   *string
+}) (bool, error) {
+	return q.h.engine.Authorize(ctx, args.Username, args.Permission, deref(args.Resource))
+}
+
+func (q *query) HasRole(ctx context.Context, args struct {
+	Username string
+	Role     string
+}) (bool, error) {
+	return service.HasRole(ctx, q.h.grants, args.Username, args.Role)
+}
+
+type userPageResolver struct {
+	page  *auth.UserPage
+	users []*userResolver
+}
+
+func (p *userPageResolver) Users() []*userResolver { return p.users }
+func (p *userPageResolver) Total() int32           { return int32(p.page.Total) }
+func (p *userPageResolver) Limit() int32           { return int32(p.page.Limit) }
+func (p *userPageResolver) Offset() int32          { return int32(p.page.Offset) }
+
+type userResolver struct {
+	u *auth.User
+}
+
+func (r *userResolver) ID() gql.ID          { return gql.ID(r.u.ID.String()) }
+func (r *userResolver) Username() string    { return r.u.Username }
+func (r *userResolver) Name() string        { return r.u.Name }
+func (r *userResolver) Status() string      { return string(r.u.Status) }
+func (r *userResolver) CreatedAt() gql.Time { return gql.Time{Time: r.u.CreatedAt} }
+func (r *userResolver) CreatedBy() string   { return r.u.CreatedBy }
+func (r *userResolver) UpdatedAt() gql.Time { return gql.Time{Time: r.u.UpdatedAt} }
+func (r *userResolver) UpdatedBy() string   { return r.u.UpdatedBy }
+
+func (r *userResolver) Grants(ctx context.Context) ([]*grantResolver, error) {
+	return grantResolvers(ctx, loadersFrom(ctx).userGrants, r.u.Username)
+}
+
+func (r *userResolver) Roles(ctx context.Context) ([]*roleResolver, error) {
+	roles, err := r.roles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*roleResolver, len(roles))
+	for i, role := range roles {
+		resolvers[i] = &roleResolver{r: role}
+	}
+	return resolvers, nil
+}
+
+// Permissions lists the permissions of the user's active roles, sorted and
+// without duplicates.
+func (r *userResolver) Permissions(ctx context.Context) ([]string, error) {
+	roles, err := r.roles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	permissions := []string{}
+	for _, role := range roles {
+		if role.Status == auth.RoleStatusActive {
+			permissions = append(permissions, role.GrantedPermissions()...)
+		}
+	}
+	slices.Sort(permissions)
+	return slices.Compact(permissions), nil
+}
+
+func (r *userResolver) roles(ctx context.Context) ([]*auth.Role, error) {
+	l := loadersFrom(ctx)
+	grants, err := l.userGrants.load(ctx, r.u.Username)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]auth.RoleID, len(grants))
+	for i, g := range grants {
+		ids[i] = g.RoleID
+	}
+	roles, err := l.roles.loadAll(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(roles, func(role *auth.Role) bool { return role == nil }), nil
+}
+
+type roleResolver struct {
+	r *auth.Role
+}
+
+func (r *roleResolver) ID() gql.ID            { return gql.ID(r.r.ID.String()) }
+func (r *roleResolver) Name() string          { return r.r.Name }
+func (r *roleResolver) Description() string   { return r.r.Description }
+func (r *roleResolver) Status() string        { return string(r.r.Status) }
+func (r *roleResolver) Permissions() []string { return r.r.Permissions }
+func (r *roleResolver) CreatedAt() gql.Time   { return gql.Time{Time: r.r.CreatedAt} }
+func (r *roleResolver) UpdatedAt() gql.Time   { return gql.Time{Time: r.r.UpdatedAt} }
+
+func (r *roleResolver) Grants(ctx context.Context) ([]*grantResolver, error) {
+	return grantResolvers(ctx, loadersFrom(ctx).roleGrants, r.r.ID)
+}
+
+// grantResolvers loads the grants under key and primes the loaders behind
+// their role and user fields.
+func grantResolvers[K comparable](ctx context.Context, grants *loader[K, []*auth.Grant], key K) ([]*grantResolver, error) {
+	list, err := grants.load(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	l := loadersFrom(ctx)
+	resolvers := make([]*grantResolver, len(list))
+	for i, g := range list {
+		l.roles.prime(g.RoleID)
+		l.users.prime(g.Username)
+		resolvers[i] = &grantResolver{g: g}
+	}
+	return resolvers, nil
+}
+
+type grantResolver struct {
+	g *auth.Grant
+}
+
+func (r *grantResolver) ID() gql.ID           { return gql.ID(r.g.ID.String()) }
+func (r *grantResolver) Username() string     { return r.g.Username }
+func (r *grantResolver) AssignedBy() string   { return r.g.AssignedBy }
+func (r *grantResolver) AssignedAt() gql.Time { return gql.Time{Time: r.g.AssignedAt} }
+
+func (r *grantResolver) Role(ctx context.Context) (*roleResolver, error) {
+	role, err := loadersFrom(ctx).roles.load(ctx, r.g.RoleID)
+	if err != nil || role == nil {
+		return nil, err
+	}
+	return &roleResolver{r: role}, nil
+}
+
+// User is null for grants to service accounts and to deleted users.
+func (r *grantResolver) User(ctx context.Context) (*userResolver, error) {
+	u, err := loadersFrom(ctx).users.load(ctx, r.g.Username)
+	if err != nil || u == nil {
+		return nil, err
+	}
+	return &userResolver{u: u}, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
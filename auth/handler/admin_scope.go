@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+// adminContext returns the context for changing grants on behalf of the
+// caller. With WithDelegatedAdmin it carries the caller's
+// service.AdminScope, narrowed by the scope of their token, and assignedBy is replaced by the caller; otherwise
+// both are returned unchanged. On failure it writes the error, emitting
+// action for subject, and returns false.
+func (h *AuthZHandler) adminContext(w http.ResponseWriter, r *http.Request, action, subject, assignedBy string) (context.Context, string, bool) {
+	ctx := r.Context()
+	if !h.delegatedAdmin {
+		return ctx, assignedBy, true
+	}
+
+	admin, ok := h.principal(w, r)
+	if !ok {
+		return nil, "", false
+	}
+
+	scope, err := service.NewAdminScope(ctx, h.roleStore, h.grantStore, admin, middleware.GetScope(ctx))
+	if err != nil {
+		h.emit(r, action, subject, err)
+		h.handleServiceError(w, r, err)
+		return nil, "", false
+	}
+	return service.WithAdminScope(ctx, scope), admin, true
+}
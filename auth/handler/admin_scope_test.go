@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestWithDelegatedAdmin(t *testing.T) {
+	ctx := context.Background()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+
+	newRole := func(name string, permissions ...string) *auth.Role {
+		t.Helper()
+		role, err := service.CreateRole(ctx, roles, name, "", permissions, "system")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return role
+	}
+	acmeAdmin := newRole("acme-admin", "grants:manage:acme-*")
+	acmeEditor := newRole("acme-editor", "content:write")
+	globex := newRole("globex-editor", "content:write")
+	if _, err := service.AssignRole(ctx, grants, "alice", acmeAdmin.ID, "system"); err != nil {
+		t.Fatal(err)
+	}
+
+	audit := &recordingAudit{}
+	r := chi.NewRouter()
+	NewAuthZHandler(roles, grants, WithDelegatedAdmin(), WithAudit(audit)).RegisterRoutes(r)
+
+	send := func(method, path, user string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, strings.NewReader(string(data)))
+		req.Header.Set("Content-Type", "application/json")
+		if user != "" {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, user))
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	code := func(w *httptest.ResponseRecorder) string {
+		var resp ErrorResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp.Code
+	}
+
+	assign := func(roleID auth.RoleID) AssignRoleRequest {
+		return AssignRoleRequest{Username: "bob", RoleID: roleID.String(), AssignedBy: "forged"}
+	}
+
+	if w := send(http.MethodPost, "/grants", "", assign(acmeEditor.ID)); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous assign = %d, want 401", w.Code)
+	}
+	if w := send(http.MethodPost, "/grants", "bob", assign(acmeEditor.ID)); w.Code != http.StatusForbidden || code(w) != "PERMISSION_DENIED" {
+		t.Errorf("non-admin assign = %d %s, want 403 PERMISSION_DENIED", w.Code, w.Body)
+	}
+	if w := send(http.MethodPost, "/grants", "alice", assign(globex.ID)); w.Code != http.StatusForbidden || code(w) != "ROLE_OUT_OF_SCOPE" {
+		t.Errorf("out of scope assign = %d, want 403 ROLE_OUT_OF_SCOPE", w.Code)
+	}
+
+	w := send(http.MethodPost, "/grants", "alice", assign(acmeEditor.ID))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("in scope assign = %d: %s", w.Code, w.Body)
+	}
+	var created GrantResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.Grant.AssignedBy != "alice" {
+		t.Errorf("assigned_by = %q, want the caller", created.Grant.AssignedBy)
+	}
+
+	batch := AssignRolesRequest{Username: "carol", RoleIDs: []string{acmeEditor.ID.String(), globex.ID.String()}}
+	if w := send(http.MethodPost, "/grants:batch", "alice", batch); w.Code != http.StatusForbidden || code(w) != "ROLE_OUT_OF_SCOPE" {
+		t.Errorf("batch with a role out of scope = %d, want 403 ROLE_OUT_OF_SCOPE", w.Code)
+	}
+
+	if _, err := service.AssignRole(ctx, grants, "bob", globex.ID, "system"); err != nil {
+		t.Fatal(err)
+	}
+	revoke := func(roleID auth.RoleID) RevokeRoleRequest {
+		return RevokeRoleRequest{Username: "bob", RoleID: roleID.String()}
+	}
+	if w := send(http.MethodDelete, "/grants", "alice", revoke(globex.ID)); w.Code != http.StatusForbidden {
+		t.Errorf("out of scope revoke = %d, want 403", w.Code)
+	}
+	if w := send(http.MethodDelete, "/grants", "alice", revoke(acmeEditor.ID)); w.Code != http.StatusNoContent {
+		t.Errorf("in scope revoke = %d: %s", w.Code, w.Body)
+	}
+
+	var denied int
+	for _, e := range audit.events {
+		if e.Err != nil {
+			denied++
+		}
+	}
+	if denied != 4 {
+		t.Errorf("audited failures = %d, want 4", denied)
+	}
+
+	data, _ := json.Marshal(assign(acmeEditor.ID))
+	req := httptest.NewRequest(http.MethodPost, "/grants", strings.NewReader(string(data)))
+	req.Header.Set("Content-Type", "application/json")
+	scoped := context.WithValue(req.Context(), middleware.UserIDKey, "alice")
+	req = req.WithContext(context.WithValue(scoped, middleware.ScopeKey, []string{"content:read"}))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden || code(w) != "PERMISSION_DENIED" {
+		t.Errorf("assign with a token scoped to no grants = %d %s, want 403 PERMISSION_DENIED", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aquamarinepk/aqm/audit"
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/google/uuid"
+)
+
+type storeRecorder struct {
+	store audit.Store
+	log   log.Logger
+}
+
+// NewStoreRecorder returns an AuditRecorder that appends every Event to store,
+// so it can be listed and pruned through the audit admin API. The actor is
+// the authenticated caller when there is one, otherwise the event subject.
+// Events recorded during an impersonated session carry the impersonator in
+// their "impersonator" metadata, events by service accounts carry
+// "actor_type" set to "service_account", and located clients add "country",
+// "region", "city" and "asn" when known. The event's own metadata is
+// recorded as well.
+// Append failures are logged and do not affect the request.
+func NewStoreRecorder(store audit.Store, logger log.Logger) AuditRecorder {
+	if logger == nil {
+		logger = log.NewNoopLogger()
+	}
+	return &storeRecorder{store: store, log: logger}
+}
+
+func (s *storeRecorder) Record(ctx context.Context, e Event) {
+	actor := middleware.GetUserID(ctx)
+	if actor == "" {
+		actor = e.Subject
+	}
+
+	event := &audit.Event{
+		ID:       uuid.New(),
+		At:       e.At.UTC(),
+		Actor:    actor,
+		Action:   e.Action,
+		Resource: e.Subject,
+		Outcome:  audit.OutcomeSuccess,
+		RemoteIP: e.RemoteIP,
+	}
+	setMetadata := func(key, value string) {
+		if value == "" {
+			return
+		}
+		if event.Metadata == nil {
+			event.Metadata = map[string]string{}
+		}
+		event.Metadata[key] = value
+	}
+	for key, value := range e.Metadata {
+		setMetadata(key, value)
+	}
+	setMetadata("impersonator", e.Impersonator)
+	if auth.IsServiceAccountPrincipal(actor) {
+		setMetadata("actor_type", "service_account")
+	}
+	if loc := e.Location; loc != nil {
+		setMetadata("country", loc.CountryCode)
+		setMetadata("region", loc.Region)
+		setMetadata("city", loc.City)
+		if loc.ASN != 0 {
+			setMetadata("asn", strconv.FormatUint(uint64(loc.ASN), 10))
+		}
+	}
+	if e.Err != nil {
+		event.Outcome = audit.OutcomeFailure
+		event.Error = e.Err.Error()
+	}
+
+	if err := s.store.Append(ctx, event); err != nil {
+		s.log.Error("Cannot record audit event", "action", e.Action, "error", err)
+	}
+}
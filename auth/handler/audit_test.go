@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/audit"
+	"github.com/aquamarinepk/aqm/audit/fake"
+	"github.com/aquamarinepk/aqm/geoip"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+func TestStoreRecorder(t *testing.T) {
+	store := fake.NewStore()
+	rec := NewStoreRecorder(store, nil)
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	adminCtx := context.WithValue(context.Background(), middleware.UserIDKey, "admin")
+	rec.Record(adminCtx, Event{Action: ActionRoleAssigned, Subject: "alice", RemoteIP: "10.0.0.1", At: at})
+	rec.Record(context.Background(), Event{Action: ActionSignIn, Subject: "bob", Err: errors.New("invalid credentials"), At: at.Add(time.Minute)})
+
+	events, err := store.List(context.Background(), audit.Filter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+
+	signin, grant := events[0], events[1]
+
+	if grant.Actor != "admin" || grant.Resource != "alice" || grant.Outcome != audit.OutcomeSuccess || grant.RemoteIP != "10.0.0.1" {
+		t.Errorf("grant event = %+v", grant)
+	}
+	if signin.Actor != "bob" || signin.Outcome != audit.OutcomeFailure || signin.Error != "invalid credentials" {
+		t.Errorf("signin event = %+v", signin)
+	}
+}
+
+func TestStoreRecorderImpersonator(t *testing.T) {
+	store := fake.NewStore()
+	rec := NewStoreRecorder(store, nil)
+
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "alice")
+	rec.Record(ctx, Event{Action: ActionUserUpdated, Subject: "alice", Impersonator: "support", At: time.Now()})
+
+	events, _ := store.List(context.Background(), audit.Filter{})
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if events[0].Actor != "alice" || events[0].Metadata["impersonator"] != "support" {
+		t.Errorf("event = %+v, want actor alice impersonated by support", events[0])
+	}
+}
+
+func TestStoreRecorderServiceAccount(t *testing.T) {
+	store := fake.NewStore()
+	rec := NewStoreRecorder(store, nil)
+
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "sa:billing-worker")
+	rec.Record(ctx, Event{Action: ActionRoleAssigned, Subject: "alice", At: time.Now()})
+	rec.Record(context.Background(), Event{Action: ActionSignIn, Subject: "bob", At: time.Now()})
+
+	events, _ := store.List(context.Background(), audit.Filter{Actor: "sa:billing-worker"})
+	if len(events) != 1 || events[0].Metadata["actor_type"] != "service_account" {
+		t.Fatalf("events = %+v, want one service account event", events)
+	}
+
+	events, _ = store.List(context.Background(), audit.Filter{Actor: "bob"})
+	if len(events) != 1 || events[0].Metadata["actor_type"] != "" {
+		t.Errorf("events = %+v, want one user event without actor_type", events)
+	}
+}
+
+func TestStoreRecorderLocation(t *testing.T) {
+	store := fake.NewStore()
+	rec := NewStoreRecorder(store, nil)
+
+	loc := &geoip.Location{CountryCode: "DE", City: "Berlin", ASN: 64500}
+	rec.Record(context.Background(), Event{Action: ActionSignIn, Subject: "alice", RemoteIP: "203.0.113.9", Location: loc, At: time.Now()})
+
+	events, _ := store.List(context.Background(), audit.Filter{})
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	want := map[string]string{"country": "DE", "city": "Berlin", "asn": "64500"}
+	if len(events[0].Metadata) != len(want) {
+		t.Fatalf("metadata = %v, want %v", events[0].Metadata, want)
+	}
+	for k, v := range want {
+		if events[0].Metadata[k] != v {
+			t.Errorf("metadata[%s] = %q, want %q", k, events[0].Metadata[k], v)
+		}
+	}
+}
+
+func TestStoreRecorderMetadata(t *testing.T) {
+	store := fake.NewStore()
+	rec := NewStoreRecorder(store, nil)
+
+	rec.Record(context.Background(), Event{
+		Action:   ActionSignInRisk,
+		Subject:  "alice",
+		Metadata: map[string]string{"risk_decision": "flag", "risk_reasons": ""},
+		At:       time.Now(),
+	})
+
+	events, _ := store.List(context.Background(), audit.Filter{})
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if got := events[0].Metadata; got["risk_decision"] != "flag" || len(got) != 1 {
+		t.Errorf("metadata = %v, want only risk_decision", got)
+	}
+}
@@ -1,13 +1,20 @@
 package handler
 
 import (
-	"encoding/json"
+	"context"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aquamarinepk/aqm/auth"
 	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/mail"
+	"github.com/aquamarinepk/aqm/validation"
 	"github.com/go-chi/chi/v5"
-	"github.com/google/uuid"
 )
 
 type AuthNHandler struct {
@@ -16,14 +23,18 @@ type AuthNHandler struct {
 	tokenGen  service.TokenGenerator
 	pwdGen    service.PasswordGenerator
 	pinGen    service.PINGenerator
+	options
 }
 
+// NewAuthNHandler creates an authentication handler. Optional features such as
+// hooks, rate limiting, and auditing are attached with Option values.
 func NewAuthNHandler(
 	userStore auth.UserStore,
 	crypto service.CryptoService,
 	tokenGen service.TokenGenerator,
 	pwdGen service.PasswordGenerator,
 	pinGen service.PINGenerator,
+	opts ...Option,
 ) *AuthNHandler {
 	return &AuthNHandler{
 		userStore: userStore,
@@ -31,21 +42,93 @@ func NewAuthNHandler(
 		tokenGen:  tokenGen,
 		pwdGen:    pwdGen,
 		pinGen:    pinGen,
+		options:   newOptions(opts),
 	}
 }
 
 func (h *AuthNHandler) RegisterRoutes(r chi.Router) {
-	r.Post("/auth/signup", h.handleSignUp)
-	r.Post("/auth/signin", h.handleSignIn)
-	r.Post("/auth/signin-pin", h.handleSignInByPIN)
-	r.Post("/auth/bootstrap", h.handleBootstrap)
-	r.Post("/auth/generate-pin", h.handleGeneratePIN)
+	r.Post("/auth/signup", h.limit(h.handleSignUp))
+	r.Post("/auth/signin", h.limit(h.handleSignIn))
+	r.Post("/auth/signin-pin", h.limit(h.handleSignInByPIN))
+	h.internalRoutes(r).Post("/auth/bootstrap", h.limit(h.handleBootstrap))
+	r.Post("/auth/generate-pin", h.limit(h.handleGeneratePIN))
+
+	if h.validator != nil {
+		r.Post("/auth/verify", h.handleVerifyToken)
+	}
+	if h.validator != nil && h.tokenExchange != nil {
+		r.Post("/auth/token/exchange", h.handleTokenExchange)
+	}
+	if len(h.keys) > 0 {
+		r.Get("/.well-known/jwks.json", h.handleKeySet)
+	}
+	if h.impersonate != nil {
+		r.Post("/auth/impersonate", h.handleImpersonate)
+	}
+	if h.serviceAccounts != nil {
+		h.registerServiceAccountRoutes(r)
+	}
+
+	if h.magicLinks != nil && h.mailer != nil {
+		r.Post("/auth/magic-link", h.limit(h.handleRequestMagicLink))
+		r.Get("/auth/magic-link/verify", h.limit(h.handleMagicLinkSignIn))
+	}
 
+	if h.oauth != nil && h.identities != nil {
+		r.Get("/auth/oauth/{provider}/start", h.limit(h.handleOAuthStart))
+		r.Get("/auth/oauth/{provider}/callback", h.limit(h.handleOAuthCallback))
+	}
+
+	r.Get("/users/search", h.handleSearchUsers)
+	r.Get("/users/export", h.handleExportUsers)
+	r.Get("/users/me", h.handleGetMe)
+	r.Patch("/users/me", h.handlePatchMe)
 	r.Get("/users/{id}", h.handleGetUser)
 	r.Get("/users/username/{username}", h.handleGetUserByUsername)
 	r.Get("/users", h.handleListUsers)
 	r.Put("/users/{id}", h.handleUpdateUser)
+	r.Patch("/users/{id}", h.handlePatchUser)
+	r.Get("/users/{id}/attributes", h.handleGetUserAttributes)
+	r.Patch("/users/{id}/attributes", h.handlePatchUserAttributes)
 	r.Delete("/users/{id}", h.handleDeleteUser)
+	r.Post("/users/{id}/suspend", h.handleSuspendUser)
+	r.Post("/users/{id}/reactivate", h.handleReactivateUser)
+	r.Post("/users/{id}/password", h.handleResetPassword)
+	r.Post("/users/me/password", h.limit(h.handleChangePassword))
+	r.Post("/users/{id}/username", h.handleChangeUsername)
+	r.Post("/users/me/username", h.handleChangeUsername)
+
+	if h.emailChanges != nil && h.mailer != nil {
+		r.Post("/users/{id}/email", h.handleRequestEmailChange)
+		r.Post("/users/me/email", h.handleRequestEmailChange)
+		r.Get("/auth/email/verify", h.limit(h.handleVerifyEmailChange))
+		r.Get("/auth/email/revert", h.limit(h.handleRevertEmailChange))
+	}
+
+	if h.revocations != nil {
+		r.Post("/auth/logout", h.handleLogout)
+		r.Post("/users/{id}/logout-all", h.handleLogoutAll)
+		r.Post("/users/me/logout-all", h.handleLogoutAll)
+	}
+
+	if h.settings != nil {
+		r.Get("/settings", h.handleGetSettings)
+		r.Put("/settings", h.handleUpdateSettings)
+	}
+
+	if h.devices != nil {
+		h.registerDeviceRoutes(r)
+	}
+
+	if h.consents != nil {
+		h.registerConsentRoutes(r)
+	}
+
+	if h.avatars != nil {
+		r.Put("/users/{id}/avatar", h.handleUploadAvatar)
+		r.Get("/users/{id}/avatar", h.handleGetAvatar)
+		r.Delete("/users/{id}/avatar", h.handleDeleteAvatar)
+	}
 }
 
 type SignUpRequest struct {
@@ -61,42 +144,93 @@ type SignUpResponse struct {
 
 func (h *AuthNHandler) handleSignUp(w http.ResponseWriter, r *http.Request) {
 	var req SignUpRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	if !h.bind(w, r, &req) {
 		return
 	}
 
-	user, err := service.SignUp(
-		r.Context(),
-		h.userStore,
-		h.crypto,
-		req.Email,
-		req.Password,
-		req.Username,
-		req.DisplayName,
-	)
+	user, err := h.signUp(r.Context(), req)
 	if err != nil {
-		handleServiceError(w, err)
+		h.emit(r, ActionSignUp, req.Username, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
+	h.emit(r, ActionSignUp, user.ID.String(), nil)
+	h.sendMail(r, user, mail.TemplateWelcome, mailData{})
 
-	writeJSON(w, http.StatusCreated, SignUpResponse{User: user})
+	httpx.WriteJSON(w, http.StatusCreated, SignUpResponse{User: user})
 }
 
+// signUp creates the user in req, with the roles set by WithSignUpRoles.
+// With WithUsernameHistory, recently released usernames are refused, and
+// emails are refused unless their domain is allowed by the settings or
+// WithEmailDomains.
+func (h *AuthNHandler) signUp(ctx context.Context, req SignUpRequest) (*auth.User, error) {
+	settings, err := h.currentSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := settings.EmailDomainsOr(h.emailDomains).Check(req.Email); err != nil {
+		return nil, err
+	}
+	if err := settings.CheckPassword(req.Password); err != nil {
+		return nil, err
+	}
+
+	if h.usernames != nil {
+		if err := service.CheckUsernameReleased(ctx, h.usernames.store, h.usernames.hold, req.Username, auth.UserID{}); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.signUpRoles == nil {
+		return service.SignUp(ctx, h.userStore, h.crypto, req.Email, req.Password, req.Username, req.DisplayName)
+	}
+
+	sr := h.signUpRoles
+	return service.SignUpWithRoles(ctx, h.tx, h.userStore, sr.roles, sr.grants, h.crypto, sr.names,
+		req.Email, req.Password, req.Username, req.DisplayName)
+}
+
+// SignInRequest holds the credentials of /auth/signin. Device and
+// DeviceToken are used with WithDevices and ignored otherwise.
 type SignInRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email       string         `json:"email"`
+	Password    string         `json:"password"`
+	Device      *DeviceRequest `json:"device,omitempty"`
+	DeviceToken string         `json:"device_token,omitempty"`
 }
 
+// SignInResponse carries the session token. With WithDevices, Device is the
+// device signed in from, DeviceToken the new trusted-device token when one
+// was asked for, and TrustedDevice reports that a valid trusted-device token
+// was presented. MFARequired reports that a second factor is needed, as the
+// settings of WithSettings require it off a trusted device or
+// WithRiskEvaluator asked for one; Token is then an MFA-pending token, or
+// empty, and no DeviceToken is issued.
 type SignInResponse struct {
-	User  *auth.User `json:"user"`
-	Token string     `json:"token"`
+	User          *auth.User   `json:"user"`
+	Token         string       `json:"token"`
+	Device        *auth.Device `json:"device,omitempty"`
+	DeviceToken   string       `json:"device_token,omitempty"`
+	TrustedDevice bool         `json:"trusted_device,omitempty"`
+	MFARequired   bool         `json:"mfa_required,omitempty"`
 }
 
 func (h *AuthNHandler) handleSignIn(w http.ResponseWriter, r *http.Request) {
 	var req SignInRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	if !h.bind(w, r, &req) {
+		return
+	}
+	if h.devices != nil && req.Device != nil {
+		if err := auth.ValidateDeviceFingerprint(strings.TrimSpace(req.Device.Fingerprint)); err != nil {
+			h.handleServiceError(w, r, err)
+			return
+		}
+	}
+
+	settings, err := h.currentSettings(r.Context())
+	if err != nil {
+		h.handleServiceError(w, r, err)
 		return
 	}
 
@@ -104,16 +238,45 @@ func (h *AuthNHandler) handleSignIn(w http.ResponseWriter, r *http.Request) {
 		r.Context(),
 		h.userStore,
 		h.crypto,
-		h.tokenGen,
+		h.sessionTokens(settings),
 		req.Email,
 		req.Password,
 	)
 	if err != nil {
-		handleServiceError(w, err)
+		h.emit(r, ActionSignIn, "", err)
+		h.handleServiceError(w, r, err)
 		return
 	}
-
-	writeJSON(w, http.StatusOK, SignInResponse{User: user, Token: token})
+	h.emit(r, ActionSignIn, user.ID.String(), nil)
+
+	resp := SignInResponse{User: user, Token: token}
+	if h.devices != nil {
+		if err := h.signInDevice(r, user, req, &resp); err != nil {
+			h.handleServiceError(w, r, err)
+			return
+		}
+	}
+	fingerprint := ""
+	switch {
+	case resp.Device != nil:
+		fingerprint = resp.Device.Fingerprint
+	case req.Device != nil:
+		fingerprint = strings.TrimSpace(req.Device.Fingerprint)
+	}
+	stepUp, err := h.assessSignIn(r, user, fingerprint, resp.TrustedDevice)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	if stepUp || (settings.RequiresMFA(false) && !resp.TrustedDevice) {
+		if resp.Token, err = h.mfaPendingToken(user.ID); err != nil {
+			h.handleServiceError(w, r, err)
+			return
+		}
+		resp.DeviceToken = ""
+		resp.MFARequired = true
+	}
+	httpx.WriteJSON(w, http.StatusOK, resp)
 }
 
 type SignInByPINRequest struct {
@@ -126,18 +289,19 @@ type SignInByPINResponse struct {
 
 func (h *AuthNHandler) handleSignInByPIN(w http.ResponseWriter, r *http.Request) {
 	var req SignInByPINRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	if !h.bind(w, r, &req) {
 		return
 	}
 
 	user, err := service.SignInByPIN(r.Context(), h.userStore, h.crypto, req.PIN)
 	if err != nil {
-		handleServiceError(w, err)
+		h.emit(r, ActionSignInByPIN, "", err)
+		h.handleServiceError(w, r, err)
 		return
 	}
+	h.emit(r, ActionSignInByPIN, user.ID.String(), nil)
 
-	writeJSON(w, http.StatusOK, SignInByPINResponse{User: user})
+	httpx.WriteJSON(w, http.StatusOK, SignInByPINResponse{User: user})
 }
 
 type BootstrapResponse struct {
@@ -146,13 +310,19 @@ type BootstrapResponse struct {
 }
 
 func (h *AuthNHandler) handleBootstrap(w http.ResponseWriter, r *http.Request) {
+	if !h.allowBootstrap(w, r) {
+		return
+	}
+
 	user, password, err := service.Bootstrap(r.Context(), h.userStore, h.crypto, h.pwdGen)
 	if err != nil {
-		handleServiceError(w, err)
+		h.emit(r, ActionBootstrap, "", err)
+		h.handleServiceError(w, r, err)
 		return
 	}
+	h.emit(r, ActionBootstrap, user.ID.String(), nil)
 
-	writeJSON(w, http.StatusOK, BootstrapResponse{User: user, Password: password})
+	httpx.WriteJSON(w, http.StatusOK, BootstrapResponse{User: user, Password: password})
 }
 
 type GeneratePINRequest struct {
@@ -165,30 +335,31 @@ type GeneratePINResponse struct {
 
 func (h *AuthNHandler) handleGeneratePIN(w http.ResponseWriter, r *http.Request) {
 	var req GeneratePINRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	if !h.bind(w, r, &req) {
 		return
 	}
 
-	userID, err := uuid.Parse(req.UserID)
+	userID, err := auth.ParseUserID(req.UserID)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
 		return
 	}
 
 	user, err := service.GetUserByID(r.Context(), h.userStore, userID)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
 	pin, err := service.GeneratePIN(r.Context(), h.userStore, h.crypto, h.pinGen, user)
+	h.emit(r, ActionGeneratePIN, user.ID.String(), err)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
+	h.sendMail(r, user, mail.TemplatePIN, mailData{PIN: pin})
 
-	writeJSON(w, http.StatusOK, GeneratePINResponse{PIN: pin})
+	httpx.WriteJSON(w, http.StatusOK, GeneratePINResponse{PIN: pin})
 }
 
 type UserResponse struct {
@@ -197,19 +368,20 @@ type UserResponse struct {
 
 func (h *AuthNHandler) handleGetUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
-	userID, err := uuid.Parse(idStr)
+	userID, err := auth.ParseUserID(idStr)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
 		return
 	}
 
 	user, err := service.GetUserByID(r.Context(), h.userStore, userID)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, UserResponse{User: user})
+	setETag(w, user.Version)
+	httpx.WriteJSON(w, http.StatusOK, UserResponse{User: user})
 }
 
 func (h *AuthNHandler) handleGetUserByUsername(w http.ResponseWriter, r *http.Request) {
@@ -217,11 +389,11 @@ func (h *AuthNHandler) handleGetUserByUsername(w http.ResponseWriter, r *http.Re
 
 	user, err := service.GetUserByUsername(r.Context(), h.userStore, username)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, UserResponse{User: user})
+	httpx.WriteJSON(w, http.StatusOK, UserResponse{User: user})
 }
 
 type ListUsersResponse struct {
@@ -230,6 +402,12 @@ type ListUsersResponse struct {
 
 func (h *AuthNHandler) handleListUsers(w http.ResponseWriter, r *http.Request) {
 	status := r.URL.Query().Get("status")
+	if status != "" && !auth.UserStatus(status).IsValid() {
+		var errs validation.ValidationErrors
+		errs.AddCode("status", validation.CodeOneOf, "Unknown status "+status)
+		h.writeValidationError(w, r, "INVALID_QUERY", errs)
+		return
+	}
 
 	var users []*auth.User
 	var err error
@@ -241,58 +419,385 @@ func (h *AuthNHandler) handleListUsers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, ListUsersResponse{Users: users})
+	httpx.WriteJSON(w, http.StatusOK, ListUsersResponse{Users: users})
+}
+
+type SearchUsersResponse struct {
+	Users  []*auth.User `json:"data"`
+	Total  int          `json:"total"`
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+}
+
+// handleSearchUsers serves GET /users/search. Supported query parameters:
+// username (prefix), name (substring), status, role, created_after and
+// created_before (RFC 3339), limit, offset, and attr.<key> to match an
+// attribute value, e.g. attr.locale=en-GB.
+func (h *AuthNHandler) handleSearchUsers(w http.ResponseWriter, r *http.Request) {
+	q, errs := parseUserQuery(r)
+	if errs.HasErrors() {
+		h.writeValidationError(w, r, "INVALID_QUERY", errs)
+		return
+	}
+
+	page, err := service.SearchUsers(r.Context(), h.userStore, q)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, SearchUsersResponse{
+		Users:  page.Users,
+		Total:  page.Total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	})
+}
+
+// attributeQueryPrefix marks the search parameters that filter on a user
+// attribute.
+const attributeQueryPrefix = "attr."
+
+// parseUserQuery reads the GET /users/search parameters, reporting every
+// malformed one rather than stopping at the first.
+func parseUserQuery(r *http.Request) (auth.UserQuery, validation.ValidationErrors) {
+	v := r.URL.Query()
+	q := auth.UserQuery{
+		UsernamePrefix: v.Get("username"),
+		Name:           v.Get("name"),
+		Status:         auth.UserStatus(v.Get("status")),
+		Role:           v.Get("role"),
+	}
+
+	var errs validation.ValidationErrors
+	var err error
+	if q.CreatedAfter, err = parseQueryTime(v.Get("created_after")); err != nil {
+		errs.AddCode("created_after", validation.CodeFormat, "must be an RFC 3339 timestamp")
+	}
+	if q.CreatedBefore, err = parseQueryTime(v.Get("created_before")); err != nil {
+		errs.AddCode("created_before", validation.CodeFormat, "must be an RFC 3339 timestamp")
+	}
+	if q.Limit, err = parseQueryInt(v.Get("limit")); err != nil {
+		errs.AddCode("limit", validation.CodeFormat, "must be an integer")
+	}
+	if q.Offset, err = parseQueryInt(v.Get("offset")); err != nil {
+		errs.AddCode("offset", validation.CodeFormat, "must be an integer")
+	}
+	for _, name := range slices.Sorted(maps.Keys(v)) {
+		key, ok := strings.CutPrefix(name, attributeQueryPrefix)
+		if !ok {
+			continue
+		}
+		if err := auth.ValidateAttributeKey(key); err != nil {
+			errs.AddCode(name, validation.CodeFormat, "must name a valid attribute key")
+			continue
+		}
+		if q.Attributes == nil {
+			q.Attributes = map[string]string{}
+		}
+		q.Attributes[key] = v.Get(name)
+	}
+	return q, errs
+}
+
+func parseQueryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func parseQueryInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
 }
 
 type UpdateUserRequest struct {
 	Name string `json:"name"`
 }
 
+// handleUpdateUser serves PUT /users/{id}. The If-Match header must carry
+// the ETag returned by GET /users/{id}; a stale ETag fails with 412.
 func (h *AuthNHandler) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
-	userID, err := uuid.Parse(idStr)
+	userID, err := auth.ParseUserID(idStr)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
 		return
 	}
 
 	var req UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	ifMatch, ok := h.requireIfMatch(w, r)
+	if !ok {
 		return
 	}
 
 	user, err := service.GetUserByID(r.Context(), h.userStore, userID)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	if !matchesIfMatch(ifMatch, user.Version) {
+		h.emit(r, ActionUserUpdated, userID.String(), auth.ErrVersionConflict)
+		h.handleServiceError(w, r, auth.ErrVersionConflict)
 		return
 	}
 
 	user.Name = req.Name
-	if err := service.UpdateUser(r.Context(), h.userStore, user); err != nil {
-		handleServiceError(w, err)
+	err = service.UpdateUser(r.Context(), h.userStore, user)
+	h.emit(r, ActionUserUpdated, userID.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, user.Version)
+	httpx.WriteJSON(w, http.StatusOK, UserResponse{User: user})
+}
+
+// handlePatchUser serves PATCH /users/{id}, applying a JSON Merge Patch
+// (RFC 7386) of name, status and attributes. If-Match is optional; when sent, a stale
+// ETag fails with 412.
+func (h *AuthNHandler) handlePatchUser(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	userID, err := auth.ParseUserID(idStr)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		return
+	}
+
+	patch, err := decodeMergePatch(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
 		return
 	}
 
-	writeJSON(w, http.StatusOK, UserResponse{User: user})
+	h.patchUser(w, r, userID, patch)
+}
+
+// patchUser applies patch to the user with userID and writes the result.
+func (h *AuthNHandler) patchUser(w http.ResponseWriter, r *http.Request, userID auth.UserID, patch mergePatch) {
+	user, err := service.GetUserByID(r.Context(), h.userStore, userID)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && !matchesIfMatch(ifMatch, user.Version) {
+		h.emit(r, ActionUserUpdated, userID.String(), auth.ErrVersionConflict)
+		h.handleServiceError(w, r, auth.ErrVersionConflict)
+		return
+	}
+
+	if err := applyUserPatch(user, patch); err != nil {
+		h.emit(r, ActionUserUpdated, userID.String(), err)
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_PATCH", err.Error())
+		return
+	}
+	if err := auth.ValidateDisplayName(user.Name); err != nil {
+		h.emit(r, ActionUserUpdated, userID.String(), err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+	if err := auth.ValidateAttributes(user.Attributes); err != nil {
+		h.emit(r, ActionUserUpdated, userID.String(), err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	err = service.UpdateUser(r.Context(), h.userStore, user)
+	h.emit(r, ActionUserUpdated, userID.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, user.Version)
+	httpx.WriteJSON(w, http.StatusOK, UserResponse{User: user})
+}
+
+type UserAttributesResponse struct {
+	Attributes auth.Attributes `json:"data"`
+}
+
+// handleGetUserAttributes serves GET /users/{id}/attributes with the same
+// ETag as GET /users/{id}.
+func (h *AuthNHandler) handleGetUserAttributes(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	userID, err := auth.ParseUserID(idStr)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		return
+	}
+
+	user, err := service.GetUserByID(r.Context(), h.userStore, userID)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, user.Version)
+	httpx.WriteJSON(w, http.StatusOK, userAttributesResponse(user))
+}
+
+// handlePatchUserAttributes serves PATCH /users/{id}/attributes. The body is
+// a JSON Merge Patch of the attributes: members set keys, null removes them
+// and objects merge into stored objects. If-Match is optional; when sent, a
+// stale ETag fails with 412.
+func (h *AuthNHandler) handlePatchUserAttributes(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	userID, err := auth.ParseUserID(idStr)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		return
+	}
+
+	patch, err := decodeAttributesPatch(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+
+	user, err := service.GetUserByID(r.Context(), h.userStore, userID)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && !matchesIfMatch(ifMatch, user.Version) {
+		h.emit(r, ActionUserUpdated, userID.String(), auth.ErrVersionConflict)
+		h.handleServiceError(w, r, auth.ErrVersionConflict)
+		return
+	}
+
+	err = service.UpdateUserAttributes(r.Context(), h.userStore, user, patch)
+	h.emit(r, ActionUserUpdated, userID.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, user.Version)
+	httpx.WriteJSON(w, http.StatusOK, userAttributesResponse(user))
+}
+
+// userAttributesResponse answers an empty object rather than null for a user
+// without attributes.
+func userAttributesResponse(user *auth.User) UserAttributesResponse {
+	attrs := user.Attributes
+	if attrs == nil {
+		attrs = auth.Attributes{}
+	}
+	return UserAttributesResponse{Attributes: attrs}
 }
 
 func (h *AuthNHandler) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
-	userID, err := uuid.Parse(idStr)
+	userID, err := auth.ParseUserID(idStr)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
 		return
 	}
 
-	if err := service.DeleteUser(r.Context(), h.userStore, userID); err != nil {
-		handleServiceError(w, err)
+	err = service.DeleteUser(r.Context(), h.userStore, userID)
+	h.emit(r, ActionUserDeleted, userID.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+type ResetPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+type ResetPasswordResponse struct {
+	Password string `json:"password,omitempty"`
+}
+
+// handleResetPassword serves POST /users/{id}/password. An empty password
+// generates a random one, which is returned; a given password is not echoed.
+// With WithRevocations, every token issued to the user so far is revoked.
+func (h *AuthNHandler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	userID, err := auth.ParseUserID(idStr)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		return
+	}
+
+	var req ResetPasswordRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	password, err := h.resetPassword(r.Context(), userID, req.Password)
+	h.emit(r, ActionPasswordReset, userID.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	if err := h.revokeUserTokens(r.Context(), userID.String()); err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	var resp ResetPasswordResponse
+	if req.Password == "" {
+		resp.Password = password
+	}
+	if user, err := service.GetUserByID(r.Context(), h.userStore, userID); err == nil {
+		h.sendMail(r, user, mail.TemplatePasswordReset, mailData{Password: resp.Password})
+	}
+	httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+// mailData is what the mail templates can use. Name and Username are
+// filled in by sendMail.
+type mailData struct {
+	Name      string
+	Username  string
+	PIN       string
+	Password  string
+	Link      string
+	ExpiresIn string
+}
+
+// sendMail emails user the named template when a mailer is configured.
+func (h *AuthNHandler) sendMail(r *http.Request, user *auth.User, name string, data mailData) {
+	if h.mailer == nil {
+		return
+	}
+
+	email, err := user.GetEmail(h.crypto.EncryptionKey())
+	if err != nil {
+		h.emit(r, ActionMailSent, user.ID.String(), err)
+		return
+	}
+	h.sendMailTo(r, user, email, name, data)
+}
+
+// sendMailTo is like sendMail but emails address instead of the user's
+// stored email.
+func (h *AuthNHandler) sendMailTo(r *http.Request, user *auth.User, address, name string, data mailData) {
+	if h.mailer == nil {
+		return
+	}
+
+	data.Name, data.Username = user.Name, user.Username
+	err := h.mailer.Send(r.Context(), address, name, data)
+	h.emit(r, ActionMailSent, user.ID.String(), err)
+}
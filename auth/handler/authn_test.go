@@ -6,9 +6,15 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/aquamarinepk/aqm/auth"
 	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/validation"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -286,11 +292,8 @@ func TestHandleBootstrap(t *testing.T) {
 	w2 := httptest.NewRecorder()
 	handler.handleBootstrap(w2, req2)
 
-	var resp2 BootstrapResponse
-	json.NewDecoder(w2.Body).Decode(&resp2)
-
-	if resp2.Password != "" {
-		t.Error("handleBootstrap() second call should return empty password")
+	if w2.Code != http.StatusGone {
+		t.Errorf("handleBootstrap() second call status = %v, want %v", w2.Code, http.StatusGone)
 	}
 }
 
@@ -398,6 +401,11 @@ func TestHandleListUsers(t *testing.T) {
 			wantStatus: http.StatusOK,
 			wantMin:    3,
 		},
+		{
+			name:       "unknown status",
+			status:     "archived",
+			wantStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -426,6 +434,87 @@ func TestHandleListUsers(t *testing.T) {
 	}
 }
 
+func TestHandleSearchUsers(t *testing.T) {
+	handler := setupAuthNHandler()
+
+	for _, u := range []struct{ username, name string }{
+		{"ann", "Ann Smith"},
+		{"anna", "Anna Jones"},
+		{"bob", "Bob Smith"},
+	} {
+		body, _ := json.Marshal(SignUpRequest{
+			Email:       u.username + "@example.com",
+			Password:    "Password123!",
+			Username:    u.username,
+			DisplayName: u.name,
+		})
+		w := httptest.NewRecorder()
+		handler.handleSignUp(w, httptest.NewRequest(http.MethodPost, "/auth/signup", bytes.NewReader(body)))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Failed to create test user %s: %v", u.username, w.Body.String())
+		}
+	}
+	ann, _ := handler.userStore.GetByUsername(context.Background(), "ann")
+	if err := service.UpdateUserAttributes(context.Background(), handler.userStore, ann, map[string]any{"locale": "en-GB", "seats": 5}); err != nil {
+		t.Fatalf("Failed to set attributes: %v", err)
+	}
+
+	r := chi.NewRouter()
+	handler.RegisterRoutes(r)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantCode   string
+		wantFields []string
+		wantCount  int
+		wantTotal  int
+	}{
+		{name: "username prefix", query: "username=ann", wantStatus: http.StatusOK, wantCount: 2, wantTotal: 2},
+		{name: "name and status", query: "name=smith&status=active", wantStatus: http.StatusOK, wantCount: 2, wantTotal: 2},
+		{name: "paginated", query: "limit=1&offset=1", wantStatus: http.StatusOK, wantCount: 1, wantTotal: 3},
+		{name: "created before", query: "created_before=2000-01-01T00:00:00Z", wantStatus: http.StatusOK, wantCount: 0, wantTotal: 0},
+		{name: "invalid time", query: "created_after=yesterday", wantStatus: http.StatusBadRequest, wantCode: "INVALID_QUERY"},
+		{name: "invalid limit", query: "limit=ten", wantStatus: http.StatusBadRequest, wantCode: "INVALID_QUERY"},
+		{name: "limit too large", query: "limit=100000", wantStatus: http.StatusBadRequest, wantCode: "INVALID_QUERY"},
+		{name: "every bad parameter", query: "created_after=yesterday&limit=ten&offset=x", wantStatus: http.StatusBadRequest, wantCode: "INVALID_QUERY", wantFields: []string{"created_after", "limit", "offset"}},
+		{name: "attributes", query: "attr.locale=en-GB&attr.seats=5", wantStatus: http.StatusOK, wantCount: 1, wantTotal: 1},
+		{name: "attribute mismatch", query: "attr.locale=de-DE", wantStatus: http.StatusOK, wantCount: 0, wantTotal: 0},
+		{name: "invalid attribute key", query: "attr.Locale=en", wantStatus: http.StatusBadRequest, wantCode: "INVALID_QUERY", wantFields: []string{"attr.Locale"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/search?"+tt.query, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("GET /users/search status = %v, want %v: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantCode != "" {
+				var resp ErrorResponse
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp.Code != tt.wantCode {
+					t.Errorf("error code = %v, want %v", resp.Code, tt.wantCode)
+				}
+				if tt.wantFields != nil {
+					if got := validation.ValidationErrors(resp.Errors).Fields(); !slices.Equal(got, tt.wantFields) {
+						t.Errorf("error fields = %v, want %v", got, tt.wantFields)
+					}
+				}
+				return
+			}
+
+			var resp SearchUsersResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if len(resp.Users) != tt.wantCount || resp.Total != tt.wantTotal {
+				t.Errorf("GET /users/search = %d users, total %d; want %d, total %d", len(resp.Users), resp.Total, tt.wantCount, tt.wantTotal)
+			}
+		})
+	}
+}
+
 func TestHandleInvalidJSON(t *testing.T) {
 	handler := setupAuthNHandler()
 
@@ -639,6 +728,46 @@ func TestHandleGeneratePIN(t *testing.T) {
 	}
 }
 
+func TestUserResponseEncoder(t *testing.T) {
+	httpx.SetEncoder(httpx.NewJSONEncoder(
+		httpx.WithFieldNaming(httpx.CamelCase),
+		httpx.WithTimeFormat(httpx.TimeEpochSeconds),
+	))
+	t.Cleanup(func() { httpx.SetEncoder(nil) })
+
+	handler := setupAuthNHandler()
+	r := chi.NewRouter()
+	handler.RegisterRoutes(r)
+
+	signupBody, _ := json.Marshal(SignUpRequest{
+		Email:       "encoder@example.com",
+		Password:    "Password123!",
+		Username:    "encoderuser",
+		DisplayName: "Encoder User",
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/signup", bytes.NewReader(signupBody)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("signup status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		User map[string]any `json:"user"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("cannot decode response: %v", err)
+	}
+	if _, ok := body.User["createdAt"].(float64); !ok {
+		t.Errorf("createdAt = %v, want epoch seconds", body.User["createdAt"])
+	}
+	if _, ok := body.User["created_at"]; ok {
+		t.Error("response should not keep snake_case keys")
+	}
+	if body.User["createdBy"] == nil || body.User["username"] != "encoderuser" {
+		t.Errorf("user = %v", body.User)
+	}
+}
+
 func TestHandleGetUserByUsername(t *testing.T) {
 	handler := setupAuthNHandler()
 
@@ -717,19 +846,55 @@ func TestHandleUpdateUser(t *testing.T) {
 		name       string
 		id         string
 		body       UpdateUserRequest
+		ifMatch    string
 		wantStatus int
 		wantCode   string
+		wantETag   string
 	}{
 		{
 			name:       "valid update",
 			id:         userID.String(),
 			body:       UpdateUserRequest{Name: "Updated Name"},
+			ifMatch:    `"1"`,
 			wantStatus: http.StatusOK,
+			wantETag:   `"2"`,
+		},
+		{
+			name:       "stale etag",
+			id:         userID.String(),
+			body:       UpdateUserRequest{Name: "Lost Update"},
+			ifMatch:    `"1"`,
+			wantStatus: http.StatusPreconditionFailed,
+			wantCode:   "VERSION_CONFLICT",
+		},
+		{
+			name:       "weak etag never matches",
+			id:         userID.String(),
+			body:       UpdateUserRequest{Name: "Lost Update"},
+			ifMatch:    `W/"2"`,
+			wantStatus: http.StatusPreconditionFailed,
+			wantCode:   "VERSION_CONFLICT",
+		},
+		{
+			name:       "missing if-match",
+			id:         userID.String(),
+			body:       UpdateUserRequest{Name: "Lost Update"},
+			wantStatus: http.StatusPreconditionRequired,
+			wantCode:   "PRECONDITION_REQUIRED",
+		},
+		{
+			name:       "wildcard if-match",
+			id:         userID.String(),
+			body:       UpdateUserRequest{Name: "Forced Name"},
+			ifMatch:    "*",
+			wantStatus: http.StatusOK,
+			wantETag:   `"3"`,
 		},
 		{
 			name:       "invalid user ID",
 			id:         "invalid",
 			body:       UpdateUserRequest{Name: "Test"},
+			ifMatch:    `"1"`,
 			wantStatus: http.StatusBadRequest,
 			wantCode:   "INVALID_USER_ID",
 		},
@@ -737,6 +902,7 @@ func TestHandleUpdateUser(t *testing.T) {
 			name:       "non-existing user",
 			id:         "00000000-0000-0000-0000-000000000000",
 			body:       UpdateUserRequest{Name: "Test"},
+			ifMatch:    `"1"`,
 			wantStatus: http.StatusNotFound,
 			wantCode:   "USER_NOT_FOUND",
 		},
@@ -746,6 +912,9 @@ func TestHandleUpdateUser(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			body, _ := json.Marshal(tt.body)
 			req := httptest.NewRequest(http.MethodPut, "/users/"+tt.id, bytes.NewReader(body))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
 			rctx := chi.NewRouteContext()
 			rctx.URLParams.Add("id", tt.id)
 			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
@@ -756,6 +925,9 @@ func TestHandleUpdateUser(t *testing.T) {
 			if w.Code != tt.wantStatus {
 				t.Errorf("handleUpdateUser() status = %v, want %v", w.Code, tt.wantStatus)
 			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("handleUpdateUser() ETag = %q, want %q", got, tt.wantETag)
+			}
 
 			if tt.wantCode != "" {
 				var errResp ErrorResponse
@@ -834,3 +1006,139 @@ func TestHandleDeleteUser(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleResetPassword(t *testing.T) {
+	handler := setupAuthNHandler()
+
+	signupBody, _ := json.Marshal(SignUpRequest{
+		Email:       "reset@example.com",
+		Password:    "Password123!",
+		Username:    "resetuser",
+		DisplayName: "Reset User",
+	})
+	signupW := httptest.NewRecorder()
+	handler.handleSignUp(signupW, httptest.NewRequest(http.MethodPost, "/auth/signup", bytes.NewReader(signupBody)))
+
+	var signupResp SignUpResponse
+	json.NewDecoder(signupW.Body).Decode(&signupResp)
+	userID := signupResp.User.ID
+
+	tests := []struct {
+		name         string
+		id           string
+		body         ResetPasswordRequest
+		wantStatus   int
+		wantCode     string
+		wantPassword string
+	}{
+		{
+			name:         "generated password",
+			id:           userID.String(),
+			wantStatus:   http.StatusOK,
+			wantPassword: "GeneratedPassword123!",
+		},
+		{
+			name:       "given password",
+			id:         userID.String(),
+			body:       ResetPasswordRequest{Password: "NewPassword456!"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "weak password",
+			id:         userID.String(),
+			body:       ResetPasswordRequest{Password: "weak"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "INVALID_PASSWORD",
+		},
+		{
+			name:       "invalid user ID",
+			id:         "invalid",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "INVALID_USER_ID",
+		},
+		{
+			name:       "non-existing user",
+			id:         "00000000-0000-0000-0000-000000000000",
+			wantStatus: http.StatusNotFound,
+			wantCode:   "USER_NOT_FOUND",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest(http.MethodPost, "/users/"+tt.id+"/password", bytes.NewReader(body))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			handler.handleResetPassword(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("handleResetPassword() status = %v, want %v, body: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if tt.wantCode != "" {
+				var errResp ErrorResponse
+				json.NewDecoder(w.Body).Decode(&errResp)
+				if errResp.Code != tt.wantCode {
+					t.Errorf("handleResetPassword() error code = %v, want %v", errResp.Code, tt.wantCode)
+				}
+				return
+			}
+
+			var resp ResetPasswordResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.Password != tt.wantPassword {
+				t.Errorf("handleResetPassword() password = %q, want %q", resp.Password, tt.wantPassword)
+			}
+		})
+	}
+
+	user, _ := handler.userStore.Get(context.Background(), userID)
+	if !user.VerifyPassword("NewPassword456!") {
+		t.Error("password was not updated")
+	}
+}
+
+func TestHandleSignUpWithRoles(t *testing.T) {
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+	tx := fake.NewTransactor()
+
+	member := auth.NewRole()
+	member.Name = "member"
+	member.Status = auth.RoleStatusActive
+	member.BeforeCreate()
+	roles.Create(context.Background(), member)
+
+	newRouter := func(names ...string) chi.Router {
+		h := NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+			WithTransactor(tx), WithSignUpRoles(roles, grants, names...))
+		r := chi.NewRouter()
+		h.RegisterRoutes(r)
+		return r
+	}
+	signUp := func(r chi.Router, username string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(SignUpRequest{Email: username + "@example.com", Password: "Password123!", Username: username, DisplayName: "Test"})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/signup", bytes.NewReader(body)))
+		return w
+	}
+
+	if w := signUp(newRouter("member"), "ann"); w.Code != http.StatusCreated {
+		t.Fatalf("signup status = %d, body %s", w.Code, w.Body.String())
+	}
+	if ok, _ := grants.HasRole(context.Background(), "ann", "member"); !ok {
+		t.Error("signed up user was not granted member")
+	}
+
+	w := signUp(newRouter("missing"), "bob")
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "ROLE_NOT_FOUND") {
+		t.Errorf("signup with missing role = %d %s, want 404 ROLE_NOT_FOUND", w.Code, w.Body.String())
+	}
+	if tx.Calls() == 0 || tx.Failures() == 0 {
+		t.Errorf("transactor calls = %d, failures = %d; want sign-ups run through it", tx.Calls(), tx.Failures())
+	}
+}
@@ -1,52 +1,95 @@
 package handler
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
+	"slices"
 
 	"github.com/aquamarinepk/aqm/auth"
 	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/validation"
 	"github.com/go-chi/chi/v5"
-	"github.com/google/uuid"
 )
 
 type AuthZHandler struct {
 	roleStore  auth.RoleStore
 	grantStore auth.GrantStore
+	options
 }
 
-func NewAuthZHandler(roleStore auth.RoleStore, grantStore auth.GrantStore) *AuthZHandler {
+// NewAuthZHandler creates an authorization handler. Optional features such as
+// hooks, rate limiting, and auditing are attached with Option values.
+func NewAuthZHandler(roleStore auth.RoleStore, grantStore auth.GrantStore, opts ...Option) *AuthZHandler {
 	return &AuthZHandler{
 		roleStore:  roleStore,
 		grantStore: grantStore,
+		options:    newOptions(opts),
 	}
 }
 
 func (h *AuthZHandler) RegisterRoutes(r chi.Router) {
+	if h.limiter != nil {
+		r = r.With(func(next http.Handler) http.Handler {
+			return h.limit(next.ServeHTTP)
+		})
+	}
+
 	r.Post("/roles", h.handleCreateRole)
 	r.Get("/roles/{id}", h.handleGetRole)
 	r.Get("/roles/name/{name}", h.handleGetRoleByName)
 	r.Get("/roles", h.handleListRoles)
 	r.Put("/roles/{id}", h.handleUpdateRole)
+	r.Patch("/roles/{id}", h.handlePatchRole)
 	r.Delete("/roles/{id}", h.handleDeleteRole)
+	r.Post("/roles/{id}/diff", h.handleDiffRole)
+
+	if h.permissions != nil {
+		r.Get("/permissions", h.handleListPermissions)
+	}
+
+	if h.permissionSets != nil {
+		h.registerPermissionSetRoutes(r)
+	}
 
-	r.Post("/grants", h.handleAssignRole)
+	if h.grantApproval != nil {
+		h.registerGrantRequestRoutes(r)
+	} else {
+		r.Post("/grants", h.handleAssignRole)
+		r.Post("/grants:batch", h.handleAssignRoles)
+	}
 	r.Delete("/grants", h.handleRevokeRole)
+	r.Get("/grants/export", h.handleExportGrants)
+	r.Post("/grants:reconcile", h.handleReconcileGrants)
+	r.Get("/users/me/roles", h.handleGetMyRoles)
+	r.Get("/users/me/permissions", h.handleGetMyPermissions)
 	r.Get("/users/{username}/roles", h.handleGetUserRoles)
 	r.Get("/users/{username}/grants", h.handleGetUserGrants)
+	r.Get("/users/{username}/effective-permissions", h.handleGetEffectivePermissions)
 	r.Get("/roles/{role_id}/grants", h.handleGetRoleGrants)
 
 	r.Get("/users/{username}/permissions/{permission}", h.handleCheckPermission)
 	r.Post("/users/{username}/check-any-permission", h.handleCheckAnyPermission)
 	r.Post("/users/{username}/check-all-permissions", h.handleCheckAllPermissions)
 	r.Get("/users/{username}/has-role/{role_name}", h.handleHasRole)
+	r.Post("/authz/decide", h.handleDecide)
+
+	if h.snapshots != nil {
+		internal := h.internalRoutes(r)
+		internal.Get("/authz/export", h.handleExport)
+		internal.Post("/authz/import", h.handleImport)
+	}
 }
 
+// CreateRoleRequest creates a role granting Permissions and, with
+// WithPermissionSets, those of the sets named in PermissionSets.
 type CreateRoleRequest struct {
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Permissions []string `json:"permissions"`
-	CreatedBy   string   `json:"created_by"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	Permissions    []string `json:"permissions"`
+	PermissionSets []string `json:"permission_sets"`
+	CreatedBy      string   `json:"created_by"`
 }
 
 type RoleResponse struct {
@@ -55,42 +98,58 @@ type RoleResponse struct {
 
 func (h *AuthZHandler) handleCreateRole(w http.ResponseWriter, r *http.Request) {
 	var req CreateRoleRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	if err := h.validatePermissions(req.Permissions); err != nil {
+		h.emit(r, ActionRoleCreated, req.Name, err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	if h.permissionSets == nil && len(req.PermissionSets) > 0 {
+		h.emit(r, ActionRoleCreated, req.Name, auth.ErrPermissionSetNotFound)
+		h.handleServiceError(w, r, auth.ErrPermissionSetNotFound)
 		return
 	}
 
-	role, err := service.CreateRole(
+	role, err := service.CreateRoleWithSets(
 		r.Context(),
 		h.roleStore,
+		h.permissionSets,
 		req.Name,
 		req.Description,
 		req.Permissions,
+		req.PermissionSets,
 		req.CreatedBy,
 	)
 	if err != nil {
-		handleServiceError(w, err)
+		h.emit(r, ActionRoleCreated, req.Name, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
+	h.emit(r, ActionRoleCreated, role.ID.String(), nil)
 
-	writeJSON(w, http.StatusCreated, RoleResponse{Role: role})
+	httpx.WriteJSON(w, http.StatusCreated, RoleResponse{Role: role})
 }
 
 func (h *AuthZHandler) handleGetRole(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
-	roleID, err := uuid.Parse(idStr)
+	roleID, err := auth.ParseRoleID(idStr)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
 		return
 	}
 
 	role, err := service.GetRoleByID(r.Context(), h.roleStore, roleID)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, RoleResponse{Role: role})
+	setETag(w, role.Version)
+	httpx.WriteJSON(w, http.StatusOK, RoleResponse{Role: role})
 }
 
 func (h *AuthZHandler) handleGetRoleByName(w http.ResponseWriter, r *http.Request) {
@@ -98,11 +157,11 @@ func (h *AuthZHandler) handleGetRoleByName(w http.ResponseWriter, r *http.Reques
 
 	role, err := service.GetRoleByName(r.Context(), h.roleStore, name)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, RoleResponse{Role: role})
+	httpx.WriteJSON(w, http.StatusOK, RoleResponse{Role: role})
 }
 
 type ListRolesResponse struct {
@@ -111,6 +170,12 @@ type ListRolesResponse struct {
 
 func (h *AuthZHandler) handleListRoles(w http.ResponseWriter, r *http.Request) {
 	status := r.URL.Query().Get("status")
+	if status != "" && !auth.RoleStatus(status).IsValid() {
+		var errs validation.ValidationErrors
+		errs.AddCode("status", validation.CodeOneOf, "Unknown status "+status)
+		h.writeValidationError(w, r, "INVALID_QUERY", errs)
+		return
+	}
 
 	var roles []*auth.Role
 	var err error
@@ -122,69 +187,226 @@ func (h *AuthZHandler) handleListRoles(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, ListRolesResponse{Roles: roles})
+	httpx.WriteJSON(w, http.StatusOK, ListRolesResponse{Roles: roles})
 }
 
+// UpdateRoleRequest replaces the description, permissions and permission
+// sets of a role.
 type UpdateRoleRequest struct {
-	Description string   `json:"description"`
-	Permissions []string `json:"permissions"`
-	UpdatedBy   string   `json:"updated_by"`
+	Description    string   `json:"description"`
+	Permissions    []string `json:"permissions"`
+	PermissionSets []string `json:"permission_sets"`
+	UpdatedBy      string   `json:"updated_by"`
 }
 
+// handleUpdateRole serves PUT /roles/{id}. The If-Match header must carry
+// the ETag returned by GET /roles/{id}; a stale ETag fails with 412.
 func (h *AuthZHandler) handleUpdateRole(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
-	roleID, err := uuid.Parse(idStr)
+	roleID, err := auth.ParseRoleID(idStr)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
 		return
 	}
 
 	var req UpdateRoleRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	if err := h.validatePermissions(req.Permissions); err != nil {
+		h.emit(r, ActionRoleUpdated, roleID.String(), err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	ifMatch, ok := h.requireIfMatch(w, r)
+	if !ok {
 		return
 	}
 
 	role, err := service.GetRoleByID(r.Context(), h.roleStore, roleID)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	if !matchesIfMatch(ifMatch, role.Version) {
+		h.emit(r, ActionRoleUpdated, roleID.String(), auth.ErrVersionConflict)
+		h.handleServiceError(w, r, auth.ErrVersionConflict)
 		return
 	}
 
 	role.Description = req.Description
 	role.Permissions = req.Permissions
+	role.PermissionSets = req.PermissionSets
+	if err := h.expandRole(r, role); err != nil {
+		h.emit(r, ActionRoleUpdated, roleID.String(), err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	err = service.UpdateRole(r.Context(), h.roleStore, role, req.UpdatedBy)
+	h.emit(r, ActionRoleUpdated, roleID.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, role.Version)
+	httpx.WriteJSON(w, http.StatusOK, RoleResponse{Role: role})
+}
+
+// handlePatchRole serves PATCH /roles/{id}, applying a JSON Merge Patch
+// (RFC 7386) of description, status, permissions and permission_sets; see
+// applyRolePatch for adding and removing single permissions. Only newly
+// added permissions are checked against the catalog. If-Match is optional; when sent, a stale ETag
+// fails with 412.
+func (h *AuthZHandler) handlePatchRole(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	roleID, err := auth.ParseRoleID(idStr)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
+		return
+	}
+
+	patch, err := decodeMergePatch(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+
+	role, err := service.GetRoleByID(r.Context(), h.roleStore, roleID)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && !matchesIfMatch(ifMatch, role.Version) {
+		h.emit(r, ActionRoleUpdated, roleID.String(), auth.ErrVersionConflict)
+		h.handleServiceError(w, r, auth.ErrVersionConflict)
+		return
+	}
 
-	if err := service.UpdateRole(r.Context(), h.roleStore, role, req.UpdatedBy); err != nil {
-		handleServiceError(w, err)
+	current := slices.Clone(role.Permissions)
+	if err := applyRolePatch(role, patch); err != nil {
+		h.emit(r, ActionRoleUpdated, roleID.String(), err)
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_PATCH", err.Error())
 		return
 	}
 
-	writeJSON(w, http.StatusOK, RoleResponse{Role: role})
+	added, _ := auth.DiffPermissions(current, role.Permissions)
+	if err := h.validatePermissions(added); err != nil {
+		h.emit(r, ActionRoleUpdated, roleID.String(), err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	if _, ok := patch["permission_sets"]; ok {
+		if err := h.expandRole(r, role); err != nil {
+			h.emit(r, ActionRoleUpdated, roleID.String(), err)
+			h.handleServiceError(w, r, err)
+			return
+		}
+	}
+
+	err = service.UpdateRole(r.Context(), h.roleStore, role, middleware.GetUserID(r.Context()))
+	h.emit(r, ActionRoleUpdated, roleID.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, role.Version)
+	httpx.WriteJSON(w, http.StatusOK, RoleResponse{Role: role})
+}
+
+type DiffRoleRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+type RoleDiffResponse struct {
+	Diff *auth.RoleDiff `json:"diff"`
+}
+
+func (h *AuthZHandler) handleDiffRole(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	roleID, err := auth.ParseRoleID(idStr)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
+		return
+	}
+
+	var req DiffRoleRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	diff, err := service.DiffRole(r.Context(), h.roleStore, h.grantStore, roleID, req.Permissions)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, RoleDiffResponse{Diff: diff})
 }
 
 func (h *AuthZHandler) handleDeleteRole(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
-	roleID, err := uuid.Parse(idStr)
+	roleID, err := auth.ParseRoleID(idStr)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
 		return
 	}
 
-	if err := service.DeleteRole(r.Context(), h.roleStore, roleID); err != nil {
-		handleServiceError(w, err)
+	err = service.DeleteRole(r.Context(), h.roleStore, roleID)
+	h.emit(r, ActionRoleDeleted, roleID.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+type ListPermissionsResponse struct {
+	Permissions []auth.PermissionDef `json:"permissions"`
+}
+
+// handleListPermissions serves GET /permissions, optionally filtered by the
+// group query parameter.
+func (h *AuthZHandler) handleListPermissions(w http.ResponseWriter, r *http.Request) {
+	group := r.URL.Query().Get("group")
+
+	defs := h.permissions.List()
+	if group != "" {
+		filtered := defs[:0]
+		for _, def := range defs {
+			if def.Group == group {
+				filtered = append(filtered, def)
+			}
+		}
+		defs = filtered
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, ListPermissionsResponse{Permissions: defs})
+}
+
+// validatePermissions checks permissions against the registry, if any.
+func (h *AuthZHandler) validatePermissions(permissions []string) error {
+	if h.permissions == nil {
+		return nil
+	}
+	return h.permissions.Validate(permissions)
+}
+
 type AssignRoleRequest struct {
-	Username   string `json:"username"`
-	RoleID     string `json:"role_id"`
+	Username   string `json:"username" validate:"required"`
+	RoleID     string `json:"role_id" validate:"required"`
 	AssignedBy string `json:"assigned_by"`
 }
 
@@ -194,56 +416,104 @@ type GrantResponse struct {
 
 func (h *AuthZHandler) handleAssignRole(w http.ResponseWriter, r *http.Request) {
 	var req AssignRoleRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	if !h.bind(w, r, &req) {
 		return
 	}
 
-	if req.Username == "" {
-		writeError(w, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
+	roleID, err := auth.ParseRoleID(req.RoleID)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
 		return
 	}
 
-	roleID, err := uuid.Parse(req.RoleID)
+	ctx, assignedBy, ok := h.adminContext(w, r, ActionRoleAssigned, req.Username, req.AssignedBy)
+	if !ok {
+		return
+	}
+
+	grant, err := service.AssignRole(ctx, h.grantStore, req.Username, roleID, assignedBy)
+	h.emit(r, ActionRoleAssigned, req.Username, err)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
+		h.handleServiceError(w, r, err)
 		return
 	}
 
-	grant, err := service.AssignRole(r.Context(), h.grantStore, req.Username, roleID, req.AssignedBy)
+	httpx.WriteJSON(w, http.StatusCreated, GrantResponse{Grant: grant})
+}
+
+// AssignRolesRequest is the JSON body of POST /grants:batch.
+type AssignRolesRequest struct {
+	Username   string   `json:"username" validate:"required"`
+	RoleIDs    []string `json:"role_ids" validate:"required"`
+	AssignedBy string   `json:"assigned_by"`
+}
+
+type GrantsResponse struct {
+	Grants []*auth.Grant `json:"grants"`
+}
+
+// handleAssignRoles serves POST /grants:batch, assigning every role in the
+// request or, when one fails, none of them.
+func (h *AuthZHandler) handleAssignRoles(w http.ResponseWriter, r *http.Request) {
+	var req AssignRolesRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	roleIDs := make([]auth.RoleID, 0, len(req.RoleIDs))
+	for _, id := range req.RoleIDs {
+		roleID, err := auth.ParseRoleID(id)
+		if err != nil {
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
+			return
+		}
+		roleIDs = append(roleIDs, roleID)
+	}
+
+	ctx, assignedBy, ok := h.adminContext(w, r, ActionRoleAssigned, req.Username, req.AssignedBy)
+	if !ok {
+		return
+	}
+
+	grants, err := service.AssignRoles(ctx, h.tx, h.grantStore, req.Username, roleIDs, assignedBy)
 	if err != nil {
-		handleServiceError(w, err)
+		h.emit(r, ActionRoleAssigned, req.Username, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
+	for range grants {
+		h.emit(r, ActionRoleAssigned, req.Username, nil)
+	}
 
-	writeJSON(w, http.StatusCreated, GrantResponse{Grant: grant})
+	httpx.WriteJSON(w, http.StatusCreated, GrantsResponse{Grants: grants})
 }
 
 type RevokeRoleRequest struct {
-	Username string `json:"username"`
-	RoleID   string `json:"role_id"`
+	Username string `json:"username" validate:"required"`
+	RoleID   string `json:"role_id" validate:"required"`
 }
 
 func (h *AuthZHandler) handleRevokeRole(w http.ResponseWriter, r *http.Request) {
 	var req RevokeRoleRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	if !h.bind(w, r, &req) {
 		return
 	}
 
-	if req.Username == "" {
-		writeError(w, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
+	roleID, err := auth.ParseRoleID(req.RoleID)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
 		return
 	}
 
-	roleID, err := uuid.Parse(req.RoleID)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
+	ctx, _, ok := h.adminContext(w, r, ActionRoleRevoked, req.Username, "")
+	if !ok {
 		return
 	}
 
-	if err := service.RevokeRole(r.Context(), h.grantStore, req.Username, roleID); err != nil {
-		handleServiceError(w, err)
+	err = service.RevokeRole(ctx, h.grantStore, req.Username, roleID)
+	h.emit(r, ActionRoleRevoked, req.Username, err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
 		return
 	}
 
@@ -257,17 +527,17 @@ type UserRolesResponse struct {
 func (h *AuthZHandler) handleGetUserRoles(w http.ResponseWriter, r *http.Request) {
 	username := chi.URLParam(r, "username")
 	if username == "" {
-		writeError(w, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
 		return
 	}
 
 	roles, err := service.GetUserRoles(r.Context(), h.grantStore, username)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, UserRolesResponse{Roles: roles})
+	httpx.WriteJSON(w, http.StatusOK, UserRolesResponse{Roles: roles})
 }
 
 type UserGrantsResponse struct {
@@ -277,17 +547,41 @@ type UserGrantsResponse struct {
 func (h *AuthZHandler) handleGetUserGrants(w http.ResponseWriter, r *http.Request) {
 	username := chi.URLParam(r, "username")
 	if username == "" {
-		writeError(w, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
 		return
 	}
 
 	grants, err := service.GetUserGrants(r.Context(), h.grantStore, username)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, UserGrantsResponse{Grants: grants})
+	httpx.WriteJSON(w, http.StatusOK, UserGrantsResponse{Grants: grants})
+}
+
+type EffectivePermissionsResponse struct {
+	Permissions []auth.EffectivePermission `json:"permissions"`
+}
+
+// handleGetEffectivePermissions serves
+// GET /users/{username}/effective-permissions, every permission the user
+// holds with the grants, roles, permission sets and wildcards it comes
+// from, for troubleshooting access.
+func (h *AuthZHandler) handleGetEffectivePermissions(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	if username == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
+		return
+	}
+
+	permissions, err := service.EffectivePermissions(r.Context(), h.grantStore, h.permissionSets, h.permissions, username)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, EffectivePermissionsResponse{Permissions: permissions})
 }
 
 type RoleGrantsResponse struct {
@@ -296,19 +590,19 @@ type RoleGrantsResponse struct {
 
 func (h *AuthZHandler) handleGetRoleGrants(w http.ResponseWriter, r *http.Request) {
 	roleIDStr := chi.URLParam(r, "role_id")
-	roleID, err := uuid.Parse(roleIDStr)
+	roleID, err := auth.ParseRoleID(roleIDStr)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
 		return
 	}
 
 	grants, err := service.GetRoleGrants(r.Context(), h.grantStore, roleID)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, RoleGrantsResponse{Grants: grants})
+	httpx.WriteJSON(w, http.StatusOK, RoleGrantsResponse{Grants: grants})
 }
 
 type PermissionCheckResponse struct {
@@ -318,19 +612,48 @@ type PermissionCheckResponse struct {
 func (h *AuthZHandler) handleCheckPermission(w http.ResponseWriter, r *http.Request) {
 	username := chi.URLParam(r, "username")
 	if username == "" {
-		writeError(w, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
 		return
 	}
 
 	permission := chi.URLParam(r, "permission")
 
-	hasPermission, err := service.CheckPermission(r.Context(), h.grantStore, username, permission)
+	hasPermission, err := h.checkPermission(r.Context(), username, permission)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, PermissionCheckResponse{HasPermission: hasPermission})
+}
+
+// DecideRequest asks whether a user holds a permission. UserID is the
+// username, the key grants are stored under.
+type DecideRequest struct {
+	UserID     string `json:"user_id" validate:"required"`
+	Permission string `json:"permission" validate:"required"`
+	Resource   string `json:"resource,omitempty"`
+}
+
+type DecisionResponse struct {
+	Decision *auth.Decision `json:"decision"`
+}
+
+// handleDecide serves POST /authz/decide. It returns the decision with a
+// trace of every grant considered, for debugging authorization.
+func (h *AuthZHandler) handleDecide(w http.ResponseWriter, r *http.Request) {
+	var req DecideRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	decision, err := service.Decide(r.Context(), h.grantStore, req.UserID, req.Permission, req.Resource)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, PermissionCheckResponse{HasPermission: hasPermission})
+	httpx.WriteJSON(w, http.StatusOK, DecisionResponse{Decision: decision})
 }
 
 type CheckAnyPermissionRequest struct {
@@ -340,23 +663,22 @@ type CheckAnyPermissionRequest struct {
 func (h *AuthZHandler) handleCheckAnyPermission(w http.ResponseWriter, r *http.Request) {
 	username := chi.URLParam(r, "username")
 	if username == "" {
-		writeError(w, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
 		return
 	}
 
 	var req CheckAnyPermissionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	if !h.bind(w, r, &req) {
 		return
 	}
 
-	hasPermission, err := service.CheckAnyPermission(r.Context(), h.grantStore, username, req.Permissions)
+	hasPermission, err := h.checkAnyPermission(r.Context(), username, req.Permissions)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, PermissionCheckResponse{HasPermission: hasPermission})
+	httpx.WriteJSON(w, http.StatusOK, PermissionCheckResponse{HasPermission: hasPermission})
 }
 
 type CheckAllPermissionsRequest struct {
@@ -366,23 +688,22 @@ type CheckAllPermissionsRequest struct {
 func (h *AuthZHandler) handleCheckAllPermissions(w http.ResponseWriter, r *http.Request) {
 	username := chi.URLParam(r, "username")
 	if username == "" {
-		writeError(w, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
 		return
 	}
 
 	var req CheckAllPermissionsRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	if !h.bind(w, r, &req) {
 		return
 	}
 
-	hasPermission, err := service.CheckAllPermissions(r.Context(), h.grantStore, username, req.Permissions)
+	hasPermission, err := h.checkAllPermissions(r.Context(), username, req.Permissions)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, PermissionCheckResponse{HasPermission: hasPermission})
+	httpx.WriteJSON(w, http.StatusOK, PermissionCheckResponse{HasPermission: hasPermission})
 }
 
 type HasRoleResponse struct {
@@ -392,7 +713,7 @@ type HasRoleResponse struct {
 func (h *AuthZHandler) handleHasRole(w http.ResponseWriter, r *http.Request) {
 	username := chi.URLParam(r, "username")
 	if username == "" {
-		writeError(w, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
 		return
 	}
 
@@ -400,9 +721,58 @@ func (h *AuthZHandler) handleHasRole(w http.ResponseWriter, r *http.Request) {
 
 	hasRole, err := service.HasRole(r.Context(), h.grantStore, username, roleName)
 	if err != nil {
-		handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, HasRoleResponse{HasRole: hasRole})
+	httpx.WriteJSON(w, http.StatusOK, HasRoleResponse{HasRole: hasRole})
+}
+
+// checkPermission asks the configured engine, falling back to evaluating
+// grants when none is set.
+func (h *AuthZHandler) checkPermission(ctx context.Context, username, permission string) (bool, error) {
+	if h.engine == nil {
+		return service.CheckPermission(ctx, h.grantStore, username, permission)
+	}
+	return h.engine.Authorize(ctx, username, permission, "")
+}
+
+func (h *AuthZHandler) checkAnyPermission(ctx context.Context, username string, permissions []string) (bool, error) {
+	if h.engine == nil {
+		return service.CheckAnyPermission(ctx, h.grantStore, username, permissions)
+	}
+	if batch, ok := h.engine.(auth.BatchAuthorizer); ok {
+		allowed, err := batch.AuthorizeAll(ctx, username, permissions, "")
+		if err != nil {
+			return false, err
+		}
+		return slices.Contains(allowed, true), nil
+	}
+	for _, permission := range permissions {
+		allowed, err := h.engine.Authorize(ctx, username, permission, "")
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+	return false, nil
+}
+
+func (h *AuthZHandler) checkAllPermissions(ctx context.Context, username string, permissions []string) (bool, error) {
+	if h.engine == nil {
+		return service.CheckAllPermissions(ctx, h.grantStore, username, permissions)
+	}
+	if batch, ok := h.engine.(auth.BatchAuthorizer); ok {
+		allowed, err := batch.AuthorizeAll(ctx, username, permissions, "")
+		if err != nil {
+			return false, err
+		}
+		return !slices.Contains(allowed, false), nil
+	}
+	for _, permission := range permissions {
+		allowed, err := h.engine.Authorize(ctx, username, permission, "")
+		if err != nil || !allowed {
+			return false, err
+		}
+	}
+	return true, nil
 }
@@ -6,9 +6,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/aquamarinepk/aqm/auth"
 	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/validation"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -142,6 +146,221 @@ func TestHandleGetRole(t *testing.T) {
 	}
 }
 
+func TestHandleDiffRole(t *testing.T) {
+	handler := setupAuthZHandler()
+
+	createBody, _ := json.Marshal(CreateRoleRequest{
+		Name:        "reviewer",
+		Description: "Can review content",
+		Permissions: []string{"content.read", "content.comment"},
+		CreatedBy:   "admin",
+	})
+	createW := httptest.NewRecorder()
+	handler.handleCreateRole(createW, httptest.NewRequest(http.MethodPost, "/roles", bytes.NewReader(createBody)))
+
+	var createResp RoleResponse
+	json.NewDecoder(createW.Body).Decode(&createResp)
+	roleID := createResp.Role.ID
+	handler.grantStore.Create(context.Background(), auth.NewGrant("alice", roleID, "admin"))
+
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "existing role",
+			id:         roleID.String(),
+			body:       `{"permissions":["content.read","content.write"]}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid role ID",
+			id:         "invalid",
+			body:       `{"permissions":[]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "INVALID_ROLE_ID",
+		},
+		{
+			name:       "invalid body",
+			id:         roleID.String(),
+			body:       `{`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "INVALID_REQUEST",
+		},
+		{
+			name:       "non-existing role",
+			id:         "00000000-0000-0000-0000-000000000000",
+			body:       `{"permissions":[]}`,
+			wantStatus: http.StatusNotFound,
+			wantCode:   "ROLE_NOT_FOUND",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/roles/"+tt.id+"/diff", bytes.NewReader([]byte(tt.body)))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			handler.handleDiffRole(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("handleDiffRole() status = %v, want %v, body: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if tt.wantCode != "" {
+				var errResp ErrorResponse
+				json.NewDecoder(w.Body).Decode(&errResp)
+				if errResp.Code != tt.wantCode {
+					t.Errorf("handleDiffRole() error code = %v, want %v", errResp.Code, tt.wantCode)
+				}
+				return
+			}
+
+			var resp RoleDiffResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if len(resp.Diff.Added) != 1 || resp.Diff.Added[0] != "content.write" {
+				t.Errorf("handleDiffRole() added = %v, want [content.write]", resp.Diff.Added)
+			}
+			if len(resp.Diff.Removed) != 1 || resp.Diff.Removed[0] != "content.comment" {
+				t.Errorf("handleDiffRole() removed = %v, want [content.comment]", resp.Diff.Removed)
+			}
+			if resp.Diff.AffectedUsers != 1 {
+				t.Errorf("handleDiffRole() affected users = %v, want 1", resp.Diff.AffectedUsers)
+			}
+		})
+	}
+}
+
+func TestPermissionCatalog(t *testing.T) {
+	registry := auth.NewPermissionRegistry().MustRegister(
+		auth.PermissionDef{Name: "content:read", Description: "Read content", Group: "content"},
+		auth.PermissionDef{Name: "content:write", Description: "Write content", Group: "content"},
+		auth.PermissionDef{Name: "audit:read", Description: "Read audit trail", Group: "audit"},
+	)
+	roleStore := fake.NewRoleStore()
+	handler := NewAuthZHandler(roleStore, fake.NewGrantStore(roleStore), WithPermissions(registry))
+	r := chi.NewRouter()
+	handler.RegisterRoutes(r)
+
+	do := func(method, path string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, &buf))
+		return w
+	}
+
+	w := do(http.MethodGet, "/permissions?group=content", nil)
+	var list ListPermissionsResponse
+	json.NewDecoder(w.Body).Decode(&list)
+	if w.Code != http.StatusOK || len(list.Permissions) != 2 || list.Permissions[0].Name != "content:read" {
+		t.Errorf("GET /permissions?group=content = %d %+v", w.Code, list)
+	}
+
+	w = do(http.MethodPost, "/roles", CreateRoleRequest{Name: "typo", Permissions: []string{"contnet:read"}, CreatedBy: "admin"})
+	var errResp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if w.Code != http.StatusBadRequest || errResp.Code != "UNKNOWN_PERMISSION" {
+		t.Errorf("POST /roles with unknown permission = %d %+v", w.Code, errResp)
+	}
+
+	w = do(http.MethodPost, "/roles", CreateRoleRequest{Name: "editor", Permissions: []string{"content:*"}, CreatedBy: "admin"})
+	var created RoleResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /roles = %d %s", w.Code, w.Body.String())
+	}
+
+	w = do(http.MethodPut, "/roles/"+created.Role.ID.String(), UpdateRoleRequest{Permissions: []string{"billing:read"}, UpdatedBy: "admin"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("PUT /roles with unknown permission = %d, want 400", w.Code)
+	}
+	role, _ := roleStore.Get(context.Background(), created.Role.ID)
+	if len(role.Permissions) != 1 || role.Permissions[0] != "content:*" {
+		t.Errorf("role permissions = %v, want unchanged", role.Permissions)
+	}
+
+	registry.AllowUnknown(true)
+	body, _ := json.Marshal(UpdateRoleRequest{Permissions: []string{"billing:read"}, UpdatedBy: "admin"})
+	req := httptest.NewRequest(http.MethodPut, "/roles/"+created.Role.ID.String(), bytes.NewReader(body))
+	req.Header.Set("If-Match", etag(created.Role.Version))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("PUT /roles with unknown permissions allowed = %d, want 200", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	plain := chi.NewRouter()
+	setupAuthZHandler().RegisterRoutes(plain)
+	plain.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/permissions", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /permissions without registry = %d, want 404", w.Code)
+	}
+}
+
+func TestHandleDecide(t *testing.T) {
+	roleStore := fake.NewRoleStore()
+	grantStore := fake.NewGrantStore(roleStore)
+	handler := NewAuthZHandler(roleStore, grantStore)
+	r := chi.NewRouter()
+	handler.RegisterRoutes(r)
+
+	role := auth.NewRole()
+	role.Name = "editor"
+	role.Permissions = []string{"posts:*"}
+	role.CreatedBy, role.UpdatedBy = "system", "system"
+	role.BeforeCreate()
+	roleStore.Create(context.Background(), role)
+	grantStore.Create(context.Background(), auth.NewGrant("ann", role.ID, "system"))
+
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantAllowed bool
+	}{
+		{name: "allow", body: `{"user_id":"ann","permission":"posts:write","resource":"post-1"}`, wantStatus: http.StatusOK, wantAllowed: true},
+		{name: "deny", body: `{"user_id":"ann","permission":"users:write"}`, wantStatus: http.StatusOK},
+		{name: "missing permission", body: `{"user_id":"ann"}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid body", body: `{`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/authz/decide", bytes.NewBufferString(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("POST /authz/decide status = %v, want %v: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp DecisionResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.Decision.Allowed != tt.wantAllowed {
+				t.Errorf("allowed = %v, want %v", resp.Decision.Allowed, tt.wantAllowed)
+			}
+			if len(resp.Decision.Trace) != 1 || resp.Decision.Trace[0].Role != "editor" {
+				t.Errorf("trace = %+v, want one step for editor", resp.Decision.Trace)
+			}
+			if resp.Decision.Reason == "" {
+				t.Error("expected a reason")
+			}
+		})
+	}
+}
+
 func TestHandleListRoles(t *testing.T) {
 	handler := setupAuthZHandler()
 
@@ -176,6 +395,11 @@ func TestHandleListRoles(t *testing.T) {
 			wantStatus: http.StatusOK,
 			wantMin:    3,
 		},
+		{
+			name:       "unknown status",
+			status:     "archived",
+			wantStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -204,6 +428,59 @@ func TestHandleListRoles(t *testing.T) {
 	}
 }
 
+func TestHandleAssignRoleBinding(t *testing.T) {
+	handler := setupAuthZHandler()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string
+	}{
+		{name: "unknown field", contentType: "application/json", body: `{"username":"bob","role_id":"x","admin":true}`, wantStatus: http.StatusBadRequest, wantCode: "INVALID_REQUEST"},
+		{name: "wrong content type", contentType: "text/plain", body: `{"username":"bob"}`, wantStatus: http.StatusUnsupportedMediaType, wantCode: "UNSUPPORTED_MEDIA_TYPE"},
+		{name: "missing fields", contentType: "application/json", body: `{}`, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/grants", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			handler.handleAssignRole(w, req)
+
+			var resp ErrorResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if w.Code != tt.wantStatus || resp.Code != tt.wantCode {
+				t.Errorf("handleAssignRole() = %d %s, want %d %s", w.Code, resp.Code, tt.wantStatus, tt.wantCode)
+			}
+			if tt.wantCode == "VALIDATION_FAILED" && len(resp.Errors) != 2 {
+				t.Errorf("errors = %+v, want username and role_id", resp.Errors)
+			}
+		})
+	}
+}
+
+func TestHandleListRolesFieldErrors(t *testing.T) {
+	handler := setupAuthZHandler()
+
+	w := httptest.NewRecorder()
+	handler.handleListRoles(w, httptest.NewRequest(http.MethodGet, "/roles?status=archived", nil))
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("cannot decode response: %v", err)
+	}
+	if resp.Code != "INVALID_QUERY" {
+		t.Errorf("error code = %v, want INVALID_QUERY", resp.Code)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Field != "status" || resp.Errors[0].Code != validation.CodeOneOf {
+		t.Errorf("errors = %+v, want one oneof error on status", resp.Errors)
+	}
+}
+
 func TestHandleAssignRole(t *testing.T) {
 	authNHandler := setupAuthNHandler()
 	authZHandler := setupAuthZHandler()
@@ -260,7 +537,7 @@ func TestHandleAssignRole(t *testing.T) {
 				AssignedBy: "admin",
 			},
 			wantStatus: http.StatusBadRequest,
-			wantCode:   "INVALID_USERNAME",
+			wantCode:   "VALIDATION_FAILED",
 		},
 		{
 			name: "invalid role ID",
@@ -705,8 +982,10 @@ func TestHandleUpdateRole(t *testing.T) {
 		name       string
 		id         string
 		body       UpdateRoleRequest
+		ifMatch    string
 		wantStatus int
 		wantCode   string
+		wantETag   string
 	}{
 		{
 			name: "valid update",
@@ -716,7 +995,44 @@ func TestHandleUpdateRole(t *testing.T) {
 				Permissions: []string{"read", "write"},
 				UpdatedBy:   "admin",
 			},
+			ifMatch:    `"1"`,
 			wantStatus: http.StatusOK,
+			wantETag:   `"2"`,
+		},
+		{
+			name: "stale etag",
+			id:   roleID.String(),
+			body: UpdateRoleRequest{
+				Description: "Lost update",
+				Permissions: []string{"read"},
+				UpdatedBy:   "admin",
+			},
+			ifMatch:    `"1"`,
+			wantStatus: http.StatusPreconditionFailed,
+			wantCode:   "VERSION_CONFLICT",
+		},
+		{
+			name: "missing if-match",
+			id:   roleID.String(),
+			body: UpdateRoleRequest{
+				Description: "Lost update",
+				Permissions: []string{"read"},
+				UpdatedBy:   "admin",
+			},
+			wantStatus: http.StatusPreconditionRequired,
+			wantCode:   "PRECONDITION_REQUIRED",
+		},
+		{
+			name: "one of several etags matches",
+			id:   roleID.String(),
+			body: UpdateRoleRequest{
+				Description: "Second update",
+				Permissions: []string{"read"},
+				UpdatedBy:   "admin",
+			},
+			ifMatch:    `"1", "2"`,
+			wantStatus: http.StatusOK,
+			wantETag:   `"3"`,
 		},
 		{
 			name: "invalid role ID",
@@ -726,6 +1042,7 @@ func TestHandleUpdateRole(t *testing.T) {
 				Permissions: []string{"test"},
 				UpdatedBy:   "admin",
 			},
+			ifMatch:    `"1"`,
 			wantStatus: http.StatusBadRequest,
 			wantCode:   "INVALID_ROLE_ID",
 		},
@@ -737,6 +1054,7 @@ func TestHandleUpdateRole(t *testing.T) {
 				Permissions: []string{"test"},
 				UpdatedBy:   "admin",
 			},
+			ifMatch:    `"1"`,
 			wantStatus: http.StatusNotFound,
 			wantCode:   "ROLE_NOT_FOUND",
 		},
@@ -746,6 +1064,9 @@ func TestHandleUpdateRole(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			body, _ := json.Marshal(tt.body)
 			req := httptest.NewRequest(http.MethodPut, "/roles/"+tt.id, bytes.NewReader(body))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
 			rctx := chi.NewRouteContext()
 			rctx.URLParams.Add("id", tt.id)
 			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
@@ -756,6 +1077,9 @@ func TestHandleUpdateRole(t *testing.T) {
 			if w.Code != tt.wantStatus {
 				t.Errorf("handleUpdateRole() status = %v, want %v", w.Code, tt.wantStatus)
 			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("handleUpdateRole() ETag = %q, want %q", got, tt.wantETag)
+			}
 
 			if tt.wantCode != "" {
 				var errResp ErrorResponse
@@ -901,7 +1225,7 @@ func TestHandleRevokeRole(t *testing.T) {
 				RoleID:   roleID.String(),
 			},
 			wantStatus: http.StatusBadRequest,
-			wantCode:   "INVALID_USERNAME",
+			wantCode:   "VALIDATION_FAILED",
 		},
 		{
 			name: "invalid role ID",
@@ -1030,6 +1354,36 @@ func TestHandleGetUserGrants(t *testing.T) {
 	}
 }
 
+func TestHandleGetEffectivePermissions(t *testing.T) {
+	ctx := context.Background()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+	registry := auth.NewPermissionRegistry().MustRegister(
+		auth.PermissionDef{Name: "posts:read"},
+		auth.PermissionDef{Name: "posts:write"},
+	)
+	writer, _ := service.CreateRole(ctx, roles, "writer", "", []string{"posts:*"}, "system")
+	service.AssignRole(ctx, grants, "ann", writer.ID, "system")
+
+	r := chi.NewRouter()
+	NewAuthZHandler(roles, grants, WithPermissions(registry)).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/ann/effective-permissions", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp EffectivePermissionsResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Permissions) != 3 {
+		t.Fatalf("permissions = %+v, want posts:* expanded to 2", resp.Permissions)
+	}
+	write := resp.Permissions[2]
+	if write.Permission != "posts:write" || len(write.Sources) != 1 || write.Sources[0].Role != "writer" || write.Sources[0].Wildcard != "posts:*" {
+		t.Errorf("posts:write = %+v", write)
+	}
+}
+
 func TestHandleGetRoleGrants(t *testing.T) {
 	authZHandler := setupAuthZHandler()
 
@@ -1340,3 +1694,113 @@ func TestHandleHasRole(t *testing.T) {
 		})
 	}
 }
+
+// prefixEngine allows any permission starting with prefix.
+type prefixEngine struct {
+	prefix string
+}
+
+func (e prefixEngine) Authorize(_ context.Context, subject, permission, _ string) (bool, error) {
+	return subject != "" && strings.HasPrefix(permission, e.prefix), nil
+}
+
+func TestHandleCheckWithEngine(t *testing.T) {
+	roleStore := fake.NewRoleStore()
+	h := NewAuthZHandler(roleStore, fake.NewGrantStore(roleStore), WithEngine(prefixEngine{prefix: "posts:"}))
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		want   bool
+	}{
+		{name: "single allowed", method: http.MethodGet, path: "/users/ann/permissions/posts:read", want: true},
+		{name: "single denied", method: http.MethodGet, path: "/users/ann/permissions/users:read", want: false},
+		{name: "any allowed", method: http.MethodPost, path: "/users/ann/check-any-permission", body: CheckAnyPermissionRequest{Permissions: []string{"users:read", "posts:read"}}, want: true},
+		{name: "any denied", method: http.MethodPost, path: "/users/ann/check-any-permission", body: CheckAnyPermissionRequest{Permissions: []string{"users:read"}}, want: false},
+		{name: "all allowed", method: http.MethodPost, path: "/users/ann/check-all-permissions", body: CheckAllPermissionsRequest{Permissions: []string{"posts:read", "posts:write"}}, want: true},
+		{name: "all denied", method: http.MethodPost, path: "/users/ann/check-all-permissions", body: CheckAllPermissionsRequest{Permissions: []string{"posts:read", "users:read"}}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			if tt.body != nil {
+				json.NewEncoder(&body).Encode(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, tt.path, &body)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %v, want %v", w.Code, http.StatusOK)
+			}
+			var resp PermissionCheckResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.HasPermission != tt.want {
+				t.Errorf("hasPermission = %v, want %v", resp.HasPermission, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleAssignRoles(t *testing.T) {
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+	tx := fake.NewTransactor()
+	h := NewAuthZHandler(roles, grants, WithTransactor(tx))
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	var ids []string
+	for _, name := range []string{"editor", "viewer"} {
+		role := auth.NewRole()
+		role.Name = name
+		role.BeforeCreate()
+		roles.Create(context.Background(), role)
+		ids = append(ids, role.ID.String())
+	}
+
+	assign := func(body AssignRolesRequest) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/grants:batch", bytes.NewReader(b)))
+		return w
+	}
+
+	w := assign(AssignRolesRequest{Username: "ann", RoleIDs: ids, AssignedBy: "admin"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var resp GrantsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || len(resp.Grants) != 2 {
+		t.Errorf("response = %+v, %v; want 2 grants", resp, err)
+	}
+	if tx.Calls() != 1 {
+		t.Errorf("transactor ran %d units of work, want 1", tx.Calls())
+	}
+
+	tests := []struct {
+		name       string
+		body       AssignRolesRequest
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "held role", body: AssignRolesRequest{Username: "ann", RoleIDs: ids[:1]}, wantStatus: http.StatusConflict, wantCode: "GRANT_ALREADY_EXISTS"},
+		{name: "missing username", body: AssignRolesRequest{RoleIDs: ids}, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED"},
+		{name: "no roles", body: AssignRolesRequest{Username: "bob"}, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED"},
+		{name: "bad role id", body: AssignRolesRequest{Username: "bob", RoleIDs: []string{"nope"}}, wantStatus: http.StatusBadRequest, wantCode: "INVALID_ROLE_ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := assign(tt.body)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantCode) {
+				t.Errorf("status = %d, body %s; want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/assets"
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/go-chi/chi/v5"
+)
+
+// DefaultMaxAvatarSize is the avatar size limit when WithAvatars is given no
+// assets.WithMaxSize option.
+const DefaultMaxAvatarSize = 1 << 20
+
+type avatars struct {
+	storage  assets.Storage
+	uploader *assets.Uploader
+}
+
+type Avatar struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	ETag        string `json:"etag"`
+}
+
+type AvatarResponse struct {
+	Avatar Avatar `json:"data"`
+}
+
+// avatarKey is the storage key of a user's avatar.
+func avatarKey(id auth.UserID) string {
+	return "avatars/" + id.String()
+}
+
+// handleUploadAvatar serves PUT /users/{id}/avatar. The image is the raw
+// body or the "file" field of a multipart form, and replaces any previous
+// avatar.
+func (h *AuthNHandler) handleUploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.avatarUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := service.GetUserByID(r.Context(), h.userStore, userID); err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	file, err := h.avatars.uploader.Receive(r)
+	if err != nil {
+		h.emit(r, ActionAvatarUpdated, userID.String(), err)
+		h.writeAPIError(w, r, avatarError(err))
+		return
+	}
+
+	obj, err := h.avatars.storage.Put(r.Context(), avatarKey(userID), file.Reader(), file.Size(), file.ContentType)
+	h.emit(r, ActionAvatarUpdated, userID.String(), err)
+	if err != nil {
+		h.writeAPIError(w, r, avatarError(err))
+		return
+	}
+
+	w.Header().Set("ETag", `"`+obj.ETag+`"`)
+	httpx.WriteJSON(w, http.StatusOK, AvatarResponse{Avatar: Avatar{
+		URL:         r.URL.Path,
+		ContentType: obj.ContentType,
+		Size:        obj.Size,
+		ETag:        obj.ETag,
+	}})
+}
+
+// handleGetAvatar serves GET /users/{id}/avatar, answering 304 when
+// If-None-Match carries the current ETag.
+func (h *AuthNHandler) handleGetAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.avatarUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := assets.Serve(w, r, h.avatars.storage, avatarKey(userID)); err != nil {
+		h.writeAPIError(w, r, avatarError(err))
+	}
+}
+
+// handleDeleteAvatar serves DELETE /users/{id}/avatar. Deleting a missing
+// avatar succeeds.
+func (h *AuthNHandler) handleDeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.avatarUserID(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.avatars.storage.Delete(r.Context(), avatarKey(userID))
+	h.emit(r, ActionAvatarDeleted, userID.String(), err)
+	if err != nil {
+		h.writeAPIError(w, r, avatarError(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthNHandler) avatarUserID(w http.ResponseWriter, r *http.Request) (auth.UserID, bool) {
+	userID, err := auth.ParseUserID(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		return userID, false
+	}
+	return userID, true
+}
+
+func avatarError(err error) *httpx.Error {
+	if errors.Is(err, assets.ErrNotFound) {
+		return httpx.NotFound("AVATAR_NOT_FOUND", "User has no avatar").Wrap(err)
+	}
+	return assets.HTTPError(err)
+}
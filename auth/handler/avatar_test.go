@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assetsfake "github.com/aquamarinepk/aqm/assets/fake"
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/go-chi/chi/v5"
+)
+
+var avatarPNG = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+
+func setupAvatarRouter(t *testing.T, opts ...Option) (chi.Router, string) {
+	t.Helper()
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		opts...).RegisterRoutes(r)
+
+	body, _ := json.Marshal(SignUpRequest{
+		Email:       "avatar@example.com",
+		Password:    "Password123!",
+		Username:    "avataruser",
+		DisplayName: "Avatar User",
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/signup", bytes.NewReader(body)))
+
+	var signup SignUpResponse
+	json.NewDecoder(w.Body).Decode(&signup)
+	return r, "/users/" + signup.User.ID.String() + "/avatar"
+}
+
+func avatarErrorCode(w *httptest.ResponseRecorder) string {
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	return resp.Code
+}
+
+func TestHandleAvatar(t *testing.T) {
+	storage := assetsfake.NewStorage()
+	r, path := setupAvatarRouter(t, WithAvatars(storage))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusNotFound || avatarErrorCode(w) != "AVATAR_NOT_FOUND" {
+		t.Fatalf("GET avatar before upload = %d %s, want 404 AVATAR_NOT_FOUND", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodPut, path, bytes.NewReader(avatarPNG))
+	req.Header.Set("Content-Type", "image/png")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT avatar = %d %s", w.Code, w.Body.String())
+	}
+	var resp AvatarResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Avatar.ContentType != "image/png" || resp.Avatar.Size != int64(len(avatarPNG)) || resp.Avatar.URL != path {
+		t.Errorf("PUT avatar = %+v", resp.Avatar)
+	}
+	if keys := storage.Keys(); len(keys) != 1 || !strings.HasPrefix(keys[0], "avatars/") {
+		t.Errorf("stored keys = %v", keys)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), avatarPNG) || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("GET avatar = %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	etag := w.Header().Get("ETag")
+	if etag != `"`+resp.Avatar.ETag+`"` {
+		t.Errorf("GET avatar ETag = %s, want %q", etag, resp.Avatar.ETag)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("GET avatar with If-None-Match = %d, want 304", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE avatar = %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET avatar after delete = %d, want 404", w.Code)
+	}
+}
+
+func TestHandleUploadAvatarMultipart(t *testing.T) {
+	r, path := setupAvatarRouter(t, WithAvatars(assetsfake.NewStorage()))
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("file", "me.png")
+	part.Write(avatarPNG)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPut, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT multipart avatar = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleUploadAvatarErrors(t *testing.T) {
+	r, path := setupAvatarRouter(t, WithAvatars(assetsfake.NewStorage()))
+
+	tests := []struct {
+		name       string
+		path       string
+		body       []byte
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "not an image", path: path, body: []byte("<html><script>alert(1)</script></html>"),
+			wantStatus: http.StatusUnsupportedMediaType, wantCode: "UNSUPPORTED_MEDIA_TYPE"},
+		{name: "too large", path: path, body: append(avatarPNG, make([]byte, DefaultMaxAvatarSize)...),
+			wantStatus: http.StatusRequestEntityTooLarge, wantCode: "FILE_TOO_LARGE"},
+		{name: "empty", path: path, wantStatus: http.StatusBadRequest, wantCode: "MISSING_FILE"},
+		{name: "unknown user", path: "/users/" + auth.NewUserID().String() + "/avatar", body: avatarPNG,
+			wantStatus: http.StatusNotFound, wantCode: "USER_NOT_FOUND"},
+		{name: "invalid user ID", path: "/users/nope/avatar", body: avatarPNG,
+			wantStatus: http.StatusBadRequest, wantCode: "INVALID_USER_ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, tt.path, bytes.NewReader(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("PUT avatar status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if code := avatarErrorCode(w); code != tt.wantCode {
+				t.Errorf("PUT avatar code = %s, want %s", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestAvatarRoutesDisabled(t *testing.T) {
+	r, path := setupAvatarRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, path, bytes.NewReader(avatarPNG)))
+	if w.Code != http.StatusNotFound && w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("PUT avatar without WithAvatars = %d, want the route unregistered", w.Code)
+	}
+}
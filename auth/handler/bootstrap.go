@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/netip"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// BootstrapTokenHeader carries the token required by WithBootstrap.
+const BootstrapTokenHeader = "X-Bootstrap-Token"
+
+// bootstrapGuard restricts who may create the superadmin.
+type bootstrapGuard struct {
+	token   string
+	allowed []netip.Prefix
+}
+
+// allowBootstrap reports whether r passes the WithBootstrap guard. When it
+// does not, the failure is emitted as ActionBootstrap and written to w.
+func (o *options) allowBootstrap(w http.ResponseWriter, r *http.Request) bool {
+	g := o.bootstrap
+	if g == nil {
+		return true
+	}
+
+	var err error
+	switch {
+	case len(g.allowed) > 0 && !g.allows(remoteIP(r)):
+		err = auth.ErrPermissionDenied
+	case g.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(BootstrapTokenHeader)), []byte(g.token)) != 1:
+		err = auth.ErrInvalidBootstrapToken
+	default:
+		return true
+	}
+
+	o.emit(r, ActionBootstrap, "", err)
+	o.handleServiceError(w, r, err)
+	return false
+}
+
+func (g *bootstrapGuard) allows(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range g.allowed {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
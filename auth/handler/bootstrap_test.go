@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestWithBootstrap(t *testing.T) {
+	audit := &recordingAudit{}
+	h := NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithAudit(audit), WithBootstrap("one-time-token", netip.MustParsePrefix("10.0.0.0/8")))
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	bootstrap := func(remoteAddr, token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/auth/bootstrap", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set(BootstrapTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		token      string
+		want       int
+	}{
+		{"outside allowlist", "192.0.2.1:1234", "one-time-token", http.StatusForbidden},
+		{"missing token", "10.1.2.3:1234", "", http.StatusUnauthorized},
+		{"wrong token", "10.1.2.3:1234", "guess", http.StatusUnauthorized},
+		{"allowed", "10.1.2.3:1234", "one-time-token", http.StatusOK},
+		{"after bootstrap", "10.1.2.3:1234", "one-time-token", http.StatusGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bootstrap(tt.remoteAddr, tt.token); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if len(audit.events) != len(tests) {
+		t.Fatalf("audit events = %d, want %d", len(audit.events), len(tests))
+	}
+	if !errors.Is(audit.events[0].Err, auth.ErrPermissionDenied) || !errors.Is(audit.events[1].Err, auth.ErrInvalidBootstrapToken) {
+		t.Errorf("rejected bootstrap events = %v, %v", audit.events[0].Err, audit.events[1].Err)
+	}
+	if audit.events[3].Err != nil || audit.events[3].Subject == "" {
+		t.Errorf("bootstrap event = %+v", audit.events[3])
+	}
+}
+
+func TestSystemBootstrap(t *testing.T) {
+	h := NewSystemHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewPasswordGenerator(), WithBootstrap("one-time-token"))
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	bootstrap := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/system/bootstrap", nil)
+		req.Header.Set(BootstrapTokenHeader, token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if got := bootstrap("guess"); got != http.StatusUnauthorized {
+		t.Errorf("wrong token status = %d, want 401", got)
+	}
+	if got := bootstrap("one-time-token"); got != http.StatusOK {
+		t.Errorf("bootstrap status = %d, want 200", got)
+	}
+	if got := bootstrap("one-time-token"); got != http.StatusGone {
+		t.Errorf("second bootstrap status = %d, want 410", got)
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/go-chi/chi/v5"
+)
+
+type consents struct {
+	store    auth.ConsentStore
+	policies auth.Policies
+}
+
+func (h *AuthNHandler) registerConsentRoutes(r chi.Router) {
+	r.Get("/policies", h.handleListPolicies)
+	r.Get("/policies/{policy}/pending", h.handlePendingConsent)
+	r.Get("/users/{id}/consents", h.handleListConsents)
+	r.Post("/users/{id}/consents", h.handleAcceptPolicy)
+}
+
+type Policy struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type PoliciesResponse struct {
+	Policies []Policy `json:"data"`
+}
+
+// AcceptPolicyRequest names the policy accepted. Version, when set, must be
+// the current one.
+type AcceptPolicyRequest struct {
+	Policy  string `json:"policy" validate:"required"`
+	Version string `json:"version"`
+}
+
+type ConsentResponse struct {
+	Consent *auth.Consent `json:"data"`
+}
+
+type ConsentsResponse struct {
+	Consents []*auth.Consent `json:"data"`
+}
+
+// handleListPolicies serves GET /policies with the current version of each
+// policy.
+func (h *AuthNHandler) handleListPolicies(w http.ResponseWriter, r *http.Request) {
+	policies := make([]Policy, 0, len(h.consents.policies))
+	for _, name := range h.consents.policies.Names() {
+		policies = append(policies, Policy{Name: name, Version: h.consents.policies[name]})
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, PoliciesResponse{Policies: policies})
+}
+
+// handleAcceptPolicy serves POST /users/{id}/consents. Accepting a version
+// already accepted returns the original consent.
+func (h *AuthNHandler) handleAcceptPolicy(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ParseUserID(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		return
+	}
+
+	var req AcceptPolicyRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	consent, err := service.AcceptPolicy(r.Context(), h.consents.store, h.userStore, h.consents.policies, userID, req.Policy, req.Version, remoteIP(r))
+	h.emit(r, ActionConsentAccepted, userID.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, ConsentResponse{Consent: consent})
+}
+
+// handleListConsents serves GET /users/{id}/consents, newest first.
+func (h *AuthNHandler) handleListConsents(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ParseUserID(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		return
+	}
+
+	consents, err := service.ListConsents(r.Context(), h.consents.store, userID)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, ConsentsResponse{Consents: consents})
+}
+
+// handlePendingConsent serves GET /policies/{policy}/pending, the active
+// users that have not accepted the current version of the policy.
+func (h *AuthNHandler) handlePendingConsent(w http.ResponseWriter, r *http.Request) {
+	users, err := service.PendingConsent(r.Context(), h.consents.store, h.userStore, h.consents.policies, chi.URLParam(r, "policy"))
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, ListUsersResponse{Users: users})
+}
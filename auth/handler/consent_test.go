@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/go-chi/chi/v5"
+)
+
+func setupConsentRouter(t *testing.T, opts ...Option) (chi.Router, string) {
+	t.Helper()
+	policies := auth.Policies{"terms": "2024-06", "privacy": "v2"}
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		append([]Option{WithConsents(fake.NewConsentStore(), policies)}, opts...)...).RegisterRoutes(r)
+
+	w := postJSON(t, r, "/auth/signup", SignUpRequest{
+		Email:       "consent@example.com",
+		Password:    "Password123!",
+		Username:    "consentuser",
+		DisplayName: "Consent User",
+	})
+	var signup SignUpResponse
+	json.NewDecoder(w.Body).Decode(&signup)
+	return r, "/users/" + signup.User.ID.String() + "/consents"
+}
+
+func pendingUsers(t *testing.T, r http.Handler, policy string) []*auth.User {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/policies/"+policy+"/pending", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("pending %s = %d %s", policy, w.Code, w.Body.String())
+	}
+	var resp ListUsersResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	return resp.Users
+}
+
+func TestHandleListPolicies(t *testing.T) {
+	r, _ := setupConsentRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/policies", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /policies = %d", w.Code)
+	}
+	var resp PoliciesResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	want := []Policy{{Name: "privacy", Version: "v2"}, {Name: "terms", Version: "2024-06"}}
+	if len(resp.Policies) != len(want) || resp.Policies[0] != want[0] || resp.Policies[1] != want[1] {
+		t.Errorf("GET /policies = %+v, want %+v", resp.Policies, want)
+	}
+}
+
+func TestHandleAcceptPolicy(t *testing.T) {
+	audit := &recordingAudit{}
+	r, path := setupConsentRouter(t, WithAudit(audit))
+
+	if users := pendingUsers(t, r, "terms"); len(users) != 1 {
+		t.Fatalf("pending before acceptance = %d users, want 1", len(users))
+	}
+
+	w := postJSON(t, r, path, AcceptPolicyRequest{Policy: "terms", Version: "2024-06"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("accept = %d %s", w.Code, w.Body.String())
+	}
+	var resp ConsentResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Consent == nil || resp.Consent.Policy != "terms" || resp.Consent.Version != "2024-06" || resp.Consent.RemoteIP != "192.0.2.1" {
+		t.Fatalf("accept = %+v", resp.Consent)
+	}
+
+	w = postJSON(t, r, path, AcceptPolicyRequest{Policy: "terms"})
+	var again ConsentResponse
+	json.NewDecoder(w.Body).Decode(&again)
+	if w.Code != http.StatusOK || again.Consent.ID != resp.Consent.ID {
+		t.Errorf("accept again = %d %+v, want the original consent", w.Code, again.Consent)
+	}
+
+	if users := pendingUsers(t, r, "terms"); len(users) != 0 {
+		t.Errorf("pending after acceptance = %d users, want 0", len(users))
+	}
+	if users := pendingUsers(t, r, "privacy"); len(users) != 1 {
+		t.Errorf("pending privacy = %d users, want 1", len(users))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var list ConsentsResponse
+	json.NewDecoder(w.Body).Decode(&list)
+	if w.Code != http.StatusOK || len(list.Consents) != 1 {
+		t.Errorf("list consents = %d %+v", w.Code, list.Consents)
+	}
+
+	if len(audit.events) == 0 || audit.events[len(audit.events)-1].Action != ActionConsentAccepted {
+		t.Errorf("audit events = %+v, want %s last", audit.events, ActionConsentAccepted)
+	}
+}
+
+func TestHandleAcceptPolicyErrors(t *testing.T) {
+	r, path := setupConsentRouter(t)
+
+	tests := []struct {
+		name     string
+		path     string
+		body     any
+		wantCode int
+		wantAPI  string
+	}{
+		{"outdated version", path, AcceptPolicyRequest{Policy: "terms", Version: "2023-01"}, http.StatusBadRequest, "INVALID_CONSENT"},
+		{"unknown policy", path, AcceptPolicyRequest{Policy: "cookies"}, http.StatusNotFound, "POLICY_NOT_FOUND"},
+		{"missing policy", path, AcceptPolicyRequest{}, http.StatusBadRequest, ""},
+		{"invalid user id", "/users/nope/consents", AcceptPolicyRequest{Policy: "terms"}, http.StatusBadRequest, "INVALID_USER_ID"},
+		{"unknown user", "/users/" + auth.NewUserID().String() + "/consents", AcceptPolicyRequest{Policy: "terms"}, http.StatusNotFound, "USER_NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postJSON(t, r, tt.path, tt.body)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantAPI != "" {
+				if code := avatarErrorCode(w); code != tt.wantAPI {
+					t.Errorf("code = %s, want %s", code, tt.wantAPI)
+				}
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/policies/cookies/pending", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("pending unknown policy = %d, want 404", w.Code)
+	}
+}
+
+func TestConsentRoutesDisabled(t *testing.T) {
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator()).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/policies", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /policies without WithConsents = %d, want 404", w.Code)
+	}
+}
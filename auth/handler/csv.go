@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// csvColumn is one column a CSV export can include.
+type csvColumn[T any] struct {
+	name  string
+	value func(T) string
+}
+
+// selectColumns returns the columns named in the comma-separated param, in
+// the order given, or those named in defaults when param is empty.
+func selectColumns[T any](all []csvColumn[T], param string, defaults []string) ([]csvColumn[T], error) {
+	names := defaults
+	if param != "" {
+		names = strings.Split(param, ",")
+	}
+
+	selected := make([]csvColumn[T], 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		i := slices.IndexFunc(all, func(c csvColumn[T]) bool { return c.name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		selected = append(selected, all[i])
+	}
+	return selected, nil
+}
+
+// csvExport streams rows to an HTTP response as RFC 4180 CSV: CRLF line
+// endings, with fields quoted when they hold commas, quotes or line breaks.
+type csvExport[T any] struct {
+	w       *csv.Writer
+	flusher http.Flusher
+	columns []csvColumn[T]
+	record  []string
+}
+
+// newCSVExport writes the response headers and the header row. Once it has
+// been called the status is committed, so later failures can only cut the
+// export short.
+func newCSVExport[T any](w http.ResponseWriter, filename string, columns []csvColumn[T]) (*csvExport[T], error) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	e := &csvExport[T]{
+		w:       csv.NewWriter(w),
+		columns: columns,
+		record:  make([]string, len(columns)),
+	}
+	e.w.UseCRLF = true
+	e.flusher, _ = w.(http.Flusher)
+
+	for i, c := range columns {
+		e.record[i] = c.name
+	}
+	if err := e.w.Write(e.record); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *csvExport[T]) write(items []T) error {
+	for _, item := range items {
+		for i, c := range e.columns {
+			e.record[i] = c.value(item)
+		}
+		if err := e.w.Write(e.record); err != nil {
+			return err
+		}
+	}
+	return e.flush()
+}
+
+// flush sends the rows written so far to the client.
+func (e *csvExport[T]) flush() error {
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		return err
+	}
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+	return nil
+}
+
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSelectColumns(t *testing.T) {
+	all := []csvColumn[string]{
+		{"a", func(s string) string { return s }},
+		{"b", func(s string) string { return s + s }},
+	}
+
+	tests := []struct {
+		name    string
+		param   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "defaults", want: []string{"a"}},
+		{name: "given order", param: "b, a", want: []string{"b", "a"}},
+		{name: "unknown", param: "a,c", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectColumns(all, tt.param, []string{"a"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectColumns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for i, c := range got {
+				if c.name != tt.want[i] {
+					t.Errorf("column %d = %q, want %q", i, c.name, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCSVExport(t *testing.T) {
+	w := httptest.NewRecorder()
+	columns := []csvColumn[string]{{"value", func(s string) string { return s }}}
+
+	export, err := newCSVExport(w, "values.csv", columns)
+	if err != nil {
+		t.Fatalf("newCSVExport() error = %v", err)
+	}
+	if err := export.write([]string{"plain", "a,b", `say "hi"`, " leading"}); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+
+	want := "value\r\nplain\r\n\"a,b\"\r\n\"say \"\"hi\"\"\"\r\n\" leading\"\r\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="values.csv"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if !w.Flushed {
+		t.Error("write() should flush the response")
+	}
+	if csvTime(time.Time{}) != "" {
+		t.Error("csvTime() of the zero time should be empty")
+	}
+}
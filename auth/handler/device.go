@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/go-chi/chi/v5"
+)
+
+// DefaultDeviceTrustTTL is how long a remembered device stays trusted when
+// WithDevices is given no TTL.
+const DefaultDeviceTrustTTL = 30 * 24 * time.Hour
+
+type devices struct {
+	store auth.DeviceStore
+	ttl   time.Duration
+}
+
+func (h *AuthNHandler) registerDeviceRoutes(r chi.Router) {
+	r.Get("/users/{id}/devices", h.handleListDevices)
+	r.Delete("/users/{id}/devices", h.handleRevokeDevices)
+	r.Delete("/users/{id}/devices/{deviceID}", h.handleRevokeDevice)
+}
+
+// DeviceRequest identifies the device signing in. Remember asks for a
+// trusted-device token.
+type DeviceRequest struct {
+	Fingerprint string `json:"fingerprint"`
+	Name        string `json:"name"`
+	Remember    bool   `json:"remember"`
+}
+
+type DevicesResponse struct {
+	Devices []*auth.Device `json:"data"`
+}
+
+// signInDevice registers the device of a successful sign-in and fills the
+// device fields of resp. A trusted-device token that does not verify, or
+// that belongs to another device than the one reported, leaves
+// resp.TrustedDevice false rather than failing the sign-in.
+func (h *AuthNHandler) signInDevice(r *http.Request, user *auth.User, req SignInRequest, resp *SignInResponse) error {
+	ctx := r.Context()
+
+	var trusted *auth.Device
+	if req.DeviceToken != "" {
+		device, err := service.VerifyTrustedDevice(ctx, h.devices.store, user.ID, req.DeviceToken)
+		if err == nil && (req.Device == nil || strings.TrimSpace(req.Device.Fingerprint) == device.Fingerprint) {
+			trusted = device
+		}
+	}
+
+	seen := service.DeviceSighting{IP: remoteIP(r), UserAgent: r.UserAgent()}
+	if loc := clientLocation(r); loc != nil {
+		seen.Country, seen.City = loc.CountryCode, loc.City
+	}
+	switch {
+	case req.Device != nil:
+		seen.Fingerprint, seen.Name = req.Device.Fingerprint, req.Device.Name
+	case trusted != nil:
+		seen.Fingerprint = trusted.Fingerprint
+	default:
+		return nil
+	}
+
+	device, err := service.RegisterDevice(ctx, h.devices.store, user.ID, seen)
+	if err != nil {
+		return err
+	}
+	resp.Device = device
+	resp.TrustedDevice = trusted != nil
+
+	if req.Device != nil && req.Device.Remember && trusted == nil {
+		token, err := service.TrustDevice(ctx, h.devices.store, device, h.devices.ttl)
+		h.emit(r, ActionDeviceTrusted, device.ID.String(), err)
+		if err != nil {
+			return err
+		}
+		resp.DeviceToken = token
+	}
+	return nil
+}
+
+// handleListDevices serves GET /users/{id}/devices.
+func (h *AuthNHandler) handleListDevices(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ParseUserID(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		return
+	}
+
+	devices, err := service.ListDevices(r.Context(), h.devices.store, userID)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, DevicesResponse{Devices: devices})
+}
+
+// handleRevokeDevice serves DELETE /users/{id}/devices/{deviceID}. The
+// device's trusted-device token stops working immediately.
+func (h *AuthNHandler) handleRevokeDevice(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ParseUserID(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		return
+	}
+	deviceID, err := auth.ParseDeviceID(chi.URLParam(r, "deviceID"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_DEVICE_ID", "Invalid device ID format")
+		return
+	}
+
+	err = service.RevokeDevice(r.Context(), h.devices.store, userID, deviceID)
+	h.emit(r, ActionDeviceRevoked, deviceID.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRevokeDevices serves DELETE /users/{id}/devices, forgetting every
+// device of the user.
+func (h *AuthNHandler) handleRevokeDevices(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ParseUserID(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		return
+	}
+
+	err = service.RevokeDevices(r.Context(), h.devices.store, userID)
+	h.emit(r, ActionDeviceRevoked, userID.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
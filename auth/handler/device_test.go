@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/geoip"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func setupDeviceRouter(t *testing.T, opts ...Option) (chi.Router, string) {
+	t.Helper()
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		append([]Option{WithDevices(fake.NewDeviceStore(), 0)}, opts...)...).RegisterRoutes(r)
+
+	w := postJSON(t, r, "/auth/signup", SignUpRequest{
+		Email:       "device@example.com",
+		Password:    "Password123!",
+		Username:    "deviceuser",
+		DisplayName: "Device User",
+	})
+	var signup SignUpResponse
+	json.NewDecoder(w.Body).Decode(&signup)
+	return r, "/users/" + signup.User.ID.String() + "/devices"
+}
+
+func deviceSignIn(t *testing.T, r http.Handler, req SignInRequest) SignInResponse {
+	t.Helper()
+	req.Email, req.Password = "device@example.com", "Password123!"
+	w := postJSON(t, r, "/auth/signin", req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("signin = %d %s", w.Code, w.Body.String())
+	}
+	var resp SignInResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	return resp
+}
+
+func TestSignInWithDevice(t *testing.T) {
+	r, _ := setupDeviceRouter(t)
+
+	resp := deviceSignIn(t, r, SignInRequest{})
+	if resp.Device != nil || resp.DeviceToken != "" || resp.TrustedDevice {
+		t.Errorf("signin without device = %+v", resp)
+	}
+
+	resp = deviceSignIn(t, r, SignInRequest{Device: &DeviceRequest{Fingerprint: "fp-1", Name: "Laptop", Remember: true}})
+	if resp.Device == nil || resp.Device.Name != "Laptop" || resp.Device.LastSeenIP != "192.0.2.1" {
+		t.Fatalf("signin device = %+v", resp.Device)
+	}
+	if resp.DeviceToken == "" || resp.TrustedDevice {
+		t.Fatalf("remembered signin = token %q trusted %v", resp.DeviceToken, resp.TrustedDevice)
+	}
+	token := resp.DeviceToken
+
+	resp = deviceSignIn(t, r, SignInRequest{DeviceToken: token})
+	if !resp.TrustedDevice || resp.Device == nil || resp.Device.Fingerprint != "fp-1" || resp.DeviceToken != "" {
+		t.Errorf("signin with device token = %+v", resp)
+	}
+
+	resp = deviceSignIn(t, r, SignInRequest{Device: &DeviceRequest{Fingerprint: "fp-2"}, DeviceToken: token})
+	if resp.TrustedDevice {
+		t.Error("device token accepted for another device")
+	}
+
+	resp = deviceSignIn(t, r, SignInRequest{DeviceToken: token + "x"})
+	if resp.TrustedDevice || resp.Device != nil {
+		t.Errorf("signin with bad device token = %+v", resp)
+	}
+
+	w := postJSON(t, r, "/auth/signin", SignInRequest{Email: "device@example.com", Password: "Password123!", Device: &DeviceRequest{}})
+	if w.Code != http.StatusBadRequest || avatarErrorCode(w) != "INVALID_DEVICE" {
+		t.Errorf("signin with empty fingerprint = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSignInMFARequiredTrustedDevice(t *testing.T) {
+	settings := fake.NewSettingsStore()
+	r, _ := setupDeviceRouter(t, WithSettings(settings))
+	token := deviceSignIn(t, r, SignInRequest{Device: &DeviceRequest{Fingerprint: "fp-1", Remember: true}}).DeviceToken
+
+	required := true
+	settings.Save(t.Context(), &auth.Settings{MFARequired: &required})
+
+	resp := deviceSignIn(t, r, SignInRequest{Device: &DeviceRequest{Fingerprint: "fp-2", Remember: true}})
+	if !resp.MFARequired || !strings.HasSuffix(resp.Token, "-mfa-pending") || resp.DeviceToken != "" {
+		t.Errorf("untrusted signin with MFA required = %+v, want an MFA-pending token", resp)
+	}
+
+	resp = deviceSignIn(t, r, SignInRequest{DeviceToken: token})
+	if !resp.TrustedDevice || resp.MFARequired || strings.HasSuffix(resp.Token, "-mfa-pending") {
+		t.Errorf("trusted signin with MFA required = %+v, want the session token", resp)
+	}
+}
+
+func TestHandleDevices(t *testing.T) {
+	r, path := setupDeviceRouter(t)
+
+	token := deviceSignIn(t, r, SignInRequest{Device: &DeviceRequest{Fingerprint: "fp-1", Remember: true}}).DeviceToken
+	deviceSignIn(t, r, SignInRequest{Device: &DeviceRequest{Fingerprint: "fp-2"}})
+
+	list := func() []string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET devices = %d %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Devices []struct {
+				ID          string `json:"id"`
+				Fingerprint string `json:"fingerprint"`
+			} `json:"data"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		var ids []string
+		for _, d := range resp.Devices {
+			ids = append(ids, d.ID)
+		}
+		return ids
+	}
+
+	ids := list()
+	if len(ids) != 2 {
+		t.Fatalf("devices = %v, want 2", ids)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path+"/"+ids[0], nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE device = %d %s", w.Code, w.Body.String())
+	}
+	if got := list(); len(got) != 1 {
+		t.Errorf("devices after revoke = %v", got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path+"/"+ids[0], nil))
+	if w.Code != http.StatusNotFound || avatarErrorCode(w) != "DEVICE_NOT_FOUND" {
+		t.Errorf("DELETE revoked device = %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path+"/not-an-id", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("DELETE bad device id = %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE devices = %d %s", w.Code, w.Body.String())
+	}
+	if got := list(); len(got) != 0 {
+		t.Errorf("devices after revoking all = %v", got)
+	}
+	if resp := deviceSignIn(t, r, SignInRequest{DeviceToken: token}); resp.TrustedDevice {
+		t.Error("revoked device still trusted")
+	}
+}
+
+func TestDeviceRoutesDisabled(t *testing.T) {
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator()).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/00000000-0000-0000-0000-000000000000/devices", nil))
+	if w.Code != http.StatusNotFound && w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET devices without WithDevices = %d", w.Code)
+	}
+}
+
+func TestSignInDeviceLocation(t *testing.T) {
+	locator := geoip.LocatorFunc(func(ip netip.Addr) (*geoip.Location, error) {
+		return &geoip.Location{CountryCode: "DE", City: "Berlin"}, nil
+	})
+	events := &recordingAudit{}
+	r := chi.NewRouter()
+	r.Use(middleware.ClientIP(middleware.ClientIPConfig{Locator: locator}))
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithDevices(fake.NewDeviceStore(), 0), WithAudit(events)).RegisterRoutes(r)
+
+	postJSON(t, r, "/auth/signup", SignUpRequest{
+		Email:       "device@example.com",
+		Password:    "Password123!",
+		Username:    "deviceuser",
+		DisplayName: "Device User",
+	})
+	resp := deviceSignIn(t, r, SignInRequest{Device: &DeviceRequest{Fingerprint: "fp-1"}})
+	if resp.Device.LastSeenCountry != "DE" || resp.Device.LastSeenCity != "Berlin" {
+		t.Errorf("device = %+v, want it located in Berlin", resp.Device)
+	}
+
+	last := events.events[len(events.events)-1]
+	if last.Action != ActionSignIn || last.Location == nil || last.Location.CountryCode != "DE" {
+		t.Errorf("last event = %+v, want a located sign-in", last)
+	}
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/mail"
+)
+
+type emailChanges struct {
+	changes   *service.EmailChanges
+	verifyURL string
+	revertURL string
+}
+
+type EmailChangeRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// handleRequestEmailChange serves POST /users/{id}/email and
+// POST /users/me/email, emailing the new address a verification link. The
+// stored email is unchanged until the link is followed, so the request
+// answers 202 Accepted.
+func (h *AuthNHandler) handleRequestEmailChange(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req EmailChangeRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	ec := h.emailChanges
+	user, email, token, err := service.RequestEmailChange(r.Context(), h.userStore, h.crypto, ec.changes, userID, req.Email)
+	h.emit(r, ActionEmailChangeRequested, userID.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.sendMailTo(r, user, email, mail.TemplateEmailChange, mailData{
+		Link:      tokenLink(ec.verifyURL, token),
+		ExpiresIn: expiresIn(ec.changes.TTL()),
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleVerifyEmailChange serves GET /auth/email/verify?token=..., storing
+// the new address and emailing the old one a link to revert the change.
+// Each token works once.
+func (h *AuthNHandler) handleVerifyEmailChange(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "Token is required")
+		return
+	}
+
+	ec := h.emailChanges
+	user, previous, revert, err := service.ConfirmEmailChange(r.Context(), h.userStore, h.crypto, ec.changes, token)
+	if err != nil {
+		h.emit(r, ActionEmailChanged, "", err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.emit(r, ActionEmailChanged, user.ID.String(), nil)
+	h.sendMailTo(r, user, previous, mail.TemplateEmailChanged, mailData{
+		Link:      tokenLink(ec.revertURL, revert),
+		ExpiresIn: expiresIn(ec.changes.RevertWindow()),
+	})
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	setETag(w, user.Version)
+	httpx.WriteJSON(w, http.StatusOK, UserResponse{User: user})
+}
+
+// handleRevertEmailChange serves GET /auth/email/revert?token=...,
+// restoring the address an email change replaced. Each token works once.
+func (h *AuthNHandler) handleRevertEmailChange(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "Token is required")
+		return
+	}
+
+	user, err := service.RevertEmailChange(r.Context(), h.userStore, h.crypto, h.emailChanges.changes, token)
+	if err != nil {
+		h.emit(r, ActionEmailChangeReverted, "", err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.emit(r, ActionEmailChangeReverted, user.ID.String(), nil)
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	setETag(w, user.Version)
+	httpx.WriteJSON(w, http.StatusOK, UserResponse{User: user})
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/mail"
+	mailfake "github.com/aquamarinepk/aqm/mail/fake"
+	"github.com/go-chi/chi/v5"
+)
+
+var emailChangeLinkPattern = regexp.MustCompile(`https://app\.example\.com/email/(verify|revert)\?[^\s]+`)
+
+func emailChangeToken(t *testing.T, msg *mail.Message) string {
+	t.Helper()
+	link := emailChangeLinkPattern.FindString(msg.Text)
+	if link == "" {
+		t.Fatalf("message text = %q, want a link", msg.Text)
+	}
+	u, _ := url.Parse(link)
+	return u.Query().Get("token")
+}
+
+func getPath(r http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestEmailChange(t *testing.T) {
+	sender := mailfake.NewSender()
+	audit := &recordingAudit{}
+	users := fake.NewUserStore()
+	crypto := fake.NewCryptoService()
+	changes := service.NewEmailChanges([]byte("email-change-key"), fake.NewNonceStore(), 0, 0)
+
+	r := chi.NewRouter()
+	NewAuthNHandler(users, crypto, fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithMailer(mail.NewMailer(sender, nil, "noreply@example.com")),
+		WithEmailChanges(changes, "https://app.example.com/email/verify", "https://app.example.com/email/revert"),
+		WithAudit(audit),
+	).RegisterRoutes(r)
+
+	w := postJSON(t, r, "/auth/signup", SignUpRequest{Email: "ann@example.com", Password: "Password123!", Username: "ann", DisplayName: "Ann"})
+	var signup SignUpResponse
+	json.NewDecoder(w.Body).Decode(&signup)
+	id := signup.User.ID.String()
+	postJSON(t, r, "/auth/signup", SignUpRequest{Email: "bob@example.com", Password: "Password123!", Username: "bob", DisplayName: "Bob"})
+
+	if w := postJSON(t, r, "/users/"+id+"/email", EmailChangeRequest{Email: "bob@example.com"}); w.Code != http.StatusConflict {
+		t.Errorf("change to a taken email = %d, want 409", w.Code)
+	}
+	if w := postJSONAs(r, id, "/users/me/email", EmailChangeRequest{Email: "Ann.New@Example.com"}); w.Code != http.StatusAccepted {
+		t.Fatalf("request change = %d: %s", w.Code, w.Body)
+	}
+
+	msgs := sender.Messages()
+	verifyMsg := msgs[len(msgs)-1]
+	if verifyMsg.To[0] != "ann.new@example.com" || verifyMsg.Subject != "Confirm your new email address" {
+		t.Errorf("verification message = %v %q", verifyMsg.To, verifyMsg.Subject)
+	}
+	token := emailChangeToken(t, verifyMsg)
+
+	if w := getPath(r, "/auth/email/verify"); w.Code != http.StatusBadRequest {
+		t.Errorf("verify without token = %d, want 400", w.Code)
+	}
+	w = getPath(r, "/auth/email/verify?token="+url.QueryEscape(token))
+	if w.Code != http.StatusOK {
+		t.Fatalf("verify = %d: %s", w.Code, w.Body)
+	}
+	if _, err := users.GetByEmailLookup(t.Context(), crypto.ComputeLookupHash("ann.new@example.com")); err != nil {
+		t.Errorf("new email lookup error = %v", err)
+	}
+	if w := getPath(r, "/auth/email/verify?token="+url.QueryEscape(token)); w.Code != http.StatusBadRequest {
+		t.Errorf("verify replay = %d, want 400", w.Code)
+	}
+
+	msgs = sender.Messages()
+	noticeMsg := msgs[len(msgs)-1]
+	if noticeMsg.To[0] != "ann@example.com" || noticeMsg.Subject != "Your email address was changed" {
+		t.Errorf("notice message = %v %q", noticeMsg.To, noticeMsg.Subject)
+	}
+
+	w = getPath(r, "/auth/email/revert?token="+url.QueryEscape(emailChangeToken(t, noticeMsg)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("revert = %d: %s", w.Code, w.Body)
+	}
+	if _, err := users.GetByEmailLookup(t.Context(), crypto.ComputeLookupHash("ann@example.com")); err != nil {
+		t.Errorf("old email lookup after revert error = %v", err)
+	}
+
+	var actions []string
+	for _, e := range audit.events {
+		if e.Action != ActionMailSent && e.Action != ActionSignUp {
+			actions = append(actions, e.Action)
+		}
+	}
+	want := []string{ActionEmailChangeRequested, ActionEmailChangeRequested, ActionEmailChanged, ActionEmailChanged, ActionEmailChangeReverted}
+	if len(actions) != len(want) {
+		t.Fatalf("actions = %v, want %v", actions, want)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("actions = %v, want %v", actions, want)
+			break
+		}
+	}
+}
+
+func TestEmailChangeRequiresMailer(t *testing.T) {
+	r := chi.NewRouter()
+	changes := service.NewEmailChanges([]byte("email-change-key"), fake.NewNonceStore(), 0, 0)
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithEmailChanges(changes, "https://app.example.com/email/verify", "https://app.example.com/email/revert"),
+	).RegisterRoutes(r)
+
+	if w := getPath(r, "/auth/email/verify?token=x"); w.Code != http.StatusNotFound {
+		t.Errorf("verify without mailer = %d, want 404", w.Code)
+	}
+}
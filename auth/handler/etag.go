@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag formats a resource version as a strong entity tag.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", etag(version))
+}
+
+// matchesIfMatch reports whether an If-Match header value matches version.
+// It uses the strong comparison of RFC 9110: weak tags never match and "*"
+// matches any version.
+func matchesIfMatch(header string, version int64) bool {
+	want := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == want {
+			return true
+		}
+	}
+	return false
+}
+
+// requireIfMatch returns the If-Match header of r. When it is missing it
+// writes 428 Precondition Required and returns false.
+func (o *options) requireIfMatch(w http.ResponseWriter, r *http.Request) (string, bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		o.writeError(w, r, http.StatusPreconditionRequired, "PRECONDITION_REQUIRED", "If-Match header is required")
+		return "", false
+	}
+	return header, true
+}
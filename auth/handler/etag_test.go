@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMatchesIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int64
+		want    bool
+	}{
+		{`"3"`, 3, true},
+		{`"2"`, 3, false},
+		{`W/"3"`, 3, false},
+		{`*`, 3, true},
+		{`"1", "3"`, 3, true},
+		{`"1","2"`, 3, false},
+		{`3`, 3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := matchesIfMatch(tt.header, tt.version); got != tt.want {
+				t.Errorf("matchesIfMatch(%q, %d) = %v, want %v", tt.header, tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRoleETag(t *testing.T) {
+	r := chi.NewRouter()
+	setupAuthZHandler().RegisterRoutes(r)
+
+	body, _ := json.Marshal(CreateRoleRequest{Name: "tagged", Permissions: []string{"read"}, CreatedBy: "admin"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/roles", bytes.NewReader(body)))
+
+	var created RoleResponse
+	json.NewDecoder(w.Body).Decode(&created)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/roles/"+created.Role.ID.String(), nil))
+	etagHeader := w.Header().Get("ETag")
+	if etagHeader != `"1"` {
+		t.Fatalf("GET /roles/{id} ETag = %q, want %q", etagHeader, `"1"`)
+	}
+
+	body, _ = json.Marshal(UpdateRoleRequest{Description: "Tagged", Permissions: []string{"read"}, UpdatedBy: "admin"})
+	req := httptest.NewRequest(http.MethodPut, "/roles/"+created.Role.ID.String(), bytes.NewReader(body))
+	req.Header.Set("If-Match", etagHeader)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT /roles/{id} with current ETag = %d, want 200", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/roles/"+created.Role.ID.String(), bytes.NewReader(body))
+	req.Header.Set("If-Match", etagHeader)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("PUT /roles/{id} with stale ETag = %d, want 412", w.Code)
+	}
+}
+
+func TestGetUserETag(t *testing.T) {
+	h := setupAuthNHandler()
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	body, _ := json.Marshal(SignUpRequest{
+		Email:       "etag@example.com",
+		Password:    "Password123!",
+		Username:    "etaguser",
+		DisplayName: "ETag User",
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/signup", bytes.NewReader(body)))
+
+	var signup SignUpResponse
+	json.NewDecoder(w.Body).Decode(&signup)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/"+signup.User.ID.String(), nil))
+	if got := w.Header().Get("ETag"); got != `"1"` {
+		t.Errorf("GET /users/{id} ETag = %q, want %q", got, `"1"`)
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/pubsub"
+	"github.com/google/uuid"
+)
+
+// DefaultEventTopicPrefix is prepended to the action to form the topic of
+// the events published by NewEventHooks, e.g. "auth.events.user.suspended".
+// A JetStream stream bound to "auth.events.>" captures all of them.
+const DefaultEventTopicPrefix = "auth.events."
+
+// eventNamespace scopes the envelope IDs derived by EventID.
+var eventNamespace = uuid.MustParse("5b0be5c6-3f6e-4a1c-9a3e-7f4f7d0c2a61")
+
+// eventPublishAttempts is how many times an event is published before the
+// failure is logged. Retries reuse the envelope ID, so a broker that
+// de-duplicates, such as the JetStream broker, stores the event once even
+// when only the acknowledgement of an earlier attempt was lost.
+const eventPublishAttempts = 2
+
+// DomainEvent is the payload of the envelopes published by NewEventHooks.
+type DomainEvent struct {
+	Action       string            `json:"action"`
+	Subject      string            `json:"subject,omitempty"`
+	Actor        string            `json:"actor,omitempty"`
+	Impersonator string            `json:"impersonator,omitempty"`
+	RemoteIP     string            `json:"remote_ip,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// NewEventHooks returns Hooks that publish every successful operation as a
+// DomainEvent on topicPrefix + Action, DefaultEventTopicPrefix when empty.
+// The envelope ID is EventID(e), so the same event delivered twice, by a
+// retry or by hooks registered twice, has the same de-duplication ID.
+// Register them with WithHooks. Publish failures are logged and do not
+// affect the request.
+func NewEventHooks(publisher pubsub.Publisher, topicPrefix string, logger log.Logger) Hooks {
+	if logger == nil {
+		logger = log.NewNoopLogger()
+	}
+	if topicPrefix == "" {
+		topicPrefix = DefaultEventTopicPrefix
+	}
+
+	return Hooks{
+		OnSuccess: func(ctx context.Context, e Event) {
+			topic := topicPrefix + e.Action
+			env := pubsub.NewEnvelope(topic, DomainEvent{
+				Action:       e.Action,
+				Subject:      e.Subject,
+				Actor:        middleware.GetUserID(ctx),
+				Impersonator: e.Impersonator,
+				RemoteIP:     e.RemoteIP,
+				Metadata:     e.Metadata,
+			})
+			env.ID = EventID(e)
+			env.Timestamp = e.At.UTC()
+
+			var err error
+			for range eventPublishAttempts {
+				if err = publisher.Publish(ctx, topic, env); err == nil {
+					return
+				}
+			}
+			logger.Error("Cannot publish auth event", "topic", topic, "id", env.ID, "error", err)
+		},
+	}
+}
+
+// EventID returns the de-duplication ID of e, derived from its action,
+// subject and time.
+func EventID(e Event) string {
+	name := e.Action + "|" + e.Subject + "|" + strconv.FormatInt(e.At.UnixNano(), 10)
+	return uuid.NewSHA1(eventNamespace, []byte(name)).String()
+}
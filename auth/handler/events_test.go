@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/pubsub"
+	"github.com/go-chi/chi/v5"
+)
+
+type failingPublisher struct {
+	envs []pubsub.Envelope
+	fail int
+}
+
+func (p *failingPublisher) Publish(ctx context.Context, topic string, env pubsub.Envelope) error {
+	p.envs = append(p.envs, env)
+	if len(p.envs) <= p.fail {
+		return errors.New("ack timeout")
+	}
+	return nil
+}
+
+func TestNewEventHooks(t *testing.T) {
+	broker := pubsub.NewNoopBroker()
+	authn := NewAuthNHandler(
+		fake.NewUserStore(),
+		fake.NewCryptoService(),
+		fake.NewTokenGenerator(),
+		fake.NewPasswordGenerator(),
+		fake.NewPINGenerator(),
+		WithHooks(NewEventHooks(broker, "", nil)),
+	)
+	r := chi.NewRouter()
+	authn.RegisterRoutes(r)
+
+	signUp := SignUpRequest{Email: "event@example.com", Password: "Password123!", Username: "eventuser", DisplayName: "Event"}
+	if w := postJSON(t, r, "/auth/signup", signUp); w.Code != http.StatusCreated {
+		t.Fatalf("signup status = %d, body: %s", w.Code, w.Body.String())
+	}
+	postJSON(t, r, "/auth/signin", SignInRequest{Email: "event@example.com", Password: "wrong-Password1!"})
+
+	published := broker.Published()
+	if len(published) != 1 {
+		t.Fatalf("published %d envelopes, want 1: %+v", len(published), published)
+	}
+	env := published[0]
+	if env.Topic != DefaultEventTopicPrefix+ActionSignUp {
+		t.Errorf("topic = %q, want %q", env.Topic, DefaultEventTopicPrefix+ActionSignUp)
+	}
+	event, ok := env.Payload.(DomainEvent)
+	if !ok || event.Action != ActionSignUp || event.Subject == "" {
+		t.Errorf("payload = %+v, want a %s DomainEvent", env.Payload, ActionSignUp)
+	}
+}
+
+func TestNewEventHooksReusesIDOnRetry(t *testing.T) {
+	publisher := &failingPublisher{fail: 1}
+	hooks := NewEventHooks(publisher, "svc.", nil)
+
+	e := Event{Action: ActionUserSuspended, Subject: "alice", At: time.Now()}
+	hooks.OnSuccess(context.Background(), e)
+
+	if len(publisher.envs) != 2 {
+		t.Fatalf("published %d times, want 2", len(publisher.envs))
+	}
+	first, retry := publisher.envs[0], publisher.envs[1]
+	if first.ID != retry.ID || first.ID != EventID(e) {
+		t.Errorf("retry ID = %s, first = %s, want both %s", retry.ID, first.ID, EventID(e))
+	}
+	if first.Topic != "svc."+ActionUserSuspended {
+		t.Errorf("topic = %q", first.Topic)
+	}
+}
+
+func TestEventID(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC)
+	e := Event{Action: ActionUserSuspended, Subject: "alice", At: at}
+
+	if EventID(e) != EventID(e) {
+		t.Error("EventID is not deterministic")
+	}
+
+	other := e
+	other.At = at.Add(time.Nanosecond)
+	if EventID(e) == EventID(other) {
+		t.Error("events at different times share an ID")
+	}
+
+	other = e
+	other.Subject = "bob"
+	if EventID(e) == EventID(other) {
+		t.Error("events on different subjects share an ID")
+	}
+}
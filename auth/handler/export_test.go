@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/go-chi/chi/v5"
+)
+
+func readCSV(t *testing.T, w *httptest.ResponseRecorder) [][]string {
+	t.Helper()
+	if got := w.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Fatalf("Content-Type = %q, want text/csv", got)
+	}
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("parse CSV: %v\n%s", err, w.Body.String())
+	}
+	return records
+}
+
+func TestHandleExportUsers(t *testing.T) {
+	store := fake.NewUserStore()
+	cryptoSvc := fake.NewCryptoService()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	addUser := func(username, name string, status auth.UserStatus, at time.Time) {
+		u := auth.NewUser()
+		u.Username, u.Name, u.Status = username, name, status
+		if err := u.SetEmail(username+"@example.com", cryptoSvc.EncryptionKey(), cryptoSvc.SigningKey()); err != nil {
+			t.Fatalf("SetEmail() error = %v", err)
+		}
+		u.BeforeCreate()
+		u.CreatedAt = at
+		store.Create(t.Context(), u)
+	}
+	addUser("quoted", `Ann "The Admin", Jr.`, auth.UserStatusActive, base.Add(2*time.Hour))
+	addUser("multiline", "Line\nBreak", auth.UserStatusSuspended, base.Add(time.Hour))
+	for i := range auth.MaxUserQueryLimit + 5 {
+		addUser(fmt.Sprintf("bulk%03d", i), "Bulk", auth.UserStatusPending, base.Add(-time.Duration(i)*time.Minute))
+	}
+
+	h := NewAuthNHandler(store, cryptoSvc, fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator())
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	export := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/export"+query, nil))
+		return w
+	}
+
+	t.Run("columns and escaping", func(t *testing.T) {
+		w := export("?columns=username,name,email,status&status=active,suspended")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %v (%s)", w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), "\r\n") || !strings.Contains(w.Body.String(), `"Ann ""The Admin"", Jr."`) {
+			t.Errorf("body is not RFC 4180 encoded:\n%q", w.Body.String())
+		}
+
+		want := [][]string{
+			{"username", "name", "email", "status"},
+			{"quoted", `Ann "The Admin", Jr.`, "quoted@example.com", "active"},
+			{"multiline", "Line\nBreak", "multiline@example.com", "suspended"},
+		}
+		if got := readCSV(t, w); !reflect.DeepEqual(got, want) {
+			t.Errorf("records = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("pages through every user", func(t *testing.T) {
+		w := export("?status=pending")
+		records := readCSV(t, w)
+		if !reflect.DeepEqual(records[0], defaultUserColumns) {
+			t.Errorf("header = %v, want %v", records[0], defaultUserColumns)
+		}
+		if got, want := len(records)-1, auth.MaxUserQueryLimit+5; got != want {
+			t.Errorf("exported %d users, want %d", got, want)
+		}
+		if records[1][1] != "bulk000" || records[len(records)-1][1] != fmt.Sprintf("bulk%03d", auth.MaxUserQueryLimit+4) {
+			t.Errorf("users are not ordered newest first: first %v, last %v", records[1], records[len(records)-1])
+		}
+	})
+
+	t.Run("search filters", func(t *testing.T) {
+		records := readCSV(t, export("?username=multi&columns=username"))
+		if want := [][]string{{"username"}, {"multiline"}}; !reflect.DeepEqual(records, want) {
+			t.Errorf("records = %v, want %v", records, want)
+		}
+	})
+
+	for _, query := range []string{"?columns=username,password_hash", "?status=frozen", "?created_after=yesterday"} {
+		if w := export(query); w.Code != http.StatusBadRequest {
+			t.Errorf("export%s status = %v, want 400", query, w.Code)
+		}
+	}
+}
+
+func TestHandleExportGrants(t *testing.T) {
+	roleStore := fake.NewRoleStore()
+	grantStore := fake.NewGrantStore(roleStore)
+	h := NewAuthZHandler(roleStore, grantStore)
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	editor := auth.NewRole()
+	editor.Name = "editor"
+	editor.BeforeCreate()
+	roleStore.Create(t.Context(), editor)
+
+	legacy := auth.NewRole()
+	legacy.Name = "legacy"
+	legacy.Status = auth.RoleStatusInactive
+	legacy.BeforeCreate()
+	roleStore.Create(t.Context(), legacy)
+
+	assignedAt := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	for _, g := range []*auth.Grant{
+		auth.NewGrant("alice", editor.ID, "admin"),
+		auth.NewGrant("sa:publisher", editor.ID, "admin"),
+		auth.NewGrant("bob", legacy.ID, "admin"),
+	} {
+		g.AssignedAt = assignedAt
+		grantStore.Create(t.Context(), g)
+	}
+
+	export := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/grants/export"+query, nil))
+		return w
+	}
+
+	records := readCSV(t, export("?status=active&columns=username,principal_type,role,assigned_at"))
+	rows := records[1:]
+	if len(rows) != 2 {
+		t.Fatalf("exported %d grants, want 2: %v", len(rows), records)
+	}
+	want := map[string][]string{
+		"alice":        {"alice", "user", "editor", "2026-03-04T05:06:07Z"},
+		"sa:publisher": {"sa:publisher", "service_account", "editor", "2026-03-04T05:06:07Z"},
+	}
+	for _, row := range rows {
+		if !reflect.DeepEqual(row, want[row[0]]) {
+			t.Errorf("row = %v, want %v", row, want[row[0]])
+		}
+	}
+
+	records = readCSV(t, export("?role=legacy"))
+	if len(records) != 2 || records[1][0] != "bob" || records[1][1] != "legacy" {
+		t.Errorf("role=legacy records = %v", records)
+	}
+
+	for _, query := range []string{"?columns=secret", "?status=deleted"} {
+		if w := export(query); w.Code != http.StatusBadRequest {
+			t.Errorf("export%s status = %v, want 400", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/go-chi/chi/v5"
+)
+
+// grantApproval is the two-step grant flow set up by WithGrantApproval.
+type grantApproval struct {
+	store      auth.GrantRequestStore
+	permission string
+}
+
+func (h *AuthZHandler) registerGrantRequestRoutes(r chi.Router) {
+	r.Post("/grant-requests", h.handleCreateGrantRequest)
+	r.Get("/grant-requests", h.handleListGrantRequests)
+	r.Get("/grant-requests/{id}", h.handleGetGrantRequest)
+	r.Post("/grant-requests/{id}/approve", h.handleApproveGrantRequest)
+	r.Post("/grant-requests/{id}/reject", h.handleRejectGrantRequest)
+
+	r.Post("/users/me/role-requests", h.handleCreateRoleRequest)
+	r.Get("/users/me/role-requests", h.handleListRoleRequests)
+	r.Get("/users/me/role-requests/{id}", h.handleGetRoleRequest)
+}
+
+// CreateGrantRequestRequest asks for RoleID to be assigned to Username, the
+// caller when empty.
+type CreateGrantRequestRequest struct {
+	Username string `json:"username"`
+	RoleID   string `json:"role_id" validate:"required"`
+	Reason   string `json:"reason"`
+}
+
+// DecideGrantRequestRequest is the optional body of the approve and reject
+// endpoints.
+type DecideGrantRequestRequest struct {
+	Note string `json:"note"`
+}
+
+// GrantRequestResponse carries a grant request and, once approved, the
+// grant it created.
+type GrantRequestResponse struct {
+	GrantRequest *auth.GrantRequest `json:"grant_request"`
+	Grant        *auth.Grant        `json:"grant,omitempty"`
+}
+
+type ListGrantRequestsResponse struct {
+	GrantRequests []*auth.GrantRequest `json:"grant_requests"`
+}
+
+// handleCreateGrantRequest serves POST /grant-requests. The caller must be
+// authenticated and is recorded as the requester.
+func (h *AuthZHandler) handleCreateGrantRequest(w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateGrantRequestRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+	if req.Username == "" {
+		req.Username = actor
+	}
+
+	roleID, err := auth.ParseRoleID(req.RoleID)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_ROLE_ID", "Invalid role ID format")
+		return
+	}
+
+	grantReq, err := service.RequestGrant(r.Context(), h.grantApproval.store, h.roleStore, h.grantStore, req.Username, roleID, req.Reason, actor)
+	if err != nil {
+		h.emit(r, ActionGrantRequested, req.Username, err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.emit(r, ActionGrantRequested, grantReq.ID.String(), nil)
+
+	httpx.WriteJSON(w, http.StatusCreated, GrantRequestResponse{GrantRequest: grantReq})
+}
+
+// handleListGrantRequests serves GET /grant-requests, optionally filtered
+// by ?status=pending, approved or rejected.
+func (h *AuthZHandler) handleListGrantRequests(w http.ResponseWriter, r *http.Request) {
+	status, err := auth.ParseGrantRequestStatus(r.URL.Query().Get("status"))
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	requests, err := service.ListGrantRequests(r.Context(), h.grantApproval.store, status)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, ListGrantRequestsResponse{GrantRequests: requests})
+}
+
+func (h *AuthZHandler) handleGetGrantRequest(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.grantRequestID(w, r)
+	if !ok {
+		return
+	}
+
+	req, err := service.GetGrantRequest(r.Context(), h.grantApproval.store, id)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, GrantRequestResponse{GrantRequest: req})
+}
+
+// handleApproveGrantRequest serves POST /grant-requests/{id}/approve,
+// assigning the requested role. The caller must hold the approval
+// permission and must not be the requester.
+func (h *AuthZHandler) handleApproveGrantRequest(w http.ResponseWriter, r *http.Request) {
+	id, note, approver, ok := h.decideGrantRequest(w, r, ActionGrantRequestApproved)
+	if !ok {
+		return
+	}
+
+	ctx, _, ok := h.adminContext(w, r, ActionGrantRequestApproved, id.String(), approver)
+	if !ok {
+		return
+	}
+
+	req, grant, err := service.ApproveGrantRequest(ctx, h.tx, h.grantApproval.store, h.grantStore, id, approver, note)
+	h.emit(r, ActionGrantRequestApproved, id.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.emit(r, ActionRoleAssigned, req.Username, nil)
+
+	httpx.WriteJSON(w, http.StatusOK, GrantRequestResponse{GrantRequest: req, Grant: grant})
+}
+
+// handleRejectGrantRequest serves POST /grant-requests/{id}/reject. The
+// caller must hold the approval permission and must not be the requester.
+func (h *AuthZHandler) handleRejectGrantRequest(w http.ResponseWriter, r *http.Request) {
+	id, note, approver, ok := h.decideGrantRequest(w, r, ActionGrantRequestRejected)
+	if !ok {
+		return
+	}
+
+	req, err := service.RejectGrantRequest(r.Context(), h.grantApproval.store, id, approver, note)
+	h.emit(r, ActionGrantRequestRejected, id.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, GrantRequestResponse{GrantRequest: req})
+}
+
+// decideGrantRequest reads the request ID and note of a decision and checks
+// that the caller holds the approval permission. On failure it writes the
+// error, emitting action when the caller was denied, and returns false.
+func (h *AuthZHandler) decideGrantRequest(w http.ResponseWriter, r *http.Request, action string) (auth.GrantRequestID, string, string, bool) {
+	approver, ok := h.principal(w, r)
+	if !ok {
+		return auth.GrantRequestID{}, "", "", false
+	}
+
+	id, ok := h.grantRequestID(w, r)
+	if !ok {
+		return id, "", "", false
+	}
+
+	var req DecideGrantRequestRequest
+	if r.ContentLength != 0 && !h.bind(w, r, &req) {
+		return id, "", "", false
+	}
+
+	allowed, err := h.checkPermission(r.Context(), approver, h.grantApproval.permission)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return id, "", "", false
+	}
+	if !allowed {
+		h.emit(r, action, id.String(), auth.ErrPermissionDenied)
+		h.handleServiceError(w, r, auth.ErrPermissionDenied)
+		return id, "", "", false
+	}
+	return id, req.Note, approver, true
+}
+
+func (h *AuthZHandler) grantRequestID(w http.ResponseWriter, r *http.Request) (auth.GrantRequestID, bool) {
+	id, err := auth.ParseGrantRequestID(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_GRANT_REQUEST_ID", "Invalid grant request ID format")
+		return id, false
+	}
+	return id, true
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/webhook"
+	"github.com/go-chi/chi/v5"
+)
+
+func setupGrantApproval(t *testing.T, publisher *recordingPublisher) (chi.Router, *auth.Role) {
+	t.Helper()
+
+	ctx := context.Background()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+
+	approvers, err := service.CreateRole(ctx, roles, "approvers", "", []string{auth.PermissionApproveGrants}, "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oncall, err := service.CreateRole(ctx, roles, "oncall", "", []string{"incidents:manage"}, "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := grants.Create(ctx, auth.NewGrant("bob", approvers.ID, "system")); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewAuthZHandler(roles, grants,
+		WithGrantApproval(fake.NewGrantRequestStore(), ""),
+		WithTransactor(fake.NewTransactor()),
+		WithHooks(NewWebhookHooks(publisher, nil)))
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	return r, oncall
+}
+
+func postJSONAs(r http.Handler, user, path string, body any) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if user != "" {
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, user))
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGrantApproval(t *testing.T) {
+	publisher := &recordingPublisher{}
+	r, oncall := setupGrantApproval(t, publisher)
+
+	if w := postJSON(t, r, "/grants", AssignRoleRequest{Username: "ann", RoleID: oncall.ID.String()}); w.Code != http.StatusMethodNotAllowed && w.Code != http.StatusNotFound {
+		t.Errorf("POST /grants = %d, want it not served", w.Code)
+	}
+	if w := postJSONAs(r, "", "/grant-requests", CreateGrantRequestRequest{RoleID: oncall.ID.String()}); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous request = %d, want 401", w.Code)
+	}
+
+	w := postJSONAs(r, "ann", "/grant-requests", CreateGrantRequestRequest{RoleID: oncall.ID.String(), Reason: "joining the rotation"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create request = %d: %s", w.Code, w.Body)
+	}
+	var created GrantRequestResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	id := created.GrantRequest.ID.String()
+	if created.GrantRequest.Username != "ann" || !created.GrantRequest.IsPending() {
+		t.Errorf("created request = %+v", created.GrantRequest)
+	}
+	if w := postJSONAs(r, "ann", "/grant-requests", CreateGrantRequestRequest{RoleID: oncall.ID.String()}); w.Code != http.StatusConflict {
+		t.Errorf("duplicate request = %d, want 409", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/grant-requests?status=pending", nil))
+	var list ListGrantRequestsResponse
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list.GrantRequests) != 1 || list.GrantRequests[0].ID.String() != id {
+		t.Errorf("pending requests = %+v", list.GrantRequests)
+	}
+
+	tests := []struct {
+		name string
+		user string
+		want int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"requester without the permission", "ann", http.StatusForbidden},
+		{"other user without the permission", "carol", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := postJSONAs(r, tt.user, "/grant-requests/"+id+"/approve", nil); w.Code != tt.want {
+				t.Errorf("approve = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	w = postJSONAs(r, "bob", "/grant-requests/"+id+"/approve", DecideGrantRequestRequest{Note: "welcome"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("approve = %d: %s", w.Code, w.Body)
+	}
+	var approved GrantRequestResponse
+	json.NewDecoder(w.Body).Decode(&approved)
+	if approved.GrantRequest.Status != auth.GrantRequestApproved || approved.Grant == nil || approved.Grant.AssignedBy != "bob" {
+		t.Errorf("approved = %+v", approved)
+	}
+	if w := postJSONAs(r, "bob", "/grant-requests/"+id+"/reject", nil); w.Code != http.StatusConflict {
+		t.Errorf("reject decided = %d, want 409", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/ann/permissions/incidents:manage", nil))
+	var check PermissionCheckResponse
+	json.NewDecoder(w.Body).Decode(&check)
+	if !check.HasPermission {
+		t.Error("approved request did not grant the role")
+	}
+
+	w = postJSONAs(r, "bob", "/grant-requests", CreateGrantRequestRequest{RoleID: oncall.ID.String()})
+	json.NewDecoder(w.Body).Decode(&created)
+	if w := postJSONAs(r, "bob", "/grant-requests/"+created.GrantRequest.ID.String()+"/approve", nil); w.Code != http.StatusForbidden {
+		t.Errorf("self approval = %d, want 403", w.Code)
+	}
+
+	var types []string
+	for _, e := range publisher.events {
+		types = append(types, e.Type)
+	}
+	want := []string{webhook.EventGrantRequested, webhook.EventGrantApproved, webhook.EventRoleAssigned, webhook.EventGrantRequested}
+	if len(types) != len(want) {
+		t.Fatalf("webhook events = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Errorf("webhook events = %v, want %v", types, want)
+			break
+		}
+	}
+	if publisher.events[1].Subject != id || publisher.events[1].Actor != "bob" {
+		t.Errorf("approved event = %+v", publisher.events[1])
+	}
+}
+
+func TestGrantRequestReject(t *testing.T) {
+	r, oncall := setupGrantApproval(t, &recordingPublisher{})
+
+	w := postJSONAs(r, "ann", "/grant-requests", CreateGrantRequestRequest{Username: "dan", RoleID: oncall.ID.String()})
+	var created GrantRequestResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	id := created.GrantRequest.ID.String()
+
+	w = postJSONAs(r, "bob", "/grant-requests/"+id+"/reject", DecideGrantRequestRequest{Note: "not on the team"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("reject = %d: %s", w.Code, w.Body)
+	}
+	var rejected GrantRequestResponse
+	json.NewDecoder(w.Body).Decode(&rejected)
+	if rejected.GrantRequest.Status != auth.GrantRequestRejected || rejected.Grant != nil {
+		t.Errorf("rejected = %+v", rejected)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/dan/roles", nil))
+	var roles UserRolesResponse
+	json.NewDecoder(w.Body).Decode(&roles)
+	if len(roles.Roles) != 0 {
+		t.Errorf("rejected request granted %+v", roles.Roles)
+	}
+
+	for path, want := range map[string]int{
+		"/grant-requests/" + id:                                http.StatusOK,
+		"/grant-requests/not-an-id":                            http.StatusBadRequest,
+		"/grant-requests?status=expired":                       http.StatusBadRequest,
+		"/grant-requests/" + auth.NewGrantRequestID().String(): http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, w.Code, want)
+		}
+	}
+}
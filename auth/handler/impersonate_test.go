@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// permissionChecker grants the "user permission" pairs in allowed.
+type permissionChecker struct {
+	allowed map[string]bool
+}
+
+func (c permissionChecker) HasRole(ctx context.Context, userID, roleName string) (bool, error) {
+	return false, nil
+}
+
+func (c permissionChecker) CheckPermission(ctx context.Context, userID, permission string) (bool, error) {
+	return c.allowed[userID+" "+permission], nil
+}
+
+func (c permissionChecker) CheckAnyPermission(ctx context.Context, userID string, permissions []string) (bool, error) {
+	return false, nil
+}
+
+func (c permissionChecker) CheckAllPermissions(ctx context.Context, userID string, permissions []string) (bool, error) {
+	return false, nil
+}
+
+func TestHandleImpersonate(t *testing.T) {
+	fixed := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	userStore := fake.NewUserStore()
+	tokenGen := fake.NewTokenGenerator()
+	audit := &recordingAudit{}
+	checker := permissionChecker{allowed: map[string]bool{
+		"support " + auth.PermissionImpersonate: true,
+		"helper " + auth.PermissionImpersonate:  true,
+		"root *":                                true,
+	}}
+
+	h := NewAuthNHandler(userStore, fake.NewCryptoService(), tokenGen, fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithImpersonation(tokenGen, checker, 10*time.Minute), WithAudit(audit), WithClock(func() time.Time { return fixed }))
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	target := auth.NewUser()
+	target.Username = "alice"
+	target.Status = auth.UserStatusActive
+	target.BeforeCreate()
+	userStore.Create(t.Context(), target)
+
+	protected := map[string]*auth.User{}
+	for _, username := range []string{"root", "helper"} {
+		u := auth.NewUser()
+		u.Username = username
+		u.Status = auth.UserStatusActive
+		u.BeforeCreate()
+		userStore.Create(t.Context(), u)
+		protected[username] = u
+	}
+
+	tests := []struct {
+		name          string
+		caller        string
+		impersonating string
+		body          string
+		wantStatus    int
+		wantCode      string
+		wantExpiresAt time.Time
+	}{
+		{
+			name:          "allowed",
+			caller:        "support",
+			body:          `{"user_id": "` + target.ID.String() + `"}`,
+			wantStatus:    http.StatusOK,
+			wantExpiresAt: fixed.Add(10 * time.Minute),
+		},
+		{
+			name:          "shorter TTL",
+			caller:        "support",
+			body:          `{"user_id": "` + target.ID.String() + `", "ttl_seconds": 60}`,
+			wantStatus:    http.StatusOK,
+			wantExpiresAt: fixed.Add(time.Minute),
+		},
+		{
+			name:          "longer TTL is capped",
+			caller:        "support",
+			body:          `{"user_id": "` + target.ID.String() + `", "ttl_seconds": 86400}`,
+			wantStatus:    http.StatusOK,
+			wantExpiresAt: fixed.Add(10 * time.Minute),
+		},
+		{
+			name:       "unauthenticated",
+			body:       `{"user_id": "` + target.ID.String() + `"}`,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "UNAUTHORIZED",
+		},
+		{
+			name:       "missing permission",
+			caller:     "bob",
+			body:       `{"user_id": "` + target.ID.String() + `"}`,
+			wantStatus: http.StatusForbidden,
+			wantCode:   "PERMISSION_DENIED",
+		},
+		{
+			name:          "nested impersonation",
+			caller:        "support",
+			impersonating: "root",
+			body:          `{"user_id": "` + target.ID.String() + `"}`,
+			wantStatus:    http.StatusForbidden,
+			wantCode:      "IMPERSONATION_NOT_ALLOWED",
+		},
+		{
+			name:       "superadmin target",
+			caller:     "support",
+			body:       `{"user_id": "` + protected["root"].ID.String() + `"}`,
+			wantStatus: http.StatusForbidden,
+			wantCode:   "IMPERSONATION_NOT_ALLOWED",
+		},
+		{
+			name:       "impersonator target",
+			caller:     "support",
+			body:       `{"user_id": "` + protected["helper"].ID.String() + `"}`,
+			wantStatus: http.StatusForbidden,
+			wantCode:   "IMPERSONATION_NOT_ALLOWED",
+		},
+		{
+			name:       "unknown user",
+			caller:     "support",
+			body:       `{"user_id": "` + auth.NewUserID().String() + `"}`,
+			wantStatus: http.StatusNotFound,
+			wantCode:   "USER_NOT_FOUND",
+		},
+		{
+			name:       "missing user",
+			caller:     "support",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_FAILED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/auth/impersonate", strings.NewReader(tt.body))
+			ctx := req.Context()
+			if tt.caller != "" {
+				ctx = context.WithValue(ctx, middleware.UserIDKey, tt.caller)
+			}
+			if tt.impersonating != "" {
+				ctx = context.WithValue(ctx, middleware.ImpersonatorKey, tt.impersonating)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("handleImpersonate() status = %v, want %v (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantCode != "" {
+				var resp ErrorResponse
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp.Code != tt.wantCode {
+					t.Errorf("handleImpersonate() code = %v, want %v", resp.Code, tt.wantCode)
+				}
+				return
+			}
+
+			var resp ImpersonateResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.User.ID != target.ID {
+				t.Errorf("handleImpersonate() user = %v, want %v", resp.User.ID, target.ID)
+			}
+			if resp.Token != "token-support-as-"+target.ID.String() {
+				t.Errorf("handleImpersonate() token = %q", resp.Token)
+			}
+			if !resp.ExpiresAt.Equal(tt.wantExpiresAt) {
+				t.Errorf("handleImpersonate() expires_at = %v, want %v", resp.ExpiresAt, tt.wantExpiresAt)
+			}
+		})
+	}
+
+	var succeeded, failed int
+	for _, e := range audit.events {
+		if e.Action != ActionImpersonate {
+			t.Errorf("unexpected audit action %q", e.Action)
+		}
+		if e.Err == nil {
+			succeeded++
+		} else {
+			failed++
+		}
+	}
+	if succeeded != 3 || failed != 5 {
+		t.Errorf("audited %d successes and %d failures, want 3 and 5", succeeded, failed)
+	}
+}
+
+func TestEmitRecordsImpersonator(t *testing.T) {
+	audit := &recordingAudit{}
+	o := newOptions([]Option{WithAudit(audit)})
+
+	req := httptest.NewRequest(http.MethodPut, "/users/alice", nil)
+	ctx := context.WithValue(req.Context(), middleware.UserIDKey, "alice")
+	ctx = context.WithValue(ctx, middleware.ImpersonatorKey, "support")
+	o.emit(req.WithContext(ctx), ActionUserUpdated, "alice", nil)
+
+	if len(audit.events) != 1 || audit.events[0].Impersonator != "support" {
+		t.Errorf("events = %+v, want one event impersonated by support", audit.events)
+	}
+}
+
+func TestImpersonateRouteRequiresOption(t *testing.T) {
+	r := chi.NewRouter()
+	setupAuthNHandler().RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/impersonate", strings.NewReader(`{}`)))
+	if w.Code != http.StatusNotFound && w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /auth/impersonate without WithImpersonation = %d, want it unregistered", w.Code)
+	}
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/crypto"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+// DefaultRevocationTTL is how long revocations are kept when WithRevocations
+// is given no TTL and the token generator does not report one.
+const DefaultRevocationTTL = 24 * time.Hour
+
+type revocations struct {
+	store auth.RevocationStore
+	ttl   time.Duration
+}
+
+// LogoutAllResponse counts the sessions ended by POST /users/{id}/logout-all,
+// with WithSessions.
+type LogoutAllResponse struct {
+	SessionsEnded int `json:"sessions_ended"`
+}
+
+// handleLogout serves POST /auth/logout, revoking the session of the token
+// making the request. With WithSessions the session is ended too.
+func (h *AuthNHandler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, sessionID := middleware.GetUserID(ctx), middleware.GetSessionID(ctx)
+	if userID == "" || sessionID == "" {
+		h.writeError(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		return
+	}
+
+	ttl, err := h.revocationTTL(ctx)
+	if err == nil {
+		err = service.RevokeSession(ctx, h.revocations.store, userID, sessionID, ttl)
+	}
+	if err == nil && h.sessions != nil {
+		if err = h.sessions.Delete(ctx, sessionID); errors.Is(err, auth.ErrSessionNotFound) {
+			err = nil
+		}
+	}
+	h.emit(r, ActionLogout, userID, err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleLogoutAll serves POST /users/{id}/logout-all and
+// POST /users/me/logout-all, revoking every token issued to the user so
+// far, the caller's own included. With WithSessions their sessions are
+// ended too.
+func (h *AuthNHandler) handleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userIDParam(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	subject := userID.String()
+
+	err := h.revokeUserTokens(ctx, subject)
+	var resp LogoutAllResponse
+	if err == nil && h.sessions != nil {
+		resp.SessionsEnded, err = service.EndOtherSessions(ctx, h.sessions, subject, "")
+	}
+	h.emit(r, ActionLogoutAll, subject, err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+// revokeUserTokens revokes every token issued to userID so far. It does
+// nothing without WithRevocations.
+func (h *AuthNHandler) revokeUserTokens(ctx context.Context, userID string) error {
+	if h.revocations == nil {
+		return nil
+	}
+	ttl, err := h.revocationTTL(ctx)
+	if err != nil {
+		return err
+	}
+	return service.RevokeUserTokens(ctx, h.revocations.store, userID, ttl)
+}
+
+// revocationTTL is how long revocations are kept: the configured token
+// lifetime, or the session TTL of the settings when longer.
+func (h *AuthNHandler) revocationTTL(ctx context.Context) (time.Duration, error) {
+	settings, err := h.currentSettings(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ttl := h.revocations.ttl
+	if ttl <= 0 {
+		ttl = DefaultRevocationTTL
+		if gen, ok := h.tokenGen.(interface{ TTL() time.Duration }); ok && gen.TTL() > 0 {
+			ttl = gen.TTL()
+		}
+	}
+	return max(ttl, settings.SessionTTL(0)), nil
+}
+
+// tokenRevoked reports whether the token with claims was revoked, always
+// false without WithRevocations.
+func (h *AuthNHandler) tokenRevoked(ctx context.Context, claims crypto.TokenClaims) (bool, error) {
+	if h.revocations == nil {
+		return false, nil
+	}
+	return service.NewRevocationCheck(h.revocations.store).Revoked(ctx, claims)
+}
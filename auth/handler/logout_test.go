@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestLogout(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	validator := middleware.NewTokenValidator(pub)
+	audit := &recordingAudit{}
+	sessions := fake.NewSessionStore()
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), service.NewDefaultTokenGenerator(priv, time.Hour), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithTokenValidator(validator), WithRevocations(fake.NewRevocationStore(), time.Hour), WithSessions(sessions), WithAudit(audit),
+	).RegisterRoutes(r)
+
+	postJSON(t, r, "/auth/signup", SignUpRequest{Email: "ann@example.com", Password: "Password123!", Username: "ann", DisplayName: "Ann"})
+	signIn := func() string {
+		w := postJSON(t, r, "/auth/signin", SignInRequest{Email: "ann@example.com", Password: "Password123!"})
+		var resp SignInResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp.Token
+	}
+	verify := func(token string) string {
+		w := postJSON(t, r, "/auth/verify", VerifyTokenRequest{Token: token})
+		if w.Code == http.StatusOK {
+			return ""
+		}
+		var resp ErrorResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp.Code
+	}
+	postWith := func(token, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if token != "" {
+			userID, sessionID, _ := validator.ValidateToken(token)
+			ctx := context.WithValue(req.Context(), middleware.UserIDKey, userID)
+			req = req.WithContext(context.WithValue(ctx, middleware.SessionIDKey, sessionID))
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first, second := signIn(), signIn()
+	if code := verify(first); code != "" {
+		t.Fatalf("verify before logout = %s", code)
+	}
+	if w := postWith("", "/auth/logout"); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous logout = %d, want 401", w.Code)
+	}
+
+	userID, sessionID, _ := validator.ValidateToken(first)
+	sessions.Create(t.Context(), &auth.Session{ID: sessionID, UserID: userID, ExpiresAt: time.Now().Add(time.Hour)})
+	if w := postWith(first, "/auth/logout"); w.Code != http.StatusNoContent {
+		t.Fatalf("logout = %d: %s", w.Code, w.Body)
+	}
+	if code := verify(first); code != "TOKEN_REVOKED" {
+		t.Errorf("verify after logout = %q, want TOKEN_REVOKED", code)
+	}
+	if code := verify(second); code != "" {
+		t.Errorf("verify of another session after logout = %s", code)
+	}
+	if _, err := sessions.Get(t.Context(), sessionID); err != auth.ErrSessionNotFound {
+		t.Errorf("session after logout error = %v, want ErrSessionNotFound", err)
+	}
+
+	sessions.Create(t.Context(), auth.NewSession(userID, time.Hour))
+	w := postWith(second, "/users/me/logout-all")
+	var resp LogoutAllResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || resp.SessionsEnded != 1 {
+		t.Fatalf("logout-all = %d, %+v", w.Code, resp)
+	}
+	if code := verify(second); code != "TOKEN_REVOKED" {
+		t.Errorf("verify after logout-all = %q, want TOKEN_REVOKED", code)
+	}
+	if w := postWith(second, "/users/not-an-id/logout-all"); w.Code != http.StatusBadRequest {
+		t.Errorf("logout-all with bad id = %d, want 400", w.Code)
+	}
+
+	var logouts, logoutAlls int
+	for _, e := range audit.events {
+		switch {
+		case e.Action == ActionLogout && e.Err == nil:
+			logouts++
+		case e.Action == ActionLogoutAll && e.Err == nil && e.Subject == userID:
+			logoutAlls++
+		}
+	}
+	if logouts != 1 || logoutAlls != 1 {
+		t.Errorf("logout events = %d, %d, want 1, 1", logouts, logoutAlls)
+	}
+}
+
+func TestLogoutRoutesRequireRevocations(t *testing.T) {
+	r := chi.NewRouter()
+	setupAuthNHandler().RegisterRoutes(r)
+
+	if w := postJSONAs(r, auth.NewUserID().String(), "/auth/logout", nil); w.Code != http.StatusNotFound && w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /auth/logout without WithRevocations = %d, want it unregistered", w.Code)
+	}
+}
+
+func TestRevocationTTLDefaults(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	newHandler := func(tokens service.TokenGenerator, ttl time.Duration) *AuthNHandler {
+		return NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), tokens, fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+			WithRevocations(fake.NewRevocationStore(), ttl))
+	}
+
+	tests := []struct {
+		name string
+		h    *AuthNHandler
+		want time.Duration
+	}{
+		{"configured", newHandler(service.NewDefaultTokenGenerator(priv, time.Hour), 3*time.Hour), 3 * time.Hour},
+		{"zero uses the generator TTL", newHandler(service.NewDefaultTokenGenerator(priv, 2*time.Hour), 0), 2 * time.Hour},
+		{"zero without generator TTL", newHandler(fake.NewTokenGenerator(), 0), DefaultRevocationTTL},
+	}
+	for _, tt := range tests {
+		got, err := tt.h.revocationTTL(t.Context())
+		if err != nil || got != tt.want {
+			t.Errorf("%s: revocationTTL() = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestLogoutWithZeroRevocationTTL(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	validator := middleware.NewTokenValidator(pub)
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), service.NewDefaultTokenGenerator(priv, time.Hour), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithTokenValidator(validator), WithRevocations(fake.NewRevocationStore(), 0),
+	).RegisterRoutes(r)
+
+	postJSON(t, r, "/auth/signup", SignUpRequest{Email: "zed@example.com", Password: "Password123!", Username: "zed", DisplayName: "Zed"})
+	w := postJSON(t, r, "/auth/signin", SignInRequest{Email: "zed@example.com", Password: "Password123!"})
+	var signIn SignInResponse
+	json.NewDecoder(w.Body).Decode(&signIn)
+
+	userID, sessionID, _ := validator.ValidateToken(signIn.Token)
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	ctx := context.WithValue(req.Context(), middleware.UserIDKey, userID)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req.WithContext(context.WithValue(ctx, middleware.SessionIDKey, sessionID)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("logout = %d: %s", w.Code, w.Body)
+	}
+
+	w = postJSON(t, r, "/auth/verify", VerifyTokenRequest{Token: signIn.Token})
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Code != "TOKEN_REVOKED" {
+		t.Errorf("verify after logout with zero TTL = %d %q, want TOKEN_REVOKED", w.Code, resp.Code)
+	}
+}
+
+func TestSignInRightAfterLogoutAll(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	validator := middleware.NewTokenValidator(pub)
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), service.NewDefaultTokenGenerator(priv, time.Hour), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithTokenValidator(validator), WithRevocations(fake.NewRevocationStore(), time.Hour),
+	).RegisterRoutes(r)
+
+	postJSON(t, r, "/auth/signup", SignUpRequest{Email: "amy@example.com", Password: "Password123!", Username: "amy", DisplayName: "Amy"})
+	signIn := func() string {
+		w := postJSON(t, r, "/auth/signin", SignInRequest{Email: "amy@example.com", Password: "Password123!"})
+		var resp SignInResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp.Token
+	}
+	verify := func(token string) string {
+		w := postJSON(t, r, "/auth/verify", VerifyTokenRequest{Token: token})
+		var resp ErrorResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp.Code
+	}
+
+	old := signIn()
+	userID, _, _ := validator.ValidateToken(old)
+	if w := postJSONAs(r, userID, "/users/me/logout-all", nil); w.Code != http.StatusOK {
+		t.Fatalf("logout-all = %d: %s", w.Code, w.Body)
+	}
+
+	fresh := signIn()
+	if code := verify(fresh); code != "" {
+		t.Errorf("verify of a token issued right after logout-all = %q, want it valid", code)
+	}
+}
+
+func TestAccountChangesRevokeTokens(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	validator := middleware.NewTokenValidator(pub)
+	revocations := fake.NewRevocationStore()
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), service.NewDefaultTokenGenerator(priv, time.Hour), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithTokenValidator(validator), WithRevocations(revocations, time.Hour),
+	).RegisterRoutes(r)
+
+	signUpAndIn := func(username string) (string, string) {
+		email := username + "@example.com"
+		postJSON(t, r, "/auth/signup", SignUpRequest{Email: email, Password: "Password123!", Username: username, DisplayName: username})
+		w := postJSON(t, r, "/auth/signin", SignInRequest{Email: email, Password: "Password123!"})
+		var resp SignInResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		userID, _, _ := validator.ValidateToken(resp.Token)
+		return userID, resp.Token
+	}
+	revoked := func(token string) bool {
+		claims, err := validator.ValidateClaims(token)
+		if err != nil {
+			t.Fatalf("ValidateClaims() error = %v", err)
+		}
+		got, err := service.NewRevocationCheck(revocations).Revoked(t.Context(), claims)
+		if err != nil {
+			t.Fatalf("Revoked() error = %v", err)
+		}
+		return got
+	}
+
+	suspended, suspendedToken := signUpAndIn("sus")
+	if w := postJSONAs(r, "admin", "/users/"+suspended+"/suspend", SuspendUserRequest{Reason: "abuse"}); w.Code != http.StatusOK {
+		t.Fatalf("suspend = %d: %s", w.Code, w.Body)
+	}
+	if !revoked(suspendedToken) {
+		t.Error("token of a suspended user is not revoked")
+	}
+
+	reset, resetToken := signUpAndIn("res")
+	if w := postJSONAs(r, "admin", "/users/"+reset+"/password", ResetPasswordRequest{}); w.Code != http.StatusOK {
+		t.Fatalf("reset password = %d: %s", w.Code, w.Body)
+	}
+	if !revoked(resetToken) {
+		t.Error("token of a user whose password was reset is not revoked")
+	}
+
+	changed, changedToken := signUpAndIn("chg")
+	w := postJSONAs(r, changed, "/users/me/password", ChangePasswordRequest{CurrentPassword: "Password123!", NewPassword: "NewPassword456!"})
+	var resp ChangePasswordResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || resp.Token == "" {
+		t.Fatalf("change password = %d, %+v", w.Code, resp)
+	}
+	if !revoked(changedToken) {
+		t.Error("token of a user who changed their password is not revoked")
+	}
+	if revoked(resp.Token) {
+		t.Error("token returned by change password is revoked")
+	}
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/mail"
+)
+
+// Per-email limit on magic link requests when WithMagicLinks is given no
+// limiter.
+const (
+	DefaultMagicLinkLimit  = 3
+	DefaultMagicLinkWindow = time.Hour
+)
+
+type magicLinks struct {
+	links   *service.MagicLinks
+	url     string
+	limiter RateLimiter
+}
+
+// link returns the URL emailed to the user, carrying token in the token
+// query parameter.
+func (m *magicLinks) link(token string) string {
+	return tokenLink(m.url, token)
+}
+
+// tokenLink returns base with token added in the token query parameter.
+func tokenLink(base, token string) string {
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + "token=" + url.QueryEscape(token)
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// handleRequestMagicLink serves POST /auth/magic-link. It always answers 202
+// Accepted once the request is within the per-email limit, whether or not
+// the email belongs to an active user, so the endpoint cannot be used to
+// discover accounts.
+func (h *AuthNHandler) handleRequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var req MagicLinkRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	email := auth.NormalizeEmail(req.Email)
+	key := "magic-link:" + hex.EncodeToString(h.crypto.ComputeLookupHash(email))
+	if allowed, retryAfter := h.magicLinks.limiter.Allow(key); !allowed {
+		h.writeRetryError(w, r, http.StatusTooManyRequests, "RATE_LIMITED", "Too many sign-in links requested", retryAfter)
+		return
+	}
+
+	user, token, err := service.RequestMagicLink(r.Context(), h.userStore, h.crypto, h.magicLinks.links, email)
+	if err != nil {
+		h.emit(r, ActionMagicLinkRequested, "", err)
+		if !errors.Is(err, auth.ErrUserNotFound) && !errors.Is(err, auth.ErrInactiveAccount) {
+			h.handleServiceError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	h.emit(r, ActionMagicLinkRequested, user.ID.String(), nil)
+	h.sendMail(r, user, mail.TemplateMagicLink, mailData{
+		Link:      h.magicLinks.link(token),
+		ExpiresIn: expiresIn(h.magicLinks.links.TTL()),
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleMagicLinkSignIn serves GET /auth/magic-link/verify?token=...,
+// exchanging a magic link token for a session token. Each token works once.
+// Sign-ins WithRiskEvaluator asks a second factor for get an MFA-pending
+// token instead.
+func (h *AuthNHandler) handleMagicLinkSignIn(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "Token is required")
+		return
+	}
+
+	settings, err := h.currentSettings(r.Context())
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	user, sessionToken, err := service.SignInWithMagicLink(r.Context(), h.userStore, h.sessionTokens(settings), h.magicLinks.links, token)
+	if err != nil {
+		h.emit(r, ActionMagicLinkSignIn, "", err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.emit(r, ActionMagicLinkSignIn, user.ID.String(), nil)
+
+	resp := SignInResponse{User: user, Token: sessionToken}
+	stepUp, err := h.assessSignIn(r, user, "", false)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	if stepUp {
+		if resp.Token, err = h.mfaPendingToken(user.ID); err != nil {
+			h.handleServiceError(w, r, err)
+			return
+		}
+		resp.MFARequired = true
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+// expiresIn formats a link lifetime for the email, such as "15 minutes".
+func expiresIn(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d >= day && d%day == 0 {
+		if d == day {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", d/day)
+	}
+	if d >= time.Hour && d%time.Hour == 0 {
+		if d == time.Hour {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	}
+	minutes := max(int(d.Round(time.Minute)/time.Minute), 1)
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/mail"
+	mailfake "github.com/aquamarinepk/aqm/mail/fake"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+var magicLinkPattern = regexp.MustCompile(`https://app\.example\.com/magic\?[^\s]+`)
+
+func setupMagicLinkRouter(t *testing.T, sender *mailfake.Sender, audit *recordingAudit, opts ...Option) chi.Router {
+	t.Helper()
+	links := service.NewMagicLinks([]byte("magic-link-key"), fake.NewNonceStore(), 10*time.Minute)
+	h := NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		append([]Option{
+			WithMailer(mail.NewMailer(sender, nil, "noreply@example.com")),
+			WithMagicLinks(links, "https://app.example.com/magic", middleware.NewRateLimiter(2, time.Hour)),
+			WithAudit(audit),
+		}, opts...)...,
+	)
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	signUp := SignUpRequest{Email: "magic@example.com", Password: "Password123!", Username: "magicuser", DisplayName: "Magic User"}
+	if w := postJSON(t, r, "/auth/signup", signUp); w.Code != http.StatusCreated {
+		t.Fatalf("signup status = %d, body: %s", w.Code, w.Body.String())
+	}
+	return r
+}
+
+func verifyMagicLink(r http.Handler, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/magic-link/verify?token="+url.QueryEscape(token), nil))
+	return w
+}
+
+func TestMagicLinkSignIn(t *testing.T) {
+	sender := mailfake.NewSender()
+	audit := &recordingAudit{}
+	r := setupMagicLinkRouter(t, sender, audit)
+
+	if w := postJSON(t, r, "/auth/magic-link", MagicLinkRequest{Email: "Magic@Example.com"}); w.Code != http.StatusAccepted {
+		t.Fatalf("magic-link status = %d, body: %s", w.Code, w.Body.String())
+	}
+
+	msgs := sender.Messages()
+	if len(msgs) != 2 {
+		t.Fatalf("sent %d messages, want welcome and magic link", len(msgs))
+	}
+	msg := msgs[1]
+	if msg.To[0] != "magic@example.com" || msg.Subject != "Your sign-in link" {
+		t.Errorf("magic link message = %v %q", msg.To, msg.Subject)
+	}
+	link := magicLinkPattern.FindString(msg.Text)
+	if link == "" {
+		t.Fatalf("magic link text = %q, want a link", msg.Text)
+	}
+	u, _ := url.Parse(link)
+	token := u.Query().Get("token")
+
+	w := verifyMagicLink(r, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("verify status = %d, body: %s", w.Code, w.Body.String())
+	}
+	var resp SignInResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Token == "" || resp.User == nil || resp.User.Username != "magicuser" {
+		t.Errorf("verify response = %+v", resp)
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", w.Header().Get("Cache-Control"))
+	}
+
+	w = verifyMagicLink(r, token)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed verify status = %d, want 401", w.Code)
+	}
+	var errResp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if errResp.Code != "INVALID_MAGIC_LINK" {
+		t.Errorf("replayed verify code = %s, want INVALID_MAGIC_LINK", errResp.Code)
+	}
+
+	var actions []string
+	for _, e := range audit.events {
+		actions = append(actions, e.Action)
+	}
+	want := []string{ActionSignUp, ActionMailSent, ActionMagicLinkRequested, ActionMailSent, ActionMagicLinkSignIn, ActionMagicLinkSignIn}
+	if len(actions) != len(want) {
+		t.Fatalf("audited %v, want %v", actions, want)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("audited %v, want %v", actions, want)
+			break
+		}
+	}
+	if audit.events[5].Err == nil {
+		t.Error("replayed sign-in was audited as a success")
+	}
+}
+
+func TestMagicLinkRequestErrors(t *testing.T) {
+	sender := mailfake.NewSender()
+	r := setupMagicLinkRouter(t, sender, &recordingAudit{})
+
+	if w := postJSON(t, r, "/auth/magic-link", MagicLinkRequest{Email: "nobody@example.com"}); w.Code != http.StatusAccepted {
+		t.Errorf("unknown email status = %d, want 202", w.Code)
+	}
+	if len(sender.Messages()) != 1 {
+		t.Errorf("sent %d messages, want no link for an unknown email", len(sender.Messages()))
+	}
+
+	if w := postJSON(t, r, "/auth/magic-link", MagicLinkRequest{Email: "not-an-email"}); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid email status = %d, want 400", w.Code)
+	}
+
+	for i := range 2 {
+		if w := postJSON(t, r, "/auth/magic-link", MagicLinkRequest{Email: "magic@example.com"}); w.Code != http.StatusAccepted {
+			t.Fatalf("request %d status = %d, want 202", i+1, w.Code)
+		}
+	}
+	w := postJSON(t, r, "/auth/magic-link", MagicLinkRequest{Email: "MAGIC@example.com"})
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("over limit status = %d, Retry-After %q, want 429 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := postJSON(t, r, "/auth/magic-link", MagicLinkRequest{Email: "other@example.com"}); w.Code != http.StatusAccepted {
+		t.Errorf("other email status = %d, want its own limit", w.Code)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "missing token", token: "", wantStatus: http.StatusBadRequest},
+		{name: "forged token", token: "eyJ1IjoiMSJ9.c2ln", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := verifyMagicLink(r, tt.token); w.Code != tt.wantStatus {
+				t.Errorf("verify status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestMagicLinkRoutesNeedMailer(t *testing.T) {
+	links := service.NewMagicLinks([]byte("magic-link-key"), fake.NewNonceStore(), 0)
+	h := NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithMagicLinks(links, "https://app.example.com/magic", nil))
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	if w := postJSON(t, r, "/auth/magic-link", MagicLinkRequest{Email: "magic@example.com"}); w.Code != http.StatusNotFound && w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("magic-link without mailer status = %d, want the route unregistered", w.Code)
+	}
+}
+
+func TestMagicLinkURL(t *testing.T) {
+	tests := []struct {
+		base string
+		want string
+	}{
+		{base: "https://app.example.com/magic", want: "https://app.example.com/magic?token=a%2Bb"},
+		{base: "https://app.example.com/magic?next=%2Fhome", want: "https://app.example.com/magic?next=%2Fhome&token=a%2Bb"},
+	}
+	for _, tt := range tests {
+		if got := (&magicLinks{url: tt.base}).link("a+b"); got != tt.want {
+			t.Errorf("link(%q) = %q, want %q", tt.base, got, tt.want)
+		}
+	}
+
+	for d, want := range map[time.Duration]string{
+		15 * time.Minute: "15 minutes",
+		time.Minute:      "1 minute",
+		time.Hour:        "1 hour",
+		2 * time.Hour:    "2 hours",
+		90 * time.Minute: "90 minutes",
+		24 * time.Hour:   "1 day",
+		168 * time.Hour:  "7 days",
+		36 * time.Hour:   "36 hours",
+	} {
+		if got := expiresIn(d); got != want {
+			t.Errorf("expiresIn(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+// PrincipalResolver maps the subject of a token to the name its grants are
+// held under, such as a user ID to a username.
+type PrincipalResolver func(ctx context.Context, subject string) (string, error)
+
+// UserPrincipals resolves user IDs to usernames with users. Other subjects,
+// such as service account principals, are returned unchanged.
+func UserPrincipals(users auth.UserStore) PrincipalResolver {
+	return func(ctx context.Context, subject string) (string, error) {
+		id, err := auth.ParseUserID(subject)
+		if err != nil {
+			return subject, nil
+		}
+		user, err := service.GetUserByID(ctx, users, id)
+		if err != nil {
+			return "", err
+		}
+		return user.Username, nil
+	}
+}
+
+// principal returns the name the caller's grants are held under, resolved
+// with WithPrincipals. On failure it writes the error and returns false.
+func (o *options) principal(w http.ResponseWriter, r *http.Request) (string, bool) {
+	subject := middleware.GetUserID(r.Context())
+	if subject == "" {
+		o.writeError(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		return "", false
+	}
+	if o.principals == nil {
+		return subject, true
+	}
+
+	name, err := o.principals(r.Context(), subject)
+	if err != nil {
+		o.handleServiceError(w, r, err)
+		return "", false
+	}
+	return name, true
+}
+
+// currentUserID returns the ID of the authenticated user. Subjects that are
+// not user IDs, such as service account principals, answer 404. On failure
+// it writes the error and returns false.
+func (h *AuthNHandler) currentUserID(w http.ResponseWriter, r *http.Request) (auth.UserID, bool) {
+	subject := middleware.GetUserID(r.Context())
+	if subject == "" {
+		h.writeError(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		return auth.UserID{}, false
+	}
+
+	id, err := auth.ParseUserID(subject)
+	if err != nil {
+		h.handleServiceError(w, r, auth.ErrUserNotFound)
+		return auth.UserID{}, false
+	}
+	return id, true
+}
+
+// handleGetMe serves GET /users/me, the authenticated user, with the same
+// ETag as GET /users/{id}.
+func (h *AuthNHandler) handleGetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := service.GetUserByID(r.Context(), h.userStore, userID)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, user.Version)
+	httpx.WriteJSON(w, http.StatusOK, UserResponse{User: user})
+}
+
+// handlePatchMe serves PATCH /users/me like PATCH /users/{id}, except that
+// users cannot change their own status.
+func (h *AuthNHandler) handlePatchMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	patch, err := decodeMergePatch(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	if _, ok := patch["status"]; ok {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_PATCH", "status cannot be patched")
+		return
+	}
+
+	h.patchUser(w, r, userID, patch)
+}
+
+type UserPermissionsResponse struct {
+	Permissions []string `json:"permissions"`
+}
+
+// handleGetMyRoles serves GET /users/me/roles, the roles granted to the
+// caller.
+func (h *AuthZHandler) handleGetMyRoles(w http.ResponseWriter, r *http.Request) {
+	username, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	roles, err := service.GetUserRoles(r.Context(), h.grantStore, username)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, UserRolesResponse{Roles: roles})
+}
+
+// handleGetMyPermissions serves GET /users/me/permissions, the permissions
+// the caller's active roles grant.
+func (h *AuthZHandler) handleGetMyPermissions(w http.ResponseWriter, r *http.Request) {
+	username, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	permissions, err := service.GetUserPermissions(r.Context(), h.grantStore, username)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, UserPermissionsResponse{Permissions: permissions})
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func patchAs(r http.Handler, user, path string, body any) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPatch, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", mergePatchContentType)
+	if user != "" {
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, user))
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMe(t *testing.T) {
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator()).RegisterRoutes(r)
+
+	w := postJSON(t, r, "/auth/signup", SignUpRequest{
+		Email:       "me@example.com",
+		Password:    "Password123!",
+		Username:    "meuser",
+		DisplayName: "Me User",
+	})
+	var signup SignUpResponse
+	json.NewDecoder(w.Body).Decode(&signup)
+	id := signup.User.ID.String()
+
+	if w := getAs(r, "", "/users/me"); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous GET = %d, want 401", w.Code)
+	}
+	if w := getAs(r, "sa:billing", "/users/me"); w.Code != http.StatusNotFound {
+		t.Errorf("service account GET = %d, want 404", w.Code)
+	}
+
+	w = getAs(r, id, "/users/me")
+	var got UserResponse
+	json.NewDecoder(w.Body).Decode(&got)
+	if w.Code != http.StatusOK || got.User.Username != "meuser" {
+		t.Fatalf("GET = %d, %+v", w.Code, got.User)
+	}
+	if w.Header().Get("ETag") == "" {
+		t.Error("GET sent no ETag")
+	}
+
+	if w := patchAs(r, id, "/users/me", map[string]any{"status": "suspended"}); w.Code != http.StatusBadRequest {
+		t.Errorf("status patch = %d, want 400", w.Code)
+	}
+
+	w = patchAs(r, id, "/users/me", map[string]any{"name": "Renamed", "attributes": map[string]any{"team": "core"}})
+	json.NewDecoder(w.Body).Decode(&got)
+	if w.Code != http.StatusOK || got.User.Name != "Renamed" || got.User.Attributes["team"] != "core" {
+		t.Errorf("PATCH = %d, %+v", w.Code, got.User)
+	}
+
+	w = getAs(r, "", "/users/"+id)
+	json.NewDecoder(w.Body).Decode(&got)
+	if got.User.Name != "Renamed" {
+		t.Errorf("stored name = %q", got.User.Name)
+	}
+}
+
+func TestMyRolesAndPermissions(t *testing.T) {
+	ctx := context.Background()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+	users := fake.NewUserStore()
+
+	user, err := service.SignUp(ctx, users, fake.NewCryptoService(), "ann@example.com", "Password123!", "ann", "Ann")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer, _ := service.CreateRole(ctx, roles, "writer", "", []string{"posts:write", "posts:read"}, "system")
+	reader, _ := service.CreateRole(ctx, roles, "reader", "", []string{"posts:read"}, "system")
+	service.AssignRole(ctx, grants, "ann", writer.ID, "system")
+	service.AssignRole(ctx, grants, "ann", reader.ID, "system")
+	service.AssignRole(ctx, grants, "sa:billing", reader.ID, "system")
+
+	r := chi.NewRouter()
+	NewAuthZHandler(roles, grants, WithPrincipals(UserPrincipals(users))).RegisterRoutes(r)
+
+	if w := getAs(r, "", "/users/me/roles"); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous roles = %d, want 401", w.Code)
+	}
+
+	w := getAs(r, user.ID.String(), "/users/me/roles")
+	var rolesResp UserRolesResponse
+	json.NewDecoder(w.Body).Decode(&rolesResp)
+	if w.Code != http.StatusOK || len(rolesResp.Roles) != 2 {
+		t.Errorf("roles = %d, %+v", w.Code, rolesResp.Roles)
+	}
+
+	w = getAs(r, user.ID.String(), "/users/me/permissions")
+	var perms UserPermissionsResponse
+	json.NewDecoder(w.Body).Decode(&perms)
+	if want := []string{"posts:read", "posts:write"}; w.Code != http.StatusOK || !slices.Equal(perms.Permissions, want) {
+		t.Errorf("permissions = %d, %v, want %v", w.Code, perms.Permissions, want)
+	}
+
+	w = getAs(r, "sa:billing", "/users/me/permissions")
+	json.NewDecoder(w.Body).Decode(&perms)
+	if !slices.Equal(perms.Permissions, []string{"posts:read"}) {
+		t.Errorf("service account permissions = %v", perms.Permissions)
+	}
+
+	if w := getAs(r, "00000000-0000-0000-0000-000000000001", "/users/me/roles"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown user roles = %d, want 404", w.Code)
+	}
+}
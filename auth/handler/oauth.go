@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/go-chi/chi/v5"
+)
+
+const oauthStateCookie = "aqm_oauth_state"
+
+// OAuthSignInResponse carries the session token, or with MFARequired, when
+// WithRiskEvaluator asks for a second factor, an MFA-pending token.
+type OAuthSignInResponse struct {
+	User        *auth.User `json:"user"`
+	Token       string     `json:"token"`
+	Provisioned bool       `json:"provisioned"`
+	Linked      bool       `json:"linked"`
+	MFARequired bool       `json:"mfa_required,omitempty"`
+}
+
+// handleOAuthStart serves GET /auth/oauth/{provider}/start. It stores the
+// sign-in state in a cookie scoped to the provider's routes and redirects
+// to the provider's consent page.
+func (h *AuthNHandler) handleOAuthStart(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+	provider, err := h.oauth.Provider(name)
+	if err != nil {
+		h.writeError(w, r, http.StatusNotFound, "UNKNOWN_PROVIDER", "Unknown identity provider")
+		return
+	}
+
+	state, err := h.oauth.NewState(name)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+	sealed, err := h.oauth.Seal(state)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state.Value, state.CodeChallenge())
+	if err != nil {
+		h.writeError(w, r, http.StatusBadGateway, "OAUTH_PROVIDER_ERROR", "Identity provider unavailable")
+		return
+	}
+
+	http.SetCookie(w, oauthCookie(r, name, sealed, state.ExpiresAt))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// handleOAuthCallback serves GET /auth/oauth/{provider}/callback. It checks
+// the state, exchanges the code and signs in, links or provisions the user.
+func (h *AuthNHandler) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+	provider, err := h.oauth.Provider(name)
+	if err != nil {
+		h.writeError(w, r, http.StatusNotFound, "UNKNOWN_PROVIDER", "Unknown identity provider")
+		return
+	}
+
+	q := r.URL.Query()
+	if reason := q.Get("error"); reason != "" {
+		h.emit(r, ActionOAuthSignIn, name, errors.New(reason))
+		h.writeError(w, r, http.StatusUnauthorized, "OAUTH_DENIED", "Sign-in was not completed: "+reason)
+		return
+	}
+
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_OAUTH_STATE", "Missing sign-in state")
+		return
+	}
+	http.SetCookie(w, oauthCookie(r, name, "", time.Unix(0, 0)))
+
+	state, err := h.oauth.Open(cookie.Value, name, q.Get("state"))
+	if err != nil {
+		h.emit(r, ActionOAuthSignIn, name, err)
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_OAUTH_STATE", "Invalid or expired sign-in state")
+		return
+	}
+
+	code := q.Get("code")
+	if code == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "Authorization code is required")
+		return
+	}
+
+	ctx := r.Context()
+	token, err := provider.Exchange(ctx, code, state.Verifier)
+	if err != nil {
+		h.emit(r, ActionOAuthSignIn, name, err)
+		h.writeError(w, r, http.StatusBadGateway, "OAUTH_PROVIDER_ERROR", "Cannot complete sign-in with identity provider")
+		return
+	}
+	profile, err := provider.Profile(ctx, token)
+	if err != nil {
+		h.emit(r, ActionOAuthSignIn, name, err)
+		h.writeError(w, r, http.StatusBadGateway, "OAUTH_PROVIDER_ERROR", "Cannot fetch profile from identity provider")
+		return
+	}
+
+	settings, err := h.currentSettings(ctx)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	result, err := service.SignInWithIdentity(ctx, h.userStore, h.identities, h.crypto, h.sessionTokens(settings), h.pwdGen, settings.EmailDomainsOr(h.emailDomains), profile)
+	if err != nil {
+		h.emit(r, ActionOAuthSignIn, name, err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	subject := result.User.ID.String()
+	switch {
+	case result.Provisioned:
+		h.emit(r, ActionOAuthSignUp, subject, nil)
+	case result.Linked:
+		h.emit(r, ActionIdentityLinked, subject, nil)
+	}
+	h.emit(r, ActionOAuthSignIn, subject, nil)
+
+	resp := OAuthSignInResponse{
+		User:        result.User,
+		Token:       result.Token,
+		Provisioned: result.Provisioned,
+		Linked:      result.Linked,
+	}
+	stepUp, err := h.assessSignIn(r, result.User, "", false)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	if stepUp {
+		if resp.Token, err = h.mfaPendingToken(result.User.ID); err != nil {
+			h.handleServiceError(w, r, err)
+			return
+		}
+		resp.MFARequired = true
+	}
+	httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+// oauthCookie builds the state cookie. It is sent back on the provider's
+// top-level redirect, so it must be SameSite=Lax rather than Strict.
+func oauthCookie(r *http.Request, provider, value string, expires time.Time) *http.Cookie {
+	c := &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    value,
+		Path:     "/auth/oauth/" + provider,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+	if value == "" {
+		c.MaxAge = -1
+	}
+	return c
+}
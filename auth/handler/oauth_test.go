@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/oauth"
+	"github.com/go-chi/chi/v5"
+)
+
+// stubProvider is an oauth.Provider that accepts the code "good-code" and
+// checks the PKCE verifier against the challenge it was given at start.
+type stubProvider struct {
+	challenge string
+	profile   auth.ExternalProfile
+}
+
+func (p *stubProvider) Name() string {
+	return "stub"
+}
+
+func (p *stubProvider) AuthCodeURL(ctx context.Context, state, codeChallenge string) (string, error) {
+	p.challenge = codeChallenge
+	return "https://idp.example.com/authorize?state=" + url.QueryEscape(state), nil
+}
+
+func (p *stubProvider) Exchange(ctx context.Context, code, codeVerifier string) (*oauth.Token, error) {
+	s := oauth.State{Verifier: codeVerifier}
+	if code != "good-code" || s.CodeChallenge() != p.challenge {
+		return nil, oauth.ErrProvider
+	}
+	return &oauth.Token{AccessToken: "at"}, nil
+}
+
+func (p *stubProvider) Profile(ctx context.Context, token *oauth.Token) (*auth.ExternalProfile, error) {
+	profile := p.profile
+	profile.Provider = p.Name()
+	return &profile, nil
+}
+
+func setupOAuthRouter(t *testing.T, audit *recordingAudit, opts ...Option) (chi.Router, *stubProvider, *fake.IdentityStore) {
+	t.Helper()
+
+	provider := &stubProvider{profile: auth.ExternalProfile{
+		Subject:       "s-1",
+		Email:         "oauth@example.com",
+		EmailVerified: true,
+		Name:          "OAuth User",
+		Username:      "oauthuser",
+	}}
+	identities := fake.NewIdentityStore()
+
+	h := NewAuthNHandler(
+		fake.NewUserStore(),
+		fake.NewCryptoService(),
+		fake.NewTokenGenerator(),
+		fake.NewPasswordGenerator(),
+		fake.NewPINGenerator(),
+		append([]Option{
+			WithOAuth(oauth.NewRegistry([]byte("secret"), provider), identities),
+			WithAudit(audit),
+		}, opts...)...,
+	)
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	return r, provider, identities
+}
+
+func startOAuth(t *testing.T, r http.Handler) (state string, cookie *http.Cookie) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oauth/stub/start", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("start status = %d, body: %s", w.Code, w.Body.String())
+	}
+
+	loc, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid Location: %v", err)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == oauthStateCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		t.Fatal("start did not set the state cookie")
+	}
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.Path != "/auth/oauth/stub" {
+		t.Errorf("state cookie = %+v", cookie)
+	}
+	return loc.Query().Get("state"), cookie
+}
+
+func callbackOAuth(r http.Handler, query string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/auth/oauth/stub/callback?"+query, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestOAuthSignIn(t *testing.T) {
+	audit := &recordingAudit{}
+	r, _, identities := setupOAuthRouter(t, audit)
+
+	state, cookie := startOAuth(t, r)
+	w := callbackOAuth(r, url.Values{"state": {state}, "code": {"good-code"}}.Encode(), cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback status = %d, body: %s", w.Code, w.Body.String())
+	}
+
+	var resp OAuthSignInResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !resp.Provisioned || resp.Token == "" || resp.User.Username != "oauthuser" {
+		t.Errorf("response = %+v", resp)
+	}
+	if _, err := identities.GetByProviderSubject(context.Background(), "stub", "s-1"); err != nil {
+		t.Errorf("identity not stored: %v", err)
+	}
+
+	var actions []string
+	for _, e := range audit.events {
+		actions = append(actions, e.Action)
+	}
+	if len(actions) != 2 || actions[0] != ActionOAuthSignUp || actions[1] != ActionOAuthSignIn {
+		t.Errorf("audit actions = %v, want [%s %s]", actions, ActionOAuthSignUp, ActionOAuthSignIn)
+	}
+
+	state, cookie = startOAuth(t, r)
+	w = callbackOAuth(r, url.Values{"state": {state}, "code": {"good-code"}}.Encode(), cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("second callback status = %d, body: %s", w.Code, w.Body.String())
+	}
+	resp = OAuthSignInResponse{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Provisioned || resp.Linked {
+		t.Errorf("second sign-in = %+v, want existing identity", resp)
+	}
+}
+
+func TestOAuthCallbackErrors(t *testing.T) {
+	r, _, _ := setupOAuthRouter(t, &recordingAudit{})
+	state, cookie := startOAuth(t, r)
+
+	tests := []struct {
+		name       string
+		query      url.Values
+		cookie     *http.Cookie
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "provider error",
+			query:      url.Values{"error": {"access_denied"}},
+			cookie:     cookie,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "OAUTH_DENIED",
+		},
+		{
+			name:       "missing cookie",
+			query:      url.Values{"state": {state}, "code": {"good-code"}},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "INVALID_OAUTH_STATE",
+		},
+		{
+			name:       "state mismatch",
+			query:      url.Values{"state": {"forged"}, "code": {"good-code"}},
+			cookie:     cookie,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "INVALID_OAUTH_STATE",
+		},
+		{
+			name:       "missing code",
+			query:      url.Values{"state": {state}},
+			cookie:     cookie,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "INVALID_REQUEST",
+		},
+		{
+			name:       "bad code",
+			query:      url.Values{"state": {state}, "code": {"bad-code"}},
+			cookie:     cookie,
+			wantStatus: http.StatusBadGateway,
+			wantCode:   "OAUTH_PROVIDER_ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := callbackOAuth(r, tt.query.Encode(), tt.cookie)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d, body: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var errResp ErrorResponse
+			json.NewDecoder(w.Body).Decode(&errResp)
+			if errResp.Code != tt.wantCode {
+				t.Errorf("error code = %v, want %v", errResp.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestOAuthUnverifiedEmailConflict(t *testing.T) {
+	r, provider, _ := setupOAuthRouter(t, &recordingAudit{})
+
+	w := postJSON(t, r, "/auth/signup", SignUpRequest{Email: "oauth@example.com", Password: "Password123!", Username: "existing", DisplayName: "Existing"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("signup status = %d, body: %s", w.Code, w.Body.String())
+	}
+	provider.profile.EmailVerified = false
+
+	state, cookie := startOAuth(t, r)
+	w = callbackOAuth(r, url.Values{"state": {state}, "code": {"good-code"}}.Encode(), cookie)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d, body: %s", w.Code, http.StatusConflict, w.Body.String())
+	}
+	var errResp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if errResp.Code != "UNVERIFIED_EMAIL" {
+		t.Errorf("error code = %v, want UNVERIFIED_EMAIL", errResp.Code)
+	}
+}
+
+func TestOAuthEmailDomainNotAllowed(t *testing.T) {
+	r, _, identities := setupOAuthRouter(t, &recordingAudit{}, WithEmailDomains(auth.EmailDomains{Deny: []string{"Example.com"}}))
+
+	state, cookie := startOAuth(t, r)
+	w := callbackOAuth(r, url.Values{"state": {state}, "code": {"good-code"}}.Encode(), cookie)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d, body: %s", w.Code, http.StatusForbidden, w.Body.String())
+	}
+	var errResp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if errResp.Code != "EMAIL_DOMAIN_NOT_ALLOWED" {
+		t.Errorf("error code = %v, want EMAIL_DOMAIN_NOT_ALLOWED", errResp.Code)
+	}
+	if _, err := identities.GetByProviderSubject(t.Context(), "stub", "s-1"); err != auth.ErrIdentityNotFound {
+		t.Errorf("identity created for refused domain: %v", err)
+	}
+}
+
+func TestOAuthRoutes(t *testing.T) {
+	r, _, _ := setupOAuthRouter(t, &recordingAudit{})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oauth/unknown/start", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown provider status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	plain := chi.NewRouter()
+	setupAuthNHandler().RegisterRoutes(plain)
+	w = httptest.NewRecorder()
+	plain.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oauth/stub/start", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("without WithOAuth status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,704 @@
+package handler
+
+import (
+	"context"
+	"crypto/ed25519"
+	"net"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/aquamarinepk/aqm/assets"
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/oauth"
+	"github.com/aquamarinepk/aqm/auth/seed"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/geoip"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/mail"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/validation"
+	"github.com/go-chi/chi/v5"
+)
+
+// Event actions emitted by the handlers to hooks and audit recorders.
+const (
+	ActionSignUp          = "auth.signup"
+	ActionSignIn          = "auth.signin"
+	ActionSignInByPIN     = "auth.signin_pin"
+	ActionSignInRisk      = "auth.signin_risk"
+	ActionBootstrap       = "auth.bootstrap"
+	ActionGeneratePIN     = "auth.generate_pin"
+	ActionImpersonate     = "auth.impersonate"
+	ActionTokenExchanged  = "auth.token_exchange"
+	ActionUserUpdated     = "user.updated"
+	ActionUserDeleted     = "user.deleted"
+	ActionUserSuspended   = "user.suspended"
+	ActionUserReactivated = "user.reactivated"
+	ActionLogout          = "auth.logout"
+	ActionLogoutAll       = "user.logout_all"
+	ActionPasswordReset   = "user.password_reset"
+	ActionPasswordChanged = "user.password_changed"
+	ActionUsernameChanged = "user.username_changed"
+	ActionAvatarUpdated   = "user.avatar_updated"
+	ActionAvatarDeleted   = "user.avatar_deleted"
+	ActionDeviceTrusted   = "device.trusted"
+	ActionDeviceRevoked   = "device.revoked"
+	ActionConsentAccepted = "consent.accepted"
+	ActionRoleCreated     = "role.created"
+	ActionRoleUpdated     = "role.updated"
+	ActionRoleDeleted     = "role.deleted"
+	ActionRoleAssigned    = "grant.assigned"
+	ActionRoleRevoked     = "grant.revoked"
+
+	ActionGrantRequested       = "grant_request.created"
+	ActionGrantRequestApproved = "grant_request.approved"
+	ActionGrantRequestRejected = "grant_request.rejected"
+
+	ActionPermissionSetCreated = "permission_set.created"
+	ActionPermissionSetUpdated = "permission_set.updated"
+	ActionPermissionSetDeleted = "permission_set.deleted"
+
+	ActionSnapshotImported = "authz.imported"
+
+	ActionServiceToken                = "auth.client_credentials"
+	ActionServiceAccountCreated       = "service_account.created"
+	ActionServiceAccountUpdated       = "service_account.updated"
+	ActionServiceAccountDeleted       = "service_account.deleted"
+	ActionServiceAccountSecretRotated = "service_account.secret_rotated"
+
+	ActionOAuthSignIn    = "auth.oauth_signin"
+	ActionOAuthSignUp    = "auth.oauth_signup"
+	ActionIdentityLinked = "identity.linked"
+
+	ActionMagicLinkRequested = "auth.magic_link"
+	ActionMagicLinkSignIn    = "auth.signin_magic_link"
+
+	ActionEmailChangeRequested = "user.email_change_requested"
+	ActionEmailChanged         = "user.email_changed"
+	ActionEmailChangeReverted  = "user.email_change_reverted"
+
+	ActionSettingsUpdated = "settings.updated"
+
+	ActionMailSent = "mail.sent"
+)
+
+// Event describes a state-changing operation performed by a handler.
+// Err is nil when the operation succeeded.
+// Impersonator is set when the request was made with an impersonation
+// token: the authenticated user is then the one being impersonated.
+// Location is set when middleware.ClientIP located the client.
+// Metadata carries details specific to the action, such as the reasons a
+// sign-in was flagged.
+type Event struct {
+	Action       string
+	Subject      string
+	RemoteIP     string
+	Location     *geoip.Location
+	Impersonator string
+	Metadata     map[string]string
+	Err          error
+	At           time.Time
+}
+
+// Hooks are callbacks invoked after each state-changing operation.
+// Either field may be nil.
+type Hooks struct {
+	OnSuccess func(ctx context.Context, e Event)
+	OnFailure func(ctx context.Context, e Event)
+}
+
+// AuditRecorder receives every Event emitted by a handler.
+// Record is called synchronously on the request path, so implementations
+// should hand off slow work and handle their own errors.
+type AuditRecorder interface {
+	Record(ctx context.Context, e Event)
+}
+
+// RateLimiter decides whether a request identified by key may proceed.
+// When it may not, it returns how long the caller should wait.
+// *middleware.RateLimiter satisfies this interface.
+type RateLimiter interface {
+	Allow(key string) (bool, time.Duration)
+}
+
+// ErrorFormatter writes an error response. Retry-After is already set on w
+// when resp.RetryAfterSeconds is non-zero.
+type ErrorFormatter func(w http.ResponseWriter, status int, resp ErrorResponse)
+
+// Option configures optional features of AuthNHandler and AuthZHandler.
+// SystemHandler only honours WithInternal and WithBootstrap.
+type Option func(*options)
+
+type options struct {
+	hooks           []Hooks
+	limiter         RateLimiter
+	audit           AuditRecorder
+	now             func() time.Time
+	formatError     ErrorFormatter
+	oauth           *oauth.Registry
+	identities      auth.IdentityStore
+	permissions     *auth.PermissionRegistry
+	permissionSets  auth.PermissionSetStore
+	grantApproval   *grantApproval
+	delegatedAdmin  bool
+	principals      PrincipalResolver
+	engine          auth.AuthorizationEngine
+	mailer          *mail.Mailer
+	validator       middleware.SessionValidator
+	keys            []ed25519.PublicKey
+	impersonate     *impersonation
+	tokenExchange   *tokenExchange
+	serviceAccounts *serviceAccounts
+	snapshots       *seed.Seeder
+	tx              auth.Transactor
+	signUpRoles     *signUpRoles
+	avatars         *avatars
+	magicLinks      *magicLinks
+	usernames       *usernameHistory
+	emailChanges    *emailChanges
+	devices         *devices
+	sessions        auth.SessionStore
+	revocations     *revocations
+	risk            auth.RiskEvaluator
+	settings        auth.SettingsStore
+	emailDomains    auth.EmailDomains
+	consents        *consents
+	internal        func(http.Handler) http.Handler
+	bootstrap       *bootstrapGuard
+}
+
+// signUpRoles are the roles AuthNHandler grants to every user signing up.
+type signUpRoles struct {
+	roles  auth.RoleStore
+	grants auth.GrantStore
+	names  []string
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		now:  time.Now,
+		risk: auth.NoRisk,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithHooks registers callbacks for state-changing operations.
+// It may be passed more than once; hooks run in registration order.
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, hooks)
+	}
+}
+
+// WithRateLimiter throttles requests by client IP. AuthNHandler applies it
+// to the /auth endpoints; AuthZHandler applies it to every route.
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(o *options) {
+		o.limiter = limiter
+	}
+}
+
+// WithAudit records every state-changing operation with the given recorder.
+func WithAudit(recorder AuditRecorder) Option {
+	return func(o *options) {
+		o.audit = recorder
+	}
+}
+
+// WithClock overrides the time source used to stamp events.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		if now != nil {
+			o.now = now
+		}
+	}
+}
+
+// WithErrorFormat forces how error responses are written. By default the
+// format is negotiated per request: application/problem+json when the
+// Accept header prefers it, JSONErrorFormat otherwise.
+func WithErrorFormat(format ErrorFormatter) Option {
+	return func(o *options) {
+		if format != nil {
+			o.formatError = format
+		}
+	}
+}
+
+// WithOAuth enables federated sign-in on AuthNHandler through the providers
+// in registry, linking external accounts to users with identities.
+// AuthZHandler ignores it.
+func WithOAuth(registry *oauth.Registry, identities auth.IdentityStore) Option {
+	return func(o *options) {
+		o.oauth = registry
+		o.identities = identities
+	}
+}
+
+// WithPermissions makes AuthZHandler validate role permissions against
+// registry on create and update and serve the catalog at GET /permissions.
+// AuthNHandler ignores it.
+func WithPermissions(registry *auth.PermissionRegistry) Option {
+	return func(o *options) {
+		o.permissions = registry
+	}
+}
+
+// WithPermissionSets makes AuthZHandler manage the permission sets in store
+// under /permission-sets and accept permission_sets on roles, which then
+// also grant the permissions of the named sets. Updating a set refreshes
+// every role referencing it, in one unit of work with WithTransactor; sets
+// still referenced cannot be deleted. AuthNHandler ignores it.
+func WithPermissionSets(store auth.PermissionSetStore) Option {
+	return func(o *options) {
+		o.permissionSets = store
+	}
+}
+
+// WithGrantApproval makes AuthZHandler assign roles in two steps: users
+// file requests under /grant-requests or for themselves under
+// /users/me/role-requests, kept in store, and a caller holding
+// permission, auth.PermissionApproveGrants when empty, approves or rejects
+// them. Only approval creates the grant, so POST /grants and
+// POST /grants:batch are not served. Requests are emitted as
+// ActionGrantRequested, ActionGrantRequestApproved and
+// ActionGrantRequestRejected, which NewWebhookHooks publishes. AuthNHandler
+// ignores it.
+func WithGrantApproval(store auth.GrantRequestStore, permission string) Option {
+	return func(o *options) {
+		if permission == "" {
+			permission = auth.PermissionApproveGrants
+		}
+		o.grantApproval = &grantApproval{store: store, permission: permission}
+	}
+}
+
+// WithDelegatedAdmin makes AuthZHandler limit callers changing grants to
+// the roles they administer: POST and DELETE /grants, POST /grants:batch,
+// POST /grants:reconcile and approving grant requests require an
+// authenticated caller whose roles grant auth.PermissionManageGrants
+// permissions, such as grants:manage:acme-* for the roles of tenant acme.
+// A scoped token must cover those permissions too. Other callers get 403 PERMISSION_DENIED and roles outside their scope 403
+// ROLE_OUT_OF_SCOPE. The caller is recorded as the assigner. AuthNHandler
+// ignores it.
+func WithDelegatedAdmin() Option {
+	return func(o *options) {
+		o.delegatedAdmin = true
+	}
+}
+
+// WithPrincipals makes AuthZHandler look up the grants of an authenticated
+// caller, for the /users/me routes, grant requests and delegated
+// administration, under the name resolve maps the token subject to. Without
+// it the subject is used as is. AuthNHandler ignores it.
+func WithPrincipals(resolve PrincipalResolver) Option {
+	return func(o *options) {
+		o.principals = resolve
+	}
+}
+
+// WithEngine makes AuthZHandler answer permission checks with engine instead
+// of evaluating grants itself. POST /authz/decide still traces grants.
+// AuthNHandler ignores it.
+func WithEngine(engine auth.AuthorizationEngine) Option {
+	return func(o *options) {
+		o.engine = engine
+	}
+}
+
+// WithMailer makes AuthNHandler email users: a welcome message on sign-up,
+// the PIN from /auth/generate-pin, the generated password from an admin
+// reset and the links of WithMagicLinks and WithEmailChanges. Delivery is best effort; each attempt emits ActionMailSent, with
+// Err set when sending failed. AuthZHandler ignores it.
+func WithMailer(mailer *mail.Mailer) Option {
+	return func(o *options) {
+		o.mailer = mailer
+	}
+}
+
+// WithMagicLinks makes AuthNHandler serve passwordless sign-in:
+// POST /auth/magic-link emails active users a link to linkURL carrying a
+// token from links, and GET /auth/magic-link/verify exchanges the token for
+// a session token. limiter throttles requests per email address, allowing
+// DefaultMagicLinkLimit per DefaultMagicLinkWindow when nil. The routes are
+// only served together with WithMailer. AuthZHandler ignores it.
+func WithMagicLinks(links *service.MagicLinks, linkURL string, limiter RateLimiter) Option {
+	return func(o *options) {
+		if limiter == nil {
+			limiter = middleware.NewRateLimiter(DefaultMagicLinkLimit, DefaultMagicLinkWindow)
+		}
+		o.magicLinks = &magicLinks{links: links, url: linkURL, limiter: limiter}
+	}
+}
+
+// WithUsernameHistory makes AuthNHandler record in store the usernames
+// given up through POST /users/{id}/username, and keep anyone but their
+// previous owner from taking them, by renaming or signing up, for hold,
+// service.DefaultUsernameHold when zero. AuthZHandler ignores it.
+func WithUsernameHistory(store auth.UsernameHistoryStore, hold time.Duration) Option {
+	return func(o *options) {
+		o.usernames = &usernameHistory{store: store, hold: hold}
+	}
+}
+
+// WithEmailChanges makes AuthNHandler serve email changes:
+// POST /users/{id}/email emails the new address a link to verifyURL
+// carrying a token from changes, and GET /auth/email/verify exchanges it
+// for the change. The old address is then emailed a link to revertURL,
+// and GET /auth/email/revert undoes the change within the revert window.
+// The routes are only served together with WithMailer. AuthZHandler
+// ignores it.
+func WithEmailChanges(changes *service.EmailChanges, verifyURL, revertURL string) Option {
+	return func(o *options) {
+		o.emailChanges = &emailChanges{changes: changes, verifyURL: verifyURL, revertURL: revertURL}
+	}
+}
+
+// WithDevices makes AuthNHandler keep track of the devices users sign in
+// from in store. /auth/signin then accepts a device and a trusted-device
+// token, issuing a token valid for ttl, DefaultDeviceTrustTTL when zero, to
+// devices the user asks to remember and reporting trusted_device when a
+// valid token is presented so callers can skip second-factor checks. Users'
+// devices are listed and revoked under /users/{id}/devices. AuthZHandler
+// ignores it.
+func WithDevices(store auth.DeviceStore, ttl time.Duration) Option {
+	return func(o *options) {
+		if ttl <= 0 {
+			ttl = DefaultDeviceTrustTTL
+		}
+		o.devices = &devices{store: store, ttl: ttl}
+	}
+}
+
+// WithSessions makes AuthNHandler end the sessions recorded in store when
+// they should no longer be honoured; POST /users/me/password ends all of
+// the user's sessions but the one making the request, while suspending a
+// user and logging out end theirs. AuthZHandler ignores it.
+func WithSessions(store auth.SessionStore) Option {
+	return func(o *options) {
+		o.sessions = store
+	}
+}
+
+// WithRevocations makes AuthNHandler record token revocations in store:
+// POST /auth/logout revokes the caller's session and
+// POST /users/{id}/logout-all every token of the user issued so far, as do
+// suspending the user and changing or resetting their password.
+// POST /auth/verify refuses revoked tokens. ttl is the longest lifetime of
+// the session tokens issued, for which revocations are kept; when zero it
+// is the TTL of the token generator, or DefaultRevocationTTL when the
+// generator does not report one. The session TTL of WithSettings extends it
+// when longer. Wrap the token validators of
+// other services with middleware.NewRevocationValidator and
+// service.NewRevocationCheck over the same store to refuse revoked tokens
+// there too. AuthZHandler ignores it.
+func WithRevocations(store auth.RevocationStore, ttl time.Duration) Option {
+	return func(o *options) {
+		o.revocations = &revocations{store: store, ttl: ttl}
+	}
+}
+
+// WithRiskEvaluator makes AuthNHandler assess every password, magic link
+// and OAuth sign-in whose credentials verified with evaluator, which
+// defaults to auth.NoRisk. Sign-ins assessed as auth.RiskStepUp report
+// mfa_required and get an MFA-pending token instead of the session token:
+// Bearer and Session refuse it, and only routes behind
+// middleware.BearerMFAPending, where the service verifies the second
+// factor and issues the session token, accept it. Every decision other than
+// auth.RiskAllow emits ActionSignInRisk with the decision and reasons in
+// its metadata. Evaluation errors fail the sign-in. AuthZHandler
+// ignores it.
+func WithRiskEvaluator(evaluator auth.RiskEvaluator) Option {
+	return func(o *options) {
+		if evaluator != nil {
+			o.risk = evaluator
+		}
+	}
+}
+
+// WithSettings makes AuthNHandler apply the runtime settings kept in store
+// over the static configuration: the session TTL of the tokens it issues at
+// sign-in, whether password sign-ins off a trusted device of WithDevices
+// need a second factor, answered with an MFA-pending token as for
+// WithRiskEvaluator, the minimum length of new passwords and the email domains allowed to create
+// accounts, which replace those of WithEmailDomains when set.
+// GET /settings and PUT /settings read and replace them. AuthZHandler
+// ignores it.
+func WithSettings(store auth.SettingsStore) Option {
+	return func(o *options) {
+		o.settings = store
+	}
+}
+
+// WithEmailDomains makes AuthNHandler refuse sign-ups, and accounts
+// provisioned at OAuth sign-in, from email domains domains does not allow,
+// with auth.ErrEmailDomainNotAllowed. Settings saved through WithSettings
+// replace domains. AuthZHandler ignores it.
+func WithEmailDomains(domains auth.EmailDomains) Option {
+	return func(o *options) {
+		domains.Normalize()
+		o.emailDomains = domains
+	}
+}
+
+// WithConsents makes AuthNHandler record which version of each policy users
+// accepted in store. policies holds the current version of each policy.
+// GET /policies lists them, POST and GET /users/{id}/consents record and list
+// a user's acceptances and GET /policies/{policy}/pending reports the active
+// users yet to accept the current version. Pair it with
+// middleware.RequireConsent to block access until then. AuthZHandler ignores
+// it.
+func WithConsents(store auth.ConsentStore, policies auth.Policies) Option {
+	return func(o *options) {
+		o.consents = &consents{store: store, policies: policies}
+	}
+}
+
+// WithInternal guards the endpoints meant for other services rather than
+// users with guard, typically middleware.RequireSignature:
+// POST /auth/bootstrap on AuthNHandler, /authz/export and /authz/import on
+// AuthZHandler and every SystemHandler route.
+func WithInternal(guard func(http.Handler) http.Handler) Option {
+	return func(o *options) {
+		o.internal = guard
+	}
+}
+
+// WithBootstrap hardens POST /auth/bootstrap and SystemHandler's
+// POST /system/bootstrap: requests must carry token in BootstrapTokenHeader,
+// answering 401 otherwise, and come from one of the allowed networks,
+// answering 403 otherwise. An empty token or allowlist skips that check.
+// Once the superadmin exists both endpoints answer 410, so the token only
+// ever works once.
+func WithBootstrap(token string, allowed ...netip.Prefix) Option {
+	return func(o *options) {
+		o.bootstrap = &bootstrapGuard{token: token, allowed: allowed}
+	}
+}
+
+// WithAvatars makes AuthNHandler store user avatars in storage and serve
+// them at PUT, GET and DELETE /users/{id}/avatar. Uploads must be PNG, JPEG,
+// GIF or WebP images of at most DefaultMaxAvatarSize; opts override either
+// limit. AuthZHandler ignores it.
+func WithAvatars(storage assets.Storage, opts ...assets.UploadOption) Option {
+	return func(o *options) {
+		defaults := []assets.UploadOption{
+			assets.WithMaxSize(DefaultMaxAvatarSize),
+			assets.WithContentTypes(assets.ImageTypes...),
+		}
+		o.avatars = &avatars{storage: storage, uploader: assets.NewUploader(append(defaults, opts...)...)}
+	}
+}
+
+// WithTokenValidator makes AuthNHandler serve POST /auth/verify, which lets
+// other services check a token with validator, typically a
+// *middleware.TokenValidator. AuthZHandler ignores it.
+func WithTokenValidator(validator middleware.SessionValidator) Option {
+	return func(o *options) {
+		o.validator = validator
+	}
+}
+
+// WithVerificationKeys makes AuthNHandler publish keys as a key set at
+// GET /.well-known/jwks.json, so other services can verify its tokens
+// without calling it. While rotating, publish the previous key alongside the
+// new one until the tokens it signed have expired. AuthZHandler ignores it.
+func WithVerificationKeys(keys ...ed25519.PublicKey) Option {
+	return func(o *options) {
+		o.keys = keys
+	}
+}
+
+// WithImpersonation makes AuthNHandler serve POST /auth/impersonate, which
+// issues tokens acting as another user to callers holding
+// auth.PermissionImpersonate according to checker. Tokens expire after ttl,
+// DefaultImpersonationTTL when zero; callers may ask for less. The route
+// expects the caller to be authenticated by middleware such as Bearer.
+// AuthZHandler ignores it.
+func WithImpersonation(tokens service.ImpersonationTokenGenerator, checker middleware.RoleChecker, ttl time.Duration) Option {
+	return func(o *options) {
+		if ttl <= 0 {
+			ttl = DefaultImpersonationTTL
+		}
+		o.impersonate = &impersonation{tokens: tokens, checker: checker, ttl: ttl}
+	}
+}
+
+// WithTokenExchange makes AuthNHandler serve POST /auth/token/exchange,
+// which trades a valid token for one from tokens limited to some of its
+// permissions, to hand to less trusted components. Derived tokens expire
+// after ttl, DefaultTokenExchangeTTL when zero, or with the token they
+// derive from when sooner; callers may ask for less. The route needs
+// WithTokenValidator. AuthZHandler ignores it.
+func WithTokenExchange(tokens service.ScopedTokenGenerator, ttl time.Duration) Option {
+	return func(o *options) {
+		if ttl <= 0 {
+			ttl = DefaultTokenExchangeTTL
+		}
+		o.tokenExchange = &tokenExchange{tokens: tokens, ttl: ttl}
+	}
+}
+
+// WithServiceAccounts makes AuthNHandler manage the service accounts in
+// store under /service-accounts and serve POST /auth/token, which issues
+// tokens from tokens to accounts presenting their client credentials. Tokens
+// expire after ttl, DefaultServiceTokenTTL when zero, and name the account's
+// principal as subject, so its grants apply. AuthZHandler ignores it.
+func WithServiceAccounts(store auth.ServiceAccountStore, tokens service.ServiceTokenGenerator, ttl time.Duration) Option {
+	return func(o *options) {
+		if ttl <= 0 {
+			ttl = DefaultServiceTokenTTL
+		}
+		o.serviceAccounts = &serviceAccounts{store: store, tokens: tokens, ttl: ttl}
+	}
+}
+
+// WithSnapshots makes AuthZHandler serve GET /authz/export and
+// POST /authz/import, which move roles, grants and optionally users between
+// environments as seed manifests through seeder. AuthNHandler ignores it.
+func WithSnapshots(seeder *seed.Seeder) Option {
+	return func(o *options) {
+		o.snapshots = seeder
+	}
+}
+
+// WithTransactor makes operations spanning several stores, such as sign-up
+// with WithSignUpRoles and POST /grants:batch, run as one unit of work
+// through tx, so they either complete or leave nothing behind. Without it
+// they run their steps one after another.
+func WithTransactor(tx auth.Transactor) Option {
+	return func(o *options) {
+		o.tx = tx
+	}
+}
+
+// WithSignUpRoles makes AuthNHandler assign the named roles, looked up in
+// roles, to every user created through /auth/signup. Sign-up fails when a
+// role is missing; with WithTransactor the user is then not created either.
+// AuthZHandler ignores it.
+func WithSignUpRoles(roles auth.RoleStore, grants auth.GrantStore, names ...string) Option {
+	return func(o *options) {
+		o.signUpRoles = &signUpRoles{roles: roles, grants: grants, names: names}
+	}
+}
+
+// emit notifies hooks and the audit recorder about an operation.
+func (o *options) emit(r *http.Request, action, subject string, err error) {
+	o.emitWith(r, action, subject, nil, err)
+}
+
+// emitWith is like emit but attaches metadata to the event.
+func (o *options) emitWith(r *http.Request, action, subject string, metadata map[string]string, err error) {
+	if len(o.hooks) == 0 && o.audit == nil {
+		return
+	}
+
+	ctx := r.Context()
+	e := Event{
+		Action:       action,
+		Subject:      subject,
+		RemoteIP:     remoteIP(r),
+		Location:     clientLocation(r),
+		Impersonator: middleware.GetImpersonator(ctx),
+		Metadata:     metadata,
+		Err:          err,
+		At:           o.now(),
+	}
+
+	for _, h := range o.hooks {
+		if err == nil && h.OnSuccess != nil {
+			h.OnSuccess(ctx, e)
+		}
+		if err != nil && h.OnFailure != nil {
+			h.OnFailure(ctx, e)
+		}
+	}
+
+	if o.audit != nil {
+		o.audit.Record(ctx, e)
+	}
+}
+
+// limit wraps next with the configured rate limiter, if any.
+func (o *options) limit(next http.HandlerFunc) http.HandlerFunc {
+	if o.limiter == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed, retryAfter := o.limiter.Allow(remoteIP(r))
+		if !allowed {
+			o.writeRetryError(w, r, http.StatusTooManyRequests, "RATE_LIMITED", "Too many requests", retryAfter)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (o *options) writeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	o.writeAPIError(w, r, httpx.NewError(status, code, message))
+}
+
+func (o *options) writeRetryError(w http.ResponseWriter, r *http.Request, status int, code, message string, retryAfter time.Duration) {
+	o.writeAPIError(w, r, httpx.NewError(status, code, message).WithRetryAfter(retryAfter))
+}
+
+// internalRoutes returns r with the WithInternal guard applied, or r itself
+// without one.
+func (o *options) internalRoutes(r chi.Router) chi.Router {
+	if o.internal == nil {
+		return r
+	}
+	return r.With(o.internal)
+}
+
+// bind decodes and validates the JSON body of r into dst with httpx.Bind.
+// On failure it writes the error and returns false.
+func (o *options) bind(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if err := httpx.Bind(r, dst); err != nil {
+		o.writeAPIError(w, r, httpx.AsError(err))
+		return false
+	}
+	return true
+}
+
+// writeValidationError writes a 400 with code whose body lists errs field by
+// field, so clients can point at every bad input at once.
+func (o *options) writeValidationError(w http.ResponseWriter, r *http.Request, code string, errs validation.ValidationErrors) {
+	o.writeAPIError(w, r, httpx.BadRequest(code, errs.Error()).WithFields(errs))
+}
+
+func (o *options) handleServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	o.writeAPIError(w, r, serviceError(err))
+}
+
+// writeAPIError writes e with the formatter set by WithErrorFormat, or
+// negotiates the format from the Accept header when none was set.
+func (o *options) writeAPIError(w http.ResponseWriter, r *http.Request, e *httpx.Error) {
+	if o.formatError == nil {
+		httpx.WriteError(w, r, e)
+		return
+	}
+	o.formatError(w, e.Status, httpx.Response(w, e))
+}
+
+// clientLocation returns the location middleware.ClientIP found for the
+// client of r, if any.
+func clientLocation(r *http.Request) *geoip.Location {
+	if info := middleware.GetClientInfo(r.Context()); info != nil {
+		return info.Location
+	}
+	return nil
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
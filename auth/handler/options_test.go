@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/seed"
+	"github.com/aquamarinepk/aqm/crypto"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/mail"
+	mailfake "github.com/aquamarinepk/aqm/mail/fake"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+type recordingAudit struct {
+	events []Event
+}
+
+func (a *recordingAudit) Record(ctx context.Context, e Event) {
+	a.events = append(a.events, e)
+}
+
+type denyLimiter struct {
+	keys []string
+}
+
+func (l *denyLimiter) Allow(key string) (bool, time.Duration) {
+	l.keys = append(l.keys, key)
+	return false, 3 * time.Second
+}
+
+func postJSON(t *testing.T, r http.Handler, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "192.0.2.1:1234"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestWithHooksAndAudit(t *testing.T) {
+	fixed := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	audit := &recordingAudit{}
+	var successes, failures []Event
+
+	h := NewAuthNHandler(
+		fake.NewUserStore(),
+		fake.NewCryptoService(),
+		fake.NewTokenGenerator(),
+		fake.NewPasswordGenerator(),
+		fake.NewPINGenerator(),
+		WithHooks(Hooks{
+			OnSuccess: func(ctx context.Context, e Event) { successes = append(successes, e) },
+			OnFailure: func(ctx context.Context, e Event) { failures = append(failures, e) },
+		}),
+		WithAudit(audit),
+		WithClock(func() time.Time { return fixed }),
+	)
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	signUp := SignUpRequest{Email: "hook@example.com", Password: "Password123!", Username: "hookuser", DisplayName: "Hook"}
+	if w := postJSON(t, r, "/auth/signup", signUp); w.Code != http.StatusCreated {
+		t.Fatalf("signup status = %d, body: %s", w.Code, w.Body.String())
+	}
+	postJSON(t, r, "/auth/signin", SignInRequest{Email: "hook@example.com", Password: "wrong-Password1!"})
+
+	if len(successes) != 1 || successes[0].Action != ActionSignUp {
+		t.Fatalf("successes = %+v, want one %s", successes, ActionSignUp)
+	}
+	if len(failures) != 1 || failures[0].Action != ActionSignIn {
+		t.Fatalf("failures = %+v, want one %s", failures, ActionSignIn)
+	}
+	if !errors.Is(failures[0].Err, auth.ErrInvalidCredentials) {
+		t.Errorf("failure err = %v, want %v", failures[0].Err, auth.ErrInvalidCredentials)
+	}
+
+	if len(audit.events) != 2 {
+		t.Fatalf("audit events = %d, want 2", len(audit.events))
+	}
+	for _, e := range audit.events {
+		if !e.At.Equal(fixed) {
+			t.Errorf("event At = %v, want %v", e.At, fixed)
+		}
+		if e.RemoteIP != "192.0.2.1" {
+			t.Errorf("event RemoteIP = %q, want %q", e.RemoteIP, "192.0.2.1")
+		}
+	}
+}
+
+func TestWithRateLimiter(t *testing.T) {
+	limiter := &denyLimiter{}
+
+	roleStore := fake.NewRoleStore()
+	h := NewAuthZHandler(roleStore, fake.NewGrantStore(roleStore), WithRateLimiter(limiter))
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	w := postJSON(t, r, "/roles", CreateRoleRequest{Name: "editor", CreatedBy: "admin"})
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if got := w.Header().Get("Retry-After"); got != "3" {
+		t.Errorf("Retry-After = %q, want %q", got, "3")
+	}
+	if len(limiter.keys) != 1 || limiter.keys[0] != "192.0.2.1" {
+		t.Errorf("limiter keys = %v, want [192.0.2.1]", limiter.keys)
+	}
+
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Code != "RATE_LIMITED" || resp.RetryAfterSeconds != 3 {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestWithErrorFormat(t *testing.T) {
+	roleStore := fake.NewRoleStore()
+	h := NewAuthZHandler(roleStore, fake.NewGrantStore(roleStore), WithErrorFormat(ProblemErrorFormat))
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/roles/not-a-uuid", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/problem+json" {
+		t.Errorf("Content-Type = %q, want application/problem+json", got)
+	}
+
+	var resp ProblemResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("cannot decode problem: %v", err)
+	}
+	if resp.Status != http.StatusBadRequest || resp.Code != "INVALID_ROLE_ID" || resp.Title != "Bad Request" {
+		t.Errorf("problem = %+v", resp)
+	}
+}
+
+func TestNegotiatedErrorFormat(t *testing.T) {
+	roleStore := fake.NewRoleStore()
+	h := NewAuthZHandler(roleStore, fake.NewGrantStore(roleStore))
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{name: "no accept", want: "application/json"},
+		{name: "json", accept: "application/json", want: "application/json"},
+		{name: "problem", accept: "application/problem+json", want: "application/problem+json"},
+		{name: "json preferred", accept: "application/problem+json;q=0.5, application/json", want: "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/roles/not-a-uuid", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.want {
+				t.Errorf("Content-Type = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithErrorFormatOverridesAccept(t *testing.T) {
+	roleStore := fake.NewRoleStore()
+	h := NewAuthZHandler(roleStore, fake.NewGrantStore(roleStore), WithErrorFormat(JSONErrorFormat))
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/roles/not-a-uuid", nil)
+	req.Header.Set("Accept", "application/problem+json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+}
+
+func TestNewOptionsDefaults(t *testing.T) {
+	o := newOptions([]Option{WithClock(nil), WithErrorFormat(nil)})
+
+	if o.now == nil {
+		t.Error("nil clock should keep the default")
+	}
+	if o.formatError != nil {
+		t.Error("nil error format should keep per-request negotiation")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	o.emit(req, ActionSignIn, "", nil)
+}
+
+func TestWithMailer(t *testing.T) {
+	sender := mailfake.NewSender()
+	audit := &recordingAudit{}
+
+	h := NewAuthNHandler(
+		fake.NewUserStore(),
+		fake.NewCryptoService(),
+		fake.NewTokenGenerator(),
+		fake.NewPasswordGenerator(),
+		fake.NewPINGenerator(),
+		WithMailer(mail.NewMailer(sender, nil, "noreply@example.com")),
+		WithAudit(audit),
+	)
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	signUp := SignUpRequest{Email: "mail@example.com", Password: "Password123!", Username: "mailuser", DisplayName: "Mail User"}
+	w := postJSON(t, r, "/auth/signup", signUp)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("signup status = %d, body: %s", w.Code, w.Body.String())
+	}
+	var signUpResp SignUpResponse
+	json.NewDecoder(w.Body).Decode(&signUpResp)
+	id := signUpResp.User.ID.String()
+
+	if w := postJSON(t, r, "/auth/generate-pin", GeneratePINRequest{UserID: id}); w.Code != http.StatusOK {
+		t.Fatalf("generate-pin status = %d, body: %s", w.Code, w.Body.String())
+	}
+	if w := postJSON(t, r, "/users/"+id+"/password", ResetPasswordRequest{}); w.Code != http.StatusOK {
+		t.Fatalf("reset password status = %d, body: %s", w.Code, w.Body.String())
+	}
+
+	msgs := sender.Messages()
+	if len(msgs) != 3 {
+		t.Fatalf("sent %d messages, want 3", len(msgs))
+	}
+	for _, msg := range msgs {
+		if msg.To[0] != "mail@example.com" {
+			t.Errorf("message to = %v, want mail@example.com", msg.To)
+		}
+	}
+	if !strings.Contains(msgs[0].Text, "Mail User") {
+		t.Errorf("welcome text = %q, want display name", msgs[0].Text)
+	}
+	if !strings.Contains(msgs[1].Text, "123456") {
+		t.Errorf("PIN text = %q, want PIN", msgs[1].Text)
+	}
+	if !strings.Contains(msgs[2].Text, "GeneratedPassword123!") {
+		t.Errorf("reset text = %q, want generated password", msgs[2].Text)
+	}
+
+	sender.FailWith(errors.New("relay down"))
+	if w := postJSON(t, r, "/users/"+id+"/password", ResetPasswordRequest{}); w.Code != http.StatusOK {
+		t.Errorf("reset password status with failing mailer = %d, want %d", w.Code, http.StatusOK)
+	}
+	last := audit.events[len(audit.events)-1]
+	if last.Action != ActionMailSent || last.Err == nil {
+		t.Errorf("last event = %+v, want failed %s", last, ActionMailSent)
+	}
+}
+
+func TestWithInternal(t *testing.T) {
+	secret := []byte("internal-secret")
+	internal := WithInternal(middleware.RequireSignature(middleware.RequestSignatureConfig{Keys: middleware.SharedSecret(secret)}))
+
+	users := fake.NewUserStore()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+	seeder := seed.New(users, roles, grants, &seed.Config{
+		EncryptionKey: []byte("test-encryption-key-32-bytes!!!!"),
+		SigningKey:    []byte("test-signing-key-32-bytes-long!!"),
+	}, log.NewNoopLogger())
+
+	r := chi.NewRouter()
+	NewAuthNHandler(users, fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(), internal).RegisterRoutes(r)
+	NewAuthZHandler(roles, grants, WithSnapshots(seeder), internal).RegisterRoutes(r)
+	NewSystemHandler(users, fake.NewCryptoService(), fake.NewPasswordGenerator(), internal).RegisterRoutes(r)
+
+	routes := []struct{ method, path string }{
+		{http.MethodPost, "/auth/bootstrap"},
+		{http.MethodGet, "/authz/export"},
+		{http.MethodPost, "/authz/import?dry_run=true"},
+		{http.MethodGet, "/system/bootstrap-status"},
+	}
+	for _, route := range routes {
+		req := httptest.NewRequest(route.method, route.path, strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("unsigned %s %s = %d, want 401", route.method, route.path, w.Code)
+		}
+
+		req = httptest.NewRequest(route.method, route.path, strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		crypto.SignRequest(req, "", secret, time.Now())
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code == http.StatusUnauthorized {
+			t.Errorf("signed %s %s = 401", route.method, route.path)
+		}
+	}
+
+	w := postJSON(t, r, "/auth/signup", SignUpRequest{Email: "open@example.com", Password: "Password123!", Username: "openuser", DisplayName: "Open"})
+	if w.Code != http.StatusCreated {
+		t.Errorf("signup with WithInternal = %d, want 201", w.Code)
+	}
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+// ChangePasswordResponse carries, with WithRevocations, the token that
+// replaces the caller's, revoked with every other one of the user.
+type ChangePasswordResponse struct {
+	SessionsEnded int    `json:"sessions_ended"`
+	Token         string `json:"token,omitempty"`
+}
+
+// handleChangePassword serves POST /users/me/password. The caller must
+// present their current password and the new one must pass the password
+// policy, including that of WithSettings. With WithSessions, the user's other sessions are ended.
+// With WithRevocations, every token issued to the user so far is revoked
+// and the response carries a new one for the caller.
+func (h *AuthNHandler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChangePasswordRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	err := h.changePassword(r.Context(), userID, req.CurrentPassword, req.NewPassword)
+	h.emit(r, ActionPasswordChanged, userID.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	var resp ChangePasswordResponse
+	if h.revocations != nil {
+		resp.Token, err = h.reissueToken(r.Context(), userID)
+		if err != nil {
+			h.handleServiceError(w, r, err)
+			return
+		}
+	}
+	if h.sessions != nil {
+		ended, err := service.EndOtherSessions(r.Context(), h.sessions, userID.String(), middleware.GetSessionID(r.Context()))
+		if err != nil {
+			h.handleServiceError(w, r, err)
+			return
+		}
+		resp.SessionsEnded = ended
+	}
+	httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+// reissueToken revokes every token of userID and issues a new session
+// token under the current settings.
+func (h *AuthNHandler) reissueToken(ctx context.Context, userID auth.UserID) (string, error) {
+	if err := h.revokeUserTokens(ctx, userID.String()); err != nil {
+		return "", err
+	}
+	settings, err := h.currentSettings(ctx)
+	if err != nil {
+		return "", err
+	}
+	return h.sessionTokens(settings).GenerateToken(userID)
+}
+
+// changePassword checks password against the settings' password policy
+// before changing it.
+func (h *AuthNHandler) changePassword(ctx context.Context, userID auth.UserID, current, password string) error {
+	settings, err := h.currentSettings(ctx)
+	if err != nil {
+		return err
+	}
+	if err := settings.CheckPassword(password); err != nil {
+		return err
+	}
+	_, err = service.ChangePassword(ctx, h.userStore, h.crypto, userID, current, password)
+	return err
+}
+
+// resetPassword is service.ResetPassword with a given password also
+// checked against the settings' password policy.
+func (h *AuthNHandler) resetPassword(ctx context.Context, userID auth.UserID, password string) (string, error) {
+	if password != "" {
+		settings, err := h.currentSettings(ctx)
+		if err != nil {
+			return "", err
+		}
+		if err := settings.CheckPassword(password); err != nil {
+			return "", err
+		}
+	}
+	return service.ResetPassword(ctx, h.userStore, h.crypto, h.pwdGen, userID, password)
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestChangePassword(t *testing.T) {
+	audit := &recordingAudit{}
+	sessions := fake.NewSessionStore()
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithAudit(audit), WithSessions(sessions),
+	).RegisterRoutes(r)
+
+	w := postJSON(t, r, "/auth/signup", SignUpRequest{Email: "ann@example.com", Password: "Password123!", Username: "ann", DisplayName: "Ann"})
+	var signup SignUpResponse
+	json.NewDecoder(w.Body).Decode(&signup)
+	id := signup.User.ID.String()
+
+	current := auth.NewSession(id, time.Hour)
+	sessions.Create(t.Context(), current)
+	sessions.Create(t.Context(), auth.NewSession(id, time.Hour))
+	sessions.Create(t.Context(), auth.NewSession(id, time.Hour))
+
+	change := func(user string, req ChangePasswordRequest) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(req)
+		httpReq := httptest.NewRequest(http.MethodPost, "/users/me/password", bytes.NewReader(data))
+		httpReq.Header.Set("Content-Type", "application/json")
+		ctx := context.WithValue(httpReq.Context(), middleware.UserIDKey, user)
+		ctx = context.WithValue(ctx, middleware.SessionIDKey, current.ID)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httpReq.WithContext(ctx))
+		return w
+	}
+
+	tests := []struct {
+		name string
+		user string
+		req  ChangePasswordRequest
+		want int
+	}{
+		{"anonymous", "", ChangePasswordRequest{CurrentPassword: "Password123!", NewPassword: "NewPassword456!"}, http.StatusUnauthorized},
+		{"missing current password", id, ChangePasswordRequest{NewPassword: "NewPassword456!"}, http.StatusBadRequest},
+		{"wrong current password", id, ChangePasswordRequest{CurrentPassword: "Wrong123!", NewPassword: "NewPassword456!"}, http.StatusForbidden},
+		{"weak new password", id, ChangePasswordRequest{CurrentPassword: "Password123!", NewPassword: "weak"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := change(tt.user, tt.req); w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+	if left, _ := sessions.ListByUser(t.Context(), id); len(left) != 3 {
+		t.Fatalf("failed changes ended sessions: %d left", len(left))
+	}
+
+	w = change(id, ChangePasswordRequest{CurrentPassword: "Password123!", NewPassword: "NewPassword456!"})
+	var resp ChangePasswordResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || resp.SessionsEnded != 2 {
+		t.Fatalf("change = %d, %+v", w.Code, resp)
+	}
+	if left, _ := sessions.ListByUser(t.Context(), id); len(left) != 1 || left[0].ID != current.ID {
+		t.Errorf("sessions left = %+v, want only the current one", left)
+	}
+
+	if w := postJSON(t, r, "/auth/signin", SignInRequest{Email: "ann@example.com", Password: "NewPassword456!"}); w.Code != http.StatusOK {
+		t.Errorf("sign-in with new password = %d, want 200", w.Code)
+	}
+
+	var changed []Event
+	for _, e := range audit.events {
+		if e.Action == ActionPasswordChanged {
+			changed = append(changed, e)
+		}
+	}
+	if len(changed) != 3 || changed[2].Err != nil || changed[2].Subject != id {
+		t.Errorf("password change events = %+v", changed)
+	}
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// mergePatchContentType is the media type of RFC 7386 JSON Merge Patch
+// bodies. The PATCH handlers also accept plain application/json.
+const mergePatchContentType = "application/merge-patch+json"
+
+// mergePatch is a decoded JSON Merge Patch object. A member holding JSON
+// null removes the target value; absent members are left unchanged.
+type mergePatch map[string]json.RawMessage
+
+func decodeMergePatch(r *http.Request) (mergePatch, error) {
+	var patch mergePatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		return nil, errors.New("Request body must be a JSON object")
+	}
+	return patch, nil
+}
+
+// members returns the patch member names in a stable order.
+func (p mergePatch) members() []string {
+	return slices.Sorted(maps.Keys(p))
+}
+
+func isNull(value json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+}
+
+// patchString decodes a string member. Null clears the value.
+func patchString(name string, value json.RawMessage) (string, error) {
+	if isNull(value) {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", fmt.Errorf("%s must be a string", name)
+	}
+	return s, nil
+}
+
+// decodeAttributesPatch reads a merge patch of user attributes.
+func decodeAttributesPatch(r *http.Request) (map[string]any, error) {
+	var patch map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		return nil, errors.New("Request body must be a JSON object")
+	}
+	return patch, nil
+}
+
+// applyUserPatch applies the name, status and attributes members of patch
+// to user. Attributes merge key by key; null removes them all. Any other
+// member is rejected.
+func applyUserPatch(user *auth.User, patch mergePatch) error {
+	for _, name := range patch.members() {
+		value := patch[name]
+		switch name {
+		case "name":
+			s, err := patchString(name, value)
+			if err != nil {
+				return err
+			}
+			user.Name = s
+		case "status":
+			s, err := patchString(name, value)
+			if err != nil {
+				return err
+			}
+			status := auth.UserStatus(s)
+			if !status.IsValid() {
+				return fmt.Errorf("status %q is not valid", s)
+			}
+			user.Status = status
+		case "attributes":
+			if err := patchAttributes(user, value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s cannot be patched", name)
+		}
+	}
+	return nil
+}
+
+func patchAttributes(user *auth.User, value json.RawMessage) error {
+	if isNull(value) {
+		user.Attributes = nil
+		return nil
+	}
+	var changes map[string]any
+	if err := json.Unmarshal(value, &changes); err != nil || changes == nil {
+		return errors.New("attributes must be an object")
+	}
+	if user.Attributes == nil {
+		user.Attributes = auth.Attributes{}
+	}
+	user.Attributes.Merge(changes)
+	if len(user.Attributes) == 0 {
+		user.Attributes = nil
+	}
+	return nil
+}
+
+// applyRolePatch applies the description, status, permissions and
+// permission_sets members of patch to role. Permission sets are replaced
+// as a whole. Permissions given as an array replace the role's
+// permissions. Given as an object keyed by permission, true adds the
+// permission and null or false removes it, leaving the others untouched:
+//
+//	{"permissions": {"content:publish": true, "content:delete": null}}
+func applyRolePatch(role *auth.Role, patch mergePatch) error {
+	for _, name := range patch.members() {
+		value := patch[name]
+		switch name {
+		case "description":
+			s, err := patchString(name, value)
+			if err != nil {
+				return err
+			}
+			role.Description = s
+		case "status":
+			s, err := patchString(name, value)
+			if err != nil {
+				return err
+			}
+			status := auth.RoleStatus(s)
+			if !status.IsValid() {
+				return fmt.Errorf("status %q is not valid", s)
+			}
+			role.Status = status
+		case "permissions":
+			if err := patchPermissions(role, value); err != nil {
+				return err
+			}
+		case "permission_sets":
+			if isNull(value) {
+				role.PermissionSets = nil
+				continue
+			}
+			var sets []string
+			if err := json.Unmarshal(value, &sets); err != nil {
+				return errors.New("permission_sets must be an array of set names")
+			}
+			role.PermissionSets = sets
+		default:
+			return fmt.Errorf("%s cannot be patched", name)
+		}
+	}
+	return nil
+}
+
+func patchPermissions(role *auth.Role, value json.RawMessage) error {
+	if isNull(value) {
+		role.Permissions = []string{}
+		return nil
+	}
+
+	var replace []string
+	if err := json.Unmarshal(value, &replace); err == nil {
+		if replace == nil {
+			replace = []string{}
+		}
+		role.Permissions = replace
+		return nil
+	}
+
+	var changes map[string]*bool
+	if err := json.Unmarshal(value, &changes); err != nil {
+		return errors.New("permissions must be an array or an object of permission to true or null")
+	}
+	for _, permission := range slices.Sorted(maps.Keys(changes)) {
+		if add := changes[permission]; add != nil && *add {
+			role.AddPermission(permission)
+		} else {
+			role.RemovePermission(permission)
+		}
+	}
+	return nil
+}
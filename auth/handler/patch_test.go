@@ -0,0 +1,427 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestApplyRolePatch(t *testing.T) {
+	tests := []struct {
+		name            string
+		patch           string
+		wantDescription string
+		wantStatus      auth.RoleStatus
+		wantPermissions []string
+		wantErr         bool
+	}{
+		{
+			name:            "empty patch",
+			patch:           `{}`,
+			wantDescription: "Editors",
+			wantStatus:      auth.RoleStatusActive,
+			wantPermissions: []string{"content:read", "content:write"},
+		},
+		{
+			name:            "description and status",
+			patch:           `{"description": "Retired", "status": "inactive"}`,
+			wantDescription: "Retired",
+			wantStatus:      auth.RoleStatusInactive,
+			wantPermissions: []string{"content:read", "content:write"},
+		},
+		{
+			name:            "null description",
+			patch:           `{"description": null}`,
+			wantStatus:      auth.RoleStatusActive,
+			wantPermissions: []string{"content:read", "content:write"},
+		},
+		{
+			name:            "replace permissions",
+			patch:           `{"permissions": ["audit:read"]}`,
+			wantDescription: "Editors",
+			wantStatus:      auth.RoleStatusActive,
+			wantPermissions: []string{"audit:read"},
+		},
+		{
+			name:            "add and remove permissions",
+			patch:           `{"permissions": {"content:publish": true, "content:write": null, "content:read": false}}`,
+			wantDescription: "Editors",
+			wantStatus:      auth.RoleStatusActive,
+			wantPermissions: []string{"content:publish"},
+		},
+		{
+			name:            "add existing permission",
+			patch:           `{"permissions": {"content:read": true}}`,
+			wantDescription: "Editors",
+			wantStatus:      auth.RoleStatusActive,
+			wantPermissions: []string{"content:read", "content:write"},
+		},
+		{
+			name:            "null permissions",
+			patch:           `{"permissions": null}`,
+			wantDescription: "Editors",
+			wantStatus:      auth.RoleStatusActive,
+			wantPermissions: []string{},
+		},
+		{name: "invalid status", patch: `{"status": "archived"}`, wantErr: true},
+		{name: "null status", patch: `{"status": null}`, wantErr: true},
+		{name: "read-only member", patch: `{"name": "renamed"}`, wantErr: true},
+		{name: "invalid permissions", patch: `{"permissions": "content:read"}`, wantErr: true},
+		{name: "invalid description", patch: `{"description": 42}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role := auth.NewRole()
+			role.Name = "editor"
+			role.Description = "Editors"
+			role.Permissions = []string{"content:read", "content:write"}
+
+			var patch mergePatch
+			if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+				t.Fatalf("invalid test patch: %v", err)
+			}
+
+			err := applyRolePatch(role, patch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyRolePatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if role.Description != tt.wantDescription {
+				t.Errorf("Description = %q, want %q", role.Description, tt.wantDescription)
+			}
+			if role.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", role.Status, tt.wantStatus)
+			}
+			if !slices.Equal(role.Permissions, tt.wantPermissions) {
+				t.Errorf("Permissions = %v, want %v", role.Permissions, tt.wantPermissions)
+			}
+		})
+	}
+}
+
+func TestApplyUserPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		patch      string
+		wantName   string
+		wantStatus auth.UserStatus
+		wantErr    bool
+	}{
+		{name: "name", patch: `{"name": "New Name"}`, wantName: "New Name", wantStatus: auth.UserStatusActive},
+		{name: "status", patch: `{"status": "suspended"}`, wantName: "Old Name", wantStatus: auth.UserStatusSuspended},
+		{name: "null name", patch: `{"name": null}`, wantName: "", wantStatus: auth.UserStatusActive},
+		{name: "invalid status", patch: `{"status": "gone"}`, wantErr: true},
+		{name: "read-only member", patch: `{"email": "x@example.com"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := auth.NewUser()
+			user.Name = "Old Name"
+			user.Status = auth.UserStatusActive
+
+			var patch mergePatch
+			if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+				t.Fatalf("invalid test patch: %v", err)
+			}
+
+			err := applyUserPatch(user, patch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyUserPatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if user.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", user.Name, tt.wantName)
+			}
+			if user.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", user.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestApplyUserPatchAttributes(t *testing.T) {
+	user := auth.NewUser()
+	user.Attributes = auth.Attributes{"locale": "en-GB", "theme": "dark"}
+
+	patch := mergePatch{"attributes": json.RawMessage(`{"locale": null, "timezone": "UTC"}`)}
+	if err := applyUserPatch(user, patch); err != nil {
+		t.Fatalf("applyUserPatch() error = %v", err)
+	}
+	want := auth.Attributes{"theme": "dark", "timezone": "UTC"}
+	if !reflect.DeepEqual(user.Attributes, want) {
+		t.Errorf("Attributes = %v, want %v", user.Attributes, want)
+	}
+
+	if err := applyUserPatch(user, mergePatch{"attributes": json.RawMessage(`null`)}); err != nil || user.Attributes != nil {
+		t.Errorf("null attributes = %v, %v; want nil", user.Attributes, err)
+	}
+	if err := applyUserPatch(user, mergePatch{"attributes": json.RawMessage(`["x"]`)}); err == nil {
+		t.Error("applyUserPatch() accepted an array of attributes")
+	}
+}
+
+func TestHandlePatchRole(t *testing.T) {
+	registry := auth.NewPermissionRegistry().MustRegister(
+		auth.PermissionDef{Name: "content:read", Group: "content"},
+		auth.PermissionDef{Name: "content:write", Group: "content"},
+	)
+	roleStore := fake.NewRoleStore()
+	r := chi.NewRouter()
+	NewAuthZHandler(roleStore, fake.NewGrantStore(roleStore), WithPermissions(registry)).RegisterRoutes(r)
+
+	role := auth.NewRole()
+	role.Name = "editor"
+	role.Permissions = []string{"content:read", "legacy:perm"}
+	role.BeforeCreate()
+	roleStore.Create(t.Context(), role)
+	path := "/roles/" + role.ID.String()
+
+	tests := []struct {
+		name            string
+		path            string
+		patch           string
+		ifMatch         string
+		wantStatus      int
+		wantCode        string
+		wantPermissions []string
+		wantETag        string
+	}{
+		{
+			name:            "add permission keeps unregistered ones",
+			path:            path,
+			patch:           `{"permissions": {"content:write": true}}`,
+			wantStatus:      http.StatusOK,
+			wantPermissions: []string{"content:read", "legacy:perm", "content:write"},
+			wantETag:        `"2"`,
+		},
+		{
+			name:            "remove permission with current ETag",
+			path:            path,
+			patch:           `{"permissions": {"legacy:perm": null}}`,
+			ifMatch:         `"2"`,
+			wantStatus:      http.StatusOK,
+			wantPermissions: []string{"content:read", "content:write"},
+			wantETag:        `"3"`,
+		},
+		{
+			name:       "stale ETag",
+			path:       path,
+			patch:      `{"description": "x"}`,
+			ifMatch:    `"1"`,
+			wantStatus: http.StatusPreconditionFailed,
+			wantCode:   "VERSION_CONFLICT",
+		},
+		{
+			name:       "unknown permission",
+			path:       path,
+			patch:      `{"permissions": {"content:nuke": true}}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "UNKNOWN_PERMISSION",
+		},
+		{
+			name:       "read-only member",
+			path:       path,
+			patch:      `{"name": "renamed"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "INVALID_PATCH",
+		},
+		{
+			name:       "not an object",
+			path:       path,
+			patch:      `["description"]`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "INVALID_REQUEST",
+		},
+		{
+			name:       "invalid id",
+			path:       "/roles/invalid",
+			patch:      `{}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "INVALID_ROLE_ID",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, tt.path, strings.NewReader(tt.patch))
+			req.Header.Set("Content-Type", mergePatchContentType)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("handlePatchRole() status = %v, want %v (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantCode != "" {
+				var resp ErrorResponse
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp.Code != tt.wantCode {
+					t.Errorf("handlePatchRole() code = %v, want %v", resp.Code, tt.wantCode)
+				}
+				return
+			}
+
+			var resp RoleResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if !slices.Equal(resp.Role.Permissions, tt.wantPermissions) {
+				t.Errorf("handlePatchRole() permissions = %v, want %v", resp.Role.Permissions, tt.wantPermissions)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("handlePatchRole() ETag = %q, want %q", got, tt.wantETag)
+			}
+		})
+	}
+}
+
+func TestHandlePatchUser(t *testing.T) {
+	r := chi.NewRouter()
+	setupAuthNHandler().RegisterRoutes(r)
+
+	body, _ := json.Marshal(SignUpRequest{
+		Email:       "patch@example.com",
+		Password:    "Password123!",
+		Username:    "patchuser",
+		DisplayName: "Patch User",
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/signup", bytes.NewReader(body)))
+
+	var signup SignUpResponse
+	json.NewDecoder(w.Body).Decode(&signup)
+	path := "/users/" + signup.User.ID.String()
+
+	tests := []struct {
+		name       string
+		patch      string
+		ifMatch    string
+		wantStatus int
+		wantCode   string
+		wantName   string
+	}{
+		{name: "rename", patch: `{"name": "Patched"}`, ifMatch: `"1"`, wantStatus: http.StatusOK, wantName: "Patched"},
+		{name: "stale ETag", patch: `{"name": "Again"}`, ifMatch: `"1"`, wantStatus: http.StatusPreconditionFailed, wantCode: "VERSION_CONFLICT"},
+		{name: "remove name", patch: `{"name": null}`, wantStatus: http.StatusBadRequest, wantCode: "INVALID_DISPLAY_NAME"},
+		{name: "read-only member", patch: `{"username": "other"}`, wantStatus: http.StatusBadRequest, wantCode: "INVALID_PATCH"},
+		{name: "invalid JSON", patch: `{`, wantStatus: http.StatusBadRequest, wantCode: "INVALID_REQUEST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(tt.patch))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("handlePatchUser() status = %v, want %v (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantCode != "" {
+				var resp ErrorResponse
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp.Code != tt.wantCode {
+					t.Errorf("handlePatchUser() code = %v, want %v", resp.Code, tt.wantCode)
+				}
+				return
+			}
+
+			var resp UserResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.User.Name != tt.wantName {
+				t.Errorf("handlePatchUser() name = %q, want %q", resp.User.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestHandleUserAttributes(t *testing.T) {
+	r := chi.NewRouter()
+	setupAuthNHandler().RegisterRoutes(r)
+
+	body, _ := json.Marshal(SignUpRequest{
+		Email:       "attrs@example.com",
+		Password:    "Password123!",
+		Username:    "attrsuser",
+		DisplayName: "Attrs User",
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/signup", bytes.NewReader(body)))
+
+	var signup SignUpResponse
+	json.NewDecoder(w.Body).Decode(&signup)
+	path := "/users/" + signup.User.ID.String() + "/attributes"
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"data":{}}` {
+		t.Fatalf("GET attributes = %d %s, want an empty object", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name       string
+		patch      string
+		ifMatch    string
+		wantStatus int
+		wantCode   string
+		wantAttrs  auth.Attributes
+	}{
+		{name: "set", patch: `{"locale": "en-GB", "prefs": {"theme": "dark"}}`, ifMatch: `"1"`, wantStatus: http.StatusOK,
+			wantAttrs: auth.Attributes{"locale": "en-GB", "prefs": map[string]any{"theme": "dark"}}},
+		{name: "stale ETag", patch: `{"locale": "de-DE"}`, ifMatch: `"1"`, wantStatus: http.StatusPreconditionFailed, wantCode: "VERSION_CONFLICT"},
+		{name: "merge and remove", patch: `{"locale": null, "prefs": {"compact": true}}`, wantStatus: http.StatusOK,
+			wantAttrs: auth.Attributes{"prefs": map[string]any{"theme": "dark", "compact": true}}},
+		{name: "invalid key", patch: `{"Avatar URL": "x"}`, wantStatus: http.StatusBadRequest, wantCode: "INVALID_ATTRIBUTE"},
+		{name: "not an object", patch: `["locale"]`, wantStatus: http.StatusBadRequest, wantCode: "INVALID_REQUEST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(tt.patch))
+			req.Header.Set("Content-Type", mergePatchContentType)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("PATCH attributes status = %v, want %v (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantCode != "" {
+				var resp ErrorResponse
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp.Code != tt.wantCode {
+					t.Errorf("PATCH attributes code = %v, want %v", resp.Code, tt.wantCode)
+				}
+				return
+			}
+
+			var resp UserAttributesResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if !reflect.DeepEqual(resp.Attributes, tt.wantAttrs) {
+				t.Errorf("PATCH attributes = %v, want %v", resp.Attributes, tt.wantAttrs)
+			}
+			if w.Header().Get("ETag") == "" {
+				t.Error("PATCH attributes did not set an ETag")
+			}
+		})
+	}
+}
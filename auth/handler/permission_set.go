@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *AuthZHandler) registerPermissionSetRoutes(r chi.Router) {
+	r.Post("/permission-sets", h.handleCreatePermissionSet)
+	r.Get("/permission-sets", h.handleListPermissionSets)
+	r.Get("/permission-sets/{name}", h.handleGetPermissionSet)
+	r.Put("/permission-sets/{name}", h.handleUpdatePermissionSet)
+	r.Delete("/permission-sets/{name}", h.handleDeletePermissionSet)
+}
+
+type CreatePermissionSetRequest struct {
+	Name        string   `json:"name" validate:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type UpdatePermissionSetRequest struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// PermissionSetResponse carries a permission set. After an update,
+// UpdatedRoles lists the roles whose expansion changed with it.
+type PermissionSetResponse struct {
+	PermissionSet *auth.PermissionSet `json:"permission_set"`
+	UpdatedRoles  []*auth.Role        `json:"updated_roles,omitempty"`
+}
+
+type ListPermissionSetsResponse struct {
+	PermissionSets []*auth.PermissionSet `json:"permission_sets"`
+}
+
+func (h *AuthZHandler) handleCreatePermissionSet(w http.ResponseWriter, r *http.Request) {
+	var req CreatePermissionSetRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	if err := h.validatePermissions(req.Permissions); err != nil {
+		h.emit(r, ActionPermissionSetCreated, req.Name, err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	createdBy := middleware.GetUserID(r.Context())
+	set, err := service.CreatePermissionSet(r.Context(), h.permissionSets, req.Name, req.Description, req.Permissions, createdBy)
+	if err != nil {
+		h.emit(r, ActionPermissionSetCreated, req.Name, err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.emit(r, ActionPermissionSetCreated, set.Name, nil)
+
+	setETag(w, set.Version)
+	httpx.WriteJSON(w, http.StatusCreated, PermissionSetResponse{PermissionSet: set})
+}
+
+func (h *AuthZHandler) handleListPermissionSets(w http.ResponseWriter, r *http.Request) {
+	sets, err := service.ListPermissionSets(r.Context(), h.permissionSets)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, ListPermissionSetsResponse{PermissionSets: sets})
+}
+
+func (h *AuthZHandler) handleGetPermissionSet(w http.ResponseWriter, r *http.Request) {
+	set, err := service.GetPermissionSet(r.Context(), h.permissionSets, chi.URLParam(r, "name"))
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, set.Version)
+	httpx.WriteJSON(w, http.StatusOK, PermissionSetResponse{PermissionSet: set})
+}
+
+// handleUpdatePermissionSet serves PUT /permission-sets/{name}, replacing
+// the description and permissions of the set and refreshing every role
+// that references it. The If-Match header must carry the ETag returned by
+// GET /permission-sets/{name}; a stale ETag fails with 412.
+func (h *AuthZHandler) handleUpdatePermissionSet(w http.ResponseWriter, r *http.Request) {
+	name := auth.NormalizePermissionSetName(chi.URLParam(r, "name"))
+
+	var req UpdatePermissionSetRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	if err := h.validatePermissions(req.Permissions); err != nil {
+		h.emit(r, ActionPermissionSetUpdated, name, err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	ifMatch, ok := h.requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	set, err := service.GetPermissionSet(r.Context(), h.permissionSets, name)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	if !matchesIfMatch(ifMatch, set.Version) {
+		h.emit(r, ActionPermissionSetUpdated, name, auth.ErrVersionConflict)
+		h.handleServiceError(w, r, auth.ErrVersionConflict)
+		return
+	}
+
+	set.Description = req.Description
+	set.Permissions = req.Permissions
+
+	roles, err := service.UpdatePermissionSet(r.Context(), h.tx, h.permissionSets, h.roleStore, set, middleware.GetUserID(r.Context()))
+	h.emit(r, ActionPermissionSetUpdated, name, err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, set.Version)
+	httpx.WriteJSON(w, http.StatusOK, PermissionSetResponse{PermissionSet: set, UpdatedRoles: roles})
+}
+
+// handleDeletePermissionSet serves DELETE /permission-sets/{name}. Sets
+// still referenced by roles cannot be deleted and answer 409.
+func (h *AuthZHandler) handleDeletePermissionSet(w http.ResponseWriter, r *http.Request) {
+	name := auth.NormalizePermissionSetName(chi.URLParam(r, "name"))
+
+	err := service.DeletePermissionSet(r.Context(), h.permissionSets, h.roleStore, name)
+	h.emit(r, ActionPermissionSetDeleted, name, err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// expandRole refreshes the permission set expansion of role. Without
+// WithPermissionSets a role naming sets fails with auth.ErrPermissionSetNotFound.
+func (h *AuthZHandler) expandRole(r *http.Request, role *auth.Role) error {
+	if h.permissionSets == nil && len(role.PermissionSets) > 0 {
+		return auth.ErrPermissionSetNotFound
+	}
+	return service.ExpandRole(r.Context(), h.permissionSets, role)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/go-chi/chi/v5"
+)
+
+func setupPermissionSets(t *testing.T, audit *recordingAudit) (chi.Router, *fake.GrantStore) {
+	t.Helper()
+
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+	h := NewAuthZHandler(roles, grants, WithPermissionSets(fake.NewPermissionSetStore()), WithTransactor(fake.NewTransactor()), WithAudit(audit))
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	return r, grants
+}
+
+func sendJSON(r http.Handler, method, path, ifMatch string, body any) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPermissionSetRoutes(t *testing.T) {
+	audit := &recordingAudit{}
+	r, grants := setupPermissionSets(t, audit)
+
+	w := postJSON(t, r, "/permission-sets", CreatePermissionSetRequest{Name: "Content-Editor", Permissions: []string{"content:read", "content:write"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create set = %d: %s", w.Code, w.Body)
+	}
+	if w := postJSON(t, r, "/permission-sets", CreatePermissionSetRequest{Name: "content-editor"}); w.Code != http.StatusConflict {
+		t.Errorf("duplicate set = %d, want 409", w.Code)
+	}
+
+	w = postJSON(t, r, "/roles", CreateRoleRequest{Name: "writer", Permissions: []string{"profile:edit"}, PermissionSets: []string{"content-editor"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create role = %d: %s", w.Code, w.Body)
+	}
+	var created RoleResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	if len(created.Role.SetPermissions) != 2 {
+		t.Errorf("role expansion = %v, want the set's permissions", created.Role.SetPermissions)
+	}
+	if w := postJSON(t, r, "/roles", CreateRoleRequest{Name: "ghost", PermissionSets: []string{"missing"}}); w.Code != http.StatusNotFound {
+		t.Errorf("role with unknown set = %d, want 404", w.Code)
+	}
+	grants.Create(t.Context(), &auth.Grant{ID: auth.NewGrantID(), Username: "ann", RoleID: created.Role.ID})
+
+	if w := sendJSON(r, http.MethodPut, "/permission-sets/content-editor", "", UpdatePermissionSetRequest{}); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("update without If-Match = %d, want 428", w.Code)
+	}
+	w = sendJSON(r, http.MethodPut, "/permission-sets/content-editor", `"1"`, UpdatePermissionSetRequest{Permissions: []string{"content:read", "content:publish"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("update set = %d: %s", w.Code, w.Body)
+	}
+	var updated PermissionSetResponse
+	json.NewDecoder(w.Body).Decode(&updated)
+	if len(updated.UpdatedRoles) != 1 || !updated.UpdatedRoles[0].HasPermission("content:publish") {
+		t.Errorf("updated roles = %+v", updated.UpdatedRoles)
+	}
+	if w := sendJSON(r, http.MethodPut, "/permission-sets/content-editor", `"1"`, UpdatePermissionSetRequest{}); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale update = %d, want 412", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/users/ann/permissions/content:publish", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var check PermissionCheckResponse
+	json.NewDecoder(w.Body).Decode(&check)
+	if !check.HasPermission {
+		t.Errorf("check content:publish = %s, want granted through the updated set", w.Body)
+	}
+
+	if w := sendJSON(r, http.MethodDelete, "/permission-sets/content-editor", "", nil); w.Code != http.StatusConflict {
+		t.Errorf("delete referenced set = %d, want 409", w.Code)
+	}
+	w = sendJSON(r, http.MethodPatch, "/roles/"+created.Role.ID.String(), "", map[string]any{"permission_sets": nil})
+	if w.Code != http.StatusOK {
+		t.Fatalf("patch role = %d: %s", w.Code, w.Body)
+	}
+	var patched RoleResponse
+	json.NewDecoder(w.Body).Decode(&patched)
+	if patched.Role.PermissionSets != nil || patched.Role.SetPermissions != nil {
+		t.Errorf("patched role = %+v, want no sets", patched.Role)
+	}
+	if w := sendJSON(r, http.MethodDelete, "/permission-sets/content-editor", "", nil); w.Code != http.StatusNoContent {
+		t.Errorf("delete set = %d, want 204", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/permission-sets", nil))
+	var list ListPermissionSetsResponse
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list.PermissionSets) != 0 {
+		t.Errorf("list after delete = %+v", list.PermissionSets)
+	}
+
+	if audit.events[0].Action != ActionPermissionSetCreated || audit.events[0].Subject != "content-editor" {
+		t.Errorf("first event = %+v", audit.events[0])
+	}
+}
+
+func TestRolePermissionSetsDisabled(t *testing.T) {
+	r := chi.NewRouter()
+	setupAuthZHandler().RegisterRoutes(r)
+
+	if w := postJSON(t, r, "/roles", CreateRoleRequest{Name: "writer", PermissionSets: []string{"content-editor"}}); w.Code != http.StatusNotFound {
+		t.Errorf("role with sets = %d, want 404", w.Code)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/permission-sets", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /permission-sets = %d, want 404", w.Code)
+	}
+}
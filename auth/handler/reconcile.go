@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// DesiredGrant is one entry of the desired state. The role is given either
+// by ID or by name.
+type DesiredGrant struct {
+	Username string `json:"username"`
+	RoleID   string `json:"role_id,omitempty"`
+	Role     string `json:"role,omitempty"`
+}
+
+// ReconcileGrantsRequest is the JSON body of POST /grants:reconcile.
+// Roles lists additional roles (by ID or name) whose grants are reconciled
+// even if no desired entry references them, so their last members are revoked.
+type ReconcileGrantsRequest struct {
+	Grants     []DesiredGrant `json:"grants"`
+	Roles      []string       `json:"roles"`
+	DryRun     bool           `json:"dry_run"`
+	BatchSize  int            `json:"batch_size"`
+	AssignedBy string         `json:"assigned_by"`
+}
+
+type ReconcileSummary struct {
+	DryRun   bool `json:"dry_run"`
+	Assigned int  `json:"assigned"`
+	Revoked  int  `json:"revoked"`
+	Failed   int  `json:"failed"`
+	Batches  int  `json:"batches"`
+}
+
+type ReconcileGrantsResponse struct {
+	Plan     *auth.GrantPlan     `json:"plan"`
+	Summary  ReconcileSummary    `json:"summary"`
+	Failures []auth.GrantFailure `json:"failures"`
+}
+
+// ReconcileEvent is one line of a streamed reconciliation: the plan first,
+// then one line per applied batch, then the summary.
+type ReconcileEvent struct {
+	Type    string                 `json:"type"`
+	Plan    *auth.GrantPlan        `json:"plan,omitempty"`
+	Batch   *auth.GrantBatchResult `json:"batch,omitempty"`
+	Summary *ReconcileSummary      `json:"summary,omitempty"`
+}
+
+// handleReconcileGrants serves POST /grants:reconcile.
+//
+// The desired state is either a ReconcileGrantsRequest JSON body or, with
+// Content-Type application/x-ndjson, one DesiredGrant per line with the other
+// fields given as dry_run, batch_size, roles (comma-separated) and
+// assigned_by query parameters. Clients sending Accept application/x-ndjson
+// receive ReconcileEvent lines as each batch is applied; others receive a
+// single ReconcileGrantsResponse.
+func (h *AuthZHandler) handleReconcileGrants(w http.ResponseWriter, r *http.Request) {
+	req, err := decodeReconcileRequest(r)
+	if err != nil {
+		h.writeAPIError(w, r, httpx.AsError(err))
+		return
+	}
+
+	ctx, assignedBy, ok := h.adminContext(w, r, ActionRoleAssigned, "", req.AssignedBy)
+	if !ok {
+		return
+	}
+	resolve := h.roleResolver(ctx)
+
+	desired := make([]auth.GrantPair, 0, len(req.Grants))
+	for _, g := range req.Grants {
+		if g.Username == "" {
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_USERNAME", "Username is required")
+			return
+		}
+		roleID, err := resolve(g.RoleID, g.Role)
+		if err != nil {
+			h.handleServiceError(w, r, err)
+			return
+		}
+		desired = append(desired, auth.GrantPair{Username: g.Username, RoleID: roleID})
+	}
+
+	scope := make([]auth.RoleID, 0, len(req.Roles))
+	for _, ref := range req.Roles {
+		roleID, err := resolve(ref, ref)
+		if err != nil {
+			h.handleServiceError(w, r, err)
+			return
+		}
+		scope = append(scope, roleID)
+	}
+
+	plan, err := service.PlanGrants(ctx, h.grantStore, desired, scope)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	summary := ReconcileSummary{DryRun: req.DryRun}
+	stream := acceptsNDJSON(r)
+
+	var flusher http.Flusher
+	if stream {
+		w.Header().Set("Content-Type", ndjsonContentType)
+		w.WriteHeader(http.StatusOK)
+		flusher, _ = w.(http.Flusher)
+		writeNDJSON(w, ReconcileEvent{Type: "plan", Plan: plan})
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	failures := []auth.GrantFailure{}
+	if !req.DryRun {
+		err = service.ApplyGrantPlan(ctx, h.grantStore, plan, assignedBy, req.BatchSize, func(b auth.GrantBatchResult) error {
+			for _, p := range b.Assigned {
+				h.emit(r, ActionRoleAssigned, p.Username, nil)
+			}
+			for _, p := range b.Revoked {
+				h.emit(r, ActionRoleRevoked, p.Username, nil)
+			}
+			for _, f := range b.Failed {
+				action := ActionRoleAssigned
+				if f.Op == service.GrantOpRevoke {
+					action = ActionRoleRevoked
+				}
+				h.emit(r, action, f.Username, errors.New(f.Error))
+			}
+
+			summary.Batches++
+			summary.Assigned += len(b.Assigned)
+			summary.Revoked += len(b.Revoked)
+			summary.Failed += len(b.Failed)
+			failures = append(failures, b.Failed...)
+
+			if stream {
+				if err := writeNDJSON(w, ReconcileEvent{Type: "batch", Batch: &b}); err != nil {
+					return err
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			if !stream {
+				h.handleServiceError(w, r, err)
+			}
+			return
+		}
+	}
+
+	if stream {
+		writeNDJSON(w, ReconcileEvent{Type: "summary", Summary: &summary})
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, ReconcileGrantsResponse{Plan: plan, Summary: summary, Failures: failures})
+}
+
+// roleResolver returns a function that maps a role ID or, failing that, a
+// role name to a role ID, caching lookups for the duration of a request.
+func (h *AuthZHandler) roleResolver(ctx context.Context) func(id, name string) (auth.RoleID, error) {
+	byName := make(map[string]auth.RoleID)
+
+	return func(id, name string) (auth.RoleID, error) {
+		if id != "" {
+			if roleID, err := auth.ParseRoleID(id); err == nil {
+				return roleID, nil
+			}
+		}
+		if name == "" {
+			return auth.RoleID{}, auth.ErrRoleNotFound
+		}
+		if roleID, ok := byName[name]; ok {
+			return roleID, nil
+		}
+
+		role, err := service.GetRoleByName(ctx, h.roleStore, name)
+		if err != nil {
+			return auth.RoleID{}, err
+		}
+		byName[name] = role.ID
+		return role.ID, nil
+	}
+}
+
+// decodeReconcileRequest reads a JSON or NDJSON reconcile request. Errors
+// are *httpx.Error values ready to write.
+func decodeReconcileRequest(r *http.Request) (ReconcileGrantsRequest, error) {
+	var req ReconcileGrantsRequest
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != ndjsonContentType {
+		err := httpx.Bind(r, &req)
+		return req, err
+	}
+
+	q := r.URL.Query()
+	if v := q.Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			return req, httpx.BadRequest(httpx.CodeInvalidRequest, "dry_run must be a boolean")
+		}
+		req.DryRun = dryRun
+	}
+	if v := q.Get("batch_size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return req, httpx.BadRequest(httpx.CodeInvalidRequest, "batch_size must be an integer")
+		}
+		req.BatchSize = size
+	}
+	if v := q.Get("roles"); v != "" {
+		req.Roles = strings.Split(v, ",")
+	}
+	req.AssignedBy = q.Get("assigned_by")
+
+	dec := json.NewDecoder(r.Body)
+	for line := 1; ; line++ {
+		var g DesiredGrant
+		if err := dec.Decode(&g); err == io.EOF {
+			break
+		} else if err != nil {
+			return req, httpx.BadRequest(httpx.CodeInvalidRequest, fmt.Sprintf("Invalid grant on line %d", line))
+		}
+		req.Grants = append(req.Grants, g)
+	}
+	return req, nil
+}
+
+// writeNDJSON writes v as one line of an NDJSON stream, encoded like the
+// other responses.
+func writeNDJSON(w io.Writer, v any) error {
+	data, err := httpx.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func acceptsNDJSON(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil && mediaType == ndjsonContentType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+func setupReconcile(t *testing.T) (http.Handler, *AuthZHandler, map[string]auth.RoleID) {
+	t.Helper()
+
+	h := setupAuthZHandler()
+	ctx := context.Background()
+
+	roles := map[string]auth.RoleID{}
+	for _, name := range []string{"admins", "editors", "viewers"} {
+		role := auth.NewRole()
+		role.Name = name
+		role.EnsureID()
+		if err := h.roleStore.Create(ctx, role); err != nil {
+			t.Fatalf("cannot create role: %v", err)
+		}
+		roles[name] = role.ID
+	}
+
+	h.grantStore.Create(ctx, auth.NewGrant("alice", roles["admins"], "seed"))
+	h.grantStore.Create(ctx, auth.NewGrant("bob", roles["admins"], "seed"))
+	h.grantStore.Create(ctx, auth.NewGrant("carol", roles["viewers"], "seed"))
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	return r, h, roles
+}
+
+func userRoles(t *testing.T, h *AuthZHandler, roleID auth.RoleID) map[string]bool {
+	t.Helper()
+
+	grants, err := h.grantStore.GetRoleGrants(context.Background(), roleID)
+	if err != nil {
+		t.Fatalf("GetRoleGrants() error = %v", err)
+	}
+	users := map[string]bool{}
+	for _, g := range grants {
+		users[g.Username] = true
+	}
+	return users
+}
+
+func TestHandleReconcileGrants(t *testing.T) {
+	r, h, roles := setupReconcile(t)
+
+	body := `{
+		"grants": [
+			{"username": "alice", "role": "admins"},
+			{"username": "alice", "role_id": "` + roles["editors"].String() + `"},
+			{"username": "dave", "role": "editors"}
+		],
+		"roles": ["viewers"],
+		"batch_size": 2,
+		"assigned_by": "idp-sync"
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/grants:reconcile", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var resp ReconcileGrantsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("cannot decode response: %v", err)
+	}
+
+	want := ReconcileSummary{Assigned: 2, Revoked: 2, Batches: 2}
+	if resp.Summary != want {
+		t.Errorf("summary = %+v, want %+v", resp.Summary, want)
+	}
+
+	if got := userRoles(t, h, roles["admins"]); len(got) != 1 || !got["alice"] {
+		t.Errorf("admins = %v, want only alice", got)
+	}
+	if got := userRoles(t, h, roles["editors"]); len(got) != 2 || !got["alice"] || !got["dave"] {
+		t.Errorf("editors = %v, want alice and dave", got)
+	}
+	if got := userRoles(t, h, roles["viewers"]); len(got) != 0 {
+		t.Errorf("viewers = %v, want none", got)
+	}
+}
+
+func TestHandleReconcileGrantsDryRun(t *testing.T) {
+	r, h, roles := setupReconcile(t)
+
+	body := `{"grants": [{"username": "alice", "role": "admins"}], "dry_run": true}`
+	req := httptest.NewRequest(http.MethodPost, "/grants:reconcile", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var resp ReconcileGrantsResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+
+	if !resp.Summary.DryRun || resp.Summary.Revoked != 0 {
+		t.Errorf("summary = %+v, want dry run with nothing applied", resp.Summary)
+	}
+	if len(resp.Plan.Revoke) != 1 || resp.Plan.Revoke[0].Username != "bob" {
+		t.Errorf("plan = %+v, want bob revoked", resp.Plan)
+	}
+	if got := userRoles(t, h, roles["admins"]); !got["bob"] {
+		t.Error("dry run should not revoke grants")
+	}
+}
+
+func TestHandleReconcileGrantsStreaming(t *testing.T) {
+	r, h, roles := setupReconcile(t)
+
+	body := `{"username":"alice","role":"admins"}
+{"username":"erin","role":"admins"}
+`
+	req := httptest.NewRequest(http.MethodPost, "/grants:reconcile?batch_size=1&assigned_by=idp", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Accept", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+	}
+
+	var types []string
+	var summary *ReconcileSummary
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var ev ReconcileEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("cannot decode line %q: %v", scanner.Text(), err)
+		}
+		types = append(types, ev.Type)
+		if ev.Summary != nil {
+			summary = ev.Summary
+		}
+	}
+
+	if strings.Join(types, ",") != "plan,batch,batch,summary" {
+		t.Errorf("event types = %v, want plan, two batches and summary", types)
+	}
+	if summary == nil || summary.Assigned != 1 || summary.Revoked != 1 {
+		t.Errorf("summary = %+v, want one assigned and one revoked", summary)
+	}
+
+	if got := userRoles(t, h, roles["admins"]); len(got) != 2 || !got["alice"] || !got["erin"] {
+		t.Errorf("admins = %v, want alice and erin", got)
+	}
+}
+
+func TestHandleReconcileGrantsErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		target      string
+		body        string
+		wantStatus  int
+		wantCode    string
+	}{
+		{"invalid body", "application/json", "/grants:reconcile", `{`, http.StatusBadRequest, "INVALID_REQUEST"},
+		{"missing username", "application/json", "/grants:reconcile", `{"grants":[{"role":"admins"}]}`, http.StatusBadRequest, "INVALID_USERNAME"},
+		{"unknown role", "application/json", "/grants:reconcile", `{"grants":[{"username":"alice","role":"nope"}]}`, http.StatusNotFound, "ROLE_NOT_FOUND"},
+		{"missing role", "application/json", "/grants:reconcile", `{"grants":[{"username":"alice"}]}`, http.StatusNotFound, "ROLE_NOT_FOUND"},
+		{"unknown scope role", "application/json", "/grants:reconcile", `{"roles":["nope"]}`, http.StatusNotFound, "ROLE_NOT_FOUND"},
+		{"bad ndjson line", "application/x-ndjson", "/grants:reconcile", "{\"username\":\"alice\",\"role\":\"admins\"}\n{oops\n", http.StatusBadRequest, "INVALID_REQUEST"},
+		{"bad dry_run", "application/x-ndjson", "/grants:reconcile?dry_run=maybe", "", http.StatusBadRequest, "INVALID_REQUEST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, _ := setupReconcile(t)
+
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var resp ErrorResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", resp.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -1,59 +1,178 @@
 package handler
 
 import (
-	"encoding/json"
 	"errors"
 	"net/http"
 
 	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/errreport"
+	"github.com/aquamarinepk/aqm/httpx"
 )
 
-type ErrorResponse struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+// ErrorResponse is the JSON error body written by the handlers.
+type ErrorResponse = httpx.ErrorResponse
+
+// ProblemResponse is an RFC 9457 problem details body.
+type ProblemResponse = httpx.Problem
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	httpx.WriteError(w, r, httpx.NewError(status, code, message))
+}
+
+// JSONErrorFormat writes resp as a JSON body.
+func JSONErrorFormat(w http.ResponseWriter, status int, resp ErrorResponse) {
+	httpx.WriteErrorJSON(w, status, resp)
 }
 
-func writeJSON(w http.ResponseWriter, status int, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
+// ProblemErrorFormat writes resp as application/problem+json.
+func ProblemErrorFormat(w http.ResponseWriter, status int, resp ErrorResponse) {
+	httpx.WriteProblem(w, status, resp)
 }
 
-func writeError(w http.ResponseWriter, status int, code, message string) {
-	writeJSON(w, status, ErrorResponse{Code: code, Message: message})
+// handleServiceError writes the API error of err. Errors answered with a 5xx
+// are unexpected and reported to errreport.Default.
+func handleServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	e := serviceError(err)
+	if e.Status >= http.StatusInternalServerError && !errors.Is(err, auth.ErrServiceUnavailable) {
+		errreport.Capture(r.Context(), err)
+	}
+	httpx.WriteError(w, r, e)
 }
 
-func handleServiceError(w http.ResponseWriter, err error) {
+// serviceError maps a service error to an API error. Retry hints are kept
+// only on the throttling errors that advertise them.
+func serviceError(err error) *httpx.Error {
+	if retryAfter, ok := auth.RetryAfter(err); ok {
+		switch {
+		case errors.Is(err, auth.ErrAccountLocked):
+			return httpx.NewError(http.StatusTooManyRequests, "ACCOUNT_LOCKED", err.Error()).WithRetryAfter(retryAfter)
+		case errors.Is(err, auth.ErrTooManyAttempts):
+			return httpx.NewError(http.StatusTooManyRequests, "TOO_MANY_ATTEMPTS", err.Error()).WithRetryAfter(retryAfter)
+		case errors.Is(err, auth.ErrServiceUnavailable):
+			return httpx.NewError(http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", err.Error()).WithRetryAfter(retryAfter)
+		}
+	}
+
+	var status int
+	var code string
 	switch {
 	case errors.Is(err, auth.ErrUserNotFound):
-		writeError(w, http.StatusNotFound, "USER_NOT_FOUND", err.Error())
+		status, code = http.StatusNotFound, "USER_NOT_FOUND"
 	case errors.Is(err, auth.ErrUserAlreadyExists):
-		writeError(w, http.StatusConflict, "USER_ALREADY_EXISTS", err.Error())
+		status, code = http.StatusConflict, "USER_ALREADY_EXISTS"
 	case errors.Is(err, auth.ErrUsernameExists):
-		writeError(w, http.StatusConflict, "USERNAME_EXISTS", err.Error())
+		status, code = http.StatusConflict, "USERNAME_EXISTS"
 	case errors.Is(err, auth.ErrInvalidEmail):
-		writeError(w, http.StatusBadRequest, "INVALID_EMAIL", err.Error())
+		status, code = http.StatusBadRequest, "INVALID_EMAIL"
 	case errors.Is(err, auth.ErrInvalidPassword):
-		writeError(w, http.StatusBadRequest, "INVALID_PASSWORD", err.Error())
+		status, code = http.StatusBadRequest, "INVALID_PASSWORD"
 	case errors.Is(err, auth.ErrInvalidUsername):
-		writeError(w, http.StatusBadRequest, "INVALID_USERNAME", err.Error())
+		status, code = http.StatusBadRequest, "INVALID_USERNAME"
 	case errors.Is(err, auth.ErrInvalidDisplayName):
-		writeError(w, http.StatusBadRequest, "INVALID_DISPLAY_NAME", err.Error())
+		status, code = http.StatusBadRequest, "INVALID_DISPLAY_NAME"
 	case errors.Is(err, auth.ErrInvalidCredentials):
-		writeError(w, http.StatusUnauthorized, "INVALID_CREDENTIALS", err.Error())
+		status, code = http.StatusUnauthorized, "INVALID_CREDENTIALS"
+	case errors.Is(err, auth.ErrInvalidMagicLink):
+		status, code = http.StatusUnauthorized, "INVALID_MAGIC_LINK"
 	case errors.Is(err, auth.ErrInactiveAccount):
-		writeError(w, http.StatusForbidden, "INACTIVE_ACCOUNT", err.Error())
+		status, code = http.StatusForbidden, "INACTIVE_ACCOUNT"
+	case errors.Is(err, auth.ErrDeviceNotFound):
+		status, code = http.StatusNotFound, "DEVICE_NOT_FOUND"
+	case errors.Is(err, auth.ErrInvalidDevice):
+		status, code = http.StatusBadRequest, "INVALID_DEVICE"
 	case errors.Is(err, auth.ErrRoleNotFound):
-		writeError(w, http.StatusNotFound, "ROLE_NOT_FOUND", err.Error())
+		status, code = http.StatusNotFound, "ROLE_NOT_FOUND"
 	case errors.Is(err, auth.ErrRoleAlreadyExists):
-		writeError(w, http.StatusConflict, "ROLE_ALREADY_EXISTS", err.Error())
+		status, code = http.StatusConflict, "ROLE_ALREADY_EXISTS"
 	case errors.Is(err, auth.ErrInvalidRoleName):
-		writeError(w, http.StatusBadRequest, "INVALID_ROLE_NAME", err.Error())
+		status, code = http.StatusBadRequest, "INVALID_ROLE_NAME"
 	case errors.Is(err, auth.ErrGrantNotFound):
-		writeError(w, http.StatusNotFound, "GRANT_NOT_FOUND", err.Error())
+		status, code = http.StatusNotFound, "GRANT_NOT_FOUND"
 	case errors.Is(err, auth.ErrGrantAlreadyExists):
-		writeError(w, http.StatusConflict, "GRANT_ALREADY_EXISTS", err.Error())
+		status, code = http.StatusConflict, "GRANT_ALREADY_EXISTS"
+	case errors.Is(err, auth.ErrIdentityNotFound):
+		status, code = http.StatusNotFound, "IDENTITY_NOT_FOUND"
+	case errors.Is(err, auth.ErrIdentityAlreadyExists):
+		status, code = http.StatusConflict, "IDENTITY_ALREADY_EXISTS"
+	case errors.Is(err, auth.ErrInvalidIdentity):
+		status, code = http.StatusBadRequest, "INVALID_IDENTITY"
+	case errors.Is(err, auth.ErrUnverifiedEmail):
+		status, code = http.StatusConflict, "UNVERIFIED_EMAIL"
+	case errors.Is(err, auth.ErrUnknownPermission):
+		status, code = http.StatusBadRequest, "UNKNOWN_PERMISSION"
+	case errors.Is(err, auth.ErrInvalidUserQuery):
+		status, code = http.StatusBadRequest, "INVALID_QUERY"
+	case errors.Is(err, auth.ErrInvalidAttribute):
+		status, code = http.StatusBadRequest, "INVALID_ATTRIBUTE"
+	case errors.Is(err, auth.ErrConsentNotFound):
+		status, code = http.StatusNotFound, "CONSENT_NOT_FOUND"
+	case errors.Is(err, auth.ErrInvalidConsent):
+		status, code = http.StatusBadRequest, "INVALID_CONSENT"
+	case errors.Is(err, auth.ErrUnknownPolicy):
+		status, code = http.StatusNotFound, "POLICY_NOT_FOUND"
+	case errors.Is(err, auth.ErrAlreadyBootstrapped):
+		status, code = http.StatusGone, "ALREADY_BOOTSTRAPPED"
+	case errors.Is(err, auth.ErrInvalidBootstrapToken):
+		status, code = http.StatusUnauthorized, "INVALID_BOOTSTRAP_TOKEN"
+	case errors.Is(err, auth.ErrAccountLocked):
+		status, code = http.StatusTooManyRequests, "ACCOUNT_LOCKED"
+	case errors.Is(err, auth.ErrTooManyAttempts):
+		status, code = http.StatusTooManyRequests, "TOO_MANY_ATTEMPTS"
+	case errors.Is(err, auth.ErrServiceUnavailable):
+		status, code = http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE"
+	case errors.Is(err, auth.ErrPermissionDenied):
+		status, code = http.StatusForbidden, "PERMISSION_DENIED"
+	case errors.Is(err, auth.ErrImpersonationNotAllowed):
+		status, code = http.StatusForbidden, "IMPERSONATION_NOT_ALLOWED"
+	case errors.Is(err, auth.ErrServiceAccountNotFound):
+		status, code = http.StatusNotFound, "SERVICE_ACCOUNT_NOT_FOUND"
+	case errors.Is(err, auth.ErrServiceAccountExists):
+		status, code = http.StatusConflict, "SERVICE_ACCOUNT_EXISTS"
+	case errors.Is(err, auth.ErrInvalidServiceAccountName):
+		status, code = http.StatusBadRequest, "INVALID_SERVICE_ACCOUNT_NAME"
+	case errors.Is(err, auth.ErrPermissionSetNotFound):
+		status, code = http.StatusNotFound, "PERMISSION_SET_NOT_FOUND"
+	case errors.Is(err, auth.ErrPermissionSetExists):
+		status, code = http.StatusConflict, "PERMISSION_SET_EXISTS"
+	case errors.Is(err, auth.ErrInvalidPermissionSetName):
+		status, code = http.StatusBadRequest, "INVALID_PERMISSION_SET_NAME"
+	case errors.Is(err, auth.ErrPermissionSetInUse):
+		status, code = http.StatusConflict, "PERMISSION_SET_IN_USE"
+	case errors.Is(err, auth.ErrGrantRequestNotFound):
+		status, code = http.StatusNotFound, "GRANT_REQUEST_NOT_FOUND"
+	case errors.Is(err, auth.ErrGrantRequestExists):
+		status, code = http.StatusConflict, "GRANT_REQUEST_EXISTS"
+	case errors.Is(err, auth.ErrGrantRequestDecided):
+		status, code = http.StatusConflict, "GRANT_REQUEST_DECIDED"
+	case errors.Is(err, auth.ErrInvalidGrantRequest):
+		status, code = http.StatusBadRequest, "INVALID_GRANT_REQUEST"
+	case errors.Is(err, auth.ErrSelfApproval):
+		status, code = http.StatusForbidden, "SELF_APPROVAL_NOT_ALLOWED"
+	case errors.Is(err, auth.ErrRoleOutOfScope):
+		status, code = http.StatusForbidden, "ROLE_OUT_OF_SCOPE"
+	case errors.Is(err, auth.ErrEmailInUse):
+		status, code = http.StatusConflict, "EMAIL_IN_USE"
+	case errors.Is(err, auth.ErrUsernameReserved):
+		status, code = http.StatusConflict, "USERNAME_RESERVED"
+	case errors.Is(err, auth.ErrInvalidEmailChange):
+		status, code = http.StatusBadRequest, "INVALID_EMAIL_CHANGE"
+	case errors.Is(err, auth.ErrIncorrectPassword):
+		status, code = http.StatusForbidden, "INCORRECT_PASSWORD"
+	case errors.Is(err, auth.ErrSettingsNotFound):
+		status, code = http.StatusNotFound, "SETTINGS_NOT_FOUND"
+	case errors.Is(err, auth.ErrInvalidSettings):
+		status, code = http.StatusBadRequest, "INVALID_SETTINGS"
+	case errors.Is(err, auth.ErrUserStatusConflict):
+		status, code = http.StatusConflict, "USER_STATUS_CONFLICT"
+	case errors.Is(err, auth.ErrInvalidScope):
+		status, code = http.StatusBadRequest, "INVALID_SCOPE"
+	case errors.Is(err, auth.ErrEmailDomainNotAllowed):
+		status, code = http.StatusForbidden, "EMAIL_DOMAIN_NOT_ALLOWED"
+	case errors.Is(err, auth.ErrVersionConflict):
+		status, code = http.StatusPreconditionFailed, "VERSION_CONFLICT"
 	default:
-		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return httpx.AsError(err)
 	}
+
+	return httpx.NewError(status, code, err.Error()).Wrap(err)
 }
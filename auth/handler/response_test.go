@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/errreport"
+)
+
+func TestHandleServiceErrorRetryAfter(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantStatus     int
+		wantCode       string
+		wantRetryAfter string
+		wantSeconds    int
+	}{
+		{
+			name:           "locked with hint",
+			err:            fmt.Errorf("signin: %w", auth.WithRetryAfter(auth.ErrAccountLocked, 5*time.Minute)),
+			wantStatus:     http.StatusTooManyRequests,
+			wantCode:       "ACCOUNT_LOCKED",
+			wantRetryAfter: "300",
+			wantSeconds:    300,
+		},
+		{
+			name:           "too many attempts with hint",
+			err:            auth.WithRetryAfter(auth.ErrTooManyAttempts, 1500*time.Millisecond),
+			wantStatus:     http.StatusTooManyRequests,
+			wantCode:       "TOO_MANY_ATTEMPTS",
+			wantRetryAfter: "2",
+			wantSeconds:    2,
+		},
+		{
+			name:           "unavailable with hint",
+			err:            auth.WithRetryAfter(auth.ErrServiceUnavailable, time.Minute),
+			wantStatus:     http.StatusServiceUnavailable,
+			wantCode:       "SERVICE_UNAVAILABLE",
+			wantRetryAfter: "60",
+			wantSeconds:    60,
+		},
+		{
+			name:       "locked without hint",
+			err:        auth.ErrAccountLocked,
+			wantStatus: http.StatusTooManyRequests,
+			wantCode:   "ACCOUNT_LOCKED",
+		},
+		{
+			name:       "hint on unrelated error is ignored",
+			err:        auth.WithRetryAfter(auth.ErrUserNotFound, time.Minute),
+			wantStatus: http.StatusNotFound,
+			wantCode:   "USER_NOT_FOUND",
+		},
+		{
+			name:       "unknown error",
+			err:        errors.New("boom"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "INTERNAL_ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handleServiceError(rec, httptest.NewRequest(http.MethodGet, "/", nil), tt.err)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+
+			var resp ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("cannot decode response: %v", err)
+			}
+			if resp.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", resp.Code, tt.wantCode)
+			}
+			if resp.RetryAfterSeconds != tt.wantSeconds {
+				t.Errorf("retry_after_seconds = %d, want %d", resp.RetryAfterSeconds, tt.wantSeconds)
+			}
+		})
+	}
+}
+
+func TestHandleServiceErrorReportsUnexpected(t *testing.T) {
+	var reported []error
+	errreport.SetDefault(errreport.ReporterFunc(func(_ context.Context, err error) {
+		reported = append(reported, err)
+	}))
+	defer errreport.SetDefault(nil)
+
+	boom := errors.New("boom")
+	for _, err := range []error{boom, auth.ErrUserNotFound, auth.WithRetryAfter(auth.ErrServiceUnavailable, time.Minute)} {
+		handleServiceError(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), err)
+	}
+
+	if len(reported) != 1 || reported[0] != boom {
+		t.Errorf("reported = %v, want only the unexpected error", reported)
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+)
+
+// DefaultMFAPendingTTL is the lifetime of the MFA-pending token returned
+// by sign-ins that need a second factor.
+const DefaultMFAPendingTTL = 5 * time.Minute
+
+// assessSignIn runs the risk evaluator on a sign-in whose credentials
+// verified and reports whether it asks for a second factor. fingerprint is
+// the device signed in from, empty when none was reported.
+func (h *AuthNHandler) assessSignIn(r *http.Request, user *auth.User, fingerprint string, trusted bool) (bool, error) {
+	attempt := auth.SignInAttempt{
+		UserID:            user.ID,
+		IP:                remoteIP(r),
+		UserAgent:         r.UserAgent(),
+		DeviceFingerprint: fingerprint,
+		TrustedDevice:     trusted,
+		At:                h.now(),
+	}
+	if loc := clientLocation(r); loc != nil {
+		attempt.Country, attempt.City = loc.CountryCode, loc.City
+	}
+
+	assessment, err := h.risk.Evaluate(r.Context(), attempt)
+	if err != nil {
+		return false, fmt.Errorf("assess sign-in: %w", err)
+	}
+	if !assessment.Decision.Exceeds(auth.RiskAllow) {
+		return false, nil
+	}
+
+	h.emitWith(r, ActionSignInRisk, user.ID.String(), map[string]string{
+		"risk_decision": string(assessment.Decision),
+		"risk_reasons":  strings.Join(assessment.Reasons, ","),
+	}, nil)
+	return assessment.Decision == auth.RiskStepUp, nil
+}
+
+// mfaPendingToken returns the token handed out instead of the session token
+// of a sign-in that needs a second factor: an MFA-pending token valid for
+// DefaultMFAPendingTTL, which only middleware.BearerMFAPending accepts, or
+// none when the token generator cannot issue them.
+func (h *AuthNHandler) mfaPendingToken(userID auth.UserID) (string, error) {
+	gen, ok := h.tokenGen.(service.MFATokenGenerator)
+	if !ok {
+		return "", nil
+	}
+	return gen.GenerateMFAPendingToken(userID, DefaultMFAPendingTTL)
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	mailfake "github.com/aquamarinepk/aqm/mail/fake"
+	"github.com/go-chi/chi/v5"
+)
+
+type stubRisk struct {
+	assessment auth.RiskAssessment
+	err        error
+	attempts   []auth.SignInAttempt
+}
+
+func (s *stubRisk) Evaluate(_ context.Context, attempt auth.SignInAttempt) (auth.RiskAssessment, error) {
+	s.attempts = append(s.attempts, attempt)
+	return s.assessment, s.err
+}
+
+func TestSignInRisk(t *testing.T) {
+	risk := &stubRisk{}
+	audit := &recordingAudit{}
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithRiskEvaluator(risk), WithAudit(audit),
+	).RegisterRoutes(r)
+	postJSON(t, r, "/auth/signup", SignUpRequest{Email: "ann@example.com", Password: "Password123!", Username: "ann", DisplayName: "Ann"})
+
+	signIn := func() (int, SignInResponse) {
+		w := postJSON(t, r, "/auth/signin", SignInRequest{Email: "ann@example.com", Password: "Password123!"})
+		var resp SignInResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+	riskEvents := func() []Event {
+		var events []Event
+		for _, e := range audit.events {
+			if e.Action == ActionSignInRisk {
+				events = append(events, e)
+			}
+		}
+		return events
+	}
+
+	risk.assessment = auth.RiskAssessment{Decision: auth.RiskAllow}
+	if code, resp := signIn(); code != http.StatusOK || resp.MFARequired {
+		t.Errorf("allowed sign-in = %d, mfa_required %v", code, resp.MFARequired)
+	}
+	if len(riskEvents()) != 0 {
+		t.Error("allowed sign-in emitted a risk event")
+	}
+	if got := risk.attempts[0]; got.IP == "" || got.At.IsZero() || got.UserID.IsZero() {
+		t.Errorf("attempt = %+v", got)
+	}
+
+	risk.assessment = auth.RiskAssessment{Decision: auth.RiskStepUp, Reasons: []string{auth.RiskReasonNewCountry, auth.RiskReasonVelocity}}
+	code, resp := signIn()
+	if code != http.StatusOK || !resp.MFARequired || resp.Token != "token-"+resp.User.ID.String()+"-mfa-pending" {
+		t.Errorf("step-up sign-in = %d, %+v, want an MFA-pending token", code, resp)
+	}
+	events := riskEvents()
+	if len(events) != 1 || events[0].Metadata["risk_decision"] != "step_up" || events[0].Metadata["risk_reasons"] != "new_country,velocity" {
+		t.Errorf("risk events = %+v", events)
+	}
+
+	risk.assessment = auth.RiskAssessment{Decision: auth.RiskFlag, Reasons: []string{auth.RiskReasonNewIP}}
+	if _, resp := signIn(); resp.MFARequired {
+		t.Error("flagged sign-in required MFA")
+	}
+	if len(riskEvents()) != 2 {
+		t.Errorf("flagged sign-in emitted %d risk events in total, want 2", len(riskEvents()))
+	}
+
+	risk.err = errors.New("risk service down")
+	if code, _ := signIn(); code != http.StatusInternalServerError {
+		t.Errorf("evaluation failure = %d, want 500", code)
+	}
+}
+
+func TestMagicLinkSignInRiskStepUp(t *testing.T) {
+	sender := mailfake.NewSender()
+	audit := &recordingAudit{}
+	r := setupMagicLinkRouter(t, sender, audit, WithRiskEvaluator(&stubRisk{assessment: auth.RiskAssessment{Decision: auth.RiskStepUp}}))
+
+	postJSON(t, r, "/auth/magic-link", MagicLinkRequest{Email: "magic@example.com"})
+	msgs := sender.Messages()
+	u, _ := url.Parse(magicLinkPattern.FindString(msgs[len(msgs)-1].Text))
+
+	w := verifyMagicLink(r, u.Query().Get("token"))
+	var resp SignInResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || !resp.MFARequired || !strings.HasSuffix(resp.Token, "-mfa-pending") {
+		t.Errorf("step-up magic link sign-in = %d, %+v, want an MFA-pending token", w.Code, resp)
+	}
+}
+
+func TestOAuthSignInRiskStepUp(t *testing.T) {
+	audit := &recordingAudit{}
+	r, _, _ := setupOAuthRouter(t, audit, WithRiskEvaluator(&stubRisk{assessment: auth.RiskAssessment{Decision: auth.RiskStepUp}}))
+
+	state, cookie := startOAuth(t, r)
+	w := callbackOAuth(r, url.Values{"state": {state}, "code": {"good-code"}}.Encode(), cookie)
+	var resp OAuthSignInResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || !resp.MFARequired || !strings.HasSuffix(resp.Token, "-mfa-pending") {
+		t.Errorf("step-up OAuth sign-in = %d, %+v, want an MFA-pending token", w.Code, resp)
+	}
+	var risky int
+	for _, e := range audit.events {
+		if e.Action == ActionSignInRisk {
+			risky++
+		}
+	}
+	if risky != 1 {
+		t.Errorf("risk events = %d, want 1", risky)
+	}
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+)
+
+// RoleRequestRequest is the JSON body of POST /users/me/role-requests. The
+// role is named by RoleID or, when that is empty, by Role.
+type RoleRequestRequest struct {
+	RoleID        string `json:"role_id"`
+	Role          string `json:"role"`
+	Justification string `json:"justification" validate:"required"`
+}
+
+// handleCreateRoleRequest serves POST /users/me/role-requests, filing a
+// grant request for the caller. It is decided like any other request under
+// /grant-requests.
+func (h *AuthZHandler) handleCreateRoleRequest(w http.ResponseWriter, r *http.Request) {
+	username, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	var req RoleRequestRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	roleID, err := h.roleResolver(r.Context())(req.RoleID, req.Role)
+	if err != nil {
+		h.emit(r, ActionGrantRequested, username, err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	grantReq, err := service.RequestGrant(r.Context(), h.grantApproval.store, h.roleStore, h.grantStore, username, roleID, req.Justification, username)
+	if err != nil {
+		h.emit(r, ActionGrantRequested, username, err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.emit(r, ActionGrantRequested, grantReq.ID.String(), nil)
+
+	httpx.WriteJSON(w, http.StatusCreated, GrantRequestResponse{GrantRequest: grantReq})
+}
+
+// handleListRoleRequests serves GET /users/me/role-requests, listing the
+// requests for the caller, newest first, with their status.
+func (h *AuthZHandler) handleListRoleRequests(w http.ResponseWriter, r *http.Request) {
+	username, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	requests, err := service.ListUserGrantRequests(r.Context(), h.grantApproval.store, username)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, ListGrantRequestsResponse{GrantRequests: requests})
+}
+
+// handleGetRoleRequest serves GET /users/me/role-requests/{id}. Requests
+// for other users answer 404.
+func (h *AuthZHandler) handleGetRoleRequest(w http.ResponseWriter, r *http.Request) {
+	username, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := h.grantRequestID(w, r)
+	if !ok {
+		return
+	}
+
+	req, err := service.GetGrantRequest(r.Context(), h.grantApproval.store, id)
+	if err == nil && req.Username != username {
+		err = auth.ErrGrantRequestNotFound
+	}
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, GrantRequestResponse{GrantRequest: req})
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+func getAs(r http.Handler, user, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if user != "" {
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, user))
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRoleRequests(t *testing.T) {
+	r, oncall := setupGrantApproval(t, &recordingPublisher{})
+
+	if w := postJSONAs(r, "", "/users/me/role-requests", RoleRequestRequest{Role: "oncall", Justification: "rotation"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous request = %d, want 401", w.Code)
+	}
+	if w := postJSONAs(r, "ann", "/users/me/role-requests", RoleRequestRequest{Role: "oncall"}); w.Code != http.StatusBadRequest {
+		t.Errorf("request without justification = %d, want 400", w.Code)
+	}
+	if w := postJSONAs(r, "ann", "/users/me/role-requests", RoleRequestRequest{Role: "missing", Justification: "x"}); w.Code != http.StatusNotFound {
+		t.Errorf("request for an unknown role = %d, want 404", w.Code)
+	}
+
+	w := postJSONAs(r, "ann", "/users/me/role-requests", RoleRequestRequest{Role: "oncall", Justification: "joining the rotation"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create = %d: %s", w.Code, w.Body)
+	}
+	var created GrantRequestResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	req := created.GrantRequest
+	if req.Username != "ann" || req.RequestedBy != "ann" || req.RoleID != oncall.ID || req.Reason != "joining the rotation" {
+		t.Errorf("created = %+v", req)
+	}
+	id := req.ID.String()
+
+	w = getAs(r, "bob", "/grant-requests?status=pending")
+	var pending ListGrantRequestsResponse
+	json.NewDecoder(w.Body).Decode(&pending)
+	if len(pending.GrantRequests) != 1 || pending.GrantRequests[0].ID != req.ID {
+		t.Errorf("pending for admins = %+v", pending.GrantRequests)
+	}
+
+	if w := postJSONAs(r, "bob", "/grant-requests/"+id+"/approve", nil); w.Code != http.StatusOK {
+		t.Fatalf("approve = %d: %s", w.Code, w.Body)
+	}
+
+	w = getAs(r, "ann", "/users/me/role-requests")
+	var mine ListGrantRequestsResponse
+	json.NewDecoder(w.Body).Decode(&mine)
+	if len(mine.GrantRequests) != 1 || mine.GrantRequests[0].Status != auth.GrantRequestApproved {
+		t.Errorf("own requests = %+v", mine.GrantRequests)
+	}
+
+	w = getAs(r, "ann", "/users/me/role-requests/"+id)
+	var got GrantRequestResponse
+	json.NewDecoder(w.Body).Decode(&got)
+	if w.Code != http.StatusOK || got.GrantRequest.Status != auth.GrantRequestApproved || got.GrantRequest.DecidedBy != "bob" {
+		t.Errorf("own request = %d %+v", w.Code, got.GrantRequest)
+	}
+	if w := getAs(r, "carol", "/users/me/role-requests/"+id); w.Code != http.StatusNotFound {
+		t.Errorf("someone else's request = %d, want 404", w.Code)
+	}
+	if w := getAs(r, "", "/users/me/role-requests"); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous list = %d, want 401", w.Code)
+	}
+}
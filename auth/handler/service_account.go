@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// DefaultServiceTokenTTL is the lifetime of service account tokens when
+// WithServiceAccounts is given no TTL.
+const DefaultServiceTokenTTL = time.Hour
+
+// GrantTypeClientCredentials is the only grant type served by /auth/token.
+const GrantTypeClientCredentials = "client_credentials"
+
+type serviceAccounts struct {
+	store  auth.ServiceAccountStore
+	tokens service.ServiceTokenGenerator
+	ttl    time.Duration
+}
+
+func (h *AuthNHandler) registerServiceAccountRoutes(r chi.Router) {
+	r.Post("/auth/token", h.limit(h.handleToken))
+
+	r.Post("/service-accounts", h.handleCreateServiceAccount)
+	r.Get("/service-accounts", h.handleListServiceAccounts)
+	r.Get("/service-accounts/{id}", h.handleGetServiceAccount)
+	r.Put("/service-accounts/{id}", h.handleUpdateServiceAccount)
+	r.Delete("/service-accounts/{id}", h.handleDeleteServiceAccount)
+	r.Post("/service-accounts/{id}/secret", h.handleRotateServiceAccountSecret)
+}
+
+// TokenResponse is an OAuth 2.0 access token response (RFC 6749 §5.1).
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// TokenErrorResponse is an OAuth 2.0 error response (RFC 6749 §5.2).
+type TokenErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// handleToken serves POST /auth/token with the client_credentials grant.
+// Clients authenticate with HTTP Basic or with client_id and client_secret
+// form fields. Responses and errors follow RFC 6749 rather than the API
+// error format, so stock OAuth clients can use the endpoint.
+func (h *AuthNHandler) handleToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, http.StatusBadRequest, "invalid_request", "Invalid form body")
+		return
+	}
+	if grant := r.PostForm.Get("grant_type"); grant != GrantTypeClientCredentials {
+		writeTokenError(w, http.StatusBadRequest, "unsupported_grant_type", "Only client_credentials is supported")
+		return
+	}
+
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID == "" || secret == "" {
+		writeTokenError(w, http.StatusBadRequest, "invalid_request", "client_id and client_secret are required")
+		return
+	}
+
+	ttl := h.serviceAccounts.ttl
+	account, token, err := service.AuthenticateServiceAccount(r.Context(), h.serviceAccounts.store, h.serviceAccounts.tokens, clientID, secret, ttl)
+	if err != nil {
+		h.emit(r, ActionServiceToken, clientID, err)
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+			writeTokenError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		case errors.Is(err, auth.ErrInactiveAccount):
+			writeTokenError(w, http.StatusBadRequest, "unauthorized_client", "Service account is disabled")
+		default:
+			writeTokenError(w, http.StatusInternalServerError, "server_error", "Cannot issue token")
+		}
+		return
+	}
+	h.emit(r, ActionServiceToken, account.Principal(), nil)
+
+	httpx.WriteJSON(w, http.StatusOK, TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(ttl / time.Second),
+	})
+}
+
+func writeTokenError(w http.ResponseWriter, status int, code, description string) {
+	httpx.WriteJSON(w, status, TokenErrorResponse{Error: code, ErrorDescription: description})
+}
+
+type CreateServiceAccountRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// CreateServiceAccountResponse carries the client secret, which is shown
+// only once.
+type CreateServiceAccountResponse struct {
+	ServiceAccount *auth.ServiceAccount `json:"data"`
+	ClientSecret   string               `json:"client_secret"`
+}
+
+type ServiceAccountResponse struct {
+	ServiceAccount *auth.ServiceAccount `json:"data"`
+}
+
+type ListServiceAccountsResponse struct {
+	ServiceAccounts []*auth.ServiceAccount `json:"data"`
+}
+
+func (h *AuthNHandler) handleCreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	var req CreateServiceAccountRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	createdBy := middleware.GetUserID(r.Context())
+	account, secret, err := service.CreateServiceAccount(r.Context(), h.serviceAccounts.store, req.Name, req.Description, createdBy)
+	if err != nil {
+		h.emit(r, ActionServiceAccountCreated, req.Name, err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.emit(r, ActionServiceAccountCreated, account.ID.String(), nil)
+
+	httpx.WriteJSON(w, http.StatusCreated, CreateServiceAccountResponse{ServiceAccount: account, ClientSecret: secret})
+}
+
+func (h *AuthNHandler) handleListServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := service.ListServiceAccounts(r.Context(), h.serviceAccounts.store)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, ListServiceAccountsResponse{ServiceAccounts: accounts})
+}
+
+func (h *AuthNHandler) handleGetServiceAccount(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.serviceAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	account, err := service.GetServiceAccount(r.Context(), h.serviceAccounts.store, id)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, ServiceAccountResponse{ServiceAccount: account})
+}
+
+// UpdateServiceAccountRequest replaces the description. An empty status
+// keeps the current one.
+type UpdateServiceAccountRequest struct {
+	Description string                    `json:"description"`
+	Status      auth.ServiceAccountStatus `json:"status"`
+}
+
+// handleUpdateServiceAccount serves PUT /service-accounts/{id}. Disabling
+// an account stops it from getting new tokens; tokens already issued stay
+// valid until they expire.
+func (h *AuthNHandler) handleUpdateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.serviceAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateServiceAccountRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+	if req.Status != "" && !req.Status.IsValid() {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_STATUS", "Status must be active or disabled")
+		return
+	}
+
+	account, err := service.GetServiceAccount(r.Context(), h.serviceAccounts.store, id)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	account.Description = req.Description
+	if req.Status != "" {
+		account.Status = req.Status
+	}
+	account.UpdatedBy = middleware.GetUserID(r.Context())
+
+	err = service.UpdateServiceAccount(r.Context(), h.serviceAccounts.store, account)
+	h.emit(r, ActionServiceAccountUpdated, id.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, ServiceAccountResponse{ServiceAccount: account})
+}
+
+func (h *AuthNHandler) handleDeleteServiceAccount(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.serviceAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	err := service.DeleteServiceAccount(r.Context(), h.serviceAccounts.store, id)
+	h.emit(r, ActionServiceAccountDeleted, id.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRotateServiceAccountSecret serves POST /service-accounts/{id}/secret.
+// The previous secret stops working at once.
+func (h *AuthNHandler) handleRotateServiceAccountSecret(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.serviceAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	updatedBy := middleware.GetUserID(r.Context())
+	account, secret, err := service.RotateServiceAccountSecret(r.Context(), h.serviceAccounts.store, id, updatedBy)
+	h.emit(r, ActionServiceAccountSecretRotated, id.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, CreateServiceAccountResponse{ServiceAccount: account, ClientSecret: secret})
+}
+
+func (h *AuthNHandler) serviceAccountID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_SERVICE_ACCOUNT_ID", "Invalid service account ID format")
+		return uuid.Nil, false
+	}
+	return id, true
+}
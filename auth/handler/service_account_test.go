@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func setupServiceAccounts(t *testing.T, audit *recordingAudit) (chi.Router, *fake.ServiceAccountStore) {
+	t.Helper()
+	store := fake.NewServiceAccountStore()
+	tokenGen := fake.NewTokenGenerator()
+	h := NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), tokenGen, fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithServiceAccounts(store, tokenGen, 30*time.Minute), WithAudit(audit))
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	return r, store
+}
+
+func createServiceAccount(t *testing.T, r chi.Router, name string) CreateServiceAccountResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/service-accounts", strings.NewReader(`{"name": "`+name+`", "description": "nightly jobs"}`))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "admin"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %v, want %v (%s)", w.Code, http.StatusCreated, w.Body.String())
+	}
+	var resp CreateServiceAccountResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode create response: %v", err)
+	}
+	return resp
+}
+
+func requestToken(r chi.Router, form url.Values, basicID, basicSecret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/auth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicID != "" {
+		req.SetBasicAuth(basicID, basicSecret)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleCreateServiceAccount(t *testing.T) {
+	audit := &recordingAudit{}
+	r, _ := setupServiceAccounts(t, audit)
+
+	resp := createServiceAccount(t, r, "billing-worker")
+	if resp.ClientSecret == "" || resp.ServiceAccount.ClientID == "" {
+		t.Fatalf("create response = %+v, want client credentials", resp)
+	}
+	if resp.ServiceAccount.CreatedBy != "admin" {
+		t.Errorf("CreatedBy = %q, want admin", resp.ServiceAccount.CreatedBy)
+	}
+
+	for _, body := range []string{`{"name": "billing-worker"}`, `{"name": "no spaces"}`, `{`} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/service-accounts", strings.NewReader(body)))
+		if w.Code != http.StatusConflict && w.Code != http.StatusBadRequest {
+			t.Errorf("create %s status = %v, want 409 or 400", body, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/service-accounts", nil))
+	var list ListServiceAccountsResponse
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list.ServiceAccounts) != 1 {
+		t.Fatalf("list = %d accounts, want 1", len(list.ServiceAccounts))
+	}
+	if strings.Contains(w.Body.String(), "secret") {
+		t.Errorf("list exposes secret material: %s", w.Body.String())
+	}
+
+	if len(audit.events) == 0 || audit.events[0].Action != ActionServiceAccountCreated {
+		t.Errorf("events = %+v, want %s first", audit.events, ActionServiceAccountCreated)
+	}
+}
+
+func TestHandleToken(t *testing.T) {
+	audit := &recordingAudit{}
+	r, store := setupServiceAccounts(t, audit)
+	created := createServiceAccount(t, r, "billing-worker")
+	clientID, secret := created.ServiceAccount.ClientID, created.ClientSecret
+
+	disabled := createServiceAccount(t, r, "retired")
+	account, _ := store.Get(t.Context(), disabled.ServiceAccount.ID)
+	account.Status = auth.ServiceAccountStatusDisabled
+
+	grant := url.Values{"grant_type": {GrantTypeClientCredentials}}
+	credentials := url.Values{"grant_type": {GrantTypeClientCredentials}, "client_id": {clientID}, "client_secret": {secret}}
+
+	tests := []struct {
+		name        string
+		form        url.Values
+		basicID     string
+		basicSecret string
+		wantStatus  int
+		wantError   string
+	}{
+		{name: "basic auth", form: grant, basicID: clientID, basicSecret: secret, wantStatus: http.StatusOK},
+		{name: "form credentials", form: credentials, wantStatus: http.StatusOK},
+		{name: "wrong secret", form: grant, basicID: clientID, basicSecret: "nope", wantStatus: http.StatusUnauthorized, wantError: "invalid_client"},
+		{name: "unknown client", form: grant, basicID: "sa_unknown", basicSecret: secret, wantStatus: http.StatusUnauthorized, wantError: "invalid_client"},
+		{name: "disabled", form: grant, basicID: disabled.ServiceAccount.ClientID, basicSecret: disabled.ClientSecret, wantStatus: http.StatusBadRequest, wantError: "unauthorized_client"},
+		{name: "missing credentials", form: grant, wantStatus: http.StatusBadRequest, wantError: "invalid_request"},
+		{name: "password grant", form: url.Values{"grant_type": {"password"}}, basicID: clientID, basicSecret: secret, wantStatus: http.StatusBadRequest, wantError: "unsupported_grant_type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := requestToken(r, tt.form, tt.basicID, tt.basicSecret)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("handleToken() status = %v, want %v (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", w.Header().Get("Cache-Control"))
+			}
+
+			if tt.wantError != "" {
+				var resp TokenErrorResponse
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp.Error != tt.wantError {
+					t.Errorf("error = %q, want %q", resp.Error, tt.wantError)
+				}
+				return
+			}
+
+			var resp TokenResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.AccessToken != "token-sa:billing-worker" || resp.TokenType != "Bearer" || resp.ExpiresIn != 1800 {
+				t.Errorf("token response = %+v", resp)
+			}
+		})
+	}
+
+	last := audit.events[len(audit.events)-1]
+	if last.Action != ActionServiceToken {
+		t.Errorf("last event = %+v, want %s", last, ActionServiceToken)
+	}
+}
+
+func TestHandleServiceAccountLifecycle(t *testing.T) {
+	r, _ := setupServiceAccounts(t, &recordingAudit{})
+	created := createServiceAccount(t, r, "billing-worker")
+	path := "/service-accounts/" + created.ServiceAccount.ID.String()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"description": "invoices", "status": "disabled"}`)))
+	var updated ServiceAccountResponse
+	json.NewDecoder(w.Body).Decode(&updated)
+	if w.Code != http.StatusOK || updated.ServiceAccount.Status != auth.ServiceAccountStatusDisabled || updated.ServiceAccount.Description != "invoices" {
+		t.Fatalf("update status = %v, account = %+v", w.Code, updated.ServiceAccount)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"status": "sleeping"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid status = %v, want 400", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"status": "active"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("re-enable status = %v", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path+"/secret", nil))
+	var rotated CreateServiceAccountResponse
+	json.NewDecoder(w.Body).Decode(&rotated)
+	if w.Code != http.StatusOK || rotated.ClientSecret == "" || rotated.ClientSecret == created.ClientSecret {
+		t.Fatalf("rotate status = %v, secret = %q", w.Code, rotated.ClientSecret)
+	}
+
+	grant := url.Values{"grant_type": {GrantTypeClientCredentials}}
+	if w := requestToken(r, grant, created.ServiceAccount.ClientID, created.ClientSecret); w.Code != http.StatusUnauthorized {
+		t.Errorf("old secret status = %v, want 401", w.Code)
+	}
+	if w := requestToken(r, grant, created.ServiceAccount.ClientID, rotated.ClientSecret); w.Code != http.StatusOK {
+		t.Errorf("new secret status = %v, want 200", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("delete status = %v, want 204", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("get after delete = %v, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/service-accounts/"+uuid.Nil.String()+"x", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid id = %v, want 400", w.Code)
+	}
+}
+
+func TestServiceAccountRoutesDisabled(t *testing.T) {
+	r := chi.NewRouter()
+	setupAuthNHandler().RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/token", nil))
+	if w.Code != http.StatusNotFound && w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("/auth/token without WithServiceAccounts = %v, want 404", w.Code)
+	}
+}
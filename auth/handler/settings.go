@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+type SettingsResponse struct {
+	Settings *auth.Settings `json:"settings"`
+}
+
+// currentSettings returns the settings of WithSettings, or nil without
+// them; the accessors of auth.Settings then return their fallbacks.
+func (o *options) currentSettings(ctx context.Context) (*auth.Settings, error) {
+	if o.settings == nil {
+		return nil, nil
+	}
+	return service.GetSettings(ctx, o.settings)
+}
+
+// sessionTokens returns the token generator for sign-ins under settings.
+func (h *AuthNHandler) sessionTokens(settings *auth.Settings) service.TokenGenerator {
+	return service.WithTokenTTL(h.tokenGen, settings.SessionTTL(0))
+}
+
+// handleGetSettings serves GET /settings. Its ETag is "0" until the
+// settings are first saved.
+func (h *AuthNHandler) handleGetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := service.GetSettings(r.Context(), h.settings)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, settings.Version)
+	httpx.WriteJSON(w, http.StatusOK, SettingsResponse{Settings: settings})
+}
+
+// handleUpdateSettings serves PUT /settings, replacing every setting; unset
+// fields fall back to the static configuration. The If-Match header must
+// carry the ETag returned by GET /settings; a stale ETag fails with 412.
+func (h *AuthNHandler) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req auth.Settings
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	ifMatch, ok := h.requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	current, err := service.GetSettings(r.Context(), h.settings)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	if !matchesIfMatch(ifMatch, current.Version) {
+		h.emit(r, ActionSettingsUpdated, "settings", auth.ErrVersionConflict)
+		h.handleServiceError(w, r, auth.ErrVersionConflict)
+		return
+	}
+
+	settings := &auth.Settings{
+		SessionTTLSeconds: req.SessionTTLSeconds,
+		MFARequired:       req.MFARequired,
+		PasswordPolicy:    req.PasswordPolicy,
+		EmailDomains:      req.EmailDomains,
+		Version:           current.Version,
+	}
+	err = service.UpdateSettings(r.Context(), h.settings, settings, middleware.GetUserID(r.Context()))
+	h.emit(r, ActionSettingsUpdated, "settings", err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, settings.Version)
+	httpx.WriteJSON(w, http.StatusOK, SettingsResponse{Settings: settings})
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestSettingsRoutes(t *testing.T) {
+	audit := &recordingAudit{}
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithSettings(fake.NewSettingsStore()), WithAudit(audit),
+	).RegisterRoutes(r)
+
+	w := getAs(r, "", "/settings")
+	var resp SettingsResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || resp.Settings.Version != 0 || w.Header().Get("ETag") != `"0"` {
+		t.Fatalf("GET before save = %d, %+v, ETag %s", w.Code, resp.Settings, w.Header().Get("ETag"))
+	}
+
+	required := true
+	update := auth.Settings{
+		SessionTTLSeconds: 900,
+		MFARequired:       &required,
+		PasswordPolicy:    &auth.PasswordPolicy{MinLength: 14},
+		EmailDomains:      &auth.EmailDomains{Allow: []string{"Example.com"}},
+	}
+	if w := sendJSON(r, http.MethodPut, "/settings", "", update); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("PUT without If-Match = %d, want 428", w.Code)
+	}
+	if w := sendJSON(r, http.MethodPut, "/settings", `"3"`, update); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("PUT with stale If-Match = %d, want 412", w.Code)
+	}
+	if w := sendJSON(r, http.MethodPut, "/settings", `"0"`, auth.Settings{SessionTTLSeconds: -1}); w.Code != http.StatusBadRequest {
+		t.Errorf("PUT invalid settings = %d, want 400", w.Code)
+	}
+
+	w = sendJSON(r, http.MethodPut, "/settings", `"0"`, update)
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || resp.Settings.Version != 1 || resp.Settings.EmailDomains.Allow[0] != "example.com" || w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("PUT = %d, %+v", w.Code, resp.Settings)
+	}
+
+	var updates int
+	for _, e := range audit.events {
+		if e.Action == ActionSettingsUpdated {
+			updates++
+		}
+	}
+	if updates != 3 {
+		t.Errorf("settings events = %d, want 3", updates)
+	}
+}
+
+func TestEmailDomainsSignUp(t *testing.T) {
+	settings := fake.NewSettingsStore()
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithEmailDomains(auth.EmailDomains{Allow: []string{"*.example.com"}}), WithSettings(settings),
+	).RegisterRoutes(r)
+
+	signUp := func(email, username string) int {
+		return postJSON(t, r, "/auth/signup", SignUpRequest{Email: email, Password: "Password123!", Username: username, DisplayName: "User"}).Code
+	}
+	if code := signUp("ann@eu.example.com", "ann"); code != http.StatusCreated {
+		t.Errorf("sign-up from allowed subdomain = %d, want 201", code)
+	}
+	if code := signUp("bob@example.com", "bob"); code != http.StatusForbidden {
+		t.Errorf("sign-up from parent domain = %d, want 403", code)
+	}
+
+	settings.Save(t.Context(), &auth.Settings{EmailDomains: &auth.EmailDomains{Deny: []string{"evil.com"}}})
+	if code := signUp("bob@example.com", "bob"); code != http.StatusCreated {
+		t.Errorf("sign-up after settings replaced the lists = %d, want 201", code)
+	}
+	if code := signUp("eve@evil.com", "eve"); code != http.StatusForbidden {
+		t.Errorf("sign-up from denied domain = %d, want 403", code)
+	}
+}
+
+func TestSettingsApplied(t *testing.T) {
+	settings := fake.NewSettingsStore()
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithSettings(settings),
+	).RegisterRoutes(r)
+
+	w := postJSON(t, r, "/auth/signup", SignUpRequest{Email: "ann@example.com", Password: "Password123!", Username: "ann", DisplayName: "Ann"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("sign-up without settings = %d: %s", w.Code, w.Body)
+	}
+	var signup SignUpResponse
+	json.NewDecoder(w.Body).Decode(&signup)
+
+	w = postJSON(t, r, "/auth/signin", SignInRequest{Email: "ann@example.com", Password: "Password123!"})
+	var signin SignInResponse
+	json.NewDecoder(w.Body).Decode(&signin)
+	if signin.MFARequired || strings.Contains(signin.Token, "-ttl-") {
+		t.Errorf("sign-in without settings = %+v", signin)
+	}
+
+	required := true
+	settings.Save(t.Context(), &auth.Settings{
+		SessionTTLSeconds: 900,
+		MFARequired:       &required,
+		PasswordPolicy:    &auth.PasswordPolicy{MinLength: 14},
+		EmailDomains:      &auth.EmailDomains{Allow: []string{"example.com"}},
+	})
+
+	w = postJSON(t, r, "/auth/signup", SignUpRequest{Email: "eve@evil.com", Password: "LongerPassword123!", Username: "eve", DisplayName: "Eve"})
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "EMAIL_DOMAIN_NOT_ALLOWED") {
+		t.Errorf("sign-up from other domain = %d: %s", w.Code, w.Body)
+	}
+	w = postJSON(t, r, "/auth/signup", SignUpRequest{Email: "bob@example.com", Password: "Password123!", Username: "bob", DisplayName: "Bob"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("sign-up with short password = %d, want 400", w.Code)
+	}
+	w = postJSON(t, r, "/auth/signup", SignUpRequest{Email: "bob@example.com", Password: "LongerPassword123!", Username: "bob", DisplayName: "Bob"})
+	if w.Code != http.StatusCreated {
+		t.Errorf("allowed sign-up = %d: %s", w.Code, w.Body)
+	}
+
+	w = postJSON(t, r, "/auth/signin", SignInRequest{Email: "ann@example.com", Password: "Password123!"})
+	json.NewDecoder(w.Body).Decode(&signin)
+	if !signin.MFARequired || signin.Token != "token-"+signup.User.ID.String()+"-mfa-pending" {
+		t.Errorf("sign-in with settings = %+v", signin)
+	}
+
+	id := signup.User.ID.String()
+	if w := postJSONAs(r, id, "/users/me/password", ChangePasswordRequest{CurrentPassword: "Password123!", NewPassword: "Another123!"}); w.Code != http.StatusBadRequest {
+		t.Errorf("change to short password = %d, want 400", w.Code)
+	}
+	if w := postJSON(t, r, "/users/"+id+"/password", ResetPasswordRequest{Password: "Another123!"}); w.Code != http.StatusBadRequest {
+		t.Errorf("reset to short password = %d, want 400", w.Code)
+	}
+	if w := postJSONAs(r, id, "/users/me/password", ChangePasswordRequest{CurrentPassword: "Password123!", NewPassword: "AnotherLonger123!"}); w.Code != http.StatusOK {
+		t.Errorf("change to long password = %d: %s", w.Code, w.Body)
+	}
+}
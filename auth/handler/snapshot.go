@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/aquamarinepk/aqm/auth/seed"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+const yamlContentType = "application/yaml"
+
+// maxSnapshotSize bounds the body accepted by POST /authz/import.
+const maxSnapshotSize = 16 << 20
+
+// ImportResponse reports what an import changed, or would change on a dry
+// run. Result is nil on a dry run.
+type ImportResponse struct {
+	DryRun bool              `json:"dry_run"`
+	Diff   *seed.Diff        `json:"diff"`
+	Result *seed.ApplyResult `json:"result,omitempty"`
+}
+
+// handleExport serves GET /authz/export. It writes every role and grant as a
+// seed manifest, in YAML when format=yaml and JSON otherwise. With
+// users=true the users are included, without credentials.
+func (h *AuthZHandler) handleExport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	users, err := parseQueryBool(q.Get("users"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_QUERY", "users must be a boolean")
+		return
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = seed.FormatJSON
+	}
+	if format != seed.FormatJSON && format != seed.FormatYAML {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_QUERY", "format must be json or yaml")
+		return
+	}
+
+	m, err := h.snapshots.Export(r.Context(), users)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	data, err := seed.EncodeManifest(m, format)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	contentType := "application/json"
+	if format == seed.FormatYAML {
+		contentType = yamlContentType
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="authz.`+format+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// handleImport serves POST /authz/import. The body is a seed manifest, read
+// as YAML when the Content-Type is application/yaml and as JSON otherwise.
+// Missing entries are created and differing ones updated; nothing is
+// deleted. With dry_run=true only the diff is computed. The manifest's
+// created_by defaults to the caller.
+func (h *AuthZHandler) handleImport(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := parseQueryBool(r.URL.Query().Get("dry_run"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_QUERY", "dry_run must be a boolean")
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSnapshotSize))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "Cannot read request body")
+		return
+	}
+	m, err := seed.DecodeManifest(data, snapshotFormat(r))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "Invalid snapshot")
+		return
+	}
+	if m.CreatedBy == "" {
+		m.CreatedBy = middleware.GetUserID(r.Context())
+	}
+
+	ctx := r.Context()
+	diff, err := h.snapshots.Diff(ctx, m)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	if dryRun {
+		httpx.WriteJSON(w, http.StatusOK, ImportResponse{DryRun: true, Diff: diff})
+		return
+	}
+
+	res, err := h.snapshots.Apply(ctx, m)
+	h.emit(r, ActionSnapshotImported, "authz", err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, ImportResponse{Diff: diff, Result: res})
+}
+
+func snapshotFormat(r *http.Request) string {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case yamlContentType, "application/x-yaml", "text/yaml":
+		return seed.FormatYAML
+	default:
+		return seed.FormatJSON
+	}
+}
+
+func parseQueryBool(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
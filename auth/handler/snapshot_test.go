@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/seed"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+const snapshotYAML = `
+roles:
+  - name: editor
+    description: Edits content
+    permissions: [content:read, content:write]
+grants:
+  - username: alice
+    role: editor
+  - username: sa:publisher
+    role: editor
+`
+
+func setupSnapshots(t *testing.T, audit *recordingAudit) chi.Router {
+	t.Helper()
+	users := fake.NewUserStore()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+	seeder := seed.New(users, roles, grants, &seed.Config{
+		EncryptionKey: []byte("test-encryption-key-32-bytes!!!!"),
+		SigningKey:    []byte("test-signing-key-32-bytes-long!!"),
+	}, log.NewNoopLogger())
+
+	r := chi.NewRouter()
+	NewAuthZHandler(roles, grants, WithSnapshots(seeder), WithAudit(audit)).RegisterRoutes(r)
+	return r
+}
+
+func importSnapshot(r chi.Router, query, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/authz/import"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "admin"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleImportDryRun(t *testing.T) {
+	audit := &recordingAudit{}
+	r := setupSnapshots(t, audit)
+
+	w := importSnapshot(r, "?dry_run=true", "application/yaml", snapshotYAML)
+	if w.Code != http.StatusOK {
+		t.Fatalf("dry run status = %v (%s)", w.Code, w.Body.String())
+	}
+	var resp ImportResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if !resp.DryRun || resp.Result != nil || len(resp.Diff.Changes) != 3 {
+		t.Fatalf("dry run response = %+v", resp)
+	}
+	if len(audit.events) != 0 {
+		t.Errorf("dry run emitted %d events, want 0", len(audit.events))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/authz/export", nil))
+	var m seed.Manifest
+	json.NewDecoder(w.Body).Decode(&m)
+	if len(m.Roles) != 0 {
+		t.Errorf("dry run created roles: %+v", m.Roles)
+	}
+}
+
+func TestHandleImportAndExport(t *testing.T) {
+	audit := &recordingAudit{}
+	r := setupSnapshots(t, audit)
+
+	w := importSnapshot(r, "", "application/yaml", snapshotYAML)
+	if w.Code != http.StatusOK {
+		t.Fatalf("import status = %v (%s)", w.Code, w.Body.String())
+	}
+	var resp ImportResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Result == nil || resp.Result.Created != 3 {
+		t.Fatalf("import response = %+v", resp)
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != ActionSnapshotImported {
+		t.Errorf("events = %+v, want one %s", audit.events, ActionSnapshotImported)
+	}
+
+	tests := []struct {
+		name            string
+		query           string
+		wantContentType string
+	}{
+		{name: "json", query: "", wantContentType: "application/json"},
+		{name: "yaml", query: "?format=yaml", wantContentType: "application/yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/authz/export"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("export status = %v (%s)", w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+
+			format := seed.FormatJSON
+			if tt.name == "yaml" {
+				format = seed.FormatYAML
+			}
+			m, err := seed.DecodeManifest(w.Body.Bytes(), format)
+			if err != nil {
+				t.Fatalf("DecodeManifest() error = %v", err)
+			}
+			if len(m.Roles) != 1 || len(m.Grants) != 2 || m.Grants[0].Username != "alice" {
+				t.Errorf("exported manifest = %+v", m)
+			}
+		})
+	}
+
+	w = importSnapshot(r, "?dry_run=1", "application/json", `{"roles": [{"name": "editor", "description": "Edits content", "permissions": ["content:read"]}]}`)
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Diff.Changes) != 1 || resp.Diff.Changes[0].Action != seed.ActionUpdate {
+		t.Errorf("update diff = %+v", resp.Diff)
+	}
+}
+
+func TestHandleImportErrors(t *testing.T) {
+	r := setupSnapshots(t, &recordingAudit{})
+
+	tests := []struct {
+		name       string
+		query      string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "invalid body", body: `{`, wantStatus: http.StatusBadRequest, wantCode: "INVALID_REQUEST"},
+		{name: "invalid dry_run", query: "?dry_run=maybe", body: `{}`, wantStatus: http.StatusBadRequest, wantCode: "INVALID_QUERY"},
+		{name: "unknown role", body: `{"grants": [{"username": "alice", "role": "missing"}]}`, wantStatus: http.StatusNotFound, wantCode: "ROLE_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := importSnapshot(r, tt.query, "application/json", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %v, want %v (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var resp ErrorResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", resp.Code, tt.wantCode)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/authz/export?format=toml", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("export format=toml status = %v, want 400", w.Code)
+	}
+}
@@ -2,22 +2,24 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/aquamarinepk/aqm/auth"
 	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
 	"github.com/go-chi/chi/v5"
 )
 
 // SystemHandler handles system-level operations like bootstrap and user lookup.
 // These endpoints are designed for inter-service communication during system initialization.
-// NOTE: Bootstrap endpoints could be disabled in production environments.
-// They exist for development/initial setup. Consider adding feature flag
-// to disable them when not needed.
+// Bootstrap stops working once the superadmin exists; lock it down further
+// with WithBootstrap and WithInternal.
 type SystemHandler struct {
 	userStore auth.UserStore
 	crypto    service.CryptoService
 	pwdGen    service.PasswordGenerator
+	options
 }
 
 // SystemBootstrapStatusResponse represents the current bootstrap status
@@ -42,16 +44,19 @@ type SystemUserIDResponse struct {
 }
 
 // NewSystemHandler creates a new system handler with required dependencies.
-func NewSystemHandler(userStore auth.UserStore, crypto service.CryptoService, pwdGen service.PasswordGenerator) *SystemHandler {
+// Pass WithInternal to restrict its routes to trusted services.
+func NewSystemHandler(userStore auth.UserStore, crypto service.CryptoService, pwdGen service.PasswordGenerator, opts ...Option) *SystemHandler {
 	return &SystemHandler{
 		userStore: userStore,
 		crypto:    crypto,
 		pwdGen:    pwdGen,
+		options:   newOptions(opts),
 	}
 }
 
 // RegisterRoutes registers system management routes
 func (h *SystemHandler) RegisterRoutes(r chi.Router) {
+	r = h.internalRoutes(r)
 	r.Get("/system/bootstrap-status", h.GetBootstrapStatus)
 	r.Post("/system/bootstrap", h.Bootstrap)
 	r.Get("/system/users/by-email/{email}", h.GetUserIDByEmail)
@@ -64,35 +69,43 @@ func (h *SystemHandler) GetBootstrapStatus(w http.ResponseWriter, r *http.Reques
 
 	superadmin, err := h.findSuperadmin(ctx)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "BOOTSTRAP_CHECK_FAILED", "Failed to check bootstrap state")
+		writeError(w, r, http.StatusInternalServerError, "BOOTSTRAP_CHECK_FAILED", "Failed to check bootstrap state")
 		return
 	}
 
 	if superadmin == nil {
-		writeJSON(w, http.StatusOK, SystemBootstrapStatusResponse{NeedsBootstrap: true})
+		httpx.WriteJSON(w, http.StatusOK, SystemBootstrapStatusResponse{NeedsBootstrap: true})
 		return
 	}
 
-	writeJSON(w, http.StatusOK, SystemBootstrapStatusResponse{
+	httpx.WriteJSON(w, http.StatusOK, SystemBootstrapStatusResponse{
 		NeedsBootstrap:     false,
 		SuperadminID:       superadmin.ID.String(),
 		SuperadminUsername: superadmin.Username,
 	})
 }
 
-// Bootstrap creates the superadmin user if it doesn't exist.
-// Returns superadmin details with password only if newly created.
-// This endpoint is idempotent - multiple calls are safe.
+// Bootstrap creates the superadmin user and returns its details with the
+// generated password. Once the superadmin exists it answers 410, so callers
+// should check GetBootstrapStatus first.
 func (h *SystemHandler) Bootstrap(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	if !h.allowBootstrap(w, r) {
+		return
+	}
+
 	user, password, err := service.Bootstrap(ctx, h.userStore, h.crypto, h.pwdGen)
+	if errors.Is(err, auth.ErrAlreadyBootstrapped) {
+		h.handleServiceError(w, r, err)
+		return
+	}
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "BOOTSTRAP_FAILED", "Failed to bootstrap superadmin")
+		writeError(w, r, http.StatusInternalServerError, "BOOTSTRAP_FAILED", "Failed to bootstrap superadmin")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, SystemBootstrapResponse{
+	httpx.WriteJSON(w, http.StatusOK, SystemBootstrapResponse{
 		SuperadminID:       user.ID.String(),
 		SuperadminUsername: user.Username,
 		Email:              service.SuperadminEmail,
@@ -107,7 +120,7 @@ func (h *SystemHandler) GetUserIDByEmail(w http.ResponseWriter, r *http.Request)
 
 	email := chi.URLParam(r, "email")
 	if email == "" {
-		writeError(w, http.StatusBadRequest, "MISSING_EMAIL", "Email parameter is required")
+		writeError(w, r, http.StatusBadRequest, "MISSING_EMAIL", "Email parameter is required")
 		return
 	}
 
@@ -117,14 +130,14 @@ func (h *SystemHandler) GetUserIDByEmail(w http.ResponseWriter, r *http.Request)
 	user, err := h.userStore.GetByEmailLookup(ctx, emailLookup)
 	if err != nil {
 		if err == auth.ErrUserNotFound {
-			writeError(w, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+			writeError(w, r, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "LOOKUP_FAILED", "Failed to lookup user")
+		writeError(w, r, http.StatusInternalServerError, "LOOKUP_FAILED", "Failed to lookup user")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, SystemUserIDResponse{
+	httpx.WriteJSON(w, http.StatusOK, SystemUserIDResponse{
 		UserID: user.ID.String(),
 		Email:  email,
 	})
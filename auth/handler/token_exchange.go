@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+)
+
+// DefaultTokenExchangeTTL is the longest lifetime of the tokens derived by
+// POST /auth/token/exchange when WithTokenExchange is given no TTL.
+const DefaultTokenExchangeTTL = 5 * time.Minute
+
+type tokenExchange struct {
+	tokens service.ScopedTokenGenerator
+	ttl    time.Duration
+}
+
+// TokenExchangeRequest trades Token for a token limited to Permissions.
+// TTLSeconds, when set, asks for a token shorter lived than the configured
+// maximum.
+type TokenExchangeRequest struct {
+	Token       string   `json:"token" validate:"required"`
+	Permissions []string `json:"permissions" validate:"required"`
+	TTLSeconds  int      `json:"ttl_seconds,omitempty" validate:"min=0"`
+}
+
+type TokenExchangeResponse struct {
+	Token     string    `json:"token"`
+	Scope     []string  `json:"scope"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// handleTokenExchange serves POST /auth/token/exchange. Token is verified as
+// by POST /auth/verify and fails the same way. The derived token keeps its
+// subject, session and actor, so revoking the session revokes both, and a
+// scoped token can only be narrowed further; other permissions fail with
+// 400 INVALID_SCOPE.
+func (h *AuthNHandler) handleTokenExchange(w http.ResponseWriter, r *http.Request) {
+	var req TokenExchangeRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	parent, ok := h.verifyToken(w, r, req.Token)
+	if !ok {
+		return
+	}
+
+	ttl := h.tokenExchange.ttl
+	if requested := time.Duration(req.TTLSeconds) * time.Second; requested > 0 && requested < ttl {
+		ttl = requested
+	}
+
+	token, scope, expiresAt, err := service.ExchangeToken(h.tokenExchange.tokens, parent, req.Permissions, ttl)
+	h.emitWith(r, ActionTokenExchanged, parent.Subject, map[string]string{"scope": strings.Join(req.Permissions, " ")}, err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, TokenExchangeResponse{
+		Token:     token,
+		Scope:     scope,
+		ExpiresAt: expiresAt.UTC(),
+	})
+}
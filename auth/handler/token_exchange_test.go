@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestTokenExchange(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	tokens := service.NewDefaultTokenGenerator(priv, time.Hour)
+	audit := &recordingAudit{}
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), tokens, fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithTokenValidator(middleware.NewTokenValidator(pub)), WithTokenExchange(tokens, 10*time.Minute), WithAudit(audit),
+	).RegisterRoutes(r)
+
+	postJSON(t, r, "/auth/signup", SignUpRequest{Email: "ann@example.com", Password: "Password123!", Username: "ann", DisplayName: "Ann"})
+	w := postJSON(t, r, "/auth/signin", SignInRequest{Email: "ann@example.com", Password: "Password123!"})
+	var signIn SignInResponse
+	json.NewDecoder(w.Body).Decode(&signIn)
+
+	exchange := func(req TokenExchangeRequest) (int, TokenExchangeResponse, string) {
+		w := postJSON(t, r, "/auth/token/exchange", req)
+		var resp TokenExchangeResponse
+		var errResp ErrorResponse
+		if w.Code == http.StatusOK {
+			json.NewDecoder(w.Body).Decode(&resp)
+		} else {
+			json.NewDecoder(w.Body).Decode(&errResp)
+		}
+		return w.Code, resp, errResp.Code
+	}
+
+	code, derived, _ := exchange(TokenExchangeRequest{Token: signIn.Token, Permissions: []string{"orders:read", "orders:list"}, TTLSeconds: 60})
+	if code != http.StatusOK {
+		t.Fatalf("exchange = %d", code)
+	}
+	if !slices.Equal(derived.Scope, []string{"orders:list", "orders:read"}) {
+		t.Errorf("scope = %v", derived.Scope)
+	}
+	if d := time.Until(derived.ExpiresAt); d > time.Minute {
+		t.Errorf("derived token expires in %v, want at most the 1m asked for", d)
+	}
+
+	w = postJSON(t, r, "/auth/verify", VerifyTokenRequest{Token: derived.Token})
+	var verified VerifyTokenResponse
+	json.NewDecoder(w.Body).Decode(&verified)
+	if w.Code != http.StatusOK || !slices.Equal(verified.Scope, derived.Scope) || verified.UserID == "" {
+		t.Errorf("verify derived = %d, %+v", w.Code, verified)
+	}
+
+	tests := []struct {
+		name string
+		req  TokenExchangeRequest
+		want int
+		code string
+	}{
+		{"narrows derived", TokenExchangeRequest{Token: derived.Token, Permissions: []string{"orders:read"}}, http.StatusOK, ""},
+		{"widens derived", TokenExchangeRequest{Token: derived.Token, Permissions: []string{"orders:write"}}, http.StatusBadRequest, "INVALID_SCOPE"},
+		{"empty scope", TokenExchangeRequest{Token: signIn.Token, Permissions: []string{}}, http.StatusBadRequest, ""},
+		{"invalid token", TokenExchangeRequest{Token: "nope", Permissions: []string{"orders:read"}}, http.StatusUnauthorized, "INVALID_TOKEN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, code := exchange(tt.req)
+			if got != tt.want || (tt.code != "" && code != tt.code) {
+				t.Errorf("exchange = %d %s, want %d %s", got, code, tt.want, tt.code)
+			}
+		})
+	}
+
+	var exchanged int
+	for _, e := range audit.events {
+		if e.Action == ActionTokenExchanged && e.Err == nil {
+			exchanged++
+		}
+	}
+	if exchanged != 2 {
+		t.Errorf("exchange events = %d, want 2", exchanged)
+	}
+}
+
+func TestTokenExchangeRequiresOption(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithTokenValidator(middleware.NewTokenValidator(pub)),
+	).RegisterRoutes(r)
+
+	if w := postJSON(t, r, "/auth/token/exchange", TokenExchangeRequest{Token: "t", Permissions: []string{"a:b"}}); w.Code != http.StatusNotFound && w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /auth/token/exchange without WithTokenExchange = %d, want it unregistered", w.Code)
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestSuspendAndReactivateUser(t *testing.T) {
+	audit := &recordingAudit{}
+	sessions := fake.NewSessionStore()
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithAudit(audit), WithSessions(sessions),
+	).RegisterRoutes(r)
+
+	w := postJSON(t, r, "/auth/signup", SignUpRequest{Email: "ann@example.com", Password: "Password123!", Username: "ann", DisplayName: "Ann"})
+	var signup SignUpResponse
+	json.NewDecoder(w.Body).Decode(&signup)
+	id := signup.User.ID.String()
+	sessions.Create(t.Context(), auth.NewSession(id, time.Hour))
+
+	if w := postJSONAs(r, "admin", "/users/"+id+"/suspend", SuspendUserRequest{}); w.Code != http.StatusBadRequest {
+		t.Errorf("suspend without reason = %d, want 400", w.Code)
+	}
+
+	w = postJSONAs(r, "admin", "/users/"+id+"/suspend", SuspendUserRequest{Reason: "chargeback"})
+	var resp UserStatusResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || resp.User.Status != auth.UserStatusSuspended || resp.SessionsEnded != 1 {
+		t.Fatalf("suspend = %d, %+v", w.Code, resp)
+	}
+	if w := postJSON(t, r, "/auth/signin", SignInRequest{Email: "ann@example.com", Password: "Password123!"}); w.Code != http.StatusForbidden {
+		t.Errorf("sign-in while suspended = %d, want 403", w.Code)
+	}
+	if w := postJSONAs(r, "admin", "/users/"+id+"/suspend", SuspendUserRequest{Reason: "again"}); w.Code != http.StatusConflict {
+		t.Errorf("suspend twice = %d, want 409", w.Code)
+	}
+
+	if w := postJSONAs(r, "admin", "/users/"+id+"/reactivate", nil); w.Code != http.StatusOK {
+		t.Fatalf("reactivate = %d: %s", w.Code, w.Body)
+	}
+	if w := postJSON(t, r, "/auth/signin", SignInRequest{Email: "ann@example.com", Password: "Password123!"}); w.Code != http.StatusOK {
+		t.Errorf("sign-in after reactivation = %d, want 200", w.Code)
+	}
+	if w := postJSONAs(r, "admin", "/users/"+id+"/reactivate", ReactivateUserRequest{Reason: "resolved"}); w.Code != http.StatusConflict {
+		t.Errorf("reactivate active user = %d, want 409", w.Code)
+	}
+	if w := postJSONAs(r, "admin", "/users/not-an-id/suspend", SuspendUserRequest{Reason: "x"}); w.Code != http.StatusBadRequest {
+		t.Errorf("suspend with bad id = %d, want 400", w.Code)
+	}
+
+	var suspended *Event
+	for i, e := range audit.events {
+		if e.Action == ActionUserSuspended && e.Err == nil {
+			suspended = &audit.events[i]
+		}
+	}
+	if suspended == nil || suspended.Subject != id || suspended.Metadata["reason"] != "chargeback" {
+		t.Errorf("suspension event = %+v, want reason chargeback", suspended)
+	}
+}
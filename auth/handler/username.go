@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+type usernameHistory struct {
+	store auth.UsernameHistoryStore
+	hold  time.Duration
+}
+
+type ChangeUsernameRequest struct {
+	Username string `json:"username" validate:"required"`
+}
+
+// userIDParam returns the user named by the id URL parameter, or the
+// authenticated user on /users/me routes. On failure it writes the error
+// and returns false.
+func (h *AuthNHandler) userIDParam(w http.ResponseWriter, r *http.Request) (auth.UserID, bool) {
+	idStr := chi.URLParam(r, "id")
+	if idStr == "" {
+		return h.currentUserID(w, r)
+	}
+
+	userID, err := auth.ParseUserID(idStr)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_USER_ID", "Invalid user ID format")
+		return userID, false
+	}
+	return userID, true
+}
+
+// handleChangeUsername serves POST /users/{id}/username and
+// POST /users/me/username. The username must be unused and, with
+// WithUsernameHistory, not released recently by another user.
+func (h *AuthNHandler) handleChangeUsername(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChangeUsernameRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	var history auth.UsernameHistoryStore
+	var hold time.Duration
+	if h.usernames != nil {
+		history, hold = h.usernames.store, h.usernames.hold
+	}
+
+	user, err := service.ChangeUsername(r.Context(), h.tx, h.userStore, history, hold, userID, req.Username, middleware.GetUserID(r.Context()))
+	h.emit(r, ActionUsernameChanged, userID.String(), err)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, user.Version)
+	httpx.WriteJSON(w, http.StatusOK, UserResponse{User: user})
+}
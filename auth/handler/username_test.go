@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestChangeUsername(t *testing.T) {
+	history := fake.NewUsernameHistoryStore()
+	r := chi.NewRouter()
+	NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		WithUsernameHistory(history, 0),
+	).RegisterRoutes(r)
+
+	w := postJSON(t, r, "/auth/signup", SignUpRequest{Email: "ann@example.com", Password: "Password123!", Username: "ann", DisplayName: "Ann"})
+	var signup SignUpResponse
+	json.NewDecoder(w.Body).Decode(&signup)
+	id := signup.User.ID.String()
+	w = postJSON(t, r, "/auth/signup", SignUpRequest{Email: "bob@example.com", Password: "Password123!", Username: "bob", DisplayName: "Bob"})
+	json.NewDecoder(w.Body).Decode(&signup)
+	bob := signup.User.ID.String()
+
+	if w := postJSON(t, r, "/users/not-a-uuid/username", ChangeUsernameRequest{Username: "annie"}); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid ID = %d, want 400", w.Code)
+	}
+	if w := postJSON(t, r, "/users/"+id+"/username", ChangeUsernameRequest{Username: "bob"}); w.Code != http.StatusConflict {
+		t.Errorf("taken username = %d, want 409", w.Code)
+	}
+
+	w = postJSONAs(r, id, "/users/me/username", ChangeUsernameRequest{Username: "annie"})
+	var resp UserResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || resp.User.Username != "annie" {
+		t.Fatalf("change = %d: %s", w.Code, w.Body)
+	}
+	if changes, _ := history.ListByUser(t.Context(), resp.User.ID); len(changes) != 1 || changes[0].ChangedBy != id {
+		t.Errorf("history = %+v", changes)
+	}
+
+	if w := postJSON(t, r, "/users/"+bob+"/username", ChangeUsernameRequest{Username: "ann"}); w.Code != http.StatusConflict {
+		t.Errorf("released username = %d, want 409", w.Code)
+	}
+	w = postJSON(t, r, "/auth/signup", SignUpRequest{Email: "eve@example.com", Password: "Password123!", Username: "ann", DisplayName: "Eve"})
+	if w.Code != http.StatusConflict {
+		t.Errorf("sign-up with released username = %d, want 409", w.Code)
+	}
+	if w := postJSON(t, r, "/users/"+id+"/username", ChangeUsernameRequest{Username: "ann"}); w.Code != http.StatusOK {
+		t.Errorf("previous owner taking it back = %d, want 200", w.Code)
+	}
+}
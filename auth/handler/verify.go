@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/crypto"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+type VerifyTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// VerifyTokenResponse identifies the subject and session of a valid token.
+// Impersonator and Scope are set for impersonation and scoped tokens, and
+// Claims to the custom claims of the token, when the validator is a
+// middleware.ClaimsValidator.
+type VerifyTokenResponse struct {
+	UserID       string            `json:"user_id"`
+	SessionID    string            `json:"session_id"`
+	Impersonator string            `json:"impersonator,omitempty"`
+	Scope        []string          `json:"scope,omitempty"`
+	Claims       map[string]string `json:"claims,omitempty"`
+}
+
+// handleVerifyToken serves POST /auth/verify. Invalid or expired tokens fail
+// with 401 INVALID_TOKEN, tokens revoked through WithRevocations with 401
+// TOKEN_REVOKED, and tokens of users who are no longer active, such as
+// suspended ones, with 401 INACTIVE_ACCOUNT.
+func (h *AuthNHandler) handleVerifyToken(w http.ResponseWriter, r *http.Request) {
+	var req VerifyTokenRequest
+	if !h.bind(w, r, &req) {
+		return
+	}
+
+	claims, ok := h.verifyToken(w, r, req.Token)
+	if !ok {
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, VerifyTokenResponse{
+		UserID:       claims.Subject,
+		SessionID:    claims.SessionID,
+		Impersonator: claims.Actor,
+		Scope:        claims.Scope,
+		Claims:       claims.Context,
+	})
+}
+
+// verifyToken returns the claims of token, or writes the error of
+// handleVerifyToken and returns false when it is not valid.
+func (h *AuthNHandler) verifyToken(w http.ResponseWriter, r *http.Request, token string) (crypto.TokenClaims, bool) {
+	ctx := r.Context()
+	var claims crypto.TokenClaims
+	var err error
+	switch v := h.validator.(type) {
+	case middleware.ContextClaimsValidator:
+		claims, err = v.ValidateClaimsContext(ctx, token)
+	case middleware.ClaimsValidator:
+		claims, err = v.ValidateClaims(token)
+	default:
+		claims.Subject, claims.SessionID, err = v.ValidateToken(token)
+	}
+	if errors.Is(err, middleware.ErrTokenRevoked) {
+		h.writeError(w, r, http.StatusUnauthorized, "TOKEN_REVOKED", "Token has been revoked")
+		return claims, false
+	}
+	if err != nil {
+		h.writeError(w, r, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid or expired token")
+		return claims, false
+	}
+
+	revoked, err := h.tokenRevoked(ctx, claims)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return claims, false
+	}
+	if revoked {
+		h.writeError(w, r, http.StatusUnauthorized, "TOKEN_REVOKED", "Token has been revoked")
+		return claims, false
+	}
+
+	if userID, err := auth.ParseUserID(claims.Subject); err == nil {
+		user, err := h.userStore.Get(ctx, userID)
+		switch {
+		case errors.Is(err, auth.ErrUserNotFound):
+		case err != nil:
+			h.handleServiceError(w, r, err)
+			return claims, false
+		case user.Status != auth.UserStatusActive:
+			h.writeError(w, r, http.StatusUnauthorized, "INACTIVE_ACCOUNT", "Account is not active")
+			return claims, false
+		}
+	}
+	return claims, true
+}
+
+// handleKeySet serves GET /.well-known/jwks.json with the keys given to
+// WithVerificationKeys. Clients may cache it for five minutes.
+func (h *AuthNHandler) handleKeySet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	httpx.WriteJSON(w, http.StatusOK, crypto.NewKeySet(h.keys...))
+}
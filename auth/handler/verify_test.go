@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/crypto"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandleVerifyToken(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	valid, _ := crypto.GenerateToken(crypto.TokenClaims{
+		Subject:   "user-1",
+		SessionID: "session-1",
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	}, priv)
+
+	h := NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(),
+		fake.NewPasswordGenerator(), fake.NewPINGenerator(), WithTokenValidator(middleware.NewTokenValidator(pub)))
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "valid token", body: `{"token": "` + valid + `"}`, wantStatus: http.StatusOK},
+		{name: "invalid token", body: `{"token": "v4.public.garbage"}`, wantStatus: http.StatusUnauthorized, wantCode: "INVALID_TOKEN"},
+		{name: "missing token", body: `{}`, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/verify", bytes.NewBufferString(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("handleVerifyToken() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if tt.wantCode != "" {
+				var resp ErrorResponse
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp.Code != tt.wantCode {
+					t.Errorf("handleVerifyToken() code = %v, want %v", resp.Code, tt.wantCode)
+				}
+				return
+			}
+
+			var resp VerifyTokenResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.UserID != "user-1" || resp.SessionID != "session-1" {
+				t.Errorf("handleVerifyToken() = %+v, want user-1/session-1", resp)
+			}
+		})
+	}
+}
+
+func TestVerifyTokenInactiveUser(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	users := fake.NewUserStore()
+	h := NewAuthNHandler(users, fake.NewCryptoService(), fake.NewTokenGenerator(),
+		fake.NewPasswordGenerator(), fake.NewPINGenerator(), WithTokenValidator(middleware.NewTokenValidator(pub)))
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	w := postJSON(t, r, "/auth/signup", SignUpRequest{Email: "ann@example.com", Password: "Password123!", Username: "ann", DisplayName: "Ann"})
+	var signup SignUpResponse
+	json.NewDecoder(w.Body).Decode(&signup)
+	token, _ := crypto.GenerateToken(crypto.TokenClaims{
+		Subject:   signup.User.ID.String(),
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	}, priv)
+
+	if w := postJSON(t, r, "/auth/verify", VerifyTokenRequest{Token: token}); w.Code != http.StatusOK {
+		t.Fatalf("verify active user = %d: %s", w.Code, w.Body)
+	}
+	if w := postJSON(t, r, "/users/"+signup.User.ID.String()+"/suspend", SuspendUserRequest{Reason: "abuse"}); w.Code != http.StatusOK {
+		t.Fatalf("suspend = %d: %s", w.Code, w.Body)
+	}
+
+	w = postJSON(t, r, "/auth/verify", VerifyTokenRequest{Token: token})
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusUnauthorized || resp.Code != "INACTIVE_ACCOUNT" {
+		t.Errorf("verify suspended user = %d %s, want 401 INACTIVE_ACCOUNT", w.Code, resp.Code)
+	}
+}
+
+func TestVerifyTokenRouteRequiresValidator(t *testing.T) {
+	r := chi.NewRouter()
+	setupAuthNHandler().RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/verify", bytes.NewBufferString(`{"token": "x"}`)))
+	if w.Code != http.StatusNotFound && w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /auth/verify without a validator = %d, want it unregistered", w.Code)
+	}
+}
+
+func TestHandleKeySet(t *testing.T) {
+	current, _, _ := ed25519.GenerateKey(rand.Reader)
+	previous, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	h := NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(),
+		fake.NewPasswordGenerator(), fake.NewPINGenerator(), WithVerificationKeys(current, previous))
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("handleKeySet() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	if w.Header().Get("Cache-Control") == "" {
+		t.Error("handleKeySet() should set Cache-Control")
+	}
+
+	keys, err := crypto.ParseKeySet(w.Body.Bytes())
+	if err != nil {
+		t.Fatalf("ParseKeySet() error = %v", err)
+	}
+	if len(keys) != 2 || !keys[0].Equal(current) || !keys[1].Equal(previous) {
+		t.Errorf("handleKeySet() published %d keys, want the current and previous keys", len(keys))
+	}
+}
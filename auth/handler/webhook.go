@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/webhook"
+	"github.com/google/uuid"
+)
+
+// webhookOnSuccess and webhookOnFailure map handler actions to the webhook
+// event published when they succeed or fail.
+var (
+	webhookOnSuccess = map[string]string{
+		ActionSignUp:       webhook.EventUserCreated,
+		ActionOAuthSignUp:  webhook.EventUserCreated,
+		ActionRoleAssigned: webhook.EventRoleAssigned,
+
+		ActionGrantRequested:       webhook.EventGrantRequested,
+		ActionGrantRequestApproved: webhook.EventGrantApproved,
+		ActionGrantRequestRejected: webhook.EventGrantRejected,
+	}
+	webhookOnFailure = map[string]string{
+		ActionSignIn:      webhook.EventSignInFailed,
+		ActionSignInByPIN: webhook.EventSignInFailed,
+	}
+)
+
+// NewWebhookHooks returns Hooks that publish user.created, role.assigned,
+// sign-in.failed and, with WithGrantApproval, the grant request events,
+// typically to a *webhook.Dispatcher. Register them with WithHooks. Publish
+// failures are logged and do not affect the request.
+func NewWebhookHooks(publisher webhook.Publisher, logger log.Logger) Hooks {
+	if logger == nil {
+		logger = log.NewNoopLogger()
+	}
+
+	publish := func(ctx context.Context, eventType string, e Event) {
+		event := &webhook.Event{
+			ID:       uuid.New(),
+			Type:     eventType,
+			At:       e.At.UTC(),
+			Actor:    middleware.GetUserID(ctx),
+			Subject:  e.Subject,
+			RemoteIP: e.RemoteIP,
+		}
+		if e.Err != nil {
+			event.Error = e.Err.Error()
+		}
+		if err := publisher.Publish(ctx, event); err != nil {
+			logger.Error("Cannot publish webhook event", "event", eventType, "error", err)
+		}
+	}
+
+	return Hooks{
+		OnSuccess: func(ctx context.Context, e Event) {
+			if eventType, ok := webhookOnSuccess[e.Action]; ok {
+				publish(ctx, eventType, e)
+			}
+		},
+		OnFailure: func(ctx context.Context, e Event) {
+			if eventType, ok := webhookOnFailure[e.Action]; ok {
+				publish(ctx, eventType, e)
+			}
+		},
+	}
+}
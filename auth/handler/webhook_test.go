@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/webhook"
+	"github.com/go-chi/chi/v5"
+)
+
+type recordingPublisher struct {
+	events []*webhook.Event
+	err    error
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, e *webhook.Event) error {
+	p.events = append(p.events, e)
+	return p.err
+}
+
+func TestNewWebhookHooks(t *testing.T) {
+	publisher := &recordingPublisher{}
+	hooks := NewWebhookHooks(publisher, nil)
+
+	authn := NewAuthNHandler(
+		fake.NewUserStore(),
+		fake.NewCryptoService(),
+		fake.NewTokenGenerator(),
+		fake.NewPasswordGenerator(),
+		fake.NewPINGenerator(),
+		WithHooks(hooks),
+	)
+	roles := fake.NewRoleStore()
+	authz := NewAuthZHandler(roles, fake.NewGrantStore(roles), WithHooks(hooks))
+
+	r := chi.NewRouter()
+	authn.RegisterRoutes(r)
+	authz.RegisterRoutes(r)
+
+	signUp := SignUpRequest{Email: "hook@example.com", Password: "Password123!", Username: "hookuser", DisplayName: "Hook"}
+	if w := postJSON(t, r, "/auth/signup", signUp); w.Code != http.StatusCreated {
+		t.Fatalf("signup status = %d, body: %s", w.Code, w.Body.String())
+	}
+	postJSON(t, r, "/auth/signin", SignInRequest{Email: "hook@example.com", Password: "Password123!"})
+	postJSON(t, r, "/auth/signin", SignInRequest{Email: "hook@example.com", Password: "wrong-Password1!"})
+
+	if len(publisher.events) != 2 {
+		t.Fatalf("published %d events, want 2: %+v", len(publisher.events), publisher.events)
+	}
+	created, failed := publisher.events[0], publisher.events[1]
+	if created.Type != webhook.EventUserCreated || created.Subject == "" || created.Error != "" {
+		t.Errorf("first event = %+v, want %s", created, webhook.EventUserCreated)
+	}
+	if failed.Type != webhook.EventSignInFailed || failed.Error == "" || failed.RemoteIP != "192.0.2.1" {
+		t.Errorf("second event = %+v, want %s", failed, webhook.EventSignInFailed)
+	}
+
+	publisher.err = errors.New("store down")
+	if w := postJSON(t, r, "/auth/signin", SignInRequest{Email: "hook@example.com", Password: "wrong-Password1!"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("signin status with failing publisher = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
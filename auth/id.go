@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"database/sql/driver"
+
+	"github.com/google/uuid"
+)
+
+// ID identifies an entity of type T. IDs of different entities are distinct
+// types, so passing a RoleID where a UserID is expected does not compile.
+// An ID is a UUID underneath: it prints, marshals to JSON and is stored in
+// databases the same way.
+type ID[T any] uuid.UUID
+
+// Typed IDs of the auth entities.
+type (
+	UserID         = ID[User]
+	RoleID         = ID[Role]
+	GrantID        = ID[Grant]
+	DeviceID       = ID[Device]
+	ConsentID      = ID[Consent]
+	GrantRequestID = ID[GrantRequest]
+)
+
+// NewID returns a new random ID.
+func NewID[T any]() ID[T] {
+	return ID[T](uuid.New())
+}
+
+// ParseID parses s, in any format accepted by uuid.Parse, as an ID.
+func ParseID[T any](s string) (ID[T], error) {
+	u, err := uuid.Parse(s)
+	if err != nil {
+		return ID[T]{}, err
+	}
+	return ID[T](u), nil
+}
+
+// NewUserID returns a new random UserID.
+func NewUserID() UserID { return NewID[User]() }
+
+// NewRoleID returns a new random RoleID.
+func NewRoleID() RoleID { return NewID[Role]() }
+
+// NewGrantID returns a new random GrantID.
+func NewGrantID() GrantID { return NewID[Grant]() }
+
+// NewDeviceID returns a new random DeviceID.
+func NewDeviceID() DeviceID { return NewID[Device]() }
+
+// NewConsentID returns a new random ConsentID.
+func NewConsentID() ConsentID { return NewID[Consent]() }
+
+// NewGrantRequestID returns a new random GrantRequestID.
+func NewGrantRequestID() GrantRequestID { return NewID[GrantRequest]() }
+
+// ParseUserID parses s as a UserID.
+func ParseUserID(s string) (UserID, error) { return ParseID[User](s) }
+
+// ParseRoleID parses s as a RoleID.
+func ParseRoleID(s string) (RoleID, error) { return ParseID[Role](s) }
+
+// ParseGrantID parses s as a GrantID.
+func ParseGrantID(s string) (GrantID, error) { return ParseID[Grant](s) }
+
+// ParseDeviceID parses s as a DeviceID.
+func ParseDeviceID(s string) (DeviceID, error) { return ParseID[Device](s) }
+
+// ParseConsentID parses s as a ConsentID.
+func ParseConsentID(s string) (ConsentID, error) { return ParseID[Consent](s) }
+
+// ParseGrantRequestID parses s as a GrantRequestID.
+func ParseGrantRequestID(s string) (GrantRequestID, error) { return ParseID[GrantRequest](s) }
+
+// UUID returns id as a plain UUID.
+func (id ID[T]) UUID() uuid.UUID {
+	return uuid.UUID(id)
+}
+
+// IsZero reports whether id is the zero ID.
+func (id ID[T]) IsZero() bool {
+	return uuid.UUID(id) == uuid.Nil
+}
+
+// String returns id in the canonical UUID form.
+func (id ID[T]) String() string {
+	return uuid.UUID(id).String()
+}
+
+// MarshalText implements encoding.TextMarshaler, so IDs are JSON strings
+// and can be map keys.
+func (id ID[T]) MarshalText() ([]byte, error) {
+	return uuid.UUID(id).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (id *ID[T]) UnmarshalText(data []byte) error {
+	return (*uuid.UUID)(id).UnmarshalText(data)
+}
+
+// Scan implements sql.Scanner.
+func (id *ID[T]) Scan(src any) error {
+	return (*uuid.UUID)(id).Scan(src)
+}
+
+// Value implements driver.Valuer.
+func (id ID[T]) Value() (driver.Value, error) {
+	return uuid.UUID(id).Value()
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestID(t *testing.T) {
+	id := NewUserID()
+	if id.IsZero() {
+		t.Fatal("NewUserID() returned the zero ID")
+	}
+	if id.String() != id.UUID().String() {
+		t.Errorf("String() = %s, want %s", id.String(), id.UUID())
+	}
+
+	parsed, err := ParseUserID(id.String())
+	if err != nil || parsed != id {
+		t.Errorf("ParseUserID(%s) = %v, %v", id, parsed, err)
+	}
+	if _, err := ParseRoleID("not-a-uuid"); err == nil {
+		t.Error("ParseRoleID() accepted an invalid ID")
+	}
+
+	var zero GrantID
+	if !zero.IsZero() {
+		t.Error("zero GrantID is not zero")
+	}
+}
+
+func TestIDJSON(t *testing.T) {
+	grant := NewGrant("ann", NewRoleID(), "admin")
+
+	data, err := json.Marshal(grant)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var raw map[string]any
+	json.Unmarshal(data, &raw)
+	if raw["role_id"] != grant.RoleID.String() {
+		t.Errorf("role_id = %v, want the UUID string %s", raw["role_id"], grant.RoleID)
+	}
+
+	var got Grant
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.ID != grant.ID || got.RoleID != grant.RoleID {
+		t.Errorf("round trip = %+v, want %+v", got, grant)
+	}
+
+	keyed, _ := json.Marshal(map[RoleID]int{grant.RoleID: 1})
+	if string(keyed) != `{"`+grant.RoleID.String()+`":1}` {
+		t.Errorf("map key JSON = %s", keyed)
+	}
+}
+
+func TestIDSQL(t *testing.T) {
+	id := NewRoleID()
+
+	v, err := id.Value()
+	if err != nil || v != id.String() {
+		t.Errorf("Value() = %v, %v; want %s", v, err, id)
+	}
+
+	var scanned RoleID
+	if err := scanned.Scan(id.String()); err != nil || scanned != id {
+		t.Errorf("Scan(string) = %v, %v; want %s", scanned, err, id)
+	}
+	u := uuid.UUID(id)
+	if err := scanned.Scan(u[:]); err != nil || scanned != id {
+		t.Errorf("Scan(bytes) = %v, %v; want %s", scanned, err, id)
+	}
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Identity links a user to an account at an external identity provider.
+// Provider is the configured provider name and Subject the provider's
+// stable user identifier; together they are unique.
+type Identity struct {
+	ID        uuid.UUID `json:"id" db:"id" bson:"_id"`
+	UserID    UserID    `json:"user_id" db:"user_id" bson:"user_id"`
+	Provider  string    `json:"provider" db:"provider" bson:"provider"`
+	Subject   string    `json:"subject" db:"subject" bson:"subject"`
+	CreatedAt time.Time `json:"created_at" db:"created_at" bson:"created_at"`
+}
+
+func NewIdentity(userID UserID, provider, subject string) *Identity {
+	return &Identity{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Provider:  provider,
+		Subject:   subject,
+		CreatedAt: time.Now(),
+	}
+}
+
+// ExternalProfile is the user information returned by an identity provider
+// after a successful sign-in.
+type ExternalProfile struct {
+	Provider      string `json:"provider"`
+	Subject       string `json:"subject"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Username      string `json:"username"`
+}
+
+func (p *ExternalProfile) Validate() error {
+	if strings.TrimSpace(p.Provider) == "" || strings.TrimSpace(p.Subject) == "" {
+		return ErrInvalidIdentity
+	}
+	return nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 
 	"github.com/aquamarinepk/aqm/auth"
-	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -30,7 +29,7 @@ func (s *grantStore) Create(ctx context.Context, grant *auth.Grant) error {
 	return nil
 }
 
-func (s *grantStore) Delete(ctx context.Context, username string, roleID uuid.UUID) error {
+func (s *grantStore) Delete(ctx context.Context, username string, roleID auth.RoleID) error {
 	filter := bson.M{"username": username, "role_id": roleID}
 	result, err := s.grantsColl.DeleteOne(ctx, filter)
 	if err != nil {
@@ -58,7 +57,7 @@ func (s *grantStore) GetUserGrants(ctx context.Context, username string) ([]*aut
 	return grants, nil
 }
 
-func (s *grantStore) GetRoleGrants(ctx context.Context, roleID uuid.UUID) ([]*auth.Grant, error) {
+func (s *grantStore) GetRoleGrants(ctx context.Context, roleID auth.RoleID) ([]*auth.Grant, error) {
 	filter := bson.M{"role_id": roleID}
 	opts := options.Find().SetSort(bson.D{{Key: "assigned_at", Value: -1}})
 	cursor, err := s.grantsColl.Find(ctx, filter, opts)
@@ -93,7 +92,7 @@ func (s *grantStore) GetUserRoles(ctx context.Context, username string) ([]*auth
 	}
 
 	// Extract role IDs
-	roleIDs := make([]uuid.UUID, len(grants))
+	roleIDs := make([]auth.RoleID, len(grants))
 	for i, g := range grants {
 		roleIDs[i] = g.RoleID
 	}
@@ -135,4 +134,8 @@ func (s *grantStore) HasRole(ctx context.Context, username string, roleName stri
 	return count > 0, nil
 }
 
+func (s *grantStore) Ping(ctx context.Context) error {
+	return s.grantsColl.Database().Client().Ping(ctx, nil)
+}
+
 var _ auth.GrantStore = (*grantStore)(nil)
@@ -4,7 +4,6 @@ import (
 	"context"
 
 	"github.com/aquamarinepk/aqm/auth"
-	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -26,7 +25,7 @@ func (s *roleStore) Create(ctx context.Context, role *auth.Role) error {
 	return nil
 }
 
-func (s *roleStore) Get(ctx context.Context, id uuid.UUID) (*auth.Role, error) {
+func (s *roleStore) Get(ctx context.Context, id auth.RoleID) (*auth.Role, error) {
 	filter := bson.M{"_id": id}
 	role := &auth.Role{}
 	err := s.coll.FindOne(ctx, filter).Decode(role)
@@ -52,22 +51,25 @@ func (s *roleStore) GetByName(ctx context.Context, name string) (*auth.Role, err
 	return role, nil
 }
 
+// Update writes role if its Version still matches the stored one and
+// increments it; otherwise it returns auth.ErrVersionConflict.
 func (s *roleStore) Update(ctx context.Context, role *auth.Role) error {
-	filter := bson.M{"_id": role.ID}
-	update := bson.M{"$set": role}
-	result, err := s.coll.UpdateOne(ctx, filter, update)
+	next := *role
+	next.Version++
+	result, err := s.coll.UpdateOne(ctx, versionFilter(role.ID, role.Version), bson.M{"$set": &next})
 	if err != nil {
 		return err
 	}
 	if result.MatchedCount == 0 {
-		return auth.ErrRoleNotFound
+		return missingOrConflict(ctx, s.coll, role.ID, auth.ErrRoleNotFound)
 	}
+	role.Version = next.Version
 	return nil
 }
 
-func (s *roleStore) Delete(ctx context.Context, id uuid.UUID) error {
+func (s *roleStore) Delete(ctx context.Context, id auth.RoleID) error {
 	filter := bson.M{"_id": id}
-	update := bson.M{"$set": bson.M{"status": "inactive", "updated_at": bson.M{"$currentDate": true}}}
+	update := bson.M{"$set": bson.M{"status": "inactive", "updated_at": bson.M{"$currentDate": true}}, "$inc": bson.M{"version": 1}}
 	result, err := s.coll.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
@@ -109,4 +111,8 @@ func (s *roleStore) ListByStatus(ctx context.Context, status auth.RoleStatus) ([
 	return roles, nil
 }
 
+func (s *roleStore) Ping(ctx context.Context) error {
+	return s.coll.Database().Client().Ping(ctx, nil)
+}
+
 var _ auth.RoleStore = (*roleStore)(nil)
@@ -2,9 +2,11 @@ package mongo
 
 import (
 	"context"
+	"fmt"
+	"regexp"
+	"strconv"
 
 	"github.com/aquamarinepk/aqm/auth"
-	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -26,7 +28,7 @@ func (s *userStore) Create(ctx context.Context, user *auth.User) error {
 	return nil
 }
 
-func (s *userStore) Get(ctx context.Context, id uuid.UUID) (*auth.User, error) {
+func (s *userStore) Get(ctx context.Context, id auth.UserID) (*auth.User, error) {
 	filter := bson.M{"_id": id}
 	user := &auth.User{}
 	err := s.coll.FindOne(ctx, filter).Decode(user)
@@ -78,22 +80,25 @@ func (s *userStore) GetByPINLookup(ctx context.Context, lookup []byte) (*auth.Us
 	return user, nil
 }
 
+// Update writes user if its Version still matches the stored one and
+// increments it; otherwise it returns auth.ErrVersionConflict.
 func (s *userStore) Update(ctx context.Context, user *auth.User) error {
-	filter := bson.M{"_id": user.ID}
-	update := bson.M{"$set": user}
-	result, err := s.coll.UpdateOne(ctx, filter, update)
+	next := *user
+	next.Version++
+	result, err := s.coll.UpdateOne(ctx, versionFilter(user.ID, user.Version), bson.M{"$set": &next})
 	if err != nil {
 		return err
 	}
 	if result.MatchedCount == 0 {
-		return auth.ErrUserNotFound
+		return missingOrConflict(ctx, s.coll, user.ID, auth.ErrUserNotFound)
 	}
+	user.Version = next.Version
 	return nil
 }
 
-func (s *userStore) Delete(ctx context.Context, id uuid.UUID) error {
+func (s *userStore) Delete(ctx context.Context, id auth.UserID) error {
 	filter := bson.M{"_id": id}
-	update := bson.M{"$set": bson.M{"status": "deleted", "updated_at": bson.M{"$currentDate": true}}}
+	update := bson.M{"$set": bson.M{"status": "deleted", "updated_at": bson.M{"$currentDate": true}}, "$inc": bson.M{"version": 1}}
 	result, err := s.coll.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
@@ -135,4 +140,81 @@ func (s *userStore) ListByStatus(ctx context.Context, status auth.UserStatus) ([
 	return users, nil
 }
 
+// Search supports every UserQuery field except Role, for which it returns
+// auth.ErrInvalidUserQuery: grants live in another collection.
+func (s *userStore) Search(ctx context.Context, q auth.UserQuery) (*auth.UserPage, error) {
+	q, err := q.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	if q.Role != "" {
+		return nil, fmt.Errorf("%w: role filter is not supported", auth.ErrInvalidUserQuery)
+	}
+
+	filter := bson.M{}
+	if q.UsernamePrefix != "" {
+		filter["username"] = bson.M{"$regex": "^" + regexp.QuoteMeta(q.UsernamePrefix)}
+	}
+	if q.Name != "" {
+		filter["name"] = bson.M{"$regex": regexp.QuoteMeta(q.Name), "$options": "i"}
+	}
+	if q.Status != "" {
+		filter["status"] = q.Status
+	}
+	created := bson.M{}
+	if !q.CreatedAfter.IsZero() {
+		created["$gte"] = q.CreatedAfter
+	}
+	if !q.CreatedBefore.IsZero() {
+		created["$lt"] = q.CreatedBefore
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+	for key, value := range q.Attributes {
+		filter["attributes."+key] = bson.M{"$in": attributeCandidates(value)}
+	}
+
+	total, err := s.coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64(q.Offset)).
+		SetLimit(int64(q.Limit))
+	cursor, err := s.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []*auth.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return &auth.UserPage{Users: users, Total: int(total), Limit: q.Limit, Offset: q.Offset}, nil
+}
+
+// attributeCandidates returns the stored values whose text form is value,
+// matching auth.Attributes.Text: the string itself and, when value parses as
+// one, the boolean or number.
+func attributeCandidates(value string) []any {
+	candidates := []any{value}
+	if b, err := strconv.ParseBool(value); err == nil && strconv.FormatBool(b) == value {
+		candidates = append(candidates, b)
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		candidates = append(candidates, n)
+	} else if f, err := strconv.ParseFloat(value, 64); err == nil {
+		candidates = append(candidates, f)
+	}
+	return candidates
+}
+
+func (s *userStore) Ping(ctx context.Context) error {
+	return s.coll.Database().Client().Ping(ctx, nil)
+}
+
 var _ auth.UserStore = (*userStore)(nil)
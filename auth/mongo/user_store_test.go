@@ -148,6 +148,7 @@ func TestMongoUserStoreUpdate(t *testing.T) {
 	user.PasswordSalt = []byte("salt")
 	user.BeforeCreate()
 	store.Create(ctx, user)
+	stale := *user
 
 	user.Name = "Updated Name"
 	user.BeforeUpdate()
@@ -161,4 +162,11 @@ func TestMongoUserStoreUpdate(t *testing.T) {
 	if retrieved.Name != "Updated Name" {
 		t.Errorf("Update() name = %v, want Updated Name", retrieved.Name)
 	}
+	if retrieved.Version != 2 {
+		t.Errorf("Update() version = %d, want 2", retrieved.Version)
+	}
+
+	if err := store.Update(ctx, &stale); err != auth.ErrVersionConflict {
+		t.Errorf("Update() with stale version error = %v, want %v", err, auth.ErrVersionConflict)
+	}
 }
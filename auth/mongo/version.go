@@ -0,0 +1,32 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// versionFilter matches the document with id stored at version. Documents
+// written before versioning have no version field and match version 0.
+func versionFilter[T any](id auth.ID[T], version int64) bson.M {
+	if version == 0 {
+		return bson.M{"_id": id, "version": bson.M{"$in": bson.A{0, nil}}}
+	}
+	return bson.M{"_id": id, "version": version}
+}
+
+// missingOrConflict explains a versioned update that matched no document:
+// it is gone (notFound) or was changed since it was read
+// (auth.ErrVersionConflict).
+func missingOrConflict[T any](ctx context.Context, coll *mongo.Collection, id auth.ID[T], notFound error) error {
+	n, err := coll.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return auth.ErrVersionConflict
+}
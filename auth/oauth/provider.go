@@ -0,0 +1,365 @@
+// Package oauth implements the OAuth2 authorization-code flow with PKCE for
+// federated sign-in through Google, GitHub and generic OIDC providers.
+//
+// Providers are built from config.AuthConfig.OAuth with FromConfig and
+// mounted on handler.AuthNHandler with handler.WithOAuth.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/config"
+)
+
+// Provider types accepted in config.OAuthProviderConfig.Type.
+const (
+	TypeGoogle = "google"
+	TypeGitHub = "github"
+	TypeOIDC   = "oidc"
+)
+
+// ErrProvider wraps failures talking to an identity provider.
+var ErrProvider = errors.New("identity provider error")
+
+// Provider is an OAuth2 identity provider.
+type Provider interface {
+	// Name is the configured provider name used in routes and identities.
+	Name() string
+	// AuthCodeURL returns the URL the user is redirected to in order to sign in.
+	AuthCodeURL(ctx context.Context, state, codeChallenge string) (string, error)
+	// Exchange trades an authorization code for a token.
+	Exchange(ctx context.Context, code, codeVerifier string) (*Token, error)
+	// Profile fetches the signed-in user's profile.
+	Profile(ctx context.Context, token *Token) (*auth.ExternalProfile, error)
+}
+
+// Token is an OAuth2 token response.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// Endpoints are the provider URLs used by the flow.
+type Endpoints struct {
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+}
+
+var (
+	googleEndpoints = Endpoints{
+		AuthURL:     "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:    "https://oauth2.googleapis.com/token",
+		UserInfoURL: "https://openidconnect.googleapis.com/v1/userinfo",
+	}
+	githubEndpoints = Endpoints{
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+	}
+
+	defaultScopes = map[string][]string{
+		TypeGoogle: {"openid", "email", "profile"},
+		TypeGitHub: {"read:user", "user:email"},
+		TypeOIDC:   {"openid", "email", "profile"},
+	}
+)
+
+// New creates the provider described by cfg. Endpoints set in cfg override
+// the built-in Google and GitHub ones (e.g. for GitHub Enterprise); OIDC
+// providers without explicit endpoints discover them from cfg.Issuer on first use.
+// A nil client uses a client with a 10s timeout.
+func New(name string, cfg config.OAuthProviderConfig, client *http.Client) (Provider, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("oauth provider %s: client_id and redirect_url are required", name)
+	}
+
+	p := &provider{name: name, cfg: cfg, client: client}
+	if len(p.cfg.Scopes) == 0 {
+		p.cfg.Scopes = defaultScopes[cfg.Type]
+	}
+
+	switch cfg.Type {
+	case TypeGoogle:
+		p.endpoints = googleEndpoints
+		p.profile = p.oidcProfile
+	case TypeGitHub:
+		p.endpoints = githubEndpoints
+		p.profile = p.githubProfile
+	case TypeOIDC:
+		if cfg.Issuer == "" && (cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "") {
+			return nil, fmt.Errorf("oauth provider %s: issuer or explicit endpoints are required", name)
+		}
+		p.profile = p.oidcProfile
+	default:
+		return nil, fmt.Errorf("oauth provider %s: unknown type %q", name, cfg.Type)
+	}
+
+	if cfg.AuthURL != "" {
+		p.endpoints.AuthURL = cfg.AuthURL
+	}
+	if cfg.TokenURL != "" {
+		p.endpoints.TokenURL = cfg.TokenURL
+	}
+	if cfg.UserInfoURL != "" {
+		p.endpoints.UserInfoURL = cfg.UserInfoURL
+	}
+	p.discovered = p.endpoints.AuthURL != "" && p.endpoints.TokenURL != "" && p.endpoints.UserInfoURL != ""
+
+	return p, nil
+}
+
+type provider struct {
+	name    string
+	cfg     config.OAuthProviderConfig
+	client  *http.Client
+	profile func(ctx context.Context, token *Token) (*auth.ExternalProfile, error)
+
+	mu         sync.Mutex
+	endpoints  Endpoints
+	discovered bool
+}
+
+func (p *provider) Name() string {
+	return p.name
+}
+
+func (p *provider) AuthCodeURL(ctx context.Context, state, codeChallenge string) (string, error) {
+	endpoints, err := p.resolveEndpoints(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(endpoints.AuthURL, "?") {
+		sep = "&"
+	}
+	return endpoints.AuthURL + sep + q.Encode(), nil
+}
+
+func (p *provider) Exchange(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	endpoints, err := p.resolveEndpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"client_secret": {p.cfg.ClientSecret},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoints.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var resp struct {
+		Token
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := p.do(req, &resp); err != nil {
+		return nil, fmt.Errorf("cannot exchange code: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("%w: token endpoint returned %s: %s", ErrProvider, resp.Error, resp.ErrorDescription)
+	}
+	if resp.AccessToken == "" {
+		return nil, fmt.Errorf("%w: token endpoint returned no access token", ErrProvider)
+	}
+	return &resp.Token, nil
+}
+
+func (p *provider) Profile(ctx context.Context, token *Token) (*auth.ExternalProfile, error) {
+	if token == nil || token.AccessToken == "" {
+		return nil, fmt.Errorf("%w: missing access token", ErrProvider)
+	}
+	profile, err := p.profile(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch profile: %w", err)
+	}
+	profile.Provider = p.name
+	return profile, nil
+}
+
+// oidcProfile reads the standard OIDC userinfo claims.
+func (p *provider) oidcProfile(ctx context.Context, token *Token) (*auth.ExternalProfile, error) {
+	endpoints, err := p.resolveEndpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims struct {
+		Subject           string `json:"sub"`
+		Email             string `json:"email"`
+		EmailVerified     any    `json:"email_verified"`
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+	if err := p.get(ctx, endpoints.UserInfoURL, token, &claims); err != nil {
+		return nil, err
+	}
+
+	verified := false
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		verified = v
+	case string:
+		verified, _ = strconv.ParseBool(v)
+	}
+
+	return &auth.ExternalProfile{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: verified,
+		Name:          claims.Name,
+		Username:      claims.PreferredUsername,
+	}, nil
+}
+
+// githubProfile reads the GitHub user and, since the public email may be
+// hidden or unverified, its primary verified email from /user/emails.
+func (p *provider) githubProfile(ctx context.Context, token *Token) (*auth.ExternalProfile, error) {
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	if err := p.get(ctx, p.endpoints.UserInfoURL, token, &user); err != nil {
+		return nil, err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(ctx, strings.TrimSuffix(p.endpoints.UserInfoURL, "/")+"/emails", token, &emails); err != nil {
+		return nil, err
+	}
+
+	profile := &auth.ExternalProfile{
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Email:    user.Email,
+		Name:     user.Name,
+		Username: user.Login,
+	}
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			profile.Email = e.Email
+			profile.EmailVerified = true
+			break
+		}
+	}
+	return profile, nil
+}
+
+// resolveEndpoints returns the provider endpoints, running OIDC discovery
+// against the issuer the first time they are needed.
+func (p *provider) resolveEndpoints(ctx context.Context) (Endpoints, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovered {
+		return p.endpoints, nil
+	}
+
+	wellKnown := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return Endpoints{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	var doc struct {
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	if err := p.do(req, &doc); err != nil {
+		return Endpoints{}, fmt.Errorf("cannot discover %s endpoints: %w", p.name, err)
+	}
+
+	if p.endpoints.AuthURL == "" {
+		p.endpoints.AuthURL = doc.AuthorizationEndpoint
+	}
+	if p.endpoints.TokenURL == "" {
+		p.endpoints.TokenURL = doc.TokenEndpoint
+	}
+	if p.endpoints.UserInfoURL == "" {
+		p.endpoints.UserInfoURL = doc.UserInfoEndpoint
+	}
+	if p.endpoints.AuthURL == "" || p.endpoints.TokenURL == "" || p.endpoints.UserInfoURL == "" {
+		return Endpoints{}, fmt.Errorf("%w: discovery document for %s is missing endpoints", ErrProvider, p.name)
+	}
+
+	p.discovered = true
+	return p.endpoints, nil
+}
+
+func (p *provider) get(ctx context.Context, endpoint string, token *Token, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+	return p.do(req, v)
+}
+
+func (p *provider) do(req *http.Request, v any) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrProvider, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrProvider, err)
+	}
+	// Token endpoints report failures as 400 with a JSON error body, which
+	// Exchange decodes; any other non-200 status is an error.
+	if resp.StatusCode != http.StatusOK && !(resp.StatusCode == http.StatusBadRequest && req.Method == http.MethodPost) {
+		return fmt.Errorf("%w: %s returned status %d", ErrProvider, req.URL.Host, resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%w: cannot decode response from %s: %v", ErrProvider, req.URL.Host, err)
+	}
+	return nil
+}
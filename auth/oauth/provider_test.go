@@ -0,0 +1,223 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/config"
+)
+
+func newTestIDP(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"userinfo_endpoint":      srv.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "good-code" || r.Form.Get("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "bad code"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "at-123", "token_type": "Bearer"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer at-123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"sub":                "sub-1",
+			"email":              "jane@example.com",
+			"email_verified":     "true",
+			"name":               "Jane Doe",
+			"preferred_username": "jane",
+		})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"id": 42, "login": "octocat", "name": "Octo Cat", "email": nil})
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"email": "other@example.com", "primary": false, "verified": true},
+			{"email": "octo@example.com", "primary": true, "verified": true},
+		})
+	})
+
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.OAuthProviderConfig
+		wantErr bool
+	}{
+		{
+			name: "google",
+			cfg:  config.OAuthProviderConfig{Type: TypeGoogle, ClientID: "id", RedirectURL: "http://app/cb"},
+		},
+		{
+			name: "github",
+			cfg:  config.OAuthProviderConfig{Type: TypeGitHub, ClientID: "id", RedirectURL: "http://app/cb"},
+		},
+		{
+			name: "oidc with issuer",
+			cfg:  config.OAuthProviderConfig{Type: TypeOIDC, ClientID: "id", RedirectURL: "http://app/cb", Issuer: "http://idp"},
+		},
+		{
+			name:    "oidc without issuer",
+			cfg:     config.OAuthProviderConfig{Type: TypeOIDC, ClientID: "id", RedirectURL: "http://app/cb"},
+			wantErr: true,
+		},
+		{
+			name:    "missing client id",
+			cfg:     config.OAuthProviderConfig{Type: TypeGoogle, RedirectURL: "http://app/cb"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			cfg:     config.OAuthProviderConfig{Type: "facebook", ClientID: "id", RedirectURL: "http://app/cb"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.name, tt.cfg, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && p.Name() != tt.name {
+				t.Errorf("Name() = %q, want %q", p.Name(), tt.name)
+			}
+		})
+	}
+}
+
+func TestProviderAuthCodeURL(t *testing.T) {
+	p, err := New("google", config.OAuthProviderConfig{
+		Type:        TypeGoogle,
+		ClientID:    "client-1",
+		RedirectURL: "http://app/auth/oauth/google/callback",
+	}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	raw, err := p.AuthCodeURL(context.Background(), "state-1", "challenge-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	if !strings.HasPrefix(raw, googleEndpoints.AuthURL+"?") {
+		t.Errorf("AuthCodeURL() = %q, want prefix %q", raw, googleEndpoints.AuthURL)
+	}
+
+	u, _ := url.Parse(raw)
+	q := u.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             "client-1",
+		"redirect_uri":          "http://app/auth/oauth/google/callback",
+		"scope":                 "openid email profile",
+		"state":                 "state-1",
+		"code_challenge":        "challenge-1",
+		"code_challenge_method": "S256",
+	}
+	for k, v := range want {
+		if got := q.Get(k); got != v {
+			t.Errorf("query %s = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestProviderOIDCFlow(t *testing.T) {
+	idp := newTestIDP(t)
+	p, err := New("corp", config.OAuthProviderConfig{
+		Type:        TypeOIDC,
+		ClientID:    "id",
+		RedirectURL: "http://app/cb",
+		Issuer:      idp.URL,
+	}, idp.Client())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := context.Background()
+
+	raw, err := p.AuthCodeURL(ctx, "s", "c")
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	if !strings.HasPrefix(raw, idp.URL+"/authorize?") {
+		t.Errorf("AuthCodeURL() = %q, want discovered endpoint", raw)
+	}
+
+	if _, err := p.Exchange(ctx, "bad-code", "verifier"); !errors.Is(err, ErrProvider) {
+		t.Errorf("Exchange(bad) error = %v, want ErrProvider", err)
+	}
+
+	token, err := p.Exchange(ctx, "good-code", "verifier")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if token.AccessToken != "at-123" {
+		t.Errorf("AccessToken = %q, want at-123", token.AccessToken)
+	}
+
+	profile, err := p.Profile(ctx, token)
+	if err != nil {
+		t.Fatalf("Profile() error = %v", err)
+	}
+	if profile.Provider != "corp" || profile.Subject != "sub-1" || profile.Email != "jane@example.com" {
+		t.Errorf("Profile() = %+v", profile)
+	}
+	if !profile.EmailVerified {
+		t.Error("EmailVerified = false, want true")
+	}
+	if profile.Username != "jane" || profile.Name != "Jane Doe" {
+		t.Errorf("Profile() = %+v", profile)
+	}
+
+	if _, err := p.Profile(ctx, &Token{AccessToken: "wrong"}); !errors.Is(err, ErrProvider) {
+		t.Errorf("Profile(wrong token) error = %v, want ErrProvider", err)
+	}
+}
+
+func TestProviderGitHubProfile(t *testing.T) {
+	idp := newTestIDP(t)
+	p, err := New("github", config.OAuthProviderConfig{
+		Type:        TypeGitHub,
+		ClientID:    "id",
+		RedirectURL: "http://app/cb",
+		UserInfoURL: idp.URL + "/user",
+	}, idp.Client())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	profile, err := p.Profile(context.Background(), &Token{AccessToken: "at"})
+	if err != nil {
+		t.Fatalf("Profile() error = %v", err)
+	}
+	if profile.Subject != "42" || profile.Username != "octocat" {
+		t.Errorf("Profile() = %+v", profile)
+	}
+	if profile.Email != "octo@example.com" || !profile.EmailVerified {
+		t.Errorf("Profile() email = %q verified %v, want primary verified email", profile.Email, profile.EmailVerified)
+	}
+}
@@ -0,0 +1,173 @@
+package oauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+)
+
+var (
+	// ErrUnknownProvider is returned for a provider name that is not registered.
+	ErrUnknownProvider = errors.New("unknown oauth provider")
+	// ErrInvalidState is returned when a callback's state does not match the
+	// one issued at start, has been tampered with, or has expired.
+	ErrInvalidState = errors.New("invalid oauth state")
+)
+
+// DefaultStateTTL is how long a sign-in started at /start may take to complete.
+const DefaultStateTTL = 10 * time.Minute
+
+// State is the per-sign-in data kept between /start and /callback.
+// Value is sent to the provider as the state parameter; Verifier is the
+// PKCE code verifier.
+type State struct {
+	Provider  string    `json:"p"`
+	Value     string    `json:"s"`
+	Verifier  string    `json:"v"`
+	ExpiresAt time.Time `json:"e"`
+}
+
+// CodeChallenge returns the S256 PKCE challenge for the state's verifier.
+func (s *State) CodeChallenge() string {
+	sum := sha256.Sum256([]byte(s.Verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Registry holds the configured providers and seals sign-in state so it
+// can be kept client-side in a cookie.
+type Registry struct {
+	providers map[string]Provider
+	key       []byte
+	ttl       time.Duration
+	now       func() time.Time
+}
+
+// NewRegistry creates a registry whose state is signed with key.
+func NewRegistry(key []byte, providers ...Provider) *Registry {
+	r := &Registry{
+		providers: make(map[string]Provider, len(providers)),
+		key:       key,
+		ttl:       DefaultStateTTL,
+		now:       time.Now,
+	}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+	}
+	return r
+}
+
+// FromConfig builds a registry for every provider in cfg.OAuth, signing
+// state with cfg.SessionSecret.
+func FromConfig(cfg config.AuthConfig, client *http.Client) (*Registry, error) {
+	if len(cfg.OAuth) > 0 && cfg.SessionSecret == "" {
+		return nil, fmt.Errorf("auth.session_secret is required for oauth providers")
+	}
+
+	providers := make([]Provider, 0, len(cfg.OAuth))
+	for name, pc := range cfg.OAuth {
+		p, err := New(name, pc, client)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	return NewRegistry([]byte(cfg.SessionSecret), providers...), nil
+}
+
+// Provider returns the provider registered under name.
+func (r *Registry) Provider(name string) (Provider, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// Names returns the registered provider names in sorted order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewState creates fresh state for a sign-in with provider.
+func (r *Registry) NewState(provider string) (*State, error) {
+	value, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomString(48)
+	if err != nil {
+		return nil, err
+	}
+	return &State{
+		Provider:  provider,
+		Value:     value,
+		Verifier:  verifier,
+		ExpiresAt: r.now().Add(r.ttl),
+	}, nil
+}
+
+// Seal encodes and signs s.
+func (r *Registry) Seal(s *State) (string, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + r.sign(payload), nil
+}
+
+// Open verifies sealed and checks that it was issued for provider with the
+// given state value and has not expired.
+func (r *Registry) Open(sealed, provider, value string) (*State, error) {
+	payload, sig, ok := strings.Cut(sealed, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(r.sign(payload))) {
+		return nil, ErrInvalidState
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, ErrInvalidState
+	}
+
+	if s.Provider != provider || subtle.ConstantTimeCompare([]byte(s.Value), []byte(value)) != 1 {
+		return nil, ErrInvalidState
+	}
+	if !r.now().Before(s.ExpiresAt) {
+		return nil, ErrInvalidState
+	}
+	return &s, nil
+}
+
+func (r *Registry) sign(payload string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot generate random state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
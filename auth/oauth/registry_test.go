@@ -0,0 +1,101 @@
+package oauth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+)
+
+func TestRegistrySealOpen(t *testing.T) {
+	r := NewRegistry([]byte("secret"))
+
+	state, err := r.NewState("google")
+	if err != nil {
+		t.Fatalf("NewState() error = %v", err)
+	}
+	sealed, err := r.Seal(state)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	got, err := r.Open(sealed, "google", state.Value)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got.Verifier != state.Verifier {
+		t.Errorf("Verifier = %q, want %q", got.Verifier, state.Verifier)
+	}
+	if got.CodeChallenge() == "" || got.CodeChallenge() == got.Verifier {
+		t.Errorf("CodeChallenge() = %q, want S256 of verifier", got.CodeChallenge())
+	}
+
+	tests := []struct {
+		name     string
+		sealed   string
+		provider string
+		value    string
+	}{
+		{name: "wrong provider", sealed: sealed, provider: "github", value: state.Value},
+		{name: "wrong value", sealed: sealed, provider: "google", value: "other"},
+		{name: "tampered payload", sealed: "x" + sealed, provider: "google", value: state.Value},
+		{name: "no signature", sealed: strings.Split(sealed, ".")[0], provider: "google", value: state.Value},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := r.Open(tt.sealed, tt.provider, tt.value); !errors.Is(err, ErrInvalidState) {
+				t.Errorf("Open() error = %v, want ErrInvalidState", err)
+			}
+		})
+	}
+
+	other := NewRegistry([]byte("other-secret"))
+	if _, err := other.Open(sealed, "google", state.Value); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Open() with other key error = %v, want ErrInvalidState", err)
+	}
+}
+
+func TestRegistryOpenExpired(t *testing.T) {
+	r := NewRegistry([]byte("secret"))
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	state, _ := r.NewState("google")
+	sealed, _ := r.Seal(state)
+
+	r.now = func() time.Time { return now.Add(DefaultStateTTL + time.Second) }
+	if _, err := r.Open(sealed, "google", state.Value); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Open() error = %v, want ErrInvalidState", err)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	cfg := config.AuthConfig{
+		SessionSecret: "secret",
+		OAuth: map[string]config.OAuthProviderConfig{
+			"google": {Type: TypeGoogle, ClientID: "id", RedirectURL: "http://app/cb"},
+			"github": {Type: TypeGitHub, ClientID: "id", RedirectURL: "http://app/cb"},
+		},
+	}
+
+	r, err := FromConfig(cfg, nil)
+	if err != nil {
+		t.Fatalf("FromConfig() error = %v", err)
+	}
+	if got := strings.Join(r.Names(), ","); got != "github,google" {
+		t.Errorf("Names() = %q, want github,google", got)
+	}
+	if _, err := r.Provider("google"); err != nil {
+		t.Errorf("Provider(google) error = %v", err)
+	}
+	if _, err := r.Provider("okta"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("Provider(okta) error = %v, want ErrUnknownProvider", err)
+	}
+
+	cfg.SessionSecret = ""
+	if _, err := FromConfig(cfg, nil); err == nil {
+		t.Error("FromConfig() without session secret error = nil, want error")
+	}
+}
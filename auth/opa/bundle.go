@@ -0,0 +1,169 @@
+package opa
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// MaxBundleSize caps the uncompressed size of a bundle read from a server.
+const MaxBundleSize = 16 << 20
+
+// Policy is one Rego module of a bundle.
+type Policy struct {
+	Path   string `json:"path"`
+	Module string `json:"module"`
+}
+
+// Bundle is a set of Rego policies and the data they read, laid out as an
+// OPA bundle: *.rego files, data.json files merged into Data at their
+// directory's path, and an optional .manifest holding the revision.
+type Bundle struct {
+	Revision string         `json:"revision"`
+	Policies []Policy       `json:"policies"`
+	Data     map[string]any `json:"data,omitempty"`
+}
+
+// Source returns the current bundle.
+type Source func(ctx context.Context) (*Bundle, error)
+
+// FSSource reads the bundle under root in fsys, typically an embed.FS.
+func FSSource(fsys fs.FS, root string) Source {
+	return func(context.Context) (*Bundle, error) {
+		return LoadFS(fsys, root)
+	}
+}
+
+// HTTPSource downloads a gzipped tarball bundle from url, as served by OPA
+// bundle servers. A nil client uses http.DefaultClient.
+func HTTPSource(client *http.Client, url string) Source {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context) (*Bundle, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetch bundle: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetch bundle: server returned %d", resp.StatusCode)
+		}
+		return ReadBundle(resp.Body)
+	}
+}
+
+// LoadFS reads the bundle under root in fsys.
+func LoadFS(fsys fs.FS, root string) (*Bundle, error) {
+	b := &Bundle{}
+	err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+		return b.add(rel, data)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load bundle %s: %w", root, err)
+	}
+	b.sort()
+	return b, nil
+}
+
+// ReadBundle reads a gzipped tarball bundle.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	defer gz.Close()
+
+	b := &Bundle{}
+	tr := tar.NewReader(io.LimitReader(gz, MaxBundleSize))
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		if err := b.add(strings.TrimPrefix(path.Clean("/"+hdr.Name), "/"), data); err != nil {
+			return nil, err
+		}
+	}
+	b.sort()
+	return b, nil
+}
+
+func (b *Bundle) add(name string, data []byte) error {
+	switch base := path.Base(name); {
+	case base == ".manifest":
+		var m struct {
+			Revision string `json:"revision"`
+		}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidBundle, name, err)
+		}
+		b.Revision = m.Revision
+	case base == "data.json":
+		var doc map[string]any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidBundle, name, err)
+		}
+		b.merge(path.Dir(name), doc)
+	case strings.HasSuffix(base, ".rego"):
+		b.Policies = append(b.Policies, Policy{Path: name, Module: string(data)})
+	}
+	return nil
+}
+
+// merge sets doc at dir inside Data, merging with what is already there.
+func (b *Bundle) merge(dir string, doc map[string]any) {
+	if b.Data == nil {
+		b.Data = map[string]any{}
+	}
+	node := b.Data
+	if dir != "." {
+		for _, key := range strings.Split(dir, "/") {
+			child, ok := node[key].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				node[key] = child
+			}
+			node = child
+		}
+	}
+	for k, v := range doc {
+		node[k] = v
+	}
+}
+
+func (b *Bundle) sort() {
+	sort.Slice(b.Policies, func(i, j int) bool { return b.Policies[i].Path < b.Policies[j].Path })
+}
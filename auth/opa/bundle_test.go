@@ -0,0 +1,106 @@
+package opa
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+var bundleFiles = map[string]string{
+	".manifest":             `{"revision": "abc123"}`,
+	"authz/authz.rego":      "package aqm.authz\n",
+	"authz/roles/data.json": `{"admin": ["users:*"]}`,
+	"common.rego":           "package aqm.common\n",
+	"data.json":             `{"tenant": "acme"}`,
+	"README.md":             "ignored",
+}
+
+var wantBundle = &Bundle{
+	Revision: "abc123",
+	Policies: []Policy{
+		{Path: "authz/authz.rego", Module: "package aqm.authz\n"},
+		{Path: "common.rego", Module: "package aqm.common\n"},
+	},
+	Data: map[string]any{
+		"tenant": "acme",
+		"authz":  map[string]any{"roles": map[string]any{"admin": []any{"users:*"}}},
+	},
+}
+
+func tarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: "/" + name, Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(body))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for name, body := range bundleFiles {
+		fsys["policies/"+name] = &fstest.MapFile{Data: []byte(body)}
+	}
+
+	b, err := FSSource(fsys, "policies")(context.Background())
+	if err != nil {
+		t.Fatalf("LoadFS() error = %v", err)
+	}
+	if !reflect.DeepEqual(b, wantBundle) {
+		t.Errorf("LoadFS() = %+v, want %+v", b, wantBundle)
+	}
+
+	fsys["policies/data.json"] = &fstest.MapFile{Data: []byte("{")}
+	if _, err := LoadFS(fsys, "policies"); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("LoadFS() with bad data.json error = %v, want ErrInvalidBundle", err)
+	}
+	if _, err := LoadFS(fsys, "missing"); err == nil {
+		t.Error("expected error for missing root")
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	body := tarball(t, bundleFiles)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bundles/authz.tar.gz":
+			w.Write(body)
+		case "/bundles/corrupt.tar.gz":
+			w.Write([]byte("not gzip"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	b, err := HTTPSource(srv.Client(), srv.URL+"/bundles/authz.tar.gz")(ctx)
+	if err != nil {
+		t.Fatalf("HTTPSource() error = %v", err)
+	}
+	if !reflect.DeepEqual(b, wantBundle) {
+		t.Errorf("HTTPSource() = %+v, want %+v", b, wantBundle)
+	}
+
+	if _, err := HTTPSource(nil, srv.URL+"/bundles/corrupt.tar.gz")(ctx); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("corrupt bundle error = %v, want ErrInvalidBundle", err)
+	}
+	if _, err := HTTPSource(nil, srv.URL+"/bundles/missing.tar.gz")(ctx); err == nil {
+		t.Error("expected error for missing bundle")
+	}
+}
@@ -0,0 +1,74 @@
+package opa
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// Permissions required by the policy admin routes.
+const (
+	PermissionRead  = "authz:policies:read"
+	PermissionWrite = "authz:policies:write"
+)
+
+type Handler struct {
+	engine  *Engine
+	checker middleware.RoleChecker
+}
+
+// NewHandler creates the policy admin handler. Callers must hold
+// PermissionRead to view the active bundle and PermissionWrite to replace
+// it; checker decides those permissions, not the Rego policies.
+func NewHandler(engine *Engine, checker middleware.RoleChecker) *Handler {
+	return &Handler{engine: engine, checker: checker}
+}
+
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.With(middleware.RequirePermission(h.checker, PermissionRead)).Get("/authz/policies", h.handleGetPolicies)
+	r.With(middleware.RequirePermission(h.checker, PermissionWrite)).Put("/authz/policies", h.handlePutPolicies)
+}
+
+type PoliciesResponse struct {
+	Bundle *Bundle `json:"bundle"`
+}
+
+type ErrorResponse = httpx.ErrorResponse
+
+// handleGetPolicies serves GET /authz/policies with the active bundle.
+func (h *Handler) handleGetPolicies(w http.ResponseWriter, r *http.Request) {
+	b := h.engine.Bundle()
+	if b == nil {
+		httpx.WriteError(w, r, httpx.NotFound("NO_POLICY", "No policy bundle loaded"))
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, PoliciesResponse{Bundle: b})
+}
+
+// handlePutPolicies serves PUT /authz/policies. The body is a Bundle in
+// JSON; it replaces the active bundle once it compiles.
+func (h *Handler) handlePutPolicies(w http.ResponseWriter, r *http.Request) {
+	var b Bundle
+	if err := httpx.Bind(r, &b); err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	if len(b.Policies) == 0 {
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_POLICY", "At least one policy is required"))
+		return
+	}
+
+	if err := h.engine.Load(r.Context(), &b); err != nil {
+		if errors.Is(err, ErrInvalidBundle) {
+			httpx.WriteError(w, r, httpx.BadRequest("INVALID_POLICY", err.Error()))
+			return
+		}
+		httpx.WriteError(w, r, httpx.Internal("Cannot load policy bundle"))
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, PoliciesResponse{Bundle: &b})
+}
@@ -0,0 +1,114 @@
+package opa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeChecker struct {
+	allowed map[string]bool
+}
+
+func (c fakeChecker) HasRole(ctx context.Context, userID, roleName string) (bool, error) {
+	return false, nil
+}
+
+func (c fakeChecker) CheckPermission(ctx context.Context, userID, permission string) (bool, error) {
+	return c.allowed[permission], nil
+}
+
+func (c fakeChecker) CheckAnyPermission(ctx context.Context, userID string, permissions []string) (bool, error) {
+	return false, nil
+}
+
+func (c fakeChecker) CheckAllPermissions(ctx context.Context, userID string, permissions []string) (bool, error) {
+	return false, nil
+}
+
+func setupHandler(t *testing.T, e *Engine, allowed ...string) http.Handler {
+	t.Helper()
+
+	checker := fakeChecker{allowed: map[string]bool{}}
+	for _, p := range allowed {
+		checker.allowed[p] = true
+	}
+
+	r := chi.NewRouter()
+	NewHandler(e, checker).RegisterRoutes(r)
+	return r
+}
+
+func serve(h http.Handler, method, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/authz/policies", bytes.NewBufferString(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "admin"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandlePolicies(t *testing.T) {
+	e := newTestEngine(t, "")
+	h := setupHandler(t, e, PermissionRead, PermissionWrite)
+
+	if rec := serve(h, http.MethodGet, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET before load status = %d, want 404", rec.Code)
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "invalid json", body: "{", wantCode: http.StatusBadRequest, wantErr: "INVALID_REQUEST"},
+		{name: "no policies", body: `{"revision": "1"}`, wantCode: http.StatusBadRequest, wantErr: "INVALID_POLICY"},
+		{name: "compile error", body: `{"revision": "1", "policies": [{"path": "a.rego", "module": "error"}]}`, wantCode: http.StatusBadRequest, wantErr: "INVALID_POLICY"},
+		{name: "loaded", body: `{"revision": "2", "policies": [{"path": "a.rego", "module": "allow ann posts:read"}]}`, wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(h, http.MethodPut, tt.body)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantErr != "" {
+				var errResp ErrorResponse
+				json.NewDecoder(rec.Body).Decode(&errResp)
+				if errResp.Code != tt.wantErr {
+					t.Errorf("error code = %q, want %q", errResp.Code, tt.wantErr)
+				}
+			}
+		})
+	}
+
+	rec := serve(h, http.MethodGet, "")
+	var resp PoliciesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, decode error = %v", rec.Code, err)
+	}
+	if resp.Bundle.Revision != "2" || len(resp.Bundle.Policies) != 1 {
+		t.Errorf("GET bundle = %+v, want revision 2 with 1 policy", resp.Bundle)
+	}
+	if ok, _ := e.Authorize(context.Background(), "ann", "posts:read", ""); !ok {
+		t.Error("expected uploaded policy to be active")
+	}
+}
+
+func TestHandlePoliciesForbidden(t *testing.T) {
+	h := setupHandler(t, newTestEngine(t, "allow * *"), PermissionRead)
+
+	if rec := serve(h, http.MethodGet, ""); rec.Code != http.StatusOK {
+		t.Errorf("GET status = %d, want 200", rec.Code)
+	}
+	if rec := serve(h, http.MethodPut, `{"policies": [{"path": "a.rego", "module": ""}]}`); rec.Code != http.StatusForbidden {
+		t.Errorf("PUT status = %d, want 403", rec.Code)
+	}
+}
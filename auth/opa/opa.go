@@ -0,0 +1,177 @@
+// Package opa evaluates authorization decisions with Rego policies.
+//
+// Policies are loaded as bundles, from an embed.FS or a bundle server, and
+// compiled by a Compiler. RegoCompiler evaluates them with OPA:
+//
+//	e := opa.NewEngine(opa.RegoCompiler(opa.DefaultQuery), logger)
+//	go e.Poll(ctx, opa.FSSource(policies, "policies"), time.Minute)
+//	r.With(opa.RequirePermission(e, "docs:read", docID)).Get("/docs/{id}", get)
+package opa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+var (
+	// ErrNoPolicy is returned by evaluations made before a bundle is loaded.
+	ErrNoPolicy = errors.New("no policy bundle loaded")
+	// ErrInvalidBundle is returned for bundles that cannot be read or compiled.
+	ErrInvalidBundle = errors.New("invalid policy bundle")
+)
+
+// Input is the document policies see as input.
+type Input struct {
+	Subject    string `json:"subject"`
+	Permission string `json:"permission"`
+	Resource   string `json:"resource,omitempty"`
+	Method     string `json:"method,omitempty"`
+	Path       string `json:"path,omitempty"`
+}
+
+// Evaluator answers whether a policy allows input.
+type Evaluator interface {
+	Eval(ctx context.Context, input Input) (bool, error)
+}
+
+// EvaluatorFunc adapts a function to Evaluator.
+type EvaluatorFunc func(ctx context.Context, input Input) (bool, error)
+
+func (f EvaluatorFunc) Eval(ctx context.Context, input Input) (bool, error) {
+	return f(ctx, input)
+}
+
+// Compiler prepares an Evaluator for a bundle.
+type Compiler func(ctx context.Context, b *Bundle) (Evaluator, error)
+
+// Engine holds the active bundle and is an auth.AuthorizationEngine.
+// Loading a new bundle swaps it in atomically; if it fails to compile the
+// previous one stays active.
+type Engine struct {
+	compile Compiler
+	log     log.Logger
+
+	mu        sync.RWMutex
+	bundle    *Bundle
+	evaluator Evaluator
+}
+
+func NewEngine(compile Compiler, logger log.Logger) *Engine {
+	return &Engine{compile: compile, log: logger}
+}
+
+// Load compiles b and makes it the active bundle.
+func (e *Engine) Load(ctx context.Context, b *Bundle) error {
+	evaluator, err := e.compile(ctx, b)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+
+	e.mu.Lock()
+	e.bundle, e.evaluator = b, evaluator
+	e.mu.Unlock()
+
+	e.log.Infof("loaded policy bundle: revision=%s policies=%d", b.Revision, len(b.Policies))
+	return nil
+}
+
+// Bundle returns the active bundle, or nil before the first Load.
+func (e *Engine) Bundle() *Bundle {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.bundle
+}
+
+// Evaluate runs the active policy on input.
+func (e *Engine) Evaluate(ctx context.Context, input Input) (bool, error) {
+	e.mu.RLock()
+	evaluator := e.evaluator
+	e.mu.RUnlock()
+
+	if evaluator == nil {
+		return false, ErrNoPolicy
+	}
+	return evaluator.Eval(ctx, input)
+}
+
+func (e *Engine) Authorize(ctx context.Context, subject, permission, resource string) (bool, error) {
+	if subject == "" {
+		return false, nil
+	}
+	return e.Evaluate(ctx, Input{Subject: subject, Permission: permission, Resource: resource})
+}
+
+// Poll loads the bundle from src every interval until ctx is done. A bundle
+// whose revision matches the active one is not recompiled; failures are
+// logged and the active bundle is kept.
+func (e *Engine) Poll(ctx context.Context, src Source, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		e.refresh(ctx, src)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Engine) refresh(ctx context.Context, src Source) {
+	b, err := src(ctx)
+	if err != nil {
+		e.log.Errorf("cannot fetch policy bundle: %v", err)
+		return
+	}
+	if current := e.Bundle(); current != nil && b.Revision != "" && b.Revision == current.Revision {
+		return
+	}
+	if err := e.Load(ctx, b); err != nil {
+		e.log.Errorf("cannot load policy bundle: %v", err)
+	}
+}
+
+// RequirePermission creates middleware that lets a request through when the
+// active policy allows the session user permission. resource, which may be
+// nil, names the resource being accessed; the request method and path are
+// passed to the policy as well.
+func RequirePermission(e *Engine, permission string, resource func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := middleware.GetUserID(r.Context())
+			if userID == "" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			input := Input{Subject: userID, Permission: permission, Method: r.Method, Path: r.URL.Path}
+			if resource != nil {
+				input.Resource = resource(r)
+			}
+
+			allowed, err := e.Evaluate(r.Context(), input)
+			if err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			if !allowed {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+var _ auth.AuthorizationEngine = (*Engine)(nil)
@@ -0,0 +1,178 @@
+package opa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+// testCompile stands in for a Rego compiler. Each module line
+// "allow <subject> <permission>" allows that pair; "*" matches anything.
+// A module containing "error" fails to compile.
+func testCompile(_ context.Context, b *Bundle) (Evaluator, error) {
+	var rules [][]string
+	for _, p := range b.Policies {
+		if strings.Contains(p.Module, "error") {
+			return nil, errors.New("rego_parse_error in " + p.Path)
+		}
+		for _, line := range strings.Split(p.Module, "\n") {
+			if f := strings.Fields(line); len(f) == 3 && f[0] == "allow" {
+				rules = append(rules, f[1:])
+			}
+		}
+	}
+	return EvaluatorFunc(func(_ context.Context, in Input) (bool, error) {
+		for _, r := range rules {
+			if (r[0] == "*" || r[0] == in.Subject) && (r[1] == "*" || r[1] == in.Permission) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}), nil
+}
+
+func newTestEngine(t *testing.T, module string) *Engine {
+	t.Helper()
+
+	e := NewEngine(testCompile, log.NewNoopLogger())
+	if module != "" {
+		if err := e.Load(context.Background(), &Bundle{Revision: "1", Policies: []Policy{{Path: "authz.rego", Module: module}}}); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+	}
+	return e
+}
+
+func TestEngine(t *testing.T) {
+	ctx := context.Background()
+
+	e := newTestEngine(t, "")
+	if _, err := e.Authorize(ctx, "ann", "posts:read", ""); !errors.Is(err, ErrNoPolicy) {
+		t.Errorf("Authorize() before Load error = %v, want ErrNoPolicy", err)
+	}
+
+	e = newTestEngine(t, "allow ann posts:read")
+
+	tests := []struct {
+		name       string
+		subject    string
+		permission string
+		want       bool
+	}{
+		{name: "allowed", subject: "ann", permission: "posts:read", want: true},
+		{name: "denied", subject: "ann", permission: "posts:write", want: false},
+		{name: "empty subject", subject: "", permission: "posts:read", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Authorize(ctx, tt.subject, tt.permission, "")
+			if err != nil {
+				t.Fatalf("Authorize() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Authorize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	err := e.Load(ctx, &Bundle{Revision: "2", Policies: []Policy{{Path: "bad.rego", Module: "error"}}})
+	if !errors.Is(err, ErrInvalidBundle) {
+		t.Fatalf("Load() error = %v, want ErrInvalidBundle", err)
+	}
+	if rev := e.Bundle().Revision; rev != "1" {
+		t.Errorf("active revision = %q, want 1 to be kept", rev)
+	}
+}
+
+func TestEnginePoll(t *testing.T) {
+	e := newTestEngine(t, "")
+
+	var calls atomic.Int32
+	src := func(context.Context) (*Bundle, error) {
+		if calls.Add(1) == 2 {
+			return nil, errors.New("bundle server down")
+		}
+		return &Bundle{Revision: "7", Policies: []Policy{{Path: "authz.rego", Module: "allow * *"}}}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.Poll(ctx, src, time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if b := e.Bundle(); b == nil || b.Revision != "7" {
+		t.Fatalf("Bundle() = %+v, want revision 7", b)
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	var gotInput Input
+	e := NewEngine(func(context.Context, *Bundle) (Evaluator, error) {
+		return EvaluatorFunc(func(_ context.Context, in Input) (bool, error) {
+			gotInput = in
+			return in.Resource == "doc-1", nil
+		}), nil
+	}, log.NewNoopLogger())
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	resource := func(r *http.Request) string { return r.URL.Query().Get("doc") }
+	h := RequirePermission(e, "docs:read", resource)(next)
+
+	serve := func(userID, target string) int {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if userID != "" {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve("ann", "/docs?doc=doc-1"); code != http.StatusInternalServerError {
+		t.Errorf("status without bundle = %d, want 500", code)
+	}
+
+	e.Load(context.Background(), &Bundle{})
+
+	tests := []struct {
+		name   string
+		userID string
+		target string
+		want   int
+	}{
+		{name: "allowed", userID: "ann", target: "/docs?doc=doc-1", want: http.StatusOK},
+		{name: "denied", userID: "ann", target: "/docs?doc=doc-2", want: http.StatusForbidden},
+		{name: "anonymous", userID: "", target: "/docs?doc=doc-1", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := serve(tt.userID, tt.target); code != tt.want {
+				t.Errorf("status = %d, want %d", code, tt.want)
+			}
+		})
+	}
+
+	want := Input{Subject: "ann", Permission: "docs:read", Resource: "doc-2", Method: http.MethodGet, Path: "/docs"}
+	if gotInput != want {
+		t.Errorf("policy input = %+v, want %+v", gotInput, want)
+	}
+}
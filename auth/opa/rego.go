@@ -0,0 +1,45 @@
+package opa
+
+import (
+	"context"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+)
+
+// DefaultQuery is the decision RegoCompiler evaluates when given none.
+const DefaultQuery = "data.aqm.authz.allow"
+
+// RegoCompiler returns a Compiler that prepares query, DefaultQuery when
+// empty, over the modules and data of a bundle with OPA. Input is passed to
+// the policies as input, and an input is allowed when query evaluates to
+// true.
+func RegoCompiler(query string) Compiler {
+	if query == "" {
+		query = DefaultQuery
+	}
+
+	return func(ctx context.Context, b *Bundle) (Evaluator, error) {
+		data := b.Data
+		if data == nil {
+			data = map[string]any{}
+		}
+		opts := []func(*rego.Rego){rego.Query(query), rego.Store(inmem.NewFromObject(data))}
+		for _, p := range b.Policies {
+			opts = append(opts, rego.Module(p.Path, p.Module))
+		}
+
+		prepared, err := rego.New(opts...).PrepareForEval(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return EvaluatorFunc(func(ctx context.Context, in Input) (bool, error) {
+			rs, err := prepared.Eval(ctx, rego.EvalInput(in))
+			if err != nil {
+				return false, err
+			}
+			return rs.Allowed(), nil
+		}), nil
+	}
+}
@@ -0,0 +1,97 @@
+package opa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+func newRegoEngine(t *testing.T) *Engine {
+	t.Helper()
+
+	b, err := LoadFS(os.DirFS("testdata"), "bundle")
+	if err != nil {
+		t.Fatalf("LoadFS() error = %v", err)
+	}
+	e := NewEngine(RegoCompiler(""), log.NewNoopLogger())
+	if err := e.Load(context.Background(), b); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return e
+}
+
+func TestRegoCompiler(t *testing.T) {
+	ctx := context.Background()
+	e := newRegoEngine(t)
+
+	tests := []struct {
+		name       string
+		subject    string
+		permission string
+		resource   string
+		want       bool
+	}{
+		{name: "role permission", subject: "ann", permission: "posts:write", want: true},
+		{name: "permission not in role", subject: "ann", permission: "users:read", want: false},
+		{name: "owner reads document", subject: "bob", permission: "docs:read", resource: "doc-1", want: true},
+		{name: "other reads document", subject: "ann", permission: "docs:read", resource: "doc-1", want: false},
+		{name: "unknown subject", subject: "eve", permission: "posts:read", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Authorize(ctx, tt.subject, tt.permission, tt.resource)
+			if err != nil {
+				t.Fatalf("Authorize() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Authorize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegoCompilerInvalidPolicy(t *testing.T) {
+	e := newRegoEngine(t)
+
+	err := e.Load(context.Background(), &Bundle{Revision: "r2", Policies: []Policy{{Path: "bad.rego", Module: "package aqm.authz\n\nallow if {"}}})
+	if !errors.Is(err, ErrInvalidBundle) {
+		t.Fatalf("Load() error = %v, want ErrInvalidBundle", err)
+	}
+	if b := e.Bundle(); b.Revision != "r1" {
+		t.Errorf("active revision = %q, want r1 kept", b.Revision)
+	}
+}
+
+func TestRequirePermissionWithRego(t *testing.T) {
+	e := newRegoEngine(t)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := RequirePermission(e, "docs:read", func(r *http.Request) string { return r.URL.Query().Get("doc") })(next)
+
+	tests := []struct {
+		name   string
+		userID string
+		want   int
+	}{
+		{name: "owner", userID: "bob", want: http.StatusOK},
+		{name: "not owner", userID: "ann", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/docs?doc=doc-1", nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, tt.userID))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package aqm.authz
+
+# A subject may use a permission granted to one of its roles, or read any
+# document it owns.
+
+default allow := false
+
+allow if {
+	some role in data.aqm.authz.subjects[input.subject]
+	input.permission in data.aqm.authz.roles[role]
+}
+
+allow if {
+	input.permission == "docs:read"
+	data.aqm.authz.owners[input.resource] == input.subject
+}
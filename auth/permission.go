@@ -1,9 +1,16 @@
 package auth
 
-import "strings"
+import (
+	"sort"
+	"strings"
+)
 
 type Permission string
 
+// PermissionImpersonate lets support admins obtain tokens acting as other
+// users (see handler.WithImpersonation).
+const PermissionImpersonate = "users:impersonate"
+
 func (p Permission) String() string {
 	return string(p)
 }
@@ -59,3 +66,34 @@ func HasAllPermissions(permissions []string, required []string) bool {
 	}
 	return true
 }
+
+// DiffPermissions compares two permission sets and returns the permissions
+// present only in proposed (added) and only in current (removed).
+// Duplicates are ignored and both results are sorted.
+func DiffPermissions(current, proposed []string) (added, removed []string) {
+	cur := make(map[string]bool, len(current))
+	for _, p := range current {
+		cur[p] = true
+	}
+	next := make(map[string]bool, len(proposed))
+	for _, p := range proposed {
+		next[p] = true
+	}
+
+	added = []string{}
+	for p := range next {
+		if !cur[p] {
+			added = append(added, p)
+		}
+	}
+	removed = []string{}
+	for p := range cur {
+		if !next[p] {
+			removed = append(removed, p)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
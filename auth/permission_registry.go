@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PermissionDef describes a permission a service checks for. Group is a
+// free-form label admin UIs use to cluster related permissions.
+type PermissionDef struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Group       string `json:"group"`
+}
+
+// PermissionRegistry is the catalog of permissions known to a service.
+// Services register their permissions at startup; roles are then validated
+// against it so typos do not silently grant nothing.
+type PermissionRegistry struct {
+	mu           sync.RWMutex
+	defs         map[string]PermissionDef
+	allowUnknown bool
+}
+
+func NewPermissionRegistry() *PermissionRegistry {
+	return &PermissionRegistry{defs: make(map[string]PermissionDef)}
+}
+
+// Register adds defs to the catalog. Names must be non-empty, free of
+// wildcards and not registered yet.
+func (r *PermissionRegistry) Register(defs ...PermissionDef) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, def := range defs {
+		def.Name = strings.TrimSpace(def.Name)
+		if def.Name == "" || strings.Contains(def.Name, "*") {
+			return fmt.Errorf("%w: %q", ErrInvalidPermission, def.Name)
+		}
+		if _, exists := r.defs[def.Name]; exists {
+			return fmt.Errorf("%w: %s already registered", ErrInvalidPermission, def.Name)
+		}
+		r.defs[def.Name] = def
+	}
+	return nil
+}
+
+// MustRegister is like Register but panics on error. It suits package-level
+// declarations.
+func (r *PermissionRegistry) MustRegister(defs ...PermissionDef) *PermissionRegistry {
+	if err := r.Register(defs...); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// AllowUnknown turns validation off while keeping the catalog, e.g. while a
+// service migrates existing roles onto registered permissions.
+func (r *PermissionRegistry) AllowUnknown(allow bool) *PermissionRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.allowUnknown = allow
+	return r
+}
+
+// Lookup returns the definition registered under name.
+func (r *PermissionRegistry) Lookup(name string) (PermissionDef, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, ok := r.defs[name]
+	return def, ok
+}
+
+// List returns every definition ordered by group, then name.
+func (r *PermissionRegistry) List() []PermissionDef {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	defs := make([]PermissionDef, 0, len(r.defs))
+	for _, def := range r.defs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		if defs[i].Group != defs[j].Group {
+			return defs[i].Group < defs[j].Group
+		}
+		return defs[i].Name < defs[j].Name
+	})
+	return defs
+}
+
+// Validate checks that every permission is registered or is a wildcard
+// matching at least one registered permission. It returns
+// ErrUnknownPermission naming the offenders, or nil when unknown
+// permissions are allowed.
+func (r *PermissionRegistry) Validate(permissions []string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.allowUnknown {
+		return nil
+	}
+
+	var unknown []string
+	for _, p := range permissions {
+		if !r.known(Permission(p)) {
+			unknown = append(unknown, p)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownPermission, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func (r *PermissionRegistry) known(p Permission) bool {
+	if _, ok := r.defs[string(p)]; ok {
+		return true
+	}
+	if !strings.Contains(string(p), "*") {
+		return false
+	}
+	for name := range r.defs {
+		if p.Matches(Permission(name)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func newTestRegistry(t *testing.T) *PermissionRegistry {
+	t.Helper()
+
+	r := NewPermissionRegistry()
+	err := r.Register(
+		PermissionDef{Name: "users:read", Description: "View users", Group: "users"},
+		PermissionDef{Name: "users:write", Description: "Edit users", Group: "users"},
+		PermissionDef{Name: "audit:read", Description: "View the audit trail", Group: "audit"},
+	)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	return r
+}
+
+func TestPermissionRegistryRegister(t *testing.T) {
+	r := newTestRegistry(t)
+
+	tests := []struct {
+		name string
+		def  PermissionDef
+	}{
+		{name: "empty name", def: PermissionDef{Name: " "}},
+		{name: "wildcard", def: PermissionDef{Name: "users:*"}},
+		{name: "duplicate", def: PermissionDef{Name: "users:read"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.Register(tt.def); !errors.Is(err, ErrInvalidPermission) {
+				t.Errorf("Register() error = %v, want ErrInvalidPermission", err)
+			}
+		})
+	}
+
+	def, ok := r.Lookup("audit:read")
+	if !ok || def.Group != "audit" {
+		t.Errorf("Lookup(audit:read) = %+v, %v", def, ok)
+	}
+	if _, ok := r.Lookup("audit:delete"); ok {
+		t.Error("Lookup(audit:delete) found an unregistered permission")
+	}
+}
+
+func TestPermissionRegistryMustRegister(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustRegister() with a duplicate did not panic")
+		}
+	}()
+	NewPermissionRegistry().MustRegister(PermissionDef{Name: "a"}, PermissionDef{Name: "a"})
+}
+
+func TestPermissionRegistryList(t *testing.T) {
+	defs := newTestRegistry(t).List()
+
+	want := []string{"audit:read", "users:read", "users:write"}
+	if len(defs) != len(want) {
+		t.Fatalf("List() returned %d definitions, want %d", len(defs), len(want))
+	}
+	for i, def := range defs {
+		if def.Name != want[i] {
+			t.Errorf("List()[%d] = %s, want %s", i, def.Name, want[i])
+		}
+	}
+}
+
+func TestPermissionRegistryValidate(t *testing.T) {
+	r := newTestRegistry(t)
+
+	tests := []struct {
+		name        string
+		permissions []string
+		wantErr     bool
+	}{
+		{name: "none", permissions: nil},
+		{name: "registered", permissions: []string{"users:read", "audit:read"}},
+		{name: "matching wildcard", permissions: []string{"users:*"}},
+		{name: "superuser wildcard", permissions: []string{"*"}},
+		{name: "unknown", permissions: []string{"users:read", "usr:write"}, wantErr: true},
+		{name: "wildcard matching nothing", permissions: []string{"billing:*"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Validate(tt.permissions)
+			if tt.wantErr && !errors.Is(err, ErrUnknownPermission) {
+				t.Errorf("Validate() error = %v, want ErrUnknownPermission", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+
+	if err := r.AllowUnknown(true).Validate([]string{"usr:write"}); err != nil {
+		t.Errorf("Validate() with unknown permissions allowed error = %v", err)
+	}
+}
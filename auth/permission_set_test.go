@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPermissionSetValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     *PermissionSet
+		wantErr bool
+	}{
+		{name: "valid", set: NewPermissionSet(" Content-Editor ", "", nil)},
+		{name: "too short", set: NewPermissionSet("x", "", nil), wantErr: true},
+		{name: "too long", set: NewPermissionSet(strings.Repeat("s", 65), "", nil), wantErr: true},
+		{name: "with spaces", set: NewPermissionSet("content editor", "", nil), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.set.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidPermissionSetName) {
+				t.Errorf("Validate() error = %v, want ErrInvalidPermissionSetName", err)
+			}
+		})
+	}
+}
+
+func TestPermissionSetBeforeCreate(t *testing.T) {
+	set := NewPermissionSet("Editor", " Edits  content ", nil)
+	set.BeforeCreate()
+
+	if set.Name != "editor" || set.Version != 1 || set.CreatedAt.IsZero() || set.Permissions == nil {
+		t.Errorf("BeforeCreate() = %+v", set)
+	}
+}
+
+func TestExpandPermissionSets(t *testing.T) {
+	got := ExpandPermissionSets([]*PermissionSet{
+		{Name: "editor", Permissions: []string{"content:read", "content:write"}},
+		{Name: "reviewer", Permissions: []string{"content:read", "content:approve"}},
+	})
+	want := []string{"content:read", "content:write", "content:approve"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ExpandPermissionSets() = %v, want %v", got, want)
+	}
+}
+
+func TestRoleGrantedPermissions(t *testing.T) {
+	role := &Role{
+		Permissions:    []string{"users:read"},
+		PermissionSets: []string{"editor"},
+		SetPermissions: []string{"users:read", "content:write"},
+	}
+
+	if got := role.GrantedPermissions(); !slices.Equal(got, []string{"users:read", "content:write"}) {
+		t.Errorf("GrantedPermissions() = %v", got)
+	}
+	if !role.HasPermission("content:write") {
+		t.Error("HasPermission() ignores permission set permissions")
+	}
+	if !role.ReferencesPermissionSet("editor") || role.ReferencesPermissionSet("reviewer") {
+		t.Error("ReferencesPermissionSet() mismatch")
+	}
+}
@@ -125,3 +125,45 @@ func TestHasAllPermissions(t *testing.T) {
 		})
 	}
 }
+
+func TestDiffPermissions(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     []string
+		proposed    []string
+		wantAdded   []string
+		wantRemoved []string
+	}{
+		{"no change", []string{"users:read"}, []string{"users:read"}, []string{}, []string{}},
+		{"added only", []string{"users:read"}, []string{"users:read", "users:write"}, []string{"users:write"}, []string{}},
+		{"removed only", []string{"users:read", "users:write"}, []string{"users:read"}, []string{}, []string{"users:write"}},
+		{"replaced", []string{"b", "a"}, []string{"d", "c"}, []string{"c", "d"}, []string{"a", "b"}},
+		{"duplicates ignored", []string{"a", "a"}, []string{"a", "b", "b"}, []string{"b"}, []string{}},
+		{"empty current", nil, []string{"a"}, []string{"a"}, []string{}},
+		{"empty proposed", []string{"a"}, nil, []string{}, []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := DiffPermissions(tt.current, tt.proposed)
+			if !equalStrings(added, tt.wantAdded) {
+				t.Errorf("DiffPermissions() added = %v, want %v", added, tt.wantAdded)
+			}
+			if !equalStrings(removed, tt.wantRemoved) {
+				t.Errorf("DiffPermissions() removed = %v, want %v", removed, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/db/dbutil"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const consentColumns = `id, user_id, policy, version, accepted_at, remote_ip`
+
+type consentStore struct {
+	db *sql.DB
+}
+
+func NewConsentStore(db *sql.DB) auth.ConsentStore {
+	return &consentStore{db: db}
+}
+
+func scanConsent(row dbutil.Scanner) (*auth.Consent, error) {
+	consent := &auth.Consent{}
+	err := row.Scan(&consent.ID, &consent.UserID, &consent.Policy, &consent.Version, &consent.AcceptedAt, &consent.RemoteIP)
+	if err == sql.ErrNoRows {
+		return nil, auth.ErrConsentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+func (s *consentStore) Create(ctx context.Context, consent *auth.Consent) error {
+	query := `
+		INSERT INTO consents (` + consentColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := dbutil.Conn(ctx, s.db).ExecContext(ctx, query,
+		consent.ID, consent.UserID, consent.Policy, consent.Version, consent.AcceptedAt, consent.RemoteIP,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return auth.ErrConsentAlreadyExists
+	}
+	return err
+}
+
+func (s *consentStore) Get(ctx context.Context, userID auth.UserID, policy, version string) (*auth.Consent, error) {
+	query := `SELECT ` + consentColumns + ` FROM consents WHERE user_id = $1 AND policy = $2 AND version = $3`
+	return scanConsent(dbutil.Conn(ctx, s.db).QueryRowContext(ctx, query, userID, policy, version))
+}
+
+func (s *consentStore) ListByUser(ctx context.Context, userID auth.UserID) ([]*auth.Consent, error) {
+	query := `SELECT ` + consentColumns + ` FROM consents WHERE user_id = $1 ORDER BY accepted_at DESC`
+	rows, err := dbutil.Conn(ctx, s.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := make([]*auth.Consent, 0)
+	for rows.Next() {
+		consent, err := scanConsent(rows)
+		if err != nil {
+			return nil, err
+		}
+		consents = append(consents, consent)
+	}
+	return consents, rows.Err()
+}
+
+func (s *consentStore) ListAccepted(ctx context.Context, policy, version string) ([]auth.UserID, error) {
+	query := `SELECT user_id FROM consents WHERE policy = $1 AND version = $2`
+	rows, err := dbutil.Conn(ctx, s.db).QueryContext(ctx, query, policy, version)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]auth.UserID, 0)
+	for rows.Next() {
+		var id auth.UserID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		users = append(users, id)
+	}
+	return users, rows.Err()
+}
+
+func (s *consentStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func setupConsentTestDB(t *testing.T) (auth.ConsentStore, *auth.User, func()) {
+	t.Helper()
+
+	db, cleanup := setupTestDB(t)
+	ctx := context.Background()
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS consents (
+			id UUID PRIMARY KEY,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			policy TEXT NOT NULL,
+			version TEXT NOT NULL,
+			accepted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			remote_ip TEXT NOT NULL DEFAULT '',
+			UNIQUE(user_id, policy, version)
+		)
+	`)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to create consents table: %v", err)
+	}
+
+	user := auth.NewUser()
+	user.Username = "consentuser"
+	user.Name = "Consent User"
+	user.EmailCT = []byte("encrypted")
+	user.EmailIV = []byte("iv")
+	user.EmailTag = []byte("tag")
+	user.EmailLookup = []byte("consent-lookup")
+	user.PasswordHash = []byte("hash")
+	user.PasswordSalt = []byte("salt")
+	user.BeforeCreate()
+	if err := NewUserStore(db).Create(ctx, user); err != nil {
+		cleanup()
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return NewConsentStore(db), user, func() {
+		db.Exec("DROP TABLE IF EXISTS consents")
+		cleanup()
+	}
+}
+
+func TestConsentStore(t *testing.T) {
+	store, user, cleanup := setupConsentTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	older := auth.NewConsent(user.ID, "terms", "v1")
+	older.AcceptedAt = time.Now().Add(-time.Hour)
+	older.BeforeCreate()
+	newer := auth.NewConsent(user.ID, "terms", "v2")
+	newer.RemoteIP = "203.0.113.5"
+	newer.BeforeCreate()
+	for _, consent := range []*auth.Consent{older, newer} {
+		if err := store.Create(ctx, consent); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	duplicate := auth.NewConsent(user.ID, "terms", "v2")
+	duplicate.BeforeCreate()
+	if err := store.Create(ctx, duplicate); err != auth.ErrConsentAlreadyExists {
+		t.Errorf("Create() duplicate error = %v, want ErrConsentAlreadyExists", err)
+	}
+
+	got, err := store.Get(ctx, user.ID, "terms", "v2")
+	if err != nil || got.ID != newer.ID || got.RemoteIP != "203.0.113.5" {
+		t.Errorf("Get() = %+v, %v", got, err)
+	}
+	if _, err := store.Get(ctx, user.ID, "privacy", "v1"); err != auth.ErrConsentNotFound {
+		t.Errorf("Get() missing error = %v, want ErrConsentNotFound", err)
+	}
+
+	consents, err := store.ListByUser(ctx, user.ID)
+	if err != nil || len(consents) != 2 || consents[0].ID != newer.ID {
+		t.Errorf("ListByUser() = %+v, %v", consents, err)
+	}
+
+	accepted, err := store.ListAccepted(ctx, "terms", "v1")
+	if err != nil || len(accepted) != 1 || accepted[0] != user.ID {
+		t.Errorf("ListAccepted() = %v, %v", accepted, err)
+	}
+}
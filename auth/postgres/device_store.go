@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/db/dbutil"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const deviceColumns = `id, user_id, fingerprint, name, user_agent, last_seen_ip, last_seen_country, last_seen_city, last_seen_at, trust_token_hash, trusted_until, created_at`
+
+type deviceStore struct {
+	db *sql.DB
+}
+
+func NewDeviceStore(db *sql.DB) auth.DeviceStore {
+	return &deviceStore{db: db}
+}
+
+func scanDevice(row dbutil.Scanner) (*auth.Device, error) {
+	device := &auth.Device{}
+	err := row.Scan(
+		&device.ID, &device.UserID, &device.Fingerprint, &device.Name, &device.UserAgent,
+		&device.LastSeenIP, &device.LastSeenCountry, &device.LastSeenCity, &device.LastSeenAt, &device.TrustTokenHash, &device.TrustedUntil, &device.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, auth.ErrDeviceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+func (s *deviceStore) Create(ctx context.Context, device *auth.Device) error {
+	query := `
+		INSERT INTO devices (` + deviceColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	_, err := dbutil.Conn(ctx, s.db).ExecContext(ctx, query,
+		device.ID, device.UserID, device.Fingerprint, device.Name, device.UserAgent,
+		device.LastSeenIP, device.LastSeenCountry, device.LastSeenCity, device.LastSeenAt, device.TrustTokenHash, device.TrustedUntil, device.CreatedAt,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return auth.ErrDeviceAlreadyExists
+	}
+	return err
+}
+
+func (s *deviceStore) Get(ctx context.Context, id auth.DeviceID) (*auth.Device, error) {
+	query := `SELECT ` + deviceColumns + ` FROM devices WHERE id = $1`
+	return scanDevice(dbutil.Conn(ctx, s.db).QueryRowContext(ctx, query, id))
+}
+
+func (s *deviceStore) GetByFingerprint(ctx context.Context, userID auth.UserID, fingerprint string) (*auth.Device, error) {
+	query := `SELECT ` + deviceColumns + ` FROM devices WHERE user_id = $1 AND fingerprint = $2`
+	return scanDevice(dbutil.Conn(ctx, s.db).QueryRowContext(ctx, query, userID, fingerprint))
+}
+
+func (s *deviceStore) Update(ctx context.Context, device *auth.Device) error {
+	query := `
+		UPDATE devices
+		SET name = $2, user_agent = $3, last_seen_ip = $4, last_seen_country = $5, last_seen_city = $6,
+			last_seen_at = $7, trust_token_hash = $8, trusted_until = $9
+		WHERE id = $1
+	`
+	result, err := dbutil.Conn(ctx, s.db).ExecContext(ctx, query,
+		device.ID, device.Name, device.UserAgent, device.LastSeenIP, device.LastSeenCountry, device.LastSeenCity,
+		device.LastSeenAt, device.TrustTokenHash, device.TrustedUntil,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return auth.ErrDeviceNotFound
+	}
+	return nil
+}
+
+func (s *deviceStore) Delete(ctx context.Context, id auth.DeviceID) error {
+	result, err := dbutil.Conn(ctx, s.db).ExecContext(ctx, `DELETE FROM devices WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return auth.ErrDeviceNotFound
+	}
+	return nil
+}
+
+func (s *deviceStore) ListByUser(ctx context.Context, userID auth.UserID) ([]*auth.Device, error) {
+	query := `SELECT ` + deviceColumns + ` FROM devices WHERE user_id = $1 ORDER BY last_seen_at DESC`
+	rows, err := dbutil.Conn(ctx, s.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make([]*auth.Device, 0)
+	for rows.Next() {
+		device, err := scanDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+	return devices, rows.Err()
+}
+
+func (s *deviceStore) DeleteByUser(ctx context.Context, userID auth.UserID) error {
+	_, err := dbutil.Conn(ctx, s.db).ExecContext(ctx, `DELETE FROM devices WHERE user_id = $1`, userID)
+	return err
+}
+
+func (s *deviceStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+var _ auth.DeviceStore = (*deviceStore)(nil)
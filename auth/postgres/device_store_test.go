@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func setupDeviceTestDB(t *testing.T) (auth.DeviceStore, *auth.User, func()) {
+	t.Helper()
+
+	db, cleanup := setupTestDB(t)
+	ctx := context.Background()
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS devices (
+			id UUID PRIMARY KEY,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			fingerprint TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			user_agent TEXT NOT NULL DEFAULT '',
+			last_seen_ip TEXT NOT NULL DEFAULT '',
+			last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			trust_token_hash BYTEA,
+			trusted_until TIMESTAMPTZ,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			UNIQUE(user_id, fingerprint)
+		)
+	`)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to create devices table: %v", err)
+	}
+
+	user := auth.NewUser()
+	user.Username = "deviceuser"
+	user.Name = "Device User"
+	user.EmailCT = []byte("encrypted")
+	user.EmailIV = []byte("iv")
+	user.EmailTag = []byte("tag")
+	user.EmailLookup = []byte("device-lookup")
+	user.PasswordHash = []byte("hash")
+	user.PasswordSalt = []byte("salt")
+	user.BeforeCreate()
+	if err := NewUserStore(db).Create(ctx, user); err != nil {
+		cleanup()
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return NewDeviceStore(db), user, func() {
+		db.Exec("DROP TABLE IF EXISTS devices")
+		cleanup()
+	}
+}
+
+func TestDeviceStoreCreateAndGet(t *testing.T) {
+	store, user, cleanup := setupDeviceTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	device := auth.NewDevice(user.ID, "fp-1", "Laptop")
+	device.Touch("203.0.113.5", "Firefox", time.Now())
+	device.LastSeenCountry, device.LastSeenCity = "DE", "Berlin"
+	device.BeforeCreate()
+
+	if err := store.Create(ctx, device); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	duplicate := auth.NewDevice(user.ID, "fp-1", "Other")
+	duplicate.BeforeCreate()
+	if err := store.Create(ctx, duplicate); err != auth.ErrDeviceAlreadyExists {
+		t.Errorf("Create() duplicate error = %v, want ErrDeviceAlreadyExists", err)
+	}
+
+	got, err := store.GetByFingerprint(ctx, user.ID, "fp-1")
+	if err != nil {
+		t.Fatalf("GetByFingerprint() error = %v", err)
+	}
+	if got.ID != device.ID || got.Name != "Laptop" || got.LastSeenIP != "203.0.113.5" || got.LastSeenCity != "Berlin" || got.TrustedUntil != nil {
+		t.Errorf("GetByFingerprint() = %+v", got)
+	}
+	if _, err := store.Get(ctx, auth.NewDeviceID()); err != auth.ErrDeviceNotFound {
+		t.Errorf("Get() missing error = %v, want ErrDeviceNotFound", err)
+	}
+}
+
+func TestDeviceStoreTrustAndList(t *testing.T) {
+	store, user, cleanup := setupDeviceTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	older := auth.NewDevice(user.ID, "fp-1", "Laptop")
+	older.LastSeenAt = now.Add(-time.Hour)
+	older.BeforeCreate()
+	newer := auth.NewDevice(user.ID, "fp-2", "Phone")
+	newer.BeforeCreate()
+	for _, device := range []*auth.Device{older, newer} {
+		if err := store.Create(ctx, device); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	token, _ := older.Trust(time.Hour, now)
+	if err := store.Update(ctx, older); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, _ := store.Get(ctx, older.ID)
+	if !got.VerifyTrustToken(token, now) {
+		t.Error("stored device does not verify its trust token")
+	}
+
+	devices, err := store.ListByUser(ctx, user.ID)
+	if err != nil || len(devices) != 2 || devices[0].ID != newer.ID {
+		t.Fatalf("ListByUser() = %v, %v, want newest first", devices, err)
+	}
+
+	if err := store.Delete(ctx, newer.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := store.Delete(ctx, newer.ID); err != auth.ErrDeviceNotFound {
+		t.Errorf("Delete() twice error = %v, want ErrDeviceNotFound", err)
+	}
+	if err := store.DeleteByUser(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteByUser() error = %v", err)
+	}
+	if devices, _ := store.ListByUser(ctx, user.ID); len(devices) != 0 {
+		t.Errorf("ListByUser() after DeleteByUser = %v", devices)
+	}
+}
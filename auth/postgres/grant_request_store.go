@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/db/dbutil"
+)
+
+const grantRequestColumns = `id, username, role_id, reason, status, requested_by, requested_at,
+	decided_by, decided_at, note, grant_id`
+
+var grantRequestErrors = dbutil.ErrorMap{
+	NotFound: auth.ErrGrantRequestNotFound,
+	Unique:   map[string]error{"": auth.ErrGrantRequestExists},
+}
+
+type grantRequestStore struct {
+	db *sql.DB
+}
+
+func NewGrantRequestStore(db *sql.DB) auth.GrantRequestStore {
+	return &grantRequestStore{db: db}
+}
+
+func scanGrantRequest(row dbutil.Scanner) (*auth.GrantRequest, error) {
+	req := &auth.GrantRequest{}
+	err := row.Scan(
+		&req.ID, &req.Username, &req.RoleID, &req.Reason, &req.Status, &req.RequestedBy, &req.RequestedAt,
+		&req.DecidedBy, &req.DecidedAt, &req.Note, &req.GrantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (s *grantRequestStore) Create(ctx context.Context, req *auth.GrantRequest) error {
+	query := `
+		INSERT INTO grant_requests (` + grantRequestColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err := dbutil.Exec(ctx, dbutil.Conn(ctx, s.db), grantRequestErrors, query,
+		req.ID, req.Username, req.RoleID, req.Reason, req.Status, req.RequestedBy, req.RequestedAt,
+		req.DecidedBy, req.DecidedAt, req.Note, req.GrantID,
+	)
+	return err
+}
+
+func (s *grantRequestStore) Get(ctx context.Context, id auth.GrantRequestID) (*auth.GrantRequest, error) {
+	query := `SELECT ` + grantRequestColumns + ` FROM grant_requests WHERE id = $1`
+	return dbutil.Get(ctx, dbutil.Conn(ctx, s.db), scanGrantRequest, grantRequestErrors, query, id)
+}
+
+func (s *grantRequestStore) Update(ctx context.Context, req *auth.GrantRequest) error {
+	q := dbutil.Conn(ctx, s.db)
+	query := `
+		UPDATE grant_requests SET
+			status = $2, decided_by = $3, decided_at = $4, note = $5, grant_id = $6
+		WHERE id = $1 AND status = 'pending'
+	`
+	rows, err := dbutil.Exec(ctx, q, grantRequestErrors, query,
+		req.ID, req.Status, req.DecidedBy, req.DecidedAt, req.Note, req.GrantID,
+	)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		var exists bool
+		query := `SELECT EXISTS (SELECT 1 FROM grant_requests WHERE id = $1)`
+		if err := q.QueryRowContext(ctx, query, req.ID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return auth.ErrGrantRequestNotFound
+		}
+		return auth.ErrGrantRequestDecided
+	}
+	return nil
+}
+
+func (s *grantRequestStore) List(ctx context.Context, status auth.GrantRequestStatus) ([]*auth.GrantRequest, error) {
+	query := `SELECT ` + grantRequestColumns + ` FROM grant_requests
+		WHERE $1 = '' OR status = $1
+		ORDER BY requested_at ASC`
+	return dbutil.Select(ctx, dbutil.Conn(ctx, s.db), scanGrantRequest, query, status)
+}
+
+func (s *grantRequestStore) ListByUser(ctx context.Context, username string) ([]*auth.GrantRequest, error) {
+	query := `SELECT ` + grantRequestColumns + ` FROM grant_requests
+		WHERE username = $1
+		ORDER BY requested_at DESC`
+	return dbutil.Select(ctx, dbutil.Conn(ctx, s.db), scanGrantRequest, query, username)
+}
+
+func (s *grantRequestStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+var _ auth.GrantRequestStore = (*grantRequestStore)(nil)
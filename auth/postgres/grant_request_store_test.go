@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func setupGrantRequestTestDB(t *testing.T) (auth.GrantRequestStore, func()) {
+	t.Helper()
+
+	db, cleanup := setupTestDB(t)
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS grant_requests (
+			id UUID PRIMARY KEY,
+			username TEXT NOT NULL,
+			role_id UUID NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'pending',
+			requested_by TEXT NOT NULL,
+			requested_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			decided_by TEXT NOT NULL DEFAULT '',
+			decided_at TIMESTAMPTZ,
+			note TEXT NOT NULL DEFAULT '',
+			grant_id UUID
+		)
+	`)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to create grant_requests table: %v", err)
+	}
+
+	return NewGrantRequestStore(db), func() {
+		db.Exec("DROP TABLE IF EXISTS grant_requests")
+		cleanup()
+	}
+}
+
+func TestGrantRequestStore(t *testing.T) {
+	store, cleanup := setupGrantRequestTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	first := auth.NewGrantRequest("ann", auth.NewRoleID(), "on-call", "ann")
+	second := auth.NewGrantRequest("bob", auth.NewRoleID(), "", "bob")
+	second.RequestedAt = first.RequestedAt.Add(time.Second)
+	for _, req := range []*auth.GrantRequest{second, first} {
+		if err := store.Create(ctx, req); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	req, err := store.Get(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if req.DecidedAt != nil || req.GrantID != nil || req.Reason != "on-call" {
+		t.Errorf("Get() = %+v", req)
+	}
+
+	grantID := auth.NewGrantID()
+	req.GrantID = &grantID
+	if err := req.Decide(auth.GrantRequestApproved, "carol", "ok", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Update(ctx, req); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := store.Update(ctx, req); err != auth.ErrGrantRequestDecided {
+		t.Errorf("Update() decided error = %v, want ErrGrantRequestDecided", err)
+	}
+	if err := store.Update(ctx, auth.NewGrantRequest("dan", auth.NewRoleID(), "", "dan")); err != auth.ErrGrantRequestNotFound {
+		t.Errorf("Update() missing error = %v, want ErrGrantRequestNotFound", err)
+	}
+
+	got, _ := store.Get(ctx, first.ID)
+	if got.Status != auth.GrantRequestApproved || got.DecidedAt == nil || got.GrantID == nil || *got.GrantID != grantID {
+		t.Errorf("Get() after Update() = %+v", got)
+	}
+
+	all, err := store.List(ctx, "")
+	if err != nil || len(all) != 2 || all[0].ID != first.ID {
+		t.Errorf("List() = %+v, %v", all, err)
+	}
+	pending, _ := store.List(ctx, auth.GrantRequestPending)
+	if len(pending) != 1 || pending[0].ID != second.ID {
+		t.Errorf("List(pending) = %+v", pending)
+	}
+
+	mine, _ := store.ListByUser(ctx, "ann")
+	if len(mine) != 1 || mine[0].ID != first.ID {
+		t.Errorf("ListByUser(ann) = %+v", mine)
+	}
+	if none, err := store.ListByUser(ctx, "zoe"); err != nil || len(none) != 0 {
+		t.Errorf("ListByUser(zoe) = %+v, %v", none, err)
+	}
+
+	if _, err := store.Get(ctx, auth.NewGrantRequestID()); err != auth.ErrGrantRequestNotFound {
+		t.Errorf("Get() missing error = %v, want ErrGrantRequestNotFound", err)
+	}
+}
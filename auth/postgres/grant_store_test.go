@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 
 	"github.com/aquamarinepk/aqm/auth"
@@ -11,6 +12,20 @@ func setupGrantTestDB(t *testing.T) (*grantStore, *roleStore, func()) {
 	t.Helper()
 
 	db, cleanup := setupTestDB(t)
+	createGrantTables(t, db)
+
+	gstore := NewGrantStore(db).(*grantStore)
+	rstore := NewRoleStore(db).(*roleStore)
+
+	return gstore, rstore, func() {
+		db.Exec("DROP TABLE IF EXISTS grants")
+		db.Exec("DROP TABLE IF EXISTS roles")
+		cleanup()
+	}
+}
+
+func createGrantTables(t *testing.T, db *sql.DB) {
+	t.Helper()
 
 	_, err := db.Exec(`
 		CREATE TABLE IF NOT EXISTS roles (
@@ -22,7 +37,10 @@ func setupGrantTestDB(t *testing.T) (*grantStore, *roleStore, func()) {
 			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
 			created_by TEXT NOT NULL,
 			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-			updated_by TEXT NOT NULL
+			updated_by TEXT NOT NULL,
+			version BIGINT NOT NULL DEFAULT 1,
+			permission_sets JSONB NOT NULL DEFAULT '[]'::jsonb,
+			set_permissions JSONB NOT NULL DEFAULT '[]'::jsonb
 		);
 		CREATE TABLE IF NOT EXISTS grants (
 			id UUID PRIMARY KEY,
@@ -36,15 +54,6 @@ func setupGrantTestDB(t *testing.T) (*grantStore, *roleStore, func()) {
 	if err != nil {
 		t.Fatalf("failed to create tables: %v", err)
 	}
-
-	gstore := NewGrantStore(db).(*grantStore)
-	rstore := NewRoleStore(db).(*roleStore)
-
-	return gstore, rstore, func() {
-		db.Exec("DROP TABLE IF EXISTS grants")
-		db.Exec("DROP TABLE IF EXISTS roles")
-		cleanup()
-	}
 }
 
 func TestGrantStoreCreate(t *testing.T) {
@@ -205,3 +214,18 @@ func TestGrantStoreGetUserRoles(t *testing.T) {
 		t.Errorf("GetUserRoles() missing expected roles")
 	}
 }
+
+func TestGrantStorePing(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := NewGrantStore(db)
+	if err := store.Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+
+	db.Close()
+	if err := store.Ping(context.Background()); err == nil {
+		t.Error("Ping() should fail once the database is closed")
+	}
+}
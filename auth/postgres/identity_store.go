@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/db/dbutil"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type identityStore struct {
+	db *sql.DB
+}
+
+func NewIdentityStore(db *sql.DB) auth.IdentityStore {
+	return &identityStore{db: db}
+}
+
+func (s *identityStore) Create(ctx context.Context, identity *auth.Identity) error {
+	query := `
+		INSERT INTO identities (id, user_id, provider, subject, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := dbutil.Conn(ctx, s.db).ExecContext(ctx, query,
+		identity.ID, identity.UserID, identity.Provider, identity.Subject, identity.CreatedAt,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return auth.ErrIdentityAlreadyExists
+	}
+	return err
+}
+
+func (s *identityStore) GetByProviderSubject(ctx context.Context, provider, subject string) (*auth.Identity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, created_at
+		FROM identities
+		WHERE provider = $1 AND subject = $2
+	`
+	identity := &auth.Identity{}
+	err := dbutil.Conn(ctx, s.db).QueryRowContext(ctx, query, provider, subject).Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, auth.ErrIdentityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (s *identityStore) ListByUser(ctx context.Context, userID auth.UserID) ([]*auth.Identity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, created_at
+		FROM identities
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+	rows, err := dbutil.Conn(ctx, s.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*auth.Identity
+	for rows.Next() {
+		identity := &auth.Identity{}
+		if err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+func (s *identityStore) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := dbutil.Conn(ctx, s.db).ExecContext(ctx, `DELETE FROM identities WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return auth.ErrIdentityNotFound
+	}
+	return nil
+}
+
+func (s *identityStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+var _ auth.IdentityStore = (*identityStore)(nil)
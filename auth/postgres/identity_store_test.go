@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func setupIdentityTestDB(t *testing.T) (auth.IdentityStore, *auth.User, func()) {
+	t.Helper()
+
+	db, cleanup := setupTestDB(t)
+	ctx := context.Background()
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS identities (
+			id UUID PRIMARY KEY,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			provider TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			UNIQUE(provider, subject)
+		)
+	`)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to create identities table: %v", err)
+	}
+
+	user := auth.NewUser()
+	user.Username = "identityuser"
+	user.Name = "Identity User"
+	user.EmailCT = []byte("encrypted")
+	user.EmailIV = []byte("iv")
+	user.EmailTag = []byte("tag")
+	user.EmailLookup = []byte("identity-lookup")
+	user.PasswordHash = []byte("hash")
+	user.PasswordSalt = []byte("salt")
+	user.BeforeCreate()
+	if err := NewUserStore(db).Create(ctx, user); err != nil {
+		cleanup()
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return NewIdentityStore(db), user, func() {
+		db.Exec("DROP TABLE IF EXISTS identities")
+		cleanup()
+	}
+}
+
+func TestIdentityStoreCreateAndGet(t *testing.T) {
+	store, user, cleanup := setupIdentityTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	identity := auth.NewIdentity(user.ID, "google", "g-1")
+
+	if err := store.Create(ctx, identity); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := store.Create(ctx, auth.NewIdentity(user.ID, "google", "g-1")); err != auth.ErrIdentityAlreadyExists {
+		t.Errorf("Create() duplicate error = %v, want ErrIdentityAlreadyExists", err)
+	}
+
+	got, err := store.GetByProviderSubject(ctx, "google", "g-1")
+	if err != nil {
+		t.Fatalf("GetByProviderSubject() error = %v", err)
+	}
+	if got.ID != identity.ID || got.UserID != user.ID {
+		t.Errorf("GetByProviderSubject() = %+v, want %+v", got, identity)
+	}
+
+	if _, err := store.GetByProviderSubject(ctx, "github", "g-1"); err != auth.ErrIdentityNotFound {
+		t.Errorf("GetByProviderSubject() error = %v, want ErrIdentityNotFound", err)
+	}
+}
+
+func TestIdentityStoreListAndDelete(t *testing.T) {
+	store, user, cleanup := setupIdentityTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	google := auth.NewIdentity(user.ID, "google", "g-1")
+	github := auth.NewIdentity(user.ID, "github", "42")
+	store.Create(ctx, google)
+	store.Create(ctx, github)
+
+	list, err := store.ListByUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("ListByUser() error = %v", err)
+	}
+	if len(list) != 2 {
+		t.Errorf("ListByUser() count = %d, want 2", len(list))
+	}
+
+	if err := store.Delete(ctx, google.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := store.Delete(ctx, google.ID); err != auth.ErrIdentityNotFound {
+		t.Errorf("Delete() again error = %v, want ErrIdentityNotFound", err)
+	}
+
+	list, _ = store.ListByUser(ctx, user.ID)
+	if len(list) != 1 || list[0].Provider != "github" {
+		t.Errorf("ListByUser() after delete = %v, want github only", list)
+	}
+}
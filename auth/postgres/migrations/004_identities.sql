@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS identities (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_identities_user_id ON identities(user_id);
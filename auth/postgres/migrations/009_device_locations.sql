@@ -0,0 +1,2 @@
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS last_seen_country TEXT NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS last_seen_city TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS consents (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    policy TEXT NOT NULL,
+    version TEXT NOT NULL,
+    accepted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    remote_ip TEXT NOT NULL DEFAULT '',
+    UNIQUE(user_id, policy, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_consents_policy ON consents(policy, version);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS permission_sets (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    permissions JSONB NOT NULL DEFAULT '[]'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_by TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_by TEXT NOT NULL,
+    version BIGINT NOT NULL DEFAULT 1
+);
+
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS permission_sets JSONB NOT NULL DEFAULT '[]'::jsonb;
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS set_permissions JSONB NOT NULL DEFAULT '[]'::jsonb;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_grant_requests_username ON grant_requests(username, requested_at);
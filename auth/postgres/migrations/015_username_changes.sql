@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS username_changes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_username TEXT NOT NULL,
+    new_username TEXT NOT NULL,
+    changed_by TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_username_changes_old_username ON username_changes(old_username, changed_at);
+CREATE INDEX IF NOT EXISTS idx_username_changes_user_id ON username_changes(user_id, changed_at);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS settings (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    data JSONB NOT NULL DEFAULT '{}'::jsonb,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_by TEXT NOT NULL DEFAULT '',
+    version BIGINT NOT NULL DEFAULT 1
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS token_revocations (
+    id BIGSERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    session_id TEXT NOT NULL DEFAULT '',
+    issued_before TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_revocations_session_id ON token_revocations(session_id) WHERE session_id <> '';
+CREATE INDEX IF NOT EXISTS idx_token_revocations_user_id ON token_revocations(user_id) WHERE session_id = '';
+CREATE INDEX IF NOT EXISTS idx_token_revocations_expires_at ON token_revocations(expires_at);
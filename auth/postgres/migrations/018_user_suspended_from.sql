@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_from TEXT NOT NULL DEFAULT '';
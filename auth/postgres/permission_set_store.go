@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/db/dbutil"
+)
+
+const permissionSetColumns = `name, description, permissions,
+	created_at, created_by, updated_at, updated_by, version`
+
+var permissionSetErrors = dbutil.ErrorMap{
+	NotFound: auth.ErrPermissionSetNotFound,
+	Unique:   map[string]error{"": auth.ErrPermissionSetExists},
+}
+
+type permissionSetStore struct {
+	db *sql.DB
+}
+
+func NewPermissionSetStore(db *sql.DB) auth.PermissionSetStore {
+	return &permissionSetStore{db: db}
+}
+
+func scanPermissionSet(row dbutil.Scanner) (*auth.PermissionSet, error) {
+	set := &auth.PermissionSet{}
+	var permsJSON []byte
+	err := row.Scan(
+		&set.Name, &set.Description, &permsJSON,
+		&set.CreatedAt, &set.CreatedBy, &set.UpdatedAt, &set.UpdatedBy, &set.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(permsJSON, &set.Permissions); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+func (s *permissionSetStore) Create(ctx context.Context, set *auth.PermissionSet) error {
+	permsJSON, err := json.Marshal(set.Permissions)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO permission_sets (` + permissionSetColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err = dbutil.Exec(ctx, dbutil.Conn(ctx, s.db), permissionSetErrors, query,
+		set.Name, set.Description, permsJSON,
+		set.CreatedAt, set.CreatedBy, set.UpdatedAt, set.UpdatedBy, set.Version,
+	)
+	return err
+}
+
+func (s *permissionSetStore) Get(ctx context.Context, name string) (*auth.PermissionSet, error) {
+	query := `SELECT ` + permissionSetColumns + ` FROM permission_sets WHERE name = $1`
+	return dbutil.Get(ctx, dbutil.Conn(ctx, s.db), scanPermissionSet, permissionSetErrors, query, name)
+}
+
+func (s *permissionSetStore) Update(ctx context.Context, set *auth.PermissionSet) error {
+	permsJSON, err := json.Marshal(set.Permissions)
+	if err != nil {
+		return err
+	}
+
+	q := dbutil.Conn(ctx, s.db)
+	query := `
+		UPDATE permission_sets SET
+			description = $2, permissions = $3,
+			updated_at = $4, updated_by = $5, version = version + 1
+		WHERE name = $1 AND version = $6
+	`
+	rows, err := dbutil.Exec(ctx, q, permissionSetErrors, query,
+		set.Name, set.Description, permsJSON, set.UpdatedAt, set.UpdatedBy, set.Version,
+	)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		var exists bool
+		query := `SELECT EXISTS (SELECT 1 FROM permission_sets WHERE name = $1)`
+		if err := q.QueryRowContext(ctx, query, set.Name).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return auth.ErrPermissionSetNotFound
+		}
+		return auth.ErrVersionConflict
+	}
+	set.Version++
+	return nil
+}
+
+func (s *permissionSetStore) Delete(ctx context.Context, name string) error {
+	query := `DELETE FROM permission_sets WHERE name = $1`
+	return dbutil.ExecOne(ctx, dbutil.Conn(ctx, s.db), permissionSetErrors, query, name)
+}
+
+func (s *permissionSetStore) List(ctx context.Context) ([]*auth.PermissionSet, error) {
+	query := `SELECT ` + permissionSetColumns + ` FROM permission_sets ORDER BY name ASC`
+	return dbutil.Select(ctx, dbutil.Conn(ctx, s.db), scanPermissionSet, query)
+}
+
+func (s *permissionSetStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+var _ auth.PermissionSetStore = (*permissionSetStore)(nil)
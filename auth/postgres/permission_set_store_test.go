@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func setupPermissionSetTestDB(t *testing.T) (auth.PermissionSetStore, func()) {
+	t.Helper()
+
+	db, cleanup := setupTestDB(t)
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS permission_sets (
+			name TEXT PRIMARY KEY,
+			description TEXT NOT NULL DEFAULT '',
+			permissions JSONB NOT NULL DEFAULT '[]'::jsonb,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			created_by TEXT NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_by TEXT NOT NULL,
+			version BIGINT NOT NULL DEFAULT 1
+		)
+	`)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to create permission_sets table: %v", err)
+	}
+
+	return NewPermissionSetStore(db), func() {
+		db.Exec("DROP TABLE IF EXISTS permission_sets")
+		cleanup()
+	}
+}
+
+func TestPermissionSetStore(t *testing.T) {
+	store, cleanup := setupPermissionSetTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, name := range []string{"reviewer", "content-editor"} {
+		set := auth.NewPermissionSet(name, "", []string{"content:read"})
+		set.CreatedBy, set.UpdatedBy = "system", "system"
+		set.BeforeCreate()
+		if err := store.Create(ctx, set); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	dup := auth.NewPermissionSet("reviewer", "", nil)
+	dup.BeforeCreate()
+	if err := store.Create(ctx, dup); err != auth.ErrPermissionSetExists {
+		t.Errorf("Create() duplicate error = %v, want ErrPermissionSetExists", err)
+	}
+
+	set, err := store.Get(ctx, "content-editor")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	stale := *set
+	set.Permissions = []string{"content:read", "content:write"}
+	set.BeforeUpdate()
+	if err := store.Update(ctx, set); err != nil || set.Version != 2 {
+		t.Fatalf("Update() = %v, version %d", err, set.Version)
+	}
+	if err := store.Update(ctx, &stale); err != auth.ErrVersionConflict {
+		t.Errorf("Update() stale error = %v, want ErrVersionConflict", err)
+	}
+	if got, _ := store.Get(ctx, "content-editor"); len(got.Permissions) != 2 {
+		t.Errorf("Get() after update = %+v", got)
+	}
+
+	sets, err := store.List(ctx)
+	if err != nil || len(sets) != 2 || sets[0].Name != "content-editor" {
+		t.Errorf("List() = %+v, %v", sets, err)
+	}
+
+	if err := store.Delete(ctx, "reviewer"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, "reviewer"); err != auth.ErrPermissionSetNotFound {
+		t.Errorf("Get() deleted error = %v, want ErrPermissionSetNotFound", err)
+	}
+	if err := store.Update(ctx, &auth.PermissionSet{Name: "reviewer", Version: 1}); err != auth.ErrPermissionSetNotFound {
+		t.Errorf("Update() missing error = %v, want ErrPermissionSetNotFound", err)
+	}
+}
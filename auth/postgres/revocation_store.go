@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/db/dbutil"
+)
+
+var revocationErrors = dbutil.ErrorMap{}
+
+type revocationStore struct {
+	db *sql.DB
+}
+
+// NewRevocationStore returns a store keeping token revocations in the
+// token_revocations table. Create deletes the expired rows, so the table
+// stays small without calling DeleteExpired; expired rows are ignored until
+// then.
+func NewRevocationStore(db *sql.DB) auth.RevocationStore {
+	return &revocationStore{db: db}
+}
+
+// Create stores revocation, deleting the expired ones in the same
+// statement.
+func (s *revocationStore) Create(ctx context.Context, revocation *auth.Revocation) error {
+	query := `
+		WITH expired AS (
+			DELETE FROM token_revocations WHERE expires_at <= NOW()
+		)
+		INSERT INTO token_revocations (user_id, session_id, issued_before, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := dbutil.Exec(ctx, dbutil.Conn(ctx, s.db), revocationErrors, query,
+		revocation.UserID, revocation.SessionID, revocation.IssuedBefore, revocation.ExpiresAt,
+	)
+	return err
+}
+
+func (s *revocationStore) IsRevoked(ctx context.Context, userID, sessionID string, issuedAt time.Time) (bool, error) {
+	query := `SELECT EXISTS (
+		SELECT 1 FROM token_revocations
+		WHERE expires_at > NOW() AND (
+			(session_id <> '' AND session_id = $2) OR
+			(session_id = '' AND user_id = $1 AND issued_before > $3)
+		)
+	)`
+	scan := func(row dbutil.Scanner) (bool, error) {
+		var revoked bool
+		err := row.Scan(&revoked)
+		return revoked, err
+	}
+	return dbutil.Get(ctx, dbutil.Conn(ctx, s.db), scan, revocationErrors, query, userID, sessionID, issuedAt)
+}
+
+func (s *revocationStore) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	query := `DELETE FROM token_revocations WHERE expires_at <= $1`
+	deleted, err := dbutil.Exec(ctx, dbutil.Conn(ctx, s.db), revocationErrors, query, now)
+	return int(deleted), err
+}
+
+func (s *revocationStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+var _ auth.RevocationStore = (*revocationStore)(nil)
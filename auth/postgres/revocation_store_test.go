@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func setupRevocationTestDB(t *testing.T) (auth.RevocationStore, func()) {
+	t.Helper()
+
+	db, cleanup := setupTestDB(t)
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS token_revocations (
+			id BIGSERIAL PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			session_id TEXT NOT NULL DEFAULT '',
+			issued_before TIMESTAMPTZ NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL
+		)
+	`)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to create token_revocations table: %v", err)
+	}
+
+	return NewRevocationStore(db), func() {
+		db.Exec("DROP TABLE IF EXISTS token_revocations")
+		cleanup()
+	}
+}
+
+func TestRevocationStore(t *testing.T) {
+	store, cleanup := setupRevocationTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	revocations := []*auth.Revocation{
+		{UserID: "ann", SessionID: "s-1", IssuedBefore: now, ExpiresAt: now.Add(time.Hour)},
+		{UserID: "bob", IssuedBefore: now, ExpiresAt: now.Add(time.Hour)},
+		{UserID: "eve", IssuedBefore: now, ExpiresAt: now.Add(-time.Minute)},
+	}
+	for _, r := range revocations {
+		if err := store.Create(ctx, r); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		userID    string
+		sessionID string
+		issuedAt  time.Time
+		want      bool
+	}{
+		{"revoked session", "ann", "s-1", now, true},
+		{"other session", "ann", "s-2", now.Add(-time.Minute), false},
+		{"user token issued before", "bob", "s-3", now.Add(-time.Minute), true},
+		{"user token issued after", "bob", "s-4", now.Add(time.Minute), false},
+		{"user token issued in the same second", "bob", "s-6", now.Add(time.Millisecond), false},
+		{"expired revocation", "eve", "s-5", now.Add(-time.Hour), false},
+	}
+	for _, tt := range tests {
+		got, err := store.IsRevoked(ctx, tt.userID, tt.sessionID, tt.issuedAt)
+		if err != nil {
+			t.Fatalf("%s: IsRevoked() error = %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: IsRevoked() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	deleted, err := store.DeleteExpired(ctx, now)
+	if err != nil || deleted != 1 {
+		t.Errorf("DeleteExpired() = %d, %v, want 1", deleted, err)
+	}
+}
+
+func TestRevocationStoreCreatePrunesExpired(t *testing.T) {
+	store, cleanup := setupRevocationTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	expired := &auth.Revocation{UserID: "ann", IssuedBefore: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)}
+	current := &auth.Revocation{UserID: "bob", IssuedBefore: now, ExpiresAt: now.Add(time.Hour)}
+	for _, r := range []*auth.Revocation{expired, current} {
+		if err := store.Create(ctx, r); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	if deleted, err := store.DeleteExpired(ctx, now); err != nil || deleted != 0 {
+		t.Errorf("DeleteExpired() after Create() = %d, %v, want nothing left to delete", deleted, err)
+	}
+	if revoked, _ := store.IsRevoked(ctx, "bob", "s-1", now.Add(-time.Minute)); !revoked {
+		t.Error("Create() pruned an unexpired revocation")
+	}
+}
@@ -6,9 +6,18 @@ import (
 	"encoding/json"
 
 	"github.com/aquamarinepk/aqm/auth"
-	"github.com/google/uuid"
+	"github.com/aquamarinepk/aqm/db/dbutil"
 )
 
+const roleColumns = `id, name, description, permissions, status,
+	created_at, created_by, updated_at, updated_by, version,
+	permission_sets, set_permissions`
+
+var roleErrors = dbutil.ErrorMap{
+	NotFound: auth.ErrRoleNotFound,
+	Unique:   map[string]error{"": auth.ErrRoleAlreadyExists},
+}
+
 type roleStore struct {
 	db *sql.DB
 }
@@ -17,188 +26,125 @@ func NewRoleStore(db *sql.DB) auth.RoleStore {
 	return &roleStore{db: db}
 }
 
-func (s *roleStore) Create(ctx context.Context, role *auth.Role) error {
-	permsJSON, err := json.Marshal(role.Permissions)
-	if err != nil {
-		return err
-	}
-
-	query := `
-		INSERT INTO roles (
-			id, name, description, permissions, status,
-			created_at, created_by, updated_at, updated_by
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9
-		)
-	`
-	_, err = s.db.ExecContext(ctx, query,
-		role.ID, role.Name, role.Description, permsJSON, role.Status,
-		role.CreatedAt, role.CreatedBy, role.UpdatedAt, role.UpdatedBy,
-	)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (s *roleStore) Get(ctx context.Context, id uuid.UUID) (*auth.Role, error) {
-	query := `
-		SELECT id, name, description, permissions, status,
-			created_at, created_by, updated_at, updated_by
-		FROM roles
-		WHERE id = $1
-	`
+func scanRole(row dbutil.Scanner) (*auth.Role, error) {
 	role := &auth.Role{}
-	var permsJSON []byte
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
+	var permsJSON, setsJSON, setPermsJSON []byte
+	err := row.Scan(
 		&role.ID, &role.Name, &role.Description, &permsJSON, &role.Status,
-		&role.CreatedAt, &role.CreatedBy, &role.UpdatedAt, &role.UpdatedBy,
+		&role.CreatedAt, &role.CreatedBy, &role.UpdatedAt, &role.UpdatedBy, &role.Version,
+		&setsJSON, &setPermsJSON,
 	)
-	if err == sql.ErrNoRows {
-		return nil, auth.ErrRoleNotFound
-	}
 	if err != nil {
 		return nil, err
 	}
 	if err := json.Unmarshal(permsJSON, &role.Permissions); err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal(setsJSON, &role.PermissionSets); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(setPermsJSON, &role.SetPermissions); err != nil {
+		return nil, err
+	}
+	if len(role.PermissionSets) == 0 {
+		role.PermissionSets, role.SetPermissions = nil, nil
+	}
 	return role, nil
 }
 
-func (s *roleStore) GetByName(ctx context.Context, name string) (*auth.Role, error) {
-	query := `
-		SELECT id, name, description, permissions, status,
-			created_at, created_by, updated_at, updated_by
-		FROM roles
-		WHERE name = $1
-	`
-	role := &auth.Role{}
-	var permsJSON []byte
-	err := s.db.QueryRowContext(ctx, query, name).Scan(
-		&role.ID, &role.Name, &role.Description, &permsJSON, &role.Status,
-		&role.CreatedAt, &role.CreatedBy, &role.UpdatedAt, &role.UpdatedBy,
-	)
-	if err == sql.ErrNoRows {
-		return nil, auth.ErrRoleNotFound
+// rolePermissionsJSON encodes the permission lists of role for storage.
+func rolePermissionsJSON(role *auth.Role) (perms, sets, setPerms []byte, err error) {
+	if perms, err = json.Marshal(role.Permissions); err != nil {
+		return nil, nil, nil, err
 	}
-	if err != nil {
-		return nil, err
+	if sets, err = json.Marshal(nonNilStrings(role.PermissionSets)); err != nil {
+		return nil, nil, nil, err
 	}
-	if err := json.Unmarshal(permsJSON, &role.Permissions); err != nil {
-		return nil, err
+	if setPerms, err = json.Marshal(nonNilStrings(role.SetPermissions)); err != nil {
+		return nil, nil, nil, err
 	}
-	return role, nil
+	return perms, sets, setPerms, nil
 }
 
-func (s *roleStore) Update(ctx context.Context, role *auth.Role) error {
-	permsJSON, err := json.Marshal(role.Permissions)
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func (s *roleStore) Create(ctx context.Context, role *auth.Role) error {
+	permsJSON, setsJSON, setPermsJSON, err := rolePermissionsJSON(role)
 	if err != nil {
 		return err
 	}
 
 	query := `
-		UPDATE roles SET
-			name = $2, description = $3, permissions = $4, status = $5,
-			updated_at = $6, updated_by = $7
-		WHERE id = $1
+		INSERT INTO roles (` + roleColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
-	result, err := s.db.ExecContext(ctx, query,
+	_, err = dbutil.Exec(ctx, dbutil.Conn(ctx, s.db), roleErrors, query,
 		role.ID, role.Name, role.Description, permsJSON, role.Status,
-		role.UpdatedAt, role.UpdatedBy,
+		role.CreatedAt, role.CreatedBy, role.UpdatedAt, role.UpdatedBy, role.Version,
+		setsJSON, setPermsJSON,
 	)
-	if err != nil {
-		return err
-	}
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return auth.ErrRoleNotFound
-	}
-	return nil
+	return err
+}
+
+func (s *roleStore) Get(ctx context.Context, id auth.RoleID) (*auth.Role, error) {
+	query := `SELECT ` + roleColumns + ` FROM roles WHERE id = $1`
+	return dbutil.Get(ctx, dbutil.Conn(ctx, s.db), scanRole, roleErrors, query, id)
+}
+
+func (s *roleStore) GetByName(ctx context.Context, name string) (*auth.Role, error) {
+	query := `SELECT ` + roleColumns + ` FROM roles WHERE name = $1`
+	return dbutil.Get(ctx, dbutil.Conn(ctx, s.db), scanRole, roleErrors, query, name)
 }
 
-func (s *roleStore) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `UPDATE roles SET status = 'inactive', updated_at = NOW() WHERE id = $1`
-	result, err := s.db.ExecContext(ctx, query, id)
+func (s *roleStore) Update(ctx context.Context, role *auth.Role) error {
+	permsJSON, setsJSON, setPermsJSON, err := rolePermissionsJSON(role)
 	if err != nil {
 		return err
 	}
-	rows, err := result.RowsAffected()
+
+	query := `
+		UPDATE roles SET
+			name = $2, description = $3, permissions = $4, status = $5,
+			updated_at = $6, updated_by = $7, version = version + 1,
+			permission_sets = $9, set_permissions = $10
+		WHERE id = $1 AND version = $8
+	`
+	rows, err := dbutil.Exec(ctx, dbutil.Conn(ctx, s.db), roleErrors, query,
+		role.ID, role.Name, role.Description, permsJSON, role.Status,
+		role.UpdatedAt, role.UpdatedBy, role.Version, setsJSON, setPermsJSON,
+	)
 	if err != nil {
 		return err
 	}
 	if rows == 0 {
-		return auth.ErrRoleNotFound
+		return missingOrConflict(ctx, dbutil.Conn(ctx, s.db), "roles", role.ID, auth.ErrRoleNotFound)
 	}
+	role.Version++
 	return nil
 }
 
+func (s *roleStore) Delete(ctx context.Context, id auth.RoleID) error {
+	query := `UPDATE roles SET status = 'inactive', updated_at = NOW(), version = version + 1 WHERE id = $1`
+	return dbutil.ExecOne(ctx, dbutil.Conn(ctx, s.db), roleErrors, query, id)
+}
+
 func (s *roleStore) List(ctx context.Context) ([]*auth.Role, error) {
-	query := `
-		SELECT id, name, description, permissions, status,
-			created_at, created_by, updated_at, updated_by
-		FROM roles
-		ORDER BY created_at DESC
-	`
-	rows, err := s.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var roles []*auth.Role
-	for rows.Next() {
-		role := &auth.Role{}
-		var permsJSON []byte
-		err := rows.Scan(
-			&role.ID, &role.Name, &role.Description, &permsJSON, &role.Status,
-			&role.CreatedAt, &role.CreatedBy, &role.UpdatedAt, &role.UpdatedBy,
-		)
-		if err != nil {
-			return nil, err
-		}
-		if err := json.Unmarshal(permsJSON, &role.Permissions); err != nil {
-			return nil, err
-		}
-		roles = append(roles, role)
-	}
-	return roles, rows.Err()
+	query := `SELECT ` + roleColumns + ` FROM roles ORDER BY created_at DESC`
+	return dbutil.Select(ctx, dbutil.Conn(ctx, s.db), scanRole, query)
 }
 
 func (s *roleStore) ListByStatus(ctx context.Context, status auth.RoleStatus) ([]*auth.Role, error) {
-	query := `
-		SELECT id, name, description, permissions, status,
-			created_at, created_by, updated_at, updated_by
-		FROM roles
-		WHERE status = $1
-		ORDER BY created_at DESC
-	`
-	rows, err := s.db.QueryContext(ctx, query, status)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var roles []*auth.Role
-	for rows.Next() {
-		role := &auth.Role{}
-		var permsJSON []byte
-		err := rows.Scan(
-			&role.ID, &role.Name, &role.Description, &permsJSON, &role.Status,
-			&role.CreatedAt, &role.CreatedBy, &role.UpdatedAt, &role.UpdatedBy,
-		)
-		if err != nil {
-			return nil, err
-		}
-		if err := json.Unmarshal(permsJSON, &role.Permissions); err != nil {
-			return nil, err
-		}
-		roles = append(roles, role)
-	}
-	return roles, rows.Err()
+	query := `SELECT ` + roleColumns + ` FROM roles WHERE status = $1 ORDER BY created_at DESC`
+	return dbutil.Select(ctx, dbutil.Conn(ctx, s.db), scanRole, query, status)
+}
+
+func (s *roleStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
 }
 
 var _ auth.RoleStore = (*roleStore)(nil)
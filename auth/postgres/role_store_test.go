@@ -22,7 +22,10 @@ func setupRoleTestDB(t *testing.T) (*roleStore, func()) {
 			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
 			created_by TEXT NOT NULL,
 			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-			updated_by TEXT NOT NULL
+			updated_by TEXT NOT NULL,
+			version BIGINT NOT NULL DEFAULT 1,
+			permission_sets JSONB NOT NULL DEFAULT '[]'::jsonb,
+			set_permissions JSONB NOT NULL DEFAULT '[]'::jsonb
 		)
 	`)
 	if err != nil {
@@ -158,6 +161,23 @@ func TestRoleStoreUpdate(t *testing.T) {
 			},
 			wantErr: auth.ErrRoleNotFound,
 		},
+		{
+			name: "stale version",
+			setup: func() *auth.Role {
+				role := auth.NewRole()
+				role.Name = "stale"
+				role.CreatedBy = "system"
+				role.UpdatedBy = "system"
+				role.BeforeCreate()
+				store.Create(ctx, role)
+
+				current := *role
+				current.BeforeUpdate()
+				store.Update(ctx, &current)
+				return role
+			},
+			wantErr: auth.ErrVersionConflict,
+		},
 	}
 
 	for _, tt := range tests {
@@ -186,6 +206,10 @@ func TestRoleStoreUpdate(t *testing.T) {
 				t.Fatalf("Get() error = %v", err)
 			}
 
+			if retrieved.Version != 2 || role.Version != 2 {
+				t.Errorf("Version = %d (stored %d), want 2", role.Version, retrieved.Version)
+			}
+
 			if retrieved.Description != "Updated Moderator" {
 				t.Errorf("Description = %v, want %v", retrieved.Description, "Updated Moderator")
 			}
@@ -201,6 +225,38 @@ func TestRoleStoreUpdate(t *testing.T) {
 	}
 }
 
+func TestRoleStorePermissionSets(t *testing.T) {
+	store, cleanup := setupRoleTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	role := auth.NewRole()
+	role.Name = "editor"
+	role.Permissions = []string{"content:read"}
+	role.PermissionSets = []string{"content-editor"}
+	role.SetPermissions = []string{"content:write"}
+	role.CreatedBy = "system"
+	role.UpdatedBy = "system"
+	role.BeforeCreate()
+	if err := store.Create(ctx, role); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := store.Get(ctx, role.ID)
+	if err != nil || len(got.PermissionSets) != 1 || len(got.SetPermissions) != 1 {
+		t.Fatalf("Get() = %+v, %v", got, err)
+	}
+
+	got.PermissionSets, got.SetPermissions = nil, nil
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, _ = store.Get(ctx, role.ID)
+	if got.PermissionSets != nil || got.SetPermissions != nil {
+		t.Errorf("Get() after clearing sets = %+v", got)
+	}
+}
+
 func TestRoleStoreDelete(t *testing.T) {
 	store, cleanup := setupRoleTestDB(t)
 	defer cleanup()
@@ -338,3 +394,18 @@ func TestRoleStoreListByStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestRoleStorePing(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := NewRoleStore(db)
+	if err := store.Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+
+	db.Close()
+	if err := store.Ping(context.Background()); err == nil {
+		t.Error("Ping() should fail once the database is closed")
+	}
+}
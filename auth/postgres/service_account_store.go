@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/db/dbutil"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const serviceAccountColumns = `id, name, description, client_id, secret_hash, status, created_at, created_by, updated_at, updated_by`
+
+type serviceAccountStore struct {
+	db *sql.DB
+}
+
+func NewServiceAccountStore(db *sql.DB) auth.ServiceAccountStore {
+	return &serviceAccountStore{db: db}
+}
+
+func scanServiceAccount(row dbutil.Scanner) (*auth.ServiceAccount, error) {
+	account := &auth.ServiceAccount{}
+	err := row.Scan(
+		&account.ID, &account.Name, &account.Description, &account.ClientID, &account.SecretHash,
+		&account.Status, &account.CreatedAt, &account.CreatedBy, &account.UpdatedAt, &account.UpdatedBy,
+	)
+	if err == sql.ErrNoRows {
+		return nil, auth.ErrServiceAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (s *serviceAccountStore) Create(ctx context.Context, account *auth.ServiceAccount) error {
+	query := `
+		INSERT INTO service_accounts (` + serviceAccountColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err := dbutil.Conn(ctx, s.db).ExecContext(ctx, query,
+		account.ID, account.Name, account.Description, account.ClientID, account.SecretHash,
+		account.Status, account.CreatedAt, account.CreatedBy, account.UpdatedAt, account.UpdatedBy,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return auth.ErrServiceAccountExists
+	}
+	return err
+}
+
+func (s *serviceAccountStore) Get(ctx context.Context, id uuid.UUID) (*auth.ServiceAccount, error) {
+	query := `SELECT ` + serviceAccountColumns + ` FROM service_accounts WHERE id = $1`
+	return scanServiceAccount(dbutil.Conn(ctx, s.db).QueryRowContext(ctx, query, id))
+}
+
+func (s *serviceAccountStore) GetByClientID(ctx context.Context, clientID string) (*auth.ServiceAccount, error) {
+	query := `SELECT ` + serviceAccountColumns + ` FROM service_accounts WHERE client_id = $1`
+	return scanServiceAccount(dbutil.Conn(ctx, s.db).QueryRowContext(ctx, query, clientID))
+}
+
+func (s *serviceAccountStore) Update(ctx context.Context, account *auth.ServiceAccount) error {
+	query := `
+		UPDATE service_accounts
+		SET description = $2, secret_hash = $3, status = $4, updated_at = $5, updated_by = $6
+		WHERE id = $1
+	`
+	result, err := dbutil.Conn(ctx, s.db).ExecContext(ctx, query,
+		account.ID, account.Description, account.SecretHash, account.Status, account.UpdatedAt, account.UpdatedBy,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return auth.ErrServiceAccountNotFound
+	}
+	return nil
+}
+
+func (s *serviceAccountStore) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := dbutil.Conn(ctx, s.db).ExecContext(ctx, `DELETE FROM service_accounts WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return auth.ErrServiceAccountNotFound
+	}
+	return nil
+}
+
+func (s *serviceAccountStore) List(ctx context.Context) ([]*auth.ServiceAccount, error) {
+	query := `SELECT ` + serviceAccountColumns + ` FROM service_accounts ORDER BY name`
+	rows, err := dbutil.Conn(ctx, s.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*auth.ServiceAccount
+	for rows.Next() {
+		account, err := scanServiceAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+func (s *serviceAccountStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+var _ auth.ServiceAccountStore = (*serviceAccountStore)(nil)
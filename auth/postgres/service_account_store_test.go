@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/google/uuid"
+)
+
+func setupServiceAccountTestDB(t *testing.T) (auth.ServiceAccountStore, func()) {
+	t.Helper()
+
+	db, cleanup := setupTestDB(t)
+
+	_, err := db.ExecContext(context.Background(), `
+		CREATE TABLE IF NOT EXISTS service_accounts (
+			id UUID PRIMARY KEY,
+			name TEXT UNIQUE NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			client_id TEXT UNIQUE NOT NULL,
+			secret_hash BYTEA NOT NULL,
+			status TEXT NOT NULL DEFAULT 'active',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			created_by TEXT NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_by TEXT NOT NULL
+		)
+	`)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to create service_accounts table: %v", err)
+	}
+
+	return NewServiceAccountStore(db), func() {
+		db.Exec("DROP TABLE IF EXISTS service_accounts")
+		cleanup()
+	}
+}
+
+func newTestServiceAccount(t *testing.T, name string) *auth.ServiceAccount {
+	t.Helper()
+	account := auth.NewServiceAccount()
+	account.Name = name
+	account.CreatedBy = "admin"
+	account.UpdatedBy = "admin"
+	if _, err := account.SetSecret(); err != nil {
+		t.Fatalf("SetSecret() error = %v", err)
+	}
+	account.BeforeCreate()
+	return account
+}
+
+func TestServiceAccountStoreCreateAndGet(t *testing.T) {
+	store, cleanup := setupServiceAccountTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	account := newTestServiceAccount(t, "worker")
+
+	if err := store.Create(ctx, account); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := store.Create(ctx, newTestServiceAccount(t, "worker")); err != auth.ErrServiceAccountExists {
+		t.Errorf("Create() duplicate error = %v, want ErrServiceAccountExists", err)
+	}
+
+	got, err := store.GetByClientID(ctx, account.ClientID)
+	if err != nil {
+		t.Fatalf("GetByClientID() error = %v", err)
+	}
+	if got.ID != account.ID || got.Name != "worker" || got.Status != auth.ServiceAccountStatusActive {
+		t.Errorf("GetByClientID() = %+v", got)
+	}
+	if string(got.SecretHash) != string(account.SecretHash) {
+		t.Error("GetByClientID() should return the stored secret hash")
+	}
+
+	if _, err := store.Get(ctx, uuid.New()); err != auth.ErrServiceAccountNotFound {
+		t.Errorf("Get() error = %v, want ErrServiceAccountNotFound", err)
+	}
+}
+
+func TestServiceAccountStoreUpdateListDelete(t *testing.T) {
+	store, cleanup := setupServiceAccountTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	worker := newTestServiceAccount(t, "worker")
+	billing := newTestServiceAccount(t, "billing")
+	store.Create(ctx, worker)
+	store.Create(ctx, billing)
+
+	worker.Status = auth.ServiceAccountStatusDisabled
+	worker.Description = "Nightly jobs"
+	worker.BeforeUpdate()
+	if err := store.Update(ctx, worker); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, _ := store.Get(ctx, worker.ID)
+	if got.Status != auth.ServiceAccountStatusDisabled || got.Description != "Nightly jobs" {
+		t.Errorf("Update() stored %+v", got)
+	}
+
+	list, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "billing" {
+		t.Errorf("List() = %v, want billing first", list)
+	}
+
+	if err := store.Delete(ctx, worker.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := store.Delete(ctx, worker.ID); err != auth.ErrServiceAccountNotFound {
+		t.Errorf("Delete() twice error = %v, want ErrServiceAccountNotFound", err)
+	}
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/db/dbutil"
+)
+
+var settingsErrors = dbutil.ErrorMap{
+	NotFound: auth.ErrSettingsNotFound,
+}
+
+// settingsData is the part of auth.Settings kept in the data column.
+type settingsData struct {
+	SessionTTLSeconds int                  `json:"session_ttl_seconds,omitempty"`
+	MFARequired       *bool                `json:"mfa_required,omitempty"`
+	PasswordPolicy    *auth.PasswordPolicy `json:"password_policy,omitempty"`
+	EmailDomains      *auth.EmailDomains   `json:"email_domains,omitempty"`
+}
+
+type settingsStore struct {
+	db *sql.DB
+}
+
+// NewSettingsStore returns a store keeping the settings in a single row of
+// the settings table.
+func NewSettingsStore(db *sql.DB) auth.SettingsStore {
+	return &settingsStore{db: db}
+}
+
+func scanSettings(row dbutil.Scanner) (*auth.Settings, error) {
+	settings := &auth.Settings{}
+	var dataJSON []byte
+	if err := row.Scan(&dataJSON, &settings.UpdatedAt, &settings.UpdatedBy, &settings.Version); err != nil {
+		return nil, err
+	}
+
+	var data settingsData
+	if err := json.Unmarshal(dataJSON, &data); err != nil {
+		return nil, err
+	}
+	settings.SessionTTLSeconds = data.SessionTTLSeconds
+	settings.MFARequired = data.MFARequired
+	settings.PasswordPolicy = data.PasswordPolicy
+	settings.EmailDomains = data.EmailDomains
+	return settings, nil
+}
+
+func (s *settingsStore) Get(ctx context.Context) (*auth.Settings, error) {
+	query := `SELECT data, updated_at, updated_by, version FROM settings WHERE id`
+	return dbutil.Get(ctx, dbutil.Conn(ctx, s.db), scanSettings, settingsErrors, query)
+}
+
+func (s *settingsStore) Save(ctx context.Context, settings *auth.Settings) error {
+	dataJSON, err := json.Marshal(settingsData{
+		SessionTTLSeconds: settings.SessionTTLSeconds,
+		MFARequired:       settings.MFARequired,
+		PasswordPolicy:    settings.PasswordPolicy,
+		EmailDomains:      settings.EmailDomains,
+	})
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE settings SET data = $1, updated_at = $2, updated_by = $3, version = version + 1
+		WHERE id AND version = $4
+	`
+	if settings.Version == 0 {
+		query = `
+			INSERT INTO settings (id, data, updated_at, updated_by, version)
+			VALUES (TRUE, $1, $2, $3, $4 + 1)
+			ON CONFLICT (id) DO NOTHING
+		`
+	}
+
+	rows, err := dbutil.Exec(ctx, dbutil.Conn(ctx, s.db), settingsErrors, query,
+		dataJSON, settings.UpdatedAt, settings.UpdatedBy, settings.Version,
+	)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return auth.ErrVersionConflict
+	}
+	settings.Version++
+	return nil
+}
+
+func (s *settingsStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+var _ auth.SettingsStore = (*settingsStore)(nil)
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func setupSettingsTestDB(t *testing.T) (auth.SettingsStore, func()) {
+	t.Helper()
+
+	db, cleanup := setupTestDB(t)
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS settings (
+			id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+			data JSONB NOT NULL DEFAULT '{}'::jsonb,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_by TEXT NOT NULL DEFAULT '',
+			version BIGINT NOT NULL DEFAULT 1
+		)
+	`)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to create settings table: %v", err)
+	}
+
+	return NewSettingsStore(db), func() {
+		db.Exec("DROP TABLE IF EXISTS settings")
+		cleanup()
+	}
+}
+
+func TestSettingsStore(t *testing.T) {
+	store, cleanup := setupSettingsTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx); err != auth.ErrSettingsNotFound {
+		t.Fatalf("Get() before Save error = %v, want ErrSettingsNotFound", err)
+	}
+
+	required := true
+	settings := &auth.Settings{
+		SessionTTLSeconds: 3600,
+		MFARequired:       &required,
+		PasswordPolicy:    &auth.PasswordPolicy{MinLength: 12},
+		EmailDomains:      &auth.EmailDomains{Allow: []string{"example.com"}, Deny: []string{"*.example.com"}},
+		UpdatedAt:         time.Now(),
+		UpdatedBy:         "admin",
+	}
+	if err := store.Save(ctx, settings); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if settings.Version != 1 {
+		t.Errorf("Version = %d, want 1", settings.Version)
+	}
+
+	got, err := store.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.SessionTTLSeconds != 3600 || got.MFARequired == nil || !*got.MFARequired ||
+		got.PasswordPolicy == nil || got.PasswordPolicy.MinLength != 12 ||
+		got.EmailDomains == nil || len(got.EmailDomains.Deny) != 1 || got.UpdatedBy != "admin" || got.Version != 1 {
+		t.Errorf("Get() = %+v", got)
+	}
+
+	if err := store.Save(ctx, &auth.Settings{UpdatedAt: time.Now()}); err != auth.ErrVersionConflict {
+		t.Errorf("second create error = %v, want ErrVersionConflict", err)
+	}
+
+	got.SessionTTLSeconds = 60
+	if err := store.Save(ctx, got); err != nil || got.Version != 2 {
+		t.Fatalf("Save() update = %v, version %d", err, got.Version)
+	}
+	stale := *got
+	stale.Version = 1
+	if err := store.Save(ctx, &stale); err != auth.ErrVersionConflict {
+		t.Errorf("stale update error = %v, want ErrVersionConflict", err)
+	}
+}
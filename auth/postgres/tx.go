@@ -0,0 +1,14 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/db/dbutil"
+)
+
+// NewTransactor returns an auth.Transactor running units of work in
+// transactions on db. Stores created on the same db take part in them.
+func NewTransactor(db *sql.DB) auth.Transactor {
+	return dbutil.NewTransactor(db)
+}
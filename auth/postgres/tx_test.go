@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func TestTransactorRollsBackAcrossStores(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	createGrantTables(t, db)
+	defer db.Exec("DROP TABLE IF EXISTS grants; DROP TABLE IF EXISTS roles")
+
+	users := NewUserStore(db)
+	grants := NewGrantStore(db)
+	tx := NewTransactor(db)
+	ctx := context.Background()
+
+	user := auth.NewUser()
+	user.Username = "txuser"
+	user.Name = "Tx User"
+	user.EmailCT = []byte("encrypted")
+	user.EmailIV = []byte("iv")
+	user.EmailTag = []byte("tag")
+	user.EmailLookup = []byte("tx-lookup")
+	user.PasswordHash = []byte("hash")
+	user.PasswordSalt = []byte("salt")
+	user.Status = auth.UserStatusActive
+	user.BeforeCreate()
+
+	err := tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := users.Create(ctx, user); err != nil {
+			return err
+		}
+		return grants.Create(ctx, auth.NewGrant(user.Username, auth.NewRoleID(), "test"))
+	})
+	if !errors.Is(err, auth.ErrRoleNotFound) {
+		t.Fatalf("WithinTx() error = %v, want %v", err, auth.ErrRoleNotFound)
+	}
+
+	if _, err := users.GetByUsername(ctx, user.Username); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("GetByUsername() after rollback error = %v, want %v", err, auth.ErrUserNotFound)
+	}
+
+	if err := tx.WithinTx(ctx, func(ctx context.Context) error { return users.Create(ctx, user) }); err != nil {
+		t.Fatalf("WithinTx() commit error = %v", err)
+	}
+	if _, err := users.GetByUsername(ctx, user.Username); err != nil {
+		t.Errorf("GetByUsername() after commit error = %v", err)
+	}
+}
@@ -3,11 +3,29 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
 
 	"github.com/aquamarinepk/aqm/auth"
-	"github.com/google/uuid"
+	"github.com/aquamarinepk/aqm/db/dbutil"
 )
 
+const userColumns = `id, username, name,
+	email_ct, email_iv, email_tag, email_lookup,
+	password_hash, password_salt,
+	mfa_secret_ct, pin_ct, pin_iv, pin_tag, pin_lookup, key_scheme,
+	status, suspended_from, attributes, created_at, created_by, updated_at, updated_by, version`
+
+var userErrors = dbutil.ErrorMap{
+	NotFound: auth.ErrUserNotFound,
+	Unique: map[string]error{
+		"users_username_key": auth.ErrUsernameExists,
+		"":                   auth.ErrUserAlreadyExists,
+	},
+}
+
 type userStore struct {
 	db *sql.DB
 }
@@ -16,252 +34,188 @@ func NewUserStore(db *sql.DB) auth.UserStore {
 	return &userStore{db: db}
 }
 
-func (s *userStore) Create(ctx context.Context, user *auth.User) error {
-	query := `
-		INSERT INTO users (
-			id, username, name,
-			email_ct, email_iv, email_tag, email_lookup,
-			password_hash, password_salt,
-			mfa_secret_ct, pin_ct, pin_iv, pin_tag, pin_lookup,
-			status, created_at, created_by, updated_at, updated_by
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19
-		)
-	`
-	_, err := s.db.ExecContext(ctx, query,
-		user.ID, user.Username, user.Name,
-		user.EmailCT, user.EmailIV, user.EmailTag, user.EmailLookup,
-		user.PasswordHash, user.PasswordSalt,
-		user.MFASecretCT, user.PINCT, user.PINIV, user.PINTag, user.PINLookup,
-		user.Status, user.CreatedAt, user.CreatedBy, user.UpdatedAt, user.UpdatedBy,
-	)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (s *userStore) Get(ctx context.Context, id uuid.UUID) (*auth.User, error) {
-	query := `
-		SELECT id, username, name,
-			email_ct, email_iv, email_tag, email_lookup,
-			password_hash, password_salt,
-			mfa_secret_ct, pin_ct, pin_iv, pin_tag, pin_lookup,
-			status, created_at, created_by, updated_at, updated_by
-		FROM users
-		WHERE id = $1
-	`
+func scanUser(row dbutil.Scanner) (*auth.User, error) {
 	user := &auth.User{}
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
+	var attrsJSON []byte
+	err := row.Scan(
 		&user.ID, &user.Username, &user.Name,
 		&user.EmailCT, &user.EmailIV, &user.EmailTag, &user.EmailLookup,
 		&user.PasswordHash, &user.PasswordSalt,
-		&user.MFASecretCT, &user.PINCT, &user.PINIV, &user.PINTag, &user.PINLookup,
-		&user.Status, &user.CreatedAt, &user.CreatedBy, &user.UpdatedAt, &user.UpdatedBy,
+		&user.MFASecretCT, &user.PINCT, &user.PINIV, &user.PINTag, &user.PINLookup, &user.KeyScheme,
+		&user.Status, &user.SuspendedFrom, &attrsJSON, &user.CreatedAt, &user.CreatedBy, &user.UpdatedAt, &user.UpdatedBy, &user.Version,
 	)
-	if err == sql.ErrNoRows {
-		return nil, auth.ErrUserNotFound
-	}
 	if err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal(attrsJSON, &user.Attributes); err != nil {
+		return nil, err
+	}
+	if len(user.Attributes) == 0 {
+		user.Attributes = nil
+	}
 	return user, nil
 }
 
-func (s *userStore) GetByEmailLookup(ctx context.Context, lookup []byte) (*auth.User, error) {
-	query := `
-		SELECT id, username, name,
-			email_ct, email_iv, email_tag, email_lookup,
-			password_hash, password_salt,
-			mfa_secret_ct, pin_ct, pin_iv, pin_tag, pin_lookup,
-			status, created_at, created_by, updated_at, updated_by
-		FROM users
-		WHERE email_lookup = $1
-	`
-	user := &auth.User{}
-	err := s.db.QueryRowContext(ctx, query, lookup).Scan(
-		&user.ID, &user.Username, &user.Name,
-		&user.EmailCT, &user.EmailIV, &user.EmailTag, &user.EmailLookup,
-		&user.PasswordHash, &user.PasswordSalt,
-		&user.MFASecretCT, &user.PINCT, &user.PINIV, &user.PINTag, &user.PINLookup,
-		&user.Status, &user.CreatedAt, &user.CreatedBy, &user.UpdatedAt, &user.UpdatedBy,
-	)
-	if err == sql.ErrNoRows {
-		return nil, auth.ErrUserNotFound
+func userArgs(user *auth.User) (dbutil.Args, error) {
+	attrs := user.Attributes
+	if attrs == nil {
+		attrs = auth.Attributes{}
 	}
+	attrsJSON, err := json.Marshal(attrs)
 	if err != nil {
 		return nil, err
 	}
-	return user, nil
+	return dbutil.Args{
+		"id": user.ID, "username": user.Username, "name": user.Name,
+		"email_ct": user.EmailCT, "email_iv": user.EmailIV, "email_tag": user.EmailTag, "email_lookup": user.EmailLookup,
+		"password_hash": user.PasswordHash, "password_salt": user.PasswordSalt,
+		"mfa_secret_ct": user.MFASecretCT, "pin_ct": user.PINCT, "pin_iv": user.PINIV, "pin_tag": user.PINTag, "pin_lookup": user.PINLookup, "key_scheme": user.KeyScheme,
+		"status": user.Status, "suspended_from": user.SuspendedFrom, "attributes": attrsJSON, "created_at": user.CreatedAt, "created_by": user.CreatedBy,
+		"updated_at": user.UpdatedAt, "updated_by": user.UpdatedBy, "version": user.Version,
+	}, nil
 }
 
-func (s *userStore) GetByUsername(ctx context.Context, username string) (*auth.User, error) {
-	query := `
-		SELECT id, username, name,
-			email_ct, email_iv, email_tag, email_lookup,
-			password_hash, password_salt,
-			mfa_secret_ct, pin_ct, pin_iv, pin_tag, pin_lookup,
-			status, created_at, created_by, updated_at, updated_by
-		FROM users
-		WHERE username = $1
-	`
-	user := &auth.User{}
-	err := s.db.QueryRowContext(ctx, query, username).Scan(
-		&user.ID, &user.Username, &user.Name,
-		&user.EmailCT, &user.EmailIV, &user.EmailTag, &user.EmailLookup,
-		&user.PasswordHash, &user.PasswordSalt,
-		&user.MFASecretCT, &user.PINCT, &user.PINIV, &user.PINTag, &user.PINLookup,
-		&user.Status, &user.CreatedAt, &user.CreatedBy, &user.UpdatedAt, &user.UpdatedBy,
-	)
-	if err == sql.ErrNoRows {
-		return nil, auth.ErrUserNotFound
+func (s *userStore) Create(ctx context.Context, user *auth.User) error {
+	named, err := userArgs(user)
+	if err != nil {
+		return err
 	}
+	query, args, err := dbutil.Named(`
+		INSERT INTO users (`+userColumns+`) VALUES (
+			:id, :username, :name,
+			:email_ct, :email_iv, :email_tag, :email_lookup,
+			:password_hash, :password_salt,
+			:mfa_secret_ct, :pin_ct, :pin_iv, :pin_tag, :pin_lookup, :key_scheme,
+			:status, :suspended_from, :attributes, :created_at, :created_by, :updated_at, :updated_by, :version
+		)
+	`, named)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return user, nil
+	_, err = dbutil.Exec(ctx, dbutil.Conn(ctx, s.db), userErrors, query, args...)
+	return err
+}
+
+func (s *userStore) Get(ctx context.Context, id auth.UserID) (*auth.User, error) {
+	return s.getBy(ctx, "id", id)
+}
+
+func (s *userStore) GetByEmailLookup(ctx context.Context, lookup []byte) (*auth.User, error) {
+	return s.getBy(ctx, "email_lookup", lookup)
+}
+
+func (s *userStore) GetByUsername(ctx context.Context, username string) (*auth.User, error) {
+	return s.getBy(ctx, "username", username)
 }
 
 func (s *userStore) GetByPINLookup(ctx context.Context, lookup []byte) (*auth.User, error) {
-	query := `
-		SELECT id, username, name,
-			email_ct, email_iv, email_tag, email_lookup,
-			password_hash, password_salt,
-			mfa_secret_ct, pin_ct, pin_iv, pin_tag, pin_lookup,
-			status, created_at, created_by, updated_at, updated_by
-		FROM users
-		WHERE pin_lookup = $1
-	`
-	user := &auth.User{}
-	err := s.db.QueryRowContext(ctx, query, lookup).Scan(
-		&user.ID, &user.Username, &user.Name,
-		&user.EmailCT, &user.EmailIV, &user.EmailTag, &user.EmailLookup,
-		&user.PasswordHash, &user.PasswordSalt,
-		&user.MFASecretCT, &user.PINCT, &user.PINIV, &user.PINTag, &user.PINLookup,
-		&user.Status, &user.CreatedAt, &user.CreatedBy, &user.UpdatedAt, &user.UpdatedBy,
-	)
-	if err == sql.ErrNoRows {
-		return nil, auth.ErrUserNotFound
-	}
-	if err != nil {
-		return nil, err
-	}
-	return user, nil
+	return s.getBy(ctx, "pin_lookup", lookup)
+}
+
+// getBy reads the user whose column equals value; column is never user input.
+func (s *userStore) getBy(ctx context.Context, column string, value any) (*auth.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE ` + column + ` = $1`
+	return dbutil.Get(ctx, dbutil.Conn(ctx, s.db), scanUser, userErrors, query, value)
 }
 
+// Update writes user if its Version still matches the stored one and
+// increments it; otherwise it returns auth.ErrVersionConflict.
 func (s *userStore) Update(ctx context.Context, user *auth.User) error {
-	query := `
-		UPDATE users SET
-			username = $2, name = $3,
-			email_ct = $4, email_iv = $5, email_tag = $6, email_lookup = $7,
-			password_hash = $8, password_salt = $9,
-			mfa_secret_ct = $10, pin_ct = $11, pin_iv = $12, pin_tag = $13, pin_lookup = $14,
-			status = $15, updated_at = $16, updated_by = $17
-		WHERE id = $1
-	`
-	result, err := s.db.ExecContext(ctx, query,
-		user.ID, user.Username, user.Name,
-		user.EmailCT, user.EmailIV, user.EmailTag, user.EmailLookup,
-		user.PasswordHash, user.PasswordSalt,
-		user.MFASecretCT, user.PINCT, user.PINIV, user.PINTag, user.PINLookup,
-		user.Status, user.UpdatedAt, user.UpdatedBy,
-	)
+	named, err := userArgs(user)
 	if err != nil {
 		return err
 	}
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return auth.ErrUserNotFound
-	}
-	return nil
-}
-
-func (s *userStore) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `UPDATE users SET status = 'deleted', updated_at = NOW() WHERE id = $1`
-	result, err := s.db.ExecContext(ctx, query, id)
+	query, args, err := dbutil.Named(`
+		UPDATE users SET
+			username = :username, name = :name,
+			email_ct = :email_ct, email_iv = :email_iv, email_tag = :email_tag, email_lookup = :email_lookup,
+			password_hash = :password_hash, password_salt = :password_salt,
+			mfa_secret_ct = :mfa_secret_ct, pin_ct = :pin_ct, pin_iv = :pin_iv, pin_tag = :pin_tag, pin_lookup = :pin_lookup, key_scheme = :key_scheme,
+			status = :status, suspended_from = :suspended_from, attributes = :attributes, updated_at = :updated_at, updated_by = :updated_by, version = version + 1
+		WHERE id = :id AND version = :version
+	`, named)
 	if err != nil {
 		return err
 	}
-	rows, err := result.RowsAffected()
+	rows, err := dbutil.Exec(ctx, dbutil.Conn(ctx, s.db), userErrors, query, args...)
 	if err != nil {
 		return err
 	}
 	if rows == 0 {
-		return auth.ErrUserNotFound
+		return missingOrConflict(ctx, dbutil.Conn(ctx, s.db), "users", user.ID, auth.ErrUserNotFound)
 	}
+	user.Version++
 	return nil
 }
 
+func (s *userStore) Delete(ctx context.Context, id auth.UserID) error {
+	query := `UPDATE users SET status = 'deleted', updated_at = NOW(), version = version + 1 WHERE id = $1`
+	return dbutil.ExecOne(ctx, dbutil.Conn(ctx, s.db), userErrors, query, id)
+}
+
 func (s *userStore) List(ctx context.Context) ([]*auth.User, error) {
-	query := `
-		SELECT id, username, name,
-			email_ct, email_iv, email_tag, email_lookup,
-			password_hash, password_salt,
-			mfa_secret_ct, pin_ct, pin_iv, pin_tag, pin_lookup,
-			status, created_at, created_by, updated_at, updated_by
-		FROM users
-		ORDER BY created_at DESC
-	`
-	rows, err := s.db.QueryContext(ctx, query)
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY created_at DESC`
+	return dbutil.Select(ctx, dbutil.Conn(ctx, s.db), scanUser, query)
+}
+
+func (s *userStore) ListByStatus(ctx context.Context, status auth.UserStatus) ([]*auth.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE status = $1 ORDER BY created_at DESC`
+	return dbutil.Select(ctx, dbutil.Conn(ctx, s.db), scanUser, query, status)
+}
+
+func (s *userStore) Search(ctx context.Context, q auth.UserQuery) (*auth.UserPage, error) {
+	q, err := q.Normalize()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var users []*auth.User
-	for rows.Next() {
-		user := &auth.User{}
-		err := rows.Scan(
-			&user.ID, &user.Username, &user.Name,
-			&user.EmailCT, &user.EmailIV, &user.EmailTag, &user.EmailLookup,
-			&user.PasswordHash, &user.PasswordSalt,
-			&user.MFASecretCT, &user.PINCT, &user.PINIV, &user.PINTag, &user.PINLookup,
-			&user.Status, &user.CreatedAt, &user.CreatedBy, &user.UpdatedAt, &user.UpdatedBy,
-		)
-		if err != nil {
-			return nil, err
-		}
-		users = append(users, user)
+	var where dbutil.Where
+	if q.UsernamePrefix != "" {
+		where.Add(`username LIKE ? ESCAPE '\'`, escapeLike(q.UsernamePrefix)+"%")
+	}
+	if q.Name != "" {
+		where.Add(`name ILIKE ? ESCAPE '\'`, "%"+escapeLike(q.Name)+"%")
+	}
+	if q.Status != "" {
+		where.Add("status = ?", q.Status)
+	}
+	if q.Role != "" {
+		where.Add(`EXISTS (
+			SELECT 1 FROM grants g JOIN roles r ON r.id = g.role_id
+			WHERE g.username = users.username AND r.name = ?
+		)`, q.Role)
+	}
+	if !q.CreatedAfter.IsZero() {
+		where.Add("created_at >= ?", q.CreatedAfter)
+	}
+	if !q.CreatedBefore.IsZero() {
+		where.Add("created_at < ?", q.CreatedBefore)
+	}
+	for _, key := range slices.Sorted(maps.Keys(q.Attributes)) {
+		where.Add("attributes->>"+where.Arg(key)+" = ?", q.Attributes[key])
 	}
-	return users, rows.Err()
-}
 
-func (s *userStore) ListByStatus(ctx context.Context, status auth.UserStatus) ([]*auth.User, error) {
-	query := `
-		SELECT id, username, name,
-			email_ct, email_iv, email_tag, email_lookup,
-			password_hash, password_salt,
-			mfa_secret_ct, pin_ct, pin_iv, pin_tag, pin_lookup,
-			status, created_at, created_by, updated_at, updated_by
-		FROM users
-		WHERE status = $1
-		ORDER BY created_at DESC
-	`
-	rows, err := s.db.QueryContext(ctx, query, status)
-	if err != nil {
+	page := &auth.UserPage{Limit: q.Limit, Offset: q.Offset}
+	if err := dbutil.Conn(ctx, s.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM users"+where.SQL(), where.Args()...).Scan(&page.Total); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var users []*auth.User
-	for rows.Next() {
-		user := &auth.User{}
-		err := rows.Scan(
-			&user.ID, &user.Username, &user.Name,
-			&user.EmailCT, &user.EmailIV, &user.EmailTag, &user.EmailLookup,
-			&user.PasswordHash, &user.PasswordSalt,
-			&user.MFASecretCT, &user.PINCT, &user.PINIV, &user.PINTag, &user.PINLookup,
-			&user.Status, &user.CreatedAt, &user.CreatedBy, &user.UpdatedAt, &user.UpdatedBy,
-		)
-		if err != nil {
-			return nil, err
-		}
-		users = append(users, user)
+	query := `SELECT ` + userColumns + ` FROM users` + where.SQL() +
+		` ORDER BY created_at DESC, id LIMIT ` + where.Arg(q.Limit) + ` OFFSET ` + where.Arg(q.Offset)
+	page.Users, err = dbutil.Select(ctx, dbutil.Conn(ctx, s.db), scanUser, query, where.Args()...)
+	if err != nil {
+		return nil, err
 	}
-	return users, rows.Err()
+	return page, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (s *userStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
 }
 
 var _ auth.UserStore = (*userStore)(nil)
@@ -3,48 +3,23 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aquamarinepk/aqm/auth"
-	_ "github.com/lib/pq"
-	"github.com/testcontainers/testcontainers-go"
-	"github.com/testcontainers/testcontainers-go/modules/postgres"
-	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/aquamarinepk/aqm/testhelper"
 )
 
+func TestMain(m *testing.M) {
+	testhelper.Main(m)
+}
+
 func setupTestDB(t *testing.T) (*sql.DB, func()) {
 	t.Helper()
 
 	ctx := context.Background()
-
-	postgresContainer, err := postgres.RunContainer(ctx,
-		testcontainers.WithImage("postgres:15-alpine"),
-		postgres.WithDatabase("testdb"),
-		postgres.WithUsername("postgres"),
-		postgres.WithPassword("postgres"),
-		testcontainers.WithWaitStrategy(
-			wait.ForLog("database system is ready to accept connections").
-				WithOccurrence(2).
-				WithStartupTimeout(60*time.Second)),
-	)
-	if err != nil {
-		t.Fatalf("failed to start postgres container: %v", err)
-	}
-
-	connStr, err := postgresContainer.ConnectionString(ctx, "sslmode=disable")
-	if err != nil {
-		t.Fatalf("failed to get connection string: %v", err)
-	}
-
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		t.Fatalf("failed to connect to postgres: %v", err)
-	}
-
-	if err := db.PingContext(ctx); err != nil {
-		t.Fatalf("failed to ping postgres: %v", err)
-	}
+	db, _, cleanup := testhelper.SetupTestDB(t)
 
 	// Create tables
 	migrations := []string{
@@ -63,27 +38,25 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 			pin_iv BYTEA,
 			pin_tag BYTEA,
 			pin_lookup BYTEA UNIQUE,
+			key_scheme SMALLINT NOT NULL DEFAULT 0,
 			status TEXT NOT NULL DEFAULT 'active',
+			suspended_from TEXT NOT NULL DEFAULT '',
+			attributes JSONB NOT NULL DEFAULT '{}'::jsonb,
 			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
 			created_by TEXT NOT NULL,
 			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-			updated_by TEXT NOT NULL
+			updated_by TEXT NOT NULL,
+			version BIGINT NOT NULL DEFAULT 1
 		)`,
 	}
 
 	for _, migration := range migrations {
 		if _, err := db.ExecContext(ctx, migration); err != nil {
+			cleanup()
 			t.Fatalf("failed to run migration: %v", err)
 		}
 	}
 
-	cleanup := func() {
-		db.Close()
-		if err := postgresContainer.Terminate(context.Background()); err != nil {
-			t.Logf("failed to terminate container: %v", err)
-		}
-	}
-
 	return db, cleanup
 }
 
@@ -120,6 +93,51 @@ func TestUserStoreCreate(t *testing.T) {
 	if retrieved.Username != user.Username {
 		t.Errorf("Username = %v, want %v", retrieved.Username, user.Username)
 	}
+	if retrieved.Attributes != nil {
+		t.Errorf("Attributes = %v, want nil", retrieved.Attributes)
+	}
+}
+
+func TestUserStoreAttributes(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := NewUserStore(db)
+	ctx := context.Background()
+
+	user := auth.NewUser()
+	user.Username = "attrs"
+	user.Name = "Attrs User"
+	user.EmailCT = []byte("encrypted")
+	user.EmailIV = []byte("iv")
+	user.EmailTag = []byte("tag")
+	user.EmailLookup = []byte("attrs_lookup")
+	user.PasswordHash = []byte("hash")
+	user.PasswordSalt = []byte("salt")
+	user.Attributes = auth.Attributes{"avatar_url": "https://example.com/a.png", "seats": 3}
+	user.BeforeCreate()
+	if err := store.Create(ctx, user); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	user.Attributes.Merge(map[string]any{"seats": nil, "timezone": "Europe/Berlin"})
+	if err := store.Update(ctx, user); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	got, err := store.Get(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if s, _ := got.Attributes.String("timezone"); s != "Europe/Berlin" {
+		t.Errorf("timezone = %q, want Europe/Berlin", s)
+	}
+	if _, ok := got.Attributes.Get("seats"); ok {
+		t.Errorf("seats still set: %v", got.Attributes)
+	}
+	if len(got.Attributes) != 2 {
+		t.Errorf("Attributes = %v, want 2 entries", got.Attributes)
+	}
 }
 
 func TestUserStoreGet(t *testing.T) {
@@ -294,6 +312,30 @@ func TestUserStoreUpdate(t *testing.T) {
 			},
 			wantErr: auth.ErrUserNotFound,
 		},
+		{
+			name: "stale version",
+			setup: func() *auth.User {
+				user := auth.NewUser()
+				user.Username = "stale"
+				user.Name = "Stale"
+				user.EmailCT = []byte("encrypted")
+				user.EmailIV = []byte("iv")
+				user.EmailTag = []byte("tag")
+				user.EmailLookup = []byte("lookup_stale")
+				user.PasswordHash = []byte("hash")
+				user.PasswordSalt = []byte("salt")
+				user.CreatedBy = "test"
+				user.UpdatedBy = "test"
+				user.BeforeCreate()
+				store.Create(ctx, user)
+
+				current := *user
+				current.BeforeUpdate()
+				store.Update(ctx, &current)
+				return user
+			},
+			wantErr: auth.ErrVersionConflict,
+		},
 	}
 
 	for _, tt := range tests {
@@ -319,6 +361,9 @@ func TestUserStoreUpdate(t *testing.T) {
 			if retrieved.Name != "Updated Name" {
 				t.Errorf("Update() name = %v, want %v", retrieved.Name, "Updated Name")
 			}
+			if retrieved.Version != 2 || user.Version != 2 {
+				t.Errorf("Update() version = %d (stored %d), want 2", user.Version, retrieved.Version)
+			}
 		})
 	}
 }
@@ -625,3 +670,109 @@ func TestUserStoreListByStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestUserStoreSearch(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	createGrantTables(t, db)
+
+	store := NewUserStore(db)
+	ctx := context.Background()
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, u := range []struct {
+		username, name string
+		status         auth.UserStatus
+		attrs          auth.Attributes
+	}{
+		{"ann", "Ann Smith", auth.UserStatusActive, auth.Attributes{"locale": "en-GB", "beta": true}},
+		{"anna", "Anna Jones", auth.UserStatusSuspended, auth.Attributes{"locale": "de-DE", "seats": 5}},
+		{"bob_x", "Bob 100% Smith", auth.UserStatusActive, nil},
+	} {
+		user := auth.NewUser()
+		user.Username = u.username
+		user.Name = u.name
+		user.Status = u.status
+		user.Attributes = u.attrs
+		user.EmailCT = []byte("encrypted")
+		user.EmailIV = []byte("iv")
+		user.EmailTag = []byte("tag")
+		user.EmailLookup = []byte(u.username + "_lookup")
+		user.PasswordHash = []byte("hash")
+		user.PasswordSalt = []byte("salt")
+		user.CreatedBy = "test"
+		user.UpdatedBy = "test"
+		user.BeforeCreate()
+		user.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		if err := store.Create(ctx, user); err != nil {
+			t.Fatalf("Failed to create user %s: %v", u.username, err)
+		}
+	}
+
+	role := auth.NewRole()
+	role.Name = "admin"
+	role.CreatedBy = "test"
+	role.UpdatedBy = "test"
+	role.BeforeCreate()
+	if err := NewRoleStore(db).Create(ctx, role); err != nil {
+		t.Fatalf("Failed to create role: %v", err)
+	}
+	if err := NewGrantStore(db).Create(ctx, auth.NewGrant("bob_x", role.ID, "test")); err != nil {
+		t.Fatalf("Failed to create grant: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		query     auth.UserQuery
+		wantNames []string
+		wantTotal int
+	}{
+		{name: "all newest first", query: auth.UserQuery{}, wantNames: []string{"bob_x", "anna", "ann"}, wantTotal: 3},
+		{name: "username prefix", query: auth.UserQuery{UsernamePrefix: "ann"}, wantNames: []string{"anna", "ann"}, wantTotal: 2},
+		{name: "prefix wildcard is literal", query: auth.UserQuery{UsernamePrefix: "bob_"}, wantNames: []string{"bob_x"}, wantTotal: 1},
+		{name: "name substring", query: auth.UserQuery{Name: "SMITH"}, wantNames: []string{"bob_x", "ann"}, wantTotal: 2},
+		{name: "name wildcard is literal", query: auth.UserQuery{Name: "100%"}, wantNames: []string{"bob_x"}, wantTotal: 1},
+		{name: "status", query: auth.UserQuery{Status: auth.UserStatusSuspended}, wantNames: []string{"anna"}, wantTotal: 1},
+		{name: "role", query: auth.UserQuery{Role: "admin"}, wantNames: []string{"bob_x"}, wantTotal: 1},
+		{name: "created range", query: auth.UserQuery{CreatedAfter: base.Add(time.Hour), CreatedBefore: base.Add(2 * time.Hour)}, wantNames: []string{"anna"}, wantTotal: 1},
+		{name: "page", query: auth.UserQuery{Limit: 1, Offset: 1}, wantNames: []string{"anna"}, wantTotal: 3},
+		{name: "string attribute", query: auth.UserQuery{Attributes: map[string]string{"locale": "de-DE"}}, wantNames: []string{"anna"}, wantTotal: 1},
+		{name: "bool and number attributes", query: auth.UserQuery{Attributes: map[string]string{"beta": "true"}}, wantNames: []string{"ann"}, wantTotal: 1},
+		{name: "number attribute", query: auth.UserQuery{Attributes: map[string]string{"seats": "5"}}, wantNames: []string{"anna"}, wantTotal: 1},
+		{name: "missing attribute", query: auth.UserQuery{Attributes: map[string]string{"locale": "fr-FR"}}, wantNames: []string{}, wantTotal: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := store.Search(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if page.Total != tt.wantTotal {
+				t.Errorf("Search() total = %d, want %d", page.Total, tt.wantTotal)
+			}
+			got := make([]string, 0, len(page.Users))
+			for _, u := range page.Users {
+				got = append(got, u.Username)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantNames, ",") {
+				t.Errorf("Search() users = %v, want %v", got, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestUserStorePing(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := NewUserStore(db)
+	if err := store.Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+
+	db.Close()
+	if err := store.Ping(context.Background()); err == nil {
+		t.Error("Ping() should fail once the database is closed")
+	}
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/db/dbutil"
+)
+
+const usernameChangeColumns = `user_id, old_username, new_username, changed_by, changed_at`
+
+var usernameChangeErrors = dbutil.ErrorMap{
+	ForeignKey: map[string]error{"": auth.ErrUserNotFound},
+}
+
+type usernameHistoryStore struct {
+	db *sql.DB
+}
+
+func NewUsernameHistoryStore(db *sql.DB) auth.UsernameHistoryStore {
+	return &usernameHistoryStore{db: db}
+}
+
+func scanUsernameChange(row dbutil.Scanner) (*auth.UsernameChange, error) {
+	c := &auth.UsernameChange{}
+	if err := row.Scan(&c.UserID, &c.OldUsername, &c.NewUsername, &c.ChangedBy, &c.ChangedAt); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *usernameHistoryStore) Record(ctx context.Context, change *auth.UsernameChange) error {
+	query := `
+		INSERT INTO username_changes (` + usernameChangeColumns + `)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := dbutil.Exec(ctx, dbutil.Conn(ctx, s.db), usernameChangeErrors, query,
+		change.UserID, change.OldUsername, change.NewUsername, change.ChangedBy, change.ChangedAt,
+	)
+	return err
+}
+
+func (s *usernameHistoryStore) ReleasedSince(ctx context.Context, username string, since time.Time) ([]*auth.UsernameChange, error) {
+	query := `SELECT ` + usernameChangeColumns + ` FROM username_changes
+		WHERE old_username = $1 AND changed_at >= $2
+		ORDER BY changed_at DESC, id DESC`
+	return dbutil.Select(ctx, dbutil.Conn(ctx, s.db), scanUsernameChange, query, username, since)
+}
+
+func (s *usernameHistoryStore) ListByUser(ctx context.Context, userID auth.UserID) ([]*auth.UsernameChange, error) {
+	query := `SELECT ` + usernameChangeColumns + ` FROM username_changes
+		WHERE user_id = $1
+		ORDER BY changed_at DESC, id DESC`
+	return dbutil.Select(ctx, dbutil.Conn(ctx, s.db), scanUsernameChange, query, userID)
+}
+
+func (s *usernameHistoryStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+var _ auth.UsernameHistoryStore = (*usernameHistoryStore)(nil)
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func setupUsernameHistoryTestDB(t *testing.T) (auth.UsernameHistoryStore, func()) {
+	t.Helper()
+
+	db, cleanup := setupTestDB(t)
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS username_changes (
+			id BIGSERIAL PRIMARY KEY,
+			user_id UUID NOT NULL,
+			old_username TEXT NOT NULL,
+			new_username TEXT NOT NULL,
+			changed_by TEXT NOT NULL,
+			changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to create username_changes table: %v", err)
+	}
+
+	return NewUsernameHistoryStore(db), func() {
+		db.Exec("DROP TABLE IF EXISTS username_changes")
+		cleanup()
+	}
+}
+
+func TestUsernameHistoryStore(t *testing.T) {
+	store, cleanup := setupUsernameHistoryTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	ann, bob := auth.NewUserID(), auth.NewUserID()
+
+	changes := []*auth.UsernameChange{
+		{UserID: ann, OldUsername: "ann", NewUsername: "annie", ChangedBy: "ann", ChangedAt: now.Add(-48 * time.Hour)},
+		{UserID: ann, OldUsername: "annie", NewUsername: "ann2", ChangedBy: "ann", ChangedAt: now.Add(-time.Hour)},
+		{UserID: bob, OldUsername: "ann", NewUsername: "bob", ChangedBy: "admin", ChangedAt: now},
+	}
+	for _, c := range changes {
+		if err := store.Record(ctx, c); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	released, err := store.ReleasedSince(ctx, "ann", now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("ReleasedSince() error = %v", err)
+	}
+	if len(released) != 1 || released[0].UserID != bob || !released[0].ChangedAt.Equal(now) {
+		t.Errorf("ReleasedSince() = %+v, want bob's change", released)
+	}
+	if none, err := store.ReleasedSince(ctx, "zoe", time.Time{}); err != nil || len(none) != 0 {
+		t.Errorf("ReleasedSince(zoe) = %+v, %v", none, err)
+	}
+
+	history, err := store.ListByUser(ctx, ann)
+	if err != nil {
+		t.Fatalf("ListByUser() error = %v", err)
+	}
+	if len(history) != 2 || history[0].NewUsername != "ann2" || history[1].OldUsername != "ann" {
+		t.Errorf("ListByUser() = %+v", history)
+	}
+}
@@ -0,0 +1,22 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/db/dbutil"
+)
+
+// missingOrConflict explains a versioned update that matched no row: the row
+// is gone (notFound) or was changed since it was read (auth.ErrVersionConflict).
+func missingOrConflict[T any](ctx context.Context, q dbutil.Querier, table string, id auth.ID[T], notFound error) error {
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM " + table + " WHERE id = $1)"
+	if err := q.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return notFound
+	}
+	return auth.ErrVersionConflict
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"errors"
+	"time"
+)
+
+// RetryAfterError annotates an error with the time after which the client may retry.
+// Handlers translate it into a Retry-After header and retry_after_seconds field.
+type RetryAfterError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// WithRetryAfter wraps err with a retry hint of d.
+func WithRetryAfter(err error, d time.Duration) error {
+	return &RetryAfterError{Err: err, After: d}
+}
+
+// RetryAfter returns the retry hint carried by err, if any.
+func RetryAfter(err error) (time.Duration, bool) {
+	var rae *RetryAfterError
+	if errors.As(err, &rae) {
+		return rae.After, true
+	}
+	return 0, false
+}
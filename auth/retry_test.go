@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		want   time.Duration
+		wantOK bool
+	}{
+		{"direct", WithRetryAfter(ErrAccountLocked, 30*time.Second), 30 * time.Second, true},
+		{"wrapped", fmt.Errorf("signin: %w", WithRetryAfter(ErrTooManyAttempts, time.Minute)), time.Minute, true},
+		{"no hint", ErrInvalidCredentials, 0, false},
+		{"nil", nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := RetryAfter(tt.err)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("RetryAfter() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRetryAfterErrorUnwrap(t *testing.T) {
+	err := WithRetryAfter(ErrAccountLocked, time.Second)
+
+	if !errors.Is(err, ErrAccountLocked) {
+		t.Error("RetryAfterError should unwrap to the wrapped error")
+	}
+	if err.Error() != ErrAccountLocked.Error() {
+		t.Errorf("Error() = %q, want %q", err.Error(), ErrAccountLocked.Error())
+	}
+}
@@ -0,0 +1,47 @@
+package auth
+
+import "time"
+
+// Revocation makes tokens invalid before they expire: those of session
+// SessionID when it is set, and otherwise every token of UserID issued
+// before IssuedBefore. It is kept until ExpiresAt, once every token it
+// covers has expired.
+type Revocation struct {
+	UserID       string    `json:"user_id"`
+	SessionID    string    `json:"session_id,omitempty"`
+	IssuedBefore time.Time `json:"issued_before"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// NewSessionRevocation revokes the tokens of session sessionID of userID
+// for ttl, the longest lifetime of those tokens.
+func NewSessionRevocation(userID, sessionID string, ttl time.Duration) *Revocation {
+	now := time.Now()
+	return &Revocation{
+		UserID:       userID,
+		SessionID:    sessionID,
+		IssuedBefore: now,
+		ExpiresAt:    now.Add(ttl),
+	}
+}
+
+// NewUserRevocation revokes every token of userID issued so far for ttl,
+// the longest lifetime of those tokens.
+func NewUserRevocation(userID string, ttl time.Duration) *Revocation {
+	return NewSessionRevocation(userID, "", ttl)
+}
+
+// Covers reports whether the revocation applies to a token of userID and
+// sessionID issued at issuedAt. Tokens without an issue time are covered
+// by every revocation of their user.
+func (r *Revocation) Covers(userID, sessionID string, issuedAt time.Time) bool {
+	if r.SessionID != "" {
+		return sessionID == r.SessionID
+	}
+	return userID == r.UserID && issuedAt.Before(r.IssuedBefore)
+}
+
+// Expired reports whether the revocation can be forgotten at now.
+func (r *Revocation) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRevocationCovers(t *testing.T) {
+	session := NewSessionRevocation("ann", "s-1", time.Hour)
+	if !session.Covers("ann", "s-1", time.Now()) || session.Covers("ann", "s-2", time.Time{}) {
+		t.Error("session revocation should cover only its session")
+	}
+
+	user := NewUserRevocation("ann", time.Hour)
+	before := user.IssuedBefore.Add(-time.Minute)
+	tests := []struct {
+		name     string
+		userID   string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"issued before", "ann", before, true},
+		{"without issue time", "ann", time.Time{}, true},
+		{"issued after", "ann", user.IssuedBefore.Add(time.Second), false},
+		{"other user", "bob", before, false},
+	}
+	for _, tt := range tests {
+		if got := user.Covers(tt.userID, "s-9", tt.issuedAt); got != tt.want {
+			t.Errorf("%s: Covers() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if user.Expired(time.Now()) || !user.Expired(user.ExpiresAt) {
+		t.Error("Expired() should hold from ExpiresAt on")
+	}
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// RiskDecision is what a RiskEvaluator wants done with a sign-in, ordered
+// from least to most restrictive.
+type RiskDecision string
+
+const (
+	// RiskAllow lets the sign-in through unremarked.
+	RiskAllow RiskDecision = "allow"
+	// RiskFlag lets the sign-in through but records it as suspicious.
+	RiskFlag RiskDecision = "flag"
+	// RiskStepUp requires a second factor before the session is trusted.
+	RiskStepUp RiskDecision = "step_up"
+)
+
+// Reasons a RiskAssessment may give.
+const (
+	RiskReasonNewIP      = "new_ip"
+	RiskReasonNewCountry = "new_country"
+	RiskReasonVelocity   = "velocity"
+)
+
+func (d RiskDecision) rank() int {
+	switch d {
+	case RiskFlag:
+		return 1
+	case RiskStepUp:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Exceeds reports whether d is more restrictive than other.
+func (d RiskDecision) Exceeds(other RiskDecision) bool {
+	return d.rank() > other.rank()
+}
+
+// SignInAttempt describes a sign-in whose credentials verified. Country
+// and City are empty when the client was not located, DeviceFingerprint
+// when no device was reported.
+type SignInAttempt struct {
+	UserID            UserID
+	IP                string
+	Country           string
+	City              string
+	UserAgent         string
+	DeviceFingerprint string
+	TrustedDevice     bool
+	At                time.Time
+}
+
+// RiskAssessment is the outcome of evaluating a SignInAttempt, with the
+// reasons behind any decision other than RiskAllow.
+type RiskAssessment struct {
+	Decision RiskDecision `json:"decision"`
+	Reasons  []string     `json:"reasons,omitempty"`
+}
+
+// Raise makes decision the assessment's decision when it is more
+// restrictive, and adds reason.
+func (a *RiskAssessment) Raise(decision RiskDecision, reason string) {
+	if decision.Exceeds(a.Decision) {
+		a.Decision = decision
+	}
+	if decision.Exceeds(RiskAllow) {
+		a.Reasons = append(a.Reasons, reason)
+	}
+}
+
+// RiskEvaluator assesses sign-ins, for example against the places and
+// pace a user usually signs in from.
+type RiskEvaluator interface {
+	Evaluate(ctx context.Context, attempt SignInAttempt) (RiskAssessment, error)
+}
+
+// NoRisk is the RiskEvaluator that allows every sign-in.
+var NoRisk RiskEvaluator = noRisk{}
+
+type noRisk struct{}
+
+func (noRisk) Evaluate(context.Context, SignInAttempt) (RiskAssessment, error) {
+	return RiskAssessment{Decision: RiskAllow}, nil
+}
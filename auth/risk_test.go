@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestRiskAssessmentRaise(t *testing.T) {
+	a := RiskAssessment{Decision: RiskAllow}
+	a.Raise(RiskAllow, RiskReasonNewIP)
+	if a.Decision != RiskAllow || len(a.Reasons) != 0 {
+		t.Errorf("allow raise = %+v", a)
+	}
+
+	a.Raise(RiskStepUp, RiskReasonNewCountry)
+	a.Raise(RiskFlag, RiskReasonNewIP)
+	if a.Decision != RiskStepUp {
+		t.Errorf("decision = %q, want step_up", a.Decision)
+	}
+	if want := []string{RiskReasonNewCountry, RiskReasonNewIP}; !slices.Equal(a.Reasons, want) {
+		t.Errorf("reasons = %v, want %v", a.Reasons, want)
+	}
+}
+
+func TestNoRisk(t *testing.T) {
+	a, err := NoRisk.Evaluate(context.Background(), SignInAttempt{IP: "203.0.113.9"})
+	if err != nil || a.Decision != RiskAllow {
+		t.Errorf("NoRisk.Evaluate() = %+v, %v", a, err)
+	}
+}
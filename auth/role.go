@@ -1,22 +1,45 @@
 package auth
 
 import (
+	"slices"
 	"time"
-
-	"github.com/google/uuid"
 )
 
 type Role struct {
-	ID          uuid.UUID  `json:"id" db:"id" bson:"_id"`
+	ID          RoleID     `json:"id" db:"id" bson:"_id"`
 	Name        string     `json:"name" db:"name" bson:"name"`
 	Description string     `json:"description" db:"description" bson:"description"`
 	Permissions []string   `json:"permissions" db:"permissions" bson:"permissions"`
 	Status      RoleStatus `json:"status" db:"status" bson:"status"`
 
+	// PermissionSets names the permission sets the role references.
+	// SetPermissions holds their expansion and is kept up to date by the
+	// service; GrantedPermissions combines it with Permissions.
+	PermissionSets []string `json:"permission_sets,omitempty" db:"permission_sets" bson:"permission_sets"`
+	SetPermissions []string `json:"set_permissions,omitempty" db:"set_permissions" bson:"set_permissions"`
+
 	CreatedAt time.Time `json:"created_at" db:"created_at" bson:"created_at"`
 	CreatedBy string    `json:"created_by" db:"created_by" bson:"created_by"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at" bson:"updated_at"`
 	UpdatedBy string    `json:"updated_by" db:"updated_by" bson:"updated_by"`
+
+	// Version is incremented by the store on every update. Update fails with
+	// ErrVersionConflict when it no longer matches the stored version.
+	Version int64 `json:"version" db:"version" bson:"version"`
+}
+
+// RoleDiff describes the impact of replacing a role's permissions.
+// AffectedUsers counts the distinct users holding the role.
+type RoleDiff struct {
+	RoleID        RoleID   `json:"role_id"`
+	Added         []string `json:"added"`
+	Removed       []string `json:"removed"`
+	AffectedUsers int      `json:"affected_users"`
+}
+
+// HasChanges reports whether the diff adds or removes any permission.
+func (d *RoleDiff) HasChanges() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0
 }
 
 func NewRole() *Role {
@@ -27,8 +50,8 @@ func NewRole() *Role {
 }
 
 func (r *Role) EnsureID() {
-	if r.ID == uuid.Nil {
-		r.ID = uuid.New()
+	if r.ID.IsZero() {
+		r.ID = NewRoleID()
 	}
 }
 
@@ -37,6 +60,7 @@ func (r *Role) BeforeCreate() {
 	now := time.Now()
 	r.CreatedAt = now
 	r.UpdatedAt = now
+	r.Version = 1
 	r.Name = NormalizeRoleName(r.Name)
 	r.Description = NormalizeDisplayName(r.Description)
 	if r.Permissions == nil {
@@ -53,8 +77,30 @@ func (r *Role) BeforeUpdate() {
 	}
 }
 
+// GrantedPermissions returns the role's own permissions followed by those
+// of its permission sets it does not list itself.
+func (r *Role) GrantedPermissions() []string {
+	if len(r.SetPermissions) == 0 {
+		return r.Permissions
+	}
+	granted := make([]string, 0, len(r.Permissions)+len(r.SetPermissions))
+	granted = append(granted, r.Permissions...)
+	for _, p := range r.SetPermissions {
+		if !slices.Contains(r.Permissions, p) {
+			granted = append(granted, p)
+		}
+	}
+	return granted
+}
+
+// ReferencesPermissionSet reports whether the role references the named
+// permission set.
+func (r *Role) ReferencesPermissionSet(name string) bool {
+	return slices.Contains(r.PermissionSets, name)
+}
+
 func (r *Role) HasPermission(permission string) bool {
-	return HasPermission(r.Permissions, permission)
+	return HasPermission(r.GrantedPermissions(), permission)
 }
 
 func (r *Role) AddPermission(permission string) {
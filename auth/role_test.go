@@ -1,10 +1,6 @@
 package auth
 
-import (
-	"testing"
-
-	"github.com/google/uuid"
-)
+import "testing"
 
 func TestNewRole(t *testing.T) {
 	role := NewRole()
@@ -25,21 +21,21 @@ func TestNewRole(t *testing.T) {
 func TestRoleEnsureID(t *testing.T) {
 	tests := []struct {
 		name    string
-		initial uuid.UUID
+		initial RoleID
 		wantNil bool
 	}{
-		{"generates ID when nil", uuid.Nil, false},
-		{"keeps existing ID", uuid.New(), false},
+		{"generates ID when nil", RoleID{}, false},
+		{"keeps existing ID", NewRoleID(), false},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			role := &Role{ID: tt.initial}
 			role.EnsureID()
-			if (role.ID == uuid.Nil) == !tt.wantNil {
-				t.Errorf("EnsureID() ID nil = %v, wantNil %v", role.ID == uuid.Nil, tt.wantNil)
+			if (role.ID.IsZero()) == !tt.wantNil {
+				t.Errorf("EnsureID() ID nil = %v, wantNil %v", role.ID.IsZero(), tt.wantNil)
 			}
-			if tt.initial != uuid.Nil && role.ID != tt.initial {
+			if !tt.initial.IsZero() && role.ID != tt.initial {
 				t.Error("EnsureID() changed existing ID")
 			}
 		})
@@ -55,7 +51,7 @@ func TestRoleBeforeCreate(t *testing.T) {
 
 	role.BeforeCreate()
 
-	if role.ID == uuid.Nil {
+	if role.ID.IsZero() {
 		t.Error("BeforeCreate() did not generate ID")
 	}
 	if role.Name != "superadmin" {
@@ -212,3 +208,23 @@ func TestRoleValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestRoleDiffHasChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		diff RoleDiff
+		want bool
+	}{
+		{"no changes", RoleDiff{Added: []string{}, Removed: []string{}}, false},
+		{"added", RoleDiff{Added: []string{"users:read"}}, true},
+		{"removed", RoleDiff{Removed: []string{"users:read"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.diff.HasChanges(); got != tt.want {
+				t.Errorf("HasChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
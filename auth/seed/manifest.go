@@ -0,0 +1,251 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+// DefaultCreatedBy is recorded as creator when a manifest does not set
+// created_by.
+const DefaultCreatedBy = "seed"
+
+// Manifest declares the roles, users and grants a service starts with.
+// Roles are applied first, then users, then grants, so grants may refer to
+// roles and users declared in the same manifest.
+type Manifest struct {
+	CreatedBy string          `json:"created_by" yaml:"created_by"`
+	Roles     []ManifestRole  `json:"roles" yaml:"roles"`
+	Users     []ManifestUser  `json:"users" yaml:"users"`
+	Grants    []ManifestGrant `json:"grants" yaml:"grants"`
+}
+
+type ManifestRole struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description" yaml:"description"`
+	Permissions []string `json:"permissions" yaml:"permissions"`
+}
+
+// ManifestUser declares a user. Password and PIN are only set when the user
+// is created, so credentials changed afterwards are never reset by a seed.
+// A user created without a password cannot sign in with one until it is
+// reset.
+type ManifestUser struct {
+	Username string `json:"username" yaml:"username"`
+	Name     string `json:"name" yaml:"name"`
+	Email    string `json:"email" yaml:"email"`
+	Password string `json:"password" yaml:"password"`
+	PIN      string `json:"pin" yaml:"pin"`
+}
+
+// ManifestGrant assigns the role with the given name to a user.
+type ManifestGrant struct {
+	Username string `json:"username" yaml:"username"`
+	Role     string `json:"role" yaml:"role"`
+}
+
+// ApplyResult counts what applying a manifest changed.
+type ApplyResult struct {
+	Created   int `json:"created" yaml:"created"`
+	Updated   int `json:"updated" yaml:"updated"`
+	Unchanged int `json:"unchanged" yaml:"unchanged"`
+}
+
+// ParseManifest decodes a manifest. The format is picked from the name's
+// extension: .yaml, .yml or .json.
+func ParseManifest(name string, data []byte) (*Manifest, error) {
+	var format string
+	switch ext := strings.ToLower(path.Ext(name)); ext {
+	case ".yaml", ".yml":
+		format = FormatYAML
+	case ".json":
+		format = FormatJSON
+	default:
+		return nil, fmt.Errorf("unsupported seed manifest format %q", ext)
+	}
+
+	m, err := DecodeManifest(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return m, nil
+}
+
+// ApplyFile reads the manifest at name from fsys, typically an embed.FS,
+// and applies it.
+func (s *Seeder) ApplyFile(ctx context.Context, fsys fs.FS, name string) (*ApplyResult, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("read seed manifest: %w", err)
+	}
+
+	m, err := ParseManifest(name, data)
+	if err != nil {
+		return nil, err
+	}
+	return s.Apply(ctx, m)
+}
+
+// Apply upserts the manifest entries: missing ones are created, existing
+// ones are updated where they differ and left alone otherwise. Applying the
+// same manifest twice changes nothing the second time.
+func (s *Seeder) Apply(ctx context.Context, m *Manifest) (*ApplyResult, error) {
+	by := m.CreatedBy
+	if by == "" {
+		by = DefaultCreatedBy
+	}
+
+	res := &ApplyResult{}
+	for _, r := range m.Roles {
+		changed, err := s.applyRole(ctx, r, by)
+		if err != nil {
+			return res, fmt.Errorf("seed role %s: %w", r.Name, err)
+		}
+		res.count(changed)
+	}
+	for _, u := range m.Users {
+		changed, err := s.applyUser(ctx, u, by)
+		if err != nil {
+			return res, fmt.Errorf("seed user %s: %w", u.Username, err)
+		}
+		res.count(changed)
+	}
+	for _, g := range m.Grants {
+		changed, err := s.applyGrant(ctx, g, by)
+		if err != nil {
+			return res, fmt.Errorf("seed grant %s to %s: %w", g.Role, g.Username, err)
+		}
+		res.count(changed)
+	}
+
+	s.log.Infof("applied seed manifest: created=%d updated=%d unchanged=%d", res.Created, res.Updated, res.Unchanged)
+	return res, nil
+}
+
+type change int
+
+const (
+	unchanged change = iota
+	created
+	updated
+)
+
+func (r *ApplyResult) count(c change) {
+	switch c {
+	case created:
+		r.Created++
+	case updated:
+		r.Updated++
+	default:
+		r.Unchanged++
+	}
+}
+
+func (s *Seeder) applyRole(ctx context.Context, in ManifestRole, by string) (change, error) {
+	role, isNew, err := s.SeedRoleIfMissing(ctx, RoleInput{
+		Name:        in.Name,
+		Description: in.Description,
+		Permissions: in.Permissions,
+		CreatedBy:   by,
+	})
+	if err != nil || isNew {
+		return created, err
+	}
+
+	permissions := in.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	if role.Description == auth.NormalizeDisplayName(in.Description) && samePermissions(role.Permissions, permissions) {
+		return unchanged, nil
+	}
+
+	role.Description = in.Description
+	role.Permissions = permissions
+	role.UpdatedBy = by
+	role.BeforeUpdate()
+
+	if err := role.Validate(); err != nil {
+		return unchanged, err
+	}
+	if err := s.roles.Update(ctx, role); err != nil {
+		return unchanged, err
+	}
+
+	s.log.Infof("updated seeded role: id=%s name=%s", role.ID, role.Name)
+	return updated, nil
+}
+
+func (s *Seeder) applyUser(ctx context.Context, in ManifestUser, by string) (change, error) {
+	user, isNew, err := s.SeedUserIfMissing(ctx, UserInput{
+		Username:  in.Username,
+		Name:      in.Name,
+		Email:     in.Email,
+		Password:  in.Password,
+		PIN:       in.PIN,
+		CreatedBy: by,
+	})
+	if err != nil || isNew {
+		return created, err
+	}
+
+	changed := false
+	if name := auth.NormalizeDisplayName(in.Name); user.Name != name {
+		user.Name = name
+		changed = true
+	}
+	lookup := crypto.ComputeLookupHash(auth.NormalizeEmail(in.Email), s.cfg.SigningKey)
+	if string(user.EmailLookup) != lookup {
+		if err := user.SetEmail(in.Email, s.cfg.EncryptionKey, s.cfg.SigningKey); err != nil {
+			return unchanged, err
+		}
+		changed = true
+	}
+	if !changed {
+		return unchanged, nil
+	}
+
+	user.UpdatedBy = by
+	user.BeforeUpdate()
+
+	if err := user.Validate(); err != nil {
+		return unchanged, err
+	}
+	if err := s.users.Update(ctx, user); err != nil {
+		return unchanged, err
+	}
+
+	s.log.Infof("updated seeded user: id=%s username=%s", user.ID, user.Username)
+	return updated, nil
+}
+
+func (s *Seeder) applyGrant(ctx context.Context, in ManifestGrant, by string) (change, error) {
+	role, err := s.roles.GetByName(ctx, auth.NormalizeRoleName(in.Role))
+	if err != nil {
+		return unchanged, err
+	}
+
+	_, isNew, err := s.EnsureGrant(ctx, GrantInput{Username: in.Username, RoleID: role.ID, AssignedBy: by})
+	if err != nil || !isNew {
+		return unchanged, err
+	}
+	return created, nil
+}
+
+// samePermissions reports whether a and b hold the same permissions,
+// ignoring order.
+func samePermissions(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
@@ -0,0 +1,174 @@
+package seed
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/log"
+)
+
+const yamlManifest = `
+created_by: bootstrap
+roles:
+  - name: admin
+    description: Administrator
+    permissions: [users:read, users:write]
+  - name: viewer
+    permissions: [users:read]
+users:
+  - username: ann
+    name: Ann
+    email: ann@example.com
+    password: Password123!
+grants:
+  - username: ann
+    role: admin
+  - username: ann
+    role: viewer
+`
+
+const jsonManifest = `{
+  "roles": [{"name": "admin", "description": "Administrator", "permissions": ["users:read"]}],
+  "users": [{"username": "ann", "name": "Ann", "email": "ann@example.com", "password": "Password123!"}],
+  "grants": [{"username": "ann", "role": "admin"}]
+}`
+
+func newTestSeeder() (*Seeder, *fake.UserStore, *fake.RoleStore, *fake.GrantStore) {
+	users := fake.NewUserStore()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+	cfg := &Config{
+		EncryptionKey: []byte("test-encryption-key-32-bytes!!!!"),
+		SigningKey:    []byte("test-signing-key-32-bytes-long!!"),
+	}
+	return New(users, roles, grants, cfg, log.NewNoopLogger()), users, roles, grants
+}
+
+func TestSeeder_ApplyFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"seed/auth.yaml": {Data: []byte(yamlManifest)},
+		"seed/auth.json": {Data: []byte(jsonManifest)},
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		want       ApplyResult
+		wantGrants int
+	}{
+		{name: "yaml", path: "seed/auth.yaml", want: ApplyResult{Created: 5}, wantGrants: 2},
+		{name: "json", path: "seed/auth.json", want: ApplyResult{Created: 3}, wantGrants: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			seeder, users, _, grants := newTestSeeder()
+
+			res, err := seeder.ApplyFile(ctx, fsys, tt.path)
+			if err != nil {
+				t.Fatalf("ApplyFile() error = %v", err)
+			}
+			if *res != tt.want {
+				t.Errorf("first ApplyFile() = %+v, want %+v", *res, tt.want)
+			}
+
+			res, err = seeder.ApplyFile(ctx, fsys, tt.path)
+			if err != nil {
+				t.Fatalf("second ApplyFile() error = %v", err)
+			}
+			if want := (ApplyResult{Unchanged: tt.want.Created}); *res != want {
+				t.Errorf("second ApplyFile() = %+v, want %+v", *res, want)
+			}
+
+			user, err := users.GetByUsername(ctx, "ann")
+			if err != nil {
+				t.Fatalf("GetByUsername() error = %v", err)
+			}
+			if !user.VerifyPassword("Password123!") {
+				t.Error("expected seeded password to verify")
+			}
+			userGrants, _ := grants.GetUserGrants(ctx, "ann")
+			if len(userGrants) != tt.wantGrants {
+				t.Errorf("got %d grants, want %d", len(userGrants), tt.wantGrants)
+			}
+		})
+	}
+}
+
+func TestSeeder_ApplyUpdates(t *testing.T) {
+	ctx := context.Background()
+	seeder, users, roles, _ := newTestSeeder()
+
+	m, err := ParseManifest("auth.yaml", []byte(yamlManifest))
+	if err != nil {
+		t.Fatalf("ParseManifest() error = %v", err)
+	}
+	if _, err := seeder.Apply(ctx, m); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	m.Roles[0].Permissions = []string{"users:write", "users:read", "users:delete"}
+	m.Roles[1].Permissions = []string{"users:read"}
+	m.Users[0].Name = "Ann Smith"
+	m.Users[0].Password = "Changed123!"
+
+	res, err := seeder.Apply(ctx, m)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if want := (ApplyResult{Updated: 2, Unchanged: 3}); *res != want {
+		t.Errorf("Apply() = %+v, want %+v", *res, want)
+	}
+
+	admin, _ := roles.GetByName(ctx, "admin")
+	got := append([]string(nil), admin.Permissions...)
+	sort.Strings(got)
+	if want := []string{"users:delete", "users:read", "users:write"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("admin permissions = %v, want %v", got, want)
+	}
+	if admin.UpdatedBy != "bootstrap" {
+		t.Errorf("admin updated_by = %q, want bootstrap", admin.UpdatedBy)
+	}
+
+	user, _ := users.GetByUsername(ctx, "ann")
+	if user.Name != "Ann Smith" {
+		t.Errorf("user name = %q, want Ann Smith", user.Name)
+	}
+	if !user.VerifyPassword("Password123!") {
+		t.Error("expected password to be left unchanged")
+	}
+}
+
+func TestSeeder_ApplyErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"seed.toml":    {Data: []byte("roles = []")},
+		"invalid.yaml": {Data: []byte("roles: [")},
+		"grant.yaml":   {Data: []byte("grants:\n  - username: ann\n    role: missing\n")},
+		"role.json":    {Data: []byte(`{"roles": [{"name": ""}]}`)},
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "missing file", path: "nope.yaml"},
+		{name: "unsupported format", path: "seed.toml"},
+		{name: "invalid yaml", path: "invalid.yaml"},
+		{name: "unknown role", path: "grant.yaml"},
+		{name: "invalid role", path: "role.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seeder, _, _, _ := newTestSeeder()
+			if _, err := seeder.ApplyFile(context.Background(), fsys, tt.path); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
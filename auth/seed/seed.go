@@ -2,15 +2,22 @@ package seed
 
 import (
 	"context"
+	"errors"
 
 	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/crypto"
 	"github.com/aquamarinepk/aqm/log"
-	"github.com/google/uuid"
 )
 
 type Config struct {
 	EncryptionKey []byte
 	SigningKey    []byte
+	// PasswordParams hashes seeded passwords; the zero value uses the
+	// defaults.
+	PasswordParams crypto.PasswordParams
+	// KeyScheme selects the key seeded users' email and PIN are encrypted
+	// with.
+	KeyScheme auth.KeyScheme
 }
 
 type Seeder struct {
@@ -71,8 +78,12 @@ type UserInput struct {
 	CreatedBy string
 }
 
+// SeedUser creates a user. An empty Password leaves the user without one,
+// as for users imported from an export.
 func (s *Seeder) SeedUser(ctx context.Context, input UserInput) (*auth.User, error) {
 	user := auth.NewUser()
+	user.EnsureID()
+	user.KeyScheme = s.cfg.KeyScheme
 	user.Username = input.Username
 	user.Name = input.Name
 	user.CreatedBy = input.CreatedBy
@@ -83,9 +94,11 @@ func (s *Seeder) SeedUser(ctx context.Context, input UserInput) (*auth.User, err
 		return nil, err
 	}
 
-	if err := user.SetPassword(input.Password); err != nil {
-		s.log.Errorf("failed to set password: username=%s error=%v", input.Username, err)
-		return nil, err
+	if input.Password != "" {
+		if err := user.SetPasswordWith(input.Password, s.cfg.PasswordParams); err != nil {
+			s.log.Errorf("failed to set password: username=%s error=%v", input.Username, err)
+			return nil, err
+		}
 	}
 
 	if input.PIN != "" {
@@ -113,7 +126,7 @@ func (s *Seeder) SeedUser(ctx context.Context, input UserInput) (*auth.User, err
 
 type GrantInput struct {
 	Username   string
-	RoleID     uuid.UUID
+	RoleID     auth.RoleID
 	AssignedBy string
 }
 
@@ -133,3 +146,84 @@ func (s *Seeder) SeedGrant(ctx context.Context, input GrantInput) (*auth.Grant,
 	s.log.Infof("seeded grant: id=%s username=%s role_id=%s", grant.ID, grant.Username, grant.RoleID)
 	return grant, nil
 }
+
+// SeedRoleIfMissing returns the role named input.Name, creating it first if
+// it does not exist. The flag reports whether it was created. An existing
+// role is returned as is, so seeding on every startup converges instead of
+// failing with auth.ErrRoleAlreadyExists.
+func (s *Seeder) SeedRoleIfMissing(ctx context.Context, input RoleInput) (*auth.Role, bool, error) {
+	name := auth.NormalizeRoleName(input.Name)
+	role, err := s.roles.GetByName(ctx, name)
+	if err == nil {
+		s.log.Debugf("skipped existing role: id=%s name=%s", role.ID, role.Name)
+		return role, false, nil
+	}
+	if !errors.Is(err, auth.ErrRoleNotFound) {
+		return nil, false, err
+	}
+
+	role, err = s.SeedRole(ctx, input)
+	if errors.Is(err, auth.ErrRoleAlreadyExists) {
+		// Created concurrently, e.g. by another replica starting up.
+		role, err = s.roles.GetByName(ctx, name)
+		return role, false, err
+	}
+	return role, err == nil, err
+}
+
+// SeedUserIfMissing returns the user with input.Username, creating it first
+// if it does not exist. The flag reports whether it was created. An existing
+// user is returned as is; its email and credentials are not touched.
+func (s *Seeder) SeedUserIfMissing(ctx context.Context, input UserInput) (*auth.User, bool, error) {
+	username := auth.NormalizeUsername(input.Username)
+	user, err := s.users.GetByUsername(ctx, username)
+	if err == nil {
+		s.log.Debugf("skipped existing user: id=%s username=%s", user.ID, user.Username)
+		return user, false, nil
+	}
+	if !errors.Is(err, auth.ErrUserNotFound) {
+		return nil, false, err
+	}
+
+	user, err = s.SeedUser(ctx, input)
+	if errors.Is(err, auth.ErrUsernameExists) {
+		user, err = s.users.GetByUsername(ctx, username)
+		return user, false, err
+	}
+	return user, err == nil, err
+}
+
+// EnsureGrant assigns the role to the user unless the user already holds
+// it. The flag reports whether a grant was created.
+func (s *Seeder) EnsureGrant(ctx context.Context, input GrantInput) (*auth.Grant, bool, error) {
+	username := auth.NormalizeUsername(input.Username)
+	grant, err := s.findGrant(ctx, username, input.RoleID)
+	if err != nil {
+		return nil, false, err
+	}
+	if grant != nil {
+		s.log.Debugf("skipped existing grant: username=%s role_id=%s", grant.Username, grant.RoleID)
+		return grant, false, nil
+	}
+
+	input.Username = username
+	grant, err = s.SeedGrant(ctx, input)
+	if errors.Is(err, auth.ErrGrantAlreadyExists) {
+		grant, err = s.findGrant(ctx, username, input.RoleID)
+		return grant, false, err
+	}
+	return grant, err == nil, err
+}
+
+func (s *Seeder) findGrant(ctx context.Context, username string, roleID auth.RoleID) (*auth.Grant, error) {
+	grants, err := s.grants.GetUserGrants(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range grants {
+		if g.RoleID == roleID {
+			return g, nil
+		}
+	}
+	return nil, nil
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/aquamarinepk/aqm/auth"
 	"github.com/aquamarinepk/aqm/auth/fake"
 	"github.com/aquamarinepk/aqm/log"
-	"github.com/google/uuid"
 )
 
 func TestSeeder_SeedRole(t *testing.T) {
@@ -39,7 +38,7 @@ func TestSeeder_SeedRole(t *testing.T) {
 				if role.CreatedBy != "system" {
 					t.Errorf("expected created_by=system, got=%s", role.CreatedBy)
 				}
-				if role.ID == uuid.Nil {
+				if role.ID.IsZero() {
 					t.Error("expected ID to be set")
 				}
 				if role.CreatedAt.IsZero() {
@@ -284,6 +283,29 @@ func TestSeeder_SeedUser(t *testing.T) {
 	}
 }
 
+func TestSeeder_SeedUserDerivedKey(t *testing.T) {
+	encKey := []byte("12345678901234567890123456789012")
+	cfg := &Config{EncryptionKey: encKey, SigningKey: encKey, KeyScheme: auth.KeySchemeDerived}
+	roleStore := fake.NewRoleStore()
+	seeder := New(fake.NewUserStore(), roleStore, fake.NewGrantStore(roleStore), cfg, log.NewNoopLogger())
+
+	user, err := seeder.SeedUser(context.Background(), UserInput{
+		Username: "derived",
+		Name:     "Derived User",
+		Email:    "derived@example.com",
+		PIN:      "1234",
+	})
+	if err != nil {
+		t.Fatalf("SeedUser() error = %v", err)
+	}
+	if user.KeyScheme != auth.KeySchemeDerived {
+		t.Errorf("KeyScheme = %v, want %v", user.KeyScheme, auth.KeySchemeDerived)
+	}
+	if email, err := user.GetEmail(encKey); err != nil || email != "derived@example.com" {
+		t.Errorf("GetEmail() = %q, %v", email, err)
+	}
+}
+
 func TestSeeder_SeedGrant(t *testing.T) {
 	encKey := make([]byte, 32)
 	sigKey := make([]byte, 32)
@@ -294,14 +316,14 @@ func TestSeeder_SeedGrant(t *testing.T) {
 
 	tests := []struct {
 		name       string
-		setupFunc  func(ctx context.Context, seeder *Seeder) (username string, roleID uuid.UUID)
+		setupFunc  func(ctx context.Context, seeder *Seeder) (username string, roleID auth.RoleID)
 		assignedBy string
 		wantErr    bool
-		checkFunc  func(t *testing.T, grant *auth.Grant, username string, roleID uuid.UUID)
+		checkFunc  func(t *testing.T, grant *auth.Grant, username string, roleID auth.RoleID)
 	}{
 		{
 			name: "valid grant",
-			setupFunc: func(ctx context.Context, seeder *Seeder) (string, uuid.UUID) {
+			setupFunc: func(ctx context.Context, seeder *Seeder) (string, auth.RoleID) {
 				user, _ := seeder.SeedUser(ctx, UserInput{
 					Username:  "testuser",
 					Name:      "Test User",
@@ -318,7 +340,7 @@ func TestSeeder_SeedGrant(t *testing.T) {
 			},
 			assignedBy: "system",
 			wantErr:    false,
-			checkFunc: func(t *testing.T, grant *auth.Grant, username string, roleID uuid.UUID) {
+			checkFunc: func(t *testing.T, grant *auth.Grant, username string, roleID auth.RoleID) {
 				if grant.Username != username {
 					t.Errorf("expected username=%s, got=%s", username, grant.Username)
 				}
@@ -328,7 +350,7 @@ func TestSeeder_SeedGrant(t *testing.T) {
 				if grant.AssignedBy != "system" {
 					t.Errorf("expected assigned_by=system, got=%s", grant.AssignedBy)
 				}
-				if grant.ID == uuid.Nil {
+				if grant.ID.IsZero() {
 					t.Error("expected ID to be set")
 				}
 				if grant.AssignedAt.IsZero() {
@@ -338,7 +360,7 @@ func TestSeeder_SeedGrant(t *testing.T) {
 		},
 		{
 			name: "empty username",
-			setupFunc: func(ctx context.Context, seeder *Seeder) (string, uuid.UUID) {
+			setupFunc: func(ctx context.Context, seeder *Seeder) (string, auth.RoleID) {
 				role, _ := seeder.SeedRole(ctx, RoleInput{
 					Name:        "admin",
 					Description: "Admin role",
@@ -351,7 +373,7 @@ func TestSeeder_SeedGrant(t *testing.T) {
 		},
 		{
 			name: "invalid role ID",
-			setupFunc: func(ctx context.Context, seeder *Seeder) (string, uuid.UUID) {
+			setupFunc: func(ctx context.Context, seeder *Seeder) (string, auth.RoleID) {
 				user, _ := seeder.SeedUser(ctx, UserInput{
 					Username:  "testuser",
 					Name:      "Test User",
@@ -359,14 +381,14 @@ func TestSeeder_SeedGrant(t *testing.T) {
 					Password:  "TestPass123!",
 					CreatedBy: "system",
 				})
-				return user.Username, uuid.Nil
+				return user.Username, auth.RoleID{}
 			},
 			assignedBy: "system",
 			wantErr:    true,
 		},
 		{
 			name: "empty assigned by",
-			setupFunc: func(ctx context.Context, seeder *Seeder) (string, uuid.UUID) {
+			setupFunc: func(ctx context.Context, seeder *Seeder) (string, auth.RoleID) {
 				user, _ := seeder.SeedUser(ctx, UserInput{
 					Username:  "testuser",
 					Name:      "Test User",
@@ -386,7 +408,7 @@ func TestSeeder_SeedGrant(t *testing.T) {
 		},
 		{
 			name: "duplicate grant",
-			setupFunc: func(ctx context.Context, seeder *Seeder) (string, uuid.UUID) {
+			setupFunc: func(ctx context.Context, seeder *Seeder) (string, auth.RoleID) {
 				user, _ := seeder.SeedUser(ctx, UserInput{
 					Username:  "testuser",
 					Name:      "Test User",
@@ -476,3 +498,74 @@ func TestSeeder_New(t *testing.T) {
 		t.Error("logger not set correctly")
 	}
 }
+
+func TestSeeder_SeedRoleIfMissing(t *testing.T) {
+	ctx := context.Background()
+	seeder, _, roles, _ := newTestSeeder()
+	input := RoleInput{Name: "admin", Permissions: []string{"users:read"}, CreatedBy: "system"}
+
+	first, created, err := seeder.SeedRoleIfMissing(ctx, input)
+	if err != nil || !created {
+		t.Fatalf("first SeedRoleIfMissing() = created %v, error %v; want created", created, err)
+	}
+
+	input.Permissions = []string{"users:write"}
+	second, created, err := seeder.SeedRoleIfMissing(ctx, input)
+	if err != nil || created {
+		t.Fatalf("second SeedRoleIfMissing() = created %v, error %v; want skipped", created, err)
+	}
+	if second.ID != first.ID {
+		t.Errorf("expected existing role %s, got %s", first.ID, second.ID)
+	}
+
+	stored, _ := roles.GetByName(ctx, "admin")
+	if len(stored.Permissions) != 1 || stored.Permissions[0] != "users:read" {
+		t.Errorf("expected existing role to be left as is, got permissions %v", stored.Permissions)
+	}
+}
+
+func TestSeeder_SeedUserIfMissing(t *testing.T) {
+	ctx := context.Background()
+	seeder, _, _, _ := newTestSeeder()
+	input := UserInput{Username: "ann", Name: "Ann", Email: "ann@example.com", Password: "Password123!", CreatedBy: "system"}
+
+	first, created, err := seeder.SeedUserIfMissing(ctx, input)
+	if err != nil || !created {
+		t.Fatalf("first SeedUserIfMissing() = created %v, error %v; want created", created, err)
+	}
+
+	input.Password = "Changed123!"
+	second, created, err := seeder.SeedUserIfMissing(ctx, input)
+	if err != nil || created {
+		t.Fatalf("second SeedUserIfMissing() = created %v, error %v; want skipped", created, err)
+	}
+	if second.ID != first.ID || !second.VerifyPassword("Password123!") {
+		t.Error("expected existing user to be returned unchanged")
+	}
+
+	if _, _, err := seeder.SeedUserIfMissing(ctx, UserInput{Username: "bob", Email: "invalid"}); err == nil {
+		t.Error("expected validation error for new user")
+	}
+}
+
+func TestSeeder_EnsureGrant(t *testing.T) {
+	ctx := context.Background()
+	seeder, _, _, grants := newTestSeeder()
+	role, _, err := seeder.SeedRoleIfMissing(ctx, RoleInput{Name: "admin", CreatedBy: "system"})
+	if err != nil {
+		t.Fatalf("SeedRoleIfMissing() error = %v", err)
+	}
+	input := GrantInput{Username: "ann", RoleID: role.ID, AssignedBy: "system"}
+
+	if _, created, err := seeder.EnsureGrant(ctx, input); err != nil || !created {
+		t.Fatalf("first EnsureGrant() = created %v, error %v; want created", created, err)
+	}
+	if _, created, err := seeder.EnsureGrant(ctx, input); err != nil || created {
+		t.Fatalf("second EnsureGrant() = created %v, error %v; want skipped", created, err)
+	}
+
+	userGrants, _ := grants.GetUserGrants(ctx, "ann")
+	if len(userGrants) != 1 {
+		t.Errorf("expected 1 grant, got %d", len(userGrants))
+	}
+}
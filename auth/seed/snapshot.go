@@ -0,0 +1,243 @@
+package seed
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/crypto"
+	"go.yaml.in/yaml/v3"
+)
+
+// Formats accepted by EncodeManifest and DecodeManifest.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Kinds of entries and actions reported in a Diff.
+const (
+	KindRole  = "role"
+	KindUser  = "user"
+	KindGrant = "grant"
+
+	ActionCreate = "create"
+	ActionUpdate = "update"
+)
+
+// Change is one entry Apply would create or update. Fields lists what an
+// update changes. Grants are named by role, with the grantee in Username.
+type Change struct {
+	Kind     string   `json:"kind" yaml:"kind"`
+	Action   string   `json:"action" yaml:"action"`
+	Name     string   `json:"name" yaml:"name"`
+	Username string   `json:"username,omitempty" yaml:"username,omitempty"`
+	Fields   []string `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// Diff lists what applying a manifest would change, in the order Apply
+// makes the changes.
+type Diff struct {
+	Changes   []Change `json:"changes" yaml:"changes"`
+	Unchanged int      `json:"unchanged" yaml:"unchanged"`
+}
+
+// EncodeManifest encodes m as FormatJSON or FormatYAML.
+func EncodeManifest(m *Manifest, format string) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return yaml.Marshal(m)
+	case FormatJSON:
+		return json.MarshalIndent(m, "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported seed manifest format %q", format)
+	}
+}
+
+// DecodeManifest decodes a manifest in FormatJSON or FormatYAML.
+func DecodeManifest(data []byte, format string) (*Manifest, error) {
+	var m Manifest
+	var err error
+	switch format {
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &m)
+	case FormatJSON:
+		err = json.Unmarshal(data, &m)
+	default:
+		return nil, fmt.Errorf("unsupported seed manifest format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse seed manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// Export returns the current roles and grants as a manifest that Apply can
+// load into another environment, sorted so that exports of the same data
+// compare equal. With users set the users are included, with their emails
+// decrypted; credentials are never exported, so users created from the
+// manifest have no password until one is reset.
+func (s *Seeder) Export(ctx context.Context, users bool) (*Manifest, error) {
+	roles, err := s.roles.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list roles: %w", err)
+	}
+
+	m := &Manifest{
+		Roles:  []ManifestRole{},
+		Grants: []ManifestGrant{},
+	}
+	for _, role := range roles {
+		m.Roles = append(m.Roles, ManifestRole{
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: slices.Sorted(slices.Values(role.Permissions)),
+		})
+
+		grants, err := s.grants.GetRoleGrants(ctx, role.ID)
+		if err != nil {
+			return nil, fmt.Errorf("list grants of role %s: %w", role.Name, err)
+		}
+		for _, g := range grants {
+			m.Grants = append(m.Grants, ManifestGrant{Username: g.Username, Role: role.Name})
+		}
+	}
+
+	if users {
+		if s.users == nil {
+			return nil, errors.New("user store is required to export users")
+		}
+		all, err := s.users.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list users: %w", err)
+		}
+		m.Users = make([]ManifestUser, 0, len(all))
+		for _, u := range all {
+			email, err := u.GetEmail(s.cfg.EncryptionKey)
+			if err != nil {
+				return nil, fmt.Errorf("export user %s: %w", u.Username, err)
+			}
+			m.Users = append(m.Users, ManifestUser{Username: u.Username, Name: u.Name, Email: email})
+		}
+		slices.SortFunc(m.Users, func(a, b ManifestUser) int { return cmp.Compare(a.Username, b.Username) })
+	}
+
+	slices.SortFunc(m.Roles, func(a, b ManifestRole) int { return cmp.Compare(a.Name, b.Name) })
+	slices.SortFunc(m.Grants, func(a, b ManifestGrant) int {
+		return cmp.Or(cmp.Compare(a.Role, b.Role), cmp.Compare(a.Username, b.Username))
+	})
+	return m, nil
+}
+
+// Diff reports what Apply would change without changing anything. Like
+// Apply, it never deletes: entries missing from the manifest are kept.
+func (s *Seeder) Diff(ctx context.Context, m *Manifest) (*Diff, error) {
+	d := &Diff{Changes: []Change{}}
+	declared := make(map[string]bool, len(m.Roles))
+
+	for _, in := range m.Roles {
+		name := auth.NormalizeRoleName(in.Name)
+		if declared[name] {
+			continue
+		}
+		declared[name] = true
+
+		role, err := s.roles.GetByName(ctx, name)
+		if errors.Is(err, auth.ErrRoleNotFound) {
+			d.add(Change{Kind: KindRole, Action: ActionCreate, Name: name})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("diff role %s: %w", in.Name, err)
+		}
+
+		var fields []string
+		if role.Description != auth.NormalizeDisplayName(in.Description) {
+			fields = append(fields, "description")
+		}
+		if !samePermissions(role.Permissions, nonNil(in.Permissions)) {
+			fields = append(fields, "permissions")
+		}
+		d.update(KindRole, name, fields)
+	}
+
+	seenUsers := make(map[string]bool, len(m.Users))
+	for _, in := range m.Users {
+		username := auth.NormalizeUsername(in.Username)
+		if seenUsers[username] {
+			continue
+		}
+		seenUsers[username] = true
+
+		user, err := s.users.GetByUsername(ctx, username)
+		if errors.Is(err, auth.ErrUserNotFound) {
+			d.add(Change{Kind: KindUser, Action: ActionCreate, Name: username})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("diff user %s: %w", in.Username, err)
+		}
+
+		var fields []string
+		if user.Name != auth.NormalizeDisplayName(in.Name) {
+			fields = append(fields, "name")
+		}
+		if string(user.EmailLookup) != crypto.ComputeLookupHash(auth.NormalizeEmail(in.Email), s.cfg.SigningKey) {
+			fields = append(fields, "email")
+		}
+		d.update(KindUser, username, fields)
+	}
+
+	seenGrants := make(map[ManifestGrant]bool, len(m.Grants))
+	for _, in := range m.Grants {
+		g := ManifestGrant{Username: auth.NormalizeUsername(in.Username), Role: auth.NormalizeRoleName(in.Role)}
+		if seenGrants[g] {
+			continue
+		}
+		seenGrants[g] = true
+
+		role, err := s.roles.GetByName(ctx, g.Role)
+		if errors.Is(err, auth.ErrRoleNotFound) && declared[g.Role] {
+			d.add(Change{Kind: KindGrant, Action: ActionCreate, Name: g.Role, Username: g.Username})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("diff grant %s to %s: %w", in.Role, in.Username, err)
+		}
+
+		grant, err := s.findGrant(ctx, g.Username, role.ID)
+		if err != nil {
+			return nil, fmt.Errorf("diff grant %s to %s: %w", in.Role, in.Username, err)
+		}
+		if grant == nil {
+			d.add(Change{Kind: KindGrant, Action: ActionCreate, Name: g.Role, Username: g.Username})
+			continue
+		}
+		d.Unchanged++
+	}
+
+	return d, nil
+}
+
+func (d *Diff) add(c Change) {
+	d.Changes = append(d.Changes, c)
+}
+
+func (d *Diff) update(kind, name string, fields []string) {
+	if len(fields) == 0 {
+		d.Unchanged++
+		return
+	}
+	d.add(Change{Kind: kind, Action: ActionUpdate, Name: name, Fields: fields})
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
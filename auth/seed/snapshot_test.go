@@ -0,0 +1,135 @@
+package seed
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSeeder_ExportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source, _, _, _ := newTestSeeder()
+
+	m, err := ParseManifest("auth.yaml", []byte(yamlManifest))
+	if err != nil {
+		t.Fatalf("ParseManifest() error = %v", err)
+	}
+	if _, err := source.Apply(ctx, m); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	exported, err := source.Export(ctx, true)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	wantGrants := []ManifestGrant{{Username: "ann", Role: "admin"}, {Username: "ann", Role: "viewer"}}
+	if !reflect.DeepEqual(exported.Grants, wantGrants) {
+		t.Errorf("exported grants = %+v, want %+v", exported.Grants, wantGrants)
+	}
+	if len(exported.Roles) != 2 || exported.Roles[0].Name != "admin" {
+		t.Errorf("exported roles = %+v", exported.Roles)
+	}
+	if len(exported.Users) != 1 || exported.Users[0].Email != "ann@example.com" || exported.Users[0].Password != "" {
+		t.Errorf("exported users = %+v, want ann without credentials", exported.Users)
+	}
+
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			data, err := EncodeManifest(exported, format)
+			if err != nil {
+				t.Fatalf("EncodeManifest() error = %v", err)
+			}
+			decoded, err := DecodeManifest(data, format)
+			if err != nil {
+				t.Fatalf("DecodeManifest() error = %v", err)
+			}
+
+			target, users, _, _ := newTestSeeder()
+			diff, err := target.Diff(ctx, decoded)
+			if err != nil {
+				t.Fatalf("Diff() error = %v", err)
+			}
+			if len(diff.Changes) != 5 || diff.Unchanged != 0 {
+				t.Errorf("Diff() = %+v, want 5 creates", diff)
+			}
+			if _, err := users.GetByUsername(ctx, "ann"); err == nil {
+				t.Error("Diff() should not create users")
+			}
+
+			res, err := target.Apply(ctx, decoded)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if *res != (ApplyResult{Created: 5}) {
+				t.Errorf("Apply() = %+v, want 5 created", *res)
+			}
+
+			again, err := target.Export(ctx, true)
+			if err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+			if !reflect.DeepEqual(again, exported) {
+				t.Errorf("re-export = %+v, want %+v", again, exported)
+			}
+		})
+	}
+}
+
+func TestSeeder_Diff(t *testing.T) {
+	ctx := context.Background()
+	seeder, _, _, _ := newTestSeeder()
+
+	m, _ := ParseManifest("auth.yaml", []byte(yamlManifest))
+	if _, err := seeder.Apply(ctx, m); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	m.Roles[0].Permissions = []string{"users:read"}
+	m.Users[0].Email = "ann@example.org"
+	m.Grants = append(m.Grants, ManifestGrant{Username: "sa:worker", Role: "viewer"}, ManifestGrant{Username: "ann", Role: "admin"})
+
+	diff, err := seeder.Diff(ctx, m)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	want := []Change{
+		{Kind: KindRole, Action: ActionUpdate, Name: "admin", Fields: []string{"permissions"}},
+		{Kind: KindUser, Action: ActionUpdate, Name: "ann", Fields: []string{"email"}},
+		{Kind: KindGrant, Action: ActionCreate, Name: "viewer", Username: "sa:worker"},
+	}
+	if !reflect.DeepEqual(diff.Changes, want) {
+		t.Errorf("Diff() changes = %+v, want %+v", diff.Changes, want)
+	}
+	if diff.Unchanged != 3 {
+		t.Errorf("Diff() unchanged = %d, want 3", diff.Unchanged)
+	}
+
+	m.Grants = append(m.Grants, ManifestGrant{Username: "ann", Role: "missing"})
+	if _, err := seeder.Diff(ctx, m); err == nil {
+		t.Error("Diff() with an unknown role should fail")
+	}
+}
+
+func TestExportWithoutUsers(t *testing.T) {
+	ctx := context.Background()
+	seeder, _, _, _ := newTestSeeder()
+	m, _ := ParseManifest("auth.json", []byte(jsonManifest))
+	seeder.Apply(ctx, m)
+
+	exported, err := seeder.Export(ctx, false)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if exported.Users != nil {
+		t.Errorf("Export(false) users = %+v, want none", exported.Users)
+	}
+
+	if _, err := EncodeManifest(exported, "toml"); err == nil {
+		t.Error("EncodeManifest() with an unknown format should fail")
+	}
+	if _, err := DecodeManifest([]byte("{"), FormatJSON); err == nil {
+		t.Error("DecodeManifest() with invalid JSON should fail")
+	}
+}
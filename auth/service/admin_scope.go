@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// AdminScope is an administrator changing grants with the permissions of
+// the roles they hold. Carried in the context with WithAdminScope, it
+// limits AssignRole, AssignRoles, RevokeRole and ApplyGrantPlan to the roles
+// the administrator manages (see auth.PermissionManageGrants).
+// When the administrator uses a scoped token, a role must also be managed
+// by TokenScope.
+type AdminScope struct {
+	Admin       string
+	Permissions []string
+	TokenScope  []string
+	roles       auth.RoleStore
+}
+
+// NewAdminScope returns the scope of admin, resolving the roles being
+// assigned and revoked through roles. A non-nil tokenScope, the scope of
+// the token admin called with, narrows it further. It fails with
+// auth.ErrPermissionDenied when admin, or tokenScope, manages the grants of
+// no role at all.
+func NewAdminScope(ctx context.Context, roles auth.RoleStore, grants auth.GrantStore, admin string, tokenScope []string) (*AdminScope, error) {
+	if roles == nil {
+		return nil, fmt.Errorf("role store is required")
+	}
+	if grants == nil {
+		return nil, fmt.Errorf("grant store is required")
+	}
+	if admin == "" {
+		return nil, auth.ErrPermissionDenied
+	}
+
+	held, err := grants.GetUserRoles(ctx, admin)
+	if err != nil {
+		return nil, fmt.Errorf("get admin roles: %w", err)
+	}
+	var permissions []string
+	for _, role := range held {
+		if role.Status == auth.RoleStatusActive {
+			permissions = append(permissions, role.GrantedPermissions()...)
+		}
+	}
+	if !auth.ManagesAnyRole(permissions) {
+		return nil, auth.ErrPermissionDenied
+	}
+	if tokenScope != nil && !auth.ManagesAnyRole(tokenScope) {
+		return nil, auth.ErrPermissionDenied
+	}
+
+	return &AdminScope{Admin: admin, Permissions: permissions, TokenScope: tokenScope, roles: roles}, nil
+}
+
+// manages reports whether the administrator manages the grants of the role
+// called name.
+func (s *AdminScope) manages(name string) bool {
+	if !auth.ManagesRole(s.Permissions, name) {
+		return false
+	}
+	return s.TokenScope == nil || auth.ManagesRole(s.TokenScope, name)
+}
+
+// Check returns auth.ErrRoleOutOfScope unless the administrator manages
+// the role identified by roleID.
+func (s *AdminScope) Check(ctx context.Context, roleID auth.RoleID) error {
+	role, err := s.roles.Get(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	if !s.manages(role.Name) {
+		return fmt.Errorf("%w: %s", auth.ErrRoleOutOfScope, role.Name)
+	}
+	return nil
+}
+
+type adminScopeKey struct{}
+
+// WithAdminScope returns a copy of ctx carrying scope.
+func WithAdminScope(ctx context.Context, scope *AdminScope) context.Context {
+	return context.WithValue(ctx, adminScopeKey{}, scope)
+}
+
+// GetAdminScope returns the scope carried by ctx, or nil.
+func GetAdminScope(ctx context.Context) *AdminScope {
+	scope, _ := ctx.Value(adminScopeKey{}).(*AdminScope)
+	return scope
+}
+
+// checkAdminScope checks roleID against the scope carried by ctx, if any.
+// A role that does not exist is reported out of scope, as the scope cannot
+// cover it.
+func checkAdminScope(ctx context.Context, roleID auth.RoleID) error {
+	scope := GetAdminScope(ctx)
+	if scope == nil {
+		return nil
+	}
+	err := scope.Check(ctx, roleID)
+	if errors.Is(err, auth.ErrRoleNotFound) {
+		return fmt.Errorf("%w: unknown role %s", auth.ErrRoleOutOfScope, roleID)
+	}
+	return err
+}
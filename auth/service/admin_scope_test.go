@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestAdminScope(t *testing.T) {
+	ctx := context.Background()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+
+	newRole := func(name string, permissions ...string) *auth.Role {
+		t.Helper()
+		role, err := CreateRole(ctx, roles, name, "", permissions, "system")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return role
+	}
+	acmeAdmin := newRole("acme-admin", "grants:manage:acme-*", "grants:manage:support")
+	acmeEditor := newRole("acme-editor", "content:write")
+	support := newRole("support", "tickets:read")
+	globex := newRole("globex-editor", "content:write")
+	for _, username := range []string{"alice", "reader"} {
+		if _, err := AssignRole(ctx, grants, username, acmeEditor.ID, "system"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := AssignRole(ctx, grants, "alice", acmeAdmin.ID, "system"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewAdminScope(ctx, roles, grants, "reader", nil); !errors.Is(err, auth.ErrPermissionDenied) {
+		t.Errorf("NewAdminScope(non-admin) error = %v, want ErrPermissionDenied", err)
+	}
+	scope, err := NewAdminScope(ctx, roles, grants, "alice", nil)
+	if err != nil {
+		t.Fatalf("NewAdminScope() error = %v", err)
+	}
+	scoped := WithAdminScope(ctx, scope)
+
+	if _, err := AssignRole(scoped, grants, "bob", support.ID, "alice"); err != nil {
+		t.Errorf("AssignRole(in scope) error = %v", err)
+	}
+	if _, err := AssignRole(scoped, grants, "bob", globex.ID, "alice"); !errors.Is(err, auth.ErrRoleOutOfScope) {
+		t.Errorf("AssignRole(out of scope) error = %v, want ErrRoleOutOfScope", err)
+	}
+	if _, err := AssignRole(scoped, grants, "bob", auth.NewRoleID(), "alice"); !errors.Is(err, auth.ErrRoleOutOfScope) {
+		t.Errorf("AssignRole(unknown role) error = %v, want ErrRoleOutOfScope", err)
+	}
+	if _, err := AssignRoles(scoped, fake.NewTransactor(), grants, "carol", []auth.RoleID{acmeEditor.ID, globex.ID}, "alice"); !errors.Is(err, auth.ErrRoleOutOfScope) {
+		t.Errorf("AssignRoles(partly out of scope) error = %v, want ErrRoleOutOfScope", err)
+	}
+
+	if _, err := AssignRole(ctx, grants, "bob", globex.ID, "system"); err != nil {
+		t.Fatalf("AssignRole() without a scope error = %v", err)
+	}
+	if err := RevokeRole(scoped, grants, "bob", globex.ID); !errors.Is(err, auth.ErrRoleOutOfScope) {
+		t.Errorf("RevokeRole(out of scope) error = %v, want ErrRoleOutOfScope", err)
+	}
+	if err := RevokeRole(scoped, grants, "reader", acmeEditor.ID); err != nil {
+		t.Errorf("RevokeRole(in scope) error = %v", err)
+	}
+
+	plan := &auth.GrantPlan{
+		Assign: []auth.GrantPair{{Username: "dan", RoleID: acmeEditor.ID}, {Username: "dan", RoleID: globex.ID}},
+	}
+	var result auth.GrantBatchResult
+	err = ApplyGrantPlan(scoped, grants, plan, "alice", 0, func(b auth.GrantBatchResult) error {
+		result = b
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ApplyGrantPlan() error = %v", err)
+	}
+	if len(result.Assigned) != 1 || len(result.Failed) != 1 || result.Failed[0].RoleID != globex.ID {
+		t.Errorf("ApplyGrantPlan() result = %+v", result)
+	}
+}
+
+func TestAdminScopeWithTokenScope(t *testing.T) {
+	ctx := context.Background()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+
+	admin, err := CreateRole(ctx, roles, "admin", "", []string{"*"}, "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	acme, err := CreateRole(ctx, roles, "acme-editor", "", []string{"content:write"}, "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	globex, err := CreateRole(ctx, roles, "globex-editor", "", []string{"content:write"}, "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AssignRole(ctx, grants, "alice", admin.ID, "system"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewAdminScope(ctx, roles, grants, "alice", []string{"content:read"}); !errors.Is(err, auth.ErrPermissionDenied) {
+		t.Errorf("NewAdminScope(scope without grants) error = %v, want ErrPermissionDenied", err)
+	}
+
+	scope, err := NewAdminScope(ctx, roles, grants, "alice", []string{"grants:manage:acme-*"})
+	if err != nil {
+		t.Fatalf("NewAdminScope() error = %v", err)
+	}
+	scoped := WithAdminScope(ctx, scope)
+
+	if _, err := AssignRole(scoped, grants, "bob", acme.ID, "alice"); err != nil {
+		t.Errorf("AssignRole(in token scope) error = %v", err)
+	}
+	if _, err := AssignRole(scoped, grants, "bob", globex.ID, "alice"); !errors.Is(err, auth.ErrRoleOutOfScope) {
+		t.Errorf("AssignRole(outside token scope) error = %v, want ErrRoleOutOfScope", err)
+	}
+}
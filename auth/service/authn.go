@@ -3,9 +3,9 @@ package service
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/aquamarinepk/aqm/auth"
-	"github.com/google/uuid"
 )
 
 const SuperadminEmail = "superadmin@system.local"
@@ -59,7 +59,7 @@ func SignUp(ctx context.Context, store auth.UserStore, crypto CryptoService, ema
 	}
 
 	// Create user
-	user := auth.NewUser()
+	user := newUser(crypto)
 	user.Username = username
 	user.Name = displayName
 	user.Status = auth.UserStatusActive
@@ -68,7 +68,7 @@ func SignUp(ctx context.Context, store auth.UserStore, crypto CryptoService, ema
 		return nil, fmt.Errorf("encrypt email: %w", err)
 	}
 
-	if err := user.SetPassword(password); err != nil {
+	if err := user.SetPasswordWith(password, crypto.PasswordParams()); err != nil {
 		return nil, fmt.Errorf("hash password: %w", err)
 	}
 
@@ -81,6 +81,48 @@ func SignUp(ctx context.Context, store auth.UserStore, crypto CryptoService, ema
 	return user, nil
 }
 
+// SignUpAssigner is recorded as AssignedBy on the grants SignUpWithRoles
+// creates.
+const SignUpAssigner = "signup"
+
+// SignUpWithRoles signs up a user like SignUp and assigns them the named
+// roles as one unit of work run by tx: when a role is missing or cannot be
+// granted, the user is not created either. A nil tx runs the steps without
+// a transaction.
+func SignUpWithRoles(ctx context.Context, tx auth.Transactor, users auth.UserStore, roles auth.RoleStore, grants auth.GrantStore, crypto CryptoService, roleNames []string, email, password, username, displayName string) (*auth.User, error) {
+	if roles == nil {
+		return nil, fmt.Errorf("role store is required")
+	}
+	if grants == nil {
+		return nil, fmt.Errorf("grant store is required")
+	}
+
+	var user *auth.User
+	err := withinTx(ctx, tx, func(ctx context.Context) error {
+		var err error
+		user, err = SignUp(ctx, users, crypto, email, password, username, displayName)
+		if err != nil {
+			return err
+		}
+
+		roleIDs := make([]auth.RoleID, 0, len(roleNames))
+		for _, name := range roleNames {
+			role, err := GetRoleByName(ctx, roles, name)
+			if err != nil {
+				return fmt.Errorf("get role %s: %w", name, err)
+			}
+			roleIDs = append(roleIDs, role.ID)
+		}
+
+		_, err = AssignRoles(ctx, tx, grants, user.Username, roleIDs, SignUpAssigner)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 // SignIn authenticates a user with email and password
 func SignIn(ctx context.Context, store auth.UserStore, crypto CryptoService, tokenGen TokenGenerator, email, password string) (*auth.User, string, error) {
 	if store == nil {
@@ -112,6 +154,9 @@ func SignIn(ctx context.Context, store auth.UserStore, crypto CryptoService, tok
 		return nil, "", auth.ErrInactiveAccount
 	}
 
+	rehashPassword(ctx, store, crypto, user, password)
+	upgradeKeyScheme(ctx, store, crypto, user)
+
 	token, err := tokenGen.GenerateToken(user.ID)
 	if err != nil {
 		return nil, "", fmt.Errorf("generate token: %w", err)
@@ -151,10 +196,13 @@ func SignInByPIN(ctx context.Context, store auth.UserStore, crypto CryptoService
 		return nil, auth.ErrInactiveAccount
 	}
 
+	upgradeKeyScheme(ctx, store, crypto, user)
+
 	return user, nil
 }
 
-// Bootstrap creates the initial superadmin user with a one-time password
+// Bootstrap creates the initial superadmin user with a one-time password.
+// It returns auth.ErrAlreadyBootstrapped once the superadmin exists.
 func Bootstrap(ctx context.Context, store auth.UserStore, crypto CryptoService, pwdGen PasswordGenerator) (*auth.User, string, error) {
 	if store == nil {
 		return nil, "", fmt.Errorf("user store is required")
@@ -174,11 +222,11 @@ func Bootstrap(ctx context.Context, store auth.UserStore, crypto CryptoService,
 		return nil, "", fmt.Errorf("lookup superadmin: %w", err)
 	}
 	if existing != nil {
-		return existing, "", nil
+		return nil, "", auth.ErrAlreadyBootstrapped
 	}
 
 	// Create superadmin
-	user := auth.NewUser()
+	user := newUser(crypto)
 	user.Username = "superadmin"
 	user.Name = "Super Administrator"
 	user.Status = auth.UserStatusActive
@@ -188,7 +236,7 @@ func Bootstrap(ctx context.Context, store auth.UserStore, crypto CryptoService,
 	}
 
 	password := pwdGen.GeneratePassword()
-	if err := user.SetPassword(password); err != nil {
+	if err := user.SetPasswordWith(password, crypto.PasswordParams()); err != nil {
 		return nil, "", fmt.Errorf("hash password: %w", err)
 	}
 
@@ -248,13 +296,65 @@ func GeneratePIN(ctx context.Context, store auth.UserStore, crypto CryptoService
 }
 
 // GetUserByID retrieves a user by their ID
-func GetUserByID(ctx context.Context, store auth.UserStore, id uuid.UUID) (*auth.User, error) {
+func GetUserByID(ctx context.Context, store auth.UserStore, id auth.UserID) (*auth.User, error) {
 	if store == nil {
 		return nil, fmt.Errorf("user store is required")
 	}
 	return store.Get(ctx, id)
 }
 
+// impersonationProtected are the permissions that make a user off limits
+// to impersonation: "*", held by superadmins, and auth.PermissionImpersonate,
+// so an impersonator never acts with the rights of another one.
+var impersonationProtected = []string{"*", auth.PermissionImpersonate}
+
+// Impersonate issues a token for actor acting as the user with id, valid for
+// ttl. The caller is responsible for checking that actor holds
+// auth.PermissionImpersonate. Actors cannot impersonate themselves, only
+// active users can be impersonated, and users that checker reports holding
+// "*" or auth.PermissionImpersonate cannot be impersonated at all.
+func Impersonate(ctx context.Context, store auth.UserStore, tokenGen ImpersonationTokenGenerator, checker PermissionChecker, actor string, id auth.UserID, ttl time.Duration) (*auth.User, string, error) {
+	if store == nil {
+		return nil, "", fmt.Errorf("user store is required")
+	}
+	if tokenGen == nil {
+		return nil, "", fmt.Errorf("token generator is required")
+	}
+	if checker == nil {
+		return nil, "", fmt.Errorf("permission checker is required")
+	}
+	if actor == "" || actor == id.String() {
+		return nil, "", auth.ErrImpersonationNotAllowed
+	}
+
+	user, err := store.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if user.Status != auth.UserStatusActive {
+		return nil, "", auth.ErrInactiveAccount
+	}
+	if user.Username == actor {
+		return nil, "", auth.ErrImpersonationNotAllowed
+	}
+	for _, permission := range impersonationProtected {
+		held, err := checker.CheckPermission(ctx, user.Username, permission)
+		if err != nil {
+			return nil, "", fmt.Errorf("check target permissions: %w", err)
+		}
+		if held {
+			return nil, "", auth.ErrImpersonationNotAllowed
+		}
+	}
+
+	token, err := tokenGen.GenerateImpersonationToken(user.ID, actor, ttl)
+	if err != nil {
+		return nil, "", fmt.Errorf("generate token: %w", err)
+	}
+
+	return user, token, nil
+}
+
 // GetUserByUsername retrieves a user by their username
 func GetUserByUsername(ctx context.Context, store auth.UserStore, username string) (*auth.User, error) {
 	if store == nil {
@@ -280,6 +380,14 @@ func ListUsersByStatus(ctx context.Context, store auth.UserStore, status auth.Us
 	return store.ListByStatus(ctx, status)
 }
 
+// SearchUsers returns the page of users matching q
+func SearchUsers(ctx context.Context, store auth.UserStore, q auth.UserQuery) (*auth.UserPage, error) {
+	if store == nil {
+		return nil, fmt.Errorf("user store is required")
+	}
+	return store.Search(ctx, q)
+}
+
 // UpdateUser updates a user's information
 func UpdateUser(ctx context.Context, store auth.UserStore, user *auth.User) error {
 	if store == nil {
@@ -292,10 +400,185 @@ func UpdateUser(ctx context.Context, store auth.UserStore, user *auth.User) erro
 	return store.Update(ctx, user)
 }
 
+// UpdateUserAttributes applies patch to the user's attributes as a JSON
+// Merge Patch (null removes a key) and saves the user. The user is left
+// unchanged when the result fails auth.ValidateAttributes.
+func UpdateUserAttributes(ctx context.Context, store auth.UserStore, user *auth.User, patch map[string]any) error {
+	if store == nil {
+		return fmt.Errorf("user store is required")
+	}
+	if user == nil {
+		return fmt.Errorf("user is required")
+	}
+
+	attrs := user.Attributes.Clone()
+	if attrs == nil {
+		attrs = auth.Attributes{}
+	}
+	attrs.Merge(patch)
+	if err := auth.ValidateAttributes(attrs); err != nil {
+		return err
+	}
+	if len(attrs) == 0 {
+		attrs = nil
+	}
+
+	user.Attributes = attrs
+	return UpdateUser(ctx, store, user)
+}
+
 // DeleteUser soft-deletes a user
-func DeleteUser(ctx context.Context, store auth.UserStore, id uuid.UUID) error {
+func DeleteUser(ctx context.Context, store auth.UserStore, id auth.UserID) error {
 	if store == nil {
 		return fmt.Errorf("user store is required")
 	}
 	return store.Delete(ctx, id)
 }
+
+// ResetPassword sets a new password for a user. When password is empty a
+// random one is generated. It returns the password that was set.
+func ResetPassword(ctx context.Context, store auth.UserStore, crypto CryptoService, pwdGen PasswordGenerator, id auth.UserID, password string) (string, error) {
+	if store == nil {
+		return "", fmt.Errorf("user store is required")
+	}
+	if crypto == nil {
+		return "", fmt.Errorf("crypto service is required")
+	}
+	if password == "" {
+		if pwdGen == nil {
+			return "", fmt.Errorf("password generator is required")
+		}
+		password = pwdGen.GeneratePassword()
+	} else if err := auth.ValidatePassword(password); err != nil {
+		return "", err
+	}
+
+	user, err := store.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	if err := user.SetPasswordWith(password, crypto.PasswordParams()); err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+	user.BeforeUpdate()
+
+	if err := store.Update(ctx, user); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// ChangePassword replaces the password of a user who proved they know the
+// current one. It returns ErrIncorrectPassword when current does not verify
+// and ErrInvalidPassword when password fails the password policy or equals
+// current.
+func ChangePassword(ctx context.Context, store auth.UserStore, crypto CryptoService, id auth.UserID, current, password string) (*auth.User, error) {
+	if store == nil {
+		return nil, fmt.Errorf("user store is required")
+	}
+	if crypto == nil {
+		return nil, fmt.Errorf("crypto service is required")
+	}
+
+	user, err := store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.VerifyPassword(current) {
+		return nil, auth.ErrIncorrectPassword
+	}
+	if password == current {
+		return nil, fmt.Errorf("%w: new password must differ from the current one", auth.ErrInvalidPassword)
+	}
+
+	if err := user.SetPasswordWith(password, crypto.PasswordParams()); err != nil {
+		return nil, err
+	}
+	user.BeforeUpdate()
+
+	if err := store.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// newUser returns a user whose data is encrypted under the key scheme of
+// crypto. Derived keys are bound to the user ID, so it is assigned here.
+func newUser(crypto CryptoService) *auth.User {
+	user := auth.NewUser()
+	user.EnsureID()
+	user.KeyScheme = crypto.KeyScheme()
+	return user
+}
+
+// upgradeKeyScheme moves a user that just signed in to derived keys when
+// crypto uses them. Like rehashPassword it ignores failures; the data still
+// decrypts under the old scheme.
+func upgradeKeyScheme(ctx context.Context, store auth.UserStore, crypto CryptoService, user *auth.User) {
+	if crypto.KeyScheme() != auth.KeySchemeDerived || user.KeyScheme == auth.KeySchemeDerived {
+		return
+	}
+	upgraded := *user
+	if err := upgraded.UseDerivedKey(crypto.EncryptionKey()); err != nil {
+		return
+	}
+	upgraded.BeforeUpdate()
+	if err := store.Update(ctx, &upgraded); err != nil {
+		return
+	}
+	*user = upgraded
+}
+
+// MigrateUserKeys re-encrypts the email and PIN of every user still on the
+// master key scheme with their derived key, for when crypto uses derived
+// keys. It returns how many users were migrated. Users changed concurrently
+// fail with auth.ErrVersionConflict; running it again picks them up, as
+// users already migrated are skipped.
+func MigrateUserKeys(ctx context.Context, store auth.UserStore, crypto CryptoService) (int, error) {
+	if store == nil {
+		return 0, fmt.Errorf("user store is required")
+	}
+	if crypto == nil {
+		return 0, fmt.Errorf("crypto service is required")
+	}
+	if crypto.KeyScheme() != auth.KeySchemeDerived {
+		return 0, nil
+	}
+
+	users, err := store.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list users: %w", err)
+	}
+
+	migrated := 0
+	for _, user := range users {
+		if user.KeyScheme == auth.KeySchemeDerived {
+			continue
+		}
+		if err := user.UseDerivedKey(crypto.EncryptionKey()); err != nil {
+			return migrated, fmt.Errorf("re-encrypt user %s: %w", user.ID, err)
+		}
+		user.BeforeUpdate()
+		if err := store.Update(ctx, user); err != nil {
+			return migrated, fmt.Errorf("update user %s: %w", user.ID, err)
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+// rehashPassword upgrades the stored hash of a password that just verified
+// when the configured parameters changed since it was set. Failures are
+// ignored: the old hash still works and the next sign-in tries again.
+func rehashPassword(ctx context.Context, store auth.UserStore, crypto CryptoService, user *auth.User, password string) {
+	params := crypto.PasswordParams()
+	if !user.PasswordNeedsRehash(params) {
+		return
+	}
+	if err := user.RehashPassword(password, params); err != nil {
+		return
+	}
+	user.BeforeUpdate()
+	store.Update(ctx, user)
+}
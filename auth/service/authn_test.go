@@ -2,11 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/aquamarinepk/aqm/auth"
 	"github.com/aquamarinepk/aqm/auth/fake"
-	"github.com/google/uuid"
+	"github.com/aquamarinepk/aqm/crypto"
 )
 
 func TestSignUp(t *testing.T) {
@@ -164,13 +166,13 @@ func TestBootstrap(t *testing.T) {
 		t.Errorf("Bootstrap() username = %v, want superadmin", user.Username)
 	}
 
-	_, password2, err := Bootstrap(ctx, store, crypto, pwdGen)
-	if err != nil {
-		t.Fatalf("Bootstrap() second call error = %v", err)
+	again, password2, err := Bootstrap(ctx, store, crypto, pwdGen)
+	if !errors.Is(err, auth.ErrAlreadyBootstrapped) {
+		t.Fatalf("Bootstrap() second call error = %v, want ErrAlreadyBootstrapped", err)
 	}
 
-	if password2 != "" {
-		t.Error("Bootstrap() second call should return empty password when superadmin exists")
+	if again != nil || password2 != "" {
+		t.Error("Bootstrap() second call should return no user or password when superadmin exists")
 	}
 }
 
@@ -255,7 +257,7 @@ func TestGetUserByID(t *testing.T) {
 
 	tests := []struct {
 		name    string
-		id      uuid.UUID
+		id      auth.UserID
 		wantErr bool
 	}{
 		{
@@ -265,7 +267,7 @@ func TestGetUserByID(t *testing.T) {
 		},
 		{
 			name:    "non-existing user",
-			id:      uuid.New(),
+			id:      auth.NewUserID(),
 			wantErr: true,
 		},
 	}
@@ -364,6 +366,28 @@ func TestListUsersByStatus(t *testing.T) {
 	}
 }
 
+func TestSearchUsers(t *testing.T) {
+	store := fake.NewUserStore()
+	crypto := fake.NewCryptoService()
+	ctx := context.Background()
+
+	SignUp(ctx, store, crypto, "search1@example.com", "Password123!", "searchuser1", "Search User 1")
+	SignUp(ctx, store, crypto, "search2@example.com", "Password123!", "searchuser2", "Search User 2")
+	SignUp(ctx, store, crypto, "other@example.com", "Password123!", "other", "Other User")
+
+	page, err := SearchUsers(ctx, store, auth.UserQuery{UsernamePrefix: "search", Limit: 1})
+	if err != nil {
+		t.Fatalf("SearchUsers() error = %v", err)
+	}
+	if page.Total != 2 || len(page.Users) != 1 {
+		t.Errorf("SearchUsers() = total %d, %d users; want total 2, 1 user", page.Total, len(page.Users))
+	}
+
+	if _, err := SearchUsers(ctx, nil, auth.UserQuery{}); err == nil {
+		t.Error("SearchUsers() with nil store should fail")
+	}
+}
+
 func TestUpdateUser(t *testing.T) {
 	store := fake.NewUserStore()
 	crypto := fake.NewCryptoService()
@@ -383,6 +407,39 @@ func TestUpdateUser(t *testing.T) {
 	}
 }
 
+func TestUpdateUserAttributes(t *testing.T) {
+	store := fake.NewUserStore()
+	crypto := fake.NewCryptoService()
+	ctx := context.Background()
+
+	user, _ := SignUp(ctx, store, crypto, "attrs@example.com", "Password123!", "attrsuser", "Attrs User")
+
+	if err := UpdateUserAttributes(ctx, store, user, map[string]any{"locale": "en-GB", "prefs": map[string]any{"theme": "dark"}}); err != nil {
+		t.Fatalf("UpdateUserAttributes() error = %v", err)
+	}
+	if err := UpdateUserAttributes(ctx, store, user, map[string]any{"locale": nil, "prefs": map[string]any{"compact": true}}); err != nil {
+		t.Fatalf("UpdateUserAttributes() error = %v", err)
+	}
+
+	retrieved, _ := GetUserByID(ctx, store, user.ID)
+	if _, ok := retrieved.Attributes.Get("locale"); ok {
+		t.Errorf("locale was not removed: %v", retrieved.Attributes)
+	}
+	prefs, _ := retrieved.Attributes["prefs"].(map[string]any)
+	if prefs["theme"] != "dark" || prefs["compact"] != true {
+		t.Errorf("prefs = %v, want merged theme and compact", prefs)
+	}
+
+	version := retrieved.Version
+	err := UpdateUserAttributes(ctx, store, user, map[string]any{"Bad Key": 1})
+	if !errors.Is(err, auth.ErrInvalidAttribute) {
+		t.Fatalf("UpdateUserAttributes() error = %v, want ErrInvalidAttribute", err)
+	}
+	if _, ok := user.Attributes.Get("Bad Key"); ok || user.Version != version {
+		t.Errorf("user changed after a rejected patch: %v", user.Attributes)
+	}
+}
+
 func TestDeleteUser(t *testing.T) {
 	store := fake.NewUserStore()
 	crypto := fake.NewCryptoService()
@@ -463,6 +520,134 @@ func TestAssignRoleErrors(t *testing.T) {
 	}
 }
 
+func TestSignInRehashesPassword(t *testing.T) {
+	store := fake.NewUserStore()
+	cryptoSvc := fake.NewCryptoService()
+	tokenGen := fake.NewTokenGenerator()
+	ctx := context.Background()
+
+	user, err := SignUp(ctx, store, cryptoSvc, "rehash@example.com", "Password123!", "rehashuser", "Rehash User")
+	if err != nil {
+		t.Fatalf("SignUp() error = %v", err)
+	}
+	params := crypto.PasswordParams{Algorithm: crypto.PasswordAlgorithmBcrypt, Cost: 4}
+	if !user.PasswordNeedsRehash(params) {
+		t.Fatal("expected default argon2id hash to need a rehash for bcrypt")
+	}
+
+	cryptoSvc.WithPasswordParams(params)
+	if _, _, err := SignIn(ctx, store, cryptoSvc, tokenGen, "rehash@example.com", "Password123!"); err != nil {
+		t.Fatalf("SignIn() error = %v", err)
+	}
+
+	stored, _ := store.Get(ctx, user.ID)
+	if stored.PasswordNeedsRehash(params) {
+		t.Error("expected SignIn() to rehash the password with the new parameters")
+	}
+	if _, _, err := SignIn(ctx, store, cryptoSvc, tokenGen, "rehash@example.com", "Password123!"); err != nil {
+		t.Errorf("SignIn() after rehash error = %v", err)
+	}
+}
+
+func TestSignUpWithDerivedKeys(t *testing.T) {
+	store := fake.NewUserStore()
+	cryptoSvc := fake.NewCryptoService().WithKeyScheme(auth.KeySchemeDerived)
+	ctx := context.Background()
+
+	user, err := SignUp(ctx, store, cryptoSvc, "derived@example.com", "Password123!", "deriveduser", "Derived User")
+	if err != nil {
+		t.Fatalf("SignUp() error = %v", err)
+	}
+	stored, _ := store.Get(ctx, user.ID)
+	if stored.KeyScheme != auth.KeySchemeDerived {
+		t.Errorf("KeyScheme = %v, want %v", stored.KeyScheme, auth.KeySchemeDerived)
+	}
+	if email, err := stored.GetEmail(cryptoSvc.EncryptionKey()); err != nil || email != "derived@example.com" {
+		t.Errorf("GetEmail() = %q, %v", email, err)
+	}
+	if _, err := crypto.DecryptEmail(string(stored.EmailCT), string(stored.EmailIV), string(stored.EmailTag), cryptoSvc.EncryptionKey()); err == nil {
+		t.Error("email of a derived-key user decrypts with the master key")
+	}
+}
+
+func TestSignInUpgradesKeyScheme(t *testing.T) {
+	store := fake.NewUserStore()
+	cryptoSvc := fake.NewCryptoService()
+	tokenGen := fake.NewTokenGenerator()
+	ctx := context.Background()
+
+	user, err := SignUp(ctx, store, cryptoSvc, "legacy@example.com", "Password123!", "legacyuser", "Legacy User")
+	if err != nil {
+		t.Fatalf("SignUp() error = %v", err)
+	}
+	if user.KeyScheme != auth.KeySchemeMaster {
+		t.Fatalf("KeyScheme = %v, want %v", user.KeyScheme, auth.KeySchemeMaster)
+	}
+
+	cryptoSvc.WithKeyScheme(auth.KeySchemeDerived)
+	signedIn, _, err := SignIn(ctx, store, cryptoSvc, tokenGen, "legacy@example.com", "Password123!")
+	if err != nil {
+		t.Fatalf("SignIn() error = %v", err)
+	}
+	stored, _ := store.Get(ctx, user.ID)
+	if stored.KeyScheme != auth.KeySchemeDerived || signedIn.KeyScheme != auth.KeySchemeDerived {
+		t.Errorf("KeyScheme after SignIn() = stored %v, returned %v", stored.KeyScheme, signedIn.KeyScheme)
+	}
+	if email, err := stored.GetEmail(cryptoSvc.EncryptionKey()); err != nil || email != "legacy@example.com" {
+		t.Errorf("GetEmail() after upgrade = %q, %v", email, err)
+	}
+	if _, _, err := SignIn(ctx, store, cryptoSvc, tokenGen, "legacy@example.com", "Password123!"); err != nil {
+		t.Errorf("SignIn() after upgrade error = %v", err)
+	}
+}
+
+func TestMigrateUserKeys(t *testing.T) {
+	store := fake.NewUserStore()
+	cryptoSvc := fake.NewCryptoService()
+	pinGen := fake.NewPINGenerator()
+	ctx := context.Background()
+
+	var ids []auth.UserID
+	for _, name := range []string{"one", "two"} {
+		user, err := SignUp(ctx, store, cryptoSvc, name+"@example.com", "Password123!", name+"user", "User "+name)
+		if err != nil {
+			t.Fatalf("SignUp() error = %v", err)
+		}
+		ids = append(ids, user.ID)
+	}
+	withPIN, _ := store.Get(ctx, ids[0])
+	pin, err := GeneratePIN(ctx, store, cryptoSvc, pinGen, withPIN)
+	if err != nil {
+		t.Fatalf("GeneratePIN() error = %v", err)
+	}
+
+	if n, err := MigrateUserKeys(ctx, store, cryptoSvc); err != nil || n != 0 {
+		t.Errorf("MigrateUserKeys() without derived keys = %d, %v, want 0", n, err)
+	}
+
+	cryptoSvc.WithKeyScheme(auth.KeySchemeDerived)
+	n, err := MigrateUserKeys(ctx, store, cryptoSvc)
+	if err != nil || n != 2 {
+		t.Fatalf("MigrateUserKeys() = %d, %v, want 2", n, err)
+	}
+	for i, id := range ids {
+		user, _ := store.Get(ctx, id)
+		if user.KeyScheme != auth.KeySchemeDerived {
+			t.Errorf("user %d KeyScheme = %v", i, user.KeyScheme)
+		}
+		if _, err := user.GetEmail(cryptoSvc.EncryptionKey()); err != nil {
+			t.Errorf("user %d GetEmail() error = %v", i, err)
+		}
+	}
+	if _, err := SignInByPIN(ctx, store, cryptoSvc, pin); err != nil {
+		t.Errorf("SignInByPIN() after migration error = %v", err)
+	}
+
+	if n, err := MigrateUserKeys(ctx, store, cryptoSvc); err != nil || n != 0 {
+		t.Errorf("MigrateUserKeys() again = %d, %v, want 0", n, err)
+	}
+}
+
 func TestSignInInactiveUser(t *testing.T) {
 	store := fake.NewUserStore()
 	crypto := fake.NewCryptoService()
@@ -480,3 +665,175 @@ func TestSignInInactiveUser(t *testing.T) {
 		t.Error("SignIn() with inactive user should fail")
 	}
 }
+
+func TestResetPassword(t *testing.T) {
+	store := fake.NewUserStore()
+	crypto := fake.NewCryptoService()
+	pwdGen := fake.NewPasswordGenerator()
+	ctx := context.Background()
+
+	user, _ := SignUp(ctx, store, crypto, "reset@example.com", "Password123!", "resetuser", "Reset User")
+
+	password, err := ResetPassword(ctx, store, crypto, pwdGen, user.ID, "")
+	if err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	if password != "GeneratedPassword123!" {
+		t.Errorf("ResetPassword() = %q, want generated password", password)
+	}
+
+	if _, err := ResetPassword(ctx, store, crypto, pwdGen, user.ID, "NewPassword456!"); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	updated, _ := store.Get(ctx, user.ID)
+	if !updated.VerifyPassword("NewPassword456!") || updated.VerifyPassword("Password123!") {
+		t.Error("ResetPassword() did not replace the password")
+	}
+
+	if _, err := ResetPassword(ctx, store, crypto, pwdGen, user.ID, "weak"); err == nil {
+		t.Error("ResetPassword() with weak password should fail")
+	}
+	if _, err := ResetPassword(ctx, store, crypto, pwdGen, auth.NewUserID(), ""); err != auth.ErrUserNotFound {
+		t.Errorf("ResetPassword() unknown user error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	store := fake.NewUserStore()
+	crypto := fake.NewCryptoService()
+	ctx := context.Background()
+
+	user, _ := SignUp(ctx, store, crypto, "change@example.com", "Password123!", "changeuser", "Change User")
+
+	tests := []struct {
+		name     string
+		current  string
+		password string
+		want     error
+	}{
+		{"wrong current password", "Wrong123!", "NewPassword456!", auth.ErrIncorrectPassword},
+		{"weak password", "Password123!", "weak", auth.ErrInvalidPassword},
+		{"unchanged password", "Password123!", "Password123!", auth.ErrInvalidPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ChangePassword(ctx, store, crypto, user.ID, tt.current, tt.password); !errors.Is(err, tt.want) {
+				t.Errorf("ChangePassword() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := ChangePassword(ctx, store, crypto, user.ID, "Password123!", "NewPassword456!"); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	updated, _ := store.Get(ctx, user.ID)
+	if !updated.VerifyPassword("NewPassword456!") || updated.VerifyPassword("Password123!") {
+		t.Error("ChangePassword() did not replace the password")
+	}
+
+	if _, err := ChangePassword(ctx, store, crypto, auth.NewUserID(), "Password123!", "NewPassword456!"); err != auth.ErrUserNotFound {
+		t.Errorf("ChangePassword() unknown user error = %v, want ErrUserNotFound", err)
+	}
+}
+
+// permissionsByUser is a PermissionChecker granting each username its
+// listed permissions.
+type permissionsByUser map[string][]string
+
+func (p permissionsByUser) CheckPermission(ctx context.Context, username, permission string) (bool, error) {
+	return auth.HasPermission(p[username], permission), nil
+}
+
+func TestImpersonate(t *testing.T) {
+	store := fake.NewUserStore()
+	crypto := fake.NewCryptoService()
+	tokenGen := fake.NewTokenGenerator()
+	ctx := context.Background()
+
+	target, _ := SignUp(ctx, store, crypto, "target@example.com", "Password123!", "target", "Target User")
+	inactive, _ := SignUp(ctx, store, crypto, "gone@example.com", "Password123!", "gone", "Gone User")
+	DeleteUser(ctx, store, inactive.ID)
+	root, _ := SignUp(ctx, store, crypto, "root@example.com", "Password123!", "root", "Root User")
+	support, _ := SignUp(ctx, store, crypto, "support@example.com", "Password123!", "support", "Support User")
+	checker := permissionsByUser{
+		"admin-1": {auth.PermissionImpersonate},
+		"root":    {"*"},
+		"support": {auth.PermissionImpersonate, "users:read"},
+	}
+
+	tests := []struct {
+		name    string
+		actor   string
+		id      auth.UserID
+		wantErr error
+	}{
+		{name: "active user", actor: "admin-1", id: target.ID},
+		{name: "self", actor: target.ID.String(), id: target.ID, wantErr: auth.ErrImpersonationNotAllowed},
+		{name: "no actor", id: target.ID, wantErr: auth.ErrImpersonationNotAllowed},
+		{name: "inactive user", actor: "admin-1", id: inactive.ID, wantErr: auth.ErrInactiveAccount},
+		{name: "unknown user", actor: "admin-1", id: auth.NewUserID(), wantErr: auth.ErrUserNotFound},
+		{name: "superadmin", actor: "admin-1", id: root.ID, wantErr: auth.ErrImpersonationNotAllowed},
+		{name: "other impersonator", actor: "admin-1", id: support.ID, wantErr: auth.ErrImpersonationNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, token, err := Impersonate(ctx, store, tokenGen, checker, tt.actor, tt.id, time.Minute)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Impersonate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if user.ID != tt.id {
+				t.Errorf("Impersonate() user = %v, want %v", user.ID, tt.id)
+			}
+			if token != "token-admin-1-as-"+tt.id.String() {
+				t.Errorf("Impersonate() token = %q", token)
+			}
+		})
+	}
+}
+
+func TestSignUpWithRoles(t *testing.T) {
+	ctx := context.Background()
+	users := fake.NewUserStore()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+	crypto := fake.NewCryptoService()
+	tx := fake.NewTransactor()
+
+	if _, err := CreateRole(ctx, roles, "member", "Member", []string{"posts:read"}, "system"); err != nil {
+		t.Fatalf("CreateRole() error = %v", err)
+	}
+
+	user, err := SignUpWithRoles(ctx, tx, users, roles, grants, crypto, []string{"member"}, "ann@example.com", "Password123!", "ann", "Ann")
+	if err != nil {
+		t.Fatalf("SignUpWithRoles() error = %v", err)
+	}
+	ok, err := grants.HasRole(ctx, user.Username, "member")
+	if err != nil || !ok {
+		t.Errorf("HasRole(member) = %v, %v; want true", ok, err)
+	}
+	userGrants, _ := grants.GetUserGrants(ctx, user.Username)
+	if len(userGrants) != 1 || userGrants[0].AssignedBy != SignUpAssigner {
+		t.Errorf("grants = %+v, want one assigned by %s", userGrants, SignUpAssigner)
+	}
+
+	_, err = SignUpWithRoles(ctx, tx, users, roles, grants, crypto, []string{"missing"}, "bob@example.com", "Password123!", "bob", "Bob")
+	if !errors.Is(err, auth.ErrRoleNotFound) {
+		t.Errorf("SignUpWithRoles() missing role error = %v, want %v", err, auth.ErrRoleNotFound)
+	}
+	if tx.Failures() != 1 {
+		t.Errorf("transactor saw %d failures, want the failed sign-up", tx.Failures())
+	}
+
+	_, err = SignUpWithRoles(ctx, tx, users, roles, grants, crypto, nil, "ann@example.com", "Password123!", "ann2", "Ann")
+	if !errors.Is(err, auth.ErrUserAlreadyExists) {
+		t.Errorf("SignUpWithRoles() duplicate error = %v, want %v", err, auth.ErrUserAlreadyExists)
+	}
+
+	if _, err := SignUpWithRoles(ctx, tx, users, nil, grants, crypto, nil, "c@example.com", "Password123!", "carl", "Carl"); err == nil {
+		t.Error("SignUpWithRoles() with nil role store should fail")
+	}
+}
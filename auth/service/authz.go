@@ -2,14 +2,22 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
 
 	"github.com/aquamarinepk/aqm/auth"
-	"github.com/google/uuid"
 )
 
 // CreateRole creates a new role with permissions
 func CreateRole(ctx context.Context, store auth.RoleStore, name, description string, permissions []string, createdBy string) (*auth.Role, error) {
+	return CreateRoleWithSets(ctx, store, nil, name, description, permissions, nil, createdBy)
+}
+
+// CreateRoleWithSets creates a new role with permissions that also grants
+// those of the permission sets named in setNames, looked up in sets.
+func CreateRoleWithSets(ctx context.Context, store auth.RoleStore, sets auth.PermissionSetStore, name, description string, permissions, setNames []string, createdBy string) (*auth.Role, error) {
 	if store == nil {
 		return nil, fmt.Errorf("role store is required")
 	}
@@ -35,6 +43,10 @@ func CreateRole(ctx context.Context, store auth.RoleStore, name, description str
 	role.Status = auth.RoleStatusActive
 	role.CreatedBy = createdBy
 	role.UpdatedBy = createdBy
+	role.PermissionSets = setNames
+	if err := ExpandRole(ctx, sets, role); err != nil {
+		return nil, err
+	}
 
 	role.BeforeCreate()
 
@@ -46,7 +58,7 @@ func CreateRole(ctx context.Context, store auth.RoleStore, name, description str
 }
 
 // GetRoleByID retrieves a role by ID
-func GetRoleByID(ctx context.Context, store auth.RoleStore, id uuid.UUID) (*auth.Role, error) {
+func GetRoleByID(ctx context.Context, store auth.RoleStore, id auth.RoleID) (*auth.Role, error) {
 	if store == nil {
 		return nil, fmt.Errorf("role store is required")
 	}
@@ -91,19 +103,59 @@ func UpdateRole(ctx context.Context, store auth.RoleStore, role *auth.Role, upda
 	return store.Update(ctx, role)
 }
 
+// DiffRole computes the permissions added and removed if the role's
+// permissions were replaced with proposed, plus how many users hold the role.
+// Nothing is written; use it to preview an UpdateRole.
+func DiffRole(ctx context.Context, roleStore auth.RoleStore, grantStore auth.GrantStore, id auth.RoleID, proposed []string) (*auth.RoleDiff, error) {
+	if roleStore == nil {
+		return nil, fmt.Errorf("role store is required")
+	}
+	if grantStore == nil {
+		return nil, fmt.Errorf("grant store is required")
+	}
+
+	role, err := roleStore.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	added, removed := auth.DiffPermissions(role.Permissions, proposed)
+
+	grants, err := grantStore.GetRoleGrants(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get role grants: %w", err)
+	}
+
+	users := make(map[string]bool, len(grants))
+	for _, g := range grants {
+		users[g.Username] = true
+	}
+
+	return &auth.RoleDiff{
+		RoleID:        role.ID,
+		Added:         added,
+		Removed:       removed,
+		AffectedUsers: len(users),
+	}, nil
+}
+
 // DeleteRole soft-deletes a role
-func DeleteRole(ctx context.Context, store auth.RoleStore, id uuid.UUID) error {
+func DeleteRole(ctx context.Context, store auth.RoleStore, id auth.RoleID) error {
 	if store == nil {
 		return fmt.Errorf("role store is required")
 	}
 	return store.Delete(ctx, id)
 }
 
-// AssignRole assigns a role to a user
-func AssignRole(ctx context.Context, store auth.GrantStore, username string, roleID uuid.UUID, assignedBy string) (*auth.Grant, error) {
+// AssignRole assigns a role to a user. With an AdminScope in ctx it fails
+// with auth.ErrRoleOutOfScope for roles the administrator does not manage.
+func AssignRole(ctx context.Context, store auth.GrantStore, username string, roleID auth.RoleID, assignedBy string) (*auth.Grant, error) {
 	if store == nil {
 		return nil, fmt.Errorf("grant store is required")
 	}
+	if err := checkAdminScope(ctx, roleID); err != nil {
+		return nil, err
+	}
 
 	// Check if grant already exists
 	grants, err := store.GetUserGrants(ctx, username)
@@ -125,14 +177,215 @@ func AssignRole(ctx context.Context, store auth.GrantStore, username string, rol
 	return grant, nil
 }
 
-// RevokeRole removes a role from a user
-func RevokeRole(ctx context.Context, store auth.GrantStore, username string, roleID uuid.UUID) error {
+// AssignRoles assigns several roles to a user as one unit of work run by
+// tx: either every grant is created or, when one fails, none is. Repeated
+// role IDs are assigned once; a role the user already holds fails the
+// whole call with auth.ErrGrantAlreadyExists, and so does one outside the
+// AdminScope in ctx with auth.ErrRoleOutOfScope. A nil tx runs the grants
+// without a transaction.
+func AssignRoles(ctx context.Context, tx auth.Transactor, store auth.GrantStore, username string, roleIDs []auth.RoleID, assignedBy string) ([]*auth.Grant, error) {
+	if store == nil {
+		return nil, fmt.Errorf("grant store is required")
+	}
+
+	grants := make([]*auth.Grant, 0, len(roleIDs))
+	err := withinTx(ctx, tx, func(ctx context.Context) error {
+		existing, err := store.GetUserGrants(ctx, username)
+		if err != nil {
+			return fmt.Errorf("check existing grants: %w", err)
+		}
+		held := make(map[auth.RoleID]bool, len(existing))
+		for _, g := range existing {
+			held[g.RoleID] = true
+		}
+
+		assigned := make(map[auth.RoleID]bool, len(roleIDs))
+		for _, roleID := range roleIDs {
+			if assigned[roleID] {
+				continue
+			}
+			if held[roleID] {
+				return auth.ErrGrantAlreadyExists
+			}
+			if err := checkAdminScope(ctx, roleID); err != nil {
+				return err
+			}
+
+			grant := auth.NewGrant(username, roleID, assignedBy)
+			if err := store.Create(ctx, grant); err != nil {
+				return fmt.Errorf("create grant: %w", err)
+			}
+			assigned[roleID] = true
+			grants = append(grants, grant)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// RevokeRole removes a role from a user. With an AdminScope in ctx it fails
+// with auth.ErrRoleOutOfScope for roles the administrator does not manage.
+func RevokeRole(ctx context.Context, store auth.GrantStore, username string, roleID auth.RoleID) error {
 	if store == nil {
 		return fmt.Errorf("grant store is required")
 	}
+	if err := checkAdminScope(ctx, roleID); err != nil {
+		return err
+	}
 	return store.Delete(ctx, username, roleID)
 }
 
+// Operations reported in auth.GrantFailure.
+const (
+	GrantOpAssign = "assign"
+	GrantOpRevoke = "revoke"
+)
+
+// DefaultGrantBatchSize is used by ApplyGrantPlan when batchSize is not positive.
+const DefaultGrantBatchSize = 100
+
+// PlanGrants computes the changes that make the grants of every role in
+// desired, plus any role listed in scope, match desired exactly: missing
+// pairs are assigned and grants of those roles not in desired are revoked.
+// Roles outside that set are left untouched. Nothing is written.
+func PlanGrants(ctx context.Context, store auth.GrantStore, desired []auth.GrantPair, scope []auth.RoleID) (*auth.GrantPlan, error) {
+	if store == nil {
+		return nil, fmt.Errorf("grant store is required")
+	}
+
+	want := make(map[auth.GrantPair]bool, len(desired))
+	var roles []auth.RoleID
+	seen := make(map[auth.RoleID]bool)
+	addRole := func(id auth.RoleID) {
+		if !seen[id] {
+			seen[id] = true
+			roles = append(roles, id)
+		}
+	}
+
+	for _, pair := range desired {
+		want[pair] = true
+		addRole(pair.RoleID)
+	}
+	for _, id := range scope {
+		addRole(id)
+	}
+
+	plan := &auth.GrantPlan{Assign: []auth.GrantPair{}, Revoke: []auth.GrantPair{}}
+	have := make(map[auth.GrantPair]bool)
+
+	for _, roleID := range roles {
+		grants, err := store.GetRoleGrants(ctx, roleID)
+		if err != nil {
+			return nil, fmt.Errorf("get role grants: %w", err)
+		}
+		for _, g := range grants {
+			pair := auth.GrantPair{Username: g.Username, RoleID: g.RoleID}
+			have[pair] = true
+			if !want[pair] {
+				plan.Revoke = append(plan.Revoke, pair)
+			}
+		}
+	}
+
+	for _, pair := range desired {
+		if !have[pair] {
+			plan.Assign = append(plan.Assign, pair)
+			have[pair] = true
+		}
+	}
+
+	sortPairs(plan.Assign)
+	sortPairs(plan.Revoke)
+	return plan, nil
+}
+
+// ApplyGrantPlan applies plan in batches of batchSize, assignments first,
+// calling onBatch after each batch. A change that fails is reported in the
+// batch result and does not stop the run; assigning an existing grant or
+// revoking a missing one counts as applied, while changes to roles outside
+// the AdminScope in ctx fail. The run stops early if ctx is cancelled or
+// onBatch returns an error.
+func ApplyGrantPlan(ctx context.Context, store auth.GrantStore, plan *auth.GrantPlan, assignedBy string, batchSize int, onBatch func(auth.GrantBatchResult) error) error {
+	if store == nil {
+		return fmt.Errorf("grant store is required")
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultGrantBatchSize
+	}
+
+	type change struct {
+		pair auth.GrantPair
+		op   string
+	}
+
+	changes := make([]change, 0, len(plan.Assign)+len(plan.Revoke))
+	for _, p := range plan.Assign {
+		changes = append(changes, change{p, GrantOpAssign})
+	}
+	for _, p := range plan.Revoke {
+		changes = append(changes, change{p, GrantOpRevoke})
+	}
+
+	for start, batch := 0, 1; start < len(changes); start, batch = start+batchSize, batch+1 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := min(start+batchSize, len(changes))
+		result := auth.GrantBatchResult{
+			Batch:    batch,
+			Assigned: []auth.GrantPair{},
+			Revoked:  []auth.GrantPair{},
+			Failed:   []auth.GrantFailure{},
+		}
+
+		for _, c := range changes[start:end] {
+			err := checkAdminScope(ctx, c.pair.RoleID)
+			if err == nil && c.op == GrantOpAssign {
+				err = store.Create(ctx, auth.NewGrant(c.pair.Username, c.pair.RoleID, assignedBy))
+				if errors.Is(err, auth.ErrGrantAlreadyExists) {
+					err = nil
+				}
+			} else if err == nil {
+				err = store.Delete(ctx, c.pair.Username, c.pair.RoleID)
+				if errors.Is(err, auth.ErrGrantNotFound) {
+					err = nil
+				}
+			}
+
+			switch {
+			case err != nil:
+				result.Failed = append(result.Failed, auth.GrantFailure{GrantPair: c.pair, Op: c.op, Error: err.Error()})
+			case c.op == GrantOpAssign:
+				result.Assigned = append(result.Assigned, c.pair)
+			default:
+				result.Revoked = append(result.Revoked, c.pair)
+			}
+		}
+
+		if onBatch != nil {
+			if err := onBatch(result); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func sortPairs(pairs []auth.GrantPair) {
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].RoleID != pairs[j].RoleID {
+			return pairs[i].RoleID.String() < pairs[j].RoleID.String()
+		}
+		return pairs[i].Username < pairs[j].Username
+	})
+}
+
 // GetUserRoles retrieves all roles for a user
 func GetUserRoles(ctx context.Context, store auth.GrantStore, username string) ([]*auth.Role, error) {
 	if store == nil {
@@ -149,8 +402,31 @@ func GetUserGrants(ctx context.Context, store auth.GrantStore, username string)
 	return store.GetUserGrants(ctx, username)
 }
 
+// GetUserPermissions returns the permissions granted to a user by their
+// active roles, sorted and without duplicates.
+func GetUserPermissions(ctx context.Context, store auth.GrantStore, username string) ([]string, error) {
+	if store == nil {
+		return nil, fmt.Errorf("grant store is required")
+	}
+
+	roles, err := store.GetUserRoles(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("get user roles: %w", err)
+	}
+
+	permissions := make([]string, 0)
+	for _, role := range roles {
+		if role.Status != auth.RoleStatusActive {
+			continue
+		}
+		permissions = append(permissions, role.GrantedPermissions()...)
+	}
+	slices.Sort(permissions)
+	return slices.Compact(permissions), nil
+}
+
 // GetRoleGrants retrieves all grants for a role
-func GetRoleGrants(ctx context.Context, store auth.GrantStore, roleID uuid.UUID) ([]*auth.Grant, error) {
+func GetRoleGrants(ctx context.Context, store auth.GrantStore, roleID auth.RoleID) ([]*auth.Grant, error) {
 	if store == nil {
 		return nil, fmt.Errorf("grant store is required")
 	}
@@ -172,7 +448,7 @@ func CheckPermission(ctx context.Context, store auth.GrantStore, username string
 		if role.Status != auth.RoleStatusActive {
 			continue
 		}
-		if auth.HasPermission(role.Permissions, permission) {
+		if auth.HasPermission(role.GrantedPermissions(), permission) {
 			return true, nil
 		}
 	}
@@ -195,7 +471,7 @@ func CheckAnyPermission(ctx context.Context, store auth.GrantStore, username str
 		if role.Status != auth.RoleStatusActive {
 			continue
 		}
-		if auth.HasAnyPermission(role.Permissions, permissions) {
+		if auth.HasAnyPermission(role.GrantedPermissions(), permissions) {
 			return true, nil
 		}
 	}
@@ -220,12 +496,100 @@ func CheckAllPermissions(ctx context.Context, store auth.GrantStore, username st
 		if role.Status != auth.RoleStatusActive {
 			continue
 		}
-		allPerms = append(allPerms, role.Permissions...)
+		allPerms = append(allPerms, role.GrantedPermissions()...)
 	}
 
 	return auth.HasAllPermissions(allPerms, permissions), nil
 }
 
+// Decide evaluates whether username holds permission and explains the
+// outcome: every grant is listed, oldest first, with the role it points to
+// and why it did or did not satisfy the request, so redundant grants show up
+// too. Resource is recorded but not evaluated, as role permissions are not
+// scoped to resources.
+func Decide(ctx context.Context, store auth.GrantStore, username, permission, resource string) (*auth.Decision, error) {
+	if store == nil {
+		return nil, fmt.Errorf("grant store is required")
+	}
+
+	grants, err := store.GetUserGrants(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("get user grants: %w", err)
+	}
+	roles, err := store.GetUserRoles(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("get user roles: %w", err)
+	}
+	byID := make(map[auth.RoleID]*auth.Role, len(roles))
+	for _, role := range roles {
+		byID[role.ID] = role
+	}
+
+	sort.Slice(grants, func(i, j int) bool {
+		return grants[i].AssignedAt.Before(grants[j].AssignedAt)
+	})
+
+	d := &auth.Decision{
+		UserID:     username,
+		Permission: permission,
+		Resource:   resource,
+		Effect:     auth.EffectDeny,
+		Trace:      make([]auth.DecisionStep, 0, len(grants)),
+	}
+
+	var inactive int
+	for _, grant := range grants {
+		step := auth.DecisionStep{GrantID: grant.ID, RoleID: grant.RoleID, Outcome: auth.StepNoMatch}
+
+		role, ok := byID[grant.RoleID]
+		switch {
+		case !ok:
+			step.Outcome = auth.StepRoleMissing
+		case role.Status != auth.RoleStatusActive:
+			step.Role, step.RoleStatus = role.Name, role.Status
+			step.Outcome = auth.StepRoleInactive
+			if auth.HasPermission(role.GrantedPermissions(), permission) {
+				inactive++
+			}
+		default:
+			step.Role, step.RoleStatus = role.Name, role.Status
+			if match, ok := matchingPermission(role.GrantedPermissions(), permission); ok {
+				step.Outcome, step.MatchedBy = auth.StepMatched, match
+				if !d.Allowed {
+					d.Allowed, d.Effect = true, auth.EffectAllow
+					d.Reason = fmt.Sprintf("granted by role %s through permission %s", role.Name, match)
+				}
+			}
+		}
+
+		d.Trace = append(d.Trace, step)
+	}
+
+	if !d.Allowed {
+		switch {
+		case len(grants) == 0:
+			d.Reason = "user has no grants"
+		case inactive > 0:
+			d.Reason = fmt.Sprintf("only inactive roles grant %s", permission)
+		default:
+			d.Reason = fmt.Sprintf("no granted role includes %s", permission)
+		}
+	}
+
+	return d, nil
+}
+
+// matchingPermission returns the first of permissions that satisfies required.
+func matchingPermission(permissions []string, required string) (string, bool) {
+	req := auth.Permission(required)
+	for _, p := range permissions {
+		if auth.Permission(p).Matches(req) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
 // HasRole checks if a user has a specific role by name
 func HasRole(ctx context.Context, store auth.GrantStore, username string, roleName string) (bool, error) {
 	if store == nil {
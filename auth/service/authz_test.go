@@ -2,11 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"testing"
 
 	"github.com/aquamarinepk/aqm/auth"
 	"github.com/aquamarinepk/aqm/auth/fake"
-	"github.com/google/uuid"
 )
 
 func TestCreateRole(t *testing.T) {
@@ -79,7 +80,7 @@ func TestGetRoleByID(t *testing.T) {
 
 	tests := []struct {
 		name    string
-		id      uuid.UUID
+		id      auth.RoleID
 		wantErr bool
 	}{
 		{
@@ -89,7 +90,7 @@ func TestGetRoleByID(t *testing.T) {
 		},
 		{
 			name:    "non-existing role",
-			id:      uuid.New(),
+			id:      auth.NewRoleID(),
 			wantErr: true,
 		},
 	}
@@ -234,7 +235,7 @@ func TestAssignRole(t *testing.T) {
 	tests := []struct {
 		name       string
 		username   string
-		roleID     uuid.UUID
+		roleID     auth.RoleID
 		assignedBy string
 		wantErr    bool
 	}{
@@ -339,6 +340,34 @@ func TestGetUserGrants(t *testing.T) {
 	}
 }
 
+func TestGetUserPermissions(t *testing.T) {
+	roleStore := fake.NewRoleStore()
+	grantStore := fake.NewGrantStore(roleStore)
+	ctx := context.Background()
+
+	reader, _ := CreateRole(ctx, roleStore, "reader", "Reader", []string{"users:read", "content:read"}, "system")
+	writer, _ := CreateRole(ctx, roleStore, "writer", "Writer", []string{"content:write", "content:read"}, "system")
+	retired, _ := CreateRole(ctx, roleStore, "retired", "Retired", []string{"admin:delete"}, "system")
+	username := "testuser"
+	AssignRole(ctx, grantStore, username, reader.ID, "admin")
+	AssignRole(ctx, grantStore, username, writer.ID, "admin")
+	AssignRole(ctx, grantStore, username, retired.ID, "admin")
+	DeleteRole(ctx, roleStore, retired.ID)
+
+	got, err := GetUserPermissions(ctx, grantStore, username)
+	if err != nil {
+		t.Fatalf("GetUserPermissions() error = %v", err)
+	}
+	want := []string{"content:read", "content:write", "users:read"}
+	if !slices.Equal(got, want) {
+		t.Errorf("GetUserPermissions() = %v, want %v", got, want)
+	}
+
+	if got, _ := GetUserPermissions(ctx, grantStore, "nobody"); got == nil || len(got) != 0 {
+		t.Errorf("GetUserPermissions(no grants) = %#v, want empty", got)
+	}
+}
+
 func TestGetRoleGrants(t *testing.T) {
 	roleStore := fake.NewRoleStore()
 	grantStore := fake.NewGrantStore(roleStore)
@@ -361,6 +390,139 @@ func TestGetRoleGrants(t *testing.T) {
 	}
 }
 
+func TestDiffRole(t *testing.T) {
+	roleStore := fake.NewRoleStore()
+	grantStore := fake.NewGrantStore(roleStore)
+	ctx := context.Background()
+
+	role, _ := CreateRole(ctx, roleStore, "reviewer", "Reviewer", []string{"docs:read", "docs:comment"}, "system")
+	AssignRole(ctx, grantStore, "testuser1", role.ID, "admin")
+	AssignRole(ctx, grantStore, "testuser2", role.ID, "admin")
+
+	diff, err := DiffRole(ctx, roleStore, grantStore, role.ID, []string{"docs:read", "docs:write"})
+	if err != nil {
+		t.Fatalf("DiffRole() error = %v", err)
+	}
+
+	if len(diff.Added) != 1 || diff.Added[0] != "docs:write" {
+		t.Errorf("DiffRole() added = %v, want [docs:write]", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != "docs:comment" {
+		t.Errorf("DiffRole() removed = %v, want [docs:comment]", diff.Removed)
+	}
+	if diff.AffectedUsers != 2 {
+		t.Errorf("DiffRole() affected users = %v, want 2", diff.AffectedUsers)
+	}
+
+	retrieved, _ := GetRoleByID(ctx, roleStore, role.ID)
+	if len(retrieved.Permissions) != 2 || retrieved.Permissions[1] != "docs:comment" {
+		t.Errorf("DiffRole() should not modify the role, got %v", retrieved.Permissions)
+	}
+}
+
+func TestDiffRoleNotFound(t *testing.T) {
+	roleStore := fake.NewRoleStore()
+	grantStore := fake.NewGrantStore(roleStore)
+
+	_, err := DiffRole(context.Background(), roleStore, grantStore, auth.NewRoleID(), []string{"docs:read"})
+	if !errors.Is(err, auth.ErrRoleNotFound) {
+		t.Errorf("DiffRole() error = %v, want %v", err, auth.ErrRoleNotFound)
+	}
+}
+
+func TestDecide(t *testing.T) {
+	roleStore := fake.NewRoleStore()
+	grantStore := fake.NewGrantStore(roleStore)
+	ctx := context.Background()
+
+	viewer, _ := CreateRole(ctx, roleStore, "viewer", "Viewer", []string{"users:read"}, "system")
+	admin, _ := CreateRole(ctx, roleStore, "admin", "Admin", []string{"users:*"}, "system")
+	retired, _ := CreateRole(ctx, roleStore, "retired", "Retired", []string{"billing:read"}, "system")
+	retired.Status = auth.RoleStatusInactive
+	roleStore.Update(ctx, retired)
+
+	AssignRole(ctx, grantStore, "ann", viewer.ID, "system")
+	AssignRole(ctx, grantStore, "ann", admin.ID, "system")
+	AssignRole(ctx, grantStore, "ann", retired.ID, "system")
+
+	tests := []struct {
+		name        string
+		username    string
+		permission  string
+		wantAllowed bool
+		wantReason  string
+		wantOutcome map[string]string
+	}{
+		{
+			name:        "allowed through wildcard",
+			username:    "ann",
+			permission:  "users:write",
+			wantAllowed: true,
+			wantReason:  "granted by role admin through permission users:*",
+			wantOutcome: map[string]string{"viewer": auth.StepNoMatch, "admin": auth.StepMatched, "retired": auth.StepRoleInactive},
+		},
+		{
+			name:        "redundant grants are traced",
+			username:    "ann",
+			permission:  "users:read",
+			wantAllowed: true,
+			wantOutcome: map[string]string{"viewer": auth.StepMatched, "admin": auth.StepMatched},
+		},
+		{
+			name:       "only inactive role grants it",
+			username:   "ann",
+			permission: "billing:read",
+			wantReason: "only inactive roles grant billing:read",
+		},
+		{
+			name:       "no role includes it",
+			username:   "ann",
+			permission: "audit:read",
+			wantReason: "no granted role includes audit:read",
+		},
+		{
+			name:       "no grants",
+			username:   "bob",
+			permission: "users:read",
+			wantReason: "user has no grants",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Decide(ctx, grantStore, tt.username, tt.permission, "user-1")
+			if err != nil {
+				t.Fatalf("Decide() error = %v", err)
+			}
+			if d.Allowed != tt.wantAllowed {
+				t.Errorf("Decide() allowed = %v, want %v", d.Allowed, tt.wantAllowed)
+			}
+			wantEffect := auth.EffectDeny
+			if tt.wantAllowed {
+				wantEffect = auth.EffectAllow
+			}
+			if d.Effect != wantEffect {
+				t.Errorf("Decide() effect = %v, want %v", d.Effect, wantEffect)
+			}
+			if tt.wantReason != "" && d.Reason != tt.wantReason {
+				t.Errorf("Decide() reason = %q, want %q", d.Reason, tt.wantReason)
+			}
+			if d.Resource != "user-1" {
+				t.Errorf("Decide() resource = %q, want user-1", d.Resource)
+			}
+			for _, step := range d.Trace {
+				if want, ok := tt.wantOutcome[step.Role]; ok && step.Outcome != want {
+					t.Errorf("trace for %s = %s, want %s", step.Role, step.Outcome, want)
+				}
+			}
+		})
+	}
+
+	if _, err := Decide(ctx, nil, "ann", "users:read", ""); err == nil {
+		t.Error("Decide() with nil store should fail")
+	}
+}
+
 func TestCheckPermission(t *testing.T) {
 	roleStore := fake.NewRoleStore()
 	grantStore := fake.NewGrantStore(roleStore)
@@ -550,3 +712,161 @@ func TestHasRole(t *testing.T) {
 		})
 	}
 }
+
+func TestPlanGrants(t *testing.T) {
+	roleStore := fake.NewRoleStore()
+	grantStore := fake.NewGrantStore(roleStore)
+	ctx := context.Background()
+
+	admins := auth.NewRoleID()
+	editors := auth.NewRoleID()
+	viewers := auth.NewRoleID()
+	untouched := auth.NewRoleID()
+
+	for _, g := range []*auth.Grant{
+		auth.NewGrant("alice", admins, "seed"),
+		auth.NewGrant("bob", admins, "seed"),
+		auth.NewGrant("carol", viewers, "seed"),
+		auth.NewGrant("dave", untouched, "seed"),
+	} {
+		grantStore.Create(ctx, g)
+	}
+
+	desired := []auth.GrantPair{
+		{Username: "alice", RoleID: admins},
+		{Username: "alice", RoleID: editors},
+		{Username: "alice", RoleID: editors},
+	}
+
+	plan, err := PlanGrants(ctx, grantStore, desired, []auth.RoleID{viewers})
+	if err != nil {
+		t.Fatalf("PlanGrants() error = %v", err)
+	}
+
+	if len(plan.Assign) != 1 || plan.Assign[0] != (auth.GrantPair{Username: "alice", RoleID: editors}) {
+		t.Errorf("Assign = %v, want alice->editors once", plan.Assign)
+	}
+
+	revoke := map[auth.GrantPair]bool{}
+	for _, p := range plan.Revoke {
+		revoke[p] = true
+	}
+	if len(plan.Revoke) != 2 || !revoke[auth.GrantPair{Username: "bob", RoleID: admins}] || !revoke[auth.GrantPair{Username: "carol", RoleID: viewers}] {
+		t.Errorf("Revoke = %v, want bob->admins and carol->viewers", plan.Revoke)
+	}
+	if !plan.HasChanges() {
+		t.Error("HasChanges() = false, want true")
+	}
+
+	if _, err := PlanGrants(ctx, nil, desired, nil); err == nil {
+		t.Error("PlanGrants() with nil store should fail")
+	}
+}
+
+func TestApplyGrantPlan(t *testing.T) {
+	roleStore := fake.NewRoleStore()
+	grantStore := fake.NewGrantStore(roleStore)
+	ctx := context.Background()
+
+	roleID := auth.NewRoleID()
+	grantStore.Create(ctx, auth.NewGrant("zed", roleID, "seed"))
+
+	plan := &auth.GrantPlan{
+		Assign: []auth.GrantPair{
+			{Username: "alice", RoleID: roleID},
+			{Username: "bob", RoleID: roleID},
+			{Username: "carol", RoleID: roleID},
+		},
+		Revoke: []auth.GrantPair{
+			{Username: "zed", RoleID: roleID},
+			{Username: "ghost", RoleID: roleID},
+		},
+	}
+
+	var batches []auth.GrantBatchResult
+	err := ApplyGrantPlan(ctx, grantStore, plan, "sync", 2, func(b auth.GrantBatchResult) error {
+		batches = append(batches, b)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ApplyGrantPlan() error = %v", err)
+	}
+
+	if len(batches) != 3 {
+		t.Fatalf("got %d batches, want 3", len(batches))
+	}
+	if len(batches[0].Assigned) != 2 || len(batches[1].Assigned) != 1 || len(batches[1].Revoked) != 1 || len(batches[2].Revoked) != 1 {
+		t.Errorf("unexpected batch split: %+v", batches)
+	}
+
+	grants, _ := grantStore.GetRoleGrants(ctx, roleID)
+	users := map[string]string{}
+	for _, g := range grants {
+		users[g.Username] = g.AssignedBy
+	}
+	if len(users) != 3 || users["alice"] != "sync" || users["zed"] != "" {
+		t.Errorf("grants after apply = %v", users)
+	}
+}
+
+func TestApplyGrantPlanStopsOnCallbackError(t *testing.T) {
+	roleStore := fake.NewRoleStore()
+	grantStore := fake.NewGrantStore(roleStore)
+	roleID := auth.NewRoleID()
+
+	plan := &auth.GrantPlan{Assign: []auth.GrantPair{
+		{Username: "alice", RoleID: roleID},
+		{Username: "bob", RoleID: roleID},
+	}}
+
+	stop := errors.New("client gone")
+	calls := 0
+	err := ApplyGrantPlan(context.Background(), grantStore, plan, "sync", 1, func(b auth.GrantBatchResult) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("ApplyGrantPlan() error = %v, want %v", err, stop)
+	}
+	if calls != 1 {
+		t.Errorf("onBatch called %d times, want 1", calls)
+	}
+}
+
+func TestAssignRoles(t *testing.T) {
+	roleStore := fake.NewRoleStore()
+	grantStore := fake.NewGrantStore(roleStore)
+	tx := fake.NewTransactor()
+	ctx := context.Background()
+
+	editor, _ := CreateRole(ctx, roleStore, "editor", "Editor", []string{"posts:write"}, "system")
+	viewer, _ := CreateRole(ctx, roleStore, "viewer", "Viewer", []string{"posts:read"}, "system")
+
+	grants, err := AssignRoles(ctx, tx, grantStore, "ann", []auth.RoleID{editor.ID, viewer.ID, editor.ID}, "admin")
+	if err != nil {
+		t.Fatalf("AssignRoles() error = %v", err)
+	}
+	if len(grants) != 2 || grants[0].RoleID != editor.ID || grants[1].AssignedBy != "admin" {
+		t.Errorf("AssignRoles() = %+v, want editor and viewer grants by admin", grants)
+	}
+	if tx.Calls() != 1 {
+		t.Errorf("transactor ran %d units of work, want 1", tx.Calls())
+	}
+
+	_, err = AssignRoles(ctx, tx, grantStore, "ann", []auth.RoleID{viewer.ID}, "admin")
+	if !errors.Is(err, auth.ErrGrantAlreadyExists) {
+		t.Errorf("AssignRoles() held role error = %v, want %v", err, auth.ErrGrantAlreadyExists)
+	}
+	if tx.Failures() != 1 {
+		t.Errorf("transactor saw %d failures, want 1", tx.Failures())
+	}
+
+	grants, err = AssignRoles(ctx, nil, grantStore, "bob", []auth.RoleID{viewer.ID}, "admin")
+	if err != nil || len(grants) != 1 {
+		t.Errorf("AssignRoles() without transactor = %v, %v; want one grant", grants, err)
+	}
+
+	if _, err := AssignRoles(ctx, tx, nil, "ann", nil, "admin"); err == nil {
+		t.Error("AssignRoles() with nil store should fail")
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+// ClaimsEnricher adds custom claims, such as a tenant ID, plan or feature
+// flags, to the tokens DefaultTokenGenerator issues. claims holds the
+// registered claims of the token; the returned ones are merged into its
+// Context. An error fails the issuance.
+type ClaimsEnricher interface {
+	EnrichClaims(ctx context.Context, claims crypto.TokenClaims) (map[string]string, error)
+}
+
+// ClaimsEnricherFunc adapts a function to the ClaimsEnricher interface.
+type ClaimsEnricherFunc func(ctx context.Context, claims crypto.TokenClaims) (map[string]string, error)
+
+// EnrichClaims implements ClaimsEnricher.
+func (f ClaimsEnricherFunc) EnrichClaims(ctx context.Context, claims crypto.TokenClaims) (map[string]string, error) {
+	return f(ctx, claims)
+}
+
+// EnrichClaims runs enrichers in order over claims and returns them with
+// the custom claims added. Later enrichers override the claims of earlier
+// ones.
+func EnrichClaims(ctx context.Context, claims crypto.TokenClaims, enrichers ...ClaimsEnricher) (crypto.TokenClaims, error) {
+	for _, e := range enrichers {
+		extra, err := e.EnrichClaims(ctx, claims)
+		if err != nil {
+			return claims, fmt.Errorf("enrich claims: %w", err)
+		}
+		if len(extra) == 0 {
+			continue
+		}
+		merged := make(map[string]string, len(claims.Context)+len(extra))
+		maps.Copy(merged, claims.Context)
+		maps.Copy(merged, extra)
+		claims.Context = merged
+	}
+	return claims, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+func TestEnrichClaims(t *testing.T) {
+	tenant := ClaimsEnricherFunc(func(ctx context.Context, claims crypto.TokenClaims) (map[string]string, error) {
+		return map[string]string{"tenant": "acme-" + claims.Subject, "plan": "free"}, nil
+	})
+	plan := ClaimsEnricherFunc(func(ctx context.Context, claims crypto.TokenClaims) (map[string]string, error) {
+		return map[string]string{"plan": "pro"}, nil
+	})
+
+	claims, err := EnrichClaims(context.Background(), crypto.TokenClaims{Subject: "1"}, tenant, plan)
+	if err != nil {
+		t.Fatalf("EnrichClaims() error = %v", err)
+	}
+	if claims.Context["tenant"] != "acme-1" || claims.Context["plan"] != "pro" {
+		t.Errorf("Context = %v, want tenant acme-1 on plan pro", claims.Context)
+	}
+
+	failing := ClaimsEnricherFunc(func(ctx context.Context, claims crypto.TokenClaims) (map[string]string, error) {
+		return nil, errors.New("tenant store down")
+	})
+	if _, err := EnrichClaims(context.Background(), crypto.TokenClaims{}, tenant, failing); err == nil {
+		t.Error("EnrichClaims() with a failing enricher succeeded")
+	}
+}
+
+func TestDefaultTokenGeneratorWithClaimsEnrichers(t *testing.T) {
+	pubKey, privKey, _ := ed25519.GenerateKey(nil)
+	generator := NewDefaultTokenGenerator(privKey, time.Hour).WithClaimsEnrichers(
+		ClaimsEnricherFunc(func(ctx context.Context, claims crypto.TokenClaims) (map[string]string, error) {
+			return map[string]string{"tenant": "acme"}, nil
+		}),
+	)
+
+	token, err := generator.GenerateToken(auth.NewUserID())
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	claims, err := crypto.VerifyToken(token, pubKey)
+	if err != nil || claims.Context["tenant"] != "acme" {
+		t.Fatalf("claims = %+v, %v, want tenant acme", claims, err)
+	}
+
+	scoped, err := generator.GenerateScopedToken(crypto.TokenClaims{Subject: "1", Context: map[string]string{"tenant": "other"}}, []string{"a:b"}, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("GenerateScopedToken() error = %v", err)
+	}
+	derived, _ := crypto.VerifyToken(scoped, pubKey)
+	if derived.Context["tenant"] != "other" {
+		t.Errorf("scoped token tenant = %q, want the parent's", derived.Context["tenant"])
+	}
+
+	failing := NewDefaultTokenGenerator(privKey, time.Hour).WithClaimsEnrichers(
+		ClaimsEnricherFunc(func(ctx context.Context, claims crypto.TokenClaims) (map[string]string, error) {
+			return nil, errors.New("down")
+		}),
+	)
+	if _, err := failing.GenerateServiceToken("sa:worker", time.Hour); err == nil {
+		t.Error("GenerateServiceToken() with a failing enricher succeeded")
+	}
+}
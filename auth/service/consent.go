@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// AcceptPolicy records that userID accepted the current version of policy,
+// from remoteIP. A non-empty version must be the current one, so a client
+// cannot accept terms other than those it showed; an outdated version fails
+// with auth.ErrInvalidConsent. Accepting the same version again returns the
+// existing consent.
+func AcceptPolicy(ctx context.Context, store auth.ConsentStore, users auth.UserStore, policies auth.Policies, userID auth.UserID, policy, version, remoteIP string) (*auth.Consent, error) {
+	if store == nil {
+		return nil, fmt.Errorf("consent store is required")
+	}
+
+	current, err := currentPolicyVersion(policies, policy)
+	if err != nil {
+		return nil, err
+	}
+	if version != "" && version != current {
+		return nil, fmt.Errorf("%w: version %q of %s is not the current one", auth.ErrInvalidConsent, version, policy)
+	}
+
+	if _, err := users.Get(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	consent := auth.NewConsent(userID, policy, current)
+	consent.RemoteIP = remoteIP
+	if err := consent.Validate(); err != nil {
+		return nil, err
+	}
+
+	existing, err := store.Get(ctx, userID, consent.Policy, current)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, auth.ErrConsentNotFound) {
+		return nil, fmt.Errorf("lookup consent: %w", err)
+	}
+
+	consent.BeforeCreate()
+	if err := store.Create(ctx, consent); err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+// HasConsented reports whether userID accepted the current version of
+// policy.
+func HasConsented(ctx context.Context, store auth.ConsentStore, policies auth.Policies, userID auth.UserID, policy string) (bool, error) {
+	if store == nil {
+		return false, fmt.Errorf("consent store is required")
+	}
+
+	current, err := currentPolicyVersion(policies, policy)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = store.Get(ctx, userID, auth.NormalizePolicyName(policy), current)
+	if errors.Is(err, auth.ErrConsentNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListConsents returns every policy acceptance of userID, newest first.
+func ListConsents(ctx context.Context, store auth.ConsentStore, userID auth.UserID) ([]*auth.Consent, error) {
+	if store == nil {
+		return nil, fmt.Errorf("consent store is required")
+	}
+	return store.ListByUser(ctx, userID)
+}
+
+// PendingConsent returns the active users that have not accepted the
+// current version of policy.
+func PendingConsent(ctx context.Context, store auth.ConsentStore, users auth.UserStore, policies auth.Policies, policy string) ([]*auth.User, error) {
+	if store == nil {
+		return nil, fmt.Errorf("consent store is required")
+	}
+
+	current, err := currentPolicyVersion(policies, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted, err := store.ListAccepted(ctx, auth.NormalizePolicyName(policy), current)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[auth.UserID]bool, len(accepted))
+	for _, id := range accepted {
+		done[id] = true
+	}
+
+	active, err := users.ListByStatus(ctx, auth.UserStatusActive)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]*auth.User, 0)
+	for _, user := range active {
+		if !done[user.ID] {
+			pending = append(pending, user)
+		}
+	}
+	return pending, nil
+}
+
+func currentPolicyVersion(policies auth.Policies, policy string) (string, error) {
+	current, ok := policies.Current(policy)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", auth.ErrUnknownPolicy, policy)
+	}
+	return current, nil
+}
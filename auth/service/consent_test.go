@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestAcceptPolicy(t *testing.T) {
+	ctx := context.Background()
+	store := fake.NewConsentStore()
+	users := fake.NewUserStore()
+	crypto := fake.NewCryptoService()
+	policies := auth.Policies{"terms": "v2"}
+
+	user, err := SignUp(ctx, users, crypto, "consent@example.com", "Password123!", "consentuser", "Consent User")
+	if err != nil {
+		t.Fatalf("SignUp() error = %v", err)
+	}
+
+	consent, err := AcceptPolicy(ctx, store, users, policies, user.ID, "Terms", "", "203.0.113.5")
+	if err != nil {
+		t.Fatalf("AcceptPolicy() error = %v", err)
+	}
+	if consent.Policy != "terms" || consent.Version != "v2" || consent.RemoteIP != "203.0.113.5" {
+		t.Errorf("AcceptPolicy() = %+v", consent)
+	}
+
+	again, err := AcceptPolicy(ctx, store, users, policies, user.ID, "terms", "v2", "203.0.113.6")
+	if err != nil || again.ID != consent.ID {
+		t.Errorf("AcceptPolicy() again = %+v, %v, want the existing consent", again, err)
+	}
+
+	if _, err := AcceptPolicy(ctx, store, users, policies, user.ID, "terms", "v1", ""); !errors.Is(err, auth.ErrInvalidConsent) {
+		t.Errorf("AcceptPolicy(old version) error = %v, want ErrInvalidConsent", err)
+	}
+	if _, err := AcceptPolicy(ctx, store, users, policies, user.ID, "cookies", "", ""); !errors.Is(err, auth.ErrUnknownPolicy) {
+		t.Errorf("AcceptPolicy(unknown policy) error = %v, want ErrUnknownPolicy", err)
+	}
+	if _, err := AcceptPolicy(ctx, store, users, policies, auth.NewUserID(), "terms", "", ""); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("AcceptPolicy(unknown user) error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestHasConsentedAndPending(t *testing.T) {
+	ctx := context.Background()
+	store := fake.NewConsentStore()
+	users := fake.NewUserStore()
+	crypto := fake.NewCryptoService()
+	policies := auth.Policies{"terms": "v1"}
+
+	ann, _ := SignUp(ctx, users, crypto, "ann@example.com", "Password123!", "ann", "Ann")
+	bob, _ := SignUp(ctx, users, crypto, "bob@example.com", "Password123!", "bob", "Bob")
+
+	if ok, err := HasConsented(ctx, store, policies, ann.ID, "terms"); ok || err != nil {
+		t.Errorf("HasConsented() before accepting = %v, %v", ok, err)
+	}
+	AcceptPolicy(ctx, store, users, policies, ann.ID, "terms", "", "")
+	if ok, err := HasConsented(ctx, store, policies, ann.ID, "terms"); !ok || err != nil {
+		t.Errorf("HasConsented() after accepting = %v, %v", ok, err)
+	}
+
+	pending, err := PendingConsent(ctx, store, users, policies, "terms")
+	if err != nil || len(pending) != 1 || pending[0].ID != bob.ID {
+		t.Errorf("PendingConsent() = %v, %v, want only bob", pending, err)
+	}
+
+	// Publishing a new version makes everyone accept again.
+	policies["terms"] = "v2"
+	if ok, _ := HasConsented(ctx, store, policies, ann.ID, "terms"); ok {
+		t.Error("HasConsented() true for an outdated version")
+	}
+	if pending, _ := PendingConsent(ctx, store, users, policies, "terms"); len(pending) != 2 {
+		t.Errorf("PendingConsent() after new version = %d users, want 2", len(pending))
+	}
+	if consents, _ := ListConsents(ctx, store, ann.ID); len(consents) != 1 {
+		t.Errorf("ListConsents() = %d, want 1", len(consents))
+	}
+
+	if _, err := HasConsented(ctx, store, policies, ann.ID, "cookies"); !errors.Is(err, auth.ErrUnknownPolicy) {
+		t.Errorf("HasConsented(unknown) error = %v", err)
+	}
+	if _, err := PendingConsent(ctx, store, users, policies, "cookies"); !errors.Is(err, auth.ErrUnknownPolicy) {
+		t.Errorf("PendingConsent(unknown) error = %v", err)
+	}
+}
@@ -1,26 +1,88 @@
 package service
 
 import (
+	"context"
 	"crypto/ed25519"
 	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"math/big"
 	"time"
 
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/config"
 	"github.com/aquamarinepk/aqm/crypto"
+	"github.com/aquamarinepk/aqm/validation"
 	"github.com/google/uuid"
 )
 
 // DefaultCryptoService implements CryptoService using aqm crypto primitives
 type DefaultCryptoService struct {
-	encryptionKey []byte
-	signingKey    []byte
+	encryptionKey  []byte
+	signingKey     []byte
+	passwordParams crypto.PasswordParams
+	keyScheme      auth.KeyScheme
 }
 
 func NewDefaultCryptoService(encryptionKey, signingKey []byte) *DefaultCryptoService {
 	return &DefaultCryptoService{
-		encryptionKey: encryptionKey,
-		signingKey:    signingKey,
+		encryptionKey:  encryptionKey,
+		signingKey:     signingKey,
+		passwordParams: crypto.DefaultPasswordParams(),
+	}
+}
+
+// WithPasswordParams sets the password hashing parameters. Users whose
+// stored hash uses other parameters are rehashed on their next sign-in.
+func (s *DefaultCryptoService) WithPasswordParams(params crypto.PasswordParams) *DefaultCryptoService {
+	s.passwordParams = params
+	return s
+}
+
+// WithDerivedKeys makes new users' email and PIN encrypted with a key
+// derived from the encryption key and their ID, so a single leaked record
+// key does not decrypt the others. Existing users are re-encrypted on their
+// next sign-in or with MigrateUserKeys. Disabling it again only affects new
+// users; users already on derived keys keep them.
+func (s *DefaultCryptoService) WithDerivedKeys(enabled bool) *DefaultCryptoService {
+	s.keyScheme = auth.KeySchemeMaster
+	if enabled {
+		s.keyScheme = auth.KeySchemeDerived
+	}
+	return s
+}
+
+func init() {
+	config.RegisterValidator("auth", validateAuthKeys)
+}
+
+// validateAuthKeys checks that the keys of the auth section fit the
+// primitives they are used with.
+func validateAuthKeys(cfg *config.Config) validation.ValidationErrors {
+	var errs validation.ValidationErrors
+	if len(cfg.Auth.EncryptionKey) != 32 {
+		errs.Add("encryption_key", fmt.Sprintf("must be 32 bytes, got %d", len(cfg.Auth.EncryptionKey)))
+	}
+	if len(cfg.Auth.SigningKey) < 32 {
+		errs.Add("signing_key", fmt.Sprintf("must be at least 32 bytes, got %d", len(cfg.Auth.SigningKey)))
+	}
+	if k := cfg.Auth.TokenPrivateKey; k != "" {
+		if raw, err := base64.StdEncoding.DecodeString(k); err != nil || len(raw) != ed25519.PrivateKeySize {
+			errs.Add("token_private_key", fmt.Sprintf("must be a base64 Ed25519 private key of %d bytes", ed25519.PrivateKeySize))
+		}
+	}
+	return errs
+}
+
+// PasswordParamsFromConfig converts the auth.password config section, as
+// checked by config.Validate, to hashing parameters.
+func PasswordParamsFromConfig(cfg config.PasswordConfig) crypto.PasswordParams {
+	return crypto.PasswordParams{
+		Algorithm:   cfg.Algorithm,
+		Memory:      uint32(cfg.Memory),
+		Iterations:  uint32(cfg.Iterations),
+		Parallelism: uint8(cfg.Parallelism),
+		Cost:        cfg.Cost,
 	}
 }
 
@@ -42,10 +104,19 @@ func (s *DefaultCryptoService) ComputePINLookupHash(pin string) []byte {
 	return []byte(hash)
 }
 
+func (s *DefaultCryptoService) PasswordParams() crypto.PasswordParams {
+	return s.passwordParams
+}
+
+func (s *DefaultCryptoService) KeyScheme() auth.KeyScheme {
+	return s.keyScheme
+}
+
 // DefaultTokenGenerator implements TokenGenerator using PASETO v4
 type DefaultTokenGenerator struct {
 	privateKey ed25519.PrivateKey
 	ttl        time.Duration
+	enrichers  []ClaimsEnricher
 }
 
 func NewDefaultTokenGenerator(privateKey ed25519.PrivateKey, ttl time.Duration) *DefaultTokenGenerator {
@@ -55,16 +126,113 @@ func NewDefaultTokenGenerator(privateKey ed25519.PrivateKey, ttl time.Duration)
 	}
 }
 
-func (g *DefaultTokenGenerator) GenerateToken(userID uuid.UUID) (string, error) {
-	sessionID := crypto.GenerateSessionID()
+// WithClaimsEnrichers adds the custom claims of enrichers to every token
+// issued, except scoped ones, which keep those of their parent. Token
+// generation has no request context, so enrichers are run with
+// context.Background().
+func (g *DefaultTokenGenerator) WithClaimsEnrichers(enrichers ...ClaimsEnricher) *DefaultTokenGenerator {
+	g.enrichers = append(g.enrichers, enrichers...)
+	return g
+}
+
+// TTL returns the lifetime of the session tokens issued by GenerateToken.
+func (g *DefaultTokenGenerator) TTL() time.Duration {
+	return g.ttl
+}
+
+func (g *DefaultTokenGenerator) GenerateToken(userID auth.UserID) (string, error) {
+	return g.GenerateTokenWithTTL(userID, g.ttl)
+}
+
+// GenerateTokenWithTTL issues a session token for userID that expires after
+// ttl instead of the generator's own TTL.
+func (g *DefaultTokenGenerator) GenerateTokenWithTTL(userID auth.UserID, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := crypto.TokenClaims{
+		Subject:      userID.String(),
+		SessionID:    crypto.GenerateSessionID(),
+		ExpiresAt:    now.Add(ttl).Unix(),
+		IssuedAt:     now.Unix(),
+		IssuedAtNsec: int64(now.Nanosecond()),
+	}
+	return g.generate(claims, "token", true)
+}
+
+// GenerateImpersonationToken issues a token for actor acting as userID that
+// expires after ttl, independently of the generator's own TTL.
+func (g *DefaultTokenGenerator) GenerateImpersonationToken(userID auth.UserID, actor string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := crypto.TokenClaims{
+		Subject:      userID.String(),
+		SessionID:    crypto.GenerateSessionID(),
+		ExpiresAt:    now.Add(ttl).Unix(),
+		IssuedAt:     now.Unix(),
+		IssuedAtNsec: int64(now.Nanosecond()),
+		Actor:        actor,
+	}
+	return g.generate(claims, "impersonation token", true)
+}
+
+// GenerateMFAPendingToken issues an MFA-pending token for userID that
+// expires after ttl. It carries no enriched claims, as it grants nothing
+// until the second factor verifies.
+func (g *DefaultTokenGenerator) GenerateMFAPendingToken(userID auth.UserID, ttl time.Duration) (string, error) {
+	now := time.Now()
 	claims := crypto.TokenClaims{
-		Subject:   userID.String(),
-		SessionID: sessionID,
-		ExpiresAt: time.Now().Add(g.ttl).Unix(),
+		Subject:      userID.String(),
+		SessionID:    crypto.GenerateSessionID(),
+		ExpiresAt:    now.Add(ttl).Unix(),
+		IssuedAt:     now.Unix(),
+		IssuedAtNsec: int64(now.Nanosecond()),
+		MFAPending:   true,
+	}
+	return g.generate(claims, "MFA-pending token", false)
+}
+
+// GenerateServiceToken issues a token for a service account principal that
+// expires after ttl.
+func (g *DefaultTokenGenerator) GenerateServiceToken(subject string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := crypto.TokenClaims{
+		Subject:      subject,
+		SessionID:    crypto.GenerateSessionID(),
+		ExpiresAt:    now.Add(ttl).Unix(),
+		IssuedAt:     now.Unix(),
+		IssuedAtNsec: int64(now.Nanosecond()),
+	}
+	return g.generate(claims, "service token", true)
+}
+
+// GenerateScopedToken issues a token derived from parent, for the same
+// subject, session and actor, limited to scope and expiring at expiresAt.
+func (g *DefaultTokenGenerator) GenerateScopedToken(parent crypto.TokenClaims, scope []string, expiresAt time.Time) (string, error) {
+	now := time.Now()
+	claims := crypto.TokenClaims{
+		Subject:      parent.Subject,
+		SessionID:    parent.SessionID,
+		Context:      parent.Context,
+		ExpiresAt:    expiresAt.Unix(),
+		IssuedAt:     now.Unix(),
+		IssuedAtNsec: int64(now.Nanosecond()),
+		AuthzVersion: parent.AuthzVersion,
+		Actor:        parent.Actor,
+		Scope:        scope,
+	}
+	return g.generate(claims, "scoped token", false)
+}
+
+// generate signs claims, enriched first when enrich is set. kind names the
+// token in errors.
+func (g *DefaultTokenGenerator) generate(claims crypto.TokenClaims, kind string, enrich bool) (string, error) {
+	if enrich {
+		var err error
+		if claims, err = EnrichClaims(context.Background(), claims, g.enrichers...); err != nil {
+			return "", fmt.Errorf("generate %s: %w", kind, err)
+		}
 	}
 	token, err := crypto.GenerateToken(claims, g.privateKey)
 	if err != nil {
-		return "", fmt.Errorf("generate token: %w", err)
+		return "", fmt.Errorf("generate %s: %w", kind, err)
 	}
 	return token, nil
 }
@@ -2,10 +2,15 @@ package service
 
 import (
 	"crypto/ed25519"
+	"errors"
 	"testing"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/crypto"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/validation"
 )
 
 func TestNewDefaultCryptoService(t *testing.T) {
@@ -26,6 +31,19 @@ func TestNewDefaultCryptoService(t *testing.T) {
 	}
 }
 
+func TestDefaultCryptoServiceWithDerivedKeys(t *testing.T) {
+	service := NewDefaultCryptoService(make([]byte, 32), make([]byte, 32))
+	if service.KeyScheme() != auth.KeySchemeMaster {
+		t.Errorf("KeyScheme() = %v, want %v", service.KeyScheme(), auth.KeySchemeMaster)
+	}
+	if service.WithDerivedKeys(true).KeyScheme() != auth.KeySchemeDerived {
+		t.Errorf("KeyScheme() with derived keys = %v, want %v", service.KeyScheme(), auth.KeySchemeDerived)
+	}
+	if service.WithDerivedKeys(false).KeyScheme() != auth.KeySchemeMaster {
+		t.Errorf("KeyScheme() after disabling = %v, want %v", service.KeyScheme(), auth.KeySchemeMaster)
+	}
+}
+
 func TestDefaultCryptoServiceComputeLookupHash(t *testing.T) {
 	encKey := make([]byte, 32)
 	sigKey := make([]byte, 32)
@@ -69,11 +87,11 @@ func TestNewDefaultTokenGenerator(t *testing.T) {
 }
 
 func TestDefaultTokenGeneratorGenerateToken(t *testing.T) {
-	_, privKey, _ := ed25519.GenerateKey(nil)
+	pubKey, privKey, _ := ed25519.GenerateKey(nil)
 	ttl := time.Hour
 	generator := NewDefaultTokenGenerator(privKey, ttl)
 
-	token, err := generator.GenerateToken(uuid.New())
+	token, err := generator.GenerateToken(auth.NewUserID())
 	if err != nil {
 		t.Fatalf("GenerateToken() error = %v", err)
 	}
@@ -82,10 +100,106 @@ func TestDefaultTokenGeneratorGenerateToken(t *testing.T) {
 		t.Error("GenerateToken() returned empty token")
 	}
 
-	token2, _ := generator.GenerateToken(uuid.New())
+	token2, _ := generator.GenerateToken(auth.NewUserID())
 	if token == token2 {
 		t.Error("GenerateToken() should generate unique tokens")
 	}
+
+	claims, err := crypto.VerifyToken(token, pubKey)
+	if err != nil || time.Since(time.Unix(claims.IssuedAt, 0)) > time.Minute {
+		t.Errorf("GenerateToken() claims = %+v, %v, want issued now", claims, err)
+	}
+}
+
+func TestDefaultTokenGeneratorGenerateImpersonationToken(t *testing.T) {
+	pubKey, privKey, _ := ed25519.GenerateKey(nil)
+	generator := NewDefaultTokenGenerator(privKey, 24*time.Hour)
+	userID := auth.NewUserID()
+
+	token, err := generator.GenerateImpersonationToken(userID, "admin-1", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateImpersonationToken() error = %v", err)
+	}
+
+	claims, err := crypto.VerifyToken(token, pubKey)
+	if err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	if claims.Subject != userID.String() || claims.Actor != "admin-1" {
+		t.Errorf("claims = %+v, want subject %v acting as admin-1", claims, userID)
+	}
+	if ttl := time.Until(time.Unix(claims.ExpiresAt, 0)); ttl > 10*time.Minute {
+		t.Errorf("token expires in %v, want at most 10m", ttl)
+	}
+}
+
+func TestDefaultTokenGeneratorGenerateMFAPendingToken(t *testing.T) {
+	pubKey, privKey, _ := ed25519.GenerateKey(nil)
+	generator := NewDefaultTokenGenerator(privKey, 24*time.Hour)
+	userID := auth.NewUserID()
+
+	token, err := generator.GenerateMFAPendingToken(userID, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateMFAPendingToken() error = %v", err)
+	}
+
+	claims, err := crypto.VerifyToken(token, pubKey)
+	if err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	if claims.Subject != userID.String() || !claims.MFAPending {
+		t.Errorf("claims = %+v, want an MFA-pending token for %v", claims, userID)
+	}
+	if ttl := time.Until(time.Unix(claims.ExpiresAt, 0)); ttl > 5*time.Minute {
+		t.Errorf("token expires in %v, want at most 5m", ttl)
+	}
+}
+
+func TestDefaultTokenGeneratorGenerateScopedToken(t *testing.T) {
+	pubKey, privKey, _ := ed25519.GenerateKey(nil)
+	generator := NewDefaultTokenGenerator(privKey, 24*time.Hour)
+	parent := crypto.TokenClaims{Subject: "user-1", SessionID: "session-1", Actor: "admin-1", AuthzVersion: 3}
+	expiresAt := time.Now().Add(5 * time.Minute)
+
+	token, err := generator.GenerateScopedToken(parent, []string{"orders:read"}, expiresAt)
+	if err != nil {
+		t.Fatalf("GenerateScopedToken() error = %v", err)
+	}
+
+	claims, err := crypto.VerifyToken(token, pubKey)
+	if err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	if claims.Subject != "user-1" || claims.SessionID != "session-1" || claims.Actor != "admin-1" || claims.AuthzVersion != 3 {
+		t.Errorf("claims = %+v, want those of the parent", claims)
+	}
+	if len(claims.Scope) != 1 || claims.Scope[0] != "orders:read" {
+		t.Errorf("Scope = %v, want [orders:read]", claims.Scope)
+	}
+	if claims.ExpiresAt != expiresAt.Unix() || claims.IssuedAt == 0 {
+		t.Errorf("ExpiresAt, IssuedAt = %d, %d, want %d and set", claims.ExpiresAt, claims.IssuedAt, expiresAt.Unix())
+	}
+}
+
+func TestDefaultTokenGeneratorGenerateServiceToken(t *testing.T) {
+	pubKey, privKey, _ := ed25519.GenerateKey(nil)
+	generator := NewDefaultTokenGenerator(privKey, 24*time.Hour)
+
+	token, err := generator.GenerateServiceToken("sa:worker", time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateServiceToken() error = %v", err)
+	}
+
+	claims, err := crypto.VerifyToken(token, pubKey)
+	if err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	if claims.Subject != "sa:worker" || claims.Actor != "" {
+		t.Errorf("claims = %+v, want subject sa:worker", claims)
+	}
+	if ttl := time.Until(time.Unix(claims.ExpiresAt, 0)); ttl > time.Hour {
+		t.Errorf("token expires in %v, want at most 1h", ttl)
+	}
 }
 
 func TestNewDefaultPasswordGenerator(t *testing.T) {
@@ -142,3 +256,24 @@ func TestDefaultPINGeneratorGeneratePIN(t *testing.T) {
 		t.Error("GeneratePIN() should generate different PINs (note: small chance of collision)")
 	}
 }
+
+func TestAuthKeysValidator(t *testing.T) {
+	_, err := config.New(log.NewNoopLogger(), config.WithDefaults(map[string]interface{}{
+		"auth.encryption_key":    "short",
+		"auth.signing_key":       "also-short",
+		"auth.token_private_key": "bm90IGEga2V5",
+	}))
+	var errs validation.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("config.New() error = %v, want validation errors", err)
+	}
+	for _, field := range []string{"auth.encryption_key", "auth.signing_key", "auth.token_private_key"} {
+		if len(errs.ForField(field)) == 0 {
+			t.Errorf("errors = %v, want one for %s", errs, field)
+		}
+	}
+
+	if _, err := config.New(log.NewNoopLogger()); err != nil {
+		t.Errorf("config.New() with default keys error = %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// DeviceSighting describes the device a request comes from: the
+// fingerprint and name reported by the client, and the address, user agent
+// and, when the client was located, country code and city seen by the
+// server.
+type DeviceSighting struct {
+	Fingerprint string
+	Name        string
+	IP          string
+	UserAgent   string
+	Country     string
+	City        string
+}
+
+func (s DeviceSighting) touch(device *auth.Device, at time.Time) {
+	device.Touch(s.IP, s.UserAgent, at)
+	device.LastSeenCountry, device.LastSeenCity = s.Country, s.City
+}
+
+// RegisterDevice records that userID signed in from the device in seen,
+// creating it on first sight and updating when and where it was last seen
+// otherwise. A non-empty name renames a known device.
+func RegisterDevice(ctx context.Context, store auth.DeviceStore, userID auth.UserID, seen DeviceSighting) (*auth.Device, error) {
+	if store == nil {
+		return nil, fmt.Errorf("device store is required")
+	}
+
+	fingerprint := strings.TrimSpace(seen.Fingerprint)
+	if err := auth.ValidateDeviceFingerprint(fingerprint); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	device, err := store.GetByFingerprint(ctx, userID, fingerprint)
+	if err == auth.ErrDeviceNotFound {
+		device = auth.NewDevice(userID, fingerprint, seen.Name)
+		seen.touch(device, now)
+		if err := device.Validate(); err != nil {
+			return nil, err
+		}
+		device.BeforeCreate()
+		if err := store.Create(ctx, device); err != nil {
+			return nil, err
+		}
+		return device, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lookup device: %w", err)
+	}
+
+	if name := auth.NormalizeDeviceName(seen.Name); name != "" {
+		device.Name = name
+	}
+	seen.touch(device, now)
+	if err := device.Validate(); err != nil {
+		return nil, err
+	}
+	if err := store.Update(ctx, device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// TrustDevice marks device as trusted for ttl and returns its
+// trusted-device token, which replaces any earlier one. The token is only
+// returned here; clients keep it and present it to VerifyTrustedDevice.
+func TrustDevice(ctx context.Context, store auth.DeviceStore, device *auth.Device, ttl time.Duration) (string, error) {
+	if store == nil {
+		return "", fmt.Errorf("device store is required")
+	}
+	if device == nil {
+		return "", fmt.Errorf("device is required")
+	}
+
+	secret, err := device.Trust(ttl, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("generate device token: %w", err)
+	}
+	if err := store.Update(ctx, device); err != nil {
+		return "", err
+	}
+	return device.ID.String() + "." + secret, nil
+}
+
+// VerifyTrustedDevice returns the device a trusted-device token from
+// TrustDevice belongs to. It fails with auth.ErrUntrustedDevice when the
+// token is malformed, belongs to another user, was replaced or revoked, or
+// has expired.
+func VerifyTrustedDevice(ctx context.Context, store auth.DeviceStore, userID auth.UserID, token string) (*auth.Device, error) {
+	if store == nil {
+		return nil, fmt.Errorf("device store is required")
+	}
+
+	idStr, secret, ok := strings.Cut(token, ".")
+	if !ok || secret == "" {
+		return nil, auth.ErrUntrustedDevice
+	}
+	id, err := auth.ParseDeviceID(idStr)
+	if err != nil {
+		return nil, auth.ErrUntrustedDevice
+	}
+
+	device, err := store.Get(ctx, id)
+	if err == auth.ErrDeviceNotFound {
+		return nil, auth.ErrUntrustedDevice
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lookup device: %w", err)
+	}
+	if device.UserID != userID || !device.VerifyTrustToken(secret, time.Now()) {
+		return nil, auth.ErrUntrustedDevice
+	}
+	return device, nil
+}
+
+// ListDevices returns the user's devices, most recently seen first.
+func ListDevices(ctx context.Context, store auth.DeviceStore, userID auth.UserID) ([]*auth.Device, error) {
+	if store == nil {
+		return nil, fmt.Errorf("device store is required")
+	}
+	return store.ListByUser(ctx, userID)
+}
+
+// RevokeDevice forgets one of the user's devices, invalidating its
+// trusted-device token. Devices of other users are reported as not found.
+func RevokeDevice(ctx context.Context, store auth.DeviceStore, userID auth.UserID, id auth.DeviceID) error {
+	if store == nil {
+		return fmt.Errorf("device store is required")
+	}
+
+	device, err := store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if device.UserID != userID {
+		return auth.ErrDeviceNotFound
+	}
+	return store.Delete(ctx, id)
+}
+
+// RevokeDevices forgets all of the user's devices.
+func RevokeDevices(ctx context.Context, store auth.DeviceStore, userID auth.UserID) error {
+	if store == nil {
+		return fmt.Errorf("device store is required")
+	}
+	return store.DeleteByUser(ctx, userID)
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestRegisterDevice(t *testing.T) {
+	ctx := context.Background()
+	store := fake.NewDeviceStore()
+	userID := auth.NewUserID()
+
+	device, err := RegisterDevice(ctx, store, userID, DeviceSighting{Fingerprint: " fp-1 ", Name: "Laptop", IP: "203.0.113.5", UserAgent: "Firefox", Country: "DE", City: "Berlin"})
+	if err != nil {
+		t.Fatalf("RegisterDevice() error = %v", err)
+	}
+	if device.Fingerprint != "fp-1" || device.Name != "Laptop" || device.LastSeenIP != "203.0.113.5" || device.LastSeenCountry != "DE" || device.LastSeenCity != "Berlin" {
+		t.Errorf("RegisterDevice() = %+v", device)
+	}
+
+	again, err := RegisterDevice(ctx, store, userID, DeviceSighting{Fingerprint: "fp-1", IP: "203.0.113.6"})
+	if err != nil {
+		t.Fatalf("RegisterDevice() again error = %v", err)
+	}
+	if again.ID != device.ID || again.Name != "Laptop" || again.LastSeenIP != "203.0.113.6" || again.LastSeenCountry != "" {
+		t.Errorf("RegisterDevice() again = %+v, want the same device seen from the new IP", again)
+	}
+
+	if _, err := RegisterDevice(ctx, store, userID, DeviceSighting{Fingerprint: ""}); !errors.Is(err, auth.ErrInvalidDevice) {
+		t.Errorf("RegisterDevice() without fingerprint error = %v, want ErrInvalidDevice", err)
+	}
+	if devices, _ := ListDevices(ctx, store, userID); len(devices) != 1 {
+		t.Errorf("ListDevices() = %d devices, want 1", len(devices))
+	}
+}
+
+func TestTrustedDevice(t *testing.T) {
+	ctx := context.Background()
+	store := fake.NewDeviceStore()
+	ann, bob := auth.NewUserID(), auth.NewUserID()
+
+	device, _ := RegisterDevice(ctx, store, ann, DeviceSighting{Fingerprint: "fp-1"})
+	token, err := TrustDevice(ctx, store, device, time.Hour)
+	if err != nil {
+		t.Fatalf("TrustDevice() error = %v", err)
+	}
+
+	got, err := VerifyTrustedDevice(ctx, store, ann, token)
+	if err != nil || got.ID != device.ID {
+		t.Fatalf("VerifyTrustedDevice() = %v, %v", got, err)
+	}
+
+	tests := []struct {
+		name   string
+		userID auth.UserID
+		token  string
+	}{
+		{name: "other user", userID: bob, token: token},
+		{name: "malformed", userID: ann, token: "nope"},
+		{name: "bad device ID", userID: ann, token: "nope.secret"},
+		{name: "unknown device", userID: ann, token: auth.NewDeviceID().String() + ".secret"},
+		{name: "wrong secret", userID: ann, token: device.ID.String() + ".secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := VerifyTrustedDevice(ctx, store, tt.userID, tt.token); !errors.Is(err, auth.ErrUntrustedDevice) {
+				t.Errorf("VerifyTrustedDevice() error = %v, want ErrUntrustedDevice", err)
+			}
+		})
+	}
+
+	if err := RevokeDevice(ctx, store, bob, device.ID); !errors.Is(err, auth.ErrDeviceNotFound) {
+		t.Errorf("RevokeDevice() by another user error = %v, want ErrDeviceNotFound", err)
+	}
+	if err := RevokeDevice(ctx, store, ann, device.ID); err != nil {
+		t.Fatalf("RevokeDevice() error = %v", err)
+	}
+	if _, err := VerifyTrustedDevice(ctx, store, ann, token); !errors.Is(err, auth.ErrUntrustedDevice) {
+		t.Errorf("VerifyTrustedDevice() after revoke error = %v, want ErrUntrustedDevice", err)
+	}
+
+	RegisterDevice(ctx, store, ann, DeviceSighting{Fingerprint: "fp-2"})
+	if err := RevokeDevices(ctx, store, ann); err != nil {
+		t.Fatalf("RevokeDevices() error = %v", err)
+	}
+	if devices, _ := ListDevices(ctx, store, ann); len(devices) != 0 {
+		t.Errorf("ListDevices() after RevokeDevices = %v", devices)
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// EffectivePermissions lists the permissions username holds through the
+// active roles of their grants, sorted by permission, each with its
+// sources, oldest grant first. Roles neither nest nor are granted to
+// groups, so a permission comes either from a role itself or, when sets is
+// not nil, from one of the permission sets it references. With registry,
+// wildcards are also expanded into the registered permissions they match.
+func EffectivePermissions(ctx context.Context, store auth.GrantStore, sets auth.PermissionSetStore, registry *auth.PermissionRegistry, username string) ([]auth.EffectivePermission, error) {
+	if store == nil {
+		return nil, fmt.Errorf("grant store is required")
+	}
+
+	grants, err := store.GetUserGrants(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("get user grants: %w", err)
+	}
+	roles, err := store.GetUserRoles(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("get user roles: %w", err)
+	}
+	byID := make(map[auth.RoleID]*auth.Role, len(roles))
+	for _, role := range roles {
+		byID[role.ID] = role
+	}
+
+	sort.Slice(grants, func(i, j int) bool {
+		return grants[i].AssignedAt.Before(grants[j].AssignedAt)
+	})
+
+	var registered []auth.PermissionDef
+	if registry != nil {
+		registered = registry.List()
+	}
+
+	byPermission := make(map[string]*auth.EffectivePermission)
+	add := func(permission string, src auth.PermissionSource) {
+		ep, ok := byPermission[permission]
+		if !ok {
+			ep = &auth.EffectivePermission{Permission: permission}
+			byPermission[permission] = ep
+		}
+		ep.Sources = append(ep.Sources, src)
+	}
+	grant := func(permission string, src auth.PermissionSource) {
+		add(permission, src)
+		if !strings.Contains(permission, "*") {
+			return
+		}
+		for _, def := range registered {
+			if auth.Permission(permission).Matches(auth.Permission(def.Name)) {
+				expanded := src
+				expanded.Wildcard = permission
+				add(def.Name, expanded)
+			}
+		}
+	}
+
+	for _, g := range grants {
+		role, ok := byID[g.RoleID]
+		if !ok || role.Status != auth.RoleStatusActive {
+			continue
+		}
+		src := auth.PermissionSource{GrantID: g.ID, RoleID: role.ID, Role: role.Name}
+
+		if sets == nil {
+			for _, p := range role.GrantedPermissions() {
+				grant(p, src)
+			}
+			continue
+		}
+		for _, p := range role.Permissions {
+			grant(p, src)
+		}
+		for _, name := range role.PermissionSets {
+			set, err := sets.Get(ctx, name)
+			if errors.Is(err, auth.ErrPermissionSetNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("get permission set %s: %w", name, err)
+			}
+			fromSet := src
+			fromSet.PermissionSet = set.Name
+			for _, p := range set.Permissions {
+				grant(p, fromSet)
+			}
+		}
+	}
+
+	permissions := make([]auth.EffectivePermission, 0, len(byPermission))
+	for _, ep := range byPermission {
+		permissions = append(permissions, *ep)
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i].Permission < permissions[j].Permission
+	})
+	return permissions, nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestEffectivePermissions(t *testing.T) {
+	ctx := context.Background()
+	sets := fake.NewPermissionSetStore()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+	registry := auth.NewPermissionRegistry().MustRegister(
+		auth.PermissionDef{Name: "posts:read"},
+		auth.PermissionDef{Name: "posts:write"},
+		auth.PermissionDef{Name: "users:read"},
+	)
+
+	CreatePermissionSet(ctx, sets, "reader-set", "", []string{"posts:read"}, "admin")
+	writer, _ := CreateRoleWithSets(ctx, roles, sets, "writer", "", []string{"posts:*"}, []string{"reader-set"}, "admin")
+	reader, _ := CreateRole(ctx, roles, "reader", "", []string{"posts:read"}, "admin")
+	retired, _ := CreateRole(ctx, roles, "retired", "", []string{"users:read"}, "admin")
+	writerGrant, _ := AssignRole(ctx, grants, "ann", writer.ID, "admin")
+	AssignRole(ctx, grants, "ann", reader.ID, "admin")
+	AssignRole(ctx, grants, "ann", retired.ID, "admin")
+	DeleteRole(ctx, roles, retired.ID)
+
+	got, err := EffectivePermissions(ctx, grants, sets, registry, "ann")
+	if err != nil {
+		t.Fatalf("EffectivePermissions() error = %v", err)
+	}
+	sources := make(map[string][]auth.PermissionSource)
+	var order []string
+	for _, ep := range got {
+		order = append(order, ep.Permission)
+		sources[ep.Permission] = ep.Sources
+	}
+	if want := []string{"posts:*", "posts:read", "posts:write"}; !slices.Equal(order, want) {
+		t.Fatalf("EffectivePermissions() = %v, want %v", order, want)
+	}
+
+	read := sources["posts:read"]
+	if len(read) != 3 {
+		t.Fatalf("posts:read sources = %+v, want 3", read)
+	}
+	if read[0].Role != "writer" || read[0].GrantID != writerGrant.ID || read[0].Wildcard != "posts:*" {
+		t.Errorf("wildcard source = %+v", read[0])
+	}
+	if read[1].Role != "writer" || read[1].PermissionSet != "reader-set" || read[1].Wildcard != "" {
+		t.Errorf("permission set source = %+v", read[1])
+	}
+	if read[2].Role != "reader" || read[2].PermissionSet != "" {
+		t.Errorf("direct source = %+v", read[2])
+	}
+	if write := sources["posts:write"]; len(write) != 1 || write[0].Wildcard != "posts:*" {
+		t.Errorf("posts:write sources = %+v", write)
+	}
+
+	plain, err := EffectivePermissions(ctx, grants, nil, nil, "ann")
+	if err != nil {
+		t.Fatalf("EffectivePermissions() without sets error = %v", err)
+	}
+	if len(plain) != 2 || plain[0].Permission != "posts:*" || len(plain[1].Sources) != 2 {
+		t.Errorf("EffectivePermissions() without sets and registry = %+v", plain)
+	}
+}
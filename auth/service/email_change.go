@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// Defaults used by NewEmailChanges for zero durations.
+const (
+	DefaultEmailChangeTTL    = 24 * time.Hour
+	DefaultEmailRevertWindow = 7 * 24 * time.Hour
+)
+
+// Purposes of email change tokens, so a token cannot be used for the other
+// step.
+const (
+	emailChangeVerify = "verify"
+	emailChangeRevert = "revert"
+)
+
+// EmailChanges issues and redeems the tokens of email changes. A change is
+// confirmed with a token sent to the new address and can be undone within
+// the revert window with a token sent to the old one. Like MagicLinks,
+// tokens are signed with HMAC-SHA256 and single use through the NonceStore.
+// Each token carries the address it is sent to.
+type EmailChanges struct {
+	key          []byte
+	nonces       auth.NonceStore
+	ttl          time.Duration
+	revertWindow time.Duration
+	now          func() time.Time
+}
+
+type emailChangeClaims struct {
+	UserID    string `json:"u"`
+	Email     string `json:"a"`
+	Purpose   string `json:"p"`
+	Nonce     string `json:"n"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// NewEmailChanges creates tokens signed with key whose nonces are kept in
+// nonces. Verification tokens last ttl, DefaultEmailChangeTTL when zero,
+// and revert tokens revertWindow, DefaultEmailRevertWindow when zero.
+func NewEmailChanges(key []byte, nonces auth.NonceStore, ttl, revertWindow time.Duration) *EmailChanges {
+	if ttl <= 0 {
+		ttl = DefaultEmailChangeTTL
+	}
+	if revertWindow <= 0 {
+		revertWindow = DefaultEmailRevertWindow
+	}
+	return &EmailChanges{key: key, nonces: nonces, ttl: ttl, revertWindow: revertWindow, now: time.Now}
+}
+
+// TTL returns how long verification tokens stay valid.
+func (c *EmailChanges) TTL() time.Duration {
+	return c.ttl
+}
+
+// RevertWindow returns how long a confirmed change can be reverted.
+func (c *EmailChanges) RevertWindow() time.Duration {
+	return c.revertWindow
+}
+
+func (c *EmailChanges) issue(purpose string, userID auth.UserID, email string, ttl time.Duration) (string, error) {
+	nonce := make([]byte, 24)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate email change nonce: %w", err)
+	}
+
+	data, err := json.Marshal(emailChangeClaims{
+		UserID:    userID.String(),
+		Email:     email,
+		Purpose:   purpose,
+		Nonce:     base64.RawURLEncoding.EncodeToString(nonce),
+		ExpiresAt: c.now().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + c.sign(payload), nil
+}
+
+// redeem checks a token issued for purpose and consumes its nonce,
+// returning the user and address it carries. Tampered, expired and already
+// redeemed tokens fail with auth.ErrInvalidEmailChange.
+func (c *EmailChanges) redeem(ctx context.Context, purpose, token string) (auth.UserID, string, error) {
+	var userID auth.UserID
+
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(c.sign(payload))) {
+		return userID, "", auth.ErrInvalidEmailChange
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return userID, "", auth.ErrInvalidEmailChange
+	}
+	var claims emailChangeClaims
+	if err := json.Unmarshal(data, &claims); err != nil || claims.Nonce == "" || claims.Purpose != purpose {
+		return userID, "", auth.ErrInvalidEmailChange
+	}
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+	if !c.now().Before(expiresAt) {
+		return userID, "", auth.ErrInvalidEmailChange
+	}
+	userID, err = auth.ParseUserID(claims.UserID)
+	if err != nil {
+		return userID, "", auth.ErrInvalidEmailChange
+	}
+
+	first, err := c.nonces.Use(ctx, claims.Nonce, expiresAt)
+	if err != nil {
+		return userID, "", fmt.Errorf("consume email change: %w", err)
+	}
+	if !first {
+		return userID, "", auth.ErrInvalidEmailChange
+	}
+	return userID, claims.Email, nil
+}
+
+func (c *EmailChanges) sign(payload string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte("email-change:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// RequestEmailChange checks that email can replace the address of the user
+// with userID and returns the normalized address and a token confirming the
+// change, to be sent to that address. The stored address is unchanged
+// until ConfirmEmailChange. Addresses of any user, including this one, fail
+// with auth.ErrEmailInUse.
+func RequestEmailChange(ctx context.Context, store auth.UserStore, crypto CryptoService, changes *EmailChanges, userID auth.UserID, email string) (*auth.User, string, string, error) {
+	if store == nil {
+		return nil, "", "", fmt.Errorf("user store is required")
+	}
+	if crypto == nil {
+		return nil, "", "", fmt.Errorf("crypto service is required")
+	}
+	if changes == nil {
+		return nil, "", "", fmt.Errorf("email changes are required")
+	}
+
+	email = auth.NormalizeEmail(email)
+	if err := auth.ValidateEmail(email); err != nil {
+		return nil, "", "", err
+	}
+
+	user, err := store.Get(ctx, userID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if err := checkEmailFree(ctx, store, crypto, email); err != nil {
+		return nil, "", "", err
+	}
+
+	token, err := changes.issue(emailChangeVerify, user.ID, email, changes.ttl)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return user, email, token, nil
+}
+
+// ConfirmEmailChange redeems a token from RequestEmailChange and stores the
+// new address, whose encrypted value and lookup hash are written in the
+// same update. It returns the user, the previous address and a token
+// reverting the change, to be sent to the previous address.
+func ConfirmEmailChange(ctx context.Context, store auth.UserStore, crypto CryptoService, changes *EmailChanges, token string) (*auth.User, string, string, error) {
+	if store == nil {
+		return nil, "", "", fmt.Errorf("user store is required")
+	}
+	if crypto == nil {
+		return nil, "", "", fmt.Errorf("crypto service is required")
+	}
+	if changes == nil {
+		return nil, "", "", fmt.Errorf("email changes are required")
+	}
+
+	userID, email, err := changes.redeem(ctx, emailChangeVerify, token)
+	if err != nil {
+		return nil, "", "", err
+	}
+	user, previous, err := setUserEmail(ctx, store, crypto, userID, email)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	revert, err := changes.issue(emailChangeRevert, user.ID, previous, changes.revertWindow)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return user, previous, revert, nil
+}
+
+// RevertEmailChange redeems a token from ConfirmEmailChange and restores
+// the address the change replaced.
+func RevertEmailChange(ctx context.Context, store auth.UserStore, crypto CryptoService, changes *EmailChanges, token string) (*auth.User, error) {
+	if store == nil {
+		return nil, fmt.Errorf("user store is required")
+	}
+	if crypto == nil {
+		return nil, fmt.Errorf("crypto service is required")
+	}
+	if changes == nil {
+		return nil, fmt.Errorf("email changes are required")
+	}
+
+	userID, email, err := changes.redeem(ctx, emailChangeRevert, token)
+	if err != nil {
+		return nil, err
+	}
+	user, _, err := setUserEmail(ctx, store, crypto, userID, email)
+	return user, err
+}
+
+// setUserEmail replaces the address of the user with userID, returning the
+// user and the address it had.
+func setUserEmail(ctx context.Context, store auth.UserStore, crypto CryptoService, userID auth.UserID, email string) (*auth.User, string, error) {
+	user, err := store.Get(ctx, userID)
+	if errors.Is(err, auth.ErrUserNotFound) {
+		return nil, "", auth.ErrInvalidEmailChange
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkEmailFree(ctx, store, crypto, email); err != nil {
+		return nil, "", err
+	}
+
+	previous, err := user.GetEmail(crypto.EncryptionKey())
+	if err != nil {
+		return nil, "", fmt.Errorf("decrypt email: %w", err)
+	}
+
+	updated := *user
+	if err := updated.SetEmail(email, crypto.EncryptionKey(), crypto.SigningKey()); err != nil {
+		return nil, "", fmt.Errorf("encrypt email: %w", err)
+	}
+	updated.BeforeUpdate()
+	err = store.Update(ctx, &updated)
+	if errors.Is(err, auth.ErrUserAlreadyExists) {
+		return nil, "", auth.ErrEmailInUse
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &updated, previous, nil
+}
+
+func checkEmailFree(ctx context.Context, store auth.UserStore, crypto CryptoService, email string) error {
+	existing, err := store.GetByEmailLookup(ctx, crypto.ComputeLookupHash(email))
+	if err != nil && !errors.Is(err, auth.ErrUserNotFound) {
+		return fmt.Errorf("check existing email: %w", err)
+	}
+	if existing != nil {
+		return auth.ErrEmailInUse
+	}
+	return nil
+}
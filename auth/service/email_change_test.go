@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestEmailChange(t *testing.T) {
+	ctx := context.Background()
+	store := fake.NewUserStore()
+	crypto := fake.NewCryptoService()
+	changes := NewEmailChanges([]byte("email-change-key"), fake.NewNonceStore(), 0, 0)
+
+	ann, err := SignUp(ctx, store, crypto, "ann@example.com", "Password123!", "ann", "Ann")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SignUp(ctx, store, crypto, "bob@example.com", "Password123!", "bob", "Bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, err := RequestEmailChange(ctx, store, crypto, changes, ann.ID, "bad"); !errors.Is(err, auth.ErrInvalidEmail) {
+		t.Errorf("RequestEmailChange(invalid) error = %v, want ErrInvalidEmail", err)
+	}
+	if _, _, _, err := RequestEmailChange(ctx, store, crypto, changes, ann.ID, "Bob@Example.com"); !errors.Is(err, auth.ErrEmailInUse) {
+		t.Errorf("RequestEmailChange(taken) error = %v, want ErrEmailInUse", err)
+	}
+
+	_, email, token, err := RequestEmailChange(ctx, store, crypto, changes, ann.ID, " Ann.New@Example.com ")
+	if err != nil {
+		t.Fatalf("RequestEmailChange() error = %v", err)
+	}
+	if email != "ann.new@example.com" {
+		t.Errorf("RequestEmailChange() email = %q", email)
+	}
+	if stored, _ := store.Get(ctx, ann.ID); mustEmail(t, stored, crypto) != "ann@example.com" {
+		t.Error("RequestEmailChange() changed the stored email")
+	}
+
+	user, previous, revert, err := ConfirmEmailChange(ctx, store, crypto, changes, token)
+	if err != nil {
+		t.Fatalf("ConfirmEmailChange() error = %v", err)
+	}
+	if previous != "ann@example.com" || mustEmail(t, user, crypto) != "ann.new@example.com" {
+		t.Errorf("ConfirmEmailChange() previous = %q, email = %q", previous, mustEmail(t, user, crypto))
+	}
+	if found, err := store.GetByEmailLookup(ctx, crypto.ComputeLookupHash("ann.new@example.com")); err != nil || found.ID != ann.ID {
+		t.Errorf("lookup of new email = %v, %v", found, err)
+	}
+	if _, err := store.GetByEmailLookup(ctx, crypto.ComputeLookupHash("ann@example.com")); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("lookup of old email error = %v, want ErrUserNotFound", err)
+	}
+	if _, _, _, err := ConfirmEmailChange(ctx, store, crypto, changes, token); !errors.Is(err, auth.ErrInvalidEmailChange) {
+		t.Errorf("ConfirmEmailChange() replay error = %v, want ErrInvalidEmailChange", err)
+	}
+	if _, err := RevertEmailChange(ctx, store, crypto, changes, token); !errors.Is(err, auth.ErrInvalidEmailChange) {
+		t.Errorf("RevertEmailChange(verification token) error = %v, want ErrInvalidEmailChange", err)
+	}
+
+	user, err = RevertEmailChange(ctx, store, crypto, changes, revert)
+	if err != nil {
+		t.Fatalf("RevertEmailChange() error = %v", err)
+	}
+	if mustEmail(t, user, crypto) != "ann@example.com" {
+		t.Errorf("RevertEmailChange() email = %q", mustEmail(t, user, crypto))
+	}
+	if _, err := RevertEmailChange(ctx, store, crypto, changes, revert); !errors.Is(err, auth.ErrInvalidEmailChange) {
+		t.Errorf("RevertEmailChange() replay error = %v, want ErrInvalidEmailChange", err)
+	}
+}
+
+func TestEmailChangeExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	changes := NewEmailChanges([]byte("email-change-key"), fake.NewNonceStore(), time.Hour, 2*time.Hour)
+	changes.now = func() time.Time { return now }
+	userID := auth.NewUserID()
+
+	verify, _ := changes.issue(emailChangeVerify, userID, "ann@example.com", changes.TTL())
+	revert, _ := changes.issue(emailChangeRevert, userID, "ann@example.com", changes.RevertWindow())
+
+	now = now.Add(90 * time.Minute)
+	if _, _, err := changes.redeem(ctx, emailChangeVerify, verify); !errors.Is(err, auth.ErrInvalidEmailChange) {
+		t.Errorf("redeem() expired error = %v, want ErrInvalidEmailChange", err)
+	}
+	if got, email, err := changes.redeem(ctx, emailChangeRevert, revert); err != nil || got != userID || email != "ann@example.com" {
+		t.Errorf("redeem() within revert window = %v, %q, %v", got, email, err)
+	}
+	if _, _, err := changes.redeem(ctx, emailChangeVerify, "garbage"); !errors.Is(err, auth.ErrInvalidEmailChange) {
+		t.Errorf("redeem(garbage) error = %v, want ErrInvalidEmailChange", err)
+	}
+}
+
+func mustEmail(t *testing.T, user *auth.User, crypto CryptoService) string {
+	t.Helper()
+	email, err := user.GetEmail(crypto.EncryptionKey())
+	if err != nil {
+		t.Fatalf("GetEmail() error = %v", err)
+	}
+	return email
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"testing"
 
-	"github.com/google/uuid"
+	"github.com/aquamarinepk/aqm/auth"
 )
 
 // Test nil parameter cases for authn functions
@@ -110,7 +110,7 @@ func TestAuthnNilParameters(t *testing.T) {
 	})
 
 	t.Run("GetUserByID nil store", func(t *testing.T) {
-		_, err := GetUserByID(ctx, nil, uuid.New())
+		_, err := GetUserByID(ctx, nil, auth.NewUserID())
 		if err == nil {
 			t.Error("GetUserByID() with nil store should error")
 		}
@@ -152,7 +152,7 @@ func TestAuthnNilParameters(t *testing.T) {
 	})
 
 	t.Run("DeleteUser nil store", func(t *testing.T) {
-		err := DeleteUser(ctx, nil, uuid.New())
+		err := DeleteUser(ctx, nil, auth.NewUserID())
 		if err == nil {
 			t.Error("DeleteUser() with nil store should error")
 		}
@@ -171,7 +171,7 @@ func TestAuthzNilParameters(t *testing.T) {
 	})
 
 	t.Run("GetRoleByID nil store", func(t *testing.T) {
-		_, err := GetRoleByID(ctx, nil, uuid.New())
+		_, err := GetRoleByID(ctx, nil, auth.NewRoleID())
 		if err == nil {
 			t.Error("GetRoleByID() with nil store should error")
 		}
@@ -213,21 +213,21 @@ func TestAuthzNilParameters(t *testing.T) {
 	})
 
 	t.Run("DeleteRole nil store", func(t *testing.T) {
-		err := DeleteRole(ctx, nil, uuid.New())
+		err := DeleteRole(ctx, nil, auth.NewRoleID())
 		if err == nil {
 			t.Error("DeleteRole() with nil store should error")
 		}
 	})
 
 	t.Run("AssignRole nil store", func(t *testing.T) {
-		_, err := AssignRole(ctx, nil, "testuser", uuid.New(), "user")
+		_, err := AssignRole(ctx, nil, "testuser", auth.NewRoleID(), "user")
 		if err == nil {
 			t.Error("AssignRole() with nil store should error")
 		}
 	})
 
 	t.Run("RevokeRole nil store", func(t *testing.T) {
-		err := RevokeRole(ctx, nil, "testuser", uuid.New())
+		err := RevokeRole(ctx, nil, "testuser", auth.NewRoleID())
 		if err == nil {
 			t.Error("RevokeRole() with nil store should error")
 		}
@@ -248,7 +248,7 @@ func TestAuthzNilParameters(t *testing.T) {
 	})
 
 	t.Run("GetRoleGrants nil store", func(t *testing.T) {
-		_, err := GetRoleGrants(ctx, nil, uuid.New())
+		_, err := GetRoleGrants(ctx, nil, auth.NewRoleID())
 		if err == nil {
 			t.Error("GetRoleGrants() with nil store should error")
 		}
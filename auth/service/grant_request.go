@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// RequestGrant files a pending request, made by requestedBy, for roleID to
+// be assigned to username. It fails with auth.ErrGrantAlreadyExists when
+// the user holds the role and with auth.ErrGrantRequestExists when the same
+// request is already pending.
+func RequestGrant(ctx context.Context, requests auth.GrantRequestStore, roles auth.RoleStore, grants auth.GrantStore, username string, roleID auth.RoleID, reason, requestedBy string) (*auth.GrantRequest, error) {
+	if requests == nil {
+		return nil, fmt.Errorf("grant request store is required")
+	}
+	if roles == nil {
+		return nil, fmt.Errorf("role store is required")
+	}
+	if grants == nil {
+		return nil, fmt.Errorf("grant store is required")
+	}
+
+	req := auth.NewGrantRequest(username, roleID, reason, requestedBy)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	if _, err := roles.Get(ctx, roleID); err != nil {
+		return nil, err
+	}
+
+	held, err := grants.GetUserGrants(ctx, req.Username)
+	if err != nil {
+		return nil, fmt.Errorf("check existing grants: %w", err)
+	}
+	for _, g := range held {
+		if g.RoleID == roleID {
+			return nil, auth.ErrGrantAlreadyExists
+		}
+	}
+
+	pending, err := requests.List(ctx, auth.GrantRequestPending)
+	if err != nil {
+		return nil, fmt.Errorf("check pending requests: %w", err)
+	}
+	for _, p := range pending {
+		if p.Username == req.Username && p.RoleID == roleID {
+			return nil, auth.ErrGrantRequestExists
+		}
+	}
+
+	if err := requests.Create(ctx, req); err != nil {
+		return nil, fmt.Errorf("create grant request: %w", err)
+	}
+	return req, nil
+}
+
+// GetGrantRequest retrieves a grant request by ID.
+func GetGrantRequest(ctx context.Context, requests auth.GrantRequestStore, id auth.GrantRequestID) (*auth.GrantRequest, error) {
+	if requests == nil {
+		return nil, fmt.Errorf("grant request store is required")
+	}
+	return requests.Get(ctx, id)
+}
+
+// ListGrantRequests retrieves the grant requests in status, or all of them
+// when status is empty, oldest first.
+func ListGrantRequests(ctx context.Context, requests auth.GrantRequestStore, status auth.GrantRequestStatus) ([]*auth.GrantRequest, error) {
+	if requests == nil {
+		return nil, fmt.Errorf("grant request store is required")
+	}
+	return requests.List(ctx, status)
+}
+
+// ApproveGrantRequest approves the pending request id on behalf of
+// approver and assigns the requested role, returning the decided request
+// and the new grant. Callers check that approver may decide requests;
+// requesters cannot approve their own. With tx the grant and the decision
+// are stored as one unit of work, so a request approved concurrently by
+// someone else creates no second grant.
+func ApproveGrantRequest(ctx context.Context, tx auth.Transactor, requests auth.GrantRequestStore, grants auth.GrantStore, id auth.GrantRequestID, approver, note string) (*auth.GrantRequest, *auth.Grant, error) {
+	if requests == nil {
+		return nil, nil, fmt.Errorf("grant request store is required")
+	}
+	if grants == nil {
+		return nil, nil, fmt.Errorf("grant store is required")
+	}
+
+	var req *auth.GrantRequest
+	var grant *auth.Grant
+	err := withinTx(ctx, tx, func(ctx context.Context) error {
+		var err error
+		req, err = requests.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := req.Decide(auth.GrantRequestApproved, approver, note, time.Now()); err != nil {
+			return err
+		}
+
+		grant, err = AssignRole(ctx, grants, req.Username, req.RoleID, approver)
+		if err != nil {
+			return err
+		}
+		req.GrantID = &grant.ID
+
+		if err := requests.Update(ctx, req); err != nil {
+			if errors.Is(err, auth.ErrGrantRequestDecided) {
+				return err
+			}
+			return fmt.Errorf("update grant request: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, grant, nil
+}
+
+// RejectGrantRequest rejects the pending request id on behalf of approver.
+// No grant is created. Requesters cannot reject their own requests.
+func RejectGrantRequest(ctx context.Context, requests auth.GrantRequestStore, id auth.GrantRequestID, approver, note string) (*auth.GrantRequest, error) {
+	if requests == nil {
+		return nil, fmt.Errorf("grant request store is required")
+	}
+
+	req, err := requests.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Decide(auth.GrantRequestRejected, approver, note, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := requests.Update(ctx, req); err != nil {
+		if errors.Is(err, auth.ErrGrantRequestDecided) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("update grant request: %w", err)
+	}
+	return req, nil
+}
+
+// ListUserGrantRequests retrieves the grant requests for username, newest
+// first.
+func ListUserGrantRequests(ctx context.Context, requests auth.GrantRequestStore, username string) ([]*auth.GrantRequest, error) {
+	if requests == nil {
+		return nil, fmt.Errorf("grant request store is required")
+	}
+	return requests.ListByUser(ctx, username)
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestGrantRequests(t *testing.T) {
+	ctx := context.Background()
+	requests := fake.NewGrantRequestStore()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+
+	role, err := CreateRole(ctx, roles, "oncall", "", []string{"incidents:manage"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := RequestGrant(ctx, requests, roles, grants, "ann", role.ID, "joining the rotation", "ann")
+	if err != nil {
+		t.Fatalf("RequestGrant() error = %v", err)
+	}
+	if !req.IsPending() {
+		t.Errorf("RequestGrant() status = %q, want pending", req.Status)
+	}
+	if _, err := RequestGrant(ctx, requests, roles, grants, "ann", role.ID, "", "ann"); !errors.Is(err, auth.ErrGrantRequestExists) {
+		t.Errorf("RequestGrant() duplicate error = %v, want ErrGrantRequestExists", err)
+	}
+	if _, err := RequestGrant(ctx, requests, roles, grants, "ann", auth.NewRoleID(), "", "ann"); !errors.Is(err, auth.ErrRoleNotFound) {
+		t.Errorf("RequestGrant() unknown role error = %v, want ErrRoleNotFound", err)
+	}
+	if ok, _ := CheckPermission(ctx, grants, "ann", "incidents:manage"); ok {
+		t.Error("pending request granted the role")
+	}
+
+	if _, _, err := ApproveGrantRequest(ctx, fake.NewTransactor(), requests, grants, req.ID, "ann", ""); !errors.Is(err, auth.ErrSelfApproval) {
+		t.Errorf("ApproveGrantRequest() by requester error = %v, want ErrSelfApproval", err)
+	}
+	approved, grant, err := ApproveGrantRequest(ctx, fake.NewTransactor(), requests, grants, req.ID, "bob", "welcome")
+	if err != nil {
+		t.Fatalf("ApproveGrantRequest() error = %v", err)
+	}
+	if approved.Status != auth.GrantRequestApproved || approved.GrantID == nil || *approved.GrantID != grant.ID || grant.AssignedBy != "bob" {
+		t.Errorf("ApproveGrantRequest() = %+v, %+v", approved, grant)
+	}
+	if ok, _ := CheckPermission(ctx, grants, "ann", "incidents:manage"); !ok {
+		t.Error("approved request did not grant the role")
+	}
+	if _, _, err := ApproveGrantRequest(ctx, nil, requests, grants, req.ID, "carol", ""); !errors.Is(err, auth.ErrGrantRequestDecided) {
+		t.Errorf("ApproveGrantRequest() twice error = %v, want ErrGrantRequestDecided", err)
+	}
+	if _, err := RequestGrant(ctx, requests, roles, grants, "ann", role.ID, "", "ann"); !errors.Is(err, auth.ErrGrantAlreadyExists) {
+		t.Errorf("RequestGrant() for a held role error = %v, want ErrGrantAlreadyExists", err)
+	}
+
+	other, err := RequestGrant(ctx, requests, roles, grants, "dan", role.ID, "", "ann")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected, err := RejectGrantRequest(ctx, requests, other.ID, "bob", "not on the team")
+	if err != nil || rejected.Status != auth.GrantRequestRejected || rejected.Note != "not on the team" {
+		t.Fatalf("RejectGrantRequest() = %+v, %v", rejected, err)
+	}
+	if ok, _ := CheckPermission(ctx, grants, "dan", "incidents:manage"); ok {
+		t.Error("rejected request granted the role")
+	}
+
+	pending, _ := ListGrantRequests(ctx, requests, auth.GrantRequestPending)
+	all, _ := ListGrantRequests(ctx, requests, "")
+	if len(pending) != 0 || len(all) != 2 {
+		t.Errorf("ListGrantRequests() pending = %d, all = %d", len(pending), len(all))
+	}
+	if mine, _ := ListUserGrantRequests(ctx, requests, "dan"); len(mine) != 1 || mine[0].ID != other.ID {
+		t.Errorf("ListUserGrantRequests(dan) = %+v", mine)
+	}
+	if _, err := GetGrantRequest(ctx, requests, auth.NewGrantRequestID()); !errors.Is(err, auth.ErrGrantRequestNotFound) {
+		t.Errorf("GetGrantRequest() missing error = %v, want ErrGrantRequestNotFound", err)
+	}
+}
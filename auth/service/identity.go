@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/google/uuid"
+)
+
+// FederatedSignIn is the outcome of SignInWithIdentity.
+// Provisioned is set when a new user was created for the identity and
+// Linked when the identity was attached to an existing user by email.
+type FederatedSignIn struct {
+	User        *auth.User
+	Token       string
+	Identity    *auth.Identity
+	Provisioned bool
+	Linked      bool
+}
+
+// SignInWithIdentity signs in the user behind an external identity.
+//
+// A known identity signs in its user. An unknown identity whose provider
+// verified the email is linked to the user with that email, or, if there is
+// none, a new active user is provisioned with a random password, provided
+// domains allows the email. Unverified emails are never linked to existing
+// accounts.
+func SignInWithIdentity(ctx context.Context, users auth.UserStore, identities auth.IdentityStore, crypto CryptoService, tokenGen TokenGenerator, pwdGen PasswordGenerator, domains auth.EmailDomains, profile *auth.ExternalProfile) (*FederatedSignIn, error) {
+	if users == nil {
+		return nil, fmt.Errorf("user store is required")
+	}
+	if identities == nil {
+		return nil, fmt.Errorf("identity store is required")
+	}
+	if crypto == nil {
+		return nil, fmt.Errorf("crypto service is required")
+	}
+	if tokenGen == nil {
+		return nil, fmt.Errorf("token generator is required")
+	}
+	if pwdGen == nil {
+		return nil, fmt.Errorf("password generator is required")
+	}
+	if profile == nil {
+		return nil, auth.ErrInvalidIdentity
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := &FederatedSignIn{}
+
+	identity, err := identities.GetByProviderSubject(ctx, profile.Provider, profile.Subject)
+	switch {
+	case err == nil:
+		user, err := users.Get(ctx, identity.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("lookup identity user: %w", err)
+		}
+		result.User = user
+	case errors.Is(err, auth.ErrIdentityNotFound):
+		user, provisioned, err := resolveIdentityUser(ctx, users, crypto, pwdGen, domains, profile)
+		if err != nil {
+			return nil, err
+		}
+
+		identity = auth.NewIdentity(user.ID, profile.Provider, profile.Subject)
+		if err := identities.Create(ctx, identity); err != nil {
+			return nil, fmt.Errorf("create identity: %w", err)
+		}
+
+		result.User = user
+		result.Provisioned = provisioned
+		result.Linked = !provisioned
+	default:
+		return nil, fmt.Errorf("lookup identity: %w", err)
+	}
+	result.Identity = identity
+
+	if result.User.Status != auth.UserStatusActive {
+		return nil, auth.ErrInactiveAccount
+	}
+
+	token, err := tokenGen.GenerateToken(result.User.ID)
+	if err != nil {
+		return nil, fmt.Errorf("generate token: %w", err)
+	}
+	result.Token = token
+
+	return result, nil
+}
+
+// resolveIdentityUser finds the user to link profile to by email, or
+// provisions one if domains allows the email. It reports whether the user
+// was provisioned.
+func resolveIdentityUser(ctx context.Context, store auth.UserStore, crypto CryptoService, pwdGen PasswordGenerator, domains auth.EmailDomains, profile *auth.ExternalProfile) (*auth.User, bool, error) {
+	email := auth.NormalizeEmail(profile.Email)
+	if err := auth.ValidateEmail(email); err != nil {
+		return nil, false, err
+	}
+
+	existing, err := store.GetByEmailLookup(ctx, crypto.ComputeLookupHash(email))
+	if err != nil && err != auth.ErrUserNotFound {
+		return nil, false, fmt.Errorf("check existing user: %w", err)
+	}
+	if existing != nil {
+		if !profile.EmailVerified {
+			return nil, false, auth.ErrUnverifiedEmail
+		}
+		return existing, false, nil
+	}
+	if err := domains.Check(email); err != nil {
+		return nil, false, err
+	}
+
+	username, err := availableUsername(ctx, store, profile)
+	if err != nil {
+		return nil, false, err
+	}
+
+	user := newUser(crypto)
+	user.Username = username
+	user.Name = auth.NormalizeDisplayName(profile.Name)
+	if auth.ValidateDisplayName(user.Name) != nil {
+		user.Name = username
+	}
+	user.Status = auth.UserStatusActive
+	user.CreatedBy = profile.Provider
+	user.UpdatedBy = profile.Provider
+
+	if err := user.SetEmail(email, crypto.EncryptionKey(), crypto.SigningKey()); err != nil {
+		return nil, false, fmt.Errorf("encrypt email: %w", err)
+	}
+	if err := user.SetPasswordWith(pwdGen.GeneratePassword(), crypto.PasswordParams()); err != nil {
+		return nil, false, fmt.Errorf("hash password: %w", err)
+	}
+
+	user.BeforeCreate()
+
+	if err := store.Create(ctx, user); err != nil {
+		return nil, false, fmt.Errorf("create user: %w", err)
+	}
+
+	return user, true, nil
+}
+
+// availableUsername derives a username from the profile's username or email
+// and appends a random suffix until it is not taken.
+func availableUsername(ctx context.Context, store auth.UserStore, profile *auth.ExternalProfile) (string, error) {
+	base := profile.Username
+	if base == "" {
+		base, _, _ = strings.Cut(profile.Email, "@")
+	}
+	base = sanitizeUsername(base)
+
+	const maxAttempts = 5
+	candidate := base
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		existing, err := store.GetByUsername(ctx, candidate)
+		if err != nil && err != auth.ErrUserNotFound {
+			return "", fmt.Errorf("check existing username: %w", err)
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%s", base, uuid.NewString()[:6])
+	}
+
+	return "", auth.ErrUsernameExists
+}
+
+// sanitizeUsername maps s to a valid username, replacing disallowed
+// characters with '-' and padding or truncating it to the allowed length.
+func sanitizeUsername(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, auth.NormalizeUsername(s))
+	s = auth.NormalizeUsername(s)
+
+	for len(s) > 25 {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = auth.NormalizeUsername(s[:len(s)-size])
+	}
+	if len(s) < 3 {
+		s = "user" + s
+	}
+	return s
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestSignInWithIdentity(t *testing.T) {
+	ctx := context.Background()
+	crypto := fake.NewCryptoService()
+	tokenGen := fake.NewTokenGenerator()
+	pwdGen := fake.NewPasswordGenerator()
+
+	t.Run("provisions new user", func(t *testing.T) {
+		users := fake.NewUserStore()
+		identities := fake.NewIdentityStore()
+		profile := &auth.ExternalProfile{Provider: "google", Subject: "g-1", Email: "New@Example.com", EmailVerified: true, Name: "New User", Username: "newuser"}
+
+		result, err := SignInWithIdentity(ctx, users, identities, crypto, tokenGen, pwdGen, auth.EmailDomains{}, profile)
+		if err != nil {
+			t.Fatalf("SignInWithIdentity() error = %v", err)
+		}
+		if !result.Provisioned || result.Linked {
+			t.Errorf("Provisioned = %v, Linked = %v, want true, false", result.Provisioned, result.Linked)
+		}
+		if result.User.Username != "newuser" || result.User.Status != auth.UserStatusActive {
+			t.Errorf("User = %+v", result.User)
+		}
+		if result.User.CreatedBy != "google" {
+			t.Errorf("CreatedBy = %q, want google", result.User.CreatedBy)
+		}
+		if result.Token == "" {
+			t.Error("Token is empty")
+		}
+		if _, err := users.GetByEmailLookup(ctx, crypto.ComputeLookupHash("new@example.com")); err != nil {
+			t.Errorf("provisioned user not found by email: %v", err)
+		}
+
+		again, err := SignInWithIdentity(ctx, users, identities, crypto, tokenGen, pwdGen, auth.EmailDomains{}, profile)
+		if err != nil {
+			t.Fatalf("second SignInWithIdentity() error = %v", err)
+		}
+		if again.Provisioned || again.Linked || again.User.ID != result.User.ID {
+			t.Errorf("second sign-in = %+v, want existing user without provisioning", again)
+		}
+	})
+
+	t.Run("links verified email", func(t *testing.T) {
+		users := fake.NewUserStore()
+		identities := fake.NewIdentityStore()
+		existing, err := SignUp(ctx, users, crypto, "link@example.com", "Password123!", "linkuser", "Link User")
+		if err != nil {
+			t.Fatalf("SignUp() error = %v", err)
+		}
+
+		profile := &auth.ExternalProfile{Provider: "github", Subject: "42", Email: "link@example.com", EmailVerified: true}
+		result, err := SignInWithIdentity(ctx, users, identities, crypto, tokenGen, pwdGen, auth.EmailDomains{}, profile)
+		if err != nil {
+			t.Fatalf("SignInWithIdentity() error = %v", err)
+		}
+		if !result.Linked || result.User.ID != existing.ID {
+			t.Errorf("result = %+v, want link to %s", result, existing.ID)
+		}
+
+		linked, _ := identities.ListByUser(ctx, existing.ID)
+		if len(linked) != 1 || linked[0].Provider != "github" {
+			t.Errorf("ListByUser() = %v, want one github identity", linked)
+		}
+	})
+
+	t.Run("refuses unverified email", func(t *testing.T) {
+		users := fake.NewUserStore()
+		identities := fake.NewIdentityStore()
+		if _, err := SignUp(ctx, users, crypto, "taken@example.com", "Password123!", "takenuser", "Taken User"); err != nil {
+			t.Fatalf("SignUp() error = %v", err)
+		}
+
+		profile := &auth.ExternalProfile{Provider: "corp", Subject: "c-1", Email: "taken@example.com"}
+		_, err := SignInWithIdentity(ctx, users, identities, crypto, tokenGen, pwdGen, auth.EmailDomains{}, profile)
+		if !errors.Is(err, auth.ErrUnverifiedEmail) {
+			t.Errorf("SignInWithIdentity() error = %v, want ErrUnverifiedEmail", err)
+		}
+	})
+
+	t.Run("refuses provisioning from denied domain", func(t *testing.T) {
+		users := fake.NewUserStore()
+		identities := fake.NewIdentityStore()
+		if _, err := SignUp(ctx, users, crypto, "old@evil.com", "Password123!", "olduser", "Old User"); err != nil {
+			t.Fatalf("SignUp() error = %v", err)
+		}
+		domains := auth.EmailDomains{Allow: []string{"example.com"}}
+
+		profile := &auth.ExternalProfile{Provider: "google", Subject: "g-3", Email: "eve@evil.com", EmailVerified: true}
+		if _, err := SignInWithIdentity(ctx, users, identities, crypto, tokenGen, pwdGen, domains, profile); !errors.Is(err, auth.ErrEmailDomainNotAllowed) {
+			t.Errorf("SignInWithIdentity() error = %v, want ErrEmailDomainNotAllowed", err)
+		}
+
+		profile = &auth.ExternalProfile{Provider: "google", Subject: "g-4", Email: "old@evil.com", EmailVerified: true}
+		if result, err := SignInWithIdentity(ctx, users, identities, crypto, tokenGen, pwdGen, domains, profile); err != nil || !result.Linked {
+			t.Errorf("linking existing account = %+v, %v, want linked", result, err)
+		}
+	})
+
+	t.Run("suffixes taken username", func(t *testing.T) {
+		users := fake.NewUserStore()
+		identities := fake.NewIdentityStore()
+		if _, err := SignUp(ctx, users, crypto, "first@example.com", "Password123!", "octocat", "Octo Cat"); err != nil {
+			t.Fatalf("SignUp() error = %v", err)
+		}
+
+		profile := &auth.ExternalProfile{Provider: "github", Subject: "7", Email: "second@example.com", EmailVerified: true, Username: "octocat"}
+		result, err := SignInWithIdentity(ctx, users, identities, crypto, tokenGen, pwdGen, auth.EmailDomains{}, profile)
+		if err != nil {
+			t.Fatalf("SignInWithIdentity() error = %v", err)
+		}
+		if !strings.HasPrefix(result.User.Username, "octocat-") {
+			t.Errorf("Username = %q, want octocat- suffix", result.User.Username)
+		}
+	})
+
+	t.Run("rejects inactive user", func(t *testing.T) {
+		users := fake.NewUserStore()
+		identities := fake.NewIdentityStore()
+		user, err := SignUp(ctx, users, crypto, "off@example.com", "Password123!", "offuser", "Off User")
+		if err != nil {
+			t.Fatalf("SignUp() error = %v", err)
+		}
+		user.Status = auth.UserStatusSuspended
+		if err := users.Update(ctx, user); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+
+		profile := &auth.ExternalProfile{Provider: "google", Subject: "g-2", Email: "off@example.com", EmailVerified: true}
+		_, err = SignInWithIdentity(ctx, users, identities, crypto, tokenGen, pwdGen, auth.EmailDomains{}, profile)
+		if !errors.Is(err, auth.ErrInactiveAccount) {
+			t.Errorf("SignInWithIdentity() error = %v, want ErrInactiveAccount", err)
+		}
+	})
+
+	t.Run("rejects invalid profile", func(t *testing.T) {
+		_, err := SignInWithIdentity(ctx, fake.NewUserStore(), fake.NewIdentityStore(), crypto, tokenGen, pwdGen, auth.EmailDomains{}, &auth.ExternalProfile{Provider: "google"})
+		if !errors.Is(err, auth.ErrInvalidIdentity) {
+			t.Errorf("SignInWithIdentity() error = %v, want ErrInvalidIdentity", err)
+		}
+	})
+}
+
+func TestSanitizeUsername(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"jane.doe", "jane.doe"},
+		{"Jane Doe", "jane-doe"},
+		{"ab", "userab"},
+		{strings.Repeat("a", 40), strings.Repeat("a", 25)},
+	}
+	for _, tt := range tests {
+		if got := sanitizeUsername(tt.in); got != tt.want {
+			t.Errorf("sanitizeUsername(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
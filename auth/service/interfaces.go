@@ -1,6 +1,12 @@
 package service
 
-import "github.com/google/uuid"
+import (
+	"context"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/crypto"
+)
 
 // CryptoService handles encryption and hashing operations
 type CryptoService interface {
@@ -8,11 +14,56 @@ type CryptoService interface {
 	SigningKey() []byte
 	ComputeLookupHash(value string) []byte
 	ComputePINLookupHash(pin string) []byte
+	// PasswordParams selects how passwords are hashed.
+	PasswordParams() crypto.PasswordParams
+	// KeyScheme selects the key new users' email and PIN are encrypted
+	// with. Users on another scheme move to it on their next sign-in or
+	// with MigrateUserKeys.
+	KeyScheme() auth.KeyScheme
 }
 
 // TokenGenerator generates session tokens
 type TokenGenerator interface {
-	GenerateToken(userID uuid.UUID) (string, error)
+	GenerateToken(userID auth.UserID) (string, error)
+}
+
+// TTLTokenGenerator is a TokenGenerator that can also issue session tokens
+// valid for another lifetime than its default.
+type TTLTokenGenerator interface {
+	TokenGenerator
+	GenerateTokenWithTTL(userID auth.UserID, ttl time.Duration) (string, error)
+}
+
+// ImpersonationTokenGenerator issues tokens for actor acting as userID. The
+// actor is recorded in the token so services can tell impersonated requests
+// apart and audit them.
+type ImpersonationTokenGenerator interface {
+	GenerateImpersonationToken(userID auth.UserID, actor string, ttl time.Duration) (string, error)
+}
+
+// MFATokenGenerator issues MFA-pending tokens for userID: proof that the
+// first factor verified, accepted only where the second one is checked
+// (see middleware.BearerMFAPending).
+type MFATokenGenerator interface {
+	GenerateMFAPendingToken(userID auth.UserID, ttl time.Duration) (string, error)
+}
+
+// PermissionChecker reports whether a user, by username, holds a
+// permission. middleware.RoleChecker satisfies it.
+type PermissionChecker interface {
+	CheckPermission(ctx context.Context, username, permission string) (bool, error)
+}
+
+// ServiceTokenGenerator issues access tokens for service accounts. subject
+// is the account's principal.
+type ServiceTokenGenerator interface {
+	GenerateServiceToken(subject string, ttl time.Duration) (string, error)
+}
+
+// ScopedTokenGenerator issues tokens derived from a parent token, limited
+// to scope and expiring at expiresAt.
+type ScopedTokenGenerator interface {
+	GenerateScopedToken(parent crypto.TokenClaims, scope []string, expiresAt time.Time) (string, error)
 }
 
 // PasswordGenerator generates secure passwords
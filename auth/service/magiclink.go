@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// DefaultMagicLinkTTL is how long a magic link stays valid when
+// NewMagicLinks is given no TTL.
+const DefaultMagicLinkTTL = 15 * time.Minute
+
+// MagicLinks issues and redeems passwordless sign-in tokens. A token names
+// the user, a random nonce and its expiry, and is signed with HMAC-SHA256,
+// so nothing is stored until it is redeemed; the nonce is then recorded in
+// the NonceStore so each token signs in once.
+type MagicLinks struct {
+	key    []byte
+	nonces auth.NonceStore
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+type magicLinkClaims struct {
+	UserID    string `json:"u"`
+	Nonce     string `json:"n"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// NewMagicLinks creates links signed with key whose nonces are kept in
+// nonces. Every replica verifying the links must share both. ttl is
+// DefaultMagicLinkTTL when zero.
+func NewMagicLinks(key []byte, nonces auth.NonceStore, ttl time.Duration) *MagicLinks {
+	if ttl <= 0 {
+		ttl = DefaultMagicLinkTTL
+	}
+	return &MagicLinks{key: key, nonces: nonces, ttl: ttl, now: time.Now}
+}
+
+// TTL returns how long issued links stay valid.
+func (m *MagicLinks) TTL() time.Duration {
+	return m.ttl
+}
+
+// Issue returns a new token signing in userID.
+func (m *MagicLinks) Issue(userID auth.UserID) (string, error) {
+	nonce := make([]byte, 24)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate magic link nonce: %w", err)
+	}
+
+	data, err := json.Marshal(magicLinkClaims{
+		UserID:    userID.String(),
+		Nonce:     base64.RawURLEncoding.EncodeToString(nonce),
+		ExpiresAt: m.now().Add(m.ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + m.sign(payload), nil
+}
+
+// Redeem checks token and consumes its nonce, returning the user it signs
+// in. Tampered, expired and already redeemed tokens fail with
+// auth.ErrInvalidMagicLink.
+func (m *MagicLinks) Redeem(ctx context.Context, token string) (auth.UserID, error) {
+	var userID auth.UserID
+
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(m.sign(payload))) {
+		return userID, auth.ErrInvalidMagicLink
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return userID, auth.ErrInvalidMagicLink
+	}
+	var claims magicLinkClaims
+	if err := json.Unmarshal(data, &claims); err != nil || claims.Nonce == "" {
+		return userID, auth.ErrInvalidMagicLink
+	}
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+	if !m.now().Before(expiresAt) {
+		return userID, auth.ErrInvalidMagicLink
+	}
+	userID, err = auth.ParseUserID(claims.UserID)
+	if err != nil {
+		return userID, auth.ErrInvalidMagicLink
+	}
+
+	first, err := m.nonces.Use(ctx, claims.Nonce, expiresAt)
+	if err != nil {
+		return userID, fmt.Errorf("consume magic link: %w", err)
+	}
+	if !first {
+		return userID, auth.ErrInvalidMagicLink
+	}
+	return userID, nil
+}
+
+func (m *MagicLinks) sign(payload string) string {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte("magic-link:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// RequestMagicLink issues a magic link token for the active user with email.
+// It fails with auth.ErrUserNotFound for unknown emails and
+// auth.ErrInactiveAccount for users who cannot sign in; callers should not
+// reveal either to the requester.
+func RequestMagicLink(ctx context.Context, store auth.UserStore, crypto CryptoService, links *MagicLinks, email string) (*auth.User, string, error) {
+	if store == nil {
+		return nil, "", fmt.Errorf("user store is required")
+	}
+	if crypto == nil {
+		return nil, "", fmt.Errorf("crypto service is required")
+	}
+	if links == nil {
+		return nil, "", fmt.Errorf("magic links are required")
+	}
+
+	user, err := store.GetByEmailLookup(ctx, crypto.ComputeLookupHash(auth.NormalizeEmail(email)))
+	if err != nil {
+		return nil, "", err
+	}
+	if user.Status != auth.UserStatusActive {
+		return nil, "", auth.ErrInactiveAccount
+	}
+
+	token, err := links.Issue(user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, token, nil
+}
+
+// SignInWithMagicLink redeems a magic link token and issues a session token
+// for its user, who must still be active.
+func SignInWithMagicLink(ctx context.Context, store auth.UserStore, tokenGen TokenGenerator, links *MagicLinks, token string) (*auth.User, string, error) {
+	if store == nil {
+		return nil, "", fmt.Errorf("user store is required")
+	}
+	if tokenGen == nil {
+		return nil, "", fmt.Errorf("token generator is required")
+	}
+	if links == nil {
+		return nil, "", fmt.Errorf("magic links are required")
+	}
+
+	userID, err := links.Redeem(ctx, token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	user, err := store.Get(ctx, userID)
+	if err == auth.ErrUserNotFound {
+		return nil, "", auth.ErrInvalidMagicLink
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if user.Status != auth.UserStatusActive {
+		return nil, "", auth.ErrInactiveAccount
+	}
+
+	sessionToken, err := tokenGen.GenerateToken(user.ID)
+	if err != nil {
+		return nil, "", fmt.Errorf("generate token: %w", err)
+	}
+	return user, sessionToken, nil
+}
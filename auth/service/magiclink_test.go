@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestMagicLinksRedeem(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	links := NewMagicLinks([]byte("magic-link-key"), fake.NewNonceStore(), time.Minute)
+	links.now = func() time.Time { return now }
+	userID := auth.NewUserID()
+
+	token, err := links.Issue(userID)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	other, _ := links.Issue(userID)
+	if other == token {
+		t.Error("Issue() returned the same token twice")
+	}
+
+	got, err := links.Redeem(ctx, token)
+	if err != nil || got != userID {
+		t.Fatalf("Redeem() = %v, %v, want %v", got, err, userID)
+	}
+	if _, err := links.Redeem(ctx, token); !errors.Is(err, auth.ErrInvalidMagicLink) {
+		t.Errorf("Redeem() replay error = %v, want ErrInvalidMagicLink", err)
+	}
+
+	payload, _, _ := strings.Cut(other, ".")
+	otherKey := NewMagicLinks([]byte("another-key"), fake.NewNonceStore(), time.Minute)
+	tests := []struct {
+		name  string
+		links *MagicLinks
+		token string
+	}{
+		{name: "garbage", links: links, token: "not-a-token"},
+		{name: "tampered signature", links: links, token: payload + ".AAAA"},
+		{name: "wrong key", links: otherKey, token: other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.links.Redeem(ctx, tt.token); !errors.Is(err, auth.ErrInvalidMagicLink) {
+				t.Errorf("Redeem() error = %v, want ErrInvalidMagicLink", err)
+			}
+		})
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := links.Redeem(ctx, other); !errors.Is(err, auth.ErrInvalidMagicLink) {
+		t.Errorf("Redeem() expired error = %v, want ErrInvalidMagicLink", err)
+	}
+}
+
+func TestSignInWithMagicLink(t *testing.T) {
+	ctx := context.Background()
+	store := fake.NewUserStore()
+	crypto := fake.NewCryptoService()
+	tokenGen := fake.NewTokenGenerator()
+	links := NewMagicLinks([]byte("magic-link-key"), fake.NewNonceStore(), 0)
+
+	if links.TTL() != DefaultMagicLinkTTL {
+		t.Errorf("TTL() = %v, want DefaultMagicLinkTTL", links.TTL())
+	}
+
+	user, err := SignUp(ctx, store, crypto, "magic@example.com", "Password123!", "magicuser", "Magic User")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := RequestMagicLink(ctx, store, crypto, links, "nobody@example.com"); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("RequestMagicLink() unknown email error = %v, want ErrUserNotFound", err)
+	}
+
+	requested, token, err := RequestMagicLink(ctx, store, crypto, links, " Magic@Example.com ")
+	if err != nil || requested.ID != user.ID {
+		t.Fatalf("RequestMagicLink() = %v, %v", requested, err)
+	}
+
+	signedIn, sessionToken, err := SignInWithMagicLink(ctx, store, tokenGen, links, token)
+	if err != nil {
+		t.Fatalf("SignInWithMagicLink() error = %v", err)
+	}
+	if signedIn.ID != user.ID || sessionToken == "" {
+		t.Errorf("SignInWithMagicLink() = %v, %q", signedIn.ID, sessionToken)
+	}
+	if _, _, err := SignInWithMagicLink(ctx, store, tokenGen, links, token); !errors.Is(err, auth.ErrInvalidMagicLink) {
+		t.Errorf("SignInWithMagicLink() replay error = %v, want ErrInvalidMagicLink", err)
+	}
+
+	_, token, _ = RequestMagicLink(ctx, store, crypto, links, "magic@example.com")
+	DeleteUser(ctx, store, user.ID)
+	if _, _, err := SignInWithMagicLink(ctx, store, tokenGen, links, token); !errors.Is(err, auth.ErrInactiveAccount) {
+		t.Errorf("SignInWithMagicLink() inactive error = %v, want ErrInactiveAccount", err)
+	}
+	if _, _, err := RequestMagicLink(ctx, store, crypto, links, "magic@example.com"); !errors.Is(err, auth.ErrInactiveAccount) {
+		t.Errorf("RequestMagicLink() inactive error = %v, want ErrInactiveAccount", err)
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// CreatePermissionSet creates a permission set granting permissions.
+func CreatePermissionSet(ctx context.Context, store auth.PermissionSetStore, name, description string, permissions []string, createdBy string) (*auth.PermissionSet, error) {
+	if store == nil {
+		return nil, fmt.Errorf("permission set store is required")
+	}
+
+	set := auth.NewPermissionSet(name, description, permissions)
+	if err := set.Validate(); err != nil {
+		return nil, err
+	}
+	set.CreatedBy = createdBy
+	set.UpdatedBy = createdBy
+	set.BeforeCreate()
+
+	if err := store.Create(ctx, set); err != nil {
+		if errors.Is(err, auth.ErrPermissionSetExists) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("create permission set: %w", err)
+	}
+	return set, nil
+}
+
+// GetPermissionSet retrieves a permission set by name.
+func GetPermissionSet(ctx context.Context, store auth.PermissionSetStore, name string) (*auth.PermissionSet, error) {
+	if store == nil {
+		return nil, fmt.Errorf("permission set store is required")
+	}
+	return store.Get(ctx, auth.NormalizePermissionSetName(name))
+}
+
+// ListPermissionSets retrieves every permission set, ordered by name.
+func ListPermissionSets(ctx context.Context, store auth.PermissionSetStore) ([]*auth.PermissionSet, error) {
+	if store == nil {
+		return nil, fmt.Errorf("permission set store is required")
+	}
+	return store.List(ctx)
+}
+
+// UpdatePermissionSet stores set and refreshes the expansion of every role
+// referencing it, returning those roles. With tx the set and the roles are
+// updated as one unit of work.
+func UpdatePermissionSet(ctx context.Context, tx auth.Transactor, sets auth.PermissionSetStore, roles auth.RoleStore, set *auth.PermissionSet, updatedBy string) ([]*auth.Role, error) {
+	if sets == nil {
+		return nil, fmt.Errorf("permission set store is required")
+	}
+	if roles == nil {
+		return nil, fmt.Errorf("role store is required")
+	}
+	if set == nil {
+		return nil, fmt.Errorf("permission set is required")
+	}
+
+	var updated []*auth.Role
+	err := withinTx(ctx, tx, func(ctx context.Context) error {
+		set.UpdatedBy = updatedBy
+		set.BeforeUpdate()
+		if err := sets.Update(ctx, set); err != nil {
+			return err
+		}
+
+		referencing, err := rolesReferencing(ctx, roles, set.Name)
+		if err != nil {
+			return err
+		}
+		for _, role := range referencing {
+			if err := ExpandRole(ctx, sets, role); err != nil {
+				return fmt.Errorf("expand role %s: %w", role.Name, err)
+			}
+			if err := UpdateRole(ctx, roles, role, updatedBy); err != nil {
+				return fmt.Errorf("update role %s: %w", role.Name, err)
+			}
+		}
+		updated = referencing
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeletePermissionSet deletes the named permission set. It fails with
+// auth.ErrPermissionSetInUse while roles reference the set.
+func DeletePermissionSet(ctx context.Context, sets auth.PermissionSetStore, roles auth.RoleStore, name string) error {
+	if sets == nil {
+		return fmt.Errorf("permission set store is required")
+	}
+	if roles == nil {
+		return fmt.Errorf("role store is required")
+	}
+
+	name = auth.NormalizePermissionSetName(name)
+	referencing, err := rolesReferencing(ctx, roles, name)
+	if err != nil {
+		return err
+	}
+	if len(referencing) > 0 {
+		return fmt.Errorf("%w: %s is used by %d roles", auth.ErrPermissionSetInUse, name, len(referencing))
+	}
+	return sets.Delete(ctx, name)
+}
+
+// ExpandRole normalizes the permission set names of role, dropping
+// duplicates, and sets its SetPermissions to the permissions of those sets.
+// A name without a set fails with auth.ErrPermissionSetNotFound. Nothing is
+// written; call it before creating or updating the role.
+func ExpandRole(ctx context.Context, sets auth.PermissionSetStore, role *auth.Role) error {
+	if len(role.PermissionSets) == 0 {
+		role.PermissionSets, role.SetPermissions = nil, nil
+		return nil
+	}
+	if sets == nil {
+		return fmt.Errorf("permission set store is required")
+	}
+
+	names := make([]string, 0, len(role.PermissionSets))
+	resolved := make([]*auth.PermissionSet, 0, len(role.PermissionSets))
+	for _, name := range role.PermissionSets {
+		name = auth.NormalizePermissionSetName(name)
+		if slices.Contains(names, name) {
+			continue
+		}
+		set, err := sets.Get(ctx, name)
+		if errors.Is(err, auth.ErrPermissionSetNotFound) {
+			return fmt.Errorf("%w: %s", auth.ErrPermissionSetNotFound, name)
+		}
+		if err != nil {
+			return fmt.Errorf("get permission set %s: %w", name, err)
+		}
+		names = append(names, name)
+		resolved = append(resolved, set)
+	}
+
+	role.PermissionSets = names
+	role.SetPermissions = auth.ExpandPermissionSets(resolved)
+	return nil
+}
+
+// rolesReferencing returns the roles that reference the named permission set.
+func rolesReferencing(ctx context.Context, roles auth.RoleStore, name string) ([]*auth.Role, error) {
+	all, err := roles.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list roles: %w", err)
+	}
+	var referencing []*auth.Role
+	for _, role := range all {
+		if role.ReferencesPermissionSet(name) {
+			referencing = append(referencing, role)
+		}
+	}
+	return referencing, nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestPermissionSets(t *testing.T) {
+	ctx := context.Background()
+	sets := fake.NewPermissionSetStore()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+
+	editor, err := CreatePermissionSet(ctx, sets, " Content-Editor ", "Edits content", []string{"content:read", "content:write"}, "admin")
+	if err != nil {
+		t.Fatalf("CreatePermissionSet() error = %v", err)
+	}
+	if editor.Name != "content-editor" || editor.Version != 1 {
+		t.Errorf("CreatePermissionSet() = %+v", editor)
+	}
+	if _, err := CreatePermissionSet(ctx, sets, "content-editor", "", nil, "admin"); !errors.Is(err, auth.ErrPermissionSetExists) {
+		t.Errorf("CreatePermissionSet() duplicate error = %v, want ErrPermissionSetExists", err)
+	}
+	if _, err := CreatePermissionSet(ctx, sets, "x", "", nil, "admin"); !errors.Is(err, auth.ErrInvalidPermissionSetName) {
+		t.Errorf("CreatePermissionSet() short name error = %v, want ErrInvalidPermissionSetName", err)
+	}
+	if _, err := CreatePermissionSet(ctx, sets, "reviewer", "", []string{"content:read", "content:approve"}, "admin"); err != nil {
+		t.Fatalf("CreatePermissionSet() error = %v", err)
+	}
+
+	role, err := CreateRoleWithSets(ctx, roles, sets, "writer", "", []string{"profile:edit"}, []string{"content-editor", "Reviewer", "content-editor"}, "admin")
+	if err != nil {
+		t.Fatalf("CreateRoleWithSets() error = %v", err)
+	}
+	if len(role.PermissionSets) != 2 || len(role.SetPermissions) != 3 {
+		t.Errorf("CreateRoleWithSets() sets = %v, expansion = %v", role.PermissionSets, role.SetPermissions)
+	}
+	if len(role.GrantedPermissions()) != 4 {
+		t.Errorf("GrantedPermissions() = %v", role.GrantedPermissions())
+	}
+	if _, err := CreateRoleWithSets(ctx, roles, sets, "ghost", "", nil, []string{"missing"}, "admin"); !errors.Is(err, auth.ErrPermissionSetNotFound) {
+		t.Errorf("CreateRoleWithSets() unknown set error = %v, want ErrPermissionSetNotFound", err)
+	}
+	if _, err := AssignRole(ctx, grants, "ann", role.ID, "admin"); err != nil {
+		t.Fatalf("AssignRole() error = %v", err)
+	}
+
+	if ok, _ := CheckPermission(ctx, grants, "ann", "content:write"); !ok {
+		t.Error("CheckPermission(content:write) = false, want granted through content-editor")
+	}
+	if ok, _ := CheckPermission(ctx, grants, "ann", "content:publish"); ok {
+		t.Error("CheckPermission(content:publish) = true before the set grants it")
+	}
+
+	editor.Permissions = append(editor.Permissions, "content:publish")
+	updated, err := UpdatePermissionSet(ctx, fake.NewTransactor(), sets, roles, editor, "admin")
+	if err != nil {
+		t.Fatalf("UpdatePermissionSet() error = %v", err)
+	}
+	if len(updated) != 1 || updated[0].ID != role.ID || updated[0].UpdatedBy != "admin" {
+		t.Errorf("UpdatePermissionSet() roles = %+v", updated)
+	}
+	if ok, _ := CheckPermission(ctx, grants, "ann", "content:publish"); !ok {
+		t.Error("CheckPermission(content:publish) = false after updating the set")
+	}
+
+	if err := DeletePermissionSet(ctx, sets, roles, "content-editor"); !errors.Is(err, auth.ErrPermissionSetInUse) {
+		t.Errorf("DeletePermissionSet() in use error = %v, want ErrPermissionSetInUse", err)
+	}
+
+	role.PermissionSets = []string{"reviewer"}
+	if err := ExpandRole(ctx, sets, role); err != nil {
+		t.Fatalf("ExpandRole() error = %v", err)
+	}
+	if err := UpdateRole(ctx, roles, role, "admin"); err != nil {
+		t.Fatalf("UpdateRole() error = %v", err)
+	}
+	if ok, _ := CheckPermission(ctx, grants, "ann", "content:write"); ok {
+		t.Error("CheckPermission(content:write) = true after dropping the set")
+	}
+	if err := DeletePermissionSet(ctx, sets, roles, "Content-Editor"); err != nil {
+		t.Errorf("DeletePermissionSet() error = %v", err)
+	}
+
+	list, _ := ListPermissionSets(ctx, sets)
+	if len(list) != 1 || list[0].Name != "reviewer" {
+		t.Errorf("ListPermissionSets() = %+v", list)
+	}
+	if _, err := GetPermissionSet(ctx, sets, "content-editor"); !errors.Is(err, auth.ErrPermissionSetNotFound) {
+		t.Errorf("GetPermissionSet() deleted error = %v, want ErrPermissionSetNotFound", err)
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+// RevokeSession revokes the tokens of session sessionID of userID. ttl is
+// the longest lifetime of the tokens issued, after which the revocation is
+// forgotten.
+func RevokeSession(ctx context.Context, store auth.RevocationStore, userID, sessionID string, ttl time.Duration) error {
+	if store == nil {
+		return fmt.Errorf("revocation store is required")
+	}
+	if sessionID == "" {
+		return fmt.Errorf("session ID is required")
+	}
+	return store.Create(ctx, auth.NewSessionRevocation(userID, sessionID, ttl))
+}
+
+// RevokeUserTokens revokes every token issued to userID so far, signing the
+// user out everywhere. ttl is as for RevokeSession.
+func RevokeUserTokens(ctx context.Context, store auth.RevocationStore, userID string, ttl time.Duration) error {
+	if store == nil {
+		return fmt.Errorf("revocation store is required")
+	}
+	return store.Create(ctx, auth.NewUserRevocation(userID, ttl))
+}
+
+// RevocationCheck checks token claims against a RevocationStore. It is a
+// middleware.RevocationChecker, to be wrapped around token validators with
+// middleware.NewRevocationValidator.
+type RevocationCheck struct {
+	store auth.RevocationStore
+}
+
+// NewRevocationCheck creates a RevocationCheck backed by store.
+func NewRevocationCheck(store auth.RevocationStore) *RevocationCheck {
+	return &RevocationCheck{store: store}
+}
+
+// Revoked reports whether the token with claims was revoked. Tokens
+// without an issue time count as issued before every revocation of their
+// subject.
+func (c *RevocationCheck) Revoked(ctx context.Context, claims crypto.TokenClaims) (bool, error) {
+	return c.store.IsRevoked(ctx, claims.Subject, claims.SessionID, claims.IssuedTime())
+}
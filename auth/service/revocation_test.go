@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+func TestRevocation(t *testing.T) {
+	ctx := context.Background()
+	store := fake.NewRevocationStore()
+	check := NewRevocationCheck(store)
+	issued := time.Now().Add(-time.Minute).Unix()
+
+	revoked := func(userID, sessionID string, issuedAt int64) bool {
+		t.Helper()
+		got, err := check.Revoked(ctx, crypto.TokenClaims{Subject: userID, SessionID: sessionID, IssuedAt: issuedAt})
+		if err != nil {
+			t.Fatalf("Revoked() error = %v", err)
+		}
+		return got
+	}
+
+	if err := RevokeSession(ctx, store, "ann", "s-1", time.Hour); err != nil {
+		t.Fatalf("RevokeSession() error = %v", err)
+	}
+	if !revoked("ann", "s-1", issued) || revoked("ann", "s-2", issued) {
+		t.Error("RevokeSession() should revoke only the session")
+	}
+	if err := RevokeSession(ctx, store, "ann", "", time.Hour); err == nil {
+		t.Error("RevokeSession() without session ID should fail")
+	}
+
+	if err := RevokeUserTokens(ctx, store, "bob", time.Hour); err != nil {
+		t.Fatalf("RevokeUserTokens() error = %v", err)
+	}
+	if !revoked("bob", "s-3", issued) || !revoked("bob", "s-4", 0) {
+		t.Error("RevokeUserTokens() should revoke earlier tokens and tokens without an issue time")
+	}
+	if revoked("bob", "s-5", time.Now().Add(time.Minute).Unix()) {
+		t.Error("RevokeUserTokens() revoked a later token")
+	}
+	if revoked("ann", "s-2", 0) {
+		t.Error("RevokeUserTokens() revoked another user's token")
+	}
+
+	if err := RevokeUserTokens(ctx, store, "cid", time.Hour); err != nil {
+		t.Fatalf("RevokeUserTokens() error = %v", err)
+	}
+	later := time.Now()
+	claims := crypto.TokenClaims{Subject: "cid", SessionID: "s-6", IssuedAt: later.Unix(), IssuedAtNsec: int64(later.Nanosecond())}
+	if got, err := check.Revoked(ctx, claims); err != nil || got {
+		t.Errorf("Revoked() of a token issued right after RevokeUserTokens() = %v, %v, want false", got, err)
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// Defaults of HeuristicRiskConfig.
+const (
+	DefaultRiskVelocityLimit  = 5
+	DefaultRiskVelocityWindow = 10 * time.Minute
+	DefaultRiskRemember       = 20
+)
+
+// HeuristicRiskConfig configures NewHeuristicRisk.
+type HeuristicRiskConfig struct {
+	// NewIP is the decision for a sign-in from an address the user has not
+	// signed in from before. auth.RiskFlag when empty.
+	NewIP auth.RiskDecision
+	// NewCountry is the decision for a sign-in from a country the user has
+	// not signed in from before. auth.RiskStepUp when empty.
+	NewCountry auth.RiskDecision
+	// VelocityLimit is how many sign-ins a user may make within
+	// VelocityWindow before further ones need a second factor.
+	// DefaultRiskVelocityLimit and DefaultRiskVelocityWindow when zero.
+	VelocityLimit  int
+	VelocityWindow time.Duration
+	// Remember is how many addresses and countries are remembered per
+	// user. DefaultRiskRemember when zero.
+	Remember int
+}
+
+// HeuristicRisk is a RiskEvaluator that compares each sign-in with the
+// user's earlier ones: a new address, a new country or too many sign-ins
+// in a short time raise the risk. A user's first sign-in only sets the
+// baseline, and sign-ins from trusted devices are at most flagged. What it
+// has seen is kept in memory, so each replica learns on its own and
+// restarts forget it.
+type HeuristicRisk struct {
+	cfg   HeuristicRiskConfig
+	mu    sync.Mutex
+	users map[auth.UserID]*signInHistory
+}
+
+type signInHistory struct {
+	ips       []string
+	countries []string
+	times     []time.Time
+}
+
+// NewHeuristicRisk creates a HeuristicRisk configured with cfg.
+func NewHeuristicRisk(cfg HeuristicRiskConfig) *HeuristicRisk {
+	if cfg.NewIP == "" {
+		cfg.NewIP = auth.RiskFlag
+	}
+	if cfg.NewCountry == "" {
+		cfg.NewCountry = auth.RiskStepUp
+	}
+	if cfg.VelocityLimit <= 0 {
+		cfg.VelocityLimit = DefaultRiskVelocityLimit
+	}
+	if cfg.VelocityWindow <= 0 {
+		cfg.VelocityWindow = DefaultRiskVelocityWindow
+	}
+	if cfg.Remember <= 0 {
+		cfg.Remember = DefaultRiskRemember
+	}
+	return &HeuristicRisk{cfg: cfg, users: make(map[auth.UserID]*signInHistory)}
+}
+
+// Evaluate assesses attempt and remembers it for later sign-ins of the
+// same user.
+func (e *HeuristicRisk) Evaluate(_ context.Context, attempt auth.SignInAttempt) (auth.RiskAssessment, error) {
+	at := attempt.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	assessment := auth.RiskAssessment{Decision: auth.RiskAllow}
+	history, seen := e.users[attempt.UserID]
+	if !seen {
+		history = &signInHistory{}
+		e.users[attempt.UserID] = history
+	}
+
+	if seen {
+		if attempt.IP != "" && !slices.Contains(history.ips, attempt.IP) {
+			assessment.Raise(e.cfg.NewIP, auth.RiskReasonNewIP)
+		}
+		if attempt.Country != "" && !slices.Contains(history.countries, attempt.Country) {
+			assessment.Raise(e.cfg.NewCountry, auth.RiskReasonNewCountry)
+		}
+	}
+
+	since := at.Add(-e.cfg.VelocityWindow)
+	history.times = slices.DeleteFunc(history.times, func(t time.Time) bool { return !t.After(since) })
+	if len(history.times) >= e.cfg.VelocityLimit {
+		assessment.Raise(auth.RiskStepUp, auth.RiskReasonVelocity)
+	}
+
+	if attempt.TrustedDevice && assessment.Decision.Exceeds(auth.RiskFlag) {
+		assessment.Decision = auth.RiskFlag
+	}
+
+	history.times = append(history.times, at)
+	history.ips = remember(history.ips, attempt.IP, e.cfg.Remember)
+	history.countries = remember(history.countries, attempt.Country, e.cfg.Remember)
+	return assessment, nil
+}
+
+// remember moves value to the end of values, dropping the oldest entries
+// beyond limit. Empty values are not remembered.
+func remember(values []string, value string, limit int) []string {
+	if value == "" {
+		return values
+	}
+	values = slices.DeleteFunc(values, func(v string) bool { return v == value })
+	values = append(values, value)
+	if len(values) > limit {
+		values = values[len(values)-limit:]
+	}
+	return values
+}
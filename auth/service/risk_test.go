@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func TestHeuristicRisk(t *testing.T) {
+	ctx := context.Background()
+	risk := NewHeuristicRisk(HeuristicRiskConfig{VelocityLimit: 3, VelocityWindow: time.Minute})
+	user := auth.NewUserID()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		attempt auth.SignInAttempt
+		want    auth.RiskDecision
+		reasons []string
+	}{
+		{"first sign-in", auth.SignInAttempt{IP: "203.0.113.1", Country: "ES", At: start}, auth.RiskAllow, nil},
+		{"same place", auth.SignInAttempt{IP: "203.0.113.1", Country: "ES", At: start.Add(time.Hour)}, auth.RiskAllow, nil},
+		{"new address", auth.SignInAttempt{IP: "203.0.113.2", Country: "ES", At: start.Add(2 * time.Hour)}, auth.RiskFlag, []string{auth.RiskReasonNewIP}},
+		{"new country", auth.SignInAttempt{IP: "198.51.100.7", Country: "BR", At: start.Add(3 * time.Hour)}, auth.RiskStepUp, []string{auth.RiskReasonNewIP, auth.RiskReasonNewCountry}},
+		{"new country from trusted device", auth.SignInAttempt{IP: "192.0.2.4", Country: "JP", TrustedDevice: true, At: start.Add(4 * time.Hour)}, auth.RiskFlag, []string{auth.RiskReasonNewIP, auth.RiskReasonNewCountry}},
+		{"country seen before", auth.SignInAttempt{IP: "198.51.100.7", Country: "BR", At: start.Add(5 * time.Hour)}, auth.RiskAllow, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.attempt.UserID = user
+			got, err := risk.Evaluate(ctx, tt.attempt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Decision != tt.want || !slices.Equal(got.Reasons, tt.reasons) {
+				t.Errorf("Evaluate() = %+v, want %q %v", got, tt.want, tt.reasons)
+			}
+		})
+	}
+
+	if got, _ := risk.Evaluate(ctx, auth.SignInAttempt{UserID: auth.NewUserID(), IP: "192.0.2.99", Country: "US", At: start}); got.Decision != auth.RiskAllow {
+		t.Errorf("other user's first sign-in = %+v, want allow", got)
+	}
+}
+
+func TestHeuristicRiskVelocity(t *testing.T) {
+	ctx := context.Background()
+	risk := NewHeuristicRisk(HeuristicRiskConfig{VelocityLimit: 2, VelocityWindow: time.Minute})
+	user := auth.NewUserID()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	attempt := func(at time.Time) auth.RiskAssessment {
+		got, _ := risk.Evaluate(ctx, auth.SignInAttempt{UserID: user, IP: "203.0.113.1", At: at})
+		return got
+	}
+
+	attempt(start)
+	if got := attempt(start.Add(10 * time.Second)); got.Decision != auth.RiskAllow {
+		t.Errorf("second sign-in = %+v, want allow", got)
+	}
+	got := attempt(start.Add(20 * time.Second))
+	if got.Decision != auth.RiskStepUp || !slices.Equal(got.Reasons, []string{auth.RiskReasonVelocity}) {
+		t.Errorf("third sign-in = %+v, want velocity step-up", got)
+	}
+	if got := attempt(start.Add(5 * time.Minute)); got.Decision != auth.RiskAllow {
+		t.Errorf("sign-in after the window = %+v, want allow", got)
+	}
+}
+
+func TestRemember(t *testing.T) {
+	values := remember(nil, "a", 2)
+	values = remember(values, "b", 2)
+	values = remember(values, "a", 2)
+	values = remember(values, "", 2)
+	values = remember(values, "c", 2)
+	if want := []string{"a", "c"}; !slices.Equal(values, want) {
+		t.Errorf("remember() = %v, want %v", values, want)
+	}
+}
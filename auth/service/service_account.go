@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/google/uuid"
+)
+
+// CreateServiceAccount creates an active service account and returns it with
+// its client secret, which is not stored and cannot be retrieved again.
+// Names follow the username rules.
+func CreateServiceAccount(ctx context.Context, store auth.ServiceAccountStore, name, description, createdBy string) (*auth.ServiceAccount, string, error) {
+	if store == nil {
+		return nil, "", fmt.Errorf("service account store is required")
+	}
+	if err := auth.ValidateUsername(name); err != nil {
+		return nil, "", auth.ErrInvalidServiceAccountName
+	}
+
+	account := auth.NewServiceAccount()
+	account.Name = name
+	account.Description = strings.TrimSpace(description)
+	account.CreatedBy = createdBy
+	account.UpdatedBy = createdBy
+	account.BeforeCreate()
+
+	secret, err := account.SetSecret()
+	if err != nil {
+		return nil, "", fmt.Errorf("generate secret: %w", err)
+	}
+
+	if err := store.Create(ctx, account); err != nil {
+		return nil, "", err
+	}
+
+	return account, secret, nil
+}
+
+func GetServiceAccount(ctx context.Context, store auth.ServiceAccountStore, id uuid.UUID) (*auth.ServiceAccount, error) {
+	if store == nil {
+		return nil, fmt.Errorf("service account store is required")
+	}
+	return store.Get(ctx, id)
+}
+
+func ListServiceAccounts(ctx context.Context, store auth.ServiceAccountStore) ([]*auth.ServiceAccount, error) {
+	if store == nil {
+		return nil, fmt.Errorf("service account store is required")
+	}
+	return store.List(ctx)
+}
+
+// UpdateServiceAccount stores changes to an account's description or status.
+func UpdateServiceAccount(ctx context.Context, store auth.ServiceAccountStore, account *auth.ServiceAccount) error {
+	if store == nil {
+		return fmt.Errorf("service account store is required")
+	}
+	if account == nil {
+		return fmt.Errorf("service account is required")
+	}
+	account.BeforeUpdate()
+	return store.Update(ctx, account)
+}
+
+// RotateServiceAccountSecret replaces an account's client secret and returns
+// the new one. The previous secret stops working immediately; tokens already
+// issued stay valid until they expire.
+func RotateServiceAccountSecret(ctx context.Context, store auth.ServiceAccountStore, id uuid.UUID, updatedBy string) (*auth.ServiceAccount, string, error) {
+	if store == nil {
+		return nil, "", fmt.Errorf("service account store is required")
+	}
+
+	account, err := store.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := account.SetSecret()
+	if err != nil {
+		return nil, "", fmt.Errorf("generate secret: %w", err)
+	}
+	account.UpdatedBy = updatedBy
+	account.BeforeUpdate()
+
+	if err := store.Update(ctx, account); err != nil {
+		return nil, "", err
+	}
+	return account, secret, nil
+}
+
+// DeleteServiceAccount removes an account. Its grants are kept by the authz
+// service and must be revoked separately.
+func DeleteServiceAccount(ctx context.Context, store auth.ServiceAccountStore, id uuid.UUID) error {
+	if store == nil {
+		return fmt.Errorf("service account store is required")
+	}
+	return store.Delete(ctx, id)
+}
+
+// AuthenticateServiceAccount checks a client ID and secret and issues a token
+// for the account's principal, valid for ttl. Unknown client IDs and wrong
+// secrets both fail with auth.ErrInvalidCredentials; disabled accounts fail
+// with auth.ErrInactiveAccount.
+func AuthenticateServiceAccount(ctx context.Context, store auth.ServiceAccountStore, tokenGen ServiceTokenGenerator, clientID, secret string, ttl time.Duration) (*auth.ServiceAccount, string, error) {
+	if store == nil {
+		return nil, "", fmt.Errorf("service account store is required")
+	}
+	if tokenGen == nil {
+		return nil, "", fmt.Errorf("token generator is required")
+	}
+
+	account, err := store.GetByClientID(ctx, clientID)
+	if err == auth.ErrServiceAccountNotFound {
+		return nil, "", auth.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("lookup service account: %w", err)
+	}
+
+	if !account.VerifySecret(secret) {
+		return nil, "", auth.ErrInvalidCredentials
+	}
+	if account.Status != auth.ServiceAccountStatusActive {
+		return nil, "", auth.ErrInactiveAccount
+	}
+
+	token, err := tokenGen.GenerateServiceToken(account.Principal(), ttl)
+	if err != nil {
+		return nil, "", fmt.Errorf("generate token: %w", err)
+	}
+
+	return account, token, nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestCreateServiceAccount(t *testing.T) {
+	ctx := t.Context()
+	store := fake.NewServiceAccountStore()
+
+	account, secret, err := CreateServiceAccount(ctx, store, "Billing-Worker", " nightly jobs ", "admin")
+	if err != nil {
+		t.Fatalf("CreateServiceAccount() error = %v", err)
+	}
+	if account.Name != "billing-worker" || account.Description != "nightly jobs" || account.CreatedBy != "admin" {
+		t.Errorf("account = %+v", account)
+	}
+	if secret == "" || !account.VerifySecret(secret) {
+		t.Errorf("secret %q does not verify", secret)
+	}
+
+	tests := []struct {
+		name    string
+		accName string
+		wantErr error
+	}{
+		{name: "duplicate", accName: "billing-worker", wantErr: auth.ErrServiceAccountExists},
+		{name: "invalid name", accName: "sa:worker", wantErr: auth.ErrInvalidServiceAccountName},
+		{name: "empty name", accName: "", wantErr: auth.ErrInvalidServiceAccountName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := CreateServiceAccount(ctx, store, tt.accName, "", "admin"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CreateServiceAccount() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, _, err := CreateServiceAccount(ctx, nil, "worker", "", "admin"); err == nil {
+		t.Error("CreateServiceAccount() with nil store should fail")
+	}
+}
+
+func TestAuthenticateServiceAccount(t *testing.T) {
+	ctx := t.Context()
+	store := fake.NewServiceAccountStore()
+	tokenGen := fake.NewTokenGenerator()
+
+	account, secret, err := CreateServiceAccount(ctx, store, "worker", "", "admin")
+	if err != nil {
+		t.Fatalf("CreateServiceAccount() error = %v", err)
+	}
+
+	got, token, err := AuthenticateServiceAccount(ctx, store, tokenGen, account.ClientID, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("AuthenticateServiceAccount() error = %v", err)
+	}
+	if got.ID != account.ID || token != "token-sa:worker" {
+		t.Errorf("AuthenticateServiceAccount() = %v, %q", got.ID, token)
+	}
+
+	if _, _, err := AuthenticateServiceAccount(ctx, store, tokenGen, account.ClientID, "wrong", time.Minute); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("wrong secret error = %v, want %v", err, auth.ErrInvalidCredentials)
+	}
+	if _, _, err := AuthenticateServiceAccount(ctx, store, tokenGen, "sa_unknown", secret, time.Minute); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("unknown client error = %v, want %v", err, auth.ErrInvalidCredentials)
+	}
+
+	account.Status = auth.ServiceAccountStatusDisabled
+	if err := UpdateServiceAccount(ctx, store, account); err != nil {
+		t.Fatalf("UpdateServiceAccount() error = %v", err)
+	}
+	if _, _, err := AuthenticateServiceAccount(ctx, store, tokenGen, account.ClientID, secret, time.Minute); !errors.Is(err, auth.ErrInactiveAccount) {
+		t.Errorf("disabled account error = %v, want %v", err, auth.ErrInactiveAccount)
+	}
+}
+
+func TestRotateServiceAccountSecret(t *testing.T) {
+	ctx := t.Context()
+	store := fake.NewServiceAccountStore()
+
+	account, oldSecret, _ := CreateServiceAccount(ctx, store, "worker", "", "admin")
+
+	rotated, newSecret, err := RotateServiceAccountSecret(ctx, store, account.ID, "ops")
+	if err != nil {
+		t.Fatalf("RotateServiceAccountSecret() error = %v", err)
+	}
+	if rotated.VerifySecret(oldSecret) || !rotated.VerifySecret(newSecret) {
+		t.Error("RotateServiceAccountSecret() should replace the secret")
+	}
+	if rotated.UpdatedBy != "ops" {
+		t.Errorf("UpdatedBy = %q, want ops", rotated.UpdatedBy)
+	}
+
+	if err := DeleteServiceAccount(ctx, store, account.ID); err != nil {
+		t.Fatalf("DeleteServiceAccount() error = %v", err)
+	}
+	if _, _, err := RotateServiceAccountSecret(ctx, store, account.ID, "ops"); !errors.Is(err, auth.ErrServiceAccountNotFound) {
+		t.Errorf("rotate deleted account error = %v, want %v", err, auth.ErrServiceAccountNotFound)
+	}
+	accounts, _ := ListServiceAccounts(ctx, store)
+	if len(accounts) != 0 {
+		t.Errorf("ListServiceAccounts() = %d accounts, want 0", len(accounts))
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// EndOtherSessions deletes the sessions of userID except keep, typically
+// the session making the request, and returns how many were deleted. An
+// empty keep ends every session of the user.
+func EndOtherSessions(ctx context.Context, store auth.SessionStore, userID, keep string) (int, error) {
+	if store == nil {
+		return 0, fmt.Errorf("session store is required")
+	}
+
+	sessions, err := store.ListByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	ended := 0
+	for _, session := range sessions {
+		if session.ID == keep {
+			continue
+		}
+		if err := store.Delete(ctx, session.ID); err != nil {
+			return ended, fmt.Errorf("delete session %s: %w", session.ID, err)
+		}
+		ended++
+	}
+	return ended, nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestEndOtherSessions(t *testing.T) {
+	ctx := context.Background()
+	store := fake.NewSessionStore()
+
+	current := auth.NewSession("ann", time.Hour)
+	store.Create(ctx, current)
+	store.Create(ctx, auth.NewSession("ann", time.Hour))
+	store.Create(ctx, auth.NewSession("ann", time.Hour))
+	store.Create(ctx, auth.NewSession("bob", time.Hour))
+
+	ended, err := EndOtherSessions(ctx, store, "ann", current.ID)
+	if err != nil || ended != 2 {
+		t.Fatalf("EndOtherSessions() = %d, %v, want 2", ended, err)
+	}
+
+	left, _ := store.ListByUser(ctx, "ann")
+	if len(left) != 1 || left[0].ID != current.ID {
+		t.Errorf("sessions left = %+v, want only the current one", left)
+	}
+	if others, _ := store.ListByUser(ctx, "bob"); len(others) != 1 {
+		t.Errorf("other user's sessions = %d, want 1", len(others))
+	}
+
+	if ended, _ := EndOtherSessions(ctx, store, "ann", ""); ended != 1 {
+		t.Errorf("EndOtherSessions() without keep = %d, want 1", ended)
+	}
+	if _, err := EndOtherSessions(ctx, nil, "ann", ""); err == nil {
+		t.Error("EndOtherSessions() without store should fail")
+	}
+}
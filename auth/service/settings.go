@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// GetSettings returns the stored settings, or empty settings at version
+// zero when none were saved yet.
+func GetSettings(ctx context.Context, store auth.SettingsStore) (*auth.Settings, error) {
+	if store == nil {
+		return nil, fmt.Errorf("settings store is required")
+	}
+
+	settings, err := store.Get(ctx)
+	if errors.Is(err, auth.ErrSettingsNotFound) {
+		return &auth.Settings{}, nil
+	}
+	return settings, err
+}
+
+// UpdateSettings normalizes, validates and saves settings. Its Version must
+// be the one read with GetSettings; Save fails with auth.ErrVersionConflict
+// otherwise.
+func UpdateSettings(ctx context.Context, store auth.SettingsStore, settings *auth.Settings, updatedBy string) error {
+	if store == nil {
+		return fmt.Errorf("settings store is required")
+	}
+
+	settings.Normalize()
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	settings.UpdatedAt = time.Now()
+	settings.UpdatedBy = updatedBy
+	return store.Save(ctx, settings)
+}
+
+// WithTokenTTL returns a TokenGenerator that issues tokens with gen valid
+// for ttl. gen itself is returned when ttl is zero or gen cannot vary the
+// lifetime of its tokens.
+func WithTokenTTL(gen TokenGenerator, ttl time.Duration) TokenGenerator {
+	ttlGen, ok := gen.(TTLTokenGenerator)
+	if !ok || ttl <= 0 {
+		return gen
+	}
+	return ttlTokenGenerator{gen: ttlGen, ttl: ttl}
+}
+
+type ttlTokenGenerator struct {
+	gen TTLTokenGenerator
+	ttl time.Duration
+}
+
+func (g ttlTokenGenerator) GenerateToken(userID auth.UserID) (string, error) {
+	return g.gen.GenerateTokenWithTTL(userID, g.ttl)
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestSettings(t *testing.T) {
+	ctx := context.Background()
+	store := fake.NewSettingsStore()
+
+	settings, err := GetSettings(ctx, store)
+	if err != nil || settings.Version != 0 {
+		t.Fatalf("GetSettings() before save = %+v, %v", settings, err)
+	}
+
+	settings.SessionTTLSeconds = 900
+	settings.EmailDomains = &auth.EmailDomains{Allow: []string{"Example.com", "example.com"}}
+	if err := UpdateSettings(ctx, store, settings, "admin"); err != nil {
+		t.Fatalf("UpdateSettings() error = %v", err)
+	}
+
+	got, _ := GetSettings(ctx, store)
+	if got.Version != 1 || got.UpdatedBy != "admin" || got.UpdatedAt.IsZero() || len(got.EmailDomains.Allow) != 1 || got.EmailDomains.Allow[0] != "example.com" {
+		t.Errorf("GetSettings() = %+v", got)
+	}
+
+	got.SessionTTLSeconds = -5
+	if err := UpdateSettings(ctx, store, got, "admin"); !errors.Is(err, auth.ErrInvalidSettings) {
+		t.Errorf("UpdateSettings() invalid error = %v, want ErrInvalidSettings", err)
+	}
+	if err := UpdateSettings(ctx, store, &auth.Settings{}, "admin"); err != auth.ErrVersionConflict {
+		t.Errorf("UpdateSettings() stale error = %v, want ErrVersionConflict", err)
+	}
+}
+
+func TestWithTokenTTL(t *testing.T) {
+	gen := fake.NewTokenGenerator()
+	id := auth.NewUserID()
+
+	token, _ := WithTokenTTL(gen, 10*time.Minute).GenerateToken(id)
+	if want := "token-" + id.String() + "-ttl-600"; token != want {
+		t.Errorf("token = %q, want %q", token, want)
+	}
+	if WithTokenTTL(gen, 0) != TokenGenerator(gen) {
+		t.Error("WithTokenTTL() without TTL did not return the generator")
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+// ExchangeToken derives from the token with parent claims a token limited to
+// the permissions in scope, expiring after ttl or with its parent, whichever
+// comes first. A scoped parent can only be narrowed further: every
+// permission asked for must be covered by its scope. The derived token
+// never grants more than its subject holds, as scope is checked on top of
+// the subject's permissions (see middleware.ScopedChecker).
+func ExchangeToken(gen ScopedTokenGenerator, parent crypto.TokenClaims, scope []string, ttl time.Duration) (string, []string, time.Time, error) {
+	if gen == nil {
+		return "", nil, time.Time{}, fmt.Errorf("scoped token generator is required")
+	}
+	if ttl <= 0 {
+		return "", nil, time.Time{}, fmt.Errorf("token TTL must be positive")
+	}
+
+	scope = slices.Clone(scope)
+	slices.Sort(scope)
+	scope = slices.Compact(scope)
+	if len(scope) == 0 {
+		return "", nil, time.Time{}, fmt.Errorf("%w: at least one permission is required", auth.ErrInvalidScope)
+	}
+	for _, p := range scope {
+		if p == "" || strings.ContainsAny(p, " \t\r\n") {
+			return "", nil, time.Time{}, fmt.Errorf("%w: %q is not a permission", auth.ErrInvalidScope, p)
+		}
+		if len(parent.Scope) > 0 && !auth.HasPermission(parent.Scope, p) {
+			return "", nil, time.Time{}, fmt.Errorf("%w: %s is outside the token's scope", auth.ErrInvalidScope, p)
+		}
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	if parent.ExpiresAt > 0 && time.Unix(parent.ExpiresAt, 0).Before(expiresAt) {
+		expiresAt = time.Unix(parent.ExpiresAt, 0)
+	}
+
+	token, err := gen.GenerateScopedToken(parent, scope, expiresAt)
+	if err != nil {
+		return "", nil, time.Time{}, err
+	}
+	return token, scope, expiresAt, nil
+}
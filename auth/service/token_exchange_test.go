@@ -0,0 +1,67 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+func TestExchangeToken(t *testing.T) {
+	pubKey, privKey, _ := ed25519.GenerateKey(nil)
+	gen := NewDefaultTokenGenerator(privKey, time.Hour)
+	parent := crypto.TokenClaims{Subject: "user-1", SessionID: "session-1", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+
+	token, scope, expiresAt, err := ExchangeToken(gen, parent, []string{"orders:read", "invoices:read", "orders:read"}, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("ExchangeToken() error = %v", err)
+	}
+	if want := []string{"invoices:read", "orders:read"}; !slices.Equal(scope, want) {
+		t.Errorf("scope = %v, want %v", scope, want)
+	}
+	if d := time.Until(expiresAt); d > 5*time.Minute || d < 4*time.Minute {
+		t.Errorf("expires in %v, want about 5m", d)
+	}
+	claims, err := crypto.VerifyToken(token, pubKey)
+	if err != nil || claims.Subject != "user-1" || !slices.Equal(claims.Scope, scope) {
+		t.Fatalf("derived claims = %+v, %v", claims, err)
+	}
+
+	t.Run("capped by parent expiry", func(t *testing.T) {
+		short := parent
+		short.ExpiresAt = time.Now().Add(time.Minute).Unix()
+		_, _, expiresAt, err := ExchangeToken(gen, short, []string{"orders:read"}, time.Hour)
+		if err != nil || expiresAt.Unix() != short.ExpiresAt {
+			t.Errorf("ExchangeToken() expiresAt = %v, %v, want parent expiry", expiresAt, err)
+		}
+	})
+
+	t.Run("narrows scoped parent", func(t *testing.T) {
+		if _, _, _, err := ExchangeToken(gen, claims, []string{"orders:read"}, time.Minute); err != nil {
+			t.Errorf("ExchangeToken() within scope error = %v", err)
+		}
+		if _, _, _, err := ExchangeToken(gen, claims, []string{"orders:write"}, time.Minute); !errors.Is(err, auth.ErrInvalidScope) {
+			t.Errorf("ExchangeToken() widening scope error = %v, want ErrInvalidScope", err)
+		}
+	})
+
+	for name, scope := range map[string][]string{
+		"empty":      nil,
+		"blank":      {""},
+		"whitespace": {"orders:read orders:write"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, _, err := ExchangeToken(gen, parent, scope, time.Minute); !errors.Is(err, auth.ErrInvalidScope) {
+				t.Errorf("ExchangeToken() error = %v, want ErrInvalidScope", err)
+			}
+		})
+	}
+
+	if _, _, _, err := ExchangeToken(gen, parent, []string{"orders:read"}, 0); err == nil {
+		t.Error("ExchangeToken() with zero TTL succeeded")
+	}
+}
@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// withinTx runs fn through tx, or directly when tx is nil.
+func withinTx(ctx context.Context, tx auth.Transactor, fn func(ctx context.Context) error) error {
+	if tx == nil {
+		return fn(ctx)
+	}
+	return tx.WithinTx(ctx, fn)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// SuspendUser suspends an active or pending user, which blocks every
+// sign-in until ReactivateUser. Suspending a suspended or deleted user
+// fails with auth.ErrUserStatusConflict.
+func SuspendUser(ctx context.Context, store auth.UserStore, id auth.UserID, suspendedBy string) (*auth.User, error) {
+	return setUserStatus(ctx, store, id, suspendedBy, []auth.UserStatus{auth.UserStatusActive, auth.UserStatusPending}, func(user *auth.User) {
+		user.SuspendedFrom = user.Status
+		user.Status = auth.UserStatusSuspended
+	})
+}
+
+// ReactivateUser gives a suspended user back the status they had before
+// suspension, active for users suspended without one recorded.
+// Reactivating a user that is not suspended fails with
+// auth.ErrUserStatusConflict.
+func ReactivateUser(ctx context.Context, store auth.UserStore, id auth.UserID, reactivatedBy string) (*auth.User, error) {
+	return setUserStatus(ctx, store, id, reactivatedBy, []auth.UserStatus{auth.UserStatusSuspended}, func(user *auth.User) {
+		user.Status = user.SuspendedFrom
+		if user.Status == "" {
+			user.Status = auth.UserStatusActive
+		}
+		user.SuspendedFrom = ""
+	})
+}
+
+// setUserStatus applies change to the user if their status is one of from.
+func setUserStatus(ctx context.Context, store auth.UserStore, id auth.UserID, updatedBy string, from []auth.UserStatus, change func(*auth.User)) (*auth.User, error) {
+	if store == nil {
+		return nil, fmt.Errorf("user store is required")
+	}
+
+	user, err := store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(from, user.Status) {
+		return nil, fmt.Errorf("%w: user is %s", auth.ErrUserStatusConflict, user.Status)
+	}
+
+	change(user)
+	if updatedBy != "" {
+		user.UpdatedBy = updatedBy
+	}
+	user.BeforeUpdate()
+
+	if err := store.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestSuspendAndReactivateUser(t *testing.T) {
+	ctx := context.Background()
+	users := fake.NewUserStore()
+	crypto := fake.NewCryptoService()
+	tokenGen := fake.NewTokenGenerator()
+
+	user, err := SignUp(ctx, users, crypto, "ann@example.com", "Password123!", "ann", "Ann")
+	if err != nil {
+		t.Fatalf("SignUp() error = %v", err)
+	}
+
+	if _, err := ReactivateUser(ctx, users, user.ID, "admin"); !errors.Is(err, auth.ErrUserStatusConflict) {
+		t.Errorf("ReactivateUser() active user error = %v, want ErrUserStatusConflict", err)
+	}
+
+	suspended, err := SuspendUser(ctx, users, user.ID, "admin")
+	if err != nil {
+		t.Fatalf("SuspendUser() error = %v", err)
+	}
+	if suspended.Status != auth.UserStatusSuspended || suspended.UpdatedBy != "admin" {
+		t.Errorf("SuspendUser() = %+v", suspended)
+	}
+	if _, _, err := SignIn(ctx, users, crypto, tokenGen, "ann@example.com", "Password123!"); !errors.Is(err, auth.ErrInactiveAccount) {
+		t.Errorf("SignIn() while suspended error = %v, want ErrInactiveAccount", err)
+	}
+	if _, err := SuspendUser(ctx, users, user.ID, "admin"); !errors.Is(err, auth.ErrUserStatusConflict) {
+		t.Errorf("SuspendUser() twice error = %v, want ErrUserStatusConflict", err)
+	}
+
+	reactivated, err := ReactivateUser(ctx, users, user.ID, "admin")
+	if err != nil || reactivated.Status != auth.UserStatusActive {
+		t.Fatalf("ReactivateUser() = %+v, %v", reactivated, err)
+	}
+	if _, _, err := SignIn(ctx, users, crypto, tokenGen, "ann@example.com", "Password123!"); err != nil {
+		t.Errorf("SignIn() after reactivation error = %v", err)
+	}
+
+	if _, err := SuspendUser(ctx, users, auth.NewUserID(), "admin"); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("SuspendUser() unknown user error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestReactivateUserRestoresStatus(t *testing.T) {
+	ctx := context.Background()
+	users := fake.NewUserStore()
+
+	user, err := SignUp(ctx, users, fake.NewCryptoService(), "pat@example.com", "Password123!", "pat", "Pat")
+	if err != nil {
+		t.Fatalf("SignUp() error = %v", err)
+	}
+	user.Status = auth.UserStatusPending
+	if err := users.Update(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	suspended, err := SuspendUser(ctx, users, user.ID, "admin")
+	if err != nil {
+		t.Fatalf("SuspendUser() error = %v", err)
+	}
+	if suspended.Status != auth.UserStatusSuspended || suspended.SuspendedFrom != auth.UserStatusPending {
+		t.Errorf("SuspendUser() = %s from %s, want suspended from pending", suspended.Status, suspended.SuspendedFrom)
+	}
+
+	reactivated, err := ReactivateUser(ctx, users, user.ID, "admin")
+	if err != nil {
+		t.Fatalf("ReactivateUser() error = %v", err)
+	}
+	if reactivated.Status != auth.UserStatusPending || reactivated.SuspendedFrom != "" {
+		t.Errorf("ReactivateUser() = %s from %s, want pending", reactivated.Status, reactivated.SuspendedFrom)
+	}
+	stored, _ := users.Get(ctx, user.ID)
+	if stored.Status != auth.UserStatusPending {
+		t.Errorf("stored status = %s, want pending", stored.Status)
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// DefaultUsernameHold is how long a username given up by one user cannot be
+// taken by another when no hold is given.
+const DefaultUsernameHold = 30 * 24 * time.Hour
+
+// CheckUsernameReleased fails with auth.ErrUsernameReserved when username
+// was given up within hold by a user other than userID, who may take it
+// back. userID is zero for new users. A nil history checks nothing.
+func CheckUsernameReleased(ctx context.Context, history auth.UsernameHistoryStore, hold time.Duration, username string, userID auth.UserID) error {
+	if history == nil {
+		return nil
+	}
+	if hold <= 0 {
+		hold = DefaultUsernameHold
+	}
+
+	released, err := history.ReleasedSince(ctx, auth.NormalizeUsername(username), time.Now().Add(-hold))
+	if err != nil {
+		return fmt.Errorf("check username history: %w", err)
+	}
+	for _, change := range released {
+		if change.UserID != userID {
+			return auth.ErrUsernameReserved
+		}
+	}
+	return nil
+}
+
+// ChangeUsername renames the user with userID. The new username must be
+// valid, unused and, with history, not released by someone else within
+// hold; the old one is then recorded in history as one unit of work run by
+// tx. Grants are held by username, so services keeping grants must move
+// them to the new name.
+func ChangeUsername(ctx context.Context, tx auth.Transactor, store auth.UserStore, history auth.UsernameHistoryStore, hold time.Duration, userID auth.UserID, username, changedBy string) (*auth.User, error) {
+	if store == nil {
+		return nil, fmt.Errorf("user store is required")
+	}
+
+	username = auth.NormalizeUsername(username)
+	if err := auth.ValidateUsername(username); err != nil {
+		return nil, err
+	}
+
+	user, err := store.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Username == username {
+		return user, nil
+	}
+
+	existing, err := store.GetByUsername(ctx, username)
+	if err != nil && !errors.Is(err, auth.ErrUserNotFound) {
+		return nil, fmt.Errorf("check existing username: %w", err)
+	}
+	if existing != nil {
+		return nil, auth.ErrUsernameExists
+	}
+	if err := CheckUsernameReleased(ctx, history, hold, username, userID); err != nil {
+		return nil, err
+	}
+
+	change := &auth.UsernameChange{
+		UserID:      userID,
+		OldUsername: user.Username,
+		NewUsername: username,
+		ChangedBy:   changedBy,
+		ChangedAt:   time.Now(),
+	}
+	updated := *user
+	updated.Username = username
+	updated.UpdatedBy = changedBy
+	updated.BeforeUpdate()
+
+	err = withinTx(ctx, tx, func(ctx context.Context) error {
+		if err := store.Update(ctx, &updated); err != nil {
+			return err
+		}
+		if history == nil {
+			return nil
+		}
+		return history.Record(ctx, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
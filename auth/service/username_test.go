@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func TestChangeUsername(t *testing.T) {
+	ctx := context.Background()
+	store := fake.NewUserStore()
+	history := fake.NewUsernameHistoryStore()
+	crypto := fake.NewCryptoService()
+
+	ann, _ := SignUp(ctx, store, crypto, "ann@example.com", "Password123!", "ann", "Ann")
+	bob, _ := SignUp(ctx, store, crypto, "bob@example.com", "Password123!", "bob", "Bob")
+
+	if _, err := ChangeUsername(ctx, nil, store, history, 0, ann.ID, "bob", "ann"); !errors.Is(err, auth.ErrUsernameExists) {
+		t.Errorf("ChangeUsername(taken) error = %v, want ErrUsernameExists", err)
+	}
+	if _, err := ChangeUsername(ctx, nil, store, history, 0, ann.ID, "x", "ann"); !errors.Is(err, auth.ErrInvalidUsername) {
+		t.Errorf("ChangeUsername(invalid) error = %v, want ErrInvalidUsername", err)
+	}
+
+	user, err := ChangeUsername(ctx, fake.NewTransactor(), store, history, 0, ann.ID, " Annie ", "ann")
+	if err != nil {
+		t.Fatalf("ChangeUsername() error = %v", err)
+	}
+	if user.Username != "annie" || user.UpdatedBy != "ann" {
+		t.Errorf("ChangeUsername() = %+v", user)
+	}
+	if _, err := store.GetByUsername(ctx, "ann"); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("GetByUsername(old) error = %v, want ErrUserNotFound", err)
+	}
+	changes, _ := history.ListByUser(ctx, ann.ID)
+	if len(changes) != 1 || changes[0].OldUsername != "ann" || changes[0].NewUsername != "annie" || changes[0].ChangedBy != "ann" {
+		t.Errorf("history = %+v", changes)
+	}
+
+	if _, err := ChangeUsername(ctx, nil, store, history, 0, bob.ID, "ann", "bob"); !errors.Is(err, auth.ErrUsernameReserved) {
+		t.Errorf("ChangeUsername(released) error = %v, want ErrUsernameReserved", err)
+	}
+	if err := CheckUsernameReleased(ctx, history, 0, "ann", auth.UserID{}); !errors.Is(err, auth.ErrUsernameReserved) {
+		t.Errorf("CheckUsernameReleased(new user) error = %v, want ErrUsernameReserved", err)
+	}
+	if err := CheckUsernameReleased(ctx, history, time.Nanosecond, "ann", auth.UserID{}); err != nil {
+		t.Errorf("CheckUsernameReleased() after hold error = %v", err)
+	}
+
+	if user, err := ChangeUsername(ctx, nil, store, history, 0, ann.ID, "ann", "ann"); err != nil || user.Username != "ann" {
+		t.Errorf("ChangeUsername(back) = %v, %v", user, err)
+	}
+
+	if _, err := ChangeUsername(ctx, nil, store, nil, 0, bob.ID, "annie", "bob"); err != nil {
+		t.Errorf("ChangeUsername() without history error = %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestServiceAccountBeforeCreate(t *testing.T) {
+	a := NewServiceAccount()
+	a.Name = "Billing-Worker"
+	a.BeforeCreate()
+
+	if a.Name != "billing-worker" {
+		t.Errorf("Name = %q, want normalized billing-worker", a.Name)
+	}
+	if !strings.HasPrefix(a.ClientID, "sa_") {
+		t.Errorf("ClientID = %q, want an sa_ prefix", a.ClientID)
+	}
+	if a.Principal() != "sa:billing-worker" {
+		t.Errorf("Principal() = %q", a.Principal())
+	}
+	if !IsServiceAccountPrincipal(a.Principal()) || IsServiceAccountPrincipal("billing-worker") {
+		t.Error("IsServiceAccountPrincipal() should only match service account principals")
+	}
+
+	other := NewServiceAccount()
+	other.BeforeCreate()
+	if other.ClientID == a.ClientID {
+		t.Error("BeforeCreate() should generate unique client IDs")
+	}
+}
+
+func TestServiceAccountSecret(t *testing.T) {
+	a := NewServiceAccount()
+	if a.VerifySecret("") {
+		t.Error("VerifySecret() should fail before a secret is set")
+	}
+
+	secret, err := a.SetSecret()
+	if err != nil {
+		t.Fatalf("SetSecret() error = %v", err)
+	}
+	if !a.VerifySecret(secret) {
+		t.Error("VerifySecret() should accept the generated secret")
+	}
+	if a.VerifySecret(secret + "x") {
+		t.Error("VerifySecret() should reject other secrets")
+	}
+
+	rotated, _ := a.SetSecret()
+	if a.VerifySecret(secret) || !a.VerifySecret(rotated) {
+		t.Error("SetSecret() should replace the previous secret")
+	}
+}
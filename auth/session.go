@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+// Session is a signed-in session. ID is the session ID carried in the
+// token's sid claim and UserID its subject.
+type Session struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewSession creates a session for userID with a fresh ID that expires after ttl.
+func NewSession(userID string, ttl time.Duration) *Session {
+	now := time.Now()
+	return &Session{
+		ID:        crypto.GenerateSessionID(),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+}
+
+// Expired reports whether the session has expired at now.
+func (s *Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
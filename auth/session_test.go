@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewSession(t *testing.T) {
+	s := NewSession("user-1", time.Hour)
+
+	if s.ID == "" {
+		t.Error("NewSession() ID is empty")
+	}
+	if s.UserID != "user-1" {
+		t.Errorf("NewSession() UserID = %q, want user-1", s.UserID)
+	}
+	if got := s.ExpiresAt.Sub(s.CreatedAt); got != time.Hour {
+		t.Errorf("NewSession() lifetime = %v, want 1h", got)
+	}
+	if NewSession("user-1", time.Hour).ID == s.ID {
+		t.Error("NewSession() reused a session ID")
+	}
+}
+
+func TestSessionExpired(t *testing.T) {
+	s := NewSession("user-1", time.Minute)
+
+	if s.Expired(s.CreatedAt) {
+		t.Error("Expired() at creation = true, want false")
+	}
+	if !s.Expired(s.ExpiresAt) {
+		t.Error("Expired() at ExpiresAt = false, want true")
+	}
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// Bounds on the values Settings may hold.
+const (
+	MaxSessionTTL     = 30 * 24 * time.Hour
+	MinPasswordLength = 8
+	MaxPasswordLength = 128
+)
+
+// Settings are the auth policies of the deployment that administrators can
+// change at runtime. Each unset field leaves the static configuration in
+// force, so an empty Settings changes nothing.
+type Settings struct {
+	// SessionTTLSeconds is how long session tokens issued at sign-in stay
+	// valid.
+	SessionTTLSeconds int `json:"session_ttl_seconds,omitempty"`
+	// MFARequired asks for a second factor on every password sign-in from
+	// an untrusted device, which then gets an MFA-pending token instead of
+	// the session token.
+	MFARequired *bool `json:"mfa_required,omitempty"`
+	// PasswordPolicy tightens the built-in password rules for new
+	// passwords.
+	PasswordPolicy *PasswordPolicy `json:"password_policy,omitempty"`
+	// EmailDomains replaces the static lists of email domains allowed and
+	// denied to create accounts.
+	EmailDomains *EmailDomains `json:"email_domains,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+
+	// Version is incremented by the store on every save. Save fails with
+	// ErrVersionConflict when it no longer matches the stored version; it
+	// is zero until the settings are first saved.
+	Version int64 `json:"version"`
+}
+
+// PasswordPolicy holds password requirements on top of the built-in ones,
+// which always apply.
+type PasswordPolicy struct {
+	MinLength int `json:"min_length,omitempty"`
+}
+
+// SessionTTL returns the session token lifetime, or fallback when the
+// settings leave it unset.
+func (s *Settings) SessionTTL(fallback time.Duration) time.Duration {
+	if s == nil || s.SessionTTLSeconds <= 0 {
+		return fallback
+	}
+	return time.Duration(s.SessionTTLSeconds) * time.Second
+}
+
+// RequiresMFA reports whether sign-ins need a second factor, or fallback
+// when the settings leave it unset.
+func (s *Settings) RequiresMFA(fallback bool) bool {
+	if s == nil || s.MFARequired == nil {
+		return fallback
+	}
+	return *s.MFARequired
+}
+
+// CheckPassword checks password against the password policy. The
+// built-in rules are checked by ValidatePassword.
+func (s *Settings) CheckPassword(password string) error {
+	if s == nil || s.PasswordPolicy == nil {
+		return nil
+	}
+	if minLength := s.PasswordPolicy.MinLength; utf8.RuneCountInString(password) < minLength {
+		return fmt.Errorf("%w: password must be at least %d characters", ErrInvalidPassword, minLength)
+	}
+	return nil
+}
+
+// EmailDomainsOr returns the email domain lists, or fallback when the
+// settings leave them unset.
+func (s *Settings) EmailDomainsOr(fallback EmailDomains) EmailDomains {
+	if s == nil || s.EmailDomains == nil {
+		return fallback
+	}
+	return *s.EmailDomains
+}
+
+// Normalize normalizes the email domain lists.
+func (s *Settings) Normalize() {
+	if s.EmailDomains != nil {
+		s.EmailDomains.Normalize()
+	}
+}
+
+// Validate checks that every set value is within bounds.
+func (s *Settings) Validate() error {
+	if s.SessionTTLSeconds < 0 || time.Duration(s.SessionTTLSeconds)*time.Second > MaxSessionTTL {
+		return fmt.Errorf("%w: session_ttl_seconds must be between 0 and %d", ErrInvalidSettings, int(MaxSessionTTL/time.Second))
+	}
+	if p := s.PasswordPolicy; p != nil && p.MinLength != 0 && (p.MinLength < MinPasswordLength || p.MinLength > MaxPasswordLength) {
+		return fmt.Errorf("%w: password_policy.min_length must be between %d and %d", ErrInvalidSettings, MinPasswordLength, MaxPasswordLength)
+	}
+	if s.EmailDomains != nil {
+		if err := s.EmailDomains.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+		}
+	}
+	return nil
+}
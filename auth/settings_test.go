@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSettingsFallbacks(t *testing.T) {
+	var unset *Settings
+	if unset.SessionTTL(time.Hour) != time.Hour || unset.RequiresMFA(true) != true {
+		t.Error("nil settings do not fall back")
+	}
+
+	required := false
+	s := &Settings{SessionTTLSeconds: 600, MFARequired: &required}
+	if got := s.SessionTTL(time.Hour); got != 10*time.Minute {
+		t.Errorf("SessionTTL() = %v, want 10m", got)
+	}
+	if s.RequiresMFA(true) {
+		t.Error("RequiresMFA() ignored the setting")
+	}
+}
+
+func TestSettingsCheckPassword(t *testing.T) {
+	s := &Settings{PasswordPolicy: &PasswordPolicy{MinLength: 12}}
+	if err := s.CheckPassword("Short123!"); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("CheckPassword() short error = %v, want ErrInvalidPassword", err)
+	}
+	if err := s.CheckPassword("LongEnough123!"); err != nil {
+		t.Errorf("CheckPassword() error = %v", err)
+	}
+	if err := (&Settings{}).CheckPassword("x"); err != nil {
+		t.Errorf("CheckPassword() without policy error = %v", err)
+	}
+}
+
+func TestSettingsEmailDomains(t *testing.T) {
+	fallback := EmailDomains{Deny: []string{"evil.com"}}
+	var unset *Settings
+	if got := unset.EmailDomainsOr(fallback); !slices.Equal(got.Deny, fallback.Deny) {
+		t.Errorf("EmailDomainsOr() on nil settings = %+v, want fallback", got)
+	}
+
+	s := &Settings{EmailDomains: &EmailDomains{Allow: []string{" @Example.com", "example.com"}}}
+	s.Normalize()
+	got := s.EmailDomainsOr(fallback)
+	if !slices.Equal(got.Allow, []string{"example.com"}) || got.Deny != nil {
+		t.Errorf("EmailDomainsOr() = %+v, want normalized settings lists", got)
+	}
+}
+
+func TestSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		wantErr  bool
+	}{
+		{"empty", Settings{}, false},
+		{"full", Settings{SessionTTLSeconds: 3600, PasswordPolicy: &PasswordPolicy{MinLength: 12}, EmailDomains: &EmailDomains{Allow: []string{"example.com"}}}, false},
+		{"negative TTL", Settings{SessionTTLSeconds: -1}, true},
+		{"TTL too long", Settings{SessionTTLSeconds: int(MaxSessionTTL/time.Second) + 1}, true},
+		{"min length too short", Settings{PasswordPolicy: &PasswordPolicy{MinLength: 4}}, true},
+		{"min length too long", Settings{PasswordPolicy: &PasswordPolicy{MinLength: 200}}, true},
+		{"bad domain", Settings{EmailDomains: &EmailDomains{Deny: []string{"not a domain"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.settings.Validate()
+			if tt.wantErr != (err != nil) || (err != nil && !errors.Is(err, ErrInvalidSettings)) {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -41,3 +41,23 @@ func (s RoleStatus) IsValid() bool {
 		return false
 	}
 }
+
+type ServiceAccountStatus string
+
+const (
+	ServiceAccountStatusActive   ServiceAccountStatus = "active"
+	ServiceAccountStatusDisabled ServiceAccountStatus = "disabled"
+)
+
+func (s ServiceAccountStatus) String() string {
+	return string(s)
+}
+
+func (s ServiceAccountStatus) IsValid() bool {
+	switch s {
+	case ServiceAccountStatusActive, ServiceAccountStatusDisabled:
+		return true
+	default:
+		return false
+	}
+}
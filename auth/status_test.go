@@ -86,3 +86,24 @@ func TestRoleStatusIsValid(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceAccountStatusIsValid(t *testing.T) {
+	tests := []struct {
+		name   string
+		status ServiceAccountStatus
+		want   bool
+	}{
+		{"active is valid", ServiceAccountStatusActive, true},
+		{"disabled is valid", ServiceAccountStatusDisabled, true},
+		{"empty is invalid", ServiceAccountStatus(""), false},
+		{"user status is invalid", ServiceAccountStatus("suspended"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.status.IsValid(); got != tt.want {
+				t.Errorf("ServiceAccountStatus.IsValid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
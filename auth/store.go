@@ -2,37 +2,197 @@ package auth
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type UserStore interface {
 	Create(ctx context.Context, user *User) error
-	Get(ctx context.Context, id uuid.UUID) (*User, error)
+	Get(ctx context.Context, id UserID) (*User, error)
 	GetByEmailLookup(ctx context.Context, lookup []byte) (*User, error)
 	GetByUsername(ctx context.Context, username string) (*User, error)
 	GetByPINLookup(ctx context.Context, lookup []byte) (*User, error)
 	Update(ctx context.Context, user *User) error
-	Delete(ctx context.Context, id uuid.UUID) error
+	Delete(ctx context.Context, id UserID) error
 	List(ctx context.Context) ([]*User, error)
 	ListByStatus(ctx context.Context, status UserStatus) ([]*User, error)
+	// Search returns the page of users matching q, newest first. It
+	// normalizes q and returns ErrInvalidUserQuery when it is invalid.
+	Search(ctx context.Context, q UserQuery) (*UserPage, error)
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
 }
 
 type RoleStore interface {
 	Create(ctx context.Context, role *Role) error
-	Get(ctx context.Context, id uuid.UUID) (*Role, error)
+	Get(ctx context.Context, id RoleID) (*Role, error)
 	GetByName(ctx context.Context, name string) (*Role, error)
 	Update(ctx context.Context, role *Role) error
-	Delete(ctx context.Context, id uuid.UUID) error
+	Delete(ctx context.Context, id RoleID) error
 	List(ctx context.Context) ([]*Role, error)
 	ListByStatus(ctx context.Context, status RoleStatus) ([]*Role, error)
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
 }
 
 type GrantStore interface {
 	Create(ctx context.Context, grant *Grant) error
-	Delete(ctx context.Context, username string, roleID uuid.UUID) error
+	Delete(ctx context.Context, username string, roleID RoleID) error
 	GetUserGrants(ctx context.Context, username string) ([]*Grant, error)
-	GetRoleGrants(ctx context.Context, roleID uuid.UUID) ([]*Grant, error)
+	GetRoleGrants(ctx context.Context, roleID RoleID) ([]*Grant, error)
 	GetUserRoles(ctx context.Context, username string) ([]*Role, error)
 	HasRole(ctx context.Context, username string, roleName string) (bool, error)
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
+}
+
+type IdentityStore interface {
+	Create(ctx context.Context, identity *Identity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*Identity, error)
+	ListByUser(ctx context.Context, userID UserID) ([]*Identity, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
+}
+
+// ServiceAccountStore persists service accounts. Names and client IDs are
+// unique; Create returns ErrServiceAccountExists for duplicates.
+type ServiceAccountStore interface {
+	Create(ctx context.Context, account *ServiceAccount) error
+	Get(ctx context.Context, id uuid.UUID) (*ServiceAccount, error)
+	GetByClientID(ctx context.Context, clientID string) (*ServiceAccount, error)
+	Update(ctx context.Context, account *ServiceAccount) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context) ([]*ServiceAccount, error)
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
+}
+
+// SessionStore keeps track of issued sessions so they can be looked up and
+// revoked before their tokens expire. Get returns ErrSessionNotFound for
+// sessions that were deleted or have expired.
+type SessionStore interface {
+	Create(ctx context.Context, session *Session) error
+	Get(ctx context.Context, id string) (*Session, error)
+	ListByUser(ctx context.Context, userID string) ([]*Session, error)
+	Delete(ctx context.Context, id string) error
+	DeleteByUser(ctx context.Context, userID string) error
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
+}
+
+// RevocationStore keeps token revocations until they expire. IsRevoked
+// reports whether an unexpired revocation covers a token, as
+// Revocation.Covers; DeleteExpired removes the expired ones and returns how
+// many, bounding the store to the revocations of one token lifetime.
+type RevocationStore interface {
+	Create(ctx context.Context, revocation *Revocation) error
+	IsRevoked(ctx context.Context, userID, sessionID string, issuedAt time.Time) (bool, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
+}
+
+// DeviceStore keeps the devices users sign in from. A user has at most one
+// device per fingerprint; Create returns ErrDeviceAlreadyExists otherwise.
+// ListByUser returns the user's devices most recently seen first.
+type DeviceStore interface {
+	Create(ctx context.Context, device *Device) error
+	Get(ctx context.Context, id DeviceID) (*Device, error)
+	GetByFingerprint(ctx context.Context, userID UserID, fingerprint string) (*Device, error)
+	Update(ctx context.Context, device *Device) error
+	Delete(ctx context.Context, id DeviceID) error
+	ListByUser(ctx context.Context, userID UserID) ([]*Device, error)
+	DeleteByUser(ctx context.Context, userID UserID) error
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
+}
+
+// ConsentStore keeps users' policy acceptances. A user accepts each version
+// of a policy at most once; Create returns ErrConsentAlreadyExists otherwise.
+type ConsentStore interface {
+	Create(ctx context.Context, consent *Consent) error
+	// Get returns userID's acceptance of version of policy, or
+	// ErrConsentNotFound.
+	Get(ctx context.Context, userID UserID, policy, version string) (*Consent, error)
+	// ListByUser returns every acceptance of userID, newest first.
+	ListByUser(ctx context.Context, userID UserID) ([]*Consent, error)
+	// ListAccepted returns the users that accepted version of policy.
+	ListAccepted(ctx context.Context, policy, version string) ([]UserID, error)
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
+}
+
+// PermissionSetStore keeps permission sets by name. Create returns
+// ErrPermissionSetExists for taken names and Update fails with
+// ErrVersionConflict when the set changed since it was read.
+type PermissionSetStore interface {
+	Create(ctx context.Context, set *PermissionSet) error
+	Get(ctx context.Context, name string) (*PermissionSet, error)
+	Update(ctx context.Context, set *PermissionSet) error
+	Delete(ctx context.Context, name string) error
+	// List returns every set ordered by name.
+	List(ctx context.Context) ([]*PermissionSet, error)
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
+}
+
+// GrantRequestStore keeps requests for role grants awaiting approval.
+// Update only stores decisions of requests still pending, failing with
+// ErrGrantRequestDecided otherwise, so two approvers cannot both decide a
+// request.
+type GrantRequestStore interface {
+	Create(ctx context.Context, req *GrantRequest) error
+	Get(ctx context.Context, id GrantRequestID) (*GrantRequest, error)
+	Update(ctx context.Context, req *GrantRequest) error
+	// List returns the requests in status, or every request when status is
+	// empty, oldest first.
+	List(ctx context.Context, status GrantRequestStatus) ([]*GrantRequest, error)
+	// ListByUser returns the requests for username, newest first.
+	ListByUser(ctx context.Context, username string) ([]*GrantRequest, error)
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
+}
+
+// UsernameHistoryStore keeps the usernames users gave up, so they are not
+// claimed by someone else right after.
+type UsernameHistoryStore interface {
+	Record(ctx context.Context, change *UsernameChange) error
+	// ReleasedSince returns the changes giving up username at or after
+	// since, newest first.
+	ReleasedSince(ctx context.Context, username string, since time.Time) ([]*UsernameChange, error)
+	// ListByUser returns the username changes of a user, newest first.
+	ListByUser(ctx context.Context, userID UserID) ([]*UsernameChange, error)
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
+}
+
+// NonceStore remembers single-use values, such as the nonce of a magic
+// link, until they expire. Use records nonce and reports whether this was
+// its first use; later calls with the same nonce before expiresAt return
+// false.
+type NonceStore interface {
+	Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+}
+
+// Transactor runs a unit of work spanning several stores atomically.
+// WithinTx calls fn with a context carrying the transaction; stores sharing
+// the transactor's backend use it for every call made with that context.
+// The work is committed when fn returns nil and rolled back otherwise.
+// Calls nested in fn join the outer transaction.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// SettingsStore keeps the single Settings record of the deployment. Get
+// returns ErrSettingsNotFound until the settings are first saved. Save
+// creates the record when Version is zero and updates it otherwise, failing
+// with ErrVersionConflict when it changed since it was read; on success it
+// increments Version.
+type SettingsStore interface {
+	Get(ctx context.Context) (*Settings, error)
+	Save(ctx context.Context, settings *Settings) error
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
 }
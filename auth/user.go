@@ -1,16 +1,27 @@
 package auth
 
 import (
+	"errors"
 	"time"
 
 	"github.com/aquamarinepk/aqm/crypto"
-	"github.com/google/uuid"
+)
+
+// KeyScheme selects the key that encrypts a user's email and PIN.
+type KeyScheme int
+
+const (
+	// KeySchemeMaster encrypts with the service's encryption key itself.
+	KeySchemeMaster KeyScheme = iota
+	// KeySchemeDerived encrypts with a key derived from the encryption key
+	// and the user ID, see crypto.DeriveUserKey.
+	KeySchemeDerived
 )
 
 type User struct {
-	ID       uuid.UUID `json:"id" db:"id" bson:"_id"`
-	Username string    `json:"username" db:"username" bson:"username"`
-	Name     string    `json:"name" db:"name" bson:"name"`
+	ID       UserID `json:"id" db:"id" bson:"_id"`
+	Username string `json:"username" db:"username" bson:"username"`
+	Name     string `json:"name" db:"name" bson:"name"`
 
 	EmailCT     []byte `json:"-" db:"email_ct" bson:"email_ct"`
 	EmailIV     []byte `json:"-" db:"email_iv" bson:"email_iv"`
@@ -27,12 +38,25 @@ type User struct {
 	PINTag    []byte `json:"-" db:"pin_tag" bson:"pin_tag,omitempty"`
 	PINLookup []byte `json:"-" db:"pin_lookup" bson:"pin_lookup,omitempty"`
 
+	// KeyScheme tells which key EmailCT and PINCT are encrypted with.
+	KeyScheme KeyScheme `json:"-" db:"key_scheme" bson:"key_scheme,omitempty"`
+
 	Status UserStatus `json:"status" db:"status" bson:"status"`
+	// SuspendedFrom is the status a suspended user had before suspension,
+	// which reactivating restores.
+	SuspendedFrom UserStatus `json:"suspended_from,omitempty" db:"suspended_from" bson:"suspended_from,omitempty"`
+
+	// Attributes holds custom profile data; see Attributes.
+	Attributes Attributes `json:"attributes,omitempty" db:"attributes" bson:"attributes"`
 
 	CreatedAt time.Time `json:"created_at" db:"created_at" bson:"created_at"`
 	CreatedBy string    `json:"created_by" db:"created_by" bson:"created_by"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at" bson:"updated_at"`
 	UpdatedBy string    `json:"updated_by" db:"updated_by" bson:"updated_by"`
+
+	// Version is incremented by the store on every update. Update fails with
+	// ErrVersionConflict when it no longer matches the stored version.
+	Version int64 `json:"version" db:"version" bson:"version"`
 }
 
 func NewUser() *User {
@@ -42,8 +66,8 @@ func NewUser() *User {
 }
 
 func (u *User) EnsureID() {
-	if u.ID == uuid.Nil {
-		u.ID = uuid.New()
+	if u.ID.IsZero() {
+		u.ID = NewUserID()
 	}
 }
 
@@ -52,6 +76,7 @@ func (u *User) BeforeCreate() {
 	now := time.Now()
 	u.CreatedAt = now
 	u.UpdatedAt = now
+	u.Version = 1
 	u.Username = NormalizeUsername(u.Username)
 	u.Name = NormalizeDisplayName(u.Name)
 }
@@ -62,6 +87,23 @@ func (u *User) BeforeUpdate() {
 	u.Name = NormalizeDisplayName(u.Name)
 }
 
+// SetAttribute stores value under key after validating the key.
+func (u *User) SetAttribute(key string, value any) error {
+	if err := ValidateAttributeKey(key); err != nil {
+		return err
+	}
+	if u.Attributes == nil {
+		u.Attributes = Attributes{}
+	}
+	u.Attributes.Set(key, value)
+	return nil
+}
+
+// DeleteAttribute removes the attribute stored under key.
+func (u *User) DeleteAttribute(key string) {
+	u.Attributes.Delete(key)
+}
+
 func (u *User) SetEmail(email string, encryptionKey, signingKey []byte) error {
 	if err := ValidateEmail(email); err != nil {
 		return err
@@ -69,7 +111,11 @@ func (u *User) SetEmail(email string, encryptionKey, signingKey []byte) error {
 
 	normalized := NormalizeEmail(email)
 
-	ct, iv, tag, err := crypto.EncryptEmail(normalized, encryptionKey)
+	key, err := u.dataKey(encryptionKey)
+	if err != nil {
+		return ErrEncryptionFailed
+	}
+	ct, iv, tag, err := crypto.EncryptEmail(normalized, key)
 	if err != nil {
 		return ErrEncryptionFailed
 	}
@@ -85,11 +131,15 @@ func (u *User) SetEmail(email string, encryptionKey, signingKey []byte) error {
 }
 
 func (u *User) GetEmail(encryptionKey []byte) (string, error) {
+	key, err := u.dataKey(encryptionKey)
+	if err != nil {
+		return "", ErrDecryptionFailed
+	}
 	email, err := crypto.DecryptEmail(
 		string(u.EmailCT),
 		string(u.EmailIV),
 		string(u.EmailTag),
-		encryptionKey,
+		key,
 	)
 	if err != nil {
 		return "", ErrDecryptionFailed
@@ -97,29 +147,51 @@ func (u *User) GetEmail(encryptionKey []byte) (string, error) {
 	return email, nil
 }
 
+// SetPassword validates password and hashes it with the default
+// parameters.
 func (u *User) SetPassword(password string) error {
+	return u.SetPasswordWith(password, crypto.DefaultPasswordParams())
+}
+
+// SetPasswordWith validates password and hashes it with params.
+func (u *User) SetPasswordWith(password string, params crypto.PasswordParams) error {
 	if err := ValidatePassword(password); err != nil {
 		return err
 	}
+	return u.RehashPassword(password, params)
+}
 
-	salt, err := crypto.GenerateSalt()
+// RehashPassword hashes password with params without checking the password
+// policy, for upgrading the hash of a password that just verified.
+func (u *User) RehashPassword(password string, params crypto.PasswordParams) error {
+	hash, err := crypto.HashPasswordWith(password, params)
 	if err != nil {
 		return ErrPasswordHashFailed
 	}
 
-	hash := crypto.HashPassword(password, salt)
-	if hash == nil {
-		return ErrPasswordHashFailed
-	}
-
 	u.PasswordHash = hash
-	u.PasswordSalt = salt
+	u.PasswordSalt = []byte{}
 
 	return nil
 }
 
+// VerifyPassword checks password against the stored hash. Hashes stored
+// with a separate salt predate self-describing hashes and use the fixed
+// Argon2id parameters of crypto.HashPassword.
 func (u *User) VerifyPassword(password string) bool {
-	return crypto.VerifyPassword(password, u.PasswordHash, u.PasswordSalt)
+	if len(u.PasswordSalt) > 0 {
+		return crypto.VerifyPassword(password, u.PasswordHash, u.PasswordSalt)
+	}
+	return crypto.VerifyPasswordHash(password, u.PasswordHash)
+}
+
+// PasswordNeedsRehash reports whether the stored hash was not made with
+// params. Salted legacy hashes always need one.
+func (u *User) PasswordNeedsRehash(params crypto.PasswordParams) bool {
+	if len(u.PasswordSalt) > 0 {
+		return true
+	}
+	return crypto.PasswordNeedsRehash(u.PasswordHash, params)
 }
 
 func (u *User) SetPIN(pin string, encryptionKey, signingKey []byte) error {
@@ -127,7 +199,11 @@ func (u *User) SetPIN(pin string, encryptionKey, signingKey []byte) error {
 		return ErrInvalidPassword
 	}
 
-	ct, iv, tag, err := crypto.EncryptEmail(pin, encryptionKey)
+	key, err := u.dataKey(encryptionKey)
+	if err != nil {
+		return ErrEncryptionFailed
+	}
+	ct, iv, tag, err := crypto.EncryptEmail(pin, key)
 	if err != nil {
 		return ErrEncryptionFailed
 	}
@@ -151,6 +227,64 @@ func (u *User) VerifyPIN(pin string, signingKey []byte) bool {
 	return string(u.PINLookup) == lookup
 }
 
+// UseDerivedKey switches the user to KeySchemeDerived: the email and PIN
+// encrypted with encryptionKey under the current scheme are re-encrypted
+// with the user's derived key. It does nothing for users already on it. The
+// change must be stored for the new ciphertexts to be read back.
+func (u *User) UseDerivedKey(encryptionKey []byte) error {
+	if u.KeyScheme == KeySchemeDerived {
+		return nil
+	}
+
+	email, err := u.GetEmail(encryptionKey)
+	if err != nil {
+		return err
+	}
+	var pin string
+	if len(u.PINCT) > 0 {
+		pin, err = crypto.DecryptEmail(string(u.PINCT), string(u.PINIV), string(u.PINTag), encryptionKey)
+		if err != nil {
+			return ErrDecryptionFailed
+		}
+	}
+
+	u.EnsureID()
+	derived := *u
+	derived.KeyScheme = KeySchemeDerived
+	key, err := derived.dataKey(encryptionKey)
+	if err != nil {
+		return ErrEncryptionFailed
+	}
+
+	ct, iv, tag, err := crypto.EncryptEmail(email, key)
+	if err != nil {
+		return ErrEncryptionFailed
+	}
+	derived.EmailCT, derived.EmailIV, derived.EmailTag = []byte(ct), []byte(iv), []byte(tag)
+	if pin != "" {
+		ct, iv, tag, err := crypto.EncryptEmail(pin, key)
+		if err != nil {
+			return ErrEncryptionFailed
+		}
+		derived.PINCT, derived.PINIV, derived.PINTag = []byte(ct), []byte(iv), []byte(tag)
+	}
+
+	*u = derived
+	return nil
+}
+
+// dataKey returns the key the user's email and PIN are encrypted with under
+// u.KeyScheme. Derived keys are bound to the user ID, which must be set.
+func (u *User) dataKey(encryptionKey []byte) ([]byte, error) {
+	if u.KeyScheme != KeySchemeDerived {
+		return encryptionKey, nil
+	}
+	if u.ID.IsZero() {
+		return nil, errors.New("user ID is required to derive its key")
+	}
+	return crypto.DeriveUserKey(encryptionKey, u.ID.String())
+}
+
 func (u *User) Validate() error {
 	if err := ValidateUsername(u.Username); err != nil {
 		return err
@@ -161,5 +295,8 @@ func (u *User) Validate() error {
 	if !u.Status.IsValid() {
 		return ErrInactiveAccount
 	}
+	if err := ValidateAttributes(u.Attributes); err != nil {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Default and maximum page sizes for UserStore.Search.
+const (
+	DefaultUserQueryLimit = 50
+	MaxUserQueryLimit     = 500
+)
+
+// UserQuery selects users in UserStore.Search. Zero fields match everything.
+// CreatedAfter is inclusive and CreatedBefore exclusive. Results are ordered
+// newest first.
+type UserQuery struct {
+	// UsernamePrefix matches usernames starting with it.
+	UsernamePrefix string
+	// Name matches display names containing it, ignoring case.
+	Name   string
+	Status UserStatus
+	// Role matches users granted the role with this name.
+	Role          string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// Attributes matches users whose attributes hold every given key with
+	// the given value, compared as text (see Attributes.Text).
+	Attributes map[string]string
+	Limit      int
+	Offset     int
+}
+
+// Normalize applies the default limit, normalizes the username prefix and
+// role name like the stored values and validates the query.
+func (q UserQuery) Normalize() (UserQuery, error) {
+	if q.Limit == 0 {
+		q.Limit = DefaultUserQueryLimit
+	}
+	if q.Limit < 0 || q.Limit > MaxUserQueryLimit || q.Offset < 0 {
+		return q, ErrInvalidUserQuery
+	}
+	if !q.CreatedAfter.IsZero() && !q.CreatedBefore.IsZero() && !q.CreatedAfter.Before(q.CreatedBefore) {
+		return q, ErrInvalidUserQuery
+	}
+	if q.Status != "" && !q.Status.IsValid() {
+		return q, ErrInvalidUserQuery
+	}
+	for key := range q.Attributes {
+		if err := ValidateAttributeKey(key); err != nil {
+			return q, fmt.Errorf("%w: %v", ErrInvalidUserQuery, err)
+		}
+	}
+	q.UsernamePrefix = strings.ToLower(strings.TrimSpace(q.UsernamePrefix))
+	q.Name = strings.TrimSpace(q.Name)
+	q.Role = NormalizeRoleName(q.Role)
+	return q, nil
+}
+
+// Matches reports whether u satisfies every field of q except Role, which
+// needs the user's grants. Stores that filter in memory use it.
+func (q UserQuery) Matches(u *User) bool {
+	if q.UsernamePrefix != "" && !strings.HasPrefix(u.Username, q.UsernamePrefix) {
+		return false
+	}
+	if q.Name != "" && !strings.Contains(strings.ToLower(u.Name), strings.ToLower(q.Name)) {
+		return false
+	}
+	if q.Status != "" && u.Status != q.Status {
+		return false
+	}
+	if !q.CreatedAfter.IsZero() && u.CreatedAt.Before(q.CreatedAfter) {
+		return false
+	}
+	if !q.CreatedBefore.IsZero() && !u.CreatedAt.Before(q.CreatedBefore) {
+		return false
+	}
+	for key, want := range q.Attributes {
+		if got, ok := u.Attributes.Text(key); !ok || got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// UserPage is one page of UserStore.Search results. Total counts every
+// matching user, ignoring Limit and Offset.
+type UserPage struct {
+	Users  []*User
+	Total  int
+	Limit  int
+	Offset int
+}
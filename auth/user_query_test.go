@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUserQueryNormalize(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		query   UserQuery
+		want    UserQuery
+		wantErr bool
+	}{
+		{
+			name:  "defaults",
+			query: UserQuery{},
+			want:  UserQuery{Limit: DefaultUserQueryLimit},
+		},
+		{
+			name:  "normalizes text fields",
+			query: UserQuery{UsernamePrefix: " Ann", Name: " Smith ", Role: "Admin ", Limit: 10},
+			want:  UserQuery{UsernamePrefix: "ann", Name: "Smith", Role: "admin", Limit: 10},
+		},
+		{name: "negative limit", query: UserQuery{Limit: -1}, wantErr: true},
+		{name: "limit too large", query: UserQuery{Limit: MaxUserQueryLimit + 1}, wantErr: true},
+		{name: "negative offset", query: UserQuery{Offset: -1}, wantErr: true},
+		{name: "invalid status", query: UserQuery{Status: "unknown"}, wantErr: true},
+		{name: "empty range", query: UserQuery{CreatedAfter: now, CreatedBefore: now}, wantErr: true},
+		{name: "invalid attribute key", query: UserQuery{Attributes: map[string]string{"Locale": "en"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query.Normalize()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidUserQuery) {
+					t.Errorf("Normalize() error = %v, want ErrInvalidUserQuery", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Normalize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserQueryMatches(t *testing.T) {
+	created := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	user := &User{Username: "ann.smith", Name: "Ann Smith", Status: UserStatusActive, CreatedAt: created,
+		Attributes: Attributes{"locale": "en-GB", "beta": true, "seats": float64(5)}}
+
+	tests := []struct {
+		name  string
+		query UserQuery
+		want  bool
+	}{
+		{name: "empty query", query: UserQuery{}, want: true},
+		{name: "username prefix", query: UserQuery{UsernamePrefix: "ann"}, want: true},
+		{name: "username not prefix", query: UserQuery{UsernamePrefix: "smith"}, want: false},
+		{name: "name substring ignoring case", query: UserQuery{Name: "SMI"}, want: true},
+		{name: "name mismatch", query: UserQuery{Name: "bob"}, want: false},
+		{name: "status", query: UserQuery{Status: UserStatusSuspended}, want: false},
+		{name: "created after inclusive", query: UserQuery{CreatedAfter: created}, want: true},
+		{name: "created before exclusive", query: UserQuery{CreatedBefore: created}, want: false},
+		{name: "role is ignored", query: UserQuery{Role: "admin"}, want: true},
+		{name: "attributes", query: UserQuery{Attributes: map[string]string{"locale": "en-GB", "beta": "true", "seats": "5"}}, want: true},
+		{name: "attribute mismatch", query: UserQuery{Attributes: map[string]string{"locale": "de-DE"}}, want: false},
+		{name: "attribute missing", query: UserQuery{Attributes: map[string]string{"timezone": "UTC"}}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.query.Matches(user); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
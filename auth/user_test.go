@@ -3,7 +3,7 @@ package auth
 import (
 	"testing"
 
-	"github.com/google/uuid"
+	"github.com/aquamarinepk/aqm/crypto"
 )
 
 func TestNewUser(t *testing.T) {
@@ -19,21 +19,21 @@ func TestNewUser(t *testing.T) {
 func TestUserEnsureID(t *testing.T) {
 	tests := []struct {
 		name    string
-		initial uuid.UUID
+		initial UserID
 		wantNil bool
 	}{
-		{"generates ID when nil", uuid.Nil, false},
-		{"keeps existing ID", uuid.New(), false},
+		{"generates ID when nil", UserID{}, false},
+		{"keeps existing ID", NewUserID(), false},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			user := &User{ID: tt.initial}
 			user.EnsureID()
-			if (user.ID == uuid.Nil) == !tt.wantNil {
-				t.Errorf("EnsureID() ID nil = %v, wantNil %v", user.ID == uuid.Nil, tt.wantNil)
+			if (user.ID.IsZero()) == !tt.wantNil {
+				t.Errorf("EnsureID() ID nil = %v, wantNil %v", user.ID.IsZero(), tt.wantNil)
 			}
-			if tt.initial != uuid.Nil && user.ID != tt.initial {
+			if !tt.initial.IsZero() && user.ID != tt.initial {
 				t.Error("EnsureID() changed existing ID")
 			}
 		})
@@ -48,7 +48,7 @@ func TestUserBeforeCreate(t *testing.T) {
 
 	user.BeforeCreate()
 
-	if user.ID == uuid.Nil {
+	if user.ID.IsZero() {
 		t.Error("BeforeCreate() did not generate ID")
 	}
 	if user.Username != "johndoe" {
@@ -161,6 +161,92 @@ func TestUserGetEmailDecryptionError(t *testing.T) {
 	}
 }
 
+func TestUserDerivedKey(t *testing.T) {
+	encKey := make([]byte, 32)
+	signKey := make([]byte, 32)
+
+	user := NewUser()
+	user.EnsureID()
+	user.KeyScheme = KeySchemeDerived
+	if err := user.SetEmail("derived@example.com", encKey, signKey); err != nil {
+		t.Fatalf("SetEmail() error = %v", err)
+	}
+
+	if got, err := user.GetEmail(encKey); err != nil || got != "derived@example.com" {
+		t.Errorf("GetEmail() = %q, %v", got, err)
+	}
+	if _, err := crypto.DecryptEmail(string(user.EmailCT), string(user.EmailIV), string(user.EmailTag), encKey); err == nil {
+		t.Error("derived email decrypts with the master key")
+	}
+
+	other := *user
+	other.ID = NewUserID()
+	if _, err := other.GetEmail(encKey); err != ErrDecryptionFailed {
+		t.Errorf("GetEmail() under another user ID error = %v, want %v", err, ErrDecryptionFailed)
+	}
+
+	noID := &User{KeyScheme: KeySchemeDerived}
+	if err := noID.SetEmail("derived@example.com", encKey, signKey); err != ErrEncryptionFailed {
+		t.Errorf("SetEmail() without ID error = %v, want %v", err, ErrEncryptionFailed)
+	}
+}
+
+func TestUserUseDerivedKey(t *testing.T) {
+	encKey := make([]byte, 32)
+	signKey := make([]byte, 32)
+
+	user := NewUser()
+	user.EnsureID()
+	if err := user.SetEmail("legacy@example.com", encKey, signKey); err != nil {
+		t.Fatalf("SetEmail() error = %v", err)
+	}
+	if err := user.SetPIN("123456", encKey, signKey); err != nil {
+		t.Fatalf("SetPIN() error = %v", err)
+	}
+	lookup := string(user.EmailLookup)
+	masterCT := string(user.EmailCT)
+
+	if err := user.UseDerivedKey(encKey); err != nil {
+		t.Fatalf("UseDerivedKey() error = %v", err)
+	}
+	if user.KeyScheme != KeySchemeDerived {
+		t.Errorf("KeyScheme = %v, want %v", user.KeyScheme, KeySchemeDerived)
+	}
+	if string(user.EmailCT) == masterCT || string(user.EmailLookup) != lookup {
+		t.Error("UseDerivedKey() did not re-encrypt the email or changed its lookup hash")
+	}
+	if got, err := user.GetEmail(encKey); err != nil || got != "legacy@example.com" {
+		t.Errorf("GetEmail() after UseDerivedKey() = %q, %v", got, err)
+	}
+
+	key, _ := crypto.DeriveUserKey(encKey, user.ID.String())
+	if pin, err := crypto.DecryptEmail(string(user.PINCT), string(user.PINIV), string(user.PINTag), key); err != nil || pin != "123456" {
+		t.Errorf("PIN under derived key = %q, %v", pin, err)
+	}
+
+	ct := string(user.EmailCT)
+	if err := user.UseDerivedKey(encKey); err != nil || string(user.EmailCT) != ct {
+		t.Errorf("UseDerivedKey() on a derived user = %v, changed ciphertext %v", err, string(user.EmailCT) != ct)
+	}
+}
+
+func TestUserUseDerivedKeyWrongKey(t *testing.T) {
+	user := NewUser()
+	user.EnsureID()
+	if err := user.SetEmail("legacy@example.com", make([]byte, 32), make([]byte, 32)); err != nil {
+		t.Fatalf("SetEmail() error = %v", err)
+	}
+
+	wrong := make([]byte, 32)
+	wrong[0] = 1
+	if err := user.UseDerivedKey(wrong); err != ErrDecryptionFailed {
+		t.Errorf("UseDerivedKey(wrong key) error = %v, want %v", err, ErrDecryptionFailed)
+	}
+	if user.KeyScheme != KeySchemeMaster {
+		t.Error("UseDerivedKey() failure changed the key scheme")
+	}
+}
+
 func TestUserSetPassword(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -184,8 +270,8 @@ func TestUserSetPassword(t *testing.T) {
 				if len(user.PasswordHash) == 0 {
 					t.Error("SetPassword() did not set PasswordHash")
 				}
-				if len(user.PasswordSalt) == 0 {
-					t.Error("SetPassword() did not set PasswordSalt")
+				if len(user.PasswordSalt) != 0 {
+					t.Error("SetPassword() set PasswordSalt, want it encoded in PasswordHash")
 				}
 			}
 		})
@@ -219,6 +305,39 @@ func TestUserVerifyPassword(t *testing.T) {
 	}
 }
 
+func TestUserPasswordRehash(t *testing.T) {
+	cheap := crypto.PasswordParams{Algorithm: crypto.PasswordAlgorithmArgon2id, Memory: 64, Iterations: 1, Parallelism: 1}
+
+	salt, _ := crypto.GenerateSalt()
+	user := NewUser()
+	user.PasswordHash = crypto.HashPassword("Test1234!", salt)
+	user.PasswordSalt = salt
+
+	if !user.VerifyPassword("Test1234!") {
+		t.Fatal("expected legacy salted hash to verify")
+	}
+	if !user.PasswordNeedsRehash(crypto.DefaultPasswordParams()) {
+		t.Error("expected legacy salted hash to need a rehash")
+	}
+
+	if err := user.RehashPassword("Test1234!", cheap); err != nil {
+		t.Fatalf("RehashPassword() error = %v", err)
+	}
+	if !user.VerifyPassword("Test1234!") {
+		t.Error("expected rehashed password to verify")
+	}
+	if user.PasswordNeedsRehash(cheap) {
+		t.Error("expected no rehash with unchanged parameters")
+	}
+	if !user.PasswordNeedsRehash(crypto.DefaultPasswordParams()) {
+		t.Error("expected rehash after parameters change")
+	}
+
+	if err := user.SetPasswordWith("Test1234!", crypto.PasswordParams{Algorithm: "md5"}); err != ErrPasswordHashFailed {
+		t.Errorf("SetPasswordWith() error = %v, want %v", err, ErrPasswordHashFailed)
+	}
+}
+
 func TestUserSetPIN(t *testing.T) {
 	encKey := make([]byte, 32)
 	signKey := make([]byte, 32)
@@ -0,0 +1,12 @@
+package auth
+
+import "time"
+
+// UsernameChange records a user giving up OldUsername for NewUsername.
+type UsernameChange struct {
+	UserID      UserID    `json:"user_id" db:"user_id" bson:"user_id"`
+	OldUsername string    `json:"old_username" db:"old_username" bson:"old_username"`
+	NewUsername string    `json:"new_username" db:"new_username" bson:"new_username"`
+	ChangedBy   string    `json:"changed_by" db:"changed_by" bson:"changed_by"`
+	ChangedAt   time.Time `json:"changed_at" db:"changed_at" bson:"changed_at"`
+}
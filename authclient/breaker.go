@@ -0,0 +1,99 @@
+package authclient
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker is a consecutive-failure circuit breaker. It opens after failures
+// errors in a row, rejects calls for cooldown, then admits a single trial
+// call whose outcome closes or reopens it. A nil *breaker admits everything.
+type breaker struct {
+	failures int
+	cooldown time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	count    int
+	openedAt time.Time
+}
+
+func newBreaker(failures int, cooldown time.Duration) *breaker {
+	if failures <= 0 {
+		return nil
+	}
+	return &breaker{failures: failures, cooldown: cooldown, now: time.Now}
+}
+
+// allow reports whether a call may proceed. When it returns true the caller
+// must report the outcome with success or failure.
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// A trial call is already in flight.
+		return false
+	default:
+		return true
+	}
+}
+
+func (b *breaker) success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerClosed
+	b.count = 0
+}
+
+func (b *breaker) failure() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.count++
+	if b.state == breakerHalfOpen || b.count >= b.failures {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// abort reports a call that ended without a verdict on the remote service,
+// such as one cancelled by its caller. A trial call is given back so the
+// next call becomes the trial.
+func (b *breaker) abort() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+	}
+}
@@ -0,0 +1,65 @@
+package authclient
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.allow()
+	b.failure()
+	if !b.allow() {
+		t.Fatal("breaker opened before reaching the failure threshold")
+	}
+	b.failure()
+	if b.allow() {
+		t.Fatal("breaker should be open after 2 consecutive failures")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("breaker should admit a trial call after the cooldown")
+	}
+	if b.allow() {
+		t.Fatal("breaker should admit only one trial call at a time")
+	}
+	b.failure()
+	if b.allow() {
+		t.Fatal("a failed trial call should reopen the breaker")
+	}
+
+	now = now.Add(time.Minute)
+	b.allow()
+	b.success()
+	for range 3 {
+		if !b.allow() {
+			t.Fatal("a successful trial call should close the breaker")
+		}
+	}
+}
+
+func TestBreakerSuccessResetsCount(t *testing.T) {
+	b := newBreaker(2, time.Minute)
+
+	b.failure()
+	b.success()
+	b.failure()
+	if !b.allow() {
+		t.Error("failures separated by a success should not open the breaker")
+	}
+}
+
+func TestNilBreaker(t *testing.T) {
+	b := newBreaker(0, time.Minute)
+	if b != nil {
+		t.Fatal("newBreaker(0) should disable the breaker")
+	}
+	b.failure()
+	if !b.allow() {
+		t.Error("a disabled breaker should admit every call")
+	}
+}
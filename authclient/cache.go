@@ -0,0 +1,89 @@
+package authclient
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepThreshold is the number of entries at which set drops expired ones.
+const sweepThreshold = 1024
+
+// cache is a TTL cache of remote answers. It is safe for concurrent use; a
+// nil *cache stores nothing.
+type cache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu    sync.Mutex
+	items map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value     any
+	expiresAt time.Time
+}
+
+func newCache(ttl time.Duration) *cache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &cache{
+		ttl:   ttl,
+		now:   time.Now,
+		items: make(map[string]cacheEntry),
+	}
+}
+
+func (c *cache) get(key string) (any, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	if c.now().After(e.expiresAt) {
+		delete(c.items, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *cache) set(key string, value any) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if len(c.items) >= sweepThreshold {
+		for k, e := range c.items {
+			if now.After(e.expiresAt) {
+				delete(c.items, k)
+			}
+		}
+	}
+	c.items[key] = cacheEntry{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+func (c *cache) purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.items)
+}
+
+func (c *cache) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
@@ -0,0 +1,60 @@
+package authclient
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.set("a", true)
+	if v, ok := c.get("a"); !ok || v != true {
+		t.Fatalf("get() = %v, %v, want true, true", v, ok)
+	}
+
+	now = now.Add(time.Minute + time.Second)
+	if _, ok := c.get("a"); ok {
+		t.Error("get() should miss an expired entry")
+	}
+	if c.len() != 0 {
+		t.Error("expired entries should be removed on read")
+	}
+
+	c.set("b", 1)
+	c.purge()
+	if _, ok := c.get("b"); ok {
+		t.Error("purge() should drop every entry")
+	}
+}
+
+func TestCacheSweep(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	for i := range sweepThreshold {
+		c.set(fmt.Sprint(i), i)
+	}
+	now = now.Add(2 * time.Minute)
+	c.set("fresh", true)
+
+	if c.len() != 1 {
+		t.Errorf("len() = %d, want 1 after sweeping expired entries", c.len())
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	c := newCache(0)
+	if c != nil {
+		t.Fatal("newCache(0) should disable the cache")
+	}
+	c.set("a", true)
+	if _, ok := c.get("a"); ok {
+		t.Error("a disabled cache should store nothing")
+	}
+	c.purge()
+}
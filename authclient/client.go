@@ -0,0 +1,275 @@
+// Package authclient is a typed client for the aqm authn and authz HTTP
+// services, for applications that delegate authentication and authorization
+// to them. Each service is called through its own retrying HTTP client and
+// circuit breaker, and answers are cached locally for a short TTL.
+package authclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/httpclient"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+var (
+	// ErrCircuitOpen is returned without calling the service while its
+	// circuit breaker is open.
+	ErrCircuitOpen = errors.New("circuit breaker open")
+	// ErrNotConfigured is returned by calls to a service whose URL is empty.
+	ErrNotConfigured = errors.New("service URL not configured")
+)
+
+// Client calls the authn and authz services described by a
+// config.AuthClientConfig. It implements middleware.RoleChecker and
+// middleware.SessionValidator, so it can back the session middleware of a
+// service that does not own the auth stores.
+//
+// Permission, role and user answers are cached for CacheTTL; token
+// verifications and errors are never cached. With a KeySetURL tokens are
+// verified locally instead of by the authn service. Server errors and transport
+// failures count towards opening a service's breaker, client errors do not.
+type Client struct {
+	cfg        config.AuthClientConfig
+	httpClient *http.Client
+	log        log.Logger
+	authn      *service
+	authz      *service
+	cache      *cache
+	keys       *middleware.RemoteKeySet
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the http.Client used for requests, e.g. one with a
+// custom transport. Its Timeout is overridden by the configured timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		if client != nil {
+			c.httpClient = client
+		}
+	}
+}
+
+// New creates a Client from cfg. Either URL may be empty when the
+// application only uses the other service; calls to it then fail with
+// ErrNotConfigured.
+func New(cfg config.AuthClientConfig, logger log.Logger, opts ...Option) *Client {
+	c := &Client{
+		cfg:   cfg,
+		log:   logger,
+		cache: newCache(cfg.CacheTTL),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.authn = c.newService(cfg.AuthNURL)
+	c.authz = c.newService(cfg.AuthZURL)
+	c.keys = c.newKeySet(cfg.KeySetURL)
+	return c
+}
+
+func (c *Client) newService(baseURL string) *service {
+	if baseURL == "" {
+		return nil
+	}
+
+	opts := []httpclient.Option{
+		httpclient.WithTimeout(c.cfg.Timeout),
+		httpclient.WithRetryMax(c.cfg.RetryMax),
+		httpclient.WithRetryDelay(c.cfg.RetryDelay),
+	}
+	if c.httpClient != nil {
+		// Copy so the configured timeout does not leak into the caller's client.
+		hc := *c.httpClient
+		opts = append([]httpclient.Option{httpclient.WithHTTPClient(&hc)}, opts...)
+	}
+	if c.cfg.APIKey != "" {
+		opts = append(opts, httpclient.WithHeader("Authorization", "Bearer "+c.cfg.APIKey))
+	}
+
+	return &service{
+		http:    httpclient.New(strings.TrimSuffix(baseURL, "/"), c.log, opts...),
+		breaker: newBreaker(c.cfg.Breaker.Failures, c.cfg.Breaker.Cooldown),
+	}
+}
+
+type permissionCheckResponse struct {
+	HasPermission bool `json:"has_permission"`
+}
+
+type permissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+type hasRoleResponse struct {
+	HasRole bool `json:"has_role"`
+}
+
+type userResponse struct {
+	User *auth.User `json:"data"`
+}
+
+// CheckPermission reports whether username holds permission.
+func (c *Client) CheckPermission(ctx context.Context, username, permission string) (bool, error) {
+	path := "/users/" + url.PathEscape(username) + "/permissions/" + url.PathEscape(permission)
+	return cachedBool(c, cacheKey("perm", username, permission), func() (bool, error) {
+		var resp permissionCheckResponse
+		err := c.authz.do(ctx, http.MethodGet, path, nil, &resp)
+		return resp.HasPermission, err
+	})
+}
+
+// CheckAnyPermission reports whether username holds at least one of
+// permissions.
+func (c *Client) CheckAnyPermission(ctx context.Context, username string, permissions []string) (bool, error) {
+	return c.checkPermissions(ctx, "check-any-permission", username, permissions)
+}
+
+// CheckAllPermissions reports whether username holds every one of
+// permissions.
+func (c *Client) CheckAllPermissions(ctx context.Context, username string, permissions []string) (bool, error) {
+	return c.checkPermissions(ctx, "check-all-permissions", username, permissions)
+}
+
+func (c *Client) checkPermissions(ctx context.Context, op, username string, permissions []string) (bool, error) {
+	path := "/users/" + url.PathEscape(username) + "/" + op
+	key := cacheKey(append([]string{op, username}, permissions...)...)
+	return cachedBool(c, key, func() (bool, error) {
+		var resp permissionCheckResponse
+		err := c.authz.do(ctx, http.MethodPost, path, permissionsRequest{Permissions: permissions}, &resp)
+		return resp.HasPermission, err
+	})
+}
+
+// HasRole reports whether username has been granted roleName.
+func (c *Client) HasRole(ctx context.Context, username, roleName string) (bool, error) {
+	path := "/users/" + url.PathEscape(username) + "/has-role/" + url.PathEscape(roleName)
+	return cachedBool(c, cacheKey("role", username, roleName), func() (bool, error) {
+		var resp hasRoleResponse
+		err := c.authz.do(ctx, http.MethodGet, path, nil, &resp)
+		return resp.HasRole, err
+	})
+}
+
+// GetUser fetches the user with id. It returns an error wrapping
+// auth.ErrUserNotFound when the service answers 404. The returned user is
+// the caller's to modify.
+func (c *Client) GetUser(ctx context.Context, id auth.UserID) (*auth.User, error) {
+	key := cacheKey("user", id.String())
+	if v, ok := c.cache.get(key); ok {
+		user := *v.(*auth.User)
+		user.Attributes = user.Attributes.Clone()
+		return &user, nil
+	}
+
+	var resp userResponse
+	err := c.authn.do(ctx, http.MethodGet, "/users/"+id.String(), nil, &resp)
+	if e, ok := statusError(err, http.StatusNotFound); ok {
+		return nil, e.Wrap(auth.ErrUserNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.User == nil {
+		return nil, fmt.Errorf("get user %s: empty response", id)
+	}
+
+	c.cache.set(key, resp.User)
+	user := *resp.User
+	user.Attributes = user.Attributes.Clone()
+	return &user, nil
+}
+
+// Purge drops every cached answer, for example after changing a user's
+// grants.
+func (c *Client) Purge() {
+	c.cache.purge()
+}
+
+func cacheKey(parts ...string) string {
+	return strings.Join(parts, "\x00")
+}
+
+func cachedBool(c *Client, key string, load func() (bool, error)) (bool, error) {
+	if v, ok := c.cache.get(key); ok {
+		return v.(bool), nil
+	}
+	result, err := load()
+	if err != nil {
+		return false, err
+	}
+	c.cache.set(key, result)
+	return result, nil
+}
+
+// service is one remote service with its breaker. A nil *service is one
+// whose URL was not configured.
+type service struct {
+	http    *httpclient.Client
+	breaker *breaker
+}
+
+// do sends the request and decodes a successful response into out. Error
+// responses become an *httpx.Error carrying the service's status, code and
+// message.
+func (s *service) do(ctx context.Context, method, path string, body, out any) error {
+	if s == nil {
+		return ErrNotConfigured
+	}
+	if !s.breaker.allow() {
+		return ErrCircuitOpen
+	}
+
+	resp, err := s.http.Do(ctx, method, path, body)
+	if err != nil {
+		if ctx.Err() != nil {
+			s.breaker.abort()
+		} else {
+			s.breaker.failure()
+		}
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	s.breaker.success()
+
+	if !resp.IsSuccess() {
+		return responseError(resp)
+	}
+	if err := resp.JSON(out); err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+func responseError(resp *httpclient.Response) error {
+	var body httpx.ErrorResponse
+	if err := json.Unmarshal(resp.Body, &body); err != nil || body.Code == "" {
+		return httpx.NewError(resp.StatusCode, "", http.StatusText(resp.StatusCode))
+	}
+	return httpx.NewError(resp.StatusCode, body.Code, body.Message)
+}
+
+// statusError returns the *httpx.Error in err's chain if it carries status.
+func statusError(err error, status int) (*httpx.Error, bool) {
+	var e *httpx.Error
+	if errors.As(err, &e) && e.Status == status {
+		return e, true
+	}
+	return nil, false
+}
+
+var (
+	_ middleware.RoleChecker      = (*Client)(nil)
+	_ middleware.SessionValidator = (*Client)(nil)
+	_ middleware.ClaimsValidator  = (*Client)(nil)
+)
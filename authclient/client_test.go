@@ -0,0 +1,248 @@
+package authclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/handler"
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/go-chi/chi/v5"
+)
+
+// countingServer serves h and counts the requests it receives.
+func countingServer(t *testing.T, h http.Handler) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		h.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func testConfig() config.AuthClientConfig {
+	return config.AuthClientConfig{
+		Timeout:    time.Second,
+		RetryMax:   0,
+		RetryDelay: time.Millisecond,
+		CacheTTL:   time.Minute,
+		Breaker:    config.BreakerConfig{Failures: 2, Cooldown: time.Minute},
+	}
+}
+
+func setupAuthZ(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	ctx := t.Context()
+	roleStore := fake.NewRoleStore()
+	grantStore := fake.NewGrantStore(roleStore)
+
+	role := auth.NewRole()
+	role.Name = "editor"
+	role.Permissions = []string{"content:read", "content:write"}
+	role.BeforeCreate()
+	roleStore.Create(ctx, role)
+	grantStore.Create(ctx, auth.NewGrant("alice", role.ID, "test"))
+
+	r := chi.NewRouter()
+	handler.NewAuthZHandler(roleStore, grantStore).RegisterRoutes(r)
+	return countingServer(t, r)
+}
+
+func TestClientAuthZ(t *testing.T) {
+	srv, calls := setupAuthZ(t)
+	cfg := testConfig()
+	cfg.AuthZURL = srv.URL
+	c := New(cfg, log.NewNoopLogger())
+	ctx := t.Context()
+
+	tests := []struct {
+		name  string
+		check func() (bool, error)
+		want  bool
+	}{
+		{"permission held", func() (bool, error) { return c.CheckPermission(ctx, "alice", "content:read") }, true},
+		{"permission not held", func() (bool, error) { return c.CheckPermission(ctx, "alice", "content:delete") }, false},
+		{"any permission", func() (bool, error) {
+			return c.CheckAnyPermission(ctx, "alice", []string{"content:delete", "content:write"})
+		}, true},
+		{"all permissions", func() (bool, error) {
+			return c.CheckAllPermissions(ctx, "alice", []string{"content:delete", "content:write"})
+		}, false},
+		{"role held", func() (bool, error) { return c.HasRole(ctx, "alice", "editor") }, true},
+		{"role not held", func() (bool, error) { return c.HasRole(ctx, "bob", "editor") }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.check()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+
+			before := calls.Load()
+			if again, _ := tt.check(); again != got {
+				t.Errorf("cached answer = %v, want %v", again, got)
+			}
+			if calls.Load() != before {
+				t.Error("second call should be served from the cache")
+			}
+		})
+	}
+
+	c.Purge()
+	before := calls.Load()
+	c.HasRole(ctx, "alice", "editor")
+	if calls.Load() != before+1 {
+		t.Error("Purge() should drop cached answers")
+	}
+}
+
+func TestClientGetUser(t *testing.T) {
+	userStore := fake.NewUserStore()
+	user := auth.NewUser()
+	user.Username = "alice"
+	user.Name = "Alice"
+	user.BeforeCreate()
+	userStore.Create(t.Context(), user)
+
+	r := chi.NewRouter()
+	handler.NewAuthNHandler(userStore, fake.NewCryptoService(), fake.NewTokenGenerator(),
+		fake.NewPasswordGenerator(), fake.NewPINGenerator()).RegisterRoutes(r)
+	srv, calls := countingServer(t, r)
+
+	cfg := testConfig()
+	cfg.AuthNURL = srv.URL
+	c := New(cfg, log.NewNoopLogger())
+
+	got, err := c.GetUser(t.Context(), user.ID)
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	if got.Username != "alice" || got.Name != "Alice" {
+		t.Errorf("GetUser() = %+v", got)
+	}
+
+	got.Name = "changed"
+	again, _ := c.GetUser(t.Context(), user.ID)
+	if again.Name != "Alice" {
+		t.Error("GetUser() should return a copy of the cached user")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+
+	_, err = c.GetUser(t.Context(), auth.NewUser().ID)
+	if !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("GetUser() error = %v, want ErrUserNotFound", err)
+	}
+	var apiErr *httpx.Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound {
+		t.Errorf("GetUser() error = %v, want a 404 *httpx.Error", err)
+	}
+}
+
+func TestClientAPIKey(t *testing.T) {
+	var got string
+	srv, _ := countingServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+		httpx.WriteJSON(w, http.StatusOK, map[string]bool{"has_role": true})
+	}))
+
+	cfg := testConfig()
+	cfg.AuthZURL = srv.URL
+	cfg.APIKey = "secret"
+	New(cfg, log.NewNoopLogger()).HasRole(t.Context(), "alice", "editor")
+
+	if got != "Bearer secret" {
+		t.Errorf("Authorization = %q, want %q", got, "Bearer secret")
+	}
+}
+
+func TestClientErrorResponse(t *testing.T) {
+	srv, _ := countingServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httpx.WriteError(w, r, httpx.Forbidden("FORBIDDEN", "Not allowed"))
+	}))
+
+	cfg := testConfig()
+	cfg.AuthZURL = srv.URL
+	c := New(cfg, log.NewNoopLogger())
+
+	for range 3 {
+		_, err := c.HasRole(t.Context(), "alice", "editor")
+		var apiErr *httpx.Error
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("HasRole() error = %v, want *httpx.Error", err)
+		}
+		if apiErr.Status != http.StatusForbidden || apiErr.Code != "FORBIDDEN" || apiErr.Message != "Not allowed" {
+			t.Errorf("HasRole() error = %+v", apiErr)
+		}
+	}
+	// Client errors say nothing about the service's health.
+	if errors.Is(c.authz.do(t.Context(), http.MethodGet, "/", nil, nil), ErrCircuitOpen) {
+		t.Error("4xx responses should not open the breaker")
+	}
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	srv, calls := countingServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	cfg := testConfig()
+	cfg.AuthZURL = srv.URL
+	cfg.RetryMax = 1
+	c := New(cfg, log.NewNoopLogger())
+
+	for range 2 {
+		if _, err := c.HasRole(t.Context(), "alice", "editor"); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("HasRole() error = %v, want a server error", err)
+		}
+	}
+	if calls.Load() != 4 {
+		t.Errorf("calls = %d, want 4: each call is retried once", calls.Load())
+	}
+
+	if _, err := c.HasRole(t.Context(), "alice", "editor"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("HasRole() error = %v, want ErrCircuitOpen", err)
+	}
+	if calls.Load() != 4 {
+		t.Error("an open breaker should not call the service")
+	}
+}
+
+func TestClientNotConfigured(t *testing.T) {
+	c := New(testConfig(), log.NewNoopLogger())
+
+	if _, err := c.CheckPermission(t.Context(), "alice", "content:read"); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("CheckPermission() error = %v, want ErrNotConfigured", err)
+	}
+	if _, err := c.VerifyToken(t.Context(), "token"); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("VerifyToken() error = %v, want ErrNotConfigured", err)
+	}
+}
+
+func TestClientCacheDisabled(t *testing.T) {
+	srv, calls := setupAuthZ(t)
+	cfg := testConfig()
+	cfg.AuthZURL = srv.URL
+	cfg.CacheTTL = 0
+	c := New(cfg, log.NewNoopLogger())
+
+	c.HasRole(t.Context(), "alice", "editor")
+	c.HasRole(t.Context(), "alice", "editor")
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2 with the cache disabled", calls.Load())
+	}
+}
@@ -0,0 +1,155 @@
+package authclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/crypto"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+// TokenInfo identifies the session a verified token belongs to.
+// Impersonator is set for impersonation tokens, Scope for scoped tokens and
+// Claims to the custom claims added at issuance.
+type TokenInfo struct {
+	UserID       string            `json:"user_id"`
+	SessionID    string            `json:"session_id"`
+	Impersonator string            `json:"impersonator,omitempty"`
+	Scope        []string          `json:"scope,omitempty"`
+	Claims       map[string]string `json:"claims,omitempty"`
+}
+
+// Claim returns the custom claim name, and an empty string when it is not
+// set.
+func (i *TokenInfo) Claim(name string) string {
+	return i.Claims[name]
+}
+
+type verifyTokenRequest struct {
+	Token string `json:"token"`
+}
+
+func (c *Client) newKeySet(url string) *middleware.RemoteKeySet {
+	if url == "" {
+		return nil
+	}
+
+	hc := &http.Client{Timeout: 5 * time.Second}
+	if c.httpClient != nil {
+		copied := *c.httpClient
+		hc = &copied
+	}
+	if c.cfg.Timeout > 0 {
+		hc.Timeout = c.cfg.Timeout
+	}
+
+	return middleware.NewRemoteKeySet(middleware.RemoteKeySetConfig{
+		URL:             url,
+		Client:          hc,
+		RefreshInterval: c.cfg.KeyRefresh,
+		Logger:          c.log,
+	})
+}
+
+// VerifyToken checks token and returns the session it belongs to. It returns
+// an error wrapping auth.ErrTokenVerificationFailed when the token is
+// invalid or expired.
+//
+// With a KeySetURL the token is verified locally against the published keys,
+// falling back to the authn service only while no keys can be fetched.
+// Otherwise the authn service is asked, which must be built with
+// handler.WithTokenValidator.
+func (c *Client) VerifyToken(ctx context.Context, token string) (*TokenInfo, error) {
+	if c.keys != nil {
+		info, err := c.verifyLocal(ctx, token)
+		if !errors.Is(err, middleware.ErrNoVerificationKeys) || c.authn == nil {
+			return info, err
+		}
+		c.log.Error("Cannot verify token locally, asking authn", "error", err)
+	}
+
+	var info TokenInfo
+	err := c.authn.do(ctx, http.MethodPost, "/auth/verify", verifyTokenRequest{Token: token}, &info)
+	if e, ok := statusError(err, http.StatusUnauthorized); ok {
+		return nil, e.Wrap(auth.ErrTokenVerificationFailed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// verifyLocal verifies token against each key of the key set. An expired
+// token is reported as such even if other keys reject it as invalid.
+func (c *Client) verifyLocal(ctx context.Context, token string) (*TokenInfo, error) {
+	keys, err := c.keys.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = crypto.ErrInvalidToken
+	for _, key := range keys {
+		claims, verr := crypto.VerifyToken(token, key)
+		if verr == nil {
+			return &TokenInfo{
+				UserID:       claims.Subject,
+				SessionID:    claims.SessionID,
+				Impersonator: claims.Actor,
+				Scope:        claims.Scope,
+				Claims:       claims.Context,
+			}, nil
+		}
+		if errors.Is(verr, crypto.ErrTokenExpired) {
+			err = verr
+		}
+	}
+	return nil, fmt.Errorf("%w: %w", auth.ErrTokenVerificationFailed, err)
+}
+
+// ValidateToken implements middleware.SessionValidator with VerifyToken.
+func (c *Client) ValidateToken(token string) (string, string, error) {
+	info, err := c.VerifyToken(context.Background(), token)
+	if err != nil {
+		return "", "", err
+	}
+	return info.UserID, info.SessionID, nil
+}
+
+// ValidateClaims implements middleware.ClaimsValidator with VerifyToken, so
+// Session and Bearer record the impersonator, scope and custom claims of
+// tokens. Only the subject, session, actor, scope and custom claims are set.
+func (c *Client) ValidateClaims(token string) (crypto.TokenClaims, error) {
+	info, err := c.VerifyToken(context.Background(), token)
+	if err != nil {
+		return crypto.TokenClaims{}, err
+	}
+	return crypto.TokenClaims{
+		Subject:   info.UserID,
+		SessionID: info.SessionID,
+		Context:   info.Claims,
+		Actor:     info.Impersonator,
+		Scope:     info.Scope,
+	}, nil
+}
+
+// Start fetches the key set and keeps it refreshed in the background. It
+// does nothing without a KeySetURL. Register the client with the app
+// lifecycle so keys are ready before the first request.
+func (c *Client) Start(ctx context.Context) error {
+	if c.keys == nil {
+		return nil
+	}
+	return c.keys.Start(ctx)
+}
+
+// Stop ends the background key set refresh.
+func (c *Client) Stop(ctx context.Context) error {
+	if c.keys == nil {
+		return nil
+	}
+	return c.keys.Stop(ctx)
+}
@@ -0,0 +1,236 @@
+package authclient
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/handler"
+	authservice "github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/crypto"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func signToken(t *testing.T, priv ed25519.PrivateKey, expiresAt time.Time) string {
+	t.Helper()
+	token, err := crypto.GenerateToken(crypto.TokenClaims{
+		Subject:   "user-1",
+		SessionID: "session-1",
+		ExpiresAt: expiresAt.Unix(),
+	}, priv)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	return token
+}
+
+func TestClientVerifyToken(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	token := signToken(t, priv, time.Now().Add(time.Hour))
+
+	r := chi.NewRouter()
+	handler.NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(),
+		fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		handler.WithTokenValidator(middleware.NewTokenValidator(pub))).RegisterRoutes(r)
+	srv, calls := countingServer(t, r)
+
+	cfg := testConfig()
+	cfg.AuthNURL = srv.URL
+	c := New(cfg, log.NewNoopLogger())
+
+	userID, sessionID, err := c.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if userID != "user-1" || sessionID != "session-1" {
+		t.Errorf("ValidateToken() = %q, %q", userID, sessionID)
+	}
+
+	c.ValidateToken(token)
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2: verifications are not cached", calls.Load())
+	}
+
+	if _, err := c.VerifyToken(t.Context(), "v4.public.garbage"); !errors.Is(err, auth.ErrTokenVerificationFailed) {
+		t.Errorf("VerifyToken() error = %v, want ErrTokenVerificationFailed", err)
+	}
+}
+
+func TestClientVerifyTokenLocally(t *testing.T) {
+	current, currentPriv, _ := ed25519.GenerateKey(rand.Reader)
+	previous, previousPriv, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherPriv, _ := ed25519.GenerateKey(rand.Reader)
+
+	r := chi.NewRouter()
+	handler.NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(),
+		fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		handler.WithVerificationKeys(current, previous)).RegisterRoutes(r)
+	srv, calls := countingServer(t, r)
+
+	cfg := testConfig()
+	cfg.KeySetURL = srv.URL + "/.well-known/jwks.json"
+	cfg.KeyRefresh = time.Hour
+	c := New(cfg, log.NewNoopLogger())
+	if err := c.Start(t.Context()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { c.Stop(t.Context()) })
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "current key", token: signToken(t, currentPriv, time.Now().Add(time.Hour))},
+		{name: "previous key", token: signToken(t, previousPriv, time.Now().Add(time.Hour))},
+		{name: "unknown key", token: signToken(t, otherPriv, time.Now().Add(time.Hour)), wantErr: crypto.ErrInvalidToken},
+		{name: "expired", token: signToken(t, currentPriv, time.Now().Add(-time.Minute)), wantErr: crypto.ErrTokenExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := c.VerifyToken(t.Context(), tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !errors.Is(err, auth.ErrTokenVerificationFailed) {
+					t.Errorf("VerifyToken() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyToken() error = %v", err)
+			}
+			if info.UserID != "user-1" || info.SessionID != "session-1" {
+				t.Errorf("VerifyToken() = %+v", info)
+			}
+		})
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1: only the key set fetch", calls.Load())
+	}
+}
+
+func TestClientVerifyTokenFallsBackToAuthN(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+
+	r := chi.NewRouter()
+	handler.NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(),
+		fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		handler.WithTokenValidator(middleware.NewTokenValidator(pub))).RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig()
+	cfg.AuthNURL = srv.URL
+	// The authn service publishes no key set, so local verification has no keys.
+	cfg.KeySetURL = srv.URL + "/.well-known/jwks.json"
+	c := New(cfg, log.NewNoopLogger())
+
+	info, err := c.VerifyToken(t.Context(), signToken(t, priv, time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	if info.UserID != "user-1" {
+		t.Errorf("VerifyToken() = %+v", info)
+	}
+
+	cfg.AuthNURL = ""
+	_, err = New(cfg, log.NewNoopLogger()).VerifyToken(t.Context(), "token")
+	if !errors.Is(err, middleware.ErrNoVerificationKeys) {
+		t.Errorf("VerifyToken() error = %v, want ErrNoVerificationKeys", err)
+	}
+}
+
+func TestClientStartWithoutKeySet(t *testing.T) {
+	c := New(testConfig(), log.NewNoopLogger())
+	if err := c.Start(t.Context()); err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+	if err := c.Stop(t.Context()); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+}
+
+func TestClientVerifyImpersonationToken(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	token, _ := crypto.GenerateToken(crypto.TokenClaims{
+		Subject:   "user-1",
+		SessionID: "session-1",
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		Actor:     "support",
+	}, priv)
+
+	r := chi.NewRouter()
+	handler.NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(),
+		fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		handler.WithTokenValidator(middleware.NewTokenValidator(pub)),
+		handler.WithVerificationKeys(pub)).RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	remote := testConfig()
+	remote.AuthNURL = srv.URL
+	local := testConfig()
+	local.KeySetURL = srv.URL + "/.well-known/jwks.json"
+
+	for name, cfg := range map[string]config.AuthClientConfig{"remote": remote, "local": local} {
+		t.Run(name, func(t *testing.T) {
+			claims, err := New(cfg, log.NewNoopLogger()).ValidateClaims(token)
+			if err != nil {
+				t.Fatalf("ValidateClaims() error = %v", err)
+			}
+			if claims.Subject != "user-1" || claims.Actor != "support" {
+				t.Errorf("ValidateClaims() = %+v, want user-1 acting as support", claims)
+			}
+		})
+	}
+}
+
+func TestClientVerifyEnrichedToken(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	tokens := authservice.NewDefaultTokenGenerator(priv, time.Hour).WithClaimsEnrichers(
+		authservice.ClaimsEnricherFunc(func(ctx context.Context, claims crypto.TokenClaims) (map[string]string, error) {
+			return map[string]string{"tenant": "acme", "plan": "pro"}, nil
+		}),
+	)
+	token, _ := tokens.GenerateToken(auth.NewUserID())
+
+	r := chi.NewRouter()
+	handler.NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), tokens,
+		fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		handler.WithTokenValidator(middleware.NewTokenValidator(pub)),
+		handler.WithVerificationKeys(pub)).RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	remote := testConfig()
+	remote.AuthNURL = srv.URL
+	local := testConfig()
+	local.KeySetURL = srv.URL + "/.well-known/jwks.json"
+
+	for name, cfg := range map[string]config.AuthClientConfig{"remote": remote, "local": local} {
+		t.Run(name, func(t *testing.T) {
+			c := New(cfg, log.NewNoopLogger())
+			info, err := c.VerifyToken(t.Context(), token)
+			if err != nil {
+				t.Fatalf("VerifyToken() error = %v", err)
+			}
+			if info.Claim("tenant") != "acme" || info.Claim("plan") != "pro" {
+				t.Errorf("VerifyToken() claims = %v, want tenant acme on plan pro", info.Claims)
+			}
+			claims, err := c.ValidateClaims(token)
+			if err != nil || claims.Context["tenant"] != "acme" {
+				t.Errorf("ValidateClaims() = %+v, %v, want tenant acme", claims, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/aquamarinepk/aqm/audit"
+	audithandler "github.com/aquamarinepk/aqm/audit/handler"
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/handler"
+	"github.com/aquamarinepk/aqm/auth/seed"
+	"github.com/aquamarinepk/aqm/httpclient"
+	"github.com/aquamarinepk/aqm/log"
+)
+
+// Backend performs the admin operations, either over the HTTP API or
+// directly on the stores. Roles are referenced by name or ID.
+type Backend interface {
+	CreateUser(ctx context.Context, email, password, username, name string) (*auth.User, error)
+	ListUsers(ctx context.Context, status auth.UserStatus) ([]*auth.User, error)
+	// ResetPassword sets password, or a generated one when it is empty, and
+	// returns the generated password.
+	ResetPassword(ctx context.Context, username, password string) (string, error)
+	CreateRole(ctx context.Context, name, description string, permissions []string, createdBy string) (*auth.Role, error)
+	ListRoles(ctx context.Context) ([]*auth.Role, error)
+	AssignRole(ctx context.Context, username, role, assignedBy string) (*auth.Grant, error)
+	RevokeRole(ctx context.Context, username, role string) error
+	ListGrants(ctx context.Context, username string) ([]*auth.Grant, error)
+	ListAuditEvents(ctx context.Context, filter audit.Filter) ([]*audit.Event, error)
+	// Bootstrap creates the superadmin and returns it with its generated
+	// password. It returns auth.ErrAlreadyBootstrapped when the superadmin
+	// already exists.
+	Bootstrap(ctx context.Context) (*auth.User, string, error)
+	// Export returns the roles and grants, and the users when users is set.
+	Export(ctx context.Context, users bool) (*seed.Manifest, error)
+	// Import applies m, or only computes what it would change when dryRun is
+	// set.
+	Import(ctx context.Context, m *seed.Manifest, dryRun bool) (*handler.ImportResponse, error)
+}
+
+// openBackend builds the backend selected by the global flags.
+func openBackend(g globalFlags) (Backend, func(), error) {
+	if g.direct {
+		return openStoreBackend(g.config, g.envPrefix)
+	}
+
+	opts := []httpclient.Option{httpclient.WithRetryMax(0), httpclient.WithTimeout(30 * time.Second)}
+	if g.token != "" {
+		opts = append(opts, httpclient.WithHeader("Authorization", "Bearer "+g.token))
+	}
+	if g.bootstrapToken != "" {
+		opts = append(opts, httpclient.WithHeader(handler.BootstrapTokenHeader, g.bootstrapToken))
+	}
+	return newHTTPBackend(httpclient.New(g.server, log.NewNoopLogger(), opts...)), func() {}, nil
+}
+
+type httpBackend struct {
+	client *httpclient.Client
+}
+
+func newHTTPBackend(client *httpclient.Client) *httpBackend {
+	return &httpBackend{client: client}
+}
+
+// APIError is a non-2xx response from the auth service.
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("server returned %d %s", e.Status, http.StatusText(e.Status))
+	}
+	return fmt.Sprintf("%s: %s (%d)", e.Code, e.Message, e.Status)
+}
+
+// call sends a request and decodes a successful response into out, which
+// may be nil.
+func (b *httpBackend) call(ctx context.Context, method, path string, body, out any) error {
+	resp, err := b.client.Do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if !resp.IsSuccess() {
+		apiErr := &APIError{Status: resp.StatusCode}
+		var errResp handler.ErrorResponse
+		if resp.JSON(&errResp) == nil {
+			apiErr.Code, apiErr.Message = errResp.Code, errResp.Message
+		}
+		return apiErr
+	}
+	if out == nil || len(resp.Body) == 0 {
+		return nil
+	}
+	return resp.JSON(out)
+}
+
+func (b *httpBackend) CreateUser(ctx context.Context, email, password, username, name string) (*auth.User, error) {
+	var resp handler.SignUpResponse
+	req := handler.SignUpRequest{Email: email, Password: password, Username: username, DisplayName: name}
+	if err := b.call(ctx, http.MethodPost, "/auth/signup", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.User, nil
+}
+
+func (b *httpBackend) ListUsers(ctx context.Context, status auth.UserStatus) ([]*auth.User, error) {
+	path := "/users"
+	if status != "" {
+		path += "?status=" + url.QueryEscape(string(status))
+	}
+	var resp handler.ListUsersResponse
+	if err := b.call(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Users, nil
+}
+
+func (b *httpBackend) ResetPassword(ctx context.Context, username, password string) (string, error) {
+	var user handler.UserResponse
+	if err := b.call(ctx, http.MethodGet, "/users/username/"+url.PathEscape(username), nil, &user); err != nil {
+		return "", err
+	}
+
+	var resp handler.ResetPasswordResponse
+	path := "/users/" + user.User.ID.String() + "/password"
+	if err := b.call(ctx, http.MethodPost, path, handler.ResetPasswordRequest{Password: password}, &resp); err != nil {
+		return "", err
+	}
+	return resp.Password, nil
+}
+
+func (b *httpBackend) CreateRole(ctx context.Context, name, description string, permissions []string, createdBy string) (*auth.Role, error) {
+	var resp handler.RoleResponse
+	req := handler.CreateRoleRequest{Name: name, Description: description, Permissions: permissions, CreatedBy: createdBy}
+	if err := b.call(ctx, http.MethodPost, "/roles", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Role, nil
+}
+
+func (b *httpBackend) ListRoles(ctx context.Context) ([]*auth.Role, error) {
+	var resp handler.ListRolesResponse
+	if err := b.call(ctx, http.MethodGet, "/roles", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Roles, nil
+}
+
+func (b *httpBackend) AssignRole(ctx context.Context, username, role, assignedBy string) (*auth.Grant, error) {
+	roleID, err := b.roleID(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp handler.GrantResponse
+	req := handler.AssignRoleRequest{Username: username, RoleID: roleID.String(), AssignedBy: assignedBy}
+	if err := b.call(ctx, http.MethodPost, "/grants", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Grant, nil
+}
+
+func (b *httpBackend) RevokeRole(ctx context.Context, username, role string) error {
+	roleID, err := b.roleID(ctx, role)
+	if err != nil {
+		return err
+	}
+	req := handler.RevokeRoleRequest{Username: username, RoleID: roleID.String()}
+	return b.call(ctx, http.MethodDelete, "/grants", req, nil)
+}
+
+func (b *httpBackend) ListGrants(ctx context.Context, username string) ([]*auth.Grant, error) {
+	var resp handler.UserGrantsResponse
+	if err := b.call(ctx, http.MethodGet, "/users/"+url.PathEscape(username)+"/grants", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Grants, nil
+}
+
+func (b *httpBackend) ListAuditEvents(ctx context.Context, filter audit.Filter) ([]*audit.Event, error) {
+	q := url.Values{}
+	setQuery(q, "actor", filter.Actor)
+	setQuery(q, "action", filter.Action)
+	setQuery(q, "resource", filter.Resource)
+	setQuery(q, "outcome", filter.Outcome)
+	if !filter.Since.IsZero() {
+		q.Set("since", filter.Since.Format(time.RFC3339))
+	}
+	if !filter.Until.IsZero() {
+		q.Set("until", filter.Until.Format(time.RFC3339))
+	}
+	if filter.Limit > 0 {
+		q.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		q.Set("offset", strconv.Itoa(filter.Offset))
+	}
+
+	path := "/audit/events"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	var resp audithandler.ListEventsResponse
+	if err := b.call(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
+
+func (b *httpBackend) Bootstrap(ctx context.Context) (*auth.User, string, error) {
+	var resp handler.BootstrapResponse
+	err := b.call(ctx, http.MethodPost, "/auth/bootstrap", nil, &resp)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusGone {
+		return nil, "", auth.ErrAlreadyBootstrapped
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.User, resp.Password, nil
+}
+
+func (b *httpBackend) Export(ctx context.Context, users bool) (*seed.Manifest, error) {
+	var m seed.Manifest
+	path := "/authz/export?users=" + strconv.FormatBool(users)
+	if err := b.call(ctx, http.MethodGet, path, nil, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (b *httpBackend) Import(ctx context.Context, m *seed.Manifest, dryRun bool) (*handler.ImportResponse, error) {
+	var resp handler.ImportResponse
+	path := "/authz/import?dry_run=" + strconv.FormatBool(dryRun)
+	if err := b.call(ctx, http.MethodPost, path, m, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// roleID resolves a role name or ID to an ID.
+func (b *httpBackend) roleID(ctx context.Context, ref string) (auth.RoleID, error) {
+	if id, err := auth.ParseRoleID(ref); err == nil {
+		return id, nil
+	}
+	var resp handler.RoleResponse
+	if err := b.call(ctx, http.MethodGet, "/roles/name/"+url.PathEscape(ref), nil, &resp); err != nil {
+		return auth.RoleID{}, err
+	}
+	return resp.Role.ID, nil
+}
+
+func setQuery(q url.Values, key, value string) {
+	if value != "" {
+		q.Set(key, value)
+	}
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/audit"
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/seed"
+	"github.com/aquamarinepk/aqm/auth/service"
+)
+
+var commands = map[string]command{
+	"users create":         {usage: "Create a user", run: runUsersCreate},
+	"users list":           {usage: "List users", run: runUsersList},
+	"users reset-password": {usage: "Set or generate a new password for a user", run: runUsersResetPassword},
+	"roles create":         {usage: "Create a role", run: runRolesCreate},
+	"roles list":           {usage: "List roles", run: runRolesList},
+	"grants assign":        {usage: "Assign a role to a user", run: runGrantsAssign},
+	"grants revoke":        {usage: "Revoke a role from a user", run: runGrantsRevoke},
+	"grants list":          {usage: "List a user's grants", run: runGrantsList},
+	"audit dump":           {usage: "Print audit events", run: runAuditDump},
+	"authz export":         {usage: "Write roles, grants and optionally users to a snapshot", run: runAuthzExport},
+	"authz import":         {usage: "Apply a snapshot, or show what it would change", run: runAuthzImport},
+	"bootstrap":            {usage: "Create the superadmin, reporting when it already exists", run: runBootstrap},
+}
+
+// generatedPasswordLength is the length of passwords aqmctl generates for
+// new users created without --password.
+const generatedPasswordLength = 24
+
+type createdUser struct {
+	User     *auth.User `json:"user"`
+	Password string     `json:"password,omitempty"`
+}
+
+func runUsersCreate(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("users create", e)
+	email := fs.String("email", "", "Email address")
+	username := fs.String("username", "", "Username")
+	name := fs.String("name", "", "Display name (defaults to the username)")
+	password := fs.String("password", "", "Password (generated and printed when empty)")
+	if err := parseFlags(fs, args, "email", "username"); err != nil {
+		return err
+	}
+
+	if *name == "" {
+		*name = *username
+	}
+	generated := ""
+	if *password == "" {
+		generated = service.NewDefaultPasswordGenerator(generatedPasswordLength).GeneratePassword()
+		*password = generated
+	}
+
+	user, err := e.backend.CreateUser(ctx, *email, *password, *username, *name)
+	if err != nil {
+		return err
+	}
+
+	return e.out.print(createdUser{User: user, Password: generated},
+		[]string{"ID", "USERNAME", "NAME", "STATUS", "PASSWORD"},
+		[][]string{{user.ID.String(), user.Username, user.Name, string(user.Status), orDash(generated)}})
+}
+
+func runUsersList(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("users list", e)
+	status := fs.String("status", "", "Only list users with this status")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	users, err := e.backend.ListUsers(ctx, auth.UserStatus(*status))
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(users))
+	for _, u := range users {
+		rows = append(rows, []string{u.ID.String(), u.Username, u.Name, string(u.Status), formatTime(u.CreatedAt)})
+	}
+	return e.out.print(users, []string{"ID", "USERNAME", "NAME", "STATUS", "CREATED"}, rows)
+}
+
+type passwordReset struct {
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+}
+
+func runUsersResetPassword(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("users reset-password", e)
+	username := fs.String("username", "", "Username")
+	password := fs.String("password", "", "New password (generated and printed when empty)")
+	if err := parseFlags(fs, args, "username"); err != nil {
+		return err
+	}
+
+	generated, err := e.backend.ResetPassword(ctx, *username, *password)
+	if err != nil {
+		return err
+	}
+
+	return e.out.print(passwordReset{Username: *username, Password: generated},
+		[]string{"USERNAME", "PASSWORD"},
+		[][]string{{*username, orDash(generated)}})
+}
+
+func runRolesCreate(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("roles create", e)
+	name := fs.String("name", "", "Role name")
+	description := fs.String("description", "", "Role description")
+	permissions := fs.StringSlice("permissions", nil, "Comma-separated permissions")
+	if err := parseFlags(fs, args, "name"); err != nil {
+		return err
+	}
+
+	role, err := e.backend.CreateRole(ctx, *name, *description, *permissions, e.actor)
+	if err != nil {
+		return err
+	}
+	return e.out.print(role, roleHeader, [][]string{roleRow(role)})
+}
+
+func runRolesList(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("roles list", e)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	roles, err := e.backend.ListRoles(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(roles))
+	for _, r := range roles {
+		rows = append(rows, roleRow(r))
+	}
+	return e.out.print(roles, roleHeader, rows)
+}
+
+var roleHeader = []string{"ID", "NAME", "STATUS", "PERMISSIONS"}
+
+func roleRow(r *auth.Role) []string {
+	return []string{r.ID.String(), r.Name, string(r.Status), strings.Join(r.Permissions, ",")}
+}
+
+func runGrantsAssign(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("grants assign", e)
+	username := fs.String("username", "", "Username")
+	role := fs.String("role", "", "Role name or ID")
+	if err := parseFlags(fs, args, "username", "role"); err != nil {
+		return err
+	}
+
+	grant, err := e.backend.AssignRole(ctx, *username, *role, e.actor)
+	if err != nil {
+		return err
+	}
+	return e.out.print(grant, grantHeader, [][]string{grantRow(grant)})
+}
+
+func runGrantsRevoke(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("grants revoke", e)
+	username := fs.String("username", "", "Username")
+	role := fs.String("role", "", "Role name or ID")
+	if err := parseFlags(fs, args, "username", "role"); err != nil {
+		return err
+	}
+
+	if err := e.backend.RevokeRole(ctx, *username, *role); err != nil {
+		return err
+	}
+	return e.out.message(fmt.Sprintf("Revoked %s from %s", *role, *username))
+}
+
+func runGrantsList(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("grants list", e)
+	username := fs.String("username", "", "Username")
+	if err := parseFlags(fs, args, "username"); err != nil {
+		return err
+	}
+
+	grants, err := e.backend.ListGrants(ctx, *username)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(grants))
+	for _, g := range grants {
+		rows = append(rows, grantRow(g))
+	}
+	return e.out.print(grants, grantHeader, rows)
+}
+
+var grantHeader = []string{"USERNAME", "ROLE_ID", "ASSIGNED_BY", "ASSIGNED_AT"}
+
+func grantRow(g *auth.Grant) []string {
+	return []string{g.Username, g.RoleID.String(), g.AssignedBy, formatTime(g.AssignedAt)}
+}
+
+func runAuditDump(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("audit dump", e)
+	var filter audit.Filter
+	fs.StringVar(&filter.Actor, "actor", "", "Only events by this actor")
+	fs.StringVar(&filter.Action, "action", "", "Only events with this action")
+	fs.StringVar(&filter.Resource, "resource", "", "Only events on this resource")
+	fs.StringVar(&filter.Outcome, "outcome", "", "Only events with this outcome (success, failure)")
+	since := fs.String("since", "", "Only events at or after this RFC 3339 time or duration ago (e.g. 24h)")
+	until := fs.String("until", "", "Only events before this RFC 3339 time or duration ago")
+	limit := fs.Int("limit", 0, "Maximum number of events (all when 0)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	var err error
+	now := time.Now()
+	if filter.Since, err = parseSince(*since, now); err != nil {
+		return fmt.Errorf("--since: %w", err)
+	}
+	if filter.Until, err = parseSince(*until, now); err != nil {
+		return fmt.Errorf("--until: %w", err)
+	}
+	if *limit < 0 {
+		return fmt.Errorf("--limit must not be negative")
+	}
+
+	events, err := dumpEvents(ctx, e.backend, filter, *limit)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(events))
+	for _, ev := range events {
+		rows = append(rows, []string{formatTime(ev.At), ev.Actor, ev.Action, ev.Resource, ev.Outcome, ev.Error})
+	}
+	return e.out.print(events, []string{"AT", "ACTOR", "ACTION", "RESOURCE", "OUTCOME", "ERROR"}, rows)
+}
+
+// dumpEvents pages through the audit trail until limit events were read or
+// the trail is exhausted. A zero limit reads every matching event.
+func dumpEvents(ctx context.Context, b Backend, filter audit.Filter, limit int) ([]*audit.Event, error) {
+	events := []*audit.Event{}
+	for {
+		filter.Limit = audit.MaxLimit
+		if limit > 0 && limit-len(events) < filter.Limit {
+			filter.Limit = limit - len(events)
+		}
+
+		page, err := b.ListAuditEvents(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, page...)
+
+		if len(page) < filter.Limit || (limit > 0 && len(events) >= limit) {
+			return events, nil
+		}
+		filter.Offset += len(page)
+	}
+}
+
+func runAuthzExport(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("authz export", e)
+	users := fs.Bool("users", false, "Include users (without credentials)")
+	format := fs.String("format", seed.FormatYAML, "Snapshot format (yaml, json)")
+	file := fs.String("file", "", "Write the snapshot to this file instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *format != seed.FormatYAML && *format != seed.FormatJSON {
+		return fmt.Errorf("--format must be yaml or json")
+	}
+
+	m, err := e.backend.Export(ctx, *users)
+	if err != nil {
+		return err
+	}
+	data, err := seed.EncodeManifest(m, *format)
+	if err != nil {
+		return err
+	}
+
+	if *file == "" {
+		_, err = e.out.w.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*file, data, 0o600); err != nil {
+		return err
+	}
+	return e.out.message(fmt.Sprintf("Exported %d roles, %d grants and %d users to %s", len(m.Roles), len(m.Grants), len(m.Users), *file))
+}
+
+// runAuthzImport prints the changes of a snapshot, applying them unless
+// --dry-run is set. Entries missing from the snapshot are never deleted.
+func runAuthzImport(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("authz import", e)
+	file := fs.String("file", "", "Snapshot to import (.yaml, .yml or .json)")
+	dryRun := fs.Bool("dry-run", false, "Only show what would change")
+	if err := parseFlags(fs, args, "file"); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	m, err := seed.ParseManifest(*file, data)
+	if err != nil {
+		return err
+	}
+	if m.CreatedBy == "" {
+		m.CreatedBy = e.actor
+	}
+
+	resp, err := e.backend.Import(ctx, m, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(resp.Diff.Changes))
+	for _, c := range resp.Diff.Changes {
+		rows = append(rows, []string{c.Action, c.Kind, c.Name, orDash(c.Username), orDash(strings.Join(c.Fields, ","))})
+	}
+	if err := e.out.print(resp, []string{"ACTION", "KIND", "NAME", "USERNAME", "FIELDS"}, rows); err != nil {
+		return err
+	}
+	if e.out.format == "json" {
+		return nil
+	}
+	if *dryRun {
+		return e.out.message(fmt.Sprintf("Dry run: %d changes, %d unchanged", len(resp.Diff.Changes), resp.Diff.Unchanged))
+	}
+	return e.out.message(fmt.Sprintf("Created %d, updated %d, unchanged %d", resp.Result.Created, resp.Result.Updated, resp.Result.Unchanged))
+}
+
+type bootstrapResult struct {
+	User     *auth.User `json:"user"`
+	Password string     `json:"password,omitempty"`
+}
+
+func runBootstrap(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("bootstrap", e)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	user, password, err := e.backend.Bootstrap(ctx)
+	if errors.Is(err, auth.ErrAlreadyBootstrapped) {
+		return e.out.message("Superadmin already exists")
+	}
+	if err != nil {
+		return err
+	}
+
+	return e.out.print(bootstrapResult{User: user, Password: password},
+		[]string{"ID", "USERNAME", "EMAIL", "PASSWORD"},
+		[][]string{{user.ID.String(), user.Username, service.SuperadminEmail, orDash(password)}})
+}
+
+// parseSince accepts an RFC 3339 timestamp or a positive duration meaning
+// that long before now.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(-d).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC 3339 timestamp or a positive duration", value)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
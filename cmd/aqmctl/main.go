@@ -0,0 +1,176 @@
+// Command aqmctl administers aqm auth services.
+//
+// It talks to a running service over its HTTP API or, with --direct, opens
+// the service's database from its config file and works on the stores.
+//
+//	aqmctl [global flags] <command> <subcommand> [flags]
+//
+// Run "aqmctl help" for the list of commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/aquamarinepk/aqm/auth/handler"
+	"github.com/spf13/pflag"
+)
+
+// errUsage is returned for invalid command lines; the message has already
+// been printed with the usage text.
+var errUsage = errors.New("usage error")
+
+type globalFlags struct {
+	server         string
+	token          string
+	bootstrapToken string
+	output         string
+	actor          string
+	direct         bool
+	config         string
+	envPrefix      string
+}
+
+// env is what every command runs with.
+type env struct {
+	backend Backend
+	out     *printer
+	stderr  io.Writer
+	actor   string
+}
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, e *env, args []string) error
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr, openBackend))
+}
+
+// run executes the command line args and returns the process exit code.
+// open builds the backend from the parsed global flags.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, open func(globalFlags) (Backend, func(), error)) int {
+	var g globalFlags
+	fs := pflag.NewFlagSet("aqmctl", pflag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.SetInterspersed(false)
+	fs.StringVar(&g.server, "server", envOr("AQMCTL_SERVER", "http://localhost:8080"), "Base URL of the auth service")
+	fs.StringVar(&g.token, "token", os.Getenv("AQMCTL_TOKEN"), "Bearer token sent to the auth service")
+	fs.StringVar(&g.bootstrapToken, "bootstrap-token", os.Getenv("AQMCTL_BOOTSTRAP_TOKEN"), "Token sent in "+handler.BootstrapTokenHeader+" when the service requires one to bootstrap")
+	fs.StringVarP(&g.output, "output", "o", "table", "Output format (table, json)")
+	fs.StringVar(&g.actor, "actor", "aqmctl", "Name recorded as creator of roles and grants")
+	fs.BoolVar(&g.direct, "direct", false, "Work directly on the database instead of the HTTP API")
+	fs.StringVar(&g.config, "config", "config.yaml", "Service config file used with --direct")
+	fs.StringVar(&g.envPrefix, "env-prefix", "", "Environment variable prefix used with --direct")
+	fs.Usage = func() { printUsage(stderr, fs) }
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	rest := fs.Args()
+	if len(rest) == 0 || rest[0] == "help" {
+		printUsage(stdout, fs)
+		return 0
+	}
+	if g.output != "table" && g.output != "json" {
+		fmt.Fprintf(stderr, "aqmctl: unknown output format %q\n", g.output)
+		return 2
+	}
+
+	name := rest[0]
+	if len(rest) > 1 && !strings.HasPrefix(rest[1], "-") {
+		name += " " + rest[1]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "aqmctl: unknown command %q\n\n", name)
+		printUsage(stderr, fs)
+		return 2
+	}
+	cmdArgs := rest[len(strings.Fields(name)):]
+
+	backend, closeBackend, err := open(g)
+	if err != nil {
+		fmt.Fprintf(stderr, "aqmctl: %v\n", err)
+		return 1
+	}
+	defer closeBackend()
+
+	e := &env{backend: backend, out: newPrinter(stdout, g.output), stderr: stderr, actor: g.actor}
+	if err := cmd.run(ctx, e, cmdArgs); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return 0
+		}
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		fmt.Fprintf(stderr, "aqmctl: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func printUsage(w io.Writer, fs *pflag.FlagSet) {
+	fmt.Fprintln(w, "Usage: aqmctl [global flags] <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-22s %s\n", name, commands[name].usage)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Global flags:")
+	fmt.Fprint(w, fs.FlagUsages())
+}
+
+// newFlagSet returns a flag set for a subcommand printing to e.stderr.
+func newFlagSet(name string, e *env) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+// parseFlags parses args and checks that every required flag is set.
+func parseFlags(fs *pflag.FlagSet, args []string, required ...string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	for _, name := range required {
+		if !fs.Changed(name) {
+			fmt.Fprintf(fs.Output(), "aqmctl %s: --%s is required\n", fs.Name(), name)
+			fs.PrintDefaults()
+			return errUsage
+		}
+	}
+	return nil
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/audit"
+	auditfake "github.com/aquamarinepk/aqm/audit/fake"
+	"github.com/aquamarinepk/aqm/auth/fake"
+	"github.com/aquamarinepk/aqm/auth/handler"
+	"github.com/aquamarinepk/aqm/auth/seed"
+	"github.com/aquamarinepk/aqm/httpclient"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/go-chi/chi/v5"
+)
+
+type result struct {
+	code   int
+	stdout string
+	stderr string
+}
+
+func runCmd(t *testing.T, open func(globalFlags) (Backend, func(), error), args ...string) result {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr, open)
+	return result{code: code, stdout: stdout.String(), stderr: stderr.String()}
+}
+
+func backendOf(b Backend) func(globalFlags) (Backend, func(), error) {
+	return func(globalFlags) (Backend, func(), error) {
+		return b, func() {}, nil
+	}
+}
+
+// newTestServer serves the auth API on fake stores plus an audit endpoint
+// listing events from auditStore.
+func newTestServer(t *testing.T, auditStore audit.Store) *httptest.Server {
+	t.Helper()
+
+	users := fake.NewUserStore()
+	roles := fake.NewRoleStore()
+	grants := fake.NewGrantStore(roles)
+
+	r := chi.NewRouter()
+	handler.NewAuthNHandler(users, fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator()).RegisterRoutes(r)
+	seeder := seed.New(users, roles, grants, testSeedConfig, log.NewNoopLogger())
+	handler.NewAuthZHandler(roles, grants, handler.WithSnapshots(seeder)).RegisterRoutes(r)
+	r.Get("/audit/events", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer admin-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		q := r.URL.Query()
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		filter, _ := audit.Filter{Action: q.Get("action"), Limit: limit, Offset: offset}.Normalize()
+		events, _ := auditStore.List(r.Context(), filter)
+		json.NewEncoder(w).Encode(map[string]any{"events": events})
+	})
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+var testSeedConfig = &seed.Config{
+	EncryptionKey: []byte("test-encryption-key-32-bytes!!!!"),
+	SigningKey:    []byte("test-signing-key-32-bytes-long!!"),
+}
+
+func newDirectBackend() (*storeBackend, *auditfake.Store) {
+	roles := fake.NewRoleStore()
+	auditStore := auditfake.NewStore()
+	return newStoreBackend(fake.NewUserStore(), roles, fake.NewGrantStore(roles), auditStore, fake.NewCryptoService(), fake.NewPasswordGenerator(), testSeedConfig), auditStore
+}
+
+func appendEvents(t *testing.T, store audit.Store, n int) {
+	t.Helper()
+
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		e := audit.NewEvent("admin", "grant.assigned", "user-"+strconv.Itoa(i))
+		e.At = at.Add(time.Duration(i) * time.Second)
+		if err := store.Append(context.Background(), e); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	auditStore := auditfake.NewStore()
+	srv := newTestServer(t, auditStore)
+	httpB := newHTTPBackend(httpclient.New(srv.URL, log.NewNoopLogger(), httpclient.WithRetryMax(0), httpclient.WithHeader("Authorization", "Bearer admin-token")))
+	directB, directAudit := newDirectBackend()
+
+	backends := map[string]struct {
+		backend Backend
+		audit   audit.Store
+	}{
+		"http":   {backend: httpB, audit: auditStore},
+		"direct": {backend: directB, audit: directAudit},
+	}
+
+	for name, tb := range backends {
+		t.Run(name, func(t *testing.T) {
+			open := backendOf(tb.backend)
+
+			res := runCmd(t, open, "bootstrap")
+			if res.code != 0 || !strings.Contains(res.stdout, "superadmin") || !strings.Contains(res.stdout, "GeneratedPassword123!") {
+				t.Fatalf("bootstrap = %+v", res)
+			}
+
+			res = runCmd(t, open, "bootstrap")
+			if res.code != 0 || !strings.Contains(res.stdout, "already exists") {
+				t.Errorf("second bootstrap = %+v, want already exists", res)
+			}
+
+			res = runCmd(t, open, "users", "create", "--email", "ann@example.com", "--username", "ann", "--password", "Password123!")
+			if res.code != 0 || !strings.Contains(res.stdout, "ann") {
+				t.Fatalf("users create = %+v", res)
+			}
+
+			res = runCmd(t, open, "users", "create", "--email", "ann@example.com", "--username", "ann2", "--password", "Password123!")
+			if res.code != 1 || res.stderr == "" {
+				t.Errorf("users create duplicate = %+v, want exit 1 with error", res)
+			}
+
+			res = runCmd(t, open, "-o", "json", "users", "reset-password", "--username", "ann")
+			var reset passwordReset
+			if res.code != 0 || json.Unmarshal([]byte(res.stdout), &reset) != nil || reset.Password != "GeneratedPassword123!" {
+				t.Errorf("users reset-password = %+v", res)
+			}
+
+			res = runCmd(t, open, "roles", "create", "--name", "editor", "--permissions", "posts:read,posts:write")
+			if res.code != 0 || !strings.Contains(res.stdout, "posts:read,posts:write") {
+				t.Fatalf("roles create = %+v", res)
+			}
+
+			res = runCmd(t, open, "--actor", "ops", "grants", "assign", "--username", "ann", "--role", "editor")
+			if res.code != 0 || !strings.Contains(res.stdout, "ops") {
+				t.Fatalf("grants assign = %+v", res)
+			}
+
+			res = runCmd(t, open, "-o", "json", "grants", "list", "--username", "ann")
+			var grants []map[string]any
+			if res.code != 0 || json.Unmarshal([]byte(res.stdout), &grants) != nil || len(grants) != 1 {
+				t.Errorf("grants list = %+v", res)
+			}
+
+			res = runCmd(t, open, "grants", "revoke", "--username", "ann", "--role", "editor")
+			if res.code != 0 || !strings.Contains(res.stdout, "Revoked editor from ann") {
+				t.Errorf("grants revoke = %+v", res)
+			}
+
+			res = runCmd(t, open, "-o", "json", "users", "list")
+			var users []map[string]any
+			if res.code != 0 || json.Unmarshal([]byte(res.stdout), &users) != nil || len(users) != 2 {
+				t.Errorf("users list = %+v", res)
+			}
+
+			appendEvents(t, tb.audit, audit.MaxLimit+20)
+
+			res = runCmd(t, open, "-o", "json", "audit", "dump")
+			var events []*audit.Event
+			if res.code != 0 || json.Unmarshal([]byte(res.stdout), &events) != nil || len(events) != audit.MaxLimit+20 {
+				t.Errorf("audit dump returned %d events, exit %d, stderr %s", len(events), res.code, res.stderr)
+			}
+
+			res = runCmd(t, open, "audit", "dump", "--limit", "3", "--action", "grant.assigned")
+			if lines := strings.Count(res.stdout, "\n"); res.code != 0 || lines != 4 {
+				t.Errorf("audit dump --limit 3 printed %d lines, want header and 3 rows:\n%s", lines, res.stdout)
+			}
+		})
+	}
+}
+
+func TestAuthzSnapshot(t *testing.T) {
+	newHTTP := func() Backend {
+		srv := newTestServer(t, auditfake.NewStore())
+		return newHTTPBackend(httpclient.New(srv.URL, log.NewNoopLogger(), httpclient.WithRetryMax(0)))
+	}
+	newDirect := func() Backend {
+		b, _ := newDirectBackend()
+		return b
+	}
+
+	for name, newBackend := range map[string]func() Backend{"http": newHTTP, "direct": newDirect} {
+		t.Run(name, func(t *testing.T) {
+			source, target := backendOf(newBackend()), backendOf(newBackend())
+			file := filepath.Join(t.TempDir(), "authz.yaml")
+
+			runCmd(t, source, "roles", "create", "--name", "editor", "--permissions", "posts:read,posts:write")
+			runCmd(t, source, "grants", "assign", "--username", "ann", "--role", "editor")
+
+			res := runCmd(t, source, "authz", "export", "--file", file)
+			if res.code != 0 || !strings.Contains(res.stdout, "Exported 1 roles, 1 grants") {
+				t.Fatalf("authz export = %+v", res)
+			}
+
+			res = runCmd(t, target, "authz", "import", "--file", file, "--dry-run")
+			if res.code != 0 || strings.Count(res.stdout, "create") != 2 || !strings.Contains(res.stdout, "Dry run: 2 changes") {
+				t.Fatalf("authz import --dry-run = %+v", res)
+			}
+			if res := runCmd(t, target, "roles", "list"); strings.Contains(res.stdout, "editor") {
+				t.Errorf("dry run created the role: %s", res.stdout)
+			}
+
+			res = runCmd(t, target, "authz", "import", "--file", file)
+			if res.code != 0 || !strings.Contains(res.stdout, "Created 2, updated 0, unchanged 0") {
+				t.Fatalf("authz import = %+v", res)
+			}
+
+			want := runCmd(t, source, "authz", "export", "--format", "json")
+			got := runCmd(t, target, "authz", "export", "--format", "json")
+			if got.code != 0 || got.stdout != want.stdout {
+				t.Errorf("target export = %s, want %s", got.stdout, want.stdout)
+			}
+
+			res = runCmd(t, target, "-o", "json", "authz", "import", "--file", file, "--dry-run")
+			var resp handler.ImportResponse
+			if res.code != 0 || json.Unmarshal([]byte(res.stdout), &resp) != nil || len(resp.Diff.Changes) != 0 || resp.Diff.Unchanged != 2 {
+				t.Errorf("second dry run = %+v", res)
+			}
+		})
+	}
+}
+
+func TestUsage(t *testing.T) {
+	b, _ := newDirectBackend()
+	open := backendOf(b)
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "no command", args: nil, wantCode: 0},
+		{name: "help", args: []string{"help"}, wantCode: 0},
+		{name: "unknown command", args: []string{"users", "frobnicate"}, wantCode: 2, wantErr: "unknown command"},
+		{name: "missing flag", args: []string{"users", "create", "--email", "a@example.com"}, wantCode: 2, wantErr: "--username is required"},
+		{name: "bad output", args: []string{"-o", "yaml", "users", "list"}, wantCode: 2, wantErr: "unknown output format"},
+		{name: "bad since", args: []string{"audit", "dump", "--since", "yesterday"}, wantCode: 1, wantErr: "--since"},
+		{name: "bad snapshot format", args: []string{"authz", "export", "--format", "toml"}, wantCode: 1, wantErr: "--format"},
+		{name: "missing snapshot", args: []string{"authz", "import"}, wantCode: 2, wantErr: "--file is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runCmd(t, open, tt.args...)
+			if res.code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d; stderr: %s", res.code, tt.wantCode, res.stderr)
+			}
+			if tt.wantErr != "" && !strings.Contains(res.stderr, tt.wantErr) {
+				t.Errorf("stderr = %q, want to contain %q", res.stderr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBootstrapToken(t *testing.T) {
+	r := chi.NewRouter()
+	handler.NewAuthNHandler(fake.NewUserStore(), fake.NewCryptoService(), fake.NewTokenGenerator(), fake.NewPasswordGenerator(), fake.NewPINGenerator(),
+		handler.WithBootstrap("bootstrap-secret")).RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	res := runCmd(t, openBackend, "--server", srv.URL, "bootstrap")
+	if res.code != 1 || !strings.Contains(res.stderr, "401") {
+		t.Errorf("bootstrap without token = %+v, want 401", res)
+	}
+
+	res = runCmd(t, openBackend, "--server", srv.URL, "--bootstrap-token", "bootstrap-secret", "bootstrap")
+	if res.code != 0 || !strings.Contains(res.stdout, "superadmin") {
+		t.Errorf("bootstrap with token = %+v", res)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	srv := newTestServer(t, auditfake.NewStore())
+	b := newHTTPBackend(httpclient.New(srv.URL, log.NewNoopLogger(), httpclient.WithRetryMax(0)))
+
+	res := runCmd(t, backendOf(b), "grants", "assign", "--username", "ann", "--role", "missing")
+	if res.code != 1 || !strings.Contains(res.stderr, "ROLE_NOT_FOUND") {
+		t.Errorf("grants assign = %+v, want ROLE_NOT_FOUND", res)
+	}
+
+	res = runCmd(t, backendOf(b), "audit", "dump")
+	if res.code != 1 || !strings.Contains(res.stderr, "401") {
+		t.Errorf("audit dump without token = %+v, want 401", res)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// printer writes command results as an aligned table or as indented JSON.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) *printer {
+	return &printer{w: w, format: format}
+}
+
+// print writes v as JSON, or header and rows as a table.
+func (p *printer) print(v any, header []string, rows [][]string) error {
+	if p.format == "json" {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// message writes a confirmation for commands without a result.
+func (p *printer) message(msg string) error {
+	if p.format == "json" {
+		return p.print(map[string]string{"message": msg}, nil, nil)
+	}
+	_, err := fmt.Fprintln(p.w, msg)
+	return err
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/aquamarinepk/aqm/audit"
+	auditpostgres "github.com/aquamarinepk/aqm/audit/postgres"
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/handler"
+	"github.com/aquamarinepk/aqm/auth/postgres"
+	"github.com/aquamarinepk/aqm/auth/seed"
+	"github.com/aquamarinepk/aqm/auth/service"
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/db"
+	"github.com/aquamarinepk/aqm/log"
+)
+
+// storeBackend runs the admin operations on the stores through the auth
+// service layer, bypassing the HTTP API and its permission checks.
+type storeBackend struct {
+	users  auth.UserStore
+	roles  auth.RoleStore
+	grants auth.GrantStore
+	audit  audit.Store
+	crypto service.CryptoService
+	pwdGen service.PasswordGenerator
+	seeder *seed.Seeder
+}
+
+func newStoreBackend(users auth.UserStore, roles auth.RoleStore, grants auth.GrantStore, auditStore audit.Store, crypto service.CryptoService, pwdGen service.PasswordGenerator, seedCfg *seed.Config) *storeBackend {
+	return &storeBackend{
+		users:  users,
+		roles:  roles,
+		grants: grants,
+		audit:  auditStore,
+		crypto: crypto,
+		pwdGen: pwdGen,
+		seeder: seed.New(users, roles, grants, seedCfg, log.NewNoopLogger()),
+	}
+}
+
+// openStoreBackend loads the service config and opens its PostgreSQL
+// database. Migrations are not run; the service owns its schema.
+func openStoreBackend(path, envPrefix string) (Backend, func(), error) {
+	opts := []config.Option{config.WithFile(path), config.WithEnvExpansion()}
+	if envPrefix != "" {
+		opts = append(opts, config.WithPrefix(envPrefix))
+	}
+	cfg, err := config.New(log.NewNoopLogger(), opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot load config: %w", err)
+	}
+	if cfg.Database.Driver != "postgres" {
+		return nil, nil, fmt.Errorf("--direct requires database.driver postgres, got %q", cfg.Database.Driver)
+	}
+
+	sqlDB, err := database.Open(cfg.Database)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot open database: %w", err)
+	}
+	if err := sqlDB.PingContext(context.Background()); err != nil {
+		sqlDB.Close()
+		return nil, nil, fmt.Errorf("cannot connect to database: %w", err)
+	}
+
+	crypto := service.NewDefaultCryptoService([]byte(cfg.Auth.EncryptionKey), []byte(cfg.Auth.SigningKey)).
+		WithPasswordParams(service.PasswordParamsFromConfig(cfg.Auth.Password)).
+		WithDerivedKeys(cfg.Auth.DerivedKeys)
+	b := newStoreBackend(
+		postgres.NewUserStore(sqlDB),
+		postgres.NewRoleStore(sqlDB),
+		postgres.NewGrantStore(sqlDB),
+		auditpostgres.NewStore(sqlDB),
+		crypto,
+		service.NewDefaultPasswordGenerator(generatedPasswordLength),
+		&seed.Config{
+			EncryptionKey:  []byte(cfg.Auth.EncryptionKey),
+			SigningKey:     []byte(cfg.Auth.SigningKey),
+			PasswordParams: service.PasswordParamsFromConfig(cfg.Auth.Password),
+			KeyScheme:      crypto.KeyScheme(),
+		},
+	)
+	return b, closer(sqlDB), nil
+}
+
+func closer(sqlDB *sql.DB) func() {
+	return func() { sqlDB.Close() }
+}
+
+func (b *storeBackend) CreateUser(ctx context.Context, email, password, username, name string) (*auth.User, error) {
+	return service.SignUp(ctx, b.users, b.crypto, email, password, username, name)
+}
+
+func (b *storeBackend) ListUsers(ctx context.Context, status auth.UserStatus) ([]*auth.User, error) {
+	if status != "" {
+		return service.ListUsersByStatus(ctx, b.users, status)
+	}
+	return service.ListUsers(ctx, b.users)
+}
+
+func (b *storeBackend) ResetPassword(ctx context.Context, username, password string) (string, error) {
+	user, err := service.GetUserByUsername(ctx, b.users, username)
+	if err != nil {
+		return "", err
+	}
+	set, err := service.ResetPassword(ctx, b.users, b.crypto, b.pwdGen, user.ID, password)
+	if err != nil {
+		return "", err
+	}
+	if password != "" {
+		return "", nil
+	}
+	return set, nil
+}
+
+func (b *storeBackend) CreateRole(ctx context.Context, name, description string, permissions []string, createdBy string) (*auth.Role, error) {
+	return service.CreateRole(ctx, b.roles, name, description, permissions, createdBy)
+}
+
+func (b *storeBackend) ListRoles(ctx context.Context) ([]*auth.Role, error) {
+	return service.ListRoles(ctx, b.roles)
+}
+
+func (b *storeBackend) AssignRole(ctx context.Context, username, role, assignedBy string) (*auth.Grant, error) {
+	roleID, err := b.roleID(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	return service.AssignRole(ctx, b.grants, username, roleID, assignedBy)
+}
+
+func (b *storeBackend) RevokeRole(ctx context.Context, username, role string) error {
+	roleID, err := b.roleID(ctx, role)
+	if err != nil {
+		return err
+	}
+	return service.RevokeRole(ctx, b.grants, username, roleID)
+}
+
+func (b *storeBackend) ListGrants(ctx context.Context, username string) ([]*auth.Grant, error) {
+	return service.GetUserGrants(ctx, b.grants, username)
+}
+
+func (b *storeBackend) ListAuditEvents(ctx context.Context, filter audit.Filter) ([]*audit.Event, error) {
+	filter, err := filter.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	return b.audit.List(ctx, filter)
+}
+
+func (b *storeBackend) Bootstrap(ctx context.Context) (*auth.User, string, error) {
+	return service.Bootstrap(ctx, b.users, b.crypto, b.pwdGen)
+}
+
+func (b *storeBackend) Export(ctx context.Context, users bool) (*seed.Manifest, error) {
+	return b.seeder.Export(ctx, users)
+}
+
+func (b *storeBackend) Import(ctx context.Context, m *seed.Manifest, dryRun bool) (*handler.ImportResponse, error) {
+	diff, err := b.seeder.Diff(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return &handler.ImportResponse{DryRun: true, Diff: diff}, nil
+	}
+	res, err := b.seeder.Apply(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return &handler.ImportResponse{Diff: diff, Result: res}, nil
+}
+
+func (b *storeBackend) roleID(ctx context.Context, ref string) (auth.RoleID, error) {
+	if id, err := auth.ParseRoleID(ref); err == nil {
+		return id, nil
+	}
+	role, err := service.GetRoleByName(ctx, b.roles, ref)
+	if err != nil {
+		return auth.RoleID{}, err
+	}
+	return role.ID, nil
+}
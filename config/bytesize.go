@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a size in bytes, set in config as an integer or with a unit:
+// "512", "64KB", "10MB", "1GB". Units are powers of 1024; KiB, MiB and GiB
+// are accepted as well. koanf decodes it through UnmarshalText.
+type ByteSize int64
+
+// Byte size units.
+const (
+	Byte     ByteSize = 1
+	Kilobyte          = 1024 * Byte
+	Megabyte          = 1024 * Kilobyte
+	Gigabyte          = 1024 * Megabyte
+)
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   ByteSize
+}{
+	{"kib", Kilobyte}, {"mib", Megabyte}, {"gib", Gigabyte},
+	{"kb", Kilobyte}, {"mb", Megabyte}, {"gb", Gigabyte},
+	{"k", Kilobyte}, {"m", Megabyte}, {"g", Gigabyte},
+	{"b", Byte},
+}
+
+// ParseByteSize parses s as a ByteSize.
+func ParseByteSize(s string) (ByteSize, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	unit := Byte
+	for _, u := range byteSizeUnits {
+		if strings.HasSuffix(v, u.suffix) {
+			v, unit = strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	return ByteSize(n * float64(unit)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// String returns the size with the largest unit that divides it, e.g. "10MB".
+func (b ByteSize) String() string {
+	for _, u := range []struct {
+		suffix string
+		size   ByteSize
+	}{{"GB", Gigabyte}, {"MB", Megabyte}, {"KB", Kilobyte}} {
+		if b != 0 && b%u.size == 0 {
+			return fmt.Sprintf("%d%s", b/u.size, u.suffix)
+		}
+	}
+	return strconv.FormatInt(int64(b), 10)
+}
+
+// Int64 returns the size in bytes.
+func (b ByteSize) Int64() int64 {
+	return int64(b)
+}
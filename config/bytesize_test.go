@@ -0,0 +1,55 @@
+package config
+
+import "testing"
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ByteSize
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"512", 512, false},
+		{"64KB", 64 * Kilobyte, false},
+		{"10MB", 10 * Megabyte, false},
+		{"10 mib", 10 * Megabyte, false},
+		{"1.5G", Gigabyte + 512*Megabyte, false},
+		{"100B", 100, false},
+		{"", 0, true},
+		{"ten MB", 0, true},
+		{"-1MB", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseByteSize(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseByteSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseByteSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	tests := []struct {
+		in   ByteSize
+		want string
+	}{
+		{0, "0"},
+		{1000, "1000"},
+		{2 * Kilobyte, "2KB"},
+		{10 * Megabyte, "10MB"},
+		{Gigabyte, "1GB"},
+		{Megabyte + 1, "1048577"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("ByteSize(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+		if back, err := ParseByteSize(tt.in.String()); err != nil || back != tt.in {
+			t.Errorf("ParseByteSize(%q) = %d, %v, want %d", tt.in.String(), back, err, tt.in)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"net/netip"
 	"os"
 	"strings"
 	"time"
@@ -18,51 +20,185 @@ import (
 
 // Config holds the application configuration.
 type Config struct {
-	Log      LogConfig      `koanf:"log"`
-	Server   ServerConfig   `koanf:"server"`
-	Database DatabaseConfig `koanf:"database"`
-	NATS     NATSConfig     `koanf:"nats"`
-	Assets   AssetsConfig   `koanf:"assets"`
-	Auth     AuthConfig     `koanf:"auth"`
-	AQM      AQMConfig      `koanf:"aqm"`
+	Log           LogConfig           `koanf:"log"`
+	Server        ServerConfig        `koanf:"server"`
+	Database      DatabaseConfig      `koanf:"database"`
+	NATS          NATSConfig          `koanf:"nats"`
+	Kafka         KafkaConfig         `koanf:"kafka"`
+	Redis         RedisConfig         `koanf:"redis"`
+	Assets        AssetsConfig        `koanf:"assets"`
+	Auth          AuthConfig          `koanf:"auth"`
+	Mail          MailConfig          `koanf:"mail"`
+	AuthClient    AuthClientConfig    `koanf:"authclient"`
+	AQM           AQMConfig           `koanf:"aqm"`
+	Observability ObservabilityConfig `koanf:"observability"`
 
 	// Internal fields (not marshaled by koanf)
-	k      *koanf.Koanf
-	logger log.Logger
+	k        *koanf.Koanf
+	logger   log.Logger
+	sources  map[string]string
+	resolved map[string]bool
 }
 
 // AQMConfig holds framework-level configuration shared across all services.
 // These settings use the AQM_ environment variable prefix.
 type AQMConfig struct {
 	DevMode bool `koanf:"devmode"`
+	// Profile is the active config profile, read from AQM_PROFILE
+	// (see WithProfiles).
+	Profile string `koanf:"profile"`
 }
 
 // LogConfig holds logging configuration.
 type LogConfig struct {
-	Level string `koanf:"level"`
+	Level  string `koanf:"level"`
+	Format string `koanf:"format"`
+}
+
+// NewLogger creates a logger from the configured level and format.
+func (c LogConfig) NewLogger() log.Logger {
+	return log.NewLoggerWithFormat(c.Level, c.Format)
 }
 
 // ServerConfig holds HTTP server configuration.
+// Zero timeouts mean no timeout; a zero MaxBodyBytes means no body limit.
 type ServerConfig struct {
-	Port string `koanf:"port"`
+	Port              string            `koanf:"port"`
+	ReadHeaderTimeout time.Duration     `koanf:"readheadertimeout"`
+	ReadTimeout       time.Duration     `koanf:"readtimeout"`
+	WriteTimeout      time.Duration     `koanf:"writetimeout"`
+	IdleTimeout       time.Duration     `koanf:"idletimeout"`
+	MaxBodyBytes      ByteSize          `koanf:"maxbodybytes"`
+	TLS               TLSConfig         `koanf:"tls"`
+	CORS              CORSConfig        `koanf:"cors"`
+	ClientIP          ClientIPConfig    `koanf:"clientip"`
+	Maintenance       MaintenanceConfig `koanf:"maintenance"`
+}
+
+// TLSConfig holds HTTPS configuration. Certificates come either from
+// CertFile/KeyFile or, when Autocert.Domains is set, from Let's Encrypt.
+type TLSConfig struct {
+	Enabled  bool           `koanf:"enabled"`
+	CertFile string         `koanf:"certfile"`
+	KeyFile  string         `koanf:"keyfile"`
+	Autocert AutocertConfig `koanf:"autocert"`
+
+	// ClientCAFile is the PEM trust bundle client certificates are verified
+	// against, enabling mutual TLS. ClientAuth is "optional", the default,
+	// to verify certificates only when presented, or "required".
+	ClientCAFile string `koanf:"clientcafile"`
+	ClientAuth   string `koanf:"clientauth"`
+
+	SPIFFE SPIFFEConfig `koanf:"spiffe"`
+}
+
+// SPIFFEConfig serves TLS with the X.509 SVID of the workload, fetched from
+// a SPIFFE Workload API and kept rotated, instead of certificate files or
+// autocert. Client certificates are verified against the trust bundle of
+// the SVID as TLSConfig.ClientAuth says. Socket is the address of the
+// Workload API, the SPIFFE_ENDPOINT_SOCKET environment variable when empty.
+type SPIFFEConfig struct {
+	Enabled bool   `koanf:"enabled"`
+	Socket  string `koanf:"socket"`
+}
+
+// CORSConfig holds the cross-origin policy for browser clients. CORS is
+// disabled while AllowedOrigins is empty; "*" allows any origin.
+// MaxAge is how long browsers may cache a preflight response.
+type CORSConfig struct {
+	AllowedOrigins   []string      `koanf:"allowedorigins"`
+	AllowedMethods   []string      `koanf:"allowedmethods"`
+	AllowedHeaders   []string      `koanf:"allowedheaders"`
+	ExposedHeaders   []string      `koanf:"exposedheaders"`
+	AllowCredentials bool          `koanf:"allowcredentials"`
+	MaxAge           time.Duration `koanf:"maxage"`
+}
+
+// ClientIPConfig controls how the client address of a request is found.
+// Forwarding headers are believed only from TrustedProxies, CIDRs or single
+// addresses; Headers lists them in order of preference. GeoIPDB is the path
+// of a MaxMind DB file used to locate clients; empty disables geolocation.
+type ClientIPConfig struct {
+	TrustedProxies []string `koanf:"trustedproxies"`
+	Headers        []string `koanf:"headers"`
+	GeoIPDB        string   `koanf:"geoipdb"`
+}
+
+// MaintenanceConfig starts the service in maintenance mode, answering 503
+// with a Retry-After of RetryAfter to all but health and allowed routes until
+// it is switched off, see app.WithMaintenanceMode.
+type MaintenanceConfig struct {
+	Enabled    bool          `koanf:"enabled"`
+	RetryAfter time.Duration `koanf:"retryafter"`
+}
+
+// AutocertConfig holds ACME (Let's Encrypt) certificate settings.
+type AutocertConfig struct {
+	Domains  []string `koanf:"domains"`
+	Email    string   `koanf:"email"`
+	CacheDir string   `koanf:"cachedir"`
+	// HTTPAddr serves ACME HTTP-01 challenges and redirects other requests to HTTPS.
+	HTTPAddr string `koanf:"httpaddr"`
+}
+
+// UsesAutocert reports whether certificates are obtained via ACME.
+func (t TLSConfig) UsesAutocert() bool {
+	return t.Enabled && len(t.Autocert.Domains) > 0
+}
+
+// Client certificate modes of TLSConfig.ClientAuth.
+const (
+	ClientAuthOptional = "optional"
+	ClientAuthRequired = "required"
+)
+
+// UsesClientCerts reports whether client certificates are verified.
+func (t TLSConfig) UsesClientCerts() bool {
+	return t.Enabled && (t.ClientCAFile != "" || t.SPIFFE.Enabled)
+}
+
+// UsesSPIFFE reports whether the certificate comes from a SPIFFE Workload
+// API.
+func (t TLSConfig) UsesSPIFFE() bool {
+	return t.Enabled && t.SPIFFE.Enabled
 }
 
 // DatabaseConfig holds database connection configuration.
 type DatabaseConfig struct {
-	Driver   string `koanf:"driver"`
-	Host     string `koanf:"host"`
-	Port     int    `koanf:"port"`
-	User     string `koanf:"user"`
-	Password string `koanf:"password"`
-	Database string `koanf:"database"`
-	Schema   string `koanf:"schema"`
-	SSLMode  string `koanf:"sslmode"`
+	Driver   string        `koanf:"driver"`
+	Host     string        `koanf:"host"`
+	Port     int           `koanf:"port"`
+	User     string        `koanf:"user"`
+	Password string        `koanf:"password"`
+	Database string        `koanf:"database"`
+	Schema   string        `koanf:"schema"`
+	SSLMode  string        `koanf:"sslmode"`
+	Migrate  MigrateConfig `koanf:"migrate"`
+	Pool     PoolConfig    `koanf:"pool"`
+}
+
+// PoolConfig holds connection pool settings applied to *sql.DB.
+// Zero values leave the database/sql defaults in place.
+type PoolConfig struct {
+	MaxOpenConns    int           `koanf:"maxopenconns"`
+	MaxIdleConns    int           `koanf:"maxidleconns"`
+	ConnMaxLifetime time.Duration `koanf:"connmaxlifetime"`
+	ConnMaxIdleTime time.Duration `koanf:"connmaxidletime"`
 }
 
-// AssetsConfig holds asset storage configuration.
+// MigrateConfig controls whether pending migrations run on start.
+// With DryRun, pending migrations are logged but not applied.
+type MigrateConfig struct {
+	Enabled bool `koanf:"enabled"`
+	DryRun  bool `koanf:"dryrun"`
+}
+
+// AssetsConfig holds asset storage configuration. Storage is "none", which
+// disables uploads, "local" or "s3".
 type AssetsConfig struct {
 	Storage string      `koanf:"storage"`
 	Local   LocalConfig `koanf:"local"`
+	S3      S3Config    `koanf:"s3"`
 }
 
 // LocalConfig holds local filesystem storage configuration.
@@ -70,24 +206,278 @@ type LocalConfig struct {
 	Path string `koanf:"path"`
 }
 
-// NATSConfig holds NATS connection configuration.
+// S3Config holds S3 storage settings. Endpoint selects an S3-compatible
+// service such as MinIO; it defaults to AWS for Region. PathStyle addresses
+// the bucket in the URL path instead of the host name. Prefix is prepended
+// to every object key.
+type S3Config struct {
+	Bucket          string `koanf:"bucket"`
+	Region          string `koanf:"region"`
+	Endpoint        string `koanf:"endpoint"`
+	AccessKeyID     string `koanf:"accesskeyid"`
+	SecretAccessKey string `koanf:"secretaccesskey"`
+	Prefix          string `koanf:"prefix"`
+	PathStyle       bool   `koanf:"pathstyle"`
+}
+
+// NATSConfig holds NATS connection configuration used by the nats package.
+// MaxReconnect bounds the connection attempts, -1 retries forever; the delay
+// between attempts starts at ReconnectWait and doubles. DrainTimeout bounds
+// the drain on shutdown.
 type NATSConfig struct {
-	URL          string `koanf:"url"`
-	ClusterID    string `koanf:"clusterid"`
-	ClientID     string `koanf:"clientid"`
-	MaxReconnect int    `koanf:"maxreconnect"`
+	URL            string          `koanf:"url"`
+	ClusterID      string          `koanf:"clusterid"`
+	ClientID       string          `koanf:"clientid"`
+	MaxReconnect   int             `koanf:"maxreconnect"`
+	ReconnectWait  time.Duration   `koanf:"reconnectwait"`
+	ConnectTimeout time.Duration   `koanf:"connecttimeout"`
+	DrainTimeout   time.Duration   `koanf:"draintimeout"`
+	JetStream      JetStreamConfig `koanf:"jetstream"`
+}
+
+// JetStreamConfig configures the pubsub/nats JetStream broker. Streams and
+// Consumers, keyed by name, are created or updated on start. AckWait and
+// MaxDeliver apply to the durable consumers created on Subscribe and to the
+// configured consumers that leave them zero.
+type JetStreamConfig struct {
+	Enabled    bool                               `koanf:"enabled"`
+	AckWait    time.Duration                      `koanf:"ackwait"`
+	MaxDeliver int                                `koanf:"maxdeliver"`
+	Streams    map[string]JetStreamStreamConfig   `koanf:"streams"`
+	Consumers  map[string]JetStreamConsumerConfig `koanf:"consumers"`
+}
+
+// JetStreamStreamConfig describes a stream. Storage is "file", the default,
+// or "memory"; DuplicateWindow is how long published message IDs are
+// remembered to drop duplicates.
+type JetStreamStreamConfig struct {
+	Subjects        []string      `koanf:"subjects"`
+	Storage         string        `koanf:"storage"`
+	Replicas        int           `koanf:"replicas"`
+	MaxAge          time.Duration `koanf:"maxage"`
+	DuplicateWindow time.Duration `koanf:"duplicatewindow"`
+}
+
+// JetStreamConsumerConfig describes a durable consumer of Stream. A
+// subscriber whose SubscriberID names it consumes through it.
+type JetStreamConsumerConfig struct {
+	Stream        string        `koanf:"stream"`
+	FilterSubject string        `koanf:"filtersubject"`
+	AckWait       time.Duration `koanf:"ackwait"`
+	MaxDeliver    int           `koanf:"maxdeliver"`
+}
+
+// KafkaConfig holds Kafka connection configuration used by pubsub/kafka.
+// Every subscriber consumes in its own consumer group, GroupPrefix followed
+// by its SubscriberID, so each one receives every message of the topic.
+type KafkaConfig struct {
+	Brokers     []string        `koanf:"brokers"`
+	ClientID    string          `koanf:"clientid"`
+	GroupPrefix string          `koanf:"groupprefix"`
+	DialTimeout time.Duration   `koanf:"dialtimeout"`
+	TLS         KafkaTLSConfig  `koanf:"tls"`
+	SASL        KafkaSASLConfig `koanf:"sasl"`
+}
+
+// KafkaTLSConfig enables TLS to the brokers. CAFile replaces the system
+// roots; CertFile and KeyFile present a client certificate.
+type KafkaTLSConfig struct {
+	Enabled            bool   `koanf:"enabled"`
+	CAFile             string `koanf:"cafile"`
+	CertFile           string `koanf:"certfile"`
+	KeyFile            string `koanf:"keyfile"`
+	InsecureSkipVerify bool   `koanf:"insecureskipverify"`
+}
+
+// KafkaSASLConfig authenticates to the brokers. An empty Mechanism disables
+// SASL.
+type KafkaSASLConfig struct {
+	Mechanism string `koanf:"mechanism"`
+	Username  string `koanf:"username"`
+	Password  string `koanf:"password"`
+}
+
+// SASL mechanisms of KafkaSASLConfig.Mechanism.
+const (
+	SASLPlain       = "plain"
+	SASLScramSHA256 = "scram-sha-256"
+	SASLScramSHA512 = "scram-sha-512"
+)
+
+// RedisConfig holds Redis connection configuration. KeyPrefix is prepended
+// to every key aqm writes so several services can share a database.
+// Zero timeouts and pool size leave the client defaults in place.
+type RedisConfig struct {
+	Addr         string        `koanf:"addr"`
+	Username     string        `koanf:"username"`
+	Password     string        `koanf:"password"`
+	DB           int           `koanf:"db"`
+	KeyPrefix    string        `koanf:"keyprefix"`
+	TLS          bool          `koanf:"tls"`
+	PoolSize     int           `koanf:"poolsize"`
+	DialTimeout  time.Duration `koanf:"dialtimeout"`
+	ReadTimeout  time.Duration `koanf:"readtimeout"`
+	WriteTimeout time.Duration `koanf:"writetimeout"`
+}
+
+// MailConfig holds outgoing email configuration. Driver is "none", which
+// disables mail, "log" or "smtp"; From is the sender address.
+type MailConfig struct {
+	Driver string     `koanf:"driver"`
+	From   string     `koanf:"from"`
+	SMTP   SMTPConfig `koanf:"smtp"`
+}
+
+// ObservabilityConfig holds the settings of error reporting.
+type ObservabilityConfig struct {
+	Errors ErrorReportingConfig `koanf:"errors"`
+}
+
+// ErrorReportingConfig selects where unexpected errors are reported. Driver
+// is "none", which drops them, or "sentry", which sends them to the project
+// of DSN. Environment and Release tag every event; SampleRate is the
+// fraction of errors sent, above 0 and at most 1.
+type ErrorReportingConfig struct {
+	Driver      string  `koanf:"driver"`
+	DSN         string  `koanf:"dsn"`
+	Environment string  `koanf:"environment"`
+	Release     string  `koanf:"release"`
+	SampleRate  float64 `koanf:"samplerate"`
+}
+
+// SMTPConfig holds SMTP server settings. TLS is "starttls", "tls" for
+// implicit TLS, or "none".
+type SMTPConfig struct {
+	Host     string `koanf:"host"`
+	Port     int    `koanf:"port"`
+	Username string `koanf:"username"`
+	Password string `koanf:"password"`
+	TLS      string `koanf:"tls"`
 }
 
 // AuthConfig holds authentication and session configuration.
 type AuthConfig struct {
-	SessionSecret            string `koanf:"session_secret"`
-	TokenTTL                 string `koanf:"token_ttl"`
-	EncryptionKey            string `koanf:"encryption_key"`
-	SigningKey               string `koanf:"signing_key"`
-	TokenPrivateKey          string `koanf:"token_private_key"`
-	RegistrationTokenTTL     string `koanf:"registration_token_ttl"`
-	PasswordResetTokenTTL    string `koanf:"password_reset_token_ttl"`
-	AutoApproveRegistrations bool   `koanf:"auto_approve_registrations"`
+	SessionSecret            string        `koanf:"session_secret"`
+	TokenTTL                 time.Duration `koanf:"token_ttl"`
+	EncryptionKey            string        `koanf:"encryption_key"`
+	SigningKey               string        `koanf:"signing_key"`
+	TokenPrivateKey          string        `koanf:"token_private_key"`
+	RegistrationTokenTTL     time.Duration `koanf:"registration_token_ttl"`
+	PasswordResetTokenTTL    time.Duration `koanf:"password_reset_token_ttl"`
+	AutoApproveRegistrations bool          `koanf:"auto_approve_registrations"`
+	// OAuth configures federated sign-in providers keyed by the name used in
+	// /auth/oauth/{provider} routes.
+	OAuth map[string]OAuthProviderConfig `koanf:"oauth"`
+	// Password selects how user passwords are hashed.
+	Password PasswordConfig `koanf:"password"`
+	// DerivedKeys encrypts each user's email and PIN with a key derived
+	// from EncryptionKey and the user ID instead of EncryptionKey itself.
+	DerivedKeys bool `koanf:"derived_keys"`
+	// MagicLink configures passwordless sign-in by email.
+	MagicLink MagicLinkConfig `koanf:"magic_link"`
+	// RequestSigning configures HMAC-signed calls to internal endpoints.
+	RequestSigning RequestSigningConfig `koanf:"request_signing"`
+	// Bootstrap restricts who may create the superadmin.
+	Bootstrap BootstrapConfig `koanf:"bootstrap"`
+	// Policies maps each policy users must accept, such as "terms", to its
+	// current version. Publishing a new version asks everyone to accept
+	// again.
+	Policies map[string]string `koanf:"policies"`
+	// EmailDomains restricts the email domains that may create accounts.
+	EmailDomains EmailDomainsConfig `koanf:"email_domains"`
+}
+
+// EmailDomainsConfig lists the email domains allowed and denied to create
+// accounts, by sign-up or OAuth provisioning. "*.example.com" matches the
+// subdomains of example.com. Deny wins; an empty Allow allows every domain
+// not denied.
+type EmailDomainsConfig struct {
+	Allow []string `koanf:"allow"`
+	Deny  []string `koanf:"deny"`
+}
+
+// RequestSigningConfig configures HMAC-signed calls between services. Keys
+// maps the key IDs accepted on internal endpoints to their shared secrets;
+// KeyID names the one this service signs its own calls with. Signed
+// timestamps may be off by Tolerance.
+type RequestSigningConfig struct {
+	KeyID     string            `koanf:"key_id"`
+	Keys      map[string]string `koanf:"keys"`
+	Tolerance time.Duration     `koanf:"tolerance"`
+}
+
+// BootstrapConfig restricts the bootstrap endpoints. Callers must present
+// Token and connect from one of AllowedIPs, CIDRs or single addresses;
+// either check is skipped when empty.
+type BootstrapConfig struct {
+	Token      string   `koanf:"token"`
+	AllowedIPs []string `koanf:"allowed_ips"`
+}
+
+// MagicLinkConfig configures passwordless sign-in links. URL is the page
+// the emailed link opens, with the token in its token query parameter;
+// leaving it empty disables the feature. Links expire after TTL, and each
+// email address may ask for Limit links per Window.
+type MagicLinkConfig struct {
+	URL    string        `koanf:"url"`
+	TTL    time.Duration `koanf:"ttl"`
+	Limit  int           `koanf:"limit"`
+	Window time.Duration `koanf:"window"`
+}
+
+// PasswordConfig selects the password hashing algorithm, "argon2id" or
+// "bcrypt", and its cost. Memory is in KiB and, with Iterations and
+// Parallelism, applies to Argon2id; Cost applies to bcrypt. Changing them
+// rehashes each user's password on their next sign-in.
+type PasswordConfig struct {
+	Algorithm   string `koanf:"algorithm"`
+	Memory      int    `koanf:"memory"`
+	Iterations  int    `koanf:"iterations"`
+	Parallelism int    `koanf:"parallelism"`
+	Cost        int    `koanf:"cost"`
+}
+
+// AuthClientConfig holds the endpoints and resilience settings of
+// authclient.Client. APIKey, when set, is sent as a bearer token with every
+// request. Failed requests are retried RetryMax times with exponential
+// backoff from RetryDelay; answers are cached for CacheTTL, zero disabling
+// the cache. When KeySetURL is set tokens are verified locally against the
+// key set published there, refetched every KeyRefresh.
+type AuthClientConfig struct {
+	AuthNURL   string        `koanf:"authnurl"`
+	AuthZURL   string        `koanf:"authzurl"`
+	APIKey     string        `koanf:"apikey"`
+	Timeout    time.Duration `koanf:"timeout"`
+	RetryMax   int           `koanf:"retrymax"`
+	RetryDelay time.Duration `koanf:"retrydelay"`
+	CacheTTL   time.Duration `koanf:"cachettl"`
+	Breaker    BreakerConfig `koanf:"breaker"`
+	KeySetURL  string        `koanf:"keyseturl"`
+	KeyRefresh time.Duration `koanf:"keyrefresh"`
+}
+
+// BreakerConfig configures a circuit breaker: after Failures consecutive
+// failed requests it rejects calls for Cooldown, then lets one trial
+// request through. Zero Failures disables the breaker.
+type BreakerConfig struct {
+	Failures int           `koanf:"failures"`
+	Cooldown time.Duration `koanf:"cooldown"`
+}
+
+// OAuthProviderConfig configures one OAuth2/OIDC provider.
+// Type is "google", "github" or "oidc". Google and GitHub endpoints are
+// built in; generic OIDC providers discover them from Issuer unless
+// AuthURL, TokenURL and UserInfoURL are all given.
+type OAuthProviderConfig struct {
+	Type         string   `koanf:"type"`
+	ClientID     string   `koanf:"client_id"`
+	ClientSecret string   `koanf:"client_secret"`
+	RedirectURL  string   `koanf:"redirect_url"`
+	Scopes       []string `koanf:"scopes"`
+	Issuer       string   `koanf:"issuer"`
+	AuthURL      string   `koanf:"auth_url"`
+	TokenURL     string   `koanf:"token_url"`
+	UserInfoURL  string   `koanf:"userinfo_url"`
 }
 
 // Option configures Config during initialization.
@@ -97,11 +487,17 @@ type Option func(*configOptions) error
 type configOptions struct {
 	prefix       string
 	file         string
+	profiles     []string
 	defaults     map[string]interface{}
 	envExpansion bool
+	secrets      SecretsProvider
+	strict       bool
+	sections     []string
 }
 
-// WithPrefix sets the environment variable prefix (e.g., "AUTHN_").
+// WithPrefix sets the environment variable prefix (e.g., "AUTHN_"). The rest
+// of each name, lowercased, is the configuration path with underscores for
+// dots, as in AUTHN_AUTH_TOKEN_TTL for "auth.token_ttl".
 func WithPrefix(prefix string) Option {
 	return func(opts *configOptions) error {
 		opts.prefix = prefix
@@ -117,6 +513,51 @@ func WithFile(path string) Option {
 	}
 }
 
+// ProfileEnv names the environment variable holding the active profile of
+// WithProfiles, e.g. dev, staging or prod.
+const ProfileEnv = "AQM_PROFILE"
+
+// profilePlaceholder is replaced with the active profile in the files of
+// WithProfiles.
+const profilePlaceholder = "{profile}"
+
+// WithProfiles loads the YAML files in order, each overriding the values of
+// the previous ones, after the file of WithFile if any. The placeholder
+// {profile} is replaced with the profile named by AQM_PROFILE; files with
+// it are skipped when no profile is active. Missing files are skipped, so
+//
+//	config.WithProfiles("config.yaml", "config.{profile}.yaml")
+//
+// keeps shared settings in config.yaml and only what differs per
+// environment in config.prod.yaml.
+func WithProfiles(files ...string) Option {
+	return func(opts *configOptions) error {
+		if len(files) == 0 {
+			return fmt.Errorf("profiles need at least one file")
+		}
+		opts.profiles = files
+		return nil
+	}
+}
+
+// profileFiles returns the files of WithProfiles for profile.
+func profileFiles(files []string, profile string) ([]string, error) {
+	if strings.ContainsAny(profile, `/\`) || strings.Contains(profile, "..") {
+		return nil, fmt.Errorf("invalid %s %q", ProfileEnv, profile)
+	}
+	var paths []string
+	for _, f := range files {
+		if strings.Contains(f, profilePlaceholder) {
+			if profile == "" {
+				continue
+			}
+			f = strings.ReplaceAll(f, profilePlaceholder, profile)
+		}
+		paths = append(paths, f)
+	}
+	return paths, nil
+}
+
 // WithDefaults provides default values via a map.
 func WithDefaults(defaults map[string]interface{}) Option {
 	return func(opts *configOptions) error {
@@ -156,31 +597,84 @@ func New(logger log.Logger, opts ...Option) (*Config, error) {
 
 	// Set baseline defaults
 	baselineDefaults := map[string]interface{}{
-		"log.level":                          "info",
-		"server.port":                        ":8080",
-		"database.driver":                    "fake",
-		"database.host":                      "localhost",
-		"database.port":                      5432,
-		"database.user":                      "dev",
-		"database.password":                  "dev",
-		"database.database":                  "dev",
-		"database.schema":                    "pulap_lite",
-		"database.sslmode":                   "disable",
-		"nats.url":                           "nats://localhost:4222",
-		"nats.clusterid":                     "",
-		"nats.clientid":                      "",
-		"nats.maxreconnect":                  10,
-		"assets.storage":                     "local",
-		"assets.local.path":                  "./data/uploads",
-		"auth.session_secret":                "change-this-in-production",
-		"auth.token_ttl":                     "24h",
-		"auth.encryption_key":                "12345678901234567890123456789012",
-		"auth.signing_key":                   "abcdefghijklmnopqrstuvwxyz123456",
-		"auth.token_private_key":             "ygvuJ/guxUMFKeIcz29Ab763Cq5DT+g2+3mRfGlNiYp0GVI1wTXGsqYlDWqYjPw4G416Z6P2hag8E+/B9GxrSA==",
-		"auth.registration_token_ttl":        "72h",
-		"auth.password_reset_token_ttl":      "1h",
-		"auth.auto_approve_registrations":    false,
-		"aqm.devmode":                        false,
+		"log.level":                       "info",
+		"log.format":                      "text",
+		"server.port":                     ":8080",
+		"server.readheadertimeout":        "10s",
+		"server.readtimeout":              "30s",
+		"server.writetimeout":             "60s",
+		"server.idletimeout":              "120s",
+		"server.tls.enabled":              false,
+		"server.tls.autocert.cachedir":    "./data/autocert",
+		"server.tls.autocert.httpaddr":    ":80",
+		"server.maintenance.retryafter":   "5m",
+		"database.driver":                 "fake",
+		"database.host":                   "localhost",
+		"database.port":                   5432,
+		"database.user":                   "dev",
+		"database.password":               "dev",
+		"database.database":               "dev",
+		"database.schema":                 "pulap_lite",
+		"database.sslmode":                "disable",
+		"database.migrate.enabled":        true,
+		"database.migrate.dryrun":         false,
+		"database.pool.maxopenconns":      25,
+		"database.pool.maxidleconns":      5,
+		"database.pool.connmaxlifetime":   "30m",
+		"database.pool.connmaxidletime":   "5m",
+		"nats.url":                        "nats://localhost:4222",
+		"nats.clusterid":                  "",
+		"nats.clientid":                   "",
+		"nats.maxreconnect":               10,
+		"nats.reconnectwait":              "1s",
+		"nats.connecttimeout":             "5s",
+		"nats.draintimeout":               "10s",
+		"nats.jetstream.enabled":          false,
+		"nats.jetstream.ackwait":          "30s",
+		"nats.jetstream.maxdeliver":       5,
+		"kafka.brokers":                   []string{"localhost:9092"},
+		"kafka.clientid":                  "",
+		"kafka.groupprefix":               "",
+		"kafka.dialtimeout":               "10s",
+		"kafka.tls.enabled":               false,
+		"kafka.sasl.mechanism":            "",
+		"redis.addr":                      "localhost:6379",
+		"redis.db":                        0,
+		"redis.keyprefix":                 "aqm:",
+		"redis.dialtimeout":               "5s",
+		"assets.storage":                  "local",
+		"assets.local.path":               "./data/uploads",
+		"auth.session_secret":             "change-this-in-production",
+		"auth.token_ttl":                  "24h",
+		"auth.encryption_key":             "12345678901234567890123456789012",
+		"auth.signing_key":                "abcdefghijklmnopqrstuvwxyz123456",
+		"auth.token_private_key":          "ygvuJ/guxUMFKeIcz29Ab763Cq5DT+g2+3mRfGlNiYp0GVI1wTXGsqYlDWqYjPw4G416Z6P2hag8E+/B9GxrSA==",
+		"auth.registration_token_ttl":     "72h",
+		"auth.password_reset_token_ttl":   "1h",
+		"auth.auto_approve_registrations": false,
+		"auth.password.algorithm":         "argon2id",
+		"auth.password.memory":            64 * 1024,
+		"auth.password.iterations":        1,
+		"auth.password.parallelism":       4,
+		"auth.password.cost":              10,
+		"auth.magic_link.ttl":             "15m",
+		"auth.magic_link.limit":           3,
+		"auth.magic_link.window":          "1h",
+		"auth.request_signing.tolerance":  "5m",
+		"mail.driver":                     "none",
+		"mail.smtp.port":                  587,
+		"mail.smtp.tls":                   "starttls",
+		"authclient.timeout":              "5s",
+		"authclient.retrymax":             2,
+		"authclient.retrydelay":           "100ms",
+		"authclient.cachettl":             "30s",
+		"authclient.breaker.failures":     5,
+		"authclient.breaker.cooldown":     "30s",
+		"authclient.keyrefresh":           "5m",
+		"aqm.devmode":                     false,
+		"aqm.profile":                     "",
+		"observability.errors.driver":     "none",
+		"observability.errors.samplerate": 1.0,
 	}
 
 	// Merge baseline defaults with user-provided defaults
@@ -191,44 +685,55 @@ func New(logger log.Logger, opts ...Option) (*Config, error) {
 	}
 
 	// Load defaults
-	if err := cfg.k.Load(confmap.Provider(options.defaults, "."), nil); err != nil {
+	if err := cfg.load(SourceDefault, confmap.Provider(options.defaults, "."), nil); err != nil {
 		return nil, fmt.Errorf("failed to load defaults: %w", err)
 	}
 
-	// Load file if specified
+	// Load file and profile files if specified
+	var files []string
 	if options.file != "" {
-		raw, err := os.ReadFile(options.file)
-		if err != nil {
-			logger.Debugf("Config file not found: %s (using defaults)", options.file)
-		} else {
-			if options.envExpansion {
-				raw = []byte(os.ExpandEnv(string(raw)))
-			}
-			if err := cfg.k.Load(rawbytes.Provider(raw), yaml.Parser()); err != nil {
-				return nil, fmt.Errorf("failed to parse config file: %w", err)
-			}
-			logger.Debugf("Loaded config from file: %s", options.file)
+		files = append(files, options.file)
+	}
+	profiles, err := profileFiles(options.profiles, os.Getenv(ProfileEnv))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range append(files, profiles...) {
+		if err := cfg.loadFile(file, options.envExpansion); err != nil {
+			return nil, err
 		}
 	}
 
+	known := newSchema(options.defaults, options.sections)
+
 	// Load environment variables if prefix specified
 	if options.prefix != "" {
-		if err := cfg.k.Load(env.Provider(options.prefix, ".", func(s string) string {
-			return strings.Replace(strings.ToLower(
-				strings.TrimPrefix(s, options.prefix)), "_", ".", -1)
+		if err := cfg.load(SourceEnv, env.Provider(options.prefix, ".", func(s string) string {
+			return known.envKey(strings.ToLower(strings.TrimPrefix(s, options.prefix)))
 		}), nil); err != nil {
 			return nil, fmt.Errorf("failed to load environment variables: %w", err)
 		}
 	}
 
 	// Always load AQM_ prefixed env vars for framework-level config
-	if err := cfg.k.Load(env.Provider("AQM_", ".", func(s string) string {
-		return "aqm." + strings.Replace(strings.ToLower(
-			strings.TrimPrefix(s, "AQM_")), "_", ".", -1)
+	if err := cfg.load(SourceEnv, env.Provider("AQM_", ".", func(s string) string {
+		return known.envKey("aqm_" + strings.ToLower(strings.TrimPrefix(s, "AQM_")))
 	}), nil); err != nil {
 		return nil, fmt.Errorf("failed to load AQM environment variables: %w", err)
 	}
 
+	// Reject unknown keys in strict mode
+	if options.strict {
+		if err := cfg.checkStrict(known); err != nil {
+			return nil, err
+		}
+	}
+
+	// Resolve secret:// references
+	if err := cfg.resolveSecrets(context.Background(), options.secrets); err != nil {
+		return nil, fmt.Errorf("failed to resolve secrets: %w", err)
+	}
+
 	// Unmarshal to struct
 	if err := cfg.k.Unmarshal("", cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
@@ -245,6 +750,23 @@ func New(logger log.Logger, opts ...Option) (*Config, error) {
 	return cfg, nil
 }
 
+// loadFile loads the YAML file at path, skipping it when missing.
+func (c *Config) loadFile(path string, envExpansion bool) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		c.logger.Debugf("Config file not found: %s (using defaults)", path)
+		return nil
+	}
+	if envExpansion {
+		raw = []byte(os.ExpandEnv(string(raw)))
+	}
+	if err := c.load(SourceFile, rawbytes.Provider(raw), yaml.Parser()); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	c.logger.Debugf("Loaded config from file: %s", path)
+	return nil
+}
+
 // GetString returns the string value for the given path.
 func (c *Config) GetString(path string) string {
 	return c.k.String(path)
@@ -369,13 +891,67 @@ func (c *Config) GetStringSliceOrDef(path string, defaultValue []string) []strin
 	return val
 }
 
-// Validate validates the configuration.
+// Validate validates the configuration. The built-in sections report their
+// first error; the sections of RegisterValidator are checked afterwards and
+// report all their errors as validation.ValidationErrors.
 func (c *Config) Validate() error {
 	// Validate Server
 	if c.Server.Port == "" {
 		return fmt.Errorf("server.port is required")
 	}
 
+	if tls := c.Server.TLS; tls.Enabled {
+		hasFiles := tls.CertFile != "" || tls.KeyFile != ""
+		switch {
+		case tls.SPIFFE.Enabled && (hasFiles || len(tls.Autocert.Domains) > 0 || tls.ClientCAFile != ""):
+			return fmt.Errorf("server.tls.spiffe replaces certfile/keyfile, autocert.domains and clientcafile")
+		case tls.SPIFFE.Enabled:
+		case hasFiles && len(tls.Autocert.Domains) > 0:
+			return fmt.Errorf("server.tls: use either certfile/keyfile or autocert.domains, not both")
+		case hasFiles && (tls.CertFile == "" || tls.KeyFile == ""):
+			return fmt.Errorf("server.tls.certfile and server.tls.keyfile must be set together")
+		case !hasFiles && len(tls.Autocert.Domains) == 0:
+			return fmt.Errorf("server.tls.enabled requires certfile/keyfile or autocert.domains")
+		}
+		switch tls.ClientAuth {
+		case "", ClientAuthOptional, ClientAuthRequired:
+		default:
+			return fmt.Errorf("server.tls.clientauth must be '%s' or '%s', got '%s'", ClientAuthOptional, ClientAuthRequired, tls.ClientAuth)
+		}
+		if tls.ClientAuth != "" && tls.ClientCAFile == "" && !tls.SPIFFE.Enabled {
+			return fmt.Errorf("server.tls.clientauth requires server.tls.clientcafile or server.tls.spiffe")
+		}
+	}
+
+	for _, origin := range c.Server.CORS.AllowedOrigins {
+		switch {
+		case origin == "*" && c.Server.CORS.AllowCredentials:
+			return fmt.Errorf("server.cors: allowcredentials cannot be used with origin '*'")
+		case origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://"):
+			return fmt.Errorf("server.cors.allowedorigins: '%s' must be '*' or start with http:// or https://", origin)
+		}
+	}
+	if c.Server.CORS.MaxAge < 0 {
+		return fmt.Errorf("server.cors.maxage cannot be negative")
+	}
+
+	for _, proxy := range c.Server.ClientIP.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(proxy); err != nil {
+			return fmt.Errorf("server.clientip.trustedproxies: '%s' is not an address or CIDR", proxy)
+		}
+	}
+	for _, header := range c.Server.ClientIP.Headers {
+		if strings.TrimSpace(header) == "" {
+			return fmt.Errorf("server.clientip.headers cannot contain empty names")
+		}
+	}
+	if c.Server.Maintenance.RetryAfter < 0 {
+		return fmt.Errorf("server.maintenance.retryafter cannot be negative")
+	}
+
 	// Validate Database
 	validDrivers := map[string]bool{"fake": true, "postgres": true, "mongo": true}
 	if !validDrivers[c.Database.Driver] {
@@ -388,12 +964,209 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if pool := c.Database.Pool; pool.MaxOpenConns < 0 || pool.MaxIdleConns < 0 || pool.ConnMaxLifetime < 0 || pool.ConnMaxIdleTime < 0 {
+		return fmt.Errorf("database.pool settings cannot be negative")
+	} else if pool.MaxOpenConns > 0 && pool.MaxIdleConns > pool.MaxOpenConns {
+		return fmt.Errorf("database.pool.maxidleconns cannot exceed database.pool.maxopenconns")
+	}
+
+	// Validate NATS
+	if n := c.NATS; n.MaxReconnect < -1 || n.ReconnectWait < 0 || n.ConnectTimeout < 0 || n.DrainTimeout < 0 {
+		return fmt.Errorf("nats.maxreconnect must be -1 or more and nats timeouts cannot be negative")
+	}
+	if js := c.NATS.JetStream; js.Enabled {
+		if js.AckWait < 0 || js.MaxDeliver < -1 {
+			return fmt.Errorf("nats.jetstream.ackwait cannot be negative and maxdeliver must be -1 or more")
+		}
+		for name, st := range js.Streams {
+			switch {
+			case len(st.Subjects) == 0:
+				return fmt.Errorf("nats.jetstream.streams.%s: subjects are required", name)
+			case st.Storage != "" && st.Storage != "file" && st.Storage != "memory":
+				return fmt.Errorf("nats.jetstream.streams.%s: storage must be 'file' or 'memory', got '%s'", name, st.Storage)
+			case st.Replicas < 0 || st.MaxAge < 0 || st.DuplicateWindow < 0:
+				return fmt.Errorf("nats.jetstream.streams.%s: replicas, maxage and duplicatewindow cannot be negative", name)
+			}
+		}
+		for name, cons := range js.Consumers {
+			if cons.Stream == "" {
+				return fmt.Errorf("nats.jetstream.consumers.%s: stream is required", name)
+			}
+			if cons.AckWait < 0 || cons.MaxDeliver < -1 {
+				return fmt.Errorf("nats.jetstream.consumers.%s: ackwait cannot be negative and maxdeliver must be -1 or more", name)
+			}
+		}
+	}
+
+	// Validate Kafka
+	if k := c.Kafka; k.DialTimeout < 0 {
+		return fmt.Errorf("kafka.dialtimeout cannot be negative")
+	} else if (k.TLS.CertFile == "") != (k.TLS.KeyFile == "") {
+		return fmt.Errorf("kafka.tls.certfile and kafka.tls.keyfile must be set together")
+	}
+	switch c.Kafka.SASL.Mechanism {
+	case "":
+	case SASLPlain, SASLScramSHA256, SASLScramSHA512:
+		if c.Kafka.SASL.Username == "" {
+			return fmt.Errorf("kafka.sasl.username is required for mechanism '%s'", c.Kafka.SASL.Mechanism)
+		}
+	default:
+		return fmt.Errorf("kafka.sasl.mechanism must be '%s', '%s' or '%s', got '%s'", SASLPlain, SASLScramSHA256, SASLScramSHA512, c.Kafka.SASL.Mechanism)
+	}
+
+	// Validate Redis
+	if r := c.Redis; r.DB < 0 || r.PoolSize < 0 || r.DialTimeout < 0 || r.ReadTimeout < 0 || r.WriteTimeout < 0 {
+		return fmt.Errorf("redis.db, redis.poolsize and redis timeouts cannot be negative")
+	}
+
+	// Validate Auth
+	validOAuthTypes := map[string]bool{"google": true, "github": true, "oidc": true}
+	for name, p := range c.Auth.OAuth {
+		if !validOAuthTypes[p.Type] {
+			return fmt.Errorf("auth.oauth.%s.type must be 'google', 'github', or 'oidc', got '%s'", name, p.Type)
+		}
+		if p.ClientID == "" || p.RedirectURL == "" {
+			return fmt.Errorf("auth.oauth.%s.client_id and auth.oauth.%s.redirect_url are required", name, name)
+		}
+		if p.Type == "oidc" && p.Issuer == "" && (p.AuthURL == "" || p.TokenURL == "" || p.UserInfoURL == "") {
+			return fmt.Errorf("auth.oauth.%s requires issuer or auth_url, token_url and userinfo_url", name)
+		}
+	}
+
+	switch pw := c.Auth.Password; pw.Algorithm {
+	case "argon2id":
+		if pw.Iterations < 1 || pw.Parallelism < 1 || pw.Parallelism > 255 || pw.Memory < 8*pw.Parallelism {
+			return fmt.Errorf("auth.password: argon2id needs iterations >= 1, parallelism between 1 and 255 and memory >= 8 KiB per thread")
+		}
+	case "bcrypt":
+		if pw.Cost < 4 || pw.Cost > 31 {
+			return fmt.Errorf("auth.password.cost must be between 4 and 31, got %d", pw.Cost)
+		}
+	default:
+		return fmt.Errorf("auth.password.algorithm must be 'argon2id' or 'bcrypt', got '%s'", pw.Algorithm)
+	}
+
+	if ml := c.Auth.MagicLink; ml.URL != "" {
+		if !strings.HasPrefix(ml.URL, "http://") && !strings.HasPrefix(ml.URL, "https://") {
+			return fmt.Errorf("auth.magic_link.url must start with http:// or https://")
+		}
+		if ml.TTL <= 0 || ml.Limit < 1 || ml.Window <= 0 {
+			return fmt.Errorf("auth.magic_link needs a positive ttl, limit and window")
+		}
+	}
+
+	if rs := c.Auth.RequestSigning; len(rs.Keys) > 0 || rs.KeyID != "" {
+		for id, secret := range rs.Keys {
+			if len(secret) < 32 {
+				return fmt.Errorf("auth.request_signing.keys.%s must be at least 32 characters", id)
+			}
+		}
+		if _, ok := rs.Keys[rs.KeyID]; rs.KeyID != "" && !ok {
+			return fmt.Errorf("auth.request_signing.key_id %q is not in auth.request_signing.keys", rs.KeyID)
+		}
+		if rs.Tolerance <= 0 {
+			return fmt.Errorf("auth.request_signing.tolerance must be positive")
+		}
+	}
+
+	if token := c.Auth.Bootstrap.Token; token != "" && len(token) < 32 {
+		return fmt.Errorf("auth.bootstrap.token must be at least 32 characters")
+	}
+	for _, ip := range c.Auth.Bootstrap.AllowedIPs {
+		if _, err := netip.ParsePrefix(ip); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(ip); err != nil {
+			return fmt.Errorf("auth.bootstrap.allowed_ips: '%s' is not an address or CIDR", ip)
+		}
+	}
+
+	for name, version := range c.Auth.Policies {
+		if name == "" || strings.Trim(name, "abcdefghijklmnopqrstuvwxyz0123456789-_") != "" {
+			return fmt.Errorf("auth.policies: policy %q may only contain lowercase letters, digits, '-' and '_'", name)
+		}
+		if version == "" || strings.ContainsAny(version, " \t\r\n") {
+			return fmt.Errorf("auth.policies.%s needs a version without spaces", name)
+		}
+	}
+
+	for list, patterns := range map[string][]string{"allow": c.Auth.EmailDomains.Allow, "deny": c.Auth.EmailDomains.Deny} {
+		for _, pattern := range patterns {
+			domain := strings.TrimPrefix(pattern, "*.")
+			if domain == "" || strings.ContainsAny(domain, "*@ \t") || !strings.Contains(domain, ".") {
+				return fmt.Errorf("auth.email_domains.%s: '%s' is not a domain or '*.' and a domain", list, pattern)
+			}
+		}
+	}
+
+	// Validate Assets
+	switch c.Assets.Storage {
+	case "none":
+	case "local":
+		if c.Assets.Local.Path == "" {
+			return fmt.Errorf("assets.local.path is required for local storage")
+		}
+	case "s3":
+		if c.Assets.S3.Bucket == "" || c.Assets.S3.Region == "" {
+			return fmt.Errorf("assets.s3.bucket and assets.s3.region are required for s3 storage")
+		}
+		if c.Assets.S3.Endpoint != "" && !strings.HasPrefix(c.Assets.S3.Endpoint, "http://") && !strings.HasPrefix(c.Assets.S3.Endpoint, "https://") {
+			return fmt.Errorf("assets.s3.endpoint must start with http:// or https://")
+		}
+	default:
+		return fmt.Errorf("assets.storage must be 'none', 'local', or 's3', got '%s'", c.Assets.Storage)
+	}
+
+	// Validate Mail
+	validMailDrivers := map[string]bool{"none": true, "log": true, "smtp": true}
+	if !validMailDrivers[c.Mail.Driver] {
+		return fmt.Errorf("mail.driver must be 'none', 'log', or 'smtp', got '%s'", c.Mail.Driver)
+	}
+	if c.Mail.Driver != "none" && c.Mail.From == "" {
+		return fmt.Errorf("mail.from is required for %s driver", c.Mail.Driver)
+	}
+	if c.Mail.Driver == "smtp" {
+		if c.Mail.SMTP.Host == "" {
+			return fmt.Errorf("mail.smtp.host is required for smtp driver")
+		}
+		validTLS := map[string]bool{"starttls": true, "tls": true, "none": true}
+		if !validTLS[c.Mail.SMTP.TLS] {
+			return fmt.Errorf("mail.smtp.tls must be 'starttls', 'tls', or 'none', got '%s'", c.Mail.SMTP.TLS)
+		}
+	}
+
+	// Validate Observability
+	if d := c.Observability.Errors.Driver; d != "none" && d != "sentry" {
+		return fmt.Errorf("observability.errors.driver must be 'none' or 'sentry', got '%s'", d)
+	}
+	if c.Observability.Errors.Driver == "sentry" && c.Observability.Errors.DSN == "" {
+		return fmt.Errorf("observability.errors.dsn is required for sentry driver")
+	}
+	if r := c.Observability.Errors.SampleRate; r <= 0 || r > 1 {
+		return fmt.Errorf("observability.errors.samplerate must be above 0 and at most 1, got %v", r)
+	}
+
+	// Validate AuthClient
+	if ac := c.AuthClient; ac.Timeout < 0 || ac.RetryMax < 0 || ac.RetryDelay < 0 || ac.CacheTTL < 0 || ac.Breaker.Failures < 0 || ac.Breaker.Cooldown < 0 || ac.KeyRefresh < 0 {
+		return fmt.Errorf("authclient settings cannot be negative")
+	}
+
 	// Validate Log
 	validLevels := map[string]bool{"debug": true, "info": true, "error": true}
 	if !validLevels[c.Log.Level] {
 		return fmt.Errorf("log.level must be 'debug', 'info', or 'error', got '%s'", c.Log.Level)
 	}
 
+	validFormats := map[string]bool{"text": true, "json": true}
+	if !validFormats[c.Log.Format] {
+		return fmt.Errorf("log.format must be 'text' or 'json', got '%s'", c.Log.Format)
+	}
+
+	// Validate sections of registered validators
+	if err := c.validateSections(); err != nil {
+		return err
+	}
+
 	c.logger.Debugf("Configuration validated successfully")
 
 	return nil
@@ -435,7 +1208,20 @@ func LoadConfig(path, envPrefix string, args []string) (*Config, error) {
 		k := cfg.k
 		fs := pflag.NewFlagSet(args[0], pflag.ExitOnError)
 		fs.String("log.level", cfg.Log.Level, "Log level (debug, info, error)")
+		fs.String("log.format", cfg.Log.Format, "Log format (text, json)")
 		fs.String("server.port", cfg.Server.Port, "HTTP server port")
+		fs.Bool("server.tls.enabled", cfg.Server.TLS.Enabled, "Serve HTTPS")
+		fs.String("server.tls.certfile", cfg.Server.TLS.CertFile, "TLS certificate file")
+		fs.String("server.tls.keyfile", cfg.Server.TLS.KeyFile, "TLS private key file")
+		fs.String("server.tls.clientcafile", cfg.Server.TLS.ClientCAFile, "CA bundle client certificates are verified against")
+		fs.String("server.tls.clientauth", cfg.Server.TLS.ClientAuth, "Client certificates: optional or required")
+		fs.Bool("server.tls.spiffe.enabled", cfg.Server.TLS.SPIFFE.Enabled, "Serve the SVID of a SPIFFE Workload API")
+		fs.String("server.tls.spiffe.socket", cfg.Server.TLS.SPIFFE.Socket, "SPIFFE Workload API address")
+		fs.StringSlice("server.cors.allowedorigins", cfg.Server.CORS.AllowedOrigins, "Origins allowed to make cross-origin requests")
+		fs.StringSlice("server.clientip.trustedproxies", cfg.Server.ClientIP.TrustedProxies, "Proxies whose forwarding headers are trusted")
+		fs.String("server.clientip.geoipdb", cfg.Server.ClientIP.GeoIPDB, "MaxMind DB file for client geolocation")
+		fs.String("server.maxbodybytes", cfg.Server.MaxBodyBytes.String(), "Request body limit, e.g. 10MB (0 disables it)")
+		fs.Bool("server.maintenance.enabled", cfg.Server.Maintenance.Enabled, "Start in maintenance mode")
 		fs.String("database.driver", cfg.Database.Driver, "Database driver (fake, postgres, mongo)")
 		fs.String("database.host", cfg.Database.Host, "Database host")
 		fs.Int("database.port", cfg.Database.Port, "Database port")
@@ -444,19 +1230,44 @@ func LoadConfig(path, envPrefix string, args []string) (*Config, error) {
 		fs.String("database.database", cfg.Database.Database, "Database name")
 		fs.String("database.schema", cfg.Database.Schema, "Database schema")
 		fs.String("database.sslmode", cfg.Database.SSLMode, "Database SSL mode")
-		fs.String("assets.storage", cfg.Assets.Storage, "Asset storage backend (local)")
+		fs.Bool("database.migrate.enabled", cfg.Database.Migrate.Enabled, "Run pending migrations on start")
+		fs.Bool("database.migrate.dryrun", cfg.Database.Migrate.DryRun, "Log pending migrations without applying them")
+		fs.Int("database.pool.maxopenconns", cfg.Database.Pool.MaxOpenConns, "Maximum open database connections")
+		fs.Int("database.pool.maxidleconns", cfg.Database.Pool.MaxIdleConns, "Maximum idle database connections")
+		fs.String("redis.addr", cfg.Redis.Addr, "Redis address (host:port)")
+		fs.Int("redis.db", cfg.Redis.DB, "Redis database number")
+		fs.String("assets.storage", cfg.Assets.Storage, "Asset storage backend (none, local, s3)")
 		fs.String("assets.local.path", cfg.Assets.Local.Path, "Local storage path")
+		fs.String("assets.s3.bucket", cfg.Assets.S3.Bucket, "S3 bucket")
+		fs.String("assets.s3.region", cfg.Assets.S3.Region, "S3 region")
+		fs.String("assets.s3.endpoint", cfg.Assets.S3.Endpoint, "S3-compatible endpoint URL")
 		fs.String("auth.session_secret", cfg.Auth.SessionSecret, "Session secret")
-		fs.String("auth.token_ttl", cfg.Auth.TokenTTL, "Auth token TTL")
+		fs.Duration("auth.token_ttl", cfg.Auth.TokenTTL, "Auth token TTL")
 		fs.String("auth.encryption_key", cfg.Auth.EncryptionKey, "Email encryption key (32 bytes)")
 		fs.String("auth.signing_key", cfg.Auth.SigningKey, "Email signing key for lookup hash (32 bytes)")
 		fs.String("auth.token_private_key", cfg.Auth.TokenPrivateKey, "PASETO token private key (Ed25519 base64)")
-		fs.String("auth.registration_token_ttl", cfg.Auth.RegistrationTokenTTL, "Registration token TTL")
-		fs.String("auth.password_reset_token_ttl", cfg.Auth.PasswordResetTokenTTL, "Password reset token TTL")
+		fs.Duration("auth.registration_token_ttl", cfg.Auth.RegistrationTokenTTL, "Registration token TTL")
+		fs.Duration("auth.password_reset_token_ttl", cfg.Auth.PasswordResetTokenTTL, "Password reset token TTL")
+		fs.String("auth.bootstrap.token", cfg.Auth.Bootstrap.Token, "One-time token required to bootstrap the superadmin")
+		fs.StringSlice("auth.bootstrap.allowed_ips", cfg.Auth.Bootstrap.AllowedIPs, "Addresses allowed to bootstrap the superadmin")
+		fs.StringSlice("auth.email_domains.allow", cfg.Auth.EmailDomains.Allow, "Email domains allowed to create accounts")
+		fs.StringSlice("auth.email_domains.deny", cfg.Auth.EmailDomains.Deny, "Email domains denied to create accounts")
 		fs.Bool("auth.auto_approve_registrations", cfg.Auth.AutoApproveRegistrations, "Auto-approve new registrations")
+		fs.String("auth.password.algorithm", cfg.Auth.Password.Algorithm, "Password hashing algorithm (argon2id, bcrypt)")
+		fs.Int("auth.password.memory", cfg.Auth.Password.Memory, "Argon2id memory in KiB")
+		fs.Int("auth.password.iterations", cfg.Auth.Password.Iterations, "Argon2id iterations")
+		fs.Int("auth.password.parallelism", cfg.Auth.Password.Parallelism, "Argon2id parallelism")
+		fs.Int("auth.password.cost", cfg.Auth.Password.Cost, "bcrypt cost")
+		fs.Bool("auth.derived_keys", cfg.Auth.DerivedKeys, "Encrypt user data with per-user derived keys")
+		fs.String("mail.driver", cfg.Mail.Driver, "Mail driver (none, log, smtp)")
+		fs.String("mail.from", cfg.Mail.From, "Sender address of outgoing mail")
+		fs.String("mail.smtp.host", cfg.Mail.SMTP.Host, "SMTP server host")
+		fs.Int("mail.smtp.port", cfg.Mail.SMTP.Port, "SMTP server port")
+		fs.String("observability.errors.driver", cfg.Observability.Errors.Driver, "Error reporting driver (none, sentry)")
+		fs.String("observability.errors.dsn", cfg.Observability.Errors.DSN, "Sentry DSN errors are reported to")
 		fs.Parse(args[1:])
 
-		if err := k.Load(posflag.Provider(fs, ".", k), nil); err != nil {
+		if err := cfg.load(SourceFlag, posflag.Provider(fs, ".", k), nil); err != nil {
 			return nil, fmt.Errorf("cannot load flags: %w", err)
 		}
 
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -30,12 +31,36 @@ func TestNewWithDefaults(t *testing.T) {
 		{"database password", cfg.Database.Password, "dev"},
 		{"database name", cfg.Database.Database, "dev"},
 		{"database sslmode", cfg.Database.SSLMode, "disable"},
+		{"database migrate enabled", cfg.Database.Migrate.Enabled, true},
+		{"database migrate dryrun", cfg.Database.Migrate.DryRun, false},
+		{"database pool max open", cfg.Database.Pool.MaxOpenConns, 25},
+		{"database pool max idle", cfg.Database.Pool.MaxIdleConns, 5},
+		{"database pool conn lifetime", cfg.Database.Pool.ConnMaxLifetime, 30 * time.Minute},
+		{"database pool conn idle time", cfg.Database.Pool.ConnMaxIdleTime, 5 * time.Minute},
 		{"nats url", cfg.NATS.URL, "nats://localhost:4222"},
 		{"nats maxreconnect", cfg.NATS.MaxReconnect, 10},
+		{"nats reconnectwait", cfg.NATS.ReconnectWait, time.Second},
+		{"nats draintimeout", cfg.NATS.DrainTimeout, 10 * time.Second},
+		{"nats jetstream ackwait", cfg.NATS.JetStream.AckWait, 30 * time.Second},
+		{"nats jetstream maxdeliver", cfg.NATS.JetStream.MaxDeliver, 5},
+		{"kafka dialtimeout", cfg.Kafka.DialTimeout, 10 * time.Second},
+		{"redis addr", cfg.Redis.Addr, "localhost:6379"},
+		{"redis keyprefix", cfg.Redis.KeyPrefix, "aqm:"},
+		{"redis dialtimeout", cfg.Redis.DialTimeout, 5 * time.Second},
+		{"authclient timeout", cfg.AuthClient.Timeout, 5 * time.Second},
+		{"authclient retrymax", cfg.AuthClient.RetryMax, 2},
+		{"authclient cachettl", cfg.AuthClient.CacheTTL, 30 * time.Second},
+		{"authclient breaker failures", cfg.AuthClient.Breaker.Failures, 5},
+		{"authclient keyrefresh", cfg.AuthClient.KeyRefresh, 5 * time.Minute},
+		{"auth magic link ttl", cfg.Auth.MagicLink.TTL, 15 * time.Minute},
+		{"auth magic link limit", cfg.Auth.MagicLink.Limit, 3},
+		{"auth magic link window", cfg.Auth.MagicLink.Window, time.Hour},
+		{"auth request signing tolerance", cfg.Auth.RequestSigning.Tolerance, 5 * time.Minute},
 		{"assets storage", cfg.Assets.Storage, "local"},
 		{"assets local path", cfg.Assets.Local.Path, "./data/uploads"},
 		{"auth session secret", cfg.Auth.SessionSecret, "change-this-in-production"},
-		{"auth token ttl", cfg.Auth.TokenTTL, "24h"},
+		{"auth token ttl", cfg.Auth.TokenTTL, 24 * time.Hour},
+		{"auth registration token ttl", cfg.Auth.RegistrationTokenTTL, 72 * time.Hour},
 	}
 
 	for _, tt := range tests {
@@ -51,9 +76,9 @@ func TestNewWithCustomDefaults(t *testing.T) {
 	logger := log.NewLogger("info")
 
 	customDefaults := map[string]interface{}{
-		"server.port":      ":3000",
-		"database.driver":  "postgres",
-		"custom.field":     "custom-value",
+		"server.port":     ":3000",
+		"database.driver": "postgres",
+		"custom.field":    "custom-value",
 	}
 
 	cfg, err := New(logger, WithDefaults(customDefaults))
@@ -94,6 +119,7 @@ log:
   level: debug
 server:
   port: ":9090"
+  maxbodybytes: 10MB
 database:
   driver: postgres
   host: db.example.com
@@ -114,6 +140,13 @@ assets:
 auth:
   session_secret: test-secret
   token_ttl: 48h
+  oauth:
+    github:
+      type: github
+      client_id: gh-client
+      client_secret: gh-secret
+      redirect_url: https://app.example.com/auth/oauth/github/callback
+      scopes: [read:user, user:email]
 `
 	tempDir := t.TempDir()
 	configPath := filepath.Join(tempDir, "config.yaml")
@@ -147,7 +180,12 @@ auth:
 		{"assets storage", cfg.Assets.Storage, "local"},
 		{"assets local path", cfg.Assets.Local.Path, "/tmp/uploads"},
 		{"auth session secret", cfg.Auth.SessionSecret, "test-secret"},
-		{"auth token ttl", cfg.Auth.TokenTTL, "48h"},
+		{"auth token ttl", cfg.Auth.TokenTTL, 48 * time.Hour},
+		{"server maxbodybytes", cfg.Server.MaxBodyBytes, 10 * Megabyte},
+		{"auth oauth type", cfg.Auth.OAuth["github"].Type, "github"},
+		{"auth oauth client id", cfg.Auth.OAuth["github"].ClientID, "gh-client"},
+		{"auth oauth redirect url", cfg.Auth.OAuth["github"].RedirectURL, "https://app.example.com/auth/oauth/github/callback"},
+		{"auth oauth scopes", len(cfg.Auth.OAuth["github"].Scopes), 2},
 	}
 
 	for _, tt := range tests {
@@ -192,6 +230,65 @@ database:
 	}
 }
 
+func TestNewWithPrefixAuthKeys(t *testing.T) {
+	env := map[string]string{
+		"AUTHN_AUTH_SESSION_SECRET":               "session-secret",
+		"AUTHN_AUTH_DERIVED_KEYS":                 "true",
+		"AUTHN_AUTH_MAGIC_LINK_URL":               "https://app.example.com/magic",
+		"AUTHN_AUTH_REQUEST_SIGNING_KEY_ID":       "k1",
+		"AUTHN_AUTH_REQUEST_SIGNING_KEYS_K1":      "0123456789abcdef0123456789abcdef",
+		"AUTHN_AUTH_BOOTSTRAP_TOKEN":              "bootstrap-token-0123456789abcdef0123",
+		"AUTHN_AUTH_BOOTSTRAP_ALLOWED_IPS":        "10.0.0.0/8",
+		"AUTHN_AUTH_EMAIL_DOMAINS_ALLOW":          "example.com",
+		"AUTHN_AUTH_OAUTH_GOOGLE_TYPE":            "google",
+		"AUTHN_AUTH_OAUTH_GOOGLE_CLIENT_ID":       "client-id",
+		"AUTHN_AUTH_OAUTH_GOOGLE_CLIENT_SECRET":   "client-secret",
+		"AUTHN_AUTH_OAUTH_GOOGLE_REDIRECT_URL":    "https://app.example.com/callback",
+		"AUTHN_AUTH_OAUTH_CORP_SSO_CLIENT_ID":     "sso-client",
+		"AUTHN_AUTH_OAUTH_CORP_SSO_REDIRECT_URL":  "https://app.example.com/sso",
+		"AUTHN_AUTH_OAUTH_CORP_SSO_TYPE":          "github",
+		"AUTHN_AUTH_OAUTH_CORP_SSO_CLIENT_SECRET": "sso-secret",
+		"AUTHN_AUTH_PASSWORD_RESET_TOKEN_TTL":     "15m",
+		"AUTHN_AUTH_AUTO_APPROVE_REGISTRATIONS":   "true",
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+
+	cfg, err := New(log.NewNoopLogger(), WithPrefix("AUTHN_"), WithStrict())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	a := cfg.Auth
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"session secret", a.SessionSecret, "session-secret"},
+		{"derived keys", a.DerivedKeys, true},
+		{"magic link url", a.MagicLink.URL, "https://app.example.com/magic"},
+		{"request signing key id", a.RequestSigning.KeyID, "k1"},
+		{"request signing key", a.RequestSigning.Keys["k1"], "0123456789abcdef0123456789abcdef"},
+		{"bootstrap token", a.Bootstrap.Token, "bootstrap-token-0123456789abcdef0123"},
+		{"bootstrap allowed ips", strings.Join(a.Bootstrap.AllowedIPs, " "), "10.0.0.0/8"},
+		{"email domains", strings.Join(a.EmailDomains.Allow, " "), "example.com"},
+		{"oauth client id", a.OAuth["google"].ClientID, "client-id"},
+		{"oauth client secret", a.OAuth["google"].ClientSecret, "client-secret"},
+		{"oauth provider with underscore", a.OAuth["corp_sso"].ClientSecret, "sso-secret"},
+		{"password reset token ttl", a.PasswordResetTokenTTL, 15 * time.Minute},
+		{"auto approve registrations", a.AutoApproveRegistrations, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
 func TestNewWithPrefix(t *testing.T) {
 	logger := log.NewLogger("info")
 
@@ -303,11 +400,58 @@ func TestNewMissingFile(t *testing.T) {
 	}
 }
 
+func TestNewWithProfiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.yaml":      "server:\n  port: \":9090\"\nlog:\n  level: debug\n",
+		"config.prod.yaml": "log:\n  level: error\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("cannot write %s: %v", name, err)
+		}
+	}
+	opt := WithProfiles(filepath.Join(dir, "config.yaml"), filepath.Join(dir, "config.{profile}.yaml"))
+
+	tests := []struct {
+		profile string
+		level   string
+		wantErr bool
+	}{
+		{"", "debug", false},
+		{"prod", "error", false},
+		{"staging", "debug", false},
+		{"../prod", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			t.Setenv(ProfileEnv, tt.profile)
+			cfg, err := New(log.NewNoopLogger(), opt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if cfg.Server.Port != ":9090" || cfg.Log.Level != tt.level {
+				t.Errorf("port = %s, level = %s, want :9090 and %s", cfg.Server.Port, cfg.Log.Level, tt.level)
+			}
+			if cfg.AQM.Profile != tt.profile {
+				t.Errorf("AQM.Profile = %q, want %q", cfg.AQM.Profile, tt.profile)
+			}
+		})
+	}
+
+	if _, err := New(log.NewNoopLogger(), WithProfiles()); err == nil {
+		t.Error("New(WithProfiles()) error = nil, want error")
+	}
+}
+
 func TestGetString(t *testing.T) {
 	logger := log.NewLogger("info")
 
 	defaults := map[string]interface{}{
-		"custom.string": "test-value",
+		"custom.string":     "test-value",
 		"nested.deep.value": "deep-value",
 	}
 
@@ -341,7 +485,7 @@ func TestGetInt(t *testing.T) {
 	logger := log.NewLogger("info")
 
 	defaults := map[string]interface{}{
-		"custom.int": 42,
+		"custom.int":  42,
 		"custom.zero": 0,
 	}
 
@@ -375,7 +519,7 @@ func TestGetBool(t *testing.T) {
 	logger := log.NewLogger("info")
 
 	defaults := map[string]interface{}{
-		"custom.bool.true": true,
+		"custom.bool.true":  true,
 		"custom.bool.false": false,
 	}
 
@@ -410,7 +554,7 @@ func TestGetFloat(t *testing.T) {
 
 	defaults := map[string]interface{}{
 		"custom.float": 3.14,
-		"custom.zero": 0.0,
+		"custom.zero":  0.0,
 	}
 
 	cfg, err := New(logger, WithDefaults(defaults))
@@ -443,7 +587,7 @@ func TestGetDuration(t *testing.T) {
 
 	defaults := map[string]interface{}{
 		"custom.duration": "5m",
-		"custom.hours": "2h30m",
+		"custom.hours":    "2h30m",
 	}
 
 	cfg, err := New(logger, WithDefaults(defaults))
@@ -566,6 +710,404 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "negative pool setting",
+			modify: func(c *Config) {
+				c.Database.Pool.ConnMaxLifetime = -time.Second
+			},
+			wantErr: true,
+			errMsg:  "database.pool settings cannot be negative",
+		},
+		{
+			name: "pool idle exceeds open",
+			modify: func(c *Config) {
+				c.Database.Pool.MaxOpenConns = 2
+				c.Database.Pool.MaxIdleConns = 3
+			},
+			wantErr: true,
+			errMsg:  "maxidleconns cannot exceed",
+		},
+		{
+			name: "unlimited open conns",
+			modify: func(c *Config) {
+				c.Database.Pool.MaxOpenConns = 0
+				c.Database.Pool.MaxIdleConns = 10
+			},
+			wantErr: false,
+		},
+		{
+			name: "unlimited nats reconnects",
+			modify: func(c *Config) {
+				c.NATS.MaxReconnect = -1
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid nats maxreconnect",
+			modify: func(c *Config) {
+				c.NATS.MaxReconnect = -2
+			},
+			wantErr: true,
+			errMsg:  "nats.maxreconnect",
+		},
+		{
+			name: "jetstream stream without subjects",
+			modify: func(c *Config) {
+				c.NATS.JetStream.Enabled = true
+				c.NATS.JetStream.Streams = map[string]JetStreamStreamConfig{"AUTH": {}}
+			},
+			wantErr: true,
+			errMsg:  "nats.jetstream.streams.AUTH: subjects",
+		},
+		{
+			name: "invalid jetstream storage",
+			modify: func(c *Config) {
+				c.NATS.JetStream.Enabled = true
+				c.NATS.JetStream.Streams = map[string]JetStreamStreamConfig{"AUTH": {Subjects: []string{"auth.>"}, Storage: "disk"}}
+			},
+			wantErr: true,
+			errMsg:  "storage must be",
+		},
+		{
+			name: "jetstream consumer without stream",
+			modify: func(c *Config) {
+				c.NATS.JetStream.Enabled = true
+				c.NATS.JetStream.Consumers = map[string]JetStreamConsumerConfig{"audit": {}}
+			},
+			wantErr: true,
+			errMsg:  "nats.jetstream.consumers.audit: stream",
+		},
+		{
+			name: "disabled jetstream is not validated",
+			modify: func(c *Config) {
+				c.NATS.JetStream.Consumers = map[string]JetStreamConsumerConfig{"audit": {}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "kafka scram",
+			modify: func(c *Config) {
+				c.Kafka.SASL = KafkaSASLConfig{Mechanism: SASLScramSHA512, Username: "svc", Password: "secret"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid kafka sasl mechanism",
+			modify: func(c *Config) {
+				c.Kafka.SASL = KafkaSASLConfig{Mechanism: "gssapi", Username: "svc"}
+			},
+			wantErr: true,
+			errMsg:  "kafka.sasl.mechanism",
+		},
+		{
+			name: "kafka sasl without username",
+			modify: func(c *Config) {
+				c.Kafka.SASL.Mechanism = SASLPlain
+			},
+			wantErr: true,
+			errMsg:  "kafka.sasl.username",
+		},
+		{
+			name: "kafka client cert without key",
+			modify: func(c *Config) {
+				c.Kafka.TLS = KafkaTLSConfig{Enabled: true, CertFile: "client.pem"}
+			},
+			wantErr: true,
+			errMsg:  "kafka.tls.certfile",
+		},
+		{
+			name: "negative redis pool size",
+			modify: func(c *Config) {
+				c.Redis.PoolSize = -1
+			},
+			wantErr: true,
+			errMsg:  "redis.db, redis.poolsize",
+		},
+		{
+			name: "negative authclient retries",
+			modify: func(c *Config) {
+				c.AuthClient.RetryMax = -1
+			},
+			wantErr: true,
+			errMsg:  "authclient settings cannot be negative",
+		},
+		{
+			name: "valid oauth providers",
+			modify: func(c *Config) {
+				c.Auth.OAuth = map[string]OAuthProviderConfig{
+					"google": {Type: "google", ClientID: "id", RedirectURL: "https://app/cb"},
+					"corp":   {Type: "oidc", ClientID: "id", RedirectURL: "https://app/cb", Issuer: "https://sso.corp"},
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid oauth type",
+			modify: func(c *Config) {
+				c.Auth.OAuth = map[string]OAuthProviderConfig{"x": {Type: "saml", ClientID: "id", RedirectURL: "https://app/cb"}}
+			},
+			wantErr: true,
+			errMsg:  "auth.oauth.x.type must be",
+		},
+		{
+			name: "oauth missing client id",
+			modify: func(c *Config) {
+				c.Auth.OAuth = map[string]OAuthProviderConfig{"github": {Type: "github", RedirectURL: "https://app/cb"}}
+			},
+			wantErr: true,
+			errMsg:  "auth.oauth.github.client_id",
+		},
+		{
+			name: "oidc without issuer or endpoints",
+			modify: func(c *Config) {
+				c.Auth.OAuth = map[string]OAuthProviderConfig{"corp": {Type: "oidc", ClientID: "id", RedirectURL: "https://app/cb", AuthURL: "https://sso/auth"}}
+			},
+			wantErr: true,
+			errMsg:  "requires issuer",
+		},
+		{
+			name: "smtp mail",
+			modify: func(c *Config) {
+				c.Mail = MailConfig{Driver: "smtp", From: "noreply@example.com", SMTP: SMTPConfig{Host: "smtp.example.com", Port: 587, TLS: "starttls"}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "s3 assets",
+			modify: func(c *Config) {
+				c.Assets = AssetsConfig{Storage: "s3", S3: S3Config{Bucket: "uploads", Region: "eu-west-1", Endpoint: "http://localhost:9000"}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "s3 assets missing bucket",
+			modify: func(c *Config) {
+				c.Assets = AssetsConfig{Storage: "s3", S3: S3Config{Region: "eu-west-1"}}
+			},
+			wantErr: true,
+			errMsg:  "assets.s3.bucket and assets.s3.region are required",
+		},
+		{
+			name: "local assets missing path",
+			modify: func(c *Config) {
+				c.Assets.Local.Path = ""
+			},
+			wantErr: true,
+			errMsg:  "assets.local.path is required",
+		},
+		{
+			name: "unknown assets storage",
+			modify: func(c *Config) {
+				c.Assets.Storage = "gcs"
+			},
+			wantErr: true,
+			errMsg:  "assets.storage must be",
+		},
+		{
+			name: "magic link",
+			modify: func(c *Config) {
+				c.Auth.MagicLink.URL = "https://app.example.com/magic"
+			},
+			wantErr: false,
+		},
+		{
+			name: "magic link relative url",
+			modify: func(c *Config) {
+				c.Auth.MagicLink.URL = "/magic"
+			},
+			wantErr: true,
+			errMsg:  "auth.magic_link.url must start with",
+		},
+		{
+			name: "magic link without limit",
+			modify: func(c *Config) {
+				c.Auth.MagicLink = MagicLinkConfig{URL: "https://app.example.com/magic", TTL: time.Minute, Window: time.Hour}
+			},
+			wantErr: true,
+			errMsg:  "auth.magic_link needs a positive ttl, limit and window",
+		},
+		{
+			name: "request signing",
+			modify: func(c *Config) {
+				c.Auth.RequestSigning.Keys = map[string]string{"authz": "0123456789abcdef0123456789abcdef"}
+				c.Auth.RequestSigning.KeyID = "authz"
+			},
+			wantErr: false,
+		},
+		{
+			name: "request signing short secret",
+			modify: func(c *Config) {
+				c.Auth.RequestSigning.Keys = map[string]string{"authz": "short"}
+			},
+			wantErr: true,
+			errMsg:  "auth.request_signing.keys.authz must be at least 32 characters",
+		},
+		{
+			name: "request signing unknown key id",
+			modify: func(c *Config) {
+				c.Auth.RequestSigning.Keys = map[string]string{"authz": "0123456789abcdef0123456789abcdef"}
+				c.Auth.RequestSigning.KeyID = "gateway"
+			},
+			wantErr: true,
+			errMsg:  "is not in auth.request_signing.keys",
+		},
+		{
+			name: "bootstrap guard",
+			modify: func(c *Config) {
+				c.Auth.Bootstrap.Token = "0123456789abcdef0123456789abcdef"
+				c.Auth.Bootstrap.AllowedIPs = []string{"10.0.0.0/8", "192.0.2.10"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "bootstrap short token",
+			modify: func(c *Config) {
+				c.Auth.Bootstrap.Token = "short"
+			},
+			wantErr: true,
+			errMsg:  "auth.bootstrap.token must be at least 32 characters",
+		},
+		{
+			name: "bootstrap invalid allowed ip",
+			modify: func(c *Config) {
+				c.Auth.Bootstrap.AllowedIPs = []string{"10.0.0.0/33"}
+			},
+			wantErr: true,
+			errMsg:  "auth.bootstrap.allowed_ips: '10.0.0.0/33' is not an address or CIDR",
+		},
+		{
+			name: "policies",
+			modify: func(c *Config) {
+				c.Auth.Policies = map[string]string{"terms": "2024-06", "privacy_policy": "v2"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "policy with uppercase name",
+			modify: func(c *Config) {
+				c.Auth.Policies = map[string]string{"Terms": "v1"}
+			},
+			wantErr: true,
+			errMsg:  "may only contain lowercase letters",
+		},
+		{
+			name: "policy without version",
+			modify: func(c *Config) {
+				c.Auth.Policies = map[string]string{"terms": ""}
+			},
+			wantErr: true,
+			errMsg:  "auth.policies.terms needs a version",
+		},
+		{
+			name: "email domains",
+			modify: func(c *Config) {
+				c.Auth.EmailDomains = EmailDomainsConfig{Allow: []string{"example.com", "*.example.com"}, Deny: []string{"mailinator.com"}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "email domain with inner wildcard",
+			modify: func(c *Config) {
+				c.Auth.EmailDomains.Deny = []string{"a.*.com"}
+			},
+			wantErr: true,
+			errMsg:  "auth.email_domains.deny: 'a.*.com' is not a domain",
+		},
+		{
+			name: "unknown mail driver",
+			modify: func(c *Config) {
+				c.Mail.Driver = "sendgrid"
+			},
+			wantErr: true,
+			errMsg:  "mail.driver must be",
+		},
+		{
+			name: "mail without from",
+			modify: func(c *Config) {
+				c.Mail.Driver = "log"
+			},
+			wantErr: true,
+			errMsg:  "mail.from is required",
+		},
+		{
+			name: "smtp without host",
+			modify: func(c *Config) {
+				c.Mail = MailConfig{Driver: "smtp", From: "noreply@example.com", SMTP: SMTPConfig{TLS: "starttls"}}
+			},
+			wantErr: true,
+			errMsg:  "mail.smtp.host is required",
+		},
+		{
+			name: "invalid smtp tls",
+			modify: func(c *Config) {
+				c.Mail = MailConfig{Driver: "smtp", From: "noreply@example.com", SMTP: SMTPConfig{Host: "smtp.example.com", TLS: "ssl"}}
+			},
+			wantErr: true,
+			errMsg:  "mail.smtp.tls must be",
+		},
+		{
+			name: "sentry error reporting",
+			modify: func(c *Config) {
+				c.Observability.Errors = ErrorReportingConfig{Driver: "sentry", DSN: "https://key@sentry.example.com/1", SampleRate: 0.5}
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown error reporting driver",
+			modify: func(c *Config) {
+				c.Observability.Errors.Driver = "rollbar"
+			},
+			wantErr: true,
+			errMsg:  "observability.errors.driver must be",
+		},
+		{
+			name: "sentry without dsn",
+			modify: func(c *Config) {
+				c.Observability.Errors.Driver = "sentry"
+			},
+			wantErr: true,
+			errMsg:  "observability.errors.dsn is required",
+		},
+		{
+			name: "error reporting sample rate above 1",
+			modify: func(c *Config) {
+				c.Observability.Errors.SampleRate = 1.5
+			},
+			wantErr: true,
+			errMsg:  "observability.errors.samplerate",
+		},
+		{
+			name: "bcrypt password hashing",
+			modify: func(c *Config) {
+				c.Auth.Password.Algorithm = "bcrypt"
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown password algorithm",
+			modify: func(c *Config) {
+				c.Auth.Password.Algorithm = "md5"
+			},
+			wantErr: true,
+			errMsg:  "auth.password.algorithm must be",
+		},
+		{
+			name: "argon2id memory too low",
+			modify: func(c *Config) {
+				c.Auth.Password.Memory = 16
+			},
+			wantErr: true,
+			errMsg:  "auth.password: argon2id needs",
+		},
+		{
+			name: "bcrypt cost out of range",
+			modify: func(c *Config) {
+				c.Auth.Password.Algorithm = "bcrypt"
+				c.Auth.Password.Cost = 2
+			},
+			wantErr: true,
+			errMsg:  "auth.password.cost must be",
+		},
 		{
 			name: "invalid log level",
 			modify: func(c *Config) {
@@ -574,6 +1116,191 @@ func TestValidate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "log.level must be",
 		},
+		{
+			name: "json log format",
+			modify: func(c *Config) {
+				c.Log.Format = "json"
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid log format",
+			modify: func(c *Config) {
+				c.Log.Format = "xml"
+			},
+			wantErr: true,
+			errMsg:  "log.format must be",
+		},
+		{
+			name: "tls with cert files",
+			modify: func(c *Config) {
+				c.Server.TLS.Enabled = true
+				c.Server.TLS.CertFile = "cert.pem"
+				c.Server.TLS.KeyFile = "key.pem"
+			},
+			wantErr: false,
+		},
+		{
+			name: "tls with autocert",
+			modify: func(c *Config) {
+				c.Server.TLS.Enabled = true
+				c.Server.TLS.Autocert.Domains = []string{"example.com"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "tls without certificate source",
+			modify: func(c *Config) {
+				c.Server.TLS.Enabled = true
+			},
+			wantErr: true,
+			errMsg:  "server.tls.enabled requires",
+		},
+		{
+			name: "tls cert without key",
+			modify: func(c *Config) {
+				c.Server.TLS.Enabled = true
+				c.Server.TLS.CertFile = "cert.pem"
+			},
+			wantErr: true,
+			errMsg:  "must be set together",
+		},
+		{
+			name: "tls files and autocert",
+			modify: func(c *Config) {
+				c.Server.TLS.Enabled = true
+				c.Server.TLS.CertFile = "cert.pem"
+				c.Server.TLS.KeyFile = "key.pem"
+				c.Server.TLS.Autocert.Domains = []string{"example.com"}
+			},
+			wantErr: true,
+			errMsg:  "not both",
+		},
+		{
+			name: "tls with client certificates",
+			modify: func(c *Config) {
+				c.Server.TLS.Enabled = true
+				c.Server.TLS.CertFile = "cert.pem"
+				c.Server.TLS.KeyFile = "key.pem"
+				c.Server.TLS.ClientCAFile = "ca.pem"
+				c.Server.TLS.ClientAuth = "required"
+			},
+			wantErr: false,
+		},
+		{
+			name: "tls client auth without bundle",
+			modify: func(c *Config) {
+				c.Server.TLS.Enabled = true
+				c.Server.TLS.CertFile = "cert.pem"
+				c.Server.TLS.KeyFile = "key.pem"
+				c.Server.TLS.ClientAuth = "required"
+			},
+			wantErr: true,
+			errMsg:  "requires server.tls.clientcafile",
+		},
+		{
+			name: "invalid tls client auth",
+			modify: func(c *Config) {
+				c.Server.TLS.Enabled = true
+				c.Server.TLS.CertFile = "cert.pem"
+				c.Server.TLS.KeyFile = "key.pem"
+				c.Server.TLS.ClientCAFile = "ca.pem"
+				c.Server.TLS.ClientAuth = "always"
+			},
+			wantErr: true,
+			errMsg:  "server.tls.clientauth must be",
+		},
+		{
+			name: "tls with spiffe",
+			modify: func(c *Config) {
+				c.Server.TLS.Enabled = true
+				c.Server.TLS.SPIFFE.Enabled = true
+				c.Server.TLS.ClientAuth = "required"
+			},
+			wantErr: false,
+		},
+		{
+			name: "tls spiffe and cert files",
+			modify: func(c *Config) {
+				c.Server.TLS.Enabled = true
+				c.Server.TLS.SPIFFE.Enabled = true
+				c.Server.TLS.CertFile = "cert.pem"
+				c.Server.TLS.KeyFile = "key.pem"
+			},
+			wantErr: true,
+			errMsg:  "server.tls.spiffe replaces",
+		},
+		{
+			name: "tls settings ignored when disabled",
+			modify: func(c *Config) {
+				c.Server.TLS.CertFile = "cert.pem"
+			},
+			wantErr: false,
+		},
+		{
+			name: "cors origins",
+			modify: func(c *Config) {
+				c.Server.CORS.AllowedOrigins = []string{"https://app.example.com", "http://localhost:3000"}
+				c.Server.CORS.AllowCredentials = true
+			},
+			wantErr: false,
+		},
+		{
+			name: "cors wildcard with credentials",
+			modify: func(c *Config) {
+				c.Server.CORS.AllowedOrigins = []string{"*"}
+				c.Server.CORS.AllowCredentials = true
+			},
+			wantErr: true,
+			errMsg:  "allowcredentials cannot be used",
+		},
+		{
+			name: "cors origin without scheme",
+			modify: func(c *Config) {
+				c.Server.CORS.AllowedOrigins = []string{"app.example.com"}
+			},
+			wantErr: true,
+			errMsg:  "must be '*' or start with",
+		},
+		{
+			name: "cors negative max age",
+			modify: func(c *Config) {
+				c.Server.CORS.MaxAge = -time.Second
+			},
+			wantErr: true,
+			errMsg:  "server.cors.maxage",
+		},
+		{
+			name: "client ip trusted proxies",
+			modify: func(c *Config) {
+				c.Server.ClientIP.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.10", "2001:db8::/32"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "client ip invalid trusted proxy",
+			modify: func(c *Config) {
+				c.Server.ClientIP.TrustedProxies = []string{"10.0.0.0/40"}
+			},
+			wantErr: true,
+			errMsg:  "server.clientip.trustedproxies",
+		},
+		{
+			name: "client ip empty header",
+			modify: func(c *Config) {
+				c.Server.ClientIP.Headers = []string{"X-Forwarded-For", " "}
+			},
+			wantErr: true,
+			errMsg:  "server.clientip.headers",
+		},
+		{
+			name: "maintenance negative retry after",
+			modify: func(c *Config) {
+				c.Server.Maintenance.RetryAfter = -time.Second
+			},
+			wantErr: true,
+			errMsg:  "server.maintenance.retryafter",
+		},
 	}
 
 	for _, tt := range tests {
@@ -600,6 +1327,75 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestServerConfig(t *testing.T) {
+	yaml := `
+server:
+  port: ":8443"
+  readtimeout: 5s
+  idletimeout: 2m
+  tls:
+    enabled: true
+    autocert:
+      domains:
+        - example.com
+        - www.example.com
+      email: ops@example.com
+  cors:
+    allowedorigins: ["https://app.example.com"]
+    allowedheaders: [Authorization, Content-Type]
+    allowcredentials: true
+    maxage: 10m
+  clientip:
+    trustedproxies: ["10.0.0.0/8"]
+    geoipdb: /var/lib/geoip/GeoLite2-City.mmdb
+  maintenance:
+    enabled: true
+`
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(yaml), 0644); err != nil {
+		t.Fatalf("cannot write test config: %v", err)
+	}
+
+	cfg, err := New(log.NewNoopLogger(), WithFile(configPath))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	srv := cfg.Server
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"read timeout from file", srv.ReadTimeout, 5 * time.Second},
+		{"idle timeout from file", srv.IdleTimeout, 2 * time.Minute},
+		{"default write timeout", srv.WriteTimeout, 60 * time.Second},
+		{"default read header timeout", srv.ReadHeaderTimeout, 10 * time.Second},
+		{"tls enabled", srv.TLS.Enabled, true},
+		{"autocert domains", len(srv.TLS.Autocert.Domains), 2},
+		{"autocert email", srv.TLS.Autocert.Email, "ops@example.com"},
+		{"default cache dir", srv.TLS.Autocert.CacheDir, "./data/autocert"},
+		{"default challenge addr", srv.TLS.Autocert.HTTPAddr, ":80"},
+		{"uses autocert", srv.TLS.UsesAutocert(), true},
+		{"cors origin", srv.CORS.AllowedOrigins[0], "https://app.example.com"},
+		{"cors headers", len(srv.CORS.AllowedHeaders), 2},
+		{"cors credentials", srv.CORS.AllowCredentials, true},
+		{"cors max age", srv.CORS.MaxAge, 10 * time.Minute},
+		{"client ip proxy", srv.ClientIP.TrustedProxies[0], "10.0.0.0/8"},
+		{"client ip geoip db", srv.ClientIP.GeoIPDB, "/var/lib/geoip/GeoLite2-City.mmdb"},
+		{"maintenance enabled", srv.Maintenance.Enabled, true},
+		{"default maintenance retry after", srv.Maintenance.RetryAfter, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
 func TestLoadConfigBackwardCompatibility(t *testing.T) {
 	yaml := `
 log:
@@ -661,6 +1457,8 @@ server:
 		"--log.level=error",
 		"--database.driver=postgres",
 		"--database.host=flag.db.com",
+		"--auth.token_ttl=2h",
+		"--server.maxbodybytes=512KB",
 	}
 
 	cfg, err := LoadConfig(configPath, "TEST_", args)
@@ -676,6 +1474,8 @@ server:
 		{"flag overrides file", cfg.Log.Level, "error"},
 		{"flag sets driver", cfg.Database.Driver, "postgres"},
 		{"flag sets host", cfg.Database.Host, "flag.db.com"},
+		{"flag sets duration", cfg.Auth.TokenTTL, 2 * time.Hour},
+		{"flag sets byte size", cfg.Server.MaxBodyBytes, 512 * Kilobyte},
 		{"file value used", cfg.Server.Port, ":9090"},
 	}
 
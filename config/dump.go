@@ -0,0 +1,102 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/knadh/koanf/v2"
+)
+
+// Config sources reported by Sources.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// RedactedValue replaces sensitive values in Dump output.
+const RedactedValue = "[REDACTED]"
+
+// sensitiveKeyParts are matched against every normalized segment of a key,
+// so maps of secrets such as auth.request_signing.keys are redacted whole.
+var sensitiveKeyParts = []string{"password", "passwd", "secret", "credential", "privatekey", "apikey", "dsn"}
+
+// Dump returns the effective merged configuration as a flat map keyed by
+// full path (e.g., "database.password"). When redact is true, non-empty values
+// of sensitive keys (passwords, keys, secrets, tokens), as well as any value
+// resolved through a SecretsProvider, are replaced with RedactedValue.
+func (c *Config) Dump(redact bool) map[string]any {
+	all := c.k.All()
+
+	dump := make(map[string]any, len(all))
+	for key, val := range all {
+		if redact && c.isSensitive(key) && !isEmpty(val) {
+			val = RedactedValue
+		}
+		dump[key] = val
+	}
+	return dump
+}
+
+// Sources returns, for each key, the last source that set it: SourceDefault,
+// SourceFile, SourceEnv, or SourceFlag. Useful to diagnose precedence issues.
+func (c *Config) Sources() map[string]string {
+	sources := make(map[string]string, len(c.sources))
+	for key, src := range c.sources {
+		sources[key] = src
+	}
+	return sources
+}
+
+// load loads a provider and records source as the origin of every key it sets.
+func (c *Config) load(source string, p koanf.Provider, pa koanf.Parser) error {
+	layer := koanf.New(".")
+	if err := layer.Load(p, pa); err != nil {
+		return err
+	}
+
+	if err := c.k.Merge(layer); err != nil {
+		return err
+	}
+
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	for _, key := range layer.Keys() {
+		c.sources[key] = source
+	}
+	return nil
+}
+
+func (c *Config) isSensitive(key string) bool {
+	if c.resolved[key] {
+		return true
+	}
+	return isSensitiveKey(key)
+}
+
+func isSensitiveKey(key string) bool {
+	for _, segment := range strings.Split(key, ".") {
+		if isSensitiveSegment(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSensitiveSegment(segment string) bool {
+	name := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(segment))
+
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	name = strings.TrimSuffix(name, "s")
+	return strings.HasSuffix(name, "key") || strings.HasSuffix(name, "token")
+}
+
+func isEmpty(val any) bool {
+	s, ok := val.(string)
+	return val == nil || (ok && s == "")
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/aquamarinepk/aqm/log"
+)
+
+func TestDump(t *testing.T) {
+	cfg, err := New(log.NewNoopLogger(),
+		WithDefaults(map[string]interface{}{
+			"crypto.apikey":            "",
+			"cache.size":               64,
+			"observability.errors.dsn": "https://key@sentry.example.com/1",
+			"auth.request_signing.keys": map[string]any{
+				"svc-a": "0123456789abcdef0123456789abcdef",
+			},
+			"auth.request_signing.key_id": "svc-a",
+		}),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		key    string
+		redact bool
+		want   any
+	}{
+		{"plain value", "server.port", true, ":8080"},
+		{"non-string value", "cache.size", true, 64},
+		{"password redacted", "database.password", true, RedactedValue},
+		{"session secret redacted", "auth.session_secret", true, RedactedValue},
+		{"underscored key redacted", "auth.encryption_key", true, RedactedValue},
+		{"private key redacted", "auth.token_private_key", true, RedactedValue},
+		{"dsn redacted", "observability.errors.dsn", true, RedactedValue},
+		{"ttl not redacted", "auth.token_ttl", true, "24h"},
+		{"signing key map redacted", "auth.request_signing.keys.svc-a", true, RedactedValue},
+		{"signing key id not redacted", "auth.request_signing.key_id", true, "svc-a"},
+		{"empty sensitive value kept", "crypto.apikey", true, ""},
+		{"unredacted password", "database.password", false, "dev"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := cfg.Dump(tt.redact)[tt.key]
+			if !ok {
+				t.Fatalf("Dump() missing key %s", tt.key)
+			}
+			if got != tt.want {
+				t.Errorf("Dump()[%s] = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDumpRedactsResolvedSecrets(t *testing.T) {
+	provider := SecretsProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		return "resolved", nil
+	})
+
+	cfg, err := New(log.NewNoopLogger(),
+		WithDefaults(map[string]interface{}{"features.flagsource": "secret://flags"}),
+		WithSecretsProvider(provider),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if got := cfg.Dump(true)["features.flagsource"]; got != RedactedValue {
+		t.Errorf("Dump(true) = %v, want %v", got, RedactedValue)
+	}
+	if got := cfg.Dump(false)["features.flagsource"]; got != "resolved" {
+		t.Errorf("Dump(false) = %v, want %q", got, "resolved")
+	}
+}
+
+func TestSources(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("server:\n  port: \":9090\"\n"), 0644); err != nil {
+		t.Fatalf("cannot write test config: %v", err)
+	}
+	t.Setenv("SRCTEST_LOG_LEVEL", "debug")
+
+	cfg, err := New(log.NewNoopLogger(), WithPrefix("SRCTEST_"), WithFile(configPath))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	sources := cfg.Sources()
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"database.driver", SourceDefault},
+		{"server.port", SourceFile},
+		{"log.level", SourceEnv},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := sources[tt.key]; got != tt.want {
+				t.Errorf("Sources()[%s] = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SecretScheme is the value prefix that marks a config value as a secret reference.
+// A value like "secret://authn/crypto#encryptionkey" is replaced at load time with
+// the value returned by the configured SecretsProvider for "authn/crypto#encryptionkey".
+const SecretScheme = "secret://"
+
+// SecretsProvider resolves secret references into their plaintext values.
+// The ref passed to GetSecret has the SecretScheme prefix removed; its format
+// is provider-specific (see the config/secrets package for the built-in backends).
+type SecretsProvider interface {
+	GetSecret(ctx context.Context, ref string) (string, error)
+}
+
+// SecretsProviderFunc adapts a function to the SecretsProvider interface.
+type SecretsProviderFunc func(ctx context.Context, ref string) (string, error)
+
+// GetSecret implements SecretsProvider.
+func (f SecretsProviderFunc) GetSecret(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// WithSecretsProvider resolves secret:// references with the given provider.
+// Resolution runs after defaults, file, and environment variables are merged,
+// so a reference may come from any source.
+func WithSecretsProvider(provider SecretsProvider) Option {
+	return func(opts *configOptions) error {
+		if provider == nil {
+			return fmt.Errorf("secrets provider cannot be nil")
+		}
+		opts.secrets = provider
+		return nil
+	}
+}
+
+// resolveSecrets replaces every secret:// value in the koanf instance with
+// the value returned by the provider.
+func (c *Config) resolveSecrets(ctx context.Context, provider SecretsProvider) error {
+	all := c.k.All()
+
+	keys := make([]string, 0, len(all))
+	for key := range all {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		val, ok := all[key].(string)
+		if !ok || !strings.HasPrefix(val, SecretScheme) {
+			continue
+		}
+
+		if provider == nil {
+			return fmt.Errorf("%s references a secret but no secrets provider is configured", key)
+		}
+
+		secret, err := provider.GetSecret(ctx, strings.TrimPrefix(val, SecretScheme))
+		if err != nil {
+			return fmt.Errorf("cannot resolve secret for %s: %w", key, err)
+		}
+
+		if err := c.k.Set(key, secret); err != nil {
+			return fmt.Errorf("cannot set resolved secret for %s: %w", key, err)
+		}
+
+		if c.resolved == nil {
+			c.resolved = make(map[string]bool)
+		}
+		c.resolved[key] = true
+
+		c.logger.Debugf("Resolved secret reference for %s", key)
+	}
+
+	return nil
+}
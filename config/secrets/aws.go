@@ -0,0 +1,143 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/internal/sigv4"
+)
+
+// AWSConfig holds AWS Secrets Manager connection settings.
+type AWSConfig struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Endpoint overrides the regional endpoint (e.g., for LocalStack).
+	Endpoint string
+	Timeout  time.Duration
+}
+
+// AWSConfigFromEnv returns an AWSConfig populated from the standard
+// AWS_REGION, AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN
+// environment variables. AWS_DEFAULT_REGION is used when AWS_REGION is unset.
+func AWSConfigFromEnv() AWSConfig {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+
+	return AWSConfig{
+		Region:          region,
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// AWSSecretsManager resolves references against AWS Secrets Manager.
+// The reference "prod/authn#encryptionkey" reads the "encryptionkey" field of
+// the JSON SecretString stored under the secret id "prod/authn". Without a key
+// the SecretString is returned as is.
+type AWSSecretsManager struct {
+	cfg    AWSConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewAWSSecretsManager creates an AWS Secrets Manager provider. Timeout defaults to 10s.
+func NewAWSSecretsManager(cfg AWSConfig) *AWSSecretsManager {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", cfg.Region)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &AWSSecretsManager{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		now:    time.Now,
+	}
+}
+
+// GetSecret implements config.SecretsProvider.
+func (a *AWSSecretsManager) GetSecret(ctx context.Context, ref string) (string, error) {
+	name, key := splitRef(ref)
+	if name == "" {
+		return "", fmt.Errorf("empty aws secret reference")
+	}
+	if a.cfg.Region == "" {
+		return "", fmt.Errorf("aws region is required")
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": name})
+	if err != nil {
+		return "", fmt.Errorf("cannot encode aws request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(a.cfg.Endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("cannot create aws request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	if a.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.cfg.SessionToken)
+	}
+
+	creds := sigv4.Credentials{AccessKeyID: a.cfg.AccessKeyID, SecretAccessKey: a.cfg.SecretAccessKey}
+	sigv4.Sign(req, creds, a.cfg.Region, "secretsmanager", sigv4.PayloadHash(body), a.now())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("aws request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", awsError(resp)
+	}
+
+	var payload struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("cannot decode aws response: %w", err)
+	}
+
+	if key == "" {
+		return payload.SecretString, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(payload.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object, cannot select key %s", name, key)
+	}
+	return selectKey(fields, key)
+}
+
+func awsError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	var payload struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(raw, &payload)
+
+	if strings.HasSuffix(payload.Type, "ResourceNotFoundException") {
+		return fmt.Errorf("%w: %s", ErrSecretNotFound, payload.Message)
+	}
+	if payload.Type != "" {
+		return fmt.Errorf("aws returned status %d: %s: %s", resp.StatusCode, payload.Type, payload.Message)
+	}
+	return fmt.Errorf("aws returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+}
@@ -0,0 +1,101 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAWSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" {
+			t.Errorf("X-Amz-Target = %q", r.Header.Get("X-Amz-Target"))
+		}
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/20240102/eu-west-1/secretsmanager/aws4_request") {
+			t.Errorf("Authorization = %q", auth)
+		}
+		if !strings.Contains(auth, "x-amz-security-token") {
+			t.Errorf("Authorization should sign session token header, got %q", auth)
+		}
+
+		var body struct {
+			SecretId string `json:"SecretId"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch body.SecretId {
+		case "prod/authn":
+			w.Write([]byte(`{"Name":"prod/authn","SecretString":"{\"encryptionkey\":\"enc\",\"signingkey\":\"sig\"}"}`))
+		case "plain":
+			w.Write([]byte(`{"Name":"plain","SecretString":"hunter2"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"Secrets Manager can't find the specified secret."}`))
+		}
+	}))
+}
+
+func TestAWSSecretsManagerGetSecret(t *testing.T) {
+	srv := newAWSServer(t)
+	defer srv.Close()
+
+	sm := NewAWSSecretsManager(AWSConfig{
+		Region:          "eu-west-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		SessionToken:    "session",
+		Endpoint:        srv.URL,
+	})
+	sm.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr error
+	}{
+		{"selects json key", "prod/authn#signingkey", "sig", nil},
+		{"plain string", "plain", "hunter2", nil},
+		{"missing json key", "prod/authn#missing", "", ErrKeyNotFound},
+		{"key on non-json secret", "plain#value", "", nil},
+		{"missing secret", "nope", "", ErrSecretNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sm.GetSecret(context.Background(), tt.ref)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("GetSecret() should fail, got %q", got)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("GetSecret() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("GetSecret() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetSecret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAWSSecretsManagerRequiresRegion(t *testing.T) {
+	sm := NewAWSSecretsManager(AWSConfig{Endpoint: "http://127.0.0.1:0"})
+
+	if _, err := sm.GetSecret(context.Background(), "prod/authn"); err == nil {
+		t.Error("GetSecret() should fail without region")
+	}
+}
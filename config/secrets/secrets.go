@@ -0,0 +1,64 @@
+// Package secrets provides config.SecretsProvider implementations for
+// HashiCorp Vault (KV v2) and AWS Secrets Manager.
+//
+// References use the form "<name>#<key>". The name identifies the secret in the
+// backend and the optional key selects a single field of a JSON-structured secret:
+//
+//	crypto:
+//	  encryptionkey: "secret://authn/crypto#encryptionkey"
+//
+// Both providers talk to their backends over plain HTTP APIs with no SDK dependency.
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrSecretNotFound = errors.New("secret not found")
+	ErrKeyNotFound    = errors.New("secret key not found")
+	ErrAmbiguousKey   = errors.New("secret has multiple keys, reference must select one with #key")
+)
+
+// splitRef splits "name#key" into its name and key parts.
+func splitRef(ref string) (name, key string) {
+	name, key, _ = strings.Cut(ref, "#")
+	return name, key
+}
+
+// selectKey returns the value of key in fields. If key is empty, fields must
+// contain exactly one entry, which is returned.
+func selectKey(fields map[string]any, key string) (string, error) {
+	if key == "" {
+		if len(fields) != 1 {
+			return "", ErrAmbiguousKey
+		}
+		for _, v := range fields {
+			return stringify(v), nil
+		}
+	}
+
+	v, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+	}
+	return stringify(v), nil
+}
+
+func stringify(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case nil:
+		return ""
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(b)
+	}
+}
@@ -0,0 +1,98 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultConfig holds HashiCorp Vault connection settings.
+type VaultConfig struct {
+	Addr      string
+	Token     string
+	Mount     string
+	Namespace string
+	Timeout   time.Duration
+}
+
+// VaultConfigFromEnv returns a VaultConfig populated from the standard
+// VAULT_ADDR, VAULT_TOKEN, and VAULT_NAMESPACE environment variables.
+func VaultConfigFromEnv() VaultConfig {
+	return VaultConfig{
+		Addr:      os.Getenv("VAULT_ADDR"),
+		Token:     os.Getenv("VAULT_TOKEN"),
+		Namespace: os.Getenv("VAULT_NAMESPACE"),
+	}
+}
+
+// Vault resolves references against a Vault KV v2 secrets engine.
+// The reference "authn/crypto#encryptionkey" reads the "encryptionkey" field
+// of the secret stored at <mount>/authn/crypto.
+type Vault struct {
+	cfg    VaultConfig
+	client *http.Client
+}
+
+// NewVault creates a Vault provider. Mount defaults to "secret" and Timeout to 10s.
+func NewVault(cfg VaultConfig) *Vault {
+	if cfg.Mount == "" {
+		cfg.Mount = "secret"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	cfg.Addr = strings.TrimSuffix(cfg.Addr, "/")
+
+	return &Vault{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// GetSecret implements config.SecretsProvider.
+func (v *Vault) GetSecret(ctx context.Context, ref string) (string, error) {
+	name, key := splitRef(ref)
+	if name == "" {
+		return "", fmt.Errorf("empty vault secret reference")
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/data/%s", v.cfg.Addr, v.cfg.Mount, strings.TrimPrefix(name, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot create vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.cfg.Token)
+	if v.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.Namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("vault returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var payload struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("cannot decode vault response: %w", err)
+	}
+
+	return selectKey(payload.Data.Data, key)
+}
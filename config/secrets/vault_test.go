@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newVaultServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/authn/crypto":
+			w.Write([]byte(`{"data":{"data":{"encryptionkey":"enc","signingkey":"sig"},"metadata":{"version":1}}}`))
+		case "/v1/secret/data/single":
+			w.Write([]byte(`{"data":{"data":{"value":"only"}}}`))
+		case "/v1/kv/data/custom":
+			w.Write([]byte(`{"data":{"data":{"port":5432}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+}
+
+func TestVaultGetSecret(t *testing.T) {
+	srv := newVaultServer(t)
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		mount   string
+		token   string
+		ref     string
+		want    string
+		wantErr error
+	}{
+		{"selects key", "", "s.token", "authn/crypto#encryptionkey", "enc", nil},
+		{"single field without key", "", "s.token", "single", "only", nil},
+		{"custom mount non-string value", "kv", "s.token", "custom#port", "5432", nil},
+		{"ambiguous without key", "", "s.token", "authn/crypto", "", ErrAmbiguousKey},
+		{"missing key", "", "s.token", "authn/crypto#missing", "", ErrKeyNotFound},
+		{"missing secret", "", "s.token", "nope#value", "", ErrSecretNotFound},
+		{"bad token", "", "wrong", "single", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVault(VaultConfig{Addr: srv.URL + "/", Token: tt.token, Mount: tt.mount})
+
+			got, err := v.GetSecret(context.Background(), tt.ref)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("GetSecret() should fail, got %q", got)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("GetSecret() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("GetSecret() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetSecret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
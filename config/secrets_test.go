@@ -0,0 +1,110 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/aquamarinepk/aqm/log"
+)
+
+func mapProvider(values map[string]string) SecretsProvider {
+	return SecretsProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		v, ok := values[ref]
+		if !ok {
+			return "", errors.New("not found")
+		}
+		return v, nil
+	})
+}
+
+func TestWithSecretsProvider(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+database:
+  password: "secret://db#password"
+crypto:
+  signingkey: "secret://authn/crypto#signingkey"
+  plain: not-a-secret
+`
+	if err := os.WriteFile(configPath, []byte(yaml), 0644); err != nil {
+		t.Fatalf("cannot write test config: %v", err)
+	}
+
+	t.Setenv("SECTEST_CRYPTO_ENCRYPTIONKEY", "secret://authn/crypto#encryptionkey")
+
+	provider := mapProvider(map[string]string{
+		"db#password":                 "dbpass",
+		"authn/crypto#signingkey":     "sig",
+		"authn/crypto#encryptionkey":  "enc",
+		"defaults/token#tokenprivkey": "priv",
+	})
+
+	cfg, err := New(log.NewNoopLogger(),
+		WithPrefix("SECTEST_"),
+		WithDefaults(map[string]interface{}{
+			"crypto.tokenprivatekey": "secret://defaults/token#tokenprivkey",
+		}),
+		WithFile(configPath),
+		WithSecretsProvider(provider),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"from file into typed field", cfg.Database.Password, "dbpass"},
+		{"from file", cfg.GetString("crypto.signingkey"), "sig"},
+		{"from env", cfg.GetString("crypto.encryptionkey"), "enc"},
+		{"from defaults", cfg.GetString("crypto.tokenprivatekey"), "priv"},
+		{"plain value untouched", cfg.GetString("crypto.plain"), "not-a-secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretReferenceWithoutProvider(t *testing.T) {
+	_, err := New(log.NewNoopLogger(),
+		WithDefaults(map[string]interface{}{"crypto.signingkey": "secret://authn/crypto#signingkey"}),
+	)
+	if err == nil {
+		t.Fatal("New() should fail when a secret reference has no provider")
+	}
+	if !strings.Contains(err.Error(), "crypto.signingkey") {
+		t.Errorf("error = %q, want to mention key", err.Error())
+	}
+}
+
+func TestSecretsProviderError(t *testing.T) {
+	providerErr := errors.New("vault sealed")
+	provider := SecretsProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		return "", providerErr
+	})
+
+	_, err := New(log.NewNoopLogger(),
+		WithDefaults(map[string]interface{}{"crypto.signingkey": "secret://authn/crypto#signingkey"}),
+		WithSecretsProvider(provider),
+	)
+	if !errors.Is(err, providerErr) {
+		t.Errorf("New() error = %v, want wrapped %v", err, providerErr)
+	}
+}
+
+func TestWithSecretsProviderNil(t *testing.T) {
+	if _, err := New(log.NewNoopLogger(), WithSecretsProvider(nil)); err == nil {
+		t.Error("New() should fail with nil secrets provider")
+	}
+}
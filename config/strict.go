@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// WithStrict makes New fail when the config file or the environment sets a
+// key that maps to no configuration path, such as "databse.host", instead of
+// leaving the misspelt setting at its default. Keys given to WithDefaults are
+// known, and so is everything under sections, the top-level sections a
+// service reads itself with UnmarshalKey or GetString (e.g. "crypto").
+func WithStrict(sections ...string) Option {
+	return func(opts *configOptions) error {
+		opts.strict = true
+		opts.sections = append(opts.sections, sections...)
+		return nil
+	}
+}
+
+// schema holds the known configuration paths, which strict mode checks keys
+// against and environment variable names are mapped to.
+type schema struct {
+	known map[string]bool
+	// open are the paths any key under which is known, such as maps.
+	open []string
+	// maps holds the fields of the struct entries of each map path, none
+	// for maps of plain values.
+	maps map[string]*schema
+	// env maps the known paths with their dots turned into underscores
+	// back to the paths.
+	env map[string]string
+}
+
+func newSchema(defaults map[string]interface{}, sections []string) *schema {
+	s := &schema{known: make(map[string]bool), maps: make(map[string]*schema)}
+	s.open = append(s.open, sections...)
+	s.add(reflect.TypeOf(Config{}), "")
+	for key := range defaults {
+		s.known[key] = true
+	}
+	s.index()
+	return s
+}
+
+// index fills s.env. Of paths sharing an underscored form, the first in
+// sort order wins.
+func (s *schema) index() {
+	keys := make([]string, 0, len(s.known))
+	for key := range s.known {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	s.env = make(map[string]string, len(keys))
+	for _, key := range keys {
+		name := strings.ReplaceAll(key, ".", "_")
+		if _, ok := s.env[name]; !ok {
+			s.env[name] = key
+		}
+	}
+}
+
+// add records the koanf paths of the fields of struct type t under prefix.
+func (s *schema) add(t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("koanf")
+		if tag == "" || tag == "-" || !f.IsExported() {
+			continue
+		}
+		path := prefix + tag
+		s.known[path] = true
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.Struct:
+			s.add(ft, path+".")
+		case reflect.Map:
+			s.open = append(s.open, path)
+			var elem *schema
+			if et := ft.Elem(); et.Kind() == reflect.Struct {
+				elem = &schema{known: make(map[string]bool), maps: make(map[string]*schema)}
+				elem.add(et, "")
+				elem.index()
+			}
+			s.maps[path] = elem
+		}
+	}
+}
+
+// envKey returns the configuration path of the environment variable name,
+// lowercased and stripped of its prefix. Underscores separate sections, yet
+// keys such as "auth.token_ttl" contain them too, so known paths and the
+// entries of map sections are matched first; "auth_token_ttl" is
+// "auth.token_ttl" and "auth_oauth_google_client_id" is
+// "auth.oauth.google.client_id", preferring the longest field name. Other
+// names turn every underscore into a dot.
+func (s *schema) envKey(name string) string {
+	if key, ok := s.env[name]; ok {
+		return key
+	}
+	for path, elem := range s.maps {
+		prefix := strings.ReplaceAll(path, ".", "_") + "_"
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		if elem == nil {
+			return path + "." + rest
+		}
+		match := ""
+		for field := range elem.env {
+			if len(field) > len(match) && strings.HasSuffix(rest, "_"+field) {
+				match = field
+			}
+		}
+		if match != "" {
+			return path + "." + strings.TrimSuffix(rest, "_"+match) + "." + elem.env[match]
+		}
+	}
+	return strings.ReplaceAll(name, "_", ".")
+}
+
+func (s *schema) knows(key string) bool {
+	if s.known[key] {
+		return true
+	}
+	for _, p := range s.open {
+		if key == p || strings.HasPrefix(key, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkStrict returns an error listing the keys set by the file or the
+// environment that s does not know.
+func (c *Config) checkStrict(s *schema) error {
+	var unknown []string
+	for key, src := range c.sources {
+		if (src == SourceFile || src == SourceEnv) && !s.knows(key) {
+			unknown = append(unknown, fmt.Sprintf("%s (%s)", key, src))
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("unknown config keys: %s", strings.Join(unknown, ", "))
+}
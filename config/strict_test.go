@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+)
+
+func TestWithStrict(t *testing.T) {
+	valid := `
+server:
+  port: ":9090"
+  tls:
+    enabled: false
+database:
+  host: db.example.com
+auth:
+  session_secret: test-secret
+  policies:
+    terms: "2026-01"
+crypto:
+  encryptionkey: abc
+`
+	tests := []struct {
+		name    string
+		yaml    string
+		env     map[string]string
+		opts    []Option
+		want    []string
+		wantErr bool
+	}{
+		{name: "known keys", yaml: valid, opts: []Option{WithStrict("crypto")}},
+		{name: "service defaults", yaml: "custom:\n  feature: true\n", opts: []Option{WithStrict(), WithDefaults(map[string]interface{}{"custom.feature": false})}},
+		{
+			name:    "typos",
+			yaml:    "databse:\n  host: db\nserver:\n  prot: \":9090\"\n",
+			opts:    []Option{WithStrict()},
+			want:    []string{"unknown config keys: databse.host (file), server.prot (file)"},
+			wantErr: true,
+		},
+		{
+			name:    "service section not declared",
+			yaml:    valid,
+			opts:    []Option{WithStrict()},
+			want:    []string{"crypto.encryptionkey (file)"},
+			wantErr: true,
+		},
+		{
+			name:    "environment",
+			env:     map[string]string{"STRICT_DATABASE_HOTS": "db"},
+			opts:    []Option{WithStrict(), WithPrefix("STRICT_")},
+			want:    []string{"database.hots (env)"},
+			wantErr: true,
+		},
+		{name: "environment underscored key", env: map[string]string{"STRICT_AUTH_TOKEN_TTL": "1h"}, opts: []Option{WithStrict(), WithPrefix("STRICT_")}},
+		{
+			name:    "environment underscored typo",
+			env:     map[string]string{"STRICT_AUTH_TOKEN_TLL": "1h"},
+			opts:    []Option{WithStrict(), WithPrefix("STRICT_")},
+			want:    []string{"auth.token.tll (env)"},
+			wantErr: true,
+		},
+		{name: "not strict", yaml: "databse:\n  host: db\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			opts := tt.opts
+			if tt.yaml != "" {
+				path := filepath.Join(t.TempDir(), "config.yaml")
+				if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+					t.Fatalf("cannot write test config: %v", err)
+				}
+				opts = append(opts, WithFile(path))
+			}
+
+			_, err := New(log.NewNoopLogger(), opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("New() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWithStrictEnvUnderscoredKey(t *testing.T) {
+	t.Setenv("SVC_AUTH_TOKEN_TTL", "1h")
+	t.Setenv("SVC_AUTH_PASSWORD_RESET_TOKEN_TTL", "30m")
+
+	cfg, err := New(log.NewNoopLogger(), WithStrict(), WithPrefix("SVC_"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if cfg.Auth.TokenTTL != time.Hour || cfg.Auth.PasswordResetTokenTTL != 30*time.Minute {
+		t.Errorf("token TTLs = %v, %v, want 1h and 30m from env", cfg.Auth.TokenTTL, cfg.Auth.PasswordResetTokenTTL)
+	}
+}
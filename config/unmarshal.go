@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/aquamarinepk/aqm/validation"
+)
+
+// UnmarshalKey binds the configuration subtree at path into target.
+// Target must be a pointer to a struct whose fields use koanf tags.
+// After binding, fields are checked against their `validate` tags with
+// validation.ValidateStructTag and reported by their full koanf path (e.g.,
+// "crypto.encryptionkey"); if target implements validation.Validator, its
+// Validate method is called too.
+//
+// Example:
+//
+//	type CryptoConfig struct {
+//	    EncryptionKey string `koanf:"encryptionkey" validate:"required"`
+//	    SigningKey    string `koanf:"signingkey" validate:"required,min=32"`
+//	}
+//
+//	var crypto CryptoConfig
+//	if err := cfg.UnmarshalKey("crypto", &crypto); err != nil {
+//	    return err
+//	}
+func (c *Config) UnmarshalKey(path string, target any) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot unmarshal %s: target must be a non-nil pointer to a struct", path)
+	}
+
+	if err := c.k.Unmarshal(path, target); err != nil {
+		return fmt.Errorf("cannot unmarshal %s: %w", path, err)
+	}
+
+	var errs validation.ValidationErrors
+	for _, e := range validation.ValidateStructTag(target, "koanf") {
+		if path != "" {
+			e.Field = path + "." + e.Field
+		}
+		errs.AddError(e)
+	}
+	if v, ok := target.(validation.Validator); ok {
+		errs.Merge(v.Validate())
+	}
+
+	if errs.HasErrors() {
+		return fmt.Errorf("invalid %s config: %w", path, errs)
+	}
+
+	return nil
+}
+
+// MustUnmarshalKey is like UnmarshalKey but panics on error.
+// Intended for service wiring in main where a bad config is unrecoverable.
+func (c *Config) MustUnmarshalKey(path string, target any) {
+	if err := c.UnmarshalKey(path, target); err != nil {
+		panic(err)
+	}
+}
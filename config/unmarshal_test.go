@@ -0,0 +1,210 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/validation"
+)
+
+type testCryptoConfig struct {
+	EncryptionKey   string `koanf:"encryptionkey" validate:"required"`
+	SigningKey      string `koanf:"signingkey" validate:"required"`
+	TokenPrivateKey string `koanf:"tokenprivatekey"`
+	Rotation        struct {
+		Enabled bool   `koanf:"enabled"`
+		Period  string `koanf:"period" validate:"required"`
+	} `koanf:"rotation"`
+}
+
+type testMailConfig struct {
+	Driver string `koanf:"driver" validate:"oneof=smtp none"`
+	From   string `koanf:"from" validate:"email"`
+	Site   string `koanf:"site" validate:"url"`
+	SMTP   struct {
+		Port int `koanf:"port" validate:"min=1,max=65535"`
+	} `koanf:"smtp"`
+}
+
+type testPoolConfig struct {
+	Size int `koanf:"size"`
+}
+
+func (c testPoolConfig) Validate() validation.ValidationErrors {
+	var errs validation.ValidationErrors
+	if c.Size < 1 {
+		errs.Add("pool.size", "must be at least 1")
+	}
+	return errs
+}
+
+func newUnmarshalTestConfig(t *testing.T, yaml string) *Config {
+	t.Helper()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(yaml), 0644); err != nil {
+		t.Fatalf("cannot write test config: %v", err)
+	}
+
+	cfg, err := New(log.NewNoopLogger(), WithFile(configPath))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	return cfg
+}
+
+func TestUnmarshalKey(t *testing.T) {
+	cfg := newUnmarshalTestConfig(t, `
+crypto:
+  encryptionkey: enc
+  signingkey: sig
+  rotation:
+    enabled: true
+    period: 720h
+`)
+
+	var crypto testCryptoConfig
+	if err := cfg.UnmarshalKey("crypto", &crypto); err != nil {
+		t.Fatalf("UnmarshalKey() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"encryption key", crypto.EncryptionKey, "enc"},
+		{"signing key", crypto.SigningKey, "sig"},
+		{"optional field empty", crypto.TokenPrivateKey, ""},
+		{"nested bool", crypto.Rotation.Enabled, true},
+		{"nested string", crypto.Rotation.Period, "720h"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalKeyRequired(t *testing.T) {
+	cfg := newUnmarshalTestConfig(t, `
+crypto:
+  signingkey: sig
+`)
+
+	var crypto testCryptoConfig
+	err := cfg.UnmarshalKey("crypto", &crypto)
+	if err == nil {
+		t.Fatal("UnmarshalKey() should fail when required fields are missing")
+	}
+
+	for _, want := range []string{"crypto.encryptionkey: is required", "crypto.rotation.period: is required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %q, want to contain %q", err.Error(), want)
+		}
+	}
+
+	if strings.Contains(err.Error(), "crypto.signingkey") {
+		t.Errorf("error = %q, should not report present field", err.Error())
+	}
+}
+
+func TestUnmarshalKeyRules(t *testing.T) {
+	cfg := newUnmarshalTestConfig(t, `
+mailer:
+  driver: sendmail
+  from: not-an-email
+  site: /relative
+  smtp:
+    port: 70000
+`)
+
+	var mailer testMailConfig
+	err := cfg.UnmarshalKey("mailer", &mailer)
+	if err == nil {
+		t.Fatal("UnmarshalKey() should fail when fields break their rules")
+	}
+	for _, want := range []string{"mailer.driver", "mailer.from", "mailer.site", "mailer.smtp.port"} {
+		if !strings.Contains(err.Error(), want+":") {
+			t.Errorf("error = %q, want to contain %q", err.Error(), want)
+		}
+	}
+
+	cfg = newUnmarshalTestConfig(t, `
+mailer:
+  driver: smtp
+  from: noreply@example.com
+  site: https://example.com
+  smtp:
+    port: 587
+`)
+	if err := cfg.UnmarshalKey("mailer", &mailer); err != nil {
+		t.Errorf("UnmarshalKey() error = %v", err)
+	}
+}
+
+func TestUnmarshalKeyValidator(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{"valid", "pool:\n  size: 4\n", false},
+		{"invalid", "pool:\n  size: 0\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newUnmarshalTestConfig(t, tt.yaml)
+
+			var pool testPoolConfig
+			err := cfg.UnmarshalKey("pool", &pool)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UnmarshalKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUnmarshalKeyInvalidTarget(t *testing.T) {
+	cfg := newUnmarshalTestConfig(t, "crypto:\n  signingkey: sig\n")
+
+	var notStruct string
+	var nilPtr *testCryptoConfig
+
+	tests := []struct {
+		name   string
+		target any
+	}{
+		{"non-pointer", testCryptoConfig{}},
+		{"pointer to non-struct", &notStruct},
+		{"nil pointer", nilPtr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := cfg.UnmarshalKey("crypto", tt.target); err == nil {
+				t.Error("UnmarshalKey() should fail for invalid target")
+			}
+		})
+	}
+}
+
+func TestMustUnmarshalKey(t *testing.T) {
+	cfg := newUnmarshalTestConfig(t, "crypto:\n  signingkey: sig\n")
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("MustUnmarshalKey() should panic on validation failure")
+		}
+	}()
+
+	var crypto testCryptoConfig
+	cfg.MustUnmarshalKey("crypto", &crypto)
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aquamarinepk/aqm/validation"
+)
+
+// SectionValidator checks the config section it was registered for. It reads
+// typed fields of c or binds a service section with UnmarshalKey, and reports
+// fields relative to the section, e.g. "encryption_key" for "auth".
+type SectionValidator func(c *Config) validation.ValidationErrors
+
+type sectionValidator struct {
+	path string
+	fn   SectionValidator
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   []sectionValidator
+)
+
+// RegisterValidator adds fn to the checks of Validate for the section at
+// path, run only when the section is set. Packages owning a section register
+// from init, so every service importing them gets the checks. The errors of
+// all validators are reported together as validation.ValidationErrors.
+func RegisterValidator(path string, fn SectionValidator) {
+	if path == "" || fn == nil {
+		panic("config: RegisterValidator needs a path and a validator")
+	}
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators = append(validators, sectionValidator{path: path, fn: fn})
+}
+
+// validateSections runs the registered validators, prefixing the fields
+// they report with their section.
+func (c *Config) validateSections() error {
+	validatorsMu.RLock()
+	registered := append([]sectionValidator(nil), validators...)
+	validatorsMu.RUnlock()
+
+	var errs validation.ValidationErrors
+	for _, v := range registered {
+		if c.k != nil && !c.k.Exists(v.path) {
+			continue
+		}
+		for _, e := range v.fn(c) {
+			if e.Field == "" {
+				e.Field = v.path
+			} else {
+				e.Field = v.path + "." + e.Field
+			}
+			errs.AddError(e)
+		}
+	}
+
+	if errs.HasErrors() {
+		return fmt.Errorf("invalid config: %w", errs)
+	}
+	return nil
+}
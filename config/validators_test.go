@@ -0,0 +1,41 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/validation"
+)
+
+func TestRegisterValidator(t *testing.T) {
+	defer func(saved []sectionValidator) { validators = saved }(validators)
+
+	RegisterValidator("billing", func(c *Config) validation.ValidationErrors {
+		var errs validation.ValidationErrors
+		if c.GetString("billing.currency") == "" {
+			errs.Add("currency", "is required")
+		}
+		if c.GetInt("billing.retries") > 5 {
+			errs.Add("retries", "must be at most 5")
+		}
+		return errs
+	})
+	RegisterValidator("unset", func(c *Config) validation.ValidationErrors {
+		return validation.ValidationErrors{{Message: "must not run"}}
+	})
+
+	_, err := New(log.NewNoopLogger(), WithDefaults(map[string]interface{}{"billing.retries": 9}))
+	var errs validation.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("New() error = %v, want validation errors", err)
+	}
+	if got := strings.Join(errs.Fields(), ","); got != "billing.currency,billing.retries" {
+		t.Errorf("fields = %s, want both billing fields and nothing from unset", got)
+	}
+
+	if _, err := New(log.NewNoopLogger(), WithDefaults(map[string]interface{}{"billing.currency": "EUR"})); err != nil {
+		t.Errorf("New() error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,27 @@
+package crypto
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+)
+
+// userKeyInfo binds keys from DeriveUserKey to their purpose so the same
+// master key can derive keys for other uses without overlap.
+const userKeyInfo = "aqm user data key v1:"
+
+// DeriveKey derives a length-byte key from master with HKDF-SHA256. Keys
+// derived for different info values are independent of each other, and
+// none of them reveals master.
+func DeriveKey(master []byte, info string, length int) ([]byte, error) {
+	if len(master) < aesKeyLength {
+		return nil, ErrInvalidKey
+	}
+	return hkdf.Key(sha256.New, master, nil, info, length)
+}
+
+// DeriveUserKey derives the key that encrypts the data of the user with
+// userID, suitable for EncryptEmail. A leaked user key exposes that user's
+// records only.
+func DeriveUserKey(master []byte, userID string) ([]byte, error) {
+	return DeriveKey(master, userKeyInfo+userID, aesKeyLength)
+}
@@ -0,0 +1,54 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestDeriveUserKey(t *testing.T) {
+	master := bytes.Repeat([]byte{7}, aesKeyLength)
+
+	a, err := DeriveUserKey(master, "user-a")
+	if err != nil {
+		t.Fatalf("DeriveUserKey() error = %v", err)
+	}
+	if len(a) != aesKeyLength {
+		t.Errorf("DeriveUserKey() length = %d, want %d", len(a), aesKeyLength)
+	}
+
+	again, _ := DeriveUserKey(master, "user-a")
+	if !bytes.Equal(a, again) {
+		t.Error("DeriveUserKey() is not deterministic")
+	}
+
+	b, _ := DeriveUserKey(master, "user-b")
+	if bytes.Equal(a, b) {
+		t.Error("DeriveUserKey() gave two users the same key")
+	}
+	if bytes.Equal(a, master) {
+		t.Error("DeriveUserKey() returned the master key")
+	}
+
+	other, _ := DeriveUserKey(bytes.Repeat([]byte{8}, aesKeyLength), "user-a")
+	if bytes.Equal(a, other) {
+		t.Error("DeriveUserKey() ignores the master key")
+	}
+
+	ct, iv, tag, err := EncryptEmail("a@example.com", a)
+	if err != nil {
+		t.Fatalf("EncryptEmail() error = %v", err)
+	}
+	if _, err := DecryptEmail(ct, iv, tag, b); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("DecryptEmail(other user's key) error = %v, want ErrDecryptionFailed", err)
+	}
+	if got, err := DecryptEmail(ct, iv, tag, a); err != nil || got != "a@example.com" {
+		t.Errorf("DecryptEmail() = %q, %v", got, err)
+	}
+}
+
+func TestDeriveKeyShortMaster(t *testing.T) {
+	if _, err := DeriveKey([]byte("short"), "info", 32); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("DeriveKey(short master) error = %v, want ErrInvalidKey", err)
+	}
+}
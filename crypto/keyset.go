@@ -0,0 +1,74 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrEmptyKeySet = errors.New("key set has no usable keys")
+
+// KeySet is the JSON Web Key Set document used to publish token verification
+// keys. Only Ed25519 keys (kty OKP, crv Ed25519) are produced and consumed.
+type KeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JSONWebKey is a single public key in a KeySet.
+type JSONWebKey struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv,omitempty"`
+	X       string `json:"x,omitempty"`
+	KeyID   string `json:"kid,omitempty"`
+	Use     string `json:"use,omitempty"`
+}
+
+// NewKeySet builds a KeySet for publishing the given public keys.
+func NewKeySet(keys ...ed25519.PublicKey) KeySet {
+	set := KeySet{Keys: make([]JSONWebKey, 0, len(keys))}
+	for _, key := range keys {
+		set.Keys = append(set.Keys, JSONWebKey{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       base64.RawURLEncoding.EncodeToString(key),
+			KeyID:   KeyID(key),
+			Use:     "sig",
+		})
+	}
+	return set
+}
+
+// KeyID derives a stable identifier for a public key.
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return base64.RawURLEncoding.EncodeToString(sum[:8])
+}
+
+// ParseKeySet decodes a KeySet document and returns its Ed25519 keys.
+// Keys of other types are skipped; a set with no usable keys is an error.
+func ParseKeySet(data []byte) ([]ed25519.PublicKey, error) {
+	var set KeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("cannot decode key set: %w", err)
+	}
+
+	keys := make([]ed25519.PublicKey, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.KeyType != "OKP" || jwk.Curve != "Ed25519" {
+			continue
+		}
+		raw, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid key %q in key set", jwk.KeyID)
+		}
+		keys = append(keys, ed25519.PublicKey(raw))
+	}
+
+	if len(keys) == 0 {
+		return nil, ErrEmptyKeySet
+	}
+	return keys, nil
+}
@@ -0,0 +1,68 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestKeySetRoundTrip(t *testing.T) {
+	pub1, _, _ := ed25519.GenerateKey(rand.Reader)
+	pub2, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	data, err := json.Marshal(NewKeySet(pub1, pub2))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	keys, err := ParseKeySet(data)
+	if err != nil {
+		t.Fatalf("ParseKeySet() error = %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("ParseKeySet() returned %d keys, want 2", len(keys))
+	}
+	if !bytes.Equal(keys[0], pub1) || !bytes.Equal(keys[1], pub2) {
+		t.Error("ParseKeySet() keys do not match published keys")
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	pub1, _, _ := ed25519.GenerateKey(rand.Reader)
+	pub2, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	if KeyID(pub1) != KeyID(pub1) {
+		t.Error("KeyID() should be stable")
+	}
+	if KeyID(pub1) == KeyID(pub2) {
+		t.Error("KeyID() should differ between keys")
+	}
+}
+
+func TestParseKeySetErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{"invalid json", `{`, nil},
+		{"empty", `{"keys":[]}`, ErrEmptyKeySet},
+		{"only foreign keys", `{"keys":[{"kty":"RSA","n":"abc","e":"AQAB"}]}`, ErrEmptyKeySet},
+		{"bad key material", `{"keys":[{"kty":"OKP","crv":"Ed25519","x":"short"}]}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseKeySet([]byte(tt.data))
+			if err == nil {
+				t.Fatal("ParseKeySet() should fail")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseKeySet() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package crypto
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms.
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrUnknownPasswordAlgorithm = errors.New("unknown password algorithm")
+	ErrInvalidPasswordParams    = errors.New("invalid password parameters")
+	ErrInvalidPasswordHash      = errors.New("invalid password hash")
+)
+
+// PasswordParams selects the password hashing algorithm and its cost.
+// Memory (KiB), Iterations and Parallelism apply to Argon2id and Cost to
+// bcrypt. The zero value means DefaultPasswordParams.
+type PasswordParams struct {
+	Algorithm   string
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	Cost        int
+}
+
+// DefaultPasswordParams returns Argon2id with the parameters HashPassword
+// has always used.
+func DefaultPasswordParams() PasswordParams {
+	return PasswordParams{
+		Algorithm:   PasswordAlgorithmArgon2id,
+		Memory:      argonMemory,
+		Iterations:  argonTime,
+		Parallelism: argonThreads,
+		Cost:        bcrypt.DefaultCost,
+	}
+}
+
+func (p PasswordParams) orDefault() PasswordParams {
+	if p.Algorithm == "" {
+		return DefaultPasswordParams()
+	}
+	return p
+}
+
+// Validate checks that the parameters can be used to hash passwords.
+func (p PasswordParams) Validate() error {
+	p = p.orDefault()
+	switch p.Algorithm {
+	case PasswordAlgorithmArgon2id:
+		if p.Iterations < 1 || p.Parallelism < 1 || p.Memory < 8*uint32(p.Parallelism) {
+			return fmt.Errorf("%w: argon2id needs iterations and parallelism of at least 1 and memory of at least 8 KiB per thread", ErrInvalidPasswordParams)
+		}
+	case PasswordAlgorithmBcrypt:
+		if p.Cost < bcrypt.MinCost || p.Cost > bcrypt.MaxCost {
+			return fmt.Errorf("%w: bcrypt cost must be between %d and %d", ErrInvalidPasswordParams, bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownPasswordAlgorithm, p.Algorithm)
+	}
+	return nil
+}
+
+// HashPasswordWith hashes password with p. The result is self-describing:
+// a PHC string such as $argon2id$v=19$m=65536,t=1,p=4$salt$hash, or a
+// standard bcrypt hash, so it needs no separate salt.
+func HashPasswordWith(password string, p PasswordParams) ([]byte, error) {
+	p = p.orDefault()
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	if p.Algorithm == PasswordAlgorithmBcrypt {
+		return bcrypt.GenerateFromPassword([]byte(password), p.Cost)
+	}
+
+	salt, err := GenerateSalt()
+	if err != nil {
+		return nil, err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, argonKeyLength)
+	encoded := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+	return []byte(encoded), nil
+}
+
+// VerifyPasswordHash reports whether password matches a hash produced by
+// HashPasswordWith.
+func VerifyPasswordHash(password string, hash []byte) bool {
+	if isBcrypt(hash) {
+		return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+	}
+
+	p, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	computed := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, computed) == 1
+}
+
+// PasswordNeedsRehash reports whether hash was made with an algorithm or
+// cost other than p, or cannot be parsed.
+func PasswordNeedsRehash(hash []byte, p PasswordParams) bool {
+	p = p.orDefault()
+	if isBcrypt(hash) {
+		cost, err := bcrypt.Cost(hash)
+		return err != nil || p.Algorithm != PasswordAlgorithmBcrypt || cost != p.Cost
+	}
+
+	got, _, _, err := parseArgon2id(hash)
+	if err != nil || p.Algorithm != PasswordAlgorithmArgon2id {
+		return true
+	}
+	return got.Memory != p.Memory || got.Iterations != p.Iterations || got.Parallelism != p.Parallelism
+}
+
+func isBcrypt(hash []byte) bool {
+	return len(hash) > 3 && hash[0] == '$' && hash[1] == '2'
+}
+
+func parseArgon2id(hash []byte) (PasswordParams, []byte, []byte, error) {
+	p := PasswordParams{Algorithm: PasswordAlgorithmArgon2id}
+
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != PasswordAlgorithmArgon2id {
+		return p, nil, nil, ErrInvalidPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrInvalidPasswordHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil || p.Iterations < 1 || p.Parallelism < 1 {
+		return p, nil, nil, ErrInvalidPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrInvalidPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrInvalidPasswordHash
+	}
+	return p, salt, key, nil
+}
@@ -0,0 +1,102 @@
+package crypto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var cheapArgon = PasswordParams{Algorithm: PasswordAlgorithmArgon2id, Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestHashPasswordWith(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     PasswordParams
+		wantPrefix string
+	}{
+		{name: "defaults", params: PasswordParams{}, wantPrefix: "$argon2id$v=19$m=65536,t=1,p=4$"},
+		{name: "argon2id", params: cheapArgon, wantPrefix: "$argon2id$v=19$m=64,t=1,p=1$"},
+		{name: "bcrypt", params: PasswordParams{Algorithm: PasswordAlgorithmBcrypt, Cost: 4}, wantPrefix: "$2a$04$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := HashPasswordWith("Password123!", tt.params)
+			if err != nil {
+				t.Fatalf("HashPasswordWith() error = %v", err)
+			}
+			if !strings.HasPrefix(string(hash), tt.wantPrefix) {
+				t.Errorf("hash = %s, want prefix %s", hash, tt.wantPrefix)
+			}
+			if !VerifyPasswordHash("Password123!", hash) {
+				t.Error("expected password to verify")
+			}
+			if VerifyPasswordHash("Password124!", hash) {
+				t.Error("expected wrong password to fail")
+			}
+			if PasswordNeedsRehash(hash, tt.params) {
+				t.Error("expected no rehash with the same parameters")
+			}
+		})
+	}
+}
+
+func TestPasswordParamsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  PasswordParams
+		wantErr error
+	}{
+		{name: "zero value", params: PasswordParams{}},
+		{name: "unknown algorithm", params: PasswordParams{Algorithm: "md5"}, wantErr: ErrUnknownPasswordAlgorithm},
+		{name: "no iterations", params: PasswordParams{Algorithm: PasswordAlgorithmArgon2id, Memory: 64, Parallelism: 1}, wantErr: ErrInvalidPasswordParams},
+		{name: "too little memory", params: PasswordParams{Algorithm: PasswordAlgorithmArgon2id, Memory: 8, Iterations: 1, Parallelism: 2}, wantErr: ErrInvalidPasswordParams},
+		{name: "bcrypt cost", params: PasswordParams{Algorithm: PasswordAlgorithmBcrypt, Cost: 40}, wantErr: ErrInvalidPasswordParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if _, err := HashPasswordWith("Password123!", tt.params); err == nil {
+					t.Error("expected HashPasswordWith() to fail")
+				}
+			}
+		})
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	hash, _ := HashPasswordWith("Password123!", cheapArgon)
+
+	stronger := cheapArgon
+	stronger.Iterations = 2
+
+	tests := []struct {
+		name   string
+		hash   []byte
+		params PasswordParams
+		want   bool
+	}{
+		{name: "same", hash: hash, params: cheapArgon, want: false},
+		{name: "more iterations", hash: hash, params: stronger, want: true},
+		{name: "other algorithm", hash: hash, params: PasswordParams{Algorithm: PasswordAlgorithmBcrypt, Cost: 4}, want: true},
+		{name: "legacy raw hash", hash: HashPassword("Password123!", make([]byte, saltLength)), params: cheapArgon, want: true},
+		{name: "garbage", hash: []byte("$argon2id$v=19$m=64,t=0,p=1$c2FsdA$a2V5"), params: cheapArgon, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PasswordNeedsRehash(tt.hash, tt.params); got != tt.want {
+				t.Errorf("PasswordNeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if VerifyPasswordHash("Password123!", []byte("$argon2id$v=19$m=64,t=0,p=1$c2FsdA$a2V5")) {
+		t.Error("expected malformed hash not to verify")
+	}
+}
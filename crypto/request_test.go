@@ -0,0 +1,77 @@
+package crypto
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerifyRequest(t *testing.T) {
+	secret := []byte("internal-secret")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	signed := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/authz/import?dry_run=true", strings.NewReader(`{"roles":[]}`))
+		if err := SignRequest(r, "seeder", secret, now); err != nil {
+			t.Fatalf("SignRequest() error = %v", err)
+		}
+		return r
+	}
+
+	r := signed()
+	if r.Header.Get(HeaderSignatureKeyID) != "seeder" || !strings.HasPrefix(r.Header.Get(HeaderSignature), "sha256=") {
+		t.Fatalf("signature headers = %v", r.Header)
+	}
+	if err := VerifyRequest(r, secret, now.Add(time.Minute), 0); err != nil {
+		t.Errorf("VerifyRequest() error = %v", err)
+	}
+	if body, _ := io.ReadAll(r.Body); string(body) != `{"roles":[]}` {
+		t.Errorf("body after VerifyRequest() = %q", body)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(r *http.Request)
+		secret []byte
+		now    time.Time
+	}{
+		{name: "wrong secret", tamper: func(*http.Request) {}, secret: []byte("other"), now: now},
+		{name: "tampered body", tamper: func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader(`{}`)) }, secret: secret, now: now},
+		{name: "other method", tamper: func(r *http.Request) { r.Method = http.MethodPut }, secret: secret, now: now},
+		{name: "other path", tamper: func(r *http.Request) { r.URL.Path = "/authz/export" }, secret: secret, now: now},
+		{name: "other query", tamper: func(r *http.Request) { r.URL.RawQuery = "dry_run=false" }, secret: secret, now: now},
+		{name: "bad timestamp", tamper: func(r *http.Request) { r.Header.Set(HeaderSignatureTimestamp, "soon") }, secret: secret, now: now},
+		{name: "missing signature", tamper: func(r *http.Request) { r.Header.Del(HeaderSignature) }, secret: secret, now: now},
+		{name: "replayed", tamper: func(*http.Request) {}, secret: secret, now: now.Add(time.Hour)},
+		{name: "from the future", tamper: func(*http.Request) {}, secret: secret, now: now.Add(-time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := signed()
+			tt.tamper(r)
+			if err := VerifyRequest(r, tt.secret, tt.now, 0); !errors.Is(err, ErrInvalidRequestSignature) {
+				t.Errorf("VerifyRequest() error = %v, want ErrInvalidRequestSignature", err)
+			}
+		})
+	}
+}
+
+func TestSignRequestWithoutBody(t *testing.T) {
+	secret := []byte("internal-secret")
+	now := time.Now()
+
+	r := httptest.NewRequest(http.MethodGet, "/system/bootstrap-status", nil)
+	if err := SignRequest(r, "", secret, now); err != nil {
+		t.Fatalf("SignRequest() error = %v", err)
+	}
+	if r.Header.Get(HeaderSignatureKeyID) != "" {
+		t.Errorf("key ID header set without a key ID")
+	}
+	if err := VerifyRequest(r, secret, now, time.Second); err != nil {
+		t.Errorf("VerifyRequest() error = %v", err)
+	}
+}
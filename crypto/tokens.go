@@ -4,6 +4,7 @@ import (
 	"crypto/ed25519"
 	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
 	"aidanwoods.dev/go-paseto"
@@ -17,13 +18,37 @@ var (
 	ErrMissingPublicKey  = errors.New("missing public key")
 )
 
+// TokenClaims are the claims of a token. Context holds custom claims, such
+// as a tenant ID or plan, added at issuance.
 type TokenClaims struct {
 	Subject      string            `json:"sub"`
 	SessionID    string            `json:"sid"`
 	Audience     string            `json:"aud"`
 	Context      map[string]string `json:"ctx,omitempty"`
 	ExpiresAt    int64             `json:"exp"`
+	IssuedAt     int64             `json:"iat,omitempty"`
 	AuthzVersion int               `json:"authz_ver,omitempty"`
+	// IssuedAtNsec is the sub-second part of the issue time, so that a token
+	// issued right after a revocation, in the same second, is told apart
+	// from those it revokes.
+	IssuedAtNsec int64 `json:"-"`
+	// Actor is the user acting as Subject in an impersonation token.
+	Actor string `json:"act,omitempty"`
+	// Scope limits the token to these permissions when set, whatever else
+	// Subject may do.
+	Scope []string `json:"scope,omitempty"`
+	// MFAPending marks a token issued when the first factor verified but a
+	// second one is still required. It is accepted only where the second
+	// factor is checked.
+	MFAPending bool `json:"mfa_pending,omitempty"`
+}
+
+// IssuedTime returns the issue time of the token, zero when it has none.
+func (c TokenClaims) IssuedTime() time.Time {
+	if c.IssuedAt <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(c.IssuedAt, c.IssuedAtNsec)
 }
 
 func GenerateToken(claims TokenClaims, privateKey ed25519.PrivateKey) (string, error) {
@@ -36,6 +61,9 @@ func GenerateToken(claims TokenClaims, privateKey ed25519.PrivateKey) (string, e
 	token.SetAudience(claims.Audience)
 	token.SetSubject(claims.Subject)
 	token.SetExpiration(time.Unix(claims.ExpiresAt, 0))
+	if claims.IssuedAt > 0 {
+		token.SetString("iat", claims.IssuedTime().UTC().Format(time.RFC3339Nano))
+	}
 
 	token.SetString("sid", claims.SessionID)
 
@@ -51,6 +79,18 @@ func GenerateToken(claims TokenClaims, privateKey ed25519.PrivateKey) (string, e
 		token.SetString("authz_ver", string(rune(claims.AuthzVersion+'0')))
 	}
 
+	if claims.Actor != "" {
+		token.SetString("act", claims.Actor)
+	}
+
+	if len(claims.Scope) > 0 {
+		token.SetString("scope", strings.Join(claims.Scope, " "))
+	}
+
+	if claims.MFAPending {
+		token.SetString("mfa", "pending")
+	}
+
 	secretKey, err := paseto.NewV4AsymmetricSecretKeyFromEd25519(privateKey)
 	if err != nil {
 		return "", err
@@ -98,6 +138,12 @@ func VerifyToken(tokenString string, publicKey ed25519.PublicKey) (TokenClaims,
 		claims.ExpiresAt = expiration.Unix()
 	}
 
+	issuedAt, err := token.GetIssuedAt()
+	if err == nil {
+		claims.IssuedAt = issuedAt.Unix()
+		claims.IssuedAtNsec = int64(issuedAt.Nanosecond())
+	}
+
 	sid, err := token.GetString("sid")
 	if err == nil {
 		claims.SessionID = sid
@@ -116,6 +162,21 @@ func VerifyToken(tokenString string, publicKey ed25519.PublicKey) (TokenClaims,
 		claims.AuthzVersion = int(authzVerStr[0] - '0')
 	}
 
+	actor, err := token.GetString("act")
+	if err == nil {
+		claims.Actor = actor
+	}
+
+	scope, err := token.GetString("scope")
+	if err == nil && scope != "" {
+		claims.Scope = strings.Fields(scope)
+	}
+
+	mfa, err := token.GetString("mfa")
+	if err == nil {
+		claims.MFAPending = mfa == "pending"
+	}
+
 	return claims, nil
 }
 
@@ -2,6 +2,7 @@ package crypto
 
 import (
 	"crypto/ed25519"
+	"slices"
 	"testing"
 	"time"
 )
@@ -50,6 +51,62 @@ func TestGenerateToken(t *testing.T) {
 			privateKey: privateKey,
 			wantErr:    nil,
 		},
+		{
+			name: "with actor",
+			claims: TokenClaims{
+				Subject:   "user-123",
+				SessionID: "session-456",
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+				Actor:     "admin-789",
+			},
+			privateKey: privateKey,
+			wantErr:    nil,
+		},
+		{
+			name: "with scope",
+			claims: TokenClaims{
+				Subject:   "user-123",
+				SessionID: "session-456",
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+				Scope:     []string{"orders:read", "orders:list"},
+			},
+			privateKey: privateKey,
+			wantErr:    nil,
+		},
+		{
+			name: "mfa pending",
+			claims: TokenClaims{
+				Subject:    "user-123",
+				SessionID:  "session-456",
+				ExpiresAt:  time.Now().Add(time.Hour).Unix(),
+				MFAPending: true,
+			},
+			privateKey: privateKey,
+			wantErr:    nil,
+		},
+		{
+			name: "with issued at",
+			claims: TokenClaims{
+				Subject:   "user-123",
+				SessionID: "session-456",
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+				IssuedAt:  time.Now().Unix(),
+			},
+			privateKey: privateKey,
+			wantErr:    nil,
+		},
+		{
+			name: "with sub-second issued at",
+			claims: TokenClaims{
+				Subject:      "user-123",
+				SessionID:    "session-456",
+				ExpiresAt:    time.Now().Add(time.Hour).Unix(),
+				IssuedAt:     time.Now().Unix(),
+				IssuedAtNsec: 123456789,
+			},
+			privateKey: privateKey,
+			wantErr:    nil,
+		},
 		{
 			name: "nil private key",
 			claims: TokenClaims{
@@ -89,6 +146,22 @@ func TestGenerateToken(t *testing.T) {
 				if claims.SessionID != tt.claims.SessionID {
 					t.Errorf("SessionID = %v, want %v", claims.SessionID, tt.claims.SessionID)
 				}
+
+				if claims.Actor != tt.claims.Actor {
+					t.Errorf("Actor = %v, want %v", claims.Actor, tt.claims.Actor)
+				}
+
+				if !slices.Equal(claims.Scope, tt.claims.Scope) {
+					t.Errorf("Scope = %v, want %v", claims.Scope, tt.claims.Scope)
+				}
+
+				if claims.MFAPending != tt.claims.MFAPending {
+					t.Errorf("MFAPending = %v, want %v", claims.MFAPending, tt.claims.MFAPending)
+				}
+
+				if claims.IssuedAt != tt.claims.IssuedAt || claims.IssuedAtNsec != tt.claims.IssuedAtNsec {
+					t.Errorf("IssuedAt = %v.%09d, want %v.%09d", claims.IssuedAt, claims.IssuedAtNsec, tt.claims.IssuedAt, tt.claims.IssuedAtNsec)
+				}
 			}
 		})
 	}
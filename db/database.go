@@ -4,14 +4,46 @@ import (
 	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
+	"sync/atomic"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/aquamarinepk/aqm/config"
 	"github.com/aquamarinepk/aqm/log"
 	"github.com/aquamarinepk/aqm/migrate"
+	"github.com/aquamarinepk/aqm/telemetry"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
+// Open opens a pgx connection pool for cfg and applies its pool settings.
+// It does not connect; call PingContext to verify the database is reachable.
+func Open(cfg config.DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("pgx", cfg.ConnectionString())
+	if err != nil {
+		return nil, err
+	}
+	ApplyPool(db, cfg.Pool)
+	return db, nil
+}
+
+// ApplyPool applies the non-zero settings of pool to db.
+func ApplyPool(db *sql.DB, pool config.PoolConfig) {
+	if pool.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	if pool.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	}
+	if pool.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	}
+}
+
 type Database struct {
 	DB            *sql.DB
 	assetsFS      embed.FS
@@ -19,6 +51,9 @@ type Database struct {
 	migrationPath string
 	cfg           *config.Config
 	log           log.Logger
+	metrics       *telemetry.Prometheus
+	poolStats     prometheus.Collector
+	migrated      atomic.Bool
 }
 
 func New(assetsFS embed.FS, engine string, cfg *config.Config, logger log.Logger) *Database {
@@ -34,8 +69,14 @@ func (d *Database) SetMigrationPath(path string) {
 	d.migrationPath = path
 }
 
+// SetMetrics exposes connection pool stats (open, in use, idle, waits) on p
+// once the database is started, labelled with the database name.
+func (d *Database) SetMetrics(p *telemetry.Prometheus) {
+	d.metrics = p
+}
+
 func (d *Database) Start(ctx context.Context) error {
-	db, err := sql.Open("pgx", d.cfg.Database.ConnectionString())
+	db, err := Open(d.cfg.Database)
 	if err != nil {
 		return fmt.Errorf("cannot open database: %w", err)
 	}
@@ -48,12 +89,25 @@ func (d *Database) Start(ctx context.Context) error {
 	d.DB = db
 	d.log.Info("Database connection established")
 
+	if d.metrics != nil {
+		if err := d.registerPoolStats(); err != nil {
+			d.log.Error("Cannot register database pool metrics", "error", err)
+		}
+	}
+
 	if err := d.ensureSchema(ctx); err != nil {
 		return fmt.Errorf("cannot ensure schema: %w", err)
 	}
 
+	if !d.cfg.Database.Migrate.Enabled {
+		d.log.Info("Migrations disabled, skipping")
+		d.migrated.Store(true)
+		return nil
+	}
+
 	migrator := migrate.New(d.assetsFS, d.engine, d.log)
 	migrator.SetDB(d.DB)
+	migrator.SetDryRun(d.cfg.Database.Migrate.DryRun)
 	if d.migrationPath != "" {
 		migrator.SetPath(d.migrationPath)
 	}
@@ -61,10 +115,16 @@ func (d *Database) Start(ctx context.Context) error {
 		return fmt.Errorf("cannot run migrations: %w", err)
 	}
 
+	d.migrated.Store(true)
 	return nil
 }
 
 func (d *Database) Stop(ctx context.Context) error {
+	if d.poolStats != nil {
+		d.metrics.Registry().Unregister(d.poolStats)
+		d.poolStats = nil
+	}
+	d.migrated.Store(false)
 	if d.DB != nil {
 		d.log.Info("Closing database connection")
 		return d.DB.Close()
@@ -76,6 +136,41 @@ func (d *Database) GetDB() *sql.DB {
 	return d.DB
 }
 
+// CheckHealth pings the database. It implements app.HealthChecker.
+func (d *Database) CheckHealth(ctx context.Context) error {
+	if d.DB == nil {
+		return errors.New("database not started")
+	}
+	return d.DB.PingContext(ctx)
+}
+
+// CheckMigrations reports whether pending migrations have been applied
+// (or skipped because migrations are disabled).
+func (d *Database) CheckMigrations(ctx context.Context) error {
+	if !d.migrated.Load() {
+		return errors.New("migrations not applied")
+	}
+	return nil
+}
+
+// HealthChecks reports the "database" and "migrations" readiness checks.
+// It implements app.HealthReporter.
+func (d *Database) HealthChecks() map[string]func(context.Context) error {
+	return map[string]func(context.Context) error{
+		"database":   d.CheckHealth,
+		"migrations": d.CheckMigrations,
+	}
+}
+
+func (d *Database) registerPoolStats() error {
+	c := collectors.NewDBStatsCollector(d.DB, d.cfg.Database.Database)
+	if err := d.metrics.Register(c); err != nil {
+		return err
+	}
+	d.poolStats = c
+	return nil
+}
+
 func (d *Database) ensureSchema(ctx context.Context) error {
 	if d.cfg.Database.Schema == "" {
 		return nil
@@ -2,17 +2,25 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"embed"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aquamarinepk/aqm/config"
 	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/telemetry"
 	"github.com/aquamarinepk/aqm/testhelper"
 )
 
 //go:embed testdata
 var testAssetsFS embed.FS
 
+func TestMain(m *testing.M) {
+	testhelper.Main(m)
+}
+
 func setupTestPostgres(t *testing.T) (*config.Config, func()) {
 	t.Helper()
 	return testhelper.SetupTestDBWithConfig(t)
@@ -43,6 +51,103 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestOpenAppliesPool(t *testing.T) {
+	db, err := Open(config.DatabaseConfig{
+		Host:     "localhost",
+		Port:     5432,
+		Database: "dev",
+		Pool:     config.PoolConfig{MaxOpenConns: 7},
+	})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	if got := db.Stats().MaxOpenConnections; got != 7 {
+		t.Errorf("MaxOpenConnections = %d, want 7", got)
+	}
+}
+
+func TestApplyPoolKeepsDefaultsForZero(t *testing.T) {
+	db, err := sql.Open("pgx", "postgres://localhost/dev")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	db.SetMaxOpenConns(3)
+	ApplyPool(db, config.PoolConfig{ConnMaxLifetime: time.Minute})
+
+	if got := db.Stats().MaxOpenConnections; got != 3 {
+		t.Errorf("MaxOpenConnections = %d, want 3", got)
+	}
+}
+
+func TestCheckHealthBeforeStart(t *testing.T) {
+	db := New(testAssetsFS, "postgres", &config.Config{}, log.NewNoopLogger())
+	if err := db.CheckHealth(context.Background()); err == nil {
+		t.Error("CheckHealth() should fail before Start")
+	}
+}
+
+func TestHealthChecksBeforeStart(t *testing.T) {
+	db := New(testAssetsFS, "postgres", &config.Config{}, log.NewNoopLogger())
+
+	checks := db.HealthChecks()
+	for _, name := range []string{"database", "migrations"} {
+		check, ok := checks[name]
+		if !ok {
+			t.Fatalf("HealthChecks() missing %s", name)
+		}
+		if err := check(context.Background()); err == nil {
+			t.Errorf("%s check should fail before Start", name)
+		}
+	}
+}
+
+func TestPoolMetrics(t *testing.T) {
+	cfg, cleanup := setupTestPostgres(t)
+	defer cleanup()
+
+	metrics := telemetry.NewPrometheus()
+	db := New(testAssetsFS, "postgres", cfg, log.NewNoopLogger())
+	db.SetMetrics(metrics)
+
+	ctx := context.Background()
+	if err := db.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	if err := db.CheckHealth(ctx); err != nil {
+		t.Errorf("CheckHealth() error = %v", err)
+	}
+	if err := db.CheckMigrations(ctx); err != nil {
+		t.Errorf("CheckMigrations() error = %v", err)
+	}
+
+	families, err := metrics.Registry().Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	found := false
+	for _, f := range families {
+		if strings.HasPrefix(f.GetName(), "go_sql_") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("pool stats should be registered after Start")
+	}
+
+	if err := db.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if err := db.Start(ctx); err != nil {
+		t.Fatalf("restart error = %v", err)
+	}
+	db.Stop(ctx)
+}
+
 func TestStartAndStop(t *testing.T) {
 	cfg, cleanup := setupTestPostgres(t)
 	defer cleanup()
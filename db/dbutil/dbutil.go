@@ -0,0 +1,148 @@
+// Package dbutil removes the boilerplate around database/sql in stores:
+// scanning rows into aggregates, checking affected rows, translating
+// driver errors into domain errors and binding named arguments.
+//
+// A store declares how to scan its aggregate and which domain errors its
+// table's failures mean, then each method is a query away:
+//
+//	var userErrors = dbutil.ErrorMap{NotFound: auth.ErrUserNotFound}
+//
+//	func (s *userStore) Get(ctx context.Context, id auth.UserID) (*auth.User, error) {
+//		return dbutil.Get(ctx, s.db, scanUser, userErrors, "SELECT ... WHERE id = $1", id)
+//	}
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PostgreSQL error codes translated by ErrorMap.
+const (
+	CodeUniqueViolation     = "23505"
+	CodeForeignKeyViolation = "23503"
+)
+
+// Querier runs queries. *sql.DB, *sql.Conn and *sql.Tx implement it, so
+// the helpers work inside transactions too.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Scanner is a row being read. *sql.Row and *sql.Rows implement it.
+type Scanner interface {
+	Scan(dest ...any) error
+}
+
+// ScanFunc reads one row into a T.
+type ScanFunc[T any] func(Scanner) (T, error)
+
+// ErrorMap translates database errors into domain errors. Constraint maps
+// are keyed by constraint name, such as "users_username_key"; the empty
+// name matches violations of any other constraint.
+type ErrorMap struct {
+	// NotFound replaces sql.ErrNoRows and zero-row results of ExecOne.
+	NotFound error
+	// Unique replaces unique violations.
+	Unique map[string]error
+	// ForeignKey replaces foreign key violations.
+	ForeignKey map[string]error
+}
+
+// Translate returns the domain error for err, or err itself when it has
+// none.
+func (m ErrorMap) Translate(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) && m.NotFound != nil {
+		return m.NotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case CodeUniqueViolation:
+		return byConstraint(m.Unique, pgErr.ConstraintName, err)
+	case CodeForeignKeyViolation:
+		return byConstraint(m.ForeignKey, pgErr.ConstraintName, err)
+	}
+	return err
+}
+
+func byConstraint(errs map[string]error, constraint string, err error) error {
+	if e, ok := errs[constraint]; ok {
+		return e
+	}
+	if e, ok := errs[""]; ok {
+		return e
+	}
+	return err
+}
+
+// IsUniqueViolation reports whether err is a PostgreSQL unique violation.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == CodeUniqueViolation
+}
+
+// Get runs a query expected to return one row and scans it. No row is
+// reported as errs.NotFound.
+func Get[T any](ctx context.Context, q Querier, scan ScanFunc[T], errs ErrorMap, query string, args ...any) (T, error) {
+	v, err := scan(q.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		var zero T
+		return zero, errs.Translate(err)
+	}
+	return v, nil
+}
+
+// Select runs a query and scans every row. It returns an empty, non-nil
+// slice when there are none.
+func Select[T any](ctx context.Context, q Querier, scan ScanFunc[T], query string, args ...any) ([]T, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []T{}
+	for rows.Next() {
+		v, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, rows.Err()
+}
+
+// Exec runs a statement and returns the number of rows it affected, with
+// errors translated by errs.
+func Exec(ctx context.Context, q Querier, errs ErrorMap, query string, args ...any) (int64, error) {
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, errs.Translate(err)
+	}
+	return result.RowsAffected()
+}
+
+// ExecOne is like Exec for statements addressing a single row, reporting
+// a statement that affected none as errs.NotFound.
+func ExecOne(ctx context.Context, q Querier, errs ErrorMap, query string, args ...any) error {
+	n, err := Exec(ctx, q, errs, query, args...)
+	if err != nil {
+		return err
+	}
+	if n == 0 && errs.NotFound != nil {
+		return errs.NotFound
+	}
+	return nil
+}
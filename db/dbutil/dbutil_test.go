@@ -0,0 +1,233 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeDriver answers every query with rows and every statement with
+// affected or err, so the helpers can be tested without a database.
+type fakeDriver struct {
+	rows      [][]driver.Value
+	affected  int64
+	err       error
+	commits   int
+	rollbacks int
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return &fakeTx{d: c.d}, nil }
+
+func (c *fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	if c.d.err != nil {
+		return nil, c.d.err
+	}
+	return &fakeRows{rows: c.d.rows}, nil
+}
+
+func (c *fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	if c.d.err != nil {
+		return nil, c.d.err
+	}
+	return driver.RowsAffected(c.d.affected), nil
+}
+
+type fakeTx struct{ d *fakeDriver }
+
+func (tx *fakeTx) Commit() error   { tx.d.commits++; return nil }
+func (tx *fakeTx) Rollback() error { tx.d.rollbacks++; return nil }
+
+type fakeRows struct {
+	rows [][]driver.Value
+	i    int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"id", "name"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.i])
+	r.i++
+	return nil
+}
+
+var driverSeq int
+
+func openFake(t *testing.T, d *fakeDriver) *sql.DB {
+	t.Helper()
+
+	driverSeq++
+	name := fmt.Sprintf("dbutil-fake-%d", driverSeq)
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type item struct {
+	ID   int64
+	Name string
+}
+
+func scanItem(row Scanner) (*item, error) {
+	it := &item{}
+	err := row.Scan(&it.ID, &it.Name)
+	return it, err
+}
+
+var (
+	errNotFound  = errors.New("not found")
+	errExists    = errors.New("already exists")
+	errNameTaken = errors.New("name taken")
+	errMissing   = errors.New("missing parent")
+)
+
+var testErrors = ErrorMap{
+	NotFound:   errNotFound,
+	Unique:     map[string]error{"items_name_key": errNameTaken, "": errExists},
+	ForeignKey: map[string]error{"": errMissing},
+}
+
+func TestTranslate(t *testing.T) {
+	other := errors.New("boom")
+	undefinedTable := &pgconn.PgError{Code: "42P01"}
+
+	tests := []struct {
+		name string
+		errs ErrorMap
+		err  error
+		want error
+	}{
+		{name: "nil", errs: testErrors, err: nil, want: nil},
+		{name: "no rows", errs: testErrors, err: sql.ErrNoRows, want: errNotFound},
+		{name: "wrapped no rows", errs: testErrors, err: fmt.Errorf("get: %w", sql.ErrNoRows), want: errNotFound},
+		{name: "no rows unmapped", errs: ErrorMap{}, err: sql.ErrNoRows, want: sql.ErrNoRows},
+		{name: "named constraint", errs: testErrors, err: &pgconn.PgError{Code: CodeUniqueViolation, ConstraintName: "items_name_key"}, want: errNameTaken},
+		{name: "other constraint", errs: testErrors, err: &pgconn.PgError{Code: CodeUniqueViolation, ConstraintName: "items_pkey"}, want: errExists},
+		{name: "foreign key", errs: testErrors, err: &pgconn.PgError{Code: CodeForeignKeyViolation, ConstraintName: "items_parent_fkey"}, want: errMissing},
+		{name: "other code", errs: testErrors, err: undefinedTable, want: undefinedTable},
+		{name: "other error", errs: testErrors, err: other, want: other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.errs.Translate(tt.err)
+			if !errors.Is(got, tt.want) {
+				t.Errorf("Translate(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	if !IsUniqueViolation(fmt.Errorf("insert: %w", &pgconn.PgError{Code: CodeUniqueViolation})) {
+		t.Error("IsUniqueViolation() = false for a wrapped unique violation")
+	}
+	if IsUniqueViolation(&pgconn.PgError{Code: CodeForeignKeyViolation}) {
+		t.Error("IsUniqueViolation() = true for a foreign key violation")
+	}
+	if IsUniqueViolation(errors.New("boom")) {
+		t.Error("IsUniqueViolation() = true for a plain error")
+	}
+}
+
+func TestGet(t *testing.T) {
+	ctx := context.Background()
+
+	db := openFake(t, &fakeDriver{rows: [][]driver.Value{{int64(1), "ann"}}})
+	got, err := Get(ctx, db, scanItem, testErrors, "SELECT id, name FROM items WHERE id = $1", 1)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.ID != 1 || got.Name != "ann" {
+		t.Errorf("Get() = %+v, want {1 ann}", got)
+	}
+
+	db = openFake(t, &fakeDriver{})
+	if _, err := Get(ctx, db, scanItem, testErrors, "SELECT id, name FROM items WHERE id = $1", 2); !errors.Is(err, errNotFound) {
+		t.Errorf("Get() missing row error = %v, want %v", err, errNotFound)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	ctx := context.Background()
+
+	db := openFake(t, &fakeDriver{rows: [][]driver.Value{{int64(1), "ann"}, {int64(2), "bob"}}})
+	got, err := Select(ctx, db, scanItem, "SELECT id, name FROM items")
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if len(got) != 2 || got[1].Name != "bob" {
+		t.Errorf("Select() = %+v, want ann and bob", got)
+	}
+
+	db = openFake(t, &fakeDriver{})
+	got, err = Select(ctx, db, scanItem, "SELECT id, name FROM items")
+	if err != nil || got == nil || len(got) != 0 {
+		t.Errorf("Select() without rows = %v, %v; want an empty slice", got, err)
+	}
+
+	boom := errors.New("boom")
+	db = openFake(t, &fakeDriver{err: boom})
+	if _, err := Select(ctx, db, scanItem, "SELECT id, name FROM items"); !errors.Is(err, boom) {
+		t.Errorf("Select() error = %v, want %v", err, boom)
+	}
+}
+
+func TestExec(t *testing.T) {
+	ctx := context.Background()
+
+	db := openFake(t, &fakeDriver{affected: 3})
+	n, err := Exec(ctx, db, testErrors, "UPDATE items SET name = $1", "x")
+	if err != nil || n != 3 {
+		t.Errorf("Exec() = %d, %v; want 3 rows", n, err)
+	}
+
+	db = openFake(t, &fakeDriver{err: &pgconn.PgError{Code: CodeUniqueViolation, ConstraintName: "items_name_key"}})
+	if _, err := Exec(ctx, db, testErrors, "INSERT INTO items (name) VALUES ($1)", "ann"); !errors.Is(err, errNameTaken) {
+		t.Errorf("Exec() error = %v, want %v", err, errNameTaken)
+	}
+}
+
+func TestExecOne(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		affected int64
+		errs     ErrorMap
+		want     error
+	}{
+		{name: "one row", affected: 1, errs: testErrors, want: nil},
+		{name: "no rows", affected: 0, errs: testErrors, want: errNotFound},
+		{name: "no rows unmapped", affected: 0, errs: ErrorMap{}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openFake(t, &fakeDriver{affected: tt.affected})
+			err := ExecOne(ctx, db, tt.errs, "DELETE FROM items WHERE id = $1", 1)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ExecOne() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package dbutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Args are named query arguments, referenced as :name.
+type Args map[string]any
+
+// Named rewrites the :name references in query as positional $n
+// parameters and returns the matching arguments. A name used more than once
+// binds to the same parameter. Quoted strings, quoted identifiers and ::
+// casts are left alone.
+func Named(query string, args Args) (string, []any, error) {
+	var (
+		b      strings.Builder
+		params []any
+		index  = map[string]int{}
+	)
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				return "", nil, fmt.Errorf("unterminated quote in query at offset %d", i)
+			}
+			b.WriteString(query[i : i+end+2])
+			i += end + 1
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			b.WriteString("::")
+			i++
+		case c == ':' && i+1 < len(query) && isNameStart(query[i+1]):
+			j := i + 1
+			for j < len(query) && isNamePart(query[j]) {
+				j++
+			}
+			name := query[i+1 : j]
+			n, ok := index[name]
+			if !ok {
+				v, found := args[name]
+				if !found {
+					return "", nil, fmt.Errorf("missing query argument %q", name)
+				}
+				params = append(params, v)
+				n = len(params)
+				index[name] = n
+			}
+			b.WriteString("$" + strconv.Itoa(n))
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), params, nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNamePart(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+// Where builds a WHERE clause from optional conditions, numbering their
+// parameters in order.
+type Where struct {
+	conds []string
+	args  []any
+}
+
+// Add appends cond, in which ? stands for arg.
+func (w *Where) Add(cond string, arg any) {
+	w.conds = append(w.conds, strings.Replace(cond, "?", w.Arg(arg), 1))
+}
+
+// Arg appends arg and returns its placeholder, for conditions and clauses
+// with more than one parameter, such as LIMIT and OFFSET.
+func (w *Where) Arg(arg any) string {
+	w.args = append(w.args, arg)
+	return "$" + strconv.Itoa(len(w.args))
+}
+
+// SQL returns the clause with a leading space, or "" without conditions.
+func (w *Where) SQL() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// Args returns the parameters added so far.
+func (w *Where) Args() []any {
+	return w.args
+}
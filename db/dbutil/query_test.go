@@ -0,0 +1,107 @@
+package dbutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNamed(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		args      Args
+		wantQuery string
+		wantArgs  []any
+		wantErr   string
+	}{
+		{
+			name:      "positional order",
+			query:     "INSERT INTO users (id, name) VALUES (:id, :name)",
+			args:      Args{"name": "ann", "id": 1},
+			wantQuery: "INSERT INTO users (id, name) VALUES ($1, $2)",
+			wantArgs:  []any{1, "ann"},
+		},
+		{
+			name:      "repeated name",
+			query:     "UPDATE users SET name = :name WHERE id = :id AND name <> :name",
+			args:      Args{"name": "ann", "id": 1},
+			wantQuery: "UPDATE users SET name = $1 WHERE id = $2 AND name <> $1",
+			wantArgs:  []any{"ann", 1},
+		},
+		{
+			name:      "casts",
+			query:     "SELECT :data::jsonb, created_at::date FROM users",
+			args:      Args{"data": "{}"},
+			wantQuery: "SELECT $1::jsonb, created_at::date FROM users",
+			wantArgs:  []any{"{}"},
+		},
+		{
+			name:      "quotes",
+			query:     `SELECT ':skip', "col:umn" FROM users WHERE id = :id`,
+			args:      Args{"id": 1},
+			wantQuery: `SELECT ':skip', "col:umn" FROM users WHERE id = $1`,
+			wantArgs:  []any{1},
+		},
+		{
+			name:      "unused args",
+			query:     "SELECT 1",
+			args:      Args{"id": 1},
+			wantQuery: "SELECT 1",
+		},
+		{
+			name:    "missing arg",
+			query:   "SELECT * FROM users WHERE id = :id",
+			args:    Args{},
+			wantErr: `missing query argument "id"`,
+		},
+		{
+			name:    "unterminated quote",
+			query:   "SELECT 'oops FROM users",
+			wantErr: "unterminated quote",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := Named(tt.query, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Named() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Named() error = %v", err)
+			}
+			if query != tt.wantQuery {
+				t.Errorf("Named() query = %q, want %q", query, tt.wantQuery)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("Named() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestWhere(t *testing.T) {
+	var empty Where
+	if empty.SQL() != "" || len(empty.Args()) != 0 {
+		t.Errorf("empty Where = %q %v, want no clause", empty.SQL(), empty.Args())
+	}
+
+	var w Where
+	w.Add("status = ?", "active")
+	w.Add("name ILIKE ?", "%ann%")
+	limit := w.Arg(10)
+
+	if got, want := w.SQL(), " WHERE status = $1 AND name ILIKE $2"; got != want {
+		t.Errorf("SQL() = %q, want %q", got, want)
+	}
+	if limit != "$3" {
+		t.Errorf("Arg() = %q, want $3", limit)
+	}
+	if got, want := w.Args(), []any{"active", "%ann%", 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Args() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type txKey struct{ db *sql.DB }
+
+// Transactor runs functions in transactions on a database. Stores opt in
+// by reading their Querier from Conn, so work done through them with the
+// context WithinTx passes on is part of the transaction.
+type Transactor struct {
+	db *sql.DB
+}
+
+// NewTransactor returns a Transactor for db.
+func NewTransactor(db *sql.DB) *Transactor {
+	return &Transactor{db: db}
+}
+
+// WithinTx runs fn in a transaction, committing it when fn returns nil and
+// rolling it back when fn fails or panics. When ctx already carries a
+// transaction on the same database, fn joins it and the outermost call
+// decides the outcome.
+func (t *Transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	key := txKey{t.db}
+	if _, ok := ctx.Value(key).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, key, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Conn returns the transaction on db carried by ctx, or db itself when
+// there is none.
+func Conn(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := ctx.Value(txKey{db}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,52 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestTransactor(t *testing.T) {
+	ctx := context.Background()
+	d := &fakeDriver{affected: 1}
+	db := openFake(t, d)
+	tx := NewTransactor(db)
+
+	if _, ok := Conn(ctx, db).(*sql.DB); !ok {
+		t.Error("Conn() outside a transaction should return the database")
+	}
+
+	err := tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, ok := Conn(ctx, db).(*sql.Tx); !ok {
+			t.Error("Conn() inside WithinTx should return the transaction")
+		}
+		if _, ok := Conn(ctx, openFake(t, &fakeDriver{})).(*sql.DB); !ok {
+			t.Error("Conn() should not hand a transaction to another database")
+		}
+		return ExecOne(ctx, Conn(ctx, db), ErrorMap{}, "DELETE FROM items WHERE id = $1", 1)
+	})
+	if err != nil || d.commits != 1 || d.rollbacks != 0 {
+		t.Errorf("WithinTx() = %v, commits %d, rollbacks %d; want one commit", err, d.commits, d.rollbacks)
+	}
+
+	boom := errors.New("boom")
+	err = tx.WithinTx(ctx, func(ctx context.Context) error {
+		return tx.WithinTx(ctx, func(ctx context.Context) error { return boom })
+	})
+	if !errors.Is(err, boom) || d.commits != 1 || d.rollbacks != 1 {
+		t.Errorf("nested WithinTx() = %v, commits %d, rollbacks %d; want one rollback", err, d.commits, d.rollbacks)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("WithinTx() should re-panic")
+			}
+		}()
+		tx.WithinTx(ctx, func(ctx context.Context) error { panic("boom") })
+	}()
+	if d.rollbacks != 2 {
+		t.Errorf("rollbacks after panic = %d, want 2", d.rollbacks)
+	}
+}
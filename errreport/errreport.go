@@ -0,0 +1,214 @@
+// Package errreport sends unexpected errors to an error tracker such as
+// Sentry, with what is known about the request and user they happened for.
+//
+// Services set the process-wide reporter once with SetDefault, typically
+// from FromConfig, and report with Capture. app.Start
+// reports components that fail to start, the auth handlers report service
+// errors answered with a 5xx, and Middleware reports panics. Until a
+// reporter is set, errors are dropped.
+package errreport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+// Error reporting drivers accepted in config.ErrorReportingConfig.Driver.
+const (
+	DriverNone   = "none"
+	DriverSentry = "sentry"
+)
+
+var ErrUnknownDriver = errors.New("unknown error reporting driver")
+
+// Reporter sends errors to an error tracker. Report must not block on the
+// tracker; implementations queue or drop.
+type Reporter interface {
+	Report(ctx context.Context, err error)
+}
+
+// ReporterFunc adapts a function to Reporter.
+type ReporterFunc func(ctx context.Context, err error)
+
+func (f ReporterFunc) Report(ctx context.Context, err error) {
+	f(ctx, err)
+}
+
+type noopReporter struct{}
+
+func (noopReporter) Report(context.Context, error) {}
+
+// NewNoopReporter returns a Reporter that drops every error.
+func NewNoopReporter() Reporter {
+	return noopReporter{}
+}
+
+// FromConfig builds the reporter selected by cfg.Driver, a noop one for
+// DriverNone.
+func FromConfig(cfg config.ErrorReportingConfig, logger log.Logger) (Reporter, error) {
+	switch cfg.Driver {
+	case DriverNone, "":
+		return NewNoopReporter(), nil
+	case DriverSentry:
+		s, err := NewSentry(SentryConfig{
+			DSN:         cfg.DSN,
+			Environment: cfg.Environment,
+			Release:     cfg.Release,
+			SampleRate:  cfg.SampleRate,
+			Logger:      logger,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDriver, cfg.Driver)
+	}
+}
+
+type holder struct{ r Reporter }
+
+var defaultReporter atomic.Pointer[holder]
+
+// SetDefault sets the reporter used by Capture; nil restores the noop one.
+func SetDefault(r Reporter) {
+	if r == nil {
+		r = NewNoopReporter()
+	}
+	defaultReporter.Store(&holder{r: r})
+}
+
+// Default returns the reporter used by Capture.
+func Default() Reporter {
+	if h := defaultReporter.Load(); h != nil {
+		return h.r
+	}
+	return noopReporter{}
+}
+
+// Capture reports err to the default reporter. Nil errors and cancellations
+// are not reported.
+func Capture(ctx context.Context, err error) {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	Default().Report(ctx, err)
+}
+
+// Event is a reported error with its context.
+type Event struct {
+	Err       error
+	Time      time.Time
+	UserID    string
+	RequestID string
+	Request   *Request
+	// Stack holds the frames of the caller of Report, innermost first.
+	Stack []runtime.Frame
+}
+
+// Request describes the request an error happened for. URL leaves out the
+// query, which may carry secrets.
+type Request struct {
+	Method   string
+	URL      string
+	RemoteIP string
+}
+
+type contextKey string
+
+const requestKey contextKey = "errreport_request"
+
+// NewEvent returns the event of err reported with ctx: the user of
+// middleware.GetUserID, the chi request ID and the request recorded by
+// Middleware. skip is the number of frames above the caller of NewEvent
+// to leave out of the stack.
+func NewEvent(ctx context.Context, err error, skip int) Event {
+	e := Event{Err: err, Time: time.Now().UTC()}
+	if ctx != nil {
+		e.UserID = middleware.GetUserID(ctx)
+		e.RequestID = chimw.GetReqID(ctx)
+		e.Request, _ = ctx.Value(requestKey).(*Request)
+	}
+
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(skip+2, pcs)])
+	for {
+		f, more := frames.Next()
+		e.Stack = append(e.Stack, f)
+		if !more {
+			break
+		}
+	}
+	return e
+}
+
+// WithRequest returns a copy of ctx recording r for the events reported
+// with it.
+func WithRequest(ctx context.Context, r *http.Request) context.Context {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIP = r.RemoteAddr
+	}
+	return context.WithValue(ctx, requestKey, &Request{
+		Method:   r.Method,
+		URL:      scheme + "://" + r.Host + r.URL.Path,
+		RemoteIP: remoteIP,
+	})
+}
+
+// Middleware records each request for the errors reported while serving it
+// and reports panics to reporter, the default one when nil, before passing
+// them on to the recovering middleware of the stack. Place it after
+// middleware.ClientIP so the client address is known.
+func Middleware(reporter Reporter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(WithRequest(r.Context(), r))
+			defer func() {
+				if v := recover(); v != nil {
+					if v != http.ErrAbortHandler {
+						rep := reporter
+						if rep == nil {
+							rep = Default()
+						}
+						rep.Report(r.Context(), &PanicError{Value: v})
+					}
+					panic(v)
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// PanicError is reported by Middleware for a recovered panic.
+type PanicError struct {
+	Value any
+}
+
+func (e *PanicError) Error() string {
+	if err, ok := e.Value.(error); ok {
+		return "panic: " + err.Error()
+	}
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
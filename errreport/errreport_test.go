@@ -0,0 +1,122 @@
+package errreport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+type recorder struct {
+	events []Event
+}
+
+func (r *recorder) Report(ctx context.Context, err error) {
+	r.events = append(r.events, NewEvent(ctx, err, 1))
+}
+
+func TestCapture(t *testing.T) {
+	rec := &recorder{}
+	SetDefault(rec)
+	defer SetDefault(nil)
+
+	Capture(context.Background(), errors.New("boom"))
+	Capture(context.Background(), nil)
+	Capture(context.Background(), context.Canceled)
+
+	if len(rec.events) != 1 || rec.events[0].Err.Error() != "boom" {
+		t.Fatalf("events = %+v, want only boom", rec.events)
+	}
+	if len(rec.events[0].Stack) == 0 || !strings.HasSuffix(rec.events[0].Stack[0].Function, "errreport.Capture") {
+		t.Errorf("stack starts at %v, want the caller of Report", rec.events[0].Stack)
+	}
+
+	SetDefault(nil)
+	Capture(context.Background(), errors.New("dropped"))
+	if len(rec.events) != 1 {
+		t.Errorf("events after SetDefault(nil) = %d, want 1", len(rec.events))
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	rec := &recorder{}
+	handler := chimw.RequestID(Middleware(rec)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.UserIDKey, "user-1")
+		if r.URL.Path == "/panic" {
+			panic("kaboom")
+		}
+		rec.Report(ctx, errors.New("store down"))
+		w.WriteHeader(http.StatusInternalServerError)
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders?token=secret", nil)
+	req.RemoteAddr = "198.51.100.1:1234"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(rec.events) != 1 {
+		t.Fatalf("events = %d, want 1", len(rec.events))
+	}
+	e := rec.events[0]
+	if e.UserID != "user-1" || e.RequestID == "" {
+		t.Errorf("user = %q, request id = %q", e.UserID, e.RequestID)
+	}
+	if e.Request == nil || e.Request.Method != http.MethodGet || e.Request.URL != "http://example.com/orders" || e.Request.RemoteIP != "198.51.100.1" {
+		t.Errorf("request = %+v", e.Request)
+	}
+
+	func() {
+		defer func() {
+			if v := recover(); v != "kaboom" {
+				t.Errorf("recovered %v, want the panic passed on", v)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	}()
+
+	var pe *PanicError
+	if len(rec.events) != 2 || !errors.As(rec.events[1].Err, &pe) || pe.Error() != "panic: kaboom" {
+		t.Errorf("events = %+v, want the panic reported", rec.events)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.ErrorReportingConfig
+		want    string
+		wantErr bool
+	}{
+		{"none", config.ErrorReportingConfig{Driver: DriverNone}, "errreport.noopReporter", false},
+		{"sentry", config.ErrorReportingConfig{Driver: DriverSentry, DSN: "https://key@sentry.example.com/1"}, "*errreport.Sentry", false},
+		{"sentry without dsn", config.ErrorReportingConfig{Driver: DriverSentry}, "", true},
+		{"unknown", config.ErrorReportingConfig{Driver: "rollbar"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := FromConfig(tt.cfg, log.NewNoopLogger())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if r != nil {
+					t.Errorf("FromConfig() = %v, want nil", r)
+				}
+				return
+			}
+			if got := fmt.Sprintf("%T", r); got != tt.want {
+				t.Errorf("FromConfig() = %s, want %s", got, tt.want)
+			}
+			if s, ok := r.(*Sentry); ok {
+				s.Stop(context.Background())
+			}
+		})
+	}
+}
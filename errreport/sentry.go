@@ -0,0 +1,294 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mathrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+)
+
+// SentryConfig configures a Sentry reporter.
+type SentryConfig struct {
+	// DSN is the client key of the Sentry project,
+	// https://<public key>@<host>/<project id>.
+	DSN         string
+	Environment string
+	Release     string
+	// ServerName defaults to the hostname.
+	ServerName string
+	// SampleRate is the fraction of errors sent, from 0 to 1. Default 1.
+	SampleRate float64
+	// QueueSize bounds the events waiting to be sent; more are dropped.
+	// Default 100.
+	QueueSize int
+	// Timeout bounds each delivery. Default 5s.
+	Timeout time.Duration
+	Client  *http.Client
+	Logger  log.Logger
+}
+
+// Sentry reports errors to Sentry through its envelope endpoint. Events are
+// sent in the background; Stop sends those still queued. It implements Stop
+// for the app lifecycle.
+type Sentry struct {
+	cfg      SentryConfig
+	endpoint string
+	auth     string
+
+	mu      sync.RWMutex
+	stopped bool
+	queue   chan Event
+	done    chan struct{}
+}
+
+// NewSentry creates a Sentry reporter for cfg and starts sending.
+func NewSentry(cfg SentryConfig) (*Sentry, error) {
+	endpoint, key, err := parseDSN(cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SampleRate <= 0 || cfg.SampleRate > 1 {
+		cfg.SampleRate = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: cfg.Timeout}
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName, _ = os.Hostname()
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNoopLogger()
+	}
+
+	s := &Sentry{
+		cfg:      cfg,
+		endpoint: endpoint,
+		auth:     "Sentry sentry_version=7, sentry_client=aqm-errreport/1.0, sentry_key=" + key,
+		queue:    make(chan Event, cfg.QueueSize),
+		done:     make(chan struct{}),
+	}
+	go s.send()
+	return s, nil
+}
+
+// parseDSN returns the envelope endpoint and public key of dsn.
+func parseDSN(dsn string) (string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid sentry dsn: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", "", errors.New("invalid sentry dsn: scheme must be http or https")
+	}
+	key := u.User.Username()
+	if key == "" {
+		return "", "", errors.New("invalid sentry dsn: public key is required")
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	if i < 0 || path[i+1:] == "" {
+		return "", "", errors.New("invalid sentry dsn: project id is required")
+	}
+	return fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, path[:i], path[i+1:]), key, nil
+}
+
+// Report queues err for delivery, dropping it when sampled out, when the
+// queue is full or after Stop.
+func (s *Sentry) Report(ctx context.Context, err error) {
+	if err == nil || mathrand.Float64() >= s.cfg.SampleRate {
+		return
+	}
+	e := NewEvent(ctx, err, 1)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.stopped {
+		return
+	}
+	select {
+	case s.queue <- e:
+	default:
+		s.cfg.Logger.Error("Sentry queue is full, dropping error", "error", err)
+	}
+}
+
+// Stop stops accepting errors and waits until those queued are sent.
+func (s *Sentry) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Sentry) send() {
+	defer close(s.done)
+	for e := range s.queue {
+		if err := s.deliver(e); err != nil {
+			s.cfg.Logger.Error("Cannot send error to Sentry", "error", err)
+		}
+	}
+}
+
+func (s *Sentry) deliver(e Event) error {
+	body, err := s.envelope(e)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("sentry responded %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Exception   sentryExceptions  `json:"exception"`
+	User        *sentryUser       `json:"user,omitempty"`
+	Request     *sentryRequest    `json:"request,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+}
+
+type sentryUser struct {
+	ID        string `json:"id,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+}
+
+type sentryRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// envelope encodes e as a Sentry envelope holding one event.
+func (s *Sentry) envelope(e Event) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	event := sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   e.Time.Format(time.RFC3339Nano),
+		Level:       "error",
+		Platform:    "go",
+		Environment: s.cfg.Environment,
+		Release:     s.cfg.Release,
+		ServerName:  s.cfg.ServerName,
+		Exception:   sentryExceptions{Values: exceptions(e)},
+	}
+	if e.UserID != "" || (e.Request != nil && e.Request.RemoteIP != "") {
+		event.User = &sentryUser{ID: e.UserID}
+		if e.Request != nil {
+			event.User.IPAddress = e.Request.RemoteIP
+		}
+	}
+	if e.Request != nil {
+		event.Request = &sentryRequest{Method: e.Request.Method, URL: e.Request.URL}
+	}
+	if e.RequestID != "" {
+		event.Tags = map[string]string{"request_id": e.RequestID}
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	header, _ := json.Marshal(map[string]string{"event_id": event.EventID, "sent_at": time.Now().UTC().Format(time.RFC3339Nano)})
+	item, _ := json.Marshal(map[string]any{"type": "event", "length": len(payload)})
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.WriteByte('\n')
+	buf.Write(item)
+	buf.WriteByte('\n')
+	buf.Write(payload)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// exceptions returns the chain of e.Err, outermost last as Sentry expects,
+// the stack of the report attached to the outermost.
+func exceptions(e Event) []sentryException {
+	var chain []sentryException
+	for err := e.Err; err != nil; err = errors.Unwrap(err) {
+		chain = append([]sentryException{{Type: reflect.TypeOf(err).String(), Value: err.Error()}}, chain...)
+	}
+
+	frames := make([]sentryFrame, 0, len(e.Stack))
+	for i := len(e.Stack) - 1; i >= 0; i-- {
+		f := e.Stack[i]
+		frames = append(frames, sentryFrame{Function: f.Function, AbsPath: f.File, Lineno: f.Line})
+	}
+	if len(frames) > 0 {
+		chain[len(chain)-1].Stacktrace = &sentryStacktrace{Frames: frames}
+	}
+	return chain
+}
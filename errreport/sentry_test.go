@@ -0,0 +1,118 @@
+package errreport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		dsn      string
+		endpoint string
+		key      string
+		wantErr  bool
+	}{
+		{"https://abc@o1.ingest.sentry.io/42", "https://o1.ingest.sentry.io/api/42/envelope/", "abc", false},
+		{"http://abc@localhost:9000/sentry/7", "http://localhost:9000/sentry/api/7/envelope/", "abc", false},
+		{"https://o1.ingest.sentry.io/42", "", "", true},
+		{"https://abc@o1.ingest.sentry.io/", "", "", true},
+		{"ftp://abc@host/1", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			endpoint, key, err := parseDSN(tt.dsn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDSN() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if endpoint != tt.endpoint || key != tt.key {
+				t.Errorf("parseDSN() = %q, %q, want %q, %q", endpoint, key, tt.endpoint, tt.key)
+			}
+		})
+	}
+}
+
+func TestSentry(t *testing.T) {
+	var mu sync.Mutex
+	var auth string
+	var events []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/42/envelope/" {
+			http.NotFound(w, r)
+			return
+		}
+		sc := bufio.NewScanner(r.Body)
+		sc.Buffer(nil, 1<<20)
+		var lines []string
+		for sc.Scan() {
+			lines = append(lines, sc.Text())
+		}
+		if len(lines) != 3 {
+			t.Errorf("envelope has %d lines, want 3", len(lines))
+			return
+		}
+		var event map[string]any
+		json.Unmarshal([]byte(lines[2]), &event)
+
+		mu.Lock()
+		defer mu.Unlock()
+		auth = r.Header.Get("X-Sentry-Auth")
+		events = append(events, event)
+	}))
+	defer srv.Close()
+
+	s, err := NewSentry(SentryConfig{DSN: strings.Replace(srv.URL, "://", "://key@", 1) + "/42", Environment: "test", Release: "1.2.3"})
+	if err != nil {
+		t.Fatalf("NewSentry() error = %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user-1")
+	ctx = WithRequest(ctx, httptest.NewRequest(http.MethodPost, "/orders", nil))
+	s.Report(ctx, fmt.Errorf("cannot save order: %w", errors.New("connection refused")))
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	s.Report(ctx, errors.New("after stop"))
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(auth, "sentry_key=key") {
+		t.Errorf("X-Sentry-Auth = %q", auth)
+	}
+	if len(events) != 1 {
+		t.Fatalf("events = %d, want 1", len(events))
+	}
+	e := events[0]
+	if e["environment"] != "test" || e["release"] != "1.2.3" || e["level"] != "error" {
+		t.Errorf("event = %v", e)
+	}
+	if user, _ := e["user"].(map[string]any); user["id"] != "user-1" || user["ip_address"] != "192.0.2.1" {
+		t.Errorf("user = %v", e["user"])
+	}
+	if req, _ := e["request"].(map[string]any); req["method"] != http.MethodPost || req["url"] != "http://example.com/orders" {
+		t.Errorf("request = %v", e["request"])
+	}
+	values := e["exception"].(map[string]any)["values"].([]any)
+	if len(values) != 2 {
+		t.Fatalf("exception chain = %v, want 2", values)
+	}
+	outer := values[1].(map[string]any)
+	if outer["value"] != "cannot save order: connection refused" || outer["stacktrace"] == nil {
+		t.Errorf("outermost exception = %v, want it last with the stack", outer)
+	}
+}
+
+func TestNewSentryInvalidDSN(t *testing.T) {
+	if _, err := NewSentry(SentryConfig{DSN: "not a dsn"}); err == nil {
+		t.Error("NewSentry() error = nil, want error")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/aquamarinepk/aqm/config"
 	"github.com/aquamarinepk/aqm/log"
 	"github.com/aquamarinepk/aqm/migrate"
+	aqmnats "github.com/aquamarinepk/aqm/nats"
 	"github.com/aquamarinepk/aqm/pubsub"
 	"github.com/aquamarinepk/aqm/pubsub/nats"
 	"github.com/go-chi/chi/v5"
@@ -28,6 +29,7 @@ type Service struct {
 	cfg    *config.Config
 	log    log.Logger
 	db     *sql.DB
+	nats   *aqmnats.Conn
 	broker *nats.Broker
 	store  Store
 }
@@ -66,16 +68,8 @@ func New(migrationsFS embed.FS, cfg *config.Config, logger log.Logger) (*Service
 	s.store = NewPostgresStore(db)
 
 	if cfg.NATS.URL != "" {
-		natsCfg := nats.Config{
-			URL:            cfg.NATS.URL,
-			MaxReconnect:   cfg.NATS.MaxReconnect,
-			ReconnectWait:  nats.DefaultConfig().ReconnectWait,
-			ConnectTimeout: nats.DefaultConfig().ConnectTimeout,
-		}
-		if natsCfg.MaxReconnect == 0 {
-			natsCfg.MaxReconnect = nats.DefaultConfig().MaxReconnect
-		}
-		s.broker = nats.NewBroker(natsCfg, logger)
+		s.nats = aqmnats.New(cfg.NATS, logger)
+		s.broker = nats.NewBrokerWithConn(s.nats, logger)
 	}
 
 	return s, nil
@@ -84,6 +78,9 @@ func New(migrationsFS embed.FS, cfg *config.Config, logger log.Logger) (*Service
 // Start initializes the service and subscribes to audit events.
 func (s *Service) Start(ctx context.Context) error {
 	if s.broker != nil {
+		if err := s.nats.Start(ctx); err != nil {
+			return fmt.Errorf("cannot connect to NATS: %w", err)
+		}
 		if err := s.broker.Start(ctx); err != nil {
 			return fmt.Errorf("cannot start NATS broker: %w", err)
 		}
@@ -106,12 +103,23 @@ func (s *Service) RegisterRoutes(r chi.Router) {
 	r.Get("/events", s.handleListEvents)
 }
 
+// HealthChecks reports the readiness of the NATS connection, if configured.
+func (s *Service) HealthChecks() map[string]func(context.Context) error {
+	if s.nats == nil {
+		return nil
+	}
+	return s.nats.HealthChecks()
+}
+
 // Stop gracefully shuts down the service and closes database connections.
 func (s *Service) Stop(ctx context.Context) error {
 	if s.broker != nil {
 		if err := s.broker.Stop(ctx); err != nil {
 			s.log.Errorf("Error stopping NATS broker: %v", err)
 		}
+		if err := s.nats.Stop(ctx); err != nil {
+			s.log.Errorf("Error draining NATS connection: %v", err)
+		}
 	}
 
 	if s.db != nil {
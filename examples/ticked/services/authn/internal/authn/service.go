@@ -7,13 +7,18 @@ import (
 	"embed"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 
+	"github.com/aquamarinepk/aqm/assets"
 	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
 	"github.com/aquamarinepk/aqm/auth/handler"
 	"github.com/aquamarinepk/aqm/auth/service"
 	"github.com/aquamarinepk/aqm/config"
 	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/mail"
+	"github.com/aquamarinepk/aqm/middleware"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -40,6 +45,13 @@ type Service struct {
 	systemHandler *handler.SystemHandler
 }
 
+// CryptoConfig holds the keys bound from the "crypto" config section.
+type CryptoConfig struct {
+	EncryptionKey   string `koanf:"encryptionkey" validate:"required"`
+	SigningKey      string `koanf:"signingkey" validate:"required"`
+	TokenPrivateKey string `koanf:"tokenprivatekey" validate:"required"`
+}
+
 // New creates a new Service with the given configuration.
 // It initializes stores (postgres or fake based on config), crypto services,
 // and HTTP handlers. Returns an error if initialization fails.
@@ -68,42 +80,32 @@ func New(migrationsFS embed.FS, cfg *config.Config, logger log.Logger) (*Service
 	}
 
 	// Initialize crypto services
-	encKeyStr := cfg.GetString("crypto.encryptionkey")
-	if encKeyStr == "" {
-		return nil, fmt.Errorf("crypto.encryptionkey is required")
+	var cryptoCfg CryptoConfig
+	if err := cfg.UnmarshalKey("crypto", &cryptoCfg); err != nil {
+		return nil, err
 	}
-	encKey, err := hex.DecodeString(encKeyStr)
+
+	encKey, err := hex.DecodeString(cryptoCfg.EncryptionKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode encryption key: %w", err)
 	}
 
-	signKeyStr := cfg.GetString("crypto.signingkey")
-	if signKeyStr == "" {
-		return nil, fmt.Errorf("crypto.signingkey is required")
-	}
-	signKey, err := hex.DecodeString(signKeyStr)
+	signKey, err := hex.DecodeString(cryptoCfg.SigningKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode signing key: %w", err)
 	}
 
-	tokenKeyStr := cfg.GetString("crypto.tokenprivatekey")
-	if tokenKeyStr == "" {
-		return nil, fmt.Errorf("crypto.tokenprivatekey is required")
-	}
-	tokenKey, err := base64.StdEncoding.DecodeString(tokenKeyStr)
+	tokenKey, err := base64.StdEncoding.DecodeString(cryptoCfg.TokenPrivateKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode token private key: %w", err)
 	}
 
-	tokenTTL, err := cfg.GetDuration("auth.tokenttl")
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse token TTL: %w", err)
-	}
-
 	passwordLength := cfg.GetIntOrDef("auth.passwordlength", 32)
 
-	s.crypto = service.NewDefaultCryptoService(encKey, signKey)
-	s.tokenGen = service.NewDefaultTokenGenerator(ed25519.PrivateKey(tokenKey), tokenTTL)
+	s.crypto = service.NewDefaultCryptoService(encKey, signKey).
+		WithPasswordParams(service.PasswordParamsFromConfig(cfg.Auth.Password)).
+		WithDerivedKeys(cfg.Auth.DerivedKeys)
+	s.tokenGen = service.NewDefaultTokenGenerator(ed25519.PrivateKey(tokenKey), cfg.Auth.TokenTTL)
 
 	// Check for dev mode - use fixed password generator for easier development
 	if cfg.AQM.DevMode {
@@ -115,6 +117,39 @@ func New(migrationsFS embed.FS, cfg *config.Config, logger log.Logger) (*Service
 
 	s.pinGen = service.NewDefaultPINGenerator()
 
+	sender, err := mail.FromConfig(cfg.Mail, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure mail: %w", err)
+	}
+	var opts []handler.Option
+	if sender != nil {
+		opts = append(opts, handler.WithMailer(mail.NewMailer(sender, nil, cfg.Mail.From)))
+	}
+	if ml := cfg.Auth.MagicLink; ml.URL != "" && sender != nil {
+		links := service.NewMagicLinks([]byte(cfg.Auth.SessionSecret), fake.NewNonceStore(), ml.TTL)
+		opts = append(opts, handler.WithMagicLinks(links, ml.URL, middleware.NewRateLimiter(ml.Limit, ml.Window)))
+	}
+
+	opts = append(opts, handler.WithEmailDomains(auth.EmailDomains{
+		Allow: cfg.Auth.EmailDomains.Allow,
+		Deny:  cfg.Auth.EmailDomains.Deny,
+	}))
+
+	allowedIPs, err := middleware.ParseAllowlist(cfg.Auth.Bootstrap.AllowedIPs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bootstrap allowlist: %w", err)
+	}
+	bootstrapGuard := handler.WithBootstrap(cfg.Auth.Bootstrap.Token, allowedIPs...)
+	opts = append(opts, bootstrapGuard)
+
+	storage, err := assets.FromConfig(cfg.Assets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure assets: %w", err)
+	}
+	if storage != nil {
+		opts = append(opts, handler.WithAvatars(storage))
+	}
+
 	// Initialize handlers
 	s.authnHandler = handler.NewAuthNHandler(
 		s.userStore,
@@ -122,6 +157,7 @@ func New(migrationsFS embed.FS, cfg *config.Config, logger log.Logger) (*Service
 		s.tokenGen,
 		s.pwdGen,
 		s.pinGen,
+		opts...,
 	)
 
 	s.authzHandler = handler.NewAuthZHandler(
@@ -133,6 +169,7 @@ func New(migrationsFS embed.FS, cfg *config.Config, logger log.Logger) (*Service
 		s.userStore,
 		s.crypto,
 		s.pwdGen,
+		bootstrapGuard,
 	)
 
 	return s, nil
@@ -147,6 +184,16 @@ func (s *Service) Start(ctx context.Context) error {
 		}
 	}
 
+	if s.cfg.Auth.DerivedKeys {
+		migrated, err := service.MigrateUserKeys(ctx, s.userStore, s.crypto)
+		if err != nil {
+			return fmt.Errorf("user key migration failed: %w", err)
+		}
+		if migrated > 0 {
+			s.logger.Infof("Moved %d users to derived encryption keys", migrated)
+		}
+	}
+
 	s.logger.Info("Service started successfully")
 	return nil
 }
@@ -173,21 +220,20 @@ func (s *Service) Stop(ctx context.Context) error {
 // bootstrap creates the superadmin user if it doesn't exist.
 // It uses the Bootstrap service function which is idempotent.
 func (s *Service) bootstrap(ctx context.Context) error {
-	user, password, err := service.Bootstrap(ctx, s.userStore, s.crypto, s.pwdGen)
+	_, password, err := service.Bootstrap(ctx, s.userStore, s.crypto, s.pwdGen)
+	if errors.Is(err, auth.ErrAlreadyBootstrapped) {
+		s.logger.Infof("Superadmin already exists: %s", service.SuperadminEmail)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	// Only log if a new superadmin was created (password is returned)
-	if password != "" {
-		s.logger.Info("============================================")
-		s.logger.Infof("Superadmin created: %s", service.SuperadminEmail)
-		s.logger.Infof("Password: %s", password)
-		s.logger.Info("CHANGE THIS PASSWORD IMMEDIATELY!")
-		s.logger.Info("============================================")
-	} else {
-		s.logger.Infof("Superadmin already exists: %s (ID: %s)", service.SuperadminEmail, user.ID)
-	}
+	s.logger.Info("============================================")
+	s.logger.Infof("Superadmin created: %s", service.SuperadminEmail)
+	s.logger.Infof("Password: %s", password)
+	s.logger.Info("CHANGE THIS PASSWORD IMMEDIATELY!")
+	s.logger.Info("============================================")
 
 	return nil
 }
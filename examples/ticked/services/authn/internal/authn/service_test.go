@@ -39,7 +39,6 @@ func validTestConfig(t *testing.T) *config.Config {
 			"crypto.encryptionkey":   "",
 			"crypto.signingkey":      "",
 			"crypto.tokenprivatekey": "",
-			"auth.tokenttl":          "24h",
 			"auth.passwordlength":    32,
 			"auth.enablebootstrap":   true,
 		}),
@@ -7,7 +7,6 @@ import (
 
 	"github.com/aquamarinepk/aqm/auth"
 	"github.com/aquamarinepk/aqm/log"
-	"github.com/google/uuid"
 )
 
 //go:embed testdata/.gitkeep
@@ -33,7 +32,7 @@ func TestNewFakeStores(t *testing.T) {
 
 	// Create a test user
 	user := &auth.User{
-		ID:       uuid.New(),
+		ID:       auth.NewUserID(),
 		Username: "testuser",
 		Status:   auth.UserStatusActive,
 	}
@@ -55,7 +54,7 @@ func TestNewFakeStores(t *testing.T) {
 
 	// Create a test role
 	role := &auth.Role{
-		ID:     uuid.New(),
+		ID:     auth.NewRoleID(),
 		Name:   "testrole",
 		Status: auth.RoleStatusActive,
 	}
@@ -32,7 +32,6 @@ func main() {
 			"crypto.encryptionkey":   "",
 			"crypto.signingkey":      "",
 			"crypto.tokenprivatekey": "",
-			"auth.tokenttl":          "24h",
 			"auth.passwordlength":    32,
 			"auth.enablebootstrap":   true,
 		}),
@@ -0,0 +1,2 @@
+-- +migrate Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS key_scheme SMALLINT NOT NULL DEFAULT 0;
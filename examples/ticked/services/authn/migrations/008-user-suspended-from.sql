@@ -0,0 +1,2 @@
+-- +migrate Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_from TEXT NOT NULL DEFAULT '';
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/handler"
 	"github.com/aquamarinepk/aqm/auth/seed"
 	"github.com/aquamarinepk/aqm/config"
 	"github.com/aquamarinepk/aqm/log"
@@ -19,6 +20,7 @@ type BootstrapService struct {
 	grantStore auth.GrantStore
 	seeder     *seed.Seeder
 	authnURL   string
+	token      string
 	httpClient *http.Client
 	log        log.Logger
 }
@@ -49,6 +51,7 @@ func NewBootstrapService(roleStore auth.RoleStore, grantStore auth.GrantStore, s
 		grantStore: grantStore,
 		seeder:     seeder,
 		authnURL:   authnURL,
+		token:      cfg.Auth.Bootstrap.Token,
 		httpClient: &http.Client{Timeout: 30 * time.Second},
 		log:        logger,
 	}
@@ -135,6 +138,9 @@ func (s *BootstrapService) triggerBootstrap(ctx context.Context) (*BootstrapResp
 	if err != nil {
 		return nil, err
 	}
+	if s.token != "" {
+		req.Header.Set(handler.BootstrapTokenHeader, s.token)
+	}
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
@@ -172,26 +178,19 @@ func (s *BootstrapService) bootstrapRoles(ctx context.Context) error {
 		{
 			Name:        "user",
 			Description: "Regular user with basic permissions",
-			Permissions: []string{"profile:read", "profile:update"},
+			Permissions: []string{"profile:read", "profile:update", "lists:read", "lists:write"},
 			CreatedBy:   "system",
 		},
 	}
 
 	for _, roleInput := range roles {
-		existing, err := s.roleStore.GetByName(ctx, roleInput.Name)
-		if err != nil && err != auth.ErrRoleNotFound {
-			return fmt.Errorf("failed to check role existence: %w", err)
-		}
-
-		if existing != nil {
-			s.log.Infof("Role already exists: name=%s id=%s", roleInput.Name, existing.ID)
-			continue
-		}
-
-		_, err = s.seeder.SeedRole(ctx, roleInput)
+		role, created, err := s.seeder.SeedRoleIfMissing(ctx, roleInput)
 		if err != nil {
 			return fmt.Errorf("failed to seed role %s: %w", roleInput.Name, err)
 		}
+		if !created {
+			s.log.Infof("Role already exists: name=%s id=%s", role.Name, role.ID)
+		}
 	}
 
 	return nil
@@ -208,19 +207,7 @@ func (s *BootstrapService) ensureSuperadminGrant(ctx context.Context, superadmin
 		return fmt.Errorf("superadmin role not found: %w", err)
 	}
 
-	grants, err := s.grantStore.GetUserGrants(ctx, superadminUsername)
-	if err != nil {
-		s.log.Errorf("Failed to check existing grants (continuing): %v", err)
-	} else {
-		for _, g := range grants {
-			if g.RoleID == role.ID {
-				s.log.Infof("Superadmin grant already exists: grant_id=%s", g.ID)
-				return nil
-			}
-		}
-	}
-
-	_, err = s.seeder.SeedGrant(ctx, seed.GrantInput{
+	grant, created, err := s.seeder.EnsureGrant(ctx, seed.GrantInput{
 		Username:   superadminUsername,
 		RoleID:     role.ID,
 		AssignedBy: "system",
@@ -228,6 +215,10 @@ func (s *BootstrapService) ensureSuperadminGrant(ctx context.Context, superadmin
 	if err != nil {
 		return fmt.Errorf("failed to create superadmin grant: %w", err)
 	}
+	if !created {
+		s.log.Infof("Superadmin grant already exists: grant_id=%s", grant.ID)
+		return nil
+	}
 
 	s.log.Infof("Superadmin grant created successfully: username=%s role_id=%s", superadminUsername, role.ID)
 	return nil
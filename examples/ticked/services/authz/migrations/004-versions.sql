@@ -0,0 +1,2 @@
+-- +migrate Up
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+-- +migrate Up
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS permission_sets JSONB NOT NULL DEFAULT '[]'::jsonb;
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS set_permissions JSONB NOT NULL DEFAULT '[]'::jsonb;
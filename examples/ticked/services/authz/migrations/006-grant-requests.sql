@@ -0,0 +1,16 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS grant_requests (
+    id UUID PRIMARY KEY,
+    username TEXT NOT NULL,
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending',
+    requested_by TEXT NOT NULL,
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    decided_by TEXT NOT NULL DEFAULT '',
+    decided_at TIMESTAMPTZ,
+    note TEXT NOT NULL DEFAULT '',
+    grant_id UUID
+);
+
+CREATE INDEX IF NOT EXISTS idx_grant_requests_status ON grant_requests(status, requested_at);
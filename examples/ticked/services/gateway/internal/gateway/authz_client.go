@@ -0,0 +1,140 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/httpclient"
+)
+
+// PermissionChecker decides whether a user holds a permission.
+type PermissionChecker interface {
+	CheckPermission(ctx context.Context, userID, permission string) (bool, error)
+}
+
+// AuthZClient checks permissions against the authz service.
+// Tokens carry the authn user ID while grants are keyed by username, so the
+// client resolves usernames through authn first. Both lookups are cached.
+type AuthZClient struct {
+	authn *httpclient.Client
+	authz *httpclient.Client
+
+	usernames *ttlCache[string]
+	decisions *ttlCache[bool]
+}
+
+// NewAuthZClient creates a permission checker backed by authn and authz.
+func NewAuthZClient(authn, authz *httpclient.Client, cacheTTL time.Duration) *AuthZClient {
+	return &AuthZClient{
+		authn:     authn,
+		authz:     authz,
+		usernames: newTTLCache[string](cacheTTL),
+		decisions: newTTLCache[bool](cacheTTL),
+	}
+}
+
+// CheckPermission implements PermissionChecker.
+func (c *AuthZClient) CheckPermission(ctx context.Context, userID, permission string) (bool, error) {
+	key := userID + ":" + permission
+	if allowed, ok := c.decisions.get(key); ok {
+		return allowed, nil
+	}
+
+	username, err := c.username(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	path := fmt.Sprintf("/users/%s/permissions/%s", url.PathEscape(username), url.PathEscape(permission))
+	resp, err := c.authz.Get(ctx, path)
+	if err != nil {
+		return false, fmt.Errorf("authz check request: %w", err)
+	}
+	if !resp.IsSuccess() {
+		return false, fmt.Errorf("authz returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		HasPermission bool `json:"has_permission"`
+	}
+	if err := resp.JSON(&result); err != nil {
+		return false, fmt.Errorf("parse authz response: %w", err)
+	}
+
+	c.decisions.set(key, result.HasPermission)
+	return result.HasPermission, nil
+}
+
+func (c *AuthZClient) username(ctx context.Context, userID string) (string, error) {
+	if username, ok := c.usernames.get(userID); ok {
+		return username, nil
+	}
+
+	resp, err := c.authn.Get(ctx, "/users/"+url.PathEscape(userID))
+	if err != nil {
+		return "", fmt.Errorf("authn user request: %w", err)
+	}
+	if !resp.IsSuccess() {
+		return "", fmt.Errorf("authn returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		User struct {
+			Username string `json:"username"`
+		} `json:"user"`
+	}
+	if err := resp.JSON(&result); err != nil {
+		return "", fmt.Errorf("parse authn response: %w", err)
+	}
+
+	c.usernames.set(userID, result.User.Username)
+	return result.User.Username, nil
+}
+
+// ttlCache is a minimal expiring map; entries are replaced on the next miss.
+type ttlCache[T any] struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]ttlEntry[T]
+}
+
+type ttlEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+func newTTLCache[T any](ttl time.Duration) *ttlCache[T] {
+	return &ttlCache[T]{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]ttlEntry[T]),
+	}
+}
+
+func (c *ttlCache[T]) get(key string) (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || c.now().After(entry.expiresAt) {
+		var zero T
+		return zero, false
+	}
+	return entry.value, true
+}
+
+func (c *ttlCache[T]) set(key string, value T) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = ttlEntry[T]{value: value, expiresAt: c.now().Add(c.ttl)}
+}
@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/httpclient"
+	"github.com/aquamarinepk/aqm/log"
+)
+
+func TestAuthZClientCheckPermission(t *testing.T) {
+	var authnCalls, authzCalls atomic.Int32
+
+	authn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authnCalls.Add(1)
+		if r.URL.Path != "/users/user-1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"user":{"id":"user-1","username":"alice"}}`))
+	}))
+	defer authn.Close()
+
+	authz := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authzCalls.Add(1)
+		switch r.URL.Path {
+		case "/users/alice/permissions/lists:read":
+			w.Write([]byte(`{"has_permission":true}`))
+		default:
+			w.Write([]byte(`{"has_permission":false}`))
+		}
+	}))
+	defer authz.Close()
+
+	logger := log.NewNoopLogger()
+	client := NewAuthZClient(
+		httpclient.New(authn.URL, logger, httpclient.WithRetryMax(0)),
+		httpclient.New(authz.URL, logger, httpclient.WithRetryMax(0)),
+		time.Minute,
+	)
+
+	ctx := context.Background()
+
+	allowed, err := client.CheckPermission(ctx, "user-1", "lists:read")
+	if err != nil || !allowed {
+		t.Fatalf("CheckPermission(lists:read) = %v, %v, want true, nil", allowed, err)
+	}
+
+	allowed, err = client.CheckPermission(ctx, "user-1", "lists:write")
+	if err != nil || allowed {
+		t.Fatalf("CheckPermission(lists:write) = %v, %v, want false, nil", allowed, err)
+	}
+
+	if _, err := client.CheckPermission(ctx, "user-1", "lists:read"); err != nil {
+		t.Fatalf("cached CheckPermission() error = %v", err)
+	}
+
+	if got := authnCalls.Load(); got != 1 {
+		t.Errorf("authn calls = %d, want 1 (username cached)", got)
+	}
+	if got := authzCalls.Load(); got != 2 {
+		t.Errorf("authz calls = %d, want 2 (decision cached)", got)
+	}
+
+	if _, err := client.CheckPermission(ctx, "unknown", "lists:read"); err == nil {
+		t.Error("CheckPermission() should fail for unknown user")
+	}
+}
+
+func TestTTLCacheExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newTTLCache[bool](time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.set("k", true)
+	if v, ok := c.get("k"); !ok || !v {
+		t.Fatalf("get() = %v, %v, want true, true", v, ok)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.get("k"); ok {
+		t.Error("get() should miss after TTL")
+	}
+}
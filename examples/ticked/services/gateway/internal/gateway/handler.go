@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler exposes the public API and forwards it to the internal services.
+type Handler struct {
+	validator middleware.SessionValidator
+	checker   PermissionChecker
+	authn     *httputil.ReverseProxy
+	ticked    *httputil.ReverseProxy
+	log       log.Logger
+}
+
+// NewHandler creates the gateway handler.
+// authnURL and tickedURL are the upstream base URLs.
+func NewHandler(validator middleware.SessionValidator, checker PermissionChecker, authnURL, tickedURL *url.URL, logger log.Logger) *Handler {
+	return &Handler{
+		validator: validator,
+		checker:   checker,
+		authn:     newProxy(authnURL, nil, logger),
+		ticked:    newProxy(tickedURL, tickedPath, logger),
+		log:       logger,
+	}
+}
+
+// RegisterRoutes registers the public API.
+//
+//	POST   /auth/signup                  -> authn (anonymous)
+//	POST   /auth/signin                  -> authn (anonymous)
+//	GET    /api/list                     -> ticked, requires lists:read
+//	POST   /api/list/items               -> ticked, requires lists:write
+//	PATCH  /api/list/items/{itemID}      -> ticked, requires lists:write
+//	DELETE /api/list/items/{itemID}      -> ticked, requires lists:write
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Post("/auth/signup", h.authn.ServeHTTP)
+	r.Post("/auth/signin", h.authn.ServeHTTP)
+
+	r.Route("/api", func(r chi.Router) {
+		r.Use(middleware.Bearer(h.validator))
+
+		r.With(h.require("lists:read")).Get("/list", h.ticked.ServeHTTP)
+		r.With(h.require("lists:write")).Post("/list/items", h.ticked.ServeHTTP)
+		r.With(h.require("lists:write")).Patch("/list/items/{itemID}", h.ticked.ServeHTTP)
+		r.With(h.require("lists:write")).Delete("/list/items/{itemID}", h.ticked.ServeHTTP)
+	})
+}
+
+// require rejects requests whose user lacks permission. Authz failures are
+// reported as 503 rather than 403 so clients can tell denial from outage.
+func (h *Handler) require(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := middleware.GetUserID(r.Context())
+
+			allowed, err := h.checker.CheckPermission(r.Context(), userID, permission)
+			if err != nil {
+				h.log.Errorf("Permission check failed for %s (%s): %v", userID, permission, err)
+				httpx.WriteError(w, r, httpx.NewError(http.StatusServiceUnavailable, "AUTHZ_UNAVAILABLE", "Authorization service unavailable"))
+				return
+			}
+			if !allowed {
+				httpx.WriteError(w, r, httpx.Forbidden("PERMISSION_DENIED", "Missing permission "+permission))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// tickedPath maps /api/list... to ticked's per-user routes, taking the user
+// from the verified token rather than the URL.
+func tickedPath(r *http.Request) string {
+	userID := url.PathEscape(middleware.GetUserID(r.Context()))
+	base := "/users/" + userID + "/list"
+
+	if itemID := chi.URLParam(r, "itemID"); itemID != "" {
+		return base + "/items/" + url.PathEscape(itemID)
+	}
+	if r.URL.Path == "/api/list/items" {
+		return base + "/items"
+	}
+	return base
+}
@@ -0,0 +1,184 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeValidator struct{}
+
+func (fakeValidator) ValidateToken(token string) (string, string, error) {
+	if token != "valid" {
+		return "", "", errors.New("invalid token")
+	}
+	return "user-1", "sess-1", nil
+}
+
+type fakeChecker struct {
+	allowed map[string]bool
+	err     error
+}
+
+func (c fakeChecker) CheckPermission(ctx context.Context, userID, permission string) (bool, error) {
+	return c.allowed[permission], c.err
+}
+
+// newUpstream mimics ticked and authn routes, echoing what reached them.
+func newUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	r := chi.NewRouter()
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream-Path", r.URL.Path)
+		w.Header().Set("X-Upstream-User", r.Header.Get(HeaderUserID))
+		w.Header().Set("X-Upstream-Request", r.Header.Get(HeaderRequestID))
+		w.WriteHeader(http.StatusOK)
+	}
+	r.Post("/auth/signin", echo)
+	r.Route("/users/{userID}/list", func(r chi.Router) {
+		r.Get("/", echo)
+		r.Post("/items", echo)
+		r.Route("/items/{itemID}", func(r chi.Router) {
+			r.Patch("/", echo)
+			r.Delete("/", echo)
+		})
+	})
+
+	return httptest.NewServer(r)
+}
+
+func newTestRouter(t *testing.T, upstream string, checker PermissionChecker) chi.Router {
+	t.Helper()
+
+	target, err := url.Parse(upstream)
+	if err != nil {
+		t.Fatalf("cannot parse upstream URL: %v", err)
+	}
+
+	h := NewHandler(fakeValidator{}, checker, target, target, log.NewNoopLogger())
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	h.RegisterRoutes(r)
+	return r
+}
+
+func TestHandlerRoutes(t *testing.T) {
+	upstream := newUpstream(t)
+	defer upstream.Close()
+
+	checker := fakeChecker{allowed: map[string]bool{"lists:read": true, "lists:write": true}}
+	r := newTestRouter(t, upstream.URL, checker)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		token        string
+		spoofUser    string
+		wantStatus   int
+		wantPath     string
+		wantUpstream string
+	}{
+		{"anonymous signin", http.MethodPost, "/auth/signin", "", "", http.StatusOK, "/auth/signin", ""},
+		{"get list", http.MethodGet, "/api/list", "valid", "", http.StatusOK, "/users/user-1/list", "user-1"},
+		{"add item", http.MethodPost, "/api/list/items", "valid", "", http.StatusOK, "/users/user-1/list/items", "user-1"},
+		{"update item", http.MethodPatch, "/api/list/items/item-9", "valid", "", http.StatusOK, "/users/user-1/list/items/item-9", "user-1"},
+		{"delete item", http.MethodDelete, "/api/list/items/item-9", "valid", "", http.StatusOK, "/users/user-1/list/items/item-9", "user-1"},
+		{"spoofed user header replaced", http.MethodGet, "/api/list", "valid", "admin", http.StatusOK, "/users/user-1/list", "user-1"},
+		{"missing token", http.MethodGet, "/api/list", "", "", http.StatusUnauthorized, "", ""},
+		{"invalid token", http.MethodGet, "/api/list", "forged", "", http.StatusUnauthorized, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+			req.Header.Set("X-Request-ID", "req-1")
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.spoofUser != "" {
+				req.Header.Set(HeaderUserID, tt.spoofUser)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := rec.Header().Get("X-Upstream-Path"); got != tt.wantPath {
+				t.Errorf("upstream path = %q, want %q", got, tt.wantPath)
+			}
+			if got := rec.Header().Get("X-Upstream-User"); got != tt.wantUpstream {
+				t.Errorf("upstream user = %q, want %q", got, tt.wantUpstream)
+			}
+			if got := rec.Header().Get("X-Upstream-Request"); got != "req-1" {
+				t.Errorf("upstream request id = %q, want %q", got, "req-1")
+			}
+		})
+	}
+}
+
+func TestHandlerPermissions(t *testing.T) {
+	upstream := newUpstream(t)
+	defer upstream.Close()
+
+	tests := []struct {
+		name       string
+		checker    fakeChecker
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"read allowed", fakeChecker{allowed: map[string]bool{"lists:read": true}}, http.MethodGet, "/api/list", http.StatusOK},
+		{"write denied", fakeChecker{allowed: map[string]bool{"lists:read": true}}, http.MethodPost, "/api/list/items", http.StatusForbidden},
+		{"authz unavailable", fakeChecker{err: errors.New("connection refused")}, http.MethodGet, "/api/list", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(t, upstream.URL, tt.checker)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer valid")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHandlerUpstreamDown(t *testing.T) {
+	upstream := newUpstream(t)
+	upstream.Close()
+
+	r := newTestRouter(t, upstream.URL, fakeChecker{allowed: map[string]bool{"lists:read": true}})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/list", nil)
+	req.Header.Set("Authorization", "Bearer valid")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+	if !strings.Contains(rec.Body.String(), "UPSTREAM_UNAVAILABLE") {
+		t.Errorf("body = %q, want UPSTREAM_UNAVAILABLE", rec.Body.String())
+	}
+}
@@ -0,0 +1,49 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+// Headers forwarded to upstream services so they can correlate and attribute requests.
+const (
+	HeaderRequestID = "X-Request-ID"
+	HeaderUserID    = "X-User-ID"
+)
+
+// newProxy creates a reverse proxy to target. rewrite maps the inbound
+// request path to the upstream path; nil keeps the path unchanged.
+func newProxy(target *url.URL, rewrite func(r *http.Request) string, logger log.Logger) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+
+			if rewrite != nil {
+				pr.Out.URL.Path = strings.TrimSuffix(target.Path, "/") + rewrite(pr.In)
+				pr.Out.URL.RawPath = ""
+			}
+
+			if id := middleware.GetRequestID(pr.In.Context()); id != "" {
+				pr.Out.Header.Set(HeaderRequestID, id)
+			}
+
+			// Never trust a client-supplied identity header.
+			pr.Out.Header.Del(HeaderUserID)
+			if userID := middleware.GetUserID(pr.In.Context()); userID != "" {
+				pr.Out.Header.Set(HeaderUserID, userID)
+			}
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			logger.Errorf("Upstream %s unavailable: %v", target.Host, err)
+			httpx.WriteError(w, r, httpx.NewError(http.StatusBadGateway, "UPSTREAM_UNAVAILABLE", fmt.Sprintf("%s is unavailable", target.Host)))
+		},
+	}
+}
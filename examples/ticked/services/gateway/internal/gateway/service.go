@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/httpclient"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/preflight"
+	"github.com/go-chi/chi/v5"
+)
+
+const defaultCacheTTL = 30 * time.Second
+
+type Service struct {
+	cfg *config.Config
+	log log.Logger
+
+	authnURL  string
+	authzURL  string
+	tickedURL string
+
+	handler *Handler
+}
+
+func New(cfg *config.Config, logger log.Logger) (*Service, error) {
+	s := &Service{
+		cfg:       cfg,
+		log:       logger,
+		authnURL:  cfg.GetStringOrDef("services.authn.url", "http://localhost:8082"),
+		authzURL:  cfg.GetStringOrDef("services.authz.url", "http://localhost:8083"),
+		tickedURL: cfg.GetStringOrDef("services.ticked.url", "http://localhost:8084"),
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(cfg.GetString("crypto.tokenpublickey"))
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("crypto.tokenpublickey must be a base64 Ed25519 public key")
+	}
+
+	authnURL, err := url.Parse(s.authnURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid services.authn.url: %w", err)
+	}
+	tickedURL, err := url.Parse(s.tickedURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid services.ticked.url: %w", err)
+	}
+
+	checker := NewAuthZClient(
+		httpclient.New(s.authnURL, logger),
+		httpclient.New(s.authzURL, logger),
+		cfg.GetDurationOrDef("authz.cachettl", defaultCacheTTL),
+	)
+
+	validator := middleware.NewTokenValidator(ed25519.PublicKey(publicKey))
+	s.handler = NewHandler(validator, checker, authnURL, tickedURL, logger)
+
+	return s, nil
+}
+
+func (s *Service) Start(ctx context.Context) error {
+	if s.cfg.GetBoolOrDef("preflight.enabled", true) {
+		checker := preflight.New(s.log)
+		checker.Add(preflight.HTTPCheck("authn", s.authnURL+"/health"))
+		checker.Add(preflight.HTTPCheck("authz", s.authzURL+"/health"))
+		checker.Add(preflight.HTTPCheck("ticked", s.tickedURL+"/health"))
+
+		if err := checker.RunAll(ctx); err != nil {
+			return fmt.Errorf("preflight checks failed: %w", err)
+		}
+	}
+
+	s.log.Infof("Gateway service started successfully")
+	return nil
+}
+
+func (s *Service) Stop(ctx context.Context) error {
+	s.log.Infof("Gateway service stopped")
+	return nil
+}
+
+func (s *Service) RegisterRoutes(r chi.Router) {
+	s.handler.RegisterRoutes(r)
+}
@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/telemetry"
+)
+
+const traceparentHeader = "Traceparent"
+
+type traceKey struct{}
+
+// LogTracer is a telemetry.Tracer that writes finished spans to the logger.
+// It keeps the example dependency-free; swap in an OpenTelemetry-backed tracer in production.
+type LogTracer struct {
+	log log.Logger
+}
+
+// NewLogTracer creates a tracer that logs spans at debug level.
+func NewLogTracer(logger log.Logger) *LogTracer {
+	return &LogTracer{log: logger}
+}
+
+// Start implements telemetry.Tracer.
+func (t *LogTracer) Start(ctx context.Context, name string, attrs map[string]any) (context.Context, telemetry.Span) {
+	return ctx, &logSpan{log: t.log, name: name, attrs: attrs, traceID: TraceID(ctx), start: time.Now()}
+}
+
+type logSpan struct {
+	log     log.Logger
+	name    string
+	attrs   map[string]any
+	traceID string
+	start   time.Time
+}
+
+func (s *logSpan) End(err error) {
+	s.log.Debugf("span=%s trace_id=%s duration=%s attrs=%v err=%v", s.name, s.traceID, time.Since(s.start), s.attrs, err)
+}
+
+// Tracing starts a span per request and propagates a W3C traceparent header,
+// generating one when the client did not send it. Proxied requests forward it upstream.
+func Tracing(tracer telemetry.Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			traceID := parseTraceID(r.Header.Get(traceparentHeader))
+			if traceID == "" {
+				traceID = randomHex(16)
+			}
+			r.Header.Set(traceparentHeader, "00-"+traceID+"-"+randomHex(8)+"-01")
+
+			ctx := context.WithValue(r.Context(), traceKey{}, traceID)
+			ctx, span := tracer.Start(ctx, r.Method+" "+r.URL.Path, map[string]any{
+				"http.method": r.Method,
+				"http.path":   r.URL.Path,
+			})
+			defer span.End(nil)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// TraceID returns the trace ID carried by ctx, if any.
+func TraceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceKey{}).(string)
+	return id
+}
+
+func parseTraceID(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 {
+		return ""
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return ""
+	}
+	return parts[1]
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,48 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/log"
+)
+
+func TestTracing(t *testing.T) {
+	var gotTraceID, gotHeader string
+	handler := Tracing(NewLogTracer(log.NewNoopLogger()))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTraceID = TraceID(r.Context())
+		gotHeader = r.Header.Get(traceparentHeader)
+	}))
+
+	tests := []struct {
+		name        string
+		traceparent string
+		wantTraceID string
+	}{
+		{"propagates incoming trace", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"generates trace when missing", "", ""},
+		{"replaces malformed trace", "garbage", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/list", nil)
+			if tt.traceparent != "" {
+				req.Header.Set(traceparentHeader, tt.traceparent)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if len(gotTraceID) != 32 {
+				t.Fatalf("trace ID = %q, want 32 hex chars", gotTraceID)
+			}
+			if tt.wantTraceID != "" && gotTraceID != tt.wantTraceID {
+				t.Errorf("trace ID = %q, want %q", gotTraceID, tt.wantTraceID)
+			}
+			if !strings.HasPrefix(gotHeader, "00-"+gotTraceID+"-") {
+				t.Errorf("traceparent = %q, want to carry trace ID %q", gotHeader, gotTraceID)
+			}
+		})
+	}
+}
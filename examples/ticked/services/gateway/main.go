@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aquamarinepk/aqm/app"
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/examples/ticked/services/gateway/internal/gateway"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/telemetry"
+)
+
+const (
+	name    = "gateway"
+	version = "0.1.0"
+)
+
+func main() {
+	logger := log.NewLogger("info")
+
+	cfg, err := config.New(logger,
+		config.WithPrefix("GATEWAY_"),
+		config.WithFile("config.yaml"),
+	)
+	if err != nil {
+		logger.Errorf("Cannot load config: %v", err)
+		os.Exit(1)
+	}
+	logger = cfg.Log.NewLogger().With("service", name)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	profile := app.ProfilePublic()
+	profile.ClientIP, err = app.ClientIPFromConfig(cfg.Server.ClientIP)
+	if err != nil {
+		logger.Errorf("Cannot configure client IP: %v", err)
+		os.Exit(1)
+	}
+
+	// Middlewares must be registered before any route.
+	router := app.NewRouter(logger, app.WithProfile(profile))
+	router.Use(
+		middleware.RequestID,
+		middleware.RequestLogger(logger),
+		gateway.Tracing(gateway.NewLogTracer(logger)),
+		telemetry.MetricsMiddleware(telemetry.NoopMetrics{}),
+	)
+	app.ApplyRouterOptions(router,
+		app.WithPing(),
+		app.WithHealthChecks(name, version),
+	)
+
+	var deps []any
+
+	svc, err := gateway.New(cfg, logger)
+	if err != nil {
+		logger.Errorf("Cannot create service: %v", err)
+		os.Exit(1)
+	}
+
+	deps = append(deps, svc)
+
+	starts, stops, registrars := app.Setup(ctx, router, deps...)
+
+	if err := app.Start(ctx, logger, starts, stops, registrars, router); err != nil {
+		logger.Errorf("Cannot start %s(%s): %v", name, version, err)
+		os.Exit(1)
+	}
+
+	logger.Infof("%s(%s) started successfully", name, version)
+
+	go func() {
+		logger.Infof("Server listening on %s", cfg.Server.Port)
+		if err := app.ServeProfile(router, cfg.Server.Port, profile); err != nil {
+			logger.Errorf("Server error: %v", err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+	<-stop
+
+	logger.Infof("Shutting down %s(%s)...", name, version)
+	cancel()
+
+	for i := len(stops) - 1; i >= 0; i-- {
+		if err := stops[i](context.Background()); err != nil {
+			logger.Errorf("Error stopping component: %v", err)
+		}
+	}
+
+	fmt.Println("Goodbye!")
+}
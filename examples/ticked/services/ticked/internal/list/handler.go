@@ -1,11 +1,11 @@
 package list
 
 import (
-	"encoding/json"
 	"errors"
 	"net/http"
 
 	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/httpx"
 	"github.com/aquamarinepk/aqm/log"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -45,23 +45,23 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 func (h *Handler) handleGetList(w http.ResponseWriter, r *http.Request) {
 	userID, err := parseUserID(r)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_USER_ID", err.Error())
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_USER_ID", err.Error()))
 		return
 	}
 
 	list, err := h.service.GetList(r.Context(), userID)
 	if err != nil {
-		h.handleDomainError(w, err)
+		h.handleDomainError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, list)
+	httpx.WriteJSON(w, http.StatusOK, list)
 }
 
 func (h *Handler) handleAddItem(w http.ResponseWriter, r *http.Request) {
 	userID, err := parseUserID(r)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_USER_ID", err.Error())
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_USER_ID", err.Error()))
 		return
 	}
 
@@ -69,30 +69,30 @@ func (h *Handler) handleAddItem(w http.ResponseWriter, r *http.Request) {
 		Text string `json:"text"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_PAYLOAD", "Malformed JSON payload")
+	if err := httpx.Bind(r, &payload); err != nil {
+		httpx.WriteError(w, r, err)
 		return
 	}
 
 	list, err := h.service.AddItem(r.Context(), userID, payload.Text)
 	if err != nil {
-		h.handleDomainError(w, err)
+		h.handleDomainError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, list)
+	httpx.WriteJSON(w, http.StatusCreated, list)
 }
 
 func (h *Handler) handleUpdateItem(w http.ResponseWriter, r *http.Request) {
 	userID, err := parseUserID(r)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_USER_ID", err.Error())
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_USER_ID", err.Error()))
 		return
 	}
 
 	itemID, err := parseItemID(r)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_ITEM_ID", err.Error())
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_ITEM_ID", err.Error()))
 		return
 	}
 
@@ -101,74 +101,59 @@ func (h *Handler) handleUpdateItem(w http.ResponseWriter, r *http.Request) {
 		Completed *bool   `json:"completed"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_PAYLOAD", "Malformed JSON payload")
+	if err := httpx.Bind(r, &payload); err != nil {
+		httpx.WriteError(w, r, err)
 		return
 	}
 
 	list, err := h.service.UpdateItem(r.Context(), userID, itemID, payload.Text, payload.Completed)
 	if err != nil {
-		h.handleDomainError(w, err)
+		h.handleDomainError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, list)
+	httpx.WriteJSON(w, http.StatusOK, list)
 }
 
 func (h *Handler) handleRemoveItem(w http.ResponseWriter, r *http.Request) {
 	userID, err := parseUserID(r)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_USER_ID", err.Error())
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_USER_ID", err.Error()))
 		return
 	}
 
 	itemID, err := parseItemID(r)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "INVALID_ITEM_ID", err.Error())
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_ITEM_ID", err.Error()))
 		return
 	}
 
 	list, err := h.service.RemoveItem(r.Context(), userID, itemID)
 	if err != nil {
-		h.handleDomainError(w, err)
+		h.handleDomainError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, list)
+	httpx.WriteJSON(w, http.StatusOK, list)
 }
 
-func (h *Handler) handleDomainError(w http.ResponseWriter, err error) {
+func (h *Handler) handleDomainError(w http.ResponseWriter, r *http.Request, err error) {
 	switch {
 	case errors.Is(err, ErrNotFound):
-		writeError(w, http.StatusNotFound, "LIST_NOT_FOUND", "List not found")
+		httpx.WriteError(w, r, httpx.NotFound("LIST_NOT_FOUND", "List not found"))
 	case errors.Is(err, ErrItemNotFound):
-		writeError(w, http.StatusNotFound, "ITEM_NOT_FOUND", "Item not found")
+		httpx.WriteError(w, r, httpx.NotFound("ITEM_NOT_FOUND", "Item not found"))
 	case errors.Is(err, ErrItemTextEmpty):
-		writeError(w, http.StatusBadRequest, "ITEM_TEXT_EMPTY", err.Error())
+		httpx.WriteError(w, r, httpx.BadRequest("ITEM_TEXT_EMPTY", err.Error()))
 	case errors.Is(err, ErrItemTextTooLong):
-		writeError(w, http.StatusBadRequest, "ITEM_TEXT_TOO_LONG", err.Error())
+		httpx.WriteError(w, r, httpx.BadRequest("ITEM_TEXT_TOO_LONG", err.Error()))
 	default:
-		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		httpx.WriteError(w, r, httpx.Internal("Internal server error"))
 	}
 }
 
 // Helper functions
 
-type errorResponse struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-}
-
-func writeJSON(w http.ResponseWriter, status int, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
-}
-
-func writeError(w http.ResponseWriter, status int, code, message string) {
-	writeJSON(w, status, errorResponse{Code: code, Message: message})
-}
-
 func parseUserID(r *http.Request) (uuid.UUID, error) {
 	return uuid.Parse(chi.URLParam(r, "userID"))
 }
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/aquamarinepk/aqm/httpx"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
@@ -144,7 +145,7 @@ func TestHandlerGetList(t *testing.T) {
 			}
 
 			if tt.wantCode != "" {
-				var resp errorResponse
+				var resp httpx.ErrorResponse
 				json.NewDecoder(rec.Body).Decode(&resp)
 				if resp.Code != tt.wantCode {
 					t.Errorf("handleGetList() code = %s, want %s", resp.Code, tt.wantCode)
@@ -229,7 +230,7 @@ func TestHandlerAddItem(t *testing.T) {
 			payload:    "invalid json",
 			service:    &testService{},
 			wantStatus: http.StatusBadRequest,
-			wantCode:   "INVALID_PAYLOAD",
+			wantCode:   "INVALID_REQUEST",
 		},
 	}
 
@@ -252,7 +253,7 @@ func TestHandlerAddItem(t *testing.T) {
 			}
 
 			if tt.wantCode != "" {
-				var resp errorResponse
+				var resp httpx.ErrorResponse
 				json.NewDecoder(rec.Body).Decode(&resp)
 				if resp.Code != tt.wantCode {
 					t.Errorf("handleAddItem() code = %s, want %s", resp.Code, tt.wantCode)
@@ -349,7 +350,7 @@ func TestHandlerUpdateItem(t *testing.T) {
 			payload:    "invalid json",
 			service:    &testService{},
 			wantStatus: http.StatusBadRequest,
-			wantCode:   "INVALID_PAYLOAD",
+			wantCode:   "INVALID_REQUEST",
 		},
 	}
 
@@ -372,7 +373,7 @@ func TestHandlerUpdateItem(t *testing.T) {
 			}
 
 			if tt.wantCode != "" {
-				var resp errorResponse
+				var resp httpx.ErrorResponse
 				json.NewDecoder(rec.Body).Decode(&resp)
 				if resp.Code != tt.wantCode {
 					t.Errorf("handleUpdateItem() code = %s, want %s", resp.Code, tt.wantCode)
@@ -463,7 +464,7 @@ func TestHandlerRemoveItem(t *testing.T) {
 			}
 
 			if tt.wantCode != "" {
-				var resp errorResponse
+				var resp httpx.ErrorResponse
 				json.NewDecoder(rec.Body).Decode(&resp)
 				if resp.Code != tt.wantCode {
 					t.Errorf("handleRemoveItem() code = %s, want %s", resp.Code, tt.wantCode)
@@ -10,6 +10,7 @@ import (
 	"github.com/aquamarinepk/aqm/examples/ticked/services/ticked/internal/list"
 	"github.com/aquamarinepk/aqm/log"
 	"github.com/aquamarinepk/aqm/migrate"
+	aqmnats "github.com/aquamarinepk/aqm/nats"
 	"github.com/aquamarinepk/aqm/pubsub/nats"
 	"github.com/go-chi/chi/v5"
 	_ "github.com/lib/pq"
@@ -20,6 +21,7 @@ type Service struct {
 	cfg    *config.Config
 	log    log.Logger
 	db     *sql.DB
+	nats   *aqmnats.Conn
 	broker *nats.Broker
 
 	listHandler *list.Handler
@@ -64,16 +66,8 @@ func New(migrationsFS embed.FS, cfg *config.Config, logger log.Logger) (*Service
 
 	// Initialize NATS broker if configured using static config
 	if cfg.NATS.URL != "" {
-		natsCfg := nats.Config{
-			URL:            cfg.NATS.URL,
-			MaxReconnect:   cfg.NATS.MaxReconnect,
-			ReconnectWait:  nats.DefaultConfig().ReconnectWait,
-			ConnectTimeout: nats.DefaultConfig().ConnectTimeout,
-		}
-		if natsCfg.MaxReconnect == 0 {
-			natsCfg.MaxReconnect = nats.DefaultConfig().MaxReconnect
-		}
-		s.broker = nats.NewBroker(natsCfg, logger)
+		s.nats = aqmnats.New(cfg.NATS, logger)
+		s.broker = nats.NewBrokerWithConn(s.nats, logger)
 	}
 
 	// Initialize service and handler
@@ -87,6 +81,9 @@ func New(migrationsFS embed.FS, cfg *config.Config, logger log.Logger) (*Service
 func (s *Service) Start(ctx context.Context) error {
 	// Start NATS broker if configured
 	if s.broker != nil {
+		if err := s.nats.Start(ctx); err != nil {
+			return fmt.Errorf("cannot connect to NATS: %w", err)
+		}
 		if err := s.broker.Start(ctx); err != nil {
 			return fmt.Errorf("cannot start NATS broker: %w", err)
 		}
@@ -102,12 +99,23 @@ func (s *Service) RegisterRoutes(r chi.Router) {
 	s.listHandler.RegisterRoutes(r)
 }
 
+// HealthChecks reports the readiness of the NATS connection, if configured.
+func (s *Service) HealthChecks() map[string]func(context.Context) error {
+	if s.nats == nil {
+		return nil
+	}
+	return s.nats.HealthChecks()
+}
+
 // Stop gracefully shuts down the service and closes database connections.
 func (s *Service) Stop(ctx context.Context) error {
 	if s.broker != nil {
 		if err := s.broker.Stop(ctx); err != nil {
 			s.log.Errorf("Error stopping NATS broker: %v", err)
 		}
+		if err := s.nats.Stop(ctx); err != nil {
+			s.log.Errorf("Error draining NATS connection: %v", err)
+		}
 	}
 
 	if s.db != nil {
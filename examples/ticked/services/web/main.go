@@ -38,17 +38,20 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	tmplMgr := web.NewTemplateManager(app.DevFS(assetsFS, ".", cfg.AQM.DevMode), logger)
+
 	router := app.NewRouter(logger)
+	app.ApplyRouterOptions(router, app.WithDefaultInternalMiddlewares())
+	if cfg.AQM.DevMode {
+		app.ApplyRouterOptions(router, app.WithDevReload("assets", tmplMgr))
+	}
 	app.ApplyRouterOptions(router,
-		app.WithDefaultInternalMiddlewares(),
 		app.WithPing(),
 		app.WithDebugRoutes(),
 		app.WithHealthChecks(name, version),
 	)
 
 	var deps []any
-
-	tmplMgr := web.NewTemplateManager(assetsFS, logger)
 	deps = append(deps, tmplMgr)
 
 	svc, err := websvc.New(tmplMgr, cfg, logger)
@@ -0,0 +1,43 @@
+// Package geoip resolves client addresses to locations.
+//
+// A Locator maps an address to a Location. Reader implements it over a
+// MaxMind DB file such as GeoLite2-City, GeoIP2-City or GeoLite2-ASN; the
+// whole database is read into memory when opened.
+package geoip
+
+import (
+	"errors"
+	"net/netip"
+)
+
+var (
+	ErrInvalidDatabase = errors.New("invalid geoip database")
+)
+
+// Location is what a database knows about an address. Fields the database
+// does not carry are left empty; names are in English.
+type Location struct {
+	CountryCode string  `json:"country_code,omitempty"`
+	Country     string  `json:"country,omitempty"`
+	Region      string  `json:"region,omitempty"`
+	City        string  `json:"city,omitempty"`
+	Latitude    float64 `json:"latitude,omitempty"`
+	Longitude   float64 `json:"longitude,omitempty"`
+	TimeZone    string  `json:"time_zone,omitempty"`
+	ASN         uint32  `json:"asn,omitempty"`
+	ASOrg       string  `json:"as_org,omitempty"`
+}
+
+// Locator resolves addresses. Locate returns a nil Location without error
+// when the address is not in the database.
+type Locator interface {
+	Locate(ip netip.Addr) (*Location, error)
+}
+
+// LocatorFunc adapts a function to the Locator interface.
+type LocatorFunc func(ip netip.Addr) (*Location, error)
+
+// Locate implements Locator.
+func (f LocatorFunc) Locate(ip netip.Addr) (*Location, error) {
+	return f(ip)
+}
@@ -0,0 +1,404 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+)
+
+// metadataMarker precedes the metadata map at the end of a MaxMind DB file.
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// Data section types of the MaxMind DB format.
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// maxDepth bounds nesting in the data section so a corrupt file cannot
+// recurse without end.
+const maxDepth = 32
+
+// Metadata describes a MaxMind DB file.
+type Metadata struct {
+	DatabaseType string
+	IPVersion    int
+	NodeCount    uint
+	RecordSize   uint
+	BuildEpoch   uint64
+}
+
+// Reader looks addresses up in a MaxMind DB file held in memory. It is
+// safe for concurrent use.
+type Reader struct {
+	meta      Metadata
+	tree      []byte
+	data      []byte
+	ipv4Start uint
+}
+
+// Open reads the MaxMind DB file at path.
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open geoip database: %w", err)
+	}
+	return NewReader(buf)
+}
+
+// NewReader parses a MaxMind DB file held in buf. The Reader keeps buf.
+func NewReader(buf []byte) (*Reader, error) {
+	at := bytes.LastIndex(buf, metadataMarker)
+	if at < 0 {
+		return nil, fmt.Errorf("%w: no metadata", ErrInvalidDatabase)
+	}
+
+	raw, _, err := decoder{data: buf[at+len(metadataMarker):]}.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: metadata: %v", ErrInvalidDatabase, err)
+	}
+	fields, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", ErrInvalidDatabase)
+	}
+	meta := Metadata{
+		DatabaseType: str(fields["database_type"]),
+		IPVersion:    int(uintValue(fields["ip_version"])),
+		NodeCount:    uint(uintValue(fields["node_count"])),
+		RecordSize:   uint(uintValue(fields["record_size"])),
+		BuildEpoch:   uintValue(fields["build_epoch"]),
+	}
+	if major := uintValue(fields["binary_format_major_version"]); major != 2 {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrInvalidDatabase, major)
+	}
+	switch meta.RecordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidDatabase, meta.RecordSize)
+	}
+	if meta.IPVersion != 4 && meta.IPVersion != 6 {
+		return nil, fmt.Errorf("%w: unsupported ip version %d", ErrInvalidDatabase, meta.IPVersion)
+	}
+
+	treeSize := meta.NodeCount * meta.RecordSize / 4
+	if treeSize+16 > uint(at) {
+		return nil, fmt.Errorf("%w: search tree exceeds file", ErrInvalidDatabase)
+	}
+
+	r := &Reader{
+		meta: meta,
+		tree: buf[:treeSize],
+		data: buf[treeSize+16 : at],
+	}
+	if meta.IPVersion == 6 {
+		for i := 0; i < 96 && r.ipv4Start < meta.NodeCount; i++ {
+			r.ipv4Start = r.record(r.ipv4Start, 0)
+		}
+	}
+	return r, nil
+}
+
+// Metadata returns the database's metadata.
+func (r *Reader) Metadata() Metadata {
+	return r.meta
+}
+
+// Locate implements Locator. IPv6 addresses are never found in an IPv4
+// database.
+func (r *Reader) Locate(ip netip.Addr) (*Location, error) {
+	record, err := r.Lookup(ip)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	return locationOf(record), nil
+}
+
+// Lookup returns the raw data record of ip, nil when it is not in the
+// database. Maps decode to map[string]any, arrays to []any, strings to
+// string, numbers to uint64, int64 or float64 and booleans to bool.
+func (r *Reader) Lookup(ip netip.Addr) (map[string]any, error) {
+	ip = ip.Unmap()
+	if !ip.IsValid() || (ip.Is6() && r.meta.IPVersion == 4) {
+		return nil, nil
+	}
+
+	node := uint(0)
+	if ip.Is4() && r.meta.IPVersion == 6 {
+		node = r.ipv4Start
+	}
+	addr := ip.AsSlice()
+	for i := 0; i < len(addr)*8 && node < r.meta.NodeCount; i++ {
+		bit := uint(addr[i/8]>>(7-i%8)) & 1
+		node = r.record(node, bit)
+	}
+
+	switch {
+	case node == r.meta.NodeCount:
+		return nil, nil
+	case node < r.meta.NodeCount:
+		return nil, fmt.Errorf("%w: search tree too deep", ErrInvalidDatabase)
+	}
+
+	offset := node - r.meta.NodeCount - 16
+	if offset >= uint(len(r.data)) {
+		return nil, fmt.Errorf("%w: record points outside data section", ErrInvalidDatabase)
+	}
+	value, _, err := decoder{data: r.data}.decode(offset, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDatabase, err)
+	}
+	record, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: record is not a map", ErrInvalidDatabase)
+	}
+	return record, nil
+}
+
+// record reads the left (bit 0) or right (bit 1) record of node.
+func (r *Reader) record(node, bit uint) uint {
+	size := r.meta.RecordSize
+	b := r.tree[node*size/4:]
+	switch size {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// decoder reads values from a MaxMind DB data section. Pointers are
+// offsets into data.
+type decoder struct {
+	data []byte
+}
+
+// decode reads the value at offset and returns it with the offset that
+// follows it.
+func (d decoder) decode(offset uint, depth int) (any, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, fmt.Errorf("data nested too deeply")
+	}
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == typePointer {
+		value, _, err := d.decode(size, depth+1)
+		return value, offset, err
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, size)
+		for range size {
+			var key, value any
+			if key, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("map key is not a string")
+			}
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			m[k] = value
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, min(size, 1024))
+		for range size {
+			var value any
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.data)) {
+		return nil, 0, fmt.Errorf("value exceeds data section")
+	}
+	b := d.data[offset : offset+size]
+	next := offset + size
+	switch typ {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes:
+		return bytes.Clone(b), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("double of %d bytes", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("float of %d bytes", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, fmt.Errorf("integer of %d bytes", size)
+		}
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, next, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("int32 of %d bytes", size)
+		}
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return int64(int32(v)), next, nil
+	case typeUint128:
+		return bytes.Clone(b), next, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported data type %d", typ)
+	}
+}
+
+// control reads the control byte at offset and returns the value's type
+// and size, and the offset of its payload. For pointers size is the target
+// offset.
+func (d decoder) control(offset uint) (typ int, size uint, next uint, err error) {
+	read := func(n uint) ([]byte, error) {
+		if offset+n > uint(len(d.data)) {
+			return nil, fmt.Errorf("truncated data section")
+		}
+		b := d.data[offset : offset+n]
+		offset += n
+		return b, nil
+	}
+
+	b, err := read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	ctrl := b[0]
+	typ = int(ctrl >> 5)
+
+	if typ == typePointer {
+		n := uint(ctrl>>3)&3 + 1
+		if b, err = read(n); err != nil {
+			return 0, 0, 0, err
+		}
+		var p uint
+		if n < 4 {
+			p = uint(ctrl & 7)
+		}
+		for _, c := range b {
+			p = p<<8 | uint(c)
+		}
+		switch n {
+		case 2:
+			p += 2048
+		case 3:
+			p += 526336
+		}
+		return typ, p, offset, nil
+	}
+
+	if typ == typeExtended {
+		if b, err = read(1); err != nil {
+			return 0, 0, 0, err
+		}
+		typ = 7 + int(b[0])
+		if typ <= typeMap {
+			return 0, 0, 0, fmt.Errorf("invalid extended type %d", typ)
+		}
+	}
+
+	size = uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if b, err = read(n); err != nil {
+			return 0, 0, 0, err
+		}
+		var v uint
+		for _, c := range b {
+			v = v<<8 | uint(c)
+		}
+		switch n {
+		case 1:
+			size = 29 + v
+		case 2:
+			size = 285 + v
+		default:
+			size = 65821 + v
+		}
+	}
+	return typ, size, offset, nil
+}
+
+// locationOf reads the fields of the GeoIP2 City, Country and ASN record
+// layouts.
+func locationOf(record map[string]any) *Location {
+	loc := &Location{
+		CountryCode: str(path(record, "country", "iso_code")),
+		Country:     str(path(record, "country", "names", "en")),
+		City:        str(path(record, "city", "names", "en")),
+		TimeZone:    str(path(record, "location", "time_zone")),
+		ASN:         uint32(uintValue(record["autonomous_system_number"])),
+		ASOrg:       str(record["autonomous_system_organization"]),
+	}
+	if subdivisions, ok := record["subdivisions"].([]any); ok && len(subdivisions) > 0 {
+		loc.Region = str(path(subdivisions[0], "names", "en"))
+	}
+	loc.Latitude, _ = path(record, "location", "latitude").(float64)
+	loc.Longitude, _ = path(record, "location", "longitude").(float64)
+	return loc
+}
+
+func path(v any, keys ...string) any {
+	for _, key := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+func str(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+func uintValue(v any) uint64 {
+	n, _ := v.(uint64)
+	return n
+}
+
+var _ Locator = (*Reader)(nil)
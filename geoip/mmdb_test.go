@@ -0,0 +1,320 @@
+package geoip
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// testDB builds MaxMind DB files for tests.
+type testDB struct {
+	ipVersion  int
+	recordSize uint
+	nodes      [][2]int // child node index, or ^dataIndex, or 0 for empty
+	records    []map[string]any
+}
+
+func newTestDB(ipVersion int, recordSize uint) *testDB {
+	return &testDB{ipVersion: ipVersion, recordSize: recordSize, nodes: [][2]int{{}}}
+}
+
+func (db *testDB) insert(t *testing.T, prefix string, record map[string]any) {
+	t.Helper()
+	p := netip.MustParsePrefix(prefix)
+	addr := p.Addr().AsSlice()
+	bits := p.Bits()
+	if p.Addr().Is4() && db.ipVersion == 6 {
+		addr = append(make([]byte, 12), addr...)
+		bits += 96
+	}
+
+	db.records = append(db.records, record)
+	node := 0
+	for i := 0; i < bits; i++ {
+		bit := int(addr[i/8]>>(7-i%8)) & 1
+		if i == bits-1 {
+			db.nodes[node][bit] = ^(len(db.records) - 1)
+			return
+		}
+		next := db.nodes[node][bit]
+		if next <= 0 {
+			db.nodes = append(db.nodes, [2]int{})
+			next = len(db.nodes) - 1
+			db.nodes[node][bit] = next
+		}
+		node = next
+	}
+}
+
+func (db *testDB) bytes() []byte {
+	var data []byte
+	offsets := make([]uint, len(db.records))
+	for i, record := range db.records {
+		offsets[i] = uint(len(data))
+		data = encode(data, record)
+	}
+
+	count := uint(len(db.nodes))
+	var tree []byte
+	for _, node := range db.nodes {
+		var values [2]uint
+		for i, v := range node {
+			switch {
+			case v > 0:
+				values[i] = uint(v)
+			case v < 0:
+				values[i] = count + 16 + offsets[^v]
+			default:
+				values[i] = count
+			}
+		}
+		tree = append(tree, encodeNode(db.recordSize, values[0], values[1])...)
+	}
+
+	buf := append(tree, make([]byte, 16)...)
+	buf = append(buf, data...)
+	buf = append(buf, metadataMarker...)
+	return encode(buf, map[string]any{
+		"binary_format_major_version": uint64(2),
+		"binary_format_minor_version": uint64(0),
+		"database_type":               "Test-City",
+		"ip_version":                  uint64(db.ipVersion),
+		"node_count":                  uint64(count),
+		"record_size":                 uint64(db.recordSize),
+		"build_epoch":                 uint64(1700000000),
+		"languages":                   []any{"en"},
+	})
+}
+
+func encodeNode(size, left, right uint) []byte {
+	switch size {
+	case 24:
+		return []byte{byte(left >> 16), byte(left >> 8), byte(left), byte(right >> 16), byte(right >> 8), byte(right)}
+	case 28:
+		return []byte{byte(left >> 16), byte(left >> 8), byte(left), byte(left>>20)&0xF0 | byte(right>>24)&0x0F, byte(right >> 16), byte(right >> 8), byte(right)}
+	default:
+		return binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, uint32(left)), uint32(right))
+	}
+}
+
+func encode(buf []byte, v any) []byte {
+	switch v := v.(type) {
+	case string:
+		return append(encodeControl(buf, typeString, uint(len(v))), v...)
+	case float64:
+		return binary.BigEndian.AppendUint64(encodeControl(buf, typeDouble, 8), math.Float64bits(v))
+	case uint64:
+		b := binary.BigEndian.AppendUint64(nil, v)
+		for len(b) > 0 && b[0] == 0 {
+			b = b[1:]
+		}
+		return append(encodeControl(buf, typeUint64, uint(len(b))), b...)
+	case bool:
+		size := uint(0)
+		if v {
+			size = 1
+		}
+		return encodeControl(buf, typeBool, size)
+	case []any:
+		buf = encodeControl(buf, typeArray, uint(len(v)))
+		for _, e := range v {
+			buf = encode(buf, e)
+		}
+		return buf
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf = encodeControl(buf, typeMap, uint(len(v)))
+		for _, k := range keys {
+			buf = encode(encode(buf, k), v[k])
+		}
+		return buf
+	default:
+		panic("unsupported test value")
+	}
+}
+
+func encodeControl(buf []byte, typ int, size uint) []byte {
+	var ext []byte
+	var sizeBits byte
+	switch {
+	case size < 29:
+		sizeBits = byte(size)
+	case size < 285:
+		sizeBits, ext = 29, []byte{byte(size - 29)}
+	default:
+		size -= 285
+		sizeBits, ext = 30, []byte{byte(size >> 8), byte(size)}
+	}
+	if typ > typeMap {
+		buf = append(buf, sizeBits, byte(typ-7))
+	} else {
+		buf = append(buf, byte(typ)<<5|sizeBits)
+	}
+	return append(buf, ext...)
+}
+
+func cityRecord(code, country, region, city string) map[string]any {
+	return map[string]any{
+		"country":      map[string]any{"iso_code": code, "names": map[string]any{"en": country, "de": "x"}},
+		"subdivisions": []any{map[string]any{"names": map[string]any{"en": region}}},
+		"city":         map[string]any{"names": map[string]any{"en": city}},
+		"location":     map[string]any{"latitude": 52.52, "longitude": 13.405, "time_zone": "Europe/Berlin"},
+	}
+}
+
+func TestReaderLocate(t *testing.T) {
+	for _, size := range []uint{24, 28, 32} {
+		db := newTestDB(6, size)
+		db.insert(t, "203.0.113.0/24", cityRecord("DE", "Germany", "Berlin", "Berlin"))
+		db.insert(t, "2001:db8::/32", map[string]any{
+			"autonomous_system_number":       uint64(64500),
+			"autonomous_system_organization": "Example Net",
+			"is_anycast":                     true,
+		})
+
+		r, err := NewReader(db.bytes())
+		if err != nil {
+			t.Fatalf("record size %d: NewReader() error = %v", size, err)
+		}
+		if meta := r.Metadata(); meta.DatabaseType != "Test-City" || meta.IPVersion != 6 || meta.RecordSize != size {
+			t.Errorf("Metadata() = %+v", meta)
+		}
+
+		loc, err := r.Locate(netip.MustParseAddr("203.0.113.9"))
+		if err != nil || loc == nil {
+			t.Fatalf("record size %d: Locate(v4) = %v, %v", size, loc, err)
+		}
+		want := Location{CountryCode: "DE", Country: "Germany", Region: "Berlin", City: "Berlin", Latitude: 52.52, Longitude: 13.405, TimeZone: "Europe/Berlin"}
+		if *loc != want {
+			t.Errorf("Locate(v4) = %+v, want %+v", *loc, want)
+		}
+
+		loc, err = r.Locate(netip.MustParseAddr("::ffff:203.0.113.200"))
+		if err != nil || loc == nil || loc.City != "Berlin" {
+			t.Errorf("Locate(v4-mapped) = %+v, %v", loc, err)
+		}
+
+		loc, err = r.Locate(netip.MustParseAddr("2001:db8:1::7"))
+		if err != nil || loc == nil || loc.ASN != 64500 || loc.ASOrg != "Example Net" || loc.Country != "" {
+			t.Errorf("Locate(v6) = %+v, %v", loc, err)
+		}
+
+		for _, miss := range []string{"198.51.100.1", "2001:db9::1"} {
+			if loc, err := r.Locate(netip.MustParseAddr(miss)); err != nil || loc != nil {
+				t.Errorf("Locate(%s) = %+v, %v, want not found", miss, loc, err)
+			}
+		}
+	}
+}
+
+func TestReaderIPv4Database(t *testing.T) {
+	db := newTestDB(4, 24)
+	db.insert(t, "192.0.2.0/25", cityRecord("FR", "France", "Île-de-France", "Paris"))
+	r, err := NewReader(db.bytes())
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+
+	if loc, err := r.Locate(netip.MustParseAddr("192.0.2.100")); err != nil || loc == nil || loc.Region != "Île-de-France" {
+		t.Errorf("Locate() = %+v, %v", loc, err)
+	}
+	if loc, err := r.Locate(netip.MustParseAddr("192.0.2.200")); err != nil || loc != nil {
+		t.Errorf("Locate(outside prefix) = %+v, %v", loc, err)
+	}
+	if loc, err := r.Locate(netip.MustParseAddr("2001:db8::1")); err != nil || loc != nil {
+		t.Errorf("Locate(v6 in v4 database) = %+v, %v", loc, err)
+	}
+}
+
+func TestReaderLookupRaw(t *testing.T) {
+	db := newTestDB(6, 24)
+	long := make([]byte, 300)
+	for i := range long {
+		long[i] = 'a'
+	}
+	db.insert(t, "2001:db8::/48", map[string]any{"note": string(long), "flag": false})
+	r, err := NewReader(db.bytes())
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+
+	record, err := r.Lookup(netip.MustParseAddr("2001:db8::1"))
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if record["note"] != string(long) || record["flag"] != false {
+		t.Errorf("Lookup() = %v", record)
+	}
+}
+
+func TestDecoderPointer(t *testing.T) {
+	// A map whose value is a pointer to the string at offset 0.
+	data := encode(nil, "shared")
+	mapAt := uint(len(data))
+	data = encodeControl(data, typeMap, 1)
+	data = encode(data, "k")
+	data = append(data, byte(typePointer)<<5, 0)
+
+	value, next, err := decoder{data: data}.decode(mapAt, 0)
+	if err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+	if m, ok := value.(map[string]any); !ok || m["k"] != "shared" {
+		t.Errorf("decode() = %v", value)
+	}
+	if next != uint(len(data)) {
+		t.Errorf("decode() next = %d, want %d", next, len(data))
+	}
+}
+
+func TestNewReaderInvalid(t *testing.T) {
+	if _, err := NewReader([]byte("not a database")); !errors.Is(err, ErrInvalidDatabase) {
+		t.Errorf("NewReader(garbage) error = %v, want ErrInvalidDatabase", err)
+	}
+
+	db := newTestDB(6, 24)
+	db.insert(t, "2001:db8::/32", map[string]any{"a": "b"})
+	buf := db.bytes()
+	if _, err := NewReader(buf[len(buf)/2:]); !errors.Is(err, ErrInvalidDatabase) {
+		t.Errorf("NewReader(truncated) error = %v, want ErrInvalidDatabase", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	db := newTestDB(6, 24)
+	db.insert(t, "203.0.113.0/24", cityRecord("DE", "Germany", "Berlin", "Berlin"))
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, db.bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if loc, _ := r.Locate(netip.MustParseAddr("203.0.113.1")); loc == nil || loc.CountryCode != "DE" {
+		t.Errorf("Locate() = %+v", loc)
+	}
+
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Error("Open(missing) succeeded")
+	}
+}
+
+func TestLocatorFunc(t *testing.T) {
+	var l Locator = LocatorFunc(func(ip netip.Addr) (*Location, error) {
+		return &Location{CountryCode: "NL"}, nil
+	})
+	if loc, _ := l.Locate(netip.MustParseAddr("192.0.2.1")); loc.CountryCode != "NL" {
+		t.Errorf("Locate() = %+v", loc)
+	}
+}
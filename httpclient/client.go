@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/aquamarinepk/aqm/crypto"
 	"github.com/aquamarinepk/aqm/log"
 )
 
@@ -17,9 +18,16 @@ type Client struct {
 	httpClient *http.Client
 	retryMax   int
 	retryDelay time.Duration
+	headers    http.Header
+	signer     *signer
 	log        log.Logger
 }
 
+type signer struct {
+	keyID  string
+	secret []byte
+}
+
 type Response struct {
 	StatusCode int
 	Body       []byte
@@ -32,6 +40,7 @@ func New(baseURL string, logger log.Logger, opts ...Option) *Client {
 		httpClient: &http.Client{Timeout: 30 * time.Second},
 		retryMax:   3,
 		retryDelay: 100 * time.Millisecond,
+		headers:    make(http.Header),
 		log:        logger,
 	}
 
@@ -50,6 +59,14 @@ func (c *Client) Post(ctx context.Context, path string, body interface{}) (*Resp
 	return c.Do(ctx, http.MethodPost, path, body)
 }
 
+func (c *Client) Put(ctx context.Context, path string, body interface{}) (*Response, error) {
+	return c.Do(ctx, http.MethodPut, path, body)
+}
+
+func (c *Client) Delete(ctx context.Context, path string) (*Response, error) {
+	return c.Do(ctx, http.MethodDelete, path, nil)
+}
+
 func (c *Client) Do(ctx context.Context, method, path string, body interface{}) (*Response, error) {
 	url := c.baseURL + path
 
@@ -85,9 +102,17 @@ func (c *Client) Do(ctx context.Context, method, path string, body interface{})
 			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
 
+		for key, values := range c.headers {
+			req.Header[key] = values
+		}
 		if body != nil {
 			req.Header.Set("Content-Type", "application/json")
 		}
+		if c.signer != nil {
+			if err := crypto.SignRequest(req, c.signer.keyID, c.signer.secret, time.Now()); err != nil {
+				return nil, fmt.Errorf("failed to sign request: %w", err)
+			}
+		}
 
 		c.log.Debugf("HTTP %s %s (attempt %d/%d)", method, url, attempt+1, c.retryMax+1)
 
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aquamarinepk/aqm/crypto"
 	"github.com/aquamarinepk/aqm/log"
 )
 
@@ -203,6 +204,59 @@ func TestClientContextCancellation(t *testing.T) {
 	}
 }
 
+func TestClientPutDeleteHeaders(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if got := r.Header.Get("Authorization"); got != "Bearer token-1" {
+			t.Errorf("Authorization = %q, want Bearer token-1", got)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	logger := log.NewLogger("error")
+	client := New(server.URL, logger, WithHeader("Authorization", "Bearer token-1"))
+	ctx := context.Background()
+
+	if _, err := client.Put(ctx, "/items/1", map[string]string{"name": "x"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, err := client.Delete(ctx, "/items/1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if len(methods) != 2 || methods[0] != http.MethodPut || methods[1] != http.MethodDelete {
+		t.Errorf("methods = %v, want [PUT DELETE]", methods)
+	}
+}
+
+func TestClientRequestSigning(t *testing.T) {
+	secret := []byte("internal-secret")
+	var verified []error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verified = append(verified, crypto.VerifyRequest(r, secret, time.Now(), 0))
+		if r.Header.Get(crypto.HeaderSignatureKeyID) != "authz" {
+			t.Errorf("key ID = %q", r.Header.Get(crypto.HeaderSignatureKeyID))
+		}
+		if len(verified) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, log.NewNoopLogger(), WithRequestSigning("authz", secret), WithRetryDelay(time.Millisecond))
+	resp, err := client.Post(context.Background(), "/authz/import?dry_run=true", map[string]string{"a": "b"})
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Post() = %v, %v", resp, err)
+	}
+	if len(verified) != 2 || verified[0] != nil || verified[1] != nil {
+		t.Errorf("server verification = %v, want two valid signatures", verified)
+	}
+}
+
 func TestClientOptions(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -38,3 +38,19 @@ func WithHTTPClient(client *http.Client) Option {
 		}
 	}
 }
+
+// WithHeader sets a header sent with every request, e.g. Authorization.
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.headers.Set(key, value)
+	}
+}
+
+// WithRequestSigning signs every request with crypto.SignRequest, for
+// endpoints guarded by middleware.RequireSignature. Retries are signed
+// afresh. keyID may be empty when the server accepts a single shared secret.
+func WithRequestSigning(keyID string, secret []byte) Option {
+	return func(c *Client) {
+		c.signer = &signer{keyID: keyID, secret: secret}
+	}
+}
@@ -0,0 +1,97 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/aquamarinepk/aqm/validation"
+)
+
+// CodeUnsupportedMediaType is the code Bind reports for a non-JSON body.
+const CodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+
+// Bind decodes the JSON body of r into dst and validates it. Decoding is
+// strict: unknown fields and trailing data are rejected. A Content-Type
+// other than application/json (or a +json type) fails with 415; a request
+// without one is read as JSON. When dst is a struct its `validate` tags are
+// checked with validation.ValidateStruct and, if dst implements
+// validation.Validator, its Validate method is called too.
+//
+// The returned error is always an *Error ready for WriteError:
+//
+//	var req CreateRoleRequest
+//	if err := httpx.Bind(r, &req); err != nil {
+//	    httpx.WriteError(w, r, err)
+//	    return
+//	}
+func Bind(r *http.Request, dst any) error {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || !isJSONMediaType(mediaType) {
+			return NewError(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "Content-Type must be application/json")
+		}
+	}
+
+	if r.Body == nil || r.Body == http.NoBody {
+		return BadRequest(CodeInvalidRequest, "Request body is required")
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return BadRequest(CodeInvalidRequest, "Request body must hold a single JSON value")
+	}
+
+	var errs validation.ValidationErrors
+	if isStruct(dst) {
+		errs = validation.ValidateStruct(dst)
+	}
+	if v, ok := dst.(validation.Validator); ok {
+		errs.Merge(v.Validate())
+	}
+	if errs.HasErrors() {
+		return ValidationFailed(errs)
+	}
+	return nil
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == ContentTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+func isStruct(v any) bool {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t != nil && t.Kind() == reflect.Struct
+}
+
+// decodeError maps a json decoding failure to a client error, naming the
+// offending field where the decoder reports one.
+func decodeError(err error) *Error {
+	var typeErr *json.UnmarshalTypeError
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, io.EOF):
+		return BadRequest(CodeInvalidRequest, "Request body is required")
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return BadRequest(CodeInvalidRequest, fmt.Sprintf("Field %q must be %s", typeErr.Field, typeErr.Type)).Wrap(err)
+	case errors.As(err, &maxErr):
+		return NewError(http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE", "Request body is too large").Wrap(err)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return BadRequest(CodeInvalidRequest, "Unknown field "+field).Wrap(err)
+	default:
+		return BadRequest(CodeInvalidRequest, "Invalid request body").Wrap(err)
+	}
+}
@@ -0,0 +1,142 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/validation"
+)
+
+type bindRequest struct {
+	Name  string `json:"name" validate:"required,min=3"`
+	Count int    `json:"count"`
+}
+
+type checkedRequest struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+func (c checkedRequest) Validate() validation.ValidationErrors {
+	var errs validation.ValidationErrors
+	if c.End < c.Start {
+		errs.AddCode("end", validation.CodeRange, "must not be before start")
+	}
+	return errs
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string
+		wantFields  []string
+	}{
+		{name: "valid", contentType: "application/json", body: `{"name":"alice","count":2}`},
+		{name: "charset parameter", contentType: "application/json; charset=utf-8", body: `{"name":"alice"}`},
+		{name: "json suffix", contentType: "application/vnd.aqm+json", body: `{"name":"alice"}`},
+		{name: "no content type", body: `{"name":"alice"}`},
+		{name: "wrong content type", contentType: "text/plain", body: `{"name":"alice"}`, wantStatus: http.StatusUnsupportedMediaType, wantCode: CodeUnsupportedMediaType},
+		{name: "form content type", contentType: "application/x-www-form-urlencoded", body: `name=alice`, wantStatus: http.StatusUnsupportedMediaType, wantCode: CodeUnsupportedMediaType},
+		{name: "empty body", contentType: "application/json", wantStatus: http.StatusBadRequest, wantCode: CodeInvalidRequest},
+		{name: "malformed", contentType: "application/json", body: `{"name":`, wantStatus: http.StatusBadRequest, wantCode: CodeInvalidRequest},
+		{name: "unknown field", contentType: "application/json", body: `{"name":"alice","admin":true}`, wantStatus: http.StatusBadRequest, wantCode: CodeInvalidRequest},
+		{name: "wrong type", contentType: "application/json", body: `{"name":"alice","count":"two"}`, wantStatus: http.StatusBadRequest, wantCode: CodeInvalidRequest},
+		{name: "trailing data", contentType: "application/json", body: `{"name":"alice"} {"name":"bob"}`, wantStatus: http.StatusBadRequest, wantCode: CodeInvalidRequest},
+		{name: "failed validation", contentType: "application/json", body: `{"name":"al"}`, wantStatus: http.StatusBadRequest, wantCode: CodeValidation, wantFields: []string{"name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.body == "" {
+				r = httptest.NewRequest(http.MethodPost, "/", nil)
+			}
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			var req bindRequest
+			err := Bind(r, &req)
+
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("Bind() error = %v", err)
+				}
+				if req.Name != "alice" {
+					t.Errorf("Name = %q, want alice", req.Name)
+				}
+				return
+			}
+
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Bind() error = %v, want *Error", err)
+			}
+			if e.Status != tt.wantStatus || e.Code != tt.wantCode {
+				t.Errorf("Bind() = %d %s (%s), want %d %s", e.Status, e.Code, e.Message, tt.wantStatus, tt.wantCode)
+			}
+			if tt.wantFields != nil {
+				if got := e.Fields.Fields(); len(got) != len(tt.wantFields) || got[0] != tt.wantFields[0] {
+					t.Errorf("Fields = %v, want %v", got, tt.wantFields)
+				}
+			}
+		})
+	}
+}
+
+func TestBindMessages(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{body: `{"name":"alice","admin":true}`, want: `Unknown field "admin"`},
+		{body: `{"name":"alice","count":"two"}`, want: `Field "count" must be int`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			var req bindRequest
+			if got := AsError(Bind(r, &req)).Message; got != tt.want {
+				t.Errorf("Message = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindValidator(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"start":5,"end":1}`))
+	var req checkedRequest
+	e := AsError(Bind(r, &req))
+	if e.Code != CodeValidation || len(e.Fields) != 1 || e.Fields[0].Field != "end" {
+		t.Errorf("Bind() = %+v, want a validation error on end", e)
+	}
+}
+
+func TestBindTooLarge(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"`+strings.Repeat("a", 64)+`"}`))
+	r.Body = http.MaxBytesReader(w, r.Body, 16)
+
+	var req bindRequest
+	if e := AsError(Bind(r, &req)); e.Status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Bind() status = %d, want %d", e.Status, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestBindNonStruct(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":1}`))
+	var dst map[string]int
+	if err := Bind(r, &dst); err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	if dst["a"] != 1 {
+		t.Errorf("dst = %v", dst)
+	}
+}
@@ -0,0 +1,534 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// Encoder renders response bodies as JSON. WriteJSON and the error writers
+// use the encoder installed with SetEncoder.
+type Encoder interface {
+	Marshal(v any) ([]byte, error)
+}
+
+// FieldNaming selects how JSON object keys derived from struct fields are
+// written. Map keys are data and are never renamed.
+type FieldNaming int
+
+const (
+	// NamesAsTagged writes the json tag name, or the Go field name when
+	// there is no tag, exactly as encoding/json does.
+	NamesAsTagged FieldNaming = iota
+	// SnakeCase writes keys as snake_case, e.g. created_at.
+	SnakeCase
+	// CamelCase writes keys as camelCase, e.g. createdAt.
+	CamelCase
+)
+
+// TimeFormat selects how time.Time values are written.
+type TimeFormat int
+
+const (
+	// TimeRFC3339 writes times as RFC 3339 strings with nanoseconds, as
+	// encoding/json does.
+	TimeRFC3339 TimeFormat = iota
+	// TimeEpochSeconds writes times as Unix seconds.
+	TimeEpochSeconds
+	// TimeEpochMillis writes times as Unix milliseconds.
+	TimeEpochMillis
+)
+
+// JSONEncoder is the default Encoder. With no options it produces the same
+// output as encoding/json; field naming and time rendering can be changed
+// without touching struct tags. Types that implement json.Marshaler or
+// encoding.TextMarshaler keep their own encoding.
+type JSONEncoder struct {
+	naming     FieldNaming
+	timeFormat TimeFormat
+	fields     sync.Map // reflect.Type -> []encField
+}
+
+// EncoderOption configures a JSONEncoder.
+type EncoderOption func(*JSONEncoder)
+
+// WithFieldNaming sets how struct field keys are written.
+func WithFieldNaming(naming FieldNaming) EncoderOption {
+	return func(e *JSONEncoder) {
+		e.naming = naming
+	}
+}
+
+// WithTimeFormat sets how time.Time values are written.
+func WithTimeFormat(format TimeFormat) EncoderOption {
+	return func(e *JSONEncoder) {
+		e.timeFormat = format
+	}
+}
+
+// NewJSONEncoder creates a JSONEncoder.
+//
+// Example:
+//
+//	httpx.SetEncoder(httpx.NewJSONEncoder(
+//	    httpx.WithFieldNaming(httpx.CamelCase),
+//	    httpx.WithTimeFormat(httpx.TimeEpochMillis),
+//	))
+func NewJSONEncoder(opts ...EncoderOption) *JSONEncoder {
+	e := &JSONEncoder{}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+type encoderBox struct{ Encoder }
+
+var defaultEncoder atomic.Pointer[encoderBox]
+
+func init() {
+	SetEncoder(nil)
+}
+
+// SetEncoder installs enc as the encoder used for every response written by
+// this package. A nil enc restores the encoding/json compatible default.
+// Call it once at startup, before serving requests.
+func SetEncoder(enc Encoder) {
+	if enc == nil {
+		enc = NewJSONEncoder()
+	}
+	defaultEncoder.Store(&encoderBox{enc})
+}
+
+// DefaultEncoder returns the encoder installed with SetEncoder.
+func DefaultEncoder() Encoder {
+	return defaultEncoder.Load().Encoder
+}
+
+// Marshal encodes v with the default encoder. Packages that write JSON
+// outside WriteJSON, such as event streams, use it to stay consistent
+// with regular responses.
+func Marshal(v any) ([]byte, error) {
+	return DefaultEncoder().Marshal(v)
+}
+
+// Marshal implements Encoder.
+func (e *JSONEncoder) Marshal(v any) ([]byte, error) {
+	if e.naming == NamesAsTagged && e.timeFormat == TimeRFC3339 {
+		return json.Marshal(v)
+	}
+
+	var buf bytes.Buffer
+	if err := e.encode(&buf, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+func (e *JSONEncoder) encode(buf *bytes.Buffer, v reflect.Value) error {
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+
+	if k := v.Kind(); k == reflect.Pointer || k == reflect.Interface {
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return e.encode(buf, v.Elem())
+	}
+	if v.Type() == timeType {
+		return e.encodeTime(buf, v.Interface().(time.Time))
+	}
+	if implementsMarshaler(v) {
+		return writeMarshaled(buf, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return e.encodeStruct(buf, v)
+
+	case reflect.Map:
+		return e.encodeMap(buf, v)
+
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return writeMarshaled(buf, v)
+		}
+		return e.encodeList(buf, v)
+
+	case reflect.Array:
+		return e.encodeList(buf, v)
+
+	default:
+		return writeMarshaled(buf, v)
+	}
+}
+
+func implementsMarshaler(v reflect.Value) bool {
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return true
+	}
+	if v.CanAddr() {
+		pt := reflect.PointerTo(t)
+		return pt.Implements(jsonMarshalerType) || pt.Implements(textMarshalerType)
+	}
+	return false
+}
+
+func writeMarshaled(buf *bytes.Buffer, v reflect.Value) error {
+	if v.CanAddr() {
+		v = v.Addr()
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	buf.Write(data)
+	return nil
+}
+
+func (e *JSONEncoder) encodeTime(buf *bytes.Buffer, t time.Time) error {
+	switch e.timeFormat {
+	case TimeEpochSeconds:
+		if t.IsZero() {
+			buf.WriteString("null")
+			return nil
+		}
+		buf.WriteString(strconv.FormatInt(t.Unix(), 10))
+		return nil
+	case TimeEpochMillis:
+		if t.IsZero() {
+			buf.WriteString("null")
+			return nil
+		}
+		buf.WriteString(strconv.FormatInt(t.UnixMilli(), 10))
+		return nil
+	default:
+		data, err := t.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		return nil
+	}
+}
+
+func (e *JSONEncoder) encodeStruct(buf *bytes.Buffer, v reflect.Value) error {
+	buf.WriteByte('{')
+	first := true
+	for _, f := range e.structFields(v.Type()) {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		buf.Write(f.key)
+
+		if f.quoted {
+			var inner bytes.Buffer
+			if err := e.encode(&inner, fv); err != nil {
+				return err
+			}
+			writeString(buf, inner.String())
+			continue
+		}
+		if err := e.encode(buf, fv); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func (e *JSONEncoder) encodeMap(buf *bytes.Buffer, v reflect.Value) error {
+	if v.IsNil() {
+		buf.WriteString("null")
+		return nil
+	}
+
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := mapKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{key: key, value: iter.Value()})
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return strings.Compare(a.key, b.key) })
+
+	buf.WriteByte('{')
+	for i, en := range entries {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeString(buf, en.key)
+		buf.WriteByte(':')
+		if err := e.encode(buf, en.value); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func (e *JSONEncoder) encodeList(buf *bytes.Buffer, v reflect.Value) error {
+	buf.WriteByte('[')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := e.encode(buf, v.Index(i)); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+// mapKey renders a map key the way encoding/json does.
+func mapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Pointer && k.IsNil() {
+			return "", nil
+		}
+		text, err := tm.MarshalText()
+		return string(text), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("httpx: unsupported map key type %s", k.Type())
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	data, _ := json.Marshal(s)
+	buf.Write(data)
+}
+
+// encField is a struct field ready to encode: its index path, including
+// embedded structs, and its pre-rendered "key": prefix.
+type encField struct {
+	name      string
+	index     []int
+	key       []byte
+	omitEmpty bool
+	quoted    bool
+}
+
+func (e *JSONEncoder) structFields(t reflect.Type) []encField {
+	if cached, ok := e.fields.Load(t); ok {
+		return cached.([]encField)
+	}
+
+	fields := collectFields(t, nil, e.naming)
+	e.fields.Store(t, fields)
+	return fields
+}
+
+// collectFields lists the encodable fields of t. Fields of untagged embedded
+// structs are promoted; a name at a shallower depth wins, as in encoding/json.
+func collectFields(t reflect.Type, index []int, naming FieldNaming) []encField {
+	var fields []encField
+	var promoted [][]encField
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		idx := append(slices.Clone(index), i)
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			promoted = append(promoted, collectFields(ft, idx, naming))
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+		key := renameField(name, naming)
+		fields = append(fields, encField{
+			name:      key,
+			index:     idx,
+			key:       fieldKey(key),
+			omitEmpty: hasTagOption(opts, "omitempty"),
+			quoted:    hasTagOption(opts, "string") && isQuotable(sf.Type),
+		})
+	}
+
+	for _, group := range promoted {
+		for _, f := range group {
+			if !slices.ContainsFunc(fields, func(o encField) bool { return o.name == f.name }) {
+				fields = append(fields, f)
+			}
+		}
+	}
+	slices.SortStableFunc(fields, func(a, b encField) int { return slices.Compare(a.index, b.index) })
+	return fields
+}
+
+func fieldKey(name string) []byte {
+	var buf bytes.Buffer
+	writeString(&buf, name)
+	buf.WriteByte(':')
+	return buf.Bytes()
+}
+
+func hasTagOption(opts, option string) bool {
+	for opts != "" {
+		var o string
+		o, opts, _ = strings.Cut(opts, ",")
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func isQuotable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// fieldByIndex walks index through embedded pointers, reporting false when
+// one of them is nil.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// renameField converts a field name to the requested naming. Words are split
+// on underscores, hyphens and case changes, keeping acronyms together, so
+// "created_at", "CreatedAt" and "createdAt" all become created_at or createdAt.
+func renameField(name string, naming FieldNaming) string {
+	if naming == NamesAsTagged {
+		return name
+	}
+
+	words := splitWords(name)
+	if len(words) == 0 {
+		return name
+	}
+	switch naming {
+	case SnakeCase:
+		for i, w := range words {
+			words[i] = strings.ToLower(w)
+		}
+		return strings.Join(words, "_")
+	default:
+		var b strings.Builder
+		for i, w := range words {
+			lower := strings.ToLower(w)
+			if i == 0 {
+				b.WriteString(lower)
+				continue
+			}
+			r := []rune(lower)
+			r[0] = unicode.ToUpper(r[0])
+			b.WriteString(string(r))
+		}
+		return b.String()
+	}
+}
+
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			words = append(words, string(runes[start:end]))
+		}
+	}
+
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == ' ':
+			flush(i)
+			start = i + 1
+		case i > start && unicode.IsUpper(r):
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				flush(i)
+				start = i
+			}
+		}
+	}
+	flush(len(runes))
+	return words
+}
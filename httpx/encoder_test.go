@@ -0,0 +1,219 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type encBase struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type encRecord struct {
+	encBase
+	DisplayName string            `json:"display_name"`
+	UserID      string            `json:"userID,omitempty"`
+	Secret      string            `json:"-"`
+	Count       int               `json:"count,string"`
+	DeletedAt   *time.Time        `json:"deleted_at,omitempty"`
+	ExpiresAt   *time.Time        `json:"expires_at"`
+	Labels      map[string]string `json:"labels"`
+	Nested      []encNested       `json:"nested"`
+	Raw         json.RawMessage   `json:"raw_payload,omitempty"`
+	HTTPStatus  int
+	hidden      string
+}
+
+type encNested struct {
+	LastSeen time.Time `json:"last_seen"`
+}
+
+func newEncRecord() encRecord {
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	return encRecord{
+		encBase:     encBase{ID: uuid.MustParse("7d0d8b8e-6a43-4a53-9d0a-3a8e4d5b2c10"), CreatedAt: at},
+		DisplayName: "Ada",
+		Secret:      "s3cret",
+		Count:       3,
+		ExpiresAt:   &at,
+		Labels:      map[string]string{"team_name": "core"},
+		Nested:      []encNested{{LastSeen: at}},
+		Raw:         json.RawMessage(`{"keep_me":1}`),
+		HTTPStatus:  200,
+		hidden:      "x",
+	}
+}
+
+func TestJSONEncoderDefaultMatchesEncodingJSON(t *testing.T) {
+	rec := newEncRecord()
+	want, _ := json.Marshal(rec)
+
+	got, err := NewJSONEncoder().Marshal(rec)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+}
+
+func TestJSONEncoderSnakeCase(t *testing.T) {
+	rec := newEncRecord()
+	rec.UserID = "u-1"
+
+	got, err := NewJSONEncoder(WithFieldNaming(SnakeCase)).Marshal(rec)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	want := `{"id":"7d0d8b8e-6a43-4a53-9d0a-3a8e4d5b2c10","created_at":"2024-05-06T07:08:09Z",` +
+		`"display_name":"Ada","user_id":"u-1","count":"3","expires_at":"2024-05-06T07:08:09Z","labels":{"team_name":"core"},` +
+		`"nested":[{"last_seen":"2024-05-06T07:08:09Z"}],"raw_payload":{"keep_me":1},"http_status":200}`
+	if string(got) != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestJSONEncoderCamelCaseEpoch(t *testing.T) {
+	enc := NewJSONEncoder(WithFieldNaming(CamelCase), WithTimeFormat(TimeEpochMillis))
+
+	got, err := enc.Marshal(newEncRecord())
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	want := `{"id":"7d0d8b8e-6a43-4a53-9d0a-3a8e4d5b2c10","createdAt":1714979289000,` +
+		`"displayName":"Ada","count":"3","expiresAt":1714979289000,"labels":{"team_name":"core"},` +
+		`"nested":[{"lastSeen":1714979289000}],"rawPayload":{"keep_me":1},"httpStatus":200}`
+	if string(got) != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestJSONEncoderEpochSeconds(t *testing.T) {
+	enc := NewJSONEncoder(WithTimeFormat(TimeEpochSeconds))
+
+	got, err := enc.Marshal(struct {
+		At   time.Time  `json:"at"`
+		Zero time.Time  `json:"zero"`
+		Nil  *time.Time `json:"nil"`
+	}{At: time.Unix(1700000000, 0)})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := `{"at":1700000000,"zero":null,"nil":null}`; string(got) != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+}
+
+func TestJSONEncoderValues(t *testing.T) {
+	enc := NewJSONEncoder(WithFieldNaming(CamelCase))
+
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{name: "nil", v: nil, want: `null`},
+		{name: "html escaped", v: "<a>", want: `"\u003ca\u003e"`},
+		{name: "nil slice", v: []int(nil), want: `null`},
+		{name: "bytes", v: []byte("hi"), want: `"aGk="`},
+		{name: "int map keys", v: map[int]bool{2: true, 1: false}, want: `{"1":false,"2":true}`},
+		{name: "array", v: [2]string{"a", "b"}, want: `["a","b"]`},
+		{name: "interface slice", v: []any{1, "x", map[string]any{"some_key": nil}}, want: `[1,"x",{"some_key":null}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := enc.Marshal(tt.v)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenameField(t *testing.T) {
+	tests := []struct {
+		name  string
+		snake string
+		camel string
+	}{
+		{name: "created_at", snake: "created_at", camel: "createdAt"},
+		{name: "CreatedAt", snake: "created_at", camel: "createdAt"},
+		{name: "createdAt", snake: "created_at", camel: "createdAt"},
+		{name: "ID", snake: "id", camel: "id"},
+		{name: "UserID", snake: "user_id", camel: "userId"},
+		{name: "HTTPServer", snake: "http_server", camel: "httpServer"},
+		{name: "retry-after", snake: "retry_after", camel: "retryAfter"},
+		{name: "oauth2_token", snake: "oauth2_token", camel: "oauth2Token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renameField(tt.name, SnakeCase); got != tt.snake {
+				t.Errorf("renameField(%q, SnakeCase) = %q, want %q", tt.name, got, tt.snake)
+			}
+			if got := renameField(tt.name, CamelCase); got != tt.camel {
+				t.Errorf("renameField(%q, CamelCase) = %q, want %q", tt.name, got, tt.camel)
+			}
+			if got := renameField(tt.name, NamesAsTagged); got != tt.name {
+				t.Errorf("renameField(%q, NamesAsTagged) = %q", tt.name, got)
+			}
+		})
+	}
+}
+
+func TestSetEncoder(t *testing.T) {
+	SetEncoder(NewJSONEncoder(WithFieldNaming(CamelCase)))
+	t.Cleanup(func() { SetEncoder(nil) })
+
+	w := httptest.NewRecorder()
+	WriteError(w, httptest.NewRequest(http.MethodGet, "/", nil),
+		NewError(http.StatusTooManyRequests, "RATE_LIMITED", "Slow down").WithRetryAfter(time.Second))
+
+	if body := w.Body.String(); !strings.Contains(body, `"retryAfterSeconds":1`) {
+		t.Errorf("body = %s, want camelCase keys", body)
+	}
+
+	SetEncoder(nil)
+	w = httptest.NewRecorder()
+	WriteJSON(w, http.StatusOK, struct {
+		DisplayName string `json:"display_name"`
+	}{"Ada"})
+	if got := w.Body.String(); got != "{\"display_name\":\"Ada\"}\n" {
+		t.Errorf("body = %q after restoring the default", got)
+	}
+}
+
+type failingEncoder struct{}
+
+func (failingEncoder) Marshal(any) ([]byte, error) {
+	return nil, errors.New("cannot encode")
+}
+
+func TestWriteJSONEncodeFailure(t *testing.T) {
+	SetEncoder(failingEncoder{})
+	t.Cleanup(func() { SetEncoder(nil) })
+
+	w := httptest.NewRecorder()
+	WriteJSON(w, http.StatusOK, "anything")
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if !strings.Contains(w.Body.String(), CodeInternal) {
+		t.Errorf("body = %s, want %s", w.Body.String(), CodeInternal)
+	}
+}
@@ -0,0 +1,112 @@
+// Package httpx holds the HTTP response conventions shared by aqm handlers:
+// typed API errors that carry a status and a stable code, and the JSON and
+// RFC 9457 problem+json bodies they are written as.
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aquamarinepk/aqm/validation"
+)
+
+// Codes used by the convenience constructors when callers have nothing more
+// specific to say.
+const (
+	CodeInvalidRequest = "INVALID_REQUEST"
+	CodeInternal       = "INTERNAL_ERROR"
+	CodeValidation     = "VALIDATION_FAILED"
+)
+
+// Error is an API error: the HTTP status, a stable machine-readable code and
+// a message safe to show to clients. RetryAfter, when positive, is advertised
+// in the Retry-After header and the body. Fields lists per-field failures and
+// is written as the errors member of the body. Err is the underlying cause;
+// it is never written to the response.
+type Error struct {
+	Status     int
+	Code       string
+	Message    string
+	RetryAfter time.Duration
+	Fields     validation.ValidationErrors
+	Err        error
+}
+
+// NewError creates an error with the given status, code and message.
+func NewError(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func BadRequest(code, message string) *Error {
+	return NewError(http.StatusBadRequest, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return NewError(http.StatusUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return NewError(http.StatusForbidden, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return NewError(http.StatusNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return NewError(http.StatusConflict, code, message)
+}
+
+// ValidationFailed creates a 400 error with CodeValidation carrying errs
+// as its field errors.
+func ValidationFailed(errs validation.ValidationErrors) *Error {
+	return BadRequest(CodeValidation, errs.Error()).WithFields(errs)
+}
+
+// Internal creates a 500 error with CodeInternal.
+func Internal(message string) *Error {
+	return NewError(http.StatusInternalServerError, CodeInternal, message)
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithRetryAfter returns a copy of e advertising d as the retry delay.
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	c := *e
+	c.RetryAfter = d
+	return &c
+}
+
+// WithFields returns a copy of e reporting errs as its field errors.
+func (e *Error) WithFields(errs validation.ValidationErrors) *Error {
+	c := *e
+	c.Fields = errs
+	return &c
+}
+
+// Wrap returns a copy of e with err recorded as its cause.
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// AsError returns the *Error in err's chain. Any other error becomes a
+// generic internal error so its message never reaches the client.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Internal("Internal server error").Wrap(err)
+}
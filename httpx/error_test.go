@@ -0,0 +1,94 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/validation"
+)
+
+func TestConstructors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        *Error
+		wantStatus int
+		wantCode   string
+	}{
+		{"bad request", BadRequest("INVALID_X", "bad"), http.StatusBadRequest, "INVALID_X"},
+		{"unauthorized", Unauthorized("NO_AUTH", "no"), http.StatusUnauthorized, "NO_AUTH"},
+		{"forbidden", Forbidden("DENIED", "no"), http.StatusForbidden, "DENIED"},
+		{"not found", NotFound("X_NOT_FOUND", "missing"), http.StatusNotFound, "X_NOT_FOUND"},
+		{"conflict", Conflict("X_EXISTS", "exists"), http.StatusConflict, "X_EXISTS"},
+		{"internal", Internal("boom"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.Status != tt.wantStatus || tt.err.Code != tt.wantCode {
+				t.Errorf("error = %+v, want status %d code %s", tt.err, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestErrorCopies(t *testing.T) {
+	base := NotFound("USER_NOT_FOUND", "User not found")
+	cause := errors.New("no rows")
+
+	wrapped := base.Wrap(cause).WithRetryAfter(time.Second)
+	if base.Err != nil || base.RetryAfter != 0 {
+		t.Errorf("base was mutated: %+v", base)
+	}
+	if !errors.Is(wrapped, cause) {
+		t.Error("wrapped error should unwrap to its cause")
+	}
+	if wrapped.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %v, want 1s", wrapped.RetryAfter)
+	}
+	if got := wrapped.Error(); got != "USER_NOT_FOUND: User not found: no rows" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestValidationFailed(t *testing.T) {
+	var errs validation.ValidationErrors
+	errs.AddCode("email", validation.CodeRequired, "is required")
+
+	e := ValidationFailed(errs)
+	if e.Status != http.StatusBadRequest || e.Code != CodeValidation {
+		t.Errorf("error = %+v, want status 400 code %s", e, CodeValidation)
+	}
+	if e.Message != "email: is required" {
+		t.Errorf("Message = %q", e.Message)
+	}
+	if len(e.Fields) != 1 || e.Fields[0].Field != "email" {
+		t.Errorf("Fields = %+v", e.Fields)
+	}
+
+	base := BadRequest("INVALID_QUERY", "bad")
+	if base.WithFields(errs); base.Fields != nil {
+		t.Errorf("base was mutated: %+v", base)
+	}
+}
+
+func TestAsError(t *testing.T) {
+	typed := Conflict("ROLE_EXISTS", "Role exists")
+	if got := AsError(fmt.Errorf("create: %w", typed)); got != typed {
+		t.Errorf("AsError() = %+v, want the wrapped *Error", got)
+	}
+
+	cause := errors.New("connection refused")
+	got := AsError(cause)
+	if got.Status != http.StatusInternalServerError || got.Code != CodeInternal {
+		t.Errorf("AsError() = %+v, want internal error", got)
+	}
+	if got.Message != "Internal server error" {
+		t.Errorf("message = %q, should not leak the cause", got.Message)
+	}
+	if !errors.Is(got, cause) {
+		t.Error("internal error should keep the cause")
+	}
+}
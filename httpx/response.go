@@ -0,0 +1,161 @@
+package httpx
+
+import (
+	"encoding/json"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aquamarinepk/aqm/validation"
+)
+
+// Content types written by this package.
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeProblem = "application/problem+json"
+)
+
+// ErrorResponse is the default JSON error body. Errors holds the field
+// errors of a validation failure.
+type ErrorResponse struct {
+	Code              string                       `json:"code"`
+	Message           string                       `json:"message"`
+	RetryAfterSeconds int                          `json:"retry_after_seconds,omitempty"`
+	Errors            []validation.ValidationError `json:"errors,omitempty"`
+}
+
+// Problem is an RFC 9457 (formerly RFC 7807) problem details body. Code,
+// RetryAfterSeconds and Errors are extension members mirroring ErrorResponse.
+type Problem struct {
+	Type              string                       `json:"type"`
+	Title             string                       `json:"title"`
+	Status            int                          `json:"status"`
+	Detail            string                       `json:"detail,omitempty"`
+	Code              string                       `json:"code"`
+	RetryAfterSeconds int                          `json:"retry_after_seconds,omitempty"`
+	Errors            []validation.ValidationError `json:"errors,omitempty"`
+}
+
+// WriteJSON writes data as a JSON body with the given status, encoded with
+// the encoder installed with SetEncoder. When data cannot be encoded a
+// generic internal error is written instead.
+func WriteJSON(w http.ResponseWriter, status int, data any) {
+	writeEncoded(w, status, ContentTypeJSON, data)
+}
+
+func writeEncoded(w http.ResponseWriter, status int, contentType string, data any) {
+	body, err := Marshal(data)
+	if err != nil {
+		contentType, status = ContentTypeJSON, http.StatusInternalServerError
+		body, _ = json.Marshal(ErrorResponse{Code: CodeInternal, Message: "Cannot encode response"})
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+// WriteError writes err as an error response. Errors that are not an *Error
+// are written as a generic internal error. The body is problem+json when
+// the request's Accept header prefers it and ErrorResponse JSON otherwise.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	e := AsError(err)
+	resp := Response(w, e)
+	if WantsProblem(r) {
+		WriteProblem(w, e.Status, resp)
+		return
+	}
+	WriteErrorJSON(w, e.Status, resp)
+}
+
+// Response sets the Retry-After header on w when e carries a retry hint and
+// returns the body to write for e.
+func Response(w http.ResponseWriter, e *Error) ErrorResponse {
+	resp := ErrorResponse{Code: e.Code, Message: e.Message, Errors: e.Fields}
+	if e.RetryAfter > 0 {
+		resp.RetryAfterSeconds = SetRetryAfter(w, e.RetryAfter)
+	}
+	return resp
+}
+
+// WriteErrorJSON writes resp as an ErrorResponse JSON body.
+func WriteErrorJSON(w http.ResponseWriter, status int, resp ErrorResponse) {
+	WriteJSON(w, status, resp)
+}
+
+// WriteProblem writes resp as an application/problem+json body.
+func WriteProblem(w http.ResponseWriter, status int, resp ErrorResponse) {
+	writeEncoded(w, status, ContentTypeProblem, Problem{
+		Type:              "about:blank",
+		Title:             http.StatusText(status),
+		Status:            status,
+		Detail:            resp.Message,
+		Code:              resp.Code,
+		RetryAfterSeconds: resp.RetryAfterSeconds,
+		Errors:            resp.Errors,
+	})
+}
+
+// WantsProblem reports whether r's Accept header names
+// application/problem+json with a quality at least as high as
+// application/json. Wildcards alone never select problem+json, so clients
+// that do not ask for it keep getting the plain JSON body.
+func WantsProblem(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return false
+	}
+
+	problemQ, jsonQ := -1.0, 0.0
+	jsonSpecificity := -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case ContentTypeProblem:
+			problemQ = q
+		case ContentTypeJSON:
+			jsonQ, jsonSpecificity = q, 2
+		case "application/*":
+			if jsonSpecificity < 1 {
+				jsonQ, jsonSpecificity = q, 1
+			}
+		case "*/*":
+			if jsonSpecificity < 0 {
+				jsonQ, jsonSpecificity = q, 0
+			}
+		}
+	}
+	return problemQ > 0 && problemQ >= jsonQ
+}
+
+// RetryAfterSeconds converts d to whole seconds for a Retry-After hint,
+// rounding up so clients never retry early. Non-positive durations yield 1.
+func RetryAfterSeconds(d time.Duration) int {
+	secs := int(math.Ceil(d.Seconds()))
+	if secs < 1 {
+		return 1
+	}
+	return secs
+}
+
+// SetRetryAfter sets the Retry-After header for d and returns the advertised seconds.
+func SetRetryAfter(w http.ResponseWriter, d time.Duration) int {
+	secs := RetryAfterSeconds(d)
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	return secs
+}
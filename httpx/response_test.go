@@ -0,0 +1,174 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/validation"
+)
+
+func TestWantsProblem(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"application/*", false},
+		{"application/problem+json", true},
+		{"application/problem+json, application/json", true},
+		{"application/json, application/problem+json;q=0.9", false},
+		{"application/problem+json;q=0.5, */*;q=0.1", true},
+		{"application/problem+json;q=0", false},
+		{"text/html, application/problem+json", true},
+		{"application/problem+json;q=bad", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			if got := WantsProblem(r); got != tt.want {
+				t.Errorf("WantsProblem(%q) = %v, want %v", tt.accept, got, tt.want)
+			}
+		})
+	}
+
+	if WantsProblem(nil) {
+		t.Error("nil request should not want problem+json")
+	}
+}
+
+func TestWriteErrorJSON(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	WriteError(w, r, NotFound("USER_NOT_FOUND", "User not found"))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if got := w.Header().Get("Content-Type"); got != ContentTypeJSON {
+		t.Errorf("Content-Type = %q, want %q", got, ContentTypeJSON)
+	}
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("cannot decode response: %v", err)
+	}
+	if resp.Code != "USER_NOT_FOUND" || resp.Message != "User not found" {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestWriteErrorFields(t *testing.T) {
+	var errs validation.ValidationErrors
+	errs.AddCode("limit", validation.CodeFormat, "must be an integer")
+	errs.AddCode("offset", validation.CodeFormat, "must be an integer")
+
+	for _, accept := range []string{ContentTypeJSON, ContentTypeProblem} {
+		t.Run(accept, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", accept)
+			w := httptest.NewRecorder()
+
+			WriteError(w, r, ValidationFailed(errs))
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			var body struct {
+				Code   string                       `json:"code"`
+				Errors []validation.ValidationError `json:"errors"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("cannot decode response: %v", err)
+			}
+			if body.Code != CodeValidation {
+				t.Errorf("code = %q, want %q", body.Code, CodeValidation)
+			}
+			if !reflect.DeepEqual(body.Errors, []validation.ValidationError(errs)) {
+				t.Errorf("errors = %+v, want %+v", body.Errors, errs)
+			}
+		})
+	}
+}
+
+func TestWriteErrorProblem(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", ContentTypeProblem)
+	w := httptest.NewRecorder()
+
+	WriteError(w, r, NewError(http.StatusTooManyRequests, "RATE_LIMITED", "Too many requests").WithRetryAfter(1500*time.Millisecond))
+
+	if got := w.Header().Get("Content-Type"); got != ContentTypeProblem {
+		t.Errorf("Content-Type = %q, want %q", got, ContentTypeProblem)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+
+	var resp Problem
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("cannot decode problem: %v", err)
+	}
+	want := Problem{
+		Type:              "about:blank",
+		Title:             "Too Many Requests",
+		Status:            http.StatusTooManyRequests,
+		Detail:            "Too many requests",
+		Code:              "RATE_LIMITED",
+		RetryAfterSeconds: 2,
+	}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("problem = %+v, want %+v", resp, want)
+	}
+}
+
+func TestWriteErrorUntyped(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	WriteError(w, r, errors.New("pq: connection refused"))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if w.Header().Get("Retry-After") != "" {
+		t.Error("Retry-After should not be set without a hint")
+	}
+
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Code != CodeInternal || resp.Message != "Internal server error" {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{0, 1},
+		{-time.Second, 1},
+		{time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{time.Minute, 60},
+	}
+
+	for _, tt := range tests {
+		if got := RetryAfterSeconds(tt.d); got != tt.want {
+			t.Errorf("RetryAfterSeconds(%v) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
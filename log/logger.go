@@ -1,7 +1,9 @@
 package log
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
@@ -16,6 +18,12 @@ const (
 	ErrorLevel
 )
 
+// Output formats accepted by NewLoggerWithFormat.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
 // Logger provides structured logging with level-based filtering.
 type Logger interface {
 	Debug(v ...any)
@@ -37,17 +45,25 @@ type slogLogger struct {
 // Defaults to InfoLevel if level string is unrecognized.
 // Output format is JSON if LOG_FORMAT=json, otherwise human-readable text.
 func NewLogger(logLevelStr string) Logger {
-	level := parseLevel(logLevelStr)
+	return NewLoggerWithFormat(logLevelStr, os.Getenv("LOG_FORMAT"))
+}
+
+// NewLoggerWithFormat creates a logger with the specified level and output format.
+// Format is FormatJSON or FormatText (case-insensitive); anything else falls back to text.
+func NewLoggerWithFormat(logLevelStr, format string) Logger {
+	return newLogger(os.Stdout, parseLevel(logLevelStr), format)
+}
+
+func newLogger(w io.Writer, level LogLevel, format string) *slogLogger {
+	opts := &slog.HandlerOptions{
+		Level: toSlogLevel(level),
+	}
 
 	var handler slog.Handler
-	if os.Getenv("LOG_FORMAT") == "json" {
-		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-			Level: toSlogLevel(level),
-		})
+	if strings.EqualFold(format, FormatJSON) {
+		handler = slog.NewJSONHandler(w, opts)
 	} else {
-		handler = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-			Level: toSlogLevel(level),
-		})
+		handler = slog.NewTextHandler(w, opts)
 	}
 
 	return &slogLogger{
@@ -58,7 +74,8 @@ func NewLogger(logLevelStr string) Logger {
 
 func (l *slogLogger) Debug(v ...any) {
 	if l.logLevel <= DebugLevel {
-		l.logger.Debug(fmt.Sprint(v...))
+		msg, attrs := split(v)
+		l.logger.Debug(msg, attrs...)
 	}
 }
 
@@ -70,7 +87,8 @@ func (l *slogLogger) Debugf(format string, a ...any) {
 
 func (l *slogLogger) Info(v ...any) {
 	if l.logLevel <= InfoLevel {
-		l.logger.Info(fmt.Sprint(v...))
+		msg, attrs := split(v)
+		l.logger.Info(msg, attrs...)
 	}
 }
 
@@ -82,7 +100,8 @@ func (l *slogLogger) Infof(format string, a ...any) {
 
 func (l *slogLogger) Error(v ...any) {
 	if l.logLevel <= ErrorLevel {
-		l.logger.Error(fmt.Sprint(v...))
+		msg, attrs := split(v)
+		l.logger.Error(msg, attrs...)
 	}
 }
 
@@ -92,6 +111,27 @@ func (l *slogLogger) Errorf(format string, a ...any) {
 	}
 }
 
+// split separates a leading message from trailing key-value pairs, so that
+// logger.Info("User created", "user_id", id) emits user_id as a structured field.
+// Arguments that don't follow that shape are formatted into the message.
+func split(v []any) (string, []any) {
+	if len(v) < 3 || len(v)%2 == 0 {
+		return fmt.Sprint(v...), nil
+	}
+
+	msg, ok := v[0].(string)
+	if !ok {
+		return fmt.Sprint(v...), nil
+	}
+	for i := 1; i < len(v); i += 2 {
+		if _, ok := v[i].(string); !ok {
+			return fmt.Sprint(v...), nil
+		}
+	}
+
+	return msg, v[1:]
+}
+
 // With returns a new logger with additional contextual fields.
 // The returned logger preserves the current log level.
 func (l *slogLogger) With(args ...any) Logger {
@@ -117,6 +157,25 @@ func NewNoopLogger() Logger {
 	return noopLogger{}
 }
 
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying logger.
+func NewContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx by NewContext,
+// or a no-op logger if there is none.
+func FromContext(ctx context.Context) Logger {
+	if ctx == nil {
+		return noopLogger{}
+	}
+	if logger, ok := ctx.Value(ctxKey{}).(Logger); ok {
+		return logger
+	}
+	return noopLogger{}
+}
+
 func parseLevel(level string) LogLevel {
 	level = strings.ToLower(level)
 	switch level {
@@ -2,6 +2,8 @@ package log
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"log/slog"
 	"strings"
 	"testing"
@@ -231,6 +233,98 @@ func TestNewNoopLogger(t *testing.T) {
 	contextLogger.Info("test")
 }
 
+func TestNewLoggerWithFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		wantJSON bool
+	}{
+		{"json", FormatJSON, true},
+		{"json uppercase", "JSON", true},
+		{"text", FormatText, false},
+		{"empty defaults to text", "", false},
+		{"unknown defaults to text", "xml", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := newLogger(buf, InfoLevel, tt.format)
+			logger.Info("hello")
+
+			var entry map[string]any
+			isJSON := json.Unmarshal(buf.Bytes(), &entry) == nil
+			if isJSON != tt.wantJSON {
+				t.Errorf("JSON output = %v, want %v (output: %q)", isJSON, tt.wantJSON, buf.String())
+			}
+		})
+	}
+}
+
+func TestStructuredFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newLogger(buf, InfoLevel, FormatJSON)
+
+	logger.With("service", "authn").Info("User created", "user_id", "u-1", "attempts", 3)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("cannot decode log entry %q: %v", buf.String(), err)
+	}
+
+	want := map[string]any{
+		"msg":      "User created",
+		"service":  "authn",
+		"user_id":  "u-1",
+		"attempts": float64(3),
+	}
+	for key, val := range want {
+		if entry[key] != val {
+			t.Errorf("entry[%s] = %v, want %v", key, entry[key], val)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []any
+		wantMsg   string
+		wantAttrs int
+	}{
+		{"message only", []any{"hello"}, "hello", 0},
+		{"message with fields", []any{"hello", "k", "v"}, "hello", 2},
+		{"odd fields", []any{"hello", "k"}, "hellok", 0},
+		{"non-string key", []any{"hello", 1, "v"}, "hello1v", 0},
+		{"non-string message", []any{42, "k", "v"}, "42kv", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, attrs := split(tt.args)
+			if msg != tt.wantMsg || len(attrs) != tt.wantAttrs {
+				t.Errorf("split() = %q, %d attrs, want %q, %d attrs", msg, len(attrs), tt.wantMsg, tt.wantAttrs)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newTestLogger(buf, InfoLevel)
+
+	ctx := NewContext(context.Background(), logger)
+	FromContext(ctx).Info("from context")
+
+	if !strings.Contains(buf.String(), "from context") {
+		t.Errorf("FromContext() should return stored logger, output: %q", buf.String())
+	}
+
+	if _, ok := FromContext(context.Background()).(noopLogger); !ok {
+		t.Error("FromContext() without logger should return noop logger")
+	}
+}
+
 func newTestLogger(buf *bytes.Buffer, level LogLevel) *slogLogger {
 	handler := slog.NewTextHandler(buf, &slog.HandlerOptions{
 		Level: toSlogLevel(level),
@@ -0,0 +1,50 @@
+package fake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aquamarinepk/aqm/mail"
+)
+
+// Sender records messages instead of delivering them.
+type Sender struct {
+	mu       sync.Mutex
+	messages []*mail.Message
+	err      error
+}
+
+func NewSender() *Sender {
+	return &Sender{}
+}
+
+func (s *Sender) Send(ctx context.Context, msg *mail.Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (s *Sender) Messages() []*mail.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*mail.Message(nil), s.messages...)
+}
+
+// FailWith makes later sends return err; nil restores normal behavior.
+func (s *Sender) FailWith(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+}
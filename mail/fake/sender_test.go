@@ -0,0 +1,32 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/mail"
+)
+
+func TestSender(t *testing.T) {
+	ctx := context.Background()
+	s := NewSender()
+	msg := &mail.Message{From: "a@acme.test", To: []string{"b@acme.test"}, Subject: "hi"}
+
+	if err := s.Send(ctx, msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := s.Send(ctx, &mail.Message{From: "a@acme.test"}); !errors.Is(err, mail.ErrNoRecipients) {
+		t.Errorf("Send() without recipients error = %v, want ErrNoRecipients", err)
+	}
+
+	boom := errors.New("boom")
+	s.FailWith(boom)
+	if err := s.Send(ctx, msg); !errors.Is(err, boom) {
+		t.Errorf("Send() error = %v, want %v", err, boom)
+	}
+
+	if got := s.Messages(); len(got) != 1 || got[0] != msg {
+		t.Errorf("Messages() = %v, want the one successful message", got)
+	}
+}
@@ -0,0 +1,125 @@
+// Package mail sends transactional email such as PINs and password resets.
+//
+// A Sender delivers messages; SMTPSender talks to a mail server and
+// LogSender writes messages to a logger for development. A Mailer renders
+// named templates into messages and sends them.
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/log"
+)
+
+// Mail drivers accepted in config.MailConfig.Driver.
+const (
+	DriverNone = "none"
+	DriverLog  = "log"
+	DriverSMTP = "smtp"
+)
+
+var (
+	ErrNoRecipients   = errors.New("mail has no recipients")
+	ErrNoSender       = errors.New("mail has no sender address")
+	ErrUnknownDriver  = errors.New("unknown mail driver")
+	ErrUnknownMessage = errors.New("unknown mail template")
+)
+
+// Message is an email. HTML is optional; when set the message is sent as
+// multipart/alternative with Text as the plain part.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Validate checks that the message can be delivered.
+func (m *Message) Validate() error {
+	if m.From == "" {
+		return ErrNoSender
+	}
+	if len(m.To) == 0 {
+		return ErrNoRecipients
+	}
+	for _, to := range m.To {
+		if to == "" {
+			return ErrNoRecipients
+		}
+	}
+	return nil
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// FromConfig builds the sender selected by cfg.Driver. It returns a nil
+// Sender for DriverNone, meaning mail is disabled.
+func FromConfig(cfg config.MailConfig, logger log.Logger) (Sender, error) {
+	switch cfg.Driver {
+	case DriverNone, "":
+		return nil, nil
+	case DriverLog:
+		return NewLogSender(logger), nil
+	case DriverSMTP:
+		return NewSMTPSender(cfg.SMTP), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDriver, cfg.Driver)
+	}
+}
+
+// LogSender logs messages instead of delivering them.
+type LogSender struct {
+	log log.Logger
+}
+
+func NewLogSender(logger log.Logger) *LogSender {
+	return &LogSender{log: logger}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg *Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+	s.log.Infof("mail: from=%s to=%v subject=%q\n%s", msg.From, msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+// Mailer renders templates into messages sent from a fixed address.
+type Mailer struct {
+	sender    Sender
+	templates *Templates
+	from      string
+}
+
+// NewMailer creates a Mailer. A nil templates uses DefaultTemplates.
+func NewMailer(sender Sender, templates *Templates, from string) *Mailer {
+	if templates == nil {
+		templates = DefaultTemplates()
+	}
+	return &Mailer{sender: sender, templates: templates, from: from}
+}
+
+// Send renders the template name with data and sends it to to.
+func (m *Mailer) Send(ctx context.Context, to, name string, data any) error {
+	msg, err := m.templates.Render(name, data)
+	if err != nil {
+		return err
+	}
+	msg.From = m.from
+	msg.To = []string{to}
+
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+	if err := m.sender.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send %s mail: %w", name, err)
+	}
+	return nil
+}
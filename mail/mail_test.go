@@ -0,0 +1,138 @@
+package mail_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/mail"
+	"github.com/aquamarinepk/aqm/mail/fake"
+)
+
+type data struct {
+	Name     string
+	Username string
+	PIN      string
+	Password string
+}
+
+func TestMailer(t *testing.T) {
+	sender := fake.NewSender()
+	m := mail.NewMailer(sender, nil, "Acme <noreply@acme.test>")
+
+	err := m.Send(context.Background(), "ann@example.com", mail.TemplatePIN, data{Name: "Ann", PIN: "123456"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	msgs := sender.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(msgs))
+	}
+	msg := msgs[0]
+	if msg.From != "Acme <noreply@acme.test>" || msg.To[0] != "ann@example.com" {
+		t.Errorf("from/to = %q/%v", msg.From, msg.To)
+	}
+	if msg.Subject != "Your sign-in PIN" {
+		t.Errorf("subject = %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "123456") || !strings.Contains(msg.HTML, "<strong>123456</strong>") {
+		t.Errorf("PIN missing from bodies: text=%q html=%q", msg.Text, msg.HTML)
+	}
+
+	if err := m.Send(context.Background(), "ann@example.com", "invoice", data{}); !errors.Is(err, mail.ErrUnknownMessage) {
+		t.Errorf("Send() unknown template error = %v, want ErrUnknownMessage", err)
+	}
+	if err := m.Send(context.Background(), "", mail.TemplatePIN, data{}); !errors.Is(err, mail.ErrNoRecipients) {
+		t.Errorf("Send() without recipient error = %v, want ErrNoRecipients", err)
+	}
+
+	sender.FailWith(errors.New("relay down"))
+	if err := m.Send(context.Background(), "ann@example.com", mail.TemplatePIN, data{}); err == nil {
+		t.Error("expected send error to be returned")
+	}
+}
+
+func TestNewTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pin.subject.tmpl":     {Data: []byte("PIN for {{.Username}}\n")},
+		"invoice.subject.tmpl": {Data: []byte("Invoice")},
+		"invoice.text.tmpl":    {Data: []byte("Total: {{.Password}}")},
+	}
+	tmpl, err := mail.NewTemplates(fsys)
+	if err != nil {
+		t.Fatalf("NewTemplates() error = %v", err)
+	}
+
+	msg, err := tmpl.Render(mail.TemplatePIN, data{Username: "ann", PIN: "42"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if msg.Subject != "PIN for ann" || !strings.Contains(msg.Text, "42") {
+		t.Errorf("Render() = %+v, want overridden subject and built-in body", msg)
+	}
+
+	msg, err = tmpl.Render("invoice", data{Password: "10"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if msg.Text != "Total: 10" || msg.HTML != "" {
+		t.Errorf("Render() = %+v", msg)
+	}
+
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{name: "no subject", fsys: fstest.MapFS{"x.text.tmpl": {Data: []byte("x")}}},
+		{name: "no body", fsys: fstest.MapFS{"x.subject.tmpl": {Data: []byte("x")}}},
+		{name: "bad part", fsys: fstest.MapFS{"x.body.tmpl": {Data: []byte("x")}}},
+		{name: "bad name", fsys: fstest.MapFS{"x.tmpl": {Data: []byte("x")}}},
+		{name: "parse error", fsys: fstest.MapFS{"pin.text.tmpl": {Data: []byte("{{.PIN")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := mail.NewTemplates(tt.fsys); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.MailConfig
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "none", cfg: config.MailConfig{Driver: mail.DriverNone}, wantNil: true},
+		{name: "log", cfg: config.MailConfig{Driver: mail.DriverLog}},
+		{name: "smtp", cfg: config.MailConfig{Driver: mail.DriverSMTP, SMTP: config.SMTPConfig{Host: "localhost"}}},
+		{name: "unknown", cfg: config.MailConfig{Driver: "sendgrid"}, wantNil: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, err := mail.FromConfig(tt.cfg, log.NewNoopLogger())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (sender == nil) != tt.wantNil {
+				t.Errorf("FromConfig() sender = %v, wantNil %v", sender, tt.wantNil)
+			}
+		})
+	}
+
+	sender, _ := mail.FromConfig(config.MailConfig{Driver: mail.DriverLog}, log.NewNoopLogger())
+	if err := sender.Send(context.Background(), &mail.Message{From: "a@b.test", To: []string{"c@d.test"}}); err != nil {
+		t.Errorf("LogSender.Send() error = %v", err)
+	}
+	if err := sender.Send(context.Background(), &mail.Message{To: []string{"c@d.test"}}); !errors.Is(err, mail.ErrNoSender) {
+		t.Errorf("LogSender.Send() without from error = %v, want ErrNoSender", err)
+	}
+}
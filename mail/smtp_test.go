@@ -0,0 +1,150 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+)
+
+// smtpServer accepts one session and records the envelope and data.
+type smtpServer struct {
+	addr     string
+	starttls bool
+	done     chan struct{}
+	from     string
+	rcpt     []string
+	data     string
+}
+
+func newSMTPServer(t *testing.T, starttls bool) *smtpServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &smtpServer{addr: ln.Addr().String(), starttls: starttls, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s.serve(textproto.NewConn(conn))
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(c *textproto.Conn) {
+	c.PrintfLine("220 test ESMTP")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.Fields(line + " ")[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			if s.starttls {
+				c.PrintfLine("250-test\r\n250 STARTTLS")
+			} else {
+				c.PrintfLine("250 test")
+			}
+		case "MAIL":
+			s.from = strings.TrimSuffix(strings.TrimPrefix(line, "MAIL FROM:<"), ">")
+			c.PrintfLine("250 OK")
+		case "RCPT":
+			s.rcpt = append(s.rcpt, strings.TrimSuffix(strings.TrimPrefix(line, "RCPT TO:<"), ">"))
+			c.PrintfLine("250 OK")
+		case "DATA":
+			c.PrintfLine("354 go ahead")
+			data, _ := c.ReadDotBytes()
+			s.data = string(data)
+			c.PrintfLine("250 OK")
+		case "QUIT":
+			c.PrintfLine("221 bye")
+			return
+		default:
+			c.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (s *smtpServer) config(tls string) config.SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.addr)
+	p, _ := strconv.Atoi(port)
+	return config.SMTPConfig{Host: host, Port: p, TLS: tls}
+}
+
+func TestSMTPSender(t *testing.T) {
+	srv := newSMTPServer(t, false)
+	sender := NewSMTPSender(srv.config(TLSNone))
+	sender.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := sender.Send(ctx, &Message{
+		From:    "Acme <noreply@acme.test>",
+		To:      []string{"ann@example.com"},
+		Subject: "Grüße",
+		Text:    "Hello Ann",
+		HTML:    "<p>Hello Ann</p>",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	<-srv.done
+
+	if srv.from != "noreply@acme.test" || len(srv.rcpt) != 1 || srv.rcpt[0] != "ann@example.com" {
+		t.Errorf("envelope = %q -> %v", srv.from, srv.rcpt)
+	}
+
+	msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(srv.data))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("cannot parse message headers: %v", err)
+	}
+	if got := msg.Get("Subject"); got != "=?utf-8?q?Gr=C3=BC=C3=9Fe?=" {
+		t.Errorf("Subject = %q", got)
+	}
+	if got := msg.Get("Date"); got != "Fri, 02 Jan 2026 03:04:05 +0000" {
+		t.Errorf("Date = %q", got)
+	}
+	if !strings.HasPrefix(msg.Get("Content-Type"), "multipart/alternative; boundary=") {
+		t.Errorf("Content-Type = %q", msg.Get("Content-Type"))
+	}
+	if !strings.Contains(srv.data, "Hello Ann") || !strings.Contains(srv.data, "<p>Hello Ann</p>") {
+		t.Errorf("bodies missing from data:\n%s", srv.data)
+	}
+}
+
+func TestSMTPSenderRequiresStartTLS(t *testing.T) {
+	srv := newSMTPServer(t, false)
+	sender := NewSMTPSender(srv.config(""))
+
+	err := sender.Send(context.Background(), &Message{From: "a@acme.test", To: []string{"b@acme.test"}, Text: "x"})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Send() error = %v, want STARTTLS error", err)
+	}
+}
+
+func TestSMTPSenderErrors(t *testing.T) {
+	sender := NewSMTPSender(config.SMTPConfig{Host: "127.0.0.1", Port: 1, TLS: TLSNone})
+
+	if err := sender.Send(context.Background(), &Message{To: []string{"b@acme.test"}}); err != ErrNoSender {
+		t.Errorf("Send() without from error = %v, want ErrNoSender", err)
+	}
+	if err := sender.Send(context.Background(), &Message{From: "a@acme.test", To: []string{"b@acme.test"}}); err == nil {
+		t.Error("expected connection error")
+	}
+}
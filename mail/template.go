@@ -0,0 +1,140 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Templates used by the authn handlers.
+const (
+	TemplateWelcome       = "welcome"
+	TemplatePIN           = "pin"
+	TemplatePasswordReset = "password_reset"
+	TemplateMagicLink     = "magic_link"
+	TemplateEmailChange   = "email_change"
+	TemplateEmailChanged  = "email_changed"
+)
+
+//go:embed templates/*.tmpl
+var defaultFS embed.FS
+
+// Templates holds named messages. Each message is made of up to three
+// files: <name>.subject.tmpl and <name>.text.tmpl, both text/template, and
+// an optional <name>.html.tmpl parsed with html/template.
+type Templates struct {
+	subject map[string]*texttemplate.Template
+	text    map[string]*texttemplate.Template
+	html    map[string]*htmltemplate.Template
+}
+
+// DefaultTemplates returns the built-in welcome, pin, password_reset,
+// magic_link, email_change and email_changed messages.
+func DefaultTemplates() *Templates {
+	t, err := NewTemplates(nil)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// NewTemplates loads the built-in templates and then the *.tmpl files at
+// the root of fsys, typically an embed.FS, which replace built-in files of
+// the same name. fsys may be nil.
+func NewTemplates(fsys fs.FS) (*Templates, error) {
+	t := &Templates{
+		subject: map[string]*texttemplate.Template{},
+		text:    map[string]*texttemplate.Template{},
+		html:    map[string]*htmltemplate.Template{},
+	}
+
+	sub, _ := fs.Sub(defaultFS, "templates")
+	if err := t.load(sub); err != nil {
+		return nil, err
+	}
+	if fsys != nil {
+		if err := t.load(fsys); err != nil {
+			return nil, err
+		}
+	}
+
+	for name := range t.text {
+		if t.subject[name] == nil {
+			return nil, fmt.Errorf("mail template %s has no subject", name)
+		}
+	}
+	for name := range t.subject {
+		if t.text[name] == nil {
+			return nil, fmt.Errorf("mail template %s has no text body", name)
+		}
+	}
+	return t, nil
+}
+
+func (t *Templates) load(fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "*.tmpl")
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+
+		name, part, ok := strings.Cut(strings.TrimSuffix(file, ".tmpl"), ".")
+		if !ok {
+			return fmt.Errorf("mail template %s: name must be <message>.<subject|text|html>.tmpl", file)
+		}
+
+		switch part {
+		case "subject":
+			t.subject[name], err = texttemplate.New(file).Parse(strings.TrimSpace(string(data)))
+		case "text":
+			t.text[name], err = texttemplate.New(file).Parse(string(data))
+		case "html":
+			t.html[name], err = htmltemplate.New(file).Parse(string(data))
+		default:
+			return fmt.Errorf("mail template %s: unknown part %q", file, part)
+		}
+		if err != nil {
+			return fmt.Errorf("parse mail template %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// Render executes the named message with data. From and To are left empty.
+func (t *Templates) Render(name string, data any) (*Message, error) {
+	subject, ok := t.subject[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMessage, name)
+	}
+
+	var buf bytes.Buffer
+	msg := &Message{}
+	if err := subject.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	msg.Subject = buf.String()
+
+	buf.Reset()
+	if err := t.text[name].Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("render %s text: %w", name, err)
+	}
+	msg.Text = buf.String()
+
+	if html, ok := t.html[name]; ok {
+		buf.Reset()
+		if err := html.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("render %s html: %w", name, err)
+		}
+		msg.HTML = buf.String()
+	}
+	return msg, nil
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"slices"
 
 	"github.com/aquamarinepk/aqm/auth"
 )
@@ -9,17 +10,29 @@ import (
 // AuthzChecker implements RoleChecker using auth.GrantStore.
 // NOTE: With username-based grants, this checker uses usernames directly
 // instead of parsing UUIDs. The username is the natural key for authorization.
+// Permission checks go through an auth.AuthorizationEngine, by default an
+// auth.GrantEngine over the same store. Engines implementing
+// auth.BatchAuthorizer decide a list of permissions in one call.
 type AuthzChecker struct {
 	grantStore auth.GrantStore
+	engine     auth.AuthorizationEngine
 }
 
 // NewAuthzChecker creates a new authorization checker.
 func NewAuthzChecker(grantStore auth.GrantStore) *AuthzChecker {
 	return &AuthzChecker{
 		grantStore: grantStore,
+		engine:     auth.NewGrantEngine(grantStore),
 	}
 }
 
+// WithEngine replaces the engine used for permission checks. Role checks
+// still read grants directly.
+func (a *AuthzChecker) WithEngine(engine auth.AuthorizationEngine) *AuthzChecker {
+	a.engine = engine
+	return a
+}
+
 // HasRole checks if a user has a specific role.
 func (a *AuthzChecker) HasRole(ctx context.Context, username string, roleName string) (bool, error) {
 	if username == "" {
@@ -36,21 +49,7 @@ func (a *AuthzChecker) CheckPermission(ctx context.Context, username string, per
 		return false, nil
 	}
 
-	roles, err := a.grantStore.GetUserRoles(ctx, username)
-	if err != nil {
-		return false, err
-	}
-
-	for _, role := range roles {
-		if role.Status != auth.RoleStatusActive {
-			continue
-		}
-		if auth.HasPermission(role.Permissions, permission) {
-			return true, nil
-		}
-	}
-
-	return false, nil
+	return a.engine.Authorize(ctx, username, permission, "")
 }
 
 // CheckAnyPermission checks if a user has any of the specified permissions.
@@ -59,17 +58,18 @@ func (a *AuthzChecker) CheckAnyPermission(ctx context.Context, username string,
 		return false, nil
 	}
 
-	roles, err := a.grantStore.GetUserRoles(ctx, username)
-	if err != nil {
-		return false, err
+	if batch, ok := a.engine.(auth.BatchAuthorizer); ok {
+		allowed, err := batch.AuthorizeAll(ctx, username, permissions, "")
+		if err != nil {
+			return false, err
+		}
+		return slices.Contains(allowed, true), nil
 	}
 
-	for _, role := range roles {
-		if role.Status != auth.RoleStatusActive {
-			continue
-		}
-		if auth.HasAnyPermission(role.Permissions, permissions) {
-			return true, nil
+	for _, permission := range permissions {
+		allowed, err := a.engine.Authorize(ctx, username, permission, "")
+		if err != nil || allowed {
+			return allowed, err
 		}
 	}
 
@@ -82,18 +82,20 @@ func (a *AuthzChecker) CheckAllPermissions(ctx context.Context, username string,
 		return false, nil
 	}
 
-	roles, err := a.grantStore.GetUserRoles(ctx, username)
-	if err != nil {
-		return false, err
+	if batch, ok := a.engine.(auth.BatchAuthorizer); ok {
+		allowed, err := batch.AuthorizeAll(ctx, username, permissions, "")
+		if err != nil {
+			return false, err
+		}
+		return !slices.Contains(allowed, false), nil
 	}
 
-	allPerms := make([]string, 0)
-	for _, role := range roles {
-		if role.Status != auth.RoleStatusActive {
-			continue
+	for _, permission := range permissions {
+		allowed, err := a.engine.Authorize(ctx, username, permission, "")
+		if err != nil || !allowed {
+			return false, err
 		}
-		allPerms = append(allPerms, role.Permissions...)
 	}
 
-	return auth.HasAllPermissions(allPerms, permissions), nil
+	return true, nil
 }
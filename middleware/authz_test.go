@@ -298,6 +298,78 @@ func (f *fakeRoleChecker) CheckAllPermissions(ctx context.Context, userID string
 	return f.checkAllPermissions, f.err
 }
 
+// engineFunc adapts a function to auth.AuthorizationEngine.
+type engineFunc func(subject, permission string) (bool, error)
+
+func (f engineFunc) Authorize(ctx context.Context, subject, permission, resource string) (bool, error) {
+	return f(subject, permission)
+}
+
+func TestAuthzChecker_WithEngine(t *testing.T) {
+	var calls []string
+	engine := engineFunc(func(subject, permission string) (bool, error) {
+		calls = append(calls, permission)
+		return subject == "ann" && permission == "posts:read", nil
+	})
+	checker := NewAuthzChecker(fake.NewGrantStore(fake.NewRoleStore())).WithEngine(engine)
+	ctx := context.Background()
+
+	if ok, _ := checker.CheckPermission(ctx, "ann", "posts:read"); !ok {
+		t.Error("CheckPermission() = false, want engine decision true")
+	}
+	if ok, _ := checker.CheckAnyPermission(ctx, "ann", []string{"posts:write", "posts:read"}); !ok {
+		t.Error("CheckAnyPermission() = false, want true")
+	}
+	if ok, _ := checker.CheckAllPermissions(ctx, "ann", []string{"posts:read", "posts:write"}); ok {
+		t.Error("CheckAllPermissions() = true, want false")
+	}
+	if ok, _ := checker.CheckPermission(ctx, "", "posts:read"); ok {
+		t.Error("CheckPermission() with empty username = true, want false")
+	}
+	if len(calls) != 5 {
+		t.Errorf("engine called %d times, want 5: %v", len(calls), calls)
+	}
+}
+
+// countingGrantStore counts the reads of user roles.
+type countingGrantStore struct {
+	auth.GrantStore
+	reads int
+}
+
+func (s *countingGrantStore) GetUserRoles(ctx context.Context, username string) ([]*auth.Role, error) {
+	s.reads++
+	return s.GrantStore.GetUserRoles(ctx, username)
+}
+
+func TestAuthzChecker_ReadsRolesOncePerCheck(t *testing.T) {
+	ctx := context.Background()
+	roleStore := fake.NewRoleStore()
+	grants := &countingGrantStore{GrantStore: fake.NewGrantStore(roleStore)}
+
+	role := auth.NewRole()
+	role.Name, role.Permissions, role.Status = "editor", []string{"posts:read", "posts:write"}, auth.RoleStatusActive
+	role.BeforeCreate()
+	_ = roleStore.Create(ctx, role)
+	_ = grants.Create(ctx, auth.NewGrant("ann", role.ID, "system"))
+
+	checker := NewAuthzChecker(grants)
+	permissions := []string{"users:read", "users:write", "posts:read", "posts:write"}
+
+	if ok, err := checker.CheckAnyPermission(ctx, "ann", permissions); err != nil || !ok {
+		t.Errorf("CheckAnyPermission() = %v, %v, want true", ok, err)
+	}
+	if ok, err := checker.CheckAllPermissions(ctx, "ann", permissions); err != nil || ok {
+		t.Errorf("CheckAllPermissions() = %v, %v, want false", ok, err)
+	}
+	if ok, err := checker.CheckAllPermissions(ctx, "ann", permissions[2:]); err != nil || !ok {
+		t.Errorf("CheckAllPermissions(granted) = %v, %v, want true", ok, err)
+	}
+	if grants.reads != 3 {
+		t.Errorf("GetUserRoles called %d times, want 3", grants.reads)
+	}
+}
+
 func TestRequireRole(t *testing.T) {
 	tests := []struct {
 		name           string
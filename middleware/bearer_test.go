@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+func TestBearer(t *testing.T) {
+	tests := []struct {
+		name          string
+		header        string
+		validator     SessionValidator
+		wantStatus    int
+		wantUserID    string
+		wantSessionID string
+	}{
+		{
+			name:          "valid token",
+			header:        "Bearer good-token",
+			validator:     &mockValidator{userID: "user-1", sessionID: "sess-1"},
+			wantStatus:    http.StatusOK,
+			wantUserID:    "user-1",
+			wantSessionID: "sess-1",
+		},
+		{
+			name:       "missing header",
+			validator:  &mockValidator{userID: "user-1"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong scheme",
+			header:     "Basic dXNlcjpwYXNz",
+			validator:  &mockValidator{userID: "user-1"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid token",
+			header:     "Bearer bad-token",
+			validator:  &mockValidator{err: errors.New("invalid")},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID, gotSessionID string
+			handler := Bearer(tt.validator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID = GetUserID(r.Context())
+				gotSessionID = GetSessionID(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if gotUserID != tt.wantUserID || gotSessionID != tt.wantSessionID {
+				t.Errorf("context = (%q, %q), want (%q, %q)", gotUserID, gotSessionID, tt.wantUserID, tt.wantSessionID)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 response should set WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"Bearer abc", "abc"},
+		{"bearer abc", "abc"},
+		{"Bearer  abc ", "abc"},
+		{"Basic abc", ""},
+		{"Bearer", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", tt.header)
+			if got := BearerToken(req); got != tt.want {
+				t.Errorf("BearerToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBearerImpersonation(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	token, _ := crypto.GenerateToken(crypto.TokenClaims{
+		Subject:   "user-1",
+		SessionID: "sess-1",
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		Actor:     "admin-1",
+	}, priv)
+
+	var gotUserID, gotImpersonator string
+	handler := Bearer(NewTokenValidator(pub))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID = GetUserID(r.Context())
+		gotImpersonator = GetImpersonator(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotUserID != "user-1" || gotImpersonator != "admin-1" {
+		t.Errorf("user = %q, impersonator = %q, want user-1 and admin-1", gotUserID, gotImpersonator)
+	}
+}
+
+func TestBearerScopeAndClaims(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	token, _ := crypto.GenerateToken(crypto.TokenClaims{
+		Subject:   "user-1",
+		SessionID: "sess-1",
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		Context:   map[string]string{"tenant": "acme"},
+		Scope:     []string{"orders:read"},
+	}, priv)
+
+	var gotTenant string
+	var gotScope []string
+	handler := Bearer(NewTokenValidator(pub))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTenant = GetClaim(r.Context(), "tenant")
+		gotScope = GetScope(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotTenant != "acme" || len(gotScope) != 1 || gotScope[0] != "orders:read" {
+		t.Errorf("tenant = %q, scope = %v, want acme and [orders:read]", gotTenant, gotScope)
+	}
+	if GetClaims(context.Background()) != nil || GetClaim(context.Background(), "tenant") != "" {
+		t.Error("GetClaims() without a token returned claims")
+	}
+}
+
+func TestBearerMFAPending(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	newToken := func(pending bool) string {
+		token, _ := crypto.GenerateToken(crypto.TokenClaims{
+			Subject:    "user-1",
+			SessionID:  "sess-1",
+			ExpiresAt:  time.Now().Add(time.Hour).Unix(),
+			MFAPending: pending,
+		}, priv)
+		return token
+	}
+	pending, session := newToken(true), newToken(false)
+	validator := NewTokenValidator(pub)
+
+	serve := func(mw func(http.Handler) http.Handler, token string) (*httptest.ResponseRecorder, string) {
+		var gotUserID string
+		handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotUserID = GetUserID(r.Context())
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec, gotUserID
+	}
+
+	rec, _ := serve(Bearer(validator), pending)
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != `Bearer error="insufficient_user_authentication"` {
+		t.Errorf("Bearer(pending) = %d %q, want 401 insufficient_user_authentication", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+	if rec, userID := serve(BearerMFAPending(validator), pending); rec.Code != http.StatusOK || userID != "user-1" {
+		t.Errorf("BearerMFAPending(pending) = %d, user %q, want 200 for user-1", rec.Code, userID)
+	}
+	if rec, _ := serve(BearerMFAPending(validator), session); rec.Code != http.StatusUnauthorized {
+		t.Errorf("BearerMFAPending(session) = %d, want 401", rec.Code)
+	}
+	if _, _, err := validator.ValidateToken(pending); !errors.Is(err, ErrMFAPending) {
+		t.Errorf("ValidateToken(pending) error = %v, want ErrMFAPending", err)
+	}
+}
+
+func TestRejectImpersonation(t *testing.T) {
+	handler := RejectImpersonation(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name         string
+		impersonator string
+		wantStatus   int
+	}{
+		{name: "own session", wantStatus: http.StatusOK},
+		{name: "impersonated session", impersonator: "admin-1", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.impersonator != "" {
+				req = req.WithContext(context.WithValue(req.Context(), ImpersonatorKey, tt.impersonator))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BlocklistProvider reports whether a client address is flagged.
+type BlocklistProvider interface {
+	Blocked(ctx context.Context, ip netip.Addr) (bool, error)
+}
+
+// BlocklistFunc adapts a function to the BlocklistProvider interface.
+type BlocklistFunc func(ctx context.Context, ip netip.Addr) (bool, error)
+
+// Blocked implements BlocklistProvider.
+func (f BlocklistFunc) Blocked(ctx context.Context, ip netip.Addr) (bool, error) {
+	return f(ctx, ip)
+}
+
+// CIDRBlocklist is a static list of addresses and networks.
+// It is safe for concurrent use and can be reloaded while serving.
+type CIDRBlocklist struct {
+	mu       sync.RWMutex
+	prefixes []netip.Prefix
+}
+
+// NewCIDRBlocklist creates a blocklist from CIDRs ("203.0.113.0/24") or
+// single addresses ("198.51.100.7").
+func NewCIDRBlocklist(entries ...string) (*CIDRBlocklist, error) {
+	prefixes, err := parsePrefixes("blocklist", entries)
+	if err != nil {
+		return nil, err
+	}
+	return &CIDRBlocklist{prefixes: prefixes}, nil
+}
+
+// LoadCIDRBlocklist reads a blocklist file with one CIDR or address per line.
+// Blank lines and lines starting with # are ignored.
+func LoadCIDRBlocklist(path string) (*CIDRBlocklist, error) {
+	b := &CIDRBlocklist{}
+	if err := b.Reload(path); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Reload replaces the blocklist with the contents of the file at path.
+// On error the current entries are kept.
+func (b *CIDRBlocklist) Reload(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open blocklist: %w", err)
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("cannot read blocklist: %w", err)
+	}
+
+	prefixes, err := parsePrefixes("blocklist", entries)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.prefixes = prefixes
+	b.mu.Unlock()
+	return nil
+}
+
+// Blocked implements BlocklistProvider.
+func (b *CIDRBlocklist) Blocked(ctx context.Context, ip netip.Addr) (bool, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, p := range b.prefixes {
+		if p.Contains(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func parsePrefixes(what string, entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		if strings.Contains(entry, "/") {
+			p, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s entry %q: %w", what, entry, err)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", what, entry, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// SetClient checks membership in a named set, such as a Redis set.
+// A go-redis client can be adapted with:
+//
+//	func (c redisSets) IsMember(ctx context.Context, set, member string) (bool, error) {
+//	    return c.SIsMember(ctx, set, member).Result()
+//	}
+type SetClient interface {
+	IsMember(ctx context.Context, set, member string) (bool, error)
+}
+
+// NewSetBlocklist flags addresses that are members of set.
+// Members are stored in their canonical string form ("203.0.113.9", "2001:db8::1").
+func NewSetBlocklist(client SetClient, set string) BlocklistProvider {
+	return BlocklistFunc(func(ctx context.Context, ip netip.Addr) (bool, error) {
+		return client.IsMember(ctx, set, ip.String())
+	})
+}
+
+// NewHTTPBlocklist queries an external reputation service with
+// GET endpoint?ip=<addr>. The service answers 200 with {"blocked": true|false};
+// 404 is treated as not blocked and any other status is an error.
+// A nil client uses one with a 2s timeout.
+func NewHTTPBlocklist(endpoint string, client *http.Client) BlocklistProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Second}
+	}
+
+	return BlocklistFunc(func(ctx context.Context, ip netip.Addr) (bool, error) {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return false, fmt.Errorf("invalid blocklist endpoint: %w", err)
+		}
+		q := u.Query()
+		q.Set("ip", ip.String())
+		u.RawQuery = q.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return false, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return false, fmt.Errorf("cannot query blocklist: %w", err)
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusNotFound:
+			return false, nil
+		default:
+			return false, fmt.Errorf("blocklist returned status %d", resp.StatusCode)
+		}
+
+		var result struct {
+			Blocked bool `json:"blocked"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return false, fmt.Errorf("cannot decode blocklist response: %w", err)
+		}
+		return result.Blocked, nil
+	})
+}
+
+// AnyBlocklist flags an address if any of the providers does. Providers are
+// consulted in order and the first error is returned.
+func AnyBlocklist(providers ...BlocklistProvider) BlocklistProvider {
+	return BlocklistFunc(func(ctx context.Context, ip netip.Addr) (bool, error) {
+		for _, p := range providers {
+			blocked, err := p.Blocked(ctx, ip)
+			if err != nil || blocked {
+				return blocked, err
+			}
+		}
+		return false, nil
+	})
+}
+
+// BlocklistConfig configures the Blocklist middleware.
+type BlocklistConfig struct {
+	Provider BlocklistProvider
+	// Tarpit delays the rejection of flagged requests, slowing down
+	// credential stuffing without telling the client it was flagged.
+	// Zero rejects immediately.
+	Tarpit time.Duration
+	// FailClosed rejects requests with 503 when the provider errors.
+	// By default they are let through.
+	FailClosed bool
+	// OnBlocked, if set, is called for every flagged request.
+	OnBlocked func(r *http.Request, ip netip.Addr)
+	// OnError, if set, is called when the provider fails.
+	OnError func(r *http.Request, err error)
+}
+
+// Blocklist rejects requests from addresses flagged by the provider with 403.
+// Place it after ClientIP when running behind a proxy and ahead of authn routes.
+// Requests whose RemoteAddr cannot be parsed are let through.
+func Blocklist(cfg BlocklistConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, err := netip.ParseAddr(clientIP(r.RemoteAddr))
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ip = ip.Unmap()
+
+			blocked, err := cfg.Provider.Blocked(r.Context(), ip)
+			if err != nil {
+				if cfg.OnError != nil {
+					cfg.OnError(r, err)
+				}
+				if cfg.FailClosed {
+					http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !blocked {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if cfg.OnBlocked != nil {
+				cfg.OnBlocked(r, ip)
+			}
+
+			if cfg.Tarpit > 0 {
+				t := time.NewTimer(cfg.Tarpit)
+				select {
+				case <-t.C:
+				case <-r.Context().Done():
+					t.Stop()
+					return
+				}
+			}
+
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type memorySets map[string]map[string]bool
+
+func (s memorySets) IsMember(ctx context.Context, set, member string) (bool, error) {
+	return s[set][member], nil
+}
+
+func serveBlocklist(h http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/auth/signin", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCIDRBlocklist(t *testing.T) {
+	b, err := NewCIDRBlocklist("203.0.113.0/24", "198.51.100.7", "2001:db8::/32")
+	if err != nil {
+		t.Fatalf("NewCIDRBlocklist() error = %v", err)
+	}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"203.0.113.42", true},
+		{"198.51.100.7", true},
+		{"198.51.100.8", false},
+		{"2001:db8::1", true},
+		{"2001:db9::1", false},
+		{"10.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			got, err := b.Blocked(context.Background(), netip.MustParseAddr(tt.ip))
+			if err != nil {
+				t.Fatalf("Blocked() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Blocked(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCIDRBlocklistInvalid(t *testing.T) {
+	for _, entry := range []string{"not-an-ip", "10.0.0.0/33"} {
+		if _, err := NewCIDRBlocklist(entry); err == nil {
+			t.Errorf("NewCIDRBlocklist(%q) should fail", entry)
+		}
+	}
+}
+
+func TestLoadCIDRBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	content := "# known scanners\n203.0.113.0/24\n\n  198.51.100.7  \n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("cannot write blocklist: %v", err)
+	}
+
+	b, err := LoadCIDRBlocklist(path)
+	if err != nil {
+		t.Fatalf("LoadCIDRBlocklist() error = %v", err)
+	}
+
+	ctx := context.Background()
+	if blocked, _ := b.Blocked(ctx, netip.MustParseAddr("198.51.100.7")); !blocked {
+		t.Error("address from file should be blocked")
+	}
+
+	if err := os.WriteFile(path, []byte("192.0.2.1\n"), 0644); err != nil {
+		t.Fatalf("cannot write blocklist: %v", err)
+	}
+	if err := b.Reload(path); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if blocked, _ := b.Blocked(ctx, netip.MustParseAddr("198.51.100.7")); blocked {
+		t.Error("reload should replace previous entries")
+	}
+
+	if err := os.WriteFile(path, []byte("garbage\n"), 0644); err != nil {
+		t.Fatalf("cannot write blocklist: %v", err)
+	}
+	if err := b.Reload(path); err == nil {
+		t.Error("Reload() should fail on invalid entries")
+	}
+	if blocked, _ := b.Blocked(ctx, netip.MustParseAddr("192.0.2.1")); !blocked {
+		t.Error("failed reload should keep current entries")
+	}
+
+	if _, err := LoadCIDRBlocklist(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadCIDRBlocklist() should fail for missing file")
+	}
+}
+
+func TestSetBlocklist(t *testing.T) {
+	sets := memorySets{"blocked_ips": {"203.0.113.9": true}}
+	b := NewSetBlocklist(sets, "blocked_ips")
+
+	ctx := context.Background()
+	if blocked, _ := b.Blocked(ctx, netip.MustParseAddr("203.0.113.9")); !blocked {
+		t.Error("set member should be blocked")
+	}
+	if blocked, _ := b.Blocked(ctx, netip.MustParseAddr("203.0.113.10")); blocked {
+		t.Error("non-member should not be blocked")
+	}
+}
+
+func TestHTTPBlocklist(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("ip") {
+		case "203.0.113.9":
+			w.Write([]byte(`{"blocked":true}`))
+		case "203.0.113.10":
+			w.Write([]byte(`{"blocked":false}`))
+		case "203.0.113.11":
+			http.NotFound(w, r)
+		default:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	b := NewHTTPBlocklist(srv.URL+"/check", nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		ip      string
+		want    bool
+		wantErr bool
+	}{
+		{"203.0.113.9", true, false},
+		{"203.0.113.10", false, false},
+		{"203.0.113.11", false, false},
+		{"203.0.113.12", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			got, err := b.Blocked(ctx, netip.MustParseAddr(tt.ip))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Blocked() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Blocked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnyBlocklist(t *testing.T) {
+	first, _ := NewCIDRBlocklist("10.0.0.0/8")
+	second, _ := NewCIDRBlocklist("192.0.2.1")
+	b := AnyBlocklist(first, second)
+
+	ctx := context.Background()
+	for ip, want := range map[string]bool{"10.1.2.3": true, "192.0.2.1": true, "192.0.2.2": false} {
+		if got, _ := b.Blocked(ctx, netip.MustParseAddr(ip)); got != want {
+			t.Errorf("Blocked(%s) = %v, want %v", ip, got, want)
+		}
+	}
+}
+
+func TestBlocklistMiddleware(t *testing.T) {
+	provider, _ := NewCIDRBlocklist("203.0.113.0/24")
+
+	var flagged netip.Addr
+	h := Blocklist(BlocklistConfig{
+		Provider:  provider,
+		OnBlocked: func(r *http.Request, ip netip.Addr) { flagged = ip },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	if rec := serveBlocklist(h, "10.0.0.1:1234"); rec.Code != http.StatusOK {
+		t.Errorf("clean address status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if rec := serveBlocklist(h, "203.0.113.5:1234"); rec.Code != http.StatusForbidden {
+		t.Errorf("flagged address status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if flagged != netip.MustParseAddr("203.0.113.5") {
+		t.Errorf("OnBlocked ip = %v, want 203.0.113.5", flagged)
+	}
+
+	if rec := serveBlocklist(h, "[::ffff:203.0.113.5]:1234"); rec.Code != http.StatusForbidden {
+		t.Errorf("IPv4-mapped address status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestBlocklistMiddlewareTarpit(t *testing.T) {
+	provider, _ := NewCIDRBlocklist("203.0.113.0/24")
+	h := Blocklist(BlocklistConfig{Provider: provider, Tarpit: 50 * time.Millisecond})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	start := time.Now()
+	rec := serveBlocklist(h, "203.0.113.5:1234")
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("tarpit elapsed = %v, want at least 50ms", elapsed)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	start = time.Now()
+	serveBlocklist(h, "10.0.0.1:1234")
+	if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+		t.Errorf("clean address should not be delayed, elapsed = %v", elapsed)
+	}
+}
+
+func TestBlocklistMiddlewareProviderError(t *testing.T) {
+	failing := BlocklistFunc(func(ctx context.Context, ip netip.Addr) (bool, error) {
+		return false, errors.New("provider down")
+	})
+
+	tests := []struct {
+		name       string
+		failClosed bool
+		want       int
+	}{
+		{"fail open", false, http.StatusOK},
+		{"fail closed", true, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotErr error
+			h := Blocklist(BlocklistConfig{
+				Provider:   failing,
+				FailClosed: tt.failClosed,
+				OnError:    func(r *http.Request, err error) { gotErr = err },
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			if rec := serveBlocklist(h, "10.0.0.1:1234"); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if gotErr == nil {
+				t.Error("OnError should be called")
+			}
+		})
+	}
+}
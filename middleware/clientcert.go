@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/spiffe"
+)
+
+const ClientCertIdentityKey contextKey = "client_cert_identity"
+
+// ErrUnknownClientCert is returned by CertPrincipals for identities they do
+// not map.
+var ErrUnknownClientCert = errors.New("unknown client certificate")
+
+// CertPrincipals maps an identity of a verified client certificate, a
+// SPIFFE ID or other URI SAN, or a DNS SAN, to the principal it
+// authenticates as.
+type CertPrincipals interface {
+	CertPrincipal(ctx context.Context, identity string) (string, error)
+}
+
+// CertPrincipalsFunc adapts a function to CertPrincipals, e.g. a lookup in a
+// service registry.
+type CertPrincipalsFunc func(ctx context.Context, identity string) (string, error)
+
+func (f CertPrincipalsFunc) CertPrincipal(ctx context.Context, identity string) (string, error) {
+	return f(ctx, identity)
+}
+
+// ServiceAccountCerts maps certificate identities to the names of the
+// service accounts they authenticate as, such as
+// "spiffe://example.org/ns/jobs/sa/worker" to "worker".
+type ServiceAccountCerts map[string]string
+
+func (c ServiceAccountCerts) CertPrincipal(_ context.Context, identity string) (string, error) {
+	name, ok := c[identity]
+	if !ok || name == "" {
+		return "", ErrUnknownClientCert
+	}
+	return auth.ServiceAccountPrefix + name, nil
+}
+
+// SPIFFEServiceAccounts maps the SPIFFE IDs of trustDomain that follow the
+// Kubernetes convention, spiffe://<trust domain>/ns/<namespace>/sa/<name>,
+// to the service account <name>, so workloads get their identity without a
+// mapping to keep. Accounts are told apart by name only, so only IDs in one
+// of namespaces map; those of other namespaces, or of any when namespaces is
+// empty, fail with ErrUnknownClientCert.
+func SPIFFEServiceAccounts(trustDomain string, namespaces ...string) CertPrincipals {
+	return CertPrincipalsFunc(func(_ context.Context, identity string) (string, error) {
+		id, err := spiffe.ParseID(identity)
+		if err != nil || !id.MemberOf(trustDomain) {
+			return "", ErrUnknownClientCert
+		}
+		segs := strings.Split(strings.TrimPrefix(id.Path, "/"), "/")
+		if len(segs) != 4 || segs[0] != "ns" || segs[2] != "sa" {
+			return "", ErrUnknownClientCert
+		}
+		if !slices.Contains(namespaces, segs[1]) {
+			return "", ErrUnknownClientCert
+		}
+		return auth.ServiceAccountPrefix + segs[3], nil
+	})
+}
+
+// CertIdentities returns the identities of cert in the order ClientCert tries
+// them: its URI SANs, SPIFFE IDs among them, then its DNS SANs.
+func CertIdentities(cert *x509.Certificate) []string {
+	var ids []string
+	for _, uri := range cert.URIs {
+		ids = append(ids, uri.String())
+	}
+	return append(ids, cert.DNSNames...)
+}
+
+// ClientCertConfig configures ClientCert.
+type ClientCertConfig struct {
+	Principals CertPrincipals
+	// TrustDomain, when set, only accepts X.509 SVIDs of that SPIFFE trust
+	// domain and maps them by their SPIFFE ID alone, ignoring other SANs.
+	TrustDomain string
+	// Optional lets requests without a client certificate through
+	// unauthenticated, so that Bearer or RequireSignature can authenticate
+	// them instead. Certificates that map to no principal are refused
+	// either way.
+	Optional bool
+}
+
+// ClientCert authenticates internal callers by the client certificate they
+// presented over mutual TLS, answering 401 when there is none or when none
+// of its identities maps to a principal. The principal, a service account's
+// for ServiceAccountCerts, is available from GetUserID, and the identity it
+// was mapped from from GetClientCertIdentity. Only certificates verified by
+// the server count, so serve with a client CA bundle (see app.ServeTLS);
+// behind a proxy terminating TLS no request carries one.
+func ClientCert(cfg ClientCertConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+				if cfg.Optional {
+					next.ServeHTTP(w, r)
+					return
+				}
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			identities, ok := cfg.identities(r.TLS.VerifiedChains[0][0])
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := r.Context()
+			for _, identity := range identities {
+				principal, err := cfg.Principals.CertPrincipal(ctx, identity)
+				if errors.Is(err, ErrUnknownClientCert) {
+					continue
+				}
+				if err != nil {
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					return
+				}
+
+				ctx = context.WithValue(ctx, UserIDKey, principal)
+				ctx = context.WithValue(ctx, ClientCertIdentityKey, identity)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		})
+	}
+}
+
+// identities returns the identities of cert to map, false when cert is not
+// an SVID of the trust domain of cfg.
+func (cfg ClientCertConfig) identities(cert *x509.Certificate) ([]string, bool) {
+	if cfg.TrustDomain == "" {
+		return CertIdentities(cert), true
+	}
+	id, err := spiffe.IDFromCert(cert)
+	if err != nil || !id.MemberOf(cfg.TrustDomain) {
+		return nil, false
+	}
+	return []string{id.String()}, true
+}
+
+// GetClientCertIdentity returns the certificate identity a request was
+// authenticated with by ClientCert, or "".
+func GetClientCertIdentity(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	identity, _ := ctx.Value(ClientCertIdentityKey).(string)
+	return identity
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestClientCert(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.org/ns/jobs/sa/worker")
+	worker := &x509.Certificate{URIs: []*url.URL{spiffe}}
+	billing := &x509.Certificate{DNSNames: []string{"billing.internal"}}
+	stranger := &x509.Certificate{DNSNames: []string{"stranger.internal"}}
+
+	principals := ServiceAccountCerts{
+		"spiffe://example.org/ns/jobs/sa/worker": "worker",
+		"billing.internal":                       "billing",
+	}
+
+	tests := []struct {
+		name          string
+		cfg           ClientCertConfig
+		cert          *x509.Certificate
+		wantStatus    int
+		wantPrincipal string
+		wantIdentity  string
+	}{
+		{name: "spiffe id", cfg: ClientCertConfig{Principals: principals}, cert: worker, wantStatus: http.StatusOK, wantPrincipal: "sa:worker", wantIdentity: "spiffe://example.org/ns/jobs/sa/worker"},
+		{name: "dns san", cfg: ClientCertConfig{Principals: principals}, cert: billing, wantStatus: http.StatusOK, wantPrincipal: "sa:billing", wantIdentity: "billing.internal"},
+		{name: "unknown certificate", cfg: ClientCertConfig{Principals: principals}, cert: stranger, wantStatus: http.StatusUnauthorized},
+		{name: "unknown certificate optional", cfg: ClientCertConfig{Principals: principals, Optional: true}, cert: stranger, wantStatus: http.StatusUnauthorized},
+		{name: "no certificate", cfg: ClientCertConfig{Principals: principals}, wantStatus: http.StatusUnauthorized},
+		{name: "no certificate optional", cfg: ClientCertConfig{Principals: principals, Optional: true}, wantStatus: http.StatusOK},
+		{
+			name: "lookup error",
+			cfg: ClientCertConfig{Principals: CertPrincipalsFunc(func(context.Context, string) (string, error) {
+				return "", errors.New("registry down")
+			})},
+			cert:       worker,
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPrincipal, gotIdentity string
+			handler := ClientCert(tt.cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPrincipal = GetUserID(r.Context())
+				gotIdentity = GetClientCertIdentity(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.cert != nil {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tt.cert}}}
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if gotPrincipal != tt.wantPrincipal || gotIdentity != tt.wantIdentity {
+				t.Errorf("principal, identity = %q, %q, want %q, %q", gotPrincipal, gotIdentity, tt.wantPrincipal, tt.wantIdentity)
+			}
+		})
+	}
+}
+
+func TestClientCertIgnoresUnverifiedCertificates(t *testing.T) {
+	handler := ClientCert(ClientCertConfig{Principals: ServiceAccountCerts{"billing.internal": "billing"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{DNSNames: []string{"billing.internal"}}}}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401 for a certificate the server did not verify", w.Code)
+	}
+}
+
+func TestClientCertTrustDomain(t *testing.T) {
+	uri := func(s string) []*url.URL {
+		u, _ := url.Parse(s)
+		return []*url.URL{u}
+	}
+	cfg := ClientCertConfig{Principals: SPIFFEServiceAccounts("example.org", "jobs", "billing"), TrustDomain: "example.org"}
+
+	tests := []struct {
+		name          string
+		cert          *x509.Certificate
+		wantStatus    int
+		wantPrincipal string
+	}{
+		{"service account svid", &x509.Certificate{URIs: uri("spiffe://example.org/ns/jobs/sa/worker")}, http.StatusOK, "sa:worker"},
+		{"service account svid of another allowed namespace", &x509.Certificate{URIs: uri("spiffe://example.org/ns/billing/sa/invoicer")}, http.StatusOK, "sa:invoicer"},
+		{"svid of a namespace not allowed", &x509.Certificate{URIs: uri("spiffe://example.org/ns/sandbox/sa/worker")}, http.StatusUnauthorized, ""},
+		{"svid of another trust domain", &x509.Certificate{URIs: uri("spiffe://other.org/ns/jobs/sa/worker")}, http.StatusUnauthorized, ""},
+		{"svid off convention", &x509.Certificate{URIs: uri("spiffe://example.org/worker")}, http.StatusUnauthorized, ""},
+		{"dns san only", &x509.Certificate{DNSNames: []string{"worker.internal"}}, http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPrincipal string
+			handler := ClientCert(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPrincipal = GetUserID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tt.cert}}}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus || gotPrincipal != tt.wantPrincipal {
+				t.Errorf("status, principal = %d, %q, want %d, %q", w.Code, gotPrincipal, tt.wantStatus, tt.wantPrincipal)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/aquamarinepk/aqm/geoip"
+)
+
+const ClientInfoKey contextKey = "client_info"
+
+// DefaultClientIPHeaders are the forwarding headers ClientIP reads when
+// ClientIPConfig.Headers is empty.
+var DefaultClientIPHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+
+// ClientIPConfig configures the ClientIP middleware.
+type ClientIPConfig struct {
+	// TrustedProxies are the networks allowed to report the client address
+	// in forwarding headers. Without any, headers are ignored and the
+	// connection's peer is the client.
+	TrustedProxies []netip.Prefix
+	// Headers are the forwarding headers to read, in order of preference;
+	// the first one present is used. Each holds a comma-separated list of
+	// addresses, the nearest hop last. DefaultClientIPHeaders when empty.
+	Headers []string
+	// Locator, if set, resolves the client address to a location.
+	Locator geoip.Locator
+	// OnError, if set, is called when the Locator fails. The request then
+	// proceeds without a location.
+	OnError func(r *http.Request, err error)
+}
+
+// ClientInfo describes the client of a request. Location is nil without a
+// Locator or when the address is not in its database.
+type ClientInfo struct {
+	IP       netip.Addr
+	Location *geoip.Location
+}
+
+// ParseTrustedProxies parses CIDRs ("10.0.0.0/8") and single addresses
+// ("192.0.2.10") for ClientIPConfig.TrustedProxies.
+func ParseTrustedProxies(entries []string) ([]netip.Prefix, error) {
+	return parsePrefixes("trusted proxy", entries)
+}
+
+// ParseAllowlist parses CIDRs and single addresses naming the clients
+// allowed to reach an endpoint.
+func ParseAllowlist(entries []string) ([]netip.Prefix, error) {
+	return parsePrefixes("allowlist", entries)
+}
+
+// ClientIP determines the client address of each request and attaches it,
+// with its location when cfg has a Locator, to the request context. When the
+// peer is a trusted proxy the forwarding header is walked from the nearest
+// hop back, skipping trusted proxies, so a client cannot spoof its address by
+// prepending entries. RemoteAddr is rewritten to the client address so rate
+// limits, blocklists and audit records downstream see it. Use it in place of
+// chi's RealIP, which trusts forwarding headers from anyone.
+func ClientIP(cfg ClientIPConfig) func(http.Handler) http.Handler {
+	headers := cfg.Headers
+	if len(headers) == 0 {
+		headers = DefaultClientIPHeaders
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, err := netip.ParseAddr(clientIP(r.RemoteAddr))
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			peer = peer.Unmap()
+
+			ip := peer
+			if trusted(cfg.TrustedProxies, peer) {
+				ip = forwardedFor(r, headers, cfg.TrustedProxies, peer)
+			}
+			if ip != peer {
+				r.RemoteAddr = ip.String()
+			}
+
+			info := &ClientInfo{IP: ip}
+			if cfg.Locator != nil {
+				loc, err := cfg.Locator.Locate(ip)
+				if err != nil && cfg.OnError != nil {
+					cfg.OnError(r, err)
+				}
+				if err == nil {
+					info.Location = loc
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), ClientInfoKey, info)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetClientInfo returns the client info attached by ClientIP, or nil.
+func GetClientInfo(ctx context.Context) *ClientInfo {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(ClientInfoKey).(*ClientInfo)
+	return info
+}
+
+// forwardedFor returns the first untrusted address of the first forwarding
+// header present, walking from the nearest hop. An entry that does not
+// parse ends the walk at the last good address.
+func forwardedFor(r *http.Request, headers []string, proxies []netip.Prefix, peer netip.Addr) netip.Addr {
+	for _, name := range headers {
+		values := r.Header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+
+		var hops []string
+		for _, v := range values {
+			hops = append(hops, strings.Split(v, ",")...)
+		}
+
+		ip := peer
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, ok := parseHop(hops[i])
+			if !ok {
+				break
+			}
+			ip = hop
+			if !trusted(proxies, hop) {
+				break
+			}
+		}
+		return ip
+	}
+	return peer
+}
+
+// parseHop parses a forwarding header entry, with or without a port.
+func parseHop(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if ip, err := netip.ParseAddr(s); err == nil {
+		return ip.Unmap(), true
+	}
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	return netip.Addr{}, false
+}
+
+func trusted(proxies []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range proxies {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/geoip"
+)
+
+func serveClientIP(t *testing.T, cfg ClientIPConfig, remoteAddr string, headers map[string]string) (*ClientInfo, string) {
+	t.Helper()
+	var info *ClientInfo
+	var seenAddr string
+	h := ClientIP(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info = GetClientInfo(r.Context())
+		seenAddr = r.RemoteAddr
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	return info, seenAddr
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.10"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+	cfg := ClientIPConfig{TrustedProxies: proxies}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"no headers", "203.0.113.5:1234", nil, "203.0.113.5"},
+		{"untrusted peer", "203.0.113.5:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.5"},
+		{"trusted peer", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		{"spoofed entry", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"proxy chain", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, 192.0.2.10, 10.9.9.9"}, "198.51.100.1"},
+		{"with port", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.1:5555"}, "198.51.100.1"},
+		{"ipv6", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "2001:db8::1"}, "2001:db8::1"},
+		{"garbage stops walk", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, bogus, 10.0.0.7"}, "10.0.0.7"},
+		{"only proxies", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "10.0.0.7"}, "10.0.0.7"},
+		{"real ip fallback", "10.1.2.3:1234", map[string]string{"X-Real-IP": "198.51.100.2"}, "198.51.100.2"},
+		{"mapped peer", "[::ffff:10.1.2.3]:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, _ := serveClientIP(t, cfg, tt.remoteAddr, tt.headers)
+			if info == nil || info.IP.String() != tt.want {
+				t.Errorf("client IP = %v, want %s", info, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPRewritesRemoteAddr(t *testing.T) {
+	cfg := ClientIPConfig{TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}
+
+	_, addr := serveClientIP(t, cfg, "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"})
+	if addr != "198.51.100.1" {
+		t.Errorf("RemoteAddr = %q, want 198.51.100.1", addr)
+	}
+	_, addr = serveClientIP(t, cfg, "203.0.113.5:1234", nil)
+	if addr != "203.0.113.5:1234" {
+		t.Errorf("RemoteAddr = %q, want it unchanged", addr)
+	}
+
+	info, addr := serveClientIP(t, cfg, "not-an-address", nil)
+	if info != nil || addr != "not-an-address" {
+		t.Errorf("unparsable peer = %v %q", info, addr)
+	}
+}
+
+func TestClientIPHeaders(t *testing.T) {
+	cfg := ClientIPConfig{
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		Headers:        []string{"CF-Connecting-IP"},
+	}
+
+	info, _ := serveClientIP(t, cfg, "10.1.2.3:1234", map[string]string{
+		"X-Forwarded-For":  "198.51.100.1",
+		"CF-Connecting-IP": "198.51.100.9",
+	})
+	if info.IP.String() != "198.51.100.9" {
+		t.Errorf("client IP = %s, want the configured header's", info.IP)
+	}
+}
+
+func TestClientIPLocator(t *testing.T) {
+	var lookups []netip.Addr
+	locator := geoip.LocatorFunc(func(ip netip.Addr) (*geoip.Location, error) {
+		lookups = append(lookups, ip)
+		if ip.Is6() {
+			return nil, errors.New("lookup failed")
+		}
+		return &geoip.Location{CountryCode: "DE", City: "Berlin"}, nil
+	})
+	var failures int
+	cfg := ClientIPConfig{Locator: locator, OnError: func(r *http.Request, err error) { failures++ }}
+
+	info, _ := serveClientIP(t, cfg, "203.0.113.5:1234", nil)
+	if info.Location == nil || info.Location.City != "Berlin" {
+		t.Errorf("location = %+v", info.Location)
+	}
+	if len(lookups) != 1 || lookups[0].String() != "203.0.113.5" {
+		t.Errorf("lookups = %v", lookups)
+	}
+
+	info, _ = serveClientIP(t, cfg, "[2001:db8::1]:1234", nil)
+	if info.Location != nil || failures != 1 {
+		t.Errorf("failed lookup = %+v, %d failures", info.Location, failures)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	if _, err := ParseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("ParseTrustedProxies(invalid) succeeded")
+	}
+	if GetClientInfo(nil) != nil {
+		t.Error("GetClientInfo(nil) != nil")
+	}
+
+	allowed, err := ParseAllowlist([]string{"10.0.0.0/8", "192.0.2.10"})
+	if err != nil || len(allowed) != 2 || !allowed[1].Contains(netip.MustParseAddr("192.0.2.10")) {
+		t.Errorf("ParseAllowlist() = %v, %v", allowed, err)
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/httpx"
+)
+
+// CodeConsentRequired is the error code RequireConsent answers with, so
+// clients know to show the policy and record its acceptance.
+const CodeConsentRequired = "CONSENT_REQUIRED"
+
+// ConsentChecker reports whether a user accepted the current version of a
+// policy.
+type ConsentChecker interface {
+	HasConsented(ctx context.Context, userID string, policy string) (bool, error)
+}
+
+// StoreConsentChecker implements ConsentChecker using auth.ConsentStore and
+// the current policy versions.
+type StoreConsentChecker struct {
+	store    auth.ConsentStore
+	policies auth.Policies
+}
+
+// NewConsentChecker creates a consent checker over store.
+func NewConsentChecker(store auth.ConsentStore, policies auth.Policies) *StoreConsentChecker {
+	return &StoreConsentChecker{store: store, policies: policies}
+}
+
+// HasConsented checks whether userID accepted the current version of
+// policy. Unknown policies are an error, so a misspelled RequireConsent
+// fails loudly instead of letting everyone through.
+func (c *StoreConsentChecker) HasConsented(ctx context.Context, userID string, policy string) (bool, error) {
+	id, err := auth.ParseUserID(userID)
+	if err != nil {
+		return false, nil
+	}
+	version, ok := c.policies.Current(policy)
+	if !ok {
+		return false, fmt.Errorf("%w: %s", auth.ErrUnknownPolicy, policy)
+	}
+
+	_, err = c.store.Get(ctx, id, auth.NormalizePolicyName(policy), version)
+	if errors.Is(err, auth.ErrConsentNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RequireConsent creates middleware that lets a request through only once
+// the authenticated user accepted the current version of policy. Others get
+// 403 with CodeConsentRequired until they do.
+func RequireConsent(checker ConsentChecker, policy string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := GetUserID(r.Context())
+			if userID == "" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			consented, err := checker.HasConsented(r.Context(), userID, policy)
+			if err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			if !consented {
+				httpx.WriteError(w, r, httpx.Forbidden(CodeConsentRequired, "Acceptance of the "+policy+" policy is required"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+var _ ConsentChecker = (*StoreConsentChecker)(nil)
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+func serveConsent(h http.Handler, userID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if userID != "" {
+		req = req.WithContext(context.WithValue(req.Context(), UserIDKey, userID))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequireConsent(t *testing.T) {
+	store := fake.NewConsentStore()
+	policies := auth.Policies{"terms": "v2"}
+	checker := NewConsentChecker(store, policies)
+
+	accepted, outdated := auth.NewUserID(), auth.NewUserID()
+	for _, c := range []*auth.Consent{auth.NewConsent(accepted, "terms", "v2"), auth.NewConsent(outdated, "terms", "v1")} {
+		c.BeforeCreate()
+		store.Create(context.Background(), c)
+	}
+
+	h := RequireConsent(checker, "terms")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	if rec := serveConsent(h, accepted.String()); rec.Code != http.StatusNoContent {
+		t.Errorf("accepted user = %d, want 204", rec.Code)
+	}
+
+	rec := serveConsent(h, outdated.String())
+	var body struct {
+		Code string `json:"code"`
+	}
+	json.NewDecoder(rec.Body).Decode(&body)
+	if rec.Code != http.StatusForbidden || body.Code != CodeConsentRequired {
+		t.Errorf("outdated user = %d %s, want 403 %s", rec.Code, body.Code, CodeConsentRequired)
+	}
+
+	if rec := serveConsent(h, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous = %d, want 401", rec.Code)
+	}
+	if rec := serveConsent(h, "sa:worker"); rec.Code != http.StatusForbidden {
+		t.Errorf("non-user principal = %d, want 403", rec.Code)
+	}
+
+	unknown := RequireConsent(checker, "cookies")(h)
+	if rec := serveConsent(unknown, accepted.String()); rec.Code != http.StatusInternalServerError {
+		t.Errorf("unknown policy = %d, want 500", rec.Code)
+	}
+}
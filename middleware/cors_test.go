@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name        string
+		cfg         CORSConfig
+		method      string
+		origin      string
+		preflight   bool
+		wantStatus  int
+		wantOrigin  string
+		wantCreds   string
+		wantMethods string
+	}{
+		{
+			name:       "no origin",
+			cfg:        CORSConfig{AllowedOrigins: []string{"https://app.example.com"}},
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "allowed origin",
+			cfg:        CORSConfig{AllowedOrigins: []string{"https://app.example.com"}},
+			method:     http.MethodGet,
+			origin:     "https://app.example.com",
+			wantStatus: http.StatusOK,
+			wantOrigin: "https://app.example.com",
+		},
+		{
+			name:       "disallowed origin",
+			cfg:        CORSConfig{AllowedOrigins: []string{"https://app.example.com"}},
+			method:     http.MethodGet,
+			origin:     "https://evil.example.com",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wildcard",
+			cfg:        CORSConfig{AllowedOrigins: []string{"*"}},
+			method:     http.MethodGet,
+			origin:     "https://any.example.com",
+			wantStatus: http.StatusOK,
+			wantOrigin: "*",
+		},
+		{
+			name:       "wildcard with credentials echoes origin",
+			cfg:        CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			method:     http.MethodGet,
+			origin:     "https://any.example.com",
+			wantStatus: http.StatusOK,
+			wantOrigin: "https://any.example.com",
+			wantCreds:  "true",
+		},
+		{
+			name:        "preflight",
+			cfg:         CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, AllowedMethods: []string{"GET", "POST"}},
+			method:      http.MethodOptions,
+			origin:      "https://app.example.com",
+			preflight:   true,
+			wantStatus:  http.StatusNoContent,
+			wantOrigin:  "https://app.example.com",
+			wantMethods: "GET, POST",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+
+			rec := httptest.NewRecorder()
+			CORS(tt.cfg)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCreds {
+				t.Errorf("Allow-Credentials = %q, want %q", got, tt.wantCreds)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("Allow-Methods = %q, want %q", got, tt.wantMethods)
+			}
+		})
+	}
+}
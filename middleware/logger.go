@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestLogger injects a request-scoped logger into the request context,
+// carrying request_id, method, and path fields. Handlers retrieve it with
+// log.FromContext(r.Context()). Place it after RequestID in the stack.
+func RequestLogger(logger log.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := GetRequestID(r.Context())
+			if requestID == "" {
+				requestID = middleware.GetReqID(r.Context())
+			}
+
+			reqLogger := logger.With(
+				"request_id", requestID,
+				"method", r.Method,
+				"path", r.URL.Path,
+			)
+
+			ctx := log.NewContext(r.Context(), reqLogger)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
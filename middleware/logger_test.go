@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/log"
+)
+
+type recordingLogger struct {
+	log.Logger
+	buf    *bytes.Buffer
+	fields []any
+}
+
+func (l *recordingLogger) Info(v ...any) {
+	for _, f := range l.fields {
+		l.buf.WriteString(" ")
+		l.buf.WriteString(f.(string))
+	}
+}
+
+func (l *recordingLogger) With(args ...any) log.Logger {
+	var fields []any
+	for _, a := range args {
+		if s, ok := a.(string); ok {
+			fields = append(fields, s)
+		}
+	}
+	return &recordingLogger{Logger: l.Logger, buf: l.buf, fields: append(l.fields, fields...)}
+}
+
+func TestRequestLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	base := &recordingLogger{Logger: log.NewNoopLogger(), buf: buf}
+
+	handler := RequestID(RequestLogger(base)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.FromContext(r.Context()).Info("handled")
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/signin", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	out := buf.String()
+	for _, want := range []string{"request_id req-123", "method POST", "path /signin"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("logged fields = %q, want to contain %q", out, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("first request should be allowed")
+	}
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("second request should be allowed")
+	}
+
+	now = now.Add(20 * time.Second)
+	ok, retry := l.Allow("a")
+	if ok {
+		t.Fatal("third request should be rejected")
+	}
+	if retry != 40*time.Second {
+		t.Errorf("retry = %v, want %v", retry, 40*time.Second)
+	}
+
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("other key should have its own window")
+	}
+
+	now = now.Add(40 * time.Second)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("request after window reset should be allowed")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	handler := RateLimit(1, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		wantStatus int
+	}{
+		{"first request", "8.8.8.8:1000", http.StatusOK},
+		{"same IP different port", "8.8.8.8:2000", http.StatusTooManyRequests},
+		{"different IP", "1.1.1.1:1000", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("RateLimit() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusTooManyRequests {
+				if rec.Header().Get("Retry-After") == "" {
+					t.Error("RateLimit() should set Retry-After when rejecting")
+				}
+				if !strings.Contains(rec.Body.String(), `"retry_after_seconds":`) {
+					t.Errorf("RateLimit() body = %q, want retry_after_seconds", rec.Body.String())
+				}
+			}
+		})
+	}
+}
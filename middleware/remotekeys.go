@@ -0,0 +1,268 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/crypto"
+	"github.com/aquamarinepk/aqm/log"
+)
+
+// ErrNoVerificationKeys is returned when no usable keys are available,
+// either because the endpoint was never reached or the cached keys are too old.
+var ErrNoVerificationKeys = errors.New("no token verification keys available")
+
+// RemoteKeySetConfig configures a RemoteKeySet.
+type RemoteKeySetConfig struct {
+	// URL of the key set document (see crypto.KeySet).
+	URL    string
+	Client *http.Client
+	// RefreshInterval is how long fetched keys are considered fresh.
+	// Default 5m.
+	RefreshInterval time.Duration
+	// MaxStale is how long past RefreshInterval cached keys keep being served
+	// while refreshes fail. Default 1h.
+	MaxStale time.Duration
+	// RetryInterval is the delay between background refresh attempts after
+	// a failure. Default 10s.
+	RetryInterval time.Duration
+	// Fallback keys are used when nothing has been fetched yet or the cache
+	// has expired, e.g. a locally configured public key.
+	Fallback []ed25519.PublicKey
+	Logger   log.Logger
+}
+
+// RemoteKeySet caches token verification keys fetched from a remote endpoint
+// with stale-while-revalidate semantics: fresh keys are served from cache,
+// stale keys are served while a refresh runs in the background, and keys are
+// only fetched synchronously when the cache is empty or past MaxStale.
+//
+// RemoteKeySet implements SessionValidator, so it can be passed directly to
+// Session or Bearer. It also implements Start and Stop for the app lifecycle.
+type RemoteKeySet struct {
+	cfg RemoteKeySetConfig
+	now func() time.Time
+
+	mu         sync.Mutex
+	keys       []ed25519.PublicKey
+	fetchedAt  time.Time
+	refreshing bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewRemoteKeySet creates a key set for cfg. No request is made until keys
+// are first needed or Start is called.
+func NewRemoteKeySet(cfg RemoteKeySetConfig) *RemoteKeySet {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = 5 * time.Minute
+	}
+	if cfg.MaxStale <= 0 {
+		cfg.MaxStale = time.Hour
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = 10 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNoopLogger()
+	}
+
+	return &RemoteKeySet{cfg: cfg, now: time.Now}
+}
+
+// Keys returns the current verification keys.
+func (s *RemoteKeySet) Keys(ctx context.Context) ([]ed25519.PublicKey, error) {
+	s.mu.Lock()
+	keys, age := s.keys, s.now().Sub(s.fetchedAt)
+	s.mu.Unlock()
+
+	switch {
+	case keys != nil && age < s.cfg.RefreshInterval:
+		return keys, nil
+	case keys != nil && age < s.cfg.RefreshInterval+s.cfg.MaxStale:
+		s.revalidate()
+		return keys, nil
+	}
+
+	if err := s.Refresh(ctx); err != nil {
+		if len(s.cfg.Fallback) > 0 {
+			s.cfg.Logger.Error("Cannot fetch verification keys, using fallback", "url", s.cfg.URL, "error", err)
+			return s.cfg.Fallback, nil
+		}
+		return nil, fmt.Errorf("%w: %v", ErrNoVerificationKeys, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys, nil
+}
+
+// Refresh fetches the key set and replaces the cache on success.
+// On failure the cached keys are kept.
+func (s *RemoteKeySet) Refresh(ctx context.Context) error {
+	keys, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = s.now()
+	s.mu.Unlock()
+
+	s.cfg.Logger.Debug("Refreshed verification keys", "url", s.cfg.URL, "count", len(keys))
+	return nil
+}
+
+// revalidate refreshes in the background unless a refresh is already running.
+func (s *RemoteKeySet) revalidate() {
+	s.mu.Lock()
+	if s.refreshing {
+		s.mu.Unlock()
+		return
+	}
+	s.refreshing = true
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			s.refreshing = false
+			s.mu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Client.Timeout+time.Second)
+		defer cancel()
+
+		if err := s.Refresh(ctx); err != nil {
+			s.cfg.Logger.Error("Cannot refresh verification keys, serving stale keys", "url", s.cfg.URL, "error", err)
+		}
+	}()
+}
+
+func (s *RemoteKeySet) fetch(ctx context.Context) ([]ed25519.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch key set: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("key set endpoint returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key set: %w", err)
+	}
+
+	return crypto.ParseKeySet(data)
+}
+
+// ValidateToken implements SessionValidator by verifying token against each
+// cached key.
+func (s *RemoteKeySet) ValidateToken(token string) (string, string, error) {
+	claims, err := s.ValidateClaims(token)
+	if err != nil {
+		return "", "", err
+	}
+	return claims.Subject, claims.SessionID, nil
+}
+
+// ValidateClaims verifies token against each cached key and returns its
+// claims. An expired token is reported as such even if other keys reject it.
+func (s *RemoteKeySet) ValidateClaims(token string) (crypto.TokenClaims, error) {
+	keys, err := s.Keys(context.Background())
+	if err != nil {
+		return crypto.TokenClaims{}, err
+	}
+
+	err = crypto.ErrInvalidToken
+	for _, key := range keys {
+		claims, verr := crypto.VerifyToken(token, key)
+		if verr == nil {
+			return claims, nil
+		}
+		if errors.Is(verr, crypto.ErrTokenExpired) {
+			err = verr
+		}
+	}
+	return crypto.TokenClaims{}, err
+}
+
+// Start fetches the keys once and refreshes them in the background every
+// RefreshInterval, retrying every RetryInterval after a failure. A failed
+// initial fetch is logged rather than returned so a brief authn outage does
+// not block startup.
+func (s *RemoteKeySet) Start(ctx context.Context) error {
+	if err := s.Refresh(ctx); err != nil {
+		s.cfg.Logger.Error("Cannot fetch verification keys at startup", "url", s.cfg.URL, "error", err)
+	}
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.loop(loopCtx)
+	return nil
+}
+
+// Stop ends the background refresh.
+func (s *RemoteKeySet) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *RemoteKeySet) loop(ctx context.Context) {
+	defer close(s.done)
+
+	wait := s.cfg.RefreshInterval
+	s.mu.Lock()
+	if s.keys == nil {
+		wait = s.cfg.RetryInterval
+	}
+	s.mu.Unlock()
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		if err := s.Refresh(ctx); err != nil {
+			s.cfg.Logger.Error("Cannot refresh verification keys", "url", s.cfg.URL, "error", err)
+			t.Reset(s.cfg.RetryInterval)
+			continue
+		}
+		t.Reset(s.cfg.RefreshInterval)
+	}
+}
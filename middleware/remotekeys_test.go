@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+type keyServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	keys  []ed25519.PublicKey
+	down  bool
+	calls atomic.Int32
+}
+
+func newKeyServer(t *testing.T, keys ...ed25519.PublicKey) *keyServer {
+	t.Helper()
+
+	ks := &keyServer{keys: keys}
+	ks.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ks.calls.Add(1)
+		ks.mu.Lock()
+		defer ks.mu.Unlock()
+
+		if ks.down {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(crypto.NewKeySet(ks.keys...))
+	}))
+	t.Cleanup(ks.Close)
+	return ks
+}
+
+func (ks *keyServer) set(down bool, keys ...ed25519.PublicKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.down = down
+	if keys != nil {
+		ks.keys = keys
+	}
+}
+
+func signTestToken(t *testing.T, priv ed25519.PrivateKey, ttl time.Duration) string {
+	t.Helper()
+
+	token, err := crypto.GenerateToken(crypto.TokenClaims{
+		Subject:   "alice",
+		SessionID: "sess-1",
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}, priv)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	return token
+}
+
+func TestRemoteKeySetFreshAndStale(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(nil)
+	ks := newKeyServer(t, pub)
+
+	now := time.Now()
+	s := NewRemoteKeySet(RemoteKeySetConfig{URL: ks.URL, RefreshInterval: time.Minute, MaxStale: time.Hour})
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := s.Keys(ctx); err != nil {
+		t.Fatalf("Keys() error = %v", err)
+	}
+	if _, err := s.Keys(ctx); err != nil {
+		t.Fatalf("Keys() error = %v", err)
+	}
+	if got := ks.calls.Load(); got != 1 {
+		t.Errorf("fresh keys should be served from cache, calls = %d", got)
+	}
+
+	ks.set(true)
+	now = now.Add(2 * time.Minute)
+
+	keys, err := s.Keys(ctx)
+	if err != nil {
+		t.Fatalf("stale Keys() error = %v", err)
+	}
+	if len(keys) != 1 {
+		t.Errorf("stale Keys() returned %d keys, want 1", len(keys))
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for ks.calls.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := ks.calls.Load(); got != 2 {
+		t.Errorf("stale keys should trigger a background refresh, calls = %d", got)
+	}
+}
+
+func TestRemoteKeySetExpired(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(nil)
+	ks := newKeyServer(t, pub)
+
+	now := time.Now()
+	s := NewRemoteKeySet(RemoteKeySetConfig{URL: ks.URL, RefreshInterval: time.Minute, MaxStale: time.Minute})
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := s.Keys(ctx); err != nil {
+		t.Fatalf("Keys() error = %v", err)
+	}
+
+	ks.set(true)
+	now = now.Add(3 * time.Minute)
+
+	if _, err := s.Keys(ctx); !errors.Is(err, ErrNoVerificationKeys) {
+		t.Errorf("Keys() past MaxStale error = %v, want ErrNoVerificationKeys", err)
+	}
+}
+
+func TestRemoteKeySetFallback(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(nil)
+	ks := newKeyServer(t)
+	ks.set(true)
+
+	s := NewRemoteKeySet(RemoteKeySetConfig{URL: ks.URL, Fallback: []ed25519.PublicKey{pub}})
+
+	keys, err := s.Keys(context.Background())
+	if err != nil {
+		t.Fatalf("Keys() error = %v", err)
+	}
+	if len(keys) != 1 || !pub.Equal(keys[0]) {
+		t.Error("Keys() should return fallback keys when the endpoint is down")
+	}
+}
+
+func TestRemoteKeySetValidateToken(t *testing.T) {
+	oldPub, oldPriv, _ := ed25519.GenerateKey(nil)
+	newPub, newPriv, _ := ed25519.GenerateKey(nil)
+	_, otherPriv, _ := ed25519.GenerateKey(nil)
+	ks := newKeyServer(t, newPub, oldPub)
+
+	s := NewRemoteKeySet(RemoteKeySetConfig{URL: ks.URL})
+
+	for name, priv := range map[string]ed25519.PrivateKey{"current key": newPriv, "previous key": oldPriv} {
+		t.Run(name, func(t *testing.T) {
+			userID, sessionID, err := s.ValidateToken(signTestToken(t, priv, time.Hour))
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			if userID != "alice" || sessionID != "sess-1" {
+				t.Errorf("ValidateToken() = %q, %q", userID, sessionID)
+			}
+		})
+	}
+
+	if _, _, err := s.ValidateToken(signTestToken(t, otherPriv, time.Hour)); !errors.Is(err, crypto.ErrInvalidToken) {
+		t.Errorf("unknown key error = %v, want ErrInvalidToken", err)
+	}
+	if _, _, err := s.ValidateToken(signTestToken(t, newPriv, -time.Hour)); !errors.Is(err, crypto.ErrTokenExpired) {
+		t.Errorf("expired token error = %v, want ErrTokenExpired", err)
+	}
+}
+
+func TestRemoteKeySetStartStop(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(nil)
+	ks := newKeyServer(t, pub)
+
+	s := NewRemoteKeySet(RemoteKeySetConfig{URL: ks.URL, RefreshInterval: 10 * time.Millisecond})
+	ctx := context.Background()
+
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for ks.calls.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := ks.calls.Load(); got < 3 {
+		t.Errorf("background loop should keep refreshing, calls = %d", got)
+	}
+
+	if err := s.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	calls := ks.calls.Load()
+	time.Sleep(30 * time.Millisecond)
+	if got := ks.calls.Load(); got != calls {
+		t.Errorf("no refresh expected after Stop, calls went from %d to %d", calls, got)
+	}
+}
+
+func TestRemoteKeySetStartWithEndpointDown(t *testing.T) {
+	ks := newKeyServer(t)
+	ks.set(true)
+
+	s := NewRemoteKeySet(RemoteKeySetConfig{URL: ks.URL})
+	if err := s.Start(context.Background()); err != nil {
+		t.Errorf("Start() should not fail when the endpoint is down, error = %v", err)
+	}
+	s.Stop(context.Background())
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aquamarinepk/aqm/httpx"
+)
+
+// RetryAfterSeconds converts d to whole seconds for a Retry-After hint,
+// rounding up so clients never retry early. Non-positive durations yield 1.
+func RetryAfterSeconds(d time.Duration) int {
+	return httpx.RetryAfterSeconds(d)
+}
+
+// SetRetryAfter sets the Retry-After header for d and returns the advertised seconds.
+func SetRetryAfter(w http.ResponseWriter, d time.Duration) int {
+	return httpx.SetRetryAfter(w, d)
+}
+
+// WriteRetryAfter writes a throttling response (typically 429 or 503) with a
+// Retry-After header and a JSON body matching the shared error shape:
+//
+//	{"code":"RATE_LIMITED","message":"Too many requests","retry_after_seconds":30}
+func WriteRetryAfter(w http.ResponseWriter, status int, code, message string, retryAfter time.Duration) {
+	e := httpx.NewError(status, code, message).WithRetryAfter(retryAfter)
+	httpx.WriteErrorJSON(w, status, httpx.Response(w, e))
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterSeconds(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want int
+	}{
+		{30 * time.Second, 30},
+		{1500 * time.Millisecond, 2},
+		{0, 1},
+		{-time.Second, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in.String(), func(t *testing.T) {
+			if got := RetryAfterSeconds(tt.in); got != tt.want {
+				t.Errorf("RetryAfterSeconds(%v) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteRetryAfter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteRetryAfter(rec, http.StatusServiceUnavailable, "MAINTENANCE", "Down for maintenance", 90*time.Second)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "90" {
+		t.Errorf("Retry-After = %q, want %q", got, "90")
+	}
+
+	want := `{"code":"MAINTENANCE","message":"Down for maintenance","retry_after_seconds":90}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+}
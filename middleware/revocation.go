@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+// ErrTokenRevoked is returned by RevocationValidator for valid tokens that
+// were revoked before they expired.
+var ErrTokenRevoked = errors.New("token revoked")
+
+// RevocationChecker reports whether a token with valid claims was revoked.
+type RevocationChecker interface {
+	Revoked(ctx context.Context, claims crypto.TokenClaims) (bool, error)
+}
+
+// RevocationFunc adapts a function to the RevocationChecker interface.
+type RevocationFunc func(ctx context.Context, claims crypto.TokenClaims) (bool, error)
+
+// Revoked implements RevocationChecker.
+func (f RevocationFunc) Revoked(ctx context.Context, claims crypto.TokenClaims) (bool, error) {
+	return f(ctx, claims)
+}
+
+// ContextClaimsValidator is implemented by validators that need the request
+// context, such as RevocationValidator. Session and Bearer prefer it over
+// ClaimsValidator.
+type ContextClaimsValidator interface {
+	ValidateClaimsContext(ctx context.Context, token string) (crypto.TokenClaims, error)
+}
+
+// RevocationValidator wraps a ClaimsValidator to also refuse tokens its
+// checker reports revoked, with ErrTokenRevoked. Tokens fail validation
+// when the checker fails too.
+type RevocationValidator struct {
+	validator ClaimsValidator
+	checker   RevocationChecker
+}
+
+// NewRevocationValidator returns a validator that checks the tokens
+// accepted by validator against checker.
+func NewRevocationValidator(validator ClaimsValidator, checker RevocationChecker) *RevocationValidator {
+	return &RevocationValidator{validator: validator, checker: checker}
+}
+
+// ValidateToken implements SessionValidator. MFA-pending tokens fail with
+// ErrMFAPending.
+func (v *RevocationValidator) ValidateToken(token string) (string, string, error) {
+	claims, err := v.ValidateClaims(token)
+	if err != nil {
+		return "", "", err
+	}
+	if claims.MFAPending {
+		return "", "", ErrMFAPending
+	}
+	return claims.Subject, claims.SessionID, nil
+}
+
+// ValidateClaims implements ClaimsValidator, checking revocation without a
+// request context.
+func (v *RevocationValidator) ValidateClaims(token string) (crypto.TokenClaims, error) {
+	return v.ValidateClaimsContext(context.Background(), token)
+}
+
+// ValidateClaimsContext implements ContextClaimsValidator.
+func (v *RevocationValidator) ValidateClaimsContext(ctx context.Context, token string) (crypto.TokenClaims, error) {
+	claims, err := v.validator.ValidateClaims(token)
+	if err != nil {
+		return crypto.TokenClaims{}, err
+	}
+	revoked, err := v.checker.Revoked(ctx, claims)
+	if err != nil {
+		return crypto.TokenClaims{}, err
+	}
+	if revoked {
+		return crypto.TokenClaims{}, ErrTokenRevoked
+	}
+	return claims, nil
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+type requestIDKey struct{}
+
+func TestRevocationValidator(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	token := func(sessionID string) string {
+		tok, _ := crypto.GenerateToken(crypto.TokenClaims{
+			Subject:   "user-1",
+			SessionID: sessionID,
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		}, priv)
+		return tok
+	}
+
+	var seen context.Context
+	checker := RevocationFunc(func(ctx context.Context, claims crypto.TokenClaims) (bool, error) {
+		seen = ctx
+		switch claims.SessionID {
+		case "revoked":
+			return true, nil
+		case "broken":
+			return false, errors.New("store down")
+		}
+		return false, nil
+	})
+	v := NewRevocationValidator(NewTokenValidator(pub), checker)
+
+	if userID, sessionID, err := v.ValidateToken(token("live")); err != nil || userID != "user-1" || sessionID != "live" {
+		t.Errorf("ValidateToken() = %q, %q, %v", userID, sessionID, err)
+	}
+	if _, err := v.ValidateClaims(token("revoked")); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateClaims() revoked error = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := v.ValidateClaims(token("broken")); err == nil {
+		t.Error("ValidateClaims() should fail when the checker fails")
+	}
+	if _, err := v.ValidateClaims("v4.public.garbage"); err == nil || errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateClaims() invalid token error = %v", err)
+	}
+
+	handler := Bearer(v)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for sessionID, want := range map[string]int{"live": http.StatusOK, "revoked": http.StatusUnauthorized} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), requestIDKey{}, "req-1"))
+		req.Header.Set("Authorization", "Bearer "+token(sessionID))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("Bearer() with %s session = %d, want %d", sessionID, w.Code, want)
+		}
+		if seen.Value(requestIDKey{}) != "req-1" {
+			t.Error("Bearer() did not pass the request context to the checker")
+		}
+	}
+}
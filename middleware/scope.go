@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+// GetScope returns the permissions a scoped token is limited to, and nil
+// for unscoped tokens.
+func GetScope(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	if scope, ok := ctx.Value(ScopeKey).([]string); ok {
+		return scope
+	}
+	return nil
+}
+
+// ScopedChecker wraps checker so that requests made with a scoped token
+// pass only permission checks their scope covers, and no role checks.
+// Unscoped requests are checked by checker alone. The Require middlewares
+// apply it already; wrap the checkers given to handlers with it.
+func ScopedChecker(checker RoleChecker) RoleChecker {
+	if _, ok := checker.(scopedChecker); ok {
+		return checker
+	}
+	return scopedChecker{checker}
+}
+
+type scopedChecker struct {
+	RoleChecker
+}
+
+func (c scopedChecker) HasRole(ctx context.Context, userID, roleName string) (bool, error) {
+	if GetScope(ctx) != nil {
+		return false, nil
+	}
+	return c.RoleChecker.HasRole(ctx, userID, roleName)
+}
+
+func (c scopedChecker) CheckPermission(ctx context.Context, userID, permission string) (bool, error) {
+	if scope := GetScope(ctx); scope != nil && !auth.HasPermission(scope, permission) {
+		return false, nil
+	}
+	return c.RoleChecker.CheckPermission(ctx, userID, permission)
+}
+
+func (c scopedChecker) CheckAnyPermission(ctx context.Context, userID string, permissions []string) (bool, error) {
+	if scope := GetScope(ctx); scope != nil {
+		var inScope []string
+		for _, p := range permissions {
+			if auth.HasPermission(scope, p) {
+				inScope = append(inScope, p)
+			}
+		}
+		if len(inScope) == 0 {
+			return false, nil
+		}
+		permissions = inScope
+	}
+	return c.RoleChecker.CheckAnyPermission(ctx, userID, permissions)
+}
+
+func (c scopedChecker) CheckAllPermissions(ctx context.Context, userID string, permissions []string) (bool, error) {
+	if scope := GetScope(ctx); scope != nil && !auth.HasAllPermissions(scope, permissions) {
+		return false, nil
+	}
+	return c.RoleChecker.CheckAllPermissions(ctx, userID, permissions)
+}
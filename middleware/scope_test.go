@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScopedChecker(t *testing.T) {
+	checker := ScopedChecker(&fakeRoleChecker{hasRole: true, checkPermission: true, checkAnyPermission: true, checkAllPermissions: true})
+	unscoped := context.Background()
+	scoped := context.WithValue(unscoped, ScopeKey, []string{"orders:read", "invoices:*"})
+
+	if ScopedChecker(checker) != checker {
+		t.Error("ScopedChecker() wrapped a scoped checker again")
+	}
+
+	tests := []struct {
+		name  string
+		check func(ctx context.Context) (bool, error)
+		want  bool
+	}{
+		{"role", func(ctx context.Context) (bool, error) { return checker.HasRole(ctx, "ann", "admin") }, false},
+		{"permission in scope", func(ctx context.Context) (bool, error) { return checker.CheckPermission(ctx, "ann", "orders:read") }, true},
+		{"wildcard in scope", func(ctx context.Context) (bool, error) { return checker.CheckPermission(ctx, "ann", "invoices:write") }, true},
+		{"permission out of scope", func(ctx context.Context) (bool, error) { return checker.CheckPermission(ctx, "ann", "orders:write") }, false},
+		{"any with one in scope", func(ctx context.Context) (bool, error) {
+			return checker.CheckAnyPermission(ctx, "ann", []string{"orders:write", "orders:read"})
+		}, true},
+		{"any out of scope", func(ctx context.Context) (bool, error) {
+			return checker.CheckAnyPermission(ctx, "ann", []string{"orders:write"})
+		}, false},
+		{"all in scope", func(ctx context.Context) (bool, error) {
+			return checker.CheckAllPermissions(ctx, "ann", []string{"orders:read", "invoices:read"})
+		}, true},
+		{"all partly out of scope", func(ctx context.Context) (bool, error) {
+			return checker.CheckAllPermissions(ctx, "ann", []string{"orders:read", "orders:write"})
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok, err := tt.check(unscoped); err != nil || !ok {
+				t.Errorf("unscoped = %v, %v, want true", ok, err)
+			}
+			if ok, err := tt.check(scoped); err != nil || ok != tt.want {
+				t.Errorf("scoped = %v, %v, want %v", ok, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequirePermissionScoped(t *testing.T) {
+	handler := RequirePermission(&fakeRoleChecker{checkPermission: true}, "orders:write")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	ctx := context.WithValue(context.Background(), UserIDKey, "ann")
+	for _, tt := range []struct {
+		name  string
+		scope []string
+		want  int
+	}{
+		{"unscoped", nil, http.StatusOK},
+		{"in scope", []string{"orders:write"}, http.StatusOK},
+		{"out of scope", []string{"orders:read"}, http.StatusForbidden},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			reqCtx := ctx
+			if tt.scope != nil {
+				reqCtx = context.WithValue(ctx, ScopeKey, tt.scope)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	if got := GetScope(context.Background()); got != nil {
+		t.Errorf("GetScope() unscoped = %v, want nil", got)
+	}
+}
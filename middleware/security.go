@@ -0,0 +1,45 @@
+package middleware
+
+import "net/http"
+
+// StrictSecurityHeaders returns the headers applied to public-facing services:
+// no sniffing, no framing, no referrer leakage, HSTS, and a deny-all CSP
+// suitable for JSON APIs.
+func StrictSecurityHeaders() map[string]string {
+	return map[string]string{
+		"X-Content-Type-Options":       "nosniff",
+		"X-Frame-Options":              "DENY",
+		"Referrer-Policy":              "no-referrer",
+		"Content-Security-Policy":      "default-src 'none'; frame-ancestors 'none'",
+		"Strict-Transport-Security":    "max-age=63072000; includeSubDomains",
+		"Cross-Origin-Opener-Policy":   "same-origin",
+		"Cross-Origin-Resource-Policy": "same-origin",
+	}
+}
+
+// SecurityHeaders sets the given response headers on every request.
+func SecurityHeaders(headers map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range headers {
+				w.Header().Set(name, value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MaxBodySize limits request bodies to n bytes. Reads past the limit fail and
+// requests declaring a larger Content-Length are rejected with 413.
+func MaxBodySize(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
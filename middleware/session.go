@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"crypto/ed25519"
+	"errors"
 	"net/http"
 
 	"github.com/aquamarinepk/aqm/crypto"
@@ -12,13 +13,27 @@ const (
 	SessionCookieName = "session_id"
 	UserIDKey         = contextKey("user_id")
 	SessionIDKey      = contextKey("session_id")
+	ImpersonatorKey   = contextKey("impersonator")
+	ScopeKey          = contextKey("scope")
+	ClaimsKey         = contextKey("claims")
 )
 
+// ErrMFAPending is returned for MFA-pending tokens where a session token is
+// required.
+var ErrMFAPending = errors.New("second factor required")
+
 // SessionValidator validates session tokens and returns user ID on success.
 type SessionValidator interface {
 	ValidateToken(token string) (userID string, sessionID string, err error)
 }
 
+// ClaimsValidator is implemented by validators that also return the full
+// token claims. Session and Bearer use it to record the impersonator of
+// impersonation tokens in the request context.
+type ClaimsValidator interface {
+	ValidateClaims(token string) (crypto.TokenClaims, error)
+}
+
 // TokenValidator implements SessionValidator using PASETO tokens.
 type TokenValidator struct {
 	publicKey ed25519.PublicKey
@@ -32,14 +47,68 @@ func NewTokenValidator(publicKey ed25519.PublicKey) *TokenValidator {
 }
 
 // ValidateToken validates a PASETO token and extracts the user ID and session ID.
+// MFA-pending tokens fail with ErrMFAPending.
 func (v *TokenValidator) ValidateToken(token string) (string, string, error) {
-	claims, err := crypto.VerifyToken(token, v.publicKey)
+	claims, err := v.ValidateClaims(token)
 	if err != nil {
 		return "", "", err
 	}
+	if claims.MFAPending {
+		return "", "", ErrMFAPending
+	}
 	return claims.Subject, claims.SessionID, nil
 }
 
+// ValidateClaims validates a PASETO token and returns its claims.
+func (v *TokenValidator) ValidateClaims(token string) (crypto.TokenClaims, error) {
+	return crypto.VerifyToken(token, v.publicKey)
+}
+
+// authenticate validates token and returns ctx with the user, session and,
+// for impersonation and scoped tokens, the impersonator and scope, and the
+// custom claims of the token. MFA-pending tokens fail with ErrMFAPending.
+func authenticate(ctx context.Context, validator SessionValidator, token string) (context.Context, error) {
+	claims, err := validateClaims(ctx, validator, token)
+	if err != nil {
+		return ctx, err
+	}
+	if claims.MFAPending {
+		return ctx, ErrMFAPending
+	}
+	return withClaims(ctx, claims), nil
+}
+
+// validateClaims validates token with the richest interface validator
+// implements.
+func validateClaims(ctx context.Context, validator SessionValidator, token string) (crypto.TokenClaims, error) {
+	var claims crypto.TokenClaims
+	var err error
+	if cv, ok := validator.(ContextClaimsValidator); ok {
+		claims, err = cv.ValidateClaimsContext(ctx, token)
+	} else if cv, ok := validator.(ClaimsValidator); ok {
+		claims, err = cv.ValidateClaims(token)
+	} else {
+		claims.Subject, claims.SessionID, err = validator.ValidateToken(token)
+	}
+	return claims, err
+}
+
+// withClaims returns ctx carrying claims for the GetX helpers.
+func withClaims(ctx context.Context, claims crypto.TokenClaims) context.Context {
+	ctx = context.WithValue(ctx, UserIDKey, claims.Subject)
+	ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
+	if claims.Actor != "" {
+		ctx = context.WithValue(ctx, ImpersonatorKey, claims.Actor)
+	}
+	if len(claims.Scope) > 0 {
+		ctx = context.WithValue(ctx, ScopeKey, claims.Scope)
+	}
+	if len(claims.Context) > 0 {
+		ctx = context.WithValue(ctx, ClaimsKey, claims.Context)
+	}
+	return ctx
+}
+
 // Session validates session cookies and injects user context.
 // If the session is invalid, it clears the cookie and redirects to /signin.
 func Session(validator SessionValidator) func(http.Handler) http.Handler {
@@ -51,17 +120,13 @@ func Session(validator SessionValidator) func(http.Handler) http.Handler {
 				return
 			}
 
-			userID, sessionID, err := validator.ValidateToken(cookie.Value)
+			ctx, err := authenticate(r.Context(), validator, cookie.Value)
 			if err != nil {
 				clearSessionCookie(w)
 				http.Redirect(w, r, "/signin", http.StatusSeeOther)
 				return
 			}
 
-			ctx := r.Context()
-			ctx = context.WithValue(ctx, UserIDKey, userID)
-			ctx = context.WithValue(ctx, SessionIDKey, sessionID)
-
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -91,6 +156,37 @@ func GetUserID(ctx context.Context) string {
 	return ""
 }
 
+// GetImpersonator returns the user acting as the authenticated user when the
+// request carries an impersonation token, and an empty string otherwise.
+func GetImpersonator(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(ImpersonatorKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// GetClaims returns the custom claims of the token the request was
+// authenticated with, such as those added by service.ClaimsEnricher, and nil
+// when it has none. Only ClaimsValidator validators provide them.
+func GetClaims(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	if claims, ok := ctx.Value(ClaimsKey).(map[string]string); ok {
+		return claims
+	}
+	return nil
+}
+
+// GetClaim returns the custom claim name of the token the request was
+// authenticated with, and an empty string when it is not set.
+func GetClaim(ctx context.Context, name string) string {
+	return GetClaims(ctx)[name]
+}
+
 // GetSessionID extracts the session ID from the context.
 // Returns an empty string if no session ID is found.
 func GetSessionID(ctx context.Context) string {
@@ -111,7 +207,8 @@ type RoleChecker interface {
 	CheckAllPermissions(ctx context.Context, userID string, permissions []string) (bool, error)
 }
 
-// RequireRole creates middleware that requires a specific role.
+// RequireRole creates middleware that requires a specific role. Scoped
+// tokens never pass it.
 func RequireRole(checker RoleChecker, roleName string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -121,7 +218,7 @@ func RequireRole(checker RoleChecker, roleName string) func(http.Handler) http.H
 				return
 			}
 
-			hasRole, err := checker.HasRole(r.Context(), userID, roleName)
+			hasRole, err := ScopedChecker(checker).HasRole(r.Context(), userID, roleName)
 			if err != nil {
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 				return
@@ -138,6 +235,7 @@ func RequireRole(checker RoleChecker, roleName string) func(http.Handler) http.H
 }
 
 // RequirePermission creates middleware that requires a specific permission.
+// Scoped tokens also need it in their scope.
 func RequirePermission(checker RoleChecker, permission string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -147,7 +245,7 @@ func RequirePermission(checker RoleChecker, permission string) func(http.Handler
 				return
 			}
 
-			hasPermission, err := checker.CheckPermission(r.Context(), userID, permission)
+			hasPermission, err := ScopedChecker(checker).CheckPermission(r.Context(), userID, permission)
 			if err != nil {
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 				return
@@ -173,7 +271,7 @@ func RequireAnyPermission(checker RoleChecker, permissions []string) func(http.H
 				return
 			}
 
-			hasPermission, err := checker.CheckAnyPermission(r.Context(), userID, permissions)
+			hasPermission, err := ScopedChecker(checker).CheckAnyPermission(r.Context(), userID, permissions)
 			if err != nil {
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 				return
@@ -199,7 +297,7 @@ func RequireAllPermissions(checker RoleChecker, permissions []string) func(http.
 				return
 			}
 
-			hasPermissions, err := checker.CheckAllPermissions(r.Context(), userID, permissions)
+			hasPermissions, err := ScopedChecker(checker).CheckAllPermissions(r.Context(), userID, permissions)
 			if err != nil {
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 				return
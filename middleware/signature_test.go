@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/crypto"
+)
+
+func TestRequireSignature(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	keys := StaticSigningKeys{"authz": []byte("authz-secret"), "seeder": []byte("seeder-secret")}
+
+	var gotKey, gotBody string
+	handler := RequireSignature(RequestSignatureConfig{Keys: keys, Now: func() time.Time { return now }, MaxBodySize: 64})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			gotKey, gotBody = GetSigningKeyID(r.Context()), string(body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+	request := func(body, keyID string, secret []byte) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/auth/bootstrap", strings.NewReader(body))
+		if secret != nil {
+			if err := crypto.SignRequest(r, keyID, secret, now); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return r
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, request(`{"a":1}`, "seeder", []byte("seeder-secret")))
+	if w.Code != http.StatusNoContent || gotKey != "seeder" || gotBody != `{"a":1}` {
+		t.Fatalf("signed request = %d, key %q, body %q", w.Code, gotKey, gotBody)
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"unsigned", request(`{}`, "", nil), http.StatusUnauthorized},
+		{"unknown key", request(`{}`, "gateway", []byte("seeder-secret")), http.StatusUnauthorized},
+		{"no key id", request(`{}`, "", []byte("seeder-secret")), http.StatusUnauthorized},
+		{"other caller's secret", request(`{}`, "seeder", []byte("authz-secret")), http.StatusUnauthorized},
+		{"body too large", request(strings.Repeat("x", 100), "seeder", []byte("seeder-secret")), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tt.req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	stale := request(`{}`, "seeder", []byte("seeder-secret"))
+	now = now.Add(time.Hour)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, stale)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("replayed request = %d, want 401", w.Code)
+	}
+}
+
+func TestRequireSignatureSharedSecret(t *testing.T) {
+	secret := []byte("shared-secret")
+	handler := RequireSignature(RequestSignatureConfig{Keys: SharedSecret(secret)})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	r := httptest.NewRequest(http.MethodGet, "/system/bootstrap-status", nil)
+	crypto.SignRequest(r, "", secret, time.Now())
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("shared secret request = %d, want 200", w.Code)
+	}
+}
+
+func TestRequireSignatureKeyLookupError(t *testing.T) {
+	keys := SigningKeysFunc(func(context.Context, string) ([]byte, error) {
+		return nil, errors.New("secrets store down")
+	})
+	handler := RequireSignature(RequestSignatureConfig{Keys: keys})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	r := httptest.NewRequest(http.MethodGet, "/internal", nil)
+	crypto.SignRequest(r, "svc", []byte("secret"), time.Now())
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("lookup failure = %d, want 500", w.Code)
+	}
+}
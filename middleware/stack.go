@@ -9,11 +9,19 @@ import (
 )
 
 // DefaultStack returns the standard middleware stack for all AQM services.
-// This stack includes: RequestID, RealIP, Logger, and Recoverer.
+// This stack includes: RequestID, ClientIP, Logger, and Recoverer. No proxy is
+// trusted, so forwarding headers are ignored and the connection's peer is the
+// client; behind a proxy use ClientIPStack.
 func DefaultStack() []func(http.Handler) http.Handler {
+	return ClientIPStack(ClientIPConfig{})
+}
+
+// ClientIPStack returns the standard middleware stack with ClientIP(cfg), so
+// forwarding headers are believed only from the trusted proxies of cfg.
+func ClientIPStack(cfg ClientIPConfig) []func(http.Handler) http.Handler {
 	return []func(http.Handler) http.Handler{
 		middleware.RequestID,
-		middleware.RealIP,
+		ClientIP(cfg),
 		middleware.Logger,
 		middleware.Recoverer,
 	}
@@ -14,6 +14,14 @@ func TestDefaultStack(t *testing.T) {
 	}
 }
 
+func TestClientIPStack(t *testing.T) {
+	stack := ClientIPStack(ClientIPConfig{})
+
+	if len(stack) != len(DefaultStack()) {
+		t.Errorf("ClientIPStack() returned %d middlewares, want %d", len(stack), len(DefaultStack()))
+	}
+}
+
 func TestDefaultInternal(t *testing.T) {
 	stack := DefaultInternal()
 
@@ -22,6 +30,30 @@ func TestDefaultInternal(t *testing.T) {
 	}
 }
 
+func TestDefaultInternalIgnoresForwardingHeaders(t *testing.T) {
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	stack := DefaultInternal()
+	for i := len(stack) - 1; i >= 0; i-- {
+		handler = stack[i](handler)
+	}
+
+	for _, header := range []string{"X-Forwarded-For", "X-Real-IP"} {
+		t.Run(header, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "8.8.8.8:1234"
+			req.Header.Set(header, "10.0.0.1")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("public peer claiming a private address = %d, want %d", rec.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
 func TestInternalOnly(t *testing.T) {
 	handler := InternalOnly()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
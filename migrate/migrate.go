@@ -3,17 +3,19 @@ package migrate
 import (
 	"context"
 	"database/sql"
-	"embed"
 	"fmt"
 	"io/fs"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/aquamarinepk/aqm/log"
 )
 
 // Migration represents a database migration.
+// Datetime is the version prefix of the file name (e.g. "20250101000000" or "001")
+// and determines the order in which migrations are applied.
 type Migration struct {
 	Datetime string
 	Name     string
@@ -21,17 +23,31 @@ type Migration struct {
 	Down     string
 }
 
+// ID returns the migration identifier as it appears in the file name.
+func (m Migration) ID() string {
+	return m.Datetime + "-" + m.Name
+}
+
+// Status reports whether a migration has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
 // Migrator handles database migrations with version tracking.
 type Migrator struct {
 	db       *sql.DB
+	dbFn     func() *sql.DB
 	log      log.Logger
-	assetsFS embed.FS
+	assetsFS fs.FS
 	engine   string
 	path     string
+	dryRun   bool
 }
 
-// New creates a new Migrator.
-func New(assetsFS embed.FS, engine string, logger log.Logger) *Migrator {
+// New creates a new Migrator. Migrations are read from assetsFS, typically an embed.FS.
+func New(assetsFS fs.FS, engine string, logger log.Logger) *Migrator {
 	return &Migrator{
 		assetsFS: assetsFS,
 		engine:   engine,
@@ -43,35 +59,51 @@ func (m *Migrator) SetDB(db *sql.DB) {
 	m.db = db
 }
 
+// SetDBProvider sets a function that returns the database at run time.
+// Use it when the connection is opened by another component's Start,
+// e.g. migrator.SetDBProvider(database.GetDB).
+func (m *Migrator) SetDBProvider(fn func() *sql.DB) {
+	m.dbFn = fn
+}
+
 func (m *Migrator) SetPath(path string) {
 	m.path = path
 }
 
+// SetDryRun makes Run and Down log the statements they would execute
+// without changing the database.
+func (m *Migrator) SetDryRun(dryRun bool) {
+	m.dryRun = dryRun
+}
+
+// Start implements app.Startable by running pending migrations, so a Migrator
+// can be passed to app.Setup after the component that opens the database.
+func (m *Migrator) Start(ctx context.Context) error {
+	return m.Run(ctx)
+}
+
 // Run executes pending migrations in order.
 // Creates migrations table if it doesn't exist.
 // Returns error if any migration fails (transactional per migration).
 func (m *Migrator) Run(ctx context.Context) error {
-	if err := m.ensureMigrationsTable(ctx); err != nil {
-		return fmt.Errorf("cannot create migrations table: %w", err)
-	}
-
-	fileMigrations, err := m.loadFileMigrations()
+	pending, err := m.Pending(ctx)
 	if err != nil {
-		return fmt.Errorf("cannot load file migrations: %w", err)
-	}
-
-	dbMigrations, err := m.loadDBMigrations()
-	if err != nil {
-		return fmt.Errorf("cannot load database migrations: %w", err)
+		return err
 	}
 
-	pending := m.findPendingMigrations(fileMigrations, dbMigrations)
-
 	if len(pending) == 0 {
 		m.log.Info("No pending migrations")
 		return nil
 	}
 
+	if m.dryRun {
+		m.log.Infof("Dry run: %d pending migration(s) would be applied", len(pending))
+		for _, migration := range pending {
+			m.log.Infof("Would apply migration %s:\n%s", migration.ID(), migration.Up)
+		}
+		return nil
+	}
+
 	m.log.Infof("Running %d pending migration(s)", len(pending))
 
 	for _, migration := range pending {
@@ -84,6 +116,122 @@ func (m *Migrator) Run(ctx context.Context) error {
 	return nil
 }
 
+// Pending returns the migrations that have not been applied yet, in order.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, st := range statuses {
+		if !st.Applied {
+			pending = append(pending, st.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Status returns every migration found in the assets with its applied state, in order.
+// Outside dry-run mode the migrations table is created if needed.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if m.conn() == nil {
+		return nil, fmt.Errorf("database is not set")
+	}
+
+	exists, err := m.prepareMigrationsTable(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create migrations table: %w", err)
+	}
+
+	fileMigrations, err := m.loadFileMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load file migrations: %w", err)
+	}
+
+	applied := map[string]time.Time{}
+	if exists {
+		applied, err = m.loadAppliedMigrations(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load database migrations: %w", err)
+		}
+	}
+
+	statuses := make([]Status, 0, len(fileMigrations))
+	for _, fm := range fileMigrations {
+		at, ok := applied[fm.Datetime+fm.Name]
+		statuses = append(statuses, Status{Migration: fm, Applied: ok, AppliedAt: at})
+	}
+	return statuses, nil
+}
+
+// Down rolls back the last steps applied migrations in reverse order using
+// their Down sections. Each rollback runs in its own transaction.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps must be at least 1, got %d", steps)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	var applied []Migration
+	for i := len(statuses) - 1; i >= 0 && len(applied) < steps; i-- {
+		if statuses[i].Applied {
+			applied = append(applied, statuses[i].Migration)
+		}
+	}
+
+	if len(applied) == 0 {
+		m.log.Info("No migrations to roll back")
+		return nil
+	}
+
+	for _, migration := range applied {
+		if migration.Down == "" {
+			return fmt.Errorf("no Down section found in migration %s", migration.ID())
+		}
+	}
+
+	if m.dryRun {
+		m.log.Infof("Dry run: %d migration(s) would be rolled back", len(applied))
+		for _, migration := range applied {
+			m.log.Infof("Would roll back migration %s:\n%s", migration.ID(), migration.Down)
+		}
+		return nil
+	}
+
+	for _, migration := range applied {
+		if err := m.rollbackMigration(ctx, migration); err != nil {
+			return fmt.Errorf("rollback of %s failed: %w", migration.ID(), err)
+		}
+		m.log.Infof("Rolled back migration: %s", migration.ID())
+	}
+
+	return nil
+}
+
+func (m *Migrator) conn() *sql.DB {
+	if m.db == nil && m.dbFn != nil {
+		return m.dbFn()
+	}
+	return m.db
+}
+
+// prepareMigrationsTable creates the migrations table, or in dry-run mode
+// only reports whether it exists.
+func (m *Migrator) prepareMigrationsTable(ctx context.Context) (bool, error) {
+	if !m.dryRun {
+		return true, m.ensureMigrationsTable(ctx)
+	}
+
+	var exists bool
+	err := m.conn().QueryRowContext(ctx, "SELECT to_regclass('migrations') IS NOT NULL").Scan(&exists)
+	return exists, err
+}
+
 func (m *Migrator) ensureMigrationsTable(ctx context.Context) error {
 	query := `
 	CREATE TABLE IF NOT EXISTS migrations (
@@ -93,7 +241,7 @@ func (m *Migrator) ensureMigrationsTable(ctx context.Context) error {
 		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 	);
 	`
-	_, err := m.db.ExecContext(ctx, query)
+	_, err := m.conn().ExecContext(ctx, query)
 	return err
 }
 
@@ -115,7 +263,7 @@ func (m *Migrator) loadFileMigrations() ([]Migration, error) {
 				return fmt.Errorf("invalid migration filename: %s", filename)
 			}
 
-			content, err := m.assetsFS.ReadFile(path)
+			content, err := fs.ReadFile(m.assetsFS, path)
 			if err != nil {
 				return fmt.Errorf("cannot read migration file %s: %w", path, err)
 			}
@@ -150,37 +298,23 @@ func (m *Migrator) loadFileMigrations() ([]Migration, error) {
 	return migrations, nil
 }
 
-func (m *Migrator) loadDBMigrations() ([]Migration, error) {
-	rows, err := m.db.Query("SELECT datetime, name FROM migrations ORDER BY datetime")
+func (m *Migrator) loadAppliedMigrations(ctx context.Context) (map[string]time.Time, error) {
+	rows, err := m.conn().QueryContext(ctx, "SELECT datetime, name, created_at FROM migrations ORDER BY datetime")
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var migrations []Migration
+	applied := make(map[string]time.Time)
 	for rows.Next() {
-		var migration Migration
-		if err := rows.Scan(&migration.Datetime, &migration.Name); err != nil {
+		var datetime, name string
+		var at time.Time
+		if err := rows.Scan(&datetime, &name, &at); err != nil {
 			return nil, err
 		}
-		migrations = append(migrations, migration)
+		applied[datetime+name] = at
 	}
-	return migrations, nil
-}
-
-func (m *Migrator) findPendingMigrations(fileMigrations, dbMigrations []Migration) []Migration {
-	dbMigrationsMap := make(map[string]struct{})
-	for _, dbMigration := range dbMigrations {
-		dbMigrationsMap[dbMigration.Datetime+dbMigration.Name] = struct{}{}
-	}
-
-	var pendingMigrations []Migration
-	for _, fileMigration := range fileMigrations {
-		if _, exists := dbMigrationsMap[fileMigration.Datetime+fileMigration.Name]; !exists {
-			pendingMigrations = append(pendingMigrations, fileMigration)
-		}
-	}
-	return pendingMigrations
+	return applied, rows.Err()
 }
 
 func (m *Migrator) runMigration(ctx context.Context, migration Migration) error {
@@ -188,7 +322,7 @@ func (m *Migrator) runMigration(ctx context.Context, migration Migration) error
 		return fmt.Errorf("no Up section found in migration %s-%s", migration.Datetime, migration.Name)
 	}
 
-	tx, err := m.db.BeginTx(ctx, nil)
+	tx, err := m.conn().BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -206,3 +340,23 @@ func (m *Migrator) runMigration(ctx context.Context, migration Migration) error
 
 	return tx.Commit()
 }
+
+func (m *Migrator) rollbackMigration(ctx context.Context, migration Migration) error {
+	tx, err := m.conn().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM migrations WHERE datetime = $1 AND name = $2",
+		migration.Datetime, migration.Name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
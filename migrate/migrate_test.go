@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"embed"
-	"fmt"
 	"testing"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -15,19 +14,14 @@ import (
 //go:embed testdata
 var testAssetsFS embed.FS
 
+func TestMain(m *testing.M) {
+	testhelper.Main(m)
+}
+
 func setupTestDB(t *testing.T) (*sql.DB, func()) {
 	t.Helper()
 
-	db, schema, cleanup := testhelper.SetupTestDB(t)
-
-	// Set search_path for this test
-	ctx := context.Background()
-	_, err := db.ExecContext(ctx, fmt.Sprintf("SET search_path TO %s", schema))
-	if err != nil {
-		cleanup()
-		t.Fatalf("cannot set search_path: %v", err)
-	}
-
+	db, _, cleanup := testhelper.SetupTestDB(t)
 	return db, cleanup
 }
 
@@ -217,3 +211,157 @@ func TestSetDB(t *testing.T) {
 		t.Error("expected db to be set after SetDB")
 	}
 }
+
+func newTestMigrator(db *sql.DB) *Migrator {
+	migrator := New(testAssetsFS, "postgres", log.NewLogger("error"))
+	migrator.SetDB(db)
+	migrator.SetPath("testdata/migration/postgres")
+	return migrator
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var exists bool
+	if err := db.QueryRow("SELECT to_regclass($1) IS NOT NULL", name).Scan(&exists); err != nil {
+		t.Fatalf("failed to check %s existence: %v", name, err)
+	}
+	return exists
+}
+
+func TestMigratorStatus(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	migrator := newTestMigrator(db)
+	ctx := context.Background()
+
+	before, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(before) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(before))
+	}
+	for _, st := range before {
+		if st.Applied {
+			t.Errorf("migration %s should be pending", st.ID())
+		}
+	}
+
+	if err := migrator.Run(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	after, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	for _, st := range after {
+		if !st.Applied || st.AppliedAt.IsZero() {
+			t.Errorf("migration %s should be applied", st.ID())
+		}
+	}
+}
+
+func TestMigratorDown(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	migrator := newTestMigrator(db)
+	ctx := context.Background()
+
+	if err := migrator.Run(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if err := migrator.Down(ctx, 1); err != nil {
+		t.Fatalf("Down(1) failed: %v", err)
+	}
+	if tableExists(t, db, "idx_test_table_name") {
+		t.Error("latest migration should be rolled back")
+	}
+	if !tableExists(t, db, "test_table") {
+		t.Error("earlier migration should remain applied")
+	}
+
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		t.Fatalf("Pending failed: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Name != "add-test-index" {
+		t.Errorf("expected add-test-index to be pending, got %v", pending)
+	}
+
+	if err := migrator.Down(ctx, 5); err != nil {
+		t.Fatalf("Down(5) failed: %v", err)
+	}
+	if tableExists(t, db, "test_table") {
+		t.Error("all migrations should be rolled back")
+	}
+
+	if err := migrator.Down(ctx, 0); err == nil {
+		t.Error("Down(0) should fail")
+	}
+}
+
+func TestMigratorDryRun(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	migrator := newTestMigrator(db)
+	migrator.SetDryRun(true)
+	ctx := context.Background()
+
+	if err := migrator.Run(ctx); err != nil {
+		t.Fatalf("dry Run failed: %v", err)
+	}
+	if tableExists(t, db, "migrations") || tableExists(t, db, "test_table") {
+		t.Error("dry run should not change the database")
+	}
+
+	migrator.SetDryRun(false)
+	if err := migrator.Run(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	migrator.SetDryRun(true)
+	if err := migrator.Down(ctx, 2); err != nil {
+		t.Fatalf("dry Down failed: %v", err)
+	}
+	if !tableExists(t, db, "test_table") {
+		t.Error("dry run rollback should not change the database")
+	}
+}
+
+func TestMigratorStartWithDBProvider(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	migrator := New(testAssetsFS, "postgres", log.NewLogger("error"))
+	migrator.SetPath("testdata/migration/postgres")
+	migrator.SetDBProvider(func() *sql.DB { return db })
+
+	if err := migrator.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if !tableExists(t, db, "test_table") {
+		t.Error("Start should apply pending migrations")
+	}
+}
+
+func TestMigratorWithoutDB(t *testing.T) {
+	migrator := New(testAssetsFS, "postgres", log.NewLogger("error"))
+
+	if err := migrator.Run(context.Background()); err == nil {
+		t.Error("Run without a database should fail")
+	}
+}
+
+func TestMigrationID(t *testing.T) {
+	m := Migration{Datetime: "20250101000000", Name: "create-test-table"}
+
+	if got := m.ID(); got != "20250101000000-create-test-table" {
+		t.Errorf("ID() = %q, want %q", got, "20250101000000-create-test-table")
+	}
+}
@@ -0,0 +1,5 @@
+-- +migrate Up
+CREATE INDEX IF NOT EXISTS idx_test_table_name ON test_table(name);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_test_table_name;
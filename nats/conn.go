@@ -0,0 +1,177 @@
+// Package nats owns the NATS connection of a service. A Conn connects with
+// backoff on Start, keeps reconnecting with backoff up to
+// NATSConfig.MaxReconnect times when the connection drops, drains on Stop
+// and reports the "nats" readiness check. Pass it to app.Setup with the
+// other components and share it with the pubsub/nats broker through
+// NewBrokerWithConn instead of opening raw connections.
+package nats
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/log"
+	gonats "github.com/nats-io/nats.go"
+)
+
+// maxBackoff caps the delay between connection attempts.
+const maxBackoff = 30 * time.Second
+
+// Conn is a NATS connection shared by the components of a service.
+type Conn struct {
+	cfg config.NATSConfig
+	log log.Logger
+
+	mu sync.RWMutex
+	nc *gonats.Conn
+}
+
+// New creates a connection for cfg. It connects on Start.
+func New(cfg config.NATSConfig, logger log.Logger) *Conn {
+	if logger == nil {
+		logger = log.NewNoopLogger()
+	}
+	if cfg.ReconnectWait <= 0 {
+		cfg.ReconnectWait = time.Second
+	}
+	return &Conn{cfg: cfg, log: logger.With("component", "nats")}
+}
+
+// Start connects to cfg.URL, retrying with backoff up to MaxReconnect times
+// (forever when negative) or until ctx is done.
+func (c *Conn) Start(ctx context.Context) error {
+	opts := []gonats.Option{
+		gonats.MaxReconnects(c.cfg.MaxReconnect),
+		gonats.CustomReconnectDelay(func(attempts int) time.Duration {
+			return backoff(c.cfg.ReconnectWait, attempts)
+		}),
+		gonats.DisconnectErrHandler(func(nc *gonats.Conn, err error) {
+			if err != nil {
+				c.log.Errorf("NATS disconnected: %v", err)
+			}
+		}),
+		gonats.ReconnectHandler(func(nc *gonats.Conn) {
+			c.log.Infof("NATS reconnected to %s", nc.ConnectedUrl())
+		}),
+		gonats.ClosedHandler(func(nc *gonats.Conn) {
+			c.log.Info("NATS connection closed")
+		}),
+	}
+	if c.cfg.ClientID != "" {
+		opts = append(opts, gonats.Name(c.cfg.ClientID))
+	}
+	if c.cfg.ConnectTimeout > 0 {
+		opts = append(opts, gonats.Timeout(c.cfg.ConnectTimeout))
+	}
+
+	for attempt := 1; ; attempt++ {
+		nc, err := gonats.Connect(c.cfg.URL, opts...)
+		if err == nil {
+			c.mu.Lock()
+			c.nc = nc
+			c.mu.Unlock()
+			c.log.Infof("Connected to NATS at %s", nc.ConnectedUrl())
+			return nil
+		}
+		if c.cfg.MaxReconnect >= 0 && attempt > c.cfg.MaxReconnect {
+			return fmt.Errorf("cannot connect to NATS: %w", err)
+		}
+
+		wait := backoff(c.cfg.ReconnectWait, attempt)
+		c.log.Errorf("Cannot connect to NATS, retrying in %s: %v", wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("cannot connect to NATS: %w", ctx.Err())
+		}
+	}
+}
+
+// Stop drains the connection: subscriptions stop receiving, messages in
+// flight are handled, pending publishes are flushed, and the connection is
+// closed. It waits up to DrainTimeout, or until ctx is done, and then closes
+// the connection regardless.
+func (c *Conn) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	nc := c.nc
+	c.nc = nil
+	c.mu.Unlock()
+	if nc == nil {
+		return nil
+	}
+
+	if c.cfg.DrainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.DrainTimeout)
+		defer cancel()
+	}
+
+	closed := make(chan struct{})
+	nc.SetClosedHandler(func(*gonats.Conn) {
+		c.log.Info("NATS connection drained")
+		close(closed)
+	})
+	if err := nc.Drain(); err != nil {
+		nc.Close()
+		return fmt.Errorf("cannot drain NATS connection: %w", err)
+	}
+
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		nc.Close()
+		return fmt.Errorf("cannot drain NATS connection: %w", ctx.Err())
+	}
+}
+
+// Close closes the connection without draining it.
+func (c *Conn) Close() {
+	c.mu.Lock()
+	nc := c.nc
+	c.nc = nil
+	c.mu.Unlock()
+	if nc != nil {
+		nc.Close()
+	}
+}
+
+// NATS returns the underlying connection, nil before Start and after Stop.
+func (c *Conn) NATS() *gonats.Conn {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.nc
+}
+
+// CheckHealth reports whether the connection is up.
+func (c *Conn) CheckHealth(ctx context.Context) error {
+	nc := c.NATS()
+	if nc == nil {
+		return fmt.Errorf("nats not connected")
+	}
+	if !nc.IsConnected() {
+		return fmt.Errorf("nats connection is %s", nc.Status())
+	}
+	return nil
+}
+
+// HealthChecks reports the "nats" readiness check.
+// Implements app.HealthReporter interface.
+func (c *Conn) HealthChecks() map[string]func(context.Context) error {
+	return map[string]func(context.Context) error{"nats": c.CheckHealth}
+}
+
+// backoff returns the delay before connection attempt n: wait, doubled on
+// every further attempt and capped at maxBackoff.
+func backoff(wait time.Duration, attempt int) time.Duration {
+	d := wait
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, max(wait, maxBackoff))
+}
@@ -0,0 +1,78 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/log"
+)
+
+// closedURL returns a NATS URL nothing listens on.
+func closedURL(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return "nats://" + addr
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		wait    time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		{time.Second, 1, time.Second},
+		{time.Second, 2, 2 * time.Second},
+		{time.Second, 4, 8 * time.Second},
+		{time.Second, 10, maxBackoff},
+		{time.Minute, 3, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.wait, tt.attempt); got != tt.want {
+			t.Errorf("backoff(%s, %d) = %s, want %s", tt.wait, tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestStartGivesUpAfterMaxReconnect(t *testing.T) {
+	c := New(config.NATSConfig{URL: closedURL(t), MaxReconnect: 2, ReconnectWait: time.Millisecond}, log.NewNoopLogger())
+
+	if err := c.Start(context.Background()); err == nil {
+		t.Fatal("Start() error = nil, want error")
+	}
+	if err := c.CheckHealth(context.Background()); err == nil {
+		t.Error("CheckHealth() error = nil, want not connected")
+	}
+}
+
+func TestStartHonorsContext(t *testing.T) {
+	c := New(config.NATSConfig{URL: closedURL(t), MaxReconnect: -1, ReconnectWait: time.Hour}, log.NewNoopLogger())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.Start(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Start() error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestStopBeforeStart(t *testing.T) {
+	c := New(config.NATSConfig{}, nil)
+
+	if err := c.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if c.NATS() != nil {
+		t.Error("NATS() != nil before Start")
+	}
+	if _, ok := c.HealthChecks()["nats"]; !ok {
+		t.Error("HealthChecks() has no nats check")
+	}
+}
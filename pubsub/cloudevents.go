@@ -0,0 +1,215 @@
+package pubsub
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CloudEventsSpecVersion is the CloudEvents version CloudEventsCodec writes
+// and accepts.
+const CloudEventsSpecVersion = "1.0"
+
+// Metadata keys mapped to CloudEvents attributes. Set them on an envelope to
+// override the type, source or subject of the emitted event; Decode sets
+// them from the received event.
+const (
+	MetaEventType    = "type"
+	MetaEventSource  = "source"
+	MetaEventSubject = "subject"
+)
+
+// metadataExtension carries the metadata whose keys are not valid
+// CloudEvents attribute names, such as MetaDeadLetterTopic.
+const metadataExtension = "aqmmetadata"
+
+// contextAttributes are the attribute names CloudEvents defines, which
+// metadata cannot use as extensions.
+var contextAttributes = map[string]bool{
+	"specversion": true, "id": true, "source": true, "type": true,
+	"datacontenttype": true, "dataschema": true, "subject": true, "time": true,
+	"data": true, "data_base64": true, metadataExtension: true,
+}
+
+// CloudEventsCodec encodes envelopes as CloudEvents 1.0 in structured JSON
+// mode, so Knative, EventBridge and other CloudEvents infrastructure can
+// route and consume them.
+//
+// The envelope ID is the event id, the topic its type and the timestamp its
+// time; the payload is the JSON data, or data_base64 for a []byte payload.
+// Metadata becomes extension attributes, except keys CloudEvents does not
+// allow, which travel together in the aqmmetadata extension. Decode reverses
+// the mapping and accepts plain JSONCodec envelopes too, so services can
+// switch formats one at a time.
+type CloudEventsCodec struct {
+	// Source identifies the emitting service, a URI reference such as
+	// "/aqm/authn". MetaEventSource overrides it per envelope.
+	Source string
+}
+
+// NewCloudEventsCodec returns a codec emitting events from source.
+func NewCloudEventsCodec(source string) CloudEventsCodec {
+	return CloudEventsCodec{Source: source}
+}
+
+// Encode implements Codec.
+func (c CloudEventsCodec) Encode(env Envelope) ([]byte, error) {
+	source := env.Metadata[MetaEventSource]
+	if source == "" {
+		source = c.Source
+	}
+	eventType := env.Metadata[MetaEventType]
+	if eventType == "" {
+		eventType = env.Topic
+	}
+	switch {
+	case env.ID == "":
+		return nil, fmt.Errorf("cloudevents id is required")
+	case source == "":
+		return nil, fmt.Errorf("cloudevents source is required")
+	case eventType == "":
+		return nil, fmt.Errorf("cloudevents type is required")
+	}
+
+	event := map[string]any{
+		"specversion": CloudEventsSpecVersion,
+		"id":          env.ID,
+		"source":      source,
+		"type":        eventType,
+	}
+	if !env.Timestamp.IsZero() {
+		event["time"] = env.Timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	if subject := env.Metadata[MetaEventSubject]; subject != "" {
+		event["subject"] = subject
+	}
+	switch data := env.Payload.(type) {
+	case nil:
+	case []byte:
+		event["datacontenttype"] = "application/octet-stream"
+		event["data_base64"] = base64.StdEncoding.EncodeToString(data)
+	default:
+		event["datacontenttype"] = "application/json"
+		event["data"] = data
+	}
+
+	hidden := make(map[string]string)
+	for k, v := range env.Metadata {
+		switch {
+		case k == MetaEventType || k == MetaEventSource || k == MetaEventSubject:
+		case validExtensionName(k) && !contextAttributes[k]:
+			event[k] = v
+		default:
+			hidden[k] = v
+		}
+	}
+	if len(hidden) > 0 {
+		encoded, err := json.Marshal(hidden)
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal metadata: %w", err)
+		}
+		event[metadataExtension] = string(encoded)
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal cloudevent: %w", err)
+	}
+	return data, nil
+}
+
+// Decode implements Codec.
+func (c CloudEventsCodec) Decode(topic string, data []byte) (Envelope, error) {
+	var event map[string]json.RawMessage
+	if err := json.Unmarshal(data, &event); err != nil {
+		return Envelope{}, fmt.Errorf("cannot unmarshal cloudevent: %w", err)
+	}
+	if _, ok := event["specversion"]; !ok {
+		return JSONCodec{}.Decode(topic, data)
+	}
+
+	attrs := make(map[string]string, len(event))
+	for k, raw := range event {
+		if k == "data" {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			// Extensions may be numbers or booleans.
+			s = string(raw)
+		}
+		attrs[k] = s
+	}
+
+	if v := attrs["specversion"]; v != CloudEventsSpecVersion {
+		return Envelope{}, fmt.Errorf("unsupported cloudevents specversion: %s", v)
+	}
+	for _, required := range []string{"id", "source", "type"} {
+		if attrs[required] == "" {
+			return Envelope{}, fmt.Errorf("cloudevent %s is required", required)
+		}
+	}
+
+	env := Envelope{
+		ID:    attrs["id"],
+		Topic: topic,
+		Metadata: map[string]string{
+			MetaEventType:   attrs["type"],
+			MetaEventSource: attrs["source"],
+		},
+	}
+	if subject := attrs["subject"]; subject != "" {
+		env.Metadata[MetaEventSubject] = subject
+	}
+	if t := attrs["time"]; t != "" {
+		ts, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return Envelope{}, fmt.Errorf("invalid cloudevent time: %w", err)
+		}
+		env.Timestamp = ts
+	}
+
+	if raw, ok := event["data"]; ok {
+		if err := json.Unmarshal(raw, &env.Payload); err != nil {
+			return Envelope{}, fmt.Errorf("cannot unmarshal cloudevent data: %w", err)
+		}
+	} else if encoded := attrs["data_base64"]; encoded != "" {
+		payload, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return Envelope{}, fmt.Errorf("invalid cloudevent data_base64: %w", err)
+		}
+		env.Payload = payload
+	}
+
+	for k, v := range attrs {
+		if !contextAttributes[k] {
+			env.Metadata[k] = v
+		}
+	}
+	if hidden := attrs[metadataExtension]; hidden != "" {
+		var metadata map[string]string
+		if err := json.Unmarshal([]byte(hidden), &metadata); err != nil {
+			return Envelope{}, fmt.Errorf("invalid cloudevent %s: %w", metadataExtension, err)
+		}
+		for k, v := range metadata {
+			env.Metadata[k] = v
+		}
+	}
+
+	return env, nil
+}
+
+// validExtensionName reports whether name is a valid CloudEvents attribute
+// name: lower-case ASCII letters and digits only.
+func validExtensionName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
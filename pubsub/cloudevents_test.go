@@ -0,0 +1,139 @@
+package pubsub
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCloudEventsCodecEncode(t *testing.T) {
+	env := NewEnvelope("todo.created", map[string]any{"title": "milk"}).
+		WithMetadata("tenant", "acme").
+		WithMetadata(MetaDeadLetterTopic, "todo.created.dlq")
+	env.Timestamp = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	data, err := NewCloudEventsCodec("/aqm/ticked").Encode(env)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	var event map[string]any
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("Encode() wrote invalid JSON: %v", err)
+	}
+	want := map[string]any{
+		"specversion":     "1.0",
+		"id":              env.ID,
+		"source":          "/aqm/ticked",
+		"type":            "todo.created",
+		"time":            "2026-01-02T03:04:05Z",
+		"datacontenttype": "application/json",
+		"tenant":          "acme",
+		"aqmmetadata":     `{"dlq_topic":"todo.created.dlq"}`,
+	}
+	for k, v := range want {
+		if event[k] != v {
+			t.Errorf("%s = %v, want %v", k, event[k], v)
+		}
+	}
+	if data, _ := event["data"].(map[string]any); data["title"] != "milk" {
+		t.Errorf("data = %v", event["data"])
+	}
+}
+
+func TestCloudEventsCodecRoundTrip(t *testing.T) {
+	codec := NewCloudEventsCodec("/aqm/ticked")
+	env := NewEnvelope("todo.created", []byte{0x01, 0x02}).
+		WithMetadata(MetaEventType, "com.example.todo.created").
+		WithMetadata(MetaEventSubject, "todo-1").
+		WithMetadata(MetaDeadLetterAttempts, "3")
+
+	data, err := codec.Encode(env)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	got, err := codec.Decode("todo.created", data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if got.ID != env.ID || got.Topic != "todo.created" || !got.Timestamp.Equal(env.Timestamp) {
+		t.Errorf("Decode() = %+v, want %+v", got, env)
+	}
+	if payload, _ := got.Payload.([]byte); !bytes.Equal(payload, []byte{0x01, 0x02}) {
+		t.Errorf("payload = %v, want the original bytes", got.Payload)
+	}
+	wantMeta := map[string]string{
+		MetaEventType:          "com.example.todo.created",
+		MetaEventSource:        "/aqm/ticked",
+		MetaEventSubject:       "todo-1",
+		MetaDeadLetterAttempts: "3",
+	}
+	for k, v := range wantMeta {
+		if got.Metadata[k] != v {
+			t.Errorf("metadata %s = %q, want %q", k, got.Metadata[k], v)
+		}
+	}
+}
+
+func TestCloudEventsCodecDecodeForeignEvent(t *testing.T) {
+	data := []byte(`{
+		"specversion": "1.0",
+		"id": "A234-1234-1234",
+		"source": "https://github.com/cloudevents/spec/pull",
+		"type": "com.github.pull_request.opened",
+		"time": "2018-04-05T17:31:00Z",
+		"comexampleextension1": "value",
+		"comexampleothervalue": 5,
+		"datacontenttype": "text/xml",
+		"data": "<much wow=\"xml\"/>"
+	}`)
+
+	got, err := CloudEventsCodec{}.Decode("github", data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.ID != "A234-1234-1234" || got.Payload != `<much wow="xml"/>` || got.Timestamp.Year() != 2018 {
+		t.Errorf("Decode() = %+v", got)
+	}
+	if got.Metadata["comexampleextension1"] != "value" || got.Metadata["comexampleothervalue"] != "5" {
+		t.Errorf("extensions = %v", got.Metadata)
+	}
+}
+
+func TestCloudEventsCodecDecodesPlainEnvelopes(t *testing.T) {
+	env := NewEnvelope("todo.created", "payload")
+	data, _ := JSONCodec{}.Encode(env)
+
+	got, err := CloudEventsCodec{}.Decode("todo.created", data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.ID != env.ID || got.Payload != "payload" {
+		t.Errorf("Decode() = %+v, want %+v", got, env)
+	}
+}
+
+func TestCloudEventsCodecErrors(t *testing.T) {
+	if _, err := (CloudEventsCodec{}).Encode(NewEnvelope("todo.created", nil)); err == nil {
+		t.Error("Encode() without source: error = nil, want error")
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"unsupported version", `{"specversion":"0.3","id":"1","source":"/s","type":"t"}`},
+		{"missing type", `{"specversion":"1.0","id":"1","source":"/s"}`},
+		{"invalid time", `{"specversion":"1.0","id":"1","source":"/s","type":"t","time":"yesterday"}`},
+		{"not json", `<event/>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := (CloudEventsCodec{}).Decode("t", []byte(tt.data)); err == nil {
+				t.Error("Decode() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Codec converts envelopes to and from the bytes a broker sends. Brokers
+// use JSONCodec unless configured otherwise.
+type Codec interface {
+	Encode(env Envelope) ([]byte, error)
+	// Decode parses a message received on topic.
+	Decode(topic string, data []byte) (Envelope, error)
+}
+
+// JSONCodec encodes envelopes as JSON objects with the Envelope fields.
+type JSONCodec struct{}
+
+// Encode implements Codec.
+func (JSONCodec) Encode(env Envelope) ([]byte, error) {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal envelope: %w", err)
+	}
+	return data, nil
+}
+
+// Decode implements Codec. Topic is filled in from topic when the message
+// does not carry it.
+func (JSONCodec) Decode(topic string, data []byte) (Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return Envelope{}, fmt.Errorf("cannot unmarshal envelope: %w", err)
+	}
+	if env.Topic == "" {
+		env.Topic = topic
+	}
+	return env, nil
+}
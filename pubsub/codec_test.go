@@ -0,0 +1,33 @@
+package pubsub
+
+import "testing"
+
+func TestJSONCodec(t *testing.T) {
+	env := NewEnvelope("orders", map[string]any{"id": "o-1"}).WithMetadata("tenant", "acme")
+
+	data, err := JSONCodec{}.Encode(env)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	got, err := JSONCodec{}.Decode("orders", data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if got.ID != env.ID || got.Topic != "orders" || got.Metadata["tenant"] != "acme" || !got.Timestamp.Equal(env.Timestamp) {
+		t.Errorf("Decode() = %+v, want %+v", got, env)
+	}
+	if payload, _ := got.Payload.(map[string]any); payload["id"] != "o-1" {
+		t.Errorf("payload = %v", got.Payload)
+	}
+}
+
+func TestJSONCodecFillsTopic(t *testing.T) {
+	got, err := JSONCodec{}.Decode("orders", []byte(`{"ID":"1"}`))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.Topic != "orders" {
+		t.Errorf("Topic = %q, want orders", got.Topic)
+	}
+}
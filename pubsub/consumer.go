@@ -0,0 +1,328 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+)
+
+// ErrConsumerStopped is returned for messages delivered after Stop began.
+var ErrConsumerStopped = errors.New("consumer stopped")
+
+// Metadata keys set on envelopes published to a dead-letter topic.
+const (
+	MetaDeadLetterTopic    = "dlq_topic"
+	MetaDeadLetterError    = "dlq_error"
+	MetaDeadLetterAttempts = "dlq_attempts"
+)
+
+// RetryPolicy controls how often a failing handler is retried.
+// The delay before retry n is InitialBackoff * Multiplier^(n-1), capped at MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+}
+
+// DefaultRetryPolicy returns three attempts with exponential backoff from 100ms.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+	}
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		d *= p.Multiplier
+	}
+	if p.MaxBackoff > 0 && time.Duration(d) > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return time.Duration(d)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying. A handler returning it has the
+// message sent to the dead-letter topic immediately.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+type attemptKey struct{}
+
+// Attempt returns the delivery attempt (starting at 1) of the message being
+// handled, or 0 outside a Consumer handler.
+func Attempt(ctx context.Context) int {
+	n, _ := ctx.Value(attemptKey{}).(int)
+	return n
+}
+
+// ConsumerOption configures a Consumer.
+type ConsumerOption func(*Consumer)
+
+// WithConsumerRetry sets the retry policy for handlers without their own.
+func WithConsumerRetry(p RetryPolicy) ConsumerOption {
+	return func(c *Consumer) {
+		c.retry = p
+	}
+}
+
+// WithDeadLetterSuffix sets the suffix appended to a topic to name its
+// dead-letter topic. The default is ".dlq".
+func WithDeadLetterSuffix(suffix string) ConsumerOption {
+	return func(c *Consumer) {
+		c.dlqSuffix = suffix
+	}
+}
+
+// HandleOption configures a single handler registration.
+type HandleOption func(*route)
+
+// WithSubscriberID names the subscription (see SubscribeOptions).
+func WithSubscriberID(id string) HandleOption {
+	return func(r *route) {
+		r.opts.SubscriberID = id
+	}
+}
+
+// WithRetry overrides the consumer retry policy for this handler.
+func WithRetry(p RetryPolicy) HandleOption {
+	return func(r *route) {
+		r.retry = &p
+	}
+}
+
+// WithDeadLetterTopic overrides the dead-letter topic for this handler.
+// An empty topic disables dead-lettering; failed messages are only logged.
+func WithDeadLetterTopic(topic string) HandleOption {
+	return func(r *route) {
+		r.dlq = &topic
+	}
+}
+
+type route struct {
+	topic   string
+	handler Handler
+	opts    SubscribeOptions
+	retry   *RetryPolicy
+	dlq     *string
+}
+
+// Consumer manages subscriptions on behalf of handlers. Handlers are
+// registered with Handle before Start; Start subscribes them, failed
+// deliveries are retried with backoff and then published to a dead-letter
+// topic, and Stop waits for in-flight messages to finish.
+//
+// Consumer implements app.Startable and app.Stoppable. Pass it to app.Setup
+// after the broker so it starts once the broker is connected and stops
+// before the broker closes; components implementing app.SubscriptionRegistrar
+// are registered with it automatically.
+type Consumer struct {
+	sub       Subscriber
+	pub       Publisher
+	log       log.Logger
+	retry     RetryPolicy
+	dlqSuffix string
+
+	mu      sync.Mutex
+	routes  []*route
+	started bool
+	stopped bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewConsumer creates a consumer that subscribes through sub and publishes
+// dead letters through pub. A nil pub disables dead-lettering.
+func NewConsumer(sub Subscriber, pub Publisher, logger log.Logger, opts ...ConsumerOption) *Consumer {
+	c := &Consumer{
+		sub:       sub,
+		pub:       pub,
+		log:       logger.With("component", "consumer"),
+		retry:     DefaultRetryPolicy(),
+		dlqSuffix: ".dlq",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Handle registers handler for topic. It must be called before Start.
+func (c *Consumer) Handle(topic string, handler Handler, opts ...HandleOption) {
+	r := &route{topic: topic, handler: handler}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.routes = append(c.routes, r)
+}
+
+// Topics returns the registered topics in registration order.
+func (c *Consumer) Topics() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	topics := make([]string, len(c.routes))
+	for i, r := range c.routes {
+		topics[i] = r.topic
+	}
+	return topics
+}
+
+// Start subscribes every registered handler.
+func (c *Consumer) Start(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.started {
+		return fmt.Errorf("consumer already started")
+	}
+	c.started = true
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	for _, r := range c.routes {
+		if err := c.sub.Subscribe(ctx, r.topic, c.deliver(r), r.opts); err != nil {
+			return fmt.Errorf("cannot subscribe to %s: %w", r.topic, err)
+		}
+		c.log.Infof("Consuming topic %s", r.topic)
+	}
+	return nil
+}
+
+// Stop stops accepting messages and waits for in-flight handlers to finish.
+// If ctx expires first, pending retries are abandoned (and dead-lettered) and
+// ctx.Err is returned.
+func (c *Consumer) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.started || c.stopped {
+		c.mu.Unlock()
+		return nil
+	}
+	c.stopped = true
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		c.cancel()
+		c.log.Info("Consumer drained")
+		return nil
+	case <-ctx.Done():
+		c.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// deliver wraps a route handler with drain tracking, retries and dead-lettering.
+func (c *Consumer) deliver(r *route) Handler {
+	policy := c.retry
+	if r.retry != nil {
+		policy = *r.retry
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	dlq := r.topic + c.dlqSuffix
+	if r.dlq != nil {
+		dlq = *r.dlq
+	}
+
+	return func(_ context.Context, env Envelope) error {
+		c.mu.Lock()
+		if c.stopped {
+			c.mu.Unlock()
+			return ErrConsumerStopped
+		}
+		c.wg.Add(1)
+		ctx := c.ctx
+		c.mu.Unlock()
+		defer c.wg.Done()
+
+		var err error
+		attempt := 1
+		for ; attempt <= policy.MaxAttempts; attempt++ {
+			err = r.handler(context.WithValue(ctx, attemptKey{}, attempt), env)
+			if err == nil {
+				return nil
+			}
+
+			var perm permanentError
+			if errors.As(err, &perm) || attempt == policy.MaxAttempts {
+				break
+			}
+
+			c.log.Debugf("Handler for %s failed on attempt %d, retrying: %v", r.topic, attempt, err)
+			if !sleep(ctx, policy.backoff(attempt)) {
+				break
+			}
+		}
+
+		c.deadLetter(dlq, r.topic, env, attempt, err)
+		return err
+	}
+}
+
+func (c *Consumer) deadLetter(dlq, topic string, env Envelope, attempts int, err error) {
+	if dlq == "" || c.pub == nil {
+		c.log.Errorf("Dropping message %s from %s after %d attempts: %v", env.ID, topic, attempts, err)
+		return
+	}
+
+	meta := make(map[string]string, len(env.Metadata)+3)
+	for k, v := range env.Metadata {
+		meta[k] = v
+	}
+	meta[MetaDeadLetterTopic] = topic
+	meta[MetaDeadLetterError] = err.Error()
+	meta[MetaDeadLetterAttempts] = strconv.Itoa(attempts)
+	env.Metadata = meta
+
+	if pubErr := c.pub.Publish(context.Background(), dlq, env); pubErr != nil {
+		c.log.Errorf("Cannot dead-letter message %s to %s: %v", env.ID, dlq, pubErr)
+		return
+	}
+	c.log.Errorf("Dead-lettered message %s from %s to %s after %d attempts: %v", env.ID, topic, dlq, attempts, err)
+}
+
+// sleep waits for d and reports whether it completed before ctx was cancelled.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
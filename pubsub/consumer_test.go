@@ -0,0 +1,285 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+)
+
+func fastRetry(attempts int) RetryPolicy {
+	return RetryPolicy{MaxAttempts: attempts, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, Multiplier: 2}
+}
+
+func startConsumer(t *testing.T, c *Consumer) {
+	t.Helper()
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { c.Stop(context.Background()) })
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{5, time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := p.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestConsumerDelivers(t *testing.T) {
+	broker := NewMemoryBroker()
+	c := NewConsumer(broker, broker, log.NewNoopLogger())
+
+	var got []string
+	var attempts []int
+	c.Handle("orders.created", func(ctx context.Context, env Envelope) error {
+		got = append(got, env.ID)
+		attempts = append(attempts, Attempt(ctx))
+		return nil
+	})
+	startConsumer(t, c)
+
+	env := NewEnvelope("orders.created", "payload")
+	if err := broker.Publish(context.Background(), "orders.created", env); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	if len(got) != 1 || got[0] != env.ID {
+		t.Errorf("handler received %v, want [%s]", got, env.ID)
+	}
+	if attempts[0] != 1 {
+		t.Errorf("Attempt() = %d, want 1", attempts[0])
+	}
+}
+
+func TestConsumerRetriesThenSucceeds(t *testing.T) {
+	broker := NewMemoryBroker()
+	c := NewConsumer(broker, broker, log.NewNoopLogger(), WithConsumerRetry(fastRetry(3)))
+
+	var calls int
+	c.Handle("orders.created", func(ctx context.Context, env Envelope) error {
+		calls++
+		if Attempt(ctx) < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+
+	var dead atomic.Int32
+	broker.Subscribe(context.Background(), "orders.created.dlq", func(ctx context.Context, env Envelope) error {
+		dead.Add(1)
+		return nil
+	}, SubscribeOptions{})
+	startConsumer(t, c)
+
+	broker.Publish(context.Background(), "orders.created", NewEnvelope("orders.created", nil))
+
+	if calls != 3 {
+		t.Errorf("handler called %d times, want 3", calls)
+	}
+	if dead.Load() != 0 {
+		t.Error("successful retry should not dead-letter")
+	}
+}
+
+func TestConsumerDeadLetters(t *testing.T) {
+	broker := NewMemoryBroker()
+	c := NewConsumer(broker, broker, log.NewNoopLogger(), WithConsumerRetry(fastRetry(2)))
+
+	var calls int
+	c.Handle("orders.created", func(ctx context.Context, env Envelope) error {
+		calls++
+		return errors.New("boom")
+	})
+
+	var dlq []Envelope
+	broker.Subscribe(context.Background(), "orders.created.dlq", func(ctx context.Context, env Envelope) error {
+		dlq = append(dlq, env)
+		return nil
+	}, SubscribeOptions{})
+	startConsumer(t, c)
+
+	env := NewEnvelopeWithMetadata("orders.created", nil, map[string]string{"tenant": "acme"})
+	broker.Publish(context.Background(), "orders.created", env)
+
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+	if len(dlq) != 1 {
+		t.Fatalf("dead-letter topic received %d messages, want 1", len(dlq))
+	}
+
+	meta := dlq[0].Metadata
+	if dlq[0].ID != env.ID || meta["tenant"] != "acme" {
+		t.Errorf("dead letter should carry the original envelope, got %+v", dlq[0])
+	}
+	if meta[MetaDeadLetterTopic] != "orders.created" || meta[MetaDeadLetterError] != "boom" || meta[MetaDeadLetterAttempts] != "2" {
+		t.Errorf("dead letter metadata = %v", meta)
+	}
+	if _, ok := env.Metadata[MetaDeadLetterError]; ok {
+		t.Error("dead-lettering should not mutate the original metadata")
+	}
+}
+
+func TestConsumerPermanentError(t *testing.T) {
+	broker := NewMemoryBroker()
+	c := NewConsumer(broker, broker, log.NewNoopLogger(), WithConsumerRetry(fastRetry(5)))
+
+	var calls int
+	c.Handle("orders.created", func(ctx context.Context, env Envelope) error {
+		calls++
+		return Permanent(errors.New("malformed"))
+	}, WithDeadLetterTopic("poison"))
+
+	var dead int
+	broker.Subscribe(context.Background(), "poison", func(ctx context.Context, env Envelope) error {
+		dead++
+		return nil
+	}, SubscribeOptions{})
+	startConsumer(t, c)
+
+	broker.Publish(context.Background(), "orders.created", NewEnvelope("orders.created", nil))
+
+	if calls != 1 {
+		t.Errorf("permanent error should not be retried, calls = %d", calls)
+	}
+	if dead != 1 {
+		t.Errorf("custom dead-letter topic received %d messages, want 1", dead)
+	}
+}
+
+func TestConsumerRouteRetryOverride(t *testing.T) {
+	broker := NewMemoryBroker()
+	c := NewConsumer(broker, nil, log.NewNoopLogger(), WithConsumerRetry(fastRetry(5)))
+
+	var calls int
+	c.Handle("orders.created", func(ctx context.Context, env Envelope) error {
+		calls++
+		return errors.New("boom")
+	}, WithRetry(fastRetry(1)))
+	startConsumer(t, c)
+
+	broker.Publish(context.Background(), "orders.created", NewEnvelope("orders.created", nil))
+
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+}
+
+func TestConsumerStartTwice(t *testing.T) {
+	c := NewConsumer(NewMemoryBroker(), nil, log.NewNoopLogger())
+	startConsumer(t, c)
+
+	if err := c.Start(context.Background()); err == nil {
+		t.Error("second Start() should fail")
+	}
+}
+
+func TestConsumerStopDrains(t *testing.T) {
+	broker := NewMemoryBroker()
+	c := NewConsumer(broker, nil, log.NewNoopLogger())
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var finished atomic.Bool
+	c.Handle("slow", func(ctx context.Context, env Envelope) error {
+		close(entered)
+		<-release
+		finished.Store(true)
+		return nil
+	})
+	startConsumer(t, c)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		broker.Publish(context.Background(), "slow", NewEnvelope("slow", nil))
+	}()
+	<-entered
+
+	stopped := make(chan error)
+	go func() { stopped <- c.Stop(context.Background()) }()
+
+	select {
+	case <-stopped:
+		t.Fatal("Stop() returned before in-flight handler finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if !finished.Load() {
+		t.Error("in-flight handler should finish before Stop returns")
+	}
+	wg.Wait()
+
+	var late atomic.Bool
+	c.Handle("late", func(ctx context.Context, env Envelope) error { return nil })
+	handler := c.deliver(&route{topic: "late", handler: func(ctx context.Context, env Envelope) error {
+		late.Store(true)
+		return nil
+	}})
+	if err := handler(context.Background(), NewEnvelope("late", nil)); !errors.Is(err, ErrConsumerStopped) {
+		t.Errorf("delivery after Stop error = %v, want ErrConsumerStopped", err)
+	}
+	if late.Load() {
+		t.Error("handler should not run after Stop")
+	}
+}
+
+func TestConsumerStopTimeoutAbandonsRetries(t *testing.T) {
+	broker := NewMemoryBroker()
+	retry := RetryPolicy{MaxAttempts: 10, InitialBackoff: time.Hour, Multiplier: 1}
+	c := NewConsumer(broker, broker, log.NewNoopLogger(), WithConsumerRetry(retry))
+
+	failed := make(chan struct{}, 1)
+	c.Handle("orders.created", func(ctx context.Context, env Envelope) error {
+		failed <- struct{}{}
+		return errors.New("boom")
+	})
+
+	dead := make(chan Envelope, 1)
+	broker.Subscribe(context.Background(), "orders.created.dlq", func(ctx context.Context, env Envelope) error {
+		dead <- env
+		return nil
+	}, SubscribeOptions{})
+	startConsumer(t, c)
+
+	go broker.Publish(context.Background(), "orders.created", NewEnvelope("orders.created", nil))
+	<-failed
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop() error = %v, want DeadlineExceeded", err)
+	}
+
+	select {
+	case env := <-dead:
+		if env.Metadata[MetaDeadLetterAttempts] != "1" {
+			t.Errorf("attempts = %s, want 1", env.Metadata[MetaDeadLetterAttempts])
+		}
+	case <-time.After(time.Second):
+		t.Error("abandoned message should be dead-lettered")
+	}
+}
@@ -0,0 +1,280 @@
+// Package kafka implements pubsub.Broker over Kafka.
+//
+// Fan-out maps to consumer groups: every subscriber consumes in its own
+// group, so each one receives every message published to the topic after it
+// subscribed. Offsets are committed only once the handler has returned, so
+// delivery is at-least-once; named subscribers resume from their committed
+// offset after a restart, ephemeral ones never commit.
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/pubsub"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// subscription is a consumer group client and its poll loop.
+type subscription struct {
+	client  *kgo.Client
+	durable bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// Broker implements pubsub.Broker using Kafka.
+type Broker struct {
+	cfg           config.KafkaConfig
+	log           log.Logger
+	mu            sync.RWMutex
+	opts          []kgo.Opt
+	producer      *kgo.Client
+	codec         pubsub.Codec
+	subscriptions map[string]*subscription
+	closed        bool
+}
+
+// Option configures a Broker.
+type Option func(*Broker)
+
+// WithCodec sets the wire format of published and received messages, for
+// example pubsub.NewCloudEventsCodec. The default is pubsub.JSONCodec.
+func WithCodec(c pubsub.Codec) Option {
+	return func(b *Broker) {
+		b.codec = c
+	}
+}
+
+// NewBroker creates a new Kafka-backed pubsub broker. It connects on Start.
+func NewBroker(cfg config.KafkaConfig, log log.Logger, opts ...Option) *Broker {
+	b := &Broker{
+		cfg:           cfg,
+		log:           log.With("component", "pubsub"),
+		codec:         pubsub.JSONCodec{},
+		subscriptions: make(map[string]*subscription),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Start connects the producer to the Kafka cluster.
+// Implements app.Startable interface.
+func (b *Broker) Start(ctx context.Context) error {
+	opts, err := clientOptions(b.cfg)
+	if err != nil {
+		return fmt.Errorf("invalid kafka config: %w", err)
+	}
+
+	producer, err := kgo.NewClient(append(slices.Clone(opts), kgo.AllowAutoTopicCreation())...)
+	if err != nil {
+		return fmt.Errorf("cannot create Kafka client: %w", err)
+	}
+	if err := producer.Ping(ctx); err != nil {
+		producer.Close()
+		return fmt.Errorf("cannot connect to Kafka: %w", err)
+	}
+
+	b.mu.Lock()
+	b.opts = opts
+	b.producer = producer
+	b.mu.Unlock()
+
+	b.log.Infof("Connected to Kafka at %s", strings.Join(brokers(b.cfg.Brokers), ","))
+	return nil
+}
+
+// Stop waits for in-flight messages, commits their offsets, leaves the
+// consumer groups and flushes pending publishes, bounded by ctx.
+// Implements app.Stoppable interface.
+func (b *Broker) Stop(ctx context.Context) error {
+	return b.shutdown(ctx)
+}
+
+// CheckHealth reports whether the broker reaches the Kafka cluster.
+// Implements app.HealthChecker interface.
+func (b *Broker) CheckHealth(ctx context.Context) error {
+	b.mu.RLock()
+	closed, producer := b.closed, b.producer
+	b.mu.RUnlock()
+
+	if closed {
+		return fmt.Errorf("broker is closed")
+	}
+	if producer == nil {
+		return fmt.Errorf("broker not connected")
+	}
+	return producer.Ping(ctx)
+}
+
+// HealthChecks reports the "kafka" readiness check.
+// Implements app.HealthReporter interface.
+func (b *Broker) HealthChecks() map[string]func(context.Context) error {
+	return map[string]func(context.Context) error{"kafka": b.CheckHealth}
+}
+
+// Publish sends a message to the specified topic and waits for the brokers
+// to acknowledge it.
+func (b *Broker) Publish(ctx context.Context, topic string, env pubsub.Envelope) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return fmt.Errorf("broker is closed")
+	}
+	producer := b.producer
+	b.mu.RUnlock()
+
+	if producer == nil {
+		return fmt.Errorf("broker not connected")
+	}
+
+	data, err := b.codec.Encode(env)
+	if err != nil {
+		return err
+	}
+
+	record := &kgo.Record{Topic: topic, Key: []byte(env.ID), Value: data}
+	if err := producer.ProduceSync(ctx, record).FirstErr(); err != nil {
+		return fmt.Errorf("cannot publish message: %w", err)
+	}
+
+	b.log.Debugf("Published message %s to topic %s", env.ID, topic)
+	return nil
+}
+
+// Subscribe registers a handler for the given topic in the consumer group
+// named after SubscriberID. The subscriber receives the messages published
+// from now on, or from its committed offset when the group already has one.
+func (b *Broker) Subscribe(ctx context.Context, topic string, handler pubsub.Handler, opts pubsub.SubscribeOptions) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return fmt.Errorf("broker is closed")
+	}
+
+	if b.producer == nil {
+		return fmt.Errorf("broker not connected")
+	}
+
+	subscriberID := opts.SubscriberID
+	durable := subscriberID != ""
+	if !durable {
+		subscriberID = fmt.Sprintf("%s-%d", topic, time.Now().UnixNano())
+	}
+
+	if _, exists := b.subscriptions[subscriberID]; exists {
+		return fmt.Errorf("subscriber %s already registered", subscriberID)
+	}
+
+	client, err := kgo.NewClient(append(slices.Clone(b.opts),
+		kgo.ConsumerGroup(b.cfg.GroupPrefix+subscriberID),
+		kgo.ConsumeTopics(topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AfterMilli(time.Now().UnixMilli())),
+		kgo.AllowAutoTopicCreation(),
+		kgo.DisableAutoCommit(),
+		kgo.BlockRebalanceOnPoll(),
+	)...)
+	if err != nil {
+		return fmt.Errorf("cannot subscribe to topic %s: %w", topic, err)
+	}
+
+	consumeCtx, cancel := context.WithCancel(context.Background())
+	sub := &subscription{
+		client:  client,
+		durable: durable,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	b.subscriptions[subscriberID] = sub
+	go b.consume(consumeCtx, sub, topic, handler)
+
+	b.log.Infof("Subscriber %s registered for topic %s", subscriberID, topic)
+	return nil
+}
+
+// consume polls the subscription until ctx is canceled. Each polled batch is
+// handled before its offsets are committed and the group may rebalance.
+func (b *Broker) consume(ctx context.Context, sub *subscription, topic string, handler pubsub.Handler) {
+	defer close(sub.done)
+
+	for {
+		fetches := sub.client.PollFetches(ctx)
+		if fetches.IsClientClosed() || ctx.Err() != nil {
+			return
+		}
+
+		fetches.EachError(func(t string, partition int32, err error) {
+			b.log.Errorf("Cannot fetch from topic %s partition %d: %v", t, partition, err)
+		})
+		fetches.EachRecord(func(record *kgo.Record) {
+			env, err := b.codec.Decode(topic, record.Value)
+			if err != nil {
+				b.log.Errorf("Cannot decode message on topic %s: %v", topic, err)
+				return
+			}
+
+			if err := handler(context.Background(), env); err != nil {
+				b.log.Errorf("Handler error for message %s: %v", env.ID, err)
+			}
+		})
+
+		if sub.durable {
+			if err := sub.client.CommitUncommittedOffsets(context.Background()); err != nil {
+				b.log.Errorf("Cannot commit offsets on topic %s: %v", topic, err)
+			}
+		}
+		sub.client.AllowRebalance()
+	}
+}
+
+// Close stops all subscriptions and closes the Kafka clients.
+func (b *Broker) Close() error {
+	return b.shutdown(context.Background())
+}
+
+func (b *Broker) shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	subs := b.subscriptions
+	b.subscriptions = make(map[string]*subscription)
+	producer := b.producer
+	b.producer = nil
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.cancel()
+	}
+
+	var err error
+	for id, sub := range subs {
+		select {
+		case <-sub.done:
+		case <-ctx.Done():
+			err = fmt.Errorf("cannot stop subscriber %s: %w", id, ctx.Err())
+		}
+		sub.client.CloseAllowingRebalance()
+	}
+
+	if producer != nil {
+		if ferr := producer.Flush(ctx); ferr != nil {
+			b.log.Errorf("Cannot flush pending messages: %v", ferr)
+		}
+		producer.Close()
+	}
+
+	b.log.Info("PubSub broker closed")
+	return err
+}
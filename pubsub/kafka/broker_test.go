@@ -0,0 +1,354 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/pubsub"
+	"github.com/testcontainers/testcontainers-go/modules/kafka"
+)
+
+// deliveryTimeout covers the consumer group join on top of the delivery.
+const deliveryTimeout = 30 * time.Second
+
+func setupKafka(t *testing.T) (config.KafkaConfig, func()) {
+	t.Helper()
+	ctx := context.Background()
+
+	container, err := kafka.Run(ctx, "confluentinc/confluent-local:7.5.0", kafka.WithClusterID("test-cluster"))
+	if err != nil {
+		t.Fatalf("cannot start Kafka container: %v", err)
+	}
+
+	brokers, err := container.Brokers(ctx)
+	if err != nil {
+		t.Fatalf("cannot get brokers: %v", err)
+	}
+
+	cleanup := func() {
+		if err := container.Terminate(context.Background()); err != nil {
+			t.Logf("cannot terminate container: %v", err)
+		}
+	}
+
+	return config.KafkaConfig{Brokers: brokers, DialTimeout: 10 * time.Second}, cleanup
+}
+
+func testLogger() log.Logger {
+	return log.NewNoopLogger()
+}
+
+func startBroker(t *testing.T, cfg config.KafkaConfig) *Broker {
+	t.Helper()
+	broker := NewBroker(cfg, testLogger())
+	if err := broker.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	return broker
+}
+
+func TestBrokerStartStop(t *testing.T) {
+	cfg, cleanup := setupKafka(t)
+	defer cleanup()
+
+	broker := NewBroker(cfg, testLogger())
+	ctx := context.Background()
+
+	if err := broker.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	if err := broker.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+}
+
+func TestBrokerPublishSubscribe(t *testing.T) {
+	cfg, cleanup := setupKafka(t)
+	defer cleanup()
+
+	broker := startBroker(t, cfg)
+	defer broker.Close()
+	ctx := context.Background()
+
+	received := make(chan pubsub.Envelope, 1)
+	if err := broker.Subscribe(ctx, "test-topic", func(ctx context.Context, env pubsub.Envelope) error {
+		received <- env
+		return nil
+	}, pubsub.SubscribeOptions{SubscriberID: "test-sub"}); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	env := pubsub.NewEnvelope("test-topic", "test-payload").WithMetadata("key1", "value1")
+	if err := broker.Publish(ctx, "test-topic", env); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	select {
+	case got := <-received:
+		if got.ID != env.ID || got.Payload != "test-payload" || got.Metadata["key1"] != "value1" {
+			t.Errorf("received %+v, want %+v", got, env)
+		}
+	case <-time.After(deliveryTimeout):
+		t.Fatal("timeout waiting for message")
+	}
+}
+
+func TestBrokerFanOut(t *testing.T) {
+	cfg, cleanup := setupKafka(t)
+	defer cleanup()
+
+	broker := startBroker(t, cfg)
+	defer broker.Close()
+	ctx := context.Background()
+
+	var count1, count2 int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	for _, id := range []string{"sub1", "sub2"} {
+		count := &count1
+		if id == "sub2" {
+			count = &count2
+		}
+		broker.Subscribe(ctx, "test-topic", func(ctx context.Context, env pubsub.Envelope) error {
+			mu.Lock()
+			*count++
+			mu.Unlock()
+			wg.Done()
+			return nil
+		}, pubsub.SubscribeOptions{SubscriberID: id})
+	}
+
+	broker.Publish(ctx, "test-topic", pubsub.NewEnvelope("test-topic", "payload"))
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(deliveryTimeout):
+		t.Fatal("timeout waiting for fan-out")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if count1 != 1 || count2 != 1 {
+		t.Errorf("handler calls = %d and %d, want 1 each", count1, count2)
+	}
+}
+
+func TestBrokerDifferentTopics(t *testing.T) {
+	cfg, cleanup := setupKafka(t)
+	defer cleanup()
+
+	broker := startBroker(t, cfg)
+	defer broker.Close()
+	ctx := context.Background()
+
+	var received2 int
+	var mu sync.Mutex
+	done := make(chan struct{})
+
+	broker.Subscribe(ctx, "topic1", func(ctx context.Context, env pubsub.Envelope) error {
+		close(done)
+		return nil
+	}, pubsub.SubscribeOptions{SubscriberID: "sub1"})
+	broker.Subscribe(ctx, "topic2", func(ctx context.Context, env pubsub.Envelope) error {
+		mu.Lock()
+		received2++
+		mu.Unlock()
+		return nil
+	}, pubsub.SubscribeOptions{SubscriberID: "sub2"})
+
+	broker.Publish(ctx, "topic1", pubsub.NewEnvelope("topic1", "payload"))
+
+	select {
+	case <-done:
+	case <-time.After(deliveryTimeout):
+		t.Fatal("timeout waiting for message")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if received2 != 0 {
+		t.Errorf("topic2 handler expected 0 calls, got %d", received2)
+	}
+}
+
+func TestBrokerNamedSubscriberResumes(t *testing.T) {
+	cfg, cleanup := setupKafka(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	received := make(chan string, 10)
+	handler := func(ctx context.Context, env pubsub.Envelope) error {
+		received <- env.Payload.(string)
+		return nil
+	}
+
+	broker := startBroker(t, cfg)
+	broker.Subscribe(ctx, "test-topic", handler, pubsub.SubscribeOptions{SubscriberID: "durable"})
+	broker.Publish(ctx, "test-topic", pubsub.NewEnvelope("test-topic", "before"))
+
+	select {
+	case got := <-received:
+		if got != "before" {
+			t.Fatalf("received %s, want before", got)
+		}
+	case <-time.After(deliveryTimeout):
+		t.Fatal("timeout waiting for first message")
+	}
+	if err := broker.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	// Published while the subscriber is down.
+	publisher := startBroker(t, cfg)
+	publisher.Publish(ctx, "test-topic", pubsub.NewEnvelope("test-topic", "while-down"))
+	publisher.Close()
+
+	broker = startBroker(t, cfg)
+	defer broker.Close()
+	broker.Subscribe(ctx, "test-topic", handler, pubsub.SubscribeOptions{SubscriberID: "durable"})
+
+	select {
+	case got := <-received:
+		if got != "while-down" {
+			t.Errorf("received %s after restart, want while-down", got)
+		}
+	case <-time.After(deliveryTimeout):
+		t.Fatal("timeout waiting for message published while down")
+	}
+}
+
+func TestBrokerDuplicateSubscriberIDFails(t *testing.T) {
+	cfg, cleanup := setupKafka(t)
+	defer cleanup()
+
+	broker := startBroker(t, cfg)
+	defer broker.Close()
+	ctx := context.Background()
+
+	handler := func(ctx context.Context, env pubsub.Envelope) error { return nil }
+
+	if err := broker.Subscribe(ctx, "test-topic", handler, pubsub.SubscribeOptions{
+		SubscriberID: "same-id",
+	}); err != nil {
+		t.Fatalf("First subscribe failed: %v", err)
+	}
+
+	err := broker.Subscribe(ctx, "test-topic", handler, pubsub.SubscribeOptions{
+		SubscriberID: "same-id",
+	})
+	if err == nil {
+		t.Error("expected error for duplicate subscriber ID")
+	}
+}
+
+func TestBrokerPublishAfterCloseFails(t *testing.T) {
+	cfg, cleanup := setupKafka(t)
+	defer cleanup()
+
+	broker := startBroker(t, cfg)
+	broker.Close()
+
+	err := broker.Publish(context.Background(), "test-topic", pubsub.NewEnvelope("test-topic", "payload"))
+	if err == nil {
+		t.Error("expected error publishing to closed broker")
+	}
+}
+
+func TestBrokerCloseIdempotent(t *testing.T) {
+	cfg, cleanup := setupKafka(t)
+	defer cleanup()
+
+	broker := startBroker(t, cfg)
+
+	if err := broker.Close(); err != nil {
+		t.Fatalf("First close failed: %v", err)
+	}
+
+	if err := broker.Close(); err != nil {
+		t.Fatalf("Second close failed: %v", err)
+	}
+}
+
+func TestBrokerHandlerError(t *testing.T) {
+	cfg, cleanup := setupKafka(t)
+	defer cleanup()
+
+	broker := startBroker(t, cfg)
+	defer broker.Close()
+	ctx := context.Background()
+
+	var callCount int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	broker.Subscribe(ctx, "test-topic", func(ctx context.Context, env pubsub.Envelope) error {
+		mu.Lock()
+		callCount++
+		mu.Unlock()
+		wg.Done()
+		return fmt.Errorf("handler error")
+	}, pubsub.SubscribeOptions{SubscriberID: "test-sub"})
+
+	broker.Publish(ctx, "test-topic", pubsub.NewEnvelope("test-topic", "msg1"))
+	broker.Publish(ctx, "test-topic", pubsub.NewEnvelope("test-topic", "msg2"))
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(deliveryTimeout):
+		t.Fatal("timeout waiting for messages")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if callCount != 2 {
+		t.Errorf("expected 2 calls despite errors, got %d", callCount)
+	}
+}
+
+func TestBrokerCheckHealth(t *testing.T) {
+	cfg, cleanup := setupKafka(t)
+	defer cleanup()
+
+	broker := NewBroker(cfg, testLogger())
+	ctx := context.Background()
+
+	if err := broker.CheckHealth(ctx); err == nil {
+		t.Error("CheckHealth should fail before Start")
+	}
+
+	if err := broker.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := broker.CheckHealth(ctx); err != nil {
+		t.Errorf("CheckHealth failed: %v", err)
+	}
+
+	broker.Close()
+	if err := broker.CheckHealth(ctx); err == nil {
+		t.Error("CheckHealth should fail after Close")
+	}
+}
@@ -0,0 +1,97 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// clientOptions returns the connection options shared by the producer and
+// every consumer: seed brokers, client ID, dial timeout, TLS and SASL.
+func clientOptions(cfg config.KafkaConfig) ([]kgo.Opt, error) {
+	seeds := brokers(cfg.Brokers)
+	if len(seeds) == 0 {
+		return nil, fmt.Errorf("kafka brokers are required")
+	}
+
+	opts := []kgo.Opt{kgo.SeedBrokers(seeds...)}
+	if cfg.ClientID != "" {
+		opts = append(opts, kgo.ClientID(cfg.ClientID))
+	}
+	if cfg.DialTimeout > 0 {
+		opts = append(opts, kgo.DialTimeout(cfg.DialTimeout))
+	}
+
+	if cfg.TLS.Enabled {
+		tlsCfg, err := tlsConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.DialTLSConfig(tlsCfg))
+	}
+
+	sasl := cfg.SASL
+	switch sasl.Mechanism {
+	case "":
+	case config.SASLPlain:
+		opts = append(opts, kgo.SASL(plain.Auth{User: sasl.Username, Pass: sasl.Password}.AsMechanism()))
+	case config.SASLScramSHA256:
+		opts = append(opts, kgo.SASL(scram.Auth{User: sasl.Username, Pass: sasl.Password}.AsSha256Mechanism()))
+	case config.SASLScramSHA512:
+		opts = append(opts, kgo.SASL(scram.Auth{User: sasl.Username, Pass: sasl.Password}.AsSha512Mechanism()))
+	default:
+		return nil, fmt.Errorf("unsupported kafka sasl mechanism: %s", sasl.Mechanism)
+	}
+
+	return opts, nil
+}
+
+// brokers flattens the configured brokers, splitting comma separated entries
+// such as the value of PREFIX_KAFKA_BROKERS.
+func brokers(entries []string) []string {
+	var seeds []string
+	for _, entry := range entries {
+		for _, seed := range strings.Split(entry, ",") {
+			if seed = strings.TrimSpace(seed); seed != "" {
+				seeds = append(seeds, seed)
+			}
+		}
+	}
+	return seeds
+}
+
+func tlsConfig(cfg config.KafkaTLSConfig) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read kafka CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in kafka CA file %s", cfg.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load kafka client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
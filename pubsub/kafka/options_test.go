@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/pubsub"
+)
+
+func TestBrokers(t *testing.T) {
+	got := brokers([]string{"kafka-1:9092, kafka-2:9092", "", "kafka-3:9092"})
+	want := []string{"kafka-1:9092", "kafka-2:9092", "kafka-3:9092"}
+	if !slices.Equal(got, want) {
+		t.Errorf("brokers() = %v, want %v", got, want)
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+	tests := []struct {
+		name    string
+		cfg     config.KafkaConfig
+		wantErr bool
+	}{
+		{"plaintext", config.KafkaConfig{Brokers: []string{"localhost:9092"}}, false},
+		{"scram", config.KafkaConfig{Brokers: []string{"localhost:9092"}, SASL: config.KafkaSASLConfig{Mechanism: config.SASLScramSHA256, Username: "svc"}}, false},
+		{"tls with system roots", config.KafkaConfig{Brokers: []string{"localhost:9092"}, TLS: config.KafkaTLSConfig{Enabled: true}}, false},
+		{"no brokers", config.KafkaConfig{}, true},
+		{"unknown mechanism", config.KafkaConfig{Brokers: []string{"localhost:9092"}, SASL: config.KafkaSASLConfig{Mechanism: "gssapi"}}, true},
+		{"missing CA file", config.KafkaConfig{Brokers: []string{"localhost:9092"}, TLS: config.KafkaTLSConfig{Enabled: true, CAFile: missing}}, true},
+		{"missing client certificate", config.KafkaConfig{Brokers: []string{"localhost:9092"}, TLS: config.KafkaTLSConfig{Enabled: true, CertFile: missing, KeyFile: missing}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := clientOptions(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("clientOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithCodec(t *testing.T) {
+	if _, ok := NewBroker(config.KafkaConfig{}, testLogger()).codec.(pubsub.JSONCodec); !ok {
+		t.Error("default codec is not pubsub.JSONCodec")
+	}
+
+	codec := pubsub.NewCloudEventsCodec("/aqm/test")
+	if got := NewBroker(config.KafkaConfig{}, testLogger(), WithCodec(codec)).codec; got != codec {
+		t.Errorf("codec = %v, want %v", got, codec)
+	}
+}
+
+func TestBrokerPublishBeforeStartFails(t *testing.T) {
+	broker := NewBroker(config.KafkaConfig{Brokers: []string{"localhost:9092"}}, testLogger())
+
+	err := broker.Publish(context.Background(), "test-topic", pubsub.NewEnvelope("test-topic", "payload"))
+	if err == nil {
+		t.Error("expected error publishing before Start")
+	}
+}
+
+func TestBrokerSubscribeBeforeStartFails(t *testing.T) {
+	broker := NewBroker(config.KafkaConfig{Brokers: []string{"localhost:9092"}}, testLogger())
+
+	err := broker.Subscribe(context.Background(), "test-topic", func(ctx context.Context, env pubsub.Envelope) error {
+		return nil
+	}, pubsub.SubscribeOptions{})
+	if err == nil {
+		t.Error("expected error subscribing before Start")
+	}
+}
+
+func TestBrokerStartInvalidConfig(t *testing.T) {
+	broker := NewBroker(config.KafkaConfig{}, testLogger())
+
+	if err := broker.Start(context.Background()); err == nil {
+		t.Error("expected error starting without brokers")
+	}
+}
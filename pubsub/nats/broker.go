@@ -2,12 +2,13 @@ package nats
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/aquamarinepk/aqm/config"
 	"github.com/aquamarinepk/aqm/log"
+	aqmnats "github.com/aquamarinepk/aqm/nats"
 	"github.com/aquamarinepk/aqm/pubsub"
 	"github.com/nats-io/nats.go"
 )
@@ -39,57 +40,105 @@ type subscription struct {
 // Broker implements pubsub.Broker using NATS.
 // NATS provides native fan-out: each subscriber receives all messages.
 type Broker struct {
-	conn          *nats.Conn
-	cfg           Config
+	conn          *aqmnats.Conn
+	owned         bool
 	log           log.Logger
 	mu            sync.RWMutex
+	codec         pubsub.Codec
 	subscriptions map[string]*subscription
 	closed        bool
 }
 
-// NewBroker creates a new NATS-backed pubsub broker.
-func NewBroker(cfg Config, log log.Logger) *Broker {
+// Option configures a Broker or a JetStreamBroker.
+type Option func(*options)
+
+type options struct {
+	codec pubsub.Codec
+}
+
+func newOptions(opts []Option) options {
+	o := options{codec: pubsub.JSONCodec{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithCodec sets the wire format of published and received messages, for
+// example pubsub.NewCloudEventsCodec. The default is pubsub.JSONCodec.
+func WithCodec(c pubsub.Codec) Option {
+	return func(o *options) {
+		o.codec = c
+	}
+}
+
+// NewBroker creates a new NATS-backed pubsub broker that owns its
+// connection: Start connects and Stop drains it.
+func NewBroker(cfg Config, log log.Logger, opts ...Option) *Broker {
+	conn := aqmnats.New(config.NATSConfig{
+		URL:            cfg.URL,
+		MaxReconnect:   cfg.MaxReconnect,
+		ReconnectWait:  cfg.ReconnectWait,
+		ConnectTimeout: cfg.ConnectTimeout,
+	}, log)
+	b := NewBrokerWithConn(conn, log, opts...)
+	b.owned = true
+	return b
+}
+
+// NewBrokerWithConn creates a broker on a shared connection. The caller owns
+// conn: register it with app.Setup ahead of the broker so it connects first
+// and drains after the broker has stopped.
+func NewBrokerWithConn(conn *aqmnats.Conn, log log.Logger, opts ...Option) *Broker {
 	return &Broker{
-		cfg:           cfg,
+		conn:          conn,
 		log:           log.With("component", "pubsub"),
+		codec:         newOptions(opts).codec,
 		subscriptions: make(map[string]*subscription),
 	}
 }
 
-// Start connects to the NATS server.
+// Start connects to the NATS server when the broker owns its connection.
 // Implements app.Startable interface.
 func (b *Broker) Start(ctx context.Context) error {
-	opts := []nats.Option{
-		nats.MaxReconnects(b.cfg.MaxReconnect),
-		nats.ReconnectWait(b.cfg.ReconnectWait),
-		nats.Timeout(b.cfg.ConnectTimeout),
-		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
-			if err != nil {
-				b.log.Errorf("NATS disconnected: %v", err)
-			}
-		}),
-		nats.ReconnectHandler(func(nc *nats.Conn) {
-			b.log.Info("NATS reconnected")
-		}),
-		nats.ClosedHandler(func(nc *nats.Conn) {
-			b.log.Info("NATS connection closed")
-		}),
-	}
-
-	conn, err := nats.Connect(b.cfg.URL, opts...)
-	if err != nil {
-		return fmt.Errorf("cannot connect to NATS: %w", err)
+	if !b.owned {
+		return nil
 	}
-
-	b.conn = conn
-	b.log.Infof("Connected to NATS at %s", b.cfg.URL)
-	return nil
+	return b.conn.Start(ctx)
 }
 
-// Stop gracefully shuts down all subscriptions and closes the connection.
+// Stop unsubscribes all handlers and, when the broker owns its connection,
+// drains and closes it.
 // Implements app.Stoppable interface.
 func (b *Broker) Stop(ctx context.Context) error {
-	return b.Close()
+	b.unsubscribe()
+	if !b.owned {
+		return nil
+	}
+	return b.conn.Stop(ctx)
+}
+
+// CheckHealth reports whether the broker holds a live NATS connection.
+// Implements app.HealthChecker interface.
+func (b *Broker) CheckHealth(ctx context.Context) error {
+	b.mu.RLock()
+	closed := b.closed
+	b.mu.RUnlock()
+
+	if closed {
+		return fmt.Errorf("broker is closed")
+	}
+	return b.conn.CheckHealth(ctx)
+}
+
+// HealthChecks reports the "nats" readiness check. A broker on a shared
+// connection reports none; the connection reports its own.
+// Implements app.HealthReporter interface.
+func (b *Broker) HealthChecks() map[string]func(context.Context) error {
+	if !b.owned {
+		return nil
+	}
+	return map[string]func(context.Context) error{"nats": b.CheckHealth}
 }
 
 // Publish sends a message to the specified topic.
@@ -99,16 +148,16 @@ func (b *Broker) Publish(ctx context.Context, topic string, env pubsub.Envelope)
 		b.mu.RUnlock()
 		return fmt.Errorf("broker is closed")
 	}
-	conn := b.conn
+	conn := b.conn.NATS()
 	b.mu.RUnlock()
 
 	if conn == nil {
 		return fmt.Errorf("broker not connected")
 	}
 
-	data, err := json.Marshal(env)
+	data, err := b.codec.Encode(env)
 	if err != nil {
-		return fmt.Errorf("cannot marshal envelope: %w", err)
+		return err
 	}
 
 	if err := conn.Publish(topic, data); err != nil {
@@ -130,7 +179,8 @@ func (b *Broker) Subscribe(ctx context.Context, topic string, handler pubsub.Han
 		return fmt.Errorf("broker is closed")
 	}
 
-	if b.conn == nil {
+	conn := b.conn.NATS()
+	if conn == nil {
 		return fmt.Errorf("broker not connected")
 	}
 
@@ -143,10 +193,10 @@ func (b *Broker) Subscribe(ctx context.Context, topic string, handler pubsub.Han
 		return fmt.Errorf("subscriber %s already registered", subscriberID)
 	}
 
-	sub, err := b.conn.Subscribe(topic, func(msg *nats.Msg) {
-		var env pubsub.Envelope
-		if err := json.Unmarshal(msg.Data, &env); err != nil {
-			b.log.Errorf("Cannot unmarshal message on topic %s: %v", topic, err)
+	sub, err := conn.Subscribe(topic, func(msg *nats.Msg) {
+		env, err := b.codec.Decode(topic, msg.Data)
+		if err != nil {
+			b.log.Errorf("Cannot decode message on topic %s: %v", topic, err)
 			return
 		}
 
@@ -167,13 +217,23 @@ func (b *Broker) Subscribe(ctx context.Context, topic string, handler pubsub.Han
 	return nil
 }
 
-// Close stops all subscriptions and closes the NATS connection.
+// Close stops all subscriptions and, when the broker owns its connection,
+// closes it without draining.
 func (b *Broker) Close() error {
+	b.unsubscribe()
+	if b.owned {
+		b.conn.Close()
+	}
+	return nil
+}
+
+// unsubscribe marks the broker closed and removes all subscriptions.
+func (b *Broker) unsubscribe() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	if b.closed {
-		return nil
+		return
 	}
 	b.closed = true
 
@@ -184,11 +244,5 @@ func (b *Broker) Close() error {
 	}
 	b.subscriptions = make(map[string]*subscription)
 
-	if b.conn != nil {
-		b.conn.Close()
-		b.conn = nil
-	}
-
 	b.log.Info("PubSub broker closed")
-	return nil
 }
@@ -3,11 +3,14 @@ package nats
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/aquamarinepk/aqm/config"
 	"github.com/aquamarinepk/aqm/log"
+	aqmnats "github.com/aquamarinepk/aqm/nats"
 	"github.com/aquamarinepk/aqm/pubsub"
 	"github.com/testcontainers/testcontainers-go/modules/nats"
 )
@@ -457,3 +460,108 @@ func TestBrokerWithMetadata(t *testing.T) {
 		t.Errorf("expected key2=value2, got %s", received.Metadata["key2"])
 	}
 }
+
+func TestBrokerCheckHealth(t *testing.T) {
+	url, cleanup := setupNATS(t)
+	defer cleanup()
+
+	cfg := DefaultConfig()
+	cfg.URL = url
+
+	broker := NewBroker(cfg, testLogger())
+	ctx := context.Background()
+
+	if err := broker.CheckHealth(ctx); err == nil {
+		t.Error("CheckHealth should fail before Start")
+	}
+
+	if err := broker.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := broker.CheckHealth(ctx); err != nil {
+		t.Errorf("CheckHealth failed: %v", err)
+	}
+
+	broker.Close()
+	if err := broker.CheckHealth(ctx); err == nil {
+		t.Error("CheckHealth should fail after Close")
+	}
+}
+
+func TestBrokerWithSharedConn(t *testing.T) {
+	url, cleanup := setupNATS(t)
+	defer cleanup()
+
+	conn := aqmnats.New(config.NATSConfig{URL: url}, testLogger())
+	ctx := context.Background()
+	if err := conn.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer conn.Stop(ctx)
+
+	broker := NewBrokerWithConn(conn, testLogger())
+	if err := broker.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if checks := broker.HealthChecks(); len(checks) != 0 {
+		t.Errorf("HealthChecks() = %v, want none on a shared connection", checks)
+	}
+	if err := broker.Publish(ctx, "shared", pubsub.NewEnvelope("shared", nil)); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	if err := broker.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if err := conn.CheckHealth(ctx); err != nil {
+		t.Errorf("shared connection closed by broker: %v", err)
+	}
+}
+
+func TestBrokerWithCloudEventsCodec(t *testing.T) {
+	url, cleanup := setupNATS(t)
+	defer cleanup()
+
+	cfg := DefaultConfig()
+	cfg.URL = url
+
+	broker := NewBroker(cfg, testLogger(), WithCodec(pubsub.NewCloudEventsCodec("/aqm/test")))
+	ctx := context.Background()
+
+	if err := broker.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer broker.Close()
+
+	raw, err := broker.conn.NATS().SubscribeSync("test-topic")
+	if err != nil {
+		t.Fatalf("SubscribeSync failed: %v", err)
+	}
+	received := make(chan pubsub.Envelope, 1)
+	broker.Subscribe(ctx, "test-topic", func(ctx context.Context, env pubsub.Envelope) error {
+		received <- env
+		return nil
+	}, pubsub.SubscribeOptions{SubscriberID: "test-sub"})
+
+	env := pubsub.NewEnvelope("test-topic", "payload")
+	if err := broker.Publish(ctx, "test-topic", env); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	msg, err := raw.NextMsg(5 * time.Second)
+	if err != nil {
+		t.Fatalf("NextMsg failed: %v", err)
+	}
+	if !strings.Contains(string(msg.Data), `"specversion":"1.0"`) {
+		t.Errorf("published %s, want a CloudEvent", msg.Data)
+	}
+
+	select {
+	case got := <-received:
+		if got.ID != env.ID || got.Metadata[pubsub.MetaEventSource] != "/aqm/test" {
+			t.Errorf("received %+v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+}
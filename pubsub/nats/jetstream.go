@@ -0,0 +1,304 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/log"
+	aqmnats "github.com/aquamarinepk/aqm/nats"
+	"github.com/aquamarinepk/aqm/pubsub"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// maxNakDelay caps the delay before a failed message is redelivered.
+const maxNakDelay = 30 * time.Second
+
+// ephemeralThreshold is how long the server keeps the consumer of an
+// unnamed subscriber after it stops consuming.
+const ephemeralThreshold = time.Minute
+
+// JetStreamBroker implements pubsub.Broker on NATS JetStream. It accepts the
+// same options as Broker.
+//
+// Start creates or updates the streams and consumers of the config.
+// Publish sends the envelope ID as the message ID, so the stream drops a
+// message published twice within its duplicate window. Every subscriber is a
+// durable consumer named after its SubscriberID: messages are acked once the
+// handler returns nil and redelivered with backoff when it fails, up to the
+// consumer's MaxDeliver, and a restarted subscriber resumes where it left.
+type JetStreamBroker struct {
+	conn          *aqmnats.Conn
+	cfg           config.JetStreamConfig
+	log           log.Logger
+	codec         pubsub.Codec
+	mu            sync.RWMutex
+	js            jetstream.JetStream
+	subscriptions map[string]jetstream.ConsumeContext
+	closed        bool
+}
+
+// NewJetStreamBroker creates a JetStream broker on conn. The caller owns
+// conn: register it with app.Setup ahead of the broker.
+func NewJetStreamBroker(conn *aqmnats.Conn, cfg config.JetStreamConfig, log log.Logger, opts ...Option) *JetStreamBroker {
+	return &JetStreamBroker{
+		conn:          conn,
+		cfg:           cfg,
+		log:           log.With("component", "pubsub"),
+		codec:         newOptions(opts).codec,
+		subscriptions: make(map[string]jetstream.ConsumeContext),
+	}
+}
+
+// Start provisions the configured streams and consumers.
+// Implements app.Startable interface.
+func (b *JetStreamBroker) Start(ctx context.Context) error {
+	nc := b.conn.NATS()
+	if nc == nil {
+		return fmt.Errorf("broker not connected")
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return fmt.Errorf("cannot open JetStream: %w", err)
+	}
+
+	for name, st := range b.cfg.Streams {
+		storage := jetstream.FileStorage
+		if st.Storage == "memory" {
+			storage = jetstream.MemoryStorage
+		}
+		if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+			Name:       name,
+			Subjects:   st.Subjects,
+			Storage:    storage,
+			Replicas:   st.Replicas,
+			MaxAge:     st.MaxAge,
+			Duplicates: st.DuplicateWindow,
+		}); err != nil {
+			return fmt.Errorf("cannot provision stream %s: %w", name, err)
+		}
+		b.log.Infof("Stream %s provisioned for %v", name, st.Subjects)
+	}
+
+	for name, cons := range b.cfg.Consumers {
+		if _, err := js.CreateOrUpdateConsumer(ctx, cons.Stream, b.consumerConfig(name, cons.FilterSubject, cons.AckWait, cons.MaxDeliver, jetstream.DeliverAllPolicy)); err != nil {
+			return fmt.Errorf("cannot provision consumer %s: %w", name, err)
+		}
+		b.log.Infof("Consumer %s provisioned on stream %s", name, cons.Stream)
+	}
+
+	b.mu.Lock()
+	b.js = js
+	b.mu.Unlock()
+	return nil
+}
+
+// Stop stops all subscriptions. Messages being handled are still acked.
+// Implements app.Stoppable interface.
+func (b *JetStreamBroker) Stop(ctx context.Context) error {
+	return b.Close()
+}
+
+// CheckHealth reports whether JetStream is reachable.
+// Implements app.HealthChecker interface.
+func (b *JetStreamBroker) CheckHealth(ctx context.Context) error {
+	b.mu.RLock()
+	closed, js := b.closed, b.js
+	b.mu.RUnlock()
+
+	if closed {
+		return fmt.Errorf("broker is closed")
+	}
+	if js == nil {
+		return fmt.Errorf("broker not connected")
+	}
+	if _, err := js.AccountInfo(ctx); err != nil {
+		return fmt.Errorf("jetstream unavailable: %w", err)
+	}
+	return nil
+}
+
+// HealthChecks reports the "jetstream" readiness check.
+// Implements app.HealthReporter interface.
+func (b *JetStreamBroker) HealthChecks() map[string]func(context.Context) error {
+	return map[string]func(context.Context) error{"jetstream": b.CheckHealth}
+}
+
+// Publish stores a message in the stream bound to topic and waits for the
+// acknowledgement. The envelope ID is the de-duplication ID.
+func (b *JetStreamBroker) Publish(ctx context.Context, topic string, env pubsub.Envelope) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return fmt.Errorf("broker is closed")
+	}
+	js := b.js
+	b.mu.RUnlock()
+
+	if js == nil {
+		return fmt.Errorf("broker not connected")
+	}
+
+	data, err := b.codec.Encode(env)
+	if err != nil {
+		return err
+	}
+
+	ack, err := js.Publish(ctx, topic, data, jetstream.WithMsgID(env.ID))
+	if err != nil {
+		return fmt.Errorf("cannot publish message: %w", err)
+	}
+
+	if ack.Duplicate {
+		b.log.Debugf("Dropped duplicate message %s on topic %s", env.ID, topic)
+	} else {
+		b.log.Debugf("Published message %s to topic %s", env.ID, topic)
+	}
+	return nil
+}
+
+// Subscribe consumes topic through the durable consumer named after
+// SubscriberID: the configured consumer of that name, or one created on the
+// stream bound to topic that receives the messages published from now on.
+// Without a SubscriberID the consumer is ephemeral.
+func (b *JetStreamBroker) Subscribe(ctx context.Context, topic string, handler pubsub.Handler, opts pubsub.SubscribeOptions) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return fmt.Errorf("broker is closed")
+	}
+
+	if b.js == nil {
+		return fmt.Errorf("broker not connected")
+	}
+
+	subscriberID := opts.SubscriberID
+	if _, exists := b.subscriptions[subscriberID]; exists && subscriberID != "" {
+		return fmt.Errorf("subscriber %s already registered", subscriberID)
+	}
+
+	consumer, err := b.consumer(ctx, topic, subscriberID)
+	if err != nil {
+		return fmt.Errorf("cannot subscribe to topic %s: %w", topic, err)
+	}
+
+	cc, err := consumer.Consume(func(msg jetstream.Msg) {
+		b.handle(topic, msg, handler)
+	})
+	if err != nil {
+		return fmt.Errorf("cannot subscribe to topic %s: %w", topic, err)
+	}
+
+	if subscriberID == "" {
+		subscriberID = consumer.CachedInfo().Name
+	}
+	b.subscriptions[subscriberID] = cc
+
+	b.log.Infof("Subscriber %s registered for topic %s", subscriberID, topic)
+	return nil
+}
+
+func (b *JetStreamBroker) consumer(ctx context.Context, topic, name string) (jetstream.Consumer, error) {
+	if cons, ok := b.cfg.Consumers[name]; ok && name != "" {
+		return b.js.Consumer(ctx, cons.Stream, name)
+	}
+
+	stream, err := b.js.StreamNameBySubject(ctx, topic)
+	if err != nil {
+		return nil, fmt.Errorf("no stream for subject %s: %w", topic, err)
+	}
+
+	cfg := b.consumerConfig(name, topic, 0, 0, jetstream.DeliverNewPolicy)
+	if name == "" {
+		cfg.InactiveThreshold = ephemeralThreshold
+		return b.js.CreateConsumer(ctx, stream, cfg)
+	}
+	return b.js.CreateOrUpdateConsumer(ctx, stream, cfg)
+}
+
+// consumerConfig returns an explicit-ack consumer, falling back to the
+// broker AckWait and MaxDeliver for zero values.
+func (b *JetStreamBroker) consumerConfig(name, subject string, ackWait time.Duration, maxDeliver int, deliver jetstream.DeliverPolicy) jetstream.ConsumerConfig {
+	if ackWait == 0 {
+		ackWait = b.cfg.AckWait
+	}
+	if maxDeliver == 0 {
+		maxDeliver = b.cfg.MaxDeliver
+	}
+	return jetstream.ConsumerConfig{
+		Durable:       name,
+		FilterSubject: subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       ackWait,
+		MaxDeliver:    maxDeliver,
+		DeliverPolicy: deliver,
+	}
+}
+
+// handle acks msg once handler succeeds and naks it with backoff otherwise.
+// Messages that cannot be decoded are terminated, as redelivery would not
+// help.
+func (b *JetStreamBroker) handle(topic string, msg jetstream.Msg, handler pubsub.Handler) {
+	env, err := b.codec.Decode(topic, msg.Data())
+	if err != nil {
+		b.log.Errorf("Cannot decode message on topic %s: %v", topic, err)
+		if err := msg.Term(); err != nil {
+			b.log.Errorf("Cannot terminate message on topic %s: %v", topic, err)
+		}
+		return
+	}
+
+	if err := handler(context.Background(), env); err != nil {
+		delivered := uint64(1)
+		if meta, merr := msg.Metadata(); merr == nil {
+			delivered = meta.NumDelivered
+		}
+		b.log.Errorf("Handler error for message %s (delivery %d): %v", env.ID, delivered, err)
+		if err := msg.NakWithDelay(nakDelay(delivered)); err != nil && !errors.Is(err, jetstream.ErrMsgAlreadyAckd) {
+			b.log.Errorf("Cannot nak message %s: %v", env.ID, err)
+		}
+		return
+	}
+
+	if err := msg.Ack(); err != nil {
+		b.log.Errorf("Cannot ack message %s: %v", env.ID, err)
+	}
+}
+
+// nakDelay returns the redelivery delay after delivery n: one second,
+// doubled for every further delivery and capped at maxNakDelay.
+func nakDelay(delivered uint64) time.Duration {
+	d := time.Second
+	for i := uint64(1); i < delivered && d < maxNakDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxNakDelay)
+}
+
+// Close stops all subscriptions.
+func (b *JetStreamBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+
+	for _, cc := range b.subscriptions {
+		cc.Drain()
+	}
+	for id, cc := range b.subscriptions {
+		<-cc.Closed()
+		b.log.Debugf("Subscriber %s stopped", id)
+	}
+	b.subscriptions = make(map[string]jetstream.ConsumeContext)
+
+	b.log.Info("PubSub broker closed")
+	return nil
+}
@@ -0,0 +1,248 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/config"
+	aqmnats "github.com/aquamarinepk/aqm/nats"
+	"github.com/aquamarinepk/aqm/pubsub"
+)
+
+func startJetStream(t *testing.T, cfg config.JetStreamConfig) (*JetStreamBroker, func()) {
+	t.Helper()
+	url, cleanup := setupNATS(t)
+
+	conn := aqmnats.New(config.NATSConfig{URL: url, MaxReconnect: 1}, testLogger())
+	ctx := context.Background()
+	if err := conn.Start(ctx); err != nil {
+		cleanup()
+		t.Fatalf("conn Start failed: %v", err)
+	}
+
+	broker := NewJetStreamBroker(conn, cfg, testLogger())
+	if err := broker.Start(ctx); err != nil {
+		conn.Close()
+		cleanup()
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	return broker, func() {
+		broker.Close()
+		conn.Close()
+		cleanup()
+	}
+}
+
+func jetStreamConfig() config.JetStreamConfig {
+	return config.JetStreamConfig{
+		Enabled:    true,
+		AckWait:    5 * time.Second,
+		MaxDeliver: 3,
+		Streams: map[string]config.JetStreamStreamConfig{
+			"AUTH": {Subjects: []string{"auth.>"}, Storage: "memory", DuplicateWindow: time.Minute},
+		},
+		Consumers: map[string]config.JetStreamConsumerConfig{
+			"audit": {Stream: "AUTH", FilterSubject: "auth.>"},
+		},
+	}
+}
+
+func TestJetStreamBrokerProvisions(t *testing.T) {
+	broker, cleanup := startJetStream(t, jetStreamConfig())
+	defer cleanup()
+
+	ctx := context.Background()
+	if err := broker.CheckHealth(ctx); err != nil {
+		t.Fatalf("CheckHealth failed: %v", err)
+	}
+
+	stream, err := broker.js.Stream(ctx, "AUTH")
+	if err != nil {
+		t.Fatalf("stream not provisioned: %v", err)
+	}
+	if got := stream.CachedInfo().Config.Duplicates; got != time.Minute {
+		t.Errorf("duplicate window = %s, want 1m", got)
+	}
+
+	cons, err := broker.js.Consumer(ctx, "AUTH", "audit")
+	if err != nil {
+		t.Fatalf("consumer not provisioned: %v", err)
+	}
+	if got := cons.CachedInfo().Config.MaxDeliver; got != 3 {
+		t.Errorf("MaxDeliver = %d, want 3", got)
+	}
+}
+
+func TestJetStreamBrokerDeduplicates(t *testing.T) {
+	broker, cleanup := startJetStream(t, jetStreamConfig())
+	defer cleanup()
+
+	ctx := context.Background()
+	env := pubsub.NewEnvelope("auth.user.signed_in", map[string]string{"user": "alice"})
+	for range 3 {
+		if err := broker.Publish(ctx, "auth.user.signed_in", env); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+
+	stream, err := broker.js.Stream(ctx, "AUTH")
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if info.State.Msgs != 1 {
+		t.Errorf("stream holds %d messages, want 1", info.State.Msgs)
+	}
+}
+
+func TestJetStreamBrokerRedelivers(t *testing.T) {
+	broker, cleanup := startJetStream(t, jetStreamConfig())
+	defer cleanup()
+
+	ctx := context.Background()
+	var mu sync.Mutex
+	attempts := 0
+	done := make(chan struct{})
+
+	handler := func(ctx context.Context, env pubsub.Envelope) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			return fmt.Errorf("transient failure")
+		}
+		close(done)
+		return nil
+	}
+
+	if err := broker.Subscribe(ctx, "auth.>", handler, pubsub.SubscribeOptions{SubscriberID: "audit"}); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	env := pubsub.NewEnvelope("auth.user.signed_up", "alice")
+	if err := broker.Publish(ctx, "auth.user.signed_up", env); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("message was not redelivered")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 2 {
+		t.Errorf("handler called %d times, want 2", attempts)
+	}
+}
+
+func TestJetStreamBrokerDurableResumes(t *testing.T) {
+	url, cleanup := setupNATS(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	cfg := jetStreamConfig()
+	cfg.Consumers = nil
+
+	newBroker := func() (*JetStreamBroker, *aqmnats.Conn) {
+		conn := aqmnats.New(config.NATSConfig{URL: url, MaxReconnect: 1}, testLogger())
+		if err := conn.Start(ctx); err != nil {
+			t.Fatalf("conn Start failed: %v", err)
+		}
+		broker := NewJetStreamBroker(conn, cfg, testLogger())
+		if err := broker.Start(ctx); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		return broker, conn
+	}
+
+	received := make(chan string, 4)
+	handler := func(ctx context.Context, env pubsub.Envelope) error {
+		received <- env.ID
+		return nil
+	}
+	opts := pubsub.SubscribeOptions{SubscriberID: "worker"}
+
+	first, conn := newBroker()
+	if err := first.Subscribe(ctx, "auth.user.created", handler, opts); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	first.Close()
+	conn.Close()
+
+	second, conn := newBroker()
+	defer conn.Close()
+	defer second.Close()
+
+	env := pubsub.NewEnvelope("auth.user.created", "bob")
+	if err := second.Publish(ctx, "auth.user.created", env); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if err := second.Subscribe(ctx, "auth.user.created", handler, opts); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	select {
+	case id := <-received:
+		if id != env.ID {
+			t.Errorf("received %s, want %s", id, env.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("durable consumer did not resume")
+	}
+}
+
+func TestJetStreamBrokerNotStarted(t *testing.T) {
+	conn := aqmnats.New(config.NATSConfig{}, testLogger())
+	broker := NewJetStreamBroker(conn, jetStreamConfig(), testLogger())
+	ctx := context.Background()
+
+	if err := broker.Start(ctx); err == nil {
+		t.Error("Start without a connection should fail")
+	}
+	if err := broker.Publish(ctx, "auth.x", pubsub.NewEnvelope("x", nil)); err == nil {
+		t.Error("Publish before Start should fail")
+	}
+	if err := broker.CheckHealth(ctx); err == nil {
+		t.Error("CheckHealth before Start should fail")
+	}
+}
+
+func TestJetStreamBrokerWithCodec(t *testing.T) {
+	conn := aqmnats.New(config.NATSConfig{}, testLogger())
+	codec := pubsub.NewCloudEventsCodec("/aqm/test")
+
+	if got := NewJetStreamBroker(conn, jetStreamConfig(), testLogger(), WithCodec(codec)).codec; got != codec {
+		t.Errorf("codec = %T, want the CloudEvents codec", got)
+	}
+	if _, ok := NewJetStreamBroker(conn, jetStreamConfig(), testLogger()).codec.(pubsub.JSONCodec); !ok {
+		t.Error("default codec is not pubsub.JSONCodec")
+	}
+}
+
+func TestNakDelay(t *testing.T) {
+	tests := []struct {
+		delivered uint64
+		want      time.Duration
+	}{
+		{0, time.Second},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{10, maxNakDelay},
+	}
+
+	for _, tt := range tests {
+		if got := nakDelay(tt.delivered); got != tt.want {
+			t.Errorf("nakDelay(%d) = %s, want %s", tt.delivered, got, tt.want)
+		}
+	}
+}
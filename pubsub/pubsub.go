@@ -5,6 +5,9 @@
 //
 // Delivery is at-least-once by default. Ordering guarantees are backend-dependent
 // and MUST NOT be assumed by consumers.
+//
+// Brokers put envelopes on the wire with a Codec: JSONCodec by default, or
+// CloudEventsCodec to emit and consume CloudEvents 1.0 structured JSON.
 package pubsub
 
 import (
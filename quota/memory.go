@@ -0,0 +1,74 @@
+package quota
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-process Store. Counters are lost on restart and are
+// not shared between instances.
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]memoryCounter
+	now      func() time.Time
+}
+
+type memoryCounter struct {
+	value     int64
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: make(map[string]memoryCounter),
+		now:      time.Now,
+	}
+}
+
+// Increment implements Store. Expired counters are swept on each call.
+func (s *MemoryStore) Increment(ctx context.Context, key string, n int64, expiresAt time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, c := range s.counters {
+		if !c.expiresAt.After(now) {
+			delete(s.counters, k)
+		}
+	}
+
+	c := s.counters[key]
+	c.value += n
+	c.expiresAt = expiresAt
+	s.counters[key] = c
+
+	return c.value, nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counters[key]
+	if !ok || !c.expiresAt.After(s.now()) {
+		return 0, nil
+	}
+	return c.value, nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.counters, key)
+	return nil
+}
+
+// Ping implements Store. It always succeeds.
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 14, 15, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	expires := now.Add(time.Minute)
+	if got, _ := s.Increment(ctx, "k", 2, expires); got != 2 {
+		t.Errorf("Increment() = %d, want 2", got)
+	}
+	if got, _ := s.Increment(ctx, "k", -1, expires); got != 1 {
+		t.Errorf("Increment() = %d, want 1", got)
+	}
+	if got, _ := s.Get(ctx, "k"); got != 1 {
+		t.Errorf("Get() = %d, want 1", got)
+	}
+	if got, _ := s.Get(ctx, "missing"); got != 0 {
+		t.Errorf("Get(missing) = %d, want 0", got)
+	}
+
+	if err := s.Delete(ctx, "k"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, _ := s.Get(ctx, "k"); got != 0 {
+		t.Errorf("Get() after Delete = %d, want 0", got)
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 14, 15, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	s.Increment(ctx, "old", 5, now.Add(time.Minute))
+	now = now.Add(time.Minute)
+
+	if got, _ := s.Get(ctx, "old"); got != 0 {
+		t.Errorf("Get(expired) = %d, want 0", got)
+	}
+
+	s.Increment(ctx, "new", 1, now.Add(time.Minute))
+	if _, ok := s.counters["old"]; ok {
+		t.Error("expired counter should be swept on Increment")
+	}
+}
+
+func TestMemoryStorePing(t *testing.T) {
+	if err := NewMemoryStore().Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package quota
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+// SubjectFunc identifies the caller a request is charged to.
+// Returning an empty string skips enforcement for the request.
+type SubjectFunc func(r *http.Request) string
+
+// DefaultSubject charges authenticated requests to the user set by the
+// Session or Bearer middleware and anonymous ones to the client IP.
+func DefaultSubject(r *http.Request) string {
+	if userID := middleware.GetUserID(r.Context()); userID != "" {
+		return "user:" + userID
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// EnforceQuota consumes one unit of quota name per request and rejects
+// requests past limit with 429 and a Retry-After hint until the period resets.
+// Responses carry X-Quota-Limit, X-Quota-Remaining and X-Quota-Reset headers.
+// If the store fails the request is let through and the error is logged.
+func (m *Manager) EnforceQuota(name string, limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject := m.subject(r)
+			if subject == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			usage, err := m.Consume(r.Context(), name, subject, 1, limit)
+			if err != nil && !errors.Is(err, ErrExceeded) {
+				m.log.Error("Cannot enforce quota", "quota", name, "subject", subject, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("X-Quota-Limit", strconv.FormatInt(usage.Limit, 10))
+			h.Set("X-Quota-Remaining", strconv.FormatInt(usage.Remaining(), 10))
+			h.Set("X-Quota-Reset", strconv.FormatInt(usage.ResetAt.Unix(), 10))
+
+			if errors.Is(err, ErrExceeded) {
+				middleware.WriteRetryAfter(w, http.StatusTooManyRequests, "QUOTA_EXCEEDED",
+					"Quota "+name+" exceeded", usage.ResetAt.Sub(m.now()))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package quota
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/middleware"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestEnforceQuota(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Hour).Add(30 * time.Minute)
+	m := New(NewMemoryStore(), WithPeriod(Hourly), WithClock(fixedClock(now)))
+	handler := m.EnforceQuota("api_calls", 2)(okHandler())
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != want {
+			t.Errorf("request #%d status = %d, want %d", i+1, rec.Code, want)
+		}
+		if got := rec.Header().Get("X-Quota-Limit"); got != "2" {
+			t.Errorf("X-Quota-Limit = %q, want 2", got)
+		}
+		wantRemaining := strconv.Itoa(max(0, 1-i))
+		if got := rec.Header().Get("X-Quota-Remaining"); got != wantRemaining {
+			t.Errorf("request #%d X-Quota-Remaining = %q, want %q", i+1, got, wantRemaining)
+		}
+		wantReset := strconv.FormatInt(now.Add(30*time.Minute).Unix(), 10)
+		if got := rec.Header().Get("X-Quota-Reset"); got != wantReset {
+			t.Errorf("X-Quota-Reset = %q, want %q", got, wantReset)
+		}
+		if want == http.StatusTooManyRequests {
+			if got := rec.Header().Get("Retry-After"); got != "1800" {
+				t.Errorf("Retry-After = %q, want 1800", got)
+			}
+		}
+	}
+}
+
+func TestEnforceQuotaSubjects(t *testing.T) {
+	m := New(NewMemoryStore())
+	handler := m.EnforceQuota("api_calls", 1)(okHandler())
+
+	serve := func(userID, addr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = addr
+		if userID != "" {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve("alice", "10.0.0.1:1"); code != http.StatusOK {
+		t.Errorf("alice status = %d, want 200", code)
+	}
+	if code := serve("bob", "10.0.0.1:1"); code != http.StatusOK {
+		t.Errorf("bob on same IP status = %d, want 200", code)
+	}
+	if code := serve("", "10.0.0.1:1"); code != http.StatusOK {
+		t.Errorf("anonymous status = %d, want 200", code)
+	}
+	if code := serve("", "10.0.0.1:2"); code != http.StatusTooManyRequests {
+		t.Errorf("anonymous same IP status = %d, want 429", code)
+	}
+}
+
+func TestEnforceQuotaSkipsEmptySubject(t *testing.T) {
+	m := New(NewMemoryStore(), WithSubject(func(r *http.Request) string {
+		return r.Header.Get("X-API-Key")
+	}))
+	handler := m.EnforceQuota("api_calls", 1)(okHandler())
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("request without subject status = %d, want 200", rec.Code)
+		}
+		if rec.Header().Get("X-Quota-Limit") != "" {
+			t.Error("request without subject should not carry quota headers")
+		}
+	}
+}
+
+func TestEnforceQuotaFailsOpen(t *testing.T) {
+	m := New(failingStore{})
+	handler := m.EnforceQuota("api_calls", 1)(okHandler())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 when store fails", rec.Code)
+	}
+}
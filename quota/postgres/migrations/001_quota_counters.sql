@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS quota_counters (
+    key TEXT PRIMARY KEY,
+    value BIGINT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_quota_counters_expires_at ON quota_counters(expires_at);
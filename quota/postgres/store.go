@@ -0,0 +1,69 @@
+// Package postgres provides a quota.Store backed by the quota_counters table.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/aquamarinepk/aqm/quota"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) quota.Store {
+	return &store{db: db}
+}
+
+func (s *store) Increment(ctx context.Context, key string, n int64, expiresAt time.Time) (int64, error) {
+	query := `
+		INSERT INTO quota_counters (key, value, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE
+		SET value = CASE
+				WHEN quota_counters.expires_at <= NOW() THEN EXCLUDED.value
+				ELSE quota_counters.value + EXCLUDED.value
+			END,
+			expires_at = EXCLUDED.expires_at
+		RETURNING value
+	`
+	var value int64
+	if err := s.db.QueryRowContext(ctx, query, key, n, expiresAt).Scan(&value); err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+func (s *store) Get(ctx context.Context, key string) (int64, error) {
+	query := `SELECT value FROM quota_counters WHERE key = $1 AND expires_at > NOW()`
+	var value int64
+	err := s.db.QueryRowContext(ctx, query, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+func (s *store) Delete(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM quota_counters WHERE key = $1`, key)
+	return err
+}
+
+func (s *store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Purge removes expired counters and returns how many were deleted.
+func Purge(ctx context.Context, db *sql.DB) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM quota_counters WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/testhelper"
+)
+
+func TestMain(m *testing.M) {
+	testhelper.Main(m)
+}
+
+func setupTestDB(t *testing.T) (*sql.DB, func()) {
+	t.Helper()
+
+	db, _, cleanup := testhelper.SetupTestDB(t)
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS quota_counters (
+			key TEXT PRIMARY KEY,
+			value BIGINT NOT NULL DEFAULT 0,
+			expires_at TIMESTAMPTZ NOT NULL
+		)
+	`)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to create tables: %v", err)
+	}
+
+	return db, cleanup
+}
+
+func TestStoreIncrement(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := NewStore(db)
+	ctx := context.Background()
+	expires := time.Now().Add(time.Hour)
+
+	for i, want := range []int64{2, 5, 4} {
+		n := []int64{2, 3, -1}[i]
+		got, err := s.Increment(ctx, "api_calls:user:1:0", n, expires)
+		if err != nil {
+			t.Fatalf("Increment() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("Increment(%d) = %d, want %d", n, got, want)
+		}
+	}
+
+	got, err := s.Get(ctx, "api_calls:user:1:0")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got != 4 {
+		t.Errorf("Get() = %d, want 4", got)
+	}
+}
+
+func TestStoreGetMissingAndExpired(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := NewStore(db)
+	ctx := context.Background()
+
+	if got, err := s.Get(ctx, "missing"); err != nil || got != 0 {
+		t.Errorf("Get(missing) = %d, %v; want 0, nil", got, err)
+	}
+
+	if _, err := s.Increment(ctx, "old", 3, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("Increment() error = %v", err)
+	}
+	if got, err := s.Get(ctx, "old"); err != nil || got != 0 {
+		t.Errorf("Get(expired) = %d, %v; want 0, nil", got, err)
+	}
+
+	purged, err := Purge(ctx, db)
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Purge() = %d, want 1", purged)
+	}
+}
+
+func TestStoreDelete(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := NewStore(db)
+	ctx := context.Background()
+
+	if _, err := s.Increment(ctx, "k", 1, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Increment() error = %v", err)
+	}
+	if err := s.Delete(ctx, "k"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, _ := s.Get(ctx, "k"); got != 0 {
+		t.Errorf("Get() after Delete = %d, want 0", got)
+	}
+}
+
+func TestStorePing(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	if err := store.Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+
+	db.Close()
+	if err := store.Ping(context.Background()); err == nil {
+		t.Error("Ping() should fail once the database is closed")
+	}
+}
@@ -0,0 +1,195 @@
+// Package quota tracks usage counters per subject over fixed periods and
+// enforces limits on them. Counters are kept in a Store so limits hold
+// across service instances; MemoryStore is provided for tests and single
+// instances, and quota/postgres for shared deployments.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+)
+
+// ErrExceeded is returned by Consume when the limit would be exceeded.
+var ErrExceeded = errors.New("quota exceeded")
+
+// Store persists usage counters.
+type Store interface {
+	// Increment adds n (which may be negative) to the counter under key and
+	// returns the new total. Missing counters start at zero. expiresAt tells
+	// the store when the counter may be discarded.
+	Increment(ctx context.Context, key string, n int64, expiresAt time.Time) (int64, error)
+	// Get returns the current total under key, or zero if there is none.
+	Get(ctx context.Context, key string) (int64, error)
+	// Delete removes the counter under key.
+	Delete(ctx context.Context, key string) error
+	// Ping reports whether the backing storage is reachable.
+	Ping(ctx context.Context) error
+}
+
+// Period maps an instant to the window that contains it. Counters reset
+// at the end of each window.
+type Period func(t time.Time) (start, end time.Time)
+
+// Every returns a Period of fixed windows of length d aligned to the Unix epoch.
+func Every(d time.Duration) Period {
+	return func(t time.Time) (time.Time, time.Time) {
+		start := t.UTC().Truncate(d)
+		return start, start.Add(d)
+	}
+}
+
+var (
+	// Hourly resets counters at the top of every hour (UTC).
+	Hourly = Every(time.Hour)
+	// Daily resets counters at midnight UTC.
+	Daily = Every(24 * time.Hour)
+)
+
+// Monthly resets counters on the first day of each calendar month (UTC).
+func Monthly(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// Usage is a snapshot of a counter in the current window.
+type Usage struct {
+	Name    string    `json:"name"`
+	Subject string    `json:"subject"`
+	Used    int64     `json:"used"`
+	Limit   int64     `json:"limit"`
+	ResetAt time.Time `json:"reset_at"`
+}
+
+// Remaining returns how many units are left in the current window.
+func (u Usage) Remaining() int64 {
+	if u.Used >= u.Limit {
+		return 0
+	}
+	return u.Limit - u.Used
+}
+
+// Exceeded reports whether usage has reached the limit.
+func (u Usage) Exceeded() bool {
+	return u.Used >= u.Limit
+}
+
+// Manager consumes and reports quotas backed by a Store.
+type Manager struct {
+	store   Store
+	period  Period
+	now     func() time.Time
+	subject SubjectFunc
+	log     log.Logger
+}
+
+// Option configures a Manager.
+type Option func(*Manager)
+
+// WithPeriod sets the reset period. The default is Daily.
+func WithPeriod(p Period) Option {
+	return func(m *Manager) {
+		if p != nil {
+			m.period = p
+		}
+	}
+}
+
+// WithClock overrides the time source.
+func WithClock(now func() time.Time) Option {
+	return func(m *Manager) {
+		if now != nil {
+			m.now = now
+		}
+	}
+}
+
+// WithSubject sets how EnforceQuota identifies the caller.
+// The default is DefaultSubject.
+func WithSubject(fn SubjectFunc) Option {
+	return func(m *Manager) {
+		if fn != nil {
+			m.subject = fn
+		}
+	}
+}
+
+// WithLogger sets the logger used to report store failures.
+func WithLogger(logger log.Logger) Option {
+	return func(m *Manager) {
+		if logger != nil {
+			m.log = logger
+		}
+	}
+}
+
+// New creates a Manager that keeps counters in store.
+func New(store Store, opts ...Option) *Manager {
+	m := &Manager{
+		store:   store,
+		period:  Daily,
+		now:     time.Now,
+		subject: DefaultSubject,
+		log:     log.NewNoopLogger(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Consume records n units of quota name for subject. If that would take
+// usage past limit, nothing is recorded and ErrExceeded is returned along
+// with the current usage.
+func (m *Manager) Consume(ctx context.Context, name, subject string, n, limit int64) (Usage, error) {
+	key, end := m.window(name, subject)
+	usage := Usage{Name: name, Subject: subject, Limit: limit, ResetAt: end}
+
+	used, err := m.store.Increment(ctx, key, n, end)
+	if err != nil {
+		return usage, fmt.Errorf("cannot increment quota %s: %w", name, err)
+	}
+
+	if used > limit {
+		used, err = m.store.Increment(ctx, key, -n, end)
+		if err != nil {
+			return usage, fmt.Errorf("cannot release quota %s: %w", name, err)
+		}
+		usage.Used = used
+		return usage, ErrExceeded
+	}
+
+	usage.Used = used
+	return usage, nil
+}
+
+// Usage returns the current usage of quota name for subject.
+func (m *Manager) Usage(ctx context.Context, name, subject string, limit int64) (Usage, error) {
+	key, end := m.window(name, subject)
+
+	used, err := m.store.Get(ctx, key)
+	if err != nil {
+		return Usage{}, fmt.Errorf("cannot get quota %s: %w", name, err)
+	}
+
+	return Usage{Name: name, Subject: subject, Used: used, Limit: limit, ResetAt: end}, nil
+}
+
+// Reset clears the current window of quota name for subject.
+func (m *Manager) Reset(ctx context.Context, name, subject string) error {
+	key, _ := m.window(name, subject)
+	if err := m.store.Delete(ctx, key); err != nil {
+		return fmt.Errorf("cannot reset quota %s: %w", name, err)
+	}
+	return nil
+}
+
+// window returns the store key for the current window and when it ends.
+func (m *Manager) window(name, subject string) (string, time.Time) {
+	start, end := m.period(m.now())
+	return fmt.Sprintf("%s:%s:%d", name, subject, start.Unix()), end
+}
@@ -0,0 +1,143 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type failingStore struct{}
+
+func (failingStore) Increment(context.Context, string, int64, time.Time) (int64, error) {
+	return 0, errors.New("store down")
+}
+func (failingStore) Get(context.Context, string) (int64, error) { return 0, errors.New("store down") }
+func (failingStore) Delete(context.Context, string) error       { return errors.New("store down") }
+func (failingStore) Ping(context.Context) error                 { return errors.New("store down") }
+
+func fixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+func TestPeriods(t *testing.T) {
+	at := time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		period    Period
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{"hourly", Hourly, time.Date(2025, 3, 14, 15, 0, 0, 0, time.UTC), time.Date(2025, 3, 14, 16, 0, 0, 0, time.UTC)},
+		{"daily", Daily, time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"monthly", Monthly, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"every 10m", Every(10 * time.Minute), time.Date(2025, 3, 14, 15, 0, 0, 0, time.UTC), time.Date(2025, 3, 14, 15, 10, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := tt.period(at)
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("period = [%v, %v), want [%v, %v)", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestManagerConsume(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Hour)
+	m := New(NewMemoryStore(), WithPeriod(Hourly), WithClock(fixedClock(now)))
+
+	for i := 1; i <= 3; i++ {
+		usage, err := m.Consume(ctx, "api_calls", "user:1", 1, 3)
+		if err != nil {
+			t.Fatalf("Consume() #%d error = %v", i, err)
+		}
+		if usage.Used != int64(i) || usage.Remaining() != int64(3-i) {
+			t.Errorf("Consume() #%d used=%d remaining=%d", i, usage.Used, usage.Remaining())
+		}
+	}
+
+	usage, err := m.Consume(ctx, "api_calls", "user:1", 1, 3)
+	if !errors.Is(err, ErrExceeded) {
+		t.Fatalf("Consume() error = %v, want ErrExceeded", err)
+	}
+	if usage.Used != 3 {
+		t.Errorf("rejected Consume() should not be recorded, used = %d", usage.Used)
+	}
+	if !usage.ResetAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("ResetAt = %v, want %v", usage.ResetAt, now.Add(time.Hour))
+	}
+
+	if _, err := m.Consume(ctx, "api_calls", "user:2", 1, 3); err != nil {
+		t.Errorf("other subject should have its own counter, error = %v", err)
+	}
+	if _, err := m.Consume(ctx, "exports", "user:1", 1, 3); err != nil {
+		t.Errorf("other quota should have its own counter, error = %v", err)
+	}
+}
+
+func TestManagerPeriodReset(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Hour)
+	m := New(NewMemoryStore(), WithPeriod(Hourly), WithClock(func() time.Time { return now }))
+
+	if _, err := m.Consume(ctx, "api_calls", "user:1", 1, 1); err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+	if _, err := m.Consume(ctx, "api_calls", "user:1", 1, 1); !errors.Is(err, ErrExceeded) {
+		t.Fatalf("Consume() error = %v, want ErrExceeded", err)
+	}
+
+	now = now.Add(time.Hour)
+
+	if _, err := m.Consume(ctx, "api_calls", "user:1", 1, 1); err != nil {
+		t.Errorf("Consume() in next period error = %v", err)
+	}
+}
+
+func TestManagerUsageAndReset(t *testing.T) {
+	ctx := context.Background()
+	m := New(NewMemoryStore())
+
+	if _, err := m.Consume(ctx, "api_calls", "user:1", 5, 10); err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+
+	usage, err := m.Usage(ctx, "api_calls", "user:1", 10)
+	if err != nil {
+		t.Fatalf("Usage() error = %v", err)
+	}
+	if usage.Used != 5 || usage.Remaining() != 5 || usage.Exceeded() {
+		t.Errorf("Usage() = %+v", usage)
+	}
+
+	if err := m.Reset(ctx, "api_calls", "user:1"); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+
+	usage, err = m.Usage(ctx, "api_calls", "user:1", 10)
+	if err != nil {
+		t.Fatalf("Usage() error = %v", err)
+	}
+	if usage.Used != 0 {
+		t.Errorf("Usage() after Reset used = %d, want 0", usage.Used)
+	}
+}
+
+func TestManagerStoreErrors(t *testing.T) {
+	ctx := context.Background()
+	m := New(failingStore{})
+
+	if _, err := m.Consume(ctx, "api_calls", "user:1", 1, 1); err == nil || errors.Is(err, ErrExceeded) {
+		t.Errorf("Consume() error = %v, want store error", err)
+	}
+	if _, err := m.Usage(ctx, "api_calls", "user:1", 1); err == nil {
+		t.Error("Usage() should return store error")
+	}
+	if err := m.Reset(ctx, "api_calls", "user:1"); err == nil {
+		t.Error("Reset() should return store error")
+	}
+}
@@ -0,0 +1,63 @@
+package redis
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/aquamarinepk/aqm/config"
+	"github.com/aquamarinepk/aqm/middleware"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// newTestClient returns a client talking to an in-process Redis server.
+func newTestClient(t *testing.T) (*Client, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	c := NewWithClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr(), MaxRetries: -1}), "test:")
+	t.Cleanup(func() { c.Stop(context.Background()) })
+	return c, mr
+}
+
+func TestNew(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := New(config.RedisConfig{Addr: mr.Addr(), KeyPrefix: "svc:"})
+	defer c.Stop(context.Background())
+
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if got := c.Key("session", "abc"); got != "svc:session:abc" {
+		t.Errorf("Key() = %q, want svc:session:abc", got)
+	}
+}
+
+func TestPingUnreachable(t *testing.T) {
+	c, mr := newTestClient(t)
+	mr.Close()
+
+	if err := c.Ping(context.Background()); err == nil {
+		t.Error("Ping() error = nil, want error for a stopped server")
+	}
+}
+
+func TestIsMemberBacksSetBlocklist(t *testing.T) {
+	c, mr := newTestClient(t)
+	mr.SAdd("test:blocked", "203.0.113.9")
+	blocklist := middleware.NewSetBlocklist(c, "blocked")
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"203.0.113.9", true},
+		{"198.51.100.1", false},
+	}
+	for _, tt := range tests {
+		got, err := blocklist.Blocked(context.Background(), netip.MustParseAddr(tt.ip))
+		if err != nil || got != tt.want {
+			t.Errorf("Blocked(%s) = %v, %v, want %v, nil", tt.ip, got, err, tt.want)
+		}
+	}
+}
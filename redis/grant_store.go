@@ -0,0 +1,138 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// DefaultGrantTTL is used by NewGrantStore when ttl is not positive.
+const DefaultGrantTTL = 30 * time.Second
+
+// cacheField stores one cached read in a user's or role's hash, starting
+// the hash TTL with its first field so nothing in it outlives the TTL.
+var cacheField = goredis.NewScript(`
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+end
+return 1
+`)
+
+// GrantStore is an auth.GrantStore that caches the reads of another store
+// in Redis, so every replica shares one cache and sees the others'
+// invalidations. The reads for a user, and the grants of a role, are kept
+// in one hash each; Create and Delete drop the hashes they affect.
+//
+// Redis failures never fail a call: reads fall back to the wrapped store
+// and a missed invalidation lasts at most the TTL. As with
+// cache.GrantStore, changes made through other paths, such as edits to a
+// role's permissions, are visible once entries expire or after Invalidate
+// or Purge.
+type GrantStore struct {
+	client *Client
+	store  auth.GrantStore
+	ttl    time.Duration
+}
+
+// NewGrantStore wraps store with a Redis read cache whose entries expire
+// after ttl.
+func NewGrantStore(client *Client, store auth.GrantStore, ttl time.Duration) *GrantStore {
+	if ttl <= 0 {
+		ttl = DefaultGrantTTL
+	}
+	return &GrantStore{client: client, store: store, ttl: ttl}
+}
+
+func (s *GrantStore) userKey(username string) string {
+	return s.client.Key("grants", "user", username)
+}
+
+func (s *GrantStore) roleKey(roleID auth.RoleID) string {
+	return s.client.Key("grants", "role", roleID.String())
+}
+
+func (s *GrantStore) Create(ctx context.Context, grant *auth.Grant) error {
+	err := s.store.Create(ctx, grant)
+	s.client.rdb.Del(ctx, s.userKey(grant.Username), s.roleKey(grant.RoleID))
+	return err
+}
+
+func (s *GrantStore) Delete(ctx context.Context, username string, roleID auth.RoleID) error {
+	err := s.store.Delete(ctx, username, roleID)
+	s.client.rdb.Del(ctx, s.userKey(username), s.roleKey(roleID))
+	return err
+}
+
+func (s *GrantStore) GetUserGrants(ctx context.Context, username string) ([]*auth.Grant, error) {
+	return cached(ctx, s, s.userKey(username), "grants", func() ([]*auth.Grant, error) {
+		return s.store.GetUserGrants(ctx, username)
+	})
+}
+
+func (s *GrantStore) GetRoleGrants(ctx context.Context, roleID auth.RoleID) ([]*auth.Grant, error) {
+	return cached(ctx, s, s.roleKey(roleID), "grants", func() ([]*auth.Grant, error) {
+		return s.store.GetRoleGrants(ctx, roleID)
+	})
+}
+
+func (s *GrantStore) GetUserRoles(ctx context.Context, username string) ([]*auth.Role, error) {
+	return cached(ctx, s, s.userKey(username), "roles", func() ([]*auth.Role, error) {
+		return s.store.GetUserRoles(ctx, username)
+	})
+}
+
+func (s *GrantStore) HasRole(ctx context.Context, username string, roleName string) (bool, error) {
+	return cached(ctx, s, s.userKey(username), "has:"+roleName, func() (bool, error) {
+		return s.store.HasRole(ctx, username, roleName)
+	})
+}
+
+// Ping checks the wrapped store.
+func (s *GrantStore) Ping(ctx context.Context) error {
+	return s.store.Ping(ctx)
+}
+
+// Invalidate drops every cached read for username.
+func (s *GrantStore) Invalidate(ctx context.Context, username string) error {
+	return s.client.rdb.Del(ctx, s.userKey(username)).Err()
+}
+
+// Purge drops every cached read, for example after a role's permissions
+// change.
+func (s *GrantStore) Purge(ctx context.Context) error {
+	iter := s.client.rdb.Scan(ctx, 0, s.client.Key("grants", "*"), 100).Iterator()
+	for iter.Next(ctx) {
+		if err := s.client.rdb.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+// cached returns the value of field in the hash at key, loading and caching
+// it on a miss or when Redis cannot be read.
+func cached[T any](ctx context.Context, s *GrantStore, key, field string, load func() (T, error)) (T, error) {
+	if data, err := s.client.rdb.HGet(ctx, key, field).Bytes(); err == nil {
+		var v T
+		if json.Unmarshal(data, &v) == nil {
+			return v, nil
+		}
+	}
+
+	v, err := load()
+	if err != nil {
+		return v, err
+	}
+	if data, err := json.Marshal(v); err == nil {
+		ms := strconv.FormatInt(max(s.ttl.Milliseconds(), 1), 10)
+		cacheField.Run(ctx, s.client.rdb, []string{key}, field, data, ms)
+	}
+	return v, nil
+}
+
+var _ auth.GrantStore = (*GrantStore)(nil)
@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/aquamarinepk/aqm/auth"
+	"github.com/aquamarinepk/aqm/auth/fake"
+)
+
+// countingStore counts the reads that reach the wrapped store.
+type countingStore struct {
+	auth.GrantStore
+	reads atomic.Int64
+}
+
+func (s *countingStore) GetUserGrants(ctx context.Context, username string) ([]*auth.Grant, error) {
+	s.reads.Add(1)
+	return s.GrantStore.GetUserGrants(ctx, username)
+}
+
+func (s *countingStore) GetUserRoles(ctx context.Context, username string) ([]*auth.Role, error) {
+	s.reads.Add(1)
+	return s.GrantStore.GetUserRoles(ctx, username)
+}
+
+func (s *countingStore) HasRole(ctx context.Context, username string, roleName string) (bool, error) {
+	s.reads.Add(1)
+	return s.GrantStore.HasRole(ctx, username, roleName)
+}
+
+func setupGrantStore(t *testing.T) (*GrantStore, *countingStore, *auth.Role, *miniredis.Miniredis) {
+	t.Helper()
+	roleStore := fake.NewRoleStore()
+	role := auth.NewRole()
+	role.Name = "editor"
+	role.Permissions = []string{"content:write"}
+	role.BeforeCreate()
+	if err := roleStore.Create(context.Background(), role); err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+	client, mr := newTestClient(t)
+	inner := &countingStore{GrantStore: fake.NewGrantStore(roleStore)}
+	return NewGrantStore(client, inner, time.Minute), inner, role, mr
+}
+
+func TestGrantStoreCachesReads(t *testing.T) {
+	ctx := context.Background()
+	s, inner, role, _ := setupGrantStore(t)
+	s.Create(ctx, auth.NewGrant("alice", role.ID, "admin"))
+
+	for range 3 {
+		if has, err := s.HasRole(ctx, "alice", "editor"); err != nil || !has {
+			t.Fatalf("HasRole() = %v, %v, want true, nil", has, err)
+		}
+		roles, err := s.GetUserRoles(ctx, "alice")
+		if err != nil || len(roles) != 1 || roles[0].Permissions[0] != "content:write" || roles[0].Version != 1 {
+			t.Fatalf("GetUserRoles() = %v, %v, want the editor role", roles, err)
+		}
+	}
+	if got := inner.reads.Load(); got != 2 {
+		t.Errorf("wrapped store reads = %d, want 2", got)
+	}
+}
+
+func TestGrantStoreSharedInvalidation(t *testing.T) {
+	ctx := context.Background()
+	s, inner, role, _ := setupGrantStore(t)
+	// A second replica shares the Redis cache and the backing store.
+	replica := NewGrantStore(s.client, inner, time.Minute)
+
+	if has, _ := replica.HasRole(ctx, "alice", "editor"); has {
+		t.Fatal("HasRole() before grant = true, want false")
+	}
+	if err := s.Create(ctx, auth.NewGrant("alice", role.ID, "admin")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if has, _ := replica.HasRole(ctx, "alice", "editor"); !has {
+		t.Error("replica HasRole() after Create = false, want true")
+	}
+
+	if err := s.Delete(ctx, "alice", role.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if grants, _ := replica.GetRoleGrants(ctx, role.ID); len(grants) != 0 {
+		t.Errorf("replica GetRoleGrants() after Delete = %d grants, want 0", len(grants))
+	}
+}
+
+func TestGrantStoreExpiresAndPurges(t *testing.T) {
+	ctx := context.Background()
+	s, inner, _, mr := setupGrantStore(t)
+
+	s.GetUserGrants(ctx, "alice")
+	s.GetUserGrants(ctx, "alice")
+	mr.FastForward(2 * time.Minute)
+	s.GetUserGrants(ctx, "alice")
+	if got := inner.reads.Load(); got != 2 {
+		t.Errorf("wrapped store reads = %d, want 2", got)
+	}
+
+	s.GetUserGrants(ctx, "bob")
+	if err := s.Invalidate(ctx, "alice"); err != nil {
+		t.Fatalf("Invalidate() error = %v", err)
+	}
+	if keys := mr.Keys(); len(keys) != 1 {
+		t.Errorf("keys after Invalidate = %v, want bob's only", keys)
+	}
+	if err := s.Purge(ctx); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("keys after Purge = %v, want none", keys)
+	}
+}
+
+func TestGrantStoreFallsBackWhenRedisIsDown(t *testing.T) {
+	ctx := context.Background()
+	s, inner, role, mr := setupGrantStore(t)
+	s.Create(ctx, auth.NewGrant("alice", role.ID, "admin"))
+	mr.Close()
+
+	if has, err := s.HasRole(ctx, "alice", "editor"); err != nil || !has {
+		t.Errorf("HasRole() = %v, %v, want true, nil from the wrapped store", has, err)
+	}
+	if err := s.Delete(ctx, "alice", role.ID); err != nil {
+		t.Errorf("Delete() error = %v, want nil", err)
+	}
+	if inner.reads.Load() != 1 {
+		t.Errorf("wrapped store reads = %d, want 1", inner.reads.Load())
+	}
+}
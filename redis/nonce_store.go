@@ -0,0 +1,32 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// NonceStore is an auth.NonceStore keeping each used nonce under its own key
+// until it expires, so a nonce used on one replica is rejected on all.
+type NonceStore struct {
+	client *Client
+}
+
+func NewNonceStore(client *Client) *NonceStore {
+	return &NonceStore{client: client}
+}
+
+// Use records nonce with SET NX. A nonce that has already expired is
+// reported as used, since whatever carried it is no longer valid.
+func (s *NonceStore) Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return false, nil
+	}
+
+	ok, err := s.client.rdb.SetNX(ctx, s.client.Key("nonce", nonce), 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("use nonce: %w", err)
+	}
+	return ok, nil
+}
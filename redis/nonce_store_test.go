@@ -0,0 +1,36 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNonceStoreUse(t *testing.T) {
+	ctx := context.Background()
+	client, mr := newTestClient(t)
+	s := NewNonceStore(client)
+
+	if ok, err := s.Use(ctx, "n1", time.Now().Add(time.Minute)); err != nil || !ok {
+		t.Fatalf("first Use() = %v, %v, want true", ok, err)
+	}
+	if ok, _ := s.Use(ctx, "n1", time.Now().Add(time.Minute)); ok {
+		t.Error("second Use() = true, want false")
+	}
+	if !mr.Exists("test:nonce:n1") {
+		t.Error("nonce key not stored under the client prefix")
+	}
+	if ok, _ := s.Use(ctx, "n2", time.Now().Add(-time.Second)); ok {
+		t.Error("Use() of an expired nonce = true, want false")
+	}
+
+	mr.FastForward(2 * time.Minute)
+	if ok, _ := s.Use(ctx, "n1", time.Now().Add(time.Minute)); !ok {
+		t.Error("Use() after the nonce expired = false, want true")
+	}
+
+	mr.Close()
+	if _, err := s.Use(ctx, "n3", time.Now().Add(time.Minute)); err == nil {
+		t.Error("Use() error = nil, want error for a stopped server")
+	}
+}
@@ -0,0 +1,64 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// countRequest increments the window counter, starting the window on the
+// first request, and returns the count and the milliseconds left in it.
+var countRequest = goredis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {n, ttl}
+`)
+
+// rateLimitTimeout bounds each Allow round trip.
+const rateLimitTimeout = time.Second
+
+// RateLimiter is a fixed-window limiter whose counters live in Redis, so
+// every replica enforces the same limit. It satisfies handler.RateLimiter.
+// When Redis is unavailable requests are allowed and the error is logged,
+// so an outage does not lock everyone out.
+type RateLimiter struct {
+	client *Client
+	limit  int
+	window time.Duration
+	log    log.Logger
+}
+
+// NewRateLimiter creates a limiter that allows limit requests per window for
+// each key. A nil logger discards errors.
+func NewRateLimiter(client *Client, limit int, window time.Duration, logger log.Logger) *RateLimiter {
+	if logger == nil {
+		logger = log.NewNoopLogger()
+	}
+	return &RateLimiter{client: client, limit: limit, window: window, log: logger}
+}
+
+// Allow records a request for key and reports whether it is within the limit.
+// When it is not, the returned duration is the time until the window resets.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), rateLimitTimeout)
+	defer cancel()
+
+	window := strconv.FormatInt(max(l.window.Milliseconds(), 1), 10)
+	res, err := countRequest.Run(ctx, l.client.rdb, []string{l.client.Key("ratelimit", key)}, window).Int64Slice()
+	if err != nil || len(res) != 2 {
+		l.log.Error("Cannot check rate limit, allowing request", "key", key, "error", err)
+		return true, 0
+	}
+
+	if res[0] > int64(l.limit) {
+		return false, time.Duration(res[1]) * time.Millisecond
+	}
+	return true, 0
+}
@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	client, mr := newTestClient(t)
+	l := NewRateLimiter(client, 2, time.Minute, nil)
+
+	for i := range 2 {
+		if ok, _ := l.Allow("203.0.113.9"); !ok {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+
+	mr.FastForward(20 * time.Second)
+	ok, retry := l.Allow("203.0.113.9")
+	if ok {
+		t.Fatal("third request should be rejected")
+	}
+	if retry <= 39*time.Second || retry > 40*time.Second {
+		t.Errorf("retry = %v, want about 40s", retry)
+	}
+
+	if ok, _ := l.Allow("198.51.100.1"); !ok {
+		t.Error("other keys should have their own window")
+	}
+
+	mr.FastForward(41 * time.Second)
+	if ok, _ := l.Allow("203.0.113.9"); !ok {
+		t.Error("request after the window resets should be allowed")
+	}
+}
+
+func TestRateLimiterSharedAcrossInstances(t *testing.T) {
+	client, _ := newTestClient(t)
+	a := NewRateLimiter(client, 1, time.Minute, nil)
+	b := NewRateLimiter(client, 1, time.Minute, nil)
+
+	if ok, _ := a.Allow("k"); !ok {
+		t.Fatal("first request should be allowed")
+	}
+	if ok, _ := b.Allow("k"); ok {
+		t.Error("a second replica should see the shared counter")
+	}
+}
+
+func TestRateLimiterFailsOpen(t *testing.T) {
+	client, mr := newTestClient(t)
+	l := NewRateLimiter(client, 1, time.Minute, nil)
+	mr.Close()
+
+	for range 3 {
+		if ok, _ := l.Allow("k"); !ok {
+			t.Fatal("requests should be allowed while Redis is down")
+		}
+	}
+}
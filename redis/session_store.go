@@ -0,0 +1,168 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// createSession stores the session with its TTL and adds it to the user's
+// index, extending the index TTL to outlive its longest session.
+var createSession = goredis.NewScript(`
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+redis.call('SADD', KEYS[2], ARGV[3])
+if redis.call('PTTL', KEYS[2]) < tonumber(ARGV[2]) then
+	redis.call('PEXPIRE', KEYS[2], ARGV[2])
+end
+return 1
+`)
+
+// SessionStore is an auth.SessionStore keeping each session under its own
+// key until it expires, plus a per-user index of session IDs.
+type SessionStore struct {
+	client *Client
+}
+
+func NewSessionStore(client *Client) *SessionStore {
+	return &SessionStore{client: client}
+}
+
+func (s *SessionStore) sessionKey(id string) string {
+	return s.client.Key("session", id)
+}
+
+func (s *SessionStore) userKey(userID string) string {
+	return s.client.Key("user-sessions", userID)
+}
+
+// Create stores session until its ExpiresAt. Sessions that have already
+// expired are not stored.
+func (s *SessionStore) Create(ctx context.Context, session *auth.Session) error {
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("encode session: %w", err)
+	}
+
+	keys := []string{s.sessionKey(session.ID), s.userKey(session.UserID)}
+	ms := strconv.FormatInt(max(ttl.Milliseconds(), 1), 10)
+	if err := createSession.Run(ctx, s.client.rdb, keys, data, ms, session.ID).Err(); err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	return nil
+}
+
+func (s *SessionStore) Get(ctx context.Context, id string) (*auth.Session, error) {
+	data, err := s.client.rdb.Get(ctx, s.sessionKey(id)).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, auth.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get session: %w", err)
+	}
+	return decodeSession(data)
+}
+
+// ListByUser returns the user's live sessions, oldest first. IDs of expired
+// sessions are pruned from the index as they are found.
+func (s *SessionStore) ListByUser(ctx context.Context, userID string) ([]*auth.Session, error) {
+	ids, err := s.client.rdb.SMembers(ctx, s.userKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+
+	sessions := make([]*auth.Session, 0, len(ids))
+	if len(ids) == 0 {
+		return sessions, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.sessionKey(id)
+	}
+	values, err := s.client.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+
+	var expired []any
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			expired = append(expired, ids[i])
+			continue
+		}
+		session, err := decodeSession([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if len(expired) > 0 {
+		s.client.rdb.SRem(ctx, s.userKey(userID), expired...)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+func (s *SessionStore) Delete(ctx context.Context, id string) error {
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.rdb.TxPipelined(ctx, func(p goredis.Pipeliner) error {
+		p.Del(ctx, s.sessionKey(id))
+		p.SRem(ctx, s.userKey(session.UserID), id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return nil
+}
+
+func (s *SessionStore) DeleteByUser(ctx context.Context, userID string) error {
+	ids, err := s.client.rdb.SMembers(ctx, s.userKey(userID)).Result()
+	if err != nil {
+		return fmt.Errorf("delete sessions: %w", err)
+	}
+
+	keys := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		keys = append(keys, s.sessionKey(id))
+	}
+	keys = append(keys, s.userKey(userID))
+	if err := s.client.rdb.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("delete sessions: %w", err)
+	}
+	return nil
+}
+
+func (s *SessionStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx)
+}
+
+func decodeSession(data []byte) (*auth.Session, error) {
+	var session auth.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("decode session: %w", err)
+	}
+	return &session, nil
+}
+
+var _ auth.SessionStore = (*SessionStore)(nil)
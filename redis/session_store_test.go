@@ -0,0 +1,97 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/auth"
+)
+
+func TestSessionStore(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	store := NewSessionStore(client)
+
+	first := auth.NewSession("alice", time.Hour)
+	second := auth.NewSession("alice", time.Hour)
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+	other := auth.NewSession("bob", time.Hour)
+	for _, s := range []*auth.Session{second, first, other} {
+		if err := store.Create(ctx, s); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	got, err := store.Get(ctx, first.ID)
+	if err != nil || got.UserID != "alice" || !got.ExpiresAt.Equal(first.ExpiresAt) {
+		t.Fatalf("Get() = %+v, %v, want alice's session", got, err)
+	}
+
+	sessions, err := store.ListByUser(ctx, "alice")
+	if err != nil || len(sessions) != 2 || sessions[0].ID != first.ID {
+		t.Errorf("ListByUser() = %v, %v, want first then second", sessions, err)
+	}
+
+	if err := store.Delete(ctx, first.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, first.ID); !errors.Is(err, auth.ErrSessionNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrSessionNotFound", err)
+	}
+	if err := store.Delete(ctx, first.ID); !errors.Is(err, auth.ErrSessionNotFound) {
+		t.Errorf("Delete() twice error = %v, want ErrSessionNotFound", err)
+	}
+
+	if err := store.DeleteByUser(ctx, "alice"); err != nil {
+		t.Fatalf("DeleteByUser() error = %v", err)
+	}
+	if sessions, _ := store.ListByUser(ctx, "alice"); len(sessions) != 0 {
+		t.Errorf("ListByUser() after DeleteByUser = %d sessions, want 0", len(sessions))
+	}
+	if _, err := store.Get(ctx, other.ID); err != nil {
+		t.Errorf("DeleteByUser() removed another user's session: %v", err)
+	}
+}
+
+func TestSessionStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	client, mr := newTestClient(t)
+	store := NewSessionStore(client)
+
+	short := auth.NewSession("alice", time.Minute)
+	long := auth.NewSession("alice", time.Hour)
+	store.Create(ctx, short)
+	store.Create(ctx, long)
+
+	if ttl := mr.TTL(store.userKey("alice")); ttl < 59*time.Minute {
+		t.Errorf("user index TTL = %v, want it to outlive the longest session", ttl)
+	}
+
+	mr.FastForward(2 * time.Minute)
+	if _, err := store.Get(ctx, short.ID); !errors.Is(err, auth.ErrSessionNotFound) {
+		t.Errorf("Get() expired session error = %v, want ErrSessionNotFound", err)
+	}
+	sessions, _ := store.ListByUser(ctx, "alice")
+	if len(sessions) != 1 || sessions[0].ID != long.ID {
+		t.Errorf("ListByUser() = %v, want only the live session", sessions)
+	}
+	if members, _ := mr.Members(store.userKey("alice")); len(members) != 1 {
+		t.Errorf("user index = %v, want the expired ID pruned", members)
+	}
+}
+
+func TestSessionStoreSkipsExpired(t *testing.T) {
+	ctx := context.Background()
+	client, mr := newTestClient(t)
+	store := NewSessionStore(client)
+
+	session := auth.NewSession("alice", -time.Minute)
+	if err := store.Create(ctx, session); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("Create() stored %v for an expired session", keys)
+	}
+}
@@ -0,0 +1,91 @@
+// Package spiffe gives workloads their SPIFFE identity.
+//
+// A Source fetches the X.509 SVID of the workload and the trust bundle of
+// its trust domain from a SPIFFE Workload API, such as the one a SPIRE agent
+// serves, and keeps them rotated. Servers present the SVID and verify the
+// SVIDs of their peers with Source.ServerTLSConfig; app.ServeTLS does it
+// with server.tls.spiffe, and middleware.ClientCert maps peer IDs to
+// callers.
+package spiffe
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrInvalidID = errors.New("invalid spiffe id")
+	ErrNoSVID    = errors.New("no svid available")
+)
+
+// ID is a SPIFFE ID, spiffe://<trust domain><path>.
+type ID struct {
+	TrustDomain string
+	Path        string
+}
+
+// ParseID parses s as a SPIFFE ID: the spiffe scheme, a lowercase trust
+// domain without port or user info, and a path of non-empty segments other
+// than "." and "..", without query or fragment.
+func ParseID(s string) (ID, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return ID{}, fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+	return idFromURL(u)
+}
+
+func idFromURL(u *url.URL) (ID, error) {
+	switch {
+	case u.Scheme != "spiffe":
+		return ID{}, fmt.Errorf("%w: scheme must be spiffe", ErrInvalidID)
+	case u.Opaque != "" || u.User != nil || u.Port() != "" || u.RawQuery != "" || u.Fragment != "":
+		return ID{}, fmt.Errorf("%w: %s", ErrInvalidID, u)
+	case !ValidTrustDomain(u.Host):
+		return ID{}, fmt.Errorf("%w: trust domain %q", ErrInvalidID, u.Host)
+	}
+
+	if u.Path != "" {
+		for _, seg := range strings.Split(u.Path[1:], "/") {
+			if seg == "" || seg == "." || seg == ".." {
+				return ID{}, fmt.Errorf("%w: path %q", ErrInvalidID, u.Path)
+			}
+		}
+	}
+	return ID{TrustDomain: u.Host, Path: u.Path}, nil
+}
+
+// ValidTrustDomain reports whether name is a trust domain: lowercase
+// letters, digits, dots, dashes and underscores.
+func ValidTrustDomain(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '.' && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func (id ID) String() string {
+	return "spiffe://" + id.TrustDomain + id.Path
+}
+
+// MemberOf reports whether id belongs to trustDomain.
+func (id ID) MemberOf(trustDomain string) bool {
+	return id.TrustDomain == trustDomain
+}
+
+// IDFromCert returns the SPIFFE ID of an X.509 SVID, which carries exactly
+// one URI SAN.
+func IDFromCert(cert *x509.Certificate) (ID, error) {
+	if len(cert.URIs) != 1 {
+		return ID{}, fmt.Errorf("%w: svid must have exactly one uri san, has %d", ErrInvalidID, len(cert.URIs))
+	}
+	return idFromURL(cert.URIs[0])
+}
@@ -0,0 +1,62 @@
+package spiffe
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ID
+		wantErr bool
+	}{
+		{in: "spiffe://example.org/ns/jobs/sa/worker", want: ID{TrustDomain: "example.org", Path: "/ns/jobs/sa/worker"}},
+		{in: "spiffe://example.org", want: ID{TrustDomain: "example.org"}},
+		{in: "https://example.org/worker", wantErr: true},
+		{in: "spiffe://Example.org/worker", wantErr: true},
+		{in: "spiffe://example.org:8443/worker", wantErr: true},
+		{in: "spiffe://user@example.org/worker", wantErr: true},
+		{in: "spiffe://example.org/worker?x=1", wantErr: true},
+		{in: "spiffe://example.org/jobs//worker", wantErr: true},
+		{in: "spiffe://example.org/jobs/../worker", wantErr: true},
+		{in: "spiffe://example.org/", wantErr: true},
+		{in: "spiffe:///worker", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseID(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidID) {
+					t.Errorf("ParseID() error = %v, want ErrInvalidID", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseID() = %+v, %v, want %+v", got, err, tt.want)
+			}
+			if got.String() != tt.in {
+				t.Errorf("String() = %q, want %q", got.String(), tt.in)
+			}
+		})
+	}
+}
+
+func TestIDFromCert(t *testing.T) {
+	worker, _ := url.Parse("spiffe://example.org/worker")
+	other, _ := url.Parse("spiffe://example.org/other")
+
+	id, err := IDFromCert(&x509.Certificate{URIs: []*url.URL{worker}})
+	if err != nil || !id.MemberOf("example.org") || id.MemberOf("example.com") {
+		t.Errorf("IDFromCert() = %+v, %v", id, err)
+	}
+
+	for name, uris := range map[string][]*url.URL{"no uri": nil, "two uris": {worker, other}} {
+		if _, err := IDFromCert(&x509.Certificate{URIs: uris}); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("IDFromCert() with %s error = %v, want ErrInvalidID", name, err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package spiffe
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// fetchX509SVID is the Workload API method streaming the X.509 SVIDs of the
+// workload.
+const fetchX509SVID = "/SpiffeWorkloadAPI/FetchX509SVID"
+
+// rawCodec passes messages through as encoded protobuf, which spares
+// generating code for the few Workload API messages read.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("raw codec cannot marshal %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("raw codec cannot unmarshal into %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
+
+// x509SVIDResponse field numbers, as in workload.proto.
+const (
+	responseSVIDs = 1
+
+	svidID     = 1
+	svidCerts  = 2
+	svidKey    = 3
+	svidBundle = 4
+)
+
+// parseX509SVIDResponse returns the first, default, SVID of an encoded
+// X509SVIDResponse.
+func parseX509SVIDResponse(msg []byte) (*SVID, error) {
+	var first []byte
+	err := eachField(msg, func(num protowire.Number, value []byte) {
+		if num == responseSVIDs && first == nil {
+			first = value
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if first == nil {
+		return nil, ErrNoSVID
+	}
+
+	var id string
+	var certs, key, bundle []byte
+	err = eachField(first, func(num protowire.Number, value []byte) {
+		switch num {
+		case svidID:
+			id = string(value)
+		case svidCerts:
+			certs = value
+		case svidKey:
+			key = value
+		case svidBundle:
+			bundle = value
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newSVID(id, certs, key, bundle)
+}
+
+// eachField calls fn with the number and contents of every length-delimited
+// field of msg, skipping the others.
+func eachField(msg []byte, fn func(num protowire.Number, value []byte)) error {
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return fmt.Errorf("invalid workload api message: %w", protowire.ParseError(n))
+		}
+		msg = msg[n:]
+
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, msg)
+			if n < 0 {
+				return fmt.Errorf("invalid workload api message: %w", protowire.ParseError(n))
+			}
+			msg = msg[n:]
+			continue
+		}
+
+		value, n := protowire.ConsumeBytes(msg)
+		if n < 0 {
+			return fmt.Errorf("invalid workload api message: %w", protowire.ParseError(n))
+		}
+		fn(num, value)
+		msg = msg[n:]
+	}
+	return nil
+}
+
+// newSVID builds an SVID from the DER encoded certificate chain, PKCS #8
+// private key and trust bundle of the Workload API.
+func newSVID(id string, certsDER, keyDER, bundleDER []byte) (*SVID, error) {
+	certs, err := x509.ParseCertificates(certsDER)
+	if err != nil || len(certs) == 0 {
+		return nil, fmt.Errorf("invalid svid certificates: %v", err)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(keyDER)
+	if err != nil {
+		return nil, fmt.Errorf("invalid svid key: %w", err)
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("invalid svid key: %T cannot sign", parsed)
+	}
+	bundle, err := x509.ParseCertificates(bundleDER)
+	if err != nil || len(bundle) == 0 {
+		return nil, fmt.Errorf("invalid svid trust bundle: %v", err)
+	}
+
+	svidID, err := IDFromCert(certs[0])
+	if err != nil {
+		return nil, err
+	}
+	if id != "" && id != svidID.String() {
+		return nil, fmt.Errorf("%w: svid is %s, not %s", ErrInvalidID, svidID, id)
+	}
+
+	return &SVID{ID: svidID, Certificates: certs, PrivateKey: key, Bundle: bundle}, nil
+}
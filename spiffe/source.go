@@ -0,0 +1,245 @@
+package spiffe
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// EndpointSocketEnv names the environment variable holding the Workload
+// API address when SourceConfig.Addr is empty.
+const EndpointSocketEnv = "SPIFFE_ENDPOINT_SOCKET"
+
+// SVID is an X.509 SVID of the workload with its private key and the trust
+// bundle of its trust domain.
+type SVID struct {
+	ID           ID
+	Certificates []*x509.Certificate
+	PrivateKey   crypto.Signer
+	Bundle       []*x509.Certificate
+}
+
+// TLSCertificate returns the SVID as a certificate to present in TLS.
+func (s *SVID) TLSCertificate() *tls.Certificate {
+	cert := &tls.Certificate{PrivateKey: s.PrivateKey, Leaf: s.Certificates[0]}
+	for _, c := range s.Certificates {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+	return cert
+}
+
+// BundlePool returns the trust bundle as a pool to verify peer SVIDs with.
+func (s *SVID) BundlePool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, c := range s.Bundle {
+		pool.AddCert(c)
+	}
+	return pool
+}
+
+// SourceConfig configures a Source.
+type SourceConfig struct {
+	// Addr of the Workload API, "unix:///path/to/agent.sock" or
+	// "tcp://host:port". The SPIFFE_ENDPOINT_SOCKET environment variable
+	// when empty.
+	Addr string
+	// StartTimeout bounds how long Start waits for the first SVID.
+	// Default 30s.
+	StartTimeout time.Duration
+	// RetryInterval is the delay before reconnecting after the Workload API
+	// stream fails. Default 5s.
+	RetryInterval time.Duration
+	Logger        log.Logger
+}
+
+// Source holds the current X.509 SVID of the workload, streamed from the
+// Workload API, which pushes a new one before the current one expires. It
+// implements Start and Stop for the app lifecycle.
+type Source struct {
+	cfg SourceConfig
+
+	mu   sync.RWMutex
+	svid *SVID
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewSource creates a source for cfg. Nothing is fetched until Start.
+func NewSource(cfg SourceConfig) *Source {
+	if cfg.Addr == "" {
+		cfg.Addr = os.Getenv(EndpointSocketEnv)
+	}
+	if cfg.StartTimeout <= 0 {
+		cfg.StartTimeout = 30 * time.Second
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = 5 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNoopLogger()
+	}
+	return &Source{cfg: cfg}
+}
+
+// Start connects to the Workload API and waits for the first SVID. Updates
+// are then received in the background until Stop, reconnecting when the
+// stream fails.
+func (s *Source) Start(ctx context.Context) error {
+	target, err := grpcTarget(s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("cannot connect to workload api: %w", err)
+	}
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	ready := make(chan struct{})
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		defer conn.Close()
+		s.watch(watchCtx, conn, ready)
+	}()
+
+	timer := time.NewTimer(s.cfg.StartTimeout)
+	defer timer.Stop()
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.Stop(context.Background())
+		return ctx.Err()
+	case <-timer.C:
+		s.Stop(context.Background())
+		return fmt.Errorf("%w: workload api at %s sent none within %s", ErrNoSVID, s.cfg.Addr, s.cfg.StartTimeout)
+	}
+}
+
+// Stop ends the background updates.
+func (s *Source) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SVID returns the current SVID, or ErrNoSVID before the first is received.
+func (s *Source) SVID() (*SVID, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.svid == nil {
+		return nil, ErrNoSVID
+	}
+	return s.svid, nil
+}
+
+// ServerTLSConfig returns a TLS configuration presenting the current SVID
+// and verifying peer SVIDs against the current trust bundle, as clientAuth
+// asks. Authorize peers by their ID with middleware.ClientCert.
+func (s *Source) ServerTLSConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			svid, err := s.SVID()
+			if err != nil {
+				return nil, err
+			}
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*svid.TLSCertificate()},
+				ClientAuth:   clientAuth,
+				ClientCAs:    svid.BundlePool(),
+				NextProtos:   []string{"h2", "http/1.1"},
+			}, nil
+		},
+	}
+}
+
+// watch receives SVIDs until ctx is done, closing ready on the first.
+func (s *Source) watch(ctx context.Context, conn *grpc.ClientConn, ready chan struct{}) {
+	var once sync.Once
+	for {
+		err := s.stream(ctx, conn, func(svid *SVID) {
+			s.mu.Lock()
+			s.svid = svid
+			s.mu.Unlock()
+			once.Do(func() { close(ready) })
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		s.cfg.Logger.Error("Workload API stream failed, retrying", "error", err, "retry_in", s.cfg.RetryInterval)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.cfg.RetryInterval):
+		}
+	}
+}
+
+// stream reads FetchX509SVID responses, passing each SVID to update, until
+// the stream fails.
+func (s *Source) stream(ctx context.Context, conn *grpc.ClientConn, update func(*SVID)) error {
+	ctx = metadata.AppendToOutgoingContext(ctx, "workload.spiffe.io", "true")
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, fetchX509SVID, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return err
+	}
+	req := []byte{}
+	if err := stream.SendMsg(&req); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	for {
+		var msg []byte
+		if err := stream.RecvMsg(&msg); err != nil {
+			return err
+		}
+		svid, err := parseX509SVIDResponse(msg)
+		if err != nil {
+			s.cfg.Logger.Error("Ignoring invalid SVID from the Workload API", "error", err)
+			continue
+		}
+		update(svid)
+	}
+}
+
+// grpcTarget turns a Workload API address into a gRPC target.
+func grpcTarget(addr string) (string, error) {
+	switch {
+	case addr == "":
+		return "", errors.New("workload api address is required, set " + EndpointSocketEnv)
+	case strings.HasPrefix(addr, "unix://"):
+		return addr, nil
+	case strings.HasPrefix(addr, "tcp://"):
+		return "passthrough:///" + strings.TrimPrefix(addr, "tcp://"), nil
+	default:
+		return "", fmt.Errorf("workload api address must be unix:// or tcp://, got %q", addr)
+	}
+}
@@ -0,0 +1,240 @@
+package spiffe
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// testCA issues SVIDs for the trust domain example.org.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "example.org"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("cannot create CA: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue returns the DER certificate and PKCS #8 key of an SVID for id.
+func (ca *testCA) issue(t *testing.T, id string) (certDER, keyDER []byte) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	uri, _ := url.Parse(id)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{uri},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("cannot issue svid: %v", err)
+	}
+	keyDER, _ = x509.MarshalPKCS8PrivateKey(key)
+	return certDER, keyDER
+}
+
+// response encodes an X509SVIDResponse with one SVID for id.
+func (ca *testCA) response(t *testing.T, id string) []byte {
+	certDER, keyDER := ca.issue(t, id)
+	var svid []byte
+	svid = protowire.AppendTag(svid, svidID, protowire.BytesType)
+	svid = protowire.AppendString(svid, id)
+	svid = protowire.AppendTag(svid, svidCerts, protowire.BytesType)
+	svid = protowire.AppendBytes(svid, certDER)
+	svid = protowire.AppendTag(svid, svidKey, protowire.BytesType)
+	svid = protowire.AppendBytes(svid, keyDER)
+	svid = protowire.AppendTag(svid, svidBundle, protowire.BytesType)
+	svid = protowire.AppendBytes(svid, ca.cert.Raw)
+	svid = protowire.AppendTag(svid, 5, protowire.BytesType)
+	svid = protowire.AppendString(svid, "internal")
+
+	var resp []byte
+	resp = protowire.AppendTag(resp, responseSVIDs, protowire.BytesType)
+	return protowire.AppendBytes(resp, svid)
+}
+
+// startWorkloadAPI serves a fake Workload API on a unix socket, streaming
+// responses as they are sent on the returned channel.
+func startWorkloadAPI(t *testing.T) (addr string, responses chan<- []byte) {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+
+	ch := make(chan []byte, 4)
+	srv := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		if method != fetchX509SVID || len(md.Get("workload.spiffe.io")) == 0 {
+			return io.EOF
+		}
+		var req []byte
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+		for {
+			select {
+			case <-stream.Context().Done():
+				return nil
+			case resp := <-ch:
+				if err := stream.SendMsg(&resp); err != nil {
+					return err
+				}
+			}
+		}
+	}))
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	return "unix://" + sock, ch
+}
+
+func TestSource(t *testing.T) {
+	ca := newTestCA(t)
+	addr, responses := startWorkloadAPI(t)
+	responses <- ca.response(t, "spiffe://example.org/web")
+
+	source := NewSource(SourceConfig{Addr: addr, StartTimeout: 5 * time.Second})
+	if _, err := source.SVID(); err != ErrNoSVID {
+		t.Errorf("SVID() before Start error = %v, want ErrNoSVID", err)
+	}
+	if err := source.Start(t.Context()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { source.Stop(context.Background()) })
+
+	svid, err := source.SVID()
+	if err != nil || svid.ID.String() != "spiffe://example.org/web" {
+		t.Fatalf("SVID() = %+v, %v", svid, err)
+	}
+
+	responses <- ca.response(t, "spiffe://example.org/web-rotated")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		svid, _ = source.SVID()
+		if svid.ID.Path == "/web-rotated" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("SVID() = %s, want the rotated one", svid.ID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSourceServerTLSConfig(t *testing.T) {
+	ca := newTestCA(t)
+	addr, responses := startWorkloadAPI(t)
+	responses <- ca.response(t, "spiffe://example.org/web")
+
+	source := NewSource(SourceConfig{Addr: addr, StartTimeout: 5 * time.Second})
+	if err := source.Start(t.Context()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { source.Stop(context.Background()) })
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", source.ServerTLSConfig(tls.RequireAndVerifyClientCert))
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	var peer string
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := IDFromCert(r.TLS.VerifiedChains[0][0])
+		peer = id.String()
+	})}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(certs ...tls.Certificate) error {
+		client := &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs},
+		}}
+		resp, err := client.Get("https://" + ln.Addr().String() + "/")
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	certDER, keyDER := ca.issue(t, "spiffe://example.org/worker")
+	key, _ := x509.ParsePKCS8PrivateKey(keyDER)
+	if err := get(tls.Certificate{Certificate: [][]byte{certDER}, PrivateKey: key}); err != nil {
+		t.Fatalf("GET with a client svid error = %v", err)
+	}
+	if peer != "spiffe://example.org/worker" {
+		t.Errorf("peer = %q, want spiffe://example.org/worker", peer)
+	}
+
+	other := newTestCA(t)
+	certDER, keyDER = other.issue(t, "spiffe://other.org/worker")
+	key, _ = x509.ParsePKCS8PrivateKey(keyDER)
+	if err := get(tls.Certificate{Certificate: [][]byte{certDER}, PrivateKey: key}); err == nil {
+		t.Error("GET with an svid of another trust domain succeeded")
+	}
+}
+
+func TestSourceStartTimeout(t *testing.T) {
+	addr, _ := startWorkloadAPI(t)
+	source := NewSource(SourceConfig{Addr: addr, StartTimeout: 50 * time.Millisecond})
+	if err := source.Start(t.Context()); err == nil {
+		t.Error("Start() without an svid succeeded")
+	}
+}
+
+func TestGRPCTarget(t *testing.T) {
+	tests := map[string]string{
+		"unix:///run/spire/agent.sock": "unix:///run/spire/agent.sock",
+		"tcp://127.0.0.1:8081":         "passthrough:///127.0.0.1:8081",
+	}
+	for addr, want := range tests {
+		if got, err := grpcTarget(addr); err != nil || got != want {
+			t.Errorf("grpcTarget(%q) = %q, %v, want %q", addr, got, err, want)
+		}
+	}
+	for _, addr := range []string{"", "/run/spire/agent.sock"} {
+		if _, err := grpcTarget(addr); err == nil {
+			t.Errorf("grpcTarget(%q) succeeded", addr)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/pubsub"
+	"github.com/go-chi/chi/v5"
+)
+
+// event is an envelope encoded for the wire.
+type event struct {
+	id    string
+	topic string
+	env   pubsub.Envelope
+	data  []byte
+}
+
+func newEvent(env pubsub.Envelope) (event, error) {
+	data, err := httpx.Marshal(env.Payload)
+	if err != nil {
+		return event{}, err
+	}
+	return event{id: env.ID, topic: env.Topic, env: env, data: data}, nil
+}
+
+func (e event) encode() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "id: %s\nevent: %s\n", oneLine(e.id), oneLine(e.topic))
+	for _, line := range bytes.Split(e.data, []byte("\n")) {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return b.Bytes()
+}
+
+// oneLine keeps field values from breaking out of their line.
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// client is a connected stream.
+type client struct {
+	ctx    context.Context
+	topics []string
+	send   chan event
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func (c *client) wants(s *Stream, e event) bool {
+	if len(c.topics) > 0 && !slices.Contains(c.topics, e.topic) {
+		return false
+	}
+	return s.filter == nil || s.filter(c.ctx, e.env)
+}
+
+// offer queues e for c, disconnecting c when it has fallen too far behind.
+func (c *client) offer(s *Stream, e event) {
+	if !c.wants(s, e) {
+		return
+	}
+	select {
+	case c.send <- e:
+	case <-c.done:
+	default:
+		s.log.Debugf("sse client too slow, disconnecting")
+		c.close()
+	}
+}
+
+func (c *client) close() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// RegisterRoutes serves the stream at its path, behind middleware.Bearer
+// when a validator is set.
+func (s *Stream) RegisterRoutes(r chi.Router) {
+	if s.validator != nil {
+		r.With(middleware.Bearer(s.validator)).Get(s.path, s.ServeHTTP)
+		return
+	}
+	r.Get(s.path, s.ServeHTTP)
+}
+
+// ServeHTTP streams events until the client disconnects or the stream is
+// drained. The topic query parameter, repeated or comma-separated, limits
+// the topics followed; a Last-Event-ID header, or lastEventId parameter for
+// clients that cannot set headers, resumes after that event.
+func (s *Stream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	topics, err := s.parseTopics(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	c := &client{
+		ctx:    r.Context(),
+		topics: topics,
+		send:   make(chan event, s.sendBuffer),
+		done:   make(chan struct{}),
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("lastEventId")
+	}
+	replay, ok := s.subscribe(c, lastID)
+	if !ok {
+		http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.unsubscribe(c)
+
+	// Streams outlive the server's write timeout.
+	rc.SetWriteDeadline(time.Time{})
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", s.retry.Milliseconds())
+	for _, e := range replay {
+		if c.wants(s, e) {
+			w.Write(e.encode())
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		s.log.Errorf("sse stream cannot be flushed: %v", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(s.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-c.done:
+			return
+		case e := <-c.send:
+			_, err = w.Write(e.encode())
+		case <-heartbeat.C:
+			_, err = w.Write([]byte(": heartbeat\n\n"))
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Stream) parseTopics(r *http.Request) ([]string, error) {
+	var topics []string
+	for _, v := range r.URL.Query()["topic"] {
+		for _, topic := range strings.Split(v, ",") {
+			topic = strings.TrimSpace(topic)
+			if topic == "" {
+				continue
+			}
+			if !slices.Contains(s.topics, topic) {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownTopic, topic)
+			}
+			topics = append(topics, topic)
+		}
+	}
+	return topics, nil
+}
@@ -0,0 +1,220 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/pubsub"
+	"github.com/go-chi/chi/v5"
+)
+
+type tokenValidator map[string]string
+
+func (v tokenValidator) ValidateToken(token string) (string, string, error) {
+	if user, ok := v[token]; ok {
+		return user, "session", nil
+	}
+	return "", "", errors.New("invalid token")
+}
+
+func newTestServer(t *testing.T, s *Stream) *httptest.Server {
+	t.Helper()
+
+	r := chi.NewRouter()
+	s.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	t.Cleanup(func() {
+		s.Drain()
+		srv.Close()
+	})
+	return srv
+}
+
+type eventReader struct {
+	t    *testing.T
+	resp *http.Response
+	scan *bufio.Scanner
+}
+
+func connect(t *testing.T, srv *httptest.Server, query string, headers map[string]string) *eventReader {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+DefaultPath+query, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return &eventReader{t: t, resp: resp, scan: bufio.NewScanner(resp.Body)}
+}
+
+// next returns the next block of lines, up to the blank line ending it.
+func (r *eventReader) next() string {
+	r.t.Helper()
+
+	lines := make(chan string, 1)
+	go func() {
+		var block []string
+		for r.scan.Scan() {
+			if r.scan.Text() == "" {
+				break
+			}
+			block = append(block, r.scan.Text())
+		}
+		lines <- strings.Join(block, "\n")
+	}()
+
+	select {
+	case block := <-lines:
+		return block
+	case <-time.After(2 * time.Second):
+		r.t.Fatal("no event before deadline")
+		return ""
+	}
+}
+
+func waitForClients(t *testing.T, s *Stream, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for s.Count() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Count() = %d, want %d", s.Count(), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	s := New([]string{"tickets.created", "tickets.closed"}, log.NewNoopLogger(), WithRetry(time.Second))
+	srv := newTestServer(t, s)
+
+	r := connect(t, srv, "?topic=tickets.closed", nil)
+	if r.resp.StatusCode != http.StatusOK || r.resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("response = %d %s", r.resp.StatusCode, r.resp.Header.Get("Content-Type"))
+	}
+	if got := r.resp.Header.Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache", got)
+	}
+	if got := r.next(); got != "retry: 1000" {
+		t.Errorf("first block = %q, want the retry hint", got)
+	}
+	waitForClients(t, s, 1)
+
+	ctx := context.Background()
+	s.Deliver(ctx, envelope("tickets.created", "1", nil))
+	s.Deliver(ctx, envelope("tickets.closed", "2", map[string]string{"id": "t1"}))
+
+	want := "id: 2\nevent: tickets.closed\ndata: {\"id\":\"t1\"}"
+	if got := r.next(); got != want {
+		t.Errorf("event = %q, want %q", got, want)
+	}
+}
+
+func TestServeHTTPResume(t *testing.T) {
+	s := New([]string{"a", "b"}, log.NewNoopLogger())
+	srv := newTestServer(t, s)
+	for _, id := range []string{"1", "2", "3"} {
+		s.Deliver(context.Background(), envelope("a", id, id))
+	}
+	s.Deliver(context.Background(), envelope("b", "4", "4"))
+
+	tests := []struct {
+		name    string
+		query   string
+		headers map[string]string
+	}{
+		{name: "header", query: "?topic=a", headers: map[string]string{"Last-Event-ID": "1"}},
+		{name: "query parameter", query: "?topic=a&lastEventId=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := connect(t, srv, tt.query, tt.headers)
+			r.next()
+			for _, id := range []string{"2", "3"} {
+				if got := r.next(); !strings.HasPrefix(got, "id: "+id+"\n") {
+					t.Errorf("replayed event = %q, want id %s", got, id)
+				}
+			}
+			s.Deliver(context.Background(), envelope("a", "5", "5"))
+			if got := r.next(); !strings.HasPrefix(got, "id: 5\n") {
+				t.Errorf("live event = %q, want id 5 after the replay", got)
+			}
+		})
+	}
+}
+
+func TestServeHTTPHeartbeat(t *testing.T) {
+	s := New([]string{"a"}, log.NewNoopLogger(), WithHeartbeat(10*time.Millisecond))
+	r := connect(t, newTestServer(t, s), "", nil)
+	r.next()
+
+	if got := r.next(); got != ": heartbeat" {
+		t.Errorf("idle block = %q, want a heartbeat", got)
+	}
+}
+
+func TestServeHTTPUnknownTopic(t *testing.T) {
+	s := New([]string{"a"}, log.NewNoopLogger())
+	r := connect(t, newTestServer(t, s), "?topic=a,secret", nil)
+	if r.resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", r.resp.StatusCode)
+	}
+}
+
+func TestServeHTTPAuthentication(t *testing.T) {
+	validator := tokenValidator{"ann-token": "ann"}
+	var user string
+	s := New([]string{"a"}, log.NewNoopLogger(), WithValidator(validator), WithFilter(func(ctx context.Context, _ pubsub.Envelope) bool {
+		user = middleware.GetUserID(ctx)
+		return true
+	}))
+	srv := newTestServer(t, s)
+
+	if r := connect(t, srv, "", nil); r.resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status without token = %d, want 401", r.resp.StatusCode)
+	}
+	if r := connect(t, srv, "", map[string]string{"Authorization": "Bearer wrong"}); r.resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status with an invalid token = %d, want 401", r.resp.StatusCode)
+	}
+
+	r := connect(t, srv, "", map[string]string{"Authorization": "Bearer ann-token"})
+	if r.resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", r.resp.StatusCode)
+	}
+	r.next()
+	waitForClients(t, s, 1)
+	s.Deliver(context.Background(), envelope("a", "1", nil))
+	r.next()
+	if user != "ann" {
+		t.Errorf("filter saw user %q, want ann", user)
+	}
+}
+
+func TestServeHTTPDrain(t *testing.T) {
+	s := New([]string{"a"}, log.NewNoopLogger())
+	srv := newTestServer(t, s)
+	r := connect(t, srv, "", nil)
+	r.next()
+	waitForClients(t, s, 1)
+
+	s.Drain()
+	if r.scan.Scan() {
+		t.Errorf("read %q after drain, want the stream to end", r.scan.Text())
+	}
+	if r := connect(t, srv, "", nil); r.resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status while draining = %d, want 503", r.resp.StatusCode)
+	}
+}
@@ -0,0 +1,269 @@
+// Package sse streams domain events to HTTP clients as Server-Sent Events.
+//
+// A Stream subscribes to a set of topics through the app's events consumer
+// and forwards every envelope to the clients connected to its endpoint,
+// GET /events/stream by default. Clients pick topics with ?topic=a,b, are
+// sent a heartbeat comment to keep proxies from closing idle streams, and
+// resume after a reconnect from the Last-Event-ID they last saw, replayed
+// from a bounded in-memory history.
+//
+// Each event is written as
+//
+//	id: <envelope ID>
+//	event: <topic>
+//	data: <payload as JSON>
+package sse
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/app"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/pubsub"
+	"github.com/google/uuid"
+)
+
+// Defaults used unless overridden by options.
+const (
+	DefaultPath       = "/events/stream"
+	DefaultHeartbeat  = 15 * time.Second
+	DefaultRetry      = 3 * time.Second
+	DefaultHistory    = 256
+	DefaultSendBuffer = 64
+)
+
+// ErrUnknownTopic is returned for events on topics the stream does not serve.
+var ErrUnknownTopic = errors.New("unknown topic")
+
+// Filter decides whether the client whose request context is ctx may see
+// env, e.g. to only stream a user's own events.
+type Filter func(ctx context.Context, env pubsub.Envelope) bool
+
+// Stream forwards events on its topics to connected clients.
+type Stream struct {
+	log        log.Logger
+	topics     []string
+	path       string
+	validator  middleware.SessionValidator
+	filter     Filter
+	heartbeat  time.Duration
+	retry      time.Duration
+	historyLen int
+	sendBuffer int
+
+	mu       sync.RWMutex
+	history  []event
+	clients  map[*client]struct{}
+	stopped  bool
+	draining bool
+	wg       sync.WaitGroup
+}
+
+// Option configures a Stream.
+type Option func(*Stream)
+
+// WithPath sets the path the stream is served at.
+func WithPath(path string) Option {
+	return func(s *Stream) {
+		if path != "" {
+			s.path = path
+		}
+	}
+}
+
+// WithValidator requires clients to authenticate with a bearer token
+// checked by validator, as middleware.Bearer does.
+func WithValidator(validator middleware.SessionValidator) Option {
+	return func(s *Stream) {
+		s.validator = validator
+	}
+}
+
+// WithFilter only sends clients the events filter accepts.
+func WithFilter(filter Filter) Option {
+	return func(s *Stream) {
+		s.filter = filter
+	}
+}
+
+// WithHeartbeat sets how often idle streams get a heartbeat comment.
+func WithHeartbeat(d time.Duration) Option {
+	return func(s *Stream) {
+		if d > 0 {
+			s.heartbeat = d
+		}
+	}
+}
+
+// WithRetry sets how long clients wait before reconnecting.
+func WithRetry(d time.Duration) Option {
+	return func(s *Stream) {
+		if d > 0 {
+			s.retry = d
+		}
+	}
+}
+
+// WithHistory sets how many recent events are kept for clients resuming
+// with Last-Event-ID. Zero disables replay.
+func WithHistory(n int) Option {
+	return func(s *Stream) {
+		if n >= 0 {
+			s.historyLen = n
+		}
+	}
+}
+
+// WithSendBuffer sets how many events may be queued per client. Clients
+// that fall further behind are disconnected and resume from history.
+func WithSendBuffer(n int) Option {
+	return func(s *Stream) {
+		if n > 0 {
+			s.sendBuffer = n
+		}
+	}
+}
+
+// New creates a stream for topics.
+func New(topics []string, logger log.Logger, opts ...Option) *Stream {
+	s := &Stream{
+		log:        logger,
+		topics:     slices.Clone(topics),
+		path:       DefaultPath,
+		heartbeat:  DefaultHeartbeat,
+		retry:      DefaultRetry,
+		historyLen: DefaultHistory,
+		sendBuffer: DefaultSendBuffer,
+		clients:    make(map[*client]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Topics returns the topics the stream serves.
+func (s *Stream) Topics() []string {
+	return slices.Clone(s.topics)
+}
+
+// RegisterSubscriptions subscribes the stream to its topics, so app.Setup
+// wires it to the events consumer.
+func (s *Stream) RegisterSubscriptions(r app.SubscriptionRouter) {
+	for _, topic := range s.topics {
+		r.Handle(topic, s.Deliver)
+	}
+}
+
+// Deliver forwards env to the clients following its topic. It is a
+// pubsub.Handler and never fails for events on the stream's topics, so
+// slow clients cannot hold up the consumer.
+func (s *Stream) Deliver(ctx context.Context, env pubsub.Envelope) error {
+	if !slices.Contains(s.topics, env.Topic) {
+		return pubsub.Permanent(ErrUnknownTopic)
+	}
+	if env.ID == "" {
+		env.ID = uuid.NewString()
+	}
+	e, err := newEvent(env)
+	if err != nil {
+		s.log.Errorf("cannot encode event %s on %s: %v", env.ID, env.Topic, err)
+		return pubsub.Permanent(err)
+	}
+
+	s.mu.Lock()
+	if s.historyLen > 0 {
+		if len(s.history) == s.historyLen {
+			s.history = slices.Delete(s.history, 0, 1)
+		}
+		s.history = append(s.history, e)
+	}
+	clients := make([]*client, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range clients {
+		c.offer(s, e)
+	}
+	return nil
+}
+
+// Count returns the number of connected clients.
+func (s *Stream) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.clients)
+}
+
+// Drain ends every open stream and refuses new ones, so clients reconnect
+// to another replica while the server shuts down. Pass the stream to
+// app.WithDrain, as http.Server.Shutdown otherwise waits for open streams.
+func (s *Stream) Drain() {
+	s.mu.Lock()
+	s.draining = true
+	clients := make([]*client, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range clients {
+		c.close()
+	}
+}
+
+// Stop drains the stream and waits for open streams to end until ctx is done.
+func (s *Stream) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.Drain()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// subscribe registers c and returns the events after lastID still in
+// history. Nothing is replayed when lastID is unknown or too old.
+func (s *Stream) subscribe(c *client, lastID string) ([]event, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped || s.draining {
+		return nil, false
+	}
+	s.clients[c] = struct{}{}
+	s.wg.Add(1)
+
+	if lastID == "" {
+		return nil, true
+	}
+	i := slices.IndexFunc(s.history, func(e event) bool { return e.id == lastID })
+	if i < 0 {
+		return nil, true
+	}
+	return slices.Clone(s.history[i+1:]), true
+}
+
+func (s *Stream) unsubscribe(c *client) {
+	s.mu.Lock()
+	delete(s.clients, c)
+	s.mu.Unlock()
+	s.wg.Done()
+}
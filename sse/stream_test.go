@@ -0,0 +1,198 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/app"
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/pubsub"
+	"github.com/go-chi/chi/v5"
+)
+
+func envelope(topic, id string, payload any) pubsub.Envelope {
+	env := pubsub.NewEnvelope(topic, payload)
+	env.ID = id
+	return env
+}
+
+func newClient(s *Stream, topics ...string) *client {
+	return &client{
+		ctx:    context.Background(),
+		topics: topics,
+		send:   make(chan event, s.sendBuffer),
+		done:   make(chan struct{}),
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	s := New([]string{"tickets.created", "tickets.closed"}, log.NewNoopLogger())
+	all, created := newClient(s), newClient(s, "tickets.created")
+	s.subscribe(all, "")
+	s.subscribe(created, "")
+
+	ctx := context.Background()
+	if err := s.Deliver(ctx, envelope("tickets.created", "1", map[string]string{"id": "t1"})); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if err := s.Deliver(ctx, envelope("tickets.closed", "2", nil)); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if err := s.Deliver(ctx, envelope("users.created", "3", nil)); !errors.Is(err, ErrUnknownTopic) {
+		t.Errorf("Deliver() on an unknown topic error = %v, want ErrUnknownTopic", err)
+	}
+
+	if len(all.send) != 2 || len(created.send) != 1 {
+		t.Errorf("queued %d and %d events, want 2 and 1", len(all.send), len(created.send))
+	}
+	if e := <-created.send; string(e.data) != `{"id":"t1"}` {
+		t.Errorf("data = %s", e.data)
+	}
+}
+
+func TestDeliverAssignsID(t *testing.T) {
+	s := New([]string{"a"}, log.NewNoopLogger())
+	s.Deliver(context.Background(), pubsub.Envelope{Topic: "a"})
+	if len(s.history) != 1 || s.history[0].id == "" {
+		t.Errorf("history = %+v, want one event with an ID", s.history)
+	}
+}
+
+func TestDeliverUnencodablePayload(t *testing.T) {
+	s := New([]string{"a"}, log.NewNoopLogger())
+	if err := s.Deliver(context.Background(), envelope("a", "1", func() {})); err == nil {
+		t.Error("Deliver() error = nil, want encoding error")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	type userKey struct{}
+	s := New([]string{"a"}, log.NewNoopLogger(), WithFilter(func(ctx context.Context, env pubsub.Envelope) bool {
+		return env.Metadata["user"] == ctx.Value(userKey{})
+	}))
+	ann := newClient(s)
+	ann.ctx = context.WithValue(context.Background(), userKey{}, "ann")
+	s.subscribe(ann, "")
+
+	s.Deliver(context.Background(), envelope("a", "1", nil).WithMetadata("user", "ann"))
+	s.Deliver(context.Background(), envelope("a", "2", nil).WithMetadata("user", "bob"))
+
+	if len(ann.send) != 1 || (<-ann.send).id != "1" {
+		t.Error("filter did not limit events to the client's user")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	s := New([]string{"a"}, log.NewNoopLogger(), WithHistory(3))
+	for _, id := range []string{"1", "2", "3", "4"} {
+		s.Deliver(context.Background(), envelope("a", id, nil))
+	}
+
+	tests := []struct {
+		lastID string
+		want   []string
+	}{
+		{lastID: "", want: nil},
+		{lastID: "2", want: []string{"3", "4"}},
+		{lastID: "4", want: nil},
+		{lastID: "1", want: nil},
+		{lastID: "unknown", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run("after "+tt.lastID, func(t *testing.T) {
+			c := newClient(s)
+			replay, ok := s.subscribe(c, tt.lastID)
+			defer s.unsubscribe(c)
+			if !ok {
+				t.Fatal("subscribe() refused the client")
+			}
+
+			var got []string
+			for _, e := range replay {
+				got = append(got, e.id)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("replay = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithHistoryDisabled(t *testing.T) {
+	s := New([]string{"a"}, log.NewNoopLogger(), WithHistory(0))
+	s.Deliver(context.Background(), envelope("a", "1", nil))
+	if len(s.history) != 0 {
+		t.Errorf("history = %d events, want none", len(s.history))
+	}
+}
+
+func TestSlowClientIsDisconnected(t *testing.T) {
+	s := New([]string{"a"}, log.NewNoopLogger(), WithSendBuffer(1))
+	c := newClient(s)
+	s.subscribe(c, "")
+
+	s.Deliver(context.Background(), envelope("a", "1", nil))
+	s.Deliver(context.Background(), envelope("a", "2", nil))
+
+	select {
+	case <-c.done:
+	default:
+		t.Error("client with a full buffer was not disconnected")
+	}
+}
+
+func TestDrainAndStop(t *testing.T) {
+	s := New([]string{"a"}, log.NewNoopLogger())
+	c := newClient(s)
+	s.subscribe(c, "")
+
+	s.Drain()
+	select {
+	case <-c.done:
+	default:
+		t.Error("Drain() did not end the open stream")
+	}
+	if _, ok := s.subscribe(newClient(s), ""); ok {
+		t.Error("subscribe() accepted a client while draining")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop() with an open stream error = %v, want deadline exceeded", err)
+	}
+
+	s.unsubscribe(c)
+	if err := s.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	broker := pubsub.NewMemoryBroker()
+	consumer := pubsub.NewConsumer(broker, nil, log.NewNoopLogger())
+	s := New([]string{"tickets.created"}, log.NewNoopLogger())
+
+	ctx := context.Background()
+	starts, _, registrars := app.Setup(ctx, chi.NewRouter(), consumer, s)
+	if len(registrars) != 1 {
+		t.Errorf("Setup() found %d route registrars, want the stream", len(registrars))
+	}
+	for _, start := range starts {
+		if err := start(ctx); err != nil {
+			t.Fatalf("start error = %v", err)
+		}
+	}
+
+	broker.Publish(ctx, "tickets.created", envelope("tickets.created", "1", nil))
+	if len(s.history) != 1 {
+		t.Errorf("history = %d events, want the published one", len(s.history))
+	}
+	if got := consumer.Topics(); len(got) != 1 || got[0] != "tickets.created" {
+		t.Errorf("consumer topics = %v", got)
+	}
+}
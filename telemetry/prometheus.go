@@ -0,0 +1,210 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// UnmatchedRoute is the route label used for requests that did not match any route.
+const UnmatchedRoute = "unmatched"
+
+// Prometheus is a Metrics implementation backed by a Prometheus registry.
+// It records HTTP request counts, latency histograms, and in-flight requests,
+// and exposes the registry so services can add their own collectors.
+type Prometheus struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+
+	mu       sync.Mutex
+	counters map[string]*prometheus.CounterVec
+}
+
+// NewPrometheus creates a Prometheus metrics instance with its own registry.
+// Go runtime and process collectors are registered alongside the HTTP metrics.
+func NewPrometheus() *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency in seconds.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests currently being served.",
+		}),
+		counters: make(map[string]*prometheus.CounterVec),
+	}
+
+	p.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		p.requests,
+		p.duration,
+		p.inFlight,
+	)
+
+	return p
+}
+
+var (
+	defaultPrometheus     *Prometheus
+	defaultPrometheusOnce sync.Once
+)
+
+// DefaultPrometheus returns the process-wide Prometheus instance used by app.WithMetrics.
+func DefaultPrometheus() *Prometheus {
+	defaultPrometheusOnce.Do(func() {
+		defaultPrometheus = NewPrometheus()
+	})
+	return defaultPrometheus
+}
+
+// RegisterCollector adds a custom collector to the default Prometheus registry,
+// making it available on the /metrics endpoint mounted by app.WithMetrics.
+func RegisterCollector(c prometheus.Collector) error {
+	return DefaultPrometheus().Register(c)
+}
+
+// Registry returns the underlying Prometheus registry.
+func (p *Prometheus) Registry() *prometheus.Registry {
+	return p.registry
+}
+
+// Register adds a custom collector to the registry.
+func (p *Prometheus) Register(c prometheus.Collector) error {
+	return p.registry.Register(c)
+}
+
+// MustRegister is like Register but panics on error.
+func (p *Prometheus) MustRegister(cs ...prometheus.Collector) {
+	p.registry.MustRegister(cs...)
+}
+
+// NewCounterVec creates and registers a counter vector, returning the existing
+// one if a counter with the same name was registered before.
+//
+// Example:
+//
+//	failures, err := metrics.NewCounterVec("auth_signin_failures_total",
+//	    "Failed sign-in attempts.", "reason")
+//	failures.WithLabelValues("invalid_credentials").Inc()
+func (p *Prometheus) NewCounterVec(name, help string, labels ...string) (*prometheus.CounterVec, error) {
+	cv := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+	if err := p.registry.Register(cv); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(*prometheus.CounterVec); ok {
+				return existing, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot register counter %s: %w", name, err)
+	}
+	return cv, nil
+}
+
+// NewHistogramVec creates and registers a histogram vector using the default buckets
+// when none are given, returning the existing one if already registered.
+func (p *Prometheus) NewHistogramVec(name, help string, buckets []float64, labels ...string) (*prometheus.HistogramVec, error) {
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+	hv := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)
+	if err := p.registry.Register(hv); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(*prometheus.HistogramVec); ok {
+				return existing, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot register histogram %s: %w", name, err)
+	}
+	return hv, nil
+}
+
+// Handler returns the HTTP handler that serves the registry in the Prometheus exposition format.
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{Registry: p.registry})
+}
+
+// Counter implements Metrics. Counters are created on first use; dots in the
+// name are replaced with underscores, and the label set of the first call wins.
+func (p *Prometheus) Counter(_ context.Context, name string, value float64, labels map[string]string) {
+	name = strings.ReplaceAll(name, ".", "_")
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	p.mu.Lock()
+	cv, ok := p.counters[name]
+	if !ok {
+		var err error
+		cv, err = p.NewCounterVec(name, name, keys...)
+		if err != nil {
+			p.mu.Unlock()
+			return
+		}
+		p.counters[name] = cv
+	}
+	p.mu.Unlock()
+
+	c, err := cv.GetMetricWith(prometheus.Labels(labels))
+	if err != nil {
+		return
+	}
+	c.Add(value)
+}
+
+// ObserveHTTPRequest implements Metrics.
+func (p *Prometheus) ObserveHTTPRequest(path, method string, status int, duration time.Duration) {
+	p.requests.WithLabelValues(path, method, strconv.Itoa(status)).Inc()
+	p.duration.WithLabelValues(path, method).Observe(duration.Seconds())
+}
+
+// Middleware instruments requests with count, latency, and in-flight metrics.
+// Requests are labelled by chi route pattern rather than raw path to keep
+// cardinality bounded; unmatched requests use UnmatchedRoute.
+func (p *Prometheus) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p.inFlight.Inc()
+			defer p.inFlight.Dec()
+
+			start := time.Now()
+			rw := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(rw, r)
+
+			p.ObserveHTTPRequest(routePattern(r), r.Method, rw.Status(), time.Since(start))
+		})
+	}
+}
+
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return UnmatchedRoute
+	}
+	if pattern := rctx.RoutePattern(); pattern != "" {
+		return pattern
+	}
+	return UnmatchedRoute
+}
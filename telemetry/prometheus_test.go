@@ -0,0 +1,127 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func scrape(t *testing.T, p *Prometheus) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("scrape status = %d, want %d", w.Code, http.StatusOK)
+	}
+	return w.Body.String()
+}
+
+func TestPrometheusMiddleware(t *testing.T) {
+	p := NewPrometheus()
+
+	r := chi.NewRouter()
+	r.Use(p.Middleware())
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if got := testutil.ToFloat64(p.inFlight); got != 1 {
+			t.Errorf("in-flight = %v, want 1", got)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(p.requests.WithLabelValues("/users/{id}", "GET", "201")); got != 2 {
+		t.Errorf("route requests = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(p.requests.WithLabelValues(UnmatchedRoute, "GET", "404")); got != 1 {
+		t.Errorf("unmatched requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(p.inFlight); got != 0 {
+		t.Errorf("in-flight after requests = %v, want 0", got)
+	}
+
+	body := scrape(t, p)
+	for _, want := range []string{
+		`http_request_duration_seconds_count{method="GET",route="/users/{id}"} 2`,
+		"http_requests_in_flight 0",
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scrape should contain %q", want)
+		}
+	}
+}
+
+func TestPrometheusCounter(t *testing.T) {
+	p := NewPrometheus()
+	ctx := context.Background()
+
+	p.Counter(ctx, "auth.signin.failures", 1, map[string]string{"reason": "invalid"})
+	p.Counter(ctx, "auth.signin.failures", 2, map[string]string{"reason": "invalid"})
+	p.Counter(ctx, "auth.signin.failures", 1, map[string]string{"other": "label"})
+
+	body := scrape(t, p)
+	want := `auth_signin_failures{reason="invalid"} 3`
+	if !strings.Contains(body, want) {
+		t.Errorf("scrape should contain %q, got:\n%s", want, body)
+	}
+}
+
+func TestPrometheusObserveHTTPRequest(t *testing.T) {
+	p := NewPrometheus()
+
+	p.ObserveHTTPRequest("/items", "POST", 500, 10*time.Millisecond)
+
+	if got := testutil.ToFloat64(p.requests.WithLabelValues("/items", "POST", "500")); got != 1 {
+		t.Errorf("requests = %v, want 1", got)
+	}
+}
+
+func TestPrometheusNewCounterVec(t *testing.T) {
+	p := NewPrometheus()
+
+	first, err := p.NewCounterVec("signin_failures_total", "Failed sign-ins.", "reason")
+	if err != nil {
+		t.Fatalf("NewCounterVec() error = %v", err)
+	}
+	second, err := p.NewCounterVec("signin_failures_total", "Failed sign-ins.", "reason")
+	if err != nil {
+		t.Fatalf("NewCounterVec() second call error = %v", err)
+	}
+	if first != second {
+		t.Error("NewCounterVec() should return the existing collector")
+	}
+
+	if _, err := p.NewCounterVec("signin_failures_total", "Failed sign-ins.", "other"); err == nil {
+		t.Error("NewCounterVec() should fail for conflicting labels")
+	}
+}
+
+func TestPrometheusNewHistogramVec(t *testing.T) {
+	p := NewPrometheus()
+
+	hv, err := p.NewHistogramVec("token_issue_seconds", "Token issue latency.", nil, "kind")
+	if err != nil {
+		t.Fatalf("NewHistogramVec() error = %v", err)
+	}
+	hv.WithLabelValues("access").Observe(0.2)
+
+	if !strings.Contains(scrape(t, p), `token_issue_seconds_count{kind="access"} 1`) {
+		t.Error("scrape should contain histogram observation")
+	}
+}
+
+func TestDefaultPrometheus(t *testing.T) {
+	if DefaultPrometheus() != DefaultPrometheus() {
+		t.Error("DefaultPrometheus() should return the same instance")
+	}
+}
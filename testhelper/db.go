@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -17,183 +19,109 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
-// SetupTestDB creates an isolated test database environment.
-// In CI (when DB_HOST is set), it uses the existing PostgreSQL instance with a unique schema.
-// Locally, it spins up a testcontainer for complete isolation.
-func SetupTestDB(t *testing.T) (*sql.DB, string, func()) {
-	t.Helper()
-	ctx := context.Background()
-
-	// Check if we're in CI environment
-	if dbHost := os.Getenv("DB_HOST"); dbHost != "" {
-		return setupCIDatabase(t, ctx)
-	}
-
-	// Local development: use testcontainers
-	return setupTestContainer(t, ctx)
+// PostgresImage is the image used for the shared test container.
+const PostgresImage = "postgres:16-alpine"
+
+// shared holds the PostgreSQL server used by every test in the binary.
+// In CI (when DB_HOST is set) it points at the existing instance; locally
+// a single testcontainer is started on first use and reused afterwards.
+var shared struct {
+	once      sync.Once
+	server    config.DatabaseConfig
+	container *postgres.PostgresContainer
+	err       error
 }
 
-// setupCIDatabase creates a unique schema in the shared CI database
-func setupCIDatabase(t *testing.T, ctx context.Context) (*sql.DB, string, func()) {
-	t.Helper()
-
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := getEnvOrDefault("DB_PORT", "5432")
-	dbUser := getEnvOrDefault("DB_USER", "postgres")
-	dbPassword := getEnvOrDefault("DB_PASSWORD", "postgres")
-	dbName := getEnvOrDefault("DB_NAME", "postgres")
-
-	// Generate unique schema name for this test
-	schema := fmt.Sprintf("test_%d_%s", time.Now().UnixNano(), randomString(8))
-
-	// Connect to database
-	connStr := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		dbHost, dbPort, dbUser, dbPassword, dbName,
-	)
-
-	db, err := sql.Open("pgx", connStr)
-	if err != nil {
-		t.Fatalf("cannot open database: %v", err)
-	}
-
-	if err := db.PingContext(ctx); err != nil {
-		db.Close()
-		t.Fatalf("cannot ping database: %v", err)
-	}
+// Main runs the tests and terminates the shared container afterwards.
+// Call it from TestMain in packages that use SetupTestDB:
+//
+//	func TestMain(m *testing.M) {
+//	    testhelper.Main(m)
+//	}
+func Main(m *testing.M) {
+	code := m.Run()
+	TerminateShared()
+	os.Exit(code)
+}
 
-	// Create unique schema
-	_, err = db.ExecContext(ctx, fmt.Sprintf("CREATE SCHEMA %s", schema))
-	if err != nil {
-		db.Close()
-		t.Fatalf("cannot create schema %s: %v", schema, err)
+// TerminateShared stops the shared container, if one was started.
+// Without it the container is reaped by testcontainers when the binary exits.
+func TerminateShared() {
+	if shared.container == nil {
+		return
 	}
-
-	// Set search_path to use this schema by default
-	_, err = db.ExecContext(ctx, fmt.Sprintf("SET search_path TO %s", schema))
-	if err != nil {
-		db.Close()
-		t.Fatalf("cannot set search_path: %v", err)
+	if err := shared.container.Terminate(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "cannot terminate postgres container: %v\n", err)
 	}
-
-	cleanup := func() {
-		// Drop schema and all its objects
-		_, _ = db.ExecContext(context.Background(),
-			fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schema))
-		db.Close()
-	}
-
-	return db, schema, cleanup
+	shared.container = nil
 }
 
-// setupTestContainer creates a new PostgreSQL container for local testing
-func setupTestContainer(t *testing.T, ctx context.Context) (*sql.DB, string, func()) {
+// SetupTestDB creates an isolated test database environment.
+// Each call gets a unique schema on the shared server; the returned *sql.DB
+// has its search_path set to that schema on every connection.
+// The cleanup function drops the schema and closes the connection.
+func SetupTestDB(t *testing.T) (*sql.DB, string, func()) {
 	t.Helper()
+	ctx := context.Background()
 
-	pgContainer, err := postgres.Run(ctx,
-		"postgres:16-alpine",
-		postgres.WithDatabase("testdb"),
-		postgres.WithUsername("postgres"),
-		postgres.WithPassword("postgres"),
-		testcontainers.WithWaitStrategy(
-			wait.ForLog("database system is ready to accept connections").
-				WithOccurrence(2)),
-	)
-	if err != nil {
-		t.Fatalf("cannot start postgres container: %v", err)
-	}
-
-	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
-	if err != nil {
-		t.Fatalf("cannot get connection string: %v", err)
-	}
+	cfg, dropSchema := setupSchema(t, ctx)
 
-	db, err := sql.Open("pgx", connStr)
+	db, err := sql.Open("pgx", cfg.ConnectionString())
 	if err != nil {
+		dropSchema()
 		t.Fatalf("cannot open database: %v", err)
 	}
 
 	if err := db.PingContext(ctx); err != nil {
 		db.Close()
+		dropSchema()
 		t.Fatalf("cannot ping database: %v", err)
 	}
 
-	// For testcontainers, we use the public schema
-	schema := "public"
-
 	cleanup := func() {
 		db.Close()
-		if err := pgContainer.Terminate(context.Background()); err != nil {
-			t.Logf("cannot terminate container: %v", err)
-		}
+		dropSchema()
 	}
 
-	return db, schema, cleanup
+	return db, cfg.Schema, cleanup
 }
 
-// SetupTestDBWithConfig returns a config.Config for testing
+// SetupTestDBWithConfig returns a config.Config pointing at a unique schema
+// on the shared server. The cleanup function drops the schema.
 func SetupTestDBWithConfig(t *testing.T) (*config.Config, func()) {
 	t.Helper()
-	ctx := context.Background()
-
-	// Check if we're in CI environment
-	if dbHost := os.Getenv("DB_HOST"); dbHost != "" {
-		return setupCIDatabaseWithConfig(t, ctx)
-	}
 
-	// Local development: use testcontainers
-	return setupTestContainerWithConfig(t, ctx)
+	dbCfg, cleanup := setupSchema(t, context.Background())
+	dbCfg.Migrate.Enabled = true
+	return &config.Config{Database: dbCfg}, cleanup
 }
 
-func setupCIDatabaseWithConfig(t *testing.T, ctx context.Context) (*config.Config, func()) {
+// setupSchema creates a unique schema on the shared server and returns the
+// connection settings for it along with a function that drops it.
+func setupSchema(t *testing.T, ctx context.Context) (config.DatabaseConfig, func()) {
 	t.Helper()
 
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := getEnvOrDefault("DB_PORT", "5432")
-	dbUser := getEnvOrDefault("DB_USER", "postgres")
-	dbPassword := getEnvOrDefault("DB_PASSWORD", "postgres")
-	dbName := getEnvOrDefault("DB_NAME", "postgres")
-
-	// Generate unique schema name for this test
-	schema := fmt.Sprintf("test_%d_%s", time.Now().UnixNano(), randomString(8))
-
-	// Connect to database to create schema
-	connStr := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		dbHost, dbPort, dbUser, dbPassword, dbName,
-	)
+	server, err := sharedServer(ctx)
+	if err != nil {
+		t.Fatalf("cannot start postgres: %v", err)
+	}
 
-	db, err := sql.Open("pgx", connStr)
+	admin, err := sql.Open("pgx", server.ConnectionString())
 	if err != nil {
 		t.Fatalf("cannot open database: %v", err)
 	}
-	defer db.Close()
+	defer admin.Close()
 
-	// Create unique schema
-	_, err = db.ExecContext(ctx, fmt.Sprintf("CREATE SCHEMA %s", schema))
-	if err != nil {
+	schema := schemaName(t)
+	if _, err := admin.ExecContext(ctx, fmt.Sprintf("CREATE SCHEMA %s", schema)); err != nil {
 		t.Fatalf("cannot create schema %s: %v", schema, err)
 	}
 
-	// Parse port as int
-	port := 5432
-	fmt.Sscanf(dbPort, "%d", &port)
-
-	cfg := &config.Config{
-		Database: config.DatabaseConfig{
-			Host:     dbHost,
-			Port:     port,
-			User:     dbUser,
-			Password: dbPassword,
-			Database: dbName,
-			Schema:   schema,
-			SSLMode:  "disable",
-		},
-	}
+	cfg := server
+	cfg.Schema = schema
 
 	cleanup := func() {
-		// Reconnect to drop schema
-		db, err := sql.Open("pgx", connStr)
+		db, err := sql.Open("pgx", server.ConnectionString())
 		if err != nil {
 			t.Logf("cannot open database for cleanup: %v", err)
 			return
@@ -207,51 +135,88 @@ func setupCIDatabaseWithConfig(t *testing.T, ctx context.Context) (*config.Confi
 	return cfg, cleanup
 }
 
-func setupTestContainerWithConfig(t *testing.T, ctx context.Context) (*config.Config, func()) {
-	t.Helper()
+// sharedServer returns connection settings for the shared server, starting
+// the container on first use.
+func sharedServer(ctx context.Context) (config.DatabaseConfig, error) {
+	shared.once.Do(func() {
+		if dbHost := os.Getenv("DB_HOST"); dbHost != "" {
+			shared.server = ciServer(dbHost)
+			return
+		}
+		shared.server, shared.container, shared.err = startContainer(ctx)
+	})
+	return shared.server, shared.err
+}
+
+func ciServer(dbHost string) config.DatabaseConfig {
+	port := 5432
+	fmt.Sscanf(getEnvOrDefault("DB_PORT", "5432"), "%d", &port)
 
+	return config.DatabaseConfig{
+		Host:     dbHost,
+		Port:     port,
+		User:     getEnvOrDefault("DB_USER", "postgres"),
+		Password: getEnvOrDefault("DB_PASSWORD", "postgres"),
+		Database: getEnvOrDefault("DB_NAME", "postgres"),
+		SSLMode:  "disable",
+	}
+}
+
+func startContainer(ctx context.Context) (config.DatabaseConfig, *postgres.PostgresContainer, error) {
 	pgContainer, err := postgres.Run(ctx,
-		"postgres:16-alpine",
+		PostgresImage,
 		postgres.WithDatabase("testdb"),
 		postgres.WithUsername("postgres"),
 		postgres.WithPassword("postgres"),
 		testcontainers.WithWaitStrategy(
 			wait.ForLog("database system is ready to accept connections").
-				WithOccurrence(2)),
+				WithOccurrence(2).
+				WithStartupTimeout(60*time.Second)),
 	)
 	if err != nil {
-		t.Fatalf("cannot start postgres container: %v", err)
+		return config.DatabaseConfig{}, nil, fmt.Errorf("cannot start postgres container: %w", err)
 	}
 
 	host, err := pgContainer.Host(ctx)
 	if err != nil {
-		t.Fatalf("cannot get container host: %v", err)
+		pgContainer.Terminate(context.Background())
+		return config.DatabaseConfig{}, nil, fmt.Errorf("cannot get container host: %w", err)
 	}
 
 	port, err := pgContainer.MappedPort(ctx, "5432")
 	if err != nil {
-		t.Fatalf("cannot get container port: %v", err)
+		pgContainer.Terminate(context.Background())
+		return config.DatabaseConfig{}, nil, fmt.Errorf("cannot get container port: %w", err)
 	}
 
-	cfg := &config.Config{
-		Database: config.DatabaseConfig{
-			Host:     host,
-			Port:     port.Int(),
-			User:     "postgres",
-			Password: "postgres",
-			Database: "testdb",
-			Schema:   "public",
-			SSLMode:  "disable",
-		},
+	server := config.DatabaseConfig{
+		Host:     host,
+		Port:     port.Int(),
+		User:     "postgres",
+		Password: "postgres",
+		Database: "testdb",
+		SSLMode:  "disable",
 	}
 
-	cleanup := func() {
-		if err := pgContainer.Terminate(context.Background()); err != nil {
-			t.Logf("cannot terminate container: %v", err)
+	return server, pgContainer, nil
+}
+
+// schemaName derives a unique, valid schema name from the test name so
+// leftovers from an interrupted run are easy to attribute.
+func schemaName(t *testing.T) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(t.Name()) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+		if b.Len() >= 32 {
+			break
 		}
 	}
-
-	return cfg, cleanup
+	return fmt.Sprintf("test_%s_%s", b.String(), randomString(8))
 }
 
 // TestLogger returns a logger suitable for testing
@@ -0,0 +1,31 @@
+package testhelper
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSchemaName(t *testing.T) {
+	valid := regexp.MustCompile(`^test_[a-z0-9_]+_[a-z0-9]{8}$`)
+
+	t.Run("Mixed/Case Name-With.Symbols", func(t *testing.T) {
+		name := schemaName(t)
+
+		if !valid.MatchString(name) {
+			t.Errorf("schemaName() = %q, not a valid schema name", name)
+		}
+		if !strings.HasPrefix(name, "test_testschemaname_mixed_case_") {
+			t.Errorf("schemaName() = %q, should be derived from the test name", name)
+		}
+		if len(name) > 63 {
+			t.Errorf("schemaName() length = %d, exceeds PostgreSQL identifier limit", len(name))
+		}
+	})
+
+	t.Run(strings.Repeat("long", 30), func(t *testing.T) {
+		if name := schemaName(t); len(name) > 63 {
+			t.Errorf("schemaName() length = %d, exceeds PostgreSQL identifier limit", len(name))
+		}
+	})
+}
@@ -1,16 +1,35 @@
 package validation
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"regexp"
 	"strings"
 
 	"github.com/google/uuid"
 )
 
+// Codes identifying which check a ValidationError failed. Clients should
+// branch on the code rather than the message.
+const (
+	CodeInvalid  = "invalid"
+	CodeRequired = "required"
+	CodeMin      = "min"
+	CodeMax      = "max"
+	CodeRange    = "range"
+	CodeOneOf    = "oneof"
+	CodeEmail    = "email"
+	CodeURL      = "url"
+	CodeFormat   = "format"
+)
+
 // ValidationError represents a single validation error for a field or key.
+// Code is one of the Code constants; an empty code is reported as CodeInvalid.
 type ValidationError struct {
-	Field   string
-	Message string
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 // Error implements the error interface.
@@ -21,9 +40,43 @@ func (e ValidationError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Field, e.Message)
 }
 
+// MarshalJSON implements json.Marshaler, defaulting an empty Code to CodeInvalid.
+func (e ValidationError) MarshalJSON() ([]byte, error) {
+	type plain ValidationError
+	if e.Code == "" {
+		e.Code = CodeInvalid
+	}
+	return json.Marshal(plain(e))
+}
+
 // ValidationErrors is a collection of validation errors that can be accumulated.
+// It marshals to JSON as {"errors":[{"field":...,"code":...,"message":...}]}.
 type ValidationErrors []ValidationError
 
+type validationErrorsJSON struct {
+	Errors []ValidationError `json:"errors"`
+}
+
+// MarshalJSON implements json.Marshaler. An empty collection marshals with
+// an empty errors array rather than null.
+func (e ValidationErrors) MarshalJSON() ([]byte, error) {
+	errs := []ValidationError(e)
+	if errs == nil {
+		errs = []ValidationError{}
+	}
+	return json.Marshal(validationErrorsJSON{Errors: errs})
+}
+
+// UnmarshalJSON implements json.Unmarshaler for the shape written by MarshalJSON.
+func (e *ValidationErrors) UnmarshalJSON(data []byte) error {
+	var body validationErrorsJSON
+	if err := json.Unmarshal(data, &body); err != nil {
+		return err
+	}
+	*e = body.Errors
+	return nil
+}
+
 // Error implements the error interface, combining all error messages.
 func (e ValidationErrors) Error() string {
 	if len(e) == 0 {
@@ -47,6 +100,11 @@ func (e *ValidationErrors) Add(field, message string) {
 	*e = append(*e, ValidationError{Field: field, Message: message})
 }
 
+// AddCode appends a validation error with an explicit code to the collection.
+func (e *ValidationErrors) AddCode(field, code, message string) {
+	*e = append(*e, ValidationError{Field: field, Code: code, Message: message})
+}
+
 // AddError appends a ValidationError to the collection.
 func (e *ValidationErrors) AddError(err ValidationError) {
 	*e = append(*e, err)
@@ -150,10 +208,29 @@ func OneOf(value string, allowed []string) bool {
 	return false
 }
 
+// IsEmail checks if a string is a well-formed email address.
+func IsEmail(value string) bool {
+	return ValidateEmail(value) == nil
+}
+
+// IsURL checks if a string is an absolute URL with a scheme and a host.
+func IsURL(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return u.Scheme != "" && u.Host != ""
+}
+
+// MatchesRegex checks if a string matches the given regular expression.
+func MatchesRegex(value string, re *regexp.Regexp) bool {
+	return re != nil && re.MatchString(value)
+}
+
 // RequiredString validates that a string field is not empty.
 func RequiredString(field, value string) ValidationError {
 	if !IsRequired(value) {
-		return ValidationError{Field: field, Message: "is required"}
+		return ValidationError{Field: field, Code: CodeRequired, Message: "is required"}
 	}
 	return ValidationError{}
 }
@@ -161,7 +238,7 @@ func RequiredString(field, value string) ValidationError {
 // RequiredUUID validates that a UUID field is not nil.
 func RequiredUUID(field string, value uuid.UUID) ValidationError {
 	if !IsRequiredUUID(value) {
-		return ValidationError{Field: field, Message: "is required"}
+		return ValidationError{Field: field, Code: CodeRequired, Message: "is required"}
 	}
 	return ValidationError{}
 }
@@ -169,7 +246,7 @@ func RequiredUUID(field string, value uuid.UUID) ValidationError {
 // StringMinLength validates that a string has at least the minimum length.
 func StringMinLength(field, value string, min int) ValidationError {
 	if !MinLength(value, min) {
-		return ValidationError{Field: field, Message: fmt.Sprintf("must be at least %d characters", min)}
+		return ValidationError{Field: field, Code: CodeMin, Message: fmt.Sprintf("must be at least %d characters", min)}
 	}
 	return ValidationError{}
 }
@@ -177,7 +254,7 @@ func StringMinLength(field, value string, min int) ValidationError {
 // StringMaxLength validates that a string does not exceed the maximum length.
 func StringMaxLength(field, value string, max int) ValidationError {
 	if !MaxLength(value, max) {
-		return ValidationError{Field: field, Message: fmt.Sprintf("must be at most %d characters", max)}
+		return ValidationError{Field: field, Code: CodeMax, Message: fmt.Sprintf("must be at most %d characters", max)}
 	}
 	return ValidationError{}
 }
@@ -185,7 +262,7 @@ func StringMaxLength(field, value string, max int) ValidationError {
 // IntMinValue validates that an integer is at least the minimum value.
 func IntMinValue(field string, value, min int) ValidationError {
 	if !MinValueInt(value, min) {
-		return ValidationError{Field: field, Message: fmt.Sprintf("must be at least %d", min)}
+		return ValidationError{Field: field, Code: CodeMin, Message: fmt.Sprintf("must be at least %d", min)}
 	}
 	return ValidationError{}
 }
@@ -193,7 +270,7 @@ func IntMinValue(field string, value, min int) ValidationError {
 // IntMaxValue validates that an integer does not exceed the maximum value.
 func IntMaxValue(field string, value, max int) ValidationError {
 	if !MaxValueInt(value, max) {
-		return ValidationError{Field: field, Message: fmt.Sprintf("must be at most %d", max)}
+		return ValidationError{Field: field, Code: CodeMax, Message: fmt.Sprintf("must be at most %d", max)}
 	}
 	return ValidationError{}
 }
@@ -201,7 +278,7 @@ func IntMaxValue(field string, value, max int) ValidationError {
 // IntInRange validates that an integer is within the specified range.
 func IntInRange(field string, value, min, max int) ValidationError {
 	if !InRange(value, min, max) {
-		return ValidationError{Field: field, Message: fmt.Sprintf("must be between %d and %d", min, max)}
+		return ValidationError{Field: field, Code: CodeRange, Message: fmt.Sprintf("must be between %d and %d", min, max)}
 	}
 	return ValidationError{}
 }
@@ -209,7 +286,31 @@ func IntInRange(field string, value, min, max int) ValidationError {
 // StringOneOf validates that a string is one of the allowed values.
 func StringOneOf(field, value string, allowed []string) ValidationError {
 	if !OneOf(value, allowed) {
-		return ValidationError{Field: field, Message: fmt.Sprintf("must be one of: %s", strings.Join(allowed, ", "))}
+		return ValidationError{Field: field, Code: CodeOneOf, Message: fmt.Sprintf("must be one of: %s", strings.Join(allowed, ", "))}
+	}
+	return ValidationError{}
+}
+
+// StringEmail validates that a string is a well-formed email address.
+func StringEmail(field, value string) ValidationError {
+	if !IsEmail(value) {
+		return ValidationError{Field: field, Code: CodeEmail, Message: "must be a valid email address"}
+	}
+	return ValidationError{}
+}
+
+// StringURL validates that a string is an absolute URL.
+func StringURL(field, value string) ValidationError {
+	if !IsURL(value) {
+		return ValidationError{Field: field, Code: CodeURL, Message: "must be a valid URL"}
+	}
+	return ValidationError{}
+}
+
+// StringMatches validates that a string matches the given regular expression.
+func StringMatches(field, value string, re *regexp.Regexp) ValidationError {
+	if !MatchesRegex(value, re) {
+		return ValidationError{Field: field, Code: CodeFormat, Message: "has an invalid format"}
 	}
 	return ValidationError{}
 }
@@ -1,6 +1,8 @@
 package validation
 
 import (
+	"encoding/json"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -1265,3 +1267,179 @@ func TestStringOneOf(t *testing.T) {
 		})
 	}
 }
+
+func TestIsEmail(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "user@example.com", want: true},
+		{value: "user.name+tag@sub.example.org", want: true},
+		{value: "user@", want: false},
+		{value: "example.com", want: false},
+		{value: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := IsEmail(tt.value); got != tt.want {
+				t.Errorf("IsEmail(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsURL(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "https://example.com", want: true},
+		{value: "http://localhost:8080/path?q=1", want: true},
+		{value: "example.com", want: false},
+		{value: "/relative/path", want: false},
+		{value: "https://", want: false},
+		{value: "://bad", want: false},
+		{value: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := IsURL(tt.value); got != tt.want {
+				t.Errorf("IsURL(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesRegex(t *testing.T) {
+	slug := regexp.MustCompile(`^[a-z0-9-]+$`)
+
+	tests := []struct {
+		name  string
+		value string
+		re    *regexp.Regexp
+		want  bool
+	}{
+		{name: "matches", value: "my-slug-1", re: slug, want: true},
+		{name: "does not match", value: "My Slug", re: slug, want: false},
+		{name: "nil regexp", value: "anything", re: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchesRegex(tt.value, tt.re); got != tt.want {
+				t.Errorf("MatchesRegex(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringFormatValidators(t *testing.T) {
+	slug := regexp.MustCompile(`^[a-z]+$`)
+
+	tests := []struct {
+		name      string
+		err       ValidationError
+		wantError bool
+	}{
+		{name: "valid email", err: StringEmail("email", "user@example.com"), wantError: false},
+		{name: "invalid email", err: StringEmail("email", "nope"), wantError: true},
+		{name: "valid url", err: StringURL("website", "https://example.com"), wantError: false},
+		{name: "invalid url", err: StringURL("website", "nope"), wantError: true},
+		{name: "matching value", err: StringMatches("slug", "abc", slug), wantError: false},
+		{name: "non-matching value", err: StringMatches("slug", "ABC", slug), wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.err.Field != "") != tt.wantError {
+				t.Errorf("error = %v, wantError %v", tt.err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestValidationErrors_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		errors ValidationErrors
+		want   string
+	}{
+		{
+			name:   "no errors",
+			errors: nil,
+			want:   `{"errors":[]}`,
+		},
+		{
+			name: "coded and uncoded errors",
+			errors: ValidationErrors{
+				{Field: "email", Code: CodeRequired, Message: "is required"},
+				{Field: "name", Message: "looks odd"},
+				{Message: "general error"},
+			},
+			want: `{"errors":[{"field":"email","code":"required","message":"is required"},` +
+				`{"field":"name","code":"invalid","message":"looks odd"},` +
+				`{"code":"invalid","message":"general error"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.errors)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("json.Marshal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrors_UnmarshalJSON(t *testing.T) {
+	var errs ValidationErrors
+	errs.AddCode("username", CodeMin, "must be at least 3 characters")
+	errs.AddCode("plan", CodeOneOf, "must be one of: free, pro")
+
+	data, err := json.Marshal(errs)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	var got ValidationErrors
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(got) != len(errs) {
+		t.Fatalf("json.Unmarshal() = %v, want %v", got, errs)
+	}
+	for i := range errs {
+		if got[i] != errs[i] {
+			t.Errorf("errors[%d] = %+v, want %+v", i, got[i], errs[i])
+		}
+	}
+}
+
+func TestValidatorCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		err  ValidationError
+		want string
+	}{
+		{name: "required", err: RequiredString("name", ""), want: CodeRequired},
+		{name: "min length", err: StringMinLength("name", "a", 2), want: CodeMin},
+		{name: "max length", err: StringMaxLength("name", "abc", 2), want: CodeMax},
+		{name: "range", err: IntInRange("age", 5, 10, 20), want: CodeRange},
+		{name: "one of", err: StringOneOf("plan", "x", []string{"a"}), want: CodeOneOf},
+		{name: "email", err: StringEmail("email", "x"), want: CodeEmail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.Code != tt.want {
+				t.Errorf("Code = %q, want %q", tt.err.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ValidateStruct checks the exported fields of v against their `validate`
+// struct tags and returns every failure. v must be a struct or a non-nil
+// pointer to one; nested structs are validated recursively.
+//
+// Supported rules, separated by commas:
+//
+//	required    the value must not be the zero value (blank strings count as zero)
+//	min=N       strings and slices need at least N elements, numbers a value of at least N
+//	max=N       strings and slices allow at most N elements, numbers a value of at most N
+//	oneof=a b   the value must be one of the space-separated options
+//	email       the value must be a well-formed email address
+//	url         the value must be an absolute URL
+//
+// Rules other than required are skipped for zero values, so optional fields
+// only need to be valid when they are set. Fields are reported by their json
+// tag name when present. ValidateStruct panics on an unknown rule or a
+// malformed parameter, since both are programming errors.
+//
+// Example:
+//
+//	type SignUpRequest struct {
+//	    Email    string `json:"email" validate:"required,email"`
+//	    Username string `json:"username" validate:"required,min=3,max=32"`
+//	    Plan     string `json:"plan" validate:"oneof=free pro"`
+//	}
+//
+//	if errs := validation.ValidateStruct(req); errs.HasErrors() {
+//	    return errs
+//	}
+func ValidateStruct(v any) ValidationErrors {
+	return ValidateStructTag(v, "json")
+}
+
+// ValidateStructTag is like ValidateStruct but reports fields by the name
+// in their nameTag struct tag, such as "koanf", falling back to the Go
+// field name.
+func ValidateStructTag(v any, nameTag string) ValidationErrors {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return ValidationErrors{{Code: CodeRequired, Message: "value is nil"}}
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: ValidateStruct expects a struct, got %s", rv.Kind()))
+	}
+
+	var errs ValidationErrors
+	validateFields("", rv, nameTag, &errs)
+	return errs
+}
+
+func validateFields(prefix string, v reflect.Value, nameTag string, errs *ValidationErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		fv := v.Field(i)
+		name := fieldName(field, nameTag)
+		if field.Anonymous && field.Tag.Get(nameTag) == "" {
+			name = ""
+		}
+		if prefix != "" && name != "" {
+			name = prefix + "." + name
+		} else if name == "" {
+			name = prefix
+		}
+
+		if tag := field.Tag.Get("validate"); tag != "" && tag != "-" {
+			validateField(name, fv, tag, errs)
+		}
+
+		for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct {
+			validateFields(name, fv, nameTag, errs)
+		}
+	}
+}
+
+func validateField(name string, fv reflect.Value, tag string, errs *ValidationErrors) {
+	zero := isZero(fv)
+	for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+		fv = fv.Elem()
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		key, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if key == "" {
+			continue
+		}
+
+		if key == "required" {
+			if zero {
+				errs.AddCode(name, CodeRequired, "is required")
+				return
+			}
+			continue
+		}
+		if zero {
+			continue
+		}
+
+		if err := applyRule(name, fv, key, param); err.Field != "" {
+			errs.AddError(err)
+		}
+	}
+}
+
+func applyRule(name string, fv reflect.Value, key, param string) ValidationError {
+	switch key {
+	case "min":
+		return checkBound(name, fv, param, true)
+	case "max":
+		return checkBound(name, fv, param, false)
+	case "oneof":
+		return StringOneOf(name, stringValue(name, fv, key), strings.Fields(param))
+	case "email":
+		return StringEmail(name, stringValue(name, fv, key))
+	case "url":
+		return StringURL(name, stringValue(name, fv, key))
+	default:
+		panic(fmt.Sprintf("validation: unknown rule %q on field %s", key, name))
+	}
+}
+
+func checkBound(name string, fv reflect.Value, param string, isMin bool) ValidationError {
+	switch fv.Kind() {
+	case reflect.String:
+		n := intParam(name, param)
+		if isMin {
+			return StringMinLength(name, fv.String(), n)
+		}
+		return StringMaxLength(name, fv.String(), n)
+
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n := intParam(name, param)
+		if isMin && fv.Len() < n {
+			return ValidationError{Field: name, Code: CodeMin, Message: fmt.Sprintf("must have at least %d items", n)}
+		}
+		if !isMin && fv.Len() > n {
+			return ValidationError{Field: name, Code: CodeMax, Message: fmt.Sprintf("must have at most %d items", n)}
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := intParam(name, param)
+		if isMin && fv.Int() < int64(n) {
+			return ValidationError{Field: name, Code: CodeMin, Message: fmt.Sprintf("must be at least %d", n)}
+		}
+		if !isMin && fv.Int() > int64(n) {
+			return ValidationError{Field: name, Code: CodeMax, Message: fmt.Sprintf("must be at most %d", n)}
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n := intParam(name, param)
+		if isMin && fv.Uint() < uint64(n) {
+			return ValidationError{Field: name, Code: CodeMin, Message: fmt.Sprintf("must be at least %d", n)}
+		}
+		if !isMin && fv.Uint() > uint64(n) {
+			return ValidationError{Field: name, Code: CodeMax, Message: fmt.Sprintf("must be at most %d", n)}
+		}
+
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validation: invalid parameter %q on field %s", param, name))
+		}
+		if isMin && fv.Float() < n {
+			return ValidationError{Field: name, Code: CodeMin, Message: fmt.Sprintf("must be at least %s", param)}
+		}
+		if !isMin && fv.Float() > n {
+			return ValidationError{Field: name, Code: CodeMax, Message: fmt.Sprintf("must be at most %s", param)}
+		}
+
+	default:
+		panic(fmt.Sprintf("validation: min/max not supported on %s field %s", fv.Kind(), name))
+	}
+	return ValidationError{}
+}
+
+func intParam(name, param string) int {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		panic(fmt.Sprintf("validation: invalid parameter %q on field %s", param, name))
+	}
+	return n
+}
+
+func stringValue(name string, fv reflect.Value, key string) string {
+	if fv.Kind() != reflect.String {
+		panic(fmt.Sprintf("validation: rule %q requires a string field, %s is %s", key, name, fv.Kind()))
+	}
+	return fv.String()
+}
+
+func isZero(fv reflect.Value) bool {
+	if fv.Kind() == reflect.String {
+		return !IsRequired(fv.String())
+	}
+	if fv.Kind() == reflect.Slice || fv.Kind() == reflect.Map {
+		return fv.Len() == 0
+	}
+	return fv.IsZero()
+}
+
+func fieldName(field reflect.StructField, nameTag string) string {
+	tag := field.Tag.Get(nameTag)
+	if tag == "" || tag == "-" {
+		return field.Name
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return field.Name
+}
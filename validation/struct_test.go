@@ -0,0 +1,221 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+)
+
+type structAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type structBase struct {
+	ID string `json:"id" validate:"required"`
+}
+
+type structRequest struct {
+	structBase
+	Email    string         `json:"email" validate:"required,email"`
+	Username string         `json:"username,omitempty" validate:"required,min=3,max=8"`
+	Plan     string         `json:"plan" validate:"oneof=free pro"`
+	Website  string         `validate:"url"`
+	Age      int            `json:"age" validate:"min=18,max=130"`
+	Score    float64        `json:"score" validate:"max=1.5"`
+	Tags     []string       `json:"tags" validate:"required,max=2"`
+	Nickname *string        `json:"nickname" validate:"required,min=2"`
+	Address  structAddress  `json:"address"`
+	Billing  *structAddress `json:"billing"`
+	Ignored  string         `json:"ignored" validate:"-"`
+	internal string         `validate:"required"`
+}
+
+func validStructRequest() structRequest {
+	nick := "jo"
+	return structRequest{
+		structBase: structBase{ID: "1"},
+		Email:      "user@example.com",
+		Username:   "alice",
+		Plan:       "pro",
+		Website:    "https://example.com",
+		Age:        30,
+		Score:      1.2,
+		Tags:       []string{"a"},
+		Nickname:   &nick,
+		Address:    structAddress{City: "Berlin"},
+	}
+}
+
+func TestValidateStruct(t *testing.T) {
+	short := "j"
+
+	tests := []struct {
+		name   string
+		mutate func(r *structRequest)
+		want   map[string]string
+	}{
+		{
+			name:   "valid request",
+			mutate: func(r *structRequest) {},
+		},
+		{
+			name: "missing required fields",
+			mutate: func(r *structRequest) {
+				r.Email = "  "
+				r.Username = ""
+				r.Tags = []string{}
+				r.Nickname = nil
+			},
+			want: map[string]string{
+				"email":    "is required",
+				"username": "is required",
+				"tags":     "is required",
+				"nickname": "is required",
+			},
+		},
+		{
+			name: "invalid formats",
+			mutate: func(r *structRequest) {
+				r.Email = "not-an-email"
+				r.Website = "example.com"
+				r.Plan = "enterprise"
+			},
+			want: map[string]string{
+				"email":   "must be a valid email address",
+				"Website": "must be a valid URL",
+				"plan":    "must be one of: free, pro",
+			},
+		},
+		{
+			name: "bounds",
+			mutate: func(r *structRequest) {
+				r.Username = "al"
+				r.Age = 12
+				r.Score = 2
+				r.Tags = []string{"a", "b", "c"}
+				r.Nickname = &short
+			},
+			want: map[string]string{
+				"username": "must be at least 3 characters",
+				"age":      "must be at least 18",
+				"score":    "must be at most 1.5",
+				"tags":     "must have at most 2 items",
+				"nickname": "must be at least 2 characters",
+			},
+		},
+		{
+			name: "upper bound",
+			mutate: func(r *structRequest) {
+				r.Username = "alexandria"
+				r.Age = 200
+			},
+			want: map[string]string{
+				"username": "must be at most 8 characters",
+				"age":      "must be at most 130",
+			},
+		},
+		{
+			name: "optional fields skipped when zero",
+			mutate: func(r *structRequest) {
+				r.Plan = ""
+				r.Website = ""
+				r.Age = 0
+			},
+		},
+		{
+			name: "nested and embedded structs",
+			mutate: func(r *structRequest) {
+				r.ID = ""
+				r.Address.City = ""
+				r.Billing = &structAddress{}
+			},
+			want: map[string]string{
+				"id":           "is required",
+				"address.city": "is required",
+				"billing.city": "is required",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validStructRequest()
+			tt.mutate(&req)
+
+			errs := ValidateStruct(&req)
+
+			got := make(map[string]string)
+			for _, err := range errs {
+				got[err.Field] = err.Message
+			}
+			if len(got) != len(errs) {
+				t.Fatalf("ValidateStruct() reported a field twice: %v", errs)
+			}
+			if len(tt.want) == 0 && len(got) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateStruct() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateStructAcceptsValue(t *testing.T) {
+	if errs := ValidateStruct(validStructRequest()); errs.HasErrors() {
+		t.Errorf("ValidateStruct() unexpected errors: %v", errs)
+	}
+}
+
+func TestValidateStructNilPointer(t *testing.T) {
+	var req *structRequest
+	errs := ValidateStruct(req)
+	if !errs.HasErrors() {
+		t.Fatal("ValidateStruct(nil) should report an error")
+	}
+}
+
+func TestValidateStructTag(t *testing.T) {
+	type pool struct {
+		Size int `json:"size" koanf:"maxsize" validate:"min=1"`
+	}
+	v := struct {
+		Driver string `koanf:"driver" validate:"required"`
+		Pool   pool   `koanf:"pool"`
+		Region string `validate:"required"`
+	}{Pool: pool{Size: -1}}
+
+	errs := ValidateStructTag(v, "koanf")
+	want := []string{"driver", "pool.maxsize", "Region"}
+	if got := errs.Fields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateStructTag() fields = %v, want %v", got, want)
+	}
+}
+
+func TestValidateStructPanics(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{name: "not a struct", value: "text"},
+		{name: "unknown rule", value: struct {
+			Name string `validate:"shiny"`
+		}{Name: "x"}},
+		{name: "malformed parameter", value: struct {
+			Name string `validate:"min=three"`
+		}{Name: "x"}},
+		{name: "string rule on int", value: struct {
+			Count int `validate:"email"`
+		}{Count: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("ValidateStruct() should panic")
+				}
+			}()
+			ValidateStruct(tt.value)
+		})
+	}
+}
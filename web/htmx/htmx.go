@@ -0,0 +1,95 @@
+// Package htmx provides helpers for requests made by htmx and for the
+// response headers that steer it.
+package htmx
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Request headers sent by htmx.
+const (
+	HeaderRequest = "HX-Request"
+	HeaderBoosted = "HX-Boosted"
+	HeaderTarget  = "HX-Target"
+	HeaderTrigger = "HX-Trigger"
+)
+
+// Response headers understood by htmx.
+const (
+	HeaderRedirect        = "HX-Redirect"
+	HeaderRefresh         = "HX-Refresh"
+	HeaderPushURL         = "HX-Push-Url"
+	HeaderRetarget        = "HX-Retarget"
+	HeaderReswap          = "HX-Reswap"
+	HeaderTriggerResponse = "HX-Trigger"
+)
+
+// IsRequest reports whether r was made by htmx.
+func IsRequest(r *http.Request) bool {
+	return r.Header.Get(HeaderRequest) == "true"
+}
+
+// IsBoosted reports whether r comes from an element with hx-boost, which
+// swaps the whole body and so expects a full page.
+func IsBoosted(r *http.Request) bool {
+	return r.Header.Get(HeaderBoosted) == "true"
+}
+
+// IsPartial reports whether r expects a fragment rather than a full page:
+// it was made by htmx and not boosted.
+func IsPartial(r *http.Request) bool {
+	return IsRequest(r) && !IsBoosted(r)
+}
+
+// Target returns the id of the element r will be swapped into, if any.
+func Target(r *http.Request) string {
+	return r.Header.Get(HeaderTarget)
+}
+
+// Trigger makes htmx fire events on the client once the response is
+// swapped. Events without details are sent as a comma-separated list.
+func Trigger(w http.ResponseWriter, events ...string) {
+	if len(events) == 0 {
+		return
+	}
+	w.Header().Set(HeaderTriggerResponse, strings.Join(events, ", "))
+}
+
+// TriggerDetail makes htmx fire events with details on the client, each
+// event name mapping to the value passed in the event's detail.
+func TriggerDetail(w http.ResponseWriter, events map[string]any) error {
+	value, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(HeaderTriggerResponse, string(value))
+	return nil
+}
+
+// Redirect makes htmx navigate to url with a full page load.
+func Redirect(w http.ResponseWriter, url string) {
+	w.Header().Set(HeaderRedirect, url)
+}
+
+// Refresh makes htmx reload the current page.
+func Refresh(w http.ResponseWriter) {
+	w.Header().Set(HeaderRefresh, "true")
+}
+
+// PushURL makes htmx push url onto the browser history.
+func PushURL(w http.ResponseWriter, url string) {
+	w.Header().Set(HeaderPushURL, url)
+}
+
+// Retarget swaps the response into the elements matching selector instead
+// of the request's target.
+func Retarget(w http.ResponseWriter, selector string) {
+	w.Header().Set(HeaderRetarget, selector)
+}
+
+// Reswap overrides how the response is swapped, e.g. "outerHTML".
+func Reswap(w http.ResponseWriter, swap string) {
+	w.Header().Set(HeaderReswap, swap)
+}
@@ -0,0 +1,80 @@
+package htmx
+
+import (
+	"math"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestHeaders(t *testing.T) {
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		wantRequest bool
+		wantPartial bool
+	}{
+		{name: "plain request", headers: nil, wantRequest: false, wantPartial: false},
+		{name: "htmx request", headers: map[string]string{"HX-Request": "true"}, wantRequest: true, wantPartial: true},
+		{name: "boosted", headers: map[string]string{"HX-Request": "true", "HX-Boosted": "true"}, wantRequest: true, wantPartial: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			if got := IsRequest(r); got != tt.wantRequest {
+				t.Errorf("IsRequest() = %v, want %v", got, tt.wantRequest)
+			}
+			if got := IsPartial(r); got != tt.wantPartial {
+				t.Errorf("IsPartial() = %v, want %v", got, tt.wantPartial)
+			}
+		})
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("HX-Target", "user-list")
+	if got := Target(r); got != "user-list" {
+		t.Errorf("Target() = %q, want user-list", got)
+	}
+}
+
+func TestResponseHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	Trigger(w, "saved", "refreshList")
+	Redirect(w, "/login")
+	Refresh(w)
+	PushURL(w, "/users?page=2")
+	Retarget(w, "#errors")
+	Reswap(w, "outerHTML")
+
+	want := map[string]string{
+		"HX-Trigger":  "saved, refreshList",
+		"HX-Redirect": "/login",
+		"HX-Refresh":  "true",
+		"HX-Push-Url": "/users?page=2",
+		"HX-Retarget": "#errors",
+		"HX-Reswap":   "outerHTML",
+	}
+	for k, v := range want {
+		if got := w.Header().Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestTriggerDetail(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := TriggerDetail(w, map[string]any{"saved": map[string]string{"id": "42"}}); err != nil {
+		t.Fatalf("TriggerDetail() error = %v", err)
+	}
+	if got := w.Header().Get("HX-Trigger"); got != `{"saved":{"id":"42"}}` {
+		t.Errorf("HX-Trigger = %q", got)
+	}
+
+	if err := TriggerDetail(httptest.NewRecorder(), map[string]any{"bad": math.Inf(1)}); err == nil {
+		t.Error("TriggerDetail() with an unencodable detail returned no error")
+	}
+}
@@ -0,0 +1,331 @@
+// Package render renders HTML pages from templates and serves static assets.
+//
+// Templates are loaded from an fs.FS, typically an embed.FS, laid out as
+//
+//	assets/templates/layouts/*.html   page layouts, e.g. layouts/base.html
+//	assets/templates/partials/*.html  fragments shared by pages
+//	assets/templates/**/*.html        pages, named by path: "users/list"
+//
+// and static files are served from assets/static. Each page is parsed with
+// the layouts and partials, so pages may define the same blocks without
+// clashing. A layout renders the page with {{block "content" .}}{{end}} and
+// a page fills it with {{define "content"}}...{{end}}; templates are named
+// by their path without the extension.
+//
+// A Renderer is an app component: Start parses the templates and
+// RegisterRoutes serves the static files.
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/web/htmx"
+)
+
+// Defaults for the directories and names a Renderer uses.
+const (
+	DefaultTemplateDir  = "assets/templates"
+	DefaultStaticDir    = "assets/static"
+	DefaultStaticPrefix = "/static/"
+	DefaultLayout       = "base"
+
+	// ContentBlock is the block a layout renders the page with and the one
+	// sent alone to htmx requests.
+	ContentBlock = "content"
+)
+
+const (
+	layoutDir  = "layouts"
+	partialDir = "partials"
+)
+
+// ErrTemplateNotFound is returned when a page, partial or layout does not exist.
+var ErrTemplateNotFound = errors.New("template not found")
+
+// Renderer renders pages and partials and serves static assets.
+type Renderer struct {
+	fsys fs.FS
+	log  log.Logger
+
+	templateDir  string
+	staticDir    string
+	staticPrefix string
+	layout       string
+	funcs        template.FuncMap
+	maxAge       int
+	reload       bool
+
+	mu     sync.RWMutex
+	pages  map[string]*page
+	shared *template.Template
+	assets map[string]string
+}
+
+// page is a page parsed with the layouts and partials.
+type page struct {
+	t *template.Template
+	// content reports whether the page itself defines ContentBlock, as
+	// opposed to inheriting the layout's empty default.
+	content bool
+}
+
+// Option configures a Renderer.
+type Option func(*Renderer)
+
+// WithTemplateDir sets the directory in fsys templates are loaded from.
+func WithTemplateDir(dir string) Option {
+	return func(r *Renderer) {
+		r.templateDir = dir
+	}
+}
+
+// WithStatic sets the directory in fsys static files are served from and
+// the URL prefix they are served under. An empty dir disables static files.
+func WithStatic(dir, prefix string) Option {
+	return func(r *Renderer) {
+		r.staticDir = dir
+		if prefix != "" {
+			r.staticPrefix = "/" + strings.Trim(prefix, "/") + "/"
+		}
+	}
+}
+
+// WithLayout sets the layout pages defining a content block are rendered
+// in, DefaultLayout unless set. Other pages, and every page when name is
+// empty, render on their own.
+func WithLayout(name string) Option {
+	return func(r *Renderer) {
+		r.layout = name
+	}
+}
+
+// WithFuncs adds functions available to every template.
+func WithFuncs(funcs template.FuncMap) Option {
+	return func(r *Renderer) {
+		for name, fn := range funcs {
+			r.funcs[name] = fn
+		}
+	}
+}
+
+// WithMaxAge sets how long, in seconds, clients may cache static files
+// requested without a fingerprint. Fingerprinted URLs from the asset
+// function are cached for a year.
+func WithMaxAge(seconds int) Option {
+	return func(r *Renderer) {
+		r.maxAge = seconds
+	}
+}
+
+// WithReload parses the templates again on every render, so edits show up
+// without a restart when fsys is an os.DirFS. Meant for development.
+func WithReload(reload bool) Option {
+	return func(r *Renderer) {
+		r.reload = reload
+	}
+}
+
+// New creates a renderer over fsys. Parsing is deferred to Start.
+func New(fsys fs.FS, log log.Logger, opts ...Option) *Renderer {
+	r := &Renderer{
+		fsys:         fsys,
+		log:          log,
+		templateDir:  DefaultTemplateDir,
+		staticDir:    DefaultStaticDir,
+		staticPrefix: DefaultStaticPrefix,
+		layout:       DefaultLayout,
+		funcs:        template.FuncMap{},
+		maxAge:       DefaultMaxAge,
+	}
+	r.funcs["asset"] = r.Asset
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Start parses the templates and fingerprints the static files.
+func (r *Renderer) Start(ctx context.Context) error {
+	if err := r.load(); err != nil {
+		r.log.Errorf("error loading templates: %v", err)
+		return err
+	}
+	r.log.Info("templates loaded successfully")
+	return nil
+}
+
+// Stop implements the lifecycle interface.
+func (r *Renderer) Stop(ctx context.Context) error {
+	return nil
+}
+
+func (r *Renderer) load() error {
+	assets, err := r.fingerprint()
+	if err != nil {
+		return err
+	}
+
+	templates, err := fs.Sub(r.fsys, r.templateDir)
+	if err != nil {
+		return err
+	}
+
+	shared := template.New("").Funcs(r.funcs)
+	var pages []string
+	err = fs.WalkDir(templates, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ".html" {
+			return err
+		}
+		if dir := strings.Split(p, "/")[0]; dir == layoutDir || dir == partialDir {
+			return parseFile(shared, templates, p)
+		}
+		pages = append(pages, p)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	parsed := make(map[string]*page, len(pages))
+	for _, p := range pages {
+		own := template.New("").Funcs(r.funcs)
+		if err := parseFile(own, templates, p); err != nil {
+			return err
+		}
+		t, err := shared.Clone()
+		if err != nil {
+			return err
+		}
+		if err := parseFile(t, templates, p); err != nil {
+			return err
+		}
+		parsed[templateName(p)] = &page{t: t, content: own.Lookup(ContentBlock) != nil}
+	}
+
+	r.mu.Lock()
+	r.shared, r.pages, r.assets = shared, parsed, assets
+	r.mu.Unlock()
+	return nil
+}
+
+func parseFile(t *template.Template, fsys fs.FS, p string) error {
+	content, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return err
+	}
+	if _, err := t.New(templateName(p)).Parse(string(content)); err != nil {
+		return fmt.Errorf("parse %s: %w", p, err)
+	}
+	return nil
+}
+
+func templateName(p string) string {
+	return strings.TrimSuffix(p, path.Ext(p))
+}
+
+// HTML writes page with status. Full pages are rendered in the layout;
+// htmx requests that are not boosted get only the page's content block, so
+// links and forms can swap the main area without reloading the layout.
+func (r *Renderer) HTML(w http.ResponseWriter, req *http.Request, status int, name string, data any) {
+	t, block, err := r.page(name, htmx.IsPartial(req))
+	if err != nil {
+		r.fail(w, name, err)
+		return
+	}
+	r.execute(w, status, t, block, data)
+}
+
+// Partial writes the partial name, e.g. "partials/user-row", or a block
+// defined by a page as "page#block", e.g. "users/list#rows".
+func (r *Renderer) Partial(w http.ResponseWriter, req *http.Request, status int, name string, data any) {
+	t, block, err := r.partial(name)
+	if err != nil {
+		r.fail(w, name, err)
+		return
+	}
+	r.execute(w, status, t, block, data)
+}
+
+func (r *Renderer) page(name string, partial bool) (*template.Template, string, error) {
+	if err := r.reloadIfEnabled(); err != nil {
+		return nil, "", err
+	}
+
+	r.mu.RLock()
+	p := r.pages[name]
+	r.mu.RUnlock()
+	if p == nil {
+		return nil, "", ErrTemplateNotFound
+	}
+
+	switch {
+	case p.content && partial:
+		return p.t, ContentBlock, nil
+	case p.content && r.layout != "":
+		layout := path.Join(layoutDir, r.layout)
+		if p.t.Lookup(layout) == nil {
+			return nil, "", fmt.Errorf("layout %s: %w", r.layout, ErrTemplateNotFound)
+		}
+		return p.t, layout, nil
+	case p.content:
+		return p.t, ContentBlock, nil
+	default:
+		return p.t, name, nil
+	}
+}
+
+func (r *Renderer) partial(name string) (*template.Template, string, error) {
+	if err := r.reloadIfEnabled(); err != nil {
+		return nil, "", err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, block := r.shared, name
+	if page, b, ok := strings.Cut(name, "#"); ok {
+		t, block = nil, b
+		if p := r.pages[page]; p != nil {
+			t = p.t
+		}
+	}
+	if t == nil || t.Lookup(block) == nil {
+		return nil, "", ErrTemplateNotFound
+	}
+	return t, block, nil
+}
+
+func (r *Renderer) reloadIfEnabled() error {
+	if !r.reload {
+		return nil
+	}
+	return r.load()
+}
+
+// execute renders into a buffer first, so a failing template results in a
+// clean 500 instead of a truncated page.
+func (r *Renderer) execute(w http.ResponseWriter, status int, t *template.Template, name string, data any) {
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+		r.fail(w, name, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+func (r *Renderer) fail(w http.ResponseWriter, name string, err error) {
+	r.log.Errorf("error rendering template %s: %v", name, err)
+	http.Error(w, "Template rendering error", http.StatusInternalServerError)
+}
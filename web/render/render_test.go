@@ -0,0 +1,218 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/aquamarinepk/aqm/log"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"assets/templates/layouts/base.html": {Data: []byte(
+			`<html><head><link href="{{asset "app.css"}}"><title>{{block "title" .}}App{{end}}</title></head>` +
+				`<body>{{block "content" .}}{{end}}</body></html>`)},
+		"assets/templates/partials/user-row.html": {Data: []byte(`<li>{{.Name}}</li>`)},
+		"assets/templates/users/list.html": {Data: []byte(
+			`{{define "title"}}Users{{end}}` +
+				`{{define "content"}}<ul>{{block "rows" .}}{{range .Users}}{{template "partials/user-row" .}}{{end}}{{end}}</ul>{{end}}`)},
+		"assets/templates/users/show.html": {Data: []byte(`{{define "content"}}<p>{{upper .Name}}</p>{{end}}`)},
+		"assets/templates/plain.html":      {Data: []byte(`<p>{{.}}</p>`)},
+		"assets/templates/fails.html":      {Data: []byte(`{{define "content"}}{{index .Users 5}}{{end}}`)},
+		"assets/static/app.css":            {Data: []byte(`body { color: teal; }`)},
+	}
+}
+
+type user struct{ Name string }
+
+func newTestRenderer(t *testing.T, fsys fstest.MapFS, opts ...Option) *Renderer {
+	t.Helper()
+
+	opts = append([]Option{WithFuncs(template.FuncMap{"upper": strings.ToUpper})}, opts...)
+	r := New(fsys, log.NewNoopLogger(), opts...)
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	return r
+}
+
+func TestStartInvalidTemplate(t *testing.T) {
+	fsys := testFS()
+	fsys["assets/templates/broken.html"] = &fstest.MapFile{Data: []byte(`{{range .}}`)}
+
+	err := New(fsys, log.NewNoopLogger()).Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "broken.html") {
+		t.Errorf("Start() error = %v, want it to name broken.html", err)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	r := newTestRenderer(t, testFS())
+	data := map[string]any{"Users": []user{{"ann"}, {"bob"}}, "Name": "ann"}
+
+	tests := []struct {
+		name       string
+		page       string
+		headers    map[string]string
+		wantStatus int
+		want       string
+		wantLayout bool
+	}{
+		{
+			name:       "full page in layout",
+			page:       "users/list",
+			wantStatus: http.StatusOK,
+			want:       `<title>Users</title></head><body><ul><li>ann</li><li>bob</li></ul></body>`,
+			wantLayout: true,
+		},
+		{
+			name:       "htmx request gets content only",
+			page:       "users/list",
+			headers:    map[string]string{"HX-Request": "true"},
+			wantStatus: http.StatusOK,
+			want:       `<ul><li>ann</li><li>bob</li></ul>`,
+		},
+		{
+			name:       "boosted request gets full page",
+			page:       "users/show",
+			headers:    map[string]string{"HX-Request": "true", "HX-Boosted": "true"},
+			wantStatus: http.StatusOK,
+			want:       `<title>App</title></head><body><p>ANN</p></body>`,
+			wantLayout: true,
+		},
+		{
+			name:       "page without content block renders alone",
+			page:       "plain",
+			wantStatus: http.StatusOK,
+			want:       `<p>map[Name:ann Users:[{ann} {bob}]]</p>`,
+		},
+		{
+			name:       "unknown page",
+			page:       "missing",
+			wantStatus: http.StatusInternalServerError,
+			want:       "Template rendering error",
+		},
+		{
+			name:       "execution error",
+			page:       "fails",
+			wantStatus: http.StatusInternalServerError,
+			want:       "Template rendering error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			r.HTML(w, req, http.StatusOK, tt.page, data)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("body = %q, want to contain %q", w.Body.String(), tt.want)
+			}
+			if got := strings.Contains(w.Body.String(), "<html>"); got != tt.wantLayout {
+				t.Errorf("rendered layout = %v, want %v", got, tt.wantLayout)
+			}
+		})
+	}
+}
+
+func TestHTMLStatus(t *testing.T) {
+	r := newTestRenderer(t, testFS())
+	w := httptest.NewRecorder()
+
+	r.HTML(w, httptest.NewRequest(http.MethodPost, "/", nil), http.StatusUnprocessableEntity, "plain", "invalid")
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %v, want 422", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestWithLayout(t *testing.T) {
+	fsys := testFS()
+	fsys["assets/templates/layouts/bare.html"] = &fstest.MapFile{Data: []byte(`<main>{{block "content" .}}{{end}}</main>`)}
+
+	w := httptest.NewRecorder()
+	newTestRenderer(t, fsys, WithLayout("bare")).HTML(w, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, "users/show", user{"cid"})
+	if got := w.Body.String(); got != "<main><p>CID</p></main>" {
+		t.Errorf("body = %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	newTestRenderer(t, fsys, WithLayout("")).HTML(w, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, "users/show", user{"cid"})
+	if got := w.Body.String(); got != "<p>CID</p>" {
+		t.Errorf("body without layout = %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	newTestRenderer(t, fsys, WithLayout("missing")).HTML(w, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, "users/show", user{"cid"})
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("missing layout status = %v, want 500", w.Code)
+	}
+}
+
+func TestPartial(t *testing.T) {
+	r := newTestRenderer(t, testFS())
+	data := map[string]any{"Users": []user{{"ann"}}}
+
+	tests := []struct {
+		name       string
+		partial    string
+		wantStatus int
+		want       string
+	}{
+		{name: "shared partial", partial: "partials/user-row", wantStatus: http.StatusOK, want: "<li></li>"},
+		{name: "page block", partial: "users/list#rows", wantStatus: http.StatusOK, want: "<li>ann</li>"},
+		{name: "unknown partial", partial: "partials/missing", wantStatus: http.StatusInternalServerError},
+		{name: "unknown block", partial: "users/list#missing", wantStatus: http.StatusInternalServerError},
+		{name: "unknown page", partial: "missing#rows", wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.Partial(w, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, tt.partial, data)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if tt.want != "" && w.Body.String() != tt.want {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPageLookup(t *testing.T) {
+	r := newTestRenderer(t, testFS())
+	if _, _, err := r.page("missing", false); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("page(missing) error = %v, want ErrTemplateNotFound", err)
+	}
+}
+
+func TestWithReload(t *testing.T) {
+	fsys := testFS()
+	r := newTestRenderer(t, fsys, WithReload(true))
+
+	fsys["assets/templates/plain.html"] = &fstest.MapFile{Data: []byte(`<p>edited</p>`)}
+	w := httptest.NewRecorder()
+	r.HTML(w, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, "plain", nil)
+	if got := w.Body.String(); got != "<p>edited</p>" {
+		t.Errorf("body = %q, want the edited template", got)
+	}
+}
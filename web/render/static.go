@@ -0,0 +1,101 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DefaultMaxAge is how long, in seconds, clients may cache static files
+// requested without a fingerprint.
+const DefaultMaxAge = 3600
+
+// immutableMaxAge is the cache lifetime of fingerprinted URLs.
+const immutableMaxAge = 365 * 24 * 3600
+
+// fingerprint hashes every static file so URLs and ETags change with content.
+func (r *Renderer) fingerprint() (map[string]string, error) {
+	assets := map[string]string{}
+	if r.staticDir == "" {
+		return assets, nil
+	}
+
+	static, err := fs.Sub(r.fsys, r.staticDir)
+	if err != nil {
+		return nil, err
+	}
+	err = fs.WalkDir(static, ".", func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && p == "." {
+			return fs.SkipAll
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(static, p)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		assets[p] = hex.EncodeToString(sum[:8])
+		return nil
+	})
+	return assets, err
+}
+
+// Asset returns the URL of a static file with a fingerprint of its content,
+// e.g. "/static/app.css?v=3f2a…", so it can be cached until it changes.
+// Templates call it as {{asset "app.css"}}. Unknown files get a plain URL.
+func (r *Renderer) Asset(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	url := r.staticPrefix + name
+
+	r.mu.RLock()
+	hash, ok := r.assets[name]
+	r.mu.RUnlock()
+	if !ok {
+		return url
+	}
+	return url + "?v=" + hash
+}
+
+// RegisterRoutes serves the static files under the static prefix.
+// Responses carry an ETag, and fingerprinted URLs are marked immutable.
+func (r *Renderer) RegisterRoutes(router chi.Router) {
+	if r.staticDir == "" {
+		return
+	}
+	router.Get(r.staticPrefix+"*", r.serveStatic)
+}
+
+func (r *Renderer) serveStatic(w http.ResponseWriter, req *http.Request) {
+	name := path.Clean(strings.TrimPrefix(req.URL.Path, r.staticPrefix))
+
+	r.mu.RLock()
+	hash, ok := r.assets[name]
+	r.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	maxAge := strconv.Itoa(r.maxAge)
+	if req.URL.Query().Get("v") == hash {
+		maxAge = strconv.Itoa(immutableMaxAge) + ", immutable"
+	}
+	w.Header().Set("Cache-Control", "public, max-age="+maxAge)
+	w.Header().Set("ETag", `"`+hash+`"`)
+
+	static, err := fs.Sub(r.fsys, r.staticDir)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	http.ServeFileFS(w, req, static, name)
+}
@@ -0,0 +1,111 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestAsset(t *testing.T) {
+	r := newTestRenderer(t, testFS())
+
+	got := r.Asset("/app.css")
+	if !strings.HasPrefix(got, "/static/app.css?v=") || len(got) != len("/static/app.css?v=")+16 {
+		t.Errorf("Asset(app.css) = %q, want a fingerprinted URL", got)
+	}
+	if got := r.Asset("missing.js"); got != "/static/missing.js" {
+		t.Errorf("Asset(missing.js) = %q, want a plain URL", got)
+	}
+
+	fsys := testFS()
+	fsys["assets/static/app.css"] = &fstest.MapFile{Data: []byte(`body { color: red; }`)}
+	if other := newTestRenderer(t, fsys).Asset("app.css"); other == got {
+		t.Error("Asset() fingerprint did not change with content")
+	}
+}
+
+func TestServeStatic(t *testing.T) {
+	r := newTestRenderer(t, testFS(), WithMaxAge(60))
+	router := chi.NewRouter()
+	r.RegisterRoutes(router)
+
+	get := func(url string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/static/app.css", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "body { color: teal; }" {
+		t.Fatalf("GET app.css = %v %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/css") {
+		t.Errorf("Content-Type = %q, want text/css", got)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=60" {
+		t.Errorf("Cache-Control = %q, want the configured max age", got)
+	}
+
+	etag := w.Header().Get("ETag")
+	if w := get("/static/app.css", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Errorf("conditional GET status = %v, want 304", w.Code)
+	}
+
+	w = get(r.Asset("app.css"), nil)
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=31536000, immutable" {
+		t.Errorf("fingerprinted Cache-Control = %q, want immutable", got)
+	}
+	if got := get("/static/app.css?v=stale", nil).Header().Get("Cache-Control"); got != "public, max-age=60" {
+		t.Errorf("stale fingerprint Cache-Control = %q, want the configured max age", got)
+	}
+
+	for _, url := range []string{"/static/missing.css", "/static/", "/static/../templates/plain.html"} {
+		if w := get(url, nil); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %v, want 404", url, w.Code)
+		}
+	}
+}
+
+func TestWithStatic(t *testing.T) {
+	fsys := testFS()
+	fsys["public/logo.svg"] = &fstest.MapFile{Data: []byte(`<svg/>`)}
+	r := newTestRenderer(t, fsys, WithStatic("public", "assets"))
+	router := chi.NewRouter()
+	r.RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/assets/logo.svg", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "<svg/>" {
+		t.Errorf("GET /assets/logo.svg = %v %q", w.Code, w.Body.String())
+	}
+	if got := r.Asset("logo.svg"); !strings.HasPrefix(got, "/assets/logo.svg?v=") {
+		t.Errorf("Asset(logo.svg) = %q", got)
+	}
+}
+
+func TestWithoutStatic(t *testing.T) {
+	fsys := testFS()
+	delete(fsys, "assets/static/app.css")
+	r := newTestRenderer(t, fsys)
+
+	if got := r.Asset("app.css"); got != "/static/app.css" {
+		t.Errorf("Asset() = %q, want a plain URL", got)
+	}
+
+	r = newTestRenderer(t, testFS(), WithStatic("", ""))
+	router := chi.NewRouter()
+	r.RegisterRoutes(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/app.css", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET with static disabled = %v, want 404", w.Code)
+	}
+}
@@ -2,26 +2,29 @@ package web
 
 import (
 	"context"
-	"embed"
 	"html/template"
 	"io/fs"
 	"net/http"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/aquamarinepk/aqm/log"
 )
 
 // TemplateManager handles template loading and rendering with embedded FS support.
 type TemplateManager struct {
-	fs        embed.FS
+	fs        fs.FS
+	mu        sync.RWMutex
 	templates *template.Template
 	log       log.Logger
 }
 
 // NewTemplateManager creates a new template manager.
 // Parsing is deferred to Start() to support async initialization.
-func NewTemplateManager(fs embed.FS, log log.Logger) *TemplateManager {
+// fs is usually an embed.FS; in development it can be the asset directory on
+// disk (see app.DevFS) so that Reload picks up edits.
+func NewTemplateManager(fs fs.FS, log log.Logger) *TemplateManager {
 	return &TemplateManager{
 		fs:  fs,
 		log: log,
@@ -31,6 +34,16 @@ func NewTemplateManager(fs embed.FS, log log.Logger) *TemplateManager {
 // Start parses templates from embedded FS.
 // This can be called asynchronously by the aqm framework.
 func (m *TemplateManager) Start(ctx context.Context) error {
+	if err := m.Reload(); err != nil {
+		return err
+	}
+	m.log.Info("templates loaded successfully")
+	return nil
+}
+
+// Reload parses the templates again, replacing those rendered only when all
+// of them parse.
+func (m *TemplateManager) Reload() error {
 	tmpl := template.New("")
 
 	// Walk the embedded FS and parse all .html files
@@ -43,7 +56,7 @@ func (m *TemplateManager) Start(ctx context.Context) error {
 			return nil
 		}
 
-		content, err := m.fs.ReadFile(path)
+		content, err := fs.ReadFile(m.fs, path)
 		if err != nil {
 			return err
 		}
@@ -61,8 +74,9 @@ func (m *TemplateManager) Start(ctx context.Context) error {
 		return err
 	}
 
+	m.mu.Lock()
 	m.templates = tmpl
-	m.log.Info("templates loaded successfully")
+	m.mu.Unlock()
 	return nil
 }
 
@@ -76,7 +90,7 @@ func (m *TemplateManager) Stop(ctx context.Context) error {
 func (m *TemplateManager) Render(w http.ResponseWriter, namespace, template string, data interface{}) {
 	path := m.buildPath(namespace, template)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := m.templates.ExecuteTemplate(w, path, data); err != nil {
+	if err := m.current().ExecuteTemplate(w, path, data); err != nil {
 		m.log.Errorf("error rendering template %s/%s: %v", namespace, template, err)
 		http.Error(w, "Template rendering error", http.StatusInternalServerError)
 	}
@@ -87,12 +101,19 @@ func (m *TemplateManager) Render(w http.ResponseWriter, namespace, template stri
 // Namespace and template are combined to form the path: "assets/templates/{namespace}/{template}.html"
 func (m *TemplateManager) RenderPartial(w http.ResponseWriter, namespace, template string, data interface{}) {
 	path := m.buildPath(namespace, template)
-	if err := m.templates.ExecuteTemplate(w, path, data); err != nil {
+	if err := m.current().ExecuteTemplate(w, path, data); err != nil {
 		m.log.Errorf("error rendering partial %s/%s: %v", namespace, template, err)
 		http.Error(w, "Partial rendering error", http.StatusInternalServerError)
 	}
 }
 
+// current returns the templates last parsed.
+func (m *TemplateManager) current() *template.Template {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.templates
+}
+
 // buildPath constructs the full template path from namespace and template name.
 func (m *TemplateManager) buildPath(namespace, template string) string {
 	return filepath.Join("assets", "templates", namespace, template+".html")
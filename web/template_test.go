@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/aquamarinepk/aqm/log"
 )
@@ -181,3 +182,31 @@ func TestTemplateManagerRenderPartial(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplateManagerReload(t *testing.T) {
+	fsys := fstest.MapFS{"assets/templates/test/page.html": {Data: []byte("v1")}}
+	mgr := NewTemplateManager(fsys, log.NewLogger("error"))
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	fsys["assets/templates/test/page.html"] = &fstest.MapFile{Data: []byte("v2 {{")}
+	if err := mgr.Reload(); err == nil {
+		t.Fatal("Reload() error = nil, want parse error")
+	}
+	w := httptest.NewRecorder()
+	mgr.Render(w, "test", "page", nil)
+	if w.Body.String() != "v1" {
+		t.Errorf("after failed reload rendered %q, want the previous v1", w.Body.String())
+	}
+
+	fsys["assets/templates/test/page.html"] = &fstest.MapFile{Data: []byte("v3")}
+	if err := mgr.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	w = httptest.NewRecorder()
+	mgr.Render(w, "test", "page", nil)
+	if w.Body.String() != "v3" {
+		t.Errorf("after reload rendered %q, want v3", w.Body.String())
+	}
+}
@@ -0,0 +1,248 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/google/uuid"
+)
+
+// RetryPolicy controls how often a failing delivery is attempted.
+// The delay before retry n is InitialBackoff * Multiplier^(n-1), capped at MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+}
+
+// DefaultRetryPolicy returns five attempts with exponential backoff from 1s.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Minute,
+		Multiplier:     4,
+	}
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		d *= p.Multiplier
+	}
+	if p.MaxBackoff > 0 && time.Duration(d) > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return time.Duration(d)
+}
+
+// Option configures a Dispatcher.
+type Option func(*Dispatcher)
+
+// WithHTTPClient sets the client used to post deliveries. The default has a
+// 10 second timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithRetry sets the retry policy. The default is DefaultRetryPolicy.
+func WithRetry(p RetryPolicy) Option {
+	return func(d *Dispatcher) {
+		d.retry = p
+	}
+}
+
+// Dispatcher delivers published events to the subscribed endpoints in the
+// background. Deliveries are recorded in the store before the first attempt
+// and updated after every attempt.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	retry  RetryPolicy
+	log    log.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	stopped bool
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewDispatcher creates a dispatcher reading endpoints from and recording
+// deliveries in store.
+func NewDispatcher(store Store, logger log.Logger, opts ...Option) *Dispatcher {
+	if logger == nil {
+		logger = log.NewNoopLogger()
+	}
+	d := &Dispatcher{
+		store:  store,
+		client: &http.Client{Timeout: 10 * time.Second},
+		retry:  DefaultRetryPolicy(),
+		log:    logger,
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.retry.MaxAttempts < 1 {
+		d.retry.MaxAttempts = 1
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	return d
+}
+
+// Publish records a pending delivery for every endpoint subscribed to the
+// event and starts delivering them. It returns once the deliveries are
+// recorded; the requests are sent in the background.
+func (d *Dispatcher) Publish(ctx context.Context, event *Event) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return ErrStopped
+	}
+
+	endpoints, err := d.store.ListEndpoints(ctx)
+	if err != nil {
+		return fmt.Errorf("list webhook endpoints: %w", err)
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for _, ep := range endpoints {
+		if !ep.Subscribed(event.Type) {
+			continue
+		}
+
+		now := d.now().UTC()
+		delivery := &Delivery{
+			ID:         uuid.New(),
+			EndpointID: ep.ID,
+			EventID:    event.ID,
+			EventType:  event.Type,
+			Payload:    payload,
+			Status:     StatusPending,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		if err := d.store.CreateDelivery(ctx, delivery); err != nil {
+			return fmt.Errorf("record webhook delivery: %w", err)
+		}
+
+		d.wg.Add(1)
+		go func(ep *Endpoint) {
+			defer d.wg.Done()
+			d.deliver(d.ctx, ep, delivery)
+		}(ep)
+	}
+	return nil
+}
+
+// Stop stops accepting events and waits for in-flight deliveries to finish.
+// If ctx expires first, pending retries are abandoned, their deliveries stay
+// pending, and ctx.Err is returned.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return nil
+	}
+	d.stopped = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// deliver attempts the delivery until it succeeds, runs out of attempts or
+// ctx is cancelled.
+func (d *Dispatcher) deliver(ctx context.Context, ep *Endpoint, delivery *Delivery) {
+	for {
+		status, err := d.attempt(ctx, ep, delivery)
+
+		delivery.Attempts++
+		delivery.ResponseStatus = status
+		delivery.Error = ""
+		delivery.UpdatedAt = d.now().UTC()
+		switch {
+		case err == nil:
+			delivery.Status = StatusSucceeded
+		case delivery.Attempts >= d.retry.MaxAttempts:
+			delivery.Status = StatusFailed
+			delivery.Error = err.Error()
+		default:
+			delivery.Error = err.Error()
+		}
+
+		// ctx is cancelled by Stop; the attempt must still be recorded.
+		if uerr := d.store.UpdateDelivery(context.WithoutCancel(ctx), delivery); uerr != nil {
+			d.log.Error("Cannot record webhook delivery", "delivery", delivery.ID, "error", uerr)
+		}
+		if delivery.Status != StatusPending {
+			if err != nil {
+				d.log.Error("Webhook delivery failed", "delivery", delivery.ID, "url", ep.URL, "attempts", delivery.Attempts, "error", err)
+			}
+			return
+		}
+
+		select {
+		case <-time.After(d.retry.backoff(delivery.Attempts)):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// attempt posts the delivery once and returns the response status.
+func (d *Dispatcher) attempt(ctx context.Context, ep *Endpoint, delivery *Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	now := d.now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(ep.Secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,161 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/webhook"
+	"github.com/aquamarinepk/aqm/webhook/fake"
+)
+
+var fastRetry = webhook.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 2}
+
+// receiver fails the first failures requests and verifies signatures on
+// the rest.
+type receiver struct {
+	t        *testing.T
+	secret   string
+	failures int32
+	calls    atomic.Int32
+
+	mu     sync.Mutex
+	events []webhook.Event
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if rc.calls.Add(1) <= rc.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	if err := webhook.Verify(rc.secret, r.Header.Get(webhook.HeaderSignature), r.Header.Get(webhook.HeaderTimestamp), body, time.Now(), 0); err != nil {
+		rc.t.Errorf("Verify() error = %v", err)
+	}
+
+	var e webhook.Event
+	json.Unmarshal(body, &e)
+	if r.Header.Get(webhook.HeaderEvent) != e.Type || r.Header.Get(webhook.HeaderDelivery) == "" {
+		rc.t.Errorf("headers = %v", r.Header)
+	}
+
+	rc.mu.Lock()
+	rc.events = append(rc.events, e)
+	rc.mu.Unlock()
+}
+
+func newEndpoint(t *testing.T, store webhook.Store, rc *receiver, events ...string) *webhook.Endpoint {
+	t.Helper()
+
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	ep := webhook.NewEndpoint(srv.URL, rc.secret, events, "admin")
+	if err := store.CreateEndpoint(context.Background(), ep); err != nil {
+		t.Fatal(err)
+	}
+	return ep
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+	store := fake.NewStore()
+
+	ok := &receiver{t: t, secret: "ok-secret"}
+	flaky := &receiver{t: t, secret: "flaky-secret", failures: 2}
+	down := &receiver{t: t, secret: "down-secret", failures: 100}
+	other := &receiver{t: t, secret: "other-secret"}
+
+	okEP := newEndpoint(t, store, ok)
+	flakyEP := newEndpoint(t, store, flaky, webhook.EventUserCreated)
+	downEP := newEndpoint(t, store, down)
+	newEndpoint(t, store, other, webhook.EventSignInFailed)
+
+	d := webhook.NewDispatcher(store, log.NewNoopLogger(), webhook.WithRetry(fastRetry))
+	if err := d.Publish(ctx, webhook.NewEvent(webhook.EventUserCreated, "user-1")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if err := d.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	if len(ok.events) != 1 || ok.events[0].Subject != "user-1" || len(flaky.events) != 1 {
+		t.Errorf("received ok=%v flaky=%v", ok.events, flaky.events)
+	}
+	if other.calls.Load() != 0 {
+		t.Errorf("unsubscribed endpoint received %d requests", other.calls.Load())
+	}
+
+	tests := []struct {
+		endpoint     *webhook.Endpoint
+		wantStatus   string
+		wantAttempts int
+		wantResponse int
+	}{
+		{endpoint: okEP, wantStatus: webhook.StatusSucceeded, wantAttempts: 1, wantResponse: http.StatusOK},
+		{endpoint: flakyEP, wantStatus: webhook.StatusSucceeded, wantAttempts: 3, wantResponse: http.StatusOK},
+		{endpoint: downEP, wantStatus: webhook.StatusFailed, wantAttempts: 3, wantResponse: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		deliveries, _ := store.ListDeliveries(ctx, webhook.DeliveryFilter{EndpointID: tt.endpoint.ID})
+		if len(deliveries) != 1 {
+			t.Fatalf("endpoint %s has %d deliveries, want 1", tt.endpoint.URL, len(deliveries))
+		}
+		got := deliveries[0]
+		if got.Status != tt.wantStatus || got.Attempts != tt.wantAttempts || got.ResponseStatus != tt.wantResponse {
+			t.Errorf("delivery = status %s, %d attempts, response %d; want %s, %d, %d",
+				got.Status, got.Attempts, got.ResponseStatus, tt.wantStatus, tt.wantAttempts, tt.wantResponse)
+		}
+		if tt.wantStatus == webhook.StatusFailed && got.Error == "" {
+			t.Error("failed delivery should record the error")
+		}
+	}
+
+	if err := d.Publish(ctx, webhook.NewEvent(webhook.EventUserCreated, "user-2")); !errors.Is(err, webhook.ErrStopped) {
+		t.Errorf("Publish() after Stop error = %v, want ErrStopped", err)
+	}
+}
+
+func TestDispatcherStopAbandonsRetries(t *testing.T) {
+	store := fake.NewStore()
+	ep := newEndpoint(t, store, &receiver{t: t, secret: "s", failures: 100})
+
+	slow := webhook.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour}
+	d := webhook.NewDispatcher(store, nil, webhook.WithRetry(slow))
+	if err := d.Publish(context.Background(), webhook.NewEvent(webhook.EventSignInFailed, "")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	// Wait for the first attempt to be recorded before stopping.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ds, _ := store.ListDeliveries(context.Background(), webhook.DeliveryFilter{EndpointID: ep.ID})
+		if len(ds) == 1 && ds[0].Attempts == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("first attempt was not recorded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := d.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop() error = %v, want DeadlineExceeded", err)
+	}
+
+	ds, _ := store.ListDeliveries(context.Background(), webhook.DeliveryFilter{EndpointID: ep.ID})
+	if ds[0].Status != webhook.StatusPending || ds[0].Attempts != 1 {
+		t.Errorf("delivery = %+v, want pending after one attempt", ds[0])
+	}
+}
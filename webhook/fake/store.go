@@ -0,0 +1,128 @@
+package fake
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/aquamarinepk/aqm/webhook"
+	"github.com/google/uuid"
+)
+
+// Store keeps endpoints and deliveries in memory. Deliveries are copied on
+// the way in and out, so callers may keep mutating the ones they pass.
+type Store struct {
+	mu         sync.RWMutex
+	endpoints  map[uuid.UUID]*webhook.Endpoint
+	deliveries []*webhook.Delivery
+}
+
+func NewStore() *Store {
+	return &Store{endpoints: make(map[uuid.UUID]*webhook.Endpoint)}
+}
+
+func (s *Store) CreateEndpoint(ctx context.Context, endpoint *webhook.Endpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.endpoints[endpoint.ID] = endpoint
+	return nil
+}
+
+func (s *Store) GetEndpoint(ctx context.Context, id uuid.UUID) (*webhook.Endpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	endpoint, ok := s.endpoints[id]
+	if !ok {
+		return nil, webhook.ErrEndpointNotFound
+	}
+	return endpoint, nil
+}
+
+func (s *Store) ListEndpoints(ctx context.Context) ([]*webhook.Endpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	endpoints := make([]*webhook.Endpoint, 0, len(s.endpoints))
+	for _, e := range s.endpoints {
+		endpoints = append(endpoints, e)
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].CreatedAt.Before(endpoints[j].CreatedAt)
+	})
+	return endpoints, nil
+}
+
+func (s *Store) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.endpoints[id]; !ok {
+		return webhook.ErrEndpointNotFound
+	}
+	delete(s.endpoints, id)
+
+	kept := s.deliveries[:0]
+	for _, d := range s.deliveries {
+		if d.EndpointID != id {
+			kept = append(kept, d)
+		}
+	}
+	s.deliveries = kept
+	return nil
+}
+
+func (s *Store) CreateDelivery(ctx context.Context, delivery *webhook.Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := *delivery
+	s.deliveries = append(s.deliveries, &d)
+	return nil
+}
+
+func (s *Store) UpdateDelivery(ctx context.Context, delivery *webhook.Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, d := range s.deliveries {
+		if d.ID == delivery.ID {
+			updated := *delivery
+			s.deliveries[i] = &updated
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *Store) ListDeliveries(ctx context.Context, filter webhook.DeliveryFilter) ([]*webhook.Delivery, error) {
+	filter, err := filter.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []*webhook.Delivery
+	for _, d := range s.deliveries {
+		if filter.Matches(d) {
+			c := *d
+			matched = append(matched, &c)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+
+	if filter.Offset >= len(matched) {
+		return []*webhook.Delivery{}, nil
+	}
+	matched = matched[filter.Offset:]
+	if len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, nil
+}
@@ -0,0 +1,103 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/webhook"
+	"github.com/google/uuid"
+)
+
+func TestStoreEndpoints(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+
+	a := webhook.NewEndpoint("https://a.example.com", "s", nil, "admin")
+	b := webhook.NewEndpoint("https://b.example.com", "s", nil, "admin")
+	b.CreatedAt = a.CreatedAt.Add(time.Second)
+	s.CreateEndpoint(ctx, b)
+	s.CreateEndpoint(ctx, a)
+
+	endpoints, _ := s.ListEndpoints(ctx)
+	if len(endpoints) != 2 || endpoints[0].ID != a.ID {
+		t.Fatalf("ListEndpoints() = %v, want oldest first", endpoints)
+	}
+	if got, err := s.GetEndpoint(ctx, b.ID); err != nil || got.URL != b.URL {
+		t.Errorf("GetEndpoint() = %v, %v", got, err)
+	}
+
+	s.CreateDelivery(ctx, &webhook.Delivery{ID: uuid.New(), EndpointID: a.ID})
+	s.CreateDelivery(ctx, &webhook.Delivery{ID: uuid.New(), EndpointID: b.ID})
+
+	if err := s.DeleteEndpoint(ctx, a.ID); err != nil {
+		t.Fatalf("DeleteEndpoint() error = %v", err)
+	}
+	if _, err := s.GetEndpoint(ctx, a.ID); !errors.Is(err, webhook.ErrEndpointNotFound) {
+		t.Errorf("GetEndpoint() after delete error = %v, want ErrEndpointNotFound", err)
+	}
+	if err := s.DeleteEndpoint(ctx, a.ID); !errors.Is(err, webhook.ErrEndpointNotFound) {
+		t.Errorf("DeleteEndpoint() twice error = %v, want ErrEndpointNotFound", err)
+	}
+	if ds, _ := s.ListDeliveries(ctx, webhook.DeliveryFilter{}); len(ds) != 1 || ds[0].EndpointID != b.ID {
+		t.Errorf("deliveries after delete = %v, want only b's", ds)
+	}
+}
+
+func TestStoreDeliveries(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	endpointID := uuid.New()
+
+	var ids []uuid.UUID
+	for i, eventType := range []string{webhook.EventUserCreated, webhook.EventRoleAssigned, webhook.EventUserCreated} {
+		d := &webhook.Delivery{
+			ID:         uuid.New(),
+			EndpointID: endpointID,
+			EventType:  eventType,
+			Status:     webhook.StatusPending,
+			CreatedAt:  base.Add(time.Duration(i) * time.Hour),
+		}
+		s.CreateDelivery(ctx, d)
+		ids = append(ids, d.ID)
+
+		// The store keeps its own copy.
+		d.Status = webhook.StatusFailed
+	}
+
+	s.UpdateDelivery(ctx, &webhook.Delivery{ID: ids[0], EndpointID: endpointID, EventType: webhook.EventUserCreated, Status: webhook.StatusSucceeded, Attempts: 1, CreatedAt: base})
+
+	tests := []struct {
+		name   string
+		filter webhook.DeliveryFilter
+		want   []uuid.UUID
+	}{
+		{"all newest first", webhook.DeliveryFilter{}, []uuid.UUID{ids[2], ids[1], ids[0]}},
+		{"by event", webhook.DeliveryFilter{EventType: webhook.EventUserCreated}, []uuid.UUID{ids[2], ids[0]}},
+		{"by status", webhook.DeliveryFilter{Status: webhook.StatusSucceeded}, []uuid.UUID{ids[0]}},
+		{"paged", webhook.DeliveryFilter{Limit: 1, Offset: 1}, []uuid.UUID{ids[1]}},
+		{"past the end", webhook.DeliveryFilter{Offset: 5}, []uuid.UUID{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.ListDeliveries(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("ListDeliveries() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ListDeliveries() returned %d, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i].ID != tt.want[i] {
+					t.Errorf("delivery %d = %s, want %s", i, got[i].ID, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := s.ListDeliveries(ctx, webhook.DeliveryFilter{Limit: -1}); !errors.Is(err, webhook.ErrInvalidFilter) {
+		t.Errorf("ListDeliveries() error = %v, want ErrInvalidFilter", err)
+	}
+}
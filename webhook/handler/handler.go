@@ -0,0 +1,206 @@
+// Package handler exposes webhook endpoint registration and the delivery log
+// over HTTP for operators.
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aquamarinepk/aqm/httpx"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/webhook"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// Permissions required by the webhook routes.
+const (
+	PermissionRead  = "webhooks:read"
+	PermissionWrite = "webhooks:write"
+)
+
+type Handler struct {
+	store   webhook.Store
+	checker middleware.RoleChecker
+}
+
+// NewHandler creates the webhook admin handler. Callers must hold
+// PermissionRead to list endpoints and deliveries and PermissionWrite to
+// register or delete endpoints; the caller is identified by the user set by
+// the Session or Bearer middleware.
+func NewHandler(store webhook.Store, checker middleware.RoleChecker) *Handler {
+	return &Handler{store: store, checker: checker}
+}
+
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	read := middleware.RequirePermission(h.checker, PermissionRead)
+	write := middleware.RequirePermission(h.checker, PermissionWrite)
+
+	r.With(write).Post("/webhooks", h.handleCreateEndpoint)
+	r.With(read).Get("/webhooks", h.handleListEndpoints)
+	r.With(read).Get("/webhooks/deliveries", h.handleListDeliveries)
+	r.With(read).Get("/webhooks/{id}", h.handleGetEndpoint)
+	r.With(write).Delete("/webhooks/{id}", h.handleDeleteEndpoint)
+}
+
+// CreateEndpointRequest registers an endpoint. A secret is generated when
+// none is given. Events lists the event types to deliver; empty means all.
+type CreateEndpointRequest struct {
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+}
+
+// CreateEndpointResponse is the only response that carries the secret.
+type CreateEndpointResponse struct {
+	Endpoint *webhook.Endpoint `json:"endpoint"`
+	Secret   string            `json:"secret"`
+}
+
+type EndpointResponse struct {
+	Endpoint *webhook.Endpoint `json:"endpoint"`
+}
+
+type ListEndpointsResponse struct {
+	Endpoints []*webhook.Endpoint `json:"endpoints"`
+}
+
+type ListDeliveriesResponse struct {
+	Deliveries []*webhook.Delivery `json:"deliveries"`
+	Limit      int                 `json:"limit"`
+	Offset     int                 `json:"offset"`
+}
+
+type ErrorResponse = httpx.ErrorResponse
+
+func (h *Handler) handleCreateEndpoint(w http.ResponseWriter, r *http.Request) {
+	var req CreateEndpointRequest
+	if err := httpx.Bind(r, &req); err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+
+	if req.Secret == "" {
+		secret, err := webhook.GenerateSecret()
+		if err != nil {
+			httpx.WriteError(w, r, httpx.Internal("Cannot generate secret"))
+			return
+		}
+		req.Secret = secret
+	}
+
+	endpoint := webhook.NewEndpoint(req.URL, req.Secret, req.Events, middleware.GetUserID(r.Context()))
+	endpoint.Description = req.Description
+	if err := endpoint.Validate(); err != nil {
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_ENDPOINT", err.Error()))
+		return
+	}
+
+	if err := h.store.CreateEndpoint(r.Context(), endpoint); err != nil {
+		httpx.WriteError(w, r, httpx.Internal("Cannot register webhook endpoint"))
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusCreated, CreateEndpointResponse{Endpoint: endpoint, Secret: endpoint.Secret})
+}
+
+func (h *Handler) handleListEndpoints(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := h.store.ListEndpoints(r.Context())
+	if err != nil {
+		httpx.WriteError(w, r, httpx.Internal("Cannot list webhook endpoints"))
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, ListEndpointsResponse{Endpoints: endpoints})
+}
+
+func (h *Handler) handleGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_ENDPOINT_ID", "Invalid endpoint ID"))
+		return
+	}
+
+	endpoint, err := h.store.GetEndpoint(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, EndpointResponse{Endpoint: endpoint})
+}
+
+func (h *Handler) handleDeleteEndpoint(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_ENDPOINT_ID", "Invalid endpoint ID"))
+		return
+	}
+
+	if err := h.store.DeleteEndpoint(r.Context(), id); err != nil {
+		writeStoreError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListDeliveries serves GET /webhooks/deliveries. Supported query
+// parameters: endpoint_id, event, status, limit, offset.
+func (h *Handler) handleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_FILTER", err.Error()))
+		return
+	}
+
+	filter, err = filter.Normalize()
+	if err != nil {
+		httpx.WriteError(w, r, httpx.BadRequest("INVALID_FILTER", "Invalid limit, offset or status"))
+		return
+	}
+
+	deliveries, err := h.store.ListDeliveries(r.Context(), filter)
+	if err != nil {
+		httpx.WriteError(w, r, httpx.Internal("Cannot list webhook deliveries"))
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, ListDeliveriesResponse{Deliveries: deliveries, Limit: filter.Limit, Offset: filter.Offset})
+}
+
+func parseFilter(r *http.Request) (webhook.DeliveryFilter, error) {
+	q := r.URL.Query()
+	filter := webhook.DeliveryFilter{
+		EventType: q.Get("event"),
+		Status:    q.Get("status"),
+	}
+
+	var err error
+	if v := q.Get("endpoint_id"); v != "" {
+		if filter.EndpointID, err = uuid.Parse(v); err != nil {
+			return filter, errors.New("endpoint_id must be a UUID")
+		}
+	}
+	if filter.Limit, err = parseInt(q.Get("limit")); err != nil {
+		return filter, errors.New("limit must be an integer")
+	}
+	if filter.Offset, err = parseInt(q.Get("offset")); err != nil {
+		return filter, errors.New("offset must be an integer")
+	}
+	return filter, nil
+}
+
+func parseInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func writeStoreError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, webhook.ErrEndpointNotFound) {
+		httpx.WriteError(w, r, httpx.NotFound("ENDPOINT_NOT_FOUND", "Webhook endpoint not found"))
+		return
+	}
+	httpx.WriteError(w, r, httpx.Internal("Internal server error"))
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/aquamarinepk/aqm/webhook"
+	"github.com/aquamarinepk/aqm/webhook/fake"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type fakeChecker struct {
+	allowed map[string]bool
+}
+
+func (c fakeChecker) HasRole(ctx context.Context, userID, roleName string) (bool, error) {
+	return false, nil
+}
+
+func (c fakeChecker) CheckPermission(ctx context.Context, userID, permission string) (bool, error) {
+	return c.allowed[permission], nil
+}
+
+func (c fakeChecker) CheckAnyPermission(ctx context.Context, userID string, permissions []string) (bool, error) {
+	return false, nil
+}
+
+func (c fakeChecker) CheckAllPermissions(ctx context.Context, userID string, permissions []string) (bool, error) {
+	return false, nil
+}
+
+func setupHandler(t *testing.T, allowed ...string) (http.Handler, *fake.Store) {
+	t.Helper()
+
+	checker := fakeChecker{allowed: map[string]bool{}}
+	for _, p := range allowed {
+		checker.allowed[p] = true
+	}
+
+	store := fake.NewStore()
+	r := chi.NewRouter()
+	NewHandler(store, checker).RegisterRoutes(r)
+	return r, store
+}
+
+func serve(h http.Handler, method, target, userID string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	if userID != "" {
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestEndpoints(t *testing.T) {
+	h, store := setupHandler(t, PermissionRead, PermissionWrite)
+
+	rec := serve(h, http.MethodPost, "/webhooks", "alice", CreateEndpointRequest{
+		URL:    "https://hooks.example.com/aqm",
+		Events: []string{webhook.EventUserCreated},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body: %s", rec.Code, rec.Body.String())
+	}
+	var created CreateEndpointResponse
+	json.NewDecoder(rec.Body).Decode(&created)
+	if !strings.HasPrefix(created.Secret, "whsec_") || created.Endpoint.CreatedBy != "alice" {
+		t.Errorf("create response = %+v", created)
+	}
+
+	stored, _ := store.GetEndpoint(context.Background(), created.Endpoint.ID)
+	if stored.Secret != created.Secret {
+		t.Error("stored secret should match the returned one")
+	}
+
+	rec = serve(h, http.MethodGet, "/webhooks/"+created.Endpoint.ID.String(), "alice", nil)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), created.Secret) {
+		t.Errorf("get = %d %s, want 200 without the secret", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(h, http.MethodGet, "/webhooks", "alice", nil)
+	var list ListEndpointsResponse
+	json.NewDecoder(rec.Body).Decode(&list)
+	if rec.Code != http.StatusOK || len(list.Endpoints) != 1 {
+		t.Errorf("list = %d %+v", rec.Code, list)
+	}
+
+	rec = serve(h, http.MethodDelete, "/webhooks/"+created.Endpoint.ID.String(), "alice", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d", rec.Code)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     any
+		wantCode int
+		wantErr  string
+	}{
+		{name: "invalid url", method: http.MethodPost, target: "/webhooks", body: CreateEndpointRequest{URL: "hooks"}, wantCode: http.StatusBadRequest, wantErr: "INVALID_ENDPOINT"},
+		{name: "unknown event", method: http.MethodPost, target: "/webhooks", body: CreateEndpointRequest{URL: "https://a.example.com", Events: []string{"x"}}, wantCode: http.StatusBadRequest, wantErr: "INVALID_ENDPOINT"},
+		{name: "invalid body", method: http.MethodPost, target: "/webhooks", body: "nope", wantCode: http.StatusBadRequest, wantErr: "INVALID_REQUEST"},
+		{name: "invalid id", method: http.MethodGet, target: "/webhooks/abc", wantCode: http.StatusBadRequest, wantErr: "INVALID_ENDPOINT_ID"},
+		{name: "get missing", method: http.MethodGet, target: "/webhooks/" + uuid.NewString(), wantCode: http.StatusNotFound, wantErr: "ENDPOINT_NOT_FOUND"},
+		{name: "delete missing", method: http.MethodDelete, target: "/webhooks/" + created.Endpoint.ID.String(), wantCode: http.StatusNotFound, wantErr: "ENDPOINT_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(h, tt.method, tt.target, "alice", tt.body)
+			var resp ErrorResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if rec.Code != tt.wantCode || resp.Code != tt.wantErr {
+				t.Errorf("status = %d %s, want %d %s", rec.Code, resp.Code, tt.wantCode, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListDeliveries(t *testing.T) {
+	h, store := setupHandler(t, PermissionRead)
+	ctx := context.Background()
+	endpointID := uuid.New()
+	store.CreateDelivery(ctx, &webhook.Delivery{ID: uuid.New(), EndpointID: endpointID, EventType: webhook.EventUserCreated, Status: webhook.StatusSucceeded})
+	store.CreateDelivery(ctx, &webhook.Delivery{ID: uuid.New(), EndpointID: endpointID, EventType: webhook.EventSignInFailed, Status: webhook.StatusFailed})
+	store.CreateDelivery(ctx, &webhook.Delivery{ID: uuid.New(), EndpointID: uuid.New(), EventType: webhook.EventUserCreated, Status: webhook.StatusFailed})
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantCount int
+	}{
+		{name: "all", query: "", wantCode: http.StatusOK, wantCount: 3},
+		{name: "by endpoint", query: "?endpoint_id=" + endpointID.String(), wantCode: http.StatusOK, wantCount: 2},
+		{name: "by event and status", query: "?event=user.created&status=failed", wantCode: http.StatusOK, wantCount: 1},
+		{name: "limit", query: "?limit=1", wantCode: http.StatusOK, wantCount: 1},
+		{name: "bad endpoint", query: "?endpoint_id=x", wantCode: http.StatusBadRequest},
+		{name: "bad limit", query: "?limit=many", wantCode: http.StatusBadRequest},
+		{name: "bad status", query: "?status=lost", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(h, http.MethodGet, "/webhooks/deliveries"+tt.query, "alice", nil)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var resp ListDeliveriesResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if len(resp.Deliveries) != tt.wantCount {
+				t.Errorf("got %d deliveries, want %d", len(resp.Deliveries), tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	h, _ := setupHandler(t, PermissionRead)
+
+	if rec := serve(h, http.MethodGet, "/webhooks", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous list status = %d, want 401", rec.Code)
+	}
+	if rec := serve(h, http.MethodPost, "/webhooks", "bob", CreateEndpointRequest{URL: "https://a.example.com"}); rec.Code != http.StatusForbidden {
+		t.Errorf("create without write permission status = %d, want 403", rec.Code)
+	}
+}
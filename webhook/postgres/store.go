@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aquamarinepk/aqm/webhook"
+	"github.com/google/uuid"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) webhook.Store {
+	return &store{db: db}
+}
+
+func (s *store) CreateEndpoint(ctx context.Context, endpoint *webhook.Endpoint) error {
+	events := endpoint.Events
+	if events == nil {
+		events = []string{}
+	}
+	eventsJSON, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO webhook_endpoints (
+			id, url, secret, events, description, active, created_by, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8
+		)
+	`
+	_, err = s.db.ExecContext(ctx, query,
+		endpoint.ID, endpoint.URL, endpoint.Secret, eventsJSON, endpoint.Description,
+		endpoint.Active, endpoint.CreatedBy, endpoint.CreatedAt,
+	)
+	return err
+}
+
+func (s *store) GetEndpoint(ctx context.Context, id uuid.UUID) (*webhook.Endpoint, error) {
+	query := `
+		SELECT id, url, secret, events, description, active, created_by, created_at
+		FROM webhook_endpoints
+		WHERE id = $1
+	`
+	endpoint, err := scanEndpoint(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, webhook.ErrEndpointNotFound
+	}
+	return endpoint, err
+}
+
+func (s *store) ListEndpoints(ctx context.Context) ([]*webhook.Endpoint, error) {
+	query := `
+		SELECT id, url, secret, events, description, active, created_by, created_at
+		FROM webhook_endpoints
+		ORDER BY created_at, id
+	`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []*webhook.Endpoint{}
+	for rows.Next() {
+		endpoint, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+func (s *store) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return webhook.ErrEndpointNotFound
+	}
+	return nil
+}
+
+func (s *store) CreateDelivery(ctx context.Context, d *webhook.Delivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (
+			id, endpoint_id, event_id, event_type, payload, status,
+			attempts, response_status, error, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+		)
+	`
+	_, err := s.db.ExecContext(ctx, query,
+		d.ID, d.EndpointID, d.EventID, d.EventType, []byte(d.Payload), d.Status,
+		d.Attempts, d.ResponseStatus, d.Error, d.CreatedAt, d.UpdatedAt,
+	)
+	return err
+}
+
+func (s *store) UpdateDelivery(ctx context.Context, d *webhook.Delivery) error {
+	query := `
+		UPDATE webhook_deliveries SET
+			status = $2, attempts = $3, response_status = $4, error = $5, updated_at = $6
+		WHERE id = $1
+	`
+	_, err := s.db.ExecContext(ctx, query,
+		d.ID, d.Status, d.Attempts, d.ResponseStatus, d.Error, d.UpdatedAt,
+	)
+	return err
+}
+
+func (s *store) ListDeliveries(ctx context.Context, filter webhook.DeliveryFilter) ([]*webhook.Delivery, error) {
+	filter, err := filter.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.EndpointID != uuid.Nil {
+		add("endpoint_id = $%d", filter.EndpointID)
+	}
+	if filter.EventType != "" {
+		add("event_type = $%d", filter.EventType)
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+
+	query := `
+		SELECT id, endpoint_id, event_id, event_type, payload, status,
+			attempts, response_status, error, created_at, updated_at
+		FROM webhook_deliveries
+	`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*webhook.Delivery{}
+	for rows.Next() {
+		d := &webhook.Delivery{}
+		var payload []byte
+		if err := rows.Scan(
+			&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &payload, &d.Status,
+			&d.Attempts, &d.ResponseStatus, &d.Error, &d.CreatedAt, &d.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEndpoint(row scanner) (*webhook.Endpoint, error) {
+	endpoint := &webhook.Endpoint{}
+	var eventsJSON []byte
+	if err := row.Scan(
+		&endpoint.ID, &endpoint.URL, &endpoint.Secret, &eventsJSON, &endpoint.Description,
+		&endpoint.Active, &endpoint.CreatedBy, &endpoint.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(eventsJSON, &endpoint.Events); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/testhelper"
+	"github.com/aquamarinepk/aqm/webhook"
+	"github.com/google/uuid"
+)
+
+func TestMain(m *testing.M) {
+	testhelper.Main(m)
+}
+
+func setupTestDB(t *testing.T) (*sql.DB, func()) {
+	t.Helper()
+
+	db, _, cleanup := testhelper.SetupTestDB(t)
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS webhook_endpoints (
+			id UUID PRIMARY KEY,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			events JSONB NOT NULL DEFAULT '[]'::jsonb,
+			description TEXT NOT NULL DEFAULT '',
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_by TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id UUID PRIMARY KEY,
+			endpoint_id UUID NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+			event_id UUID NOT NULL,
+			event_type TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			response_status INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		)
+	`)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to create tables: %v", err)
+	}
+
+	return db, cleanup
+}
+
+func TestStoreEndpoints(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := NewStore(db)
+	ctx := context.Background()
+
+	ep := webhook.NewEndpoint("https://hooks.example.com", "secret", []string{webhook.EventUserCreated}, "admin")
+	ep.CreatedAt = ep.CreatedAt.Truncate(time.Microsecond)
+	if err := s.CreateEndpoint(ctx, ep); err != nil {
+		t.Fatalf("CreateEndpoint() error = %v", err)
+	}
+
+	got, err := s.GetEndpoint(ctx, ep.ID)
+	if err != nil {
+		t.Fatalf("GetEndpoint() error = %v", err)
+	}
+	if got.Secret != "secret" || len(got.Events) != 1 || !got.Active || !got.CreatedAt.Equal(ep.CreatedAt) {
+		t.Errorf("GetEndpoint() = %+v", got)
+	}
+
+	endpoints, err := s.ListEndpoints(ctx)
+	if err != nil || len(endpoints) != 1 {
+		t.Errorf("ListEndpoints() = %v, %v", endpoints, err)
+	}
+
+	if err := s.DeleteEndpoint(ctx, ep.ID); err != nil {
+		t.Fatalf("DeleteEndpoint() error = %v", err)
+	}
+	if _, err := s.GetEndpoint(ctx, ep.ID); !errors.Is(err, webhook.ErrEndpointNotFound) {
+		t.Errorf("GetEndpoint() after delete error = %v, want ErrEndpointNotFound", err)
+	}
+	if err := s.DeleteEndpoint(ctx, ep.ID); !errors.Is(err, webhook.ErrEndpointNotFound) {
+		t.Errorf("DeleteEndpoint() twice error = %v, want ErrEndpointNotFound", err)
+	}
+}
+
+func TestStoreDeliveries(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	s := NewStore(db)
+	ctx := context.Background()
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	ep := webhook.NewEndpoint("https://hooks.example.com", "secret", nil, "admin")
+	if err := s.CreateEndpoint(ctx, ep); err != nil {
+		t.Fatalf("CreateEndpoint() error = %v", err)
+	}
+
+	var deliveries []*webhook.Delivery
+	for i, eventType := range []string{webhook.EventUserCreated, webhook.EventSignInFailed} {
+		payload, _ := json.Marshal(webhook.NewEvent(eventType, "ann"))
+		d := &webhook.Delivery{
+			ID:         uuid.New(),
+			EndpointID: ep.ID,
+			EventID:    uuid.New(),
+			EventType:  eventType,
+			Payload:    payload,
+			Status:     webhook.StatusPending,
+			CreatedAt:  base.Add(time.Duration(i) * time.Hour),
+			UpdatedAt:  base.Add(time.Duration(i) * time.Hour),
+		}
+		if err := s.CreateDelivery(ctx, d); err != nil {
+			t.Fatalf("CreateDelivery() error = %v", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	d := deliveries[0]
+	d.Status, d.Attempts, d.ResponseStatus, d.Error = webhook.StatusFailed, 3, 503, "endpoint returned 503"
+	d.UpdatedAt = base.Add(2 * time.Hour)
+	if err := s.UpdateDelivery(ctx, d); err != nil {
+		t.Fatalf("UpdateDelivery() error = %v", err)
+	}
+
+	all, err := s.ListDeliveries(ctx, webhook.DeliveryFilter{EndpointID: ep.ID})
+	if err != nil {
+		t.Fatalf("ListDeliveries() error = %v", err)
+	}
+	if len(all) != 2 || all[0].EventType != webhook.EventSignInFailed {
+		t.Fatalf("ListDeliveries() should return both deliveries newest first, got %+v", all)
+	}
+
+	failed, err := s.ListDeliveries(ctx, webhook.DeliveryFilter{Status: webhook.StatusFailed})
+	if err != nil {
+		t.Fatalf("ListDeliveries() error = %v", err)
+	}
+	if len(failed) != 1 || failed[0].Attempts != 3 || failed[0].ResponseStatus != 503 || failed[0].Error == "" {
+		t.Errorf("ListDeliveries(status) = %+v", failed)
+	}
+	var e webhook.Event
+	if err := json.Unmarshal(failed[0].Payload, &e); err != nil || e.Subject != "ann" {
+		t.Errorf("payload = %s, %v", failed[0].Payload, err)
+	}
+
+	if err := s.DeleteEndpoint(ctx, ep.ID); err != nil {
+		t.Fatalf("DeleteEndpoint() error = %v", err)
+	}
+	if all, _ := s.ListDeliveries(ctx, webhook.DeliveryFilter{}); len(all) != 0 {
+		t.Errorf("deliveries should be deleted with their endpoint, got %d", len(all))
+	}
+}
@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"type":"user.created"}`)
+	sig := Sign("secret", now, body)
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	if sig != Sign("secret", now, body) {
+		t.Fatal("Sign() is not deterministic")
+	}
+	if err := Verify("secret", sig, ts, body, now.Add(time.Minute), 0); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		sig    string
+		ts     string
+		body   []byte
+		now    time.Time
+	}{
+		{name: "wrong secret", secret: "other", sig: sig, ts: ts, body: body, now: now},
+		{name: "tampered body", secret: "secret", sig: sig, ts: ts, body: []byte(`{}`), now: now},
+		{name: "bad timestamp", secret: "secret", sig: sig, ts: "yesterday", body: body, now: now},
+		{name: "replayed", secret: "secret", sig: sig, ts: ts, body: body, now: now.Add(time.Hour)},
+		{name: "from the future", secret: "secret", sig: sig, ts: ts, body: body, now: now.Add(-time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.secret, tt.sig, tt.ts, tt.body, tt.now, 0); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify() error = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+// Package webhook notifies external endpoints about auth domain events.
+// Operators register endpoints with a shared secret; a Dispatcher posts each
+// event to the subscribed endpoints with an HMAC signature, retries failed
+// deliveries with exponential backoff and records every attempt in a Store.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEndpointNotFound = errors.New("webhook endpoint not found")
+	ErrInvalidEndpoint  = errors.New("invalid webhook endpoint")
+	ErrInvalidFilter    = errors.New("invalid delivery filter")
+	ErrStopped          = errors.New("webhook dispatcher stopped")
+)
+
+// Event types delivered to endpoints.
+const (
+	EventUserCreated  = "user.created"
+	EventRoleAssigned = "role.assigned"
+	EventSignInFailed = "sign-in.failed"
+
+	// Grant request events carry the request ID as subject.
+	EventGrantRequested = "grant.requested"
+	EventGrantApproved  = "grant.approved"
+	EventGrantRejected  = "grant.rejected"
+)
+
+// EventTypes lists every event type an endpoint may subscribe to.
+var EventTypes = []string{
+	EventUserCreated, EventRoleAssigned, EventSignInFailed,
+	EventGrantRequested, EventGrantApproved, EventGrantRejected,
+}
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Default and maximum page sizes for ListDeliveries.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// Event is the JSON payload posted to endpoints.
+type Event struct {
+	ID       uuid.UUID `json:"id"`
+	Type     string    `json:"type"`
+	At       time.Time `json:"at"`
+	Actor    string    `json:"actor,omitempty"`
+	Subject  string    `json:"subject,omitempty"`
+	RemoteIP string    `json:"remote_ip,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// NewEvent creates an event with a fresh ID, stamped with the current time.
+func NewEvent(eventType, subject string) *Event {
+	return &Event{
+		ID:      uuid.New(),
+		Type:    eventType,
+		At:      time.Now().UTC(),
+		Subject: subject,
+	}
+}
+
+// Endpoint is a registered receiver. Events lists the event types it is
+// subscribed to; an empty list subscribes it to all of them. The secret is
+// never serialized.
+type Endpoint struct {
+	ID          uuid.UUID `json:"id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"-"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NewEndpoint creates an active endpoint with a fresh ID.
+func NewEndpoint(rawURL, secret string, events []string, createdBy string) *Endpoint {
+	if events == nil {
+		events = []string{}
+	}
+	return &Endpoint{
+		ID:        uuid.New(),
+		URL:       rawURL,
+		Secret:    secret,
+		Events:    events,
+		Active:    true,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// Validate checks that the endpoint has an absolute http(s) URL, a secret
+// and only known event types.
+func (e *Endpoint) Validate() error {
+	u, err := url.Parse(e.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidEndpoint)
+	}
+	if e.Secret == "" {
+		return fmt.Errorf("%w: secret is required", ErrInvalidEndpoint)
+	}
+	for _, ev := range e.Events {
+		if !slices.Contains(EventTypes, ev) {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidEndpoint, ev)
+		}
+	}
+	return nil
+}
+
+// Subscribed reports whether the endpoint should receive events of the given type.
+func (e *Endpoint) Subscribed(eventType string) bool {
+	return e.Active && (len(e.Events) == 0 || slices.Contains(e.Events, eventType))
+}
+
+// GenerateSecret returns a random signing secret.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Delivery records the attempts to post one event to one endpoint.
+// ResponseStatus and Error describe the last attempt.
+type Delivery struct {
+	ID             uuid.UUID       `json:"id"`
+	EndpointID     uuid.UUID       `json:"endpoint_id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// DeliveryFilter selects deliveries in ListDeliveries. Zero fields match
+// everything. Results are ordered newest first.
+type DeliveryFilter struct {
+	EndpointID uuid.UUID
+	EventType  string
+	Status     string
+	Limit      int
+	Offset     int
+}
+
+// Normalize applies the default limit and validates the filter.
+func (f DeliveryFilter) Normalize() (DeliveryFilter, error) {
+	if f.Limit == 0 {
+		f.Limit = DefaultLimit
+	}
+	if f.Limit < 0 || f.Limit > MaxLimit || f.Offset < 0 {
+		return f, ErrInvalidFilter
+	}
+	switch f.Status {
+	case "", StatusPending, StatusSucceeded, StatusFailed:
+	default:
+		return f, ErrInvalidFilter
+	}
+	return f, nil
+}
+
+// Matches reports whether d satisfies the filter, ignoring Limit and Offset.
+func (f DeliveryFilter) Matches(d *Delivery) bool {
+	switch {
+	case f.EndpointID != uuid.Nil && d.EndpointID != f.EndpointID:
+		return false
+	case f.EventType != "" && d.EventType != f.EventType:
+		return false
+	case f.Status != "" && d.Status != f.Status:
+		return false
+	}
+	return true
+}
+
+// Store persists endpoints and the delivery log. Deleting an endpoint
+// deletes its deliveries.
+type Store interface {
+	CreateEndpoint(ctx context.Context, endpoint *Endpoint) error
+	GetEndpoint(ctx context.Context, id uuid.UUID) (*Endpoint, error)
+	ListEndpoints(ctx context.Context) ([]*Endpoint, error)
+	DeleteEndpoint(ctx context.Context, id uuid.UUID) error
+	CreateDelivery(ctx context.Context, delivery *Delivery) error
+	UpdateDelivery(ctx context.Context, delivery *Delivery) error
+	ListDeliveries(ctx context.Context, filter DeliveryFilter) ([]*Delivery, error)
+}
+
+// Publisher accepts events for delivery. *Dispatcher satisfies it.
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) error
+}
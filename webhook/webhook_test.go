@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestEndpointValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		secret  string
+		events  []string
+		wantErr bool
+	}{
+		{name: "valid", url: "https://hooks.example.com/aqm", secret: "s", events: []string{EventUserCreated}},
+		{name: "all events", url: "http://localhost:9000/hook", secret: "s"},
+		{name: "relative url", url: "/hook", secret: "s", wantErr: true},
+		{name: "bad scheme", url: "ftp://hooks.example.com", secret: "s", wantErr: true},
+		{name: "no secret", url: "https://hooks.example.com", wantErr: true},
+		{name: "unknown event", url: "https://hooks.example.com", secret: "s", events: []string{"user.deleted"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewEndpoint(tt.url, tt.secret, tt.events, "admin").Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidEndpoint) {
+				t.Errorf("Validate() error = %v, want ErrInvalidEndpoint", err)
+			}
+		})
+	}
+}
+
+func TestEndpointSubscribed(t *testing.T) {
+	all := NewEndpoint("https://hooks.example.com", "s", nil, "admin")
+	some := NewEndpoint("https://hooks.example.com", "s", []string{EventSignInFailed}, "admin")
+
+	if !all.Subscribed(EventUserCreated) || !all.Subscribed(EventSignInFailed) {
+		t.Error("endpoint without events should receive every event")
+	}
+	if some.Subscribed(EventUserCreated) || !some.Subscribed(EventSignInFailed) {
+		t.Error("endpoint should only receive the events it lists")
+	}
+
+	all.Active = false
+	if all.Subscribed(EventUserCreated) {
+		t.Error("inactive endpoint should receive nothing")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+	b, _ := GenerateSecret()
+	if !strings.HasPrefix(a, "whsec_") || len(a) != len("whsec_")+64 || a == b {
+		t.Errorf("GenerateSecret() = %q, %q", a, b)
+	}
+}
+
+func TestDeliveryFilter(t *testing.T) {
+	f, err := DeliveryFilter{}.Normalize()
+	if err != nil || f.Limit != DefaultLimit {
+		t.Fatalf("Normalize() = %+v, %v", f, err)
+	}
+
+	for _, bad := range []DeliveryFilter{{Limit: -1}, {Limit: MaxLimit + 1}, {Offset: -1}, {Status: "lost"}} {
+		if _, err := bad.Normalize(); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Normalize(%+v) error = %v, want ErrInvalidFilter", bad, err)
+		}
+	}
+
+	endpointID := uuid.New()
+	d := &Delivery{EndpointID: endpointID, EventType: EventUserCreated, Status: StatusFailed}
+	if !(DeliveryFilter{EndpointID: endpointID, Status: StatusFailed}).Matches(d) {
+		t.Error("expected filter to match")
+	}
+	if (DeliveryFilter{EventType: EventRoleAssigned}).Matches(d) {
+		t.Error("expected event filter not to match")
+	}
+	if (DeliveryFilter{EndpointID: uuid.New()}).Matches(d) {
+		t.Error("expected endpoint filter not to match")
+	}
+}
@@ -0,0 +1,113 @@
+package ws
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// Conn is an open connection registered with a Hub.
+type Conn struct {
+	// ID identifies the connection in logs.
+	ID string
+	// UserID is the user that opened the connection, empty when anonymous.
+	UserID string
+
+	hub  *Hub
+	ws   *websocket.Conn
+	send chan []byte
+
+	closeOnce sync.Once
+	done      chan struct{}
+	mu        sync.Mutex
+	status    websocket.StatusCode
+	reason    string
+}
+
+// Send queues msg for the connection. When the send buffer is full the
+// connection is closed and ErrSlowConsumer returned.
+func (c *Conn) Send(msg []byte) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case c.send <- msg:
+		return nil
+	default:
+		c.close(websocket.StatusPolicyViolation, "client too slow")
+		return ErrSlowConsumer
+	}
+}
+
+// Close closes the connection normally.
+func (c *Conn) Close() {
+	c.close(websocket.StatusNormalClosure, "")
+}
+
+func (c *Conn) close(status websocket.StatusCode, reason string) {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.status, c.reason = status, reason
+		c.mu.Unlock()
+		close(c.done)
+	})
+}
+
+func (c *Conn) closeStatus() (websocket.StatusCode, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status, c.reason
+}
+
+// readLoop reads until the connection fails, which also processes the
+// client's pongs and close frames.
+func (c *Conn) readLoop(ctx context.Context, cancel context.CancelFunc) {
+	defer cancel()
+	for {
+		_, msg, err := c.ws.Read(ctx)
+		if err != nil {
+			return
+		}
+		if c.hub.onMessage != nil {
+			c.hub.onMessage(ctx, c, msg)
+		}
+	}
+}
+
+// writeLoop writes queued messages and pings the client until the
+// connection is closed, in which case it returns nil, or fails.
+func (c *Conn) writeLoop(ctx context.Context) error {
+	ticker := time.NewTicker(c.hub.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.done:
+			return nil
+		case msg := <-c.send:
+			if err := c.write(ctx, msg); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, c.hub.pingInterval)
+			err := c.ws.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *Conn) write(ctx context.Context, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, c.hub.writeTimeout)
+	defer cancel()
+	return c.ws.Write(ctx, websocket.MessageText, msg)
+}
@@ -0,0 +1,307 @@
+// Package ws pushes messages to browsers over WebSockets.
+//
+// A Hub keeps a registry of open connections, indexed by the user that
+// opened them, and sends messages to everyone or to every connection of one
+// user. It pings clients to detect dead connections and closes them all when
+// stopped, so services can hand it to app.Setup alongside their other
+// components and serve it with app.WithWebSockets.
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/aquamarinepk/aqm/middleware"
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+)
+
+// Defaults used unless overridden by options.
+const (
+	DefaultPingInterval = 30 * time.Second
+	DefaultWriteTimeout = 10 * time.Second
+	DefaultSendBuffer   = 16
+)
+
+var (
+	// ErrClosed is returned when sending to a connection that is closed.
+	ErrClosed = errors.New("websocket connection closed")
+	// ErrSlowConsumer is returned when a connection's send buffer is full.
+	// The connection is closed, as the client is not keeping up.
+	ErrSlowConsumer = errors.New("websocket client too slow")
+)
+
+// MessageHandler handles a message received from a client. ctx carries the
+// values of the request that opened the connection and is canceled when the
+// connection closes.
+type MessageHandler func(ctx context.Context, c *Conn, msg []byte)
+
+// Hub tracks open connections and sends messages to them.
+type Hub struct {
+	log          log.Logger
+	pingInterval time.Duration
+	writeTimeout time.Duration
+	sendBuffer   int
+	accept       websocket.AcceptOptions
+	userID       func(*http.Request) string
+	onMessage    MessageHandler
+
+	mu      sync.RWMutex
+	conns   map[*Conn]struct{}
+	users   map[string]map[*Conn]struct{}
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// Option configures a Hub.
+type Option func(*Hub)
+
+// WithPingInterval sets how often clients are pinged. A client that does not
+// answer before the next ping is due is disconnected.
+func WithPingInterval(d time.Duration) Option {
+	return func(h *Hub) {
+		if d > 0 {
+			h.pingInterval = d
+		}
+	}
+}
+
+// WithWriteTimeout bounds how long writing a message may take.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(h *Hub) {
+		if d > 0 {
+			h.writeTimeout = d
+		}
+	}
+}
+
+// WithSendBuffer sets how many messages may be queued per connection before
+// it is treated as a slow consumer and closed.
+func WithSendBuffer(n int) Option {
+	return func(h *Hub) {
+		if n > 0 {
+			h.sendBuffer = n
+		}
+	}
+}
+
+// WithOriginPatterns allows cross-origin connections from hosts matching
+// patterns, as in path.Match. Same-origin connections are always allowed.
+func WithOriginPatterns(patterns ...string) Option {
+	return func(h *Hub) {
+		h.accept.OriginPatterns = append(h.accept.OriginPatterns, patterns...)
+	}
+}
+
+// WithUserID sets how the user opening a connection is identified. By
+// default it is the user set by the session or bearer middleware; requests
+// without one open anonymous connections that only receive broadcasts.
+func WithUserID(fn func(*http.Request) string) Option {
+	return func(h *Hub) {
+		if fn != nil {
+			h.userID = fn
+		}
+	}
+}
+
+// WithMessageHandler handles messages sent by clients. Without one they are
+// discarded.
+func WithMessageHandler(fn MessageHandler) Option {
+	return func(h *Hub) {
+		h.onMessage = fn
+	}
+}
+
+// NewHub creates a hub with no connections.
+func NewHub(log log.Logger, opts ...Option) *Hub {
+	h := &Hub{
+		log:          log,
+		pingInterval: DefaultPingInterval,
+		writeTimeout: DefaultWriteTimeout,
+		sendBuffer:   DefaultSendBuffer,
+		userID: func(r *http.Request) string {
+			return middleware.GetUserID(r.Context())
+		},
+		conns: make(map[*Conn]struct{}),
+		users: make(map[string]map[*Conn]struct{}),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection and serves it
+// until either side closes it. Once the hub is stopped it responds 503.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	if h.stopped {
+		h.mu.Unlock()
+		http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	h.wg.Add(1)
+	h.mu.Unlock()
+	defer h.wg.Done()
+
+	ws, err := websocket.Accept(w, r, &h.accept)
+	if err != nil {
+		h.log.Debugf("websocket upgrade failed: %v", err)
+		return
+	}
+
+	c := &Conn{
+		ID:     uuid.NewString(),
+		UserID: h.userID(r),
+		hub:    h,
+		ws:     ws,
+		send:   make(chan []byte, h.sendBuffer),
+		done:   make(chan struct{}),
+		status: websocket.StatusNormalClosure,
+	}
+	h.add(c)
+	defer h.remove(c)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go c.readLoop(ctx, cancel)
+	if err := c.writeLoop(ctx); err != nil {
+		// The client is gone or not responding, so there is no one to
+		// complete a close handshake with.
+		h.log.Debugf("websocket %s closed: %v", c.ID, err)
+		ws.CloseNow()
+		return
+	}
+
+	code, reason := c.closeStatus()
+	ws.Close(code, reason)
+}
+
+// Broadcast sends msg to every connection and returns how many it was
+// queued for.
+func (h *Hub) Broadcast(msg []byte) int {
+	h.mu.RLock()
+	conns := make([]*Conn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.RUnlock()
+	return sendAll(conns, msg)
+}
+
+// SendToUser sends msg to every connection of userID and returns how many
+// it was queued for.
+func (h *Hub) SendToUser(userID string, msg []byte) int {
+	if userID == "" {
+		return 0
+	}
+	h.mu.RLock()
+	conns := make([]*Conn, 0, len(h.users[userID]))
+	for c := range h.users[userID] {
+		conns = append(conns, c)
+	}
+	h.mu.RUnlock()
+	return sendAll(conns, msg)
+}
+
+// BroadcastJSON is like Broadcast but sends v encoded as JSON.
+func (h *Hub) BroadcastJSON(v any) (int, error) {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return h.Broadcast(msg), nil
+}
+
+// SendJSONToUser is like SendToUser but sends v encoded as JSON.
+func (h *Hub) SendJSONToUser(userID string, v any) (int, error) {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return h.SendToUser(userID, msg), nil
+}
+
+func sendAll(conns []*Conn, msg []byte) int {
+	sent := 0
+	for _, c := range conns {
+		if c.Send(msg) == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// Count returns the number of open connections.
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// Online reports whether userID has at least one open connection.
+func (h *Hub) Online(userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.users[userID]) > 0
+}
+
+// Stop refuses new connections, closes the open ones with status going away
+// and waits for them to finish until ctx is done.
+func (h *Hub) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	h.stopped = true
+	conns := make([]*Conn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range conns {
+		c.close(websocket.StatusGoingAway, "server shutting down")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *Hub) add(c *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.conns[c] = struct{}{}
+	if c.UserID == "" {
+		return
+	}
+	if h.users[c.UserID] == nil {
+		h.users[c.UserID] = make(map[*Conn]struct{})
+	}
+	h.users[c.UserID][c] = struct{}{}
+}
+
+func (h *Hub) remove(c *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.conns, c)
+	if set := h.users[c.UserID]; set != nil {
+		delete(set, c)
+		if len(set) == 0 {
+			delete(h.users, c.UserID)
+		}
+	}
+}
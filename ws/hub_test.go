@@ -0,0 +1,212 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquamarinepk/aqm/log"
+	"github.com/coder/websocket"
+)
+
+// newTestServer serves hub with the user taken from the "user" query
+// parameter.
+func newTestServer(t *testing.T, opts ...Option) (*Hub, *httptest.Server) {
+	t.Helper()
+
+	opts = append([]Option{WithUserID(func(r *http.Request) string { return r.URL.Query().Get("user") })}, opts...)
+	hub := NewHub(log.NewNoopLogger(), opts...)
+	srv := httptest.NewServer(hub)
+	t.Cleanup(func() {
+		hub.Stop(context.Background())
+		srv.Close()
+	})
+	return hub, srv
+}
+
+func dial(t *testing.T, srv *httptest.Server, user string) *websocket.Conn {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"?user="+user, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { c.CloseNow() })
+	return c
+}
+
+func read(t *testing.T, c *websocket.Conn) (string, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, msg, err := c.Read(ctx)
+	return string(msg), err
+}
+
+// waitFor polls until cond holds, as registration happens after the
+// handshake completes on the client.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBroadcastAndSendToUser(t *testing.T) {
+	hub, srv := newTestServer(t)
+	ann1, ann2, bob, anon := dial(t, srv, "ann"), dial(t, srv, "ann"), dial(t, srv, "bob"), dial(t, srv, "")
+	waitFor(t, func() bool { return hub.Count() == 4 })
+
+	if n := hub.SendToUser("ann", []byte("for ann")); n != 2 {
+		t.Errorf("SendToUser(ann) = %d, want 2", n)
+	}
+	for _, c := range []*websocket.Conn{ann1, ann2} {
+		if msg, err := read(t, c); err != nil || msg != "for ann" {
+			t.Errorf("ann read = %q, %v", msg, err)
+		}
+	}
+
+	if n := hub.SendToUser("", []byte("nobody")); n != 0 {
+		t.Errorf("SendToUser(\"\") = %d, want 0", n)
+	}
+
+	if n, err := hub.BroadcastJSON(map[string]string{"type": "list.updated"}); err != nil || n != 4 {
+		t.Errorf("BroadcastJSON() = %d, %v, want 4", n, err)
+	}
+	for _, c := range []*websocket.Conn{ann1, ann2, bob, anon} {
+		if msg, err := read(t, c); err != nil || msg != `{"type":"list.updated"}` {
+			t.Errorf("broadcast read = %q, %v", msg, err)
+		}
+	}
+
+	if _, err := hub.SendJSONToUser("bob", func() {}); err == nil {
+		t.Error("SendJSONToUser() with an unencodable value returned no error")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	hub, srv := newTestServer(t)
+	c := dial(t, srv, "ann")
+	waitFor(t, func() bool { return hub.Online("ann") })
+
+	if hub.Online("bob") {
+		t.Error("Online(bob) = true, want false")
+	}
+
+	c.Close(websocket.StatusNormalClosure, "")
+	waitFor(t, func() bool { return hub.Count() == 0 })
+	if hub.Online("ann") {
+		t.Error("Online(ann) = true after disconnect")
+	}
+}
+
+func TestMessageHandler(t *testing.T) {
+	_, srv := newTestServer(t, WithMessageHandler(func(ctx context.Context, c *Conn, msg []byte) {
+		c.Send([]byte(c.UserID + ": " + string(msg)))
+	}))
+	c := dial(t, srv, "ann")
+
+	if err := c.Write(context.Background(), websocket.MessageText, []byte("hello")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if msg, err := read(t, c); err != nil || msg != "ann: hello" {
+		t.Errorf("reply = %q, %v", msg, err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	hub, srv := newTestServer(t, WithPingInterval(20*time.Millisecond))
+	c := dial(t, srv, "ann")
+	// Reading lets the client answer pings; a client that answers stays
+	// connected across several intervals.
+	ctx := c.CloseRead(context.Background())
+	waitFor(t, func() bool { return hub.Count() == 1 })
+
+	time.Sleep(100 * time.Millisecond)
+	if hub.Count() != 1 || ctx.Err() != nil {
+		t.Errorf("connection dropped while answering pings")
+	}
+}
+
+func TestUnresponsiveClientIsDropped(t *testing.T) {
+	hub, srv := newTestServer(t, WithPingInterval(20*time.Millisecond))
+	// Without reading, the client never answers pings.
+	dial(t, srv, "ann")
+	waitFor(t, func() bool { return hub.Count() == 1 })
+	waitFor(t, func() bool { return hub.Count() == 0 })
+}
+
+func TestSlowConsumer(t *testing.T) {
+	c := &Conn{send: make(chan []byte, 1), done: make(chan struct{})}
+
+	if err := c.Send([]byte("queued")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := c.Send([]byte("overflow")); !errors.Is(err, ErrSlowConsumer) {
+		t.Errorf("Send() on a full buffer error = %v, want ErrSlowConsumer", err)
+	}
+	if status, _ := c.closeStatus(); status != websocket.StatusPolicyViolation {
+		t.Errorf("close status = %v, want policy violation", status)
+	}
+	if err := c.Send([]byte("after close")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Send() after close error = %v, want ErrClosed", err)
+	}
+}
+
+func TestStop(t *testing.T) {
+	hub, srv := newTestServer(t)
+	c := dial(t, srv, "ann")
+	waitFor(t, func() bool { return hub.Count() == 1 })
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := read(t, c)
+		errc <- err
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hub.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if hub.Count() != 0 {
+		t.Errorf("Count() after Stop = %d, want 0", hub.Count())
+	}
+	if status := websocket.CloseStatus(<-errc); status != websocket.StatusGoingAway {
+		t.Errorf("close status = %v, want going away", status)
+	}
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("connect after Stop status = %d, want 503", resp.StatusCode)
+	}
+}
+
+func TestRejectsPlainRequests(t *testing.T) {
+	_, srv := newTestServer(t)
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("status = %d, want 426", resp.StatusCode)
+	}
+}